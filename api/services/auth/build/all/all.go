@@ -4,6 +4,7 @@ package all
 import (
 	"github.com/ardanlabs/service/app/domain/authapp"
 	"github.com/ardanlabs/service/app/domain/checkapp"
+	"github.com/ardanlabs/service/app/domain/wellknownapp"
 	"github.com/ardanlabs/service/app/sdk/mux"
 	"github.com/ardanlabs/service/foundation/web"
 )
@@ -25,7 +26,22 @@ func (add) Add(app *web.App, cfg mux.Config) {
 	})
 
 	authapp.Routes(app, authapp.Config{
-		UserBus: cfg.BusConfig.UserBus,
-		Auth:    cfg.AuthConfig.Auth,
+		UserBus:       cfg.BusConfig.UserBus,
+		APIKeyBus:     cfg.BusConfig.APIKeyBus,
+		SessionBus:    cfg.BusConfig.SessionBus,
+		Auth:          cfg.AuthConfig.Auth,
+		LoginThrottle: cfg.AuthConfig.LoginThrottle,
+		AccessTTL:     cfg.AuthConfig.AccessTTL,
+	})
+
+	wellknownapp.Routes(app, wellknownapp.Config{
+		Log:               cfg.Log,
+		UserBus:           cfg.BusConfig.UserBus,
+		Domain:            cfg.AuthConfig.WellKnown.Domain,
+		BaseURL:           cfg.AuthConfig.WellKnown.BaseURL,
+		Issuer:            cfg.AuthConfig.WellKnown.Issuer,
+		TokenKID:          cfg.AuthConfig.WellKnown.TokenKID,
+		Sessions:          cfg.BusConfig.SessionBus != nil,
+		ChangePasswordURL: cfg.AuthConfig.WellKnown.ChangePasswordURL,
 	})
 }
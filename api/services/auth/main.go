@@ -5,7 +5,9 @@ import (
 	"errors"
 	"expvar"
 	"fmt"
+	"net"
 	"net/http"
+	"net/mail"
 	"os"
 	"os/signal"
 	"runtime"
@@ -16,15 +18,38 @@ import (
 	"github.com/ardanlabs/service/api/services/auth/build/all"
 	"github.com/ardanlabs/service/app/sdk/auth"
 	"github.com/ardanlabs/service/app/sdk/debug"
+	"github.com/ardanlabs/service/app/sdk/mid"
 	"github.com/ardanlabs/service/app/sdk/mux"
+	"github.com/ardanlabs/service/business/domain/apikeybus"
+	"github.com/ardanlabs/service/business/domain/apikeybus/stores/apikeydb"
+	"github.com/ardanlabs/service/business/domain/notificationbus"
+	"github.com/ardanlabs/service/business/domain/notificationbus/stores/notificationdb"
+	"github.com/ardanlabs/service/business/domain/sessionbus"
+	"github.com/ardanlabs/service/business/domain/sessionbus/stores/sessiondb"
+	"github.com/ardanlabs/service/business/domain/templatebus"
+	"github.com/ardanlabs/service/business/domain/templatebus/stores/templatedb"
 	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/domain/userbus/plugins/usermetrics"
+	"github.com/ardanlabs/service/business/domain/userbus/plugins/userratelimit"
 	"github.com/ardanlabs/service/business/domain/userbus/stores/usercache"
 	"github.com/ardanlabs/service/business/domain/userbus/stores/userdb"
+	"github.com/ardanlabs/service/business/sdk/breach"
+	"github.com/ardanlabs/service/business/sdk/cache"
 	"github.com/ardanlabs/service/business/sdk/delegate"
 	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/business/types/name"
+	"github.com/ardanlabs/service/foundation/boot"
+	"github.com/ardanlabs/service/foundation/ctxval"
 	"github.com/ardanlabs/service/foundation/keystore"
+	"github.com/ardanlabs/service/foundation/limiter"
+	"github.com/ardanlabs/service/foundation/limiter/redisbucket"
 	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/mailer/consolemailer"
+	"github.com/ardanlabs/service/foundation/mailer/sendgridmailer"
+	"github.com/ardanlabs/service/foundation/mailer/sesmailer"
+	"github.com/ardanlabs/service/foundation/mailer/smtpmailer"
 	"github.com/ardanlabs/service/foundation/otel"
+	"github.com/jmoiron/sqlx"
 )
 
 var build = "develop"
@@ -64,8 +89,14 @@ func run(ctx context.Context, log *logger.Logger) error {
 	// -------------------------------------------------------------------------
 	// Configuration
 
+	report := boot.New(log)
+	report.Phase(ctx, "config")
+
 	cfg := struct {
 		conf.Version
+		DevMode       bool          `conf:"default:false,help:panic when a required context value is missing"`
+		StartupBudget time.Duration `conf:"default:30s,help:fail startup when it takes longer, zero disables the check"`
+
 		Web struct {
 			ReadTimeout        time.Duration `conf:"default:5s"`
 			WriteTimeout       time.Duration `conf:"default:10s"`
@@ -77,9 +108,75 @@ func run(ctx context.Context, log *logger.Logger) error {
 		}
 		Auth struct {
 			KeysEnvVar string
-			KeysFolder string `conf:"default:zarf/keys/"`
-			ActiveKID  string `conf:"default:54bb2165-71e1-41a6-af3e-7da4a0e1e2c1"`
-			Issuer     string `conf:"default:service project"`
+			KeysFolder string        `conf:"default:zarf/keys/"`
+			ActiveKID  string        `conf:"default:54bb2165-71e1-41a6-af3e-7da4a0e1e2c1"`
+			Issuer     string        `conf:"default:service project"`
+			TokenTTL   time.Duration `conf:"default:8760h"`
+		}
+		BreakGlass struct {
+			CredentialHash string        `conf:"mask"`
+			TTL            time.Duration `conf:"default:1h"`
+		}
+		Mail struct {
+			Transport      string `conf:"default:none,help:how notifications are emailed: none, console, smtp, ses or sendgrid"`
+			From           string `conf:"default:Sales <no-reply@example.com>"`
+			SMTPHost       string `conf:"default:localhost"`
+			SMTPPort       int    `conf:"default:587"`
+			SMTPUsername   string
+			SMTPPassword   string `conf:"mask"`
+			SESRegion      string `conf:"default:us-east-1"`
+			SESAccessKeyID string
+			SESSecretKey   string        `conf:"mask"`
+			SendGridAPIKey string        `conf:"mask"`
+			Timeout        time.Duration `conf:"default:10s"`
+		}
+		LoginThrottle struct {
+			AccountBase time.Duration `conf:"default:1s,help:first delay after a failed login for an account, 0 disables"`
+			AccountMax  time.Duration `conf:"default:5m"`
+			AddrBase    time.Duration `conf:"default:250ms,help:first delay after a failed login from an address, 0 disables"`
+			AddrMax     time.Duration `conf:"default:1m"`
+			Forget      time.Duration `conf:"default:1h"`
+		}
+		Metrics struct {
+			UserExpvar string `conf:"default:userbus,help:expvar map the user business metrics are published in, empty disables"`
+		}
+		LoginRateLimit struct {
+			Backend       string        `conf:"default:memory,help:where login attempts are counted: none, memory or redis"`
+			EmailRate     float64       `conf:"default:0.1,help:login attempts per second allowed for an email once its burst is used"`
+			EmailBurst    int           `conf:"default:10"`
+			IPRate        float64       `conf:"default:1,help:login attempts per second allowed for an address once its burst is used"`
+			IPBurst       int           `conf:"default:50"`
+			RedisAddr     string        `conf:"default:redis-service:6379"`
+			RedisPassword string        `conf:"mask"`
+			RedisDB       int           `conf:"default:0"`
+			RedisTimeout  time.Duration `conf:"default:500ms"`
+		}
+		Cache struct {
+			Backend       string        `conf:"default:memory,help:where cached users are kept: memory or redis, redis shares them between instances"`
+			Capacity      int           `conf:"default:10000,help:most entries the memory cache holds"`
+			TTL           time.Duration `conf:"default:1m"`
+			RedisAddr     string        `conf:"default:redis-service:6379"`
+			RedisPassword string        `conf:"mask"`
+			RedisDB       int           `conf:"default:0"`
+			RedisTimeout  time.Duration `conf:"default:500ms"`
+		}
+		Sessions struct {
+			RefreshTTL time.Duration `conf:"default:720h,help:how long a session lasts without being refreshed"`
+			AccessTTL  time.Duration `conf:"default:15m,help:lifetime of the access tokens issued for a session"`
+		}
+		Names struct {
+			MinLength int `conf:"default:3,help:fewest characters in a name, counted as a reader sees them"`
+			MaxLength int `conf:"default:20,help:most characters in a name, must match across services"`
+		}
+		WellKnown struct {
+			BaseURL           string `conf:"help:public URL of the service, enables the authorization server metadata"`
+			Domain            string `conf:"help:domain of the acct: resources answered by webfinger, enables webfinger"`
+			ChangePasswordURL string `conf:"help:page password managers send users to, enables the change-password redirect"`
+		}
+		Rotation struct {
+			RetiringKID string
+			Deadline    string        `conf:"help:RFC3339 time the retiring key stops being accepted"`
+			Interval    time.Duration `conf:"default:1m"`
 		}
 		DB struct {
 			User         string `conf:"default:postgres"`
@@ -89,12 +186,40 @@ func run(ctx context.Context, log *logger.Logger) error {
 			MaxIdleConns int    `conf:"default:0"`
 			MaxOpenConns int    `conf:"default:0"`
 			DisableTLS   bool   `conf:"default:true"`
+			// Connections are closed once they have been open or idle for
+			// that long so the pool follows failovers and scale downs.
+			// Zero keeps them.
+			ConnMaxLifetime time.Duration `conf:"default:0s"`
+			ConnMaxIdleTime time.Duration `conf:"default:0s"`
 		}
 		Tempo struct {
 			Host        string  `conf:"default:tempo:4317"`
 			ServiceName string  `conf:"default:auth"`
 			Probability float64 `conf:"default:0.05"`
 		}
+		Hasher struct {
+			Algorithm         string `conf:"default:bcrypt"`
+			BcryptCost        int    `conf:"default:10"`
+			Argon2Memory      uint32 `conf:"default:65536"`
+			Argon2Iterations  uint32 `conf:"default:1"`
+			Argon2Parallelism uint8  `conf:"default:4"`
+		}
+		PasswordPolicy struct {
+			MinLength     int      `conf:"default:8"`
+			RequireUpper  bool     `conf:"default:true"`
+			RequireLower  bool     `conf:"default:true"`
+			RequireDigit  bool     `conf:"default:true"`
+			RequireSymbol bool     `conf:"default:false"`
+			Banned        []string `conf:"default:password;12345678;qwerty123"`
+			History       int      `conf:"default:5"`
+			Breach        struct {
+				Enabled  bool          `conf:"default:false"`
+				URL      string        `conf:"default:https://api.pwnedpasswords.com"`
+				Timeout  time.Duration `conf:"default:2s"`
+				CacheTTL time.Duration `conf:"default:1h"`
+				FailOpen bool          `conf:"default:true,help:accept passwords when the breach check can't be performed"`
+			}
+		}
 	}{
 		Version: conf.Version{
 			Build: build,
@@ -126,21 +251,32 @@ func run(ctx context.Context, log *logger.Logger) error {
 
 	log.BuildInfo(ctx)
 
+	ctxval.SetDevMode(cfg.DevMode)
+	report.SetBudget(cfg.StartupBudget)
+
+	if err := name.SetLimits(cfg.Names.MinLength, cfg.Names.MaxLength); err != nil {
+		return fmt.Errorf("setting name limits: %w", err)
+	}
+
 	expvar.NewString("build").Set(cfg.Build)
 
 	// -------------------------------------------------------------------------
 	// Database Support
 
+	report.Phase(ctx, "db")
+
 	log.Info(ctx, "startup", "status", "initializing database support", "hostport", cfg.DB.Host)
 
 	db, err := sqldb.Open(sqldb.Config{
-		User:         cfg.DB.User,
-		Password:     cfg.DB.Password,
-		Host:         cfg.DB.Host,
-		Name:         cfg.DB.Name,
-		MaxIdleConns: cfg.DB.MaxIdleConns,
-		MaxOpenConns: cfg.DB.MaxOpenConns,
-		DisableTLS:   cfg.DB.DisableTLS,
+		User:            cfg.DB.User,
+		Password:        cfg.DB.Password,
+		Host:            cfg.DB.Host,
+		Name:            cfg.DB.Name,
+		MaxIdleConns:    cfg.DB.MaxIdleConns,
+		MaxOpenConns:    cfg.DB.MaxOpenConns,
+		DisableTLS:      cfg.DB.DisableTLS,
+		ConnMaxLifetime: cfg.DB.ConnMaxLifetime,
+		ConnMaxIdleTime: cfg.DB.ConnMaxIdleTime,
 	})
 	if err != nil {
 		return fmt.Errorf("connecting to db: %w", err)
@@ -148,15 +284,198 @@ func run(ctx context.Context, log *logger.Logger) error {
 
 	defer db.Close()
 
+	poolMetrics, err := sqldb.RegisterPoolMetrics(nil, map[string]*sqlx.DB{"primary": db})
+	if err != nil {
+		return fmt.Errorf("registering pool metrics: %w", err)
+	}
+
+	defer poolMetrics.Unregister()
+
 	// -------------------------------------------------------------------------
 	// Create Business Packages
 
+	report.Phase(ctx, "business")
+
+	hasher, err := userbus.NewHasher(cfg.Hasher.Algorithm, cfg.Hasher.BcryptCost, userbus.Argon2idParams{
+		Memory:      cfg.Hasher.Argon2Memory,
+		Iterations:  cfg.Hasher.Argon2Iterations,
+		Parallelism: cfg.Hasher.Argon2Parallelism,
+	})
+	if err != nil {
+		return fmt.Errorf("constructing hasher: %w", err)
+	}
+
+	// Users change their own password through this service, so it must
+	// enforce the same policy as the sales service.
+	passwordPolicy := userbus.PasswordPolicy{
+		MinLength:     cfg.PasswordPolicy.MinLength,
+		RequireUpper:  cfg.PasswordPolicy.RequireUpper,
+		RequireLower:  cfg.PasswordPolicy.RequireLower,
+		RequireDigit:  cfg.PasswordPolicy.RequireDigit,
+		RequireSymbol: cfg.PasswordPolicy.RequireSymbol,
+		Banned:        cfg.PasswordPolicy.Banned,
+		History:       cfg.PasswordPolicy.History,
+	}
+
+	if cfg.PasswordPolicy.Breach.Enabled {
+		breachOptions := []func(opts *breach.Options){
+			breach.WithCache(cfg.PasswordPolicy.Breach.CacheTTL),
+		}
+
+		if cfg.PasswordPolicy.Breach.FailOpen {
+			breachOptions = append(breachOptions, breach.WithFailOpen())
+		}
+
+		provider := breach.NewHIBP(&http.Client{Timeout: cfg.PasswordPolicy.Breach.Timeout}, cfg.PasswordPolicy.Breach.URL)
+		passwordPolicy.Breach = breach.NewChecker(provider, breachOptions...)
+	}
+
+	userMetricsPlugin, err := usermetrics.NewPlugin(usermetrics.Config{Expvar: cfg.Metrics.UserExpvar})
+	if err != nil {
+		return fmt.Errorf("constructing user metrics: %w", err)
+	}
+
+	plugins := []userbus.Plugin{userMetricsPlugin}
+
+	switch cfg.LoginRateLimit.Backend {
+	case "", "none":
+	case "memory":
+		var rl userratelimit.Config
+
+		if rl.Email, err = limiter.NewTokenBucket(cfg.LoginRateLimit.EmailRate, cfg.LoginRateLimit.EmailBurst); err != nil {
+			return fmt.Errorf("constructing email login rate limit: %w", err)
+		}
+
+		if rl.IP, err = limiter.NewTokenBucket(cfg.LoginRateLimit.IPRate, cfg.LoginRateLimit.IPBurst); err != nil {
+			return fmt.Errorf("constructing address login rate limit: %w", err)
+		}
+
+		plugins = append(plugins, userratelimit.NewPlugin(log, rl))
+
+	case "redis":
+		newBucket := func(prefix string, rate float64, burst int) (*redisbucket.Bucket, error) {
+			return redisbucket.New(redisbucket.Config{
+				Addr:     cfg.LoginRateLimit.RedisAddr,
+				Password: cfg.LoginRateLimit.RedisPassword,
+				DB:       cfg.LoginRateLimit.RedisDB,
+				Prefix:   prefix,
+				Rate:     rate,
+				Burst:    burst,
+				Timeout:  cfg.LoginRateLimit.RedisTimeout,
+			})
+		}
+
+		emailBucket, err := newBucket("login:", cfg.LoginRateLimit.EmailRate, cfg.LoginRateLimit.EmailBurst)
+		if err != nil {
+			return fmt.Errorf("constructing email login rate limit: %w", err)
+		}
+		defer emailBucket.Close()
+
+		ipBucket, err := newBucket("login:", cfg.LoginRateLimit.IPRate, cfg.LoginRateLimit.IPBurst)
+		if err != nil {
+			return fmt.Errorf("constructing address login rate limit: %w", err)
+		}
+		defer ipBucket.Close()
+
+		plugins = append(plugins, userratelimit.NewPlugin(log, userratelimit.Config{
+			Email: emailBucket,
+			IP:    ipBucket,
+		}))
+
+	default:
+		return fmt.Errorf("unknown login rate limit backend %q", cfg.LoginRateLimit.Backend)
+	}
+
+	var userCache cache.Cache
+
+	switch cfg.Cache.Backend {
+	case "", "memory":
+		userCache = cache.NewMemory(cfg.Cache.Capacity)
+
+	case "redis":
+		rds, err := cache.NewRedis(cache.RedisConfig{
+			Addr:     cfg.Cache.RedisAddr,
+			Password: cfg.Cache.RedisPassword,
+			DB:       cfg.Cache.RedisDB,
+			Timeout:  cfg.Cache.RedisTimeout,
+		})
+		if err != nil {
+			return fmt.Errorf("constructing redis cache: %w", err)
+		}
+		defer rds.Close()
+
+		userCache = rds
+
+	default:
+		return fmt.Errorf("unknown cache backend %q", cfg.Cache.Backend)
+	}
+
+	notificationSenders := make(map[notificationbus.Channel]notificationbus.Sender)
+
+	if cfg.Mail.Transport != "" && cfg.Mail.Transport != "none" {
+		from, err := mail.ParseAddress(cfg.Mail.From)
+		if err != nil {
+			return fmt.Errorf("parsing mail from address: %w", err)
+		}
+
+		var mailer notificationbus.Sender
+
+		switch cfg.Mail.Transport {
+		case "console":
+			mailer = consolemailer.New(os.Stdout, *from)
+
+		case "smtp":
+			mailer, err = smtpmailer.New(smtpmailer.Config{
+				Host:     cfg.Mail.SMTPHost,
+				Port:     cfg.Mail.SMTPPort,
+				Username: cfg.Mail.SMTPUsername,
+				Password: cfg.Mail.SMTPPassword,
+				From:     *from,
+			})
+
+		case "ses":
+			mailer, err = sesmailer.New(&http.Client{Timeout: cfg.Mail.Timeout}, sesmailer.Config{
+				Region:          cfg.Mail.SESRegion,
+				AccessKeyID:     cfg.Mail.SESAccessKeyID,
+				SecretAccessKey: cfg.Mail.SESSecretKey,
+				From:            *from,
+			})
+
+		case "sendgrid":
+			mailer, err = sendgridmailer.New(&http.Client{Timeout: cfg.Mail.Timeout}, sendgridmailer.Config{
+				APIKey: cfg.Mail.SendGridAPIKey,
+				From:   *from,
+			})
+
+		default:
+			return fmt.Errorf("unknown mail transport %q", cfg.Mail.Transport)
+		}
+
+		if err != nil {
+			return fmt.Errorf("constructing %s mailer: %w", cfg.Mail.Transport, err)
+		}
+
+		notificationSenders[notificationbus.ChannelEmail] = mailer
+
+		log.Info(ctx, "startup", "status", "emailing notifications", "transport", cfg.Mail.Transport)
+	}
+
 	delegate := delegate.New(log)
-	userBus := userbus.NewBusiness(log, delegate, usercache.NewStore(log, userdb.NewStore(log, db), time.Minute))
+	userBus := userbus.NewBusiness(log, delegate, usercache.NewStore(log, userdb.NewStore(log, db), userCache, cfg.Cache.TTL), passwordPolicy, hasher, nil, plugins...)
+	templateBus := templatebus.NewBusiness(log, templatedb.NewStore(log, db))
+
+	// The auth service only tells the admins about break-glass access, the
+	// user notifications are sent by the sales service, so the notification
+	// business isn't given the delegate.
+	notificationBus := notificationbus.NewBusiness(log, userBus, templateBus, nil, notificationdb.NewStore(log, db), notificationSenders)
+	apiKeyBus := apikeybus.NewBusiness(log, userBus, apikeydb.NewStore(log, db))
+	sessionBus := sessionbus.NewBusiness(log, userBus, delegate, sessiondb.NewStore(log, db), cfg.Sessions.RefreshTTL)
 
 	// -------------------------------------------------------------------------
 	// Initialize authentication support
 
+	report.Phase(ctx, "keystore")
+
 	log.Info(ctx, "startup", "status", "initializing authentication support")
 
 	// Check the enviornment first to see if a key is being provided. Then
@@ -180,11 +499,30 @@ func run(ctx context.Context, log *logger.Logger) error {
 		return errors.New("no keys exist")
 	}
 
+	if cfg.Rotation.RetiringKID != "" {
+		deadline, err := time.Parse(time.RFC3339, cfg.Rotation.Deadline)
+		if err != nil {
+			return fmt.Errorf("parsing rotation deadline: %w", err)
+		}
+
+		if err := ks.Rotate(cfg.Rotation.RetiringKID, cfg.Auth.ActiveKID, deadline); err != nil {
+			return fmt.Errorf("rotating key: %w", err)
+		}
+
+		log.Info(ctx, "startup", "status", "key rotation started", "kid", cfg.Rotation.RetiringKID, "successor", cfg.Auth.ActiveKID, "deadline", deadline)
+	}
+
 	authCfg := auth.Config{
 		Log:       log,
 		UserBus:   userBus,
 		KeyLookup: ks,
 		Issuer:    cfg.Auth.Issuer,
+		TokenTTL:  cfg.Auth.TokenTTL,
+		BreakGlass: auth.BreakGlassConfig{
+			CredentialHash: cfg.BreakGlass.CredentialHash,
+			TTL:            cfg.BreakGlass.TTL,
+			Notify:         auth.NotifyAdmins(log, notificationBus),
+		},
 	}
 
 	ath, err := auth.New(authCfg)
@@ -192,9 +530,30 @@ func run(ctx context.Context, log *logger.Logger) error {
 		return fmt.Errorf("constructing auth: %w", err)
 	}
 
+	// -------------------------------------------------------------------------
+	// Initialize login throttling
+
+	var loginThrottle mid.LoginThrottle
+
+	if cfg.LoginThrottle.AccountBase > 0 {
+		loginThrottle.Account, err = limiter.NewBackoff(cfg.LoginThrottle.AccountBase, cfg.LoginThrottle.AccountMax, cfg.LoginThrottle.Forget)
+		if err != nil {
+			return fmt.Errorf("constructing account login throttle: %w", err)
+		}
+	}
+
+	if cfg.LoginThrottle.AddrBase > 0 {
+		loginThrottle.Addr, err = limiter.NewBackoff(cfg.LoginThrottle.AddrBase, cfg.LoginThrottle.AddrMax, cfg.LoginThrottle.Forget)
+		if err != nil {
+			return fmt.Errorf("constructing address login throttle: %w", err)
+		}
+	}
+
 	// -------------------------------------------------------------------------
 	// Start Tracing Support
 
+	report.Phase(ctx, "tracing")
+
 	log.Info(ctx, "startup", "status", "initializing tracing support")
 
 	traceProvider, teardown, err := otel.InitTracing(log, otel.Config{
@@ -217,6 +576,8 @@ func run(ctx context.Context, log *logger.Logger) error {
 	// -------------------------------------------------------------------------
 	// Start Debug Service
 
+	report.Phase(ctx, "servers")
+
 	go func() {
 		log.Info(ctx, "startup", "status", "debug v1 router started", "host", cfg.Web.DebugHost)
 
@@ -225,6 +586,14 @@ func run(ctx context.Context, log *logger.Logger) error {
 		}
 	}()
 
+	// -------------------------------------------------------------------------
+	// Start Key Rotation Watcher
+
+	rotCtx, rotCancel := context.WithCancel(ctx)
+	defer rotCancel()
+
+	go watchRotations(rotCtx, log, ks, cfg.Rotation.Interval)
+
 	// -------------------------------------------------------------------------
 	// Start API Service
 
@@ -239,10 +608,21 @@ func run(ctx context.Context, log *logger.Logger) error {
 		DB:     db,
 		Tracer: tracer,
 		BusConfig: mux.BusConfig{
-			UserBus: userBus,
+			UserBus:    userBus,
+			APIKeyBus:  apiKeyBus,
+			SessionBus: sessionBus,
 		},
 		AuthConfig: mux.AuthConfig{
-			Auth: ath,
+			Auth:          ath,
+			LoginThrottle: loginThrottle,
+			AccessTTL:     cfg.Sessions.AccessTTL,
+			WellKnown: mux.WellKnownConfig{
+				BaseURL:           cfg.WellKnown.BaseURL,
+				Domain:            cfg.WellKnown.Domain,
+				Issuer:            cfg.Auth.Issuer,
+				TokenKID:          cfg.Auth.ActiveKID,
+				ChangePasswordURL: cfg.WellKnown.ChangePasswordURL,
+			},
 		},
 	}
 
@@ -255,14 +635,25 @@ func run(ctx context.Context, log *logger.Logger) error {
 		ErrorLog:     logger.NewStdLogger(log, logger.LevelError),
 	}
 
+	// The listener is opened here so the time it takes to bind is part of
+	// the startup report.
+	ln, err := net.Listen("tcp", api.Addr)
+	if err != nil {
+		return fmt.Errorf("listening on api host: %w", err)
+	}
+
 	serverErrors := make(chan error, 1)
 
 	go func() {
 		log.Info(ctx, "startup", "status", "api router started", "host", api.Addr)
 
-		serverErrors <- api.ListenAndServe()
+		serverErrors <- api.Serve(ln)
 	}()
 
+	if err := report.Finish(ctx); err != nil {
+		return fmt.Errorf("startup: %w", err)
+	}
+
 	// -------------------------------------------------------------------------
 	// Shutdown
 
@@ -285,3 +676,27 @@ func run(ctx context.Context, log *logger.Logger) error {
 
 	return nil
 }
+
+// watchRotations reports the usage of keys being retired so operators can
+// see which callers still hold old tokens, and revokes each key once its
+// deadline has passed.
+func watchRotations(ctx context.Context, log *logger.Logger, ks *keystore.KeyStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			for _, rot := range ks.RevokeExpired() {
+				log.Warn(ctx, "key rotation", "status", "retired key revoked", "kid", rot.KID, "successor", rot.SuccessorKID, "uses", rot.Uses, "lastUsed", rot.LastUsed)
+			}
+
+			for _, rot := range ks.Rotations() {
+				log.Info(ctx, "key rotation", "status", "key retiring", "kid", rot.KID, "successor", rot.SuccessorKID, "deadline", rot.Deadline, "uses", rot.Uses, "lastUsed", rot.LastUsed)
+			}
+		}
+	}
+}
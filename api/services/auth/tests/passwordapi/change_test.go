@@ -0,0 +1,189 @@
+package password_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/ardanlabs/service/app/sdk/apitest"
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/extid"
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/dbtest"
+	"github.com/google/go-cmp/cmp"
+)
+
+type changePassword struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+	RefreshToken    string `json:"refreshToken,omitempty"`
+}
+
+type sessionToken struct {
+	SessionID    string `json:"sessionID"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+func policyError(rules ...string) *errs.Error {
+	var fe errs.FieldErrors
+	for _, rule := range rules {
+		fe = append(fe, errs.FieldError{Field: "password", Err: rule})
+	}
+
+	return fe.ToError()
+}
+
+func change200(busDomain dbtest.BusDomain, sd seedData) []apitest.Table {
+	table := []apitest.Table{
+		{
+			Name:       "keep-session",
+			URL:        "/v1/auth/password/" + apitest.KID,
+			Token:      sd.User.Token,
+			Method:     http.MethodPost,
+			StatusCode: http.StatusOK,
+			Input: &changePassword{
+				CurrentPassword: password,
+				NewPassword:     "Gophers2025",
+				RefreshToken:    sd.Sessions[0].RefreshToken,
+			},
+			GotResp: &sessionToken{},
+			ExpResp: &sessionToken{
+				SessionID: extid.Encode(sd.Sessions[0].ID),
+			},
+			CmpFunc: func(got any, exp any) string {
+				gotResp, exists := got.(*sessionToken)
+				if !exists {
+					return "error occurred"
+				}
+
+				if gotResp.Token == "" || gotResp.RefreshToken == "" || gotResp.RefreshToken == sd.Sessions[0].RefreshToken {
+					return fmt.Sprintf("should get a new token and refresh token: %+v", gotResp)
+				}
+
+				ctx := context.Background()
+
+				kept, err := busDomain.Session.QueryByID(ctx, sd.Sessions[0].ID)
+				if err != nil {
+					return err.Error()
+				}
+
+				if kept.Revoked() {
+					return "should keep the caller's session"
+				}
+
+				other, err := busDomain.Session.QueryByID(ctx, sd.Sessions[1].ID)
+				if err != nil {
+					return err.Error()
+				}
+
+				if !other.Revoked() {
+					return "should revoke the other sessions"
+				}
+
+				expResp := exp.(*sessionToken)
+				expResp.Token = gotResp.Token
+				expResp.RefreshToken = gotResp.RefreshToken
+
+				return cmp.Diff(gotResp, expResp)
+			},
+		},
+	}
+
+	return table
+}
+
+func change400(sd seedData) []apitest.Table {
+	table := []apitest.Table{
+		{
+			Name:       "weak",
+			URL:        "/v1/auth/password/" + apitest.KID,
+			Token:      sd.User.Token,
+			Method:     http.MethodPost,
+			StatusCode: http.StatusBadRequest,
+			Input: &changePassword{
+				CurrentPassword: password,
+				NewPassword:     "gophers",
+			},
+			GotResp: &errs.Error{},
+			ExpResp: policyError(userbus.RuleMinLength, userbus.RuleUpper, userbus.RuleDigit),
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+		{
+			Name:       "banned",
+			URL:        "/v1/auth/password/" + apitest.KID,
+			Token:      sd.User.Token,
+			Method:     http.MethodPost,
+			StatusCode: http.StatusBadRequest,
+			Input: &changePassword{
+				CurrentPassword: password,
+				NewPassword:     "Password",
+			},
+			GotResp: &errs.Error{},
+			ExpResp: policyError(userbus.RuleDigit, userbus.RuleBanned),
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+		{
+			Name:       "reused",
+			URL:        "/v1/auth/password/" + apitest.KID,
+			Token:      sd.User.Token,
+			Method:     http.MethodPost,
+			StatusCode: http.StatusBadRequest,
+			Input: &changePassword{
+				CurrentPassword: password,
+				NewPassword:     password,
+			},
+			GotResp: &errs.Error{},
+			ExpResp: policyError(userbus.RuleReused),
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+	}
+
+	return table
+}
+
+func change401(sd seedData) []apitest.Table {
+	table := []apitest.Table{
+		{
+			Name:       "wrong-password",
+			URL:        "/v1/auth/password/" + apitest.KID,
+			Token:      sd.User.Token,
+			Method:     http.MethodPost,
+			StatusCode: http.StatusUnauthorized,
+			Input: &changePassword{
+				CurrentPassword: "Wrong2024",
+				NewPassword:     "Gophers2025",
+			},
+			GotResp: &errs.Error{},
+			ExpResp: errs.New(errs.Unauthenticated, userbus.ErrAuthenticationFailure),
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+		{
+			Name:       "unknown-session",
+			URL:        "/v1/auth/password/" + apitest.KID,
+			Token:      sd.User.Token,
+			Method:     http.MethodPost,
+			StatusCode: http.StatusUnauthorized,
+			Input: &changePassword{
+				CurrentPassword: password,
+				NewPassword:     "Gophers2025",
+				RefreshToken:    "rt_unknown",
+			},
+			GotResp: &errs.Error{},
+			ExpResp: errs.Newf(errs.Unauthenticated, "invalid refresh token"),
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+	}
+
+	return table
+}
@@ -0,0 +1,26 @@
+package password_test
+
+import (
+	"testing"
+
+	"github.com/ardanlabs/service/app/sdk/apitest"
+)
+
+func Test_Password(t *testing.T) {
+	t.Parallel()
+
+	test := apitest.New(t, "Test_Password")
+
+	// -------------------------------------------------------------------------
+
+	sd, err := insertSeedData(test.DB, test.Auth)
+	if err != nil {
+		t.Fatalf("Seeding error: %s", err)
+	}
+
+	// -------------------------------------------------------------------------
+
+	test.RunAuth(t, change400(sd), "change-400")
+	test.RunAuth(t, change401(sd), "change-401")
+	test.RunAuth(t, change200(test.DB.BusDomain, sd), "change-200")
+}
@@ -0,0 +1,72 @@
+package password_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ardanlabs/service/app/sdk/apitest"
+	"github.com/ardanlabs/service/app/sdk/auth"
+	"github.com/ardanlabs/service/business/domain/sessionbus"
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/dbtest"
+	"github.com/ardanlabs/service/business/types/role"
+)
+
+// password is the password of the seeded user, which satisfies the policy.
+const password = "Gophers2024"
+
+// session represents a session of the seeded user along with its refresh
+// token.
+type session struct {
+	sessionbus.Session
+	RefreshToken string
+}
+
+type seedData struct {
+	User     apitest.User
+	Sessions []session
+}
+
+func insertSeedData(db *dbtest.Database, ath *auth.Auth) (seedData, error) {
+	ctx := context.Background()
+	busDomain := db.BusDomain
+
+	nu := userbus.TestNewUsers(1, role.User)[0]
+	nu.Password = password
+
+	usr, _, err := busDomain.User.Create(ctx, userbus.ActorTooling, nu)
+	if err != nil {
+		return seedData{}, fmt.Errorf("seeding user : %w", err)
+	}
+
+	tu := apitest.User{
+		User:  usr,
+		Token: apitest.Token(busDomain.User, ath, usr.Email.Address),
+	}
+
+	// -------------------------------------------------------------------------
+
+	var sessions []session
+	for _, device := range []string{"phone", "laptop"} {
+		ns := sessionbus.NewSession{
+			UserID: usr.ID,
+			Device: device,
+		}
+
+		sess, token, err := busDomain.Session.Create(ctx, ns)
+		if err != nil {
+			return seedData{}, fmt.Errorf("seeding session : %w", err)
+		}
+
+		sessions = append(sessions, session{Session: sess, RefreshToken: token})
+	}
+
+	// -------------------------------------------------------------------------
+
+	sd := seedData{
+		User:     tu,
+		Sessions: sessions,
+	}
+
+	return sd, nil
+}
@@ -2,15 +2,35 @@
 package all
 
 import (
+	"context"
+
+	"github.com/ardanlabs/service/app/domain/apikeyapp"
 	"github.com/ardanlabs/service/app/domain/auditapp"
 	"github.com/ardanlabs/service/app/domain/checkapp"
+	"github.com/ardanlabs/service/app/domain/clockapp"
+	"github.com/ardanlabs/service/app/domain/deadletterapp"
+	"github.com/ardanlabs/service/app/domain/graphqlapp"
+	"github.com/ardanlabs/service/app/domain/graphqlapp/graph"
+	"github.com/ardanlabs/service/app/domain/groupapp"
 	"github.com/ardanlabs/service/app/domain/homeapp"
+	"github.com/ardanlabs/service/app/domain/inviteapp"
+	"github.com/ardanlabs/service/app/domain/limitapp"
+	"github.com/ardanlabs/service/app/domain/notificationapp"
+	"github.com/ardanlabs/service/app/domain/openapiapp"
+	"github.com/ardanlabs/service/app/domain/orderapp"
+	"github.com/ardanlabs/service/app/domain/permissionapp"
 	"github.com/ardanlabs/service/app/domain/productapp"
 	"github.com/ardanlabs/service/app/domain/rawapp"
+	"github.com/ardanlabs/service/app/domain/reportapp"
+	"github.com/ardanlabs/service/app/domain/searchapp"
+	"github.com/ardanlabs/service/app/domain/streamapp"
+	"github.com/ardanlabs/service/app/domain/templateapp"
 	"github.com/ardanlabs/service/app/domain/tranapp"
 	"github.com/ardanlabs/service/app/domain/userapp"
 	"github.com/ardanlabs/service/app/domain/vproductapp"
+	"github.com/ardanlabs/service/app/domain/webhookapp"
 	"github.com/ardanlabs/service/app/sdk/mux"
+	"github.com/ardanlabs/service/app/sdk/openapi"
 	"github.com/ardanlabs/service/foundation/web"
 )
 
@@ -24,18 +44,66 @@ type add struct{}
 
 // Add implements the RouterAdder interface.
 func (add) Add(app *web.App, cfg mux.Config) {
+	apikeyapp.Routes(app, apikeyapp.Config{
+		Log:        cfg.Log,
+		APIKeyBus:  cfg.BusConfig.APIKeyBus,
+		AuthClient: cfg.SalesConfig.AuthClient,
+	})
+
 	checkapp.Routes(app, checkapp.Config{
 		Build: cfg.Build,
 		Log:   cfg.Log,
 		DB:    cfg.DB,
 	})
 
+	clockapp.Routes(app, clockapp.Config{
+		Log:        cfg.Log,
+		AuthClient: cfg.SalesConfig.AuthClient,
+		Enabled:    cfg.SalesConfig.ClockSkew,
+	})
+
+	groupapp.Routes(app, groupapp.Config{
+		Log:        cfg.Log,
+		GroupBus:   cfg.BusConfig.GroupBus,
+		AuthClient: cfg.SalesConfig.AuthClient,
+	})
+
 	homeapp.Routes(app, homeapp.Config{
 		Log:        cfg.Log,
 		HomeBus:    cfg.BusConfig.HomeBus,
 		AuthClient: cfg.SalesConfig.AuthClient,
 	})
 
+	inviteapp.Routes(app, inviteapp.Config{
+		Log:        cfg.Log,
+		DB:         cfg.DB,
+		InviteBus:  cfg.BusConfig.InviteBus,
+		AuthClient: cfg.SalesConfig.AuthClient,
+	})
+
+	limitapp.Routes(app, limitapp.Config{
+		Log:        cfg.Log,
+		Limiter:    cfg.SalesConfig.Limiter,
+		AuthClient: cfg.SalesConfig.AuthClient,
+	})
+
+	notificationapp.Routes(app, notificationapp.Config{
+		Log:             cfg.Log,
+		NotificationBus: cfg.BusConfig.NotificationBus,
+		AuthClient:      cfg.SalesConfig.AuthClient,
+	})
+
+	orderapp.Routes(app, orderapp.Config{
+		Log:        cfg.Log,
+		AuthClient: cfg.SalesConfig.AuthClient,
+	})
+
+	permissionapp.Routes(app, permissionapp.Config{
+		Log:           cfg.Log,
+		PermissionBus: cfg.BusConfig.PermissionBus,
+		AuthClient:    cfg.SalesConfig.AuthClient,
+	})
+
 	productapp.Routes(app, productapp.Config{
 		Log:        cfg.Log,
 		ProductBus: cfg.BusConfig.ProductBus,
@@ -44,6 +112,30 @@ func (add) Add(app *web.App, cfg mux.Config) {
 
 	rawapp.Routes(app)
 
+	reportapp.Routes(app, reportapp.Config{
+		Log:        cfg.Log,
+		ReportBus:  cfg.BusConfig.ReportBus,
+		AuthClient: cfg.SalesConfig.AuthClient,
+	})
+
+	searchapp.Routes(app, searchapp.Config{
+		Log:        cfg.Log,
+		SearchBus:  cfg.BusConfig.SearchBus,
+		AuthClient: cfg.SalesConfig.AuthClient,
+	})
+
+	templateapp.Routes(app, templateapp.Config{
+		Log:         cfg.Log,
+		TemplateBus: cfg.BusConfig.TemplateBus,
+		AuthClient:  cfg.SalesConfig.AuthClient,
+	})
+
+	webhookapp.Routes(app, webhookapp.Config{
+		Log:        cfg.Log,
+		WebhookBus: cfg.BusConfig.WebhookBus,
+		AuthClient: cfg.SalesConfig.AuthClient,
+	})
+
 	tranapp.Routes(app, tranapp.Config{
 		Log:        cfg.Log,
 		DB:         cfg.DB,
@@ -52,18 +144,54 @@ func (add) Add(app *web.App, cfg mux.Config) {
 		AuthClient: cfg.SalesConfig.AuthClient,
 	})
 
-	userapp.Routes(app, userapp.Config{
+	userCfg := userapp.Config{
 		Log:        cfg.Log,
 		UserBus:    cfg.BusConfig.UserBus,
 		AuthClient: cfg.SalesConfig.AuthClient,
+		DB:         cfg.DB,
+	}
+
+	userapp.Routes(app, userCfg)
+
+	userGraphQL := userapp.NewGraphQL(userCfg)
+
+	graphqlapp.Routes(app, graphqlapp.Config{
+		Log: cfg.Log,
+		Schema: graph.NewExecutableSchema(graph.Config{
+			Resolvers: &graph.Resolver{Users: userGraphQL},
+		}),
+		AuthClient: cfg.SalesConfig.AuthClient,
+		OnRequest:  []func(ctx context.Context) context.Context{userGraphQL.WithLoader},
+	})
+
+	doc := openapi.New("sales", cfg.Build)
+	userapp.RegisterOpenAPI(doc)
+
+	openapiapp.Routes(app, openapiapp.Config{
+		Doc: doc,
 	})
 
+	if cfg.SalesConfig.Events != nil {
+		streamapp.Routes(app, streamapp.Config{
+			Log:        cfg.Log,
+			Hub:        cfg.SalesConfig.Events,
+			AuthClient: cfg.SalesConfig.AuthClient,
+			Heartbeat:  cfg.SalesConfig.EventsHeartbeat,
+		})
+	}
+
 	auditapp.Routes(app, auditapp.Config{
 		Log:        cfg.Log,
 		AuditBus:   cfg.BusConfig.AuditBus,
 		AuthClient: cfg.SalesConfig.AuthClient,
 	})
 
+	deadletterapp.Routes(app, deadletterapp.Config{
+		Log:        cfg.Log,
+		Delegate:   cfg.SalesConfig.Delegate,
+		AuthClient: cfg.SalesConfig.AuthClient,
+	})
+
 	vproductapp.Routes(app, vproductapp.Config{
 		Log:         cfg.Log,
 		UserBus:     cfg.BusConfig.UserBus,
@@ -5,10 +5,14 @@ import (
 	"github.com/ardanlabs/service/app/domain/auditapp"
 	"github.com/ardanlabs/service/app/domain/checkapp"
 	"github.com/ardanlabs/service/app/domain/homeapp"
+	"github.com/ardanlabs/service/app/domain/limitapp"
+	"github.com/ardanlabs/service/app/domain/openapiapp"
+	"github.com/ardanlabs/service/app/domain/orderapp"
 	"github.com/ardanlabs/service/app/domain/productapp"
 	"github.com/ardanlabs/service/app/domain/tranapp"
 	"github.com/ardanlabs/service/app/domain/userapp"
 	"github.com/ardanlabs/service/app/sdk/mux"
+	"github.com/ardanlabs/service/app/sdk/openapi"
 	"github.com/ardanlabs/service/foundation/web"
 )
 
@@ -33,6 +37,17 @@ func (add) Add(app *web.App, cfg mux.Config) {
 		AuthClient: cfg.SalesConfig.AuthClient,
 	})
 
+	limitapp.Routes(app, limitapp.Config{
+		Log:        cfg.Log,
+		Limiter:    cfg.SalesConfig.Limiter,
+		AuthClient: cfg.SalesConfig.AuthClient,
+	})
+
+	orderapp.Routes(app, orderapp.Config{
+		Log:        cfg.Log,
+		AuthClient: cfg.SalesConfig.AuthClient,
+	})
+
 	productapp.Routes(app, productapp.Config{
 		ProductBus: cfg.BusConfig.ProductBus,
 		AuthClient: cfg.SalesConfig.AuthClient,
@@ -49,6 +64,14 @@ func (add) Add(app *web.App, cfg mux.Config) {
 	userapp.Routes(app, userapp.Config{
 		UserBus:    cfg.BusConfig.UserBus,
 		AuthClient: cfg.SalesConfig.AuthClient,
+		DB:         cfg.DB,
+	})
+
+	doc := openapi.New("sales", cfg.Build)
+	userapp.RegisterOpenAPI(doc)
+
+	openapiapp.Routes(app, openapiapp.Config{
+		Doc: doc,
 	})
 
 	auditapp.Routes(app, auditapp.Config{
@@ -3,6 +3,9 @@ package reporting
 
 import (
 	"github.com/ardanlabs/service/app/domain/checkapp"
+	"github.com/ardanlabs/service/app/domain/limitapp"
+	"github.com/ardanlabs/service/app/domain/orderapp"
+	"github.com/ardanlabs/service/app/domain/reportapp"
 	"github.com/ardanlabs/service/app/domain/vproductapp"
 	"github.com/ardanlabs/service/app/sdk/mux"
 	"github.com/ardanlabs/service/foundation/web"
@@ -29,4 +32,21 @@ func (add) Add(app *web.App, cfg mux.Config) {
 		VProductBus: cfg.BusConfig.VProductBus,
 		AuthClient:  cfg.SalesConfig.AuthClient,
 	})
+
+	limitapp.Routes(app, limitapp.Config{
+		Log:        cfg.Log,
+		Limiter:    cfg.SalesConfig.Limiter,
+		AuthClient: cfg.SalesConfig.AuthClient,
+	})
+
+	orderapp.Routes(app, orderapp.Config{
+		Log:        cfg.Log,
+		AuthClient: cfg.SalesConfig.AuthClient,
+	})
+
+	reportapp.Routes(app, reportapp.Config{
+		Log:        cfg.Log,
+		ReportBus:  cfg.BusConfig.ReportBus,
+		AuthClient: cfg.SalesConfig.AuthClient,
+	})
 }
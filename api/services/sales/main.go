@@ -3,13 +3,17 @@ package main
 import (
 	"context"
 	"embed"
+	"encoding/hex"
 	"errors"
 	"expvar"
 	"fmt"
+	"net"
 	"net/http"
+	"net/mail"
 	"os"
 	"os/signal"
 	"runtime"
+	"strings"
 	"syscall"
 	"time"
 
@@ -17,25 +21,73 @@ import (
 	"github.com/ardanlabs/service/api/services/sales/build/all"
 	"github.com/ardanlabs/service/api/services/sales/build/crud"
 	"github.com/ardanlabs/service/api/services/sales/build/reporting"
+	"github.com/ardanlabs/service/app/domain/userapp"
+	"github.com/ardanlabs/service/app/sdk/anomaly"
 	"github.com/ardanlabs/service/app/sdk/authclient"
 	"github.com/ardanlabs/service/app/sdk/debug"
+	"github.com/ardanlabs/service/app/sdk/extid"
 	"github.com/ardanlabs/service/app/sdk/mux"
+	"github.com/ardanlabs/service/app/sdk/rpc"
+	"github.com/ardanlabs/service/app/sdk/stream"
+	"github.com/ardanlabs/service/business/domain/apikeybus"
+	"github.com/ardanlabs/service/business/domain/apikeybus/stores/apikeydb"
 	"github.com/ardanlabs/service/business/domain/auditbus"
 	"github.com/ardanlabs/service/business/domain/auditbus/stores/auditdb"
+	"github.com/ardanlabs/service/business/domain/groupbus"
+	"github.com/ardanlabs/service/business/domain/groupbus/stores/groupdb"
 	"github.com/ardanlabs/service/business/domain/homebus"
 	"github.com/ardanlabs/service/business/domain/homebus/stores/homedb"
+	"github.com/ardanlabs/service/business/domain/invitebus"
+	"github.com/ardanlabs/service/business/domain/invitebus/stores/invitedb"
+	"github.com/ardanlabs/service/business/domain/notificationbus"
+	"github.com/ardanlabs/service/business/domain/notificationbus/stores/notificationdb"
+	"github.com/ardanlabs/service/business/domain/permissionbus"
+	"github.com/ardanlabs/service/business/domain/permissionbus/stores/permissiondb"
 	"github.com/ardanlabs/service/business/domain/productbus"
 	"github.com/ardanlabs/service/business/domain/productbus/stores/productdb"
+	"github.com/ardanlabs/service/business/domain/reportbus"
+	"github.com/ardanlabs/service/business/domain/reportbus/stores/reportdb"
+	"github.com/ardanlabs/service/business/domain/searchbus"
+	"github.com/ardanlabs/service/business/domain/searchbus/stores/searchdb"
+	"github.com/ardanlabs/service/business/domain/templatebus"
+	"github.com/ardanlabs/service/business/domain/templatebus/stores/templatedb"
 	"github.com/ardanlabs/service/business/domain/userbus"
 	"github.com/ardanlabs/service/business/domain/userbus/plugins/useraudit"
+	"github.com/ardanlabs/service/business/domain/userbus/plugins/userauthz"
+	"github.com/ardanlabs/service/business/domain/userbus/plugins/usermetrics"
 	"github.com/ardanlabs/service/business/domain/userbus/stores/usercache"
 	"github.com/ardanlabs/service/business/domain/userbus/stores/userdb"
 	"github.com/ardanlabs/service/business/domain/vproductbus"
 	"github.com/ardanlabs/service/business/domain/vproductbus/stores/vproductdb"
+	"github.com/ardanlabs/service/business/domain/webhookbus"
+	"github.com/ardanlabs/service/business/domain/webhookbus/stores/webhookdb"
+	"github.com/ardanlabs/service/business/sdk/breach"
+	"github.com/ardanlabs/service/business/sdk/cache"
 	"github.com/ardanlabs/service/business/sdk/delegate"
+	"github.com/ardanlabs/service/business/sdk/delegate/publishers/kafkapub"
+	"github.com/ardanlabs/service/business/sdk/delegate/publishers/mempub"
+	"github.com/ardanlabs/service/business/sdk/delegate/publishers/natspub"
+	"github.com/ardanlabs/service/business/sdk/delegate/stores/deadletterdb"
+	"github.com/ardanlabs/service/business/sdk/jobs"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/saga"
+	"github.com/ardanlabs/service/business/sdk/saga/stores/sagadb"
 	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/business/types/name"
+	"github.com/ardanlabs/service/foundation/blob/diskblob"
+	"github.com/ardanlabs/service/foundation/blob/gcsblob"
+	"github.com/ardanlabs/service/foundation/blob/s3blob"
+	"github.com/ardanlabs/service/foundation/boot"
+	"github.com/ardanlabs/service/foundation/clock"
+	"github.com/ardanlabs/service/foundation/ctxval"
+	"github.com/ardanlabs/service/foundation/limiter"
 	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/mailer/consolemailer"
+	"github.com/ardanlabs/service/foundation/mailer/sendgridmailer"
+	"github.com/ardanlabs/service/foundation/mailer/sesmailer"
+	"github.com/ardanlabs/service/foundation/mailer/smtpmailer"
 	"github.com/ardanlabs/service/foundation/otel"
+	"google.golang.org/grpc"
 )
 
 /*
@@ -51,7 +103,12 @@ var routes = "all" // go build -ldflags "-X main.routes=crud"
 func main() {
 	var log *logger.Logger
 
+	// The detector counts the failed requests from the logs so it needs to
+	// exist before the logger does.
+	detector := anomaly.New()
+
 	events := logger.Events{
+		Info: detector.Observe,
 		Error: func(ctx context.Context, r logger.Record) {
 			log.Info(ctx, "******* SEND ALERT *******")
 		},
@@ -67,13 +124,13 @@ func main() {
 
 	ctx := context.Background()
 
-	if err := run(ctx, log); err != nil {
+	if err := run(ctx, log, detector); err != nil {
 		log.Error(ctx, "startup", "err", err)
 		os.Exit(1)
 	}
 }
 
-func run(ctx context.Context, log *logger.Logger) error {
+func run(ctx context.Context, log *logger.Logger, detector *anomaly.Detector) error {
 
 	// -------------------------------------------------------------------------
 	// GOMAXPROCS
@@ -83,8 +140,14 @@ func run(ctx context.Context, log *logger.Logger) error {
 	// -------------------------------------------------------------------------
 	// Configuration
 
+	report := boot.New(log)
+	report.Phase(ctx, "config")
+
 	cfg := struct {
 		conf.Version
+		DevMode       bool          `conf:"default:false,help:panic when a required context value is missing"`
+		StartupBudget time.Duration `conf:"default:30s,help:fail startup when it takes longer, zero disables the check"`
+
 		Web struct {
 			ReadTimeout        time.Duration `conf:"default:5s"`
 			WriteTimeout       time.Duration `conf:"default:10s"`
@@ -93,10 +156,20 @@ func run(ctx context.Context, log *logger.Logger) error {
 			APIHost            string        `conf:"default:0.0.0.0:3000"`
 			DebugHost          string        `conf:"default:0.0.0.0:3010"`
 			CORSAllowedOrigins []string      `conf:"default:*"`
+			ReadOnly           bool          `conf:"default:false"`
+		}
+		GRPC struct {
+			APIHost string `conf:"help:address the grpc api listens on, empty disables it"`
 		}
 		Auth struct {
 			Host string `conf:"default:http://auth-service:6000"`
 		}
+		Staging struct {
+			ClockSkew bool `conf:"default:false,help:bind the admin endpoint that skews the service clock, only honored by builds with the staging tag"`
+		}
+		IDs struct {
+			Key string `conf:"mask,help:hex encoded AES key used to encrypt the ids exposed by the api, ids are exposed as is when empty"`
+		}
 		DB struct {
 			User         string `conf:"default:postgres"`
 			Password     string `conf:"default:postgres,mask"`
@@ -105,6 +178,32 @@ func run(ctx context.Context, log *logger.Logger) error {
 			MaxIdleConns int    `conf:"default:0"`
 			MaxOpenConns int    `conf:"default:0"`
 			DisableTLS   bool   `conf:"default:true"`
+			// Connections are closed once they have been open or idle for
+			// that long so the pool follows failovers and scale downs.
+			// Zero keeps them.
+			ConnMaxLifetime time.Duration `conf:"default:0s"`
+			ConnMaxIdleTime time.Duration `conf:"default:0s"`
+			// CostBudget rejects user list queries the planner estimates
+			// will cost more, protecting the database from pathological
+			// filters. Zero disables the check.
+			CostBudget float64 `conf:"default:0"`
+			// ReplicaHosts are read replicas of the database. Reads are
+			// spread across them and writes go to the primary.
+			ReplicaHosts []string
+			// Calls that fail for a transient reason are retried, and the
+			// breaker fails calls fast once that many fail in a row.
+			RetryAttempts    int           `conf:"default:3"`
+			RetryBase        time.Duration `conf:"default:50ms"`
+			RetryMax         time.Duration `conf:"default:1s"`
+			BreakerThreshold int           `conf:"default:5,help:transient failures in a row that open the breaker, zero disables it"`
+			BreakerCooldown  time.Duration `conf:"default:10s"`
+			// StmtCacheSize is how many prepared statements are kept for
+			// each database so hot queries aren't parsed on every call.
+			StmtCacheSize int `conf:"default:0,help:prepared statements cached per database, zero disables the cache"`
+			// StatementTimeout is the longest the database lets a single
+			// statement run. A call can ask for less with
+			// sqldb.WithStatementTimeout.
+			StatementTimeout time.Duration `conf:"default:0s,help:longest a statement may run, zero leaves statements unbounded"`
 		}
 		Tempo struct {
 			Host        string  `conf:"default:tempo:4317"`
@@ -114,6 +213,124 @@ func run(ctx context.Context, log *logger.Logger) error {
 			// 0.05 should be enough for most systems. Some might want to have
 			// this even lower.
 		}
+		PasswordPolicy struct {
+			MinLength     int      `conf:"default:8"`
+			RequireUpper  bool     `conf:"default:true"`
+			RequireLower  bool     `conf:"default:true"`
+			RequireDigit  bool     `conf:"default:true"`
+			RequireSymbol bool     `conf:"default:false"`
+			Banned        []string `conf:"default:password;12345678;qwerty123"`
+			History       int      `conf:"default:5"`
+			Breach        struct {
+				Enabled  bool          `conf:"default:false"`
+				URL      string        `conf:"default:https://api.pwnedpasswords.com"`
+				Timeout  time.Duration `conf:"default:2s"`
+				CacheTTL time.Duration `conf:"default:1h"`
+				FailOpen bool          `conf:"default:true,help:accept passwords when the breach check can't be performed"`
+			}
+		}
+		Reports struct {
+			Interval       time.Duration `conf:"default:1m"`
+			WebhookTimeout time.Duration `conf:"default:10s"`
+		}
+		Sagas struct {
+			Interval time.Duration `conf:"default:1m"`
+			Stale    time.Duration `conf:"default:5m,help:how long a saga in flight goes without progress before it's resumed"`
+		}
+		Webhooks struct {
+			Interval    time.Duration `conf:"default:10s"`
+			Timeout     time.Duration `conf:"default:10s"`
+			MaxAttempts int           `conf:"default:8"`
+			Backoff     time.Duration `conf:"default:30s"`
+			MaxBackoff  time.Duration `conf:"default:6h"`
+		}
+		Invites struct {
+			WebhookURL     string        `conf:"help:address new invites are posted to for delivery, they are emailed when empty"`
+			WebhookTimeout time.Duration `conf:"default:10s"`
+		}
+		Mail struct {
+			Transport      string `conf:"default:none,help:how notifications are emailed: none, console, smtp, ses or sendgrid"`
+			From           string `conf:"default:Sales <no-reply@example.com>"`
+			SMTPHost       string `conf:"default:localhost"`
+			SMTPPort       int    `conf:"default:587"`
+			SMTPUsername   string
+			SMTPPassword   string `conf:"mask"`
+			SESRegion      string `conf:"default:us-east-1"`
+			SESAccessKeyID string
+			SESSecretKey   string        `conf:"mask"`
+			SendGridAPIKey string        `conf:"mask"`
+			Timeout        time.Duration `conf:"default:10s"`
+		}
+		Names struct {
+			MinLength int `conf:"default:3,help:fewest characters in a name, counted as a reader sees them"`
+			MaxLength int `conf:"default:20,help:most characters in a name, must match across services"`
+		}
+		Cache struct {
+			Backend       string        `conf:"default:memory,help:where cached users are kept: memory or redis, redis shares them between instances"`
+			Capacity      int           `conf:"default:10000,help:most entries the memory cache holds"`
+			TTL           time.Duration `conf:"default:1m"`
+			RedisAddr     string        `conf:"default:redis-service:6379"`
+			RedisPassword string        `conf:"mask"`
+			RedisDB       int           `conf:"default:0"`
+			RedisTimeout  time.Duration `conf:"default:500ms"`
+		}
+		Idempotency struct {
+			PurgeInterval time.Duration `conf:"default:1h,help:how often expired idempotency keys are removed"`
+		}
+		Dormant struct {
+			DisableAfter  time.Duration `conf:"default:0s,help:disable users that haven't logged in for this long, zero turns it off"`
+			CheckInterval time.Duration `conf:"default:24h,help:how often dormant users are looked for"`
+		}
+		APIKeys struct {
+			RevokeInterval time.Duration `conf:"default:5m,help:how often rotated api keys past their overlap are revoked"`
+		}
+		Avatars struct {
+			Store           string `conf:"default:none,help:where avatars are kept: none, disk, s3 or gcs"`
+			Dir             string `conf:"default:avatars,help:directory the disk store writes to"`
+			BaseURL         string `conf:"default:http://localhost:3000/avatars,help:address the disk store's directory is served from"`
+			Bucket          string
+			Region          string `conf:"default:us-east-1"`
+			Endpoint        string `conf:"help:address of an S3 compatible service, empty for Amazon S3"`
+			PathStyle       bool
+			AccessKeyID     string
+			SecretAccessKey string `conf:"mask"`
+			PublicURL       string `conf:"help:address a public bucket or CDN is served from, URLs are signed when empty"`
+		}
+		Delegate struct {
+			Workers     int      `conf:"default:4"`
+			QueueSize   int      `conf:"default:1000"`
+			Publisher   string   `conf:"help:none, memory, nats or kafka"`
+			Hosts       []string `conf:"default:localhost:4222"`
+			Encoding    string   `conf:"default:json,help:json or protobuf"`
+			TopicPrefix string   `conf:"default:sales."`
+			Topics      string   `conf:"help:comma separated list of domain.action:topic pairs"`
+			Fields      []string `conf:"help:only publish changes to these fields"`
+			DeadLetters bool     `conf:"default:true,help:record the calls that fail so they can be replayed"`
+		}
+		RateLimit struct {
+			Requests int           `conf:"default:1000"`
+			Window   time.Duration `conf:"default:1m"`
+		}
+		Metrics struct {
+			UserExpvar string `conf:"default:userbus,help:expvar map the user business metrics are published in, empty disables"`
+		}
+		Events struct {
+			History   int           `conf:"default:1000,help:events kept for clients catching up after reconnecting"`
+			Heartbeat time.Duration `conf:"default:15s"`
+		}
+		Anomaly struct {
+			Window       time.Duration `conf:"default:1m"`
+			AuthFailures int           `conf:"default:20,help:alert on this many auth failures in the window, 0 disables"`
+			Forbidden    int           `conf:"default:50,help:alert on this many denied requests in the window, 0 disables"`
+			AdminCreated bool          `conf:"default:true"`
+		}
+		Hasher struct {
+			Algorithm         string `conf:"default:bcrypt"`
+			BcryptCost        int    `conf:"default:10"`
+			Argon2Memory      uint32 `conf:"default:65536"`
+			Argon2Iterations  uint32 `conf:"default:1"`
+			Argon2Parallelism uint8  `conf:"default:4"`
+		}
 	}{
 		Version: conf.Version{
 			Build: build,
@@ -145,44 +362,357 @@ func run(ctx context.Context, log *logger.Logger) error {
 
 	log.BuildInfo(ctx)
 
+	ctxval.SetDevMode(cfg.DevMode)
+	report.SetBudget(cfg.StartupBudget)
+
+	if err := name.SetLimits(cfg.Names.MinLength, cfg.Names.MaxLength); err != nil {
+		return fmt.Errorf("setting name limits: %w", err)
+	}
+
 	expvar.NewString("build").Set(cfg.Build)
 
 	// -------------------------------------------------------------------------
-	// Database Support
+	// Order Support
 
-	log.Info(ctx, "startup", "status", "initializing database support", "hostport", cfg.DB.Host)
+	if err := order.Validate(); err != nil {
+		return fmt.Errorf("validating order fields: %w", err)
+	}
 
-	db, err := sqldb.Open(sqldb.Config{
-		User:         cfg.DB.User,
-		Password:     cfg.DB.Password,
-		Host:         cfg.DB.Host,
-		Name:         cfg.DB.Name,
-		MaxIdleConns: cfg.DB.MaxIdleConns,
-		MaxOpenConns: cfg.DB.MaxOpenConns,
-		DisableTLS:   cfg.DB.DisableTLS,
-	})
+	// -------------------------------------------------------------------------
+	// External ID Support
+
+	if cfg.IDs.Key != "" {
+		key, err := hex.DecodeString(cfg.IDs.Key)
+		if err != nil {
+			return fmt.Errorf("decoding ids key: %w", err)
+		}
+
+		codec, err := extid.NewAES(key)
+		if err != nil {
+			return fmt.Errorf("constructing ids codec: %w", err)
+		}
+
+		extid.Use(codec)
+	}
+
+	// -------------------------------------------------------------------------
+	// Database Support
+
+	report.Phase(ctx, "db")
+
+	log.Info(ctx, "startup", "status", "initializing database support", "hostport", cfg.DB.Host, "replicas", cfg.DB.ReplicaHosts)
+
+	cluster, err := sqldb.OpenCluster(sqldb.Config{
+		User:             cfg.DB.User,
+		Password:         cfg.DB.Password,
+		Host:             cfg.DB.Host,
+		Name:             cfg.DB.Name,
+		MaxIdleConns:     cfg.DB.MaxIdleConns,
+		MaxOpenConns:     cfg.DB.MaxOpenConns,
+		DisableTLS:       cfg.DB.DisableTLS,
+		ReadOnly:         cfg.Web.ReadOnly,
+		StatementTimeout: cfg.DB.StatementTimeout,
+		ConnMaxLifetime:  cfg.DB.ConnMaxLifetime,
+		ConnMaxIdleTime:  cfg.DB.ConnMaxIdleTime,
+	}, cfg.DB.ReplicaHosts)
 	if err != nil {
 		return fmt.Errorf("connecting to db: %w", err)
 	}
 
-	defer db.Close()
+	defer cluster.Close()
+
+	if cfg.DB.StmtCacheSize > 0 {
+		cluster.CacheStatements(cfg.DB.StmtCacheSize)
+	}
+
+	db := cluster.Primary()
+
+	poolMetrics, err := sqldb.RegisterPoolMetrics(nil, cluster.Pools())
+	if err != nil {
+		return fmt.Errorf("registering pool metrics: %w", err)
+	}
+
+	defer poolMetrics.Unregister()
+
+	storeDB := sqldb.NewResilient(log, cluster,
+		sqldb.WithRetries(cfg.DB.RetryAttempts, cfg.DB.RetryBase, cfg.DB.RetryMax),
+		sqldb.WithBreaker(cfg.DB.BreakerThreshold, cfg.DB.BreakerCooldown),
+	)
 
 	// -------------------------------------------------------------------------
 	// Create Business Packages
 
-	userAuditPlugin := useraudit.NewPlugin(log, auditbus.NewBusiness(log, auditdb.NewStore(log, db)))
-	userStorage := usercache.NewStore(log, userdb.NewStore(log, db), time.Minute)
+	report.Phase(ctx, "business")
+
+	userAuditPlugin := useraudit.NewPlugin(auditbus.NewBusiness(log, auditdb.NewStore(log, storeDB)))
+	userAuthzPlugin := userauthz.NewPlugin(log)
+	userMetricsPlugin, err := usermetrics.NewPlugin(usermetrics.Config{Expvar: cfg.Metrics.UserExpvar})
+	if err != nil {
+		return fmt.Errorf("constructing user metrics: %w", err)
+	}
+
+	var userCache cache.Cache
+
+	switch cfg.Cache.Backend {
+	case "", "memory":
+		userCache = cache.NewMemory(cfg.Cache.Capacity)
+
+	case "redis":
+		rds, err := cache.NewRedis(cache.RedisConfig{
+			Addr:     cfg.Cache.RedisAddr,
+			Password: cfg.Cache.RedisPassword,
+			DB:       cfg.Cache.RedisDB,
+			Timeout:  cfg.Cache.RedisTimeout,
+		})
+		if err != nil {
+			return fmt.Errorf("constructing redis cache: %w", err)
+		}
+		defer rds.Close()
+
+		userCache = rds
+
+	default:
+		return fmt.Errorf("unknown cache backend %q", cfg.Cache.Backend)
+	}
+
+	userStorage := usercache.NewStore(log, userdb.NewStore(log, storeDB, userdb.WithCostBudget(cfg.DB.CostBudget)), userCache, cfg.Cache.TTL)
+
+	hasher, err := userbus.NewHasher(cfg.Hasher.Algorithm, cfg.Hasher.BcryptCost, userbus.Argon2idParams{
+		Memory:      cfg.Hasher.Argon2Memory,
+		Iterations:  cfg.Hasher.Argon2Iterations,
+		Parallelism: cfg.Hasher.Argon2Parallelism,
+	})
+	if err != nil {
+		return fmt.Errorf("constructing hasher: %w", err)
+	}
+
+	passwordPolicy := userbus.PasswordPolicy{
+		MinLength:     cfg.PasswordPolicy.MinLength,
+		RequireUpper:  cfg.PasswordPolicy.RequireUpper,
+		RequireLower:  cfg.PasswordPolicy.RequireLower,
+		RequireDigit:  cfg.PasswordPolicy.RequireDigit,
+		RequireSymbol: cfg.PasswordPolicy.RequireSymbol,
+		Banned:        cfg.PasswordPolicy.Banned,
+		History:       cfg.PasswordPolicy.History,
+	}
+
+	if cfg.PasswordPolicy.Breach.Enabled {
+		breachOptions := []func(opts *breach.Options){
+			breach.WithCache(cfg.PasswordPolicy.Breach.CacheTTL),
+		}
+
+		if cfg.PasswordPolicy.Breach.FailOpen {
+			breachOptions = append(breachOptions, breach.WithFailOpen())
+		}
+
+		provider := breach.NewHIBP(&http.Client{Timeout: cfg.PasswordPolicy.Breach.Timeout}, cfg.PasswordPolicy.Breach.URL)
+		passwordPolicy.Breach = breach.NewChecker(provider, breachOptions...)
+	}
+
+	var avatars userbus.AvatarStorer
+
+	switch cfg.Avatars.Store {
+	case "", "none":
+
+	case "disk":
+		if avatars, err = diskblob.New(cfg.Avatars.Dir, cfg.Avatars.BaseURL); err != nil {
+			return fmt.Errorf("constructing disk avatar store: %w", err)
+		}
+
+	case "s3":
+		avatars, err = s3blob.New(s3blob.Config{
+			Bucket:          cfg.Avatars.Bucket,
+			Region:          cfg.Avatars.Region,
+			AccessKeyID:     cfg.Avatars.AccessKeyID,
+			SecretAccessKey: cfg.Avatars.SecretAccessKey,
+			Endpoint:        cfg.Avatars.Endpoint,
+			PathStyle:       cfg.Avatars.PathStyle,
+			PublicURL:       cfg.Avatars.PublicURL,
+		})
+		if err != nil {
+			return fmt.Errorf("constructing s3 avatar store: %w", err)
+		}
+
+	case "gcs":
+		avatars, err = gcsblob.New(gcsblob.Config{
+			Bucket:          cfg.Avatars.Bucket,
+			AccessKeyID:     cfg.Avatars.AccessKeyID,
+			SecretAccessKey: cfg.Avatars.SecretAccessKey,
+			PublicURL:       cfg.Avatars.PublicURL,
+		})
+		if err != nil {
+			return fmt.Errorf("constructing gcs avatar store: %w", err)
+		}
+
+	default:
+		return fmt.Errorf("unknown avatar store %q", cfg.Avatars.Store)
+	}
+
+	notificationSenders := make(map[notificationbus.Channel]notificationbus.Sender)
+
+	if cfg.Mail.Transport != "" && cfg.Mail.Transport != "none" {
+		from, err := mail.ParseAddress(cfg.Mail.From)
+		if err != nil {
+			return fmt.Errorf("parsing mail from address: %w", err)
+		}
+
+		var mailer notificationbus.Sender
+
+		switch cfg.Mail.Transport {
+		case "console":
+			mailer = consolemailer.New(os.Stdout, *from)
+
+		case "smtp":
+			mailer, err = smtpmailer.New(smtpmailer.Config{
+				Host:     cfg.Mail.SMTPHost,
+				Port:     cfg.Mail.SMTPPort,
+				Username: cfg.Mail.SMTPUsername,
+				Password: cfg.Mail.SMTPPassword,
+				From:     *from,
+			})
+
+		case "ses":
+			mailer, err = sesmailer.New(&http.Client{Timeout: cfg.Mail.Timeout}, sesmailer.Config{
+				Region:          cfg.Mail.SESRegion,
+				AccessKeyID:     cfg.Mail.SESAccessKeyID,
+				SecretAccessKey: cfg.Mail.SESSecretKey,
+				From:            *from,
+			})
+
+		case "sendgrid":
+			mailer, err = sendgridmailer.New(&http.Client{Timeout: cfg.Mail.Timeout}, sendgridmailer.Config{
+				APIKey: cfg.Mail.SendGridAPIKey,
+				From:   *from,
+			})
+
+		default:
+			return fmt.Errorf("unknown mail transport %q", cfg.Mail.Transport)
+		}
+
+		if err != nil {
+			return fmt.Errorf("constructing %s mailer: %w", cfg.Mail.Transport, err)
+		}
+
+		notificationSenders[notificationbus.ChannelEmail] = mailer
+
+		log.Info(ctx, "startup", "status", "emailing notifications", "transport", cfg.Mail.Transport)
+	}
+
+	delegateOptions := []func(opts *delegate.Options){
+		delegate.WithWorkers(cfg.Delegate.Workers),
+		delegate.WithQueueSize(cfg.Delegate.QueueSize),
+	}
+
+	if cfg.Delegate.DeadLetters {
+		delegateOptions = append(delegateOptions, delegate.WithDeadLetters(deadletterdb.NewStore(log, db)))
+	}
+
+	if cfg.Delegate.Publisher != "" && cfg.Delegate.Publisher != "none" {
+		topics, err := delegate.ParseTopics(cfg.Delegate.TopicPrefix, cfg.Delegate.Topics)
+		if err != nil {
+			return fmt.Errorf("parsing delegate topics: %w", err)
+		}
+
+		encoder, err := delegate.ParseEncoder(cfg.Delegate.Encoding)
+		if err != nil {
+			return fmt.Errorf("parsing delegate encoding: %w", err)
+		}
+
+		var pub delegate.Publisher
+
+		switch cfg.Delegate.Publisher {
+		case "memory":
+			pub = mempub.New()
+
+		case "nats":
+			natsPub, err := natspub.New(strings.Join(cfg.Delegate.Hosts, ","), "sales")
+			if err != nil {
+				return fmt.Errorf("connecting to nats: %w", err)
+			}
+			defer natsPub.Close()
+
+			pub = natsPub
+
+		case "kafka":
+			kafkaPub := kafkapub.New(cfg.Delegate.Hosts)
+			defer kafkaPub.Close()
+
+			pub = kafkaPub
+
+		default:
+			return fmt.Errorf("unknown delegate publisher %q", cfg.Delegate.Publisher)
+		}
 
-	delegate := delegate.New(log)
-	auditBus := auditbus.NewBusiness(log, auditdb.NewStore(log, db))
-	userBus := userbus.NewBusiness(log, delegate, userStorage, userAuditPlugin)
-	productBus := productbus.NewBusiness(log, userBus, delegate, productdb.NewStore(log, db))
-	homeBus := homebus.NewBusiness(log, userBus, delegate, homedb.NewStore(log, db))
-	vproductBus := vproductbus.NewBusiness(vproductdb.NewStore(log, db))
+		log.Info(ctx, "startup", "status", "publishing delegate events", "publisher", cfg.Delegate.Publisher, "encoding", cfg.Delegate.Encoding, "fields", cfg.Delegate.Fields)
+
+		delegateOptions = append(delegateOptions, delegate.WithPublisher(pub, topics, encoder, cfg.Delegate.Fields...))
+	}
+
+	delegate := delegate.New(log, delegateOptions...)
+	auditBus := auditbus.NewBusiness(log, auditdb.NewStore(log, storeDB))
+	userBus := userbus.NewBusiness(log, delegate, userStorage, passwordPolicy, hasher, avatars, userMetricsPlugin, userAuthzPlugin, userAuditPlugin)
+	productBus := productbus.NewBusiness(log, userBus, delegate, productdb.NewStore(log, storeDB))
+	homeBus := homebus.NewBusiness(log, userBus, delegate, homedb.NewStore(log, storeDB))
+	groupBus := groupbus.NewBusiness(log, userBus, delegate, groupdb.NewStore(log, storeDB))
+	permissionBus := permissionbus.NewBusiness(log, userBus, permissiondb.NewStore(log, storeDB))
+
+	vproductBus := vproductbus.NewBusiness(vproductdb.NewStore(log, storeDB))
+
+	reportSenders := map[reportbus.Channel]reportbus.Sender{
+		reportbus.ChannelWebhook: reportbus.NewWebhookSender(&http.Client{Timeout: cfg.Reports.WebhookTimeout}),
+	}
+	reportBus := reportbus.NewBusiness(log, userBus, reportdb.NewStore(log, storeDB), reportSenders)
+	templateBus := templatebus.NewBusiness(log, templatedb.NewStore(log, storeDB))
+	notificationBus := notificationbus.NewBusiness(log, userBus, templateBus, delegate, notificationdb.NewStore(log, storeDB), notificationSenders)
+
+	var inviteSender invitebus.Sender
+	switch {
+	case cfg.Invites.WebhookURL != "":
+		inviteSender = invitebus.NewWebhookSender(&http.Client{Timeout: cfg.Invites.WebhookTimeout}, cfg.Invites.WebhookURL)
+	case len(notificationSenders) > 0:
+		inviteSender = notificationbus.NewInviteSender(notificationBus)
+	}
+	inviteBus := invitebus.NewBusiness(log, userBus, delegate, invitedb.NewStore(log, storeDB), inviteSender)
+	apiKeyBus := apikeybus.NewBusiness(log, userBus, apikeydb.NewStore(log, storeDB))
+	searchBus := searchbus.NewBusiness(log, searchdb.NewStore(log, storeDB))
+
+	webhookRetry := webhookbus.Retry{
+		MaxAttempts: cfg.Webhooks.MaxAttempts,
+		Backoff:     cfg.Webhooks.Backoff,
+		MaxBackoff:  cfg.Webhooks.MaxBackoff,
+	}
+	webhookSender := webhookbus.NewHTTPSender(&http.Client{Timeout: cfg.Webhooks.Timeout})
+	webhookBus := webhookbus.NewBusiness(log, userBus, delegate, webhookdb.NewStore(log, storeDB), webhookSender, webhookRetry)
+
+	// Workflows that span domains are registered with the orchestrator,
+	// the sagas in flight when an instance stops are resumed by the jobs.
+	sagas := saga.New(log, sagadb.NewStore(log, db))
+
+	// -------------------------------------------------------------------------
+	// Initialize anomaly detection
+
+	log.Info(ctx, "startup", "status", "initializing anomaly detection", "window", cfg.Anomaly.Window)
+
+	anomalyCfg := anomaly.Config{
+		Window:       cfg.Anomaly.Window,
+		AuthFailures: cfg.Anomaly.AuthFailures,
+		Forbidden:    cfg.Anomaly.Forbidden,
+		AdminCreated: cfg.Anomaly.AdminCreated,
+	}
+	detector.Configure(anomalyCfg, delegate)
+	detector.Watch(delegate, userBus)
+
+	// -------------------------------------------------------------------------
+	// Initialize the event stream
+
+	events := stream.New(cfg.Events.History)
+	events.Watch(delegate)
 
 	// -------------------------------------------------------------------------
 	// Initialize authentication support
 
+	report.Phase(ctx, "auth")
+
 	log.Info(ctx, "startup", "status", "initializing authentication support")
 
 	authClient := authclient.New(log, cfg.Auth.Host)
@@ -190,6 +720,8 @@ func run(ctx context.Context, log *logger.Logger) error {
 	// -------------------------------------------------------------------------
 	// Start Tracing Support
 
+	report.Phase(ctx, "tracing")
+
 	log.Info(ctx, "startup", "status", "initializing tracing support")
 
 	traceProvider, teardown, err := otel.InitTracing(log, otel.Config{
@@ -212,6 +744,8 @@ func run(ctx context.Context, log *logger.Logger) error {
 	// -------------------------------------------------------------------------
 	// Start Debug Service
 
+	report.Phase(ctx, "servers")
+
 	go func() {
 		log.Info(ctx, "startup", "status", "debug v1 router started", "host", cfg.Web.DebugHost)
 
@@ -220,6 +754,55 @@ func run(ctx context.Context, log *logger.Logger) error {
 		}
 	}()
 
+	// -------------------------------------------------------------------------
+	// Start Jobs
+
+	schedCtx, schedCancel := context.WithCancel(ctx)
+	defer schedCancel()
+
+	// The jobs write to the database so they can't run on a read replica.
+	// Instances campaign on an advisory lock so only one of them runs the
+	// jobs at a time.
+	if !cfg.Web.ReadOnly {
+		runner := jobs.New(log, jobs.NewAdvisoryLock(db, "sales-jobs"))
+
+		runner.Register(reportBus.Job(cfg.Reports.Interval))
+		runner.Register(webhookBus.Job(cfg.Webhooks.Interval))
+		runner.Register(sagas.Job(cfg.Sagas.Interval, cfg.Sagas.Stale))
+		runner.Register(userbus.PurgeJob(log, userBus, cfg.Idempotency.PurgeInterval))
+		runner.Register(apikeybus.RevokeExpiredJob(log, apiKeyBus, cfg.APIKeys.RevokeInterval))
+
+		if cfg.Dormant.DisableAfter > 0 {
+			runner.Register(userbus.DisableDormantJob(log, userBus, cfg.Dormant.CheckInterval, cfg.Dormant.DisableAfter))
+		}
+
+		go func() {
+			log.Info(ctx, "startup", "status", "job runner started")
+			runner.Run(schedCtx)
+		}()
+
+		// Users saved before names were normalized are rewritten once in
+		// the background. Instances doing it at once is harmless, the
+		// losing write is skipped.
+		go func() {
+			n, err := userBus.NormalizeNames(schedCtx)
+			if err != nil {
+				log.Error(ctx, "startup", "status", "normalizing names", "ERROR", err)
+				return
+			}
+
+			log.Info(ctx, "startup", "status", "names normalized", "users", n)
+		}()
+	}
+
+	// -------------------------------------------------------------------------
+	// Rate Limiter Support
+
+	rateLimiter, err := limiter.New(cfg.RateLimit.Requests, cfg.RateLimit.Window)
+	if err != nil {
+		return fmt.Errorf("constructing rate limiter: %w", err)
+	}
+
 	// -------------------------------------------------------------------------
 	// Start API Service
 
@@ -234,22 +817,52 @@ func run(ctx context.Context, log *logger.Logger) error {
 		DB:     db,
 		Tracer: tracer,
 		BusConfig: mux.BusConfig{
-			AuditBus:    auditBus,
-			UserBus:     userBus,
-			ProductBus:  productBus,
-			HomeBus:     homeBus,
-			VProductBus: vproductBus,
+			APIKeyBus:       apiKeyBus,
+			AuditBus:        auditBus,
+			UserBus:         userBus,
+			ProductBus:      productBus,
+			HomeBus:         homeBus,
+			GroupBus:        groupBus,
+			InviteBus:       inviteBus,
+			NotificationBus: notificationBus,
+			PermissionBus:   permissionBus,
+			VProductBus:     vproductBus,
+			ReportBus:       reportBus,
+			SearchBus:       searchBus,
+			TemplateBus:     templateBus,
+			WebhookBus:      webhookBus,
 		},
 		SalesConfig: mux.SalesConfig{
-			AuthClient: authClient,
+			AuthClient:      authClient,
+			Delegate:        delegate,
+			Limiter:         rateLimiter,
+			ClockSkew:       cfg.Staging.ClockSkew,
+			Events:          events,
+			EventsHeartbeat: cfg.Events.Heartbeat,
 		},
 	}
 
-	webAPI := mux.WebAPI(cfgMux,
-		buildRoutes(),
+	muxOptions := []func(opts *mux.Options){
 		mux.WithCORS(cfg.Web.CORSAllowedOrigins),
 		mux.WithFileServer(false, static, "static", "/"),
-	)
+		mux.WithDiagnostics(authClient),
+		mux.WithRateLimit(rateLimiter),
+	}
+
+	if cfg.Web.ReadOnly {
+		log.Info(ctx, "startup", "status", "running in read-only mode")
+		muxOptions = append(muxOptions, mux.WithReadOnly())
+	}
+
+	if cfg.Staging.ClockSkew && clock.Skewable {
+		log.Info(ctx, "startup", "status", "clock skew endpoint enabled")
+	}
+
+	if len(cfg.DB.ReplicaHosts) > 0 {
+		muxOptions = append(muxOptions, mux.WithReadYourWrites())
+	}
+
+	webAPI := mux.WebAPI(cfgMux, buildRoutes(), muxOptions...)
 
 	api := http.Server{
 		Addr:         cfg.Web.APIHost,
@@ -260,14 +873,54 @@ func run(ctx context.Context, log *logger.Logger) error {
 		ErrorLog:     logger.NewStdLogger(log, logger.LevelError),
 	}
 
-	serverErrors := make(chan error, 1)
+	// The listener is opened here so the time it takes to bind is part of
+	// the startup report.
+	ln, err := net.Listen("tcp", api.Addr)
+	if err != nil {
+		return fmt.Errorf("listening on api host: %w", err)
+	}
+
+	serverErrors := make(chan error, 2)
 
 	go func() {
 		log.Info(ctx, "startup", "status", "api router started", "host", api.Addr)
 
-		serverErrors <- api.ListenAndServe()
+		serverErrors <- api.Serve(ln)
 	}()
 
+	// Internal callers can reach the user domain over grpc. Its calls can
+	// write, so it isn't served by read-only instances.
+	var grpcAPI *grpc.Server
+
+	if cfg.GRPC.APIHost != "" && !cfg.Web.ReadOnly {
+		grpcAPI = rpc.NewServer(rpc.Config{
+			Log:    log,
+			Tracer: tracer,
+		})
+
+		userapp.RegisterGRPC(grpcAPI, userapp.Config{
+			Log:        log,
+			UserBus:    userBus,
+			AuthClient: authClient,
+			DB:         db,
+		})
+
+		grpcLn, err := net.Listen("tcp", cfg.GRPC.APIHost)
+		if err != nil {
+			return fmt.Errorf("listening on grpc host: %w", err)
+		}
+
+		go func() {
+			log.Info(ctx, "startup", "status", "grpc server started", "host", cfg.GRPC.APIHost)
+
+			serverErrors <- grpcAPI.Serve(grpcLn)
+		}()
+	}
+
+	if err := report.Finish(ctx); err != nil {
+		return fmt.Errorf("startup: %w", err)
+	}
+
 	// -------------------------------------------------------------------------
 	// Shutdown
 
@@ -282,10 +935,22 @@ func run(ctx context.Context, log *logger.Logger) error {
 		ctx, cancel := context.WithTimeout(ctx, cfg.Web.ShutdownTimeout)
 		defer cancel()
 
+		if grpcAPI != nil {
+			grpcAPI.GracefulStop()
+		}
+
+		// The streams stay open until they're told to end, which would hold
+		// up the shutdown of the api.
+		events.Close()
+
 		if err := api.Shutdown(ctx); err != nil {
 			api.Close()
 			return fmt.Errorf("could not stop server gracefully: %w", err)
 		}
+
+		if err := delegate.Shutdown(ctx); err != nil {
+			return fmt.Errorf("could not drain delegate calls: %w", err)
+		}
 	}
 
 	return nil
@@ -32,6 +32,9 @@ func create200(sd apitest.SeedData) []apitest.Table {
 				Roles:      []string{"ADMIN"},
 				Department: "ITO",
 				Enabled:    true,
+				CreatedBy:  sd.Admins[0].ID.String(),
+				UpdatedBy:  sd.Admins[0].ID.String(),
+				Version:    1,
 			},
 			CmpFunc: func(got any, exp any) string {
 				gotResp, exists := got.(*userapp.User)
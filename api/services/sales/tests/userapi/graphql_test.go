@@ -0,0 +1,196 @@
+package user_test
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/ardanlabs/service/app/sdk/apitest"
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/google/go-cmp/cmp"
+)
+
+type graphqlRequest struct {
+	Query     string         `json:"query"`
+	Variables map[string]any `json:"variables,omitempty"`
+}
+
+type graphqlUser struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type graphqlUserPage struct {
+	Items       []graphqlUser `json:"items"`
+	Total       int           `json:"total"`
+	Page        int           `json:"page"`
+	RowsPerPage int           `json:"rowsPerPage"`
+}
+
+type graphqlError struct {
+	Message    string         `json:"message"`
+	Extensions map[string]any `json:"extensions"`
+}
+
+type graphqlResponse struct {
+	Data struct {
+		User  *graphqlUser     `json:"user"`
+		Users *graphqlUserPage `json:"users"`
+	} `json:"data"`
+	Errors []graphqlError `json:"errors"`
+}
+
+func toGraphQLUser(usr userbus.User) graphqlUser {
+	return graphqlUser{
+		ID:    usr.ID.String(),
+		Name:  usr.Name.String(),
+		Email: usr.Email.Address,
+	}
+}
+
+func graphql200(sd apitest.SeedData) []apitest.Table {
+	usrs := make([]userbus.User, 0, len(sd.Admins)+len(sd.Users))
+
+	for _, adm := range sd.Admins {
+		usrs = append(usrs, adm.User)
+	}
+
+	for _, usr := range sd.Users {
+		usrs = append(usrs, usr.User)
+	}
+
+	sort.Slice(usrs, func(i, j int) bool {
+		return usrs[i].ID.String() <= usrs[j].ID.String()
+	})
+
+	items := make([]graphqlUser, len(usrs))
+	for i, usr := range usrs {
+		items[i] = toGraphQLUser(usr)
+	}
+
+	var users graphqlResponse
+	users.Data.Users = &graphqlUserPage{
+		Items:       items,
+		Total:       len(usrs),
+		Page:        1,
+		RowsPerPage: 10,
+	}
+
+	usr := toGraphQLUser(sd.Users[0].User)
+
+	var self graphqlResponse
+	self.Data.User = &usr
+
+	table := []apitest.Table{
+		{
+			Name:       "users",
+			URL:        "/v1/graphql",
+			Token:      sd.Admins[0].Token,
+			StatusCode: http.StatusOK,
+			Method:     http.MethodPost,
+			Input: graphqlRequest{
+				Query: `query Users($rows: Int) {
+					users(filter: {name: "Name"}, orderBy: [{field: "user_id", direction: "ASC"}], page: 1, rows: $rows) {
+						items { id name email }
+						total page rowsPerPage
+					}
+				}`,
+				Variables: map[string]any{"rows": 10},
+			},
+			GotResp: &graphqlResponse{},
+			ExpResp: &users,
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+		{
+			Name:       "user-self",
+			URL:        "/v1/graphql",
+			Token:      sd.Users[0].Token,
+			StatusCode: http.StatusOK,
+			Method:     http.MethodPost,
+			Input: graphqlRequest{
+				Query:     `query User($id: ID!) { user(id: $id) { id name email } }`,
+				Variables: map[string]any{"id": sd.Users[0].ID.String()},
+			},
+			GotResp: &graphqlResponse{},
+			ExpResp: &self,
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+	}
+
+	return table
+}
+
+func graphqlErrors(sd apitest.SeedData) []apitest.Table {
+	table := []apitest.Table{
+		{
+			Name:       "users-not-admin",
+			URL:        "/v1/graphql",
+			Token:      sd.Users[0].Token,
+			StatusCode: http.StatusOK,
+			Method:     http.MethodPost,
+			Input: graphqlRequest{
+				Query: `{ users { total } }`,
+			},
+			GotResp: &graphqlResponse{},
+			ExpResp: &graphqlResponse{
+				Errors: []graphqlError{
+					{
+						Extensions: map[string]any{"code": "unauthenticated"},
+					},
+				},
+			},
+			CmpFunc: func(got any, exp any) string {
+				// The message is the one returned by the auth service, only
+				// the code is checked.
+				resp := got.(*graphqlResponse)
+				for i := range resp.Errors {
+					resp.Errors[i].Message = ""
+				}
+
+				return cmp.Diff(got, exp)
+			},
+		},
+		{
+			Name:       "unknown-field",
+			URL:        "/v1/graphql",
+			Token:      sd.Admins[0].Token,
+			StatusCode: http.StatusOK,
+			Method:     http.MethodPost,
+			Input: graphqlRequest{
+				Query: `{ users { password } }`,
+			},
+			GotResp: &graphqlResponse{},
+			ExpResp: &graphqlResponse{
+				Errors: []graphqlError{
+					{
+						Message:    `Cannot query field "password" on type "UserPage".`,
+						Extensions: map[string]any{"code": "GRAPHQL_VALIDATION_FAILED"},
+					},
+				},
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+		{
+			Name:       "missing-query",
+			URL:        "/v1/graphql",
+			Token:      sd.Admins[0].Token,
+			StatusCode: http.StatusBadRequest,
+			Method:     http.MethodPost,
+			Input:      graphqlRequest{},
+			GotResp:    &errs.Error{},
+			ExpResp:    errs.Newf(errs.InvalidArgument, "query is required"),
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+	}
+
+	return table
+}
@@ -16,8 +16,11 @@ func toAppUser(bus userbus.User) userapp.User {
 		Roles:       role.ParseToString(bus.Roles),
 		Department:  bus.Department.String(),
 		Enabled:     bus.Enabled,
+		CreatedBy:   bus.CreatedBy.String(),
+		UpdatedBy:   bus.UpdatedBy.String(),
 		DateCreated: bus.DateCreated.Format(time.RFC3339),
 		DateUpdated: bus.DateUpdated.Format(time.RFC3339),
+		Version:     bus.Version,
 	}
 }
 
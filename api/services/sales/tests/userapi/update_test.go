@@ -7,6 +7,7 @@ import (
 
 	"github.com/ardanlabs/service/app/domain/userapp"
 	"github.com/ardanlabs/service/app/sdk/apitest"
+	"github.com/ardanlabs/service/app/sdk/auth"
 	"github.com/ardanlabs/service/app/sdk/errs"
 	"github.com/ardanlabs/service/business/sdk/dbtest"
 	"github.com/google/go-cmp/cmp"
@@ -35,8 +36,11 @@ func update200(sd apitest.SeedData) []apitest.Table {
 				Roles:       []string{"USER"},
 				Department:  "ITO",
 				Enabled:     true,
+				CreatedBy:   sd.Users[0].CreatedBy.String(),
+				UpdatedBy:   sd.Users[0].ID.String(),
 				DateCreated: sd.Users[0].DateCreated.Format(time.RFC3339),
 				DateUpdated: sd.Users[0].DateUpdated.Format(time.RFC3339),
+				Version:     sd.Users[0].Version + 1,
 			},
 			CmpFunc: func(got any, exp any) string {
 				gotResp, exists := got.(*userapp.User)
@@ -67,8 +71,53 @@ func update200(sd apitest.SeedData) []apitest.Table {
 				Roles:       []string{"USER"},
 				Department:  sd.Admins[0].Department.String(),
 				Enabled:     true,
+				CreatedBy:   sd.Admins[0].CreatedBy.String(),
+				UpdatedBy:   sd.Admins[0].ID.String(),
 				DateCreated: sd.Admins[0].DateCreated.Format(time.RFC3339),
 				DateUpdated: sd.Admins[0].DateUpdated.Format(time.RFC3339),
+				Version:     sd.Admins[0].Version + 1,
+			},
+			CmpFunc: func(got any, exp any) string {
+				gotResp, exists := got.(*userapp.User)
+				if !exists {
+					return "error occurred"
+				}
+
+				expResp := exp.(*userapp.User)
+				gotResp.DateUpdated = expResp.DateUpdated
+
+				return cmp.Diff(gotResp, expResp)
+			},
+		},
+	}
+
+	return table
+}
+
+func updateBreakGlass200(sd apitest.SeedData, token string) []apitest.Table {
+	table := []apitest.Table{
+		{
+			Name:       "role",
+			URL:        fmt.Sprintf("/v1/users/role/%s", sd.Users[1].ID),
+			Token:      token,
+			Method:     http.MethodPut,
+			StatusCode: http.StatusOK,
+			Input: &userapp.UpdateUserRole{
+				Roles: []string{"ADMIN"},
+			},
+			GotResp: &userapp.User{},
+			ExpResp: &userapp.User{
+				ID:          sd.Users[1].ID.String(),
+				Name:        sd.Users[1].Name.String(),
+				Email:       sd.Users[1].Email.Address,
+				Roles:       []string{"ADMIN"},
+				Department:  sd.Users[1].Department.String(),
+				Enabled:     true,
+				CreatedBy:   sd.Users[1].CreatedBy.String(),
+				UpdatedBy:   auth.BreakGlassSubject,
+				DateCreated: sd.Users[1].DateCreated.Format(time.RFC3339),
+				DateUpdated: sd.Users[1].DateUpdated.Format(time.RFC3339),
+				Version:     sd.Users[1].Version + 1,
 			},
 			CmpFunc: func(got any, exp any) string {
 				gotResp, exists := got.(*userapp.User)
@@ -1,6 +1,7 @@
 package user_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/ardanlabs/service/app/sdk/apitest"
@@ -18,6 +19,11 @@ func Test_User(t *testing.T) {
 		t.Fatalf("Seeding error: %s", err)
 	}
 
+	bgToken, _, err := test.Auth.BreakGlass(context.Background(), apitest.KID, apitest.BreakGlassCredential, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("Break-glass error: %s", err)
+	}
+
 	// -------------------------------------------------------------------------
 
 	test.Run(t, query200(sd), "query-200")
@@ -31,6 +37,10 @@ func Test_User(t *testing.T) {
 	test.Run(t, update200(sd), "update-200")
 	test.Run(t, update401(sd), "update-401")
 	test.Run(t, update400(sd), "update-400")
+	test.Run(t, updateBreakGlass200(sd, bgToken), "update-breakglass-200")
+
+	test.Run(t, graphql200(sd), "graphql-200")
+	test.Run(t, graphqlErrors(sd), "graphql-errors")
 
 	test.Run(t, delete200(sd), "delete-200")
 	test.Run(t, delete401(sd), "delete-401")
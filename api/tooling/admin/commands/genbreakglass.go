@@ -0,0 +1,26 @@
+package commands
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/ardanlabs/service/app/sdk/auth"
+)
+
+// GenBreakGlass creates a sealed break-glass credential and the hash the
+// auth service is configured with.
+func GenBreakGlass() error {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return fmt.Errorf("generating credential: %w", err)
+	}
+
+	credential := base64.RawURLEncoding.EncodeToString(secret)
+
+	fmt.Printf("-----BEGIN CREDENTIAL-----\n%s\n-----END CREDENTIAL-----\n\n", credential)
+	fmt.Println("Seal the credential above. Configure the auth service with this hash:")
+	fmt.Printf("AUTH_BREAKGLASS_CREDENTIAL_HASH=%s\n", auth.HashBreakGlassCredential(credential))
+
+	return nil
+}
@@ -34,7 +34,7 @@ func GenToken(log *logger.Logger, dbConfig sqldb.Config, keyPath string, userID
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	userBus := userbus.NewBusiness(log, nil, userdb.NewStore(log, db))
+	userBus := userbus.NewBusiness(log, nil, userdb.NewStore(log, db), userbus.PasswordPolicy{}, userbus.NewBcryptHasher(0), nil)
 
 	usr, err := userBus.QueryByID(ctx, userID)
 	if err != nil {
@@ -90,6 +90,10 @@ func GenToken(log *logger.Logger, dbConfig sqldb.Config, keyPath string, userID
 		Roles: role.ParseToString(usr.Roles),
 	}
 
+	if usr.TenantID != uuid.Nil {
+		claims.Tenant = usr.TenantID.String()
+	}
+
 	// This will generate a JWT with the claims embedded in them. The database
 	// with need to be configured with the information found in the public key
 	// file to validate these claims. Dgraph does not support key rotate at
@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ardanlabs/service/business/sdk/migrate"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+)
+
+// MigrateVersion shows the version of the schema in the database.
+func MigrateVersion(cfg sqldb.Config) error {
+	db, err := sqldb.Open(cfg)
+	if err != nil {
+		return fmt.Errorf("connect database: %w", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	version, err := migrate.Version(ctx, db)
+	if err != nil {
+		return fmt.Errorf("query version: %w", err)
+	}
+
+	fmt.Printf("database version: %v\n", version)
+	fmt.Printf("latest version:   %v\n", migrate.Latest())
+	return nil
+}
+
+// MigratePlan shows the migrations that would be applied to the database
+// without applying them. With verbose the scripts are shown as well.
+func MigratePlan(cfg sqldb.Config, verbose bool) error {
+	db, err := sqldb.Open(cfg)
+	if err != nil {
+		return fmt.Errorf("connect database: %w", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	migs, err := migrate.Plan(ctx, db)
+	if err != nil {
+		return fmt.Errorf("plan migrations: %w", err)
+	}
+
+	if len(migs) == 0 {
+		fmt.Println("database is up to date")
+		return nil
+	}
+
+	for _, mig := range migs {
+		fmt.Printf("-- Version: %v\n", mig.Version)
+		fmt.Printf("-- Description: %s\n", mig.Description)
+
+		if verbose {
+			fmt.Printf("%s\n\n", mig.Script)
+		}
+	}
+
+	fmt.Printf("%d migrations pending\n", len(migs))
+	return nil
+}
@@ -12,7 +12,6 @@ import (
 	"github.com/ardanlabs/service/business/types/name"
 	"github.com/ardanlabs/service/business/types/role"
 	"github.com/ardanlabs/service/foundation/logger"
-	"github.com/google/uuid"
 )
 
 // UserAdd adds new users into the database.
@@ -31,7 +30,7 @@ func UserAdd(log *logger.Logger, cfg sqldb.Config, nme string, email string, pas
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	userBus := userbus.NewBusiness(log, nil, userdb.NewStore(log, db))
+	userBus := userbus.NewBusiness(log, nil, userdb.NewStore(log, db), userbus.PasswordPolicy{}, userbus.NewBcryptHasher(0), nil)
 
 	addr, err := mail.ParseAddress(email)
 	if err != nil {
@@ -45,7 +44,7 @@ func UserAdd(log *logger.Logger, cfg sqldb.Config, nme string, email string, pas
 		Roles:    []role.Role{role.Admin, role.User},
 	}
 
-	usr, err := userBus.Create(ctx, uuid.UUID{}, nu)
+	usr, _, err := userBus.Create(ctx, userbus.ActorTooling, nu)
 	if err != nil {
 		return fmt.Errorf("create user: %w", err)
 	}
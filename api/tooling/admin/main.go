@@ -93,6 +93,17 @@ func processCommands(args conf.Args, log *logger.Logger, cfg config) error {
 			return fmt.Errorf("migrating database: %w", err)
 		}
 
+	case "migrate-version":
+		if err := commands.MigrateVersion(dbConfig); err != nil {
+			return fmt.Errorf("querying database version: %w", err)
+		}
+
+	case "migrate-plan":
+		verbose := args.Num(1) == "-v"
+		if err := commands.MigratePlan(dbConfig, verbose); err != nil {
+			return fmt.Errorf("planning migrations: %w", err)
+		}
+
 	case "seed":
 		if err := commands.Seed(dbConfig); err != nil {
 			return fmt.Errorf("seeding database: %w", err)
@@ -126,6 +137,11 @@ func processCommands(args conf.Args, log *logger.Logger, cfg config) error {
 			return fmt.Errorf("key generation: %w", err)
 		}
 
+	case "genbreakglass":
+		if err := commands.GenBreakGlass(); err != nil {
+			return fmt.Errorf("break-glass generation: %w", err)
+		}
+
 	case "gentoken":
 		userID, err := uuid.Parse(args.Num(1))
 		if err != nil {
@@ -141,11 +157,14 @@ func processCommands(args conf.Args, log *logger.Logger, cfg config) error {
 
 	default:
 		fmt.Println("migrate:    create the schema in the database")
+		fmt.Println("migrate-version: show the version of the schema in the database")
+		fmt.Println("migrate-plan:    show the migrations that would be applied, -v for the scripts")
 		fmt.Println("seed:       add data to the database")
 		fmt.Println("useradd:    add a new user to the database")
 		fmt.Println("users:      get a list of users from the database")
 		fmt.Println("genkey:     generate a set of private/public key files")
 		fmt.Println("gentoken:   generate a JWT for a user with claims")
+		fmt.Println("genbreakglass: generate a sealed break-glass credential")
 		fmt.Println("provide a command to get more help.")
 		return commands.ErrHelp
 	}
@@ -0,0 +1,142 @@
+// Package apikeyapp maintains the app layer api for the api key domain.
+package apikeyapp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/mid"
+	"github.com/ardanlabs/service/app/sdk/query"
+	"github.com/ardanlabs/service/business/domain/apikeybus"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/foundation/web"
+)
+
+type app struct {
+	apiKeyBus *apikeybus.Business
+}
+
+func newApp(apiKeyBus *apikeybus.Business) *app {
+	return &app{
+		apiKeyBus: apiKeyBus,
+	}
+}
+
+func (a *app) create(ctx context.Context, r *http.Request) web.Encoder {
+	var app NewKey
+	if err := web.Decode(r, &app); err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	nk, err := toBusNewKey(ctx, app)
+	if err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	key, plain, err := a.apiKeyBus.Create(ctx, nk)
+	if err != nil {
+		return errs.Newf(errs.Internal, "create: key[%+v]: %s", app, err)
+	}
+
+	return toAppCreatedKey(key, plain)
+}
+
+func (a *app) revoke(ctx context.Context, _ *http.Request) web.Encoder {
+	key, err := mid.GetAPIKey(ctx)
+	if err != nil {
+		return errs.Newf(errs.Internal, "api key missing in context: %s", err)
+	}
+
+	if _, err := a.apiKeyBus.Revoke(ctx, key); err != nil {
+		return errs.Newf(errs.Internal, "revoke: keyID[%s]: %s", key.ID, err)
+	}
+
+	return nil
+}
+
+// rotate issues a successor for the key, the key keeps working for the
+// overlap. An empty body uses the default overlap.
+func (a *app) rotate(ctx context.Context, r *http.Request) web.Encoder {
+	var app RotateKey
+	if r.ContentLength != 0 {
+		if err := web.Decode(r, &app); err != nil {
+			return errs.New(errs.InvalidArgument, err)
+		}
+	}
+
+	overlap, err := app.overlap()
+	if err != nil {
+		return errs.NewFieldErrors("overlap", err)
+	}
+
+	key, err := mid.GetAPIKey(ctx)
+	if err != nil {
+		return errs.Newf(errs.Internal, "api key missing in context: %s", err)
+	}
+
+	successor, plain, err := a.apiKeyBus.Rotate(ctx, key, overlap)
+	if err != nil {
+		switch {
+		case errors.Is(err, apikeybus.ErrRevoked):
+			return errs.New(errs.FailedPrecondition, apikeybus.ErrRevoked)
+		case errors.Is(err, apikeybus.ErrRotated):
+			return errs.New(errs.Aborted, apikeybus.ErrRotated)
+		case errors.Is(err, apikeybus.ErrUserDisabled):
+			return errs.New(errs.FailedPrecondition, apikeybus.ErrUserDisabled)
+		}
+		return errs.Newf(errs.Internal, "rotate: keyID[%s]: %s", key.ID, err)
+	}
+
+	return toAppCreatedKey(successor, plain)
+}
+
+// query returns the keys of the caller. Revoked keys are included so the
+// caller can see when they were revoked.
+func (a *app) query(ctx context.Context, r *http.Request) web.Encoder {
+	qp := parseQueryParams(r)
+
+	page, err := page.Parse(qp.Page, qp.Rows)
+	if err != nil {
+		return errs.NewFieldErrors("page", err)
+	}
+
+	filter, err := parseFilter(qp)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	userID, err := mid.GetUserID(ctx)
+	if err != nil {
+		return errs.New(errs.Unauthenticated, err)
+	}
+	filter.UserID = &userID
+
+	orderBy, err := order.Parse(orderByFields, qp.OrderBy, apikeybus.DefaultOrderBy)
+	if err != nil {
+		return errs.NewFieldErrors("order", err)
+	}
+
+	keys, err := a.apiKeyBus.Query(ctx, filter, orderBy, page)
+	if err != nil {
+		return errs.Newf(errs.Internal, "query: %s", err)
+	}
+
+	total, err := a.apiKeyBus.Count(ctx, filter)
+	if err != nil {
+		return errs.Newf(errs.Internal, "count: %s", err)
+	}
+
+	return query.NewResult(toAppKeys(keys), total, page)
+}
+
+func (a *app) queryByID(ctx context.Context, _ *http.Request) web.Encoder {
+	key, err := mid.GetAPIKey(ctx)
+	if err != nil {
+		return errs.Newf(errs.Internal, "querybyid: %s", err)
+	}
+
+	return toAppKey(key)
+}
@@ -0,0 +1,63 @@
+package apikeyapp
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/extid"
+	"github.com/ardanlabs/service/business/domain/apikeybus"
+)
+
+type queryParams struct {
+	Page    string
+	Rows    string
+	OrderBy string
+	ID      string
+	Revoked string
+}
+
+func parseQueryParams(r *http.Request) queryParams {
+	values := r.URL.Query()
+
+	filter := queryParams{
+		Page:    values.Get("page"),
+		Rows:    values.Get("rows"),
+		OrderBy: values.Get("orderBy"),
+		ID:      values.Get("key_id"),
+		Revoked: values.Get("revoked"),
+	}
+
+	return filter
+}
+
+func parseFilter(qp queryParams) (apikeybus.QueryFilter, error) {
+	var fieldErrors errs.FieldErrors
+	var filter apikeybus.QueryFilter
+
+	if qp.ID != "" {
+		id, err := extid.Decode(qp.ID)
+		switch err {
+		case nil:
+			filter.ID = &id
+		default:
+			fieldErrors.Add("key_id", err)
+		}
+	}
+
+	if qp.Revoked != "" {
+		revoked, err := strconv.ParseBool(qp.Revoked)
+		switch err {
+		case nil:
+			filter.Revoked = &revoked
+		default:
+			fieldErrors.Add("revoked", err)
+		}
+	}
+
+	if fieldErrors != nil {
+		return apikeybus.QueryFilter{}, fieldErrors.ToError()
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,171 @@
+package apikeyapp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/extid"
+	"github.com/ardanlabs/service/app/sdk/mid"
+	"github.com/ardanlabs/service/business/domain/apikeybus"
+)
+
+// Key represents information about an individual api key. The key itself is
+// never returned after it's created.
+type Key struct {
+	ID           string `json:"id"`
+	UserID       string `json:"userID"`
+	Name         string `json:"name"`
+	Prefix       string `json:"prefix"`
+	SuccessorID  string `json:"successorID,omitempty"`
+	DateCreated  string `json:"dateCreated"`
+	DateLastUsed string `json:"dateLastUsed,omitempty"`
+	DateExpires  string `json:"dateExpires,omitempty"`
+	DateRevoked  string `json:"dateRevoked,omitempty"`
+}
+
+// Encode implements the encoder interface.
+func (app Key) Encode() ([]byte, string, error) {
+	data, err := json.Marshal(app)
+	return data, "application/json", err
+}
+
+func toAppKey(key apikeybus.Key) Key {
+	app := Key{
+		ID:          extid.Encode(key.ID),
+		UserID:      extid.Encode(key.UserID),
+		Name:        key.Name,
+		Prefix:      key.Prefix,
+		DateCreated: key.DateCreated.Format(time.RFC3339),
+	}
+
+	if key.Rotated() {
+		app.SuccessorID = extid.Encode(key.SuccessorID)
+	}
+
+	if !key.DateLastUsed.IsZero() {
+		app.DateLastUsed = key.DateLastUsed.Format(time.RFC3339)
+	}
+
+	if !key.DateExpires.IsZero() {
+		app.DateExpires = key.DateExpires.Format(time.RFC3339)
+	}
+
+	if key.Revoked() {
+		app.DateRevoked = key.DateRevoked.Format(time.RFC3339)
+	}
+
+	return app
+}
+
+func toAppKeys(keys []apikeybus.Key) []Key {
+	app := make([]Key, len(keys))
+	for i, key := range keys {
+		app[i] = toAppKey(key)
+	}
+
+	return app
+}
+
+// CreatedKey represents a newly created api key along with the plaintext key,
+// which is only available in this response.
+type CreatedKey struct {
+	Key
+	APIKey string `json:"apiKey"`
+}
+
+// Encode implements the encoder interface.
+func (app CreatedKey) Encode() ([]byte, string, error) {
+	data, err := json.Marshal(app)
+	return data, "application/json", err
+}
+
+func toAppCreatedKey(key apikeybus.Key, plain string) CreatedKey {
+	return CreatedKey{
+		Key:    toAppKey(key),
+		APIKey: plain,
+	}
+}
+
+// =============================================================================
+
+// NewKey defines the data needed to add a new api key.
+type NewKey struct {
+	Name string `json:"name" validate:"required"`
+}
+
+// Decode implements the decoder interface.
+func (app *NewKey) Decode(data []byte) error {
+	return json.Unmarshal(data, app)
+}
+
+// Validate checks the data in the model is considered clean.
+func (app NewKey) Validate() error {
+	if err := errs.Check(app); err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+
+	return nil
+}
+
+func toBusNewKey(ctx context.Context, app NewKey) (apikeybus.NewKey, error) {
+	userID, err := mid.GetUserID(ctx)
+	if err != nil {
+		return apikeybus.NewKey{}, fmt.Errorf("getuserid: %w", err)
+	}
+
+	bus := apikeybus.NewKey{
+		UserID: userID,
+		Name:   app.Name,
+	}
+
+	return bus, nil
+}
+
+// =============================================================================
+
+// defaultOverlap is how long a rotated key keeps working when the caller
+// doesn't say.
+const defaultOverlap = 24 * time.Hour
+
+// maxOverlap is the longest a rotated key can keep working.
+const maxOverlap = 30 * 24 * time.Hour
+
+// RotateKey defines the data needed to rotate an api key. Overlap is how
+// long the key keeps working next to its successor, as a duration like 72h.
+type RotateKey struct {
+	Overlap string `json:"overlap"`
+}
+
+// Decode implements the decoder interface.
+func (app *RotateKey) Decode(data []byte) error {
+	return json.Unmarshal(data, app)
+}
+
+// Validate checks the data in the model is considered clean.
+func (app RotateKey) Validate() error {
+	if _, err := app.overlap(); err != nil {
+		return fmt.Errorf("validate: overlap: %w", err)
+	}
+
+	return nil
+}
+
+func (app RotateKey) overlap() (time.Duration, error) {
+	if app.Overlap == "" {
+		return defaultOverlap, nil
+	}
+
+	overlap, err := time.ParseDuration(app.Overlap)
+	if err != nil {
+		return 0, err
+	}
+
+	if overlap < 0 || overlap > maxOverlap {
+		return 0, fmt.Errorf("must be between 0s and %s", maxOverlap)
+	}
+
+	return overlap, nil
+}
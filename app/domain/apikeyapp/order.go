@@ -0,0 +1,7 @@
+package apikeyapp
+
+import (
+	"github.com/ardanlabs/service/business/domain/apikeybus"
+)
+
+var orderByFields = apikeybus.OrderFields.Mappings()
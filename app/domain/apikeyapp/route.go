@@ -0,0 +1,38 @@
+package apikeyapp
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ardanlabs/service/app/sdk/auth"
+	"github.com/ardanlabs/service/app/sdk/authclient"
+	"github.com/ardanlabs/service/app/sdk/mid"
+	"github.com/ardanlabs/service/business/domain/apikeybus"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/web"
+)
+
+// Config contains all the mandatory systems required by handlers.
+type Config struct {
+	Log        *logger.Logger
+	APIKeyBus  *apikeybus.Business
+	AuthClient *authclient.Client
+}
+
+// Routes adds specific routes for this group.
+func Routes(app *web.App, cfg Config) {
+	const version = "v1"
+
+	authen := mid.Authenticate(cfg.AuthClient)
+	ruleAny := mid.Authorize(cfg.AuthClient, auth.RuleAny)
+	ruleAuthorizeAPIKey := mid.AuthorizeAPIKey(cfg.AuthClient, cfg.APIKeyBus)
+	recentAuth := mid.RequireRecentAuth(15*time.Minute, false)
+
+	api := newApp(cfg.APIKeyBus)
+
+	app.HandlerFunc(http.MethodGet, version, "/apikeys", api.query, authen, ruleAny)
+	app.HandlerFunc(http.MethodGet, version, "/apikeys/{key_id}", api.queryByID, authen, ruleAuthorizeAPIKey)
+	app.HandlerFunc(http.MethodPost, version, "/apikeys", api.create, authen, recentAuth, ruleAny)
+	app.HandlerFunc(http.MethodPost, version, "/apikeys/{key_id}/rotate", api.rotate, authen, recentAuth, ruleAuthorizeAPIKey)
+	app.HandlerFunc(http.MethodDelete, version, "/apikeys/{key_id}", api.revoke, authen, ruleAuthorizeAPIKey)
+}
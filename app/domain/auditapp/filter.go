@@ -5,10 +5,10 @@ import (
 	"time"
 
 	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/extid"
 	"github.com/ardanlabs/service/business/domain/auditbus"
 	"github.com/ardanlabs/service/business/types/domain"
 	"github.com/ardanlabs/service/business/types/name"
-	"github.com/google/uuid"
 )
 
 type queryParams struct {
@@ -48,7 +48,7 @@ func parseFilter(qp queryParams) (auditbus.QueryFilter, error) {
 	var filter auditbus.QueryFilter
 
 	if qp.ObjID != "" {
-		id, err := uuid.Parse(qp.ObjID)
+		id, err := extid.Decode(qp.ObjID)
 		switch err {
 		case nil:
 			filter.ObjID = &id
@@ -78,7 +78,7 @@ func parseFilter(qp queryParams) (auditbus.QueryFilter, error) {
 	}
 
 	if qp.ActorID != "" {
-		id, err := uuid.Parse(qp.ActorID)
+		id, err := extid.Decode(qp.ActorID)
 		switch err {
 		case nil:
 			filter.ActorID = &id
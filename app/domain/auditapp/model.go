@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"time"
 
+	"github.com/ardanlabs/service/app/sdk/extid"
 	"github.com/ardanlabs/service/business/domain/auditbus"
 )
 
@@ -28,11 +29,11 @@ func (app Audit) Encode() ([]byte, string, error) {
 
 func toAppAudit(bus auditbus.Audit) Audit {
 	return Audit{
-		ID:        bus.ID.String(),
-		ObjID:     bus.ObjID.String(),
+		ID:        extid.Encode(bus.ID),
+		ObjID:     extid.Encode(bus.ObjID),
 		ObjDomain: bus.ObjDomain.String(),
 		ObjName:   bus.ObjName.String(),
-		ActorID:   bus.ActorID.String(),
+		ActorID:   extid.Encode(bus.ActorID),
 		Action:    bus.Action,
 		Data:      string(bus.Data),
 		Message:   bus.Message,
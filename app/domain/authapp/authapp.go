@@ -5,21 +5,30 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/ardanlabs/service/app/sdk/auth"
 	"github.com/ardanlabs/service/app/sdk/authclient"
 	"github.com/ardanlabs/service/app/sdk/errs"
 	"github.com/ardanlabs/service/app/sdk/mid"
+	"github.com/ardanlabs/service/business/domain/sessionbus"
+	"github.com/ardanlabs/service/business/domain/userbus"
 	"github.com/ardanlabs/service/foundation/web"
 )
 
 type app struct {
-	auth *auth.Auth
+	auth       *auth.Auth
+	userBus    userbus.Business
+	sessionBus *sessionbus.Business
+	accessTTL  time.Duration
 }
 
-func newApp(ath *auth.Auth) *app {
+func newApp(ath *auth.Auth, userBus userbus.Business, sessionBus *sessionbus.Business, accessTTL time.Duration) *app {
 	return &app{
-		auth: ath,
+		auth:       ath,
+		userBus:    userBus,
+		sessionBus: sessionBus,
+		accessTTL:  accessTTL,
 	}
 }
 
@@ -69,3 +78,32 @@ func (a *app) authorize(ctx context.Context, r *http.Request) web.Encoder {
 
 	return nil
 }
+
+func (a *app) breakGlass(ctx context.Context, r *http.Request) web.Encoder {
+	kid := web.Param(r, "kid")
+	if kid == "" {
+		return errs.NewFieldErrors("kid", errors.New("missing kid"))
+	}
+
+	var req breakGlassRequest
+	if err := web.Decode(r, &req); err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	tkn, expiresAt, err := a.auth.BreakGlass(ctx, kid, req.Credential, req.Reason, r.RemoteAddr)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrBreakGlassDisabled):
+			return errs.New(errs.FailedPrecondition, err)
+		case errors.Is(err, auth.ErrForbidden):
+			return errs.New(errs.Unauthenticated, err)
+		default:
+			return errs.New(errs.Internal, err)
+		}
+	}
+
+	return breakGlassToken{
+		Token:     tkn,
+		ExpiresAt: expiresAt.Format(time.RFC3339),
+	}
+}
@@ -1,6 +1,14 @@
 package authapp
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/extid"
+	"github.com/ardanlabs/service/business/domain/sessionbus"
+)
 
 type token struct {
 	Token string `json:"token"`
@@ -11,3 +19,133 @@ func (t token) Encode() ([]byte, string, error) {
 	data, err := json.Marshal(t)
 	return data, "application/json", err
 }
+
+// =============================================================================
+
+type breakGlassRequest struct {
+	Credential string `json:"credential" validate:"required"`
+	Reason     string `json:"reason" validate:"required"`
+}
+
+// Decode implements the decoder interface.
+func (req *breakGlassRequest) Decode(data []byte) error {
+	return json.Unmarshal(data, req)
+}
+
+// Validate checks the data in the model is considered clean.
+func (req breakGlassRequest) Validate() error {
+	if err := errs.Check(req); err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+
+	return nil
+}
+
+type breakGlassToken struct {
+	Token     string `json:"token"`
+	ExpiresAt string `json:"expiresAt"`
+}
+
+// Encode implements the encoder interface.
+func (t breakGlassToken) Encode() ([]byte, string, error) {
+	data, err := json.Marshal(t)
+	return data, "application/json", err
+}
+
+// =============================================================================
+
+type newSessionRequest struct {
+	Device string `json:"device"`
+}
+
+// Decode implements the decoder interface.
+func (req *newSessionRequest) Decode(data []byte) error {
+	return json.Unmarshal(data, req)
+}
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" validate:"required"`
+	NewPassword     string `json:"newPassword" validate:"required"`
+	RefreshToken    string `json:"refreshToken"`
+	Device          string `json:"device"`
+}
+
+// Decode implements the decoder interface.
+func (req *changePasswordRequest) Decode(data []byte) error {
+	return json.Unmarshal(data, req)
+}
+
+// Validate checks the data in the model is considered clean.
+func (req changePasswordRequest) Validate() error {
+	if err := errs.Check(req); err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+
+	return nil
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refreshToken" validate:"required"`
+}
+
+// Decode implements the decoder interface.
+func (req *refreshRequest) Decode(data []byte) error {
+	return json.Unmarshal(data, req)
+}
+
+// Validate checks the data in the model is considered clean.
+func (req refreshRequest) Validate() error {
+	if err := errs.Check(req); err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+
+	return nil
+}
+
+type sessionToken struct {
+	SessionID        string `json:"sessionID"`
+	Token            string `json:"token"`
+	ExpiresAt        string `json:"expiresAt"`
+	RefreshToken     string `json:"refreshToken"`
+	RefreshExpiresAt string `json:"refreshExpiresAt"`
+}
+
+// Encode implements the encoder interface.
+func (t sessionToken) Encode() ([]byte, string, error) {
+	data, err := json.Marshal(t)
+	return data, "application/json", err
+}
+
+type revoked struct {
+	Revoked int `json:"revoked"`
+}
+
+// Encode implements the encoder interface.
+func (r revoked) Encode() ([]byte, string, error) {
+	data, err := json.Marshal(r)
+	return data, "application/json", err
+}
+
+// Session represents a login on a device.
+type Session struct {
+	ID           string `json:"id"`
+	Device       string `json:"device"`
+	DateCreated  string `json:"dateCreated"`
+	DateLastUsed string `json:"dateLastUsed"`
+	DateExpires  string `json:"dateExpires"`
+}
+
+func toAppSessions(sessions []sessionbus.Session) []Session {
+	app := make([]Session, len(sessions))
+	for i, sess := range sessions {
+		app[i] = Session{
+			ID:           extid.Encode(sess.ID),
+			Device:       sess.Device,
+			DateCreated:  sess.DateCreated.Format(time.RFC3339),
+			DateLastUsed: sess.DateLastUsed.Format(time.RFC3339),
+			DateExpires:  sess.DateExpires.Format(time.RFC3339),
+		}
+	}
+
+	return app
+}
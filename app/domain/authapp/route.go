@@ -2,29 +2,47 @@ package authapp
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/ardanlabs/service/app/sdk/auth"
 	"github.com/ardanlabs/service/app/sdk/mid"
+	"github.com/ardanlabs/service/business/domain/apikeybus"
+	"github.com/ardanlabs/service/business/domain/sessionbus"
 	"github.com/ardanlabs/service/business/domain/userbus"
 	"github.com/ardanlabs/service/foundation/web"
 )
 
 // Config contains all the mandatory systems required by handlers.
 type Config struct {
-	UserBus userbus.Business
-	Auth    *auth.Auth
+	UserBus       userbus.Business
+	APIKeyBus     *apikeybus.Business
+	SessionBus    *sessionbus.Business
+	Auth          *auth.Auth
+	LoginThrottle mid.LoginThrottle
+	AccessTTL     time.Duration
 }
 
 // Routes adds specific routes for this group.
 func Routes(app *web.App, cfg Config) {
 	const version = "v1"
 
-	bearer := mid.Bearer(cfg.Auth)
-	basic := mid.Basic(cfg.Auth, cfg.UserBus)
+	bearer := mid.Bearer(cfg.Auth, cfg.APIKeyBus)
+	basic := mid.Basic(cfg.Auth, cfg.UserBus, cfg.LoginThrottle)
 
-	api := newApp(cfg.Auth)
+	api := newApp(cfg.Auth, cfg.UserBus, cfg.SessionBus, cfg.AccessTTL)
 
 	app.HandlerFunc(http.MethodGet, version, "/auth/token/{kid}", api.token, basic)
 	app.HandlerFunc(http.MethodGet, version, "/auth/authenticate", api.authenticate, bearer)
 	app.HandlerFunc(http.MethodPost, version, "/auth/authorize", api.authorize)
+	app.HandlerFunc(http.MethodPost, version, "/auth/breakglass/{kid}", api.breakGlass)
+
+	if cfg.SessionBus != nil {
+		app.HandlerFunc(http.MethodPost, version, "/auth/sessions/{kid}", api.createSession, basic)
+		app.HandlerFunc(http.MethodPost, version, "/auth/refresh/{kid}", api.refreshSession)
+		app.HandlerFunc(http.MethodPost, version, "/auth/logout", api.logout)
+		app.HandlerFunc(http.MethodPost, version, "/auth/logout/all", api.logoutAll, bearer)
+		app.HandlerFunc(http.MethodPost, version, "/auth/password/{kid}", api.changePassword, bearer)
+		app.HandlerFunc(http.MethodGet, version, "/auth/sessions", api.querySessions, bearer)
+		app.HandlerFunc(http.MethodDelete, version, "/auth/sessions/{session_id}", api.revokeSession, bearer)
+	}
 }
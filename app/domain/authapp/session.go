@@ -0,0 +1,290 @@
+package authapp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ardanlabs/service/app/sdk/auth"
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/extid"
+	"github.com/ardanlabs/service/app/sdk/mid"
+	"github.com/ardanlabs/service/app/sdk/query"
+	"github.com/ardanlabs/service/business/domain/sessionbus"
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/foundation/clock"
+	"github.com/ardanlabs/service/foundation/web"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func (a *app) createSession(ctx context.Context, r *http.Request) web.Encoder {
+	kid := web.Param(r, "kid")
+	if kid == "" {
+		return errs.NewFieldErrors("kid", errors.New("missing kid"))
+	}
+
+	var req newSessionRequest
+	if r.ContentLength != 0 {
+		if err := web.Decode(r, &req); err != nil {
+			return errs.New(errs.InvalidArgument, err)
+		}
+	}
+
+	if req.Device == "" {
+		req.Device = r.UserAgent()
+	}
+
+	// The Basic middleware function authenticated the user.
+	userID, err := mid.GetUserID(ctx)
+	if err != nil {
+		return errs.New(errs.Unauthenticated, err)
+	}
+
+	ns := sessionbus.NewSession{
+		UserID: userID,
+		Device: req.Device,
+	}
+
+	sess, refreshToken, err := a.sessionBus.Create(ctx, ns)
+	if err != nil {
+		return errs.Newf(errs.Internal, "create: %s", err)
+	}
+
+	return a.sessionToken(kid, mid.GetClaims(ctx), sess, refreshToken)
+}
+
+func (a *app) refreshSession(ctx context.Context, r *http.Request) web.Encoder {
+	kid := web.Param(r, "kid")
+	if kid == "" {
+		return errs.NewFieldErrors("kid", errors.New("missing kid"))
+	}
+
+	var req refreshRequest
+	if err := web.Decode(r, &req); err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	sess, refreshToken, err := a.sessionBus.Refresh(ctx, req.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, sessionbus.ErrInvalidToken),
+			errors.Is(err, sessionbus.ErrExpired),
+			errors.Is(err, sessionbus.ErrRevoked),
+			errors.Is(err, sessionbus.ErrTokenReused),
+			errors.Is(err, sessionbus.ErrUserDisabled):
+			return errs.New(errs.Unauthenticated, err)
+		default:
+			return errs.Newf(errs.Internal, "refresh: %s", err)
+		}
+	}
+
+	usr, err := a.userBus.QueryByID(ctx, sess.UserID)
+	if err != nil {
+		return errs.Newf(errs.Internal, "querybyid: userID[%s]: %s", sess.UserID, err)
+	}
+
+	claims := a.auth.NewClaims(usr, auth.WithAuthentication(sess.DateCreated))
+
+	// The methods used to start the session aren't kept, so a refreshed token
+	// can't satisfy a route that requires a one-time code.
+
+	return a.sessionToken(kid, claims, sess, refreshToken)
+}
+
+// changePassword replaces the caller's password. Every other session of the
+// user is revoked by the change. When the caller presents the refresh token
+// of their session it's kept and refreshed, otherwise a new one is started.
+func (a *app) changePassword(ctx context.Context, r *http.Request) web.Encoder {
+	kid := web.Param(r, "kid")
+	if kid == "" {
+		return errs.NewFieldErrors("kid", errors.New("missing kid"))
+	}
+
+	var req changePasswordRequest
+	if err := web.Decode(r, &req); err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	if req.Device == "" {
+		req.Device = r.UserAgent()
+	}
+
+	userID, err := mid.GetUserID(ctx)
+	if err != nil {
+		return errs.New(errs.Unauthenticated, err)
+	}
+
+	if req.RefreshToken != "" {
+		sess, err := a.sessionBus.QueryByToken(ctx, req.RefreshToken)
+		if err != nil {
+			if errors.Is(err, sessionbus.ErrInvalidToken) {
+				return errs.New(errs.Unauthenticated, err)
+			}
+			return errs.Newf(errs.Internal, "querybytoken: %s", err)
+		}
+
+		if sess.UserID != userID || sess.Revoked() {
+			return errs.New(errs.Unauthenticated, sessionbus.ErrInvalidToken)
+		}
+
+		ctx = sessionbus.WithCurrentSession(ctx, sess.ID)
+	}
+
+	if err := a.userBus.ChangePassword(ctx, userID, req.CurrentPassword, req.NewPassword); err != nil {
+		if errors.Is(err, userbus.ErrAuthenticationFailure) {
+			return errs.New(errs.Unauthenticated, userbus.ErrAuthenticationFailure)
+		}
+		return errs.NewError(err)
+	}
+
+	var sess sessionbus.Session
+	var refreshToken string
+
+	switch req.RefreshToken {
+	case "":
+		ns := sessionbus.NewSession{
+			UserID: userID,
+			Device: req.Device,
+		}
+
+		sess, refreshToken, err = a.sessionBus.Create(ctx, ns)
+		if err != nil {
+			return errs.Newf(errs.Internal, "create: %s", err)
+		}
+
+	default:
+		sess, refreshToken, err = a.sessionBus.Refresh(ctx, req.RefreshToken)
+		if err != nil {
+			return errs.Newf(errs.Internal, "refresh: %s", err)
+		}
+	}
+
+	usr, err := a.userBus.QueryByID(ctx, userID)
+	if err != nil {
+		return errs.Newf(errs.Internal, "querybyid: userID[%s]: %s", userID, err)
+	}
+
+	claims := a.auth.NewClaims(usr, auth.WithAuthentication(clock.Now(), auth.MethodPassword))
+
+	return a.sessionToken(kid, claims, sess, refreshToken)
+}
+
+func (a *app) logout(ctx context.Context, r *http.Request) web.Encoder {
+	var req refreshRequest
+	if err := web.Decode(r, &req); err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	if err := a.sessionBus.RevokeByToken(ctx, req.RefreshToken); err != nil {
+		if errors.Is(err, sessionbus.ErrInvalidToken) {
+			return errs.New(errs.Unauthenticated, err)
+		}
+		return errs.Newf(errs.Internal, "revokebytoken: %s", err)
+	}
+
+	return nil
+}
+
+func (a *app) logoutAll(ctx context.Context, r *http.Request) web.Encoder {
+	userID, err := mid.GetUserID(ctx)
+	if err != nil {
+		return errs.New(errs.Unauthenticated, err)
+	}
+
+	n, err := a.sessionBus.RevokeAll(ctx, userID)
+	if err != nil {
+		return errs.Newf(errs.Internal, "revokeall: %s", err)
+	}
+
+	return revoked{Revoked: n}
+}
+
+func (a *app) querySessions(ctx context.Context, r *http.Request) web.Encoder {
+	values := r.URL.Query()
+
+	page, err := page.Parse(values.Get("page"), values.Get("rows"))
+	if err != nil {
+		return errs.NewFieldErrors("page", err)
+	}
+
+	userID, err := mid.GetUserID(ctx)
+	if err != nil {
+		return errs.New(errs.Unauthenticated, err)
+	}
+
+	now := clock.Now()
+
+	filter := sessionbus.QueryFilter{
+		UserID:   &userID,
+		ActiveAt: &now,
+	}
+
+	sessions, err := a.sessionBus.Query(ctx, filter, sessionbus.DefaultOrderBy, page)
+	if err != nil {
+		return errs.Newf(errs.Internal, "query: %s", err)
+	}
+
+	total, err := a.sessionBus.Count(ctx, filter)
+	if err != nil {
+		return errs.Newf(errs.Internal, "count: %s", err)
+	}
+
+	return query.NewResult(toAppSessions(sessions), total, page)
+}
+
+func (a *app) revokeSession(ctx context.Context, r *http.Request) web.Encoder {
+	sessionID, err := extid.Decode(web.Param(r, "session_id"))
+	if err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	userID, err := mid.GetUserID(ctx)
+	if err != nil {
+		return errs.New(errs.Unauthenticated, err)
+	}
+
+	sess, err := a.sessionBus.QueryByID(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, sessionbus.ErrNotFound) {
+			return errs.New(errs.NotFound, err)
+		}
+		return errs.Newf(errs.Internal, "querybyid: sessionID[%s]: %s", sessionID, err)
+	}
+
+	// Another user's session is reported as not found so the caller can't
+	// learn which session ids exist.
+	if sess.UserID != userID {
+		return errs.New(errs.NotFound, sessionbus.ErrNotFound)
+	}
+
+	if _, err := a.sessionBus.Revoke(ctx, sess); err != nil {
+		return errs.Newf(errs.Internal, "revoke: sessionID[%s]: %s", sessionID, err)
+	}
+
+	return nil
+}
+
+// sessionToken generates a short lived access token for the session and
+// returns it along with the refresh token.
+func (a *app) sessionToken(kid string, claims auth.Claims, sess sessionbus.Session, refreshToken string) web.Encoder {
+	now := clock.Now().UTC()
+	expiresAt := now.Add(a.accessTTL)
+
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.ExpiresAt = jwt.NewNumericDate(expiresAt)
+
+	tkn, err := a.auth.GenerateToken(kid, claims)
+	if err != nil {
+		return errs.New(errs.Internal, err)
+	}
+
+	return sessionToken{
+		SessionID:        extid.Encode(sess.ID),
+		Token:            tkn,
+		ExpiresAt:        expiresAt.Format(time.RFC3339),
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: sess.DateExpires.Format(time.RFC3339),
+	}
+}
@@ -31,7 +31,8 @@ func newApp(build string, log *logger.Logger, db *sqlx.DB) *app {
 
 // readiness checks if the database is ready and if not will return a 500 status.
 // Do not respond by just returning an error because further up in the call
-// stack it will interpret that as a non-trusted error.
+// stack it will interpret that as a non-trusted error. A ready service
+// reports the state of its connection pool.
 func (a *app) readiness(ctx context.Context, r *http.Request) web.Encoder {
 	ctx, cancel := context.WithTimeout(ctx, time.Second)
 	defer cancel()
@@ -41,7 +42,12 @@ func (a *app) readiness(ctx context.Context, r *http.Request) web.Encoder {
 		return errs.New(errs.Internal, err)
 	}
 
-	return nil
+	info := Readiness{
+		Status: "ok",
+		DBPool: toAppPool(sqldb.Stats(a.db)),
+	}
+
+	return info
 }
 
 // liveness returns simple status info if the service is alive. If the
@@ -63,6 +69,7 @@ func (a *app) liveness(ctx context.Context, r *http.Request) web.Encoder {
 		Node:       os.Getenv("KUBERNETES_NODE_NAME"),
 		Namespace:  os.Getenv("KUBERNETES_NAMESPACE"),
 		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		DBPool:     toAppPool(sqldb.Stats(a.db)),
 	}
 
 	// This handler provides a free timer loop.
@@ -1,6 +1,10 @@
 package checkapp
 
-import "encoding/json"
+import (
+	"encoding/json"
+
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+)
 
 // Info represents information about the service.
 type Info struct {
@@ -12,6 +16,7 @@ type Info struct {
 	Node       string `json:"node,omitempty"`
 	Namespace  string `json:"namespace,omitempty"`
 	GOMAXPROCS int    `json:"GOMAXPROCS,omitempty"`
+	DBPool     Pool   `json:"dbPool"`
 }
 
 // Encode implements the encoder interface.
@@ -19,3 +24,42 @@ func (app Info) Encode() ([]byte, string, error) {
 	data, err := json.Marshal(app)
 	return data, "application/json", err
 }
+
+// Readiness represents the readiness of the service.
+type Readiness struct {
+	Status string `json:"status"`
+	DBPool Pool   `json:"dbPool"`
+}
+
+// Encode implements the encoder interface.
+func (app Readiness) Encode() ([]byte, string, error) {
+	data, err := json.Marshal(app)
+	return data, "application/json", err
+}
+
+// Pool represents the state of the database connection pool.
+type Pool struct {
+	MaxOpen           int    `json:"maxOpen"`
+	Open              int    `json:"open"`
+	InUse             int    `json:"inUse"`
+	Idle              int    `json:"idle"`
+	WaitCount         int64  `json:"waitCount"`
+	WaitDuration      string `json:"waitDuration"`
+	MaxIdleClosed     int64  `json:"maxIdleClosed"`
+	MaxIdleTimeClosed int64  `json:"maxIdleTimeClosed"`
+	MaxLifetimeClosed int64  `json:"maxLifetimeClosed"`
+}
+
+func toAppPool(stats sqldb.PoolStats) Pool {
+	return Pool{
+		MaxOpen:           stats.MaxOpen,
+		Open:              stats.Open,
+		InUse:             stats.InUse,
+		Idle:              stats.Idle,
+		WaitCount:         stats.WaitCount,
+		WaitDuration:      stats.WaitDuration.String(),
+		MaxIdleClosed:     stats.MaxIdleClosed,
+		MaxIdleTimeClosed: stats.MaxIdleTimeClosed,
+		MaxLifetimeClosed: stats.MaxLifetimeClosed,
+	}
+}
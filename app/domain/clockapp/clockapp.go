@@ -0,0 +1,47 @@
+// Package clockapp maintains the app layer api for skewing the service
+// clock in staging environments.
+package clockapp
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/foundation/clock"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/web"
+)
+
+type app struct {
+	log *logger.Logger
+}
+
+func newApp(log *logger.Logger) *app {
+	return &app{
+		log: log,
+	}
+}
+
+func (a *app) query(ctx context.Context, r *http.Request) web.Encoder {
+	return toAppClock()
+}
+
+func (a *app) skew(ctx context.Context, r *http.Request) web.Encoder {
+	var app Skew
+	if err := web.Decode(r, &app); err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	offset, err := app.offset()
+	if err != nil {
+		return errs.NewFieldErrors("offset", err)
+	}
+
+	if err := clock.Skew(offset); err != nil {
+		return errs.New(errs.FailedPrecondition, err)
+	}
+
+	a.log.Info(ctx, "clock skewed", "offset", offset, "now", clock.Now())
+
+	return toAppClock()
+}
@@ -0,0 +1,56 @@
+package clockapp
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ardanlabs/service/foundation/clock"
+)
+
+// Clock represents the current time as the service sees it.
+type Clock struct {
+	Now    string `json:"now"`
+	System string `json:"system"`
+	Offset string `json:"offset"`
+}
+
+// Encode implements the encoder interface.
+func (app Clock) Encode() ([]byte, string, error) {
+	data, err := json.Marshal(app)
+	return data, "application/json", err
+}
+
+func toAppClock() Clock {
+	offset := clock.Offset()
+	now := time.Now()
+
+	return Clock{
+		Now:    now.Add(offset).Format(time.RFC3339),
+		System: now.Format(time.RFC3339),
+		Offset: offset.String(),
+	}
+}
+
+// =============================================================================
+
+// Skew defines the data needed to skew the clock. The offset is a Go
+// duration, like 72h or -30m, and an offset of 0s restores the system
+// clock.
+type Skew struct {
+	Offset string `json:"offset" validate:"required"`
+}
+
+// Decode implements the decoder interface.
+func (app *Skew) Decode(data []byte) error {
+	return json.Unmarshal(data, app)
+}
+
+func (app Skew) offset() (time.Duration, error) {
+	d, err := time.ParseDuration(app.Offset)
+	if err != nil {
+		return 0, fmt.Errorf("parse: %w", err)
+	}
+
+	return d, nil
+}
@@ -0,0 +1,37 @@
+package clockapp
+
+import (
+	"net/http"
+
+	"github.com/ardanlabs/service/app/sdk/auth"
+	"github.com/ardanlabs/service/app/sdk/authclient"
+	"github.com/ardanlabs/service/app/sdk/mid"
+	"github.com/ardanlabs/service/foundation/clock"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/web"
+)
+
+// Config contains all the mandatory systems required by handlers.
+type Config struct {
+	Log        *logger.Logger
+	AuthClient *authclient.Client
+	Enabled    bool
+}
+
+// Routes adds specific routes for this group. The routes are only bound in
+// builds with the staging tag when the configuration enables them.
+func Routes(app *web.App, cfg Config) {
+	const version = "v1"
+
+	if !clock.Skewable || !cfg.Enabled {
+		return
+	}
+
+	authen := mid.Authenticate(cfg.AuthClient)
+	ruleAdmin := mid.Authorize(cfg.AuthClient, auth.RuleAdminOnly)
+
+	api := newApp(cfg.Log)
+
+	app.HandlerFunc(http.MethodGet, version, "/admin/clock", api.query, authen, ruleAdmin)
+	app.HandlerFunc(http.MethodPut, version, "/admin/clock", api.skew, authen, ruleAdmin)
+}
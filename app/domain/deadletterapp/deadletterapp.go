@@ -0,0 +1,117 @@
+// Package deadletterapp maintains the app layer api for the dead letters of
+// the delegate.
+package deadletterapp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/extid"
+	"github.com/ardanlabs/service/app/sdk/query"
+	"github.com/ardanlabs/service/business/sdk/delegate"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/foundation/web"
+)
+
+type app struct {
+	delegate *delegate.Delegate
+}
+
+func newApp(delegate *delegate.Delegate) *app {
+	return &app{
+		delegate: delegate,
+	}
+}
+
+func (a *app) query(ctx context.Context, r *http.Request) web.Encoder {
+	values := r.URL.Query()
+
+	page, err := page.Parse(values.Get("page"), values.Get("rows"))
+	if err != nil {
+		return errs.NewFieldErrors("page", err)
+	}
+
+	dls, err := a.delegate.QueryDeadLetters(ctx, page)
+	if err != nil {
+		return toAppError("query", err)
+	}
+
+	total, err := a.delegate.CountDeadLetters(ctx)
+	if err != nil {
+		return toAppError("count", err)
+	}
+
+	return query.NewResult(toAppDeadLetters(dls), total, page)
+}
+
+func (a *app) queryByID(ctx context.Context, r *http.Request) web.Encoder {
+	dl, err := a.deadLetter(ctx, r)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	return toAppDeadLetter(dl)
+}
+
+// replay calls the function of the dead letter again. The dead letter is
+// gone when the call succeeds. When it fails again the dead letter is kept
+// and the error of the call is returned.
+func (a *app) replay(ctx context.Context, r *http.Request) web.Encoder {
+	dl, err := a.deadLetter(ctx, r)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	updDL, err := a.delegate.Replay(ctx, dl)
+	if err != nil {
+		if updDL.ID == dl.ID {
+			return errs.Newf(errs.Aborted, "replay: deadLetterID[%s]: attempts[%d]: %s", dl.ID, updDL.Attempts, updDL.Error)
+		}
+		return toAppError("replay", err)
+	}
+
+	return nil
+}
+
+func (a *app) delete(ctx context.Context, r *http.Request) web.Encoder {
+	dl, err := a.deadLetter(ctx, r)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	if err := a.delegate.Discard(ctx, dl); err != nil {
+		return toAppError("discard", err)
+	}
+
+	return nil
+}
+
+// deadLetter looks up the dead letter identified in the request path.
+func (a *app) deadLetter(ctx context.Context, r *http.Request) (delegate.DeadLetter, error) {
+	id, err := extid.Decode(web.Param(r, "dead_letter_id"))
+	if err != nil {
+		return delegate.DeadLetter{}, errs.New(errs.InvalidArgument, err)
+	}
+
+	dl, err := a.delegate.QueryDeadLetterByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, delegate.ErrDeadLetterNotFound) {
+			return delegate.DeadLetter{}, errs.New(errs.NotFound, delegate.ErrDeadLetterNotFound)
+		}
+		return delegate.DeadLetter{}, toAppError("querybyid", err)
+	}
+
+	return dl, nil
+}
+
+// toAppError returns the business errors of the delegate with their codes,
+// and any other error as an internal error.
+func toAppError(op string, err error) *errs.Error {
+	if appErr, ok := errs.FromBus(err); ok {
+		return appErr
+	}
+
+	return errs.Newf(errs.Internal, "%s: %s", op, err)
+}
@@ -0,0 +1,58 @@
+package deadletterapp
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/ardanlabs/service/app/sdk/extid"
+	"github.com/ardanlabs/service/business/sdk/delegate"
+)
+
+// DeadLetter represents a call to a delegate function that failed.
+type DeadLetter struct {
+	ID          string            `json:"id"`
+	Handler     string            `json:"handler"`
+	Domain      string            `json:"domain"`
+	Action      string            `json:"action"`
+	Params      json.RawMessage   `json:"params,omitempty"`
+	Changes     []delegate.Change `json:"changes,omitempty"`
+	Error       string            `json:"error"`
+	Attempts    int               `json:"attempts"`
+	DateCreated string            `json:"dateCreated"`
+	DateUpdated string            `json:"dateUpdated"`
+}
+
+// Encode implements the encoder interface.
+func (app DeadLetter) Encode() ([]byte, string, error) {
+	data, err := json.Marshal(app)
+	return data, "application/json", err
+}
+
+func toAppDeadLetter(dl delegate.DeadLetter) DeadLetter {
+	app := DeadLetter{
+		ID:          extid.Encode(dl.ID),
+		Handler:     dl.Handler,
+		Domain:      dl.Data.Domain,
+		Action:      dl.Data.Action,
+		Changes:     dl.Data.Changes,
+		Error:       dl.Error,
+		Attempts:    dl.Attempts,
+		DateCreated: dl.DateCreated.Format(time.RFC3339),
+		DateUpdated: dl.DateUpdated.Format(time.RFC3339),
+	}
+
+	if json.Valid(dl.Data.RawParams) {
+		app.Params = dl.Data.RawParams
+	}
+
+	return app
+}
+
+func toAppDeadLetters(dls []delegate.DeadLetter) []DeadLetter {
+	app := make([]DeadLetter, len(dls))
+	for i, dl := range dls {
+		app[i] = toAppDeadLetter(dl)
+	}
+
+	return app
+}
@@ -0,0 +1,34 @@
+package deadletterapp
+
+import (
+	"net/http"
+
+	"github.com/ardanlabs/service/app/sdk/auth"
+	"github.com/ardanlabs/service/app/sdk/authclient"
+	"github.com/ardanlabs/service/app/sdk/mid"
+	"github.com/ardanlabs/service/business/sdk/delegate"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/web"
+)
+
+// Config contains all the mandatory systems required by handlers.
+type Config struct {
+	Log        *logger.Logger
+	Delegate   *delegate.Delegate
+	AuthClient *authclient.Client
+}
+
+// Routes adds specific routes for this group.
+func Routes(app *web.App, cfg Config) {
+	const version = "v1"
+
+	authen := mid.Authenticate(cfg.AuthClient)
+	ruleAdmin := mid.Authorize(cfg.AuthClient, auth.RuleAdminOnly)
+
+	api := newApp(cfg.Delegate)
+
+	app.HandlerFunc(http.MethodGet, version, "/deadletters", api.query, authen, ruleAdmin)
+	app.HandlerFunc(http.MethodGet, version, "/deadletters/{dead_letter_id}", api.queryByID, authen, ruleAdmin)
+	app.HandlerFunc(http.MethodPost, version, "/deadletters/{dead_letter_id}/replay", api.replay, authen, ruleAdmin)
+	app.HandlerFunc(http.MethodDelete, version, "/deadletters/{dead_letter_id}", api.delete, authen, ruleAdmin)
+}
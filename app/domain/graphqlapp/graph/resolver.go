@@ -0,0 +1,24 @@
+// Package graph holds the graphql executor generated by gqlgen from the
+// schemas of the domains, and the resolver that ties the domains into it.
+package graph
+
+//go:generate go run github.com/99designs/gqlgen@v0.17.73 generate
+
+import (
+	"github.com/ardanlabs/service/app/domain/userapp"
+)
+
+// Resolver provides the resolvers of each domain to the executor.
+type Resolver struct {
+	Users *userapp.GraphQL
+}
+
+// Query returns the resolver for the root query fields.
+func (r *Resolver) Query() QueryResolver {
+	return r.Users
+}
+
+// User returns the resolver for the fields of a user.
+func (r *Resolver) User() UserResolver {
+	return r.Users
+}
@@ -0,0 +1,124 @@
+// Package graphqlapp maintains the app layer api for the graphql endpoint.
+// The executor is generated by gqlgen from the schemas of the domains, see
+// the graph package, and this package serves it.
+package graphqlapp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/executor"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/web"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// maxComplexity limits the number of fields a single request can select.
+// Nested selections count toward it, so it also bounds how deep a chain of
+// managers can be followed.
+const maxComplexity = 100
+
+type app struct {
+	log       *logger.Logger
+	exec      *executor.Executor
+	onRequest []func(ctx context.Context) context.Context
+}
+
+func newApp(log *logger.Logger, schema graphql.ExecutableSchema, onRequest []func(ctx context.Context) context.Context) *app {
+	a := app{
+		log:       log,
+		exec:      executor.New(schema),
+		onRequest: onRequest,
+	}
+
+	a.exec.Use(extension.FixedComplexityLimit(maxComplexity))
+	a.exec.SetErrorPresenter(a.presentError)
+	a.exec.SetRecoverFunc(a.recoverPanic)
+
+	return &a
+}
+
+// execute runs the request against the schema. Errors are reported in the
+// response the way graphql clients expect, next to the data that could be
+// resolved, so the response is sent with a 200 unless the request can't be
+// decoded.
+func (a *app) execute(ctx context.Context, r *http.Request) web.Encoder {
+	ctx = graphql.StartOperationTrace(ctx)
+	start := graphql.Now()
+
+	var req request
+	if err := web.Decode(r, &req); err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	params := graphql.RawParams{
+		Query:         req.Query,
+		OperationName: req.OperationName,
+		Variables:     req.Variables,
+		Headers:       r.Header,
+		ReadTime: graphql.TraceTiming{
+			Start: start,
+			End:   graphql.Now(),
+		},
+	}
+
+	rc, gerrs := a.exec.CreateOperationContext(ctx, &params)
+	if gerrs != nil {
+		return response(*a.exec.DispatchError(graphql.WithOperationContext(ctx, rc), gerrs))
+	}
+
+	for _, fn := range a.onRequest {
+		ctx = fn(ctx)
+	}
+
+	responses, ctx := a.exec.DispatchOperation(ctx, rc)
+
+	return response(*responses(ctx))
+}
+
+// presentError turns an error returned by a resolver into the message and
+// code the client sees, the same as the errors middleware does. Errors
+// found parsing and validating the request are meant for the client and
+// are left as they are.
+func (a *app) presentError(ctx context.Context, err error) *gqlerror.Error {
+	gerr := graphql.DefaultErrorPresenter(ctx, err)
+	if gerr.Err == nil {
+		return gerr
+	}
+
+	var appErr *errs.Error
+	if !errors.As(gerr.Err, &appErr) {
+		var ok bool
+		if appErr, ok = errs.FromBus(gerr.Err); !ok {
+			appErr = errs.Newf(errs.Internal, "Internal Server Error")
+		}
+	}
+
+	a.log.Error(ctx, "handled error during graphql request",
+		"err", gerr.Err,
+		"path", gerr.Path.String(),
+		"source_err_file", path.Base(appErr.FileName),
+		"source_err_func", path.Base(appErr.FuncName))
+
+	if appErr.Code == errs.InternalOnlyLog {
+		appErr = errs.Newf(errs.Internal, "Internal Server Error")
+	}
+
+	gerr.Message = appErr.Message
+	gerr.Extensions = map[string]any{
+		"code": appErr.Code.String(),
+	}
+
+	return gerr
+}
+
+// recoverPanic reports a panic in a resolver as an error for the field
+// instead of failing the whole request.
+func (a *app) recoverPanic(ctx context.Context, rec any) error {
+	return errs.Newf(errs.InternalOnlyLog, "PANIC [%v]", rec)
+}
@@ -0,0 +1,293 @@
+package graphqlapp_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/service/app/domain/graphqlapp"
+	"github.com/ardanlabs/service/app/domain/graphqlapp/graph"
+	"github.com/ardanlabs/service/app/domain/userapp"
+	"github.com/ardanlabs/service/app/sdk/auth"
+	"github.com/ardanlabs/service/app/sdk/authclient"
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/domain/userbus/mocks"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/types/name"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/web"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// authService stands in for the auth service. Every caller is an admin.
+type authService struct{}
+
+func (as authService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/v1/auth/authenticate":
+		resp := authclient.AuthenticateResp{
+			UserID: uuid.New(),
+			Claims: auth.Claims{Roles: []string{role.Admin.String()}},
+		}
+		json.NewEncoder(w).Encode(resp)
+
+	case "/v1/auth/authorize":
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "unexpected call", http.StatusInternalServerError)
+	}
+}
+
+type gqlError struct {
+	Message    string         `json:"message"`
+	Extensions map[string]any `json:"extensions"`
+}
+
+type gqlResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []gqlError      `json:"errors"`
+}
+
+func newUser(n int, managerID uuid.UUID) userbus.User {
+	usr := userbus.User{
+		ID:          uuid.New(),
+		Name:        name.MustParse(fmt.Sprintf("User %d", n)),
+		Email:       mail.Address{Address: fmt.Sprintf("user%d@example.com", n)},
+		Roles:       []role.Role{role.User},
+		Enabled:     true,
+		DateCreated: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		DateUpdated: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		Version:     1,
+	}
+
+	if managerID != uuid.Nil {
+		usr.ManagerID = uuid.NullUUID{UUID: managerID, Valid: true}
+	}
+
+	return usr
+}
+
+func Test_GraphQL(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.New(&buf, logger.LevelInfo, "TEST", func(context.Context) string { return "" })
+
+	srv := httptest.NewServer(authService{})
+	defer srv.Close()
+
+	authClient := authclient.New(log, srv.URL, authclient.WithClient(srv.Client()))
+
+	// The first manager is on the page, the second one isn't.
+	mgr1 := newUser(1, uuid.Nil)
+	mgr2 := newUser(2, uuid.Nil)
+	usr3 := newUser(3, mgr1.ID)
+	usr4 := newUser(4, mgr2.ID)
+	usr5 := newUser(5, mgr2.ID)
+
+	usrs := []userbus.User{mgr1, usr3, usr4, usr5}
+
+	// errPanic makes the query panic, which must not take the request down
+	// or show the panic to the client.
+	errPanic := errors.New("panic")
+
+	var queryErr error
+	userBus := mocks.Business{
+		QueryWithCountFunc: func(ctx context.Context, filter userbus.QueryFilter, orderBy order.By, page page.Page) ([]userbus.User, int, error) {
+			switch {
+			case errors.Is(queryErr, errPanic):
+				panic(queryErr)
+			case queryErr != nil:
+				return nil, 0, queryErr
+			}
+			return usrs, len(usrs), nil
+		},
+		QueryByIDsFunc: func(ctx context.Context, userIDs []uuid.UUID) ([]userbus.User, error) {
+			var found []userbus.User
+			for _, id := range userIDs {
+				if id == mgr2.ID {
+					found = append(found, mgr2)
+				}
+			}
+			return found, nil
+		},
+	}
+
+	users := userapp.NewGraphQL(userapp.Config{
+		Log:        log,
+		UserBus:    &userBus,
+		AuthClient: authClient,
+	})
+
+	app := web.NewApp(log.Info, noop.NewTracerProvider().Tracer("test"))
+
+	graphqlapp.Routes(app, graphqlapp.Config{
+		Log: log,
+		Schema: graph.NewExecutableSchema(graph.Config{
+			Resolvers: &graph.Resolver{Users: users},
+		}),
+		AuthClient: authClient,
+		OnRequest:  []func(ctx context.Context) context.Context{users.WithLoader},
+	})
+
+	execute := func(t *testing.T, query string, variables map[string]any) gqlResponse {
+		body, err := json.Marshal(map[string]any{"query": query, "variables": variables})
+		if err != nil {
+			t.Fatalf("Should be able to marshal the request : %s", err)
+		}
+
+		r := httptest.NewRequest(http.MethodPost, "/v1/graphql", bytes.NewReader(body))
+		r.Header.Set("authorization", "Bearer admin")
+
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, r)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Should receive a status code of 200 : got %d : %s", w.Code, w.Body.String())
+		}
+
+		var resp gqlResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Should be able to unmarshal the response : %s", err)
+		}
+
+		return resp
+	}
+
+	t.Run("mapping", func(t *testing.T) {
+		userBus.Reset()
+
+		const query = `query Users($rows: Int) {
+			users(filter: {name: "User", roles: ["USER", "ADMIN"], enabled: true}, orderBy: [{field: "name", direction: "DESC"}], page: 2, rows: $rows) {
+				total page rowsPerPage
+				items { id }
+			}
+		}`
+
+		resp := execute(t, query, map[string]any{"rows": 4})
+		if len(resp.Errors) != 0 {
+			t.Fatalf("Should execute without errors : %v", resp.Errors)
+		}
+
+		calls := userBus.Calls()
+		if len(calls) != 1 || calls[0].Method != "QueryWithCount" {
+			t.Fatalf("Should query the users once : got %v", calls)
+		}
+
+		filter := calls[0].Args[0].(userbus.QueryFilter)
+		if filter.Name == nil || filter.Name.String() != "User" {
+			t.Errorf("Should filter by name : got %v", filter.Name)
+		}
+		if len(filter.Roles) != 2 || filter.Roles[0] != role.User || filter.Roles[1] != role.Admin {
+			t.Errorf("Should filter by roles : got %v", filter.Roles)
+		}
+		if filter.Enabled == nil || !*filter.Enabled {
+			t.Errorf("Should filter by enabled : got %v", filter.Enabled)
+		}
+
+		orderBy := calls[0].Args[1].(order.By)
+		if orderBy.Field != userbus.OrderByName || orderBy.Direction != order.DESC {
+			t.Errorf("Should order by name descending : got %v", orderBy)
+		}
+
+		pg := calls[0].Args[2].(page.Page)
+		if pg.Number() != 2 || pg.RowsPerPage() != 4 {
+			t.Errorf("Should ask for page 2 of 4 rows : got %s", pg)
+		}
+
+		exp := `{"users":{"total":4,"page":2,"rowsPerPage":4,"items":[` +
+			fmt.Sprintf(`{"id":%q},{"id":%q},{"id":%q},{"id":%q}`, mgr1.ID, usr3.ID, usr4.ID, usr5.ID) +
+			`]}}`
+		if string(resp.Data) != exp {
+			t.Errorf("Should get the page of users :\ngot %s\nexp %s", resp.Data, exp)
+		}
+	})
+
+	t.Run("dataloader", func(t *testing.T) {
+		userBus.Reset()
+
+		resp := execute(t, `{ users { items { id manager { id name } } } }`, nil)
+		if len(resp.Errors) != 0 {
+			t.Fatalf("Should execute without errors : %v", resp.Errors)
+		}
+
+		if n := userBus.CallCount("QueryByIDs"); n != 1 {
+			t.Fatalf("Should load the managers in a single call : got %d", n)
+		}
+
+		// The manager on the page is primed, only the other one is queried.
+		for _, c := range userBus.Calls() {
+			if c.Method != "QueryByIDs" {
+				continue
+			}
+
+			ids := c.Args[0].([]uuid.UUID)
+			if len(ids) != 1 || ids[0] != mgr2.ID {
+				t.Fatalf("Should only query the managers not on the page : got %v", ids)
+			}
+		}
+
+		exp := `{"users":{"items":[` +
+			fmt.Sprintf(`{"id":%q,"manager":null},`, mgr1.ID) +
+			fmt.Sprintf(`{"id":%q,"manager":{"id":%q,"name":"User 1"}},`, usr3.ID, mgr1.ID) +
+			fmt.Sprintf(`{"id":%q,"manager":{"id":%q,"name":"User 2"}},`, usr4.ID, mgr2.ID) +
+			fmt.Sprintf(`{"id":%q,"manager":{"id":%q,"name":"User 2"}}`, usr5.ID, mgr2.ID) +
+			`]}}`
+		if string(resp.Data) != exp {
+			t.Errorf("Should resolve the managers :\ngot %s\nexp %s", resp.Data, exp)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		deep := "id"
+		for range 100 {
+			deep = "id manager { " + deep + " }"
+		}
+
+		table := []struct {
+			name     string
+			query    string
+			queryErr error
+			message  string
+			code     string
+		}{
+			{name: "buserror", query: `{ users { total } }`, queryErr: userbus.ErrQueryTooExpensive, message: userbus.ErrQueryTooExpensive.Error(), code: "failed_precondition"},
+			{name: "internal", query: `{ users { total } }`, queryErr: errors.New("connection refused"), message: "querywithcount: connection refused", code: "internal"},
+			{name: "panic", query: `{ users { total } }`, queryErr: errPanic, message: "Internal Server Error", code: "internal"},
+			{name: "badargument", query: `{ users(orderBy: [{field: "password"}]) { total } }`, message: `[{"field":"order","error":"unknown order: password"}]`, code: "invalid_argument"},
+			{name: "validation", query: `{ users { password } }`, message: `Cannot query field "password" on type "UserPage".`, code: "GRAPHQL_VALIDATION_FAILED"},
+			{name: "complexity", query: `{ users { items { ` + deep + ` } } }`, code: "COMPLEXITY_LIMIT_EXCEEDED"},
+		}
+
+		for _, tt := range table {
+			t.Run(tt.name, func(t *testing.T) {
+				queryErr = tt.queryErr
+				defer func() { queryErr = nil }()
+
+				resp := execute(t, tt.query, nil)
+
+				if len(resp.Errors) != 1 {
+					t.Fatalf("Should get a single error : got %v", resp.Errors)
+				}
+
+				if tt.message != "" && resp.Errors[0].Message != tt.message {
+					t.Errorf("Should get the message %q : got %q", tt.message, resp.Errors[0].Message)
+				}
+
+				if code := resp.Errors[0].Extensions["code"]; code != tt.code {
+					t.Errorf("Should get the code %q : got %v", tt.code, code)
+				}
+
+			})
+		}
+	})
+}
@@ -0,0 +1,41 @@
+package graphqlapp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+type request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// Decode implements the decoder interface. Numbers in the variables are
+// kept as json.Number, which is what the executor expects for Int and Float.
+func (app *request) Decode(data []byte) error {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+
+	return d.Decode(app)
+}
+
+// Validate checks the data in the model is considered clean.
+func (app request) Validate() error {
+	if app.Query == "" {
+		return errors.New("query is required")
+	}
+
+	return nil
+}
+
+type response graphql.Response
+
+// Encode implements the encoder interface.
+func (app response) Encode() ([]byte, string, error) {
+	data, err := json.Marshal(app)
+	return data, "application/json", err
+}
@@ -0,0 +1,34 @@
+package graphqlapp
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/ardanlabs/service/app/sdk/authclient"
+	"github.com/ardanlabs/service/app/sdk/mid"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/web"
+)
+
+// Config contains all the mandatory systems required by handlers.
+type Config struct {
+	Log        *logger.Logger
+	Schema     graphql.ExecutableSchema
+	AuthClient *authclient.Client
+
+	// OnRequest is called with the context of every request before it is
+	// executed, to add the values the resolvers need, like their loaders.
+	OnRequest []func(ctx context.Context) context.Context
+}
+
+// Routes adds specific routes for this group.
+func Routes(app *web.App, cfg Config) {
+	const version = "v1"
+
+	authen := mid.Authenticate(cfg.AuthClient)
+
+	api := newApp(cfg.Log, cfg.Schema, cfg.OnRequest)
+
+	app.HandlerFunc(http.MethodPost, version, "/graphql", api.execute, authen)
+}
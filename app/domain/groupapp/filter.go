@@ -0,0 +1,93 @@
+package groupapp
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/extid"
+	"github.com/ardanlabs/service/business/domain/groupbus"
+)
+
+type queryParams struct {
+	Page             string
+	Rows             string
+	OrderBy          string
+	ID               string
+	Name             string
+	MemberID         string
+	StartCreatedDate string
+	EndCreatedDate   string
+}
+
+func parseQueryParams(r *http.Request) queryParams {
+	values := r.URL.Query()
+
+	filter := queryParams{
+		Page:             values.Get("page"),
+		Rows:             values.Get("rows"),
+		OrderBy:          values.Get("orderBy"),
+		ID:               values.Get("group_id"),
+		Name:             values.Get("name"),
+		MemberID:         values.Get("member_id"),
+		StartCreatedDate: values.Get("start_created_date"),
+		EndCreatedDate:   values.Get("end_created_date"),
+	}
+
+	return filter
+}
+
+func parseFilter(qp queryParams) (groupbus.QueryFilter, error) {
+	var fieldErrors errs.FieldErrors
+	var filter groupbus.QueryFilter
+
+	if qp.ID != "" {
+		id, err := extid.Decode(qp.ID)
+		switch err {
+		case nil:
+			filter.ID = &id
+		default:
+			fieldErrors.Add("group_id", err)
+		}
+	}
+
+	if qp.Name != "" {
+		filter.Name = &qp.Name
+	}
+
+	if qp.MemberID != "" {
+		id, err := extid.Decode(qp.MemberID)
+		switch err {
+		case nil:
+			filter.MemberID = &id
+		default:
+			fieldErrors.Add("member_id", err)
+		}
+	}
+
+	if qp.StartCreatedDate != "" {
+		t, err := time.Parse(time.RFC3339, qp.StartCreatedDate)
+		switch err {
+		case nil:
+			filter.StartCreatedDate = &t
+		default:
+			fieldErrors.Add("start_created_date", err)
+		}
+	}
+
+	if qp.EndCreatedDate != "" {
+		t, err := time.Parse(time.RFC3339, qp.EndCreatedDate)
+		switch err {
+		case nil:
+			filter.EndCreatedDate = &t
+		default:
+			fieldErrors.Add("end_created_date", err)
+		}
+	}
+
+	if fieldErrors != nil {
+		return groupbus.QueryFilter{}, fieldErrors.ToError()
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,331 @@
+// Package groupapp maintains the app layer api for the group domain.
+package groupapp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/extid"
+	"github.com/ardanlabs/service/app/sdk/mid"
+	"github.com/ardanlabs/service/app/sdk/query"
+	"github.com/ardanlabs/service/business/domain/groupbus"
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/types/grouprole"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/ardanlabs/service/foundation/web"
+)
+
+type app struct {
+	groupBus *groupbus.Business
+}
+
+func newApp(groupBus *groupbus.Business) *app {
+	return &app{
+		groupBus: groupBus,
+	}
+}
+
+// create adds a group with the caller as its owner.
+func (a *app) create(ctx context.Context, r *http.Request) web.Encoder {
+	var app NewGroup
+	if err := web.Decode(r, &app); err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	userID, err := mid.GetUserID(ctx)
+	if err != nil {
+		return errs.New(errs.Unauthenticated, err)
+	}
+
+	grp, err := a.groupBus.Create(ctx, toBusNewGroup(userID, app))
+	if err != nil {
+		return toAppError(err, "create: grp[%s]: %s", app.Name)
+	}
+
+	return toAppGroup(grp)
+}
+
+func (a *app) update(ctx context.Context, r *http.Request) web.Encoder {
+	var app UpdateGroup
+	if err := web.Decode(r, &app); err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	grp, err := a.managedGroup(ctx, r)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	updGrp, err := a.groupBus.Update(ctx, grp, toBusUpdateGroup(app))
+	if err != nil {
+		return toAppError(err, "update: groupID[%s]: %s", grp.ID)
+	}
+
+	return toAppGroup(updGrp)
+}
+
+func (a *app) delete(ctx context.Context, r *http.Request) web.Encoder {
+	grp, err := a.managedGroup(ctx, r)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	if err := a.groupBus.Delete(ctx, grp); err != nil {
+		return errs.Newf(errs.Internal, "delete: groupID[%s]: %s", grp.ID, err)
+	}
+
+	return nil
+}
+
+// query returns the groups that match the filter. Filtering on member_id
+// returns the groups a user belongs to.
+func (a *app) query(ctx context.Context, r *http.Request) web.Encoder {
+	qp := parseQueryParams(r)
+
+	page, err := page.Parse(qp.Page, qp.Rows)
+	if err != nil {
+		return errs.NewFieldErrors("page", err)
+	}
+
+	filter, err := parseFilter(qp)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	orderBy, err := order.Parse(orderByFields, qp.OrderBy, groupbus.DefaultOrderBy)
+	if err != nil {
+		return errs.NewFieldErrors("order", err)
+	}
+
+	grps, err := a.groupBus.Query(ctx, filter, orderBy, page)
+	if err != nil {
+		return errs.Newf(errs.Internal, "query: %s", err)
+	}
+
+	total, err := a.groupBus.Count(ctx, filter)
+	if err != nil {
+		return errs.Newf(errs.Internal, "count: %s", err)
+	}
+
+	return query.NewResult(toAppGroups(grps), total, page)
+}
+
+func (a *app) queryByID(ctx context.Context, r *http.Request) web.Encoder {
+	grp, err := a.group(ctx, r)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	return toAppGroup(grp)
+}
+
+// =============================================================================
+
+// queryMembers returns the users in the group.
+func (a *app) queryMembers(ctx context.Context, r *http.Request) web.Encoder {
+	qp := parseQueryParams(r)
+
+	page, err := page.Parse(qp.Page, qp.Rows)
+	if err != nil {
+		return errs.NewFieldErrors("page", err)
+	}
+
+	grp, err := a.group(ctx, r)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	mbrs, err := a.groupBus.QueryMembers(ctx, grp.ID, page)
+	if err != nil {
+		return errs.Newf(errs.Internal, "querymembers: %s", err)
+	}
+
+	total, err := a.groupBus.CountMembers(ctx, grp.ID)
+	if err != nil {
+		return errs.Newf(errs.Internal, "countmembers: %s", err)
+	}
+
+	return query.NewResult(toAppMembers(mbrs), total, page)
+}
+
+func (a *app) addMember(ctx context.Context, r *http.Request) web.Encoder {
+	var app NewMember
+	if err := web.Decode(r, &app); err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	nm, err := toBusNewMember(app)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	grp, err := a.managedGroup(ctx, r)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	mbr, err := a.groupBus.AddMember(ctx, grp, nm)
+	if err != nil {
+		return toAppError(err, "addmember: groupID[%s] userID[%s]: %s", grp.ID, nm.UserID)
+	}
+
+	return toAppMember(mbr)
+}
+
+func (a *app) updateMember(ctx context.Context, r *http.Request) web.Encoder {
+	var app UpdateMember
+	if err := web.Decode(r, &app); err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	role, err := grouprole.Parse(app.Role)
+	if err != nil {
+		return errs.NewFieldErrors("role", err)
+	}
+
+	grp, err := a.managedGroup(ctx, r)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	mbr, err := a.member(ctx, r, grp)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	updMbr, err := a.groupBus.SetMemberRole(ctx, mbr, role)
+	if err != nil {
+		return toAppError(err, "setmemberrole: groupID[%s] userID[%s]: %s", grp.ID, mbr.UserID)
+	}
+
+	return toAppMember(updMbr)
+}
+
+// removeMember takes a user out of the group. Members can always leave a
+// group, removing anyone else requires managing it.
+func (a *app) removeMember(ctx context.Context, r *http.Request) web.Encoder {
+	grp, err := a.group(ctx, r)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	mbr, err := a.member(ctx, r, grp)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	userID, err := mid.GetUserID(ctx)
+	if err != nil {
+		return errs.New(errs.Unauthenticated, err)
+	}
+
+	if mbr.UserID != userID {
+		if err := a.canManage(ctx, grp); err != nil {
+			return err.(*errs.Error)
+		}
+	}
+
+	if err := a.groupBus.RemoveMember(ctx, mbr); err != nil {
+		return toAppError(err, "removemember: groupID[%s] userID[%s]: %s", grp.ID, mbr.UserID)
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// group looks up the group identified in the request path.
+func (a *app) group(ctx context.Context, r *http.Request) (groupbus.Group, error) {
+	id, err := extid.Decode(web.Param(r, "group_id"))
+	if err != nil {
+		return groupbus.Group{}, errs.New(errs.InvalidArgument, err)
+	}
+
+	grp, err := a.groupBus.QueryByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, groupbus.ErrNotFound) {
+			return groupbus.Group{}, errs.New(errs.NotFound, err)
+		}
+		return groupbus.Group{}, errs.Newf(errs.Internal, "querybyid: groupID[%s]: %s", id, err)
+	}
+
+	return grp, nil
+}
+
+// managedGroup looks up the group identified in the request path, which the
+// caller must be able to manage.
+func (a *app) managedGroup(ctx context.Context, r *http.Request) (groupbus.Group, error) {
+	grp, err := a.group(ctx, r)
+	if err != nil {
+		return groupbus.Group{}, err
+	}
+
+	if err := a.canManage(ctx, grp); err != nil {
+		return groupbus.Group{}, err
+	}
+
+	return grp, nil
+}
+
+// member looks up the membership of the user identified in the request path.
+func (a *app) member(ctx context.Context, r *http.Request, grp groupbus.Group) (groupbus.Member, error) {
+	userID, err := extid.Decode(web.Param(r, "user_id"))
+	if err != nil {
+		return groupbus.Member{}, errs.New(errs.InvalidArgument, err)
+	}
+
+	mbr, err := a.groupBus.QueryMember(ctx, grp.ID, userID)
+	if err != nil {
+		if errors.Is(err, groupbus.ErrMemberNotFound) {
+			return groupbus.Member{}, errs.New(errs.NotFound, err)
+		}
+		return groupbus.Member{}, errs.Newf(errs.Internal, "querymember: groupID[%s] userID[%s]: %s", grp.ID, userID, err)
+	}
+
+	return mbr, nil
+}
+
+// canManage checks the caller is an admin or an owner of the group.
+func (a *app) canManage(ctx context.Context, grp groupbus.Group) error {
+	if slices.Contains(mid.GetClaims(ctx).Roles, role.Admin.String()) {
+		return nil
+	}
+
+	userID, err := mid.GetUserID(ctx)
+	if err != nil {
+		return errs.New(errs.Unauthenticated, err)
+	}
+
+	mbr, err := a.groupBus.QueryMember(ctx, grp.ID, userID)
+	switch {
+	case errors.Is(err, groupbus.ErrMemberNotFound):
+	case err != nil:
+		return errs.Newf(errs.Internal, "querymember: groupID[%s] userID[%s]: %s", grp.ID, userID, err)
+	case mbr.Role.Equal(grouprole.Owner):
+		return nil
+	}
+
+	return errs.Newf(errs.PermissionDenied, "groupID[%s] is managed by its owners", grp.ID)
+}
+
+func toAppError(err error, format string, args ...any) *errs.Error {
+	switch {
+	case errors.Is(err, groupbus.ErrUniqueName):
+		return errs.New(errs.Aborted, groupbus.ErrUniqueName)
+	case errors.Is(err, groupbus.ErrMemberExists):
+		return errs.New(errs.Aborted, groupbus.ErrMemberExists)
+	case errors.Is(err, groupbus.ErrLastOwner):
+		return errs.New(errs.FailedPrecondition, groupbus.ErrLastOwner)
+	case errors.Is(err, groupbus.ErrUserDisabled):
+		return errs.New(errs.FailedPrecondition, groupbus.ErrUserDisabled)
+	case errors.Is(err, userbus.ErrNotFound):
+		return errs.New(errs.NotFound, userbus.ErrNotFound)
+	}
+
+	return errs.Newf(errs.Internal, format, append(args, err)...)
+}
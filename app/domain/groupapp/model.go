@@ -0,0 +1,210 @@
+package groupapp
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/extid"
+	"github.com/ardanlabs/service/business/domain/groupbus"
+	"github.com/ardanlabs/service/business/types/grouprole"
+	"github.com/google/uuid"
+)
+
+// Group represents information about an individual group.
+type Group struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	DateCreated string `json:"dateCreated"`
+	DateUpdated string `json:"dateUpdated"`
+}
+
+// Encode implements the encoder interface.
+func (app Group) Encode() ([]byte, string, error) {
+	data, err := json.Marshal(app)
+	return data, "application/json", err
+}
+
+func toAppGroup(grp groupbus.Group) Group {
+	return Group{
+		ID:          extid.Encode(grp.ID),
+		Name:        grp.Name,
+		Description: grp.Description,
+		DateCreated: grp.DateCreated.Format(time.RFC3339),
+		DateUpdated: grp.DateUpdated.Format(time.RFC3339),
+	}
+}
+
+func toAppGroups(grps []groupbus.Group) []Group {
+	app := make([]Group, len(grps))
+	for i, grp := range grps {
+		app[i] = toAppGroup(grp)
+	}
+
+	return app
+}
+
+// =============================================================================
+
+// NewGroup defines the data needed to add a new group.
+type NewGroup struct {
+	Name        string `json:"name" validate:"required,max=100"`
+	Description string `json:"description" validate:"max=500"`
+}
+
+// Decode implements the decoder interface.
+func (app *NewGroup) Decode(data []byte) error {
+	return json.Unmarshal(data, app)
+}
+
+// Validate checks the data in the model is considered clean.
+func (app NewGroup) Validate() error {
+	if err := errs.Check(app); err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+
+	return nil
+}
+
+func toBusNewGroup(ownerID uuid.UUID, app NewGroup) groupbus.NewGroup {
+	return groupbus.NewGroup{
+		Name:        app.Name,
+		Description: app.Description,
+		OwnerID:     ownerID,
+	}
+}
+
+// =============================================================================
+
+// UpdateGroup defines the data needed to update a group.
+type UpdateGroup struct {
+	Name        *string `json:"name" validate:"omitempty,min=1,max=100"`
+	Description *string `json:"description" validate:"omitempty,max=500"`
+}
+
+// Decode implements the decoder interface.
+func (app *UpdateGroup) Decode(data []byte) error {
+	return json.Unmarshal(data, app)
+}
+
+// Validate checks the data in the model is considered clean.
+func (app UpdateGroup) Validate() error {
+	if err := errs.Check(app); err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+
+	return nil
+}
+
+func toBusUpdateGroup(app UpdateGroup) groupbus.UpdateGroup {
+	return groupbus.UpdateGroup{
+		Name:        app.Name,
+		Description: app.Description,
+	}
+}
+
+// =============================================================================
+
+// Member represents information about a user's membership of a group.
+type Member struct {
+	GroupID   string `json:"groupID"`
+	UserID    string `json:"userID"`
+	Role      string `json:"role"`
+	DateAdded string `json:"dateAdded"`
+}
+
+// Encode implements the encoder interface.
+func (app Member) Encode() ([]byte, string, error) {
+	data, err := json.Marshal(app)
+	return data, "application/json", err
+}
+
+func toAppMember(mbr groupbus.Member) Member {
+	return Member{
+		GroupID:   extid.Encode(mbr.GroupID),
+		UserID:    extid.Encode(mbr.UserID),
+		Role:      mbr.Role.String(),
+		DateAdded: mbr.DateAdded.Format(time.RFC3339),
+	}
+}
+
+func toAppMembers(mbrs []groupbus.Member) []Member {
+	app := make([]Member, len(mbrs))
+	for i, mbr := range mbrs {
+		app[i] = toAppMember(mbr)
+	}
+
+	return app
+}
+
+// =============================================================================
+
+// NewMember defines the data needed to add a user to a group. The role
+// defaults to a plain member.
+type NewMember struct {
+	UserID string `json:"userID" validate:"required"`
+	Role   string `json:"role"`
+}
+
+// Decode implements the decoder interface.
+func (app *NewMember) Decode(data []byte) error {
+	return json.Unmarshal(data, app)
+}
+
+// Validate checks the data in the model is considered clean.
+func (app NewMember) Validate() error {
+	if err := errs.Check(app); err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+
+	return nil
+}
+
+func toBusNewMember(app NewMember) (groupbus.NewMember, error) {
+	var fieldErrors errs.FieldErrors
+
+	userID, err := extid.Decode(app.UserID)
+	if err != nil {
+		fieldErrors.Add("userID", err)
+	}
+
+	role := grouprole.Member
+	if app.Role != "" {
+		role, err = grouprole.Parse(app.Role)
+		if err != nil {
+			fieldErrors.Add("role", err)
+		}
+	}
+
+	if fieldErrors != nil {
+		return groupbus.NewMember{}, fieldErrors.ToError()
+	}
+
+	bus := groupbus.NewMember{
+		UserID: userID,
+		Role:   role,
+	}
+
+	return bus, nil
+}
+
+// UpdateMember defines the data needed to change a member's role.
+type UpdateMember struct {
+	Role string `json:"role" validate:"required"`
+}
+
+// Decode implements the decoder interface.
+func (app *UpdateMember) Decode(data []byte) error {
+	return json.Unmarshal(data, app)
+}
+
+// Validate checks the data in the model is considered clean.
+func (app UpdateMember) Validate() error {
+	if err := errs.Check(app); err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+
+	return nil
+}
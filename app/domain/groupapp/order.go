@@ -0,0 +1,7 @@
+package groupapp
+
+import (
+	"github.com/ardanlabs/service/business/domain/groupbus"
+)
+
+var orderByFields = groupbus.OrderFields.Mappings()
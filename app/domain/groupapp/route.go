@@ -0,0 +1,39 @@
+package groupapp
+
+import (
+	"net/http"
+
+	"github.com/ardanlabs/service/app/sdk/auth"
+	"github.com/ardanlabs/service/app/sdk/authclient"
+	"github.com/ardanlabs/service/app/sdk/mid"
+	"github.com/ardanlabs/service/business/domain/groupbus"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/web"
+)
+
+// Config contains all the mandatory systems required by handlers.
+type Config struct {
+	Log        *logger.Logger
+	GroupBus   *groupbus.Business
+	AuthClient *authclient.Client
+}
+
+// Routes adds specific routes for this group.
+func Routes(app *web.App, cfg Config) {
+	const version = "v1"
+
+	authen := mid.Authenticate(cfg.AuthClient)
+	ruleAny := mid.Authorize(cfg.AuthClient, auth.RuleAny)
+
+	api := newApp(cfg.GroupBus)
+
+	app.HandlerFunc(http.MethodGet, version, "/groups", api.query, authen, ruleAny)
+	app.HandlerFunc(http.MethodGet, version, "/groups/{group_id}", api.queryByID, authen, ruleAny)
+	app.HandlerFunc(http.MethodPost, version, "/groups", api.create, authen, ruleAny)
+	app.HandlerFunc(http.MethodPut, version, "/groups/{group_id}", api.update, authen, ruleAny)
+	app.HandlerFunc(http.MethodDelete, version, "/groups/{group_id}", api.delete, authen, ruleAny)
+	app.HandlerFunc(http.MethodGet, version, "/groups/{group_id}/members", api.queryMembers, authen, ruleAny)
+	app.HandlerFunc(http.MethodPost, version, "/groups/{group_id}/members", api.addMember, authen, ruleAny)
+	app.HandlerFunc(http.MethodPut, version, "/groups/{group_id}/members/{user_id}", api.updateMember, authen, ruleAny)
+	app.HandlerFunc(http.MethodDelete, version, "/groups/{group_id}/members/{user_id}", api.removeMember, authen, ruleAny)
+}
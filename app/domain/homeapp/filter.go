@@ -5,9 +5,9 @@ import (
 	"time"
 
 	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/extid"
 	"github.com/ardanlabs/service/business/domain/homebus"
 	"github.com/ardanlabs/service/business/types/hometype"
-	"github.com/google/uuid"
 )
 
 type queryParams struct {
@@ -43,7 +43,7 @@ func parseFilter(qp queryParams) (homebus.QueryFilter, error) {
 	var filter homebus.QueryFilter
 
 	if qp.ID != "" {
-		id, err := uuid.Parse(qp.ID)
+		id, err := extid.Decode(qp.ID)
 		switch err {
 		case nil:
 			filter.ID = &id
@@ -53,7 +53,7 @@ func parseFilter(qp queryParams) (homebus.QueryFilter, error) {
 	}
 
 	if qp.UserID != "" {
-		id, err := uuid.Parse(qp.UserID)
+		id, err := extid.Decode(qp.UserID)
 		switch err {
 		case nil:
 			filter.UserID = &id
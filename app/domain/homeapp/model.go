@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/extid"
 	"github.com/ardanlabs/service/app/sdk/mid"
 	"github.com/ardanlabs/service/business/domain/homebus"
 	"github.com/ardanlabs/service/business/types/hometype"
@@ -40,8 +41,8 @@ func (app Home) Encode() ([]byte, string, error) {
 
 func toAppHome(hme homebus.Home) Home {
 	return Home{
-		ID:     hme.ID.String(),
-		UserID: hme.UserID.String(),
+		ID:     extid.Encode(hme.ID),
+		UserID: extid.Encode(hme.UserID),
 		Type:   hme.Type.String(),
 		Address: Address{
 			Address1: hme.Address.Address1,
@@ -0,0 +1,88 @@
+package inviteapp
+
+import (
+	"net/http"
+	"net/mail"
+	"strconv"
+
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/extid"
+	"github.com/ardanlabs/service/business/domain/invitebus"
+)
+
+type queryParams struct {
+	Page      string
+	Rows      string
+	OrderBy   string
+	ID        string
+	Email     string
+	InvitedBy string
+	Pending   string
+}
+
+func parseQueryParams(r *http.Request) queryParams {
+	values := r.URL.Query()
+
+	filter := queryParams{
+		Page:      values.Get("page"),
+		Rows:      values.Get("rows"),
+		OrderBy:   values.Get("orderBy"),
+		ID:        values.Get("invite_id"),
+		Email:     values.Get("email"),
+		InvitedBy: values.Get("invited_by"),
+		Pending:   values.Get("pending"),
+	}
+
+	return filter
+}
+
+func parseFilter(qp queryParams) (invitebus.QueryFilter, error) {
+	var fieldErrors errs.FieldErrors
+	var filter invitebus.QueryFilter
+
+	if qp.ID != "" {
+		id, err := extid.Decode(qp.ID)
+		switch err {
+		case nil:
+			filter.ID = &id
+		default:
+			fieldErrors.Add("invite_id", err)
+		}
+	}
+
+	if qp.Email != "" {
+		addr, err := mail.ParseAddress(qp.Email)
+		switch err {
+		case nil:
+			filter.Email = addr
+		default:
+			fieldErrors.Add("email", err)
+		}
+	}
+
+	if qp.InvitedBy != "" {
+		id, err := extid.Decode(qp.InvitedBy)
+		switch err {
+		case nil:
+			filter.InvitedBy = &id
+		default:
+			fieldErrors.Add("invited_by", err)
+		}
+	}
+
+	if qp.Pending != "" {
+		pending, err := strconv.ParseBool(qp.Pending)
+		switch err {
+		case nil:
+			filter.Pending = &pending
+		default:
+			fieldErrors.Add("pending", err)
+		}
+	}
+
+	if fieldErrors != nil {
+		return invitebus.QueryFilter{}, fieldErrors.ToError()
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,214 @@
+// Package inviteapp maintains the app layer api for the invite domain.
+package inviteapp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/extid"
+	"github.com/ardanlabs/service/app/sdk/mid"
+	"github.com/ardanlabs/service/app/sdk/query"
+	"github.com/ardanlabs/service/business/domain/invitebus"
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/foundation/web"
+)
+
+type app struct {
+	inviteBus *invitebus.Business
+}
+
+func newApp(inviteBus *invitebus.Business) *app {
+	return &app{
+		inviteBus: inviteBus,
+	}
+}
+
+// newWithTx constructs a new app value with the domain apis
+// using a store transaction that was created via middleware.
+func (a *app) newWithTx(ctx context.Context) (*app, error) {
+	tx, err := mid.GetTran(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	inviteBus, err := a.inviteBus.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	app := app{
+		inviteBus: inviteBus,
+	}
+
+	return &app, nil
+}
+
+// create invites someone on behalf of the caller. The token is only returned
+// in this response.
+func (a *app) create(ctx context.Context, r *http.Request) web.Encoder {
+	var app NewInvite
+	if err := web.Decode(r, &app); err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	ni, err := toBusNewInvite(ctx, app)
+	if err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	inv, token, err := a.inviteBus.Create(ctx, ni)
+	if err != nil {
+		switch {
+		case errors.Is(err, invitebus.ErrNoRoles):
+			return errs.NewFieldErrors("roles", err)
+		case errors.Is(err, invitebus.ErrTTLTooLong):
+			return errs.NewFieldErrors("ttl", err)
+		case errors.Is(err, invitebus.ErrUserExists):
+			return errs.New(errs.AlreadyExists, err)
+		case errors.Is(err, invitebus.ErrUserDisabled):
+			return errs.New(errs.PermissionDenied, err)
+		}
+		return errs.Newf(errs.Internal, "create: inv[%+v]: %s", app, err)
+	}
+
+	return toAppCreatedInvite(inv, token)
+}
+
+// accept creates the user for an invite. The invite is marked as accepted in
+// the same transaction the user is created in.
+func (a *app) accept(ctx context.Context, r *http.Request) web.Encoder {
+	var app AcceptInvite
+	if err := web.Decode(r, &app); err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	nme, err := toBusName(app)
+	if err != nil {
+		return errs.NewFieldErrors("name", err)
+	}
+
+	a, err = a.newWithTx(ctx)
+	if err != nil {
+		return errs.New(errs.Internal, err)
+	}
+
+	usr, err := a.inviteBus.Accept(ctx, app.Token, nme, app.Password)
+	if err != nil {
+		var ppe *userbus.PasswordPolicyError
+		var re *userbus.RuleError
+		switch {
+		case errors.As(err, &ppe):
+			return errs.NewFieldErrors("password", ppe)
+		case errors.As(err, &re):
+			return toRuleFieldErrors(re)
+		case errors.Is(err, invitebus.ErrInvalidToken):
+			return errs.New(errs.Unauthenticated, err)
+		case errors.Is(err, invitebus.ErrExpired),
+			errors.Is(err, invitebus.ErrRevoked),
+			errors.Is(err, invitebus.ErrAccepted):
+			return errs.New(errs.FailedPrecondition, err)
+		case errors.Is(err, invitebus.ErrUserExists):
+			return errs.New(errs.AlreadyExists, err)
+		case errors.Is(err, userbus.ErrInvalidActor):
+			return errs.New(errs.PermissionDenied, err)
+		}
+		return errs.Newf(errs.Internal, "accept: %s", err)
+	}
+
+	return toAppUser(usr)
+}
+
+// revoke stops an invite from being accepted.
+func (a *app) revoke(ctx context.Context, r *http.Request) web.Encoder {
+	inv, err := a.invite(ctx, r)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	if _, err := a.inviteBus.Revoke(ctx, inv); err != nil {
+		if errors.Is(err, invitebus.ErrAccepted) {
+			return errs.New(errs.FailedPrecondition, err)
+		}
+		return errs.Newf(errs.Internal, "revoke: inviteID[%s]: %s", inv.ID, err)
+	}
+
+	return nil
+}
+
+func (a *app) query(ctx context.Context, r *http.Request) web.Encoder {
+	qp := parseQueryParams(r)
+
+	page, err := page.Parse(qp.Page, qp.Rows)
+	if err != nil {
+		return errs.NewFieldErrors("page", err)
+	}
+
+	filter, err := parseFilter(qp)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	orderBy, err := order.Parse(orderByFields, qp.OrderBy, invitebus.DefaultOrderBy)
+	if err != nil {
+		return errs.NewFieldErrors("order", err)
+	}
+
+	invs, err := a.inviteBus.Query(ctx, filter, orderBy, page)
+	if err != nil {
+		return errs.Newf(errs.Internal, "query: %s", err)
+	}
+
+	total, err := a.inviteBus.Count(ctx, filter)
+	if err != nil {
+		return errs.Newf(errs.Internal, "count: %s", err)
+	}
+
+	return query.NewResult(toAppInvites(invs), total, page)
+}
+
+func (a *app) queryByID(ctx context.Context, r *http.Request) web.Encoder {
+	inv, err := a.invite(ctx, r)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	return toAppInvite(inv)
+}
+
+// =============================================================================
+
+func (a *app) invite(ctx context.Context, r *http.Request) (invitebus.Invite, error) {
+	id, err := extid.Decode(web.Param(r, "invite_id"))
+	if err != nil {
+		return invitebus.Invite{}, errs.New(errs.InvalidArgument, err)
+	}
+
+	inv, err := a.inviteBus.QueryByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, invitebus.ErrNotFound) {
+			return invitebus.Invite{}, errs.New(errs.NotFound, err)
+		}
+		return invitebus.Invite{}, errs.Newf(errs.Internal, "querybyid: inviteID[%s]: %s", id, err)
+	}
+
+	return inv, nil
+}
+
+// toRuleFieldErrors reports each broken rule against the field it names,
+// or the rule itself when it doesn't name one.
+func toRuleFieldErrors(re *userbus.RuleError) *errs.Error {
+	var fieldErrors errs.FieldErrors
+	for _, rv := range re.Violations {
+		field := rv.Field
+		if field == "" {
+			field = rv.Rule
+		}
+		fieldErrors.Add(field, errors.New(rv.Message))
+	}
+
+	return fieldErrors.ToError()
+}
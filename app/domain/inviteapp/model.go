@@ -0,0 +1,207 @@
+package inviteapp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"time"
+
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/extid"
+	"github.com/ardanlabs/service/app/sdk/mid"
+	"github.com/ardanlabs/service/business/domain/invitebus"
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/types/name"
+	"github.com/ardanlabs/service/business/types/role"
+)
+
+// Invite represents information about an individual invite. The token is
+// never returned after the invite is created.
+type Invite struct {
+	ID           string   `json:"id"`
+	Email        string   `json:"email" class:"confidential"`
+	Roles        []string `json:"roles" class:"internal"`
+	InvitedBy    string   `json:"invitedBy"`
+	UserID       string   `json:"userID,omitempty"`
+	DateCreated  string   `json:"dateCreated"`
+	DateExpires  string   `json:"dateExpires"`
+	DateAccepted string   `json:"dateAccepted,omitempty"`
+	DateRevoked  string   `json:"dateRevoked,omitempty"`
+}
+
+// Encode implements the encoder interface.
+func (app Invite) Encode() ([]byte, string, error) {
+	data, err := json.Marshal(app)
+	return data, "application/json", err
+}
+
+func toAppInvite(inv invitebus.Invite) Invite {
+	app := Invite{
+		ID:          extid.Encode(inv.ID),
+		Email:       inv.Email.Address,
+		Roles:       role.ParseToString(inv.Roles),
+		InvitedBy:   extid.Encode(inv.InvitedBy),
+		UserID:      extid.EncodeNull(inv.UserID),
+		DateCreated: inv.DateCreated.Format(time.RFC3339),
+		DateExpires: inv.DateExpires.Format(time.RFC3339),
+	}
+
+	if inv.Accepted() {
+		app.DateAccepted = inv.DateAccepted.Format(time.RFC3339)
+	}
+
+	if inv.Revoked() {
+		app.DateRevoked = inv.DateRevoked.Format(time.RFC3339)
+	}
+
+	return app
+}
+
+func toAppInvites(invs []invitebus.Invite) []Invite {
+	app := make([]Invite, len(invs))
+	for i, inv := range invs {
+		app[i] = toAppInvite(inv)
+	}
+
+	return app
+}
+
+// CreatedInvite represents a newly created invite along with the token to
+// accept it, which is only available in this response.
+type CreatedInvite struct {
+	Invite
+	Token string `json:"token"`
+}
+
+// Encode implements the encoder interface.
+func (app CreatedInvite) Encode() ([]byte, string, error) {
+	data, err := json.Marshal(app)
+	return data, "application/json", err
+}
+
+func toAppCreatedInvite(inv invitebus.Invite, token string) CreatedInvite {
+	return CreatedInvite{
+		Invite: toAppInvite(inv),
+		Token:  token,
+	}
+}
+
+// =============================================================================
+
+// NewInvite defines the data needed to invite someone. The TTL is a duration
+// such as 72h, the default is used when it's empty.
+type NewInvite struct {
+	Email string   `json:"email" validate:"required,email"`
+	Roles []string `json:"roles" validate:"required"`
+	TTL   string   `json:"ttl"`
+}
+
+// Decode implements the decoder interface.
+func (app *NewInvite) Decode(data []byte) error {
+	return json.Unmarshal(data, app)
+}
+
+// Validate checks the data in the model is considered clean.
+func (app NewInvite) Validate() error {
+	if err := errs.Check(app); err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+
+	return nil
+}
+
+func toBusNewInvite(ctx context.Context, app NewInvite) (invitebus.NewInvite, error) {
+	userID, err := mid.GetUserID(ctx)
+	if err != nil {
+		return invitebus.NewInvite{}, fmt.Errorf("getuserid: %w", err)
+	}
+
+	addr, err := mail.ParseAddress(app.Email)
+	if err != nil {
+		return invitebus.NewInvite{}, fmt.Errorf("parse: %w", err)
+	}
+
+	roles, err := role.ParseMany(app.Roles)
+	if err != nil {
+		return invitebus.NewInvite{}, fmt.Errorf("parse: %w", err)
+	}
+
+	var ttl time.Duration
+	if app.TTL != "" {
+		ttl, err = time.ParseDuration(app.TTL)
+		if err != nil {
+			return invitebus.NewInvite{}, fmt.Errorf("parse: ttl: %w", err)
+		}
+	}
+
+	bus := invitebus.NewInvite{
+		Email:     *addr,
+		Roles:     roles,
+		InvitedBy: userID,
+		TTL:       ttl,
+	}
+
+	return bus, nil
+}
+
+// =============================================================================
+
+// AcceptInvite defines the data needed to accept an invite. The email and
+// roles come from the invite.
+type AcceptInvite struct {
+	Token           string `json:"token" validate:"required"`
+	Name            string `json:"name" validate:"required"`
+	Password        string `json:"password" validate:"required"`
+	PasswordConfirm string `json:"passwordConfirm" validate:"eqfield=Password"`
+}
+
+// Decode implements the decoder interface.
+func (app *AcceptInvite) Decode(data []byte) error {
+	return json.Unmarshal(data, app)
+}
+
+// Validate checks the data in the model is considered clean.
+func (app AcceptInvite) Validate() error {
+	if err := errs.Check(app); err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+
+	return nil
+}
+
+func toBusName(app AcceptInvite) (name.Name, error) {
+	nme, err := name.Parse(app.Name)
+	if err != nil {
+		return name.Name{}, fmt.Errorf("parse: %w", err)
+	}
+
+	return nme, nil
+}
+
+// =============================================================================
+
+// User represents the user created by accepting an invite.
+type User struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name" class:"confidential"`
+	Email       string   `json:"email" class:"confidential"`
+	Roles       []string `json:"roles" class:"internal"`
+	DateCreated string   `json:"dateCreated"`
+}
+
+// Encode implements the encoder interface.
+func (app User) Encode() ([]byte, string, error) {
+	data, err := json.Marshal(app)
+	return data, "application/json", err
+}
+
+func toAppUser(bus userbus.User) User {
+	return User{
+		ID:          extid.Encode(bus.ID),
+		Name:        bus.Name.String(),
+		Email:       bus.Email.Address,
+		Roles:       role.ParseToString(bus.Roles),
+		DateCreated: bus.DateCreated.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,7 @@
+package inviteapp
+
+import (
+	"github.com/ardanlabs/service/business/domain/invitebus"
+)
+
+var orderByFields = invitebus.OrderFields.Mappings()
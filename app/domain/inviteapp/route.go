@@ -0,0 +1,41 @@
+package inviteapp
+
+import (
+	"net/http"
+
+	"github.com/ardanlabs/service/app/sdk/auth"
+	"github.com/ardanlabs/service/app/sdk/authclient"
+	"github.com/ardanlabs/service/app/sdk/mid"
+	"github.com/ardanlabs/service/business/domain/invitebus"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/web"
+	"github.com/jmoiron/sqlx"
+)
+
+// Config contains all the mandatory systems required by handlers.
+type Config struct {
+	Log        *logger.Logger
+	DB         *sqlx.DB
+	InviteBus  *invitebus.Business
+	AuthClient *authclient.Client
+}
+
+// Routes adds specific routes for this group.
+func Routes(app *web.App, cfg Config) {
+	const version = "v1"
+
+	authen := mid.Authenticate(cfg.AuthClient)
+	ruleAdmin := mid.Authorize(cfg.AuthClient, auth.RuleAdminOnly)
+	transaction := mid.BeginCommitRollback(cfg.Log, sqldb.NewBeginner(cfg.DB))
+
+	api := newApp(cfg.InviteBus)
+
+	app.HandlerFunc(http.MethodGet, version, "/invites", api.query, authen, ruleAdmin)
+	app.HandlerFunc(http.MethodGet, version, "/invites/{invite_id}", api.queryByID, authen, ruleAdmin)
+	app.HandlerFunc(http.MethodPost, version, "/invites", api.create, authen, ruleAdmin)
+	app.HandlerFunc(http.MethodDelete, version, "/invites/{invite_id}", api.revoke, authen, ruleAdmin)
+
+	// The token is what authenticates the person accepting an invite.
+	app.HandlerFunc(http.MethodPost, version, "/invites/accept", api.accept, transaction)
+}
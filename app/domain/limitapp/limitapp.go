@@ -0,0 +1,28 @@
+// Package limitapp maintains the app layer api for inspecting the caller's
+// current limits.
+package limitapp
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ardanlabs/service/app/sdk/mid"
+	"github.com/ardanlabs/service/foundation/limiter"
+	"github.com/ardanlabs/service/foundation/web"
+)
+
+type app struct {
+	limiter *limiter.Limiter
+}
+
+func newApp(limiter *limiter.Limiter) *app {
+	return &app{
+		limiter: limiter,
+	}
+}
+
+func (a *app) query(ctx context.Context, r *http.Request) web.Encoder {
+	status := a.limiter.Peek(mid.RateLimitKey(r))
+
+	return toAppLimits(status)
+}
@@ -0,0 +1,38 @@
+package limitapp
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/ardanlabs/service/foundation/limiter"
+)
+
+// RateLimit represents the caller's consumption of the request rate limit.
+type RateLimit struct {
+	Limit     int    `json:"limit"`
+	Used      int    `json:"used"`
+	Remaining int    `json:"remaining"`
+	Reset     string `json:"reset"`
+}
+
+// Limits represents the current limits for the authenticated caller.
+type Limits struct {
+	RateLimit RateLimit `json:"rateLimit"`
+}
+
+// Encode implements the encoder interface.
+func (app Limits) Encode() ([]byte, string, error) {
+	data, err := json.Marshal(app)
+	return data, "application/json", err
+}
+
+func toAppLimits(status limiter.Status) Limits {
+	return Limits{
+		RateLimit: RateLimit{
+			Limit:     status.Limit,
+			Used:      status.Limit - status.Remaining,
+			Remaining: status.Remaining,
+			Reset:     status.Reset.Format(time.RFC3339),
+		},
+	}
+}
@@ -0,0 +1,33 @@
+package limitapp
+
+import (
+	"net/http"
+
+	"github.com/ardanlabs/service/app/sdk/authclient"
+	"github.com/ardanlabs/service/app/sdk/mid"
+	"github.com/ardanlabs/service/foundation/limiter"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/web"
+)
+
+// Config contains all the mandatory systems required by handlers.
+type Config struct {
+	Log        *logger.Logger
+	Limiter    *limiter.Limiter
+	AuthClient *authclient.Client
+}
+
+// Routes adds specific routes for this group.
+func Routes(app *web.App, cfg Config) {
+	const version = "v1"
+
+	if cfg.Limiter == nil {
+		return
+	}
+
+	authen := mid.Authenticate(cfg.AuthClient)
+
+	api := newApp(cfg.Limiter)
+
+	app.HandlerFunc(http.MethodGet, version, "/limits", api.query, authen)
+}
@@ -0,0 +1,101 @@
+package notificationapp
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/business/domain/notificationbus"
+)
+
+// Preference represents whether the caller receives a kind of notification
+// on a channel.
+type Preference struct {
+	Kind        string `json:"kind"`
+	Channel     string `json:"channel"`
+	Enabled     bool   `json:"enabled"`
+	DateUpdated string `json:"dateUpdated,omitempty"`
+}
+
+// Encode implements the encoder interface.
+func (app Preference) Encode() ([]byte, string, error) {
+	data, err := json.Marshal(app)
+	return data, "application/json", err
+}
+
+func toAppPreference(pref notificationbus.Preference) Preference {
+	app := Preference{
+		Kind:    pref.Kind.String(),
+		Channel: pref.Channel.String(),
+		Enabled: pref.Enabled,
+	}
+
+	if !pref.DateUpdated.IsZero() {
+		app.DateUpdated = pref.DateUpdated.Format(time.RFC3339)
+	}
+
+	return app
+}
+
+// Preferences represents the set of notification preferences of the caller.
+type Preferences []Preference
+
+// Encode implements the encoder interface.
+func (app Preferences) Encode() ([]byte, string, error) {
+	data, err := json.Marshal(app)
+	return data, "application/json", err
+}
+
+func toAppPreferences(prefs []notificationbus.Preference) Preferences {
+	app := make(Preferences, len(prefs))
+	for i, pref := range prefs {
+		app[i] = toAppPreference(pref)
+	}
+
+	return app
+}
+
+// =============================================================================
+
+// UpdatePreference defines the data needed to turn a kind of notification on
+// or off.
+type UpdatePreference struct {
+	Kind    string `json:"kind" validate:"required"`
+	Channel string `json:"channel" validate:"required"`
+	Enabled *bool  `json:"enabled" validate:"required"`
+}
+
+// Decode implements the decoder interface.
+func (app *UpdatePreference) Decode(data []byte) error {
+	return json.Unmarshal(data, app)
+}
+
+// Validate checks the data in the model is considered clean.
+func (app UpdatePreference) Validate() error {
+	if err := errs.Check(app); err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+
+	return nil
+}
+
+func toBusUpdatePreference(app UpdatePreference) (notificationbus.UpdatePreference, error) {
+	kind, err := notificationbus.ParseKind(app.Kind)
+	if err != nil {
+		return notificationbus.UpdatePreference{}, fmt.Errorf("parse: %w", err)
+	}
+
+	channel, err := notificationbus.ParseChannel(app.Channel)
+	if err != nil {
+		return notificationbus.UpdatePreference{}, fmt.Errorf("parse: %w", err)
+	}
+
+	bus := notificationbus.UpdatePreference{
+		Kind:    kind,
+		Channel: channel,
+		Enabled: *app.Enabled,
+	}
+
+	return bus, nil
+}
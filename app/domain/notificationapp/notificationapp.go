@@ -0,0 +1,67 @@
+// Package notificationapp maintains the app layer api for the notification
+// domain.
+package notificationapp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/mid"
+	"github.com/ardanlabs/service/business/domain/notificationbus"
+	"github.com/ardanlabs/service/foundation/web"
+)
+
+type app struct {
+	notificationBus *notificationbus.Business
+}
+
+func newApp(notificationBus *notificationbus.Business) *app {
+	return &app{
+		notificationBus: notificationBus,
+	}
+}
+
+// queryPreferences returns the notification preferences of the caller.
+func (a *app) queryPreferences(ctx context.Context, _ *http.Request) web.Encoder {
+	userID, err := mid.GetUserID(ctx)
+	if err != nil {
+		return errs.New(errs.Unauthenticated, err)
+	}
+
+	prefs, err := a.notificationBus.QueryPreferences(ctx, userID)
+	if err != nil {
+		return errs.Newf(errs.Internal, "querypreferences: %s", err)
+	}
+
+	return toAppPreferences(prefs)
+}
+
+// updatePreference turns a kind of notification on or off for the caller.
+func (a *app) updatePreference(ctx context.Context, r *http.Request) web.Encoder {
+	var app UpdatePreference
+	if err := web.Decode(r, &app); err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	up, err := toBusUpdatePreference(app)
+	if err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	userID, err := mid.GetUserID(ctx)
+	if err != nil {
+		return errs.New(errs.Unauthenticated, err)
+	}
+
+	pref, err := a.notificationBus.UpdatePreference(ctx, userID, up)
+	if err != nil {
+		if errors.Is(err, notificationbus.ErrNotUserKind) {
+			return errs.NewFieldErrors("kind", err)
+		}
+		return errs.Newf(errs.Internal, "updatepreference: userID[%s]: %s", userID, err)
+	}
+
+	return toAppPreference(pref)
+}
@@ -0,0 +1,32 @@
+package notificationapp
+
+import (
+	"net/http"
+
+	"github.com/ardanlabs/service/app/sdk/auth"
+	"github.com/ardanlabs/service/app/sdk/authclient"
+	"github.com/ardanlabs/service/app/sdk/mid"
+	"github.com/ardanlabs/service/business/domain/notificationbus"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/web"
+)
+
+// Config contains all the mandatory systems required by handlers.
+type Config struct {
+	Log             *logger.Logger
+	NotificationBus *notificationbus.Business
+	AuthClient      *authclient.Client
+}
+
+// Routes adds specific routes for this group.
+func Routes(app *web.App, cfg Config) {
+	const version = "v1"
+
+	authen := mid.Authenticate(cfg.AuthClient)
+	ruleAny := mid.Authorize(cfg.AuthClient, auth.RuleAny)
+
+	api := newApp(cfg.NotificationBus)
+
+	app.HandlerFunc(http.MethodGet, version, "/notifications/preferences", api.queryPreferences, authen, ruleAny)
+	app.HandlerFunc(http.MethodPut, version, "/notifications/preferences", api.updatePreference, authen, ruleAny)
+}
@@ -3,16 +3,19 @@ package oauthapp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/mail"
 	"time"
 
 	"github.com/ardanlabs/service/app/sdk/auth"
 	"github.com/ardanlabs/service/app/sdk/errs"
-	"github.com/ardanlabs/service/business/types/role"
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/types/name"
+	"github.com/ardanlabs/service/foundation/clock"
 	"github.com/ardanlabs/service/foundation/logger"
 	"github.com/ardanlabs/service/foundation/web"
-	"github.com/golang-jwt/jwt/v4"
 	"github.com/markbates/goth"
 	"github.com/markbates/goth/gothic"
 	"github.com/markbates/goth/providers/google"
@@ -21,6 +24,7 @@ import (
 type app struct {
 	log      *logger.Logger
 	auth     *auth.Auth
+	userBus  userbus.Business
 	tokenKey string
 	uiURL    string
 	apiHost  string
@@ -37,6 +41,7 @@ func newApp(cfg Config) *app {
 
 	return &app{
 		auth:    cfg.Auth,
+		userBus: cfg.UserBus,
 		log:     cfg.Log,
 		uiURL:   cfg.GoogleUIURL,
 		apiHost: cfg.APIHost,
@@ -57,16 +62,21 @@ func (a *app) authCallback(ctx context.Context, r *http.Request) web.Encoder {
 		return errs.New(errs.Internal, err)
 	}
 
-	clms := auth.Claims{
-		RegisteredClaims: jwt.RegisteredClaims{
-			Subject:   user.UserID,
-			Issuer:    a.auth.Issuer(),
-			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(2 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
-		},
-		Roles: []string{role.Admin.String()},
+	email, err := mail.ParseAddress(user.Email)
+	if err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	usr, _, err := a.userBus.FederateLogin(ctx, user.Provider, user.UserID, *email, toBusProfile(user))
+	if err != nil {
+		if errors.Is(err, userbus.ErrEmailNotVerified) {
+			return errs.New(errs.PermissionDenied, err)
+		}
+		return errs.Newf(errs.Internal, "federatelogin: provider[%s]: %s", user.Provider, err)
 	}
 
+	clms := a.auth.NewClaims(usr, auth.WithTTL(2*time.Hour), auth.WithAuthentication(clock.Now(), auth.MethodFederated))
+
 	token, err := a.auth.GenerateToken(a.tokenKey, clms)
 	if err != nil {
 		return errs.New(errs.Internal, err)
@@ -92,3 +102,22 @@ func (a *app) logout(ctx context.Context, r *http.Request) web.Encoder {
 
 	return web.NewNoResponse()
 }
+
+// toBusProfile converts what the provider shared about the user. Providers
+// report a verified email in different ways in the raw data.
+func toBusProfile(user goth.User) userbus.Profile {
+	var profile userbus.Profile
+
+	if nme, err := name.Parse(user.Name); err == nil {
+		profile.Name = nme
+	}
+
+	for _, key := range []string{"email_verified", "verified_email"} {
+		if verified, ok := user.RawData[key].(bool); ok {
+			profile.EmailVerified = verified
+			break
+		}
+	}
+
+	return profile
+}
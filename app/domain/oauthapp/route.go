@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/ardanlabs/service/app/sdk/auth"
+	"github.com/ardanlabs/service/business/domain/userbus"
 	"github.com/ardanlabs/service/foundation/logger"
 	"github.com/ardanlabs/service/foundation/web"
 )
@@ -11,6 +12,7 @@ import (
 // Config contains all the configuration for the auth app.
 type Config struct {
 	Auth              *auth.Auth
+	UserBus           userbus.Business
 	Log               *logger.Logger
 	TokenKey          string
 	GoogleKey         string
@@ -0,0 +1,8 @@
+package openapiapp
+
+type document []byte
+
+// Encode implements the encoder interface.
+func (app document) Encode() ([]byte, string, error) {
+	return app, "application/json", nil
+}
@@ -0,0 +1,40 @@
+// Package openapiapp maintains the app layer api for the openapi document.
+package openapiapp
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/openapi"
+	"github.com/ardanlabs/service/foundation/web"
+)
+
+type app struct {
+	doc    *openapi.Document
+	routes func() []web.Route
+
+	once sync.Once
+	data []byte
+	err  error
+}
+
+func newApp(doc *openapi.Document, routes func() []web.Route) *app {
+	return &app{
+		doc:    doc,
+		routes: routes,
+	}
+}
+
+func (a *app) document(ctx context.Context, _ *http.Request) web.Encoder {
+	a.once.Do(func() {
+		a.data, a.err = a.doc.Build(a.routes())
+	})
+
+	if a.err != nil {
+		return errs.Newf(errs.Internal, "document: %s", a.err)
+	}
+
+	return document(a.data)
+}
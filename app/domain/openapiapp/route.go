@@ -0,0 +1,24 @@
+package openapiapp
+
+import (
+	"net/http"
+
+	"github.com/ardanlabs/service/app/sdk/openapi"
+	"github.com/ardanlabs/service/foundation/web"
+)
+
+// Config contains all the mandatory systems required by handlers.
+type Config struct {
+	Doc *openapi.Document
+}
+
+// Routes adds specific routes for this group. The document is built from
+// the routes the app has when it's first requested, so this can be called
+// before or after the other groups are added.
+func Routes(app *web.App, cfg Config) {
+	const version = "v1"
+
+	api := newApp(cfg.Doc, app.Routes)
+
+	app.HandlerFunc(http.MethodGet, version, "/openapi.json", api.document)
+}
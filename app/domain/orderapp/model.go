@@ -0,0 +1,46 @@
+package orderapp
+
+import (
+	"encoding/json"
+
+	"github.com/ardanlabs/service/business/sdk/order"
+)
+
+// Domain represents the values a domain accepts in the orderBy parameter.
+type Domain struct {
+	Domain     string   `json:"domain"`
+	Fields     []string `json:"fields"`
+	Directions []string `json:"directions"`
+}
+
+// Encode implements the encoder interface.
+func (app Domain) Encode() ([]byte, string, error) {
+	data, err := json.Marshal(app)
+	return data, "application/json", err
+}
+
+func toAppDomain(fields order.Fields) Domain {
+	return Domain{
+		Domain:     fields.Domain(),
+		Fields:     fields.Names(),
+		Directions: []string{order.ASC, order.DESC},
+	}
+}
+
+// Domains represents the orderBy values of every domain.
+type Domains []Domain
+
+// Encode implements the encoder interface.
+func (app Domains) Encode() ([]byte, string, error) {
+	data, err := json.Marshal(app)
+	return data, "application/json", err
+}
+
+func toAppDomains(all []order.Fields) Domains {
+	app := make(Domains, len(all))
+	for i, fields := range all {
+		app[i] = toAppDomain(fields)
+	}
+
+	return app
+}
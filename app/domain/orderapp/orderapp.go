@@ -0,0 +1,33 @@
+// Package orderapp maintains the app layer api for discovering the fields
+// each domain can be ordered by.
+package orderapp
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/foundation/web"
+)
+
+type app struct{}
+
+func newApp() *app {
+	return &app{}
+}
+
+func (a *app) query(ctx context.Context, r *http.Request) web.Encoder {
+	return toAppDomains(order.Registered())
+}
+
+func (a *app) queryByDomain(ctx context.Context, r *http.Request) web.Encoder {
+	domain := web.Param(r, "domain")
+
+	fields, exists := order.Lookup(domain)
+	if !exists {
+		return errs.Newf(errs.NotFound, "domain[%s] has no orderable fields", domain)
+	}
+
+	return toAppDomain(fields)
+}
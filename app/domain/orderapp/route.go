@@ -0,0 +1,28 @@
+package orderapp
+
+import (
+	"net/http"
+
+	"github.com/ardanlabs/service/app/sdk/authclient"
+	"github.com/ardanlabs/service/app/sdk/mid"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/web"
+)
+
+// Config contains all the mandatory systems required by handlers.
+type Config struct {
+	Log        *logger.Logger
+	AuthClient *authclient.Client
+}
+
+// Routes adds specific routes for this group.
+func Routes(app *web.App, cfg Config) {
+	const version = "v1"
+
+	authen := mid.Authenticate(cfg.AuthClient)
+
+	api := newApp()
+
+	app.HandlerFunc(http.MethodGet, version, "/orderby", api.query, authen)
+	app.HandlerFunc(http.MethodGet, version, "/orderby/{domain}", api.queryByDomain, authen)
+}
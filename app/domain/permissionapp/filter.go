@@ -0,0 +1,56 @@
+package permissionapp
+
+import (
+	"net/http"
+
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/business/domain/permissionbus"
+	"github.com/ardanlabs/service/business/types/role"
+)
+
+type queryParams struct {
+	Page    string
+	Rows    string
+	OrderBy string
+	Name    string
+	Role    string
+}
+
+func parseQueryParams(r *http.Request) queryParams {
+	values := r.URL.Query()
+
+	filter := queryParams{
+		Page:    values.Get("page"),
+		Rows:    values.Get("rows"),
+		OrderBy: values.Get("orderBy"),
+		Name:    values.Get("name"),
+		Role:    values.Get("role"),
+	}
+
+	return filter
+}
+
+func parseFilter(qp queryParams) (permissionbus.QueryFilter, error) {
+	var fieldErrors errs.FieldErrors
+	var filter permissionbus.QueryFilter
+
+	if qp.Name != "" {
+		filter.Name = &qp.Name
+	}
+
+	if qp.Role != "" {
+		r, err := role.Parse(qp.Role)
+		switch err {
+		case nil:
+			filter.Role = &r
+		default:
+			fieldErrors.Add("role", err)
+		}
+	}
+
+	if fieldErrors != nil {
+		return permissionbus.QueryFilter{}, fieldErrors.ToError()
+	}
+
+	return filter, nil
+}
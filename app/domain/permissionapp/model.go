@@ -0,0 +1,92 @@
+package permissionapp
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/business/domain/permissionbus"
+)
+
+// Permission represents information about an individual permission.
+type Permission struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	DateCreated string `json:"dateCreated"`
+}
+
+// Encode implements the encoder interface.
+func (app Permission) Encode() ([]byte, string, error) {
+	data, err := json.Marshal(app)
+	return data, "application/json", err
+}
+
+func toAppPermission(perm permissionbus.Permission) Permission {
+	return Permission{
+		Name:        perm.Name,
+		Description: perm.Description,
+		DateCreated: perm.DateCreated.Format(time.RFC3339),
+	}
+}
+
+func toAppPermissions(perms []permissionbus.Permission) []Permission {
+	app := make([]Permission, len(perms))
+	for i, perm := range perms {
+		app[i] = toAppPermission(perm)
+	}
+
+	return app
+}
+
+// =============================================================================
+
+// NewPermission defines the data needed to add a new permission.
+type NewPermission struct {
+	Name        string `json:"name" validate:"required"`
+	Description string `json:"description" validate:"required,max=200"`
+}
+
+// Decode implements the decoder interface.
+func (app *NewPermission) Decode(data []byte) error {
+	return json.Unmarshal(data, app)
+}
+
+// Validate checks the data in the model is considered clean.
+func (app NewPermission) Validate() error {
+	if err := errs.Check(app); err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+
+	return nil
+}
+
+func toBusNewPermission(app NewPermission) permissionbus.NewPermission {
+	return permissionbus.NewPermission{
+		Name:        app.Name,
+		Description: app.Description,
+	}
+}
+
+// =============================================================================
+
+// Grant represents a permission granted to a role.
+type Grant struct {
+	Role        string `json:"role"`
+	Permission  string `json:"permission"`
+	DateCreated string `json:"dateCreated"`
+}
+
+// Encode implements the encoder interface.
+func (app Grant) Encode() ([]byte, string, error) {
+	data, err := json.Marshal(app)
+	return data, "application/json", err
+}
+
+func toAppGrant(grant permissionbus.Grant) Grant {
+	return Grant{
+		Role:        grant.Role.String(),
+		Permission:  grant.Permission,
+		DateCreated: grant.DateCreated.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,7 @@
+package permissionapp
+
+import (
+	"github.com/ardanlabs/service/business/domain/permissionbus"
+)
+
+var orderByFields = permissionbus.OrderFields.Mappings()
@@ -0,0 +1,159 @@
+// Package permissionapp maintains the app layer api for the permission
+// domain.
+package permissionapp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/query"
+	"github.com/ardanlabs/service/business/domain/permissionbus"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/ardanlabs/service/foundation/web"
+)
+
+type app struct {
+	permissionBus *permissionbus.Business
+}
+
+func newApp(permissionBus *permissionbus.Business) *app {
+	return &app{
+		permissionBus: permissionBus,
+	}
+}
+
+func (a *app) create(ctx context.Context, r *http.Request) web.Encoder {
+	var app NewPermission
+	if err := web.Decode(r, &app); err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	perm, err := a.permissionBus.Create(ctx, toBusNewPermission(app))
+	if err != nil {
+		switch {
+		case errors.Is(err, permissionbus.ErrInvalidName):
+			return errs.NewFieldErrors("name", err)
+		case errors.Is(err, permissionbus.ErrUniqueName):
+			return errs.New(errs.Aborted, permissionbus.ErrUniqueName)
+		}
+		return errs.Newf(errs.Internal, "create: perm[%s]: %s", app.Name, err)
+	}
+
+	return toAppPermission(perm)
+}
+
+func (a *app) delete(ctx context.Context, r *http.Request) web.Encoder {
+	perm, err := a.permission(ctx, r)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	if perm.Name == permissionbus.Manage {
+		return errs.Newf(errs.FailedPrecondition, "permission[%s] can't be deleted", perm.Name)
+	}
+
+	if err := a.permissionBus.Delete(ctx, perm); err != nil {
+		return errs.Newf(errs.Internal, "delete: perm[%s]: %s", perm.Name, err)
+	}
+
+	return nil
+}
+
+// query returns the permissions that match the filter. Filtering on role
+// returns the permissions granted to that role.
+func (a *app) query(ctx context.Context, r *http.Request) web.Encoder {
+	qp := parseQueryParams(r)
+
+	page, err := page.Parse(qp.Page, qp.Rows)
+	if err != nil {
+		return errs.NewFieldErrors("page", err)
+	}
+
+	filter, err := parseFilter(qp)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	orderBy, err := order.Parse(orderByFields, qp.OrderBy, permissionbus.DefaultOrderBy)
+	if err != nil {
+		return errs.NewFieldErrors("order", err)
+	}
+
+	perms, err := a.permissionBus.Query(ctx, filter, orderBy, page)
+	if err != nil {
+		return errs.Newf(errs.Internal, "query: %s", err)
+	}
+
+	total, err := a.permissionBus.Count(ctx, filter)
+	if err != nil {
+		return errs.Newf(errs.Internal, "count: %s", err)
+	}
+
+	return query.NewResult(toAppPermissions(perms), total, page)
+}
+
+// =============================================================================
+
+func (a *app) grant(ctx context.Context, r *http.Request) web.Encoder {
+	rl, err := role.Parse(web.Param(r, "role"))
+	if err != nil {
+		return errs.NewFieldErrors("role", err)
+	}
+
+	perm, err := a.permission(ctx, r)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	grant, err := a.permissionBus.Grant(ctx, rl, perm)
+	if err != nil {
+		return errs.Newf(errs.Internal, "grant: role[%s] perm[%s]: %s", rl, perm.Name, err)
+	}
+
+	return toAppGrant(grant)
+}
+
+// revoke takes a permission away from a role. Admins always keep the
+// permission to manage permissions so nobody can be locked out.
+func (a *app) revoke(ctx context.Context, r *http.Request) web.Encoder {
+	rl, err := role.Parse(web.Param(r, "role"))
+	if err != nil {
+		return errs.NewFieldErrors("role", err)
+	}
+
+	perm, err := a.permission(ctx, r)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	if perm.Name == permissionbus.Manage && rl.Equal(role.Admin) {
+		return errs.Newf(errs.FailedPrecondition, "permission[%s] can't be revoked from role[%s]", perm.Name, rl)
+	}
+
+	if err := a.permissionBus.Revoke(ctx, rl, perm); err != nil {
+		return errs.Newf(errs.Internal, "revoke: role[%s] perm[%s]: %s", rl, perm.Name, err)
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// permission looks up the permission named in the request path.
+func (a *app) permission(ctx context.Context, r *http.Request) (permissionbus.Permission, error) {
+	name := web.Param(r, "name")
+
+	perm, err := a.permissionBus.QueryByName(ctx, name)
+	if err != nil {
+		if errors.Is(err, permissionbus.ErrNotFound) {
+			return permissionbus.Permission{}, errs.New(errs.NotFound, err)
+		}
+		return permissionbus.Permission{}, errs.Newf(errs.Internal, "querybyname: name[%s]: %s", name, err)
+	}
+
+	return perm, nil
+}
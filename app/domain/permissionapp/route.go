@@ -0,0 +1,34 @@
+package permissionapp
+
+import (
+	"net/http"
+
+	"github.com/ardanlabs/service/app/sdk/authclient"
+	"github.com/ardanlabs/service/app/sdk/mid"
+	"github.com/ardanlabs/service/business/domain/permissionbus"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/web"
+)
+
+// Config contains all the mandatory systems required by handlers.
+type Config struct {
+	Log           *logger.Logger
+	PermissionBus *permissionbus.Business
+	AuthClient    *authclient.Client
+}
+
+// Routes adds specific routes for this group.
+func Routes(app *web.App, cfg Config) {
+	const version = "v1"
+
+	authen := mid.Authenticate(cfg.AuthClient)
+	canManage := mid.RequirePermission(cfg.PermissionBus, permissionbus.Manage)
+
+	api := newApp(cfg.PermissionBus)
+
+	app.HandlerFunc(http.MethodGet, version, "/permissions", api.query, authen, canManage)
+	app.HandlerFunc(http.MethodPost, version, "/permissions", api.create, authen, canManage)
+	app.HandlerFunc(http.MethodDelete, version, "/permissions/{name}", api.delete, authen, canManage)
+	app.HandlerFunc(http.MethodPut, version, "/roles/{role}/permissions/{name}", api.grant, authen, canManage)
+	app.HandlerFunc(http.MethodDelete, version, "/roles/{role}/permissions/{name}", api.revoke, authen, canManage)
+}
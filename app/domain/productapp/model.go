@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/extid"
 	"github.com/ardanlabs/service/app/sdk/mid"
 	"github.com/ardanlabs/service/business/domain/productbus"
 	"github.com/ardanlabs/service/business/types/money"
@@ -33,8 +34,8 @@ func (app Product) Encode() ([]byte, string, error) {
 
 func toAppProduct(prd productbus.Product) Product {
 	return Product{
-		ID:          prd.ID.String(),
-		UserID:      prd.UserID.String(),
+		ID:          extid.Encode(prd.ID),
+		UserID:      extid.Encode(prd.UserID),
 		Name:        prd.Name.String(),
 		Cost:        prd.Cost.Value(),
 		Quantity:    prd.Quantity.Value(),
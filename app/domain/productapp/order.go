@@ -4,10 +4,4 @@ import (
 	"github.com/ardanlabs/service/business/domain/productbus"
 )
 
-var orderByFields = map[string]string{
-	"product_id": productbus.OrderByProductID,
-	"name":       productbus.OrderByName,
-	"cost":       productbus.OrderByCost,
-	"quantity":   productbus.OrderByQuantity,
-	"user_id":    productbus.OrderByUserID,
-}
+var orderByFields = productbus.OrderFields.Mappings()
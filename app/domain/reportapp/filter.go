@@ -0,0 +1,99 @@
+package reportapp
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/extid"
+	"github.com/ardanlabs/service/business/domain/reportbus"
+)
+
+type queryParams struct {
+	Page    string
+	Rows    string
+	OrderBy string
+	ID      string
+	UserID  string
+	Report  string
+	Channel string
+	Enabled string
+}
+
+func parseQueryParams(r *http.Request) queryParams {
+	values := r.URL.Query()
+
+	filter := queryParams{
+		Page:    values.Get("page"),
+		Rows:    values.Get("rows"),
+		OrderBy: values.Get("orderBy"),
+		ID:      values.Get("subscription_id"),
+		UserID:  values.Get("user_id"),
+		Report:  values.Get("report"),
+		Channel: values.Get("channel"),
+		Enabled: values.Get("enabled"),
+	}
+
+	return filter
+}
+
+func parseFilter(qp queryParams) (reportbus.QueryFilter, error) {
+	var fieldErrors errs.FieldErrors
+	var filter reportbus.QueryFilter
+
+	if qp.ID != "" {
+		id, err := extid.Decode(qp.ID)
+		switch err {
+		case nil:
+			filter.ID = &id
+		default:
+			fieldErrors.Add("subscription_id", err)
+		}
+	}
+
+	if qp.UserID != "" {
+		id, err := extid.Decode(qp.UserID)
+		switch err {
+		case nil:
+			filter.UserID = &id
+		default:
+			fieldErrors.Add("user_id", err)
+		}
+	}
+
+	if qp.Report != "" {
+		report, err := reportbus.ParseReport(qp.Report)
+		switch err {
+		case nil:
+			filter.Report = &report
+		default:
+			fieldErrors.Add("report", err)
+		}
+	}
+
+	if qp.Channel != "" {
+		channel, err := reportbus.ParseChannel(qp.Channel)
+		switch err {
+		case nil:
+			filter.Channel = &channel
+		default:
+			fieldErrors.Add("channel", err)
+		}
+	}
+
+	if qp.Enabled != "" {
+		enabled, err := strconv.ParseBool(qp.Enabled)
+		switch err {
+		case nil:
+			filter.Enabled = &enabled
+		default:
+			fieldErrors.Add("enabled", err)
+		}
+	}
+
+	if fieldErrors != nil {
+		return reportbus.QueryFilter{}, fieldErrors.ToError()
+	}
+
+	return filter, nil
+}
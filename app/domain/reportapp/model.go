@@ -0,0 +1,237 @@
+package reportapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"time"
+
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/extid"
+	"github.com/ardanlabs/service/app/sdk/mid"
+	"github.com/ardanlabs/service/business/domain/reportbus"
+	"github.com/ardanlabs/service/business/types/schedule"
+)
+
+// Subscription represents information about an individual report
+// subscription.
+type Subscription struct {
+	ID          string `json:"id"`
+	UserID      string `json:"userID"`
+	Report      string `json:"report"`
+	Schedule    string `json:"schedule"`
+	Channel     string `json:"channel"`
+	Target      string `json:"target"`
+	Enabled     bool   `json:"enabled"`
+	LastRun     string `json:"lastRun,omitempty"`
+	NextRun     string `json:"nextRun"`
+	DateCreated string `json:"dateCreated"`
+	DateUpdated string `json:"dateUpdated"`
+}
+
+// Encode implements the encoder interface.
+func (app Subscription) Encode() ([]byte, string, error) {
+	data, err := json.Marshal(app)
+	return data, "application/json", err
+}
+
+func toAppSubscription(sub reportbus.Subscription) Subscription {
+	app := Subscription{
+		ID:          extid.Encode(sub.ID),
+		UserID:      extid.Encode(sub.UserID),
+		Report:      sub.Report.String(),
+		Schedule:    sub.Schedule.String(),
+		Channel:     sub.Channel.String(),
+		Target:      sub.Target,
+		Enabled:     sub.Enabled,
+		NextRun:     sub.NextRun.Format(time.RFC3339),
+		DateCreated: sub.DateCreated.Format(time.RFC3339),
+		DateUpdated: sub.DateUpdated.Format(time.RFC3339),
+	}
+
+	if !sub.LastRun.IsZero() {
+		app.LastRun = sub.LastRun.Format(time.RFC3339)
+	}
+
+	return app
+}
+
+func toAppSubscriptions(subs []reportbus.Subscription) []Subscription {
+	app := make([]Subscription, len(subs))
+	for i, sub := range subs {
+		app[i] = toAppSubscription(sub)
+	}
+
+	return app
+}
+
+// =============================================================================
+
+// Delivery represents information about an attempt to deliver a report.
+type Delivery struct {
+	ID             string `json:"id"`
+	SubscriptionID string `json:"subscriptionID"`
+	Status         string `json:"status"`
+	Error          string `json:"error,omitempty"`
+	DateCreated    string `json:"dateCreated"`
+}
+
+func toAppDeliveries(dlvs []reportbus.Delivery) []Delivery {
+	app := make([]Delivery, len(dlvs))
+	for i, dlv := range dlvs {
+		app[i] = Delivery{
+			ID:             extid.Encode(dlv.ID),
+			SubscriptionID: extid.Encode(dlv.SubscriptionID),
+			Status:         dlv.Status,
+			Error:          dlv.Error,
+			DateCreated:    dlv.DateCreated.Format(time.RFC3339),
+		}
+	}
+
+	return app
+}
+
+// =============================================================================
+
+// NewSubscription defines the data needed to add a new subscription.
+type NewSubscription struct {
+	Report   string `json:"report" validate:"required"`
+	Schedule string `json:"schedule" validate:"required"`
+	Channel  string `json:"channel" validate:"required"`
+	Target   string `json:"target" validate:"required"`
+}
+
+// Decode implements the decoder interface.
+func (app *NewSubscription) Decode(data []byte) error {
+	return json.Unmarshal(data, app)
+}
+
+// Validate checks the data in the model is considered clean.
+func (app NewSubscription) Validate() error {
+	if err := errs.Check(app); err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+
+	return nil
+}
+
+func toBusNewSubscription(ctx context.Context, app NewSubscription) (reportbus.NewSubscription, error) {
+	userID, err := mid.GetUserID(ctx)
+	if err != nil {
+		return reportbus.NewSubscription{}, fmt.Errorf("getuserid: %w", err)
+	}
+
+	report, err := reportbus.ParseReport(app.Report)
+	if err != nil {
+		return reportbus.NewSubscription{}, fmt.Errorf("parse: %w", err)
+	}
+
+	sched, err := schedule.Parse(app.Schedule)
+	if err != nil {
+		return reportbus.NewSubscription{}, fmt.Errorf("parse: %w", err)
+	}
+
+	channel, err := reportbus.ParseChannel(app.Channel)
+	if err != nil {
+		return reportbus.NewSubscription{}, fmt.Errorf("parse: %w", err)
+	}
+
+	if err := checkTarget(channel, app.Target); err != nil {
+		return reportbus.NewSubscription{}, err
+	}
+
+	bus := reportbus.NewSubscription{
+		UserID:   userID,
+		Report:   report,
+		Schedule: sched,
+		Channel:  channel,
+		Target:   app.Target,
+	}
+
+	return bus, nil
+}
+
+// =============================================================================
+
+// UpdateSubscription defines the data needed to update a subscription.
+type UpdateSubscription struct {
+	Schedule *string `json:"schedule"`
+	Channel  *string `json:"channel"`
+	Target   *string `json:"target"`
+	Enabled  *bool   `json:"enabled"`
+}
+
+// Decode implements the decoder interface.
+func (app *UpdateSubscription) Decode(data []byte) error {
+	return json.Unmarshal(data, app)
+}
+
+// Validate checks the data in the model is considered clean.
+func (app UpdateSubscription) Validate() error {
+	if err := errs.Check(app); err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+
+	return nil
+}
+
+func toBusUpdateSubscription(sub reportbus.Subscription, app UpdateSubscription) (reportbus.UpdateSubscription, error) {
+	bus := reportbus.UpdateSubscription{
+		Target:  app.Target,
+		Enabled: app.Enabled,
+	}
+
+	if app.Schedule != nil {
+		sched, err := schedule.Parse(*app.Schedule)
+		if err != nil {
+			return reportbus.UpdateSubscription{}, fmt.Errorf("parse: %w", err)
+		}
+		bus.Schedule = &sched
+	}
+
+	channel := sub.Channel
+	if app.Channel != nil {
+		var err error
+		channel, err = reportbus.ParseChannel(*app.Channel)
+		if err != nil {
+			return reportbus.UpdateSubscription{}, fmt.Errorf("parse: %w", err)
+		}
+		bus.Channel = &channel
+	}
+
+	target := sub.Target
+	if app.Target != nil {
+		target = *app.Target
+	}
+
+	if err := checkTarget(channel, target); err != nil {
+		return reportbus.UpdateSubscription{}, err
+	}
+
+	return bus, nil
+}
+
+// checkTarget validates the target is an address the channel can deliver to.
+func checkTarget(channel reportbus.Channel, target string) error {
+	switch channel {
+	case reportbus.ChannelEmail:
+		if _, err := mail.ParseAddress(target); err != nil {
+			return fmt.Errorf("target: %w", err)
+		}
+
+	case reportbus.ChannelWebhook:
+		u, err := url.Parse(target)
+		if err != nil {
+			return fmt.Errorf("target: %w", err)
+		}
+
+		if u.Scheme != "https" && u.Scheme != "http" || u.Host == "" {
+			return errors.New("target: webhook must be an absolute http(s) url")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,7 @@
+package reportapp
+
+import (
+	"github.com/ardanlabs/service/business/domain/reportbus"
+)
+
+var orderByFields = reportbus.OrderFields.Mappings()
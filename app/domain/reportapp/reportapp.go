@@ -0,0 +1,167 @@
+// Package reportapp maintains the app layer api for the report subscription
+// domain.
+package reportapp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/extid"
+	"github.com/ardanlabs/service/app/sdk/query"
+	"github.com/ardanlabs/service/business/domain/reportbus"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/foundation/web"
+)
+
+type app struct {
+	reportBus *reportbus.Business
+}
+
+func newApp(reportBus *reportbus.Business) *app {
+	return &app{
+		reportBus: reportBus,
+	}
+}
+
+func (a *app) create(ctx context.Context, r *http.Request) web.Encoder {
+	var app NewSubscription
+	if err := web.Decode(r, &app); err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	ns, err := toBusNewSubscription(ctx, app)
+	if err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	sub, err := a.reportBus.Create(ctx, ns)
+	if err != nil {
+		return errs.Newf(errs.Internal, "create: sub[%+v]: %s", app, err)
+	}
+
+	return toAppSubscription(sub)
+}
+
+func (a *app) update(ctx context.Context, r *http.Request) web.Encoder {
+	var app UpdateSubscription
+	if err := web.Decode(r, &app); err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	sub, err := a.subscription(ctx, r)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	us, err := toBusUpdateSubscription(sub, app)
+	if err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	updSub, err := a.reportBus.Update(ctx, sub, us)
+	if err != nil {
+		return errs.Newf(errs.Internal, "update: subscriptionID[%s] us[%+v]: %s", sub.ID, us, err)
+	}
+
+	return toAppSubscription(updSub)
+}
+
+func (a *app) delete(ctx context.Context, r *http.Request) web.Encoder {
+	sub, err := a.subscription(ctx, r)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	if err := a.reportBus.Delete(ctx, sub); err != nil {
+		return errs.Newf(errs.Internal, "delete: subscriptionID[%s]: %s", sub.ID, err)
+	}
+
+	return nil
+}
+
+func (a *app) query(ctx context.Context, r *http.Request) web.Encoder {
+	qp := parseQueryParams(r)
+
+	page, err := page.Parse(qp.Page, qp.Rows)
+	if err != nil {
+		return errs.NewFieldErrors("page", err)
+	}
+
+	filter, err := parseFilter(qp)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	orderBy, err := order.Parse(orderByFields, qp.OrderBy, reportbus.DefaultOrderBy)
+	if err != nil {
+		return errs.NewFieldErrors("order", err)
+	}
+
+	subs, err := a.reportBus.Query(ctx, filter, orderBy, page)
+	if err != nil {
+		return errs.Newf(errs.Internal, "query: %s", err)
+	}
+
+	total, err := a.reportBus.Count(ctx, filter)
+	if err != nil {
+		return errs.Newf(errs.Internal, "count: %s", err)
+	}
+
+	return query.NewResult(toAppSubscriptions(subs), total, page)
+}
+
+func (a *app) queryByID(ctx context.Context, r *http.Request) web.Encoder {
+	sub, err := a.subscription(ctx, r)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	return toAppSubscription(sub)
+}
+
+func (a *app) queryDeliveries(ctx context.Context, r *http.Request) web.Encoder {
+	qp := parseQueryParams(r)
+
+	page, err := page.Parse(qp.Page, qp.Rows)
+	if err != nil {
+		return errs.NewFieldErrors("page", err)
+	}
+
+	sub, err := a.subscription(ctx, r)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	dlvs, err := a.reportBus.QueryDeliveries(ctx, sub.ID, page)
+	if err != nil {
+		return errs.Newf(errs.Internal, "querydeliveries: %s", err)
+	}
+
+	total, err := a.reportBus.CountDeliveries(ctx, sub.ID)
+	if err != nil {
+		return errs.Newf(errs.Internal, "countdeliveries: %s", err)
+	}
+
+	return query.NewResult(toAppDeliveries(dlvs), total, page)
+}
+
+// subscription looks up the subscription identified in the request path.
+func (a *app) subscription(ctx context.Context, r *http.Request) (reportbus.Subscription, error) {
+	id, err := extid.Decode(web.Param(r, "subscription_id"))
+	if err != nil {
+		return reportbus.Subscription{}, errs.New(errs.InvalidArgument, err)
+	}
+
+	sub, err := a.reportBus.QueryByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, reportbus.ErrNotFound) {
+			return reportbus.Subscription{}, errs.New(errs.NotFound, err)
+		}
+		return reportbus.Subscription{}, errs.Newf(errs.Internal, "querybyid: subscriptionID[%s]: %s", id, err)
+	}
+
+	return sub, nil
+}
@@ -0,0 +1,36 @@
+package reportapp
+
+import (
+	"net/http"
+
+	"github.com/ardanlabs/service/app/sdk/auth"
+	"github.com/ardanlabs/service/app/sdk/authclient"
+	"github.com/ardanlabs/service/app/sdk/mid"
+	"github.com/ardanlabs/service/business/domain/reportbus"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/web"
+)
+
+// Config contains all the mandatory systems required by handlers.
+type Config struct {
+	Log        *logger.Logger
+	ReportBus  *reportbus.Business
+	AuthClient *authclient.Client
+}
+
+// Routes adds specific routes for this group.
+func Routes(app *web.App, cfg Config) {
+	const version = "v1"
+
+	authen := mid.Authenticate(cfg.AuthClient)
+	ruleAdmin := mid.Authorize(cfg.AuthClient, auth.RuleAdminOnly)
+
+	api := newApp(cfg.ReportBus)
+
+	app.HandlerFunc(http.MethodGet, version, "/reports/subscriptions", api.query, authen, ruleAdmin)
+	app.HandlerFunc(http.MethodGet, version, "/reports/subscriptions/{subscription_id}", api.queryByID, authen, ruleAdmin)
+	app.HandlerFunc(http.MethodGet, version, "/reports/subscriptions/{subscription_id}/deliveries", api.queryDeliveries, authen, ruleAdmin)
+	app.HandlerFunc(http.MethodPost, version, "/reports/subscriptions", api.create, authen, ruleAdmin)
+	app.HandlerFunc(http.MethodPut, version, "/reports/subscriptions/{subscription_id}", api.update, authen, ruleAdmin)
+	app.HandlerFunc(http.MethodDelete, version, "/reports/subscriptions/{subscription_id}", api.delete, authen, ruleAdmin)
+}
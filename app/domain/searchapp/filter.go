@@ -0,0 +1,62 @@
+package searchapp
+
+import (
+	"net/http"
+
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/extid"
+	"github.com/ardanlabs/service/business/domain/searchbus"
+)
+
+type queryParams struct {
+	Page    string
+	Rows    string
+	OrderBy string
+	ID      string
+	Domain  string
+	Name    string
+}
+
+func parseQueryParams(r *http.Request) queryParams {
+	values := r.URL.Query()
+
+	filter := queryParams{
+		Page:    values.Get("page"),
+		Rows:    values.Get("rows"),
+		OrderBy: values.Get("orderBy"),
+		ID:      values.Get("search_id"),
+		Domain:  values.Get("domain"),
+		Name:    values.Get("name"),
+	}
+
+	return filter
+}
+
+func parseFilter(qp queryParams) (searchbus.QueryFilter, error) {
+	var fieldErrors errs.FieldErrors
+	var filter searchbus.QueryFilter
+
+	if qp.ID != "" {
+		id, err := extid.Decode(qp.ID)
+		switch err {
+		case nil:
+			filter.ID = &id
+		default:
+			fieldErrors.Add("search_id", err)
+		}
+	}
+
+	if qp.Domain != "" {
+		filter.Domain = &qp.Domain
+	}
+
+	if qp.Name != "" {
+		filter.Name = &qp.Name
+	}
+
+	if fieldErrors != nil {
+		return searchbus.QueryFilter{}, fieldErrors.ToError()
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,227 @@
+package searchapp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/extid"
+	"github.com/ardanlabs/service/business/domain/searchbus"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/google/uuid"
+)
+
+// domainPaths maps the domains that can be searched to the routes that
+// query them.
+var domainPaths = map[string]string{
+	"apikey":   "apikeys",
+	"audit":    "audits",
+	"group":    "groups",
+	"home":     "homes",
+	"product":  "products",
+	"template": "templates",
+	"user":     "users",
+	"vproduct": "vproducts",
+}
+
+// pagingParams are the query parameters that belong to the request running
+// a search rather than to the search itself.
+var pagingParams = []string{"page", "rows", "cursor"}
+
+// Search represents information about an individual saved search. The query
+// holds the filter and ordering in the form the domain's route accepts.
+type Search struct {
+	ID          string `json:"id"`
+	UserID      string `json:"userID"`
+	Name        string `json:"name"`
+	Domain      string `json:"domain"`
+	Query       string `json:"query"`
+	Shared      bool   `json:"shared"`
+	DateCreated string `json:"dateCreated"`
+	DateUpdated string `json:"dateUpdated"`
+}
+
+// Encode implements the encoder interface.
+func (app Search) Encode() ([]byte, string, error) {
+	data, err := json.Marshal(app)
+	return data, "application/json", err
+}
+
+func toAppSearch(srch searchbus.Search) Search {
+	return Search{
+		ID:          extid.Encode(srch.ID),
+		UserID:      extid.Encode(srch.UserID),
+		Name:        srch.Name,
+		Domain:      srch.Domain,
+		Query:       toQuery(srch, nil).Encode(),
+		Shared:      srch.Shared,
+		DateCreated: srch.DateCreated.Format(time.RFC3339),
+		DateUpdated: srch.DateUpdated.Format(time.RFC3339),
+	}
+}
+
+func toAppSearches(srchs []searchbus.Search) []Search {
+	app := make([]Search, len(srchs))
+	for i, srch := range srchs {
+		app[i] = toAppSearch(srch)
+	}
+
+	return app
+}
+
+// toQuery returns the query parameters for running the search. The paging
+// parameters of the request running it are carried over.
+func toQuery(srch searchbus.Search, paging url.Values) url.Values {
+	values := make(url.Values, len(srch.Filter)+1)
+	for k, v := range srch.Filter {
+		values.Set(k, v)
+	}
+
+	if fields, exists := order.Lookup(srch.Domain); exists && srch.OrderBy.Field != "" {
+		names := make(map[string]string)
+		for _, fld := range fields.List() {
+			names[fld.Key] = fld.Name
+		}
+
+		var parts []string
+		for _, by := range srch.OrderBy.Columns() {
+			if name, exists := names[by.Field]; exists {
+				parts = append(parts, name, by.Direction)
+			}
+		}
+
+		if len(parts) > 0 {
+			values.Set("orderBy", strings.Join(parts, ","))
+		}
+	}
+
+	for _, k := range pagingParams {
+		if v := paging.Get(k); v != "" {
+			values.Set(k, v)
+		}
+	}
+
+	return values
+}
+
+// parseQuery splits the query parameters of a search into the filter and the
+// ordering. Paging parameters aren't saved with a search.
+func parseQuery(domain string, query string) (map[string]string, order.By, error) {
+	fields, exists := order.Lookup(domain)
+	if !exists {
+		return nil, order.By{}, fmt.Errorf("domain[%s]: %w", domain, searchbus.ErrUnknownDomain)
+	}
+
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, order.By{}, fmt.Errorf("parse query: %w", err)
+	}
+
+	orderBy, err := order.Parse(fields.Mappings(), values.Get("orderBy"), order.By{})
+	if err != nil {
+		return nil, order.By{}, err
+	}
+
+	values.Del("orderBy")
+	for _, k := range pagingParams {
+		values.Del(k)
+	}
+
+	filter := make(map[string]string, len(values))
+	for k := range values {
+		filter[k] = values.Get(k)
+	}
+
+	return filter, orderBy, nil
+}
+
+// =============================================================================
+
+// NewSearch defines the data needed to save a new search.
+type NewSearch struct {
+	Name   string `json:"name" validate:"required"`
+	Domain string `json:"domain" validate:"required"`
+	Query  string `json:"query"`
+	Shared bool   `json:"shared"`
+}
+
+// Decode implements the decoder interface.
+func (app *NewSearch) Decode(data []byte) error {
+	return json.Unmarshal(data, app)
+}
+
+// Validate checks the data in the model is considered clean.
+func (app NewSearch) Validate() error {
+	if err := errs.Check(app); err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+
+	if _, exists := domainPaths[app.Domain]; !exists {
+		return errs.NewFieldErrors("domain", fmt.Errorf("domain[%s]: %w", app.Domain, searchbus.ErrUnknownDomain))
+	}
+
+	return nil
+}
+
+func toBusNewSearch(userID uuid.UUID, app NewSearch) (searchbus.NewSearch, error) {
+	filter, orderBy, err := parseQuery(app.Domain, app.Query)
+	if err != nil {
+		return searchbus.NewSearch{}, errs.NewFieldErrors("query", err)
+	}
+
+	bus := searchbus.NewSearch{
+		UserID:  userID,
+		Name:    app.Name,
+		Domain:  app.Domain,
+		Filter:  filter,
+		OrderBy: orderBy,
+		Shared:  app.Shared,
+	}
+
+	return bus, nil
+}
+
+// =============================================================================
+
+// UpdateSearch defines the data needed to update a saved search.
+type UpdateSearch struct {
+	Name   *string `json:"name"`
+	Query  *string `json:"query"`
+	Shared *bool   `json:"shared"`
+}
+
+// Decode implements the decoder interface.
+func (app *UpdateSearch) Decode(data []byte) error {
+	return json.Unmarshal(data, app)
+}
+
+// Validate checks the data in the model is considered clean.
+func (app UpdateSearch) Validate() error {
+	if app.Name != nil && *app.Name == "" {
+		return errs.NewFieldErrors("name", fmt.Errorf("name can't be empty"))
+	}
+
+	return nil
+}
+
+func toBusUpdateSearch(domain string, app UpdateSearch) (searchbus.UpdateSearch, error) {
+	bus := searchbus.UpdateSearch{
+		Name:   app.Name,
+		Shared: app.Shared,
+	}
+
+	if app.Query != nil {
+		filter, orderBy, err := parseQuery(domain, *app.Query)
+		if err != nil {
+			return searchbus.UpdateSearch{}, errs.NewFieldErrors("query", err)
+		}
+
+		bus.Filter = filter
+		bus.OrderBy = &orderBy
+	}
+
+	return bus, nil
+}
@@ -0,0 +1,7 @@
+package searchapp
+
+import (
+	"github.com/ardanlabs/service/business/domain/searchbus"
+)
+
+var orderByFields = searchbus.OrderFields.Mappings()
@@ -0,0 +1,36 @@
+package searchapp
+
+import (
+	"net/http"
+
+	"github.com/ardanlabs/service/app/sdk/auth"
+	"github.com/ardanlabs/service/app/sdk/authclient"
+	"github.com/ardanlabs/service/app/sdk/mid"
+	"github.com/ardanlabs/service/business/domain/searchbus"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/web"
+)
+
+// Config contains all the mandatory systems required by handlers.
+type Config struct {
+	Log        *logger.Logger
+	SearchBus  *searchbus.Business
+	AuthClient *authclient.Client
+}
+
+// Routes adds specific routes for this group.
+func Routes(app *web.App, cfg Config) {
+	const version = "v1"
+
+	authen := mid.Authenticate(cfg.AuthClient)
+	ruleAdmin := mid.Authorize(cfg.AuthClient, auth.RuleAdminOnly)
+
+	api := newApp(cfg.SearchBus)
+
+	app.HandlerFunc(http.MethodGet, version, "/searches", api.query, authen, ruleAdmin)
+	app.HandlerFunc(http.MethodGet, version, "/searches/{search_id}", api.queryByID, authen, ruleAdmin)
+	app.HandlerFunc(http.MethodGet, version, "/searches/run/{domain}/{name}", api.run, authen, ruleAdmin)
+	app.HandlerFunc(http.MethodPost, version, "/searches", api.create, authen, ruleAdmin)
+	app.HandlerFunc(http.MethodPut, version, "/searches/{search_id}", api.update, authen, ruleAdmin)
+	app.HandlerFunc(http.MethodDelete, version, "/searches/{search_id}", api.delete, authen, ruleAdmin)
+}
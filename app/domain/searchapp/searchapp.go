@@ -0,0 +1,233 @@
+// Package searchapp maintains the app layer api for the saved search domain.
+package searchapp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/extid"
+	"github.com/ardanlabs/service/app/sdk/mid"
+	"github.com/ardanlabs/service/app/sdk/query"
+	"github.com/ardanlabs/service/business/domain/searchbus"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/foundation/web"
+)
+
+type app struct {
+	searchBus *searchbus.Business
+}
+
+func newApp(searchBus *searchbus.Business) *app {
+	return &app{
+		searchBus: searchBus,
+	}
+}
+
+func (a *app) create(ctx context.Context, r *http.Request) web.Encoder {
+	var app NewSearch
+	if err := web.Decode(r, &app); err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	userID, err := mid.GetUserID(ctx)
+	if err != nil {
+		return errs.New(errs.Unauthenticated, err)
+	}
+
+	ns, err := toBusNewSearch(userID, app)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	srch, err := a.searchBus.Create(ctx, ns)
+	if err != nil {
+		return toAppError(err, "create: srch[%s/%s]: %s", ns.Domain, ns.Name)
+	}
+
+	return toAppSearch(srch)
+}
+
+func (a *app) update(ctx context.Context, r *http.Request) web.Encoder {
+	var app UpdateSearch
+	if err := web.Decode(r, &app); err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	srch, err := a.ownSearch(ctx, r)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	us, err := toBusUpdateSearch(srch.Domain, app)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	updSrch, err := a.searchBus.Update(ctx, srch, us)
+	if err != nil {
+		return toAppError(err, "update: searchID[%s]: %s", srch.ID)
+	}
+
+	return toAppSearch(updSrch)
+}
+
+func (a *app) delete(ctx context.Context, r *http.Request) web.Encoder {
+	srch, err := a.ownSearch(ctx, r)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	if err := a.searchBus.Delete(ctx, srch); err != nil {
+		return errs.Newf(errs.Internal, "delete: searchID[%s]: %s", srch.ID, err)
+	}
+
+	return nil
+}
+
+// query returns the searches the caller saved along with the ones shared by
+// other admins.
+func (a *app) query(ctx context.Context, r *http.Request) web.Encoder {
+	qp := parseQueryParams(r)
+
+	page, err := page.Parse(qp.Page, qp.Rows)
+	if err != nil {
+		return errs.NewFieldErrors("page", err)
+	}
+
+	filter, err := parseFilter(qp)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	userID, err := mid.GetUserID(ctx)
+	if err != nil {
+		return errs.New(errs.Unauthenticated, err)
+	}
+	filter.VisibleTo = &userID
+
+	orderBy, err := order.Parse(orderByFields, qp.OrderBy, searchbus.DefaultOrderBy)
+	if err != nil {
+		return errs.NewFieldErrors("order", err)
+	}
+
+	srchs, err := a.searchBus.Query(ctx, filter, orderBy, page)
+	if err != nil {
+		return errs.Newf(errs.Internal, "query: %s", err)
+	}
+
+	total, err := a.searchBus.Count(ctx, filter)
+	if err != nil {
+		return errs.Newf(errs.Internal, "count: %s", err)
+	}
+
+	return query.NewResult(toAppSearches(srchs), total, page)
+}
+
+func (a *app) queryByID(ctx context.Context, r *http.Request) web.Encoder {
+	srch, err := a.search(ctx, r)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	return toAppSearch(srch)
+}
+
+// run redirects to the domain's route with the filter and ordering of the
+// named search, so the results come back exactly as if the query had been
+// built by hand. Paging parameters on the request are passed along.
+func (a *app) run(ctx context.Context, r *http.Request) web.Encoder {
+	domain := web.Param(r, "domain")
+
+	path, exists := domainPaths[domain]
+	if !exists {
+		return errs.Newf(errs.NotFound, "domain[%s] can't be searched", domain)
+	}
+
+	userID, err := mid.GetUserID(ctx)
+	if err != nil {
+		return errs.New(errs.Unauthenticated, err)
+	}
+
+	name := web.Param(r, "name")
+
+	srch, err := a.searchBus.QueryByName(ctx, userID, domain, name)
+	if err != nil {
+		if errors.Is(err, searchbus.ErrNotFound) {
+			return errs.New(errs.NotFound, err)
+		}
+		return errs.Newf(errs.Internal, "querybyname: domain[%s] name[%s]: %s", domain, name, err)
+	}
+
+	url := "/v1/" + path
+	if values := toQuery(srch, r.URL.Query()); len(values) > 0 {
+		url += "?" + values.Encode()
+	}
+
+	http.Redirect(web.GetWriter(ctx), r, url, http.StatusSeeOther)
+
+	return web.NewNoResponse()
+}
+
+// =============================================================================
+
+// search looks up the search identified in the request path. Searches that
+// were saved by someone else are only found when they are shared.
+func (a *app) search(ctx context.Context, r *http.Request) (searchbus.Search, error) {
+	id, err := extid.Decode(web.Param(r, "search_id"))
+	if err != nil {
+		return searchbus.Search{}, errs.New(errs.InvalidArgument, err)
+	}
+
+	userID, err := mid.GetUserID(ctx)
+	if err != nil {
+		return searchbus.Search{}, errs.New(errs.Unauthenticated, err)
+	}
+
+	srch, err := a.searchBus.QueryByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, searchbus.ErrNotFound) {
+			return searchbus.Search{}, errs.New(errs.NotFound, err)
+		}
+		return searchbus.Search{}, errs.Newf(errs.Internal, "querybyid: searchID[%s]: %s", id, err)
+	}
+
+	if srch.UserID != userID && !srch.Shared {
+		return searchbus.Search{}, errs.New(errs.NotFound, searchbus.ErrNotFound)
+	}
+
+	return srch, nil
+}
+
+// ownSearch looks up the search identified in the request path, which must
+// have been saved by the caller.
+func (a *app) ownSearch(ctx context.Context, r *http.Request) (searchbus.Search, error) {
+	srch, err := a.search(ctx, r)
+	if err != nil {
+		return searchbus.Search{}, err
+	}
+
+	userID, err := mid.GetUserID(ctx)
+	if err != nil {
+		return searchbus.Search{}, errs.New(errs.Unauthenticated, err)
+	}
+
+	if srch.UserID != userID {
+		return searchbus.Search{}, errs.Newf(errs.PermissionDenied, "searchID[%s] belongs to another user", srch.ID)
+	}
+
+	return srch, nil
+}
+
+func toAppError(err error, format string, args ...any) *errs.Error {
+	switch {
+	case errors.Is(err, searchbus.ErrUniqueName):
+		return errs.New(errs.Aborted, searchbus.ErrUniqueName)
+	case errors.Is(err, searchbus.ErrUnknownDomain), errors.Is(err, searchbus.ErrUnknownOrder):
+		return errs.NewFieldErrors("query", err)
+	}
+
+	return errs.Newf(errs.Internal, format, append(args, err)...)
+}
@@ -0,0 +1,31 @@
+package streamapp
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ardanlabs/service/app/sdk/authclient"
+	"github.com/ardanlabs/service/app/sdk/mid"
+	"github.com/ardanlabs/service/app/sdk/stream"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/web"
+)
+
+// Config contains all the mandatory systems required by handlers.
+type Config struct {
+	Log        *logger.Logger
+	Hub        *stream.Hub
+	AuthClient *authclient.Client
+	Heartbeat  time.Duration
+}
+
+// Routes adds specific routes for this group.
+func Routes(app *web.App, cfg Config) {
+	const version = "v1"
+
+	authen := mid.Authenticate(cfg.AuthClient)
+
+	api := newApp(cfg.Log, cfg.Hub, cfg.Heartbeat)
+
+	app.HandlerFunc(http.MethodGet, version, "/events", api.events, authen)
+}
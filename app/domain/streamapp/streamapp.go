@@ -0,0 +1,136 @@
+// Package streamapp maintains the app layer api for the event stream.
+package streamapp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/ardanlabs/service/app/sdk/auth"
+	"github.com/ardanlabs/service/app/sdk/mid"
+	"github.com/ardanlabs/service/app/sdk/stream"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/sse"
+	"github.com/ardanlabs/service/foundation/web"
+	"github.com/google/uuid"
+)
+
+// retry is how long clients wait before reconnecting when the stream ends.
+const retry = 3 * time.Second
+
+type app struct {
+	log       *logger.Logger
+	hub       *stream.Hub
+	heartbeat time.Duration
+}
+
+func newApp(log *logger.Logger, hub *stream.Hub, heartbeat time.Duration) *app {
+	return &app{
+		log:       log,
+		hub:       hub,
+		heartbeat: heartbeat,
+	}
+}
+
+// events streams the events the caller is allowed to see until the client
+// goes away, the hub is closed or the caller's token expires. A client
+// that reconnects and has missed events is sent a reset event, which tells
+// it to reload what it shows.
+func (a *app) events(ctx context.Context, r *http.Request) web.Encoder {
+	claims := mid.GetClaims(ctx)
+	subjectID := mid.GetSubjectID(ctx)
+
+	sub, missed := a.hub.Subscribe(r.Header.Get("Last-Event-ID"))
+	defer sub.Close()
+
+	s, err := sse.Start(web.GetWriter(ctx), web.GetConn(ctx), retry)
+	if err != nil {
+		a.log.Error(ctx, "stream", "status", "start", "ERROR", err)
+		return web.NewNoResponse()
+	}
+
+	if missed {
+		if err := s.Send(sse.Event{Name: "reset", Data: []byte("{}")}); err != nil {
+			return web.NewNoResponse()
+		}
+	}
+
+	ticker := time.NewTicker(a.heartbeat)
+	defer ticker.Stop()
+
+	var expired <-chan time.Time
+	if claims.ExpiresAt != nil {
+		timer := time.NewTimer(time.Until(claims.ExpiresAt.Time))
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	// The context handed to the handler is bounded by the authentication
+	// timeout, the request's own context lasts as long as the client is
+	// connected.
+	done := r.Context().Done()
+
+	for {
+		select {
+		case item, ok := <-sub.C():
+			if !ok {
+				return web.NewNoResponse()
+			}
+
+			if !visible(claims, subjectID, item.Value) {
+				continue
+			}
+
+			data, err := json.Marshal(item.Value)
+			if err != nil {
+				a.log.Error(ctx, "stream", "status", "marshal", "ERROR", err)
+				continue
+			}
+
+			e := sse.Event{
+				ID:   a.hub.ID(item),
+				Name: item.Value.Domain + "." + item.Value.Action,
+				Data: data,
+			}
+
+			if err := s.Send(e); err != nil {
+				return web.NewNoResponse()
+			}
+
+		case <-ticker.C:
+			if err := s.Ping(); err != nil {
+				return web.NewNoResponse()
+			}
+
+		case <-expired:
+			return web.NewNoResponse()
+
+		case <-done:
+			return web.NewNoResponse()
+		}
+	}
+}
+
+// visible reports whether the caller may see the event. Callers only see
+// the events of their own tenant, apart from break-glass callers who see
+// every tenant. Admins see the events for every user of the tenant, other
+// users only the ones about themselves.
+func visible(claims auth.Claims, subjectID uuid.UUID, e stream.Event) bool {
+	if claims.BreakGlass {
+		return true
+	}
+
+	tenantID, err := claims.TenantID()
+	if err != nil || e.TenantID != tenantID {
+		return false
+	}
+
+	if slices.Contains(claims.Roles, role.Admin.String()) {
+		return true
+	}
+
+	return e.UserID == subjectID
+}
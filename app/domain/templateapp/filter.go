@@ -0,0 +1,81 @@
+package templateapp
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/extid"
+	"github.com/ardanlabs/service/business/domain/templatebus"
+)
+
+type queryParams struct {
+	Page    string
+	Rows    string
+	OrderBy string
+	ID      string
+	Name    string
+	Locale  string
+	Enabled string
+}
+
+func parseQueryParams(r *http.Request) queryParams {
+	values := r.URL.Query()
+
+	filter := queryParams{
+		Page:    values.Get("page"),
+		Rows:    values.Get("rows"),
+		OrderBy: values.Get("orderBy"),
+		ID:      values.Get("template_id"),
+		Name:    values.Get("name"),
+		Locale:  values.Get("locale"),
+		Enabled: values.Get("enabled"),
+	}
+
+	return filter
+}
+
+func parseFilter(qp queryParams) (templatebus.QueryFilter, error) {
+	var fieldErrors errs.FieldErrors
+	var filter templatebus.QueryFilter
+
+	if qp.ID != "" {
+		id, err := extid.Decode(qp.ID)
+		switch err {
+		case nil:
+			filter.ID = &id
+		default:
+			fieldErrors.Add("template_id", err)
+		}
+	}
+
+	if qp.Name != "" {
+		filter.Name = &qp.Name
+	}
+
+	if qp.Locale != "" {
+		locale, err := templatebus.ParseLocale(qp.Locale)
+		switch err {
+		case nil:
+			filter.Locale = &locale
+		default:
+			fieldErrors.Add("locale", err)
+		}
+	}
+
+	if qp.Enabled != "" {
+		enabled, err := strconv.ParseBool(qp.Enabled)
+		switch err {
+		case nil:
+			filter.Enabled = &enabled
+		default:
+			fieldErrors.Add("enabled", err)
+		}
+	}
+
+	if fieldErrors != nil {
+		return templatebus.QueryFilter{}, fieldErrors.ToError()
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,172 @@
+package templateapp
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/extid"
+	"github.com/ardanlabs/service/business/domain/templatebus"
+)
+
+// Template represents information about an individual email template.
+type Template struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Locale      string `json:"locale"`
+	Subject     string `json:"subject"`
+	Body        string `json:"body"`
+	Enabled     bool   `json:"enabled"`
+	DateCreated string `json:"dateCreated"`
+	DateUpdated string `json:"dateUpdated"`
+}
+
+// Encode implements the encoder interface.
+func (app Template) Encode() ([]byte, string, error) {
+	data, err := json.Marshal(app)
+	return data, "application/json", err
+}
+
+func toAppTemplate(tmpl templatebus.Template) Template {
+	return Template{
+		ID:          extid.Encode(tmpl.ID),
+		Name:        tmpl.Name,
+		Locale:      tmpl.Locale,
+		Subject:     tmpl.Subject,
+		Body:        tmpl.Body,
+		Enabled:     tmpl.Enabled,
+		DateCreated: tmpl.DateCreated.Format(time.RFC3339),
+		DateUpdated: tmpl.DateUpdated.Format(time.RFC3339),
+	}
+}
+
+func toAppTemplates(tmpls []templatebus.Template) []Template {
+	app := make([]Template, len(tmpls))
+	for i, tmpl := range tmpls {
+		app[i] = toAppTemplate(tmpl)
+	}
+
+	return app
+}
+
+// =============================================================================
+
+// NewTemplate defines the data needed to add a new template.
+type NewTemplate struct {
+	Name    string `json:"name" validate:"required"`
+	Locale  string `json:"locale" validate:"required"`
+	Subject string `json:"subject" validate:"required"`
+	Body    string `json:"body" validate:"required"`
+}
+
+// Decode implements the decoder interface.
+func (app *NewTemplate) Decode(data []byte) error {
+	return json.Unmarshal(data, app)
+}
+
+// Validate checks the data in the model is considered clean.
+func (app NewTemplate) Validate() error {
+	if err := errs.Check(app); err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+
+	return nil
+}
+
+func toBusNewTemplate(app NewTemplate) (templatebus.NewTemplate, error) {
+	locale, err := templatebus.ParseLocale(app.Locale)
+	if err != nil {
+		return templatebus.NewTemplate{}, fmt.Errorf("parse: %w", err)
+	}
+
+	bus := templatebus.NewTemplate{
+		Name:    app.Name,
+		Locale:  locale,
+		Subject: app.Subject,
+		Body:    app.Body,
+	}
+
+	return bus, nil
+}
+
+// =============================================================================
+
+// UpdateTemplate defines the data needed to update a template.
+type UpdateTemplate struct {
+	Subject *string `json:"subject"`
+	Body    *string `json:"body"`
+	Enabled *bool   `json:"enabled"`
+}
+
+// Decode implements the decoder interface.
+func (app *UpdateTemplate) Decode(data []byte) error {
+	return json.Unmarshal(data, app)
+}
+
+// Validate checks the data in the model is considered clean.
+func (app UpdateTemplate) Validate() error {
+	if err := errs.Check(app); err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+
+	return nil
+}
+
+func toBusUpdateTemplate(app UpdateTemplate) templatebus.UpdateTemplate {
+	return templatebus.UpdateTemplate{
+		Subject: app.Subject,
+		Body:    app.Body,
+		Enabled: app.Enabled,
+	}
+}
+
+// =============================================================================
+
+// Preview defines the sample data used to render a template. The name,
+// locale, subject and body are only used when previewing a template that
+// hasn't been stored.
+type Preview struct {
+	Name    string            `json:"name"`
+	Locale  string            `json:"locale"`
+	Subject string            `json:"subject"`
+	Body    string            `json:"body"`
+	Data    map[string]string `json:"data"`
+}
+
+// Decode implements the decoder interface.
+func (app *Preview) Decode(data []byte) error {
+	return json.Unmarshal(data, app)
+}
+
+// Validate checks the data in the model is considered clean.
+func (app Preview) Validate() error {
+	if err := errs.Check(app); err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+
+	return nil
+}
+
+// Message represents a rendered template.
+type Message struct {
+	Name    string `json:"name"`
+	Locale  string `json:"locale"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// Encode implements the encoder interface.
+func (app Message) Encode() ([]byte, string, error) {
+	data, err := json.Marshal(app)
+	return data, "application/json", err
+}
+
+func toAppMessage(msg templatebus.Message) Message {
+	return Message{
+		Name:    msg.Name,
+		Locale:  msg.Locale,
+		Subject: msg.Subject,
+		Body:    msg.Body,
+	}
+}
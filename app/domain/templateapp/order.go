@@ -0,0 +1,7 @@
+package templateapp
+
+import (
+	"github.com/ardanlabs/service/business/domain/templatebus"
+)
+
+var orderByFields = templatebus.OrderFields.Mappings()
@@ -0,0 +1,37 @@
+package templateapp
+
+import (
+	"net/http"
+
+	"github.com/ardanlabs/service/app/sdk/auth"
+	"github.com/ardanlabs/service/app/sdk/authclient"
+	"github.com/ardanlabs/service/app/sdk/mid"
+	"github.com/ardanlabs/service/business/domain/templatebus"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/web"
+)
+
+// Config contains all the mandatory systems required by handlers.
+type Config struct {
+	Log         *logger.Logger
+	TemplateBus *templatebus.Business
+	AuthClient  *authclient.Client
+}
+
+// Routes adds specific routes for this group.
+func Routes(app *web.App, cfg Config) {
+	const version = "v1"
+
+	authen := mid.Authenticate(cfg.AuthClient)
+	ruleAdmin := mid.Authorize(cfg.AuthClient, auth.RuleAdminOnly)
+
+	api := newApp(cfg.TemplateBus)
+
+	app.HandlerFunc(http.MethodGet, version, "/templates", api.query, authen, ruleAdmin)
+	app.HandlerFunc(http.MethodGet, version, "/templates/{template_id}", api.queryByID, authen, ruleAdmin)
+	app.HandlerFunc(http.MethodPost, version, "/templates", api.create, authen, ruleAdmin)
+	app.HandlerFunc(http.MethodPost, version, "/templates/preview", api.preview, authen, ruleAdmin)
+	app.HandlerFunc(http.MethodPost, version, "/templates/{template_id}/preview", api.previewByID, authen, ruleAdmin)
+	app.HandlerFunc(http.MethodPut, version, "/templates/{template_id}", api.update, authen, ruleAdmin)
+	app.HandlerFunc(http.MethodDelete, version, "/templates/{template_id}", api.delete, authen, ruleAdmin)
+}
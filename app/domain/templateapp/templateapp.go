@@ -0,0 +1,187 @@
+// Package templateapp maintains the app layer api for the email template
+// domain.
+package templateapp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/extid"
+	"github.com/ardanlabs/service/app/sdk/query"
+	"github.com/ardanlabs/service/business/domain/templatebus"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/foundation/web"
+)
+
+type app struct {
+	templateBus *templatebus.Business
+}
+
+func newApp(templateBus *templatebus.Business) *app {
+	return &app{
+		templateBus: templateBus,
+	}
+}
+
+func (a *app) create(ctx context.Context, r *http.Request) web.Encoder {
+	var app NewTemplate
+	if err := web.Decode(r, &app); err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	nt, err := toBusNewTemplate(app)
+	if err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	tmpl, err := a.templateBus.Create(ctx, nt)
+	if err != nil {
+		switch {
+		case errors.Is(err, templatebus.ErrInvalidTemplate):
+			return errs.New(errs.InvalidArgument, err)
+		case errors.Is(err, templatebus.ErrUniqueTemplate):
+			return errs.New(errs.Aborted, templatebus.ErrUniqueTemplate)
+		}
+		return errs.Newf(errs.Internal, "create: tmpl[%s/%s]: %s", nt.Name, nt.Locale, err)
+	}
+
+	return toAppTemplate(tmpl)
+}
+
+func (a *app) update(ctx context.Context, r *http.Request) web.Encoder {
+	var app UpdateTemplate
+	if err := web.Decode(r, &app); err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	tmpl, err := a.template(ctx, r)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	updTmpl, err := a.templateBus.Update(ctx, tmpl, toBusUpdateTemplate(app))
+	if err != nil {
+		if errors.Is(err, templatebus.ErrInvalidTemplate) {
+			return errs.New(errs.InvalidArgument, err)
+		}
+		return errs.Newf(errs.Internal, "update: templateID[%s]: %s", tmpl.ID, err)
+	}
+
+	return toAppTemplate(updTmpl)
+}
+
+func (a *app) delete(ctx context.Context, r *http.Request) web.Encoder {
+	tmpl, err := a.template(ctx, r)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	if err := a.templateBus.Delete(ctx, tmpl); err != nil {
+		return errs.Newf(errs.Internal, "delete: templateID[%s]: %s", tmpl.ID, err)
+	}
+
+	return nil
+}
+
+func (a *app) query(ctx context.Context, r *http.Request) web.Encoder {
+	qp := parseQueryParams(r)
+
+	page, err := page.Parse(qp.Page, qp.Rows)
+	if err != nil {
+		return errs.NewFieldErrors("page", err)
+	}
+
+	filter, err := parseFilter(qp)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	orderBy, err := order.Parse(orderByFields, qp.OrderBy, templatebus.DefaultOrderBy)
+	if err != nil {
+		return errs.NewFieldErrors("order", err)
+	}
+
+	tmpls, err := a.templateBus.Query(ctx, filter, orderBy, page)
+	if err != nil {
+		return errs.Newf(errs.Internal, "query: %s", err)
+	}
+
+	total, err := a.templateBus.Count(ctx, filter)
+	if err != nil {
+		return errs.Newf(errs.Internal, "count: %s", err)
+	}
+
+	return query.NewResult(toAppTemplates(tmpls), total, page)
+}
+
+func (a *app) queryByID(ctx context.Context, r *http.Request) web.Encoder {
+	tmpl, err := a.template(ctx, r)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	return toAppTemplate(tmpl)
+}
+
+func (a *app) preview(ctx context.Context, r *http.Request) web.Encoder {
+	var app Preview
+	if err := web.Decode(r, &app); err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	tmpl := templatebus.Template{
+		Name:    app.Name,
+		Locale:  app.Locale,
+		Subject: app.Subject,
+		Body:    app.Body,
+	}
+
+	return a.render(ctx, tmpl, app.Data)
+}
+
+func (a *app) previewByID(ctx context.Context, r *http.Request) web.Encoder {
+	var app Preview
+	if err := web.Decode(r, &app); err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	tmpl, err := a.template(ctx, r)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	return a.render(ctx, tmpl, app.Data)
+}
+
+func (a *app) render(ctx context.Context, tmpl templatebus.Template, data map[string]string) web.Encoder {
+	msg, err := a.templateBus.Preview(ctx, tmpl, data)
+	if err != nil {
+		if errors.Is(err, templatebus.ErrInvalidTemplate) {
+			return errs.New(errs.InvalidArgument, err)
+		}
+		return errs.Newf(errs.Internal, "preview: %s", err)
+	}
+
+	return toAppMessage(msg)
+}
+
+// template looks up the template identified in the request path.
+func (a *app) template(ctx context.Context, r *http.Request) (templatebus.Template, error) {
+	id, err := extid.Decode(web.Param(r, "template_id"))
+	if err != nil {
+		return templatebus.Template{}, errs.New(errs.InvalidArgument, err)
+	}
+
+	tmpl, err := a.templateBus.QueryByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, templatebus.ErrNotFound) {
+			return templatebus.Template{}, errs.New(errs.NotFound, err)
+		}
+		return templatebus.Template{}, errs.Newf(errs.Internal, "querybyid: templateID[%s]: %s", id, err)
+	}
+
+	return tmpl, nil
+}
@@ -7,8 +7,10 @@ import (
 	"time"
 
 	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/extid"
 	"github.com/ardanlabs/service/business/domain/productbus"
 	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/types/department"
 	"github.com/ardanlabs/service/business/types/money"
 	"github.com/ardanlabs/service/business/types/name"
 	"github.com/ardanlabs/service/business/types/quantity"
@@ -34,8 +36,8 @@ func (app Product) Encode() ([]byte, string, error) {
 
 func toAppProduct(prd productbus.Product) Product {
 	return Product{
-		ID:          prd.ID.String(),
-		UserID:      prd.UserID.String(),
+		ID:          extid.Encode(prd.ID),
+		UserID:      extid.Encode(prd.UserID),
 		Name:        prd.Name.String(),
 		Cost:        prd.Cost.Value(),
 		Quantity:    prd.Quantity.Value(),
@@ -104,7 +106,7 @@ func toBusNewUser(app NewUser) (userbus.NewUser, error) {
 		return userbus.NewUser{}, fmt.Errorf("parse: %w", err)
 	}
 
-	department, err := name.ParseNull(app.Department)
+	dept, err := department.ParseNull(app.Department)
 	if err != nil {
 		return userbus.NewUser{}, fmt.Errorf("parse: %w", err)
 	}
@@ -113,7 +115,7 @@ func toBusNewUser(app NewUser) (userbus.NewUser, error) {
 		Name:       nme,
 		Email:      *addr,
 		Roles:      roles,
-		Department: department,
+		Department: dept,
 		Password:   app.Password,
 	}
 
@@ -72,11 +72,14 @@ func (a *app) create(ctx context.Context, r *http.Request) web.Encoder {
 		return errs.New(errs.InvalidArgument, err)
 	}
 
-	usr, err := a.userBus.Create(ctx, mid.GetSubjectID(ctx), nu)
+	usr, _, err := a.userBus.Create(ctx, mid.GetSubjectID(ctx), nu)
 	if err != nil {
 		if errors.Is(err, userbus.ErrUniqueEmail) {
 			return errs.New(errs.Aborted, userbus.ErrUniqueEmail)
 		}
+		if errors.Is(err, userbus.ErrInvalidUser) {
+			return errs.NewError(err)
+		}
 		return errs.Newf(errs.Internal, "create: usr[%+v]: %s", usr, err)
 	}
 
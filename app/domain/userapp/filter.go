@@ -3,23 +3,35 @@ package userapp
 import (
 	"net/http"
 	"net/mail"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/extid"
 	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/types/department"
 	"github.com/ardanlabs/service/business/types/name"
-	"github.com/google/uuid"
+	"github.com/ardanlabs/service/business/types/role"
 )
 
 type queryParams struct {
 	Page             string
 	Rows             string
+	Cursor           string
+	Keyset           bool
 	OrderBy          string
 	ID               string
 	Name             string
 	Email            string
+	Search           string
+	Roles            string
+	Enabled          string
+	Department       string
 	StartCreatedDate string
 	EndCreatedDate   string
+	NotLoggedInSince string
 }
 
 func parseQueryParams(r *http.Request) (queryParams, error) {
@@ -28,12 +40,19 @@ func parseQueryParams(r *http.Request) (queryParams, error) {
 	filter := queryParams{
 		Page:             values.Get("page"),
 		Rows:             values.Get("rows"),
+		Cursor:           values.Get("cursor"),
+		Keyset:           values.Has("cursor"),
 		OrderBy:          values.Get("orderBy"),
 		ID:               values.Get("user_id"),
 		Name:             values.Get("name"),
 		Email:            values.Get("email"),
+		Search:           values.Get("search"),
+		Roles:            values.Get("roles"),
+		Enabled:          values.Get("enabled"),
+		Department:       values.Get("department"),
 		StartCreatedDate: values.Get("start_created_date"),
 		EndCreatedDate:   values.Get("end_created_date"),
+		NotLoggedInSince: values.Get("not_logged_in_since"),
 	}
 
 	return filter, nil
@@ -44,7 +63,7 @@ func parseFilter(qp queryParams) (userbus.QueryFilter, error) {
 	var filter userbus.QueryFilter
 
 	if qp.ID != "" {
-		id, err := uuid.Parse(qp.ID)
+		id, err := extid.Decode(qp.ID)
 		switch err {
 		case nil:
 			filter.ID = &id
@@ -73,6 +92,40 @@ func parseFilter(qp queryParams) (userbus.QueryFilter, error) {
 		}
 	}
 
+	if qp.Search != "" {
+		filter.Search = &qp.Search
+	}
+
+	if qp.Roles != "" {
+		roles, err := role.ParseMany(strings.Split(qp.Roles, ","))
+		switch err {
+		case nil:
+			filter.Roles = roles
+		default:
+			fieldErrors.Add("roles", err)
+		}
+	}
+
+	if qp.Enabled != "" {
+		enabled, err := strconv.ParseBool(qp.Enabled)
+		switch err {
+		case nil:
+			filter.Enabled = &enabled
+		default:
+			fieldErrors.Add("enabled", err)
+		}
+	}
+
+	if qp.Department != "" {
+		dept, err := department.Parse(qp.Department)
+		switch err {
+		case nil:
+			filter.Department = &dept
+		default:
+			fieldErrors.Add("department", err)
+		}
+	}
+
 	if qp.StartCreatedDate != "" {
 		t, err := time.Parse(time.RFC3339, qp.StartCreatedDate)
 		switch err {
@@ -93,9 +146,39 @@ func parseFilter(qp queryParams) (userbus.QueryFilter, error) {
 		}
 	}
 
+	if qp.NotLoggedInSince != "" {
+		t, err := time.Parse(time.RFC3339, qp.NotLoggedInSince)
+		switch err {
+		case nil:
+			filter.NotLoggedInSince = &t
+		default:
+			fieldErrors.Add("not_logged_in_since", err)
+		}
+	}
+
 	if fieldErrors != nil {
 		return userbus.QueryFilter{}, fieldErrors.ToError()
 	}
 
 	return filter, nil
 }
+
+// parsePage returns a keyset page when the caller provides a cursor, which
+// can be empty to request the first page, and an offset page otherwise.
+func parsePage(qp queryParams) (page.Page, error) {
+	if qp.Keyset {
+		pg, err := page.ParseCursor(qp.Cursor, qp.Rows)
+		if err != nil {
+			return page.Page{}, errs.NewFieldErrors("cursor", err)
+		}
+
+		return pg, nil
+	}
+
+	pg, err := page.Parse(qp.Page, qp.Rows)
+	if err != nil {
+		return page.Page{}, errs.NewFieldErrors("page", err)
+	}
+
+	return pg, nil
+}
@@ -0,0 +1,280 @@
+package userapp
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/ardanlabs/service/app/sdk/auth"
+	"github.com/ardanlabs/service/app/sdk/authclient"
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/extid"
+	"github.com/ardanlabs/service/app/sdk/mid"
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/foundation/ctxval"
+	"github.com/ardanlabs/service/foundation/dataloader"
+	"github.com/google/uuid"
+)
+
+// userLoaderKey holds the loader that batches the lookups of related users,
+// like managers, made while resolving a single graphql request.
+var userLoaderKey = ctxval.NewKey[*dataloader.Loader[string, User]]("user loader")
+
+// GraphQL resolves the user fields of the graphql api described in
+// user.graphqls. Each root field authorizes the caller with the same rule
+// as the matching http route.
+type GraphQL struct {
+	app        *app
+	authClient *authclient.Client
+	userBus    userbus.Business
+}
+
+// NewGraphQL constructs the resolvers for the user fields.
+func NewGraphQL(cfg Config) *GraphQL {
+	return &GraphQL{
+		app:        newApp(cfg.UserBus, sqldb.NewBeginner(cfg.DB)),
+		authClient: cfg.AuthClient,
+		userBus:    cfg.UserBus,
+	}
+}
+
+// WithLoader adds a user loader for the request to the context. It must be
+// called once for every graphql request.
+func (g *GraphQL) WithLoader(ctx context.Context) context.Context {
+	return userLoaderKey.Set(ctx, dataloader.New(g.loadUsers))
+}
+
+// Users resolves the users field of the query.
+func (g *GraphQL) Users(ctx context.Context, filter *UserFilter, orderBy []*UserOrder, page *int, rows *int, cursor *string) (*UserPage, error) {
+	if err := mid.AuthorizeCall(ctx, g.authClient, auth.RuleAdminOnly); err != nil {
+		return nil, err
+	}
+
+	result, err := g.app.queryUsers(ctx, toGraphQLQueryParams(filter, orderBy, page, rows, cursor))
+	if err != nil {
+		return nil, errs.NewError(err)
+	}
+
+	g.prime(ctx, result.Items...)
+
+	pg := UserPage{
+		Items:       result.Items,
+		Total:       result.Total,
+		Approximate: result.Approximate,
+		Page:        result.Page,
+		RowsPerPage: result.RowsPerPage,
+		NextCursor:  optional(result.NextCursor),
+	}
+
+	return &pg, nil
+}
+
+// User resolves the user field of the query.
+func (g *GraphQL) User(ctx context.Context, id string) (*User, error) {
+	ctx, err := mid.AuthorizeUserCall(ctx, g.authClient, g.userBus, id, auth.RuleAdminOrSubject)
+	if err != nil {
+		return nil, err
+	}
+
+	usr, err := mid.GetUser(ctx)
+	if err != nil {
+		return nil, errs.Newf(errs.Internal, "querybyid: %s", err)
+	}
+
+	app := toAppUser(usr)
+	g.prime(ctx, app)
+
+	return &app, nil
+}
+
+// Manager resolves the manager of the user through the request's loader, so
+// the managers of a page of users are queried in a single call.
+func (g *GraphQL) Manager(ctx context.Context, usr *User) (*User, error) {
+	if usr.ManagerID == "" {
+		return nil, nil
+	}
+
+	l, exists := userLoaderKey.Get(ctx)
+	if !exists {
+		return nil, errs.Newf(errs.Internal, "user loader missing in context")
+	}
+
+	mgr, err := l.Load(ctx, usr.ManagerID)
+	if err != nil {
+		if errors.Is(err, dataloader.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &mgr, nil
+}
+
+// Department resolves the department of the user, null when it isn't set.
+func (g *GraphQL) Department(ctx context.Context, usr *User) (*string, error) {
+	return optional(usr.Department), nil
+}
+
+// ManagerID resolves the id of the user's manager, null when it isn't set.
+func (g *GraphQL) ManagerID(ctx context.Context, usr *User) (*string, error) {
+	return optional(usr.ManagerID), nil
+}
+
+// Avatar resolves the avatar route of the user, null when it isn't set.
+func (g *GraphQL) Avatar(ctx context.Context, usr *User) (*string, error) {
+	return optional(usr.Avatar), nil
+}
+
+// CreatedBy resolves who created the user, null when it isn't known.
+func (g *GraphQL) CreatedBy(ctx context.Context, usr *User) (*string, error) {
+	return optional(usr.CreatedBy), nil
+}
+
+// UpdatedBy resolves who last updated the user, null when it isn't known.
+func (g *GraphQL) UpdatedBy(ctx context.Context, usr *User) (*string, error) {
+	return optional(usr.UpdatedBy), nil
+}
+
+// DateLastLogin resolves when the user last logged in, null when they
+// never have.
+func (g *GraphQL) DateLastLogin(ctx context.Context, usr *User) (*string, error) {
+	return optional(usr.DateLastLogin), nil
+}
+
+// loadUsers queries the users with the ids in a single call.
+func (g *GraphQL) loadUsers(ctx context.Context, ids []string) (map[string]User, error) {
+	keys := make(map[uuid.UUID]string, len(ids))
+	userIDs := make([]uuid.UUID, 0, len(ids))
+
+	for _, id := range ids {
+		userID, err := extid.Decode(id)
+		if err != nil {
+			continue
+		}
+		keys[userID] = id
+		userIDs = append(userIDs, userID)
+	}
+
+	usrs, err := g.userBus.QueryByIDs(ctx, userIDs)
+	if err != nil {
+		return nil, errs.Newf(errs.Internal, "querybyids: %s", err)
+	}
+
+	found := make(map[string]User, len(usrs))
+	for _, usr := range usrs {
+		found[keys[usr.ID]] = toAppUser(usr)
+	}
+
+	return found, nil
+}
+
+// prime holds the users already queried in the loader so they aren't
+// queried again when they show up as a manager.
+func (g *GraphQL) prime(ctx context.Context, usrs ...User) {
+	l, exists := userLoaderKey.Get(ctx)
+	if !exists {
+		return
+	}
+
+	for _, usr := range usrs {
+		l.Prime(usr.ID, usr)
+	}
+}
+
+// =============================================================================
+
+// UserPage represents a page of users in the graphql api.
+type UserPage struct {
+	Items       []User
+	Total       int
+	Approximate bool
+	Page        int
+	RowsPerPage int
+	NextCursor  *string
+}
+
+// UserFilter represents the filter argument of the users field.
+type UserFilter struct {
+	ID               *string
+	Name             *string
+	Email            *string
+	Search           *string
+	Roles            []string
+	Enabled          *bool
+	Department       *string
+	StartCreatedDate *string
+	EndCreatedDate   *string
+	NotLoggedInSince *string
+}
+
+// UserOrder represents an entry of the orderBy argument of the users field.
+type UserOrder struct {
+	Field     string
+	Direction *string
+}
+
+// toGraphQLQueryParams maps the arguments of the users field onto the query
+// string parameters of the http api, so both are parsed the same way.
+func toGraphQLQueryParams(filter *UserFilter, orderBy []*UserOrder, page *int, rows *int, cursor *string) queryParams {
+	var qp queryParams
+
+	if page != nil {
+		qp.Page = strconv.Itoa(*page)
+	}
+
+	if rows != nil {
+		qp.Rows = strconv.Itoa(*rows)
+	}
+
+	if cursor != nil {
+		qp.Cursor = *cursor
+		qp.Keyset = true
+	}
+
+	var parts []string
+	for _, o := range orderBy {
+		parts = append(parts, o.Field)
+		if o.Direction != nil {
+			parts = append(parts, *o.Direction)
+		}
+	}
+	qp.OrderBy = strings.Join(parts, ",")
+
+	if filter == nil {
+		return qp
+	}
+
+	str := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return *s
+	}
+
+	qp.ID = str(filter.ID)
+	qp.Name = str(filter.Name)
+	qp.Email = str(filter.Email)
+	qp.Search = str(filter.Search)
+	qp.Roles = strings.Join(filter.Roles, ",")
+	qp.Department = str(filter.Department)
+	qp.StartCreatedDate = str(filter.StartCreatedDate)
+	qp.EndCreatedDate = str(filter.EndCreatedDate)
+	qp.NotLoggedInSince = str(filter.NotLoggedInSince)
+
+	if filter.Enabled != nil {
+		qp.Enabled = strconv.FormatBool(*filter.Enabled)
+	}
+
+	return qp
+}
+
+// optional returns nil for an empty string, which graphql returns as null.
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+
+	return &s
+}
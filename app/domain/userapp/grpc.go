@@ -0,0 +1,241 @@
+package userapp
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ardanlabs/service/app/sdk/auth"
+	"github.com/ardanlabs/service/app/sdk/authclient"
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/mid"
+	"github.com/ardanlabs/service/app/sdk/rpc"
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"google.golang.org/grpc"
+)
+
+// serviceName is the fully qualified name of the service in user.proto.
+const serviceName = "sales.user.v1.UserService"
+
+// grpcApp handles the calls of the grpc api. The middleware the http routes
+// run for authentication and authorization is called by each method before
+// handing off to the same code the http handlers use.
+type grpcApp struct {
+	app        *app
+	authClient *authclient.Client
+	userBus    userbus.Business
+}
+
+func (g *grpcApp) create(ctx context.Context, req *CreateRequest) (rpc.Message, error) {
+	ctx, err := mid.AuthenticateCall(ctx, g.authClient)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := mid.AuthorizeCall(ctx, g.authClient, auth.RuleAdminOnly); err != nil {
+		return nil, err
+	}
+
+	if err := req.Validate(); err != nil {
+		return nil, errs.New(errs.InvalidArgument, err)
+	}
+
+	usr, err := g.app.createUser(ctx, req.NewUser, mid.IncomingMetadata(ctx, "idempotency-key"), req.OnConflict)
+	if err != nil {
+		return nil, err
+	}
+
+	return &usr, nil
+}
+
+func (g *grpcApp) update(ctx context.Context, req *UpdateRequest) (rpc.Message, error) {
+	ctx, err := mid.AuthenticateCall(ctx, g.authClient)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, err = mid.AuthorizeUserCall(ctx, g.authClient, g.userBus, req.UserID, auth.RuleAdminOrSubject)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := req.Validate(); err != nil {
+		return nil, errs.New(errs.InvalidArgument, err)
+	}
+
+	usr, err := mid.GetUser(ctx)
+	if err != nil {
+		return nil, errs.Newf(errs.Internal, "user missing in context: %s", err)
+	}
+
+	updUsr, err := g.app.updateUser(ctx, usr, req.UpdateUser)
+	if err != nil {
+		return nil, err
+	}
+
+	return &updUsr, nil
+}
+
+func (g *grpcApp) delete(ctx context.Context, req *DeleteRequest) (rpc.Message, error) {
+	ctx, err := mid.AuthenticateCall(ctx, g.authClient)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := mid.RequireRecentAuthCall(ctx, recentAuthMaxAge, false); err != nil {
+		return nil, err
+	}
+
+	ctx, err = mid.AuthorizeUserCall(ctx, g.authClient, g.userBus, req.UserID, auth.RuleAdminOrSubject)
+	if err != nil {
+		return nil, err
+	}
+
+	usr, err := mid.GetUser(ctx)
+	if err != nil {
+		return nil, errs.Newf(errs.Internal, "userID missing in context: %s", err)
+	}
+
+	if err := g.app.deleteUser(ctx, usr, req.Mode); err != nil {
+		return nil, err
+	}
+
+	return &DeleteResponse{}, nil
+}
+
+func (g *grpcApp) query(ctx context.Context, req *QueryRequest) (rpc.Message, error) {
+	ctx, err := mid.AuthenticateCall(ctx, g.authClient)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := mid.AuthorizeCall(ctx, g.authClient, auth.RuleAdminOnly); err != nil {
+		return nil, err
+	}
+
+	result, err := g.app.queryUsers(ctx, toQueryParams(req))
+	if err != nil {
+		return nil, errs.NewError(err)
+	}
+
+	resp := QueryResponse(result)
+
+	return &resp, nil
+}
+
+func (g *grpcApp) queryByID(ctx context.Context, req *QueryByIDRequest) (rpc.Message, error) {
+	ctx, err := mid.AuthenticateCall(ctx, g.authClient)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, err = mid.AuthorizeUserCall(ctx, g.authClient, g.userBus, req.UserID, auth.RuleAdminOrSubject)
+	if err != nil {
+		return nil, err
+	}
+
+	usr, err := mid.GetUser(ctx)
+	if err != nil {
+		return nil, errs.Newf(errs.Internal, "querybyid: %s", err)
+	}
+
+	app := toAppUser(usr)
+
+	return &app, nil
+}
+
+// token asks the auth service for a token with the basic credentials sent
+// in the metadata, the same as a call to its token endpoint.
+func (g *grpcApp) token(ctx context.Context, req *TokenRequest) (rpc.Message, error) {
+	if req.Kid == "" {
+		return nil, errs.NewFieldErrors("kid", errors.New("missing kid"))
+	}
+
+	tkn, err := g.authClient.Token(ctx, req.Kid, mid.IncomingMetadata(ctx, "authorization"), mid.IncomingMetadata(ctx, "x-otp"))
+	if err != nil {
+		var appErr *errs.Error
+		if errors.As(err, &appErr) {
+			return nil, appErr
+		}
+		return nil, errs.New(errs.Unauthenticated, err)
+	}
+
+	return &TokenResponse{Token: tkn}, nil
+}
+
+// =============================================================================
+
+// GRPCClient calls the user service over grpc. Credentials, and the
+// idempotency key for Create, are sent in the outgoing metadata of the
+// context.
+type GRPCClient struct {
+	conn grpc.ClientConnInterface
+}
+
+// NewGRPCClient constructs a client for the user service on the connection.
+func NewGRPCClient(conn grpc.ClientConnInterface) *GRPCClient {
+	return &GRPCClient{
+		conn: conn,
+	}
+}
+
+// Create adds a new user.
+func (cln *GRPCClient) Create(ctx context.Context, req CreateRequest, opts ...grpc.CallOption) (User, error) {
+	var resp User
+	if err := cln.invoke(ctx, "Create", &req, &resp, opts); err != nil {
+		return User{}, err
+	}
+
+	return resp, nil
+}
+
+// Update modifies a user.
+func (cln *GRPCClient) Update(ctx context.Context, req UpdateRequest, opts ...grpc.CallOption) (User, error) {
+	var resp User
+	if err := cln.invoke(ctx, "Update", &req, &resp, opts); err != nil {
+		return User{}, err
+	}
+
+	return resp, nil
+}
+
+// Delete removes or anonymizes a user.
+func (cln *GRPCClient) Delete(ctx context.Context, req DeleteRequest, opts ...grpc.CallOption) error {
+	return cln.invoke(ctx, "Delete", &req, &DeleteResponse{}, opts)
+}
+
+// Query returns a page of users.
+func (cln *GRPCClient) Query(ctx context.Context, req QueryRequest, opts ...grpc.CallOption) (QueryResponse, error) {
+	var resp QueryResponse
+	if err := cln.invoke(ctx, "Query", &req, &resp, opts); err != nil {
+		return QueryResponse{}, err
+	}
+
+	return resp, nil
+}
+
+// QueryByID returns the user with the specified id.
+func (cln *GRPCClient) QueryByID(ctx context.Context, userID string, opts ...grpc.CallOption) (User, error) {
+	var resp User
+	if err := cln.invoke(ctx, "QueryByID", &QueryByIDRequest{UserID: userID}, &resp, opts); err != nil {
+		return User{}, err
+	}
+
+	return resp, nil
+}
+
+// Token returns a token signed with the key for the basic credentials in
+// the outgoing metadata.
+func (cln *GRPCClient) Token(ctx context.Context, kid string, opts ...grpc.CallOption) (string, error) {
+	var resp TokenResponse
+	if err := cln.invoke(ctx, "Token", &TokenRequest{Kid: kid}, &resp, opts); err != nil {
+		return "", err
+	}
+
+	return resp.Token, nil
+}
+
+func (cln *GRPCClient) invoke(ctx context.Context, method string, req rpc.Message, resp rpc.Message, opts []grpc.CallOption) error {
+	opts = append(opts, rpc.WithCodec())
+
+	return cln.conn.Invoke(ctx, "/"+serviceName+"/"+method, req, resp, opts...)
+}
@@ -0,0 +1,500 @@
+package userapp
+
+import (
+	"strconv"
+
+	"github.com/ardanlabs/service/app/sdk/query"
+	"github.com/ardanlabs/service/app/sdk/rpc"
+)
+
+// The messages of the grpc api are encoded following the definitions in
+// user.proto.
+
+// MarshalProto implements the rpc message interface.
+func (app *User) MarshalProto() ([]byte, error) {
+	var enc rpc.Encoder
+	enc.String(1, app.ID)
+	enc.String(2, app.Name)
+	enc.String(3, app.Email)
+	enc.Strings(4, app.Roles)
+	enc.String(5, app.Department)
+	enc.String(6, app.ManagerID)
+	enc.Bool(7, app.Enabled)
+	enc.String(8, app.Avatar)
+	enc.String(9, app.CreatedBy)
+	enc.String(10, app.UpdatedBy)
+	enc.String(11, app.DateCreated)
+	enc.String(12, app.DateUpdated)
+	enc.String(13, app.DateLastLogin)
+	enc.Int(14, int64(app.Version))
+
+	return enc.Bytes(), nil
+}
+
+// UnmarshalProto implements the rpc message interface.
+func (app *User) UnmarshalProto(data []byte) error {
+	return rpc.Decode(data, func(f rpc.Field) error {
+		var err error
+
+		switch f.Num {
+		case 1:
+			app.ID, err = f.String()
+		case 2:
+			app.Name, err = f.String()
+		case 3:
+			app.Email, err = f.String()
+		case 4:
+			var r string
+			r, err = f.String()
+			app.Roles = append(app.Roles, r)
+		case 5:
+			app.Department, err = f.String()
+		case 6:
+			app.ManagerID, err = f.String()
+		case 7:
+			app.Enabled, err = f.Bool()
+		case 8:
+			app.Avatar, err = f.String()
+		case 9:
+			app.CreatedBy, err = f.String()
+		case 10:
+			app.UpdatedBy, err = f.String()
+		case 11:
+			app.DateCreated, err = f.String()
+		case 12:
+			app.DateUpdated, err = f.String()
+		case 13:
+			app.DateLastLogin, err = f.String()
+		case 14:
+			var v int64
+			v, err = f.Int()
+			app.Version = int(v)
+		}
+
+		return err
+	})
+}
+
+// =============================================================================
+
+// CreateRequest defines the data needed to add a new user over grpc.
+type CreateRequest struct {
+	NewUser
+	OnConflict string
+}
+
+// MarshalProto implements the rpc message interface.
+func (app *CreateRequest) MarshalProto() ([]byte, error) {
+	var enc rpc.Encoder
+	enc.String(1, app.Name)
+	enc.String(2, app.Email)
+	enc.Strings(3, app.Roles)
+	enc.String(4, app.Department)
+	enc.String(5, app.ManagerID)
+	enc.String(6, app.Password)
+	enc.String(7, app.PasswordConfirm)
+	enc.String(8, app.OnConflict)
+
+	return enc.Bytes(), nil
+}
+
+// UnmarshalProto implements the rpc message interface.
+func (app *CreateRequest) UnmarshalProto(data []byte) error {
+	return rpc.Decode(data, func(f rpc.Field) error {
+		var err error
+
+		switch f.Num {
+		case 1:
+			app.Name, err = f.String()
+		case 2:
+			app.Email, err = f.String()
+		case 3:
+			var r string
+			r, err = f.String()
+			app.Roles = append(app.Roles, r)
+		case 4:
+			app.Department, err = f.String()
+		case 5:
+			app.ManagerID, err = f.String()
+		case 6:
+			app.Password, err = f.String()
+		case 7:
+			app.PasswordConfirm, err = f.String()
+		case 8:
+			app.OnConflict, err = f.String()
+		}
+
+		return err
+	})
+}
+
+// =============================================================================
+
+// UpdateRequest defines the data needed to update a user over grpc.
+type UpdateRequest struct {
+	UserID string
+	UpdateUser
+}
+
+// MarshalProto implements the rpc message interface.
+func (app *UpdateRequest) MarshalProto() ([]byte, error) {
+	var version *int64
+	if app.Version != nil {
+		v := int64(*app.Version)
+		version = &v
+	}
+
+	var enc rpc.Encoder
+	enc.String(1, app.UserID)
+	enc.OptionalString(2, app.Name)
+	enc.OptionalString(3, app.Email)
+	enc.OptionalString(4, app.Department)
+	enc.OptionalString(5, app.ManagerID)
+	enc.OptionalString(6, app.Password)
+	enc.OptionalString(7, app.PasswordConfirm)
+	enc.OptionalBool(8, app.Enabled)
+	enc.OptionalInt(9, version)
+
+	return enc.Bytes(), nil
+}
+
+// UnmarshalProto implements the rpc message interface.
+func (app *UpdateRequest) UnmarshalProto(data []byte) error {
+	return rpc.Decode(data, func(f rpc.Field) error {
+		var err error
+
+		switch f.Num {
+		case 1:
+			app.UserID, err = f.String()
+		case 2:
+			app.Name, err = optionalString(f)
+		case 3:
+			app.Email, err = optionalString(f)
+		case 4:
+			app.Department, err = optionalString(f)
+		case 5:
+			app.ManagerID, err = optionalString(f)
+		case 6:
+			app.Password, err = optionalString(f)
+		case 7:
+			app.PasswordConfirm, err = optionalString(f)
+		case 8:
+			var v bool
+			v, err = f.Bool()
+			app.Enabled = &v
+		case 9:
+			var v int64
+			v, err = f.Int()
+			version := int(v)
+			app.Version = &version
+		}
+
+		return err
+	})
+}
+
+// =============================================================================
+
+// DeleteRequest defines the user to delete over grpc.
+type DeleteRequest struct {
+	UserID string
+	Mode   string
+}
+
+// MarshalProto implements the rpc message interface.
+func (app *DeleteRequest) MarshalProto() ([]byte, error) {
+	var enc rpc.Encoder
+	enc.String(1, app.UserID)
+	enc.String(2, app.Mode)
+
+	return enc.Bytes(), nil
+}
+
+// UnmarshalProto implements the rpc message interface.
+func (app *DeleteRequest) UnmarshalProto(data []byte) error {
+	return rpc.Decode(data, func(f rpc.Field) error {
+		var err error
+
+		switch f.Num {
+		case 1:
+			app.UserID, err = f.String()
+		case 2:
+			app.Mode, err = f.String()
+		}
+
+		return err
+	})
+}
+
+// DeleteResponse is the empty response to a delete over grpc.
+type DeleteResponse struct{}
+
+// MarshalProto implements the rpc message interface.
+func (app *DeleteResponse) MarshalProto() ([]byte, error) {
+	return nil, nil
+}
+
+// UnmarshalProto implements the rpc message interface.
+func (app *DeleteResponse) UnmarshalProto(data []byte) error {
+	return rpc.Decode(data, func(f rpc.Field) error {
+		return nil
+	})
+}
+
+// =============================================================================
+
+// QueryRequest defines the query string parameters of the http api for
+// querying users over grpc.
+type QueryRequest struct {
+	Page             int64
+	Rows             int64
+	Cursor           *string
+	OrderBy          string
+	UserID           string
+	Name             string
+	Email            string
+	Search           string
+	Roles            string
+	Enabled          *bool
+	Department       string
+	StartCreatedDate string
+	EndCreatedDate   string
+	NotLoggedInSince string
+}
+
+// MarshalProto implements the rpc message interface.
+func (app *QueryRequest) MarshalProto() ([]byte, error) {
+	var enc rpc.Encoder
+	enc.Int(1, app.Page)
+	enc.Int(2, app.Rows)
+	enc.OptionalString(3, app.Cursor)
+	enc.String(4, app.OrderBy)
+	enc.String(5, app.UserID)
+	enc.String(6, app.Name)
+	enc.String(7, app.Email)
+	enc.String(8, app.Search)
+	enc.String(9, app.Roles)
+	enc.OptionalBool(10, app.Enabled)
+	enc.String(11, app.Department)
+	enc.String(12, app.StartCreatedDate)
+	enc.String(13, app.EndCreatedDate)
+	enc.String(14, app.NotLoggedInSince)
+
+	return enc.Bytes(), nil
+}
+
+// UnmarshalProto implements the rpc message interface.
+func (app *QueryRequest) UnmarshalProto(data []byte) error {
+	return rpc.Decode(data, func(f rpc.Field) error {
+		var err error
+
+		switch f.Num {
+		case 1:
+			app.Page, err = f.Int()
+		case 2:
+			app.Rows, err = f.Int()
+		case 3:
+			app.Cursor, err = optionalString(f)
+		case 4:
+			app.OrderBy, err = f.String()
+		case 5:
+			app.UserID, err = f.String()
+		case 6:
+			app.Name, err = f.String()
+		case 7:
+			app.Email, err = f.String()
+		case 8:
+			app.Search, err = f.String()
+		case 9:
+			app.Roles, err = f.String()
+		case 10:
+			var v bool
+			v, err = f.Bool()
+			app.Enabled = &v
+		case 11:
+			app.Department, err = f.String()
+		case 12:
+			app.StartCreatedDate, err = f.String()
+		case 13:
+			app.EndCreatedDate, err = f.String()
+		case 14:
+			app.NotLoggedInSince, err = f.String()
+		}
+
+		return err
+	})
+}
+
+func toQueryParams(app *QueryRequest) queryParams {
+	qp := queryParams{
+		OrderBy:          app.OrderBy,
+		ID:               app.UserID,
+		Name:             app.Name,
+		Email:            app.Email,
+		Search:           app.Search,
+		Roles:            app.Roles,
+		Department:       app.Department,
+		StartCreatedDate: app.StartCreatedDate,
+		EndCreatedDate:   app.EndCreatedDate,
+		NotLoggedInSince: app.NotLoggedInSince,
+	}
+
+	if app.Page != 0 {
+		qp.Page = strconv.FormatInt(app.Page, 10)
+	}
+
+	if app.Rows != 0 {
+		qp.Rows = strconv.FormatInt(app.Rows, 10)
+	}
+
+	if app.Cursor != nil {
+		qp.Cursor = *app.Cursor
+		qp.Keyset = true
+	}
+
+	if app.Enabled != nil {
+		qp.Enabled = strconv.FormatBool(*app.Enabled)
+	}
+
+	return qp
+}
+
+// QueryResponse is a page of users queried over grpc.
+type QueryResponse query.Result[User]
+
+// MarshalProto implements the rpc message interface.
+func (app *QueryResponse) MarshalProto() ([]byte, error) {
+	var enc rpc.Encoder
+	for i := range app.Items {
+		if err := enc.Message(1, &app.Items[i]); err != nil {
+			return nil, err
+		}
+	}
+	enc.Int(2, int64(app.Total))
+	enc.Bool(3, app.Approximate)
+	enc.Int(4, int64(app.Page))
+	enc.Int(5, int64(app.RowsPerPage))
+	enc.String(6, app.NextCursor)
+
+	return enc.Bytes(), nil
+}
+
+// UnmarshalProto implements the rpc message interface.
+func (app *QueryResponse) UnmarshalProto(data []byte) error {
+	return rpc.Decode(data, func(f rpc.Field) error {
+		var err error
+		var v int64
+
+		switch f.Num {
+		case 1:
+			var usr User
+			err = f.Message(&usr)
+			app.Items = append(app.Items, usr)
+		case 2:
+			v, err = f.Int()
+			app.Total = int(v)
+		case 3:
+			app.Approximate, err = f.Bool()
+		case 4:
+			v, err = f.Int()
+			app.Page = int(v)
+		case 5:
+			v, err = f.Int()
+			app.RowsPerPage = int(v)
+		case 6:
+			app.NextCursor, err = f.String()
+		}
+
+		return err
+	})
+}
+
+// =============================================================================
+
+// QueryByIDRequest defines the user to query over grpc.
+type QueryByIDRequest struct {
+	UserID string
+}
+
+// MarshalProto implements the rpc message interface.
+func (app *QueryByIDRequest) MarshalProto() ([]byte, error) {
+	var enc rpc.Encoder
+	enc.String(1, app.UserID)
+
+	return enc.Bytes(), nil
+}
+
+// UnmarshalProto implements the rpc message interface.
+func (app *QueryByIDRequest) UnmarshalProto(data []byte) error {
+	return rpc.Decode(data, func(f rpc.Field) error {
+		var err error
+		if f.Num == 1 {
+			app.UserID, err = f.String()
+		}
+
+		return err
+	})
+}
+
+// =============================================================================
+
+// TokenRequest defines the key used to sign a token requested over grpc.
+type TokenRequest struct {
+	Kid string
+}
+
+// MarshalProto implements the rpc message interface.
+func (app *TokenRequest) MarshalProto() ([]byte, error) {
+	var enc rpc.Encoder
+	enc.String(1, app.Kid)
+
+	return enc.Bytes(), nil
+}
+
+// UnmarshalProto implements the rpc message interface.
+func (app *TokenRequest) UnmarshalProto(data []byte) error {
+	return rpc.Decode(data, func(f rpc.Field) error {
+		var err error
+		if f.Num == 1 {
+			app.Kid, err = f.String()
+		}
+
+		return err
+	})
+}
+
+// TokenResponse holds a token requested over grpc.
+type TokenResponse struct {
+	Token string
+}
+
+// MarshalProto implements the rpc message interface.
+func (app *TokenResponse) MarshalProto() ([]byte, error) {
+	var enc rpc.Encoder
+	enc.String(1, app.Token)
+
+	return enc.Bytes(), nil
+}
+
+// UnmarshalProto implements the rpc message interface.
+func (app *TokenResponse) UnmarshalProto(data []byte) error {
+	return rpc.Decode(data, func(f rpc.Field) error {
+		var err error
+		if f.Num == 1 {
+			app.Token, err = f.String()
+		}
+
+		return err
+	})
+}
+
+// =============================================================================
+
+func optionalString(f rpc.Field) (*string, error) {
+	v, err := f.String()
+	if err != nil {
+		return nil, err
+	}
+
+	return &v, nil
+}
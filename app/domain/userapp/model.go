@@ -7,21 +7,30 @@ import (
 	"time"
 
 	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/extid"
 	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/types/department"
 	"github.com/ardanlabs/service/business/types/name"
 	"github.com/ardanlabs/service/business/types/role"
+	"github.com/google/uuid"
 )
 
 // User represents information about an individual user.
 type User struct {
-	ID          string   `json:"id"`
-	Name        string   `json:"name"`
-	Email       string   `json:"email"`
-	Roles       []string `json:"roles"`
-	Department  string   `json:"department"`
-	Enabled     bool     `json:"enabled"`
-	DateCreated string   `json:"dateCreated"`
-	DateUpdated string   `json:"dateUpdated"`
+	ID            string   `json:"id"`
+	Name          string   `json:"name" class:"confidential"`
+	Email         string   `json:"email" class:"confidential"`
+	Roles         []string `json:"roles" class:"internal"`
+	Department    string   `json:"department" class:"internal"`
+	ManagerID     string   `json:"managerID,omitempty" class:"internal"`
+	Enabled       bool     `json:"enabled"`
+	Avatar        string   `json:"avatar,omitempty"`
+	CreatedBy     string   `json:"createdBy,omitempty" class:"internal"`
+	UpdatedBy     string   `json:"updatedBy,omitempty" class:"internal"`
+	DateCreated   string   `json:"dateCreated"`
+	DateUpdated   string   `json:"dateUpdated"`
+	DateLastLogin string   `json:"dateLastLogin,omitempty"`
+	Version       int      `json:"version"`
 }
 
 // Encode implements the encoder interface.
@@ -31,18 +40,43 @@ func (app User) Encode() ([]byte, string, error) {
 }
 
 func toAppUser(bus userbus.User) User {
+	// The avatar is given as the route that redirects to it, a signed url
+	// would expire while the user is cached by the client.
+	var avatar string
+	if bus.AvatarKey != "" {
+		avatar = fmt.Sprintf("/v1/users/%s/avatar", extid.Encode(bus.ID))
+	}
+
+	// Users that never logged in are left without a date.
+	var dateLastLogin string
+	if !bus.DateLastLogin.IsZero() {
+		dateLastLogin = bus.DateLastLogin.Format(time.RFC3339)
+	}
+
 	return User{
-		ID:          bus.ID.String(),
-		Name:        bus.Name.String(),
-		Email:       bus.Email.Address,
-		Roles:       role.ParseToString(bus.Roles),
-		Department:  bus.Department.String(),
-		Enabled:     bus.Enabled,
-		DateCreated: bus.DateCreated.Format(time.RFC3339),
-		DateUpdated: bus.DateUpdated.Format(time.RFC3339),
+		ID:            extid.Encode(bus.ID),
+		Name:          bus.Name.String(),
+		Email:         bus.Email.Address,
+		Roles:         role.ParseToString(bus.Roles),
+		Department:    bus.Department.String(),
+		ManagerID:     extid.EncodeNull(bus.ManagerID),
+		Enabled:       bus.Enabled,
+		Avatar:        avatar,
+		CreatedBy:     encodeActor(bus.CreatedBy),
+		UpdatedBy:     encodeActor(bus.UpdatedBy),
+		DateCreated:   bus.DateCreated.Format(time.RFC3339),
+		DateUpdated:   bus.DateUpdated.Format(time.RFC3339),
+		DateLastLogin: dateLastLogin,
+		Version:       bus.Version,
 	}
 }
 
+// encodeActor leaves out the actor of users created before changes were
+// attributed.
+func encodeActor(actorID uuid.UUID) string {
+	return extid.EncodeNull(uuid.NullUUID{UUID: actorID, Valid: actorID != uuid.Nil})
+}
+
 func toAppUsers(users []userbus.User) []User {
 	app := make([]User, len(users))
 	for i, usr := range users {
@@ -60,6 +94,7 @@ type NewUser struct {
 	Email           string   `json:"email" validate:"required,email"`
 	Roles           []string `json:"roles" validate:"required"`
 	Department      string   `json:"department"`
+	ManagerID       string   `json:"managerID"`
 	Password        string   `json:"password" validate:"required"`
 	PasswordConfirm string   `json:"passwordConfirm" validate:"eqfield=Password"`
 }
@@ -94,7 +129,12 @@ func toBusNewUser(app NewUser) (userbus.NewUser, error) {
 		return userbus.NewUser{}, fmt.Errorf("parse: %w", err)
 	}
 
-	department, err := name.ParseNull(app.Department)
+	dept, err := department.ParseNull(app.Department)
+	if err != nil {
+		return userbus.NewUser{}, fmt.Errorf("parse: %w", err)
+	}
+
+	managerID, err := parseManagerID(app.ManagerID)
 	if err != nil {
 		return userbus.NewUser{}, fmt.Errorf("parse: %w", err)
 	}
@@ -103,7 +143,8 @@ func toBusNewUser(app NewUser) (userbus.NewUser, error) {
 		Name:       nme,
 		Email:      *addr,
 		Roles:      roles,
-		Department: department,
+		Department: dept,
+		ManagerID:  managerID,
 		Password:   app.Password,
 	}
 
@@ -112,9 +153,67 @@ func toBusNewUser(app NewUser) (userbus.NewUser, error) {
 
 // =============================================================================
 
+// Set of modes a batch can be created with.
+const (
+	batchModeAtomic     = "atomic"
+	batchModeBestEffort = "best-effort"
+)
+
+// NewUserBatch defines the data needed to add a set of new users. In atomic
+// mode no user is created if any row fails, in best-effort mode every valid
+// row is created.
+type NewUserBatch struct {
+	Mode  string    `json:"mode" validate:"omitempty,oneof=atomic best-effort"`
+	Users []NewUser `json:"users" validate:"required,min=1,max=10000"`
+}
+
+// Decode implements the decoder interface.
+func (app *NewUserBatch) Decode(data []byte) error {
+	return json.Unmarshal(data, app)
+}
+
+// Validate checks the data in the model is considered clean. The rows are
+// validated individually so each failure can be reported by index.
+func (app NewUserBatch) Validate() error {
+	if err := errs.Check(app); err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+
+	return nil
+}
+
+func toBusBatchMode(mode string) userbus.BatchMode {
+	if mode == batchModeBestEffort {
+		return userbus.BatchBestEffort
+	}
+
+	return userbus.BatchAtomic
+}
+
+// BatchError represents the failure of a single row in a batch.
+type BatchError struct {
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
+// BatchResult represents the result of creating a batch of users.
+type BatchResult struct {
+	Users  []User       `json:"users"`
+	Errors []BatchError `json:"errors"`
+}
+
+// Encode implements the encoder interface.
+func (app BatchResult) Encode() ([]byte, string, error) {
+	data, err := json.Marshal(app)
+	return data, "application/json", err
+}
+
+// =============================================================================
+
 // UpdateUserRole defines the data needed to update a user role.
 type UpdateUserRole struct {
-	Roles []string `json:"roles" validate:"required"`
+	Roles   []string `json:"roles" validate:"required"`
+	Version *int     `json:"version"`
 }
 
 // Decode implements the decoder interface.
@@ -142,7 +241,8 @@ func toBusUpdateUserRole(app UpdateUserRole) (userbus.UpdateUser, error) {
 	}
 
 	bus := userbus.UpdateUser{
-		Roles: roles,
+		Roles:   roles,
+		Version: app.Version,
 	}
 
 	return bus, nil
@@ -155,9 +255,11 @@ type UpdateUser struct {
 	Name            *string `json:"name"`
 	Email           *string `json:"email" validate:"omitempty,email"`
 	Department      *string `json:"department"`
+	ManagerID       *string `json:"managerID"`
 	Password        *string `json:"password"`
 	PasswordConfirm *string `json:"passwordConfirm" validate:"omitempty,eqfield=Password"`
 	Enabled         *bool   `json:"enabled"`
+	Version         *int    `json:"version"`
 }
 
 // Decode implements the decoder interface.
@@ -193,22 +295,209 @@ func toBusUpdateUser(app UpdateUser) (userbus.UpdateUser, error) {
 		nme = &nm
 	}
 
-	var department *name.Null
+	var dept *department.Null
 	if app.Department != nil {
-		dep, err := name.ParseNull(*app.Department)
+		dep, err := department.ParseNull(*app.Department)
+		if err != nil {
+			return userbus.UpdateUser{}, fmt.Errorf("parse: %w", err)
+		}
+		dept = &dep
+	}
+
+	var managerID *uuid.NullUUID
+	if app.ManagerID != nil {
+		mgr, err := parseManagerID(*app.ManagerID)
 		if err != nil {
 			return userbus.UpdateUser{}, fmt.Errorf("parse: %w", err)
 		}
-		department = &dep
+		managerID = &mgr
 	}
 
 	bus := userbus.UpdateUser{
 		Name:       nme,
 		Email:      addr,
-		Department: department,
+		Department: dept,
+		ManagerID:  managerID,
 		Password:   app.Password,
 		Enabled:    app.Enabled,
+		Version:    app.Version,
 	}
 
 	return bus, nil
 }
+
+// parseManagerID parses the id of a user's manager. An empty string means
+// the user has no manager.
+func parseManagerID(s string) (uuid.NullUUID, error) {
+	if s == "" {
+		return uuid.NullUUID{}, nil
+	}
+
+	id, err := extid.Decode(s)
+	if err != nil {
+		return uuid.NullUUID{}, err
+	}
+
+	return uuid.NullUUID{UUID: id, Valid: true}, nil
+}
+
+// =============================================================================
+
+type users []User
+
+// Encode implements the encoder interface.
+func (app users) Encode() ([]byte, string, error) {
+	data, err := json.Marshal(app)
+	return data, "application/json", err
+}
+
+// =============================================================================
+
+// NewRoleAssignment defines the roles to add to and remove from the users
+// that match the filter.
+type NewRoleAssignment struct {
+	AddRoles    []string `json:"addRoles"`
+	RemoveRoles []string `json:"removeRoles"`
+}
+
+// Decode implements the decoder interface.
+func (app *NewRoleAssignment) Decode(data []byte) error {
+	return json.Unmarshal(data, app)
+}
+
+// Validate checks the data in the model is considered clean.
+func (app NewRoleAssignment) Validate() error {
+	if err := errs.Check(app); err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+
+	return nil
+}
+
+// RevertRoleAssignment defines the data needed to revert a role assignment.
+type RevertRoleAssignment struct {
+	RevertToken string `json:"revertToken" validate:"required"`
+}
+
+// Decode implements the decoder interface.
+func (app *RevertRoleAssignment) Decode(data []byte) error {
+	return json.Unmarshal(data, app)
+}
+
+// Validate checks the data in the model is considered clean.
+func (app RevertRoleAssignment) Validate() error {
+	if err := errs.Check(app); err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+
+	return nil
+}
+
+// RoleChange represents the change of roles for a single user.
+type RoleChange struct {
+	UserID   string   `json:"userID"`
+	OldRoles []string `json:"oldRoles" class:"internal"`
+	NewRoles []string `json:"newRoles" class:"internal"`
+}
+
+// RoleAssignment represents a previewed, applied or reverted change of roles
+// for a set of users. The revert token is only returned when the assignment
+// is applied.
+type RoleAssignment struct {
+	ID           string       `json:"id"`
+	AddRoles     []string     `json:"addRoles"`
+	RemoveRoles  []string     `json:"removeRoles"`
+	Affected     int          `json:"affected"`
+	Sample       []RoleChange `json:"sample,omitempty"`
+	RevertToken  string       `json:"revertToken,omitempty" class:"restricted"`
+	DateCreated  string       `json:"dateCreated"`
+	DateApplied  string       `json:"dateApplied,omitempty"`
+	DateReverted string       `json:"dateReverted,omitempty"`
+}
+
+// Encode implements the encoder interface.
+func (app RoleAssignment) Encode() ([]byte, string, error) {
+	data, err := json.Marshal(app)
+	return data, "application/json", err
+}
+
+func toAppRoleAssignment(bus userbus.RoleAssignment, revertToken string) RoleAssignment {
+	sample := make([]RoleChange, len(bus.Sample))
+	for i, chg := range bus.Sample {
+		sample[i] = RoleChange{
+			UserID:   extid.Encode(chg.UserID),
+			OldRoles: role.ParseToString(chg.OldRoles),
+			NewRoles: role.ParseToString(chg.NewRoles),
+		}
+	}
+
+	app := RoleAssignment{
+		ID:          extid.Encode(bus.ID),
+		AddRoles:    role.ParseToString(bus.AddRoles),
+		RemoveRoles: role.ParseToString(bus.RemoveRoles),
+		Affected:    bus.Affected,
+		Sample:      sample,
+		RevertToken: revertToken,
+		DateCreated: bus.DateCreated.Format(time.RFC3339),
+	}
+
+	if bus.Applied() {
+		app.DateApplied = bus.DateApplied.Format(time.RFC3339)
+	}
+
+	if bus.Reverted() {
+		app.DateReverted = bus.DateReverted.Format(time.RFC3339)
+	}
+
+	return app
+}
+
+// =============================================================================
+
+// Preferences represents the user's preferences keyed by name.
+type Preferences map[string]json.RawMessage
+
+// Encode implements the encoder interface.
+func (app Preferences) Encode() ([]byte, string, error) {
+	data, err := json.Marshal(app)
+	return data, "application/json", err
+}
+
+func toAppPreferences(prefs []userbus.Preference) Preferences {
+	app := make(Preferences, len(prefs))
+	for _, pref := range prefs {
+		app[pref.Key] = pref.Value
+	}
+
+	return app
+}
+
+// SetPreference defines the data needed to set a preference. The value's
+// type depends on the preference.
+type SetPreference struct {
+	Value json.RawMessage `json:"value" validate:"required"`
+}
+
+// Decode implements the decoder interface.
+func (app *SetPreference) Decode(data []byte) error {
+	return json.Unmarshal(data, app)
+}
+
+// Validate checks the data in the model is considered clean.
+func (app SetPreference) Validate() error {
+	if err := errs.Check(app); err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// export is the ZIP archive of a user's data.
+type export []byte
+
+// Encode implements the encoder interface.
+func (app export) Encode() ([]byte, string, error) {
+	return app, "application/zip", nil
+}
@@ -0,0 +1,156 @@
+package userapp
+
+import (
+	"net/http"
+
+	"github.com/ardanlabs/service/app/sdk/openapi"
+	"github.com/ardanlabs/service/app/sdk/query"
+)
+
+// RegisterOpenAPI describes the routes added by Routes. Keep the two in step
+// when a route or the model it uses changes.
+func RegisterOpenAPI(doc *openapi.Document) {
+	const version = "v1"
+
+	tags := []string{"users"}
+
+	queryParams := []openapi.Param{
+		{Name: "page", Type: "integer"},
+		{Name: "rows", Type: "integer"},
+		{Name: "cursor", Description: "keyset cursor returned as nextCursor, empty for the first page"},
+		{Name: "orderBy", Description: "field and direction, like name,ASC"},
+		{Name: "user_id"},
+		{Name: "name"},
+		{Name: "email"},
+		{Name: "search"},
+		{Name: "roles", Description: "comma separated list of roles"},
+		{Name: "enabled", Type: "boolean"},
+		{Name: "department"},
+		{Name: "start_created_date", Description: "RFC3339 time"},
+		{Name: "end_created_date", Description: "RFC3339 time"},
+		{Name: "not_logged_in_since", Description: "RFC3339 time"},
+	}
+
+	doc.Add(http.MethodGet, version, "/users", openapi.Operation{
+		Summary:  "Query users",
+		Tags:     tags,
+		Query:    queryParams,
+		Response: query.Result[User]{},
+	})
+
+	doc.Add(http.MethodGet, version, "/users/{user_id}", openapi.Operation{
+		Summary:  "Query a user by id",
+		Tags:     tags,
+		Response: User{},
+	})
+
+	doc.Add(http.MethodGet, version, "/users/{user_id}/reports", openapi.Operation{
+		Summary:  "Query the direct reports of a user",
+		Tags:     tags,
+		Response: users{},
+	})
+
+	doc.Add(http.MethodGet, version, "/users/{user_id}/chain", openapi.Operation{
+		Summary:  "Query the management chain of a user",
+		Tags:     tags,
+		Response: users{},
+	})
+
+	doc.Add(http.MethodGet, version, "/users/{user_id}/preferences", openapi.Operation{
+		Summary:  "Query the preferences of a user",
+		Tags:     tags,
+		Response: Preferences{},
+	})
+
+	doc.Add(http.MethodPut, version, "/users/{user_id}/preferences/{key}", openapi.Operation{
+		Summary:  "Set a preference",
+		Tags:     tags,
+		Request:  SetPreference{},
+		Response: Preferences{},
+	})
+
+	doc.Add(http.MethodDelete, version, "/users/{user_id}/preferences/{key}", openapi.Operation{
+		Summary: "Delete a preference",
+		Tags:    tags,
+	})
+
+	doc.Add(http.MethodGet, version, "/users/{user_id}/avatar", openapi.Operation{
+		Summary: "Download the avatar image",
+		Tags:    tags,
+		Status:  http.StatusOK,
+	})
+
+	doc.Add(http.MethodPost, version, "/users/{user_id}/avatar", openapi.Operation{
+		Summary:  "Upload the avatar image sent as the request body",
+		Tags:     tags,
+		Response: User{},
+	})
+
+	doc.Add(http.MethodGet, version, "/users/{user_id}/export", openapi.Operation{
+		Summary: "Export the data held about a user",
+		Tags:    tags,
+		Status:  http.StatusOK,
+	})
+
+	doc.Add(http.MethodPost, version, "/users", openapi.Operation{
+		Summary: "Create a user",
+		Tags:    tags,
+		Query: []openapi.Param{
+			{Name: "onConflict", Description: "return to get the existing user back when the email is taken"},
+		},
+		Headers: []openapi.Param{
+			{Name: "Idempotency-Key"},
+		},
+		Request:  NewUser{},
+		Response: User{},
+	})
+
+	doc.Add(http.MethodPost, version, "/users/batch", openapi.Operation{
+		Summary:  "Create a batch of users",
+		Tags:     tags,
+		Request:  NewUserBatch{},
+		Response: BatchResult{},
+	})
+
+	doc.Add(http.MethodPut, version, "/users/role/{user_id}", openapi.Operation{
+		Summary:  "Replace the roles of a user",
+		Tags:     tags,
+		Request:  UpdateUserRole{},
+		Response: User{},
+	})
+
+	doc.Add(http.MethodPost, version, "/users/roles/assignments", openapi.Operation{
+		Summary:  "Preview a bulk role assignment",
+		Tags:     tags,
+		Request:  NewRoleAssignment{},
+		Response: RoleAssignment{},
+	})
+
+	doc.Add(http.MethodPost, version, "/users/roles/assignments/{assignment_id}/apply", openapi.Operation{
+		Summary:  "Apply a bulk role assignment",
+		Tags:     tags,
+		Response: RoleAssignment{},
+	})
+
+	doc.Add(http.MethodPost, version, "/users/roles/revert", openapi.Operation{
+		Summary:  "Revert an applied role assignment",
+		Tags:     tags,
+		Request:  RevertRoleAssignment{},
+		Response: RoleAssignment{},
+	})
+
+	doc.Add(http.MethodPut, version, "/users/{user_id}", openapi.Operation{
+		Summary:  "Update a user",
+		Tags:     tags,
+		Request:  UpdateUser{},
+		Response: User{},
+	})
+
+	doc.Add(http.MethodDelete, version, "/users/{user_id}", openapi.Operation{
+		Summary: "Delete a user",
+		Tags:    tags,
+		Query: []openapi.Param{
+			{Name: "mode", Description: "anonymize to keep the row with the personal data removed"},
+		},
+	})
+}
@@ -4,10 +4,4 @@ import (
 	"github.com/ardanlabs/service/business/domain/userbus"
 )
 
-var orderByFields = map[string]string{
-	"user_id": userbus.OrderByID,
-	"name":    userbus.OrderByName,
-	"email":   userbus.OrderByEmail,
-	"roles":   userbus.OrderByRoles,
-	"enabled": userbus.OrderByEnabled,
-}
+var orderByFields = userbus.OrderFields.Mappings()
@@ -2,13 +2,18 @@ package userapp
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/ardanlabs/service/app/sdk/auth"
 	"github.com/ardanlabs/service/app/sdk/authclient"
 	"github.com/ardanlabs/service/app/sdk/mid"
+	"github.com/ardanlabs/service/app/sdk/rpc"
 	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
 	"github.com/ardanlabs/service/foundation/logger"
 	"github.com/ardanlabs/service/foundation/web"
+	"github.com/jmoiron/sqlx"
+	"google.golang.org/grpc"
 )
 
 // Config contains all the mandatory systems required by handlers.
@@ -16,8 +21,13 @@ type Config struct {
 	Log        *logger.Logger
 	UserBus    userbus.Business
 	AuthClient *authclient.Client
+	DB         *sqlx.DB
 }
 
+// recentAuthMaxAge is how long after logging in a user can make sensitive
+// changes without authenticating again.
+const recentAuthMaxAge = 15 * time.Minute
+
 // Routes adds specific routes for this group.
 func Routes(app *web.App, cfg Config) {
 	const version = "v1"
@@ -26,13 +36,44 @@ func Routes(app *web.App, cfg Config) {
 	ruleAdmin := mid.Authorize(cfg.AuthClient, auth.RuleAdminOnly)
 	ruleAuthorizeUser := mid.AuthorizeUser(cfg.AuthClient, cfg.UserBus, auth.RuleAdminOrSubject)
 	ruleAuthorizeAdmin := mid.AuthorizeUser(cfg.AuthClient, cfg.UserBus, auth.RuleAdminOnly)
+	recentAuth := mid.RequireRecentAuth(recentAuthMaxAge, false)
 
-	api := newApp(cfg.UserBus)
+	api := newApp(cfg.UserBus, sqldb.NewBeginner(cfg.DB))
 
 	app.HandlerFunc(http.MethodGet, version, "/users", api.query, authen, ruleAdmin)
 	app.HandlerFunc(http.MethodGet, version, "/users/{user_id}", api.queryByID, authen, ruleAuthorizeUser)
+	app.HandlerFunc(http.MethodGet, version, "/users/{user_id}/reports", api.directReports, authen, ruleAuthorizeUser)
+	app.HandlerFunc(http.MethodGet, version, "/users/{user_id}/chain", api.managementChain, authen, ruleAuthorizeUser)
+	app.HandlerFunc(http.MethodGet, version, "/users/{user_id}/preferences", api.queryPreferences, authen, ruleAuthorizeUser)
+	app.HandlerFunc(http.MethodPut, version, "/users/{user_id}/preferences/{key}", api.setPreference, authen, ruleAuthorizeUser)
+	app.HandlerFunc(http.MethodDelete, version, "/users/{user_id}/preferences/{key}", api.deletePreference, authen, ruleAuthorizeUser)
+	app.HandlerFunc(http.MethodGet, version, "/users/{user_id}/avatar", api.avatar, authen, ruleAuthorizeUser)
+	app.HandlerFunc(http.MethodPost, version, "/users/{user_id}/avatar", api.updateAvatar, authen, ruleAuthorizeUser)
+	app.HandlerFunc(http.MethodGet, version, "/users/{user_id}/export", api.exportData, authen, recentAuth, ruleAuthorizeUser)
 	app.HandlerFunc(http.MethodPost, version, "/users", api.create, authen, ruleAdmin)
-	app.HandlerFunc(http.MethodPut, version, "/users/role/{user_id}", api.updateRole, authen, ruleAuthorizeAdmin)
+	app.HandlerFunc(http.MethodPost, version, "/users/batch", api.createBatch, authen, ruleAdmin)
+	app.HandlerFunc(http.MethodPut, version, "/users/role/{user_id}", api.updateRole, authen, recentAuth, ruleAuthorizeAdmin)
+	app.HandlerFunc(http.MethodPost, version, "/users/roles/assignments", api.assignRoles, authen, recentAuth, ruleAdmin)
+	app.HandlerFunc(http.MethodPost, version, "/users/roles/assignments/{assignment_id}/apply", api.applyRoleAssignment, authen, recentAuth, ruleAdmin)
+	app.HandlerFunc(http.MethodPost, version, "/users/roles/revert", api.revertRoleAssignment, authen, recentAuth, ruleAdmin)
 	app.HandlerFunc(http.MethodPut, version, "/users/{user_id}", api.update, authen, ruleAuthorizeUser)
-	app.HandlerFunc(http.MethodDelete, version, "/users/{user_id}", api.delete, authen, ruleAuthorizeUser)
+	app.HandlerFunc(http.MethodDelete, version, "/users/{user_id}", api.delete, authen, recentAuth, ruleAuthorizeUser)
+}
+
+// RegisterGRPC adds the user service to the grpc server.
+func RegisterGRPC(srv *grpc.Server, cfg Config) {
+	g := grpcApp{
+		app:        newApp(cfg.UserBus, sqldb.NewBeginner(cfg.DB)),
+		authClient: cfg.AuthClient,
+		userBus:    cfg.UserBus,
+	}
+
+	svc := rpc.NewService(serviceName)
+	rpc.Handle(svc, "Create", g.create)
+	rpc.Handle(svc, "Update", g.update)
+	rpc.Handle(svc, "Delete", g.delete)
+	rpc.Handle(svc, "Query", g.query)
+	rpc.Handle(svc, "QueryByID", g.queryByID)
+	rpc.Handle(svc, "Token", g.token)
+	svc.Register(srv)
 }
@@ -4,57 +4,203 @@ package userapp
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"slices"
 
 	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/extid"
 	"github.com/ardanlabs/service/app/sdk/mid"
 	"github.com/ardanlabs/service/app/sdk/query"
 	"github.com/ardanlabs/service/business/domain/userbus"
 	"github.com/ardanlabs/service/business/sdk/order"
 	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/business/sdk/unitofwork"
+	"github.com/ardanlabs/service/business/types/role"
 	"github.com/ardanlabs/service/foundation/web"
 )
 
 type app struct {
 	userBus userbus.Business
+	bgn     sqldb.Beginner
 }
 
-func newApp(userBus userbus.Business) *app {
+func newApp(userBus userbus.Business, bgn sqldb.Beginner) *app {
 	return &app{
 		userBus: userBus,
+		bgn:     bgn,
 	}
 }
 
+// errBatchFailed rolls back the transaction of an atomic batch that has
+// users that failed.
+var errBatchFailed = errors.New("batch failed")
+
+// maxIdempotencyKey is the longest idempotency key a client can send.
+const maxIdempotencyKey = 255
+
 func (a *app) create(ctx context.Context, r *http.Request) web.Encoder {
 	var app NewUser
 	if err := web.Decode(r, &app); err != nil {
 		return errs.New(errs.InvalidArgument, err)
 	}
 
+	usr, err := a.createUser(ctx, app, r.Header.Get("Idempotency-Key"), r.URL.Query().Get("onConflict"))
+	if err != nil {
+		return errs.NewError(err)
+	}
+
+	return usr
+}
+
+// createUser adds the user for the http and grpc apis. The errors returned
+// are app errors.
+func (a *app) createUser(ctx context.Context, app NewUser, idempotencyKey string, onConflict string) (User, error) {
 	nc, err := toBusNewUser(app)
 	if err != nil {
-		return errs.New(errs.InvalidArgument, err)
+		return User{}, errs.New(errs.InvalidArgument, err)
+	}
+
+	// Clients that retry on a timeout send the same key with every attempt
+	// so the user is only created once.
+	nc.IdempotencyKey = idempotencyKey
+	if len(nc.IdempotencyKey) > maxIdempotencyKey {
+		return User{}, errs.NewFieldErrors("Idempotency-Key", fmt.Errorf("must be at most %d characters", maxIdempotencyKey))
+	}
+
+	// Signups can be made idempotent by asking for the existing user to be
+	// returned when the email is already taken.
+	var usr userbus.User
+	switch onConflict {
+	case "", "error":
+		usr, _, err = a.userBus.Create(ctx, mid.GetSubjectID(ctx), nc)
+	case "return":
+		usr, _, err = a.userBus.CreateOrGet(ctx, mid.GetSubjectID(ctx), nc)
+	default:
+		return User{}, errs.NewFieldErrors("onConflict", errors.New("must be error or return"))
 	}
 
-	usr, err := a.userBus.Create(ctx, mid.GetSubjectID(ctx), nc)
 	if err != nil {
 		if errors.Is(err, userbus.ErrUniqueEmail) {
-			return errs.New(errs.Aborted, userbus.ErrUniqueEmail)
+			return User{}, errs.New(errs.Aborted, userbus.ErrUniqueEmail)
+		}
+		if errors.Is(err, userbus.ErrIdempotencyMismatch) {
+			return User{}, errs.New(errs.FailedPrecondition, userbus.ErrIdempotencyMismatch)
+		}
+		if errors.Is(err, userbus.ErrForbidden) {
+			return User{}, errs.New(errs.PermissionDenied, userbus.ErrForbidden)
+		}
+		if errors.Is(err, userbus.ErrInvalidActor) {
+			return User{}, errs.New(errs.PermissionDenied, userbus.ErrInvalidActor)
+		}
+		if errors.Is(err, userbus.ErrNotFound) {
+			return User{}, errs.NewFieldErrors("managerID", userbus.ErrNotFound)
 		}
-		return errs.Newf(errs.Internal, "create: usr[%+v]: %s", usr, err)
+		if errors.Is(err, userbus.ErrInvalidUser) {
+			return User{}, errs.NewError(err)
+		}
+		var ppe *userbus.PasswordPolicyError
+		if errors.As(err, &ppe) {
+			return User{}, errs.NewFieldErrors("password", ppe)
+		}
+		var re *userbus.RuleError
+		if errors.As(err, &re) {
+			return User{}, toRuleFieldErrors(re)
+		}
+		return User{}, errs.Newf(errs.Internal, "create: usr[%+v]: %s", usr, err)
 	}
 
-	return toAppUser(usr)
+	return toAppUser(usr), nil
 }
 
-func (a *app) update(ctx context.Context, r *http.Request) web.Encoder {
-	var app UpdateUser
+func (a *app) createBatch(ctx context.Context, r *http.Request) web.Encoder {
+	var app NewUserBatch
 	if err := web.Decode(r, &app); err != nil {
 		return errs.New(errs.InvalidArgument, err)
 	}
 
-	uu, err := toBusUpdateUser(app)
-	if err != nil {
+	mode := toBusBatchMode(app.Mode)
+
+	// Keep track of the request index for each row passed to the business
+	// layer so failures are reported against the row the caller sent.
+	var result BatchResult
+	nus := make([]userbus.NewUser, 0, len(app.Users))
+	idx := make([]int, 0, len(app.Users))
+
+	for i, row := range app.Users {
+		if err := row.Validate(); err != nil {
+			result.Errors = append(result.Errors, BatchError{Index: i, Error: err.Error()})
+			continue
+		}
+
+		nu, err := toBusNewUser(row)
+		if err != nil {
+			result.Errors = append(result.Errors, BatchError{Index: i, Error: err.Error()})
+			continue
+		}
+
+		nus = append(nus, nu)
+		idx = append(idx, i)
+	}
+
+	if mode == userbus.BatchAtomic && len(result.Errors) > 0 {
+		return toBatchFieldErrors(result.Errors)
+	}
+
+	var usrs []userbus.User
+	var bes []userbus.BatchError
+
+	// In atomic mode the batch is created in a transaction that is rolled
+	// back when any of the users fails.
+	userBus := a.userBus
+	createBatch := func(ctx context.Context) error {
+		usrs, bes = userBus.CreateBatch(ctx, mid.GetSubjectID(ctx), nus, mode)
+		if mode == userbus.BatchAtomic && len(bes) > 0 {
+			return errBatchFailed
+		}
+		return nil
+	}
+
+	if mode == userbus.BatchAtomic {
+		if err := unitofwork.Run(ctx, a.bgn, mid.GetSubjectID(ctx), createBatch, unitofwork.Bind(&userBus)); err != nil && !errors.Is(err, errBatchFailed) {
+			return errs.Newf(errs.Internal, "createbatch: %s", err)
+		}
+	} else {
+		createBatch(ctx)
+	}
+
+	for _, be := range bes {
+		if be.Index < 0 {
+			if errors.Is(be.Err, userbus.ErrForbidden) {
+				return errs.New(errs.PermissionDenied, userbus.ErrForbidden)
+			}
+			if errors.Is(be.Err, userbus.ErrInvalidActor) {
+				return errs.New(errs.PermissionDenied, userbus.ErrInvalidActor)
+			}
+			return errs.Newf(errs.Internal, "createbatch: %s", be.Err)
+		}
+
+		result.Errors = append(result.Errors, BatchError{Index: idx[be.Index], Error: be.Err.Error()})
+	}
+
+	if mode == userbus.BatchAtomic && len(result.Errors) > 0 {
+		return toBatchFieldErrors(result.Errors)
+	}
+
+	slices.SortFunc(result.Errors, func(a, b BatchError) int {
+		return a.Index - b.Index
+	})
+
+	result.Users = toAppUsers(usrs)
+
+	return result
+}
+
+func (a *app) update(ctx context.Context, r *http.Request) web.Encoder {
+	var app UpdateUser
+	if err := web.Decode(r, &app); err != nil {
 		return errs.New(errs.InvalidArgument, err)
 	}
 
@@ -63,12 +209,54 @@ func (a *app) update(ctx context.Context, r *http.Request) web.Encoder {
 		return errs.Newf(errs.Internal, "user missing in context: %s", err)
 	}
 
+	updUsr, err := a.updateUser(ctx, usr, app)
+	if err != nil {
+		return errs.NewError(err)
+	}
+
+	return updUsr
+}
+
+// updateUser updates the user for the http and grpc apis. The errors
+// returned are app errors.
+func (a *app) updateUser(ctx context.Context, usr userbus.User, app UpdateUser) (User, error) {
+	uu, err := toBusUpdateUser(app)
+	if err != nil {
+		return User{}, errs.New(errs.InvalidArgument, err)
+	}
+
 	updUsr, err := a.userBus.Update(ctx, mid.GetSubjectID(ctx), usr, uu)
 	if err != nil {
-		return errs.Newf(errs.Internal, "update: userID[%s] uu[%+v]: %s", usr.ID, uu, err)
+		if errors.Is(err, userbus.ErrForbidden) {
+			return User{}, errs.New(errs.PermissionDenied, userbus.ErrForbidden)
+		}
+		if errors.Is(err, userbus.ErrInvalidActor) {
+			return User{}, errs.New(errs.PermissionDenied, userbus.ErrInvalidActor)
+		}
+		if errors.Is(err, userbus.ErrManagerCycle) {
+			return User{}, errs.NewFieldErrors("managerID", userbus.ErrManagerCycle)
+		}
+		if errors.Is(err, userbus.ErrVersionConflict) {
+			return User{}, errs.New(errs.Aborted, userbus.ErrVersionConflict)
+		}
+		if errors.Is(err, userbus.ErrNotFound) {
+			return User{}, errs.NewFieldErrors("managerID", userbus.ErrNotFound)
+		}
+		if errors.Is(err, userbus.ErrInvalidUser) {
+			return User{}, errs.NewError(err)
+		}
+		var ppe *userbus.PasswordPolicyError
+		if errors.As(err, &ppe) {
+			return User{}, errs.NewFieldErrors("password", ppe)
+		}
+		var re *userbus.RuleError
+		if errors.As(err, &re) {
+			return User{}, toRuleFieldErrors(re)
+		}
+		return User{}, errs.Newf(errs.Internal, "update: userID[%s] uu[%+v]: %s", usr.ID, uu, err)
 	}
 
-	return toAppUser(updUsr)
+	return toAppUser(updUsr), nil
 }
 
 func (a *app) updateRole(ctx context.Context, r *http.Request) web.Encoder {
@@ -89,19 +277,64 @@ func (a *app) updateRole(ctx context.Context, r *http.Request) web.Encoder {
 
 	updUsr, err := a.userBus.Update(ctx, mid.GetSubjectID(ctx), usr, uu)
 	if err != nil {
+		if errors.Is(err, userbus.ErrForbidden) {
+			return errs.New(errs.PermissionDenied, userbus.ErrForbidden)
+		}
+		if errors.Is(err, userbus.ErrInvalidActor) {
+			return errs.New(errs.PermissionDenied, userbus.ErrInvalidActor)
+		}
+		if errors.Is(err, userbus.ErrVersionConflict) {
+			return errs.New(errs.Aborted, userbus.ErrVersionConflict)
+		}
+		if errors.Is(err, userbus.ErrInvalidUser) {
+			return errs.NewError(err)
+		}
+		var re *userbus.RuleError
+		if errors.As(err, &re) {
+			return toRuleFieldErrors(re)
+		}
 		return errs.Newf(errs.Internal, "updaterole: userID[%s] uu[%+v]: %s", usr.ID, uu, err)
 	}
 
 	return toAppUser(updUsr)
 }
 
-func (a *app) delete(ctx context.Context, _ *http.Request) web.Encoder {
+// delete removes the user. With the anonymize mode the user is kept with
+// their personal data scrubbed instead.
+func (a *app) delete(ctx context.Context, r *http.Request) web.Encoder {
 	usr, err := mid.GetUser(ctx)
 	if err != nil {
 		return errs.Newf(errs.Internal, "userID missing in context: %s", err)
 	}
 
-	if err := a.userBus.Delete(ctx, mid.GetSubjectID(ctx), usr); err != nil {
+	if err := a.deleteUser(ctx, usr, r.URL.Query().Get("mode")); err != nil {
+		return errs.NewError(err)
+	}
+
+	return nil
+}
+
+// deleteUser deletes or anonymizes the user for the http and grpc apis.
+// The errors returned are app errors.
+func (a *app) deleteUser(ctx context.Context, usr userbus.User, mode string) error {
+	var err error
+
+	switch mode {
+	case "", "delete":
+		err = a.userBus.Delete(ctx, mid.GetSubjectID(ctx), usr)
+	case "anonymize":
+		_, err = a.userBus.Anonymize(ctx, mid.GetSubjectID(ctx), usr.ID)
+	default:
+		return errs.Newf(errs.InvalidArgument, "unknown delete mode %q, must be delete or anonymize", mode)
+	}
+
+	if err != nil {
+		if errors.Is(err, userbus.ErrForbidden) {
+			return errs.New(errs.PermissionDenied, userbus.ErrForbidden)
+		}
+		if errors.Is(err, userbus.ErrInvalidActor) {
+			return errs.New(errs.PermissionDenied, userbus.ErrInvalidActor)
+		}
 		return errs.Newf(errs.Internal, "delete: userID[%s]: %s", usr.ID, err)
 	}
 
@@ -114,32 +347,65 @@ func (a *app) query(ctx context.Context, r *http.Request) web.Encoder {
 		return errs.New(errs.InvalidArgument, err)
 	}
 
-	page, err := page.Parse(qp.Page, qp.Rows)
+	result, err := a.queryUsers(ctx, qp)
 	if err != nil {
-		return errs.NewFieldErrors("page", err)
+		return errs.NewError(err)
 	}
 
-	filter, err := parseFilter(qp)
+	return result
+}
+
+// queryUsers runs the query for the http and grpc apis. The errors
+// returned are app errors.
+func (a *app) queryUsers(ctx context.Context, qp queryParams) (query.Result[User], error) {
+	pg, err := parsePage(qp)
 	if err != nil {
-		return err.(*errs.Error)
+		return query.Result[User]{}, err
 	}
 
-	orderBy, err := order.Parse(orderByFields, qp.OrderBy, userbus.DefaultOrderBy)
+	filter, err := parseFilter(qp)
 	if err != nil {
-		return errs.NewFieldErrors("order", err)
+		return query.Result[User]{}, err
+	}
+
+	// Search results are ranked unless the caller asks for another order.
+	defaultOrder := userbus.DefaultOrderBy
+	if filter.Search != nil && !pg.IsKeyset() {
+		defaultOrder = userbus.RelevanceOrderBy
 	}
 
-	usrs, err := a.userBus.Query(ctx, filter, orderBy, page)
+	orderBy, err := order.Parse(orderByFields, qp.OrderBy, defaultOrder)
 	if err != nil {
-		return errs.Newf(errs.Internal, "query: %s", err)
+		return query.Result[User]{}, errs.NewFieldErrors("order", err)
+	}
+
+	if pg.IsKeyset() && !userbus.KeysetOrder(orderBy) {
+		return query.Result[User]{}, errs.NewFieldErrors("cursor", errors.New("results in this order can't be paged by cursor"))
 	}
 
-	total, err := a.userBus.Count(ctx, filter)
+	usrs, total, err := a.userBus.QueryWithCount(ctx, filter, orderBy, pg)
 	if err != nil {
-		return errs.Newf(errs.Internal, "count: %s", err)
+		if errors.Is(err, page.ErrInvalidCursor) {
+			return query.Result[User]{}, errs.NewFieldErrors("cursor", err)
+		}
+		if errors.Is(err, userbus.ErrQueryTooExpensive) {
+			return query.Result[User]{}, errs.New(errs.FailedPrecondition, userbus.ErrQueryTooExpensive)
+		}
+		if errors.Is(err, userbus.ErrQueryTimeout) {
+			return query.Result[User]{}, errs.New(errs.DeadlineExceeded, userbus.ErrQueryTimeout)
+		}
+		return query.Result[User]{}, errs.Newf(errs.Internal, "querywithcount: %s", err)
+	}
+
+	var result query.Result[User]
+	switch {
+	case pg.IsKeyset():
+		result = query.NewCursorResult(toAppUsers(usrs), total, pg, userbus.NextCursor(orderBy, pg, usrs))
+	default:
+		result = query.NewResult(toAppUsers(usrs), total, pg)
 	}
 
-	return query.NewResult(toAppUsers(usrs), total, page)
+	return result, nil
 }
 
 func (a *app) queryByID(ctx context.Context, _ *http.Request) web.Encoder {
@@ -150,3 +416,298 @@ func (a *app) queryByID(ctx context.Context, _ *http.Request) web.Encoder {
 
 	return toAppUser(usr)
 }
+
+func (a *app) directReports(ctx context.Context, _ *http.Request) web.Encoder {
+	usr, err := mid.GetUser(ctx)
+	if err != nil {
+		return errs.Newf(errs.Internal, "directreports: %s", err)
+	}
+
+	usrs, err := a.userBus.DirectReports(ctx, usr.ID)
+	if err != nil {
+		return errs.Newf(errs.Internal, "directreports: userID[%s]: %s", usr.ID, err)
+	}
+
+	return users(toAppUsers(usrs))
+}
+
+func (a *app) queryPreferences(ctx context.Context, _ *http.Request) web.Encoder {
+	usr, err := mid.GetUser(ctx)
+	if err != nil {
+		return errs.Newf(errs.Internal, "querypreferences: %s", err)
+	}
+
+	prefs, err := a.userBus.GetPreferences(ctx, usr.ID)
+	if err != nil {
+		return errs.Newf(errs.Internal, "querypreferences: userID[%s]: %s", usr.ID, err)
+	}
+
+	return toAppPreferences(prefs)
+}
+
+func (a *app) setPreference(ctx context.Context, r *http.Request) web.Encoder {
+	var app SetPreference
+	if err := web.Decode(r, &app); err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	usr, err := mid.GetUser(ctx)
+	if err != nil {
+		return errs.Newf(errs.Internal, "setpreference: %s", err)
+	}
+
+	key := web.Param(r, "key")
+
+	if _, err := a.userBus.SetPreference(ctx, usr.ID, key, app.Value); err != nil {
+		switch {
+		case errors.Is(err, userbus.ErrUnknownPreference):
+			return errs.New(errs.NotFound, userbus.ErrUnknownPreference)
+		case errors.Is(err, userbus.ErrInvalidPreference):
+			return errs.NewFieldErrors("value", err)
+		}
+		return errs.Newf(errs.Internal, "setpreference: userID[%s] key[%s]: %s", usr.ID, key, err)
+	}
+
+	prefs, err := a.userBus.GetPreferences(ctx, usr.ID)
+	if err != nil {
+		return errs.Newf(errs.Internal, "querypreferences: userID[%s]: %s", usr.ID, err)
+	}
+
+	return toAppPreferences(prefs)
+}
+
+func (a *app) deletePreference(ctx context.Context, r *http.Request) web.Encoder {
+	usr, err := mid.GetUser(ctx)
+	if err != nil {
+		return errs.Newf(errs.Internal, "deletepreference: %s", err)
+	}
+
+	key := web.Param(r, "key")
+
+	if err := a.userBus.DeletePreference(ctx, usr.ID, key); err != nil {
+		if errors.Is(err, userbus.ErrUnknownPreference) {
+			return errs.New(errs.NotFound, userbus.ErrUnknownPreference)
+		}
+		return errs.Newf(errs.Internal, "deletepreference: userID[%s] key[%s]: %s", usr.ID, key, err)
+	}
+
+	return nil
+}
+
+func (a *app) updateAvatar(ctx context.Context, r *http.Request) web.Encoder {
+	usr, err := mid.GetUser(ctx)
+	if err != nil {
+		return errs.Newf(errs.Internal, "updateavatar: %s", err)
+	}
+
+	updUsr, err := a.userBus.UpdateAvatar(ctx, mid.GetSubjectID(ctx), usr.ID, r.Body, r.Header.Get("Content-Type"))
+	if err != nil {
+		switch {
+		case errors.Is(err, userbus.ErrForbidden):
+			return errs.New(errs.PermissionDenied, userbus.ErrForbidden)
+		case errors.Is(err, userbus.ErrInvalidActor):
+			return errs.New(errs.PermissionDenied, userbus.ErrInvalidActor)
+		case errors.Is(err, userbus.ErrAvatarsDisabled):
+			return errs.New(errs.Unimplemented, userbus.ErrAvatarsDisabled)
+		case errors.Is(err, userbus.ErrAvatarTooLarge):
+			return errs.New(errs.InvalidArgument, userbus.ErrAvatarTooLarge)
+		case errors.Is(err, userbus.ErrAvatarType):
+			return errs.New(errs.InvalidArgument, userbus.ErrAvatarType)
+		case errors.Is(err, userbus.ErrVersionConflict):
+			return errs.New(errs.Aborted, userbus.ErrVersionConflict)
+		}
+		return errs.Newf(errs.Internal, "updateavatar: userID[%s]: %s", usr.ID, err)
+	}
+
+	return toAppUser(updUsr)
+}
+
+// avatar sends the client to where the user's avatar is stored. The url may
+// be signed so the redirect isn't cached for longer than it's valid.
+func (a *app) avatar(ctx context.Context, r *http.Request) web.Encoder {
+	usr, err := mid.GetUser(ctx)
+	if err != nil {
+		return errs.Newf(errs.Internal, "avatar: %s", err)
+	}
+
+	url, err := a.userBus.AvatarURL(ctx, usr)
+	if err != nil {
+		switch {
+		case errors.Is(err, userbus.ErrNoAvatar):
+			return errs.New(errs.NotFound, userbus.ErrNoAvatar)
+		case errors.Is(err, userbus.ErrAvatarsDisabled):
+			return errs.New(errs.Unimplemented, userbus.ErrAvatarsDisabled)
+		}
+		return errs.Newf(errs.Internal, "avatar: userID[%s]: %s", usr.ID, err)
+	}
+
+	w := web.GetWriter(ctx)
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int((userbus.AvatarURLTTL/2).Seconds())))
+	http.Redirect(w, r, url, http.StatusFound)
+
+	return web.NewNoResponse()
+}
+
+// exportData sends the client a ZIP archive of what the system holds about
+// the user.
+func (a *app) exportData(ctx context.Context, _ *http.Request) web.Encoder {
+	usr, err := mid.GetUser(ctx)
+	if err != nil {
+		return errs.Newf(errs.Internal, "exportdata: %s", err)
+	}
+
+	rdr, err := a.userBus.ExportData(ctx, mid.GetSubjectID(ctx), usr.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, userbus.ErrForbidden):
+			return errs.New(errs.PermissionDenied, userbus.ErrForbidden)
+		case errors.Is(err, userbus.ErrInvalidActor):
+			return errs.New(errs.PermissionDenied, userbus.ErrInvalidActor)
+		}
+		return errs.Newf(errs.Internal, "exportdata: userID[%s]: %s", usr.ID, err)
+	}
+
+	data, err := io.ReadAll(rdr)
+	if err != nil {
+		return errs.Newf(errs.Internal, "exportdata: read: userID[%s]: %s", usr.ID, err)
+	}
+
+	w := web.GetWriter(ctx)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%s.zip"`, usr.ID))
+	w.Header().Set("Cache-Control", "no-store")
+
+	return export(data)
+}
+
+func (a *app) managementChain(ctx context.Context, _ *http.Request) web.Encoder {
+	usr, err := mid.GetUser(ctx)
+	if err != nil {
+		return errs.Newf(errs.Internal, "managementchain: %s", err)
+	}
+
+	usrs, err := a.userBus.ManagementChain(ctx, usr.ID)
+	if err != nil {
+		return errs.Newf(errs.Internal, "managementchain: userID[%s]: %s", usr.ID, err)
+	}
+
+	return users(toAppUsers(usrs))
+}
+
+// toRuleFieldErrors reports each broken rule against the field it names,
+// or the rule itself when it doesn't name one.
+func toRuleFieldErrors(re *userbus.RuleError) *errs.Error {
+	var fieldErrors errs.FieldErrors
+	for _, rv := range re.Violations {
+		field := rv.Field
+		if field == "" {
+			field = rv.Rule
+		}
+		fieldErrors.Add(field, errors.New(rv.Message))
+	}
+
+	return fieldErrors.ToError()
+}
+
+func toBatchFieldErrors(bes []BatchError) *errs.Error {
+	var fieldErrors errs.FieldErrors
+	for _, be := range bes {
+		fieldErrors.Add(fmt.Sprintf("users[%d]", be.Index), errors.New(be.Error))
+	}
+
+	return fieldErrors.ToError()
+}
+
+func (a *app) assignRoles(ctx context.Context, r *http.Request) web.Encoder {
+	var app NewRoleAssignment
+	if err := web.Decode(r, &app); err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	addRoles, err := role.ParseMany(app.AddRoles)
+	if err != nil {
+		return errs.NewFieldErrors("addRoles", err)
+	}
+
+	removeRoles, err := role.ParseMany(app.RemoveRoles)
+	if err != nil {
+		return errs.NewFieldErrors("removeRoles", err)
+	}
+
+	qp, err := parseQueryParams(r)
+	if err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	filter, err := parseFilter(qp)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	ra, err := a.userBus.AssignRolesByFilter(ctx, mid.GetSubjectID(ctx), filter, addRoles, removeRoles)
+	if err != nil {
+		if errors.Is(err, userbus.ErrForbidden) {
+			return errs.New(errs.PermissionDenied, userbus.ErrForbidden)
+		}
+		if errors.Is(err, userbus.ErrInvalidActor) {
+			return errs.New(errs.PermissionDenied, userbus.ErrInvalidActor)
+		}
+		if errors.Is(err, userbus.ErrAssignmentEmpty) {
+			return errs.New(errs.InvalidArgument, userbus.ErrAssignmentEmpty)
+		}
+		return errs.Newf(errs.Internal, "assignrolesbyfilter: %s", err)
+	}
+
+	return toAppRoleAssignment(ra, "")
+}
+
+func (a *app) applyRoleAssignment(ctx context.Context, r *http.Request) web.Encoder {
+	assignmentID, err := extid.Decode(web.Param(r, "assignment_id"))
+	if err != nil {
+		return errs.NewFieldErrors("assignment_id", err)
+	}
+
+	ra, token, err := a.userBus.ApplyRoleAssignment(ctx, mid.GetSubjectID(ctx), assignmentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, userbus.ErrForbidden):
+			return errs.New(errs.PermissionDenied, userbus.ErrForbidden)
+		case errors.Is(err, userbus.ErrInvalidActor):
+			return errs.New(errs.PermissionDenied, userbus.ErrInvalidActor)
+		case errors.Is(err, userbus.ErrNotFound):
+			return errs.New(errs.NotFound, userbus.ErrNotFound)
+		case errors.Is(err, userbus.ErrAssignmentApplied):
+			return errs.New(errs.Aborted, userbus.ErrAssignmentApplied)
+		case errors.Is(err, userbus.ErrAssignmentExpired):
+			return errs.New(errs.FailedPrecondition, userbus.ErrAssignmentExpired)
+		}
+		return errs.Newf(errs.Internal, "applyroleassignment: assignmentID[%s]: %s", assignmentID, err)
+	}
+
+	return toAppRoleAssignment(ra, token)
+}
+
+func (a *app) revertRoleAssignment(ctx context.Context, r *http.Request) web.Encoder {
+	var app RevertRoleAssignment
+	if err := web.Decode(r, &app); err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	ra, err := a.userBus.RevertRoleAssignment(ctx, mid.GetSubjectID(ctx), app.RevertToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, userbus.ErrForbidden):
+			return errs.New(errs.PermissionDenied, userbus.ErrForbidden)
+		case errors.Is(err, userbus.ErrInvalidActor):
+			return errs.New(errs.PermissionDenied, userbus.ErrInvalidActor)
+		case errors.Is(err, userbus.ErrNotFound):
+			return errs.NewFieldErrors("revertToken", userbus.ErrNotFound)
+		case errors.Is(err, userbus.ErrAssignmentReverted):
+			return errs.New(errs.Aborted, userbus.ErrAssignmentReverted)
+		case errors.Is(err, userbus.ErrAssignmentExpired):
+			return errs.New(errs.FailedPrecondition, userbus.ErrAssignmentExpired)
+		}
+		return errs.Newf(errs.Internal, "revertroleassignment: %s", err)
+	}
+
+	return toAppRoleAssignment(ra, "")
+}
@@ -5,9 +5,9 @@ import (
 	"strconv"
 
 	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/extid"
 	"github.com/ardanlabs/service/business/domain/vproductbus"
 	"github.com/ardanlabs/service/business/types/name"
-	"github.com/google/uuid"
 )
 
 type queryParams struct {
@@ -43,7 +43,7 @@ func parseFilter(qp queryParams) (vproductbus.QueryFilter, error) {
 	var filter vproductbus.QueryFilter
 
 	if qp.ID != "" {
-		id, err := uuid.Parse(qp.ID)
+		id, err := extid.Decode(qp.ID)
 		switch err {
 		case nil:
 			filter.ID = &id
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"time"
 
+	"github.com/ardanlabs/service/app/sdk/extid"
 	"github.com/ardanlabs/service/business/domain/vproductbus"
 )
 
@@ -28,8 +29,8 @@ func (app Product) Encode() ([]byte, string, error) {
 
 func toAppProduct(prd vproductbus.Product) Product {
 	return Product{
-		ID:          prd.ID.String(),
-		UserID:      prd.UserID.String(),
+		ID:          extid.Encode(prd.ID),
+		UserID:      extid.Encode(prd.UserID),
 		Name:        prd.Name.String(),
 		Cost:        prd.Cost.Value(),
 		Quantity:    prd.Quantity.Value(),
@@ -0,0 +1,108 @@
+package webhookapp
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/extid"
+	"github.com/ardanlabs/service/business/domain/webhookbus"
+)
+
+type queryParams struct {
+	Page    string
+	Rows    string
+	OrderBy string
+	ID      string
+	UserID  string
+	Event   string
+	Enabled string
+	Status  string
+}
+
+func parseQueryParams(r *http.Request) queryParams {
+	values := r.URL.Query()
+
+	filter := queryParams{
+		Page:    values.Get("page"),
+		Rows:    values.Get("rows"),
+		OrderBy: values.Get("orderBy"),
+		ID:      values.Get("webhook_id"),
+		UserID:  values.Get("user_id"),
+		Event:   values.Get("event"),
+		Enabled: values.Get("enabled"),
+		Status:  values.Get("status"),
+	}
+
+	return filter
+}
+
+func parseFilter(qp queryParams) (webhookbus.QueryFilter, error) {
+	var fieldErrors errs.FieldErrors
+	var filter webhookbus.QueryFilter
+
+	if qp.ID != "" {
+		id, err := extid.Decode(qp.ID)
+		switch err {
+		case nil:
+			filter.ID = &id
+		default:
+			fieldErrors.Add("webhook_id", err)
+		}
+	}
+
+	if qp.UserID != "" {
+		id, err := extid.Decode(qp.UserID)
+		switch err {
+		case nil:
+			filter.UserID = &id
+		default:
+			fieldErrors.Add("user_id", err)
+		}
+	}
+
+	if qp.Event != "" {
+		filter.Event = &qp.Event
+	}
+
+	if qp.Enabled != "" {
+		enabled, err := strconv.ParseBool(qp.Enabled)
+		switch err {
+		case nil:
+			filter.Enabled = &enabled
+		default:
+			fieldErrors.Add("enabled", err)
+		}
+	}
+
+	if fieldErrors != nil {
+		return webhookbus.QueryFilter{}, fieldErrors.ToError()
+	}
+
+	return filter, nil
+}
+
+func parseDeliveryFilter(qp queryParams) (webhookbus.DeliveryFilter, error) {
+	var fieldErrors errs.FieldErrors
+	var filter webhookbus.DeliveryFilter
+
+	if qp.Status != "" {
+		switch qp.Status {
+		case webhookbus.StatusPending, webhookbus.StatusDelivered, webhookbus.StatusFailed:
+			filter.Status = &qp.Status
+		default:
+			fieldErrors.Add("status", fmt.Errorf("unknown status %q", qp.Status))
+		}
+	}
+
+	if qp.Event != "" {
+		filter.Event = &qp.Event
+	}
+
+	if fieldErrors != nil {
+		return webhookbus.DeliveryFilter{}, fieldErrors.ToError()
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,221 @@
+package webhookapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/extid"
+	"github.com/ardanlabs/service/app/sdk/mid"
+	"github.com/ardanlabs/service/business/domain/webhookbus"
+)
+
+// Webhook represents information about an individual webhook. The secret
+// is only returned when the webhook is created.
+type Webhook struct {
+	ID          string   `json:"id"`
+	UserID      string   `json:"userID"`
+	URL         string   `json:"url"`
+	Events      []string `json:"events"`
+	Secret      string   `json:"secret,omitempty"`
+	Enabled     bool     `json:"enabled"`
+	DateCreated string   `json:"dateCreated"`
+	DateUpdated string   `json:"dateUpdated"`
+}
+
+// Encode implements the encoder interface.
+func (app Webhook) Encode() ([]byte, string, error) {
+	data, err := json.Marshal(app)
+	return data, "application/json", err
+}
+
+func toAppWebhook(wh webhookbus.Webhook) Webhook {
+	return Webhook{
+		ID:          extid.Encode(wh.ID),
+		UserID:      extid.Encode(wh.UserID),
+		URL:         wh.URL,
+		Events:      wh.Events,
+		Enabled:     wh.Enabled,
+		DateCreated: wh.DateCreated.Format(time.RFC3339),
+		DateUpdated: wh.DateUpdated.Format(time.RFC3339),
+	}
+}
+
+func toAppWebhooks(whs []webhookbus.Webhook) []Webhook {
+	app := make([]Webhook, len(whs))
+	for i, wh := range whs {
+		app[i] = toAppWebhook(wh)
+	}
+
+	return app
+}
+
+// =============================================================================
+
+// Delivery represents information about an event sent to a webhook. The
+// payload is only returned when a single delivery is asked for.
+type Delivery struct {
+	ID          string          `json:"id"`
+	WebhookID   string          `json:"webhookID"`
+	Event       string          `json:"event"`
+	Status      string          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	LastError   string          `json:"lastError,omitempty"`
+	NextAttempt string          `json:"nextAttempt,omitempty"`
+	Payload     json.RawMessage `json:"payload,omitempty"`
+	DateCreated string          `json:"dateCreated"`
+	DateUpdated string          `json:"dateUpdated"`
+}
+
+// Encode implements the encoder interface.
+func (app Delivery) Encode() ([]byte, string, error) {
+	data, err := json.Marshal(app)
+	return data, "application/json", err
+}
+
+func toAppDelivery(dlv webhookbus.Delivery) Delivery {
+	app := Delivery{
+		ID:          extid.Encode(dlv.ID),
+		WebhookID:   extid.Encode(dlv.WebhookID),
+		Event:       dlv.Event,
+		Status:      dlv.Status,
+		Attempts:    dlv.Attempts,
+		LastError:   dlv.LastError,
+		DateCreated: dlv.DateCreated.Format(time.RFC3339),
+		DateUpdated: dlv.DateUpdated.Format(time.RFC3339),
+	}
+
+	if dlv.Status == webhookbus.StatusPending {
+		app.NextAttempt = dlv.NextAttempt.Format(time.RFC3339)
+	}
+
+	return app
+}
+
+func toAppDeliveries(dlvs []webhookbus.Delivery) []Delivery {
+	app := make([]Delivery, len(dlvs))
+	for i, dlv := range dlvs {
+		app[i] = toAppDelivery(dlv)
+	}
+
+	return app
+}
+
+// =============================================================================
+
+// NewWebhook defines the data needed to add a new webhook.
+type NewWebhook struct {
+	URL    string   `json:"url" validate:"required"`
+	Events []string `json:"events" validate:"required,min=1"`
+}
+
+// Decode implements the decoder interface.
+func (app *NewWebhook) Decode(data []byte) error {
+	return json.Unmarshal(data, app)
+}
+
+// Validate checks the data in the model is considered clean.
+func (app NewWebhook) Validate() error {
+	if err := errs.Check(app); err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+
+	return nil
+}
+
+func toBusNewWebhook(ctx context.Context, app NewWebhook) (webhookbus.NewWebhook, error) {
+	userID, err := mid.GetUserID(ctx)
+	if err != nil {
+		return webhookbus.NewWebhook{}, fmt.Errorf("getuserid: %w", err)
+	}
+
+	if err := checkURL(app.URL); err != nil {
+		return webhookbus.NewWebhook{}, err
+	}
+
+	if err := checkEvents(app.Events); err != nil {
+		return webhookbus.NewWebhook{}, err
+	}
+
+	bus := webhookbus.NewWebhook{
+		UserID: userID,
+		URL:    app.URL,
+		Events: app.Events,
+	}
+
+	return bus, nil
+}
+
+// =============================================================================
+
+// UpdateWebhook defines the data needed to update a webhook.
+type UpdateWebhook struct {
+	URL     *string  `json:"url"`
+	Events  []string `json:"events" validate:"omitempty,min=1"`
+	Enabled *bool    `json:"enabled"`
+}
+
+// Decode implements the decoder interface.
+func (app *UpdateWebhook) Decode(data []byte) error {
+	return json.Unmarshal(data, app)
+}
+
+// Validate checks the data in the model is considered clean.
+func (app UpdateWebhook) Validate() error {
+	if err := errs.Check(app); err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+
+	return nil
+}
+
+func toBusUpdateWebhook(app UpdateWebhook) (webhookbus.UpdateWebhook, error) {
+	if app.URL != nil {
+		if err := checkURL(*app.URL); err != nil {
+			return webhookbus.UpdateWebhook{}, err
+		}
+	}
+
+	if app.Events != nil {
+		if err := checkEvents(app.Events); err != nil {
+			return webhookbus.UpdateWebhook{}, err
+		}
+	}
+
+	bus := webhookbus.UpdateWebhook{
+		URL:     app.URL,
+		Events:  app.Events,
+		Enabled: app.Enabled,
+	}
+
+	return bus, nil
+}
+
+// checkURL validates the url is an absolute http(s) url.
+func checkURL(target string) error {
+	u, err := url.Parse(target)
+	if err != nil {
+		return fmt.Errorf("url: %w", err)
+	}
+
+	if u.Scheme != "https" && u.Scheme != "http" || u.Host == "" {
+		return errors.New("url: webhook must be an absolute http(s) url")
+	}
+
+	return nil
+}
+
+// checkEvents validates a webhook can be registered for every event.
+func checkEvents(events []string) error {
+	for _, event := range events {
+		if !webhookbus.ValidEvent(event) {
+			return fmt.Errorf("events: unknown event %q", event)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,7 @@
+package webhookapp
+
+import (
+	"github.com/ardanlabs/service/business/domain/webhookbus"
+)
+
+var orderByFields = webhookbus.OrderFields.Mappings()
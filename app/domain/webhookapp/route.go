@@ -0,0 +1,38 @@
+package webhookapp
+
+import (
+	"net/http"
+
+	"github.com/ardanlabs/service/app/sdk/auth"
+	"github.com/ardanlabs/service/app/sdk/authclient"
+	"github.com/ardanlabs/service/app/sdk/mid"
+	"github.com/ardanlabs/service/business/domain/webhookbus"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/web"
+)
+
+// Config contains all the mandatory systems required by handlers.
+type Config struct {
+	Log        *logger.Logger
+	WebhookBus *webhookbus.Business
+	AuthClient *authclient.Client
+}
+
+// Routes adds specific routes for this group.
+func Routes(app *web.App, cfg Config) {
+	const version = "v1"
+
+	authen := mid.Authenticate(cfg.AuthClient)
+	ruleAdmin := mid.Authorize(cfg.AuthClient, auth.RuleAdminOnly)
+
+	api := newApp(cfg.WebhookBus)
+
+	app.HandlerFunc(http.MethodGet, version, "/webhooks", api.query, authen, ruleAdmin)
+	app.HandlerFunc(http.MethodGet, version, "/webhooks/{webhook_id}", api.queryByID, authen, ruleAdmin)
+	app.HandlerFunc(http.MethodGet, version, "/webhooks/{webhook_id}/deliveries", api.queryDeliveries, authen, ruleAdmin)
+	app.HandlerFunc(http.MethodGet, version, "/webhooks/{webhook_id}/deliveries/{delivery_id}", api.queryDeliveryByID, authen, ruleAdmin)
+	app.HandlerFunc(http.MethodPost, version, "/webhooks/{webhook_id}/deliveries/{delivery_id}/redeliver", api.redeliver, authen, ruleAdmin)
+	app.HandlerFunc(http.MethodPost, version, "/webhooks", api.create, authen, ruleAdmin)
+	app.HandlerFunc(http.MethodPut, version, "/webhooks/{webhook_id}", api.update, authen, ruleAdmin)
+	app.HandlerFunc(http.MethodDelete, version, "/webhooks/{webhook_id}", api.delete, authen, ruleAdmin)
+}
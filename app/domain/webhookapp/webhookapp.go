@@ -0,0 +1,236 @@
+// Package webhookapp maintains the app layer api for the webhook domain.
+package webhookapp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/extid"
+	"github.com/ardanlabs/service/app/sdk/query"
+	"github.com/ardanlabs/service/business/domain/webhookbus"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/foundation/web"
+)
+
+type app struct {
+	webhookBus *webhookbus.Business
+}
+
+func newApp(webhookBus *webhookbus.Business) *app {
+	return &app{
+		webhookBus: webhookBus,
+	}
+}
+
+func (a *app) create(ctx context.Context, r *http.Request) web.Encoder {
+	var app NewWebhook
+	if err := web.Decode(r, &app); err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	nw, err := toBusNewWebhook(ctx, app)
+	if err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	wh, err := a.webhookBus.Create(ctx, nw)
+	if err != nil {
+		if errors.Is(err, webhookbus.ErrUserDisabled) {
+			return errs.New(errs.FailedPrecondition, err)
+		}
+		return errs.Newf(errs.Internal, "create: wh[%+v]: %s", app, err)
+	}
+
+	// The secret is only handed out here, the receiver needs it to check
+	// the signatures of the deliveries.
+	resp := toAppWebhook(wh)
+	resp.Secret = wh.Secret
+
+	return resp
+}
+
+func (a *app) update(ctx context.Context, r *http.Request) web.Encoder {
+	var app UpdateWebhook
+	if err := web.Decode(r, &app); err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	wh, err := a.webhook(ctx, r)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	uw, err := toBusUpdateWebhook(app)
+	if err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	updWh, err := a.webhookBus.Update(ctx, wh, uw)
+	if err != nil {
+		return errs.Newf(errs.Internal, "update: webhookID[%s] uw[%+v]: %s", wh.ID, uw, err)
+	}
+
+	return toAppWebhook(updWh)
+}
+
+func (a *app) delete(ctx context.Context, r *http.Request) web.Encoder {
+	wh, err := a.webhook(ctx, r)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	if err := a.webhookBus.Delete(ctx, wh); err != nil {
+		return errs.Newf(errs.Internal, "delete: webhookID[%s]: %s", wh.ID, err)
+	}
+
+	return nil
+}
+
+func (a *app) query(ctx context.Context, r *http.Request) web.Encoder {
+	qp := parseQueryParams(r)
+
+	page, err := page.Parse(qp.Page, qp.Rows)
+	if err != nil {
+		return errs.NewFieldErrors("page", err)
+	}
+
+	filter, err := parseFilter(qp)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	orderBy, err := order.Parse(orderByFields, qp.OrderBy, webhookbus.DefaultOrderBy)
+	if err != nil {
+		return errs.NewFieldErrors("order", err)
+	}
+
+	whs, err := a.webhookBus.Query(ctx, filter, orderBy, page)
+	if err != nil {
+		return errs.Newf(errs.Internal, "query: %s", err)
+	}
+
+	total, err := a.webhookBus.Count(ctx, filter)
+	if err != nil {
+		return errs.Newf(errs.Internal, "count: %s", err)
+	}
+
+	return query.NewResult(toAppWebhooks(whs), total, page)
+}
+
+func (a *app) queryByID(ctx context.Context, r *http.Request) web.Encoder {
+	wh, err := a.webhook(ctx, r)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	return toAppWebhook(wh)
+}
+
+func (a *app) queryDeliveries(ctx context.Context, r *http.Request) web.Encoder {
+	qp := parseQueryParams(r)
+
+	page, err := page.Parse(qp.Page, qp.Rows)
+	if err != nil {
+		return errs.NewFieldErrors("page", err)
+	}
+
+	filter, err := parseDeliveryFilter(qp)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	wh, err := a.webhook(ctx, r)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	dlvs, err := a.webhookBus.QueryDeliveries(ctx, wh.ID, filter, page)
+	if err != nil {
+		return errs.Newf(errs.Internal, "querydeliveries: %s", err)
+	}
+
+	total, err := a.webhookBus.CountDeliveries(ctx, wh.ID, filter)
+	if err != nil {
+		return errs.Newf(errs.Internal, "countdeliveries: %s", err)
+	}
+
+	return query.NewResult(toAppDeliveries(dlvs), total, page)
+}
+
+func (a *app) queryDeliveryByID(ctx context.Context, r *http.Request) web.Encoder {
+	dlv, err := a.delivery(ctx, r)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	resp := toAppDelivery(dlv)
+	resp.Payload = dlv.Payload
+
+	return resp
+}
+
+func (a *app) redeliver(ctx context.Context, r *http.Request) web.Encoder {
+	dlv, err := a.delivery(ctx, r)
+	if err != nil {
+		return err.(*errs.Error)
+	}
+
+	updDlv, err := a.webhookBus.Redeliver(ctx, dlv)
+	if err != nil {
+		if errors.Is(err, webhookbus.ErrDelivered) {
+			return errs.New(errs.FailedPrecondition, err)
+		}
+		return errs.Newf(errs.Internal, "redeliver: deliveryID[%s]: %s", dlv.ID, err)
+	}
+
+	return toAppDelivery(updDlv)
+}
+
+// webhook looks up the webhook identified in the request path.
+func (a *app) webhook(ctx context.Context, r *http.Request) (webhookbus.Webhook, error) {
+	id, err := extid.Decode(web.Param(r, "webhook_id"))
+	if err != nil {
+		return webhookbus.Webhook{}, errs.New(errs.InvalidArgument, err)
+	}
+
+	wh, err := a.webhookBus.QueryByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, webhookbus.ErrNotFound) {
+			return webhookbus.Webhook{}, errs.New(errs.NotFound, err)
+		}
+		return webhookbus.Webhook{}, errs.Newf(errs.Internal, "querybyid: webhookID[%s]: %s", id, err)
+	}
+
+	return wh, nil
+}
+
+// delivery looks up the delivery identified in the request path, which must
+// belong to the webhook identified with it.
+func (a *app) delivery(ctx context.Context, r *http.Request) (webhookbus.Delivery, error) {
+	wh, err := a.webhook(ctx, r)
+	if err != nil {
+		return webhookbus.Delivery{}, err
+	}
+
+	id, err := extid.Decode(web.Param(r, "delivery_id"))
+	if err != nil {
+		return webhookbus.Delivery{}, errs.New(errs.InvalidArgument, err)
+	}
+
+	dlv, err := a.webhookBus.QueryDeliveryByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, webhookbus.ErrDeliveryNotFound) {
+			return webhookbus.Delivery{}, errs.New(errs.NotFound, err)
+		}
+		return webhookbus.Delivery{}, errs.Newf(errs.Internal, "querydeliverybyid: deliveryID[%s]: %s", id, err)
+	}
+
+	if dlv.WebhookID != wh.ID {
+		return webhookbus.Delivery{}, errs.New(errs.NotFound, webhookbus.ErrDeliveryNotFound)
+	}
+
+	return dlv, nil
+}
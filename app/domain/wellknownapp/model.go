@@ -0,0 +1,61 @@
+package wellknownapp
+
+import (
+	"encoding/json"
+	"slices"
+)
+
+// relIssuer is the link relation for the issuer of an account, see OpenID
+// Connect Discovery section 2.
+const relIssuer = "http://openid.net/specs/connect/1.0/issuer"
+
+// Metadata represents the authorization server metadata.
+type Metadata struct {
+	Issuer                            string   `json:"issuer"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	RevocationEndpoint                string   `json:"revocation_endpoint,omitempty"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+}
+
+// Encode implements the encoder interface.
+func (app Metadata) Encode() ([]byte, string, error) {
+	data, err := json.Marshal(app)
+	return data, "application/json", err
+}
+
+// =============================================================================
+
+// Link represents a link in a JSON resource descriptor.
+type Link struct {
+	Rel  string `json:"rel"`
+	Href string `json:"href"`
+}
+
+// JRD represents a JSON resource descriptor from RFC 7033.
+type JRD struct {
+	Subject string `json:"subject"`
+	Links   []Link `json:"links"`
+}
+
+// Encode implements the encoder interface.
+func (app JRD) Encode() ([]byte, string, error) {
+	data, err := json.Marshal(app)
+	return data, "application/jrd+json", err
+}
+
+// toAppJRD returns the descriptor for the subject. When rels are requested
+// only the links with those relations are included.
+func toAppJRD(subject string, links []Link, rels []string) JRD {
+	if len(rels) > 0 {
+		links = slices.DeleteFunc(slices.Clone(links), func(l Link) bool {
+			return !slices.Contains(rels, l.Rel)
+		})
+	}
+
+	return JRD{
+		Subject: subject,
+		Links:   links,
+	}
+}
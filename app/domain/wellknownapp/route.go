@@ -0,0 +1,54 @@
+package wellknownapp
+
+import (
+	"net/http"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/web"
+)
+
+// Config contains all the mandatory systems required by handlers.
+type Config struct {
+	Log     *logger.Logger
+	UserBus userbus.Business
+
+	// Domain is the host of the acct: resources answered by WebFinger.
+	// WebFinger is only served when it's set.
+	Domain string
+
+	// BaseURL is the public URL of the service, used to build the endpoint
+	// URLs in the metadata.
+	BaseURL string
+
+	// Issuer is the issuer of the tokens the service signs.
+	Issuer string
+
+	// TokenKID is the key the token endpoint in the metadata signs with.
+	TokenKID string
+
+	// Sessions reports whether the session endpoints are served.
+	Sessions bool
+
+	// ChangePasswordURL is where password managers send users to change
+	// their password. The redirect is only served when it's set.
+	ChangePasswordURL string
+}
+
+// Routes adds specific routes for this group. The routes follow RFC 8615 so
+// they are bound at the root rather than under a version.
+func Routes(app *web.App, cfg Config) {
+	api := newApp(cfg)
+
+	if cfg.BaseURL != "" {
+		app.HandlerFunc(http.MethodGet, "", "/.well-known/oauth-authorization-server", api.metadata)
+	}
+
+	if cfg.Domain != "" {
+		app.HandlerFunc(http.MethodGet, "", "/.well-known/webfinger", api.webfinger)
+	}
+
+	if cfg.ChangePasswordURL != "" {
+		app.HandlerFunc(http.MethodGet, "", "/.well-known/change-password", api.changePassword)
+	}
+}
@@ -0,0 +1,132 @@
+// Package wellknownapp maintains the app layer api for the well-known
+// discovery endpoints used by federation and password managers.
+package wellknownapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"strings"
+
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/web"
+)
+
+// ErrUnknownAccount is returned for a WebFinger resource that isn't an
+// enabled account in the domain.
+var ErrUnknownAccount = errors.New("unknown account")
+
+type app struct {
+	log               *logger.Logger
+	userBus           userbus.Business
+	domain            string
+	baseURL           string
+	issuer            string
+	tokenKID          string
+	sessions          bool
+	changePasswordURL string
+}
+
+func newApp(cfg Config) *app {
+	return &app{
+		log:               cfg.Log,
+		userBus:           cfg.UserBus,
+		domain:            cfg.Domain,
+		baseURL:           strings.TrimSuffix(cfg.BaseURL, "/"),
+		issuer:            cfg.Issuer,
+		tokenKID:          cfg.TokenKID,
+		sessions:          cfg.Sessions,
+		changePasswordURL: cfg.ChangePasswordURL,
+	}
+}
+
+// metadata returns the authorization server metadata described by RFC 8414.
+func (a *app) metadata(ctx context.Context, r *http.Request) web.Encoder {
+	md := Metadata{
+		Issuer:                            a.issuer,
+		TokenEndpoint:                     a.baseURL + "/v1/auth/token/" + a.tokenKID,
+		ResponseTypesSupported:            []string{"token"},
+		GrantTypesSupported:               []string{"password"},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic"},
+	}
+
+	if a.sessions {
+		md.RevocationEndpoint = a.baseURL + "/v1/auth/logout"
+		md.GrantTypesSupported = append(md.GrantTypesSupported, "refresh_token")
+	}
+
+	return md
+}
+
+// webfinger answers RFC 7033 queries for acct: resources in the configured
+// domain. Disabled accounts are reported as unknown.
+func (a *app) webfinger(ctx context.Context, r *http.Request) web.Encoder {
+	resource := r.URL.Query().Get("resource")
+	if resource == "" {
+		return errs.NewFieldErrors("resource", errors.New("is required"))
+	}
+
+	email, err := parseAcct(resource)
+	if err != nil {
+		return errs.NewFieldErrors("resource", err)
+	}
+
+	if _, host, _ := strings.Cut(email.Address, "@"); !strings.EqualFold(host, a.domain) {
+		return errs.New(errs.NotFound, ErrUnknownAccount)
+	}
+
+	usr, err := a.userBus.QueryByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, userbus.ErrNotFound) {
+			return errs.New(errs.NotFound, ErrUnknownAccount)
+		}
+		return errs.Newf(errs.Internal, "querybyemail: email[%s]: %s", email.Address, err)
+	}
+
+	if !usr.Enabled {
+		return errs.New(errs.NotFound, ErrUnknownAccount)
+	}
+
+	// WebFinger is queried from browsers on other origins.
+	web.GetWriter(ctx).Header().Set("Access-Control-Allow-Origin", "*")
+
+	return toAppJRD(resource, a.links(), r.URL.Query()["rel"])
+}
+
+// changePassword sends password managers to the page where users change
+// their password, see https://w3c.github.io/webappsec-change-password-url.
+func (a *app) changePassword(ctx context.Context, r *http.Request) web.Encoder {
+	http.Redirect(web.GetWriter(ctx), r, a.changePasswordURL, http.StatusFound)
+
+	return web.NewNoResponse()
+}
+
+func (a *app) links() []Link {
+	href := a.baseURL
+	if href == "" {
+		href = a.issuer
+	}
+
+	return []Link{
+		{Rel: relIssuer, Href: href},
+	}
+}
+
+// parseAcct returns the email address of an acct: URI from RFC 7565.
+func parseAcct(resource string) (mail.Address, error) {
+	acct, ok := strings.CutPrefix(resource, "acct:")
+	if !ok {
+		return mail.Address{}, errors.New("must be an acct: URI")
+	}
+
+	addr, err := mail.ParseAddress(acct)
+	if err != nil || addr.Address != acct {
+		return mail.Address{}, fmt.Errorf("invalid account %q", acct)
+	}
+
+	return *addr, nil
+}
@@ -0,0 +1,287 @@
+// Package anomaly derives security counters from the service's own logs and
+// events, giving deployments without a SIEM basic detection. It counts
+// authentication failures and permission denials from the request logs and
+// admins created from the user domain's events. The counters are published
+// with expvar, and an alert is raised through the delegate when a count
+// crosses its threshold.
+package anomaly
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/delegate"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/ardanlabs/service/foundation/logger"
+)
+
+// DomainName represents the name of the domain alerts are raised for.
+const DomainName = "security"
+
+// ActionAlert is the delegate action raised when a counter crosses its
+// threshold.
+const ActionAlert = "alert"
+
+// Set of kinds of anomaly that are counted.
+const (
+	KindAuthFailure  = "auth_failure"
+	KindForbidden    = "forbidden"
+	KindAdminCreated = "admin_created"
+)
+
+// Config holds the thresholds that raise alerts. A threshold is the number
+// of occurrences within the window, zero never alerts. Every admin created
+// is alerted on when AdminCreated is set.
+type Config struct {
+	Window       time.Duration
+	AuthFailures int
+	Forbidden    int
+	AdminCreated bool
+}
+
+// Snapshot is the state of the counters at a point in time.
+type Snapshot struct {
+	AuthFailures       int64 `json:"authFailures"`
+	AuthFailuresWindow int   `json:"authFailuresWindow"`
+	Forbidden          int64 `json:"forbidden"`
+	ForbiddenWindow    int   `json:"forbiddenWindow"`
+	AdminsCreated      int64 `json:"adminsCreated"`
+	Alerts             int64 `json:"alerts"`
+}
+
+// vars is where the counters are published. expvar registers names once for
+// the process so the detector constructed last is the one published.
+var vars = expvar.NewMap("security")
+
+// Detector counts the anomalies. It's safe for concurrent use.
+type Detector struct {
+	mu       sync.Mutex
+	cfg      Config
+	delegate *delegate.Delegate
+	windows  map[string]*window
+	alerted  map[string]time.Time
+	totals   map[string]int64
+	alerts   int64
+	now      func() time.Time
+}
+
+// New constructs a detector with a one minute window that doesn't alert
+// until it's configured.
+func New() *Detector {
+	d := Detector{
+		cfg:     Config{Window: time.Minute},
+		windows: make(map[string]*window),
+		alerted: make(map[string]time.Time),
+		totals:  make(map[string]int64),
+		now:     time.Now,
+	}
+
+	for _, kind := range []string{KindAuthFailure, KindForbidden, KindAdminCreated} {
+		d.windows[kind] = newWindow(d.cfg.Window)
+	}
+
+	vars.Set("counters", expvar.Func(func() any { return d.Snapshot() }))
+
+	return &d
+}
+
+// Configure sets the thresholds and the delegate alerts are raised through.
+// Counts taken so far are kept unless the window changes. A nil delegate
+// only counts.
+func (d *Detector) Configure(cfg Config, dlg *delegate.Delegate) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if cfg.Window <= 0 {
+		cfg.Window = time.Minute
+	}
+
+	if cfg.Window != d.cfg.Window {
+		for kind := range d.windows {
+			d.windows[kind] = newWindow(cfg.Window)
+		}
+	}
+
+	d.cfg = cfg
+	d.delegate = dlg
+}
+
+// Observe inspects a log record for a completed request and counts the ones
+// that failed authentication or were denied. It has the signature of a
+// logger event function so it can be attached to the info level.
+func (d *Detector) Observe(ctx context.Context, r logger.Record) {
+	code, ok := r.Attributes["statuscode"].(errs.ErrCode)
+	if !ok {
+		return
+	}
+
+	switch {
+	case code.Equal(errs.Unauthenticated):
+		d.record(ctx, KindAuthFailure)
+
+	case code.Equal(errs.PermissionDenied):
+		d.record(ctx, KindForbidden)
+	}
+}
+
+// Watch registers with the delegate to count the users that become admins,
+// either by being created as one or by being given the role.
+func (d *Detector) Watch(dlg *delegate.Delegate, userBus userbus.Business) {
+	dlg.Register(userbus.DomainName, userbus.ActionCreated, func(ctx context.Context, data delegate.Data) error {
+		var params userbus.ActionCreatedParms
+		if err := json.Unmarshal(data.RawParams, &params); err != nil {
+			return fmt.Errorf("expected an encoded %T: %w", params, err)
+		}
+
+		usr, err := userBus.QueryByID(ctx, params.UserID)
+		if err != nil {
+			return fmt.Errorf("querybyid: userID[%s]: %w", params.UserID, err)
+		}
+
+		if slices.ContainsFunc(usr.Roles, role.Admin.Equal) {
+			d.record(ctx, KindAdminCreated)
+		}
+
+		return nil
+	})
+
+	dlg.Register(userbus.DomainName, userbus.ActionUpdated, func(ctx context.Context, data delegate.Data) error {
+		for _, chg := range data.Changes {
+			if chg.Field == userbus.FieldRoles && becameAdmin(chg) {
+				d.record(ctx, KindAdminCreated)
+			}
+		}
+
+		return nil
+	})
+}
+
+// Snapshot returns the counters as they are now.
+func (d *Detector) Snapshot() Snapshot {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+
+	return Snapshot{
+		AuthFailures:       d.totals[KindAuthFailure],
+		AuthFailuresWindow: d.windows[KindAuthFailure].count(now),
+		Forbidden:          d.totals[KindForbidden],
+		ForbiddenWindow:    d.windows[KindForbidden].count(now),
+		AdminsCreated:      d.totals[KindAdminCreated],
+		Alerts:             d.alerts,
+	}
+}
+
+// =============================================================================
+
+// record counts an occurrence and raises an alert when it crosses the
+// threshold. A rate is alerted on at most once per window so an attack
+// doesn't turn into a flood of alerts, while every admin created is. The
+// delegate is called after the lock is released since it logs, and its logs
+// come back to Observe.
+func (d *Detector) record(ctx context.Context, kind string) {
+	d.mu.Lock()
+
+	now := d.now()
+
+	d.totals[kind]++
+	n := d.windows[kind].add(now)
+
+	threshold := d.threshold(kind)
+
+	quiet := kind == KindAdminCreated || now.Sub(d.alerted[kind]) >= d.cfg.Window
+
+	alert := d.delegate != nil && threshold > 0 && n >= threshold && quiet
+
+	if alert {
+		d.alerted[kind] = now
+		d.alerts++
+	}
+
+	dlg := d.delegate
+	window := d.cfg.Window
+
+	d.mu.Unlock()
+
+	if alert {
+		dlg.Call(ctx, ActionAlertData(kind, n, window))
+	}
+}
+
+func (d *Detector) threshold(kind string) int {
+	switch kind {
+	case KindAuthFailure:
+		return d.cfg.AuthFailures
+	case KindForbidden:
+		return d.cfg.Forbidden
+	case KindAdminCreated:
+		if d.cfg.AdminCreated {
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// becameAdmin reports whether the change to the roles added the admin role.
+func becameAdmin(chg delegate.Change) bool {
+	var before, after []string
+
+	if err := json.Unmarshal(chg.New, &after); err != nil {
+		return false
+	}
+
+	// A missing old value reads as no roles at all.
+	_ = json.Unmarshal(chg.Old, &before)
+
+	admin := role.Admin.String()
+
+	return slices.Contains(after, admin) && !slices.Contains(before, admin)
+}
+
+// =============================================================================
+
+// ActionAlertParms represents the parameters for the alert action.
+type ActionAlertParms struct {
+	Kind   string
+	Count  int
+	Window time.Duration
+}
+
+// String returns a string representation of the action parameters.
+func (act *ActionAlertParms) String() string {
+	return fmt.Sprintf("&EventParamsAlert{Kind:%v, Count:%v, Window:%v}", act.Kind, act.Count, act.Window)
+}
+
+// Marshal returns the event parameters encoded as JSON.
+func (act *ActionAlertParms) Marshal() ([]byte, error) {
+	return json.Marshal(act)
+}
+
+// ActionAlertData constructs the data for the alert action.
+func ActionAlertData(kind string, count int, window time.Duration) delegate.Data {
+	params := ActionAlertParms{
+		Kind:   kind,
+		Count:  count,
+		Window: window,
+	}
+
+	rawParams, err := params.Marshal()
+	if err != nil {
+		panic(err)
+	}
+
+	return delegate.Data{
+		Domain:    DomainName,
+		Action:    ActionAlert,
+		RawParams: rawParams,
+	}
+}
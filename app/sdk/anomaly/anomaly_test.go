@@ -0,0 +1,149 @@
+package anomaly_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/service/app/sdk/anomaly"
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/delegate"
+	"github.com/ardanlabs/service/business/sdk/tenant"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/google/uuid"
+)
+
+func Test_Observe(t *testing.T) {
+	d := anomaly.New()
+
+	ctx := context.Background()
+
+	for _, code := range []errs.ErrCode{errs.Unauthenticated, errs.Unauthenticated, errs.PermissionDenied, errs.NotFound} {
+		d.Observe(ctx, completed(code))
+	}
+
+	d.Observe(ctx, logger.Record{Message: "startup"})
+
+	snap := d.Snapshot()
+
+	if snap.AuthFailures != 2 || snap.AuthFailuresWindow != 2 {
+		t.Fatalf("Should count 2 auth failures : %+v", snap)
+	}
+
+	if snap.Forbidden != 1 || snap.ForbiddenWindow != 1 {
+		t.Fatalf("Should count 1 denied request : %+v", snap)
+	}
+
+	if snap.Alerts != 0 {
+		t.Fatalf("Should not alert before being configured : %+v", snap)
+	}
+}
+
+func Test_Alert(t *testing.T) {
+	d := anomaly.New()
+	dlg, alerts := newDelegate()
+
+	d.Configure(anomaly.Config{Window: 100 * time.Millisecond, AuthFailures: 3}, dlg)
+
+	ctx := context.Background()
+
+	for range 5 {
+		d.Observe(ctx, completed(errs.Unauthenticated))
+	}
+
+	if len(*alerts) != 1 {
+		t.Fatalf("Should alert once inside the window, got %d", len(*alerts))
+	}
+
+	if got := (*alerts)[0]; got.Kind != anomaly.KindAuthFailure || got.Count != 3 {
+		t.Fatalf("Should alert on the third auth failure : %+v", got)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+
+	if snap := d.Snapshot(); snap.AuthFailures != 5 || snap.AuthFailuresWindow != 0 {
+		t.Fatalf("Should expire the window but keep the total : %+v", snap)
+	}
+
+	for range 3 {
+		d.Observe(ctx, completed(errs.Unauthenticated))
+	}
+
+	if len(*alerts) != 2 {
+		t.Fatalf("Should alert again in a new window, got %d", len(*alerts))
+	}
+
+	d.Observe(ctx, completed(errs.PermissionDenied))
+
+	if len(*alerts) != 2 {
+		t.Fatalf("Should not alert on a disabled threshold, got %d", len(*alerts))
+	}
+}
+
+func Test_AdminCreated(t *testing.T) {
+	d := anomaly.New()
+	dlg, alerts := newDelegate()
+
+	d.Configure(anomaly.Config{AdminCreated: true}, dlg)
+
+	// Only updates are called so the user business isn't needed.
+	d.Watch(dlg, nil)
+
+	ctx := context.Background()
+
+	changes := []delegate.Change{
+		delegate.NewChange(userbus.FieldRoles, []role.Role{role.User}, []role.Role{role.User, role.Admin}),
+		delegate.NewChange(userbus.FieldRoles, []role.Role{role.Admin}, []role.Role{role.Admin, role.User}),
+		delegate.NewChange(userbus.FieldRoles, []role.Role{role.User}, []role.Role{role.Admin}),
+	}
+
+	for _, chg := range changes {
+		data := userbus.ActionUpdatedData(uuid.New(), tenant.Default, []delegate.Change{chg})
+		if err := dlg.Call(ctx, data); err != nil {
+			t.Fatalf("Should be able to call the delegate : %s", err)
+		}
+	}
+
+	if snap := d.Snapshot(); snap.AdminsCreated != 2 {
+		t.Fatalf("Should count 2 admins created : %+v", snap)
+	}
+
+	if len(*alerts) != 2 {
+		t.Fatalf("Should alert on every admin created, got %d", len(*alerts))
+	}
+}
+
+// =============================================================================
+
+func completed(code errs.ErrCode) logger.Record {
+	return logger.Record{
+		Message: "request completed",
+		Level:   logger.LevelInfo,
+		Attributes: map[string]any{
+			"statuscode": code,
+		},
+	}
+}
+
+func newDelegate() (*delegate.Delegate, *[]anomaly.ActionAlertParms) {
+	dlg := delegate.New(logger.New(io.Discard, logger.LevelInfo, "TEST", func(context.Context) string { return "" }))
+
+	var alerts []anomaly.ActionAlertParms
+
+	dlg.Register(anomaly.DomainName, anomaly.ActionAlert, func(ctx context.Context, data delegate.Data) error {
+		var params anomaly.ActionAlertParms
+		if err := json.Unmarshal(data.RawParams, &params); err != nil {
+			return err
+		}
+
+		alerts = append(alerts, params)
+
+		return nil
+	})
+
+	return dlg, &alerts
+}
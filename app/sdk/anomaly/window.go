@@ -0,0 +1,56 @@
+package anomaly
+
+import "time"
+
+// buckets is how many slices a window is divided into. Counts expire one
+// slice at a time, so a count can include up to a slice more than the
+// window.
+const buckets = 60
+
+// window counts occurrences over a sliding period of time in fixed memory.
+type window struct {
+	width  time.Duration
+	counts [buckets]int
+	slots  [buckets]int64
+}
+
+func newWindow(size time.Duration) *window {
+	width := size / buckets
+	if width <= 0 {
+		width = 1
+	}
+
+	return &window{
+		width: width,
+	}
+}
+
+// add counts an occurrence at the time and returns the count for the window
+// ending then.
+func (w *window) add(now time.Time) int {
+	slot := now.UnixNano() / int64(w.width)
+	i := slot % buckets
+
+	if w.slots[i] != slot {
+		w.slots[i] = slot
+		w.counts[i] = 0
+	}
+
+	w.counts[i]++
+
+	return w.count(now)
+}
+
+// count returns the number of occurrences in the window ending at the time.
+func (w *window) count(now time.Time) int {
+	slot := now.UnixNano() / int64(w.width)
+
+	var n int
+	for i := range buckets {
+		if w.slots[i] > slot-buckets && w.slots[i] <= slot {
+			n += w.counts[i]
+		}
+	}
+
+	return n
+}
@@ -14,19 +14,28 @@ import (
 	"github.com/ardanlabs/service/app/sdk/auth"
 	"github.com/ardanlabs/service/business/domain/userbus"
 	"github.com/ardanlabs/service/business/sdk/dbtest"
-	"github.com/ardanlabs/service/business/types/role"
-	"github.com/golang-jwt/jwt/v4"
 )
 
 // Test contains functions for executing an api test.
 type Test struct {
-	DB   *dbtest.Database
-	Auth *auth.Auth
-	mux  http.Handler
+	DB      *dbtest.Database
+	Auth    *auth.Auth
+	mux     http.Handler
+	authMux http.Handler
 }
 
 // Run performs the actual test logic based on the table data.
 func (at *Test) Run(t *testing.T, table []Table, testName string) {
+	run(t, at.mux, table, testName)
+}
+
+// RunAuth performs the test logic based on the table data against the
+// routes of the auth service.
+func (at *Test) RunAuth(t *testing.T, table []Table, testName string) {
+	run(t, at.authMux, table, testName)
+}
+
+func run(t *testing.T, mux http.Handler, table []Table, testName string) {
 	for _, tt := range table {
 		f := func(t *testing.T) {
 			r := httptest.NewRequest(tt.Method, tt.URL, nil)
@@ -42,7 +51,7 @@ func (at *Test) Run(t *testing.T, table []Table, testName string) {
 			}
 
 			r.Header.Set("Authorization", "Bearer "+tt.Token)
-			at.mux.ServeHTTP(w, r)
+			mux.ServeHTTP(w, r)
 
 			if w.Code != tt.StatusCode {
 				t.Fatalf("%s: Should receive a status code of %d for the response : %d", tt.Name, tt.StatusCode, w.Code)
@@ -83,17 +92,9 @@ func Token(userBus userbus.Business, ath *auth.Auth, email string) string {
 		return ""
 	}
 
-	claims := auth.Claims{
-		RegisteredClaims: jwt.RegisteredClaims{
-			Subject:   dbUsr.ID.String(),
-			Issuer:    ath.Issuer(),
-			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
-		},
-		Roles: role.ParseToString(dbUsr.Roles),
-	}
+	claims := ath.NewClaims(dbUsr, auth.WithTTL(time.Hour), auth.WithAuthentication(time.Now(), auth.MethodPassword))
 
-	token, err := ath.GenerateToken(kid, claims)
+	token, err := ath.GenerateToken(KID, claims)
 	if err != nil {
 		return ""
 	}
@@ -3,15 +3,33 @@ package apitest
 import (
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	authbuild "github.com/ardanlabs/service/api/services/auth/build/all"
 	salesbuild "github.com/ardanlabs/service/api/services/sales/build/all"
 	"github.com/ardanlabs/service/app/sdk/auth"
 	"github.com/ardanlabs/service/app/sdk/authclient"
 	"github.com/ardanlabs/service/app/sdk/mux"
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/domain/userbus/stores/userdb"
 	"github.com/ardanlabs/service/business/sdk/dbtest"
 )
 
+// BreakGlassCredential is the sealed credential break-glass tokens are
+// granted for in the tests.
+const BreakGlassCredential = "break-glass-credential"
+
+// PasswordPolicy is the policy the auth service enforces in the tests, the
+// same as the default of the service's configuration.
+var PasswordPolicy = userbus.PasswordPolicy{
+	MinLength:    8,
+	RequireUpper: true,
+	RequireLower: true,
+	RequireDigit: true,
+	Banned:       []string{"password", "12345678", "qwerty123"},
+	History:      5,
+}
+
 // New initialized the system to run a test.
 func New(t *testing.T, testName string) *Test {
 	db := dbtest.New(t, testName)
@@ -22,6 +40,9 @@ func New(t *testing.T, testName string) *Test {
 		Log:       db.Log,
 		UserBus:   db.BusDomain.User,
 		KeyLookup: &KeyStore{},
+		BreakGlass: auth.BreakGlassConfig{
+			CredentialHash: auth.HashBreakGlassCredential(BreakGlassCredential),
+		},
 	})
 	if err != nil {
 		t.Fatal(err)
@@ -29,16 +50,25 @@ func New(t *testing.T, testName string) *Test {
 
 	// -------------------------------------------------------------------------
 
-	server := httptest.NewServer(mux.WebAPI(mux.Config{
+	// The auth service enforces the password policy, which the users of the
+	// other tests are seeded without.
+	authUserBus := userbus.NewBusiness(db.Log, db.BusDomain.Delegate, userdb.NewStore(db.Log, db.DB), PasswordPolicy, userbus.NewBcryptHasher(0), nil)
+
+	authMux := mux.WebAPI(mux.Config{
 		Log: db.Log,
 		DB:  db.DB,
 		BusConfig: mux.BusConfig{
-			UserBus: db.BusDomain.User,
+			UserBus:    authUserBus,
+			APIKeyBus:  db.BusDomain.APIKey,
+			SessionBus: db.BusDomain.Session,
 		},
 		AuthConfig: mux.AuthConfig{
-			Auth: auth,
+			Auth:      auth,
+			AccessTTL: time.Minute,
 		},
-	}, authbuild.Routes()))
+	}, authbuild.Routes())
+
+	server := httptest.NewServer(authMux)
 
 	authClient := authclient.New(db.Log, server.URL)
 
@@ -48,11 +78,15 @@ func New(t *testing.T, testName string) *Test {
 		Log: db.Log,
 		DB:  db.DB,
 		BusConfig: mux.BusConfig{
+			APIKeyBus:   db.BusDomain.APIKey,
 			AuditBus:    db.BusDomain.Audit,
 			UserBus:     db.BusDomain.User,
 			ProductBus:  db.BusDomain.Product,
 			HomeBus:     db.BusDomain.Home,
 			VProductBus: db.BusDomain.VProduct,
+			ReportBus:   db.BusDomain.Report,
+			SearchBus:   db.BusDomain.Search,
+			WebhookBus:  db.BusDomain.Webhook,
 		},
 		SalesConfig: mux.SalesConfig{
 			AuthClient: authClient,
@@ -60,8 +94,9 @@ func New(t *testing.T, testName string) *Test {
 	}, salesbuild.Routes())
 
 	return &Test{
-		DB:   db,
-		Auth: auth,
-		mux:  mux,
+		DB:      db,
+		Auth:    auth,
+		mux:     mux,
+		authMux: authMux,
 	}
 }
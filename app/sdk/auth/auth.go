@@ -8,21 +8,54 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/tenant"
+	"github.com/ardanlabs/service/foundation/clock"
 	"github.com/ardanlabs/service/foundation/logger"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
 	"github.com/open-policy-agent/opa/v1/rego"
 )
 
+// Tokens are validated against the service clock so they expire with it
+// when the clock is skewed in staging builds.
+func init() {
+	jwt.TimeFunc = clock.Now
+}
+
 // ErrForbidden is returned when a auth issue is identified.
 var ErrForbidden = errors.New("attempted action is not allowed")
 
-// Claims represents the authorization claims transmitted via a JWT.
+// Set of authentication methods recorded in the claims.
+const (
+	MethodPassword   = "pwd"
+	MethodOTP        = "otp"
+	MethodFederated  = "fed"
+	MethodBreakGlass = "bgl"
+)
+
+// Claims represents the authorization claims transmitted via a JWT. The
+// authentication time and methods record when and how the user last proved
+// who they are, which can be older than the token itself. The tenant is
+// left out for users of the default tenant.
 type Claims struct {
 	jwt.RegisteredClaims
-	Roles []string `json:"roles"`
+	Tenant      string           `json:"tenant,omitempty"`
+	Roles       []string         `json:"roles"`
+	BreakGlass  bool             `json:"breakGlass,omitempty"`
+	AuthTime    *jwt.NumericDate `json:"auth_time,omitempty"`
+	AuthMethods []string         `json:"amr,omitempty"`
+}
+
+// TenantID returns the tenant the claims were issued for.
+func (c Claims) TenantID() (uuid.UUID, error) {
+	if c.Tenant == "" {
+		return tenant.Default, nil
+	}
+
+	return uuid.Parse(c.Tenant)
 }
 
 // KeyLookup declares a method set of behavior for looking up
@@ -33,34 +66,50 @@ type KeyLookup interface {
 	PublicKey(kid string) (key string, err error)
 }
 
-// Config represents information required to initialize auth.
+// Config represents information required to initialize auth. The token ttl
+// is how long the tokens issued for users are valid for and defaults to a
+// year.
 type Config struct {
-	Log       *logger.Logger
-	UserBus   userbus.Business
-	KeyLookup KeyLookup
-	Issuer    string
+	Log        *logger.Logger
+	UserBus    userbus.Business
+	KeyLookup  KeyLookup
+	Issuer     string
+	TokenTTL   time.Duration
+	BreakGlass BreakGlassConfig
 }
 
 // Auth is used to authenticate clients. It can generate a token for a
 // set of user claims and recreate the claims by parsing the token.
 type Auth struct {
-	log       *logger.Logger
-	keyLookup KeyLookup
-	userBus   userbus.Business
-	method    jwt.SigningMethod
-	parser    *jwt.Parser
-	issuer    string
+	log        *logger.Logger
+	keyLookup  KeyLookup
+	userBus    userbus.Business
+	method     jwt.SigningMethod
+	parser     *jwt.Parser
+	issuer     string
+	tokenTTL   time.Duration
+	breakGlass BreakGlassConfig
 }
 
 // New creates an Auth to support authentication/authorization.
 func New(cfg Config) (*Auth, error) {
+	if cfg.BreakGlass.TTL <= 0 {
+		cfg.BreakGlass.TTL = time.Hour
+	}
+
+	if cfg.TokenTTL <= 0 {
+		cfg.TokenTTL = 8760 * time.Hour
+	}
+
 	a := Auth{
-		log:       cfg.Log,
-		keyLookup: cfg.KeyLookup,
-		userBus:   cfg.UserBus,
-		method:    jwt.GetSigningMethod(jwt.SigningMethodRS256.Name),
-		parser:    jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Name})),
-		issuer:    cfg.Issuer,
+		log:        cfg.Log,
+		keyLookup:  cfg.KeyLookup,
+		userBus:    cfg.UserBus,
+		method:     jwt.GetSigningMethod(jwt.SigningMethodRS256.Name),
+		parser:     jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Name})),
+		issuer:     cfg.Issuer,
+		tokenTTL:   cfg.TokenTTL,
+		breakGlass: cfg.BreakGlass,
 	}
 
 	return &a, nil
@@ -134,6 +183,21 @@ func (a *Auth) Authenticate(ctx context.Context, bearerToken string) (Claims, er
 		return Claims{}, fmt.Errorf("authentication failed : %w", err)
 	}
 
+	if _, err := claims.TenantID(); err != nil {
+		return Claims{}, fmt.Errorf("parse tenant: %w", err)
+	}
+
+	// Break-glass tokens don't belong to a user so they are checked against
+	// the break-glass configuration instead of the database.
+
+	if claims.BreakGlass {
+		if err := a.checkBreakGlass(ctx, claims); err != nil {
+			return Claims{}, fmt.Errorf("break-glass : %w", err)
+		}
+
+		return claims, nil
+	}
+
 	// Check the database for this user to verify they are still enabled.
 
 	if err := a.isUserEnabled(ctx, claims); err != nil {
@@ -211,5 +275,14 @@ func (a *Auth) isUserEnabled(ctx context.Context, claims Claims) error {
 		return fmt.Errorf("user disabled")
 	}
 
+	tenantID, err := claims.TenantID()
+	if err != nil {
+		return fmt.Errorf("parse tenant: %w", err)
+	}
+
+	if usr.TenantID != tenantID {
+		return fmt.Errorf("tenant mismatch")
+	}
+
 	return nil
 }
@@ -3,11 +3,20 @@ package auth_test
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"net/mail"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/ardanlabs/service/app/sdk/auth"
+	"github.com/ardanlabs/service/business/domain/notificationbus"
+	"github.com/ardanlabs/service/business/domain/templatebus"
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/domain/userbus/mocks"
+	"github.com/ardanlabs/service/business/sdk/order"
 	"github.com/ardanlabs/service/business/types/role"
 	"github.com/ardanlabs/service/foundation/logger"
 	"github.com/golang-jwt/jwt/v4"
@@ -257,6 +266,212 @@ func test6(ath *auth.Auth) func(t *testing.T) {
 
 // =============================================================================
 
+func Test_NewClaims(t *testing.T) {
+	ath, err := auth.New(auth.Config{
+		Log:       newUnit(t),
+		KeyLookup: &keyStore{},
+		Issuer:    "service project",
+		TokenTTL:  time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("Should be able to create an authenticator: %s", err)
+	}
+
+	usr := userbus.User{
+		ID:    uuid.MustParse("5cf37266-3473-4006-984f-9325122678b7"),
+		Roles: []role.Role{role.User},
+	}
+
+	authTime := time.Now().Add(-time.Minute)
+
+	claims := ath.NewClaims(usr,
+		auth.WithTTL(time.Minute),
+		auth.WithAuthentication(authTime, auth.MethodPassword),
+		auth.WithClaims(func(claims *auth.Claims) {
+			claims.Audience = jwt.ClaimStrings{"sales"}
+		}),
+	)
+
+	if claims.Subject != usr.ID.String() || claims.Issuer != ath.Issuer() {
+		t.Fatalf("Should identify the user and issuer : %v", claims.RegisteredClaims)
+	}
+
+	if len(claims.Roles) != 1 || claims.Roles[0] != role.User.String() {
+		t.Fatalf("Should carry the user's roles : %v", claims.Roles)
+	}
+
+	if ttl := claims.ExpiresAt.Sub(claims.IssuedAt.Time); ttl != time.Minute {
+		t.Fatalf("Should use the ttl option : %s", ttl)
+	}
+
+	if claims.AuthTime == nil || claims.AuthTime.Unix() != authTime.Unix() {
+		t.Fatalf("Should record the authentication time : %v", claims.AuthTime)
+	}
+
+	if len(claims.Audience) != 1 || claims.Audience[0] != "sales" {
+		t.Fatalf("Should apply the custom claims : %v", claims.Audience)
+	}
+
+	if tenantID, err := claims.TenantID(); err != nil || tenantID != uuid.Nil || claims.Tenant != "" {
+		t.Fatalf("Should leave out the default tenant : %q", claims.Tenant)
+	}
+
+	usr.TenantID = uuid.MustParse("9a8c1a0e-6f1b-4a51-bb4c-0ad3a0e51d6f")
+
+	claims = ath.NewClaims(usr)
+
+	if tenantID, err := claims.TenantID(); err != nil || tenantID != usr.TenantID {
+		t.Fatalf("Should carry the user's tenant : %q", claims.Tenant)
+	}
+}
+
+// =============================================================================
+
+func Test_BreakGlass(t *testing.T) {
+	log := newUnit(t)
+
+	const credential = "sealed-credential"
+
+	var notified int
+
+	ath, err := auth.New(auth.Config{
+		Log:       log,
+		UserBus:   nil,
+		KeyLookup: &keyStore{},
+		Issuer:    "service project",
+		BreakGlass: auth.BreakGlassConfig{
+			CredentialHash: auth.HashBreakGlassCredential(credential),
+			TTL:            time.Minute,
+			Notify: func(ctx context.Context, event auth.BreakGlassEvent) {
+				notified++
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Should be able to create an authenticator: %s", err)
+	}
+
+	if _, _, err := ath.BreakGlass(context.Background(), kid, "wrong", "idp outage", "127.0.0.1"); !errors.Is(err, auth.ErrForbidden) {
+		t.Fatalf("Should not grant access with the wrong credential : %v", err)
+	}
+
+	token, expiresAt, err := ath.BreakGlass(context.Background(), kid, credential, "idp outage", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("Should be able to break the glass : %s", err)
+	}
+
+	if notified != 1 {
+		t.Fatalf("Should notify the admins once, got %d", notified)
+	}
+
+	if time.Until(expiresAt) > time.Minute {
+		t.Fatalf("Should time-box the access : %s", expiresAt)
+	}
+
+	claims, err := ath.Authenticate(context.Background(), "Bearer "+token)
+	if err != nil {
+		t.Fatalf("Should be able to authenticate the break-glass token : %s", err)
+	}
+
+	if !claims.BreakGlass || claims.Subject != auth.BreakGlassSubject {
+		t.Fatalf("Should have break-glass claims : %+v", claims)
+	}
+
+	if claims.AuthTime == nil || time.Since(claims.AuthTime.Time) > time.Minute {
+		t.Fatalf("Should record when the glass was broken : %v", claims.AuthTime)
+	}
+
+	if err := ath.Authorize(context.Background(), claims, uuid.MustParse(claims.Subject), auth.RuleAdminOnly); err != nil {
+		t.Fatalf("Should be authorized as an admin : %s", err)
+	}
+
+	if name, exists := userbus.SystemActor(uuid.MustParse(claims.Subject)); !exists || name != "break-glass" {
+		t.Fatalf("Should be able to make changes as a system actor : %q", name)
+	}
+
+	disabled, err := auth.New(auth.Config{
+		Log:       log,
+		KeyLookup: &keyStore{},
+		Issuer:    "service project",
+	})
+	if err != nil {
+		t.Fatalf("Should be able to create an authenticator: %s", err)
+	}
+
+	if _, err := disabled.Authenticate(context.Background(), "Bearer "+token); err == nil {
+		t.Fatalf("Should not accept break-glass tokens when break-glass is disabled")
+	}
+}
+
+func Test_BreakGlassNotifyAdmins(t *testing.T) {
+	log := newUnit(t)
+
+	const credential = "sealed-credential"
+
+	admins := []userbus.User{
+		{ID: uuid.New(), Email: mail.Address{Address: "admin1@example.com"}, Roles: []role.Role{role.Admin}, Enabled: true},
+		{ID: uuid.New(), Email: mail.Address{Address: "admin2@example.com"}, Roles: []role.Role{role.Admin}, Enabled: true},
+	}
+
+	userBus := mocks.Business{
+		QueryAllFunc: func(ctx context.Context, filter userbus.QueryFilter, orderBy order.By, fn func(userbus.User) error) error {
+			if !slices.Equal(filter.Roles, []role.Role{role.Admin}) || filter.Enabled == nil || !*filter.Enabled {
+				return fmt.Errorf("unexpected filter: %+v", filter)
+			}
+
+			for _, adm := range admins {
+				if err := fn(adm); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	}
+
+	sender := &captureSender{}
+	senders := map[notificationbus.Channel]notificationbus.Sender{
+		notificationbus.ChannelEmail: sender,
+	}
+	notificationBus := notificationbus.NewBusiness(log, &userBus, templatebus.NewBusiness(log, templateStore{}), nil, nil, senders)
+
+	ath, err := auth.New(auth.Config{
+		Log:       log,
+		KeyLookup: &keyStore{},
+		Issuer:    "service project",
+		BreakGlass: auth.BreakGlassConfig{
+			CredentialHash: auth.HashBreakGlassCredential(credential),
+			TTL:            time.Minute,
+			Notify:         auth.NotifyAdmins(log, notificationBus),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Should be able to create an authenticator: %s", err)
+	}
+
+	if _, _, err := ath.BreakGlass(context.Background(), kid, "wrong", "idp outage", "127.0.0.1"); !errors.Is(err, auth.ErrForbidden) {
+		t.Fatalf("Should not grant access with the wrong credential : %v", err)
+	}
+
+	if len(sender.to) != 0 {
+		t.Fatalf("Should not notify the admins when access is denied : got %v", sender.to)
+	}
+
+	if _, _, err := ath.BreakGlass(context.Background(), kid, credential, "idp outage", "127.0.0.1"); err != nil {
+		t.Fatalf("Should be able to break the glass : %s", err)
+	}
+
+	if exp := []string{"admin1@example.com", "admin2@example.com"}; !slices.Equal(sender.to, exp) {
+		t.Fatalf("Should email every admin : got %v, exp %v", sender.to, exp)
+	}
+
+	for _, body := range sender.bodies {
+		if !strings.Contains(body, "idp outage") || !strings.Contains(body, "127.0.0.1") {
+			t.Fatalf("Should tell the admins the reason and address : %s", body)
+		}
+	}
+}
+
 func newUnit(t *testing.T) *logger.Logger {
 	var buf bytes.Buffer
 	log := logger.New(&buf, logger.LevelInfo, "TEST", func(context.Context) string { return "00000000-0000-0000-0000-000000000000" })
@@ -284,6 +499,27 @@ func (ks *keyStore) PublicKey(kid string) (string, error) {
 	return publicKeyPEM, nil
 }
 
+// captureSender keeps the emails it's given.
+type captureSender struct {
+	to     []string
+	bodies []string
+}
+
+func (cs *captureSender) Send(ctx context.Context, to mail.Address, subject string, body string) error {
+	cs.to = append(cs.to, to.Address)
+	cs.bodies = append(cs.bodies, body)
+	return nil
+}
+
+// templateStore has no templates of its own so the ones shipped are used.
+type templateStore struct {
+	templatebus.Storer
+}
+
+func (templateStore) QueryByNameLocale(ctx context.Context, name string, locale string) (templatebus.Template, error) {
+	return templatebus.Template{}, templatebus.ErrNotFound
+}
+
 const (
 	kid = "s4sKIjD9kIRjxs2tulPqGLdxSfgPErRN1Mu3Hd9k9NQ"
 
@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/tenant"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/ardanlabs/service/foundation/clock"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ClaimOptions represent optional parameters for the claims of a token.
+type ClaimOptions struct {
+	ttl         time.Duration
+	authTime    time.Time
+	authMethods []string
+	custom      []func(claims *Claims)
+}
+
+// WithTTL sets how long the token is valid for, replacing the configured
+// token ttl.
+func WithTTL(ttl time.Duration) func(opts *ClaimOptions) {
+	return func(opts *ClaimOptions) {
+		opts.ttl = ttl
+	}
+}
+
+// WithAuthentication records when and how the user authenticated.
+func WithAuthentication(authTime time.Time, methods ...string) func(opts *ClaimOptions) {
+	return func(opts *ClaimOptions) {
+		opts.authTime = authTime
+		opts.authMethods = methods
+	}
+}
+
+// WithClaims provides a function that can change the claims before they
+// are returned, for claims that don't have an option of their own.
+func WithClaims(fn func(claims *Claims)) func(opts *ClaimOptions) {
+	return func(opts *ClaimOptions) {
+		opts.custom = append(opts.custom, fn)
+	}
+}
+
+// NewClaims constructs the claims for a token issued to the user, so the
+// callers issuing tokens don't each build them.
+func (a *Auth) NewClaims(usr userbus.User, options ...func(opts *ClaimOptions)) Claims {
+	opts := ClaimOptions{
+		ttl: a.tokenTTL,
+	}
+	for _, option := range options {
+		option(&opts)
+	}
+
+	now := clock.Now().UTC()
+
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   usr.ID.String(),
+			Issuer:    a.issuer,
+			ExpiresAt: jwt.NewNumericDate(now.Add(opts.ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+		Roles:       role.ParseToString(usr.Roles),
+		AuthMethods: opts.authMethods,
+	}
+
+	if usr.TenantID != tenant.Default {
+		claims.Tenant = usr.TenantID.String()
+	}
+
+	if !opts.authTime.IsZero() {
+		claims.AuthTime = jwt.NewNumericDate(opts.authTime.UTC())
+	}
+
+	for _, fn := range opts.custom {
+		fn(&claims)
+	}
+
+	return claims
+}
+
+// AuthenticateAndToken verifies the user's password and returns the user
+// along with a signed token for them. Users with one-time codes enabled
+// can't be authenticated this way.
+func (a *Auth) AuthenticateAndToken(ctx context.Context, email mail.Address, password string, kid string, options ...func(opts *ClaimOptions)) (userbus.User, string, error) {
+	usr, err := a.userBus.Authenticate(ctx, email, password)
+	if err != nil {
+		return userbus.User{}, "", fmt.Errorf("authenticate: %w", err)
+	}
+
+	options = append([]func(opts *ClaimOptions){WithAuthentication(clock.Now(), MethodPassword)}, options...)
+
+	token, err := a.GenerateToken(kid, a.NewClaims(usr, options...))
+	if err != nil {
+		return userbus.User{}, "", fmt.Errorf("generate token: %w", err)
+	}
+
+	return usr, token, nil
+}
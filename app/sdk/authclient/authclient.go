@@ -11,6 +11,7 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+	"strings"
 	"time"
 
 	"github.com/ardanlabs/service/app/sdk/errs"
@@ -84,6 +85,28 @@ func (cln *Client) Authenticate(ctx context.Context, authorization string) (Auth
 	return resp, nil
 }
 
+// Token calls the auth service to generate a token for the user with the
+// basic credentials in authorization and, when the user has two factor
+// authentication enabled, the one time code in otp.
+func (cln *Client) Token(ctx context.Context, kid string, authorization string, otp string) (string, error) {
+	endpoint := fmt.Sprintf("%s/v1/auth/token/%s", cln.url, url.PathEscape(kid))
+
+	headers := map[string]string{
+		"authorization": authorization,
+	}
+
+	if otp != "" {
+		headers["X-OTP"] = otp
+	}
+
+	var resp TokenResp
+	if err := cln.do(ctx, http.MethodGet, endpoint, headers, nil, &resp); err != nil {
+		return "", err
+	}
+
+	return resp.Token, nil
+}
+
 // Authorize calls the auth service to authorize the user.
 func (cln *Client) Authorize(ctx context.Context, auth Authorize) error {
 	endpoint := fmt.Sprintf("%s/v1/auth/authorize", cln.url)
@@ -131,7 +154,7 @@ func (cln *Client) do(ctx context.Context, method string, endpoint string, heade
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
 	for key, value := range headers {
-		cln.log.Info(ctx, "authclient: rawRequest", "key", key, "value", value)
+		cln.log.Info(ctx, "authclient: rawRequest", "key", key, "value", mask(value))
 		req.Header.Set(key, value)
 	}
 
@@ -173,3 +196,14 @@ func (cln *Client) do(ctx context.Context, method string, endpoint string, heade
 		return fmt.Errorf("failed: response: %s", string(data))
 	}
 }
+
+// mask hides header values so credentials don't end up in the logs, only
+// the scheme of an authorization value is kept.
+func mask(value string) string {
+	scheme, _, found := strings.Cut(value, " ")
+	if !found {
+		return "****"
+	}
+
+	return scheme + " ****"
+}
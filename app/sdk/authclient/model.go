@@ -30,3 +30,8 @@ func (ar AuthenticateResp) Encode() ([]byte, string, error) {
 	data, err := json.Marshal(ar)
 	return data, "application/json", err
 }
+
+// TokenResp defines the information that will be received on token.
+type TokenResp struct {
+	Token string `json:"token"`
+}
@@ -2,10 +2,12 @@
 package debug
 
 import (
+	"encoding/json"
 	"expvar"
 	"net/http"
 	"net/http/pprof"
 
+	"github.com/ardanlabs/service/foundation/boot"
 	"github.com/arl/statsviz"
 )
 
@@ -22,8 +24,22 @@ func Mux() *http.ServeMux {
 	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
 	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
 	mux.Handle("/debug/vars/", expvar.Handler())
+	mux.HandleFunc("/debug/startup", startup)
 
 	statsviz.Register(mux)
 
 	return mux
 }
+
+// startup returns the report of the phases the service went through while
+// starting.
+func startup(w http.ResponseWriter, r *http.Request) {
+	rpt, ok := boot.Last()
+	if !ok {
+		http.Error(w, "no startup recorded", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rpt)
+}
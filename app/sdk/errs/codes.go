@@ -2,6 +2,9 @@ package errs
 
 import (
 	"net/http"
+
+	"github.com/ardanlabs/service/business/sdk/buserr"
+	"google.golang.org/grpc/codes"
 )
 
 var (
@@ -186,3 +189,41 @@ var httpStatus = map[ErrCode]int{
 	TooManyRequests:    http.StatusTooManyRequests,
 	InternalOnlyLog:    http.StatusInternalServerError,
 }
+
+// busCodes maps the code of a business error to the code of the error the
+// app layer returns for it.
+var grpcCodes = map[ErrCode]codes.Code{
+	OK:                 codes.OK,
+	NoContent:          codes.OK,
+	Canceled:           codes.Canceled,
+	Unknown:            codes.Unknown,
+	InvalidArgument:    codes.InvalidArgument,
+	DeadlineExceeded:   codes.DeadlineExceeded,
+	NotFound:           codes.NotFound,
+	AlreadyExists:      codes.AlreadyExists,
+	PermissionDenied:   codes.PermissionDenied,
+	ResourceExhausted:  codes.ResourceExhausted,
+	FailedPrecondition: codes.FailedPrecondition,
+	Aborted:            codes.Aborted,
+	OutOfRange:         codes.OutOfRange,
+	Unimplemented:      codes.Unimplemented,
+	Internal:           codes.Internal,
+	Unavailable:        codes.Unavailable,
+	DataLoss:           codes.DataLoss,
+	Unauthenticated:    codes.Unauthenticated,
+	TooManyRequests:    codes.ResourceExhausted,
+	InternalOnlyLog:    codes.Internal,
+}
+
+var busCodes = map[buserr.Code]ErrCode{
+	buserr.NotFound:           NotFound,
+	buserr.Conflict:           Aborted,
+	buserr.InvalidArgument:    InvalidArgument,
+	buserr.FailedPrecondition: FailedPrecondition,
+	buserr.Unauthenticated:    Unauthenticated,
+	buserr.PermissionDenied:   PermissionDenied,
+	buserr.ResourceExhausted:  TooManyRequests,
+	buserr.DeadlineExceeded:   DeadlineExceeded,
+	buserr.Unimplemented:      Unimplemented,
+	buserr.Internal:           Internal,
+}
@@ -6,6 +6,9 @@ import (
 	"errors"
 	"fmt"
 	"runtime"
+
+	"github.com/ardanlabs/service/business/sdk/buserr"
+	"google.golang.org/grpc/status"
 )
 
 // ErrCode represents an error code in the system.
@@ -82,16 +85,60 @@ func Newf(code ErrCode, format string, v ...any) *Error {
 }
 
 // NewError checks for an Error in the error interface value. If it doesn't
-// exist, will create one from the error.
+// exist, will create one from the business error in the chain or else from
+// the error itself.
 func NewError(err error) *Error {
 	var errsErr *Error
 	if errors.As(err, &errsErr) {
 		return errsErr
 	}
 
+	if errsErr, ok := FromBus(err); ok {
+		return errsErr
+	}
+
 	return New(Internal, err)
 }
 
+// FromBus constructs an error from the business error in the error's
+// chain, with the code that matches the business error's code. The field
+// violations of the business error become the message the same as field
+// errors. It reports false when there is no business error in the chain.
+func FromBus(err error) (*Error, bool) {
+	be, ok := buserr.As(err)
+	if !ok {
+		return nil, false
+	}
+
+	code, exists := busCodes[be.Code]
+	if !exists {
+		code = Internal
+	}
+
+	msg := be.Message
+	if len(be.Fields) > 0 {
+		fe := make(FieldErrors, len(be.Fields))
+		for i, fv := range be.Fields {
+			fe[i] = FieldError{
+				Field: fv.Field,
+				Err:   fv.Message,
+			}
+		}
+		msg = fe.Error()
+	}
+
+	pc, filename, line, _ := runtime.Caller(1)
+
+	appErr := Error{
+		Code:     code,
+		Message:  msg,
+		FuncName: runtime.FuncForPC(pc).Name(),
+		FileName: fmt.Sprintf("%s:%d", filename, line),
+	}
+
+	return &appErr, true
+}
+
 // Error implements the error interface.
 func (e *Error) Error() string {
 	return e.Message
@@ -109,6 +156,12 @@ func (e *Error) HTTPStatus() int {
 	return httpStatus[e.Code]
 }
 
+// GRPCStatus implements the interface the grpc status package looks for so
+// grpc handlers can return the error with the matching status code.
+func (e *Error) GRPCStatus() *status.Status {
+	return status.New(grpcCodes[e.Code], e.Message)
+}
+
 // Equal provides support for the go-cmp package and testing.
 func (e *Error) Equal(e2 *Error) bool {
 	return e.Code == e2.Code && e.Message == e2.Message
@@ -0,0 +1,119 @@
+// Package extid provides support for the ids exposed by the public APIs. The
+// business layer works with raw UUIDs and the app layer converts them to and
+// from their external form when building and reading its models. By default
+// the external form is the UUID itself, and a codec can be installed at
+// startup so the internal identifiers are never exposed.
+package extid
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidID is returned when an encrypted external id can't be decoded.
+var ErrInvalidID = errors.New("invalid id")
+
+// Codec represents the conversion between ids and their external form.
+type Codec interface {
+	Encode(id uuid.UUID) string
+	Decode(extID string) (uuid.UUID, error)
+}
+
+var codec atomic.Pointer[Codec]
+
+// Use installs the codec for all ids. It should be called once at startup
+// before any requests are handled.
+func Use(c Codec) {
+	codec.Store(&c)
+}
+
+func current() Codec {
+	if c := codec.Load(); c != nil {
+		return *c
+	}
+
+	return Plain{}
+}
+
+// Encode returns the external form of the id.
+func Encode(id uuid.UUID) string {
+	return current().Encode(id)
+}
+
+// EncodeNull returns the external form of the id, or an empty string when
+// the id isn't set.
+func EncodeNull(id uuid.NullUUID) string {
+	if !id.Valid {
+		return ""
+	}
+
+	return Encode(id.UUID)
+}
+
+// Decode returns the id for the external form.
+func Decode(extID string) (uuid.UUID, error) {
+	return current().Decode(extID)
+}
+
+// =============================================================================
+
+// Plain exposes the ids as they are.
+type Plain struct{}
+
+// Encode implements the Codec interface.
+func (Plain) Encode(id uuid.UUID) string {
+	return id.String()
+}
+
+// Decode implements the Codec interface.
+func (Plain) Decode(extID string) (uuid.UUID, error) {
+	return uuid.Parse(extID)
+}
+
+// =============================================================================
+
+// AES exposes the ids encrypted with a secret key. A UUID is exactly one AES
+// block, so each id maps to a single external id of 22 characters that
+// reveals nothing about the id without the key. Changing the key changes
+// every external id.
+type AES struct {
+	block cipher.Block
+}
+
+// NewAES constructs a codec that uses the key, which must be 16, 24 or 32
+// bytes long.
+func NewAES(key []byte) (*AES, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("new cipher: %w", err)
+	}
+
+	return &AES{block: block}, nil
+}
+
+// Encode implements the Codec interface.
+func (a *AES) Encode(id uuid.UUID) string {
+	var b [aes.BlockSize]byte
+	a.block.Encrypt(b[:], id[:])
+
+	return base64.RawURLEncoding.EncodeToString(b[:])
+}
+
+// Decode implements the Codec interface.
+func (a *AES) Decode(extID string) (uuid.UUID, error) {
+	b, err := base64.RawURLEncoding.DecodeString(extID)
+	if err != nil || len(b) != aes.BlockSize {
+		return uuid.UUID{}, ErrInvalidID
+	}
+
+	var id uuid.UUID
+	a.block.Decrypt(id[:], b)
+
+	return id, nil
+}
@@ -0,0 +1,35 @@
+package extid_test
+
+import (
+	"testing"
+
+	"github.com/ardanlabs/service/app/sdk/extid"
+	"github.com/google/uuid"
+)
+
+func Test_AES(t *testing.T) {
+	codec, err := extid.NewAES([]byte("0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("Should be able to construct the codec : %s", err)
+	}
+
+	id := uuid.New()
+
+	extID := codec.Encode(id)
+	if extID == id.String() || len(extID) != 22 {
+		t.Fatalf("Should get back an encrypted id : %s", extID)
+	}
+
+	got, err := codec.Decode(extID)
+	if err != nil {
+		t.Fatalf("Should be able to decode the id : %s", err)
+	}
+
+	if got != id {
+		t.Fatalf("Should get back the same id : got %s, exp %s", got, id)
+	}
+
+	if _, err := codec.Decode(id.String()); err == nil {
+		t.Fatalf("Should not be able to decode a raw id")
+	}
+}
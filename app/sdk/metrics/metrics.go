@@ -5,6 +5,8 @@ import (
 	"context"
 	"expvar"
 	"runtime"
+
+	"github.com/ardanlabs/service/foundation/ctxval"
 )
 
 // This holds the single instance of the metrics value needed for
@@ -35,18 +37,16 @@ func init() {
 	}
 }
 
-type ctxKey int
-
-const key ctxKey = 1
+var key = ctxval.NewKey[*metrics]("metrics")
 
 // Set sets the metrics data into the context.
 func Set(ctx context.Context) context.Context {
-	return context.WithValue(ctx, key, &m)
+	return key.Set(ctx, &m)
 }
 
 // AddGoroutines refreshes the goroutine metric.
 func AddGoroutines(ctx context.Context) int64 {
-	if v, ok := ctx.Value(key).(*metrics); ok {
+	if v, ok := key.Get(ctx); ok {
 		g := int64(runtime.NumGoroutine())
 		v.goroutines.Set(g)
 		return g
@@ -57,7 +57,7 @@ func AddGoroutines(ctx context.Context) int64 {
 
 // AddRequests increments the request metric by 1.
 func AddRequests(ctx context.Context) int64 {
-	v, ok := ctx.Value(key).(*metrics)
+	v, ok := key.Get(ctx)
 	if ok {
 		v.requests.Add(1)
 		return v.requests.Value()
@@ -68,7 +68,7 @@ func AddRequests(ctx context.Context) int64 {
 
 // AddErrors increments the errors metric by 1.
 func AddErrors(ctx context.Context) int64 {
-	if v, ok := ctx.Value(key).(*metrics); ok {
+	if v, ok := key.Get(ctx); ok {
 		v.errors.Add(1)
 		return v.errors.Value()
 	}
@@ -78,7 +78,7 @@ func AddErrors(ctx context.Context) int64 {
 
 // AddPanics increments the panics metric by 1.
 func AddPanics(ctx context.Context) int64 {
-	if v, ok := ctx.Value(key).(*metrics); ok {
+	if v, ok := key.Get(ctx); ok {
 		v.panics.Add(1)
 		return v.panics.Value()
 	}
@@ -3,6 +3,7 @@ package mid
 import (
 	"context"
 	"encoding/base64"
+	"errors"
 	"net/http"
 	"net/mail"
 	"strings"
@@ -11,10 +12,10 @@ import (
 	"github.com/ardanlabs/service/app/sdk/auth"
 	"github.com/ardanlabs/service/app/sdk/authclient"
 	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/business/domain/apikeybus"
 	"github.com/ardanlabs/service/business/domain/userbus"
-	"github.com/ardanlabs/service/business/types/role"
+	"github.com/ardanlabs/service/foundation/clock"
 	"github.com/ardanlabs/service/foundation/web"
-	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
 )
 
@@ -43,11 +44,29 @@ func Authenticate(client *authclient.Client) web.MidFunc {
 	return m
 }
 
-// Bearer processes JWT authentication logic.
-func Bearer(ath *auth.Auth) web.MidFunc {
+// Bearer processes JWT authentication logic. When the api key business is
+// provided, machine callers can also authenticate with an api key using the
+// ApiKey scheme.
+func Bearer(ath *auth.Auth, apiKeyBus *apikeybus.Business) web.MidFunc {
 	m := func(next web.HandlerFunc) web.HandlerFunc {
 		h := func(ctx context.Context, r *http.Request) web.Encoder {
-			claims, err := ath.Authenticate(ctx, r.Header.Get("authorization"))
+			authorization := r.Header.Get("authorization")
+
+			if key, found := strings.CutPrefix(authorization, "ApiKey "); found && apiKeyBus != nil {
+				usr, err := apiKeyBus.VerifyKey(ctx, key)
+				if err != nil {
+					return errs.New(errs.Unauthenticated, err)
+				}
+
+				claims := ath.NewClaims(usr)
+
+				ctx = setUserID(ctx, usr.ID)
+				ctx = setClaims(ctx, claims)
+
+				return next(ctx, r)
+			}
+
+			claims, err := ath.Authenticate(ctx, authorization)
 			if err != nil {
 				return errs.New(errs.Unauthenticated, err)
 			}
@@ -73,8 +92,11 @@ func Bearer(ath *auth.Auth) web.MidFunc {
 	return m
 }
 
-// Basic processes basic authentication logic.
-func Basic(ath *auth.Auth, userBus userbus.Business) web.MidFunc {
+// Basic processes basic authentication logic. Users with one-time codes
+// enabled provide the code, or a recovery code, in the X-OTP header. Failed
+// attempts are delayed by the throttle and the delay is reported with the
+// Retry-After header.
+func Basic(ath *auth.Auth, userBus userbus.Business, throttle LoginThrottle) web.MidFunc {
 	m := func(next web.HandlerFunc) web.HandlerFunc {
 		h := func(ctx context.Context, r *http.Request) web.Encoder {
 			email, pass, ok := parseBasicAuth(r.Header.Get("authorization"))
@@ -87,27 +109,36 @@ func Basic(ath *auth.Auth, userBus userbus.Business) web.MidFunc {
 				return errs.New(errs.Unauthenticated, err)
 			}
 
-			usr, err := userBus.Authenticate(ctx, *addr, pass)
+			accountKey, addrKey := throttleKeys(r, addr.Address)
+
+			if wait := throttle.wait(accountKey, addrKey); wait > 0 {
+				setRetryAfter(ctx, wait)
+				return errs.Newf(errs.TooManyRequests, "too many failed login attempts, retry in %s", wait.Round(time.Second))
+			}
+
+			usr, err := userBus.AuthenticateWithTOTP(userbus.WithSourceIP(ctx, remoteHost(r)), *addr, pass, r.Header.Get("X-OTP"))
 			if err != nil {
+				var aerr *userbus.AttemptsError
+				if errors.As(err, &aerr) {
+					setRetryAfter(ctx, aerr.RetryAfter)
+					return errs.Newf(errs.TooManyRequests, "too many login attempts, retry in %s", aerr.RetryAfter.Round(time.Second))
+				}
+				if errors.Is(err, userbus.ErrAuthenticationFailure) || errors.Is(err, userbus.ErrNotFound) {
+					setRetryAfter(ctx, throttle.fail(accountKey, addrKey))
+				}
 				return errs.New(errs.Unauthenticated, err)
 			}
 
-			claims := auth.Claims{
-				RegisteredClaims: jwt.RegisteredClaims{
-					Subject:   usr.ID.String(),
-					Issuer:    ath.Issuer(),
-					ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(8760 * time.Hour)),
-					IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
-				},
-				Roles: role.ParseToString(usr.Roles),
-			}
+			throttle.succeed(accountKey)
 
-			subjectID, err := uuid.Parse(claims.Subject)
-			if err != nil {
-				return errs.Newf(errs.Unauthenticated, "parsing subject: %s", err)
+			methods := []string{auth.MethodPassword}
+			if usr.TOTPEnabled {
+				methods = append(methods, auth.MethodOTP)
 			}
 
-			ctx = setUserID(ctx, subjectID)
+			claims := ath.NewClaims(usr, auth.WithAuthentication(clock.Now(), methods...))
+
+			ctx = setUserID(ctx, usr.ID)
 			ctx = setClaims(ctx, claims)
 
 			return next(ctx, r)
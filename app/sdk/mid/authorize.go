@@ -9,6 +9,8 @@ import (
 	"github.com/ardanlabs/service/app/sdk/auth"
 	"github.com/ardanlabs/service/app/sdk/authclient"
 	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/extid"
+	"github.com/ardanlabs/service/business/domain/apikeybus"
 	"github.com/ardanlabs/service/business/domain/homebus"
 	"github.com/ardanlabs/service/business/domain/productbus"
 	"github.com/ardanlabs/service/business/domain/userbus"
@@ -63,7 +65,7 @@ func AuthorizeUser(client *authclient.Client, userBus userbus.Business, rule str
 
 			if id != "" {
 				var err error
-				userID, err = uuid.Parse(id)
+				userID, err = extid.Decode(id)
 				if err != nil {
 					return errs.New(errs.Unauthenticated, ErrInvalidID)
 				}
@@ -116,7 +118,7 @@ func AuthorizeProduct(client *authclient.Client, productBus *productbus.Business
 
 			if id != "" {
 				var err error
-				productID, err := uuid.Parse(id)
+				productID, err := extid.Decode(id)
 				if err != nil {
 					return errs.New(errs.Unauthenticated, ErrInvalidID)
 				}
@@ -170,7 +172,7 @@ func AuthorizeHome(client *authclient.Client, homeBus *homebus.Business) web.Mid
 
 			if id != "" {
 				var err error
-				homeID, err := uuid.Parse(id)
+				homeID, err := extid.Decode(id)
 				if err != nil {
 					return errs.New(errs.Unauthenticated, ErrInvalidID)
 				}
@@ -210,3 +212,56 @@ func AuthorizeHome(client *authclient.Client, homeBus *homebus.Business) web.Mid
 
 	return m
 }
+
+// AuthorizeAPIKey executes the specified role and extracts the specified
+// api key from the DB if an api key id is specified in the call. Depending on
+// the rule specified, the userid from the claims may be compared with the
+// specified user id from the api key.
+func AuthorizeAPIKey(client *authclient.Client, apiKeyBus *apikeybus.Business) web.MidFunc {
+	m := func(next web.HandlerFunc) web.HandlerFunc {
+		h := func(ctx context.Context, r *http.Request) web.Encoder {
+			id := web.Param(r, "key_id")
+
+			var userID uuid.UUID
+
+			if id != "" {
+				keyID, err := extid.Decode(id)
+				if err != nil {
+					return errs.New(errs.Unauthenticated, ErrInvalidID)
+				}
+
+				key, err := apiKeyBus.QueryByID(ctx, keyID)
+				if err != nil {
+					switch {
+					case errors.Is(err, apikeybus.ErrNotFound):
+						return errs.New(errs.Unauthenticated, err)
+					default:
+						return errs.Newf(errs.Unauthenticated, "querybyid: keyID[%s]: %s", keyID, err)
+					}
+				}
+
+				userID = key.UserID
+				ctx = setAPIKey(ctx, key)
+			}
+
+			ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			defer cancel()
+
+			auth := authclient.Authorize{
+				Claims: GetClaims(ctx),
+				UserID: userID,
+				Rule:   auth.RuleAdminOrSubject,
+			}
+
+			if err := client.Authorize(ctx, auth); err != nil {
+				return errs.New(errs.Unauthenticated, err)
+			}
+
+			return next(ctx, r)
+		}
+
+		return h
+	}
+
+	return m
+}
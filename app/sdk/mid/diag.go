@@ -0,0 +1,95 @@
+package mid
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/ardanlabs/service/app/sdk/authclient"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/ardanlabs/service/foundation/diag"
+	"github.com/ardanlabs/service/foundation/web"
+)
+
+// DiagnosticsHeader is the header a caller sets to "true" to request
+// execution diagnostics. The diagnostics are returned in the same header
+// on the response.
+const DiagnosticsHeader = "X-Debug-Diagnostics"
+
+// maxDiagnosticsBytes caps the size of the diagnostics header. Proxies and
+// clients commonly reject responses with headers over 8KB, so entries are
+// dropped from the report until it fits.
+const maxDiagnosticsBytes = 4 << 10
+
+// Diagnostics collects execution diagnostics for the request when the caller
+// asks for them and is an authenticated admin. If the caller can't be
+// authorized, the request is processed normally without diagnostics.
+func Diagnostics(client *authclient.Client) web.MidFunc {
+	m := func(next web.HandlerFunc) web.HandlerFunc {
+		h := func(ctx context.Context, r *http.Request) web.Encoder {
+			if r.Header.Get(DiagnosticsHeader) != "true" {
+				return next(ctx, r)
+			}
+
+			if !isDiagnosticsAdmin(ctx, client, r) {
+				return next(ctx, r)
+			}
+
+			ctx = diag.Start(ctx)
+
+			resp := next(ctx, r)
+
+			d, _ := diag.Get(ctx)
+			data, err := encodeReport(d.Report())
+			if err != nil {
+				return resp
+			}
+
+			if w := web.GetWriter(ctx); w != nil {
+				w.Header().Set(DiagnosticsHeader, string(data))
+			}
+
+			return resp
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// encodeReport marshals the report, halving the number of entries it keeps
+// until the result fits in the diagnostics header.
+func encodeReport(rpt diag.Report) ([]byte, error) {
+	n := max(len(rpt.Spans), len(rpt.Queries), len(rpt.Cache))
+
+	for {
+		data, err := json.Marshal(rpt.Limit(n))
+		if err != nil {
+			return nil, err
+		}
+
+		if len(data) <= maxDiagnosticsBytes || n == 0 {
+			return data, nil
+		}
+
+		n /= 2
+	}
+}
+
+// isDiagnosticsAdmin authenticates the caller and checks the claims for the
+// admin role. This is what auth.RuleAdminOnly evaluates, so the check is
+// done here instead of paying a second round trip to the auth service.
+func isDiagnosticsAdmin(ctx context.Context, client *authclient.Client, r *http.Request) bool {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	resp, err := client.Authenticate(ctx, r.Header.Get("authorization"))
+	if err != nil {
+		return false
+	}
+
+	return slices.Contains(resp.Claims.Roles, role.Admin.String())
+}
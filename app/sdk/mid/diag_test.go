@@ -0,0 +1,167 @@
+package mid_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/service/app/sdk/auth"
+	"github.com/ardanlabs/service/app/sdk/authclient"
+	"github.com/ardanlabs/service/app/sdk/mid"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/ardanlabs/service/foundation/diag"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/web"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// authService stands in for the auth service. It knows the callers by the
+// token in their authorization header and counts the calls made to it.
+type authService struct {
+	roles map[string][]string
+	calls atomic.Int32
+}
+
+func (as *authService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	as.calls.Add(1)
+
+	if r.URL.Path != "/v1/auth/authenticate" {
+		http.Error(w, "unexpected call", http.StatusInternalServerError)
+		return
+	}
+
+	roles, exists := as.roles[r.Header.Get("authorization")]
+	if !exists {
+		http.Error(w, "unknown caller", http.StatusInternalServerError)
+		return
+	}
+
+	resp := authclient.AuthenticateResp{
+		UserID: uuid.New(),
+		Claims: auth.Claims{Roles: roles},
+	}
+
+	json.NewEncoder(w).Encode(resp)
+}
+
+type status struct {
+	Status string `json:"status"`
+}
+
+func (s status) Encode() ([]byte, string, error) {
+	data, err := json.Marshal(s)
+	return data, "application/json", err
+}
+
+// =============================================================================
+
+func Test_Diagnostics(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.New(&buf, logger.LevelInfo, "TEST", func(context.Context) string { return "" })
+
+	as := authService{
+		roles: map[string][]string{
+			"Bearer admin": {role.Admin.String()},
+			"Bearer user":  {role.User.String()},
+		},
+	}
+
+	srv := httptest.NewServer(&as)
+	defer srv.Close()
+
+	client := authclient.New(log, srv.URL, authclient.WithClient(srv.Client()))
+
+	app := web.NewApp(log.Info, noop.NewTracerProvider().Tracer("test"), mid.Diagnostics(client))
+
+	handler := func(ctx context.Context, r *http.Request) web.Encoder {
+		n := 1
+		if r.URL.Query().Get("queries") == "many" {
+			n = 500
+		}
+
+		for i := range n {
+			diag.AddQuery(ctx, fmt.Sprintf("SELECT * FROM users WHERE user_id = :user_id -- %d", i), time.Millisecond, nil)
+		}
+
+		return status{Status: "ok"}
+	}
+
+	app.HandlerFunc(http.MethodGet, "", "/test", handler)
+
+	table := []struct {
+		name    string
+		request bool
+		caller  string
+		path    string
+		calls   int32
+		diag    bool
+		dropped bool
+	}{
+		{name: "notrequested", request: false, caller: "Bearer admin", path: "/test", calls: 0, diag: false},
+		{name: "admin", request: true, caller: "Bearer admin", path: "/test", calls: 1, diag: true},
+		{name: "user", request: true, caller: "Bearer user", path: "/test", calls: 1, diag: false},
+		{name: "unauthenticated", request: true, caller: "Bearer unknown", path: "/test", calls: 1, diag: false},
+		{name: "capped", request: true, caller: "Bearer admin", path: "/test?queries=many", calls: 1, diag: true, dropped: true},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			as.calls.Store(0)
+
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			r.Header.Set("authorization", tt.caller)
+			if tt.request {
+				r.Header.Set(mid.DiagnosticsHeader, "true")
+			}
+
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Should process the request : got %d", w.Code)
+			}
+
+			if got := as.calls.Load(); got != tt.calls {
+				t.Fatalf("Should make %d calls to the auth service : got %d", tt.calls, got)
+			}
+
+			header := w.Header().Get(mid.DiagnosticsHeader)
+
+			if !tt.diag {
+				if header != "" {
+					t.Fatalf("Should not return diagnostics : got %s", header)
+				}
+				return
+			}
+
+			if len(header) > 4<<10 {
+				t.Fatalf("Should cap the diagnostics header : got %d bytes", len(header))
+			}
+
+			var rpt diag.Report
+			if err := json.Unmarshal([]byte(header), &rpt); err != nil {
+				t.Fatalf("Should return the diagnostics as json : %s", err)
+			}
+
+			if len(rpt.Queries) == 0 || !strings.HasPrefix(rpt.Queries[0].Statement, "SELECT") {
+				t.Fatalf("Should report the queries : got %v", rpt.Queries)
+			}
+
+			if got := rpt.Dropped > 0; got != tt.dropped {
+				t.Fatalf("Should report dropped entries %t : got %d", tt.dropped, rpt.Dropped)
+			}
+
+			if tt.dropped && len(rpt.Queries)+rpt.Dropped != 500 {
+				t.Fatalf("Should account for every query : got %d kept, %d dropped", len(rpt.Queries), rpt.Dropped)
+			}
+		})
+	}
+}
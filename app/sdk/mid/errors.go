@@ -28,7 +28,10 @@ func Errors(log *logger.Logger) web.MidFunc {
 
 			var appErr *errs.Error
 			if !errors.As(err, &appErr) {
-				appErr = errs.Newf(errs.Internal, "Internal Server Error")
+				var ok bool
+				if appErr, ok = errs.FromBus(err); !ok {
+					appErr = errs.Newf(errs.Internal, "Internal Server Error")
+				}
 			}
 
 			log.Error(ctx, "handled error during request",
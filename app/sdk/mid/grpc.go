@@ -0,0 +1,263 @@
+package mid
+
+import (
+	"context"
+	"errors"
+	"path"
+	"runtime/debug"
+	"time"
+
+	"github.com/ardanlabs/service/app/sdk/authclient"
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/extid"
+	"github.com/ardanlabs/service/app/sdk/metrics"
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/otel"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// The grpc api runs the same middleware as the web api. The interceptors
+// below are chained on the server and apply to every call, authentication
+// and authorization differ between methods so the handlers call them.
+
+// UnaryOtel starts the otel tracing for the call, continuing the trace of
+// the caller, and stores the trace id in the context.
+func UnaryOtel(tracer trace.Tracer) grpc.UnaryServerInterceptor {
+	i := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		carrier := make(map[string]string)
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			for key, values := range md {
+				if len(values) > 0 {
+					carrier[key] = values[0]
+				}
+			}
+		}
+
+		if sc := otel.ExtractCarrier(carrier); sc.IsValid() {
+			ctx = trace.ContextWithRemoteSpanContext(ctx, sc)
+		}
+
+		ctx, span := tracer.Start(ctx, info.FullMethod, trace.WithSpanKind(trace.SpanKindServer))
+		defer span.End()
+
+		ctx = otel.InjectTracing(ctx, tracer)
+
+		return handler(ctx, req)
+	}
+
+	return i
+}
+
+// UnaryLogger writes information about the call to the logs.
+func UnaryLogger(log *logger.Logger) grpc.UnaryServerInterceptor {
+	i := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		now := time.Now()
+
+		var remoteAddr string
+		if p, ok := peer.FromContext(ctx); ok {
+			remoteAddr = p.Addr.String()
+		}
+
+		log.Info(ctx, "request started", "method", info.FullMethod, "remoteaddr", remoteAddr)
+
+		resp, err := handler(ctx, req)
+
+		log.Info(ctx, "request completed", "method", info.FullMethod, "remoteaddr", remoteAddr,
+			"statuscode", status.Code(err), "since", time.Since(now).String())
+
+		return resp, err
+	}
+
+	return i
+}
+
+// UnaryErrors handles errors coming out of the call chain. The app error
+// returned carries the grpc status code that matches it.
+func UnaryErrors(log *logger.Logger) grpc.UnaryServerInterceptor {
+	i := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+
+		_, span := otel.AddSpan(ctx, "app.sdk.mid.error")
+		span.RecordError(err)
+		defer span.End()
+
+		var appErr *errs.Error
+		if !errors.As(err, &appErr) {
+			var ok bool
+			if appErr, ok = errs.FromBus(err); !ok {
+				appErr = errs.Newf(errs.Internal, "Internal Server Error")
+			}
+		}
+
+		log.Error(ctx, "handled error during request",
+			"err", err,
+			"source_err_file", path.Base(appErr.FileName),
+			"source_err_func", path.Base(appErr.FuncName))
+
+		if appErr.Code == errs.InternalOnlyLog {
+			appErr = errs.Newf(errs.Internal, "Internal Server Error")
+		}
+
+		return nil, appErr
+	}
+
+	return i
+}
+
+// UnaryMetrics updates program counters.
+func UnaryMetrics() grpc.UnaryServerInterceptor {
+	i := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx = metrics.Set(ctx)
+
+		resp, err := handler(ctx, req)
+
+		n := metrics.AddRequests(ctx)
+
+		if n%1000 == 0 {
+			metrics.AddGoroutines(ctx)
+		}
+
+		if err != nil {
+			metrics.AddErrors(ctx)
+		}
+
+		return resp, err
+	}
+
+	return i
+}
+
+// UnaryPanics recovers from panics and converts the panic to an error so it
+// is reported in UnaryMetrics and handled in UnaryErrors.
+func UnaryPanics() grpc.UnaryServerInterceptor {
+	i := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				trace := debug.Stack()
+				err = errs.Newf(errs.InternalOnlyLog, "PANIC [%v] TRACE[%s]", rec, string(trace))
+
+				metrics.AddPanics(ctx)
+			}
+		}()
+
+		return handler(ctx, req)
+	}
+
+	return i
+}
+
+// =============================================================================
+
+// AuthenticateCall validates the credentials in the authorization metadata
+// of a grpc call with the auth service, the same as Authenticate, and
+// returns the context with the user data attached.
+func AuthenticateCall(ctx context.Context, client *authclient.Client) (context.Context, error) {
+	actx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	resp, err := client.Authenticate(actx, IncomingMetadata(ctx, "authorization"))
+	if err != nil {
+		return ctx, errs.New(errs.Unauthenticated, err)
+	}
+
+	ctx = setUserID(ctx, resp.UserID)
+	ctx = setClaims(ctx, resp.Claims)
+
+	return ctx, nil
+}
+
+// AuthorizeCall validates the authenticated caller of a grpc call against
+// the rule, the same as Authorize.
+func AuthorizeCall(ctx context.Context, client *authclient.Client, rule string) error {
+	userID, err := GetUserID(ctx)
+	if err != nil {
+		return errs.New(errs.Unauthenticated, err)
+	}
+
+	auth := authclient.Authorize{
+		Claims: GetClaims(ctx),
+		UserID: userID,
+		Rule:   rule,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := client.Authorize(ctx, auth); err != nil {
+		return errs.New(errs.Unauthenticated, err)
+	}
+
+	return nil
+}
+
+// AuthorizeUserCall extracts the user with the specified id and validates
+// the authenticated caller of a grpc call against the rule, the same as
+// AuthorizeUser. The returned context holds the user for GetUser.
+func AuthorizeUserCall(ctx context.Context, client *authclient.Client, userBus userbus.Business, id string, rule string) (context.Context, error) {
+	var userID uuid.UUID
+
+	if id != "" {
+		var err error
+		userID, err = extid.Decode(id)
+		if err != nil {
+			return ctx, errs.New(errs.Unauthenticated, ErrInvalidID)
+		}
+
+		usr, err := userBus.QueryByID(ctx, userID)
+		if err != nil {
+			switch {
+			case errors.Is(err, userbus.ErrNotFound):
+				return ctx, errs.New(errs.Unauthenticated, err)
+			default:
+				return ctx, errs.Newf(errs.Unauthenticated, "querybyid: userID[%s]: %s", userID, err)
+			}
+		}
+
+		ctx = setUser(ctx, usr)
+	}
+
+	actx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	auth := authclient.Authorize{
+		Claims: GetClaims(ctx),
+		UserID: userID,
+		Rule:   rule,
+	}
+
+	if err := client.Authorize(actx, auth); err != nil {
+		return ctx, errs.New(errs.Unauthenticated, err)
+	}
+
+	return ctx, nil
+}
+
+// RequireRecentAuthCall validates the authenticated caller of a grpc call
+// has authenticated within maxAge, the same as RequireRecentAuth.
+func RequireRecentAuthCall(ctx context.Context, maxAge time.Duration, mfa bool) error {
+	if err := checkRecentAuth(GetClaims(ctx), maxAge, mfa); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// IncomingMetadata returns the first value of the key in the metadata sent
+// with a grpc call.
+func IncomingMetadata(ctx context.Context, key string) string {
+	values := metadata.ValueFromIncomingContext(ctx, key)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
@@ -3,13 +3,15 @@ package mid
 
 import (
 	"context"
-	"errors"
 
 	"github.com/ardanlabs/service/app/sdk/auth"
+	"github.com/ardanlabs/service/business/domain/apikeybus"
 	"github.com/ardanlabs/service/business/domain/homebus"
 	"github.com/ardanlabs/service/business/domain/productbus"
 	"github.com/ardanlabs/service/business/domain/userbus"
 	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/business/sdk/tenant"
+	"github.com/ardanlabs/service/foundation/ctxval"
 	"github.com/ardanlabs/service/foundation/web"
 	"github.com/google/uuid"
 )
@@ -25,28 +27,32 @@ func isError(e web.Encoder) error {
 
 // =============================================================================
 
-type ctxKey int
-
-const (
-	claimKey ctxKey = iota + 1
-	userIDKey
-	userKey
-	productKey
-	homeKey
-	trKey
+var (
+	claimKey   = ctxval.NewKey[auth.Claims]("claims")
+	userIDKey  = ctxval.NewKey[uuid.UUID]("user id")
+	userKey    = ctxval.NewKey[userbus.User]("user")
+	productKey = ctxval.NewKey[productbus.Product]("product")
+	homeKey    = ctxval.NewKey[homebus.Home]("home")
+	apiKeyKey  = ctxval.NewKey[apikeybus.Key]("api key")
+	trKey      = ctxval.NewKey[sqldb.CommitRollbacker]("transaction")
 )
 
 func setClaims(ctx context.Context, claims auth.Claims) context.Context {
-	return context.WithValue(ctx, claimKey, claims)
+	ctx = claimKey.Set(ctx, claims)
+
+	// Break-glass tokens aren't bound to a tenant and see every tenant.
+	if !claims.BreakGlass {
+		if tenantID, err := claims.TenantID(); err == nil {
+			ctx = tenant.With(ctx, tenantID)
+		}
+	}
+
+	return ctx
 }
 
 // GetClaims returns the claims from the context.
 func GetClaims(ctx context.Context) auth.Claims {
-	v, ok := ctx.Value(claimKey).(auth.Claims)
-	if !ok {
-		return auth.Claims{}
-	}
-	return v
+	return claimKey.Value(ctx)
 }
 
 // GetSubjectID returns the subject id from the claims.
@@ -62,71 +68,55 @@ func GetSubjectID(ctx context.Context) uuid.UUID {
 }
 
 func setUserID(ctx context.Context, userID uuid.UUID) context.Context {
-	return context.WithValue(ctx, userIDKey, userID)
+	return userIDKey.Set(ctx, userID)
 }
 
 // GetUserID returns the user id from the context.
 func GetUserID(ctx context.Context) (uuid.UUID, error) {
-	v, ok := ctx.Value(userIDKey).(uuid.UUID)
-	if !ok {
-		return uuid.UUID{}, errors.New("user id not found in context")
-	}
-
-	return v, nil
+	return userIDKey.Require(ctx)
 }
 
 func setUser(ctx context.Context, usr userbus.User) context.Context {
-	return context.WithValue(ctx, userKey, usr)
+	return userKey.Set(ctx, usr)
 }
 
 // GetUser returns the user from the context.
 func GetUser(ctx context.Context) (userbus.User, error) {
-	v, ok := ctx.Value(userKey).(userbus.User)
-	if !ok {
-		return userbus.User{}, errors.New("user not found in context")
-	}
-
-	return v, nil
+	return userKey.Require(ctx)
 }
 
 func setProduct(ctx context.Context, prd productbus.Product) context.Context {
-	return context.WithValue(ctx, productKey, prd)
+	return productKey.Set(ctx, prd)
 }
 
 // GetProduct returns the product from the context.
 func GetProduct(ctx context.Context) (productbus.Product, error) {
-	v, ok := ctx.Value(productKey).(productbus.Product)
-	if !ok {
-		return productbus.Product{}, errors.New("product not found in context")
-	}
-
-	return v, nil
+	return productKey.Require(ctx)
 }
 
 func setHome(ctx context.Context, hme homebus.Home) context.Context {
-	return context.WithValue(ctx, homeKey, hme)
+	return homeKey.Set(ctx, hme)
 }
 
 // GetHome returns the home from the context.
 func GetHome(ctx context.Context) (homebus.Home, error) {
-	v, ok := ctx.Value(homeKey).(homebus.Home)
-	if !ok {
-		return homebus.Home{}, errors.New("home not found in context")
-	}
+	return homeKey.Require(ctx)
+}
 
-	return v, nil
+func setAPIKey(ctx context.Context, key apikeybus.Key) context.Context {
+	return apiKeyKey.Set(ctx, key)
+}
+
+// GetAPIKey returns the api key from the context.
+func GetAPIKey(ctx context.Context) (apikeybus.Key, error) {
+	return apiKeyKey.Require(ctx)
 }
 
 func setTran(ctx context.Context, tx sqldb.CommitRollbacker) context.Context {
-	return context.WithValue(ctx, trKey, tx)
+	return trKey.Set(ctx, tx)
 }
 
 // GetTran retrieves the value that can manage a transaction.
 func GetTran(ctx context.Context) (sqldb.CommitRollbacker, error) {
-	v, ok := ctx.Value(trKey).(sqldb.CommitRollbacker)
-	if !ok {
-		return nil, errors.New("transaction not found in context")
-	}
-
-	return v, nil
+	return trKey.Require(ctx)
 }
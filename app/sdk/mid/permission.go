@@ -0,0 +1,39 @@
+package mid
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/business/domain/permissionbus"
+	"github.com/ardanlabs/service/foundation/web"
+)
+
+// RequirePermission allows the request through when the authenticated user
+// holds the named permission through any of its roles. It runs after
+// Authenticate and can stand in for or add to a role based rule.
+func RequirePermission(permissionBus *permissionbus.Business, name string) web.MidFunc {
+	m := func(next web.HandlerFunc) web.HandlerFunc {
+		h := func(ctx context.Context, r *http.Request) web.Encoder {
+			userID, err := GetUserID(ctx)
+			if err != nil {
+				return errs.New(errs.Unauthenticated, err)
+			}
+
+			ok, err := permissionBus.HasPermission(ctx, userID, name)
+			if err != nil {
+				return errs.Newf(errs.Internal, "haspermission: userID[%s] permission[%s]: %s", userID, name, err)
+			}
+
+			if !ok {
+				return errs.Newf(errs.PermissionDenied, "permission[%s] is required", name)
+			}
+
+			return next(ctx, r)
+		}
+
+		return h
+	}
+
+	return m
+}
@@ -0,0 +1,87 @@
+package mid
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/foundation/limiter"
+	"github.com/ardanlabs/service/foundation/web"
+)
+
+// Set of headers describing the caller's rate limit state. They are set on
+// every response.
+const (
+	RateLimitLimitHeader     = "RateLimit-Limit"
+	RateLimitRemainingHeader = "RateLimit-Remaining"
+	RateLimitResetHeader     = "RateLimit-Reset"
+)
+
+// RateLimit rejects requests once the caller has exhausted the requests
+// allowed in the current window. The caller's state is reported on every
+// response.
+func RateLimit(l *limiter.Limiter) web.MidFunc {
+	m := func(next web.HandlerFunc) web.HandlerFunc {
+		h := func(ctx context.Context, r *http.Request) web.Encoder {
+			status := l.Allow(RateLimitKey(r))
+
+			if w := web.GetWriter(ctx); w != nil {
+				setRateLimitHeaders(w.Header(), status)
+
+				if !status.Allowed {
+					w.Header().Set("Retry-After", resetSeconds(status.Reset))
+				}
+			}
+
+			if !status.Allowed {
+				return errs.Newf(errs.TooManyRequests, "rate limit of %d requests exceeded", status.Limit)
+			}
+
+			return next(ctx, r)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// RateLimitKey identifies the caller a request is counted against. Callers
+// presenting credentials are tracked by those credentials, everyone else by
+// their remote address.
+func RateLimitKey(r *http.Request) string {
+	if authz := r.Header.Get("authorization"); authz != "" {
+		sum := sha256.Sum256([]byte(authz))
+		return "authz:" + hex.EncodeToString(sum[:16])
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return "addr:" + host
+}
+
+// setRateLimitHeaders writes the rate limit state to the response headers.
+func setRateLimitHeaders(h http.Header, status limiter.Status) {
+	h.Set(RateLimitLimitHeader, strconv.Itoa(status.Limit))
+	h.Set(RateLimitRemainingHeader, strconv.Itoa(status.Remaining))
+	h.Set(RateLimitResetHeader, resetSeconds(status.Reset))
+}
+
+// resetSeconds returns the number of seconds until the reset, rounded up so
+// a caller waiting that long will find a new window.
+func resetSeconds(reset time.Time) string {
+	d := time.Until(reset)
+	if d < 0 {
+		d = 0
+	}
+
+	return strconv.Itoa(int((d + time.Second - 1) / time.Second))
+}
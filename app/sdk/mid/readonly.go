@@ -0,0 +1,28 @@
+package mid
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/foundation/web"
+)
+
+// ReadOnly rejects any request that could change state. It's used when the
+// service is running as a read replica.
+func ReadOnly() web.MidFunc {
+	m := func(next web.HandlerFunc) web.HandlerFunc {
+		h := func(ctx context.Context, r *http.Request) web.Encoder {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				return next(ctx, r)
+			}
+
+			return errs.Newf(errs.Unavailable, "%s %s: service is running in read-only mode", r.Method, r.URL.Path)
+		}
+
+		return h
+	}
+
+	return m
+}
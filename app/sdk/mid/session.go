@@ -0,0 +1,24 @@
+package mid
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/foundation/web"
+)
+
+// ReadYourWrites starts a database session for each request so the reads
+// made after a write in the same request go to the primary database instead
+// of a replica that may not have the write yet.
+func ReadYourWrites() web.MidFunc {
+	m := func(next web.HandlerFunc) web.HandlerFunc {
+		h := func(ctx context.Context, r *http.Request) web.Encoder {
+			return next(sqldb.WithSession(ctx), r)
+		}
+
+		return h
+	}
+
+	return m
+}
@@ -0,0 +1,83 @@
+package mid
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ardanlabs/service/foundation/limiter"
+	"github.com/ardanlabs/service/foundation/web"
+)
+
+// LoginThrottle delays login attempts after failures instead of locking the
+// account out. Failures are tracked per account and per remote address so
+// both a single account being guessed and a single address trying many
+// accounts slow down. A nil backoff disables that side of the throttle.
+type LoginThrottle struct {
+	Account *limiter.Backoff
+	Addr    *limiter.Backoff
+}
+
+// wait returns how long the caller must wait before trying again.
+func (lt LoginThrottle) wait(account string, addr string) time.Duration {
+	var d time.Duration
+
+	if lt.Account != nil {
+		d = max(d, lt.Account.Wait(account))
+	}
+
+	if lt.Addr != nil {
+		d = max(d, lt.Addr.Wait(addr))
+	}
+
+	return d
+}
+
+// fail records a failed attempt and returns how long the caller must wait
+// before trying again.
+func (lt LoginThrottle) fail(account string, addr string) time.Duration {
+	var d time.Duration
+
+	if lt.Account != nil {
+		d = max(d, lt.Account.Fail(account))
+	}
+
+	if lt.Addr != nil {
+		d = max(d, lt.Addr.Fail(addr))
+	}
+
+	return d
+}
+
+// succeed forgets the failures for the account. The failures for the
+// address are kept since a valid login says nothing about the other
+// accounts being tried from it.
+func (lt LoginThrottle) succeed(account string) {
+	if lt.Account != nil {
+		lt.Account.Reset(account)
+	}
+}
+
+// throttleKeys returns the keys the attempt is tracked by.
+func throttleKeys(r *http.Request, email string) (string, string) {
+	return "account:" + strings.ToLower(email), "addr:" + remoteHost(r)
+}
+
+// remoteHost returns the address the request came from without the port.
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// setRetryAfter tells the caller how long to wait before trying again.
+func setRetryAfter(ctx context.Context, d time.Duration) {
+	if w := web.GetWriter(ctx); w != nil && d > 0 {
+		w.Header().Set("Retry-After", resetSeconds(time.Now().Add(d)))
+	}
+}
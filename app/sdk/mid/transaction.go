@@ -12,7 +12,9 @@ import (
 	"github.com/ardanlabs/service/foundation/web"
 )
 
-// BeginCommitRollback starts a transaction for the domain call.
+// BeginCommitRollback starts a transaction for the domain call. The tenant
+// and user of the call are set on the transaction for the row-level security
+// policies, along with the statement timeout the context carries.
 func BeginCommitRollback(log *logger.Logger, bgn sqldb.Beginner) web.MidFunc {
 	m := func(next web.HandlerFunc) web.HandlerFunc {
 		h := func(ctx context.Context, r *http.Request) web.Encoder {
@@ -37,6 +39,16 @@ func BeginCommitRollback(log *logger.Logger, bgn sqldb.Beginner) web.MidFunc {
 				}
 			}()
 
+			if err := sqldb.SetCurrent(ctx, tx, GetSubjectID(ctx)); err != nil {
+				return errs.Newf(errs.Internal, "BEGIN TRANSACTION: %s", err)
+			}
+
+			if timeout, ok := sqldb.StatementTimeout(ctx); ok {
+				if err := sqldb.SetStatementTimeout(ctx, tx, timeout); err != nil {
+					return errs.Newf(errs.Internal, "BEGIN TRANSACTION: %s", err)
+				}
+			}
+
 			ctx = setTran(ctx, tx)
 
 			resp := next(ctx, r)
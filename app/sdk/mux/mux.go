@@ -5,15 +5,29 @@ package mux
 import (
 	"embed"
 	"net/http"
+	"time"
 
 	"github.com/ardanlabs/service/app/sdk/auth"
 	"github.com/ardanlabs/service/app/sdk/authclient"
 	"github.com/ardanlabs/service/app/sdk/mid"
+	"github.com/ardanlabs/service/app/sdk/stream"
+	"github.com/ardanlabs/service/business/domain/apikeybus"
 	"github.com/ardanlabs/service/business/domain/auditbus"
+	"github.com/ardanlabs/service/business/domain/groupbus"
 	"github.com/ardanlabs/service/business/domain/homebus"
+	"github.com/ardanlabs/service/business/domain/invitebus"
+	"github.com/ardanlabs/service/business/domain/notificationbus"
+	"github.com/ardanlabs/service/business/domain/permissionbus"
 	"github.com/ardanlabs/service/business/domain/productbus"
+	"github.com/ardanlabs/service/business/domain/reportbus"
+	"github.com/ardanlabs/service/business/domain/searchbus"
+	"github.com/ardanlabs/service/business/domain/sessionbus"
+	"github.com/ardanlabs/service/business/domain/templatebus"
 	"github.com/ardanlabs/service/business/domain/userbus"
 	"github.com/ardanlabs/service/business/domain/vproductbus"
+	"github.com/ardanlabs/service/business/domain/webhookbus"
+	"github.com/ardanlabs/service/business/sdk/delegate"
+	"github.com/ardanlabs/service/foundation/limiter"
 	"github.com/ardanlabs/service/foundation/logger"
 	"github.com/ardanlabs/service/foundation/web"
 	"github.com/jmoiron/sqlx"
@@ -32,6 +46,10 @@ type StaticSite struct {
 type Options struct {
 	corsOrigin []string
 	sites      []StaticSite
+	diagClient *authclient.Client
+	limiter    *limiter.Limiter
+	readOnly   bool
+	sessions   bool
 }
 
 // WithCORS provides configuration options for CORS.
@@ -41,6 +59,39 @@ func WithCORS(origins []string) func(opts *Options) {
 	}
 }
 
+// WithDiagnostics enables admins to request execution diagnostics for a
+// request using the diagnostics header. The client is used to verify the
+// caller is an admin.
+func WithDiagnostics(client *authclient.Client) func(opts *Options) {
+	return func(opts *Options) {
+		opts.diagClient = client
+	}
+}
+
+// WithRateLimit limits the number of requests each caller can make in a
+// window and reports the caller's state in the response headers.
+func WithRateLimit(l *limiter.Limiter) func(opts *Options) {
+	return func(opts *Options) {
+		opts.limiter = l
+	}
+}
+
+// WithReadOnly rejects every request that could change state so the service
+// can run as a read replica.
+func WithReadOnly() func(opts *Options) {
+	return func(opts *Options) {
+		opts.readOnly = true
+	}
+}
+
+// WithReadYourWrites makes the reads in a request that follow a write go to
+// the primary database when the stores use a cluster with read replicas.
+func WithReadYourWrites() func(opts *Options) {
+	return func(opts *Options) {
+		opts.sessions = true
+	}
+}
+
 // WithFileServer provides configuration options for file server.
 func WithFileServer(react bool, static embed.FS, dir string, path string) func(opts *Options) {
 	return func(opts *Options) {
@@ -55,20 +106,48 @@ func WithFileServer(react bool, static embed.FS, dir string, path string) func(o
 
 // SalesConfig contains sales service specific config.
 type SalesConfig struct {
-	AuthClient *authclient.Client
+	AuthClient      *authclient.Client
+	Delegate        *delegate.Delegate
+	Limiter         *limiter.Limiter
+	ClockSkew       bool
+	Events          *stream.Hub
+	EventsHeartbeat time.Duration
 }
 
 // AuthConfig contains auth service specific config.
 type AuthConfig struct {
-	Auth *auth.Auth
+	Auth          *auth.Auth
+	LoginThrottle mid.LoginThrottle
+	AccessTTL     time.Duration
+	WellKnown     WellKnownConfig
+}
+
+// WellKnownConfig contains the config for the well-known discovery
+// endpoints. An endpoint whose setting is empty isn't served.
+type WellKnownConfig struct {
+	BaseURL           string
+	Domain            string
+	Issuer            string
+	TokenKID          string
+	ChangePasswordURL string
 }
 
 type BusConfig struct {
-	AuditBus    *auditbus.Business
-	UserBus     userbus.Business
-	ProductBus  *productbus.Business
-	HomeBus     *homebus.Business
-	VProductBus *vproductbus.Business
+	APIKeyBus       *apikeybus.Business
+	AuditBus        *auditbus.Business
+	UserBus         userbus.Business
+	ProductBus      *productbus.Business
+	HomeBus         *homebus.Business
+	GroupBus        *groupbus.Business
+	InviteBus       *invitebus.Business
+	NotificationBus *notificationbus.Business
+	PermissionBus   *permissionbus.Business
+	VProductBus     *vproductbus.Business
+	ReportBus       *reportbus.Business
+	SearchBus       *searchbus.Business
+	SessionBus      *sessionbus.Business
+	TemplateBus     *templatebus.Business
+	WebhookBus      *webhookbus.Business
 }
 
 // Config contains all the mandatory systems required by handlers.
@@ -90,21 +169,45 @@ type RouteAdder interface {
 
 // WebAPI constructs a http.Handler with all application routes bound.
 func WebAPI(cfg Config, routeAdder RouteAdder, options ...func(opts *Options)) http.Handler {
+	var opts Options
+	for _, option := range options {
+		option(&opts)
+	}
+
+	var diagnostics web.MidFunc
+	if opts.diagClient != nil {
+		diagnostics = mid.Diagnostics(opts.diagClient)
+	}
+
+	var rateLimit web.MidFunc
+	if opts.limiter != nil {
+		rateLimit = mid.RateLimit(opts.limiter)
+	}
+
+	var readOnly web.MidFunc
+	if opts.readOnly {
+		readOnly = mid.ReadOnly()
+	}
+
+	var sessions web.MidFunc
+	if opts.sessions {
+		sessions = mid.ReadYourWrites()
+	}
+
 	app := web.NewApp(
 		cfg.Log.Info,
 		cfg.Tracer,
 		mid.Otel(cfg.Tracer),
+		diagnostics,
 		mid.Logger(cfg.Log),
 		mid.Errors(cfg.Log),
 		mid.Metrics(),
+		rateLimit,
+		readOnly,
+		sessions,
 		mid.Panics(),
 	)
 
-	var opts Options
-	for _, option := range options {
-		option(&opts)
-	}
-
 	if len(opts.corsOrigin) > 0 {
 		app.EnableCORS(opts.corsOrigin)
 	}
@@ -0,0 +1,255 @@
+// Package openapi generates an OpenAPI 3 document from the routes added to
+// an app and the models their handlers decode and encode. The routes come
+// from the app itself, so a route that isn't described still shows up in
+// the document with its path parameters.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/foundation/web"
+)
+
+// Param represents a query string or header parameter.
+type Param struct {
+	Name        string
+	Description string
+	Type        string
+	Required    bool
+}
+
+// Operation describes what a route expects and returns. Request and
+// Response hold a value of the model the handler decodes and encodes, nil
+// when there isn't one. The status is taken from the response when it's
+// left as zero.
+type Operation struct {
+	Summary  string
+	Tags     []string
+	Query    []Param
+	Headers  []Param
+	Request  any
+	Response any
+	Status   int
+	Public   bool
+}
+
+// Document collects the operations that describe the routes of an app.
+type Document struct {
+	title   string
+	version string
+	ops     map[string]Operation
+}
+
+// New constructs a document with the title and version reported in its
+// info section.
+func New(title string, version string) *Document {
+	return &Document{
+		title:   title,
+		version: version,
+		ops:     make(map[string]Operation),
+	}
+}
+
+// Add describes the route with the method, group and path it was added to
+// the app with.
+func (d *Document) Add(method string, group string, path string, op Operation) {
+	if group != "" {
+		path = "/" + group + path
+	}
+
+	d.ops[method+" "+path] = op
+}
+
+// Build returns the document in JSON for the set of routes.
+func (d *Document) Build(routes []web.Route) ([]byte, error) {
+	s := newSchemas()
+
+	paths := make(map[string]map[string]any)
+	for _, route := range routes {
+		path, params := pathParams(route.Path)
+
+		item, exists := paths[path]
+		if !exists {
+			item = make(map[string]any)
+			paths[path] = item
+		}
+
+		op, described := d.ops[route.Method+" "+route.Path]
+		if !described {
+			item[strings.ToLower(route.Method)] = map[string]any{
+				"operationId": operationID(route),
+				"parameters":  params,
+				"responses": map[string]any{
+					"default": map[string]any{"description": "Response"},
+				},
+			}
+			continue
+		}
+
+		item[strings.ToLower(route.Method)] = d.operation(s, route, op, params)
+	}
+
+	doc := map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   d.title,
+			"version": d.version,
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": s.defs,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{
+					"type":         "http",
+					"scheme":       "bearer",
+					"bearerFormat": "JWT",
+				},
+			},
+		},
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("marshal: %w", err)
+	}
+
+	return data, nil
+}
+
+func (d *Document) operation(s *schemas, route web.Route, op Operation, params []any) map[string]any {
+	for _, p := range op.Query {
+		params = append(params, param("query", p))
+	}
+
+	for _, p := range op.Headers {
+		params = append(params, param("header", p))
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+
+		switch v := op.Response.(type) {
+		case nil:
+			status = http.StatusNoContent
+		case interface{ HTTPStatus() int }:
+			status = v.HTTPStatus()
+		}
+	}
+
+	resp := map[string]any{
+		"description": http.StatusText(status),
+	}
+
+	if op.Response != nil {
+		resp["content"] = content(s.of(op.Response))
+	}
+
+	o := map[string]any{
+		"operationId": operationID(route),
+		"parameters":  params,
+		"responses": map[string]any{
+			strconv.Itoa(status): resp,
+			"default": map[string]any{
+				"description": "Error",
+				"content":     content(s.of(errs.Error{})),
+			},
+		},
+	}
+
+	if op.Summary != "" {
+		o["summary"] = op.Summary
+	}
+
+	if len(op.Tags) > 0 {
+		o["tags"] = op.Tags
+	}
+
+	if op.Request != nil {
+		o["requestBody"] = map[string]any{
+			"required": true,
+			"content":  content(s.of(op.Request)),
+		}
+	}
+
+	if !op.Public {
+		o["security"] = []any{
+			map[string]any{"bearerAuth": []string{}},
+		}
+	}
+
+	return o
+}
+
+// =============================================================================
+
+var pathParam = regexp.MustCompile(`\{(\w*)(\.\.\.)?\}`)
+
+// pathParams returns the path written the way OpenAPI expects and the
+// parameters found in it. The {$} pattern used to match the end of a path
+// is dropped.
+func pathParams(path string) (string, []any) {
+	params := []any{}
+
+	path = pathParam.ReplaceAllStringFunc(path, func(m string) string {
+		sub := pathParam.FindStringSubmatch(m)
+		if sub[1] == "" {
+			return ""
+		}
+
+		params = append(params, param("path", Param{
+			Name:     sub[1],
+			Type:     "string",
+			Required: true,
+		}))
+
+		return "{" + sub[1] + "}"
+	})
+
+	if path == "" {
+		path = "/"
+	}
+
+	return path, params
+}
+
+func param(in string, p Param) map[string]any {
+	typ := p.Type
+	if typ == "" {
+		typ = "string"
+	}
+
+	m := map[string]any{
+		"name":     p.Name,
+		"in":       in,
+		"required": p.Required,
+		"schema":   map[string]any{"type": typ},
+	}
+
+	if p.Description != "" {
+		m["description"] = p.Description
+	}
+
+	return m
+}
+
+func content(schema map[string]any) map[string]any {
+	return map[string]any{
+		"application/json": map[string]any{"schema": schema},
+	}
+}
+
+var nonWord = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// operationID derives an id from the method and path, which is what most
+// code generators use to name the client functions.
+func operationID(route web.Route) string {
+	path := strings.Trim(nonWord.ReplaceAllString(route.Path, "_"), "_")
+	return strings.ToLower(route.Method) + "_" + path
+}
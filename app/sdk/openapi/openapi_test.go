@@ -0,0 +1,130 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/service/app/sdk/openapi"
+	"github.com/ardanlabs/service/app/sdk/query"
+	"github.com/ardanlabs/service/foundation/web"
+)
+
+type widget struct {
+	ID       string            `json:"id"`
+	Name     string            `json:"name" validate:"required"`
+	Mode     string            `json:"mode" validate:"omitempty,oneof=fast slow"`
+	Tags     []string          `json:"tags,omitempty"`
+	Parent   *widget           `json:"parent,omitempty"`
+	Labels   map[string]string `json:"labels"`
+	Created  time.Time         `json:"created"`
+	Internal string            `json:"-"`
+}
+
+func Test_Build(t *testing.T) {
+	doc := openapi.New("test", "1.0")
+
+	doc.Add(http.MethodGet, "v1", "/widgets", openapi.Operation{
+		Summary:  "List widgets",
+		Query:    []openapi.Param{{Name: "page", Type: "integer"}},
+		Response: query.Result[widget]{},
+	})
+
+	doc.Add(http.MethodPost, "v1", "/widgets", openapi.Operation{
+		Request:  widget{},
+		Response: widget{},
+	})
+
+	doc.Add(http.MethodDelete, "v1", "/widgets/{widget_id}", openapi.Operation{})
+
+	routes := []web.Route{
+		{Method: http.MethodGet, Path: "/v1/widgets"},
+		{Method: http.MethodPost, Path: "/v1/widgets"},
+		{Method: http.MethodDelete, Path: "/v1/widgets/{widget_id}"},
+		{Method: http.MethodGet, Path: "/v1/files/{path...}"},
+	}
+
+	data, err := doc.Build(routes)
+	if err != nil {
+		t.Fatalf("Should be able to build the document : %s", err)
+	}
+
+	var got struct {
+		Paths map[string]map[string]struct {
+			OperationID string `json:"operationId"`
+			Parameters  []struct {
+				Name string `json:"name"`
+				In   string `json:"in"`
+			} `json:"parameters"`
+			RequestBody map[string]any            `json:"requestBody"`
+			Responses   map[string]map[string]any `json:"responses"`
+			Security    []any                     `json:"security"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]map[string]any `json:"properties"`
+				Required   []string                  `json:"required"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Should be able to unmarshal the document : %s", err)
+	}
+
+	list := got.Paths["/v1/widgets"]["get"]
+	if len(list.Parameters) != 1 || list.Parameters[0].In != "query" {
+		t.Errorf("expected the page query parameter, got %+v", list.Parameters)
+	}
+	if _, exists := list.Responses["200"]; !exists {
+		t.Errorf("expected a 200 response, got %v", list.Responses)
+	}
+	if len(list.Security) != 1 {
+		t.Errorf("expected the operation to require a token")
+	}
+
+	if got.Paths["/v1/widgets"]["post"].RequestBody == nil {
+		t.Errorf("expected a request body for the post")
+	}
+
+	del := got.Paths["/v1/widgets/{widget_id}"]["delete"]
+	if _, exists := del.Responses["204"]; !exists {
+		t.Errorf("expected a 204 response, got %v", del.Responses)
+	}
+	if len(del.Parameters) != 1 || del.Parameters[0].Name != "widget_id" || del.Parameters[0].In != "path" {
+		t.Errorf("expected the widget_id path parameter, got %+v", del.Parameters)
+	}
+
+	files, exists := got.Paths["/v1/files/{path}"]["get"]
+	if !exists {
+		t.Fatalf("expected the undescribed route to be in the document")
+	}
+	if files.OperationID != "get_v1_files_path" {
+		t.Errorf("expected operation id get_v1_files_path, got %s", files.OperationID)
+	}
+
+	w, exists := got.Components.Schemas["widget"]
+	if !exists {
+		t.Fatalf("expected a widget schema, got %v", got.Components.Schemas)
+	}
+	if len(w.Required) != 1 || w.Required[0] != "name" {
+		t.Errorf("expected name to be required, got %v", w.Required)
+	}
+	if _, exists := w.Properties["Internal"]; exists {
+		t.Errorf("expected fields tagged - to be left out")
+	}
+	if w.Properties["parent"]["$ref"] != "#/components/schemas/widget" {
+		t.Errorf("expected parent to refer to widget, got %v", w.Properties["parent"])
+	}
+	if w.Properties["created"]["format"] != "date-time" {
+		t.Errorf("expected created to be a date-time, got %v", w.Properties["created"])
+	}
+	if enum, _ := w.Properties["mode"]["enum"].([]any); len(enum) != 2 {
+		t.Errorf("expected mode to be an enum, got %v", w.Properties["mode"])
+	}
+
+	if _, exists := got.Components.Schemas["Resultwidget"]; !exists {
+		t.Errorf("expected the generic result schema, got %v", got.Components.Schemas)
+	}
+}
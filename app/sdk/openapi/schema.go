@@ -0,0 +1,184 @@
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType      = reflect.TypeFor[time.Time]()
+	rawType       = reflect.TypeFor[json.RawMessage]()
+	marshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// schemas builds the schemas of the models, following the rules
+// encoding/json uses to marshal them. Named structs are added to the
+// components of the document and referenced from where they're used.
+type schemas struct {
+	defs  map[string]any
+	names map[reflect.Type]string
+}
+
+func newSchemas() *schemas {
+	return &schemas{
+		defs:  make(map[string]any),
+		names: make(map[reflect.Type]string),
+	}
+}
+
+func (s *schemas) of(v any) map[string]any {
+	return s.schema(reflect.TypeOf(v))
+}
+
+func (s *schemas) schema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+
+	case t == rawType:
+		return map[string]any{}
+
+	case t.Implements(marshalerType) || reflect.PointerTo(t).Implements(marshalerType):
+		return map[string]any{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer"}
+
+	case reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+
+	case reflect.String:
+		return map[string]any{"type": "string"}
+
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": s.schema(t.Elem())}
+
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": s.schema(t.Elem())}
+
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + s.define(t)}
+	}
+
+	return map[string]any{}
+}
+
+// define adds the struct to the components the first time it's seen and
+// returns the name it was added with.
+func (s *schemas) define(t reflect.Type) string {
+	if name, exists := s.names[t]; exists {
+		return name
+	}
+
+	name := schemaName(t)
+	for i := 2; ; i++ {
+		if _, exists := s.defs[name]; !exists {
+			break
+		}
+		name = schemaName(t) + strconv.Itoa(i)
+	}
+
+	// The name is recorded before the fields are walked so a struct that
+	// refers to itself ends up with a reference instead of a loop.
+	s.names[t] = name
+	s.defs[name] = nil
+	s.defs[name] = s.object(t)
+
+	return name
+}
+
+func (s *schemas) object(t reflect.Type) map[string]any {
+	props := make(map[string]any)
+	var required []string
+
+	s.fields(t, props, &required)
+
+	obj := map[string]any{
+		"type":       "object",
+		"properties": props,
+	}
+
+	if len(required) > 0 {
+		obj["required"] = required
+	}
+
+	return obj
+}
+
+func (s *schemas) fields(t reflect.Type, props map[string]any, required *[]string) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, _, _ := strings.Cut(tag, ",")
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				s.fields(ft, props, required)
+				continue
+			}
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		schema := s.schema(f.Type)
+
+		for _, rule := range strings.Split(f.Tag.Get("validate"), ",") {
+			switch {
+			case rule == "required":
+				*required = append(*required, name)
+
+			case strings.HasPrefix(rule, "oneof="):
+				schema["enum"] = strings.Fields(strings.TrimPrefix(rule, "oneof="))
+			}
+		}
+
+		props[name] = schema
+	}
+}
+
+var qualifier = regexp.MustCompile(`[\w./-]*\.`)
+
+// schemaName returns the name of the type without package paths, so a
+// generic type like query.Result[userapp.User] becomes ResultUser.
+func schemaName(t reflect.Type) string {
+	name := qualifier.ReplaceAllString(t.Name(), "")
+	return nonWord.ReplaceAllString(name, "")
+}
@@ -7,12 +7,15 @@ import (
 	"github.com/ardanlabs/service/business/sdk/page"
 )
 
-// Result is the data model used when returning a query result.
+// Result is the data model used when returning a query result. Approximate
+// is set when the total is an estimate or was cached and may be out of date.
 type Result[T any] struct {
-	Items       []T `json:"items"`
-	Total       int `json:"total"`
-	Page        int `json:"page"`
-	RowsPerPage int `json:"rowsPerPage"`
+	Items       []T    `json:"items"`
+	Total       int    `json:"total"`
+	Approximate bool   `json:"approximate,omitempty"`
+	Page        int    `json:"page"`
+	RowsPerPage int    `json:"rowsPerPage"`
+	NextCursor  string `json:"nextCursor,omitempty"`
 }
 
 // NewResult constructs a result value to return query results.
@@ -25,6 +28,15 @@ func NewResult[T any](items []T, total int, page page.Page) Result[T] {
 	}
 }
 
+// NewCursorResult constructs a result value to return keyset query results
+// along with the cursor for the next page.
+func NewCursorResult[T any](items []T, total int, page page.Page, nextCursor string) Result[T] {
+	r := NewResult(items, total, page)
+	r.NextCursor = nextCursor
+
+	return r
+}
+
 // Encode implements the encoder interface.
 func (r Result[T]) Encode() ([]byte, string, error) {
 	data, err := json.Marshal(r)
@@ -0,0 +1,140 @@
+// Package rpc provides support for serving the app layer over grpc.
+//
+// Messages aren't generated by protoc. Each message type encodes itself in
+// the protobuf wire format following the definitions in the .proto file
+// kept next to it, the same way the delegate package publishes events, so
+// clients generated from those definitions can call the services.
+package rpc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ardanlabs/service/app/sdk/mid"
+	"github.com/ardanlabs/service/foundation/logger"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+)
+
+// Message represents a request or response that encodes itself in the
+// protobuf wire format.
+type Message interface {
+	MarshalProto() ([]byte, error)
+	UnmarshalProto(data []byte) error
+}
+
+// Config contains all the mandatory systems required by the server.
+type Config struct {
+	Log    *logger.Logger
+	Tracer trace.Tracer
+}
+
+// NewServer constructs a grpc server that runs the same middleware as the
+// web api for every call.
+func NewServer(cfg Config, options ...grpc.ServerOption) *grpc.Server {
+	opts := []grpc.ServerOption{
+		grpc.ForceServerCodec(codec{}),
+		grpc.ChainUnaryInterceptor(
+			mid.UnaryOtel(cfg.Tracer),
+			mid.UnaryLogger(cfg.Log),
+			mid.UnaryErrors(cfg.Log),
+			mid.UnaryMetrics(),
+			mid.UnaryPanics(),
+		),
+	}
+
+	return grpc.NewServer(append(opts, options...)...)
+}
+
+// WithCodec returns the call option clients in this module use to send
+// and receive Message values.
+func WithCodec() grpc.CallOption {
+	return grpc.ForceCodec(codec{})
+}
+
+// =============================================================================
+
+// Service describes a grpc service whose methods are handled by functions
+// registered with Handle, in place of the code protoc generates.
+type Service struct {
+	desc grpc.ServiceDesc
+}
+
+// NewService constructs a service with the fully qualified name from the
+// .proto file.
+func NewService(name string) *Service {
+	return &Service{
+		desc: grpc.ServiceDesc{
+			ServiceName: name,
+			HandlerType: (*any)(nil),
+		},
+	}
+}
+
+// Handle adds a unary method to the service. The request is decoded into a
+// new Req before fn is called through the server's interceptors.
+func Handle[Req any, PReq interface {
+	*Req
+	Message
+}](svc *Service, method string, fn func(ctx context.Context, req PReq) (Message, error)) {
+	fullMethod := fmt.Sprintf("/%s/%s", svc.desc.ServiceName, method)
+
+	h := func(_ any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+		req := PReq(new(Req))
+		if err := dec(req); err != nil {
+			return nil, err
+		}
+
+		handler := func(ctx context.Context, req any) (any, error) {
+			return fn(ctx, req.(PReq))
+		}
+
+		if interceptor == nil {
+			return handler(ctx, req)
+		}
+
+		info := grpc.UnaryServerInfo{
+			FullMethod: fullMethod,
+		}
+
+		return interceptor(ctx, req, &info, handler)
+	}
+
+	svc.desc.Methods = append(svc.desc.Methods, grpc.MethodDesc{
+		MethodName: method,
+		Handler:    h,
+	})
+}
+
+// Register adds the service to the server.
+func (svc *Service) Register(srv *grpc.Server) {
+	srv.RegisterService(&svc.desc, nil)
+}
+
+// =============================================================================
+
+// codec marshals Message values. It takes the name of the proto codec so
+// the content type matches what generated clients send.
+type codec struct{}
+
+func (codec) Marshal(v any) ([]byte, error) {
+	m, ok := v.(Message)
+	if !ok {
+		return nil, fmt.Errorf("marshal: %T is not a message", v)
+	}
+
+	return m.MarshalProto()
+}
+
+func (codec) Unmarshal(data []byte, v any) error {
+	m, ok := v.(Message)
+	if !ok {
+		return fmt.Errorf("unmarshal: %T is not a message", v)
+	}
+
+	return m.UnmarshalProto(data)
+}
+
+func (codec) Name() string {
+	return "proto"
+}
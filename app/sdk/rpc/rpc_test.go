@@ -0,0 +1,140 @@
+package rpc_test
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+
+	"github.com/ardanlabs/service/app/sdk/errs"
+	"github.com/ardanlabs/service/app/sdk/rpc"
+	"github.com/ardanlabs/service/foundation/logger"
+	"go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+func Test_RoundTrip(t *testing.T) {
+	conn := startServer(t)
+
+	var resp echo
+	if err := conn.Invoke(context.Background(), "/test.Echo/Echo", &echo{Text: "hello", Count: 3, Tags: []string{"a", "b"}}, &resp, rpc.WithCodec()); err != nil {
+		t.Fatalf("Should be able to call the server : %s", err)
+	}
+
+	if resp.Text != "hello" || resp.Count != 3 || len(resp.Tags) != 2 || resp.Tags[1] != "b" {
+		t.Fatalf("expected the message to round trip, got %+v", resp)
+	}
+}
+
+func Test_Errors(t *testing.T) {
+	conn := startServer(t)
+
+	tests := []struct {
+		text string
+		code codes.Code
+		msg  string
+	}{
+		{"notfound", codes.NotFound, "user not found"},
+		{"throttled", codes.ResourceExhausted, "slow down"},
+		{"internal", codes.Internal, "Internal Server Error"},
+		{"panic", codes.Internal, "Internal Server Error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			var resp echo
+			err := conn.Invoke(context.Background(), "/test.Echo/Echo", &echo{Text: tt.text}, &resp, rpc.WithCodec())
+
+			st := status.Convert(err)
+			if st.Code() != tt.code {
+				t.Fatalf("expected code %s, got %s", tt.code, st.Code())
+			}
+
+			if st.Message() != tt.msg {
+				t.Fatalf("expected message %q, got %q", tt.msg, st.Message())
+			}
+		})
+	}
+}
+
+// =============================================================================
+
+type echo struct {
+	Text  string
+	Count int64
+	Tags  []string
+}
+
+func (m *echo) MarshalProto() ([]byte, error) {
+	var enc rpc.Encoder
+	enc.String(1, m.Text)
+	enc.Int(2, m.Count)
+	enc.Strings(3, m.Tags)
+
+	return enc.Bytes(), nil
+}
+
+func (m *echo) UnmarshalProto(data []byte) error {
+	return rpc.Decode(data, func(f rpc.Field) error {
+		var err error
+		switch f.Num {
+		case 1:
+			m.Text, err = f.String()
+		case 2:
+			m.Count, err = f.Int()
+		case 3:
+			var tag string
+			tag, err = f.String()
+			m.Tags = append(m.Tags, tag)
+		}
+		return err
+	})
+}
+
+func handleEcho(ctx context.Context, req *echo) (rpc.Message, error) {
+	switch req.Text {
+	case "notfound":
+		return nil, errs.Newf(errs.NotFound, "user not found")
+	case "throttled":
+		return nil, errs.Newf(errs.TooManyRequests, "slow down")
+	case "internal":
+		return nil, errs.Newf(errs.InternalOnlyLog, "database is down")
+	case "panic":
+		panic("boom")
+	}
+
+	return req, nil
+}
+
+func startServer(t *testing.T) *grpc.ClientConn {
+	var buf bytes.Buffer
+	log := logger.New(&buf, logger.LevelInfo, "TEST", func(context.Context) string { return "" })
+
+	srv := rpc.NewServer(rpc.Config{
+		Log:    log,
+		Tracer: noop.NewTracerProvider().Tracer("test"),
+	})
+
+	svc := rpc.NewService("test.Echo")
+	rpc.Handle(svc, "Echo", handleEcho)
+	svc.Register(srv)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Should be able to listen : %s", err)
+	}
+
+	go srv.Serve(ln)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient(ln.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Should be able to create a client : %s", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
@@ -0,0 +1,186 @@
+package rpc
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Encoder appends fields to a message in the protobuf wire format. Fields
+// holding the zero value are left out the way proto3 does, except for the
+// optional ones which are written whenever they're set.
+type Encoder struct {
+	b []byte
+}
+
+// Bytes returns the encoded message.
+func (e *Encoder) Bytes() []byte {
+	return e.b
+}
+
+// String appends a string field.
+func (e *Encoder) String(num protowire.Number, v string) {
+	if v == "" {
+		return
+	}
+
+	e.b = protowire.AppendTag(e.b, num, protowire.BytesType)
+	e.b = protowire.AppendString(e.b, v)
+}
+
+// Strings appends a repeated string field.
+func (e *Encoder) Strings(num protowire.Number, vs []string) {
+	for _, v := range vs {
+		e.b = protowire.AppendTag(e.b, num, protowire.BytesType)
+		e.b = protowire.AppendString(e.b, v)
+	}
+}
+
+// Bool appends a bool field.
+func (e *Encoder) Bool(num protowire.Number, v bool) {
+	if !v {
+		return
+	}
+
+	e.b = protowire.AppendTag(e.b, num, protowire.VarintType)
+	e.b = protowire.AppendVarint(e.b, protowire.EncodeBool(v))
+}
+
+// Int appends an int64 field.
+func (e *Encoder) Int(num protowire.Number, v int64) {
+	if v == 0 {
+		return
+	}
+
+	e.b = protowire.AppendTag(e.b, num, protowire.VarintType)
+	e.b = protowire.AppendVarint(e.b, uint64(v))
+}
+
+// OptionalString appends an optional string field when it's set.
+func (e *Encoder) OptionalString(num protowire.Number, v *string) {
+	if v == nil {
+		return
+	}
+
+	e.b = protowire.AppendTag(e.b, num, protowire.BytesType)
+	e.b = protowire.AppendString(e.b, *v)
+}
+
+// OptionalBool appends an optional bool field when it's set.
+func (e *Encoder) OptionalBool(num protowire.Number, v *bool) {
+	if v == nil {
+		return
+	}
+
+	e.b = protowire.AppendTag(e.b, num, protowire.VarintType)
+	e.b = protowire.AppendVarint(e.b, protowire.EncodeBool(*v))
+}
+
+// OptionalInt appends an optional int64 field when it's set.
+func (e *Encoder) OptionalInt(num protowire.Number, v *int64) {
+	if v == nil {
+		return
+	}
+
+	e.b = protowire.AppendTag(e.b, num, protowire.VarintType)
+	e.b = protowire.AppendVarint(e.b, uint64(*v))
+}
+
+// Message appends an embedded message field.
+func (e *Encoder) Message(num protowire.Number, m Message) error {
+	data, err := m.MarshalProto()
+	if err != nil {
+		return err
+	}
+
+	e.b = protowire.AppendTag(e.b, num, protowire.BytesType)
+	e.b = protowire.AppendBytes(e.b, data)
+
+	return nil
+}
+
+// =============================================================================
+
+// Field represents a single field read from a message.
+type Field struct {
+	Num    protowire.Number
+	typ    protowire.Type
+	varint uint64
+	bytes  []byte
+}
+
+// String returns the value of a string field.
+func (f Field) String() (string, error) {
+	if f.typ != protowire.BytesType {
+		return "", fmt.Errorf("field %d: expected a string", f.Num)
+	}
+
+	return string(f.bytes), nil
+}
+
+// Bool returns the value of a bool field.
+func (f Field) Bool() (bool, error) {
+	if f.typ != protowire.VarintType {
+		return false, fmt.Errorf("field %d: expected a bool", f.Num)
+	}
+
+	return protowire.DecodeBool(f.varint), nil
+}
+
+// Int returns the value of an int64 field.
+func (f Field) Int() (int64, error) {
+	if f.typ != protowire.VarintType {
+		return 0, fmt.Errorf("field %d: expected an integer", f.Num)
+	}
+
+	return int64(f.varint), nil
+}
+
+// Message decodes an embedded message field into m.
+func (f Field) Message(m Message) error {
+	if f.typ != protowire.BytesType {
+		return fmt.Errorf("field %d: expected a message", f.Num)
+	}
+
+	return m.UnmarshalProto(f.bytes)
+}
+
+// Decode reads the fields of a message in the protobuf wire format and
+// calls fn for each one. Fields the message doesn't know are expected to
+// be ignored by fn so older messages can read newer ones.
+func Decode(data []byte, fn func(f Field) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return fmt.Errorf("decode: %w", protowire.ParseError(n))
+		}
+		data = data[n:]
+
+		f := Field{
+			Num: num,
+			typ: typ,
+		}
+
+		switch typ {
+		case protowire.VarintType:
+			f.varint, n = protowire.ConsumeVarint(data)
+
+		case protowire.BytesType:
+			f.bytes, n = protowire.ConsumeBytes(data)
+
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, data)
+		}
+
+		if n < 0 {
+			return fmt.Errorf("decode: field %d: %w", num, protowire.ParseError(n))
+		}
+		data = data[n:]
+
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+// Package stream relays the events raised through the delegate to the
+// clients of the event stream. Only the events raised by this instance are
+// seen, so with several instances a client sees the writes handled by the
+// instance it's connected to.
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/delegate"
+	"github.com/ardanlabs/service/foundation/sse"
+	"github.com/google/uuid"
+)
+
+// Event represents an event as it's sent to clients. UserID is the user the
+// event is about and TenantID the tenant the user belongs to, which isn't
+// sent but limits the clients the event is sent to.
+type Event struct {
+	Domain   string            `json:"domain"`
+	Action   string            `json:"action"`
+	UserID   uuid.UUID         `json:"userID"`
+	TenantID uuid.UUID         `json:"-"`
+	Changes  []delegate.Change `json:"changes,omitempty"`
+	Time     time.Time         `json:"time"`
+}
+
+// Hub holds the events waiting to be sent to subscribers.
+type Hub struct {
+	broker *sse.Broker[Event]
+	epoch  string
+	now    func() time.Time
+}
+
+// New constructs a hub that keeps the specified number of events for
+// clients catching up after they reconnect.
+func New(history int) *Hub {
+	return &Hub{
+		broker: sse.NewBroker[Event](history, 64),
+		epoch:  strconv.FormatInt(time.Now().UnixNano(), 36),
+		now:    time.Now,
+	}
+}
+
+// Watch registers with the delegate for the user events that are streamed.
+// Publishing doesn't wait on subscribers, so the events are published
+// synchronously to keep them in the order they were raised.
+func (h *Hub) Watch(dlg *delegate.Delegate) {
+	actions := []string{
+		userbus.ActionCreated,
+		userbus.ActionUpdated,
+		userbus.ActionDeleted,
+		userbus.ActionAnonymized,
+	}
+
+	for _, action := range actions {
+		dlg.Register(userbus.DomainName, action, h.publish)
+	}
+}
+
+func (h *Hub) publish(ctx context.Context, data delegate.Data) error {
+	var params struct {
+		UserID   uuid.UUID
+		TenantID uuid.UUID
+	}
+
+	if err := json.Unmarshal(data.RawParams, &params); err != nil {
+		return fmt.Errorf("expected params with a user id: %w", err)
+	}
+
+	h.broker.Publish(Event{
+		Domain:   data.Domain,
+		Action:   data.Action,
+		UserID:   params.UserID,
+		TenantID: params.TenantID,
+		Changes:  data.Changes,
+		Time:     h.now().UTC(),
+	})
+
+	return nil
+}
+
+// Subscribe adds a subscriber that first receives the events after the
+// one with the specified id, which is the Last-Event-ID sent by a client
+// that's reconnecting. Missed reports whether some of those events are no
+// longer held, which is always the case for ids given out before the
+// process restarted.
+func (h *Hub) Subscribe(lastEventID string) (sub *sse.Subscription[Event], missed bool) {
+	if lastEventID == "" {
+		return h.broker.Subscribe(0)
+	}
+
+	epoch, n, found := strings.Cut(lastEventID, "-")
+	lastID, err := strconv.ParseUint(n, 10, 64)
+	if !found || err != nil || epoch != h.epoch {
+		sub, _ := h.broker.Subscribe(0)
+		return sub, true
+	}
+
+	return h.broker.Subscribe(lastID)
+}
+
+// ID returns the id the client is given for the event, which it sends back
+// as Last-Event-ID when it reconnects.
+func (h *Hub) ID(item sse.Item[Event]) string {
+	return h.epoch + "-" + strconv.FormatUint(item.ID, 10)
+}
+
+// Close closes every subscription so the streams end.
+func (h *Hub) Close() {
+	h.broker.Close()
+}
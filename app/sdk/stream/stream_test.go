@@ -0,0 +1,76 @@
+package stream_test
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/service/app/sdk/stream"
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/delegate"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/google/uuid"
+)
+
+func Test_Stream(t *testing.T) {
+	log := logger.New(io.Discard, logger.LevelInfo, "TEST", func(context.Context) string { return "" })
+	dlg := delegate.New(log)
+
+	hub := stream.New(10)
+	hub.Watch(dlg)
+
+	sub, missed := hub.Subscribe("")
+	if missed {
+		t.Fatalf("expected a new subscriber to have nothing missed")
+	}
+
+	userID := uuid.New()
+	tenantID := uuid.New()
+	changes := []delegate.Change{delegate.NewChange(userbus.FieldName, "Bill", "William")}
+
+	dlg.Call(context.Background(), userbus.ActionCreatedData(userID, tenantID))
+	dlg.Call(context.Background(), userbus.ActionUpdatedData(userID, tenantID, changes))
+
+	var lastID string
+	for _, action := range []string{userbus.ActionCreated, userbus.ActionUpdated} {
+		select {
+		case item := <-sub.C():
+			if item.Value.Action != action || item.Value.UserID != userID || item.Value.TenantID != tenantID {
+				t.Fatalf("expected %s for %s of tenant %s, got %+v", action, userID, tenantID, item.Value)
+			}
+			lastID = hub.ID(item)
+
+		case <-time.After(time.Second):
+			t.Fatalf("expected the %s event", action)
+		}
+	}
+	sub.Close()
+
+	dlg.Call(context.Background(), userbus.ActionDeletedData(userID, tenantID))
+	if err := dlg.Drain(context.Background()); err != nil {
+		t.Fatalf("Should be able to drain the delegate : %s", err)
+	}
+
+	// A client reconnecting with the last id it saw is sent the events
+	// it missed.
+	sub, missed = hub.Subscribe(lastID)
+	if missed {
+		t.Fatalf("expected nothing missed after %s", lastID)
+	}
+
+	if item := <-sub.C(); item.Value.Action != userbus.ActionDeleted {
+		t.Fatalf("expected the deleted event, got %+v", item.Value)
+	}
+
+	// Ids from before a restart can't be caught up from.
+	if _, missed := hub.Subscribe("other-1"); !missed {
+		t.Errorf("expected events to be missed for an id from another process")
+	}
+
+	hub.Close()
+
+	if _, ok := <-sub.C(); ok {
+		t.Errorf("expected the subscription to be closed with the hub")
+	}
+}
@@ -0,0 +1,281 @@
+// Package apikeybus provides business access to api key domain.
+package apikeybus
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/buserr"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/foundation/clock"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/otel"
+	"github.com/google/uuid"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrNotFound     = buserr.New(buserr.NotFound, "api key not found")
+	ErrInvalidKey   = buserr.New(buserr.Unauthenticated, "invalid api key")
+	ErrRevoked      = buserr.New(buserr.Unauthenticated, "api key revoked")
+	ErrExpired      = buserr.New(buserr.Unauthenticated, "api key expired")
+	ErrRotated      = buserr.New(buserr.FailedPrecondition, "api key already rotated")
+	ErrUserDisabled = buserr.New(buserr.Unauthenticated, "user disabled")
+)
+
+// Storer interface declares the behavior this package needs to persist and
+// retrieve data.
+type Storer interface {
+	NewWithTx(tx sqldb.CommitRollbacker) (Storer, error)
+	Create(ctx context.Context, key Key) error
+	Update(ctx context.Context, key Key) error
+	Query(ctx context.Context, filter QueryFilter, orderBy order.By, page page.Page) ([]Key, error)
+	Count(ctx context.Context, filter QueryFilter) (int, error)
+	QueryByID(ctx context.Context, keyID uuid.UUID) (Key, error)
+	QueryByPrefix(ctx context.Context, prefix string) (Key, error)
+}
+
+// Business manages the set of APIs for api key access.
+type Business struct {
+	log     *logger.Logger
+	userBus userbus.Business
+	storer  Storer
+}
+
+// NewBusiness constructs an api key business API for use.
+func NewBusiness(log *logger.Logger, userBus userbus.Business, storer Storer) *Business {
+	return &Business{
+		log:     log,
+		userBus: userBus,
+		storer:  storer,
+	}
+}
+
+// NewWithTx constructs a new business value that will use the
+// specified transaction in any store related calls.
+func (b *Business) NewWithTx(tx sqldb.CommitRollbacker) (*Business, error) {
+	storer, err := b.storer.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	userBus, err := b.userBus.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	bus := Business{
+		log:     b.log,
+		userBus: userBus,
+		storer:  storer,
+	}
+
+	return &bus, nil
+}
+
+// Create issues a new key for the user. The plaintext key is returned along
+// with the key and can't be retrieved again.
+func (b *Business) Create(ctx context.Context, nk NewKey) (Key, string, error) {
+	ctx, span := otel.AddSpan(ctx, "business.apikeybus.create")
+	defer span.End()
+
+	usr, err := b.userBus.QueryByID(ctx, nk.UserID)
+	if err != nil {
+		return Key{}, "", fmt.Errorf("user.querybyid: %s: %w", nk.UserID, err)
+	}
+
+	if !usr.Enabled {
+		return Key{}, "", ErrUserDisabled
+	}
+
+	prefix, plain, err := generate()
+	if err != nil {
+		return Key{}, "", err
+	}
+
+	key := Key{
+		ID:          uuid.New(),
+		UserID:      nk.UserID,
+		Name:        nk.Name,
+		Prefix:      prefix,
+		Hash:        hash(plain),
+		DateCreated: clock.Now(),
+	}
+
+	if err := b.storer.Create(ctx, key); err != nil {
+		return Key{}, "", fmt.Errorf("create: %w", err)
+	}
+
+	return key, plain, nil
+}
+
+// Revoke marks the key as revoked so it can no longer be used. Revoking a
+// key that is already revoked has no effect.
+func (b *Business) Revoke(ctx context.Context, key Key) (Key, error) {
+	ctx, span := otel.AddSpan(ctx, "business.apikeybus.revoke")
+	defer span.End()
+
+	if key.Revoked() {
+		return key, nil
+	}
+
+	key.DateRevoked = clock.Now()
+
+	if err := b.storer.Update(ctx, key); err != nil {
+		return Key{}, fmt.Errorf("update: %w", err)
+	}
+
+	return key, nil
+}
+
+// Query retrieves a list of existing keys.
+func (b *Business) Query(ctx context.Context, filter QueryFilter, orderBy order.By, page page.Page) ([]Key, error) {
+	ctx, span := otel.AddSpan(ctx, "business.apikeybus.query")
+	defer span.End()
+
+	keys, err := b.storer.Query(ctx, filter, orderBy, page)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return keys, nil
+}
+
+// Count returns the total number of keys.
+func (b *Business) Count(ctx context.Context, filter QueryFilter) (int, error) {
+	ctx, span := otel.AddSpan(ctx, "business.apikeybus.count")
+	defer span.End()
+
+	return b.storer.Count(ctx, filter)
+}
+
+// QueryByID finds the key by the specified ID.
+func (b *Business) QueryByID(ctx context.Context, keyID uuid.UUID) (Key, error) {
+	ctx, span := otel.AddSpan(ctx, "business.apikeybus.querybyid")
+	defer span.End()
+
+	key, err := b.storer.QueryByID(ctx, keyID)
+	if err != nil {
+		return Key{}, fmt.Errorf("query: keyID[%s]: %w", keyID, err)
+	}
+
+	return key, nil
+}
+
+// VerifyKey checks the plaintext key and returns the user it was issued to.
+// The key must not be revoked or past its deadline and the user must be
+// enabled.
+func (b *Business) VerifyKey(ctx context.Context, plain string) (userbus.User, error) {
+	ctx, span := otel.AddSpan(ctx, "business.apikeybus.verifykey")
+	defer span.End()
+
+	prefix, err := parsePrefix(plain)
+	if err != nil {
+		return userbus.User{}, err
+	}
+
+	key, err := b.storer.QueryByPrefix(ctx, prefix)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return userbus.User{}, ErrInvalidKey
+		}
+		return userbus.User{}, fmt.Errorf("querybyprefix: %w", err)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hash(plain)), []byte(key.Hash)) != 1 {
+		return userbus.User{}, ErrInvalidKey
+	}
+
+	if key.Revoked() {
+		return userbus.User{}, fmt.Errorf("keyID[%s]: %w", key.ID, ErrRevoked)
+	}
+
+	if key.Expired(clock.Now()) {
+		return userbus.User{}, fmt.Errorf("keyID[%s]: %w", key.ID, ErrExpired)
+	}
+
+	usr, err := b.userBus.QueryByID(ctx, key.UserID)
+	if err != nil {
+		return userbus.User{}, fmt.Errorf("user.querybyid: %s: %w", key.UserID, err)
+	}
+
+	if !usr.Enabled {
+		return userbus.User{}, ErrUserDisabled
+	}
+
+	b.touch(ctx, key)
+
+	return usr, nil
+}
+
+// =============================================================================
+
+// touch records when the key was last used. The time is only written once a
+// minute to keep a busy key from writing on every request, and a failure is
+// logged since it doesn't affect the caller.
+func (b *Business) touch(ctx context.Context, key Key) {
+	now := clock.Now()
+	if now.Sub(key.DateLastUsed) < time.Minute {
+		return
+	}
+
+	key.DateLastUsed = now
+
+	if err := b.storer.Update(ctx, key); err != nil {
+		b.log.Error(ctx, "apikeybus: touch", "keyID", key.ID, "ERROR", err)
+	}
+}
+
+// keyPrefix marks the plaintext keys issued by this package so they can be
+// told apart from other credentials.
+const keyPrefix = "sk_"
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// generate returns a new plaintext key in the form of sk_<prefix>_<secret>
+// along with its prefix. The prefix is used to find the key and the secret
+// provides 160 bits of randomness.
+func generate() (string, string, error) {
+	b := make([]byte, 26)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("read random: %w", err)
+	}
+
+	prefix := hex.EncodeToString(b[:6])
+	secret := strings.ToLower(encoding.EncodeToString(b[6:]))
+
+	return prefix, keyPrefix + prefix + "_" + secret, nil
+}
+
+// parsePrefix returns the prefix of a plaintext key.
+func parsePrefix(plain string) (string, error) {
+	rest, found := strings.CutPrefix(plain, keyPrefix)
+	if !found {
+		return "", ErrInvalidKey
+	}
+
+	prefix, secret, found := strings.Cut(rest, "_")
+	if !found || len(prefix) != 12 || secret == "" {
+		return "", ErrInvalidKey
+	}
+
+	return prefix, nil
+}
+
+// hash returns the stored form of a plaintext key. The keys are random
+// enough that a plain SHA-256 hash is sufficient.
+func hash(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
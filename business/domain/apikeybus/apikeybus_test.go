@@ -0,0 +1,306 @@
+package apikeybus_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/apikeybus"
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/dbtest"
+	"github.com/ardanlabs/service/business/sdk/unitest"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_APIKey(t *testing.T) {
+	t.Parallel()
+
+	db := dbtest.New(t, "Test_APIKey")
+
+	sd, plains, err := insertSeedData(db.BusDomain)
+	if err != nil {
+		t.Fatalf("Seeding error: %s", err)
+	}
+
+	// -------------------------------------------------------------------------
+
+	unitest.Run(t, create(db.BusDomain, sd), "create")
+	unitest.Run(t, verify(db.BusDomain, sd, plains), "verify")
+	unitest.Run(t, rotate(db.BusDomain, sd, plains), "rotate")
+	unitest.Run(t, revokeExpired(db.BusDomain, sd, plains), "revokeexpired")
+}
+
+// =============================================================================
+
+func insertSeedData(busDomain dbtest.BusDomain) (unitest.SeedData, []string, error) {
+	ctx := context.Background()
+
+	usrs, err := userbus.TestSeedUsers(ctx, 1, role.User, busDomain.User)
+	if err != nil {
+		return unitest.SeedData{}, nil, fmt.Errorf("seeding users : %w", err)
+	}
+
+	keys, plains, err := apikeybus.TestGenerateSeedKeys(ctx, 4, busDomain.APIKey, usrs[0].ID)
+	if err != nil {
+		return unitest.SeedData{}, nil, fmt.Errorf("seeding keys : %w", err)
+	}
+
+	tu1 := unitest.User{
+		User:    usrs[0],
+		APIKeys: keys,
+	}
+
+	// -------------------------------------------------------------------------
+
+	sd := unitest.SeedData{
+		Users: []unitest.User{tu1},
+	}
+
+	return sd, plains, nil
+}
+
+// =============================================================================
+
+func create(busDomain dbtest.BusDomain, sd unitest.SeedData) []unitest.Table {
+	table := []unitest.Table{
+		{
+			Name: "basic",
+			ExpResp: apikeybus.Key{
+				UserID: sd.Users[0].ID,
+				Name:   "ci pipeline",
+			},
+			ExcFunc: func(ctx context.Context) any {
+				nk := apikeybus.NewKey{
+					UserID: sd.Users[0].ID,
+					Name:   "ci pipeline",
+				}
+
+				key, plain, err := busDomain.APIKey.Create(ctx, nk)
+				if err != nil {
+					return err
+				}
+
+				if plain == "" || plain == key.Hash {
+					return errors.New("plaintext key not returned")
+				}
+
+				return key
+			},
+			CmpFunc: func(got any, exp any) string {
+				gotResp, exists := got.(apikeybus.Key)
+				if !exists {
+					return "error occurred"
+				}
+
+				expResp := exp.(apikeybus.Key)
+
+				expResp.ID = gotResp.ID
+				expResp.Prefix = gotResp.Prefix
+				expResp.Hash = gotResp.Hash
+				expResp.DateCreated = gotResp.DateCreated
+
+				return cmp.Diff(gotResp, expResp)
+			},
+		},
+	}
+
+	return table
+}
+
+func verify(busDomain dbtest.BusDomain, sd unitest.SeedData, plains []string) []unitest.Table {
+	table := []unitest.Table{
+		{
+			Name:    "valid",
+			ExpResp: sd.Users[0].ID,
+			ExcFunc: func(ctx context.Context) any {
+				usr, err := busDomain.APIKey.VerifyKey(ctx, plains[0])
+				if err != nil {
+					return err
+				}
+
+				return usr.ID
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+		{
+			Name:    "wrong",
+			ExpResp: apikeybus.ErrInvalidKey,
+			ExcFunc: func(ctx context.Context) any {
+				_, err := busDomain.APIKey.VerifyKey(ctx, plains[0]+"x")
+				return err
+			},
+			CmpFunc: func(got any, exp any) string {
+				err, _ := got.(error)
+				if !errors.Is(err, exp.(error)) {
+					return fmt.Sprintf("got %v, exp %v", got, exp)
+				}
+				return ""
+			},
+		},
+		{
+			Name:    "revoked",
+			ExpResp: apikeybus.ErrRevoked,
+			ExcFunc: func(ctx context.Context) any {
+				if _, err := busDomain.APIKey.Revoke(ctx, sd.Users[0].APIKeys[1]); err != nil {
+					return err
+				}
+
+				_, err := busDomain.APIKey.VerifyKey(ctx, plains[1])
+				return err
+			},
+			CmpFunc: func(got any, exp any) string {
+				err, _ := got.(error)
+				if !errors.Is(err, exp.(error)) {
+					return fmt.Sprintf("got %v, exp %v", got, exp)
+				}
+				return ""
+			},
+		},
+	}
+
+	return table
+}
+
+func rotate(busDomain dbtest.BusDomain, sd unitest.SeedData, plains []string) []unitest.Table {
+	isErr := func(got any, exp any) string {
+		err, _ := got.(error)
+		if !errors.Is(err, exp.(error)) {
+			return fmt.Sprintf("got %v, exp %v", got, exp)
+		}
+		return ""
+	}
+
+	table := []unitest.Table{
+		{
+			Name:    "overlap",
+			ExpResp: true,
+			ExcFunc: func(ctx context.Context) any {
+				successor, plain, err := busDomain.APIKey.Rotate(ctx, sd.Users[0].APIKeys[2], time.Hour)
+				if err != nil {
+					return err
+				}
+
+				// Both keys work during the overlap.
+				if _, err := busDomain.APIKey.VerifyKey(ctx, plains[2]); err != nil {
+					return fmt.Errorf("rotated key: %w", err)
+				}
+
+				if _, err := busDomain.APIKey.VerifyKey(ctx, plain); err != nil {
+					return fmt.Errorf("successor: %w", err)
+				}
+
+				key, err := busDomain.APIKey.QueryByID(ctx, sd.Users[0].APIKeys[2].ID)
+				if err != nil {
+					return err
+				}
+
+				if key.SuccessorID != successor.ID {
+					return fmt.Errorf("got successor %s, exp %s", key.SuccessorID, successor.ID)
+				}
+
+				if successor.Name != key.Name || successor.UserID != key.UserID {
+					return fmt.Errorf("successor not issued like the key: %+v", successor)
+				}
+
+				return key.DateExpires.After(time.Now().Add(59 * time.Minute))
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+		{
+			Name:    "again",
+			ExpResp: apikeybus.ErrRotated,
+			ExcFunc: func(ctx context.Context) any {
+				key, err := busDomain.APIKey.QueryByID(ctx, sd.Users[0].APIKeys[2].ID)
+				if err != nil {
+					return err
+				}
+
+				_, _, err = busDomain.APIKey.Rotate(ctx, key, time.Hour)
+				return err
+			},
+			CmpFunc: isErr,
+		},
+		{
+			Name:    "revoked",
+			ExpResp: apikeybus.ErrRevoked,
+			ExcFunc: func(ctx context.Context) any {
+				key, err := busDomain.APIKey.QueryByID(ctx, sd.Users[0].APIKeys[1].ID)
+				if err != nil {
+					return err
+				}
+
+				_, _, err = busDomain.APIKey.Rotate(ctx, key, time.Hour)
+				return err
+			},
+			CmpFunc: isErr,
+		},
+		{
+			Name:    "expired",
+			ExpResp: apikeybus.ErrExpired,
+			ExcFunc: func(ctx context.Context) any {
+				if _, _, err := busDomain.APIKey.Rotate(ctx, sd.Users[0].APIKeys[3], 0); err != nil {
+					return err
+				}
+
+				_, err := busDomain.APIKey.VerifyKey(ctx, plains[3])
+				return err
+			},
+			CmpFunc: isErr,
+		},
+	}
+
+	return table
+}
+
+func revokeExpired(busDomain dbtest.BusDomain, sd unitest.SeedData, plains []string) []unitest.Table {
+	table := []unitest.Table{
+		{
+			Name:    "basic",
+			ExpResp: 1,
+			ExcFunc: func(ctx context.Context) any {
+				n, err := busDomain.APIKey.RevokeExpired(ctx)
+				if err != nil {
+					return err
+				}
+
+				// Only the key past its deadline is revoked.
+				if _, err := busDomain.APIKey.VerifyKey(ctx, plains[3]); !errors.Is(err, apikeybus.ErrRevoked) {
+					return fmt.Errorf("expired key: got %v, exp %v", err, apikeybus.ErrRevoked)
+				}
+
+				if _, err := busDomain.APIKey.VerifyKey(ctx, plains[2]); err != nil {
+					return fmt.Errorf("key in its overlap: %w", err)
+				}
+
+				return n
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+		{
+			Name:    "none",
+			ExpResp: 0,
+			ExcFunc: func(ctx context.Context) any {
+				n, err := busDomain.APIKey.RevokeExpired(ctx)
+				if err != nil {
+					return err
+				}
+
+				return n
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+	}
+
+	return table
+}
@@ -0,0 +1,44 @@
+package apikeybus
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Key represents an API key issued to a user. Only the prefix and a hash of
+// the key are kept, the plaintext key is returned once when it's created.
+// A rotated key has a successor and keeps working until it expires.
+type Key struct {
+	ID           uuid.UUID
+	UserID       uuid.UUID
+	Name         string
+	Prefix       string
+	Hash         string `class:"restricted"`
+	SuccessorID  uuid.UUID
+	DateCreated  time.Time
+	DateLastUsed time.Time
+	DateExpires  time.Time
+	DateRevoked  time.Time
+}
+
+// Revoked reports if the key has been revoked.
+func (k Key) Revoked() bool {
+	return !k.DateRevoked.IsZero()
+}
+
+// Rotated reports if a successor has been issued for the key.
+func (k Key) Rotated() bool {
+	return k.SuccessorID != uuid.Nil
+}
+
+// Expired reports if the key is past its deadline at the specified time.
+func (k Key) Expired(now time.Time) bool {
+	return !k.DateExpires.IsZero() && !now.Before(k.DateExpires)
+}
+
+// NewKey is what we require from clients when adding a Key.
+type NewKey struct {
+	UserID uuid.UUID
+	Name   string
+}
@@ -0,0 +1,21 @@
+package apikeybus
+
+import "github.com/ardanlabs/service/business/sdk/order"
+
+// DefaultOrderBy represents the default way we sort.
+var DefaultOrderBy = order.NewBy(OrderByDateCreated, order.DESC)
+
+// Set of fields that the results can be ordered by.
+const (
+	OrderByID          = "a"
+	OrderByName        = "b"
+	OrderByDateCreated = "c"
+)
+
+// OrderFields represents the fields the results can be ordered by, the names
+// clients use for them and the columns the stores order by.
+var OrderFields = order.Register("apikey",
+	order.Field{Name: "key_id", Key: OrderByID, Column: "key_id"},
+	order.Field{Name: "name", Key: OrderByName, Column: "name"},
+	order.Field{Name: "date_created", Key: OrderByDateCreated, Column: "date_created"},
+)
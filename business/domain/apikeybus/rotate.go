@@ -0,0 +1,120 @@
+package apikeybus
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ardanlabs/service/business/sdk/jobs"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/foundation/clock"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/otel"
+	"github.com/google/uuid"
+)
+
+// Rotate issues a successor for the key. The key keeps working for the
+// overlap so the integrations using it can move to the successor, and is
+// revoked by RevokeExpired once the overlap is over. The successor is
+// returned along with its plaintext key, which can't be retrieved again.
+func (b *Business) Rotate(ctx context.Context, key Key, overlap time.Duration) (Key, string, error) {
+	ctx, span := otel.AddSpan(ctx, "business.apikeybus.rotate")
+	defer span.End()
+
+	switch {
+	case key.Revoked():
+		return Key{}, "", fmt.Errorf("keyID[%s]: %w", key.ID, ErrRevoked)
+	case key.Rotated():
+		return Key{}, "", fmt.Errorf("keyID[%s]: successorID[%s]: %w", key.ID, key.SuccessorID, ErrRotated)
+	}
+
+	// The successor is created first, so a failure leaves the key working
+	// the way it was.
+	nk := NewKey{
+		UserID: key.UserID,
+		Name:   key.Name,
+	}
+
+	successor, plain, err := b.Create(ctx, nk)
+	if err != nil {
+		return Key{}, "", err
+	}
+
+	key.SuccessorID = successor.ID
+	key.DateExpires = clock.Now().Add(overlap)
+
+	if err := b.storer.Update(ctx, key); err != nil {
+		return Key{}, "", fmt.Errorf("update: keyID[%s]: %w", key.ID, err)
+	}
+
+	return successor, plain, nil
+}
+
+// RevokeExpired revokes the keys whose overlap after a rotation is over and
+// returns the number of keys revoked.
+func (b *Business) RevokeExpired(ctx context.Context) (int, error) {
+	ctx, span := otel.AddSpan(ctx, "business.apikeybus.revokeexpired")
+	defer span.End()
+
+	now := clock.Now()
+	revoked := false
+
+	filter := QueryFilter{
+		Revoked:        &revoked,
+		EndExpiresDate: &now,
+	}
+
+	// The revoked keys drop out of the filter, so the first page is read
+	// until it comes back empty. A key read twice wasn't revoked, which
+	// would otherwise loop forever.
+	pg := page.MustParse("1", "100")
+	seen := make(map[uuid.UUID]bool)
+
+	var n int
+	for {
+		keys, err := b.storer.Query(ctx, filter, DefaultOrderBy, pg)
+		if err != nil {
+			return n, fmt.Errorf("query: %w", err)
+		}
+
+		if len(keys) == 0 {
+			return n, nil
+		}
+
+		for _, key := range keys {
+			if seen[key.ID] {
+				return n, fmt.Errorf("keyID[%s]: key not revoked", key.ID)
+			}
+			seen[key.ID] = true
+
+			key.DateRevoked = now
+
+			if err := b.storer.Update(ctx, key); err != nil {
+				return n, fmt.Errorf("update: keyID[%s]: %w", key.ID, err)
+			}
+
+			n++
+		}
+	}
+}
+
+// RevokeExpiredJob returns a job that revokes the expired keys every
+// interval.
+func RevokeExpiredJob(log *logger.Logger, bus *Business, interval time.Duration) jobs.Job {
+	return jobs.Job{
+		Name:     "apikeybus.revokeexpired",
+		Schedule: jobs.Every(interval),
+		Run: func(ctx context.Context) error {
+			n, err := bus.RevokeExpired(ctx)
+			if err != nil {
+				return err
+			}
+
+			if n > 0 {
+				log.Info(ctx, "apikeybus: revoke expired", "revoked", n)
+			}
+
+			return nil
+		},
+	}
+}
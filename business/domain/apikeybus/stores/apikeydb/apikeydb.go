@@ -0,0 +1,192 @@
+// Package apikeydb contains api key related CRUD functionality.
+package apikeydb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ardanlabs/service/business/domain/apikeybus"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Store manages the set of APIs for api key database access.
+type Store struct {
+	log *logger.Logger
+	db  sqlx.ExtContext
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *logger.Logger, db sqlx.ExtContext) *Store {
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// NewWithTx constructs a new Store value replacing the sqlx DB
+// value with a sqlx DB value that is currently inside a transaction.
+func (s *Store) NewWithTx(tx sqldb.CommitRollbacker) (apikeybus.Storer, error) {
+	ec, err := sqldb.GetExtContext(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	store := Store{
+		log: s.log,
+		db:  ec,
+	}
+
+	return &store, nil
+}
+
+// Create inserts a new key into the database.
+func (s *Store) Create(ctx context.Context, k apikeybus.Key) error {
+	const q = `
+	INSERT INTO api_keys
+		(key_id, user_id, name, prefix, key_hash, successor_id, date_created, date_last_used, date_expires, date_revoked)
+	VALUES
+		(:key_id, :user_id, :name, :prefix, :key_hash, :successor_id, :date_created, :date_last_used, :date_expires, :date_revoked)`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBKey(k)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Update replaces a key document in the database.
+func (s *Store) Update(ctx context.Context, k apikeybus.Key) error {
+	const q = `
+	UPDATE
+		api_keys
+	SET
+		"name" = :name,
+		"successor_id" = :successor_id,
+		"date_last_used" = :date_last_used,
+		"date_expires" = :date_expires,
+		"date_revoked" = :date_revoked
+	WHERE
+		key_id = :key_id`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBKey(k)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Query retrieves a list of existing keys from the database.
+func (s *Store) Query(ctx context.Context, filter apikeybus.QueryFilter, orderBy order.By, page page.Page) ([]apikeybus.Key, error) {
+	data := map[string]any{
+		"offset":        (page.Number() - 1) * page.RowsPerPage(),
+		"rows_per_page": page.RowsPerPage(),
+	}
+
+	const q = `
+	SELECT
+		key_id, user_id, name, prefix, key_hash, successor_id, date_created, date_last_used, date_expires, date_revoked
+	FROM
+		api_keys`
+
+	buf := bytes.NewBufferString(q)
+	applyFilter(filter, data, buf)
+
+	orderByClause, err := orderByClause(orderBy)
+	if err != nil {
+		return nil, err
+	}
+
+	buf.WriteString(orderByClause)
+	buf.WriteString(" OFFSET :offset ROWS FETCH NEXT :rows_per_page ROWS ONLY")
+
+	var dbKeys []key
+	if err := sqldb.NamedQuerySlice(ctx, s.log, s.db, buf.String(), data, &dbKeys); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toBusKeys(dbKeys), nil
+}
+
+// Count returns the total number of keys in the DB.
+func (s *Store) Count(ctx context.Context, filter apikeybus.QueryFilter) (int, error) {
+	data := map[string]any{}
+
+	const q = `
+	SELECT
+		count(1)
+	FROM
+		api_keys`
+
+	buf := bytes.NewBufferString(q)
+	applyFilter(filter, data, buf)
+
+	var count struct {
+		Count int `db:"count"`
+	}
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, buf.String(), data, &count); err != nil {
+		return 0, fmt.Errorf("db: %w", err)
+	}
+
+	return count.Count, nil
+}
+
+// QueryByID gets the specified key from the database.
+func (s *Store) QueryByID(ctx context.Context, keyID uuid.UUID) (apikeybus.Key, error) {
+	data := struct {
+		ID string `db:"key_id"`
+	}{
+		ID: keyID.String(),
+	}
+
+	const q = `
+	SELECT
+		key_id, user_id, name, prefix, key_hash, successor_id, date_created, date_last_used, date_expires, date_revoked
+	FROM
+		api_keys
+	WHERE
+		key_id = :key_id`
+
+	var dbKey key
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbKey); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return apikeybus.Key{}, fmt.Errorf("db: %w", apikeybus.ErrNotFound)
+		}
+		return apikeybus.Key{}, fmt.Errorf("db: %w", err)
+	}
+
+	return toBusKey(dbKey), nil
+}
+
+// QueryByPrefix gets the key with the specified prefix from the database.
+func (s *Store) QueryByPrefix(ctx context.Context, prefix string) (apikeybus.Key, error) {
+	data := struct {
+		Prefix string `db:"prefix"`
+	}{
+		Prefix: prefix,
+	}
+
+	const q = `
+	SELECT
+		key_id, user_id, name, prefix, key_hash, successor_id, date_created, date_last_used, date_expires, date_revoked
+	FROM
+		api_keys
+	WHERE
+		prefix = :prefix`
+
+	var dbKey key
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbKey); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return apikeybus.Key{}, fmt.Errorf("db: %w", apikeybus.ErrNotFound)
+		}
+		return apikeybus.Key{}, fmt.Errorf("db: %w", err)
+	}
+
+	return toBusKey(dbKey), nil
+}
@@ -0,0 +1,41 @@
+package apikeydb
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/ardanlabs/service/business/domain/apikeybus"
+)
+
+func applyFilter(filter apikeybus.QueryFilter, data map[string]any, buf *bytes.Buffer) {
+	var wc []string
+
+	if filter.ID != nil {
+		data["key_id"] = filter.ID
+		wc = append(wc, "key_id = :key_id")
+	}
+
+	if filter.UserID != nil {
+		data["user_id"] = filter.UserID
+		wc = append(wc, "user_id = :user_id")
+	}
+
+	if filter.Revoked != nil {
+		switch *filter.Revoked {
+		case true:
+			wc = append(wc, "date_revoked IS NOT NULL")
+		default:
+			wc = append(wc, "date_revoked IS NULL")
+		}
+	}
+
+	if filter.EndExpiresDate != nil {
+		data["end_date_expires"] = filter.EndExpiresDate.UTC()
+		wc = append(wc, "date_expires < :end_date_expires")
+	}
+
+	if len(wc) > 0 {
+		buf.WriteString(" WHERE ")
+		buf.WriteString(strings.Join(wc, " AND "))
+	}
+}
@@ -0,0 +1,88 @@
+package apikeydb
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/apikeybus"
+	"github.com/google/uuid"
+)
+
+type key struct {
+	ID           uuid.UUID     `db:"key_id"`
+	UserID       uuid.UUID     `db:"user_id"`
+	Name         string        `db:"name"`
+	Prefix       string        `db:"prefix"`
+	Hash         string        `db:"key_hash" class:"restricted"`
+	SuccessorID  uuid.NullUUID `db:"successor_id"`
+	DateCreated  time.Time     `db:"date_created"`
+	DateLastUsed sql.NullTime  `db:"date_last_used"`
+	DateExpires  sql.NullTime  `db:"date_expires"`
+	DateRevoked  sql.NullTime  `db:"date_revoked"`
+}
+
+func toDBKey(bus apikeybus.Key) key {
+	return key{
+		ID:     bus.ID,
+		UserID: bus.UserID,
+		Name:   bus.Name,
+		Prefix: bus.Prefix,
+		Hash:   bus.Hash,
+		SuccessorID: uuid.NullUUID{
+			UUID:  bus.SuccessorID,
+			Valid: bus.SuccessorID != uuid.Nil,
+		},
+		DateCreated: bus.DateCreated.UTC(),
+		DateLastUsed: sql.NullTime{
+			Time:  bus.DateLastUsed.UTC(),
+			Valid: !bus.DateLastUsed.IsZero(),
+		},
+		DateExpires: sql.NullTime{
+			Time:  bus.DateExpires.UTC(),
+			Valid: !bus.DateExpires.IsZero(),
+		},
+		DateRevoked: sql.NullTime{
+			Time:  bus.DateRevoked.UTC(),
+			Valid: !bus.DateRevoked.IsZero(),
+		},
+	}
+}
+
+func toBusKey(db key) apikeybus.Key {
+	bus := apikeybus.Key{
+		ID:          db.ID,
+		UserID:      db.UserID,
+		Name:        db.Name,
+		Prefix:      db.Prefix,
+		Hash:        db.Hash,
+		DateCreated: db.DateCreated.In(time.Local),
+	}
+
+	if db.SuccessorID.Valid {
+		bus.SuccessorID = db.SuccessorID.UUID
+	}
+
+	if db.DateLastUsed.Valid {
+		bus.DateLastUsed = db.DateLastUsed.Time.In(time.Local)
+	}
+
+	if db.DateExpires.Valid {
+		bus.DateExpires = db.DateExpires.Time.In(time.Local)
+	}
+
+	if db.DateRevoked.Valid {
+		bus.DateRevoked = db.DateRevoked.Time.In(time.Local)
+	}
+
+	return bus
+}
+
+func toBusKeys(dbs []key) []apikeybus.Key {
+	bus := make([]apikeybus.Key, len(dbs))
+
+	for i, db := range dbs {
+		bus[i] = toBusKey(db)
+	}
+
+	return bus
+}
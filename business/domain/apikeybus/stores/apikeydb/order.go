@@ -0,0 +1,10 @@
+package apikeydb
+
+import (
+	"github.com/ardanlabs/service/business/domain/apikeybus"
+	"github.com/ardanlabs/service/business/sdk/order"
+)
+
+func orderByClause(orderBy order.By) (string, error) {
+	return apikeybus.OrderFields.Clause(orderBy)
+}
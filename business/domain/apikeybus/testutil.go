@@ -0,0 +1,48 @@
+package apikeybus
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+
+	"github.com/google/uuid"
+)
+
+// TestGenerateNewKeys is a helper method for testing.
+func TestGenerateNewKeys(n int, userID uuid.UUID) []NewKey {
+	newKeys := make([]NewKey, n)
+
+	idx := rand.Intn(10000)
+	for i := range n {
+		idx++
+
+		nk := NewKey{
+			UserID: userID,
+			Name:   fmt.Sprintf("Key%d", idx),
+		}
+
+		newKeys[i] = nk
+	}
+
+	return newKeys
+}
+
+// TestGenerateSeedKeys is a helper method for testing. The plaintext keys are
+// returned in the same order as the keys.
+func TestGenerateSeedKeys(ctx context.Context, n int, api *Business, userID uuid.UUID) ([]Key, []string, error) {
+	newKeys := TestGenerateNewKeys(n, userID)
+
+	keys := make([]Key, len(newKeys))
+	plains := make([]string, len(newKeys))
+	for i, nk := range newKeys {
+		key, plain, err := api.Create(ctx, nk)
+		if err != nil {
+			return nil, nil, fmt.Errorf("seeding key: idx: %d : %w", i, err)
+		}
+
+		keys[i] = key
+		plains[i] = plain
+	}
+
+	return keys, plains, nil
+}
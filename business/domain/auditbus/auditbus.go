@@ -5,10 +5,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"time"
 
 	"github.com/ardanlabs/service/business/sdk/order"
 	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/foundation/clock"
 	"github.com/ardanlabs/service/foundation/logger"
 	"github.com/ardanlabs/service/foundation/otel"
 	"github.com/google/uuid"
@@ -55,7 +55,7 @@ func (b *Business) Create(ctx context.Context, na NewAudit) (Audit, error) {
 		Action:    na.Action,
 		Data:      jsonData,
 		Message:   na.Message,
-		Timestamp: time.Now(),
+		Timestamp: clock.Now(),
 	}
 
 	if err := b.storer.Create(ctx, audit); err != nil {
@@ -13,3 +13,13 @@ const (
 	OrderByActorID   = "d"
 	OrderByAction    = "e"
 )
+
+// OrderFields represents the fields the results can be ordered by, the names
+// clients use for them and the columns the stores order by.
+var OrderFields = order.Register("audit",
+	order.Field{Name: "obj_id", Key: OrderByObjID, Column: "obj_id"},
+	order.Field{Name: "obj_domain", Key: OrderByObjDomain, Column: "obj_domain"},
+	order.Field{Name: "obj_name", Key: OrderByObjName, Column: "obj_name"},
+	order.Field{Name: "actor_id", Key: OrderByActorID, Column: "actor_id"},
+	order.Field{Name: "action", Key: OrderByAction, Column: "action"},
+)
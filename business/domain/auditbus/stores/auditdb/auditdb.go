@@ -21,7 +21,7 @@ type Store struct {
 }
 
 // NewStore constructs the API for data access.
-func NewStore(log *logger.Logger, db *sqlx.DB) *Store {
+func NewStore(log *logger.Logger, db sqlx.ExtContext) *Store {
 	return &Store{
 		log: log,
 		db:  db,
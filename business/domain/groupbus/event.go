@@ -0,0 +1,65 @@
+package groupbus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/delegate"
+)
+
+// DomainName represents the name of this domain.
+const DomainName = "group"
+
+// registerDelegateFunctions will register action functions with the delegate
+// system. If the business was constructed for query only, there won't be a
+// delegate provided.
+func (b *Business) registerDelegateFunctions() {
+	if b.delegate != nil {
+		b.delegate.Register(userbus.DomainName, userbus.ActionDeleted, b.actionUserDeleted)
+		b.delegate.Register(userbus.DomainName, userbus.ActionCollect, b.actionUserCollect)
+	}
+}
+
+// actionUserDeleted is executed by the user domain indirectly when a user is
+// deleted. The user is removed from every group it was a member of. A group
+// the user was the only owner of is left without one, an admin can still
+// manage it.
+func (b *Business) actionUserDeleted(ctx context.Context, data delegate.Data) error {
+	var params userbus.ActionDeletedParms
+	err := json.Unmarshal(data.RawParams, &params)
+	if err != nil {
+		return fmt.Errorf("expected an encoded %T: %w", params, err)
+	}
+
+	b.log.Info(ctx, "action-userdeleted", "user_id", params.UserID)
+
+	if err := b.storer.DeleteMembersByUserID(ctx, params.UserID); err != nil {
+		return fmt.Errorf("deletemembersbyuserid: userID[%s]: %w", params.UserID, err)
+	}
+
+	return nil
+}
+
+// actionUserCollect is executed by the user domain indirectly when a user's
+// data is exported. The groups the user is a member of are added to the
+// export.
+func (b *Business) actionUserCollect(ctx context.Context, data delegate.Data) error {
+	var params userbus.ActionCollectParms
+	err := json.Unmarshal(data.RawParams, &params)
+	if err != nil {
+		return fmt.Errorf("expected an encoded %T: %w", params, err)
+	}
+
+	grps, err := b.QueryByUserID(ctx, params.UserID)
+	if err != nil {
+		return fmt.Errorf("querybyuserid: userID[%s]: %w", params.UserID, err)
+	}
+
+	if err := userbus.AddExportData(ctx, DomainName, grps); err != nil {
+		return fmt.Errorf("addexportdata: userID[%s]: %w", params.UserID, err)
+	}
+
+	return nil
+}
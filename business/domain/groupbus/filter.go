@@ -0,0 +1,26 @@
+package groupbus
+
+import (
+	"time"
+
+	"github.com/ardanlabs/service/business/types/grouprole"
+	"github.com/google/uuid"
+)
+
+// QueryFilter holds the available fields a query can be filtered on.
+// We are using pointer semantics because the With API mutates the value.
+type QueryFilter struct {
+	ID               *uuid.UUID
+	Name             *string
+	MemberID         *uuid.UUID
+	StartCreatedDate *time.Time
+	EndCreatedDate   *time.Time
+}
+
+// MemberFilter holds the available fields a query for members can be
+// filtered on.
+type MemberFilter struct {
+	GroupID *uuid.UUID
+	UserID  *uuid.UUID
+	Role    *grouprole.Role
+}
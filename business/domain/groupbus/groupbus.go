@@ -0,0 +1,357 @@
+// Package groupbus provides business access to group domain.
+package groupbus
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/buserr"
+	"github.com/ardanlabs/service/business/sdk/delegate"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/business/types/grouprole"
+	"github.com/ardanlabs/service/foundation/clock"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/otel"
+	"github.com/google/uuid"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrNotFound       = buserr.New(buserr.NotFound, "group not found")
+	ErrUniqueName     = buserr.New(buserr.Conflict, "group name already exists")
+	ErrUserDisabled   = buserr.New(buserr.FailedPrecondition, "user disabled")
+	ErrMemberNotFound = buserr.New(buserr.NotFound, "member not found")
+	ErrMemberExists   = buserr.New(buserr.Conflict, "user is already a member")
+	ErrLastOwner      = buserr.New(buserr.FailedPrecondition, "group must keep at least one owner")
+)
+
+// Storer interface declares the behaviour this package needs to persist and
+// retrieve data.
+type Storer interface {
+	NewWithTx(tx sqldb.CommitRollbacker) (Storer, error)
+	Create(ctx context.Context, grp Group) error
+	Update(ctx context.Context, grp Group) error
+	Delete(ctx context.Context, grp Group) error
+	Query(ctx context.Context, filter QueryFilter, orderBy order.By, page page.Page) ([]Group, error)
+	Count(ctx context.Context, filter QueryFilter) (int, error)
+	QueryByID(ctx context.Context, groupID uuid.UUID) (Group, error)
+	QueryByUserID(ctx context.Context, userID uuid.UUID) ([]Group, error)
+	AddMember(ctx context.Context, mbr Member) error
+	UpdateMember(ctx context.Context, mbr Member) error
+	RemoveMember(ctx context.Context, mbr Member) error
+	DeleteMembersByUserID(ctx context.Context, userID uuid.UUID) error
+	QueryMembers(ctx context.Context, filter MemberFilter, page page.Page) ([]Member, error)
+	CountMembers(ctx context.Context, filter MemberFilter) (int, error)
+	QueryMember(ctx context.Context, groupID uuid.UUID, userID uuid.UUID) (Member, error)
+}
+
+// Business manages the set of APIs for group access.
+type Business struct {
+	log      *logger.Logger
+	userBus  userbus.Business
+	delegate *delegate.Delegate
+	storer   Storer
+}
+
+// NewBusiness constructs a group business API for use.
+func NewBusiness(log *logger.Logger, userBus userbus.Business, delegate *delegate.Delegate, storer Storer) *Business {
+	b := Business{
+		log:      log,
+		userBus:  userBus,
+		delegate: delegate,
+		storer:   storer,
+	}
+
+	b.registerDelegateFunctions()
+
+	return &b
+}
+
+// NewWithTx constructs a new domain value that will use the
+// specified transaction in any store related calls.
+func (b *Business) NewWithTx(tx sqldb.CommitRollbacker) (*Business, error) {
+	storer, err := b.storer.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	userBus, err := b.userBus.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	bus := Business{
+		log:      b.log,
+		userBus:  userBus,
+		delegate: b.delegate,
+		storer:   storer,
+	}
+
+	return &bus, nil
+}
+
+// Create adds a new group to the system with the owner as its first member.
+func (b *Business) Create(ctx context.Context, ng NewGroup) (Group, error) {
+	ctx, span := otel.AddSpan(ctx, "business.groupbus.create")
+	defer span.End()
+
+	if err := b.checkUser(ctx, ng.OwnerID); err != nil {
+		return Group{}, err
+	}
+
+	now := clock.Now()
+
+	grp := Group{
+		ID:          uuid.New(),
+		Name:        ng.Name,
+		Description: ng.Description,
+		DateCreated: now,
+		DateUpdated: now,
+	}
+
+	if err := b.storer.Create(ctx, grp); err != nil {
+		return Group{}, fmt.Errorf("create: %w", err)
+	}
+
+	owner := Member{
+		GroupID:   grp.ID,
+		UserID:    ng.OwnerID,
+		Role:      grouprole.Owner,
+		DateAdded: now,
+	}
+
+	if err := b.storer.AddMember(ctx, owner); err != nil {
+		if err := b.storer.Delete(ctx, grp); err != nil {
+			b.log.Error(ctx, "groupbus: remove group without owner", "group_id", grp.ID, "ERROR", err)
+		}
+		return Group{}, fmt.Errorf("addmember: %w", err)
+	}
+
+	return grp, nil
+}
+
+// Update modifies information about a group.
+func (b *Business) Update(ctx context.Context, grp Group, ug UpdateGroup) (Group, error) {
+	ctx, span := otel.AddSpan(ctx, "business.groupbus.update")
+	defer span.End()
+
+	if ug.Name != nil {
+		grp.Name = *ug.Name
+	}
+
+	if ug.Description != nil {
+		grp.Description = *ug.Description
+	}
+
+	grp.DateUpdated = clock.Now()
+
+	if err := b.storer.Update(ctx, grp); err != nil {
+		return Group{}, fmt.Errorf("update: %w", err)
+	}
+
+	return grp, nil
+}
+
+// Delete removes the specified group along with its memberships.
+func (b *Business) Delete(ctx context.Context, grp Group) error {
+	ctx, span := otel.AddSpan(ctx, "business.groupbus.delete")
+	defer span.End()
+
+	if err := b.storer.Delete(ctx, grp); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	return nil
+}
+
+// Query retrieves a list of existing groups.
+func (b *Business) Query(ctx context.Context, filter QueryFilter, orderBy order.By, page page.Page) ([]Group, error) {
+	ctx, span := otel.AddSpan(ctx, "business.groupbus.query")
+	defer span.End()
+
+	grps, err := b.storer.Query(ctx, filter, orderBy, page)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return grps, nil
+}
+
+// Count returns the total number of groups.
+func (b *Business) Count(ctx context.Context, filter QueryFilter) (int, error) {
+	ctx, span := otel.AddSpan(ctx, "business.groupbus.count")
+	defer span.End()
+
+	return b.storer.Count(ctx, filter)
+}
+
+// QueryByID finds the group by the specified ID.
+func (b *Business) QueryByID(ctx context.Context, groupID uuid.UUID) (Group, error) {
+	ctx, span := otel.AddSpan(ctx, "business.groupbus.querybyid")
+	defer span.End()
+
+	grp, err := b.storer.QueryByID(ctx, groupID)
+	if err != nil {
+		return Group{}, fmt.Errorf("query: groupID[%s]: %w", groupID, err)
+	}
+
+	return grp, nil
+}
+
+// QueryByUserID finds the groups the specified user is a member of.
+func (b *Business) QueryByUserID(ctx context.Context, userID uuid.UUID) ([]Group, error) {
+	ctx, span := otel.AddSpan(ctx, "business.groupbus.querybyuserid")
+	defer span.End()
+
+	grps, err := b.storer.QueryByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return grps, nil
+}
+
+// =============================================================================
+
+// AddMember adds the user to the group with the specified role.
+func (b *Business) AddMember(ctx context.Context, grp Group, nm NewMember) (Member, error) {
+	ctx, span := otel.AddSpan(ctx, "business.groupbus.addmember")
+	defer span.End()
+
+	if err := b.checkUser(ctx, nm.UserID); err != nil {
+		return Member{}, err
+	}
+
+	mbr := Member{
+		GroupID:   grp.ID,
+		UserID:    nm.UserID,
+		Role:      nm.Role,
+		DateAdded: clock.Now(),
+	}
+
+	if err := b.storer.AddMember(ctx, mbr); err != nil {
+		return Member{}, fmt.Errorf("addmember: %w", err)
+	}
+
+	return mbr, nil
+}
+
+// SetMemberRole changes the role the member has within the group. The last
+// owner of a group can't be made a plain member.
+func (b *Business) SetMemberRole(ctx context.Context, mbr Member, role grouprole.Role) (Member, error) {
+	ctx, span := otel.AddSpan(ctx, "business.groupbus.setmemberrole")
+	defer span.End()
+
+	if mbr.Role.Equal(role) {
+		return mbr, nil
+	}
+
+	if err := b.checkLastOwner(ctx, mbr); err != nil {
+		return Member{}, err
+	}
+
+	mbr.Role = role
+
+	if err := b.storer.UpdateMember(ctx, mbr); err != nil {
+		return Member{}, fmt.Errorf("updatemember: %w", err)
+	}
+
+	return mbr, nil
+}
+
+// RemoveMember takes the user out of the group. The last owner of a group
+// can't be removed.
+func (b *Business) RemoveMember(ctx context.Context, mbr Member) error {
+	ctx, span := otel.AddSpan(ctx, "business.groupbus.removemember")
+	defer span.End()
+
+	if err := b.checkLastOwner(ctx, mbr); err != nil {
+		return err
+	}
+
+	if err := b.storer.RemoveMember(ctx, mbr); err != nil {
+		return fmt.Errorf("removemember: %w", err)
+	}
+
+	return nil
+}
+
+// QueryMember finds the membership of the user in the group.
+func (b *Business) QueryMember(ctx context.Context, groupID uuid.UUID, userID uuid.UUID) (Member, error) {
+	ctx, span := otel.AddSpan(ctx, "business.groupbus.querymember")
+	defer span.End()
+
+	mbr, err := b.storer.QueryMember(ctx, groupID, userID)
+	if err != nil {
+		return Member{}, fmt.Errorf("query: groupID[%s] userID[%s]: %w", groupID, userID, err)
+	}
+
+	return mbr, nil
+}
+
+// QueryMembers retrieves the users in the group, in the order they were
+// added.
+func (b *Business) QueryMembers(ctx context.Context, groupID uuid.UUID, page page.Page) ([]Member, error) {
+	ctx, span := otel.AddSpan(ctx, "business.groupbus.querymembers")
+	defer span.End()
+
+	mbrs, err := b.storer.QueryMembers(ctx, MemberFilter{GroupID: &groupID}, page)
+	if err != nil {
+		return nil, fmt.Errorf("query: groupID[%s]: %w", groupID, err)
+	}
+
+	return mbrs, nil
+}
+
+// CountMembers returns the total number of users in the group.
+func (b *Business) CountMembers(ctx context.Context, groupID uuid.UUID) (int, error) {
+	ctx, span := otel.AddSpan(ctx, "business.groupbus.countmembers")
+	defer span.End()
+
+	return b.storer.CountMembers(ctx, MemberFilter{GroupID: &groupID})
+}
+
+// =============================================================================
+
+// checkUser makes sure the user exists and can be given a membership.
+func (b *Business) checkUser(ctx context.Context, userID uuid.UUID) error {
+	usr, err := b.userBus.QueryByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("user.querybyid: %s: %w", userID, err)
+	}
+
+	if !usr.Enabled {
+		return ErrUserDisabled
+	}
+
+	return nil
+}
+
+// checkLastOwner returns ErrLastOwner when the member is the only owner of
+// its group.
+func (b *Business) checkLastOwner(ctx context.Context, mbr Member) error {
+	if !mbr.Role.Equal(grouprole.Owner) {
+		return nil
+	}
+
+	owner := grouprole.Owner
+
+	filter := MemberFilter{
+		GroupID: &mbr.GroupID,
+		Role:    &owner,
+	}
+
+	owners, err := b.storer.CountMembers(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("countmembers: %w", err)
+	}
+
+	if owners <= 1 {
+		return fmt.Errorf("groupID[%s]: %w", mbr.GroupID, ErrLastOwner)
+	}
+
+	return nil
+}
@@ -0,0 +1,478 @@
+package groupbus_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ardanlabs/service/business/domain/groupbus"
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/dbtest"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/sdk/unitest"
+	"github.com/ardanlabs/service/business/types/grouprole"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+)
+
+func Test_Group(t *testing.T) {
+	t.Parallel()
+
+	db := dbtest.New(t, "Test_Group")
+
+	sd, err := insertSeedData(db.BusDomain)
+	if err != nil {
+		t.Fatalf("Seeding error: %s", err)
+	}
+
+	// -------------------------------------------------------------------------
+
+	unitest.Run(t, query(db.BusDomain, sd), "query")
+	unitest.Run(t, create(db.BusDomain, sd), "create")
+	unitest.Run(t, update(db.BusDomain, sd), "update")
+	unitest.Run(t, members(db.BusDomain, sd), "members")
+	unitest.Run(t, userDeleted(db.BusDomain, sd), "userdeleted")
+	unitest.Run(t, delete(db.BusDomain, sd), "delete")
+}
+
+// =============================================================================
+
+func insertSeedData(busDomain dbtest.BusDomain) (unitest.SeedData, error) {
+	ctx := context.Background()
+
+	usrs, err := userbus.TestSeedUsers(ctx, 3, role.User, busDomain.User)
+	if err != nil {
+		return unitest.SeedData{}, fmt.Errorf("seeding users : %w", err)
+	}
+
+	grps, err := groupbus.TestGenerateSeedGroups(ctx, 2, busDomain.Group, usrs[0].ID)
+	if err != nil {
+		return unitest.SeedData{}, fmt.Errorf("seeding groups : %w", err)
+	}
+
+	sd := unitest.SeedData{
+		Users: []unitest.User{
+			{User: usrs[0], Groups: grps},
+			{User: usrs[1]},
+			{User: usrs[2]},
+		},
+	}
+
+	return sd, nil
+}
+
+// =============================================================================
+
+func query(busDomain dbtest.BusDomain, sd unitest.SeedData) []unitest.Table {
+	table := []unitest.Table{
+		{
+			Name:    "byid",
+			ExpResp: sd.Users[0].Groups[0],
+			ExcFunc: func(ctx context.Context) any {
+				resp, err := busDomain.Group.QueryByID(ctx, sd.Users[0].Groups[0].ID)
+				if err != nil {
+					return err
+				}
+
+				return resp
+			},
+			CmpFunc: cmpGroup,
+		},
+		{
+			Name:    "bymember",
+			ExpResp: 2,
+			ExcFunc: func(ctx context.Context) any {
+				filter := groupbus.QueryFilter{
+					MemberID: &sd.Users[0].ID,
+				}
+
+				resp, err := busDomain.Group.Query(ctx, filter, groupbus.DefaultOrderBy, page.MustParse("1", "10"))
+				if err != nil {
+					return err
+				}
+
+				return len(resp)
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+		{
+			Name:    "notfound",
+			ExpResp: groupbus.ErrNotFound,
+			ExcFunc: func(ctx context.Context) any {
+				_, err := busDomain.Group.QueryByID(ctx, uuid.New())
+				return err
+			},
+			CmpFunc: cmpError,
+		},
+	}
+
+	return table
+}
+
+func create(busDomain dbtest.BusDomain, sd unitest.SeedData) []unitest.Table {
+	table := []unitest.Table{
+		{
+			Name: "basic",
+			ExpResp: groupbus.Member{
+				UserID: sd.Users[1].ID,
+				Role:   grouprole.Owner,
+			},
+			ExcFunc: func(ctx context.Context) any {
+				ng := groupbus.NewGroup{
+					Name:        "Platform",
+					Description: "Keeps the lights on",
+					OwnerID:     sd.Users[1].ID,
+				}
+
+				grp, err := busDomain.Group.Create(ctx, ng)
+				if err != nil {
+					return err
+				}
+
+				resp, err := busDomain.Group.QueryMember(ctx, grp.ID, sd.Users[1].ID)
+				if err != nil {
+					return err
+				}
+
+				return resp
+			},
+			CmpFunc: func(got any, exp any) string {
+				gotResp, exists := got.(groupbus.Member)
+				if !exists {
+					return "error occurred"
+				}
+
+				expResp := exp.(groupbus.Member)
+
+				expResp.GroupID = gotResp.GroupID
+				expResp.DateAdded = gotResp.DateAdded
+
+				return cmp.Diff(gotResp, expResp)
+			},
+		},
+		{
+			Name:    "duplicate",
+			ExpResp: groupbus.ErrUniqueName,
+			ExcFunc: func(ctx context.Context) any {
+				ng := groupbus.NewGroup{
+					Name:    "Platform",
+					OwnerID: sd.Users[1].ID,
+				}
+
+				_, err := busDomain.Group.Create(ctx, ng)
+				return err
+			},
+			CmpFunc: cmpError,
+		},
+		{
+			Name:    "unknown-owner",
+			ExpResp: userbus.ErrNotFound,
+			ExcFunc: func(ctx context.Context) any {
+				ng := groupbus.NewGroup{
+					Name:    "Nobody",
+					OwnerID: uuid.New(),
+				}
+
+				_, err := busDomain.Group.Create(ctx, ng)
+				return err
+			},
+			CmpFunc: cmpError,
+		},
+	}
+
+	return table
+}
+
+func update(busDomain dbtest.BusDomain, sd unitest.SeedData) []unitest.Table {
+	table := []unitest.Table{
+		{
+			Name: "basic",
+			ExpResp: groupbus.Group{
+				ID:          sd.Users[0].Groups[0].ID,
+				Name:        "Renamed",
+				Description: sd.Users[0].Groups[0].Description,
+				DateCreated: sd.Users[0].Groups[0].DateCreated,
+			},
+			ExcFunc: func(ctx context.Context) any {
+				ug := groupbus.UpdateGroup{
+					Name: dbtest.StringPointer("Renamed"),
+				}
+
+				if _, err := busDomain.Group.Update(ctx, sd.Users[0].Groups[0], ug); err != nil {
+					return err
+				}
+
+				resp, err := busDomain.Group.QueryByID(ctx, sd.Users[0].Groups[0].ID)
+				if err != nil {
+					return err
+				}
+
+				return resp
+			},
+			CmpFunc: cmpGroup,
+		},
+	}
+
+	return table
+}
+
+func members(busDomain dbtest.BusDomain, sd unitest.SeedData) []unitest.Table {
+	grp := sd.Users[0].Groups[0]
+
+	table := []unitest.Table{
+		{
+			Name: "add",
+			ExpResp: groupbus.Member{
+				GroupID: grp.ID,
+				UserID:  sd.Users[1].ID,
+				Role:    grouprole.Member,
+			},
+			ExcFunc: func(ctx context.Context) any {
+				nm := groupbus.NewMember{
+					UserID: sd.Users[1].ID,
+					Role:   grouprole.Member,
+				}
+
+				if _, err := busDomain.Group.AddMember(ctx, grp, nm); err != nil {
+					return err
+				}
+
+				resp, err := busDomain.Group.QueryMember(ctx, grp.ID, sd.Users[1].ID)
+				if err != nil {
+					return err
+				}
+
+				return resp
+			},
+			CmpFunc: cmpMember,
+		},
+		{
+			Name:    "add-again",
+			ExpResp: groupbus.ErrMemberExists,
+			ExcFunc: func(ctx context.Context) any {
+				nm := groupbus.NewMember{
+					UserID: sd.Users[1].ID,
+					Role:   grouprole.Owner,
+				}
+
+				_, err := busDomain.Group.AddMember(ctx, grp, nm)
+				return err
+			},
+			CmpFunc: cmpError,
+		},
+		{
+			Name: "users-in-group",
+			ExpResp: []groupbus.Member{
+				{GroupID: grp.ID, UserID: sd.Users[0].ID, Role: grouprole.Owner},
+				{GroupID: grp.ID, UserID: sd.Users[1].ID, Role: grouprole.Member},
+			},
+			ExcFunc: func(ctx context.Context) any {
+				resp, err := busDomain.Group.QueryMembers(ctx, grp.ID, page.MustParse("1", "10"))
+				if err != nil {
+					return err
+				}
+
+				return resp
+			},
+			CmpFunc: func(got any, exp any) string {
+				gotResp, exists := got.([]groupbus.Member)
+				if !exists {
+					return "error occurred"
+				}
+
+				expResp := exp.([]groupbus.Member)
+
+				for i := range min(len(gotResp), len(expResp)) {
+					expResp[i].DateAdded = gotResp[i].DateAdded
+				}
+
+				return cmp.Diff(gotResp, expResp)
+			},
+		},
+		{
+			Name:    "groups-for-user",
+			ExpResp: []uuid.UUID{grp.ID},
+			ExcFunc: func(ctx context.Context) any {
+				resp, err := busDomain.Group.QueryByUserID(ctx, sd.Users[1].ID)
+				if err != nil {
+					return err
+				}
+
+				ids := make([]uuid.UUID, len(resp))
+				for i, g := range resp {
+					ids[i] = g.ID
+				}
+
+				return ids
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+		{
+			Name:    "demote-last-owner",
+			ExpResp: groupbus.ErrLastOwner,
+			ExcFunc: func(ctx context.Context) any {
+				mbr, err := busDomain.Group.QueryMember(ctx, grp.ID, sd.Users[0].ID)
+				if err != nil {
+					return err
+				}
+
+				_, err = busDomain.Group.SetMemberRole(ctx, mbr, grouprole.Member)
+				return err
+			},
+			CmpFunc: cmpError,
+		},
+		{
+			Name: "promote",
+			ExpResp: groupbus.Member{
+				GroupID: grp.ID,
+				UserID:  sd.Users[1].ID,
+				Role:    grouprole.Owner,
+			},
+			ExcFunc: func(ctx context.Context) any {
+				mbr, err := busDomain.Group.QueryMember(ctx, grp.ID, sd.Users[1].ID)
+				if err != nil {
+					return err
+				}
+
+				if _, err := busDomain.Group.SetMemberRole(ctx, mbr, grouprole.Owner); err != nil {
+					return err
+				}
+
+				resp, err := busDomain.Group.QueryMember(ctx, grp.ID, sd.Users[1].ID)
+				if err != nil {
+					return err
+				}
+
+				return resp
+			},
+			CmpFunc: cmpMember,
+		},
+		{
+			Name:    "remove",
+			ExpResp: groupbus.ErrMemberNotFound,
+			ExcFunc: func(ctx context.Context) any {
+				mbr, err := busDomain.Group.QueryMember(ctx, grp.ID, sd.Users[0].ID)
+				if err != nil {
+					return err
+				}
+
+				if err := busDomain.Group.RemoveMember(ctx, mbr); err != nil {
+					return err
+				}
+
+				_, err = busDomain.Group.QueryMember(ctx, grp.ID, sd.Users[0].ID)
+				return err
+			},
+			CmpFunc: cmpError,
+		},
+	}
+
+	return table
+}
+
+func userDeleted(busDomain dbtest.BusDomain, sd unitest.SeedData) []unitest.Table {
+	grp := sd.Users[0].Groups[1]
+
+	table := []unitest.Table{
+		{
+			Name:    "memberships",
+			ExpResp: 1,
+			ExcFunc: func(ctx context.Context) any {
+				nm := groupbus.NewMember{
+					UserID: sd.Users[2].ID,
+					Role:   grouprole.Member,
+				}
+
+				if _, err := busDomain.Group.AddMember(ctx, grp, nm); err != nil {
+					return err
+				}
+
+				if err := busDomain.User.Delete(ctx, userbus.ActorSystem, sd.Users[2].User); err != nil {
+					return err
+				}
+
+				if _, err := busDomain.Group.QueryMember(ctx, grp.ID, sd.Users[2].ID); !errors.Is(err, groupbus.ErrMemberNotFound) {
+					return fmt.Errorf("expected the membership to be removed, got %v", err)
+				}
+
+				resp, err := busDomain.Group.CountMembers(ctx, grp.ID)
+				if err != nil {
+					return err
+				}
+
+				return resp
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+	}
+
+	return table
+}
+
+func delete(busDomain dbtest.BusDomain, sd unitest.SeedData) []unitest.Table {
+	table := []unitest.Table{
+		{
+			Name:    "basic",
+			ExpResp: groupbus.ErrNotFound,
+			ExcFunc: func(ctx context.Context) any {
+				grp := sd.Users[0].Groups[1]
+
+				if err := busDomain.Group.Delete(ctx, grp); err != nil {
+					return err
+				}
+
+				_, err := busDomain.Group.QueryByID(ctx, grp.ID)
+				return err
+			},
+			CmpFunc: cmpError,
+		},
+	}
+
+	return table
+}
+
+// =============================================================================
+
+func cmpGroup(got any, exp any) string {
+	gotResp, exists := got.(groupbus.Group)
+	if !exists {
+		return "error occurred"
+	}
+
+	expResp := exp.(groupbus.Group)
+
+	expResp.DateCreated = gotResp.DateCreated
+	expResp.DateUpdated = gotResp.DateUpdated
+
+	return cmp.Diff(gotResp, expResp)
+}
+
+func cmpMember(got any, exp any) string {
+	gotResp, exists := got.(groupbus.Member)
+	if !exists {
+		return "error occurred"
+	}
+
+	expResp := exp.(groupbus.Member)
+
+	expResp.DateAdded = gotResp.DateAdded
+
+	return cmp.Diff(gotResp, expResp)
+}
+
+func cmpError(got any, exp any) string {
+	err, _ := got.(error)
+	if !errors.Is(err, exp.(error)) {
+		return fmt.Sprintf("got %v, want %v", got, exp)
+	}
+
+	return ""
+}
@@ -0,0 +1,50 @@
+package groupbus
+
+import (
+	"time"
+
+	"github.com/ardanlabs/service/business/types/grouprole"
+	"github.com/google/uuid"
+)
+
+// Group represents a named set of users, such as a team.
+type Group struct {
+	ID          uuid.UUID
+	Name        string
+	Description string
+	DateCreated time.Time
+	DateUpdated time.Time
+}
+
+// NewGroup is what we require from clients when adding a Group. The owner
+// becomes the first member of the group.
+type NewGroup struct {
+	Name        string
+	Description string
+	OwnerID     uuid.UUID
+}
+
+// UpdateGroup defines what information may be provided to modify an
+// existing Group. All fields are optional so clients can send just the
+// fields they want changed.
+type UpdateGroup struct {
+	Name        *string
+	Description *string
+}
+
+// =============================================================================
+
+// Member represents a user's membership of a group and the role the user
+// has within it.
+type Member struct {
+	GroupID   uuid.UUID
+	UserID    uuid.UUID
+	Role      grouprole.Role
+	DateAdded time.Time
+}
+
+// NewMember is what we require from clients when adding a user to a group.
+type NewMember struct {
+	UserID uuid.UUID
+	Role   grouprole.Role
+}
@@ -0,0 +1,66 @@
+package groupdb
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/ardanlabs/service/business/domain/groupbus"
+)
+
+func (s *Store) applyFilter(filter groupbus.QueryFilter, data map[string]any, buf *bytes.Buffer) {
+	var wc []string
+
+	if filter.ID != nil {
+		data["group_id"] = filter.ID
+		wc = append(wc, "group_id = :group_id")
+	}
+
+	if filter.Name != nil {
+		data["name"] = "%" + *filter.Name + "%"
+		wc = append(wc, "name LIKE :name")
+	}
+
+	if filter.MemberID != nil {
+		data["member_id"] = filter.MemberID
+		wc = append(wc, "group_id IN (SELECT group_id FROM group_members WHERE user_id = :member_id)")
+	}
+
+	if filter.StartCreatedDate != nil {
+		data["start_date_created"] = filter.StartCreatedDate.UTC()
+		wc = append(wc, "date_created >= :start_date_created")
+	}
+
+	if filter.EndCreatedDate != nil {
+		data["end_date_created"] = filter.EndCreatedDate.UTC()
+		wc = append(wc, "date_created <= :end_date_created")
+	}
+
+	if len(wc) > 0 {
+		buf.WriteString(" WHERE ")
+		buf.WriteString(strings.Join(wc, " AND "))
+	}
+}
+
+func (s *Store) applyMemberFilter(filter groupbus.MemberFilter, data map[string]any, buf *bytes.Buffer) {
+	var wc []string
+
+	if filter.GroupID != nil {
+		data["group_id"] = filter.GroupID
+		wc = append(wc, "group_id = :group_id")
+	}
+
+	if filter.UserID != nil {
+		data["user_id"] = filter.UserID
+		wc = append(wc, "user_id = :user_id")
+	}
+
+	if filter.Role != nil {
+		data["role"] = filter.Role.String()
+		wc = append(wc, "role = :role")
+	}
+
+	if len(wc) > 0 {
+		buf.WriteString(" WHERE ")
+		buf.WriteString(strings.Join(wc, " AND "))
+	}
+}
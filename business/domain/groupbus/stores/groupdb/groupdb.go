@@ -0,0 +1,374 @@
+// Package groupdb contains group related CRUD functionality.
+package groupdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ardanlabs/service/business/domain/groupbus"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Store manages the set of APIs for group database access.
+type Store struct {
+	log *logger.Logger
+	db  sqlx.ExtContext
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *logger.Logger, db sqlx.ExtContext) *Store {
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// NewWithTx constructs a new Store value replacing the sqlx DB
+// value with a sqlx DB value that is currently inside a transaction.
+func (s *Store) NewWithTx(tx sqldb.CommitRollbacker) (groupbus.Storer, error) {
+	ec, err := sqldb.GetExtContext(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	store := Store{
+		log: s.log,
+		db:  ec,
+	}
+
+	return &store, nil
+}
+
+// Create inserts a new group into the database.
+func (s *Store) Create(ctx context.Context, grp groupbus.Group) error {
+	const q = `
+	INSERT INTO groups
+		(group_id, name, description, date_created, date_updated)
+	VALUES
+		(:group_id, :name, :description, :date_created, :date_updated)`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBGroup(grp)); err != nil {
+		if errors.Is(err, sqldb.ErrDBDuplicatedEntry) {
+			return fmt.Errorf("namedexeccontext: %w", groupbus.ErrUniqueName)
+		}
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Update replaces a group document in the database.
+func (s *Store) Update(ctx context.Context, grp groupbus.Group) error {
+	const q = `
+	UPDATE
+		groups
+	SET
+		"name"         = :name,
+		"description"  = :description,
+		"date_updated" = :date_updated
+	WHERE
+		group_id = :group_id`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBGroup(grp)); err != nil {
+		if errors.Is(err, sqldb.ErrDBDuplicatedEntry) {
+			return fmt.Errorf("namedexeccontext: %w", groupbus.ErrUniqueName)
+		}
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes a group and its memberships from the database.
+func (s *Store) Delete(ctx context.Context, grp groupbus.Group) error {
+	data := struct {
+		ID string `db:"group_id"`
+	}{
+		ID: grp.ID.String(),
+	}
+
+	const q = `
+	DELETE FROM
+		groups
+	WHERE
+		group_id = :group_id`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Query retrieves a list of existing groups from the database.
+func (s *Store) Query(ctx context.Context, filter groupbus.QueryFilter, orderBy order.By, page page.Page) ([]groupbus.Group, error) {
+	data := map[string]any{
+		"offset":        (page.Number() - 1) * page.RowsPerPage(),
+		"rows_per_page": page.RowsPerPage(),
+	}
+
+	const q = `
+	SELECT
+		group_id, name, description, date_created, date_updated
+	FROM
+		groups`
+
+	buf := bytes.NewBufferString(q)
+	s.applyFilter(filter, data, buf)
+
+	orderByClause, err := orderByClause(orderBy)
+	if err != nil {
+		return nil, err
+	}
+
+	buf.WriteString(orderByClause)
+	buf.WriteString(" OFFSET :offset ROWS FETCH NEXT :rows_per_page ROWS ONLY")
+
+	var dbGrps []group
+	if err := sqldb.NamedQuerySlice(ctx, s.log, s.db, buf.String(), data, &dbGrps); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toBusGroups(dbGrps), nil
+}
+
+// Count returns the total number of groups in the DB.
+func (s *Store) Count(ctx context.Context, filter groupbus.QueryFilter) (int, error) {
+	data := map[string]any{}
+
+	const q = `
+	SELECT
+		count(1)
+	FROM
+		groups`
+
+	buf := bytes.NewBufferString(q)
+	s.applyFilter(filter, data, buf)
+
+	var count struct {
+		Count int `db:"count"`
+	}
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, buf.String(), data, &count); err != nil {
+		return 0, fmt.Errorf("db: %w", err)
+	}
+
+	return count.Count, nil
+}
+
+// QueryByID gets the specified group from the database.
+func (s *Store) QueryByID(ctx context.Context, groupID uuid.UUID) (groupbus.Group, error) {
+	data := struct {
+		ID string `db:"group_id"`
+	}{
+		ID: groupID.String(),
+	}
+
+	const q = `
+	SELECT
+		group_id, name, description, date_created, date_updated
+	FROM
+		groups
+	WHERE
+		group_id = :group_id`
+
+	var dbGrp group
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbGrp); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return groupbus.Group{}, fmt.Errorf("db: %w", groupbus.ErrNotFound)
+		}
+		return groupbus.Group{}, fmt.Errorf("db: %w", err)
+	}
+
+	return toBusGroup(dbGrp), nil
+}
+
+// QueryByUserID gets the groups the specified user is a member of from the
+// database.
+func (s *Store) QueryByUserID(ctx context.Context, userID uuid.UUID) ([]groupbus.Group, error) {
+	data := struct {
+		ID string `db:"user_id"`
+	}{
+		ID: userID.String(),
+	}
+
+	const q = `
+	SELECT
+		g.group_id, g.name, g.description, g.date_created, g.date_updated
+	FROM
+		groups AS g
+	JOIN
+		group_members AS m ON m.group_id = g.group_id
+	WHERE
+		m.user_id = :user_id
+	ORDER BY
+		g.name`
+
+	var dbGrps []group
+	if err := sqldb.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbGrps); err != nil {
+		return nil, fmt.Errorf("db: %w", err)
+	}
+
+	return toBusGroups(dbGrps), nil
+}
+
+// =============================================================================
+
+// AddMember inserts a new membership into the database.
+func (s *Store) AddMember(ctx context.Context, mbr groupbus.Member) error {
+	const q = `
+	INSERT INTO group_members
+		(group_id, user_id, role, date_added)
+	VALUES
+		(:group_id, :user_id, :role, :date_added)`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBMember(mbr)); err != nil {
+		if errors.Is(err, sqldb.ErrDBDuplicatedEntry) {
+			return fmt.Errorf("namedexeccontext: %w", groupbus.ErrMemberExists)
+		}
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateMember replaces the role of a membership in the database.
+func (s *Store) UpdateMember(ctx context.Context, mbr groupbus.Member) error {
+	const q = `
+	UPDATE
+		group_members
+	SET
+		"role" = :role
+	WHERE
+		group_id = :group_id AND user_id = :user_id`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBMember(mbr)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveMember removes a membership from the database.
+func (s *Store) RemoveMember(ctx context.Context, mbr groupbus.Member) error {
+	const q = `
+	DELETE FROM
+		group_members
+	WHERE
+		group_id = :group_id AND user_id = :user_id`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBMember(mbr)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteMembersByUserID removes every membership of the specified user from
+// the database.
+func (s *Store) DeleteMembersByUserID(ctx context.Context, userID uuid.UUID) error {
+	data := struct {
+		ID string `db:"user_id"`
+	}{
+		ID: userID.String(),
+	}
+
+	const q = `
+	DELETE FROM
+		group_members
+	WHERE
+		user_id = :user_id`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// QueryMembers retrieves a list of memberships from the database in the
+// order they were added.
+func (s *Store) QueryMembers(ctx context.Context, filter groupbus.MemberFilter, page page.Page) ([]groupbus.Member, error) {
+	data := map[string]any{
+		"offset":        (page.Number() - 1) * page.RowsPerPage(),
+		"rows_per_page": page.RowsPerPage(),
+	}
+
+	const q = `
+	SELECT
+		group_id, user_id, role, date_added
+	FROM
+		group_members`
+
+	buf := bytes.NewBufferString(q)
+	s.applyMemberFilter(filter, data, buf)
+
+	buf.WriteString(" ORDER BY date_added, user_id")
+	buf.WriteString(" OFFSET :offset ROWS FETCH NEXT :rows_per_page ROWS ONLY")
+
+	var dbMbrs []member
+	if err := sqldb.NamedQuerySlice(ctx, s.log, s.db, buf.String(), data, &dbMbrs); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toBusMembers(dbMbrs)
+}
+
+// CountMembers returns the total number of memberships in the DB.
+func (s *Store) CountMembers(ctx context.Context, filter groupbus.MemberFilter) (int, error) {
+	data := map[string]any{}
+
+	const q = `
+	SELECT
+		count(1)
+	FROM
+		group_members`
+
+	buf := bytes.NewBufferString(q)
+	s.applyMemberFilter(filter, data, buf)
+
+	var count struct {
+		Count int `db:"count"`
+	}
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, buf.String(), data, &count); err != nil {
+		return 0, fmt.Errorf("db: %w", err)
+	}
+
+	return count.Count, nil
+}
+
+// QueryMember gets the membership of the user in the group from the
+// database.
+func (s *Store) QueryMember(ctx context.Context, groupID uuid.UUID, userID uuid.UUID) (groupbus.Member, error) {
+	data := struct {
+		GroupID string `db:"group_id"`
+		UserID  string `db:"user_id"`
+	}{
+		GroupID: groupID.String(),
+		UserID:  userID.String(),
+	}
+
+	const q = `
+	SELECT
+		group_id, user_id, role, date_added
+	FROM
+		group_members
+	WHERE
+		group_id = :group_id AND user_id = :user_id`
+
+	var dbMbr member
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbMbr); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return groupbus.Member{}, fmt.Errorf("db: %w", groupbus.ErrMemberNotFound)
+		}
+		return groupbus.Member{}, fmt.Errorf("db: %w", err)
+	}
+
+	return toBusMember(dbMbr)
+}
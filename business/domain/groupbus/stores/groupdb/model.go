@@ -0,0 +1,102 @@
+package groupdb
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/groupbus"
+	"github.com/ardanlabs/service/business/types/grouprole"
+	"github.com/google/uuid"
+)
+
+type group struct {
+	ID          uuid.UUID `db:"group_id"`
+	Name        string    `db:"name"`
+	Description string    `db:"description"`
+	DateCreated time.Time `db:"date_created"`
+	DateUpdated time.Time `db:"date_updated"`
+}
+
+func toDBGroup(bus groupbus.Group) group {
+	db := group{
+		ID:          bus.ID,
+		Name:        bus.Name,
+		Description: bus.Description,
+		DateCreated: bus.DateCreated.UTC(),
+		DateUpdated: bus.DateUpdated.UTC(),
+	}
+
+	return db
+}
+
+func toBusGroup(db group) groupbus.Group {
+	bus := groupbus.Group{
+		ID:          db.ID,
+		Name:        db.Name,
+		Description: db.Description,
+		DateCreated: db.DateCreated.In(time.Local),
+		DateUpdated: db.DateUpdated.In(time.Local),
+	}
+
+	return bus
+}
+
+func toBusGroups(dbs []group) []groupbus.Group {
+	bus := make([]groupbus.Group, len(dbs))
+
+	for i, db := range dbs {
+		bus[i] = toBusGroup(db)
+	}
+
+	return bus
+}
+
+// =============================================================================
+
+type member struct {
+	GroupID   uuid.UUID `db:"group_id"`
+	UserID    uuid.UUID `db:"user_id"`
+	Role      string    `db:"role"`
+	DateAdded time.Time `db:"date_added"`
+}
+
+func toDBMember(bus groupbus.Member) member {
+	db := member{
+		GroupID:   bus.GroupID,
+		UserID:    bus.UserID,
+		Role:      bus.Role.String(),
+		DateAdded: bus.DateAdded.UTC(),
+	}
+
+	return db
+}
+
+func toBusMember(db member) (groupbus.Member, error) {
+	role, err := grouprole.Parse(db.Role)
+	if err != nil {
+		return groupbus.Member{}, fmt.Errorf("parse role: %w", err)
+	}
+
+	bus := groupbus.Member{
+		GroupID:   db.GroupID,
+		UserID:    db.UserID,
+		Role:      role,
+		DateAdded: db.DateAdded.In(time.Local),
+	}
+
+	return bus, nil
+}
+
+func toBusMembers(dbs []member) ([]groupbus.Member, error) {
+	bus := make([]groupbus.Member, len(dbs))
+
+	for i, db := range dbs {
+		var err error
+		bus[i], err = toBusMember(db)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return bus, nil
+}
@@ -0,0 +1,10 @@
+package groupdb
+
+import (
+	"github.com/ardanlabs/service/business/domain/groupbus"
+	"github.com/ardanlabs/service/business/sdk/order"
+)
+
+func orderByClause(orderBy order.By) (string, error) {
+	return groupbus.OrderFields.Clause(orderBy)
+}
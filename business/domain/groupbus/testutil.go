@@ -0,0 +1,46 @@
+package groupbus
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+
+	"github.com/google/uuid"
+)
+
+// TestGenerateNewGroups is a helper method for testing.
+func TestGenerateNewGroups(n int, ownerID uuid.UUID) []NewGroup {
+	newGrps := make([]NewGroup, n)
+
+	idx := rand.Intn(10000)
+	for i := range n {
+		idx++
+
+		ng := NewGroup{
+			Name:        fmt.Sprintf("Group%d", idx),
+			Description: fmt.Sprintf("Description%d", idx),
+			OwnerID:     ownerID,
+		}
+
+		newGrps[i] = ng
+	}
+
+	return newGrps
+}
+
+// TestGenerateSeedGroups is a helper method for testing.
+func TestGenerateSeedGroups(ctx context.Context, n int, api *Business, ownerID uuid.UUID) ([]Group, error) {
+	newGrps := TestGenerateNewGroups(n, ownerID)
+
+	grps := make([]Group, len(newGrps))
+	for i, ng := range newGrps {
+		grp, err := api.Create(ctx, ng)
+		if err != nil {
+			return nil, fmt.Errorf("seeding group: idx: %d : %w", i, err)
+		}
+
+		grps[i] = grp
+	}
+
+	return grps, nil
+}
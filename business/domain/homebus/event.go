@@ -6,15 +6,22 @@ import (
 	"fmt"
 
 	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/classify"
 	"github.com/ardanlabs/service/business/sdk/delegate"
+	"github.com/ardanlabs/service/foundation/clock"
 )
 
+// DomainName represents the name of this domain.
+const DomainName = "home"
+
 // registerDelegateFunctions will register action functions with the delegate
 // system. If the business was constructed for query only, there won't be a
 // delegate provided.
 func (b *Business) registerDelegateFunctions() {
 	if b.delegate != nil {
 		b.delegate.Register(userbus.DomainName, userbus.ActionDeleted, b.actionUserDeleted)
+		b.delegate.Register(userbus.DomainName, userbus.ActionCollect, b.actionUserCollect)
+		b.delegate.Register(userbus.DomainName, userbus.ActionRedact, b.actionUserRedact)
 	}
 }
 
@@ -32,3 +39,59 @@ func (b *Business) actionUserDeleted(ctx context.Context, data delegate.Data) er
 
 	return nil
 }
+
+// actionUserCollect is executed by the user domain indirectly when a user's
+// data is exported. The homes the user owns are added to the export.
+func (b *Business) actionUserCollect(ctx context.Context, data delegate.Data) error {
+	var params userbus.ActionCollectParms
+	err := json.Unmarshal(data.RawParams, &params)
+	if err != nil {
+		return fmt.Errorf("expected an encoded %T: %w", params, err)
+	}
+
+	hmes, err := b.QueryByUserID(ctx, params.UserID)
+	if err != nil {
+		return fmt.Errorf("querybyuserid: userID[%s]: %w", params.UserID, err)
+	}
+
+	if err := userbus.AddExportData(ctx, DomainName, hmes); err != nil {
+		return fmt.Errorf("addexportdata: userID[%s]: %w", params.UserID, err)
+	}
+
+	return nil
+}
+
+// actionUserRedact is invoked by the user domain indirectly when a user is
+// anonymized. The homes are kept for whatever refers to them, but the
+// parts of their address that can identify the user are redacted. The state
+// and country are kept.
+func (b *Business) actionUserRedact(ctx context.Context, data delegate.Data) error {
+	var params userbus.ActionRedactParms
+	err := json.Unmarshal(data.RawParams, &params)
+	if err != nil {
+		return fmt.Errorf("expected an encoded %T: %w", params, err)
+	}
+
+	b.log.Info(ctx, "action-userredact", "user_id", params.UserID)
+
+	hmes, err := b.QueryByUserID(ctx, params.UserID)
+	if err != nil {
+		return fmt.Errorf("querybyuserid: userID[%s]: %w", params.UserID, err)
+	}
+
+	now := clock.Now()
+
+	for _, hme := range hmes {
+		hme.Address.Address1 = classify.Redacted
+		hme.Address.Address2 = ""
+		hme.Address.ZipCode = classify.Redacted
+		hme.Address.City = classify.Redacted
+		hme.DateUpdated = now
+
+		if err := b.storer.Update(ctx, hme); err != nil {
+			return fmt.Errorf("update: homeID[%s]: %w", hme.ID, err)
+		}
+	}
+
+	return nil
+}
@@ -3,15 +3,15 @@ package homebus
 
 import (
 	"context"
-	"errors"
 	"fmt"
-	"time"
 
 	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/buserr"
 	"github.com/ardanlabs/service/business/sdk/delegate"
 	"github.com/ardanlabs/service/business/sdk/order"
 	"github.com/ardanlabs/service/business/sdk/page"
 	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/foundation/clock"
 	"github.com/ardanlabs/service/foundation/logger"
 	"github.com/ardanlabs/service/foundation/otel"
 	"github.com/google/uuid"
@@ -19,8 +19,8 @@ import (
 
 // Set of error variables for CRUD operations.
 var (
-	ErrNotFound     = errors.New("home not found")
-	ErrUserDisabled = errors.New("user disabled")
+	ErrNotFound     = buserr.New(buserr.NotFound, "home not found")
+	ErrUserDisabled = buserr.New(buserr.FailedPrecondition, "user disabled")
 )
 
 // Storer interface declares the behaviour this package needs to persist and
@@ -95,7 +95,7 @@ func (b *Business) Create(ctx context.Context, nh NewHome) (Home, error) {
 		return Home{}, ErrUserDisabled
 	}
 
-	now := time.Now()
+	now := clock.Now()
 
 	hme := Home{
 		ID:   uuid.New(),
@@ -155,7 +155,7 @@ func (b *Business) Update(ctx context.Context, hme Home, uh UpdateHome) (Home, e
 		}
 	}
 
-	hme.DateUpdated = time.Now()
+	hme.DateUpdated = clock.Now()
 
 	if err := b.storer.Update(ctx, hme); err != nil {
 		return Home{}, fmt.Errorf("update: %w", err)
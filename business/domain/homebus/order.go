@@ -11,3 +11,11 @@ const (
 	OrderByType   = "b"
 	OrderByUserID = "c"
 )
+
+// OrderFields represents the fields the results can be ordered by, the names
+// clients use for them and the columns the stores order by.
+var OrderFields = order.Register("home",
+	order.Field{Name: "home_id", Key: OrderByID, Column: "home_id"},
+	order.Field{Name: "type", Key: OrderByType, Column: "type"},
+	order.Field{Name: "user_id", Key: OrderByUserID, Column: "user_id"},
+)
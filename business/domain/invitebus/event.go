@@ -0,0 +1,124 @@
+package invitebus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/delegate"
+	"github.com/google/uuid"
+)
+
+// DomainName represents the name of this domain.
+const DomainName = "invite"
+
+// Set of delegate actions.
+const (
+	ActionCreated  = "created"
+	ActionAccepted = "accepted"
+)
+
+// =============================================================================
+
+// ActionCreatedParms represents the parameters for the created action. The
+// email and token aren't included so they aren't copied into events, the
+// invite is delivered by the sender.
+type ActionCreatedParms struct {
+	InviteID uuid.UUID
+}
+
+// String returns a string representation of the action parameters.
+func (act *ActionCreatedParms) String() string {
+	return fmt.Sprintf("&EventParamsCreated{InviteID:%v}", act.InviteID)
+}
+
+// Marshal returns the event parameters encoded as JSON.
+func (act *ActionCreatedParms) Marshal() ([]byte, error) {
+	return json.Marshal(act)
+}
+
+// ActionCreatedData constructs the data for the created action.
+func ActionCreatedData(inviteID uuid.UUID) delegate.Data {
+	params := ActionCreatedParms{
+		InviteID: inviteID,
+	}
+
+	rawParams, err := params.Marshal()
+	if err != nil {
+		panic(err)
+	}
+
+	return delegate.Data{
+		Domain:    DomainName,
+		Action:    ActionCreated,
+		RawParams: rawParams,
+	}
+}
+
+// =============================================================================
+
+// ActionAcceptedParms represents the parameters for the accepted action.
+type ActionAcceptedParms struct {
+	InviteID uuid.UUID
+	UserID   uuid.UUID
+}
+
+// String returns a string representation of the action parameters.
+func (act *ActionAcceptedParms) String() string {
+	return fmt.Sprintf("&EventParamsAccepted{InviteID:%v, UserID:%v}", act.InviteID, act.UserID)
+}
+
+// Marshal returns the event parameters encoded as JSON.
+func (act *ActionAcceptedParms) Marshal() ([]byte, error) {
+	return json.Marshal(act)
+}
+
+// ActionAcceptedData constructs the data for the accepted action.
+func ActionAcceptedData(inviteID uuid.UUID, userID uuid.UUID) delegate.Data {
+	params := ActionAcceptedParms{
+		InviteID: inviteID,
+		UserID:   userID,
+	}
+
+	rawParams, err := params.Marshal()
+	if err != nil {
+		panic(err)
+	}
+
+	return delegate.Data{
+		Domain:    DomainName,
+		Action:    ActionAccepted,
+		RawParams: rawParams,
+	}
+}
+
+// =============================================================================
+
+// registerDelegateFunctions will register action functions with the delegate
+// system. If the business was constructed for query only, there won't be a
+// delegate provided.
+func (b *Business) registerDelegateFunctions() {
+	if b.delegate != nil {
+		b.delegate.Register(userbus.DomainName, userbus.ActionRedact, b.actionUserRedact)
+	}
+}
+
+// actionUserRedact is invoked by the user domain indirectly when a user is
+// anonymized. The invite the user accepted holds a copy of their email,
+// it's replaced with the tombstone email the user was given.
+func (b *Business) actionUserRedact(ctx context.Context, data delegate.Data) error {
+	var params userbus.ActionRedactParms
+	err := json.Unmarshal(data.RawParams, &params)
+	if err != nil {
+		return fmt.Errorf("expected an encoded %T: %w", params, err)
+	}
+
+	b.log.Info(ctx, "action-userredact", "user_id", params.UserID)
+
+	if err := b.storer.UpdateEmailByUserID(ctx, params.UserID, userbus.AnonymizedEmail(params.UserID)); err != nil {
+		return fmt.Errorf("updateemailbyuserid: userID[%s]: %w", params.UserID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,324 @@
+// Package invitebus provides business access to invite domain.
+package invitebus
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/buserr"
+	"github.com/ardanlabs/service/business/sdk/delegate"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/business/types/name"
+	"github.com/ardanlabs/service/foundation/clock"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/otel"
+	"github.com/google/uuid"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrNotFound     = buserr.New(buserr.NotFound, "invite not found")
+	ErrInvalidToken = buserr.New(buserr.Unauthenticated, "invalid invite token")
+	ErrExpired      = buserr.New(buserr.FailedPrecondition, "invite expired")
+	ErrRevoked      = buserr.New(buserr.FailedPrecondition, "invite revoked")
+	ErrAccepted     = buserr.New(buserr.FailedPrecondition, "invite already accepted")
+	ErrUserExists   = buserr.New(buserr.Conflict, "a user with the email already exists")
+	ErrUserDisabled = buserr.New(buserr.PermissionDenied, "user disabled")
+	ErrNoRoles      = buserr.New(buserr.InvalidArgument, "invite must give at least one role")
+	ErrTTLTooLong   = buserr.New(buserr.InvalidArgument, "invite can't last longer than the maximum")
+)
+
+const (
+	// DefaultTTL is how long an invite can be accepted for when no TTL is
+	// given.
+	DefaultTTL = 7 * 24 * time.Hour
+
+	// MaxTTL is the longest an invite can be accepted for.
+	MaxTTL = 30 * 24 * time.Hour
+)
+
+// Storer interface declares the behavior this package needs to persist and
+// retrieve data.
+type Storer interface {
+	NewWithTx(tx sqldb.CommitRollbacker) (Storer, error)
+	Create(ctx context.Context, inv Invite) error
+	Update(ctx context.Context, inv Invite) error
+	Query(ctx context.Context, filter QueryFilter, orderBy order.By, page page.Page) ([]Invite, error)
+	Count(ctx context.Context, filter QueryFilter) (int, error)
+	QueryByID(ctx context.Context, inviteID uuid.UUID) (Invite, error)
+	QueryByTokenHash(ctx context.Context, tokenHash string) (Invite, error)
+	UpdateEmailByUserID(ctx context.Context, userID uuid.UUID, email mail.Address) error
+}
+
+// Business manages the set of APIs for invite access.
+type Business struct {
+	log      *logger.Logger
+	userBus  userbus.Business
+	delegate *delegate.Delegate
+	storer   Storer
+	sender   Sender
+}
+
+// NewBusiness constructs an invite business API for use. The sender
+// delivers new invites, it can be nil when the token returned by Create is
+// handed over some other way.
+func NewBusiness(log *logger.Logger, userBus userbus.Business, delegate *delegate.Delegate, storer Storer, sender Sender) *Business {
+	b := Business{
+		log:      log,
+		userBus:  userBus,
+		delegate: delegate,
+		storer:   storer,
+		sender:   sender,
+	}
+
+	b.registerDelegateFunctions()
+
+	return &b
+}
+
+// NewWithTx constructs a new business value that will use the
+// specified transaction in any store related calls.
+func (b *Business) NewWithTx(tx sqldb.CommitRollbacker) (*Business, error) {
+	storer, err := b.storer.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	userBus, err := b.userBus.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	bus := Business{
+		log:      b.log,
+		userBus:  userBus,
+		delegate: b.delegate,
+		storer:   storer,
+		sender:   b.sender,
+	}
+
+	return &bus, nil
+}
+
+// Create invites someone to create their own user with the roles. The
+// plaintext token is returned along with the invite and can't be retrieved
+// again. When there is a sender the invite is delivered with it, a failure
+// to deliver is logged since the token can still be handed over.
+func (b *Business) Create(ctx context.Context, ni NewInvite) (Invite, string, error) {
+	ctx, span := otel.AddSpan(ctx, "business.invitebus.create")
+	defer span.End()
+
+	if len(ni.Roles) == 0 {
+		return Invite{}, "", ErrNoRoles
+	}
+
+	ttl := ni.TTL
+	switch {
+	case ttl <= 0:
+		ttl = DefaultTTL
+	case ttl > MaxTTL:
+		return Invite{}, "", fmt.Errorf("ttl[%s]: %w", ttl, ErrTTLTooLong)
+	}
+
+	inviter, err := b.userBus.QueryByID(ctx, ni.InvitedBy)
+	if err != nil {
+		return Invite{}, "", fmt.Errorf("user.querybyid: %s: %w", ni.InvitedBy, err)
+	}
+
+	if !inviter.Enabled {
+		return Invite{}, "", ErrUserDisabled
+	}
+
+	_, err = b.userBus.QueryByEmail(ctx, ni.Email)
+	switch {
+	case err == nil:
+		return Invite{}, "", ErrUserExists
+	case !errors.Is(err, userbus.ErrNotFound):
+		return Invite{}, "", fmt.Errorf("user.querybyemail: %w", err)
+	}
+
+	token, err := generate()
+	if err != nil {
+		return Invite{}, "", err
+	}
+
+	now := clock.Now()
+
+	inv := Invite{
+		ID:          uuid.New(),
+		Email:       ni.Email,
+		Roles:       ni.Roles,
+		InvitedBy:   ni.InvitedBy,
+		TokenHash:   hash(token),
+		DateCreated: now,
+		DateExpires: now.Add(ttl),
+	}
+
+	if err := b.storer.Create(ctx, inv); err != nil {
+		return Invite{}, "", fmt.Errorf("create: %w", err)
+	}
+
+	if b.sender != nil {
+		if err := b.sender.Send(ctx, inv, token); err != nil {
+			b.log.Error(ctx, "invitebus: send", "inviteID", inv.ID, "ERROR", err)
+		}
+	}
+
+	// Other domains may need to know when an invite is created so business
+	// logic can be applied. This represents a delegate call to other domains.
+	if err := b.delegate.Call(ctx, ActionCreatedData(inv.ID)); err != nil {
+		return Invite{}, "", fmt.Errorf("failed to execute `%s` action: %w", ActionCreated, err)
+	}
+
+	return inv, token, nil
+}
+
+// Accept creates the user for the invite the token belongs to, with the
+// name and password they chose. The user is attributed to whoever sent the
+// invite, so an invite stops working if they can no longer create users.
+func (b *Business) Accept(ctx context.Context, token string, nme name.Name, password string) (userbus.User, error) {
+	ctx, span := otel.AddSpan(ctx, "business.invitebus.accept")
+	defer span.End()
+
+	inv, err := b.storer.QueryByTokenHash(ctx, hash(token))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return userbus.User{}, ErrInvalidToken
+		}
+		return userbus.User{}, fmt.Errorf("querybytokenhash: %w", err)
+	}
+
+	now := clock.Now()
+
+	switch {
+	case inv.Revoked():
+		return userbus.User{}, fmt.Errorf("inviteID[%s]: %w", inv.ID, ErrRevoked)
+	case inv.Accepted():
+		return userbus.User{}, fmt.Errorf("inviteID[%s]: %w", inv.ID, ErrAccepted)
+	case inv.Expired(now):
+		return userbus.User{}, fmt.Errorf("inviteID[%s]: %w", inv.ID, ErrExpired)
+	}
+
+	nu := userbus.NewUser{
+		Name:     nme,
+		Email:    inv.Email,
+		Roles:    inv.Roles,
+		Password: password,
+	}
+
+	usr, _, err := b.userBus.Create(ctx, inv.InvitedBy, nu)
+	if err != nil {
+		if errors.Is(err, userbus.ErrUniqueEmail) {
+			return userbus.User{}, fmt.Errorf("inviteID[%s]: %w", inv.ID, ErrUserExists)
+		}
+		return userbus.User{}, fmt.Errorf("user.create: inviteID[%s]: %w", inv.ID, err)
+	}
+
+	inv.UserID = uuid.NullUUID{UUID: usr.ID, Valid: true}
+	inv.DateAccepted = now
+
+	if err := b.storer.Update(ctx, inv); err != nil {
+		return userbus.User{}, fmt.Errorf("update: %w", err)
+	}
+
+	// Other domains may need to know when an invite is accepted so business
+	// logic can be applied. This represents a delegate call to other domains.
+	if err := b.delegate.Call(ctx, ActionAcceptedData(inv.ID, usr.ID)); err != nil {
+		return userbus.User{}, fmt.Errorf("failed to execute `%s` action: %w", ActionAccepted, err)
+	}
+
+	return usr, nil
+}
+
+// Revoke marks the invite as revoked so it can no longer be accepted.
+// Revoking an invite that is already revoked has no effect.
+func (b *Business) Revoke(ctx context.Context, inv Invite) (Invite, error) {
+	ctx, span := otel.AddSpan(ctx, "business.invitebus.revoke")
+	defer span.End()
+
+	if inv.Accepted() {
+		return Invite{}, fmt.Errorf("inviteID[%s]: %w", inv.ID, ErrAccepted)
+	}
+
+	if inv.Revoked() {
+		return inv, nil
+	}
+
+	inv.DateRevoked = clock.Now()
+
+	if err := b.storer.Update(ctx, inv); err != nil {
+		return Invite{}, fmt.Errorf("update: %w", err)
+	}
+
+	return inv, nil
+}
+
+// Query retrieves a list of existing invites.
+func (b *Business) Query(ctx context.Context, filter QueryFilter, orderBy order.By, page page.Page) ([]Invite, error) {
+	ctx, span := otel.AddSpan(ctx, "business.invitebus.query")
+	defer span.End()
+
+	invs, err := b.storer.Query(ctx, filter, orderBy, page)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return invs, nil
+}
+
+// Count returns the total number of invites.
+func (b *Business) Count(ctx context.Context, filter QueryFilter) (int, error) {
+	ctx, span := otel.AddSpan(ctx, "business.invitebus.count")
+	defer span.End()
+
+	return b.storer.Count(ctx, filter)
+}
+
+// QueryByID finds the invite by the specified ID.
+func (b *Business) QueryByID(ctx context.Context, inviteID uuid.UUID) (Invite, error) {
+	ctx, span := otel.AddSpan(ctx, "business.invitebus.querybyid")
+	defer span.End()
+
+	inv, err := b.storer.QueryByID(ctx, inviteID)
+	if err != nil {
+		return Invite{}, fmt.Errorf("query: inviteID[%s]: %w", inviteID, err)
+	}
+
+	return inv, nil
+}
+
+// =============================================================================
+
+// tokenPrefix marks the tokens issued by this package so they can be told
+// apart from other credentials.
+const tokenPrefix = "inv_"
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// generate returns a new plaintext token with 160 bits of randomness.
+func generate() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("read random: %w", err)
+	}
+
+	return tokenPrefix + strings.ToLower(encoding.EncodeToString(b)), nil
+}
+
+// hash returns the stored form of a plaintext token. The tokens are random
+// enough that a plain SHA-256 hash is sufficient.
+func hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,275 @@
+package invitebus_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"testing"
+
+	"github.com/ardanlabs/service/business/domain/invitebus"
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/dbtest"
+	"github.com/ardanlabs/service/business/sdk/unitest"
+	"github.com/ardanlabs/service/business/types/name"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_Invite(t *testing.T) {
+	t.Parallel()
+
+	db := dbtest.New(t, "Test_Invite")
+
+	sd, tokens, err := insertSeedData(db.BusDomain)
+	if err != nil {
+		t.Fatalf("Seeding error: %s", err)
+	}
+
+	// -------------------------------------------------------------------------
+
+	unitest.Run(t, create(db.BusDomain, sd), "create")
+	unitest.Run(t, accept(db.BusDomain, sd, tokens), "accept")
+	unitest.Run(t, revoke(db.BusDomain, sd, tokens), "revoke")
+}
+
+// =============================================================================
+
+func insertSeedData(busDomain dbtest.BusDomain) (unitest.SeedData, []string, error) {
+	ctx := context.Background()
+
+	usrs, err := userbus.TestSeedUsers(ctx, 1, role.User, busDomain.User)
+	if err != nil {
+		return unitest.SeedData{}, nil, fmt.Errorf("seeding users : %w", err)
+	}
+
+	tu1 := unitest.User{
+		User: usrs[0],
+	}
+
+	// -------------------------------------------------------------------------
+
+	usrs, err = userbus.TestSeedUsers(ctx, 1, role.Admin, busDomain.User)
+	if err != nil {
+		return unitest.SeedData{}, nil, fmt.Errorf("seeding admins : %w", err)
+	}
+
+	invs, tokens, err := invitebus.TestGenerateSeedInvites(ctx, 3, busDomain.Invite, usrs[0].ID)
+	if err != nil {
+		return unitest.SeedData{}, nil, fmt.Errorf("seeding invites : %w", err)
+	}
+
+	tu2 := unitest.User{
+		User:    usrs[0],
+		Invites: invs,
+	}
+
+	// -------------------------------------------------------------------------
+
+	sd := unitest.SeedData{
+		Users:  []unitest.User{tu1},
+		Admins: []unitest.User{tu2},
+	}
+
+	return sd, tokens, nil
+}
+
+// =============================================================================
+
+func create(busDomain dbtest.BusDomain, sd unitest.SeedData) []unitest.Table {
+	table := []unitest.Table{
+		{
+			Name: "basic",
+			ExpResp: invitebus.Invite{
+				Email:     mail.Address{Address: "invited@ardanlabs.com"},
+				Roles:     []role.Role{role.User},
+				InvitedBy: sd.Admins[0].ID,
+			},
+			ExcFunc: func(ctx context.Context) any {
+				ni := invitebus.NewInvite{
+					Email:     mail.Address{Address: "invited@ardanlabs.com"},
+					Roles:     []role.Role{role.User},
+					InvitedBy: sd.Admins[0].ID,
+				}
+
+				inv, token, err := busDomain.Invite.Create(ctx, ni)
+				if err != nil {
+					return err
+				}
+
+				if token == "" || token == inv.TokenHash {
+					return errors.New("token not returned")
+				}
+
+				if exp := inv.DateCreated.Add(invitebus.DefaultTTL); !inv.DateExpires.Equal(exp) {
+					return fmt.Errorf("got expiry %v, exp %v", inv.DateExpires, exp)
+				}
+
+				return inv
+			},
+			CmpFunc: func(got any, exp any) string {
+				gotResp, exists := got.(invitebus.Invite)
+				if !exists {
+					return fmt.Sprintf("error occurred: %v", got)
+				}
+
+				expResp := exp.(invitebus.Invite)
+
+				expResp.ID = gotResp.ID
+				expResp.TokenHash = gotResp.TokenHash
+				expResp.DateCreated = gotResp.DateCreated
+				expResp.DateExpires = gotResp.DateExpires
+
+				return cmp.Diff(gotResp, expResp)
+			},
+		},
+		{
+			Name:    "user-exists",
+			ExpResp: invitebus.ErrUserExists,
+			ExcFunc: func(ctx context.Context) any {
+				ni := invitebus.NewInvite{
+					Email:     sd.Users[0].Email,
+					Roles:     []role.Role{role.User},
+					InvitedBy: sd.Admins[0].ID,
+				}
+
+				_, _, err := busDomain.Invite.Create(ctx, ni)
+				return err
+			},
+			CmpFunc: cmpError,
+		},
+		{
+			Name:    "ttl-too-long",
+			ExpResp: invitebus.ErrTTLTooLong,
+			ExcFunc: func(ctx context.Context) any {
+				ni := invitebus.NewInvite{
+					Email:     mail.Address{Address: "later@ardanlabs.com"},
+					Roles:     []role.Role{role.User},
+					InvitedBy: sd.Admins[0].ID,
+					TTL:       invitebus.MaxTTL + 1,
+				}
+
+				_, _, err := busDomain.Invite.Create(ctx, ni)
+				return err
+			},
+			CmpFunc: cmpError,
+		},
+	}
+
+	return table
+}
+
+func accept(busDomain dbtest.BusDomain, sd unitest.SeedData, tokens []string) []unitest.Table {
+	nme := name.MustParse("Invited User")
+
+	table := []unitest.Table{
+		{
+			Name: "basic",
+			ExpResp: userbus.User{
+				Name:      nme,
+				Email:     sd.Admins[0].Invites[0].Email,
+				Roles:     sd.Admins[0].Invites[0].Roles,
+				Enabled:   true,
+				CreatedBy: sd.Admins[0].ID,
+				UpdatedBy: sd.Admins[0].ID,
+			},
+			ExcFunc: func(ctx context.Context) any {
+				usr, err := busDomain.Invite.Accept(ctx, tokens[0], nme, "gophers")
+				if err != nil {
+					return err
+				}
+
+				inv, err := busDomain.Invite.QueryByID(ctx, sd.Admins[0].Invites[0].ID)
+				if err != nil {
+					return err
+				}
+
+				if !inv.Accepted() || inv.UserID.UUID != usr.ID {
+					return fmt.Errorf("invite not accepted: %+v", inv)
+				}
+
+				return usr
+			},
+			CmpFunc: func(got any, exp any) string {
+				gotResp, exists := got.(userbus.User)
+				if !exists {
+					return fmt.Sprintf("error occurred: %v", got)
+				}
+
+				expResp := exp.(userbus.User)
+
+				expResp.ID = gotResp.ID
+				expResp.PasswordHash = gotResp.PasswordHash
+				expResp.DateCreated = gotResp.DateCreated
+				expResp.DateUpdated = gotResp.DateUpdated
+				expResp.Version = gotResp.Version
+
+				return cmp.Diff(gotResp, expResp)
+			},
+		},
+		{
+			Name:    "accepted",
+			ExpResp: invitebus.ErrAccepted,
+			ExcFunc: func(ctx context.Context) any {
+				_, err := busDomain.Invite.Accept(ctx, tokens[0], nme, "gophers")
+				return err
+			},
+			CmpFunc: cmpError,
+		},
+		{
+			Name:    "invalid-token",
+			ExpResp: invitebus.ErrInvalidToken,
+			ExcFunc: func(ctx context.Context) any {
+				_, err := busDomain.Invite.Accept(ctx, tokens[1]+"x", nme, "gophers")
+				return err
+			},
+			CmpFunc: cmpError,
+		},
+	}
+
+	return table
+}
+
+func revoke(busDomain dbtest.BusDomain, sd unitest.SeedData, tokens []string) []unitest.Table {
+	table := []unitest.Table{
+		{
+			Name:    "basic",
+			ExpResp: invitebus.ErrRevoked,
+			ExcFunc: func(ctx context.Context) any {
+				if _, err := busDomain.Invite.Revoke(ctx, sd.Admins[0].Invites[2]); err != nil {
+					return err
+				}
+
+				_, err := busDomain.Invite.Accept(ctx, tokens[2], name.MustParse("Too Late"), "gophers")
+				return err
+			},
+			CmpFunc: cmpError,
+		},
+		{
+			Name:    "accepted",
+			ExpResp: invitebus.ErrAccepted,
+			ExcFunc: func(ctx context.Context) any {
+				inv, err := busDomain.Invite.QueryByID(ctx, sd.Admins[0].Invites[0].ID)
+				if err != nil {
+					return err
+				}
+
+				_, err = busDomain.Invite.Revoke(ctx, inv)
+				return err
+			},
+			CmpFunc: cmpError,
+		},
+	}
+
+	return table
+}
+
+// =============================================================================
+
+func cmpError(got any, exp any) string {
+	err, _ := got.(error)
+	if !errors.Is(err, exp.(error)) {
+		return fmt.Sprintf("got %v, exp %v", got, exp)
+	}
+	return ""
+}
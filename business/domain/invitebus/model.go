@@ -0,0 +1,49 @@
+package invitebus
+
+import (
+	"net/mail"
+	"time"
+
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/google/uuid"
+)
+
+// Invite represents an invitation for someone to create their own user. The
+// user gets the roles the invite was created with. Only a hash of the token
+// is kept, the plaintext token is returned once when the invite is created.
+type Invite struct {
+	ID           uuid.UUID
+	Email        mail.Address `class:"confidential"`
+	Roles        []role.Role  `class:"internal"`
+	InvitedBy    uuid.UUID
+	TokenHash    string `class:"restricted"`
+	UserID       uuid.NullUUID
+	DateCreated  time.Time
+	DateExpires  time.Time
+	DateAccepted time.Time
+	DateRevoked  time.Time
+}
+
+// Accepted reports if the invite has been used to create a user.
+func (inv Invite) Accepted() bool {
+	return !inv.DateAccepted.IsZero()
+}
+
+// Revoked reports if the invite has been revoked.
+func (inv Invite) Revoked() bool {
+	return !inv.DateRevoked.IsZero()
+}
+
+// Expired reports if the invite can no longer be accepted at the time.
+func (inv Invite) Expired(now time.Time) bool {
+	return !now.Before(inv.DateExpires)
+}
+
+// NewInvite is what we require from clients when adding an Invite. An invite
+// expires after DefaultTTL unless a TTL is given.
+type NewInvite struct {
+	Email     mail.Address
+	Roles     []role.Role
+	InvitedBy uuid.UUID
+	TTL       time.Duration
+}
@@ -0,0 +1,23 @@
+package invitebus
+
+import "github.com/ardanlabs/service/business/sdk/order"
+
+// DefaultOrderBy represents the default way we sort.
+var DefaultOrderBy = order.NewBy(OrderByDateCreated, order.DESC)
+
+// Set of fields that the results can be ordered by.
+const (
+	OrderByID          = "a"
+	OrderByEmail       = "b"
+	OrderByDateCreated = "c"
+	OrderByDateExpires = "d"
+)
+
+// OrderFields represents the fields the results can be ordered by, the names
+// clients use for them and the columns the stores order by.
+var OrderFields = order.Register("invite",
+	order.Field{Name: "invite_id", Key: OrderByID, Column: "invite_id"},
+	order.Field{Name: "email", Key: OrderByEmail, Column: "email"},
+	order.Field{Name: "date_created", Key: OrderByDateCreated, Column: "date_created"},
+	order.Field{Name: "date_expires", Key: OrderByDateExpires, Column: "date_expires"},
+)
@@ -0,0 +1,74 @@
+package invitebus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ardanlabs/service/business/types/role"
+)
+
+// Sender defines the behavior required to deliver an invite to the person
+// being invited. The token is what they need to accept it.
+type Sender interface {
+	Send(ctx context.Context, inv Invite, token string) error
+}
+
+// WebhookSender delivers invites by posting them to a webhook, such as a
+// mail relay that sends the invitation email.
+type WebhookSender struct {
+	client *http.Client
+	url    string
+}
+
+// NewWebhookSender constructs a sender that delivers invites to the webhook.
+func NewWebhookSender(client *http.Client, url string) *WebhookSender {
+	return &WebhookSender{
+		client: client,
+		url:    url,
+	}
+}
+
+// Send implements the Sender interface.
+func (ws *WebhookSender) Send(ctx context.Context, inv Invite, token string) error {
+	msg := struct {
+		InviteID    string   `json:"inviteID"`
+		Email       string   `json:"email"`
+		Roles       []string `json:"roles"`
+		Token       string   `json:"token"`
+		DateExpires string   `json:"dateExpires"`
+	}{
+		InviteID:    inv.ID.String(),
+		Email:       inv.Email.Address,
+		Roles:       role.ParseToString(inv.Roles),
+		Token:       token,
+		DateExpires: inv.DateExpires.Format(time.RFC3339),
+	}
+
+	content, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ws.url, bytes.NewReader(content))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := ws.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
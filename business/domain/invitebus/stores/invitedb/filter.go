@@ -0,0 +1,45 @@
+package invitedb
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/ardanlabs/service/business/domain/invitebus"
+	"github.com/ardanlabs/service/foundation/clock"
+)
+
+func applyFilter(filter invitebus.QueryFilter, data map[string]any, buf *bytes.Buffer) {
+	var wc []string
+
+	if filter.ID != nil {
+		data["invite_id"] = filter.ID
+		wc = append(wc, "invite_id = :invite_id")
+	}
+
+	if filter.Email != nil {
+		data["email"] = filter.Email.Address
+		wc = append(wc, "email = :email")
+	}
+
+	if filter.InvitedBy != nil {
+		data["invited_by"] = filter.InvitedBy
+		wc = append(wc, "invited_by = :invited_by")
+	}
+
+	// An invite is pending while it can still be accepted.
+	if filter.Pending != nil {
+		data["now"] = clock.Now().UTC()
+
+		switch *filter.Pending {
+		case true:
+			wc = append(wc, "(date_accepted IS NULL AND date_revoked IS NULL AND date_expires > :now)")
+		default:
+			wc = append(wc, "(date_accepted IS NOT NULL OR date_revoked IS NOT NULL OR date_expires <= :now)")
+		}
+	}
+
+	if len(wc) > 0 {
+		buf.WriteString(" WHERE ")
+		buf.WriteString(strings.Join(wc, " AND "))
+	}
+}
@@ -0,0 +1,226 @@
+// Package invitedb contains invite related CRUD functionality.
+package invitedb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+
+	"github.com/ardanlabs/service/business/domain/invitebus"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Store manages the set of APIs for invite database access.
+type Store struct {
+	log *logger.Logger
+	db  sqlx.ExtContext
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *logger.Logger, db sqlx.ExtContext) *Store {
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// NewWithTx constructs a new Store value replacing the sqlx DB
+// value with a sqlx DB value that is currently inside a transaction.
+func (s *Store) NewWithTx(tx sqldb.CommitRollbacker) (invitebus.Storer, error) {
+	ec, err := sqldb.GetExtContext(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	store := Store{
+		log: s.log,
+		db:  ec,
+	}
+
+	return &store, nil
+}
+
+// Create inserts a new invite into the database.
+func (s *Store) Create(ctx context.Context, inv invitebus.Invite) error {
+	const q = `
+	INSERT INTO invites
+		(invite_id, email, roles, invited_by, token_hash, user_id, date_created, date_expires, date_accepted, date_revoked)
+	VALUES
+		(:invite_id, :email, :roles, :invited_by, :token_hash, :user_id, :date_created, :date_expires, :date_accepted, :date_revoked)`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBInvite(inv)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Update replaces an invite document in the database. An invite can only be
+// accepted once, so accepting one that was accepted since it was read fails.
+func (s *Store) Update(ctx context.Context, inv invitebus.Invite) error {
+	const q = `
+	UPDATE
+		invites
+	SET
+		"user_id" = :user_id,
+		"date_accepted" = :date_accepted,
+		"date_revoked" = :date_revoked
+	WHERE
+		invite_id = :invite_id AND
+		(date_accepted IS NULL OR date_accepted = :date_accepted)
+	RETURNING
+		invite_id`
+
+	var dest struct {
+		ID uuid.UUID `db:"invite_id"`
+	}
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, toDBInvite(inv), &dest); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return fmt.Errorf("namedquerystruct: inviteID[%s]: %w", inv.ID, invitebus.ErrAccepted)
+		}
+		return fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateEmailByUserID replaces the email of the invites accepted by the user.
+func (s *Store) UpdateEmailByUserID(ctx context.Context, userID uuid.UUID, email mail.Address) error {
+	data := struct {
+		UserID uuid.UUID `db:"user_id"`
+		Email  string    `db:"email"`
+	}{
+		UserID: userID,
+		Email:  email.Address,
+	}
+
+	const q = `
+	UPDATE
+		invites
+	SET
+		"email" = :email
+	WHERE
+		user_id = :user_id`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Query retrieves a list of existing invites from the database.
+func (s *Store) Query(ctx context.Context, filter invitebus.QueryFilter, orderBy order.By, page page.Page) ([]invitebus.Invite, error) {
+	data := map[string]any{
+		"offset":        (page.Number() - 1) * page.RowsPerPage(),
+		"rows_per_page": page.RowsPerPage(),
+	}
+
+	const q = `
+	SELECT
+		invite_id, email, roles, invited_by, token_hash, user_id, date_created, date_expires, date_accepted, date_revoked
+	FROM
+		invites`
+
+	buf := bytes.NewBufferString(q)
+	applyFilter(filter, data, buf)
+
+	orderByClause, err := orderByClause(orderBy)
+	if err != nil {
+		return nil, err
+	}
+
+	buf.WriteString(orderByClause)
+	buf.WriteString(" OFFSET :offset ROWS FETCH NEXT :rows_per_page ROWS ONLY")
+
+	var dbInvs []invite
+	if err := sqldb.NamedQuerySlice(ctx, s.log, s.db, buf.String(), data, &dbInvs); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toBusInvites(dbInvs)
+}
+
+// Count returns the total number of invites in the DB.
+func (s *Store) Count(ctx context.Context, filter invitebus.QueryFilter) (int, error) {
+	data := map[string]any{}
+
+	const q = `
+	SELECT
+		count(1)
+	FROM
+		invites`
+
+	buf := bytes.NewBufferString(q)
+	applyFilter(filter, data, buf)
+
+	var count struct {
+		Count int `db:"count"`
+	}
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, buf.String(), data, &count); err != nil {
+		return 0, fmt.Errorf("db: %w", err)
+	}
+
+	return count.Count, nil
+}
+
+// QueryByID gets the specified invite from the database.
+func (s *Store) QueryByID(ctx context.Context, inviteID uuid.UUID) (invitebus.Invite, error) {
+	data := struct {
+		ID string `db:"invite_id"`
+	}{
+		ID: inviteID.String(),
+	}
+
+	const q = `
+	SELECT
+		invite_id, email, roles, invited_by, token_hash, user_id, date_created, date_expires, date_accepted, date_revoked
+	FROM
+		invites
+	WHERE
+		invite_id = :invite_id`
+
+	var dbInv invite
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbInv); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return invitebus.Invite{}, fmt.Errorf("db: %w", invitebus.ErrNotFound)
+		}
+		return invitebus.Invite{}, fmt.Errorf("db: %w", err)
+	}
+
+	return toBusInvite(dbInv)
+}
+
+// QueryByTokenHash gets the invite the token belongs to from the database.
+func (s *Store) QueryByTokenHash(ctx context.Context, tokenHash string) (invitebus.Invite, error) {
+	data := struct {
+		TokenHash string `db:"token_hash"`
+	}{
+		TokenHash: tokenHash,
+	}
+
+	const q = `
+	SELECT
+		invite_id, email, roles, invited_by, token_hash, user_id, date_created, date_expires, date_accepted, date_revoked
+	FROM
+		invites
+	WHERE
+		token_hash = :token_hash`
+
+	var dbInv invite
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbInv); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return invitebus.Invite{}, fmt.Errorf("db: %w", invitebus.ErrNotFound)
+		}
+		return invitebus.Invite{}, fmt.Errorf("db: %w", err)
+	}
+
+	return toBusInvite(dbInv)
+}
@@ -0,0 +1,89 @@
+package invitedb
+
+import (
+	"database/sql"
+	"fmt"
+	"net/mail"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/invitebus"
+	"github.com/ardanlabs/service/business/sdk/sqldb/dbarray"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/google/uuid"
+)
+
+type invite struct {
+	ID           uuid.UUID      `db:"invite_id"`
+	Email        string         `db:"email" class:"confidential"`
+	Roles        dbarray.String `db:"roles" class:"internal"`
+	InvitedBy    uuid.UUID      `db:"invited_by"`
+	TokenHash    string         `db:"token_hash" class:"restricted"`
+	UserID       uuid.NullUUID  `db:"user_id"`
+	DateCreated  time.Time      `db:"date_created"`
+	DateExpires  time.Time      `db:"date_expires"`
+	DateAccepted sql.NullTime   `db:"date_accepted"`
+	DateRevoked  sql.NullTime   `db:"date_revoked"`
+}
+
+func toDBInvite(bus invitebus.Invite) invite {
+	return invite{
+		ID:          bus.ID,
+		Email:       bus.Email.Address,
+		Roles:       role.ParseToString(bus.Roles),
+		InvitedBy:   bus.InvitedBy,
+		TokenHash:   bus.TokenHash,
+		UserID:      bus.UserID,
+		DateCreated: bus.DateCreated.UTC(),
+		DateExpires: bus.DateExpires.UTC(),
+		DateAccepted: sql.NullTime{
+			Time:  bus.DateAccepted.UTC(),
+			Valid: !bus.DateAccepted.IsZero(),
+		},
+		DateRevoked: sql.NullTime{
+			Time:  bus.DateRevoked.UTC(),
+			Valid: !bus.DateRevoked.IsZero(),
+		},
+	}
+}
+
+func toBusInvite(db invite) (invitebus.Invite, error) {
+	roles, err := role.ParseMany(db.Roles)
+	if err != nil {
+		return invitebus.Invite{}, fmt.Errorf("parse: %w", err)
+	}
+
+	bus := invitebus.Invite{
+		ID:          db.ID,
+		Email:       mail.Address{Address: db.Email},
+		Roles:       roles,
+		InvitedBy:   db.InvitedBy,
+		TokenHash:   db.TokenHash,
+		UserID:      db.UserID,
+		DateCreated: db.DateCreated.In(time.Local),
+		DateExpires: db.DateExpires.In(time.Local),
+	}
+
+	if db.DateAccepted.Valid {
+		bus.DateAccepted = db.DateAccepted.Time.In(time.Local)
+	}
+
+	if db.DateRevoked.Valid {
+		bus.DateRevoked = db.DateRevoked.Time.In(time.Local)
+	}
+
+	return bus, nil
+}
+
+func toBusInvites(dbs []invite) ([]invitebus.Invite, error) {
+	bus := make([]invitebus.Invite, len(dbs))
+
+	for i, db := range dbs {
+		var err error
+		bus[i], err = toBusInvite(db)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return bus, nil
+}
@@ -0,0 +1,10 @@
+package invitedb
+
+import (
+	"github.com/ardanlabs/service/business/domain/invitebus"
+	"github.com/ardanlabs/service/business/sdk/order"
+)
+
+func orderByClause(orderBy order.By) (string, error) {
+	return invitebus.OrderFields.Clause(orderBy)
+}
@@ -0,0 +1,51 @@
+package invitebus
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/mail"
+
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/google/uuid"
+)
+
+// TestGenerateNewInvites is a helper method for testing.
+func TestGenerateNewInvites(n int, invitedBy uuid.UUID) []NewInvite {
+	newInvs := make([]NewInvite, n)
+
+	idx := rand.Intn(10000)
+	for i := range n {
+		idx++
+
+		ni := NewInvite{
+			Email:     mail.Address{Address: fmt.Sprintf("Invite%d@gmail.com", idx)},
+			Roles:     []role.Role{role.User},
+			InvitedBy: invitedBy,
+		}
+
+		newInvs[i] = ni
+	}
+
+	return newInvs
+}
+
+// TestGenerateSeedInvites is a helper method for testing. The tokens are
+// returned in the same order as the invites.
+func TestGenerateSeedInvites(ctx context.Context, n int, api *Business, invitedBy uuid.UUID) ([]Invite, []string, error) {
+	newInvs := TestGenerateNewInvites(n, invitedBy)
+
+	invs := make([]Invite, len(newInvs))
+	tokens := make([]string, len(newInvs))
+	for i, ni := range newInvs {
+		inv, token, err := api.Create(ctx, ni)
+		if err != nil {
+			return nil, nil, fmt.Errorf("seeding invite: idx: %d : %w", i, err)
+		}
+
+		invs[i] = inv
+		tokens[i] = token
+	}
+
+	return invs, tokens, nil
+}
@@ -0,0 +1,105 @@
+package notificationbus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/delegate"
+)
+
+// DomainName represents the name of this domain.
+const DomainName = "notification"
+
+// sendTimeout limits how long delivering a notification for an event can
+// take.
+const sendTimeout = 30 * time.Second
+
+// registerDelegateFunctions will register action functions with the delegate
+// system. If the business was constructed for query only, there won't be a
+// delegate provided. Notifications are sent on the worker pool so a slow
+// sender doesn't hold up the request, and a failure to send doesn't undo it.
+func (b *Business) registerDelegateFunctions() {
+	if b.delegate != nil {
+		b.delegate.Register(userbus.DomainName, userbus.ActionCreated, b.actionUserCreated, delegate.WithAsync(), delegate.WithTimeout(sendTimeout))
+		b.delegate.Register(userbus.DomainName, userbus.ActionUpdated, b.actionUserUpdated, delegate.WithAsync(), delegate.WithTimeout(sendTimeout), delegate.WithFields(userbus.FieldPassword))
+		b.delegate.Register(userbus.DomainName, userbus.ActionCollect, b.actionUserCollect)
+	}
+}
+
+// actionUserCreated is executed by the user domain indirectly when a user is
+// created. The user is sent the welcome notification.
+func (b *Business) actionUserCreated(ctx context.Context, data delegate.Data) error {
+	var params userbus.ActionCreatedParms
+	err := json.Unmarshal(data.RawParams, &params)
+	if err != nil {
+		return fmt.Errorf("expected an encoded %T: %w", params, err)
+	}
+
+	usr, err := b.userBus.QueryByID(ctx, params.UserID)
+	if err != nil {
+		return fmt.Errorf("user.querybyid: userID[%s]: %w", params.UserID, err)
+	}
+
+	tmplData := map[string]string{
+		"name":  usr.Name.String(),
+		"email": usr.Email.Address,
+	}
+
+	if err := b.Notify(ctx, usr, KindWelcome, tmplData); err != nil {
+		return fmt.Errorf("notify: userID[%s]: %w", usr.ID, err)
+	}
+
+	return nil
+}
+
+// actionUserUpdated is executed by the user domain indirectly when a user's
+// password is changed. The user is told so they can react if it wasn't
+// them.
+func (b *Business) actionUserUpdated(ctx context.Context, data delegate.Data) error {
+	var params userbus.ActionUpdatedParms
+	err := json.Unmarshal(data.RawParams, &params)
+	if err != nil {
+		return fmt.Errorf("expected an encoded %T: %w", params, err)
+	}
+
+	usr, err := b.userBus.QueryByID(ctx, params.UserID)
+	if err != nil {
+		return fmt.Errorf("user.querybyid: userID[%s]: %w", params.UserID, err)
+	}
+
+	tmplData := map[string]string{
+		"name": usr.Name.String(),
+	}
+
+	if err := b.Notify(ctx, usr, KindPasswordChanged, tmplData); err != nil {
+		return fmt.Errorf("notify: userID[%s]: %w", usr.ID, err)
+	}
+
+	return nil
+}
+
+// actionUserCollect is executed by the user domain indirectly when a user's
+// data is exported. The user's notification preferences are added to the
+// export. It runs synchronously since the export is written once the call
+// returns.
+func (b *Business) actionUserCollect(ctx context.Context, data delegate.Data) error {
+	var params userbus.ActionCollectParms
+	err := json.Unmarshal(data.RawParams, &params)
+	if err != nil {
+		return fmt.Errorf("expected an encoded %T: %w", params, err)
+	}
+
+	prefs, err := b.QueryPreferences(ctx, params.UserID)
+	if err != nil {
+		return fmt.Errorf("querypreferences: userID[%s]: %w", params.UserID, err)
+	}
+
+	if err := userbus.AddExportData(ctx, DomainName, prefs); err != nil {
+		return fmt.Errorf("addexportdata: userID[%s]: %w", params.UserID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package notificationbus
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/invitebus"
+	"github.com/ardanlabs/service/business/types/role"
+)
+
+// InviteSender delivers invites as notifications on the email channel.
+type InviteSender struct {
+	bus *Business
+}
+
+// NewInviteSender constructs a sender the invite domain can deliver invites
+// with.
+func NewInviteSender(bus *Business) *InviteSender {
+	return &InviteSender{
+		bus: bus,
+	}
+}
+
+// Send implements the invitebus.Sender interface. The person invited isn't a
+// user yet, so there are no preferences to respect.
+func (is *InviteSender) Send(ctx context.Context, inv invitebus.Invite, token string) error {
+	data := map[string]string{
+		"email":   inv.Email.Address,
+		"roles":   strings.Join(role.ParseToString(inv.Roles), ", "),
+		"token":   token,
+		"expires": inv.DateExpires.Format(time.RFC1123),
+	}
+
+	return is.bus.send(ctx, ChannelEmail, inv.Email, KindInvite, data)
+}
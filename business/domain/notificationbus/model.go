@@ -0,0 +1,129 @@
+package notificationbus
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The set of kinds of notification. A kind is rendered with the template of
+// the same name.
+var (
+	KindWelcome         = newKind("welcome")
+	KindPasswordChanged = newKind("password_changed")
+	KindInvite          = newKind("invite")
+	KindBreakGlass      = newKind("break_glass")
+)
+
+var kinds = make(map[string]Kind)
+
+// Kind represents the event a notification is sent for.
+type Kind struct {
+	value string
+}
+
+func newKind(kind string) Kind {
+	k := Kind{kind}
+	kinds[kind] = k
+	return k
+}
+
+// String returns the name of the kind.
+func (k Kind) String() string {
+	return k.value
+}
+
+// Equal provides support for the go-cmp package and testing.
+func (k Kind) Equal(k2 Kind) bool {
+	return k.value == k2.value
+}
+
+// MarshalText provides support for logging and any marshal needs.
+func (k Kind) MarshalText() ([]byte, error) {
+	return []byte(k.value), nil
+}
+
+// ParseKind parses the string value and returns a kind if one exists.
+func ParseKind(value string) (Kind, error) {
+	k, exists := kinds[value]
+	if !exists {
+		return Kind{}, fmt.Errorf("invalid kind %q", value)
+	}
+
+	return k, nil
+}
+
+// userKinds are the kinds sent to existing users, which they can choose the
+// channels for. Invites go to people who aren't users yet and admins can't
+// turn off being told about break-glass access.
+var userKinds = []Kind{KindWelcome, KindPasswordChanged}
+
+// =============================================================================
+
+// The set of channels a notification can be delivered through.
+var (
+	ChannelEmail = newChannel("email")
+)
+
+var channels = make(map[string]Channel)
+
+// Channel represents the way a notification is delivered.
+type Channel struct {
+	value string
+}
+
+func newChannel(channel string) Channel {
+	c := Channel{channel}
+	channels[channel] = c
+	return c
+}
+
+// String returns the name of the channel.
+func (c Channel) String() string {
+	return c.value
+}
+
+// Equal provides support for the go-cmp package and testing.
+func (c Channel) Equal(c2 Channel) bool {
+	return c.value == c2.value
+}
+
+// MarshalText provides support for logging and any marshal needs.
+func (c Channel) MarshalText() ([]byte, error) {
+	return []byte(c.value), nil
+}
+
+// ParseChannel parses the string value and returns a channel if one exists.
+func ParseChannel(value string) (Channel, error) {
+	c, exists := channels[value]
+	if !exists {
+		return Channel{}, fmt.Errorf("invalid channel %q", value)
+	}
+
+	return c, nil
+}
+
+// allChannels are the channels in the order they are tried.
+var allChannels = []Channel{ChannelEmail}
+
+// =============================================================================
+
+// Preference represents whether a user receives a kind of notification on a
+// channel. A user receives every kind on every channel until they turn it
+// off, the date updated is zero for a preference that was never set.
+type Preference struct {
+	UserID      uuid.UUID
+	Kind        Kind
+	Channel     Channel
+	Enabled     bool
+	DateUpdated time.Time
+}
+
+// UpdatePreference contains the information needed to change whether a user
+// receives a kind of notification on a channel.
+type UpdatePreference struct {
+	Kind    Kind
+	Channel Channel
+	Enabled bool
+}
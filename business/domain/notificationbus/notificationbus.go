@@ -0,0 +1,236 @@
+// Package notificationbus provides business access to the notification
+// domain. Notifications are rendered from the transactional email templates
+// and delivered through the sender configured for each channel.
+package notificationbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+
+	"github.com/ardanlabs/service/business/domain/templatebus"
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/buserr"
+	"github.com/ardanlabs/service/business/sdk/delegate"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/ardanlabs/service/foundation/clock"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/otel"
+	"github.com/google/uuid"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrNotUserKind = buserr.New(buserr.InvalidArgument, "kind isn't sent to users")
+)
+
+// Storer interface declares the behavior this package needs to persist and
+// retrieve data.
+type Storer interface {
+	NewWithTx(tx sqldb.CommitRollbacker) (Storer, error)
+	QueryPreferences(ctx context.Context, userID uuid.UUID) ([]Preference, error)
+	UpsertPreference(ctx context.Context, pref Preference) error
+}
+
+// Business manages the set of APIs for notification access.
+type Business struct {
+	log         *logger.Logger
+	userBus     userbus.Business
+	templateBus *templatebus.Business
+	delegate    *delegate.Delegate
+	storer      Storer
+	senders     map[Channel]Sender
+}
+
+// NewBusiness constructs a notification business API for use. The senders
+// provide delivery for each channel, a notification for a channel without
+// a sender is skipped.
+func NewBusiness(log *logger.Logger, userBus userbus.Business, templateBus *templatebus.Business, delegate *delegate.Delegate, storer Storer, senders map[Channel]Sender) *Business {
+	b := Business{
+		log:         log,
+		userBus:     userBus,
+		templateBus: templateBus,
+		delegate:    delegate,
+		storer:      storer,
+		senders:     senders,
+	}
+
+	b.registerDelegateFunctions()
+
+	return &b
+}
+
+// NewWithTx constructs a new business value that will use the
+// specified transaction in any store related calls.
+func (b *Business) NewWithTx(tx sqldb.CommitRollbacker) (*Business, error) {
+	storer, err := b.storer.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	userBus, err := b.userBus.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	templateBus, err := b.templateBus.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	bus := Business{
+		log:         b.log,
+		userBus:     userBus,
+		templateBus: templateBus,
+		delegate:    b.delegate,
+		storer:      storer,
+		senders:     b.senders,
+	}
+
+	return &bus, nil
+}
+
+// Notify renders the kind of notification for the user and delivers it on
+// every channel they haven't turned it off for.
+func (b *Business) Notify(ctx context.Context, usr userbus.User, kind Kind, data map[string]string) error {
+	ctx, span := otel.AddSpan(ctx, "business.notificationbus.notify")
+	defer span.End()
+
+	prefs, err := b.QueryPreferences(ctx, usr.ID)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, pref := range prefs {
+		if !pref.Kind.Equal(kind) || !pref.Enabled {
+			continue
+		}
+
+		if err := b.send(ctx, pref.Channel, usr.Email, kind, data); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// NotifyAdmins renders the kind of notification and emails it to every
+// enabled admin. It's meant for events about the security of the service,
+// so their preferences aren't consulted.
+func (b *Business) NotifyAdmins(ctx context.Context, kind Kind, data map[string]string) error {
+	ctx, span := otel.AddSpan(ctx, "business.notificationbus.notifyadmins")
+	defer span.End()
+
+	enabled := true
+	filter := userbus.QueryFilter{
+		Roles:   []role.Role{role.Admin},
+		Enabled: &enabled,
+	}
+
+	var errs []error
+	err := b.userBus.QueryAll(ctx, filter, userbus.DefaultOrderBy, func(usr userbus.User) error {
+		if err := b.send(ctx, ChannelEmail, usr.Email, kind, data); err != nil {
+			errs = append(errs, fmt.Errorf("userID[%s]: %w", usr.ID, err))
+		}
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("queryall: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// QueryPreferences returns the preferences of the user for every kind and
+// channel, including the ones they never set.
+func (b *Business) QueryPreferences(ctx context.Context, userID uuid.UUID) ([]Preference, error) {
+	ctx, span := otel.AddSpan(ctx, "business.notificationbus.querypreferences")
+	defer span.End()
+
+	stored, err := b.storer.QueryPreferences(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("querypreferences: userID[%s]: %w", userID, err)
+	}
+
+	prefs := make([]Preference, 0, len(userKinds)*len(allChannels))
+	for _, kind := range userKinds {
+		for _, channel := range allChannels {
+			pref := Preference{
+				UserID:  userID,
+				Kind:    kind,
+				Channel: channel,
+				Enabled: true,
+			}
+
+			for _, s := range stored {
+				if s.Kind.Equal(kind) && s.Channel.Equal(channel) {
+					pref = s
+					break
+				}
+			}
+
+			prefs = append(prefs, pref)
+		}
+	}
+
+	return prefs, nil
+}
+
+// UpdatePreference turns a kind of notification on or off for the user on
+// a channel.
+func (b *Business) UpdatePreference(ctx context.Context, userID uuid.UUID, up UpdatePreference) (Preference, error) {
+	ctx, span := otel.AddSpan(ctx, "business.notificationbus.updatepreference")
+	defer span.End()
+
+	if !isUserKind(up.Kind) {
+		return Preference{}, fmt.Errorf("kind[%s]: %w", up.Kind, ErrNotUserKind)
+	}
+
+	pref := Preference{
+		UserID:      userID,
+		Kind:        up.Kind,
+		Channel:     up.Channel,
+		Enabled:     up.Enabled,
+		DateUpdated: clock.Now(),
+	}
+
+	if err := b.storer.UpsertPreference(ctx, pref); err != nil {
+		return Preference{}, fmt.Errorf("upsertpreference: userID[%s]: %w", userID, err)
+	}
+
+	return pref, nil
+}
+
+// =============================================================================
+
+// send renders the kind of notification and delivers it on the channel.
+func (b *Business) send(ctx context.Context, channel Channel, to mail.Address, kind Kind, data map[string]string) error {
+	sender, exists := b.senders[channel]
+	if !exists {
+		return nil
+	}
+
+	msg, err := b.templateBus.Render(ctx, kind.String(), templatebus.DefaultLocale, data)
+	if err != nil {
+		return fmt.Errorf("render: kind[%s]: %w", kind, err)
+	}
+
+	if err := sender.Send(ctx, to, msg.Subject, msg.Body); err != nil {
+		return fmt.Errorf("send: kind[%s] channel[%s]: %w", kind, channel, err)
+	}
+
+	return nil
+}
+
+func isUserKind(kind Kind) bool {
+	for _, k := range userKinds {
+		if k.Equal(kind) {
+			return true
+		}
+	}
+
+	return false
+}
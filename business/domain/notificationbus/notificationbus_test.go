@@ -0,0 +1,306 @@
+package notificationbus_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/invitebus"
+	"github.com/ardanlabs/service/business/domain/notificationbus"
+	"github.com/ardanlabs/service/business/domain/notificationbus/stores/notificationdb"
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/dbtest"
+	"github.com/ardanlabs/service/business/sdk/unitest"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+)
+
+func Test_Notification(t *testing.T) {
+	t.Parallel()
+
+	db := dbtest.New(t, "Test_Notification")
+
+	sd, err := insertSeedData(db.BusDomain)
+	if err != nil {
+		t.Fatalf("Seeding error: %s", err)
+	}
+
+	// The business is built with a sender that keeps what it's given so the
+	// delivered notifications can be checked.
+	sender := &captureSender{}
+	senders := map[notificationbus.Channel]notificationbus.Sender{
+		notificationbus.ChannelEmail: sender,
+	}
+	bus := notificationbus.NewBusiness(db.Log, db.BusDomain.User, db.BusDomain.Template, nil, notificationdb.NewStore(db.Log, db.DB), senders)
+
+	// -------------------------------------------------------------------------
+
+	unitest.Run(t, preferences(bus, sd), "preferences")
+	unitest.Run(t, notify(bus, sender, sd), "notify")
+	unitest.Run(t, invite(bus, sender), "invite")
+	unitest.Run(t, notifyAdmins(bus, sender, sd), "notifyadmins")
+}
+
+// =============================================================================
+
+func insertSeedData(busDomain dbtest.BusDomain) (unitest.SeedData, error) {
+	ctx := context.Background()
+
+	usrs, err := userbus.TestSeedUsers(ctx, 2, role.User, busDomain.User)
+	if err != nil {
+		return unitest.SeedData{}, fmt.Errorf("seeding users : %w", err)
+	}
+
+	admins, err := userbus.TestSeedUsers(ctx, 1, role.Admin, busDomain.User)
+	if err != nil {
+		return unitest.SeedData{}, fmt.Errorf("seeding admins : %w", err)
+	}
+
+	sd := unitest.SeedData{
+		Users:  []unitest.User{{User: usrs[0]}, {User: usrs[1]}},
+		Admins: []unitest.User{{User: admins[0]}},
+	}
+
+	return sd, nil
+}
+
+// =============================================================================
+
+type sent struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type captureSender struct {
+	mu   sync.Mutex
+	sent []sent
+}
+
+func (cs *captureSender) Send(ctx context.Context, to mail.Address, subject string, body string) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	cs.sent = append(cs.sent, sent{To: to.Address, Subject: subject, Body: body})
+
+	return nil
+}
+
+// take returns what was sent since the last call.
+func (cs *captureSender) take() []sent {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	s := cs.sent
+	cs.sent = nil
+
+	return s
+}
+
+// =============================================================================
+
+func preferences(bus *notificationbus.Business, sd unitest.SeedData) []unitest.Table {
+	userID := sd.Users[0].ID
+
+	table := []unitest.Table{
+		{
+			Name: "defaults",
+			ExpResp: []notificationbus.Preference{
+				{UserID: userID, Kind: notificationbus.KindWelcome, Channel: notificationbus.ChannelEmail, Enabled: true},
+				{UserID: userID, Kind: notificationbus.KindPasswordChanged, Channel: notificationbus.ChannelEmail, Enabled: true},
+			},
+			ExcFunc: func(ctx context.Context) any {
+				prefs, err := bus.QueryPreferences(ctx, userID)
+				if err != nil {
+					return err
+				}
+
+				return prefs
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+		{
+			Name: "update",
+			ExpResp: []notificationbus.Preference{
+				{UserID: userID, Kind: notificationbus.KindWelcome, Channel: notificationbus.ChannelEmail, Enabled: true},
+				{UserID: userID, Kind: notificationbus.KindPasswordChanged, Channel: notificationbus.ChannelEmail, Enabled: false},
+			},
+			ExcFunc: func(ctx context.Context) any {
+				up := notificationbus.UpdatePreference{
+					Kind:    notificationbus.KindPasswordChanged,
+					Channel: notificationbus.ChannelEmail,
+					Enabled: false,
+				}
+
+				if _, err := bus.UpdatePreference(ctx, userID, up); err != nil {
+					return err
+				}
+
+				prefs, err := bus.QueryPreferences(ctx, userID)
+				if err != nil {
+					return err
+				}
+
+				return prefs
+			},
+			CmpFunc: func(got any, exp any) string {
+				gotResp, exists := got.([]notificationbus.Preference)
+				if !exists {
+					return fmt.Sprintf("error occurred: %v", got)
+				}
+
+				expResp := exp.([]notificationbus.Preference)
+				expResp[1].DateUpdated = gotResp[1].DateUpdated
+
+				return cmp.Diff(gotResp, expResp)
+			},
+		},
+		{
+			Name:    "not-user-kind",
+			ExpResp: notificationbus.ErrNotUserKind,
+			ExcFunc: func(ctx context.Context) any {
+				up := notificationbus.UpdatePreference{
+					Kind:    notificationbus.KindInvite,
+					Channel: notificationbus.ChannelEmail,
+				}
+
+				_, err := bus.UpdatePreference(ctx, userID, up)
+				return err
+			},
+			CmpFunc: func(got any, exp any) string {
+				err, _ := got.(error)
+				if !errors.Is(err, exp.(error)) {
+					return fmt.Sprintf("got %v, exp %v", got, exp)
+				}
+				return ""
+			},
+		},
+	}
+
+	return table
+}
+
+func notify(bus *notificationbus.Business, sender *captureSender, sd unitest.SeedData) []unitest.Table {
+	table := []unitest.Table{
+		{
+			Name: "sent",
+			ExpResp: []sent{
+				{To: sd.Users[1].Email.Address, Subject: "Your password was changed"},
+			},
+			ExcFunc: func(ctx context.Context) any {
+				data := map[string]string{"name": sd.Users[1].Name.String()}
+
+				if err := bus.Notify(ctx, sd.Users[1].User, notificationbus.KindPasswordChanged, data); err != nil {
+					return err
+				}
+
+				return sender.take()
+			},
+			CmpFunc: cmpSent,
+		},
+		{
+			Name:    "turned-off",
+			ExpResp: []sent(nil),
+			ExcFunc: func(ctx context.Context) any {
+				data := map[string]string{"name": sd.Users[0].Name.String()}
+
+				if err := bus.Notify(ctx, sd.Users[0].User, notificationbus.KindPasswordChanged, data); err != nil {
+					return err
+				}
+
+				return sender.take()
+			},
+			CmpFunc: cmpSent,
+		},
+	}
+
+	return table
+}
+
+func invite(bus *notificationbus.Business, sender *captureSender) []unitest.Table {
+	table := []unitest.Table{
+		{
+			Name: "sent",
+			ExpResp: []sent{
+				{To: "invited@ardanlabs.com", Subject: "You've been invited"},
+			},
+			ExcFunc: func(ctx context.Context) any {
+				inv := invitebus.Invite{
+					ID:          uuid.New(),
+					Email:       mail.Address{Address: "invited@ardanlabs.com"},
+					Roles:       []role.Role{role.User},
+					DateExpires: time.Now().Add(time.Hour),
+				}
+
+				if err := notificationbus.NewInviteSender(bus).Send(ctx, inv, "inv_token"); err != nil {
+					return err
+				}
+
+				s := sender.take()
+				if len(s) != 1 || !strings.Contains(s[0].Body, "inv_token") {
+					return fmt.Errorf("token not in the invite: %+v", s)
+				}
+
+				return s
+			},
+			CmpFunc: cmpSent,
+		},
+	}
+
+	return table
+}
+
+func notifyAdmins(bus *notificationbus.Business, sender *captureSender, sd unitest.SeedData) []unitest.Table {
+	table := []unitest.Table{
+		{
+			Name: "sent",
+			ExpResp: []sent{
+				{To: sd.Admins[0].Email.Address, Subject: "Break-glass access was granted"},
+			},
+			ExcFunc: func(ctx context.Context) any {
+				data := map[string]string{
+					"reason":   "idp outage",
+					"remoteIP": "127.0.0.1",
+					"expires":  time.Now().Add(time.Hour).Format(time.RFC1123),
+				}
+
+				if err := bus.NotifyAdmins(ctx, notificationbus.KindBreakGlass, data); err != nil {
+					return err
+				}
+
+				s := sender.take()
+				if len(s) != 1 || !strings.Contains(s[0].Body, "idp outage") {
+					return fmt.Errorf("reason not in the notification: %+v", s)
+				}
+
+				return s
+			},
+			CmpFunc: cmpSent,
+		},
+	}
+
+	return table
+}
+
+// cmpSent compares what was sent without the bodies, which come from the
+// templates.
+func cmpSent(got any, exp any) string {
+	gotResp, exists := got.([]sent)
+	if !exists {
+		return fmt.Sprintf("error occurred: %v", got)
+	}
+
+	for i := range gotResp {
+		gotResp[i].Body = ""
+	}
+
+	return cmp.Diff(gotResp, exp.([]sent))
+}
@@ -0,0 +1,13 @@
+package notificationbus
+
+import (
+	"context"
+	"net/mail"
+)
+
+// Sender defines the behavior required to deliver a rendered notification
+// through a channel. The packages under foundation/mailer provide senders
+// for the email channel.
+type Sender interface {
+	Send(ctx context.Context, to mail.Address, subject string, body string) error
+}
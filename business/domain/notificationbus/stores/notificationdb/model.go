@@ -0,0 +1,63 @@
+package notificationdb
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/notificationbus"
+	"github.com/google/uuid"
+)
+
+type preference struct {
+	UserID      uuid.UUID `db:"user_id"`
+	Kind        string    `db:"kind"`
+	Channel     string    `db:"channel"`
+	Enabled     bool      `db:"enabled"`
+	DateUpdated time.Time `db:"date_updated"`
+}
+
+func toDBPreference(bus notificationbus.Preference) preference {
+	return preference{
+		UserID:      bus.UserID,
+		Kind:        bus.Kind.String(),
+		Channel:     bus.Channel.String(),
+		Enabled:     bus.Enabled,
+		DateUpdated: bus.DateUpdated.UTC(),
+	}
+}
+
+func toBusPreference(db preference) (notificationbus.Preference, error) {
+	kind, err := notificationbus.ParseKind(db.Kind)
+	if err != nil {
+		return notificationbus.Preference{}, fmt.Errorf("parse kind: %w", err)
+	}
+
+	channel, err := notificationbus.ParseChannel(db.Channel)
+	if err != nil {
+		return notificationbus.Preference{}, fmt.Errorf("parse channel: %w", err)
+	}
+
+	bus := notificationbus.Preference{
+		UserID:      db.UserID,
+		Kind:        kind,
+		Channel:     channel,
+		Enabled:     db.Enabled,
+		DateUpdated: db.DateUpdated.In(time.Local),
+	}
+
+	return bus, nil
+}
+
+func toBusPreferences(dbs []preference) ([]notificationbus.Preference, error) {
+	bus := make([]notificationbus.Preference, len(dbs))
+
+	for i, db := range dbs {
+		var err error
+		bus[i], err = toBusPreference(db)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return bus, nil
+}
@@ -0,0 +1,88 @@
+// Package notificationdb contains notification related CRUD functionality.
+package notificationdb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ardanlabs/service/business/domain/notificationbus"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Store manages the set of APIs for notification database access.
+type Store struct {
+	log *logger.Logger
+	db  sqlx.ExtContext
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *logger.Logger, db sqlx.ExtContext) *Store {
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// NewWithTx constructs a new Store value replacing the sqlx DB
+// value with a sqlx DB value that is currently inside a transaction.
+func (s *Store) NewWithTx(tx sqldb.CommitRollbacker) (notificationbus.Storer, error) {
+	ec, err := sqldb.GetExtContext(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	store := Store{
+		log: s.log,
+		db:  ec,
+	}
+
+	return &store, nil
+}
+
+// QueryPreferences retrieves the preferences the user has set.
+func (s *Store) QueryPreferences(ctx context.Context, userID uuid.UUID) ([]notificationbus.Preference, error) {
+	data := struct {
+		UserID string `db:"user_id"`
+	}{
+		UserID: userID.String(),
+	}
+
+	const q = `
+	SELECT
+		user_id, kind, channel, enabled, date_updated
+	FROM
+		notification_preferences
+	WHERE
+		user_id = :user_id
+	ORDER BY
+		kind, channel`
+
+	var dbPrefs []preference
+	if err := sqldb.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbPrefs); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toBusPreferences(dbPrefs)
+}
+
+// UpsertPreference adds the preference or replaces the one the user has
+// already set for the kind and channel.
+func (s *Store) UpsertPreference(ctx context.Context, pref notificationbus.Preference) error {
+	const q = `
+	INSERT INTO notification_preferences
+		(user_id, kind, channel, enabled, date_updated)
+	VALUES
+		(:user_id, :kind, :channel, :enabled, :date_updated)
+	ON CONFLICT (user_id, kind, channel) DO UPDATE SET
+		enabled = EXCLUDED.enabled,
+		date_updated = EXCLUDED.date_updated`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBPreference(pref)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,10 @@
+package permissionbus
+
+import "github.com/ardanlabs/service/business/types/role"
+
+// QueryFilter holds the available fields a query can be filtered on.
+// We are using pointer semantics because the With API mutates the value.
+type QueryFilter struct {
+	Name *string
+	Role *role.Role
+}
@@ -0,0 +1,28 @@
+package permissionbus
+
+import (
+	"time"
+
+	"github.com/ardanlabs/service/business/types/role"
+)
+
+// Permission represents a named capability that can be granted to roles.
+// Names take the form resource:action, such as user:delete.
+type Permission struct {
+	Name        string
+	Description string
+	DateCreated time.Time
+}
+
+// NewPermission is what we require from clients when adding a Permission.
+type NewPermission struct {
+	Name        string
+	Description string
+}
+
+// Grant represents a permission granted to a role.
+type Grant struct {
+	Role        role.Role
+	Permission  string
+	DateCreated time.Time
+}
@@ -0,0 +1,19 @@
+package permissionbus
+
+import "github.com/ardanlabs/service/business/sdk/order"
+
+// DefaultOrderBy represents the default way we sort.
+var DefaultOrderBy = order.NewBy(OrderByName, order.ASC)
+
+// Set of fields that the results can be ordered by.
+const (
+	OrderByName        = "a"
+	OrderByDateCreated = "b"
+)
+
+// OrderFields represents the fields the results can be ordered by, the names
+// clients use for them and the columns the stores order by.
+var OrderFields = order.Register("permission",
+	order.Field{Name: "name", Key: OrderByName, Column: "name"},
+	order.Field{Name: "date_created", Key: OrderByDateCreated, Column: "date_created"},
+)
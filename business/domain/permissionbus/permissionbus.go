@@ -0,0 +1,232 @@
+// Package permissionbus provides business access to the permission domain.
+// Permissions complement roles: a role is granted a set of named
+// permissions, and code that needs finer control than a role asks whether
+// a user holds a permission through any of its roles.
+package permissionbus
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/buserr"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/ardanlabs/service/foundation/clock"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/otel"
+	"github.com/google/uuid"
+)
+
+// Manage is the permission to manage permissions and grants. It's granted to
+// admins when the tables are created.
+const Manage = "permission:manage"
+
+// Set of error variables for CRUD operations.
+var (
+	ErrNotFound    = buserr.New(buserr.NotFound, "permission not found")
+	ErrUniqueName  = buserr.New(buserr.Conflict, "permission already exists")
+	ErrInvalidName = buserr.New(buserr.InvalidArgument, "permission name must look like resource:action")
+)
+
+// validName matches the resource:action form of a permission name.
+var validName = regexp.MustCompile(`^[a-z][a-z0-9_]*:[a-z][a-z0-9_]*$`)
+
+// Storer interface declares the behaviour this package needs to persist and
+// retrieve data.
+type Storer interface {
+	NewWithTx(tx sqldb.CommitRollbacker) (Storer, error)
+	Create(ctx context.Context, perm Permission) error
+	Delete(ctx context.Context, perm Permission) error
+	Query(ctx context.Context, filter QueryFilter, orderBy order.By, page page.Page) ([]Permission, error)
+	Count(ctx context.Context, filter QueryFilter) (int, error)
+	QueryByName(ctx context.Context, name string) (Permission, error)
+	Grant(ctx context.Context, grant Grant) error
+	Revoke(ctx context.Context, grant Grant) error
+	Granted(ctx context.Context, roles []role.Role, name string) (bool, error)
+}
+
+// Business manages the set of APIs for permission access.
+type Business struct {
+	log     *logger.Logger
+	userBus userbus.Business
+	storer  Storer
+}
+
+// NewBusiness constructs a permission business API for use.
+func NewBusiness(log *logger.Logger, userBus userbus.Business, storer Storer) *Business {
+	return &Business{
+		log:     log,
+		userBus: userBus,
+		storer:  storer,
+	}
+}
+
+// NewWithTx constructs a new domain value that will use the
+// specified transaction in any store related calls.
+func (b *Business) NewWithTx(tx sqldb.CommitRollbacker) (*Business, error) {
+	storer, err := b.storer.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	userBus, err := b.userBus.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	bus := Business{
+		log:     b.log,
+		userBus: userBus,
+		storer:  storer,
+	}
+
+	return &bus, nil
+}
+
+// Create adds a new permission to the system. It isn't granted to any role.
+func (b *Business) Create(ctx context.Context, np NewPermission) (Permission, error) {
+	ctx, span := otel.AddSpan(ctx, "business.permissionbus.create")
+	defer span.End()
+
+	if !validName.MatchString(np.Name) {
+		return Permission{}, fmt.Errorf("name[%s]: %w", np.Name, ErrInvalidName)
+	}
+
+	perm := Permission{
+		Name:        np.Name,
+		Description: np.Description,
+		DateCreated: clock.Now(),
+	}
+
+	if err := b.storer.Create(ctx, perm); err != nil {
+		return Permission{}, fmt.Errorf("create: %w", err)
+	}
+
+	return perm, nil
+}
+
+// Delete removes the specified permission and every grant of it.
+func (b *Business) Delete(ctx context.Context, perm Permission) error {
+	ctx, span := otel.AddSpan(ctx, "business.permissionbus.delete")
+	defer span.End()
+
+	if err := b.storer.Delete(ctx, perm); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	return nil
+}
+
+// Query retrieves a list of existing permissions.
+func (b *Business) Query(ctx context.Context, filter QueryFilter, orderBy order.By, page page.Page) ([]Permission, error) {
+	ctx, span := otel.AddSpan(ctx, "business.permissionbus.query")
+	defer span.End()
+
+	perms, err := b.storer.Query(ctx, filter, orderBy, page)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return perms, nil
+}
+
+// Count returns the total number of permissions.
+func (b *Business) Count(ctx context.Context, filter QueryFilter) (int, error) {
+	ctx, span := otel.AddSpan(ctx, "business.permissionbus.count")
+	defer span.End()
+
+	return b.storer.Count(ctx, filter)
+}
+
+// QueryByName finds the permission by the specified name.
+func (b *Business) QueryByName(ctx context.Context, name string) (Permission, error) {
+	ctx, span := otel.AddSpan(ctx, "business.permissionbus.querybyname")
+	defer span.End()
+
+	perm, err := b.storer.QueryByName(ctx, name)
+	if err != nil {
+		return Permission{}, fmt.Errorf("query: name[%s]: %w", name, err)
+	}
+
+	return perm, nil
+}
+
+// =============================================================================
+
+// Grant gives the role the permission. Granting a permission the role
+// already has is not an error.
+func (b *Business) Grant(ctx context.Context, r role.Role, perm Permission) (Grant, error) {
+	ctx, span := otel.AddSpan(ctx, "business.permissionbus.grant")
+	defer span.End()
+
+	grant := Grant{
+		Role:        r,
+		Permission:  perm.Name,
+		DateCreated: clock.Now(),
+	}
+
+	if err := b.storer.Grant(ctx, grant); err != nil {
+		return Grant{}, fmt.Errorf("grant: %w", err)
+	}
+
+	return grant, nil
+}
+
+// Revoke takes the permission away from the role.
+func (b *Business) Revoke(ctx context.Context, r role.Role, perm Permission) error {
+	ctx, span := otel.AddSpan(ctx, "business.permissionbus.revoke")
+	defer span.End()
+
+	grant := Grant{
+		Role:       r,
+		Permission: perm.Name,
+	}
+
+	if err := b.storer.Revoke(ctx, grant); err != nil {
+		return fmt.Errorf("revoke: %w", err)
+	}
+
+	return nil
+}
+
+// HasRolePermission reports whether any of the roles has been granted the
+// permission.
+func (b *Business) HasRolePermission(ctx context.Context, roles []role.Role, name string) (bool, error) {
+	ctx, span := otel.AddSpan(ctx, "business.permissionbus.hasrolepermission")
+	defer span.End()
+
+	if len(roles) == 0 {
+		return false, nil
+	}
+
+	ok, err := b.storer.Granted(ctx, roles, name)
+	if err != nil {
+		return false, fmt.Errorf("granted: %w", err)
+	}
+
+	return ok, nil
+}
+
+// HasPermission reports whether the user holds the permission through any
+// of its roles. The roles are read from the stored user rather than a token
+// so a change to them takes effect straight away. A disabled user holds no
+// permissions.
+func (b *Business) HasPermission(ctx context.Context, userID uuid.UUID, name string) (bool, error) {
+	ctx, span := otel.AddSpan(ctx, "business.permissionbus.haspermission")
+	defer span.End()
+
+	usr, err := b.userBus.QueryByID(ctx, userID)
+	if err != nil {
+		return false, fmt.Errorf("user.querybyid: %s: %w", userID, err)
+	}
+
+	if !usr.Enabled {
+		return false, nil
+	}
+
+	return b.HasRolePermission(ctx, usr.Roles, name)
+}
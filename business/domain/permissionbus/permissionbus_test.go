@@ -0,0 +1,276 @@
+package permissionbus_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ardanlabs/service/business/domain/permissionbus"
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/dbtest"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/sdk/unitest"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_Permission(t *testing.T) {
+	t.Parallel()
+
+	db := dbtest.New(t, "Test_Permission")
+
+	sd, err := insertSeedData(db.BusDomain)
+	if err != nil {
+		t.Fatalf("Seeding error: %s", err)
+	}
+
+	// -------------------------------------------------------------------------
+
+	unitest.Run(t, create(db.BusDomain), "create")
+	unitest.Run(t, hasPermission(db.BusDomain, sd), "haspermission")
+	unitest.Run(t, delete(db.BusDomain, sd), "delete")
+}
+
+// =============================================================================
+
+func insertSeedData(busDomain dbtest.BusDomain) (unitest.SeedData, error) {
+	ctx := context.Background()
+
+	usrs, err := userbus.TestSeedUsers(ctx, 1, role.User, busDomain.User)
+	if err != nil {
+		return unitest.SeedData{}, fmt.Errorf("seeding users : %w", err)
+	}
+
+	admins, err := userbus.TestSeedUsers(ctx, 1, role.Admin, busDomain.User)
+	if err != nil {
+		return unitest.SeedData{}, fmt.Errorf("seeding admins : %w", err)
+	}
+
+	sd := unitest.SeedData{
+		Users:  []unitest.User{{User: usrs[0]}},
+		Admins: []unitest.User{{User: admins[0]}},
+	}
+
+	return sd, nil
+}
+
+// =============================================================================
+
+func create(busDomain dbtest.BusDomain) []unitest.Table {
+	table := []unitest.Table{
+		{
+			Name: "basic",
+			ExpResp: permissionbus.Permission{
+				Name:        "report:export",
+				Description: "Export reports",
+			},
+			ExcFunc: func(ctx context.Context) any {
+				np := permissionbus.NewPermission{
+					Name:        "report:export",
+					Description: "Export reports",
+				}
+
+				if _, err := busDomain.Permission.Create(ctx, np); err != nil {
+					return err
+				}
+
+				resp, err := busDomain.Permission.QueryByName(ctx, "report:export")
+				if err != nil {
+					return err
+				}
+
+				return resp
+			},
+			CmpFunc: func(got any, exp any) string {
+				gotResp, exists := got.(permissionbus.Permission)
+				if !exists {
+					return "error occurred"
+				}
+
+				expResp := exp.(permissionbus.Permission)
+				expResp.DateCreated = gotResp.DateCreated
+
+				return cmp.Diff(gotResp, expResp)
+			},
+		},
+		{
+			Name:    "duplicate",
+			ExpResp: permissionbus.ErrUniqueName,
+			ExcFunc: func(ctx context.Context) any {
+				_, err := busDomain.Permission.Create(ctx, permissionbus.NewPermission{Name: "report:export"})
+				return err
+			},
+			CmpFunc: cmpError,
+		},
+		{
+			Name:    "invalid-name",
+			ExpResp: permissionbus.ErrInvalidName,
+			ExcFunc: func(ctx context.Context) any {
+				_, err := busDomain.Permission.Create(ctx, permissionbus.NewPermission{Name: "Export Reports"})
+				return err
+			},
+			CmpFunc: cmpError,
+		},
+	}
+
+	return table
+}
+
+func hasPermission(busDomain dbtest.BusDomain, sd unitest.SeedData) []unitest.Table {
+	check := func(ctx context.Context, usr unitest.User, name string) any {
+		ok, err := busDomain.Permission.HasPermission(ctx, usr.ID, name)
+		if err != nil {
+			return err
+		}
+
+		return ok
+	}
+
+	table := []unitest.Table{
+		{
+			Name:    "admin-manage",
+			ExpResp: true,
+			ExcFunc: func(ctx context.Context) any {
+				return check(ctx, sd.Admins[0], permissionbus.Manage)
+			},
+			CmpFunc: cmpValue,
+		},
+		{
+			Name:    "user-manage",
+			ExpResp: false,
+			ExcFunc: func(ctx context.Context) any {
+				return check(ctx, sd.Users[0], permissionbus.Manage)
+			},
+			CmpFunc: cmpValue,
+		},
+		{
+			Name:    "grant",
+			ExpResp: true,
+			ExcFunc: func(ctx context.Context) any {
+				perm, err := busDomain.Permission.QueryByName(ctx, "report:export")
+				if err != nil {
+					return err
+				}
+
+				if _, err := busDomain.Permission.Grant(ctx, role.User, perm); err != nil {
+					return err
+				}
+
+				// Granting twice is not an error.
+				if _, err := busDomain.Permission.Grant(ctx, role.User, perm); err != nil {
+					return err
+				}
+
+				return check(ctx, sd.Users[0], "report:export")
+			},
+			CmpFunc: cmpValue,
+		},
+		{
+			Name:    "by-role",
+			ExpResp: []string{"report:export"},
+			ExcFunc: func(ctx context.Context) any {
+				r := role.User
+				filter := permissionbus.QueryFilter{Role: &r}
+
+				resp, err := busDomain.Permission.Query(ctx, filter, permissionbus.DefaultOrderBy, page.MustParse("1", "10"))
+				if err != nil {
+					return err
+				}
+
+				names := make([]string, len(resp))
+				for i, perm := range resp {
+					names[i] = perm.Name
+				}
+
+				return names
+			},
+			CmpFunc: cmpValue,
+		},
+		{
+			Name:    "disabled",
+			ExpResp: false,
+			ExcFunc: func(ctx context.Context) any {
+				enabled := false
+				usr, err := busDomain.User.Update(ctx, sd.Admins[0].ID, sd.Users[0].User, userbus.UpdateUser{Enabled: &enabled})
+				if err != nil {
+					return err
+				}
+
+				return check(ctx, unitest.User{User: usr}, "report:export")
+			},
+			CmpFunc: cmpValue,
+		},
+		{
+			Name:    "revoke",
+			ExpResp: false,
+			ExcFunc: func(ctx context.Context) any {
+				perm, err := busDomain.Permission.QueryByName(ctx, "report:export")
+				if err != nil {
+					return err
+				}
+
+				if _, err := busDomain.Permission.Grant(ctx, role.Admin, perm); err != nil {
+					return err
+				}
+
+				if err := busDomain.Permission.Revoke(ctx, role.Admin, perm); err != nil {
+					return err
+				}
+
+				return check(ctx, sd.Admins[0], "report:export")
+			},
+			CmpFunc: cmpValue,
+		},
+	}
+
+	return table
+}
+
+func delete(busDomain dbtest.BusDomain, sd unitest.SeedData) []unitest.Table {
+	table := []unitest.Table{
+		{
+			Name:    "grants-removed",
+			ExpResp: false,
+			ExcFunc: func(ctx context.Context) any {
+				perm, err := busDomain.Permission.QueryByName(ctx, "report:export")
+				if err != nil {
+					return err
+				}
+
+				if err := busDomain.Permission.Delete(ctx, perm); err != nil {
+					return err
+				}
+
+				if _, err := busDomain.Permission.QueryByName(ctx, perm.Name); !errors.Is(err, permissionbus.ErrNotFound) {
+					return fmt.Errorf("expected the permission to be gone, got %v", err)
+				}
+
+				ok, err := busDomain.Permission.HasRolePermission(ctx, []role.Role{role.User}, perm.Name)
+				if err != nil {
+					return err
+				}
+
+				return ok
+			},
+			CmpFunc: cmpValue,
+		},
+	}
+
+	return table
+}
+
+// =============================================================================
+
+func cmpValue(got any, exp any) string {
+	return cmp.Diff(got, exp)
+}
+
+func cmpError(got any, exp any) string {
+	err, _ := got.(error)
+	if !errors.Is(err, exp.(error)) {
+		return fmt.Sprintf("got %v, want %v", got, exp)
+	}
+
+	return ""
+}
@@ -0,0 +1,27 @@
+package permissiondb
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/ardanlabs/service/business/domain/permissionbus"
+)
+
+func (s *Store) applyFilter(filter permissionbus.QueryFilter, data map[string]any, buf *bytes.Buffer) {
+	var wc []string
+
+	if filter.Name != nil {
+		data["name"] = "%" + *filter.Name + "%"
+		wc = append(wc, "name LIKE :name")
+	}
+
+	if filter.Role != nil {
+		data["role"] = filter.Role.String()
+		wc = append(wc, "name IN (SELECT permission FROM role_permissions WHERE role = :role)")
+	}
+
+	if len(wc) > 0 {
+		buf.WriteString(" WHERE ")
+		buf.WriteString(strings.Join(wc, " AND "))
+	}
+}
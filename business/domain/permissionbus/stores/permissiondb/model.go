@@ -0,0 +1,61 @@
+package permissiondb
+
+import (
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/permissionbus"
+)
+
+type permission struct {
+	Name        string    `db:"name"`
+	Description string    `db:"description"`
+	DateCreated time.Time `db:"date_created"`
+}
+
+func toDBPermission(bus permissionbus.Permission) permission {
+	db := permission{
+		Name:        bus.Name,
+		Description: bus.Description,
+		DateCreated: bus.DateCreated.UTC(),
+	}
+
+	return db
+}
+
+func toBusPermission(db permission) permissionbus.Permission {
+	bus := permissionbus.Permission{
+		Name:        db.Name,
+		Description: db.Description,
+		DateCreated: db.DateCreated.In(time.Local),
+	}
+
+	return bus
+}
+
+func toBusPermissions(dbs []permission) []permissionbus.Permission {
+	bus := make([]permissionbus.Permission, len(dbs))
+
+	for i, db := range dbs {
+		bus[i] = toBusPermission(db)
+	}
+
+	return bus
+}
+
+// =============================================================================
+
+type grant struct {
+	Role        string    `db:"role"`
+	Permission  string    `db:"permission"`
+	DateCreated time.Time `db:"date_created"`
+}
+
+func toDBGrant(bus permissionbus.Grant) grant {
+	db := grant{
+		Role:        bus.Role.String(),
+		Permission:  bus.Permission,
+		DateCreated: bus.DateCreated.UTC(),
+	}
+
+	return db
+}
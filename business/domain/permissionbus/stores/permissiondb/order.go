@@ -0,0 +1,10 @@
+package permissiondb
+
+import (
+	"github.com/ardanlabs/service/business/domain/permissionbus"
+	"github.com/ardanlabs/service/business/sdk/order"
+)
+
+func orderByClause(orderBy order.By) (string, error) {
+	return permissionbus.OrderFields.Clause(orderBy)
+}
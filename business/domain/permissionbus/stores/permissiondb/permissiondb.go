@@ -0,0 +1,230 @@
+// Package permissiondb contains permission related CRUD functionality.
+package permissiondb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ardanlabs/service/business/domain/permissionbus"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/business/sdk/sqldb/dbarray"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+// Store manages the set of APIs for permission database access.
+type Store struct {
+	log *logger.Logger
+	db  sqlx.ExtContext
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *logger.Logger, db sqlx.ExtContext) *Store {
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// NewWithTx constructs a new Store value replacing the sqlx DB
+// value with a sqlx DB value that is currently inside a transaction.
+func (s *Store) NewWithTx(tx sqldb.CommitRollbacker) (permissionbus.Storer, error) {
+	ec, err := sqldb.GetExtContext(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	store := Store{
+		log: s.log,
+		db:  ec,
+	}
+
+	return &store, nil
+}
+
+// Create inserts a new permission into the database.
+func (s *Store) Create(ctx context.Context, perm permissionbus.Permission) error {
+	const q = `
+	INSERT INTO permissions
+		(name, description, date_created)
+	VALUES
+		(:name, :description, :date_created)`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBPermission(perm)); err != nil {
+		if errors.Is(err, sqldb.ErrDBDuplicatedEntry) {
+			return fmt.Errorf("namedexeccontext: %w", permissionbus.ErrUniqueName)
+		}
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes a permission and its grants from the database.
+func (s *Store) Delete(ctx context.Context, perm permissionbus.Permission) error {
+	data := struct {
+		Name string `db:"name"`
+	}{
+		Name: perm.Name,
+	}
+
+	const q = `
+	DELETE FROM
+		permissions
+	WHERE
+		name = :name`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Query retrieves a list of existing permissions from the database.
+func (s *Store) Query(ctx context.Context, filter permissionbus.QueryFilter, orderBy order.By, page page.Page) ([]permissionbus.Permission, error) {
+	data := map[string]any{
+		"offset":        (page.Number() - 1) * page.RowsPerPage(),
+		"rows_per_page": page.RowsPerPage(),
+	}
+
+	const q = `
+	SELECT
+		name, description, date_created
+	FROM
+		permissions`
+
+	buf := bytes.NewBufferString(q)
+	s.applyFilter(filter, data, buf)
+
+	orderByClause, err := orderByClause(orderBy)
+	if err != nil {
+		return nil, err
+	}
+
+	buf.WriteString(orderByClause)
+	buf.WriteString(" OFFSET :offset ROWS FETCH NEXT :rows_per_page ROWS ONLY")
+
+	var dbPerms []permission
+	if err := sqldb.NamedQuerySlice(ctx, s.log, s.db, buf.String(), data, &dbPerms); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toBusPermissions(dbPerms), nil
+}
+
+// Count returns the total number of permissions in the DB.
+func (s *Store) Count(ctx context.Context, filter permissionbus.QueryFilter) (int, error) {
+	data := map[string]any{}
+
+	const q = `
+	SELECT
+		count(1)
+	FROM
+		permissions`
+
+	buf := bytes.NewBufferString(q)
+	s.applyFilter(filter, data, buf)
+
+	var count struct {
+		Count int `db:"count"`
+	}
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, buf.String(), data, &count); err != nil {
+		return 0, fmt.Errorf("db: %w", err)
+	}
+
+	return count.Count, nil
+}
+
+// QueryByName gets the specified permission from the database.
+func (s *Store) QueryByName(ctx context.Context, name string) (permissionbus.Permission, error) {
+	data := struct {
+		Name string `db:"name"`
+	}{
+		Name: name,
+	}
+
+	const q = `
+	SELECT
+		name, description, date_created
+	FROM
+		permissions
+	WHERE
+		name = :name`
+
+	var dbPerm permission
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbPerm); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return permissionbus.Permission{}, fmt.Errorf("db: %w", permissionbus.ErrNotFound)
+		}
+		return permissionbus.Permission{}, fmt.Errorf("db: %w", err)
+	}
+
+	return toBusPermission(dbPerm), nil
+}
+
+// =============================================================================
+
+// Grant inserts a grant of a permission to a role into the database.
+func (s *Store) Grant(ctx context.Context, grant permissionbus.Grant) error {
+	const q = `
+	INSERT INTO role_permissions
+		(role, permission, date_created)
+	VALUES
+		(:role, :permission, :date_created)
+	ON CONFLICT (role, permission) DO NOTHING`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBGrant(grant)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Revoke removes a grant of a permission to a role from the database.
+func (s *Store) Revoke(ctx context.Context, grant permissionbus.Grant) error {
+	const q = `
+	DELETE FROM
+		role_permissions
+	WHERE
+		role = :role AND permission = :permission`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBGrant(grant)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Granted reports whether any of the roles has been granted the permission.
+func (s *Store) Granted(ctx context.Context, roles []role.Role, name string) (bool, error) {
+	data := struct {
+		Roles      dbarray.String `db:"roles"`
+		Permission string         `db:"permission"`
+	}{
+		Roles:      role.ParseToString(roles),
+		Permission: name,
+	}
+
+	const q = `
+	SELECT
+		count(1)
+	FROM
+		role_permissions
+	WHERE
+		role = ANY(:roles) AND permission = :permission`
+
+	var count struct {
+		Count int `db:"count"`
+	}
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &count); err != nil {
+		return false, fmt.Errorf("db: %w", err)
+	}
+
+	return count.Count > 0, nil
+}
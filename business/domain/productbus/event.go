@@ -9,12 +9,16 @@ import (
 	"github.com/ardanlabs/service/business/sdk/delegate"
 )
 
+// DomainName represents the name of this domain.
+const DomainName = "product"
+
 // registerDelegateFunctions will register action functions with the delegate
 // system. If the business was constructed for query only, there won't be a
 // delegate provided.
 func (b *Business) registerDelegateFunctions() {
 	if b.delegate != nil {
 		b.delegate.Register(userbus.DomainName, userbus.ActionDeleted, b.actionUserDeleted)
+		b.delegate.Register(userbus.DomainName, userbus.ActionCollect, b.actionUserCollect)
 	}
 }
 
@@ -32,3 +36,24 @@ func (b *Business) actionUserDeleted(ctx context.Context, data delegate.Data) er
 
 	return nil
 }
+
+// actionUserCollect is executed by the user domain indirectly when a user's
+// data is exported. The products the user owns are added to the export.
+func (b *Business) actionUserCollect(ctx context.Context, data delegate.Data) error {
+	var params userbus.ActionCollectParms
+	err := json.Unmarshal(data.RawParams, &params)
+	if err != nil {
+		return fmt.Errorf("expected an encoded %T: %w", params, err)
+	}
+
+	prds, err := b.QueryByUserID(ctx, params.UserID)
+	if err != nil {
+		return fmt.Errorf("querybyuserid: userID[%s]: %w", params.UserID, err)
+	}
+
+	if err := userbus.AddExportData(ctx, DomainName, prds); err != nil {
+		return fmt.Errorf("addexportdata: userID[%s]: %w", params.UserID, err)
+	}
+
+	return nil
+}
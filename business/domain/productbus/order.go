@@ -13,3 +13,13 @@ const (
 	OrderByCost      = "d"
 	OrderByQuantity  = "e"
 )
+
+// OrderFields represents the fields the results can be ordered by, the names
+// clients use for them and the columns the stores order by.
+var OrderFields = order.Register("product",
+	order.Field{Name: "product_id", Key: OrderByProductID, Column: "product_id"},
+	order.Field{Name: "name", Key: OrderByName, Column: "name"},
+	order.Field{Name: "cost", Key: OrderByCost, Column: "cost"},
+	order.Field{Name: "quantity", Key: OrderByQuantity, Column: "quantity"},
+	order.Field{Name: "user_id", Key: OrderByUserID, Column: "user_id"},
+)
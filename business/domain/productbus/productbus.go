@@ -3,15 +3,15 @@ package productbus
 
 import (
 	"context"
-	"errors"
 	"fmt"
-	"time"
 
 	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/buserr"
 	"github.com/ardanlabs/service/business/sdk/delegate"
 	"github.com/ardanlabs/service/business/sdk/order"
 	"github.com/ardanlabs/service/business/sdk/page"
 	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/foundation/clock"
 	"github.com/ardanlabs/service/foundation/logger"
 	"github.com/ardanlabs/service/foundation/otel"
 	"github.com/google/uuid"
@@ -19,9 +19,9 @@ import (
 
 // Set of error variables for CRUD operations.
 var (
-	ErrNotFound     = errors.New("product not found")
-	ErrUserDisabled = errors.New("user disabled")
-	ErrInvalidCost  = errors.New("cost not valid")
+	ErrNotFound     = buserr.New(buserr.NotFound, "product not found")
+	ErrUserDisabled = buserr.New(buserr.FailedPrecondition, "user disabled")
+	ErrInvalidCost  = buserr.New(buserr.InvalidArgument, "cost not valid")
 )
 
 // Storer interface declares the behavior this package needs to persist and
@@ -96,7 +96,7 @@ func (b *Business) Create(ctx context.Context, np NewProduct) (Product, error) {
 		return Product{}, ErrUserDisabled
 	}
 
-	now := time.Now()
+	now := clock.Now()
 
 	prd := Product{
 		ID:          uuid.New(),
@@ -132,7 +132,7 @@ func (b *Business) Update(ctx context.Context, prd Product, up UpdateProduct) (P
 		prd.Quantity = *up.Quantity
 	}
 
-	prd.DateUpdated = time.Now()
+	prd.DateUpdated = clock.Now()
 
 	if err := b.storer.Update(ctx, prd); err != nil {
 		return Product{}, fmt.Errorf("update: %w", err)
@@ -0,0 +1,155 @@
+package reportbus
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ardanlabs/service/business/types/schedule"
+	"github.com/google/uuid"
+)
+
+// The set of reports that can be subscribed to.
+var (
+	ReportNewUsers     = newReport("new_users")
+	ReportFailedLogins = newReport("failed_logins")
+	ReportQuotaUsage   = newReport("quota_usage")
+)
+
+var reports = make(map[string]Report)
+
+// Report represents a report that can be subscribed to.
+type Report struct {
+	value string
+}
+
+func newReport(report string) Report {
+	r := Report{report}
+	reports[report] = r
+	return r
+}
+
+// String returns the name of the report.
+func (r Report) String() string {
+	return r.value
+}
+
+// Equal provides support for the go-cmp package and testing.
+func (r Report) Equal(r2 Report) bool {
+	return r.value == r2.value
+}
+
+// MarshalText provides support for logging and any marshal needs.
+func (r Report) MarshalText() ([]byte, error) {
+	return []byte(r.value), nil
+}
+
+// ParseReport parses the string value and returns a report if one exists.
+func ParseReport(value string) (Report, error) {
+	r, exists := reports[value]
+	if !exists {
+		return Report{}, fmt.Errorf("invalid report %q", value)
+	}
+
+	return r, nil
+}
+
+// =============================================================================
+
+// The set of channels a report can be delivered through.
+var (
+	ChannelEmail   = newChannel("email")
+	ChannelWebhook = newChannel("webhook")
+)
+
+var channels = make(map[string]Channel)
+
+// Channel represents the way a report is delivered.
+type Channel struct {
+	value string
+}
+
+func newChannel(channel string) Channel {
+	c := Channel{channel}
+	channels[channel] = c
+	return c
+}
+
+// String returns the name of the channel.
+func (c Channel) String() string {
+	return c.value
+}
+
+// Equal provides support for the go-cmp package and testing.
+func (c Channel) Equal(c2 Channel) bool {
+	return c.value == c2.value
+}
+
+// MarshalText provides support for logging and any marshal needs.
+func (c Channel) MarshalText() ([]byte, error) {
+	return []byte(c.value), nil
+}
+
+// ParseChannel parses the string value and returns a channel if one exists.
+func ParseChannel(value string) (Channel, error) {
+	c, exists := channels[value]
+	if !exists {
+		return Channel{}, fmt.Errorf("invalid channel %q", value)
+	}
+
+	return c, nil
+}
+
+// =============================================================================
+
+// Subscription represents a request by an admin to receive a report on a
+// schedule. The target is the email address or webhook URL the report is
+// delivered to.
+type Subscription struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Report      Report
+	Schedule    schedule.Schedule
+	Channel     Channel
+	Target      string
+	Enabled     bool
+	LastRun     time.Time
+	NextRun     time.Time
+	DateCreated time.Time
+	DateUpdated time.Time
+}
+
+// NewSubscription is what we require from clients when adding a Subscription.
+type NewSubscription struct {
+	UserID   uuid.UUID
+	Report   Report
+	Schedule schedule.Schedule
+	Channel  Channel
+	Target   string
+}
+
+// UpdateSubscription defines what information may be provided to modify an
+// existing Subscription. All fields are optional so clients can send just the
+// fields they want changed.
+type UpdateSubscription struct {
+	Schedule *schedule.Schedule
+	Channel  *Channel
+	Target   *string
+	Enabled  *bool
+}
+
+// =============================================================================
+
+// Set of delivery statuses.
+const (
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+)
+
+// Delivery represents an attempt to deliver a report for a subscription.
+type Delivery struct {
+	ID             uuid.UUID
+	SubscriptionID uuid.UUID
+	Status         string
+	Error          string
+	DateCreated    time.Time
+}
@@ -0,0 +1,23 @@
+package reportbus
+
+import "github.com/ardanlabs/service/business/sdk/order"
+
+// DefaultOrderBy represents the default way we sort.
+var DefaultOrderBy = order.NewBy(OrderByID, order.ASC)
+
+// Set of fields that the results can be ordered by.
+const (
+	OrderByID      = "a"
+	OrderByUserID  = "b"
+	OrderByReport  = "c"
+	OrderByNextRun = "d"
+)
+
+// OrderFields represents the fields the results can be ordered by, the names
+// clients use for them and the columns the stores order by.
+var OrderFields = order.Register("report",
+	order.Field{Name: "subscription_id", Key: OrderByID, Column: "subscription_id"},
+	order.Field{Name: "user_id", Key: OrderByUserID, Column: "user_id"},
+	order.Field{Name: "report", Key: OrderByReport, Column: "report"},
+	order.Field{Name: "next_run", Key: OrderByNextRun, Column: "next_run"},
+)
@@ -0,0 +1,55 @@
+package reportbus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+)
+
+// content represents the document that is delivered for a report.
+type content struct {
+	Report string    `json:"report"`
+	From   time.Time `json:"from"`
+	To     time.Time `json:"to"`
+	Data   any       `json:"data"`
+}
+
+// render produces the document for the specified report covering the
+// window between from and to.
+func (b *Business) render(ctx context.Context, report Report, from time.Time, to time.Time) ([]byte, error) {
+	var data any
+
+	switch report {
+	case ReportNewUsers:
+		filter := userbus.QueryFilter{
+			StartCreatedDate: &from,
+			EndCreatedDate:   &to,
+		}
+
+		count, err := b.userBus.Count(ctx, filter)
+		if err != nil {
+			return nil, fmt.Errorf("count users: %w", err)
+		}
+
+		data = struct {
+			Count int `json:"count"`
+		}{
+			Count: count,
+		}
+
+	default:
+		return nil, fmt.Errorf("report[%s]: %w", report, ErrReportUnavailable)
+	}
+
+	doc := content{
+		Report: report.String(),
+		From:   from.UTC(),
+		To:     to.UTC(),
+		Data:   data,
+	}
+
+	return json.Marshal(doc)
+}
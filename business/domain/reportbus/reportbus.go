@@ -0,0 +1,307 @@
+// Package reportbus provides business access to report subscription domain.
+package reportbus
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/buserr"
+	"github.com/ardanlabs/service/business/sdk/jobs"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/foundation/clock"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/otel"
+	"github.com/google/uuid"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrNotFound           = buserr.New(buserr.NotFound, "subscription not found")
+	ErrUserDisabled       = buserr.New(buserr.FailedPrecondition, "user disabled")
+	ErrReportUnavailable  = buserr.New(buserr.Unimplemented, "report not available")
+	ErrChannelUnavailable = buserr.New(buserr.Unimplemented, "channel not available")
+)
+
+// Storer interface declares the behavior this package needs to persist and
+// retrieve data.
+type Storer interface {
+	NewWithTx(tx sqldb.CommitRollbacker) (Storer, error)
+	Create(ctx context.Context, sub Subscription) error
+	Update(ctx context.Context, sub Subscription) error
+	Delete(ctx context.Context, sub Subscription) error
+	Query(ctx context.Context, filter QueryFilter, orderBy order.By, page page.Page) ([]Subscription, error)
+	Count(ctx context.Context, filter QueryFilter) (int, error)
+	QueryByID(ctx context.Context, subscriptionID uuid.UUID) (Subscription, error)
+	QueryDue(ctx context.Context, now time.Time) ([]Subscription, error)
+	CreateDelivery(ctx context.Context, dlv Delivery) error
+	QueryDeliveries(ctx context.Context, subscriptionID uuid.UUID, page page.Page) ([]Delivery, error)
+	CountDeliveries(ctx context.Context, subscriptionID uuid.UUID) (int, error)
+}
+
+// Business manages the set of APIs for report subscription access.
+type Business struct {
+	log     *logger.Logger
+	userBus userbus.Business
+	storer  Storer
+	senders map[Channel]Sender
+}
+
+// NewBusiness constructs a report business API for use. The senders provide
+// delivery for each channel. A subscription on a channel without a sender
+// is recorded as a failed delivery.
+func NewBusiness(log *logger.Logger, userBus userbus.Business, storer Storer, senders map[Channel]Sender) *Business {
+	return &Business{
+		log:     log,
+		userBus: userBus,
+		storer:  storer,
+		senders: senders,
+	}
+}
+
+// NewWithTx constructs a new business value that will use the
+// specified transaction in any store related calls.
+func (b *Business) NewWithTx(tx sqldb.CommitRollbacker) (*Business, error) {
+	storer, err := b.storer.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	userBus, err := b.userBus.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	bus := Business{
+		log:     b.log,
+		userBus: userBus,
+		storer:  storer,
+		senders: b.senders,
+	}
+
+	return &bus, nil
+}
+
+// Create adds a new subscription to the system.
+func (b *Business) Create(ctx context.Context, ns NewSubscription) (Subscription, error) {
+	ctx, span := otel.AddSpan(ctx, "business.reportbus.create")
+	defer span.End()
+
+	usr, err := b.userBus.QueryByID(ctx, ns.UserID)
+	if err != nil {
+		return Subscription{}, fmt.Errorf("user.querybyid: %s: %w", ns.UserID, err)
+	}
+
+	if !usr.Enabled {
+		return Subscription{}, ErrUserDisabled
+	}
+
+	now := clock.Now()
+
+	sub := Subscription{
+		ID:          uuid.New(),
+		UserID:      ns.UserID,
+		Report:      ns.Report,
+		Schedule:    ns.Schedule,
+		Channel:     ns.Channel,
+		Target:      ns.Target,
+		Enabled:     true,
+		NextRun:     ns.Schedule.Next(now),
+		DateCreated: now,
+		DateUpdated: now,
+	}
+
+	if err := b.storer.Create(ctx, sub); err != nil {
+		return Subscription{}, fmt.Errorf("create: %w", err)
+	}
+
+	return sub, nil
+}
+
+// Update modifies information about a subscription.
+func (b *Business) Update(ctx context.Context, sub Subscription, us UpdateSubscription) (Subscription, error) {
+	ctx, span := otel.AddSpan(ctx, "business.reportbus.update")
+	defer span.End()
+
+	now := clock.Now()
+
+	if us.Schedule != nil {
+		sub.Schedule = *us.Schedule
+		sub.NextRun = sub.Schedule.Next(now)
+	}
+
+	if us.Channel != nil {
+		sub.Channel = *us.Channel
+	}
+
+	if us.Target != nil {
+		sub.Target = *us.Target
+	}
+
+	if us.Enabled != nil {
+		sub.Enabled = *us.Enabled
+	}
+
+	sub.DateUpdated = now
+
+	if err := b.storer.Update(ctx, sub); err != nil {
+		return Subscription{}, fmt.Errorf("update: %w", err)
+	}
+
+	return sub, nil
+}
+
+// Delete removes the specified subscription and its delivery history.
+func (b *Business) Delete(ctx context.Context, sub Subscription) error {
+	ctx, span := otel.AddSpan(ctx, "business.reportbus.delete")
+	defer span.End()
+
+	if err := b.storer.Delete(ctx, sub); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	return nil
+}
+
+// Query retrieves a list of existing subscriptions.
+func (b *Business) Query(ctx context.Context, filter QueryFilter, orderBy order.By, page page.Page) ([]Subscription, error) {
+	ctx, span := otel.AddSpan(ctx, "business.reportbus.query")
+	defer span.End()
+
+	subs, err := b.storer.Query(ctx, filter, orderBy, page)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return subs, nil
+}
+
+// Count returns the total number of subscriptions.
+func (b *Business) Count(ctx context.Context, filter QueryFilter) (int, error) {
+	ctx, span := otel.AddSpan(ctx, "business.reportbus.count")
+	defer span.End()
+
+	return b.storer.Count(ctx, filter)
+}
+
+// QueryByID finds the subscription by the specified ID.
+func (b *Business) QueryByID(ctx context.Context, subscriptionID uuid.UUID) (Subscription, error) {
+	ctx, span := otel.AddSpan(ctx, "business.reportbus.querybyid")
+	defer span.End()
+
+	sub, err := b.storer.QueryByID(ctx, subscriptionID)
+	if err != nil {
+		return Subscription{}, fmt.Errorf("query: subscriptionID[%s]: %w", subscriptionID, err)
+	}
+
+	return sub, nil
+}
+
+// QueryDeliveries retrieves the delivery history for a subscription, most
+// recent first.
+func (b *Business) QueryDeliveries(ctx context.Context, subscriptionID uuid.UUID, page page.Page) ([]Delivery, error) {
+	ctx, span := otel.AddSpan(ctx, "business.reportbus.querydeliveries")
+	defer span.End()
+
+	dlvs, err := b.storer.QueryDeliveries(ctx, subscriptionID, page)
+	if err != nil {
+		return nil, fmt.Errorf("query: subscriptionID[%s]: %w", subscriptionID, err)
+	}
+
+	return dlvs, nil
+}
+
+// CountDeliveries returns the total number of deliveries for a subscription.
+func (b *Business) CountDeliveries(ctx context.Context, subscriptionID uuid.UUID) (int, error) {
+	ctx, span := otel.AddSpan(ctx, "business.reportbus.countdeliveries")
+	defer span.End()
+
+	return b.storer.CountDeliveries(ctx, subscriptionID)
+}
+
+// =============================================================================
+
+// RunDue delivers every enabled subscription that is due at the specified
+// time. A failure to deliver one subscription doesn't stop the others and
+// is recorded in the delivery history.
+func (b *Business) RunDue(ctx context.Context, now time.Time) error {
+	ctx, span := otel.AddSpan(ctx, "business.reportbus.rundue")
+	defer span.End()
+
+	subs, err := b.storer.QueryDue(ctx, now)
+	if err != nil {
+		return fmt.Errorf("querydue: %w", err)
+	}
+
+	for _, sub := range subs {
+		if err := b.run(ctx, sub, now); err != nil {
+			b.log.Error(ctx, "reportbus: run", "subscriptionID", sub.ID, "ERROR", err)
+		}
+	}
+
+	return nil
+}
+
+// Job constructs the job that runs due subscriptions every interval.
+func (b *Business) Job(interval time.Duration) jobs.Job {
+	return jobs.Job{
+		Name:     "reportbus.rundue",
+		Schedule: jobs.Every(interval),
+		Run: func(ctx context.Context) error {
+			return b.RunDue(ctx, clock.Now())
+		},
+	}
+}
+
+func (b *Business) run(ctx context.Context, sub Subscription, now time.Time) error {
+	from := sub.LastRun
+	if from.IsZero() {
+		from = sub.DateCreated
+	}
+
+	dlv := Delivery{
+		ID:             uuid.New(),
+		SubscriptionID: sub.ID,
+		Status:         StatusDelivered,
+		DateCreated:    now,
+	}
+
+	if err := b.deliver(ctx, sub, from, now); err != nil {
+		dlv.Status = StatusFailed
+		dlv.Error = err.Error()
+	}
+
+	if err := b.storer.CreateDelivery(ctx, dlv); err != nil {
+		return fmt.Errorf("createdelivery: %w", err)
+	}
+
+	sub.LastRun = now
+	sub.NextRun = sub.Schedule.Next(now)
+
+	if err := b.storer.Update(ctx, sub); err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+
+	return nil
+}
+
+func (b *Business) deliver(ctx context.Context, sub Subscription, from time.Time, to time.Time) error {
+	sender, exists := b.senders[sub.Channel]
+	if !exists {
+		return fmt.Errorf("channel[%s]: %w", sub.Channel, ErrChannelUnavailable)
+	}
+
+	content, err := b.render(ctx, sub.Report, from, to)
+	if err != nil {
+		return fmt.Errorf("render: %w", err)
+	}
+
+	if err := sender.Send(ctx, sub, content); err != nil {
+		return fmt.Errorf("send: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,239 @@
+package reportbus_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/reportbus"
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/dbtest"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/sdk/unitest"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/ardanlabs/service/business/types/schedule"
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_Report(t *testing.T) {
+	t.Parallel()
+
+	db := dbtest.New(t, "Test_Report")
+
+	sd, err := insertSeedData(db.BusDomain)
+	if err != nil {
+		t.Fatalf("Seeding error: %s", err)
+	}
+
+	// -------------------------------------------------------------------------
+
+	unitest.Run(t, query(db.BusDomain, sd), "query")
+	unitest.Run(t, create(db.BusDomain, sd), "create")
+	unitest.Run(t, update(db.BusDomain, sd), "update")
+	unitest.Run(t, runDue(db.BusDomain, sd), "rundue")
+}
+
+// =============================================================================
+
+func insertSeedData(busDomain dbtest.BusDomain) (unitest.SeedData, error) {
+	ctx := context.Background()
+
+	usrs, err := userbus.TestSeedUsers(ctx, 1, role.Admin, busDomain.User)
+	if err != nil {
+		return unitest.SeedData{}, fmt.Errorf("seeding users : %w", err)
+	}
+
+	subs, err := reportbus.TestGenerateSeedSubscriptions(ctx, 2, busDomain.Report, usrs[0].ID)
+	if err != nil {
+		return unitest.SeedData{}, fmt.Errorf("seeding subscriptions : %w", err)
+	}
+
+	tu1 := unitest.User{
+		User:          usrs[0],
+		Subscriptions: subs,
+	}
+
+	// -------------------------------------------------------------------------
+
+	sd := unitest.SeedData{
+		Admins: []unitest.User{tu1},
+	}
+
+	return sd, nil
+}
+
+// =============================================================================
+
+func query(busDomain dbtest.BusDomain, sd unitest.SeedData) []unitest.Table {
+	table := []unitest.Table{
+		{
+			Name:    "byid",
+			ExpResp: sd.Admins[0].Subscriptions[0],
+			ExcFunc: func(ctx context.Context) any {
+				resp, err := busDomain.Report.QueryByID(ctx, sd.Admins[0].Subscriptions[0].ID)
+				if err != nil {
+					return err
+				}
+
+				return resp
+			},
+			CmpFunc: func(got any, exp any) string {
+				gotResp, exists := got.(reportbus.Subscription)
+				if !exists {
+					return "error occurred"
+				}
+
+				expResp := exp.(reportbus.Subscription)
+
+				if gotResp.DateCreated.Format(time.RFC3339) == expResp.DateCreated.Format(time.RFC3339) {
+					expResp.DateCreated = gotResp.DateCreated
+				}
+
+				if gotResp.DateUpdated.Format(time.RFC3339) == expResp.DateUpdated.Format(time.RFC3339) {
+					expResp.DateUpdated = gotResp.DateUpdated
+				}
+
+				if gotResp.NextRun.Equal(expResp.NextRun) {
+					expResp.NextRun = gotResp.NextRun
+				}
+
+				return cmp.Diff(gotResp, expResp)
+			},
+		},
+	}
+
+	return table
+}
+
+func create(busDomain dbtest.BusDomain, sd unitest.SeedData) []unitest.Table {
+	table := []unitest.Table{
+		{
+			Name: "basic",
+			ExpResp: reportbus.Subscription{
+				UserID:   sd.Admins[0].ID,
+				Report:   reportbus.ReportNewUsers,
+				Schedule: schedule.MustParse("0 8 * * 1"),
+				Channel:  reportbus.ChannelEmail,
+				Target:   "admin@example.com",
+				Enabled:  true,
+			},
+			ExcFunc: func(ctx context.Context) any {
+				ns := reportbus.NewSubscription{
+					UserID:   sd.Admins[0].ID,
+					Report:   reportbus.ReportNewUsers,
+					Schedule: schedule.MustParse("0 8 * * 1"),
+					Channel:  reportbus.ChannelEmail,
+					Target:   "admin@example.com",
+				}
+
+				resp, err := busDomain.Report.Create(ctx, ns)
+				if err != nil {
+					return err
+				}
+
+				return resp
+			},
+			CmpFunc: func(got any, exp any) string {
+				gotResp, exists := got.(reportbus.Subscription)
+				if !exists {
+					return "error occurred"
+				}
+
+				if gotResp.NextRun.Weekday() != time.Monday || gotResp.NextRun.Hour() != 8 {
+					return fmt.Sprintf("unexpected next run %s", gotResp.NextRun)
+				}
+
+				expResp := exp.(reportbus.Subscription)
+
+				expResp.ID = gotResp.ID
+				expResp.NextRun = gotResp.NextRun
+				expResp.DateCreated = gotResp.DateCreated
+				expResp.DateUpdated = gotResp.DateUpdated
+
+				return cmp.Diff(gotResp, expResp)
+			},
+		},
+	}
+
+	return table
+}
+
+func update(busDomain dbtest.BusDomain, sd unitest.SeedData) []unitest.Table {
+	enabled := false
+
+	table := []unitest.Table{
+		{
+			Name: "disable",
+			ExpResp: reportbus.Subscription{
+				ID:          sd.Admins[0].Subscriptions[1].ID,
+				UserID:      sd.Admins[0].ID,
+				Report:      sd.Admins[0].Subscriptions[1].Report,
+				Schedule:    sd.Admins[0].Subscriptions[1].Schedule,
+				Channel:     sd.Admins[0].Subscriptions[1].Channel,
+				Target:      sd.Admins[0].Subscriptions[1].Target,
+				Enabled:     false,
+				NextRun:     sd.Admins[0].Subscriptions[1].NextRun,
+				DateCreated: sd.Admins[0].Subscriptions[1].DateCreated,
+			},
+			ExcFunc: func(ctx context.Context) any {
+				us := reportbus.UpdateSubscription{
+					Enabled: &enabled,
+				}
+
+				resp, err := busDomain.Report.Update(ctx, sd.Admins[0].Subscriptions[1], us)
+				if err != nil {
+					return err
+				}
+
+				return resp
+			},
+			CmpFunc: func(got any, exp any) string {
+				gotResp, exists := got.(reportbus.Subscription)
+				if !exists {
+					return "error occurred"
+				}
+
+				expResp := exp.(reportbus.Subscription)
+
+				expResp.DateUpdated = gotResp.DateUpdated
+
+				return cmp.Diff(gotResp, expResp)
+			},
+		},
+	}
+
+	return table
+}
+
+func runDue(busDomain dbtest.BusDomain, sd unitest.SeedData) []unitest.Table {
+	table := []unitest.Table{
+		{
+			Name:    "nosender",
+			ExpResp: reportbus.StatusFailed,
+			ExcFunc: func(ctx context.Context) any {
+				sub := sd.Admins[0].Subscriptions[0]
+
+				if err := busDomain.Report.RunDue(ctx, sub.NextRun.Add(time.Minute)); err != nil {
+					return err
+				}
+
+				dlvs, err := busDomain.Report.QueryDeliveries(ctx, sub.ID, page.MustParse("1", "10"))
+				if err != nil {
+					return err
+				}
+
+				if len(dlvs) != 1 {
+					return fmt.Errorf("expected 1 delivery, got %d", len(dlvs))
+				}
+
+				return dlvs[0].Status
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+	}
+
+	return table
+}
@@ -0,0 +1,48 @@
+package reportbus
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Sender defines the behavior required to deliver a rendered report through
+// a channel.
+type Sender interface {
+	Send(ctx context.Context, sub Subscription, content []byte) error
+}
+
+// WebhookSender delivers reports by posting them to the subscription target.
+type WebhookSender struct {
+	client *http.Client
+}
+
+// NewWebhookSender constructs a sender that delivers reports to webhooks.
+func NewWebhookSender(client *http.Client) *WebhookSender {
+	return &WebhookSender{
+		client: client,
+	}
+}
+
+// Send implements the Sender interface.
+func (ws *WebhookSender) Send(ctx context.Context, sub Subscription, content []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Target, bytes.NewReader(content))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := ws.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package reportdb
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/ardanlabs/service/business/domain/reportbus"
+)
+
+func applyFilter(filter reportbus.QueryFilter, data map[string]any, buf *bytes.Buffer) {
+	var wc []string
+
+	if filter.ID != nil {
+		data["subscription_id"] = filter.ID
+		wc = append(wc, "subscription_id = :subscription_id")
+	}
+
+	if filter.UserID != nil {
+		data["user_id"] = filter.UserID
+		wc = append(wc, "user_id = :user_id")
+	}
+
+	if filter.Report != nil {
+		data["report"] = filter.Report.String()
+		wc = append(wc, "report = :report")
+	}
+
+	if filter.Channel != nil {
+		data["channel"] = filter.Channel.String()
+		wc = append(wc, "channel = :channel")
+	}
+
+	if filter.Enabled != nil {
+		data["enabled"] = *filter.Enabled
+		wc = append(wc, "enabled = :enabled")
+	}
+
+	if len(wc) > 0 {
+		buf.WriteString(" WHERE ")
+		buf.WriteString(strings.Join(wc, " AND "))
+	}
+}
@@ -0,0 +1,133 @@
+package reportdb
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/reportbus"
+	"github.com/ardanlabs/service/business/types/schedule"
+	"github.com/google/uuid"
+)
+
+type subscription struct {
+	ID          uuid.UUID    `db:"subscription_id"`
+	UserID      uuid.UUID    `db:"user_id"`
+	Report      string       `db:"report"`
+	Schedule    string       `db:"schedule"`
+	Channel     string       `db:"channel"`
+	Target      string       `db:"target"`
+	Enabled     bool         `db:"enabled"`
+	LastRun     sql.NullTime `db:"last_run"`
+	NextRun     time.Time    `db:"next_run"`
+	DateCreated time.Time    `db:"date_created"`
+	DateUpdated time.Time    `db:"date_updated"`
+}
+
+func toDBSubscription(bus reportbus.Subscription) subscription {
+	return subscription{
+		ID:       bus.ID,
+		UserID:   bus.UserID,
+		Report:   bus.Report.String(),
+		Schedule: bus.Schedule.String(),
+		Channel:  bus.Channel.String(),
+		Target:   bus.Target,
+		Enabled:  bus.Enabled,
+		LastRun: sql.NullTime{
+			Time:  bus.LastRun.UTC(),
+			Valid: !bus.LastRun.IsZero(),
+		},
+		NextRun:     bus.NextRun.UTC(),
+		DateCreated: bus.DateCreated.UTC(),
+		DateUpdated: bus.DateUpdated.UTC(),
+	}
+}
+
+func toBusSubscription(db subscription) (reportbus.Subscription, error) {
+	report, err := reportbus.ParseReport(db.Report)
+	if err != nil {
+		return reportbus.Subscription{}, fmt.Errorf("parse report: %w", err)
+	}
+
+	sched, err := schedule.Parse(db.Schedule)
+	if err != nil {
+		return reportbus.Subscription{}, fmt.Errorf("parse schedule: %w", err)
+	}
+
+	channel, err := reportbus.ParseChannel(db.Channel)
+	if err != nil {
+		return reportbus.Subscription{}, fmt.Errorf("parse channel: %w", err)
+	}
+
+	bus := reportbus.Subscription{
+		ID:          db.ID,
+		UserID:      db.UserID,
+		Report:      report,
+		Schedule:    sched,
+		Channel:     channel,
+		Target:      db.Target,
+		Enabled:     db.Enabled,
+		NextRun:     db.NextRun.In(time.Local),
+		DateCreated: db.DateCreated.In(time.Local),
+		DateUpdated: db.DateUpdated.In(time.Local),
+	}
+
+	if db.LastRun.Valid {
+		bus.LastRun = db.LastRun.Time.In(time.Local)
+	}
+
+	return bus, nil
+}
+
+func toBusSubscriptions(dbs []subscription) ([]reportbus.Subscription, error) {
+	bus := make([]reportbus.Subscription, len(dbs))
+
+	for i, db := range dbs {
+		var err error
+		bus[i], err = toBusSubscription(db)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return bus, nil
+}
+
+// =============================================================================
+
+type delivery struct {
+	ID             uuid.UUID      `db:"delivery_id"`
+	SubscriptionID uuid.UUID      `db:"subscription_id"`
+	Status         string         `db:"status"`
+	Error          sql.NullString `db:"error"`
+	DateCreated    time.Time      `db:"date_created"`
+}
+
+func toDBDelivery(bus reportbus.Delivery) delivery {
+	return delivery{
+		ID:             bus.ID,
+		SubscriptionID: bus.SubscriptionID,
+		Status:         bus.Status,
+		Error: sql.NullString{
+			String: bus.Error,
+			Valid:  bus.Error != "",
+		},
+		DateCreated: bus.DateCreated.UTC(),
+	}
+}
+
+func toBusDeliveries(dbs []delivery) []reportbus.Delivery {
+	bus := make([]reportbus.Delivery, len(dbs))
+
+	for i, db := range dbs {
+		bus[i] = reportbus.Delivery{
+			ID:             db.ID,
+			SubscriptionID: db.SubscriptionID,
+			Status:         db.Status,
+			Error:          db.Error.String,
+			DateCreated:    db.DateCreated.In(time.Local),
+		}
+	}
+
+	return bus
+}
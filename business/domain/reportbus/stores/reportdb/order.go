@@ -0,0 +1,10 @@
+package reportdb
+
+import (
+	"github.com/ardanlabs/service/business/domain/reportbus"
+	"github.com/ardanlabs/service/business/sdk/order"
+)
+
+func orderByClause(orderBy order.By) (string, error) {
+	return reportbus.OrderFields.Clause(orderBy)
+}
@@ -0,0 +1,280 @@
+// Package reportdb contains report subscription related CRUD functionality.
+package reportdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/reportbus"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Store manages the set of APIs for report subscription database access.
+type Store struct {
+	log *logger.Logger
+	db  sqlx.ExtContext
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *logger.Logger, db sqlx.ExtContext) *Store {
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// NewWithTx constructs a new Store value replacing the sqlx DB
+// value with a sqlx DB value that is currently inside a transaction.
+func (s *Store) NewWithTx(tx sqldb.CommitRollbacker) (reportbus.Storer, error) {
+	ec, err := sqldb.GetExtContext(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	store := Store{
+		log: s.log,
+		db:  ec,
+	}
+
+	return &store, nil
+}
+
+// Create inserts a new subscription into the database.
+func (s *Store) Create(ctx context.Context, sub reportbus.Subscription) error {
+	const q = `
+	INSERT INTO report_subscriptions
+		(subscription_id, user_id, report, schedule, channel, target, enabled, last_run, next_run, date_created, date_updated)
+	VALUES
+		(:subscription_id, :user_id, :report, :schedule, :channel, :target, :enabled, :last_run, :next_run, :date_created, :date_updated)`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBSubscription(sub)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Update replaces a subscription document in the database.
+func (s *Store) Update(ctx context.Context, sub reportbus.Subscription) error {
+	const q = `
+	UPDATE
+		report_subscriptions
+	SET
+		"schedule" = :schedule,
+		"channel" = :channel,
+		"target" = :target,
+		"enabled" = :enabled,
+		"last_run" = :last_run,
+		"next_run" = :next_run,
+		"date_updated" = :date_updated
+	WHERE
+		subscription_id = :subscription_id`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBSubscription(sub)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes a subscription from the database.
+func (s *Store) Delete(ctx context.Context, sub reportbus.Subscription) error {
+	data := struct {
+		ID string `db:"subscription_id"`
+	}{
+		ID: sub.ID.String(),
+	}
+
+	const q = `
+	DELETE FROM
+		report_subscriptions
+	WHERE
+		subscription_id = :subscription_id`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Query retrieves a list of existing subscriptions from the database.
+func (s *Store) Query(ctx context.Context, filter reportbus.QueryFilter, orderBy order.By, page page.Page) ([]reportbus.Subscription, error) {
+	data := map[string]any{
+		"offset":        (page.Number() - 1) * page.RowsPerPage(),
+		"rows_per_page": page.RowsPerPage(),
+	}
+
+	const q = `
+	SELECT
+		subscription_id, user_id, report, schedule, channel, target, enabled, last_run, next_run, date_created, date_updated
+	FROM
+		report_subscriptions`
+
+	buf := bytes.NewBufferString(q)
+	applyFilter(filter, data, buf)
+
+	orderByClause, err := orderByClause(orderBy)
+	if err != nil {
+		return nil, err
+	}
+
+	buf.WriteString(orderByClause)
+	buf.WriteString(" OFFSET :offset ROWS FETCH NEXT :rows_per_page ROWS ONLY")
+
+	var dbSubs []subscription
+	if err := sqldb.NamedQuerySlice(ctx, s.log, s.db, buf.String(), data, &dbSubs); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toBusSubscriptions(dbSubs)
+}
+
+// Count returns the total number of subscriptions in the DB.
+func (s *Store) Count(ctx context.Context, filter reportbus.QueryFilter) (int, error) {
+	data := map[string]any{}
+
+	const q = `
+	SELECT
+		count(1)
+	FROM
+		report_subscriptions`
+
+	buf := bytes.NewBufferString(q)
+	applyFilter(filter, data, buf)
+
+	var count struct {
+		Count int `db:"count"`
+	}
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, buf.String(), data, &count); err != nil {
+		return 0, fmt.Errorf("db: %w", err)
+	}
+
+	return count.Count, nil
+}
+
+// QueryByID gets the specified subscription from the database.
+func (s *Store) QueryByID(ctx context.Context, subscriptionID uuid.UUID) (reportbus.Subscription, error) {
+	data := struct {
+		ID string `db:"subscription_id"`
+	}{
+		ID: subscriptionID.String(),
+	}
+
+	const q = `
+	SELECT
+		subscription_id, user_id, report, schedule, channel, target, enabled, last_run, next_run, date_created, date_updated
+	FROM
+		report_subscriptions
+	WHERE
+		subscription_id = :subscription_id`
+
+	var dbSub subscription
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbSub); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return reportbus.Subscription{}, fmt.Errorf("db: %w", reportbus.ErrNotFound)
+		}
+		return reportbus.Subscription{}, fmt.Errorf("db: %w", err)
+	}
+
+	return toBusSubscription(dbSub)
+}
+
+// QueryDue retrieves the enabled subscriptions that are due to run.
+func (s *Store) QueryDue(ctx context.Context, now time.Time) ([]reportbus.Subscription, error) {
+	data := map[string]any{
+		"now": now.UTC(),
+	}
+
+	const q = `
+	SELECT
+		subscription_id, user_id, report, schedule, channel, target, enabled, last_run, next_run, date_created, date_updated
+	FROM
+		report_subscriptions
+	WHERE
+		enabled = TRUE AND next_run <= :now
+	ORDER BY
+		next_run`
+
+	var dbSubs []subscription
+	if err := sqldb.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbSubs); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toBusSubscriptions(dbSubs)
+}
+
+// CreateDelivery inserts a delivery attempt into the database.
+func (s *Store) CreateDelivery(ctx context.Context, dlv reportbus.Delivery) error {
+	const q = `
+	INSERT INTO report_deliveries
+		(delivery_id, subscription_id, status, error, date_created)
+	VALUES
+		(:delivery_id, :subscription_id, :status, :error, :date_created)`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBDelivery(dlv)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// QueryDeliveries retrieves the delivery history for a subscription from the
+// database, most recent first.
+func (s *Store) QueryDeliveries(ctx context.Context, subscriptionID uuid.UUID, page page.Page) ([]reportbus.Delivery, error) {
+	data := map[string]any{
+		"subscription_id": subscriptionID,
+		"offset":          (page.Number() - 1) * page.RowsPerPage(),
+		"rows_per_page":   page.RowsPerPage(),
+	}
+
+	const q = `
+	SELECT
+		delivery_id, subscription_id, status, error, date_created
+	FROM
+		report_deliveries
+	WHERE
+		subscription_id = :subscription_id
+	ORDER BY
+		date_created DESC
+	OFFSET :offset ROWS FETCH NEXT :rows_per_page ROWS ONLY`
+
+	var dbDlvs []delivery
+	if err := sqldb.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbDlvs); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toBusDeliveries(dbDlvs), nil
+}
+
+// CountDeliveries returns the total number of deliveries for a subscription.
+func (s *Store) CountDeliveries(ctx context.Context, subscriptionID uuid.UUID) (int, error) {
+	data := map[string]any{
+		"subscription_id": subscriptionID,
+	}
+
+	const q = `
+	SELECT
+		count(1)
+	FROM
+		report_deliveries
+	WHERE
+		subscription_id = :subscription_id`
+
+	var count struct {
+		Count int `db:"count"`
+	}
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &count); err != nil {
+		return 0, fmt.Errorf("db: %w", err)
+	}
+
+	return count.Count, nil
+}
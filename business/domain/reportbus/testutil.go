@@ -0,0 +1,49 @@
+package reportbus
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+
+	"github.com/ardanlabs/service/business/types/schedule"
+	"github.com/google/uuid"
+)
+
+// TestGenerateNewSubscriptions is a helper method for testing.
+func TestGenerateNewSubscriptions(n int, userID uuid.UUID) []NewSubscription {
+	newSubs := make([]NewSubscription, n)
+
+	idx := rand.Intn(10000)
+	for i := range n {
+		idx++
+
+		ns := NewSubscription{
+			UserID:   userID,
+			Report:   ReportNewUsers,
+			Schedule: schedule.MustParse("@daily"),
+			Channel:  ChannelWebhook,
+			Target:   fmt.Sprintf("https://example.com/hooks/%d", idx),
+		}
+
+		newSubs[i] = ns
+	}
+
+	return newSubs
+}
+
+// TestGenerateSeedSubscriptions is a helper method for testing.
+func TestGenerateSeedSubscriptions(ctx context.Context, n int, api *Business, userID uuid.UUID) ([]Subscription, error) {
+	newSubs := TestGenerateNewSubscriptions(n, userID)
+
+	subs := make([]Subscription, len(newSubs))
+	for i, ns := range newSubs {
+		sub, err := api.Create(ctx, ns)
+		if err != nil {
+			return nil, fmt.Errorf("seeding subscription: idx: %d : %w", i, err)
+		}
+
+		subs[i] = sub
+	}
+
+	return subs, nil
+}
@@ -0,0 +1,17 @@
+package searchbus
+
+import (
+	"github.com/google/uuid"
+)
+
+// QueryFilter holds the available fields a query can be filtered on.
+// We are using pointer semantics because the With API mutates the value.
+// VisibleTo limits the results to the searches the user saved or that are
+// shared.
+type QueryFilter struct {
+	ID        *uuid.UUID
+	UserID    *uuid.UUID
+	Domain    *string
+	Name      *string
+	VisibleTo *uuid.UUID
+}
@@ -0,0 +1,45 @@
+package searchbus
+
+import (
+	"time"
+
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/google/uuid"
+)
+
+// Search represents a named filter and ordering saved by a user for one of
+// the domains that can be queried. The filter holds the query parameters of
+// the domain as clients send them. A shared search is visible to every
+// admin, not only the one who saved it. An OrderBy without a field leaves the
+// ordering to the domain's default.
+type Search struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Name        string
+	Domain      string
+	Filter      map[string]string
+	OrderBy     order.By
+	Shared      bool
+	DateCreated time.Time
+	DateUpdated time.Time
+}
+
+// NewSearch is what we require from clients when adding a Search.
+type NewSearch struct {
+	UserID  uuid.UUID
+	Name    string
+	Domain  string
+	Filter  map[string]string
+	OrderBy order.By
+	Shared  bool
+}
+
+// UpdateSearch defines what information may be provided to modify an
+// existing Search. All fields are optional so clients can send just the
+// fields they want changed.
+type UpdateSearch struct {
+	Name    *string
+	Filter  map[string]string
+	OrderBy *order.By
+	Shared  *bool
+}
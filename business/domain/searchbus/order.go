@@ -0,0 +1,23 @@
+package searchbus
+
+import "github.com/ardanlabs/service/business/sdk/order"
+
+// DefaultOrderBy represents the default way we sort.
+var DefaultOrderBy = order.NewBy(OrderByName, order.ASC)
+
+// Set of fields that the results can be ordered by.
+const (
+	OrderByID          = "a"
+	OrderByName        = "b"
+	OrderByDomain      = "c"
+	OrderByDateCreated = "d"
+)
+
+// OrderFields represents the fields the results can be ordered by, the names
+// clients use for them and the columns the stores order by.
+var OrderFields = order.Register("search",
+	order.Field{Name: "search_id", Key: OrderByID, Column: "search_id"},
+	order.Field{Name: "name", Key: OrderByName, Column: "name"},
+	order.Field{Name: "domain", Key: OrderByDomain, Column: "domain"},
+	order.Field{Name: "date_created", Key: OrderByDateCreated, Column: "date_created"},
+)
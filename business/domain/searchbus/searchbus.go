@@ -0,0 +1,216 @@
+// Package searchbus provides business access to saved search domain.
+package searchbus
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ardanlabs/service/business/sdk/buserr"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/foundation/clock"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/otel"
+	"github.com/google/uuid"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrNotFound      = buserr.New(buserr.NotFound, "search not found")
+	ErrUniqueName    = buserr.New(buserr.Conflict, "search name is not unique")
+	ErrUnknownDomain = buserr.New(buserr.InvalidArgument, "domain can't be searched")
+	ErrUnknownOrder  = buserr.New(buserr.InvalidArgument, "domain can't be ordered by field")
+)
+
+// Storer interface declares the behavior this package needs to persist and
+// retrieve data.
+type Storer interface {
+	NewWithTx(tx sqldb.CommitRollbacker) (Storer, error)
+	Create(ctx context.Context, srch Search) error
+	Update(ctx context.Context, srch Search) error
+	Delete(ctx context.Context, srch Search) error
+	Query(ctx context.Context, filter QueryFilter, orderBy order.By, page page.Page) ([]Search, error)
+	Count(ctx context.Context, filter QueryFilter) (int, error)
+	QueryByID(ctx context.Context, searchID uuid.UUID) (Search, error)
+	QueryByName(ctx context.Context, userID uuid.UUID, domain string, name string) (Search, error)
+}
+
+// Business manages the set of APIs for saved search access.
+type Business struct {
+	log    *logger.Logger
+	storer Storer
+}
+
+// NewBusiness constructs a saved search business API for use.
+func NewBusiness(log *logger.Logger, storer Storer) *Business {
+	return &Business{
+		log:    log,
+		storer: storer,
+	}
+}
+
+// NewWithTx constructs a new business value that will use the
+// specified transaction in any store related calls.
+func (b *Business) NewWithTx(tx sqldb.CommitRollbacker) (*Business, error) {
+	storer, err := b.storer.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	bus := Business{
+		log:    b.log,
+		storer: storer,
+	}
+
+	return &bus, nil
+}
+
+// Create saves a new search. The domain must be one that registered the
+// fields it can be ordered by, and any ordering must use one of them.
+func (b *Business) Create(ctx context.Context, ns NewSearch) (Search, error) {
+	ctx, span := otel.AddSpan(ctx, "business.searchbus.create")
+	defer span.End()
+
+	if err := checkOrder(ns.Domain, ns.OrderBy); err != nil {
+		return Search{}, err
+	}
+
+	now := clock.Now()
+
+	srch := Search{
+		ID:          uuid.New(),
+		UserID:      ns.UserID,
+		Name:        ns.Name,
+		Domain:      ns.Domain,
+		Filter:      ns.Filter,
+		OrderBy:     ns.OrderBy,
+		Shared:      ns.Shared,
+		DateCreated: now,
+		DateUpdated: now,
+	}
+
+	if err := b.storer.Create(ctx, srch); err != nil {
+		return Search{}, fmt.Errorf("create: %w", err)
+	}
+
+	return srch, nil
+}
+
+// Update modifies information about a search.
+func (b *Business) Update(ctx context.Context, srch Search, us UpdateSearch) (Search, error) {
+	ctx, span := otel.AddSpan(ctx, "business.searchbus.update")
+	defer span.End()
+
+	if us.Name != nil {
+		srch.Name = *us.Name
+	}
+
+	if us.Filter != nil {
+		srch.Filter = us.Filter
+	}
+
+	if us.OrderBy != nil {
+		if err := checkOrder(srch.Domain, *us.OrderBy); err != nil {
+			return Search{}, err
+		}
+		srch.OrderBy = *us.OrderBy
+	}
+
+	if us.Shared != nil {
+		srch.Shared = *us.Shared
+	}
+
+	srch.DateUpdated = clock.Now()
+
+	if err := b.storer.Update(ctx, srch); err != nil {
+		return Search{}, fmt.Errorf("update: %w", err)
+	}
+
+	return srch, nil
+}
+
+// Delete removes the specified search.
+func (b *Business) Delete(ctx context.Context, srch Search) error {
+	ctx, span := otel.AddSpan(ctx, "business.searchbus.delete")
+	defer span.End()
+
+	if err := b.storer.Delete(ctx, srch); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	return nil
+}
+
+// Query retrieves a list of existing searches.
+func (b *Business) Query(ctx context.Context, filter QueryFilter, orderBy order.By, page page.Page) ([]Search, error) {
+	ctx, span := otel.AddSpan(ctx, "business.searchbus.query")
+	defer span.End()
+
+	srchs, err := b.storer.Query(ctx, filter, orderBy, page)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return srchs, nil
+}
+
+// Count returns the total number of searches.
+func (b *Business) Count(ctx context.Context, filter QueryFilter) (int, error) {
+	ctx, span := otel.AddSpan(ctx, "business.searchbus.count")
+	defer span.End()
+
+	return b.storer.Count(ctx, filter)
+}
+
+// QueryByID finds the search by the specified ID.
+func (b *Business) QueryByID(ctx context.Context, searchID uuid.UUID) (Search, error) {
+	ctx, span := otel.AddSpan(ctx, "business.searchbus.querybyid")
+	defer span.End()
+
+	srch, err := b.storer.QueryByID(ctx, searchID)
+	if err != nil {
+		return Search{}, fmt.Errorf("query: searchID[%s]: %w", searchID, err)
+	}
+
+	return srch, nil
+}
+
+// QueryByName finds the search with the name for the domain that the user
+// can see. The user's own search is preferred over one shared by someone
+// else with the same name.
+func (b *Business) QueryByName(ctx context.Context, userID uuid.UUID, domain string, name string) (Search, error) {
+	ctx, span := otel.AddSpan(ctx, "business.searchbus.querybyname")
+	defer span.End()
+
+	srch, err := b.storer.QueryByName(ctx, userID, domain, name)
+	if err != nil {
+		return Search{}, fmt.Errorf("query: domain[%s] name[%s]: %w", domain, name, err)
+	}
+
+	return srch, nil
+}
+
+// =============================================================================
+
+// checkOrder verifies the domain registered the fields being ordered by. An
+// empty field leaves the ordering to the domain's default.
+func checkOrder(domain string, orderBy order.By) error {
+	fields, exists := order.Lookup(domain)
+	if !exists {
+		return fmt.Errorf("domain[%s]: %w", domain, ErrUnknownDomain)
+	}
+
+	if orderBy.Field == "" {
+		return nil
+	}
+
+	columns := fields.Columns()
+	for _, by := range orderBy.Columns() {
+		if _, exists := columns[by.Field]; !exists {
+			return fmt.Errorf("domain[%s] field[%s]: %w", domain, by.Field, ErrUnknownOrder)
+		}
+	}
+
+	return nil
+}
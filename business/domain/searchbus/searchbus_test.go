@@ -0,0 +1,237 @@
+package searchbus_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ardanlabs/service/business/domain/searchbus"
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/dbtest"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/unitest"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_Search(t *testing.T) {
+	t.Parallel()
+
+	db := dbtest.New(t, "Test_Search")
+
+	sd, err := insertSeedData(db.BusDomain)
+	if err != nil {
+		t.Fatalf("Seeding error: %s", err)
+	}
+
+	// -------------------------------------------------------------------------
+
+	unitest.Run(t, create(db.BusDomain, sd), "create")
+	unitest.Run(t, queryByName(db.BusDomain, sd), "querybyname")
+}
+
+// =============================================================================
+
+func insertSeedData(busDomain dbtest.BusDomain) (unitest.SeedData, error) {
+	ctx := context.Background()
+
+	usrs, err := userbus.TestSeedUsers(ctx, 2, role.Admin, busDomain.User)
+	if err != nil {
+		return unitest.SeedData{}, fmt.Errorf("seeding users : %w", err)
+	}
+
+	sd := unitest.SeedData{
+		Admins: []unitest.User{
+			{User: usrs[0]},
+			{User: usrs[1]},
+		},
+	}
+
+	return sd, nil
+}
+
+// =============================================================================
+
+func create(busDomain dbtest.BusDomain, sd unitest.SeedData) []unitest.Table {
+	table := []unitest.Table{
+		{
+			Name: "basic",
+			ExpResp: searchbus.Search{
+				UserID:  sd.Admins[0].ID,
+				Name:    "admins",
+				Domain:  userbus.DomainName,
+				Filter:  map[string]string{"role": "ADMIN"},
+				OrderBy: order.NewBy(userbus.OrderByName, order.DESC),
+			},
+			ExcFunc: func(ctx context.Context) any {
+				ns := searchbus.NewSearch{
+					UserID:  sd.Admins[0].ID,
+					Name:    "admins",
+					Domain:  userbus.DomainName,
+					Filter:  map[string]string{"role": "ADMIN"},
+					OrderBy: order.NewBy(userbus.OrderByName, order.DESC),
+				}
+
+				resp, err := busDomain.Search.Create(ctx, ns)
+				if err != nil {
+					return err
+				}
+
+				return resp
+			},
+			CmpFunc: func(got any, exp any) string {
+				gotResp, exists := got.(searchbus.Search)
+				if !exists {
+					return "error occurred"
+				}
+
+				expResp := exp.(searchbus.Search)
+
+				expResp.ID = gotResp.ID
+				expResp.DateCreated = gotResp.DateCreated
+				expResp.DateUpdated = gotResp.DateUpdated
+
+				return cmp.Diff(gotResp, expResp)
+			},
+		},
+		{
+			Name:    "duplicate",
+			ExpResp: searchbus.ErrUniqueName,
+			ExcFunc: func(ctx context.Context) any {
+				ns := searchbus.NewSearch{
+					UserID: sd.Admins[0].ID,
+					Name:   "admins",
+					Domain: userbus.DomainName,
+				}
+
+				_, err := busDomain.Search.Create(ctx, ns)
+				return err
+			},
+			CmpFunc: cmpError,
+		},
+		{
+			Name:    "unknown-domain",
+			ExpResp: searchbus.ErrUnknownDomain,
+			ExcFunc: func(ctx context.Context) any {
+				ns := searchbus.NewSearch{
+					UserID: sd.Admins[0].ID,
+					Name:   "widgets",
+					Domain: "widget",
+				}
+
+				_, err := busDomain.Search.Create(ctx, ns)
+				return err
+			},
+			CmpFunc: cmpError,
+		},
+		{
+			Name:    "unknown-order",
+			ExpResp: searchbus.ErrUnknownOrder,
+			ExcFunc: func(ctx context.Context) any {
+				ns := searchbus.NewSearch{
+					UserID:  sd.Admins[0].ID,
+					Name:    "by-cost",
+					Domain:  userbus.DomainName,
+					OrderBy: order.NewBy("cost", order.ASC),
+				}
+
+				_, err := busDomain.Search.Create(ctx, ns)
+				return err
+			},
+			CmpFunc: cmpError,
+		},
+	}
+
+	return table
+}
+
+func queryByName(busDomain dbtest.BusDomain, sd unitest.SeedData) []unitest.Table {
+	table := []unitest.Table{
+		{
+			Name:    "own-before-shared",
+			ExpResp: sd.Admins[1].ID,
+			ExcFunc: func(ctx context.Context) any {
+				for _, adm := range sd.Admins {
+					ns := searchbus.NewSearch{
+						UserID: adm.ID,
+						Name:   "recent",
+						Domain: userbus.DomainName,
+						Shared: true,
+					}
+
+					if _, err := busDomain.Search.Create(ctx, ns); err != nil {
+						return err
+					}
+				}
+
+				resp, err := busDomain.Search.QueryByName(ctx, sd.Admins[1].ID, userbus.DomainName, "recent")
+				if err != nil {
+					return err
+				}
+
+				return resp.UserID
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+		{
+			Name:    "shared",
+			ExpResp: sd.Admins[0].ID,
+			ExcFunc: func(ctx context.Context) any {
+				ns := searchbus.NewSearch{
+					UserID: sd.Admins[0].ID,
+					Name:   "disabled",
+					Domain: userbus.DomainName,
+					Filter: map[string]string{"enabled": "false"},
+					Shared: true,
+				}
+
+				if _, err := busDomain.Search.Create(ctx, ns); err != nil {
+					return err
+				}
+
+				resp, err := busDomain.Search.QueryByName(ctx, sd.Admins[1].ID, userbus.DomainName, "disabled")
+				if err != nil {
+					return err
+				}
+
+				return resp.UserID
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+		{
+			Name:    "private",
+			ExpResp: searchbus.ErrNotFound,
+			ExcFunc: func(ctx context.Context) any {
+				ns := searchbus.NewSearch{
+					UserID: sd.Admins[0].ID,
+					Name:   "mine",
+					Domain: userbus.DomainName,
+				}
+
+				if _, err := busDomain.Search.Create(ctx, ns); err != nil {
+					return err
+				}
+
+				_, err := busDomain.Search.QueryByName(ctx, sd.Admins[1].ID, userbus.DomainName, "mine")
+				return err
+			},
+			CmpFunc: cmpError,
+		},
+	}
+
+	return table
+}
+
+func cmpError(got any, exp any) string {
+	err, _ := got.(error)
+	if !errors.Is(err, exp.(error)) {
+		return fmt.Sprintf("got %v, want %v", got, exp)
+	}
+
+	return ""
+}
@@ -0,0 +1,42 @@
+package searchdb
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/ardanlabs/service/business/domain/searchbus"
+)
+
+func applyFilter(filter searchbus.QueryFilter, data map[string]any, buf *bytes.Buffer) {
+	var wc []string
+
+	if filter.ID != nil {
+		data["search_id"] = filter.ID
+		wc = append(wc, "search_id = :search_id")
+	}
+
+	if filter.UserID != nil {
+		data["user_id"] = filter.UserID
+		wc = append(wc, "user_id = :user_id")
+	}
+
+	if filter.Domain != nil {
+		data["domain"] = *filter.Domain
+		wc = append(wc, "domain = :domain")
+	}
+
+	if filter.Name != nil {
+		data["name"] = "%" + *filter.Name + "%"
+		wc = append(wc, "name LIKE :name")
+	}
+
+	if filter.VisibleTo != nil {
+		data["visible_to"] = filter.VisibleTo
+		wc = append(wc, "(user_id = :visible_to OR shared)")
+	}
+
+	if len(wc) > 0 {
+		buf.WriteString(" WHERE ")
+		buf.WriteString(strings.Join(wc, " AND "))
+	}
+}
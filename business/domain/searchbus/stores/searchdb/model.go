@@ -0,0 +1,104 @@
+package searchdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/searchbus"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx/types"
+)
+
+type search struct {
+	ID             uuid.UUID      `db:"search_id"`
+	UserID         uuid.UUID      `db:"user_id"`
+	Name           string         `db:"name"`
+	Domain         string         `db:"domain"`
+	Filter         types.JSONText `db:"filter"`
+	OrderField     string         `db:"order_field"`
+	OrderDirection string         `db:"order_direction"`
+	OrderThen      types.JSONText `db:"order_then"`
+	Shared         bool           `db:"shared"`
+	DateCreated    time.Time      `db:"date_created"`
+	DateUpdated    time.Time      `db:"date_updated"`
+}
+
+func toDBSearch(bus searchbus.Search) (search, error) {
+	filter := bus.Filter
+	if filter == nil {
+		filter = map[string]string{}
+	}
+
+	data, err := json.Marshal(filter)
+	if err != nil {
+		return search{}, fmt.Errorf("marshal filter: %w", err)
+	}
+
+	then := bus.OrderBy.Then
+	if then == nil {
+		then = []order.By{}
+	}
+
+	thenData, err := json.Marshal(then)
+	if err != nil {
+		return search{}, fmt.Errorf("marshal order: %w", err)
+	}
+
+	db := search{
+		ID:             bus.ID,
+		UserID:         bus.UserID,
+		Name:           bus.Name,
+		Domain:         bus.Domain,
+		Filter:         data,
+		OrderField:     bus.OrderBy.Field,
+		OrderDirection: bus.OrderBy.Direction,
+		OrderThen:      thenData,
+		Shared:         bus.Shared,
+		DateCreated:    bus.DateCreated.UTC(),
+		DateUpdated:    bus.DateUpdated.UTC(),
+	}
+
+	return db, nil
+}
+
+func toBusSearch(db search) (searchbus.Search, error) {
+	var filter map[string]string
+	if err := json.Unmarshal(db.Filter, &filter); err != nil {
+		return searchbus.Search{}, fmt.Errorf("unmarshal filter: searchID[%s]: %w", db.ID, err)
+	}
+
+	var then []order.By
+	if err := json.Unmarshal(db.OrderThen, &then); err != nil {
+		return searchbus.Search{}, fmt.Errorf("unmarshal order: searchID[%s]: %w", db.ID, err)
+	}
+
+	bus := searchbus.Search{
+		ID:          db.ID,
+		UserID:      db.UserID,
+		Name:        db.Name,
+		Domain:      db.Domain,
+		Filter:      filter,
+		OrderBy:     order.NewBy(db.OrderField, db.OrderDirection, then...),
+		Shared:      db.Shared,
+		DateCreated: db.DateCreated.In(time.Local),
+		DateUpdated: db.DateUpdated.In(time.Local),
+	}
+
+	return bus, nil
+}
+
+func toBusSearches(dbs []search) ([]searchbus.Search, error) {
+	bus := make([]searchbus.Search, len(dbs))
+
+	for i, db := range dbs {
+		var err error
+		bus[i], err = toBusSearch(db)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return bus, nil
+}
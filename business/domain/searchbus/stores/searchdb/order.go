@@ -0,0 +1,10 @@
+package searchdb
+
+import (
+	"github.com/ardanlabs/service/business/domain/searchbus"
+	"github.com/ardanlabs/service/business/sdk/order"
+)
+
+func orderByClause(orderBy order.By) (string, error) {
+	return searchbus.OrderFields.Clause(orderBy)
+}
@@ -0,0 +1,241 @@
+// Package searchdb contains saved search related CRUD functionality.
+package searchdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ardanlabs/service/business/domain/searchbus"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Store manages the set of APIs for saved search database access.
+type Store struct {
+	log *logger.Logger
+	db  sqlx.ExtContext
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *logger.Logger, db sqlx.ExtContext) *Store {
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// NewWithTx constructs a new Store value replacing the sqlx DB
+// value with a sqlx DB value that is currently inside a transaction.
+func (s *Store) NewWithTx(tx sqldb.CommitRollbacker) (searchbus.Storer, error) {
+	ec, err := sqldb.GetExtContext(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	store := Store{
+		log: s.log,
+		db:  ec,
+	}
+
+	return &store, nil
+}
+
+// Create inserts a new search into the database.
+func (s *Store) Create(ctx context.Context, srch searchbus.Search) error {
+	const q = `
+	INSERT INTO saved_searches
+		(search_id, user_id, name, domain, filter, order_field, order_direction, order_then, shared, date_created, date_updated)
+	VALUES
+		(:search_id, :user_id, :name, :domain, :filter, :order_field, :order_direction, :order_then, :shared, :date_created, :date_updated)`
+
+	dbSrch, err := toDBSearch(srch)
+	if err != nil {
+		return err
+	}
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, dbSrch); err != nil {
+		if errors.Is(err, sqldb.ErrDBDuplicatedEntry) {
+			return fmt.Errorf("namedexeccontext: %w", searchbus.ErrUniqueName)
+		}
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Update replaces a search document in the database.
+func (s *Store) Update(ctx context.Context, srch searchbus.Search) error {
+	const q = `
+	UPDATE
+		saved_searches
+	SET
+		"name" = :name,
+		"filter" = :filter,
+		"order_field" = :order_field,
+		"order_direction" = :order_direction,
+		"order_then" = :order_then,
+		"shared" = :shared,
+		"date_updated" = :date_updated
+	WHERE
+		search_id = :search_id`
+
+	dbSrch, err := toDBSearch(srch)
+	if err != nil {
+		return err
+	}
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, dbSrch); err != nil {
+		if errors.Is(err, sqldb.ErrDBDuplicatedEntry) {
+			return fmt.Errorf("namedexeccontext: %w", searchbus.ErrUniqueName)
+		}
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes a search from the database.
+func (s *Store) Delete(ctx context.Context, srch searchbus.Search) error {
+	data := struct {
+		ID string `db:"search_id"`
+	}{
+		ID: srch.ID.String(),
+	}
+
+	const q = `
+	DELETE FROM
+		saved_searches
+	WHERE
+		search_id = :search_id`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Query retrieves a list of existing searches from the database.
+func (s *Store) Query(ctx context.Context, filter searchbus.QueryFilter, orderBy order.By, page page.Page) ([]searchbus.Search, error) {
+	data := map[string]any{
+		"offset":        (page.Number() - 1) * page.RowsPerPage(),
+		"rows_per_page": page.RowsPerPage(),
+	}
+
+	const q = `
+	SELECT
+		search_id, user_id, name, domain, filter, order_field, order_direction, order_then, shared, date_created, date_updated
+	FROM
+		saved_searches`
+
+	buf := bytes.NewBufferString(q)
+	applyFilter(filter, data, buf)
+
+	orderByClause, err := orderByClause(orderBy)
+	if err != nil {
+		return nil, err
+	}
+
+	buf.WriteString(orderByClause)
+	buf.WriteString(" OFFSET :offset ROWS FETCH NEXT :rows_per_page ROWS ONLY")
+
+	var dbSrchs []search
+	if err := sqldb.NamedQuerySlice(ctx, s.log, s.db, buf.String(), data, &dbSrchs); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toBusSearches(dbSrchs)
+}
+
+// Count returns the total number of searches in the DB.
+func (s *Store) Count(ctx context.Context, filter searchbus.QueryFilter) (int, error) {
+	data := map[string]any{}
+
+	const q = `
+	SELECT
+		count(1)
+	FROM
+		saved_searches`
+
+	buf := bytes.NewBufferString(q)
+	applyFilter(filter, data, buf)
+
+	var count struct {
+		Count int `db:"count"`
+	}
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, buf.String(), data, &count); err != nil {
+		return 0, fmt.Errorf("db: %w", err)
+	}
+
+	return count.Count, nil
+}
+
+// QueryByID gets the specified search from the database.
+func (s *Store) QueryByID(ctx context.Context, searchID uuid.UUID) (searchbus.Search, error) {
+	data := struct {
+		ID string `db:"search_id"`
+	}{
+		ID: searchID.String(),
+	}
+
+	const q = `
+	SELECT
+		search_id, user_id, name, domain, filter, order_field, order_direction, order_then, shared, date_created, date_updated
+	FROM
+		saved_searches
+	WHERE
+		search_id = :search_id`
+
+	var dbSrch search
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbSrch); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return searchbus.Search{}, fmt.Errorf("db: %w", searchbus.ErrNotFound)
+		}
+		return searchbus.Search{}, fmt.Errorf("db: %w", err)
+	}
+
+	return toBusSearch(dbSrch)
+}
+
+// QueryByName gets the search with the name for the domain that the user
+// saved, or failing that one that is shared, from the database.
+func (s *Store) QueryByName(ctx context.Context, userID uuid.UUID, domain string, name string) (searchbus.Search, error) {
+	data := struct {
+		UserID string `db:"user_id"`
+		Domain string `db:"domain"`
+		Name   string `db:"name"`
+	}{
+		UserID: userID.String(),
+		Domain: domain,
+		Name:   name,
+	}
+
+	const q = `
+	SELECT
+		search_id, user_id, name, domain, filter, order_field, order_direction, order_then, shared, date_created, date_updated
+	FROM
+		saved_searches
+	WHERE
+		domain = :domain AND
+		name = :name AND
+		(user_id = :user_id OR shared)
+	ORDER BY
+		(user_id = :user_id) DESC, date_created ASC
+	LIMIT 1`
+
+	var dbSrch search
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbSrch); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return searchbus.Search{}, fmt.Errorf("db: %w", searchbus.ErrNotFound)
+		}
+		return searchbus.Search{}, fmt.Errorf("db: %w", err)
+	}
+
+	return toBusSearch(dbSrch)
+}
@@ -0,0 +1,51 @@
+package sessionbus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/delegate"
+	"github.com/ardanlabs/service/foundation/ctxval"
+	"github.com/google/uuid"
+)
+
+var currentSessionKey = ctxval.NewKey[uuid.UUID]("current session")
+
+// WithCurrentSession returns a copy of the context holding the session the
+// call is made from, so the session is kept when the user's password
+// changes.
+func WithCurrentSession(ctx context.Context, sessionID uuid.UUID) context.Context {
+	return currentSessionKey.Set(ctx, sessionID)
+}
+
+// registerDelegateFunctions will register action functions with the delegate
+// system. If the business was constructed for query only, there won't be a
+// delegate provided.
+func (b *Business) registerDelegateFunctions() {
+	if b.delegate != nil {
+		b.delegate.Register(userbus.DomainName, userbus.ActionPasswordChanged, b.actionUserPasswordChanged)
+	}
+}
+
+// actionUserPasswordChanged is executed by the user domain indirectly when
+// users change their password. Sessions started with the old password are
+// revoked so a stolen refresh token stops working. The session the change
+// was made from, if any, is kept.
+func (b *Business) actionUserPasswordChanged(ctx context.Context, data delegate.Data) error {
+	var params userbus.ActionPasswordChangedParms
+	err := json.Unmarshal(data.RawParams, &params)
+	if err != nil {
+		return fmt.Errorf("expected an encoded %T: %w", params, err)
+	}
+
+	n, err := b.RevokeOthers(ctx, params.UserID, currentSessionKey.Value(ctx))
+	if err != nil {
+		return err
+	}
+
+	b.log.Info(ctx, "action-userpasswordchanged", "user_id", params.UserID, "revoked", n)
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package sessionbus
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Session represents a login on a device. The session is kept alive by
+// exchanging its refresh token for a new one before it expires. Only hashes
+// of the current and previous refresh tokens are kept.
+type Session struct {
+	ID           uuid.UUID
+	UserID       uuid.UUID
+	Device       string
+	TokenHash    string `class:"restricted"`
+	PreviousHash string `class:"restricted"`
+	DateCreated  time.Time
+	DateExpires  time.Time
+	DateLastUsed time.Time
+	DateRevoked  time.Time
+}
+
+// Revoked reports if the session has been revoked.
+func (s Session) Revoked() bool {
+	return !s.DateRevoked.IsZero()
+}
+
+// Active reports if the session can still be refreshed at the specified
+// time.
+func (s Session) Active(now time.Time) bool {
+	return !s.Revoked() && now.Before(s.DateExpires)
+}
+
+// NewSession is what we require to start a new session.
+type NewSession struct {
+	UserID uuid.UUID
+	Device string
+}
@@ -0,0 +1,23 @@
+package sessionbus
+
+import "github.com/ardanlabs/service/business/sdk/order"
+
+// DefaultOrderBy represents the default way we sort.
+var DefaultOrderBy = order.NewBy(OrderByLastUsed, order.DESC)
+
+// Set of fields that the results can be ordered by.
+const (
+	OrderByID          = "a"
+	OrderByDevice      = "b"
+	OrderByDateCreated = "c"
+	OrderByLastUsed    = "d"
+)
+
+// OrderFields represents the fields the results can be ordered by, the names
+// clients use for them and the columns the stores order by.
+var OrderFields = order.Register("session",
+	order.Field{Name: "session_id", Key: OrderByID, Column: "session_id"},
+	order.Field{Name: "device", Key: OrderByDevice, Column: "device"},
+	order.Field{Name: "date_created", Key: OrderByDateCreated, Column: "date_created"},
+	order.Field{Name: "date_last_used", Key: OrderByLastUsed, Column: "date_last_used"},
+)
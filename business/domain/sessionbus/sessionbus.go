@@ -0,0 +1,354 @@
+// Package sessionbus provides business access to session domain.
+package sessionbus
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/buserr"
+	"github.com/ardanlabs/service/business/sdk/delegate"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/foundation/clock"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/otel"
+	"github.com/google/uuid"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrNotFound     = buserr.New(buserr.NotFound, "session not found")
+	ErrInvalidToken = buserr.New(buserr.Unauthenticated, "invalid refresh token")
+	ErrExpired      = buserr.New(buserr.Unauthenticated, "session expired")
+	ErrRevoked      = buserr.New(buserr.Unauthenticated, "session revoked")
+	ErrTokenReused  = buserr.New(buserr.Unauthenticated, "refresh token reused")
+	ErrUserDisabled = buserr.New(buserr.Unauthenticated, "user disabled")
+)
+
+// Storer interface declares the behavior this package needs to persist and
+// retrieve data.
+type Storer interface {
+	NewWithTx(tx sqldb.CommitRollbacker) (Storer, error)
+	Create(ctx context.Context, sess Session) error
+	Update(ctx context.Context, sess Session) error
+	Rotate(ctx context.Context, sess Session) error
+	Query(ctx context.Context, filter QueryFilter, orderBy order.By, page page.Page) ([]Session, error)
+	Count(ctx context.Context, filter QueryFilter) (int, error)
+	QueryByID(ctx context.Context, sessionID uuid.UUID) (Session, error)
+	QueryByTokenHash(ctx context.Context, tokenHash string) (Session, error)
+	RevokeAll(ctx context.Context, userID uuid.UUID, keepID uuid.UUID, dateRevoked time.Time) (int, error)
+}
+
+// Business manages the set of APIs for session access.
+type Business struct {
+	log      *logger.Logger
+	userBus  userbus.Business
+	delegate *delegate.Delegate
+	storer   Storer
+	ttl      time.Duration
+}
+
+// NewBusiness constructs a session business API for use. A session expires
+// when it hasn't been refreshed for the specified ttl.
+func NewBusiness(log *logger.Logger, userBus userbus.Business, delegate *delegate.Delegate, storer Storer, ttl time.Duration) *Business {
+	b := Business{
+		log:      log,
+		userBus:  userBus,
+		delegate: delegate,
+		storer:   storer,
+		ttl:      ttl,
+	}
+
+	b.registerDelegateFunctions()
+
+	return &b
+}
+
+// NewWithTx constructs a new business value that will use the
+// specified transaction in any store related calls.
+func (b *Business) NewWithTx(tx sqldb.CommitRollbacker) (*Business, error) {
+	storer, err := b.storer.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	userBus, err := b.userBus.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	bus := Business{
+		log:      b.log,
+		userBus:  userBus,
+		delegate: b.delegate,
+		storer:   storer,
+		ttl:      b.ttl,
+	}
+
+	return &bus, nil
+}
+
+// Create starts a new session for the user on the device. The refresh token
+// is returned along with the session and can't be retrieved again.
+func (b *Business) Create(ctx context.Context, ns NewSession) (Session, string, error) {
+	ctx, span := otel.AddSpan(ctx, "business.sessionbus.create")
+	defer span.End()
+
+	token, err := generate()
+	if err != nil {
+		return Session{}, "", err
+	}
+
+	now := clock.Now()
+
+	sess := Session{
+		ID:           uuid.New(),
+		UserID:       ns.UserID,
+		Device:       ns.Device,
+		TokenHash:    hash(token),
+		DateCreated:  now,
+		DateExpires:  now.Add(b.ttl),
+		DateLastUsed: now,
+	}
+
+	if err := b.storer.Create(ctx, sess); err != nil {
+		return Session{}, "", fmt.Errorf("create: %w", err)
+	}
+
+	return sess, token, nil
+}
+
+// Refresh exchanges the refresh token for a new one and extends the session.
+// Each token can only be used once. Presenting a token that has already been
+// exchanged means it was copied, so the session is revoked.
+func (b *Business) Refresh(ctx context.Context, token string) (Session, string, error) {
+	ctx, span := otel.AddSpan(ctx, "business.sessionbus.refresh")
+	defer span.End()
+
+	tokenHash := hash(token)
+
+	sess, err := b.storer.QueryByTokenHash(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return Session{}, "", ErrInvalidToken
+		}
+		return Session{}, "", fmt.Errorf("querybytokenhash: %w", err)
+	}
+
+	now := clock.Now()
+
+	switch {
+	case sess.Revoked():
+		return Session{}, "", fmt.Errorf("sessionID[%s]: %w", sess.ID, ErrRevoked)
+
+	case sess.TokenHash != tokenHash:
+		if _, err := b.Revoke(ctx, sess); err != nil {
+			return Session{}, "", err
+		}
+		return Session{}, "", fmt.Errorf("sessionID[%s]: %w", sess.ID, ErrTokenReused)
+
+	case !sess.Active(now):
+		return Session{}, "", fmt.Errorf("sessionID[%s]: %w", sess.ID, ErrExpired)
+	}
+
+	usr, err := b.userBus.QueryByID(ctx, sess.UserID)
+	if err != nil {
+		return Session{}, "", fmt.Errorf("user.querybyid: %s: %w", sess.UserID, err)
+	}
+
+	if !usr.Enabled {
+		return Session{}, "", ErrUserDisabled
+	}
+
+	newToken, err := generate()
+	if err != nil {
+		return Session{}, "", err
+	}
+
+	sess.PreviousHash = sess.TokenHash
+	sess.TokenHash = hash(newToken)
+	sess.DateLastUsed = now
+	sess.DateExpires = now.Add(b.ttl)
+
+	if err := b.storer.Rotate(ctx, sess); err != nil {
+		if !errors.Is(err, ErrTokenReused) {
+			return Session{}, "", fmt.Errorf("rotate: %w", err)
+		}
+
+		// Another refresh rotated the token first, so the token was used
+		// twice and the session is revoked the same as any other reuse.
+		if err := b.revokeByID(ctx, sess.ID); err != nil {
+			return Session{}, "", err
+		}
+		return Session{}, "", fmt.Errorf("sessionID[%s]: %w", sess.ID, ErrTokenReused)
+	}
+
+	return sess, newToken, nil
+}
+
+// Revoke ends the session so its refresh token can no longer be used.
+// Revoking a session that is already revoked has no effect.
+func (b *Business) Revoke(ctx context.Context, sess Session) (Session, error) {
+	ctx, span := otel.AddSpan(ctx, "business.sessionbus.revoke")
+	defer span.End()
+
+	if sess.Revoked() {
+		return sess, nil
+	}
+
+	sess.DateRevoked = clock.Now()
+
+	if err := b.storer.Update(ctx, sess); err != nil {
+		return Session{}, fmt.Errorf("update: %w", err)
+	}
+
+	return sess, nil
+}
+
+// RevokeByToken ends the session the refresh token belongs to.
+func (b *Business) RevokeByToken(ctx context.Context, token string) error {
+	ctx, span := otel.AddSpan(ctx, "business.sessionbus.revokebytoken")
+	defer span.End()
+
+	sess, err := b.storer.QueryByTokenHash(ctx, hash(token))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return ErrInvalidToken
+		}
+		return fmt.Errorf("querybytokenhash: %w", err)
+	}
+
+	if _, err := b.Revoke(ctx, sess); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RevokeAll ends every session of the user, logging them out everywhere.
+// The number of sessions revoked is returned.
+func (b *Business) RevokeAll(ctx context.Context, userID uuid.UUID) (int, error) {
+	ctx, span := otel.AddSpan(ctx, "business.sessionbus.revokeall")
+	defer span.End()
+
+	n, err := b.storer.RevokeAll(ctx, userID, uuid.Nil, clock.Now())
+	if err != nil {
+		return 0, fmt.Errorf("revokeall: userID[%s]: %w", userID, err)
+	}
+
+	return n, nil
+}
+
+// RevokeOthers ends every session of the user except the one specified,
+// logging them out everywhere else. The number of sessions revoked is
+// returned.
+func (b *Business) RevokeOthers(ctx context.Context, userID uuid.UUID, keepID uuid.UUID) (int, error) {
+	ctx, span := otel.AddSpan(ctx, "business.sessionbus.revokeothers")
+	defer span.End()
+
+	n, err := b.storer.RevokeAll(ctx, userID, keepID, clock.Now())
+	if err != nil {
+		return 0, fmt.Errorf("revokeall: userID[%s]: keepID[%s]: %w", userID, keepID, err)
+	}
+
+	return n, nil
+}
+
+// QueryByToken finds the session the refresh token belongs to. Only the
+// current token of a session matches, an exchanged one doesn't.
+func (b *Business) QueryByToken(ctx context.Context, token string) (Session, error) {
+	ctx, span := otel.AddSpan(ctx, "business.sessionbus.querybytoken")
+	defer span.End()
+
+	tokenHash := hash(token)
+
+	sess, err := b.storer.QueryByTokenHash(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return Session{}, ErrInvalidToken
+		}
+		return Session{}, fmt.Errorf("querybytokenhash: %w", err)
+	}
+
+	if sess.TokenHash != tokenHash {
+		return Session{}, ErrInvalidToken
+	}
+
+	return sess, nil
+}
+
+// Query retrieves a list of existing sessions.
+func (b *Business) Query(ctx context.Context, filter QueryFilter, orderBy order.By, page page.Page) ([]Session, error) {
+	ctx, span := otel.AddSpan(ctx, "business.sessionbus.query")
+	defer span.End()
+
+	sessions, err := b.storer.Query(ctx, filter, orderBy, page)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// Count returns the total number of sessions.
+func (b *Business) Count(ctx context.Context, filter QueryFilter) (int, error) {
+	ctx, span := otel.AddSpan(ctx, "business.sessionbus.count")
+	defer span.End()
+
+	return b.storer.Count(ctx, filter)
+}
+
+// QueryByID finds the session by the specified ID.
+func (b *Business) QueryByID(ctx context.Context, sessionID uuid.UUID) (Session, error) {
+	ctx, span := otel.AddSpan(ctx, "business.sessionbus.querybyid")
+	defer span.End()
+
+	sess, err := b.storer.QueryByID(ctx, sessionID)
+	if err != nil {
+		return Session{}, fmt.Errorf("query: sessionID[%s]: %w", sessionID, err)
+	}
+
+	return sess, nil
+}
+
+// =============================================================================
+
+// generate returns a new random refresh token.
+func generate() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("read random: %w", err)
+	}
+
+	return "rt_" + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hash returns the stored form of a refresh token. The tokens are random
+// enough that a plain SHA-256 hash is sufficient.
+func hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// revokeByID revokes the session as it's stored now, which may have changed
+// since it was read.
+func (b *Business) revokeByID(ctx context.Context, sessionID uuid.UUID) error {
+	sess, err := b.storer.QueryByID(ctx, sessionID)
+	if err != nil {
+		return fmt.Errorf("querybyid: sessionID[%s]: %w", sessionID, err)
+	}
+
+	if _, err := b.Revoke(ctx, sess); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,260 @@
+package sessionbus_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/ardanlabs/service/business/domain/sessionbus"
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/dbtest"
+	"github.com/ardanlabs/service/business/sdk/unitest"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_Session(t *testing.T) {
+	t.Parallel()
+
+	db := dbtest.New(t, "Test_Session")
+
+	sd, err := insertSeedData(db.BusDomain)
+	if err != nil {
+		t.Fatalf("Seeding error: %s", err)
+	}
+
+	// -------------------------------------------------------------------------
+
+	unitest.Run(t, refresh(db.BusDomain, sd), "refresh")
+	unitest.Run(t, revokeAll(db.BusDomain, sd), "revokeall")
+}
+
+// =============================================================================
+
+func insertSeedData(busDomain dbtest.BusDomain) (unitest.SeedData, error) {
+	ctx := context.Background()
+
+	usrs, err := userbus.TestSeedUsers(ctx, 2, role.User, busDomain.User)
+	if err != nil {
+		return unitest.SeedData{}, fmt.Errorf("seeding users : %w", err)
+	}
+
+	sd := unitest.SeedData{
+		Users: []unitest.User{
+			{User: usrs[0]},
+			{User: usrs[1]},
+		},
+	}
+
+	return sd, nil
+}
+
+// =============================================================================
+
+func isErr(got any, exp any) string {
+	err, _ := got.(error)
+	if !errors.Is(err, exp.(error)) {
+		return fmt.Sprintf("got %v, exp %v", got, exp)
+	}
+	return ""
+}
+
+func refresh(busDomain dbtest.BusDomain, sd unitest.SeedData) []unitest.Table {
+	ns := sessionbus.NewSession{
+		UserID: sd.Users[0].ID,
+		Device: "test",
+	}
+
+	table := []unitest.Table{
+		{
+			Name:    "rotate",
+			ExpResp: true,
+			ExcFunc: func(ctx context.Context) any {
+				sess, token, err := busDomain.Session.Create(ctx, ns)
+				if err != nil {
+					return err
+				}
+
+				got, newToken, err := busDomain.Session.Refresh(ctx, token)
+				if err != nil {
+					return err
+				}
+
+				return got.ID == sess.ID && newToken != token
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+		{
+			Name:    "reuse",
+			ExpResp: sessionbus.ErrTokenReused,
+			ExcFunc: func(ctx context.Context) any {
+				_, token, err := busDomain.Session.Create(ctx, ns)
+				if err != nil {
+					return err
+				}
+
+				if _, _, err := busDomain.Session.Refresh(ctx, token); err != nil {
+					return err
+				}
+
+				_, _, err = busDomain.Session.Refresh(ctx, token)
+				return err
+			},
+			CmpFunc: isErr,
+		},
+		{
+			Name:    "reuse-revokes",
+			ExpResp: sessionbus.ErrRevoked,
+			ExcFunc: func(ctx context.Context) any {
+				_, token, err := busDomain.Session.Create(ctx, ns)
+				if err != nil {
+					return err
+				}
+
+				_, newToken, err := busDomain.Session.Refresh(ctx, token)
+				if err != nil {
+					return err
+				}
+
+				if _, _, err := busDomain.Session.Refresh(ctx, token); !errors.Is(err, sessionbus.ErrTokenReused) {
+					return fmt.Errorf("expected reuse: %w", err)
+				}
+
+				_, _, err = busDomain.Session.Refresh(ctx, newToken)
+				return err
+			},
+			CmpFunc: isErr,
+		},
+		{
+			Name:    "concurrent",
+			ExpResp: 1,
+			ExcFunc: func(ctx context.Context) any {
+				_, token, err := busDomain.Session.Create(ctx, ns)
+				if err != nil {
+					return err
+				}
+
+				// The refreshes race with the same token, only one of them
+				// may rotate it and the rest are treated as a reuse.
+				const refreshes = 10
+
+				var wg sync.WaitGroup
+				errs := make(chan error, refreshes)
+
+				for range refreshes {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						_, _, err := busDomain.Session.Refresh(ctx, token)
+						errs <- err
+					}()
+				}
+
+				wg.Wait()
+				close(errs)
+
+				var rotated int
+				for err := range errs {
+					switch {
+					case err == nil:
+						rotated++
+					case !errors.Is(err, sessionbus.ErrTokenReused) && !errors.Is(err, sessionbus.ErrRevoked):
+						return err
+					}
+				}
+
+				return rotated
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+		{
+			Name:    "invalid",
+			ExpResp: sessionbus.ErrInvalidToken,
+			ExcFunc: func(ctx context.Context) any {
+				_, _, err := busDomain.Session.Refresh(ctx, "rt_unknown")
+				return err
+			},
+			CmpFunc: isErr,
+		},
+	}
+
+	return table
+}
+
+func revokeAll(busDomain dbtest.BusDomain, sd unitest.SeedData) []unitest.Table {
+	table := []unitest.Table{
+		{
+			Name:    "everywhere",
+			ExpResp: sessionbus.ErrRevoked,
+			ExcFunc: func(ctx context.Context) any {
+				var tokens []string
+				for _, device := range []string{"phone", "laptop"} {
+					ns := sessionbus.NewSession{
+						UserID: sd.Users[1].ID,
+						Device: device,
+					}
+
+					_, token, err := busDomain.Session.Create(ctx, ns)
+					if err != nil {
+						return err
+					}
+					tokens = append(tokens, token)
+				}
+
+				n, err := busDomain.Session.RevokeAll(ctx, sd.Users[1].ID)
+				if err != nil {
+					return err
+				}
+
+				if n != len(tokens) {
+					return fmt.Errorf("revoked %d sessions, exp %d", n, len(tokens))
+				}
+
+				_, _, err = busDomain.Session.Refresh(ctx, tokens[1])
+				return err
+			},
+			CmpFunc: isErr,
+		},
+		{
+			Name:    "others",
+			ExpResp: sessionbus.ErrRevoked,
+			ExcFunc: func(ctx context.Context) any {
+				var sessions []sessionbus.Session
+				var tokens []string
+				for _, device := range []string{"phone", "laptop"} {
+					ns := sessionbus.NewSession{
+						UserID: sd.Users[0].ID,
+						Device: device,
+					}
+
+					sess, token, err := busDomain.Session.Create(ctx, ns)
+					if err != nil {
+						return err
+					}
+					sessions = append(sessions, sess)
+					tokens = append(tokens, token)
+				}
+
+				if _, err := busDomain.Session.RevokeOthers(ctx, sd.Users[0].ID, sessions[0].ID); err != nil {
+					return err
+				}
+
+				if _, _, err := busDomain.Session.Refresh(ctx, tokens[0]); err != nil {
+					return fmt.Errorf("should keep the session: %w", err)
+				}
+
+				_, _, err := busDomain.Session.Refresh(ctx, tokens[1])
+				return err
+			},
+			CmpFunc: isErr,
+		},
+	}
+
+	return table
+}
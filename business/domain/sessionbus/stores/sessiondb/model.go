@@ -0,0 +1,70 @@
+package sessiondb
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/sessionbus"
+	"github.com/google/uuid"
+)
+
+type session struct {
+	ID           uuid.UUID      `db:"session_id"`
+	UserID       uuid.UUID      `db:"user_id"`
+	Device       string         `db:"device"`
+	TokenHash    string         `db:"token_hash" class:"restricted"`
+	PreviousHash sql.NullString `db:"previous_hash" class:"restricted"`
+	DateCreated  time.Time      `db:"date_created"`
+	DateExpires  time.Time      `db:"date_expires"`
+	DateLastUsed time.Time      `db:"date_last_used"`
+	DateRevoked  sql.NullTime   `db:"date_revoked"`
+}
+
+func toDBSession(bus sessionbus.Session) session {
+	return session{
+		ID:        bus.ID,
+		UserID:    bus.UserID,
+		Device:    bus.Device,
+		TokenHash: bus.TokenHash,
+		PreviousHash: sql.NullString{
+			String: bus.PreviousHash,
+			Valid:  bus.PreviousHash != "",
+		},
+		DateCreated:  bus.DateCreated.UTC(),
+		DateExpires:  bus.DateExpires.UTC(),
+		DateLastUsed: bus.DateLastUsed.UTC(),
+		DateRevoked: sql.NullTime{
+			Time:  bus.DateRevoked.UTC(),
+			Valid: !bus.DateRevoked.IsZero(),
+		},
+	}
+}
+
+func toBusSession(db session) sessionbus.Session {
+	bus := sessionbus.Session{
+		ID:           db.ID,
+		UserID:       db.UserID,
+		Device:       db.Device,
+		TokenHash:    db.TokenHash,
+		PreviousHash: db.PreviousHash.String,
+		DateCreated:  db.DateCreated.In(time.Local),
+		DateExpires:  db.DateExpires.In(time.Local),
+		DateLastUsed: db.DateLastUsed.In(time.Local),
+	}
+
+	if db.DateRevoked.Valid {
+		bus.DateRevoked = db.DateRevoked.Time.In(time.Local)
+	}
+
+	return bus
+}
+
+func toBusSessions(dbs []session) []sessionbus.Session {
+	bus := make([]sessionbus.Session, len(dbs))
+
+	for i, db := range dbs {
+		bus[i] = toBusSession(db)
+	}
+
+	return bus
+}
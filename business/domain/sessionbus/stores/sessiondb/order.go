@@ -0,0 +1,10 @@
+package sessiondb
+
+import (
+	"github.com/ardanlabs/service/business/domain/sessionbus"
+	"github.com/ardanlabs/service/business/sdk/order"
+)
+
+func orderByClause(orderBy order.By) (string, error) {
+	return sessionbus.OrderFields.Clause(orderBy)
+}
@@ -0,0 +1,258 @@
+// Package sessiondb contains session related CRUD functionality.
+package sessiondb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/sessionbus"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Store manages the set of APIs for session database access.
+type Store struct {
+	log *logger.Logger
+	db  sqlx.ExtContext
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *logger.Logger, db sqlx.ExtContext) *Store {
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// NewWithTx constructs a new Store value replacing the sqlx DB
+// value with a sqlx DB value that is currently inside a transaction.
+func (s *Store) NewWithTx(tx sqldb.CommitRollbacker) (sessionbus.Storer, error) {
+	ec, err := sqldb.GetExtContext(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	store := Store{
+		log: s.log,
+		db:  ec,
+	}
+
+	return &store, nil
+}
+
+// Create inserts a new session into the database.
+func (s *Store) Create(ctx context.Context, sess sessionbus.Session) error {
+	const q = `
+	INSERT INTO sessions
+		(session_id, user_id, device, token_hash, previous_hash, date_created, date_expires, date_last_used, date_revoked)
+	VALUES
+		(:session_id, :user_id, :device, :token_hash, :previous_hash, :date_created, :date_expires, :date_last_used, :date_revoked)`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBSession(sess)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Update replaces a session document in the database.
+func (s *Store) Update(ctx context.Context, sess sessionbus.Session) error {
+	const q = `
+	UPDATE
+		sessions
+	SET
+		"token_hash" = :token_hash,
+		"previous_hash" = :previous_hash,
+		"date_expires" = :date_expires,
+		"date_last_used" = :date_last_used,
+		"date_revoked" = :date_revoked
+	WHERE
+		session_id = :session_id`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBSession(sess)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Rotate replaces the refresh token of a session. The update only applies
+// when the stored token is still the one being rotated, which the session
+// carries as its previous hash, otherwise ErrTokenReused is returned so two
+// refreshes with the same token can't both succeed.
+func (s *Store) Rotate(ctx context.Context, sess sessionbus.Session) error {
+	const q = `
+	UPDATE
+		sessions
+	SET
+		"token_hash" = :token_hash,
+		"previous_hash" = :previous_hash,
+		"date_expires" = :date_expires,
+		"date_last_used" = :date_last_used
+	WHERE
+		session_id = :session_id AND
+		token_hash = :previous_hash AND
+		date_revoked IS NULL
+	RETURNING
+		session_id`
+
+	var dest struct {
+		ID uuid.UUID `db:"session_id"`
+	}
+
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, toDBSession(sess), &dest); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return fmt.Errorf("namedquerystruct: sessionID[%s]: %w", sess.ID, sessionbus.ErrTokenReused)
+		}
+		return fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return nil
+}
+
+// Query retrieves a list of existing sessions from the database.
+func (s *Store) Query(ctx context.Context, filter sessionbus.QueryFilter, orderBy order.By, page page.Page) ([]sessionbus.Session, error) {
+	data := map[string]any{
+		"offset":        (page.Number() - 1) * page.RowsPerPage(),
+		"rows_per_page": page.RowsPerPage(),
+	}
+
+	const q = `
+	SELECT
+		session_id, user_id, device, token_hash, previous_hash, date_created, date_expires, date_last_used, date_revoked
+	FROM
+		sessions`
+
+	buf := bytes.NewBufferString(q)
+	applyFilter(filter, data, buf)
+
+	orderByClause, err := orderByClause(orderBy)
+	if err != nil {
+		return nil, err
+	}
+
+	buf.WriteString(orderByClause)
+	buf.WriteString(" OFFSET :offset ROWS FETCH NEXT :rows_per_page ROWS ONLY")
+
+	var dbSessions []session
+	if err := sqldb.NamedQuerySlice(ctx, s.log, s.db, buf.String(), data, &dbSessions); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toBusSessions(dbSessions), nil
+}
+
+// Count returns the total number of sessions in the DB.
+func (s *Store) Count(ctx context.Context, filter sessionbus.QueryFilter) (int, error) {
+	data := map[string]any{}
+
+	const q = `
+	SELECT
+		count(1)
+	FROM
+		sessions`
+
+	buf := bytes.NewBufferString(q)
+	applyFilter(filter, data, buf)
+
+	var count struct {
+		Count int `db:"count"`
+	}
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, buf.String(), data, &count); err != nil {
+		return 0, fmt.Errorf("db: %w", err)
+	}
+
+	return count.Count, nil
+}
+
+// QueryByID gets the specified session from the database.
+func (s *Store) QueryByID(ctx context.Context, sessionID uuid.UUID) (sessionbus.Session, error) {
+	data := struct {
+		ID string `db:"session_id"`
+	}{
+		ID: sessionID.String(),
+	}
+
+	const q = `
+	SELECT
+		session_id, user_id, device, token_hash, previous_hash, date_created, date_expires, date_last_used, date_revoked
+	FROM
+		sessions
+	WHERE
+		session_id = :session_id`
+
+	var dbSession session
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbSession); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return sessionbus.Session{}, fmt.Errorf("db: %w", sessionbus.ErrNotFound)
+		}
+		return sessionbus.Session{}, fmt.Errorf("db: %w", err)
+	}
+
+	return toBusSession(dbSession), nil
+}
+
+// QueryByTokenHash gets the session whose current or previous refresh token
+// has the specified hash.
+func (s *Store) QueryByTokenHash(ctx context.Context, tokenHash string) (sessionbus.Session, error) {
+	data := struct {
+		TokenHash string `db:"token_hash"`
+	}{
+		TokenHash: tokenHash,
+	}
+
+	const q = `
+	SELECT
+		session_id, user_id, device, token_hash, previous_hash, date_created, date_expires, date_last_used, date_revoked
+	FROM
+		sessions
+	WHERE
+		token_hash = :token_hash OR previous_hash = :token_hash`
+
+	var dbSession session
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbSession); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return sessionbus.Session{}, fmt.Errorf("db: %w", sessionbus.ErrNotFound)
+		}
+		return sessionbus.Session{}, fmt.Errorf("db: %w", err)
+	}
+
+	return toBusSession(dbSession), nil
+}
+
+// RevokeAll revokes every session of the user that isn't already revoked,
+// except the session to keep, and returns the number of sessions revoked.
+// Pass uuid.Nil to keep no session.
+func (s *Store) RevokeAll(ctx context.Context, userID uuid.UUID, keepID uuid.UUID, dateRevoked time.Time) (int, error) {
+	data := map[string]any{
+		"user_id":      userID,
+		"keep_id":      keepID,
+		"date_revoked": dateRevoked.UTC(),
+	}
+
+	const q = `
+	UPDATE
+		sessions
+	SET
+		"date_revoked" = :date_revoked
+	WHERE
+		user_id = :user_id AND session_id <> :keep_id AND date_revoked IS NULL
+	RETURNING
+		session_id`
+
+	var ids []struct {
+		ID uuid.UUID `db:"session_id"`
+	}
+	if err := sqldb.NamedQuerySlice(ctx, s.log, s.db, q, data, &ids); err != nil {
+		return 0, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return len(ids), nil
+}
@@ -0,0 +1,57 @@
+package templatebus
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// DefaultLocale is the last locale tried when resolving a template.
+const DefaultLocale = "en"
+
+var localeRegEx = regexp.MustCompile(`^[a-z]{2,3}(-[A-Z]{2})?$`)
+
+// ParseLocale parses a language tag like "pt-br" or "pt_BR" into the
+// canonical form used by templates, "pt-BR".
+func ParseLocale(value string) (string, error) {
+	lang, region, _ := strings.Cut(strings.ReplaceAll(value, "_", "-"), "-")
+
+	locale := strings.ToLower(lang)
+	if region != "" {
+		locale += "-" + strings.ToUpper(region)
+	}
+
+	if !localeRegEx.MatchString(locale) {
+		return "", fmt.Errorf("invalid locale %q", value)
+	}
+
+	return locale, nil
+}
+
+// Fallbacks returns the locales to try, in order, when resolving a
+// template for the locale. A regional locale falls back to its language
+// and every locale falls back to the default.
+//
+//	pt-BR => pt-BR, pt, en
+func Fallbacks(locale string) []string {
+	var chain []string
+
+	add := func(l string) {
+		if !slices.Contains(chain, l) {
+			chain = append(chain, l)
+		}
+	}
+
+	if locale != "" {
+		add(locale)
+
+		if lang, _, found := strings.Cut(locale, "-"); found {
+			add(lang)
+		}
+	}
+
+	add(DefaultLocale)
+
+	return chain
+}
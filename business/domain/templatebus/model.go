@@ -0,0 +1,48 @@
+package templatebus
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Template represents a transactional email template for a locale. Stored
+// templates override the ones shipped in the template pack.
+type Template struct {
+	ID          uuid.UUID
+	Name        string
+	Locale      string
+	Subject     string
+	Body        string
+	Enabled     bool
+	DateCreated time.Time
+	DateUpdated time.Time
+}
+
+// NewTemplate is what we require from clients when adding a Template. New
+// templates are disabled until they have been previewed and enabled.
+type NewTemplate struct {
+	Name    string
+	Locale  string
+	Subject string
+	Body    string
+}
+
+// UpdateTemplate defines what information may be provided to modify an
+// existing Template. All fields are optional so clients can send just the
+// fields they want changed.
+type UpdateTemplate struct {
+	Subject *string
+	Body    *string
+	Enabled *bool
+}
+
+// Message represents a rendered template ready to be handed to a mailer.
+// The locale is the one the template was resolved for, which can differ
+// from the requested locale when a fallback was used.
+type Message struct {
+	Name    string
+	Locale  string
+	Subject string
+	Body    string
+}
@@ -0,0 +1,21 @@
+package templatebus
+
+import "github.com/ardanlabs/service/business/sdk/order"
+
+// DefaultOrderBy represents the default way we sort.
+var DefaultOrderBy = order.NewBy(OrderByName, order.ASC)
+
+// Set of fields that the results can be ordered by.
+const (
+	OrderByID     = "a"
+	OrderByName   = "b"
+	OrderByLocale = "c"
+)
+
+// OrderFields represents the fields the results can be ordered by, the names
+// clients use for them and the columns the stores order by.
+var OrderFields = order.Register("template",
+	order.Field{Name: "template_id", Key: OrderByID, Column: "template_id"},
+	order.Field{Name: "name", Key: OrderByName, Column: "name"},
+	order.Field{Name: "locale", Key: OrderByLocale, Column: "locale"},
+)
@@ -0,0 +1,80 @@
+package templatebus
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+)
+
+//go:embed pack/*.json
+var packFS embed.FS
+
+// shipped holds the templates shipped with the service. The pack is
+// embedded so a broken template fails every test and build that imports
+// this package.
+var shipped = mustLoadPack()
+
+// packEntry represents a template shipped with the service.
+type packEntry struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// pack holds the templates shipped with the service keyed by locale and
+// then by name.
+type pack map[string]map[string]compiled
+
+// loadPack parses and compiles every template in the pack. Each file holds
+// the templates for the locale in its name.
+func loadPack() (pack, error) {
+	files, err := packFS.ReadDir("pack")
+	if err != nil {
+		return nil, fmt.Errorf("readdir: %w", err)
+	}
+
+	p := make(pack)
+
+	for _, file := range files {
+		locale := strings.TrimSuffix(file.Name(), path.Ext(file.Name()))
+
+		data, err := packFS.ReadFile(path.Join("pack", file.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("readfile: %s: %w", file.Name(), err)
+		}
+
+		var entries map[string]packEntry
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, fmt.Errorf("unmarshal: %s: %w", file.Name(), err)
+		}
+
+		templates := make(map[string]compiled, len(entries))
+		for name, entry := range entries {
+			c, err := compile(entry.Subject, entry.Body)
+			if err != nil {
+				return nil, fmt.Errorf("compile: %s/%s: %w", locale, name, err)
+			}
+			templates[name] = c
+		}
+
+		p[locale] = templates
+	}
+
+	return p, nil
+}
+
+func mustLoadPack() pack {
+	p, err := loadPack()
+	if err != nil {
+		panic(err)
+	}
+
+	return p
+}
+
+// lookup returns the template shipped for the name and locale.
+func (p pack) lookup(name string, locale string) (compiled, bool) {
+	c, exists := p[locale][name]
+	return c, exists
+}
@@ -0,0 +1,149 @@
+package templatebus
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// maxOutput limits the size of a rendered template.
+const maxOutput = 256 * 1024
+
+// errOutputTooLarge is returned when a rendered template exceeds maxOutput.
+var errOutputTooLarge = errors.New("rendered template is too large")
+
+// compiled represents a template whose subject and body have been parsed.
+type compiled struct {
+	subject *template.Template
+	body    *htmltemplate.Template
+}
+
+// compile parses the subject and body in a sandbox. The templates have no
+// functions beyond the builtins, can't define or include other templates,
+// and fail on a missing key. The subject is plain text and the body is
+// HTML escaped.
+func compile(subject string, body string) (compiled, error) {
+	subjectTmpl, err := template.New("subject").Option("missingkey=error").Parse(subject)
+	if err != nil {
+		return compiled{}, fmt.Errorf("%w: subject: %w", ErrInvalidTemplate, err)
+	}
+
+	if err := checkSandbox(subjectTmpl.Tree, len(subjectTmpl.Templates())); err != nil {
+		return compiled{}, fmt.Errorf("%w: subject: %w", ErrInvalidTemplate, err)
+	}
+
+	bodyTmpl, err := htmltemplate.New("body").Option("missingkey=error").Parse(body)
+	if err != nil {
+		return compiled{}, fmt.Errorf("%w: body: %w", ErrInvalidTemplate, err)
+	}
+
+	if err := checkSandbox(bodyTmpl.Tree, len(bodyTmpl.Templates())); err != nil {
+		return compiled{}, fmt.Errorf("%w: body: %w", ErrInvalidTemplate, err)
+	}
+
+	c := compiled{
+		subject: subjectTmpl,
+		body:    bodyTmpl,
+	}
+
+	return c, nil
+}
+
+// checkSandbox rejects templates that define or call other templates, which
+// could be used to recurse without limit.
+func checkSandbox(tree *parse.Tree, templates int) error {
+	if templates > 1 {
+		return errors.New("defining templates is not allowed")
+	}
+
+	if tree == nil || tree.Root == nil {
+		return nil
+	}
+
+	var walk func(node parse.Node) error
+	walk = func(node parse.Node) error {
+		switch n := node.(type) {
+		case *parse.TemplateNode:
+			return fmt.Errorf("calling template %q is not allowed", n.Name)
+
+		case *parse.ListNode:
+			if n == nil {
+				return nil
+			}
+			for _, child := range n.Nodes {
+				if err := walk(child); err != nil {
+					return err
+				}
+			}
+
+		case *parse.IfNode:
+			return walkBranch(walk, &n.BranchNode)
+
+		case *parse.RangeNode:
+			return walkBranch(walk, &n.BranchNode)
+
+		case *parse.WithNode:
+			return walkBranch(walk, &n.BranchNode)
+		}
+
+		return nil
+	}
+
+	return walk(tree.Root)
+}
+
+func walkBranch(walk func(parse.Node) error, n *parse.BranchNode) error {
+	if err := walk(n.List); err != nil {
+		return err
+	}
+
+	if n.ElseList != nil {
+		return walk(n.ElseList)
+	}
+
+	return nil
+}
+
+// render executes the compiled template with the data. The data is limited
+// to strings so templates can't call methods on values from the service.
+func (c compiled) render(name string, locale string, data map[string]string) (Message, error) {
+	var subject bytes.Buffer
+	if err := c.subject.Execute(&limitWriter{w: &subject}, data); err != nil {
+		return Message{}, fmt.Errorf("%w: subject: %w", ErrInvalidTemplate, err)
+	}
+
+	var body bytes.Buffer
+	if err := c.body.Execute(&limitWriter{w: &body}, data); err != nil {
+		return Message{}, fmt.Errorf("%w: body: %w", ErrInvalidTemplate, err)
+	}
+
+	// A line break in the subject could be used to inject mail headers.
+	msg := Message{
+		Name:    name,
+		Locale:  locale,
+		Subject: strings.NewReplacer("\r", " ", "\n", " ").Replace(subject.String()),
+		Body:    body.String(),
+	}
+
+	return msg, nil
+}
+
+// limitWriter fails once more than maxOutput bytes have been written.
+type limitWriter struct {
+	w io.Writer
+	n int
+}
+
+func (lw *limitWriter) Write(p []byte) (int, error) {
+	lw.n += len(p)
+	if lw.n > maxOutput {
+		return 0, errOutputTooLarge
+	}
+
+	return lw.w.Write(p)
+}
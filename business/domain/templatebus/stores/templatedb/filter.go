@@ -0,0 +1,37 @@
+package templatedb
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/ardanlabs/service/business/domain/templatebus"
+)
+
+func applyFilter(filter templatebus.QueryFilter, data map[string]any, buf *bytes.Buffer) {
+	var wc []string
+
+	if filter.ID != nil {
+		data["template_id"] = filter.ID
+		wc = append(wc, "template_id = :template_id")
+	}
+
+	if filter.Name != nil {
+		data["name"] = *filter.Name
+		wc = append(wc, "name = :name")
+	}
+
+	if filter.Locale != nil {
+		data["locale"] = *filter.Locale
+		wc = append(wc, "locale = :locale")
+	}
+
+	if filter.Enabled != nil {
+		data["enabled"] = *filter.Enabled
+		wc = append(wc, "enabled = :enabled")
+	}
+
+	if len(wc) > 0 {
+		buf.WriteString(" WHERE ")
+		buf.WriteString(strings.Join(wc, " AND "))
+	}
+}
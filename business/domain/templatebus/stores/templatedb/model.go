@@ -0,0 +1,55 @@
+package templatedb
+
+import (
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/templatebus"
+	"github.com/google/uuid"
+)
+
+type template struct {
+	ID          uuid.UUID `db:"template_id"`
+	Name        string    `db:"name"`
+	Locale      string    `db:"locale"`
+	Subject     string    `db:"subject"`
+	Body        string    `db:"body"`
+	Enabled     bool      `db:"enabled"`
+	DateCreated time.Time `db:"date_created"`
+	DateUpdated time.Time `db:"date_updated"`
+}
+
+func toDBTemplate(bus templatebus.Template) template {
+	return template{
+		ID:          bus.ID,
+		Name:        bus.Name,
+		Locale:      bus.Locale,
+		Subject:     bus.Subject,
+		Body:        bus.Body,
+		Enabled:     bus.Enabled,
+		DateCreated: bus.DateCreated.UTC(),
+		DateUpdated: bus.DateUpdated.UTC(),
+	}
+}
+
+func toBusTemplate(db template) templatebus.Template {
+	return templatebus.Template{
+		ID:          db.ID,
+		Name:        db.Name,
+		Locale:      db.Locale,
+		Subject:     db.Subject,
+		Body:        db.Body,
+		Enabled:     db.Enabled,
+		DateCreated: db.DateCreated.In(time.Local),
+		DateUpdated: db.DateUpdated.In(time.Local),
+	}
+}
+
+func toBusTemplates(dbs []template) []templatebus.Template {
+	bus := make([]templatebus.Template, len(dbs))
+
+	for i, db := range dbs {
+		bus[i] = toBusTemplate(db)
+	}
+
+	return bus
+}
@@ -0,0 +1,10 @@
+package templatedb
+
+import (
+	"github.com/ardanlabs/service/business/domain/templatebus"
+	"github.com/ardanlabs/service/business/sdk/order"
+)
+
+func orderByClause(orderBy order.By) (string, error) {
+	return templatebus.OrderFields.Clause(orderBy)
+}
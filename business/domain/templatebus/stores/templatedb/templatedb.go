@@ -0,0 +1,218 @@
+// Package templatedb contains template related CRUD functionality.
+package templatedb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ardanlabs/service/business/domain/templatebus"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Store manages the set of APIs for template database access.
+type Store struct {
+	log *logger.Logger
+	db  sqlx.ExtContext
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *logger.Logger, db sqlx.ExtContext) *Store {
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// NewWithTx constructs a new Store value replacing the sqlx DB
+// value with a sqlx DB value that is currently inside a transaction.
+func (s *Store) NewWithTx(tx sqldb.CommitRollbacker) (templatebus.Storer, error) {
+	ec, err := sqldb.GetExtContext(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	store := Store{
+		log: s.log,
+		db:  ec,
+	}
+
+	return &store, nil
+}
+
+// Create inserts a new template into the database.
+func (s *Store) Create(ctx context.Context, tmpl templatebus.Template) error {
+	const q = `
+	INSERT INTO email_templates
+		(template_id, name, locale, subject, body, enabled, date_created, date_updated)
+	VALUES
+		(:template_id, :name, :locale, :subject, :body, :enabled, :date_created, :date_updated)`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBTemplate(tmpl)); err != nil {
+		if errors.Is(err, sqldb.ErrDBDuplicatedEntry) {
+			return fmt.Errorf("namedexeccontext: %w", templatebus.ErrUniqueTemplate)
+		}
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Update replaces a template document in the database.
+func (s *Store) Update(ctx context.Context, tmpl templatebus.Template) error {
+	const q = `
+	UPDATE
+		email_templates
+	SET
+		"subject" = :subject,
+		"body" = :body,
+		"enabled" = :enabled,
+		"date_updated" = :date_updated
+	WHERE
+		template_id = :template_id`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBTemplate(tmpl)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes a template from the database.
+func (s *Store) Delete(ctx context.Context, tmpl templatebus.Template) error {
+	data := struct {
+		ID string `db:"template_id"`
+	}{
+		ID: tmpl.ID.String(),
+	}
+
+	const q = `
+	DELETE FROM
+		email_templates
+	WHERE
+		template_id = :template_id`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Query retrieves a list of existing templates from the database.
+func (s *Store) Query(ctx context.Context, filter templatebus.QueryFilter, orderBy order.By, page page.Page) ([]templatebus.Template, error) {
+	data := map[string]any{
+		"offset":        (page.Number() - 1) * page.RowsPerPage(),
+		"rows_per_page": page.RowsPerPage(),
+	}
+
+	const q = `
+	SELECT
+		template_id, name, locale, subject, body, enabled, date_created, date_updated
+	FROM
+		email_templates`
+
+	buf := bytes.NewBufferString(q)
+	applyFilter(filter, data, buf)
+
+	orderByClause, err := orderByClause(orderBy)
+	if err != nil {
+		return nil, err
+	}
+
+	buf.WriteString(orderByClause)
+	buf.WriteString(" OFFSET :offset ROWS FETCH NEXT :rows_per_page ROWS ONLY")
+
+	var dbTmpls []template
+	if err := sqldb.NamedQuerySlice(ctx, s.log, s.db, buf.String(), data, &dbTmpls); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toBusTemplates(dbTmpls), nil
+}
+
+// Count returns the total number of templates in the DB.
+func (s *Store) Count(ctx context.Context, filter templatebus.QueryFilter) (int, error) {
+	data := map[string]any{}
+
+	const q = `
+	SELECT
+		count(1)
+	FROM
+		email_templates`
+
+	buf := bytes.NewBufferString(q)
+	applyFilter(filter, data, buf)
+
+	var count struct {
+		Count int `db:"count"`
+	}
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, buf.String(), data, &count); err != nil {
+		return 0, fmt.Errorf("db: %w", err)
+	}
+
+	return count.Count, nil
+}
+
+// QueryByID gets the specified template from the database.
+func (s *Store) QueryByID(ctx context.Context, templateID uuid.UUID) (templatebus.Template, error) {
+	data := struct {
+		ID string `db:"template_id"`
+	}{
+		ID: templateID.String(),
+	}
+
+	const q = `
+	SELECT
+		template_id, name, locale, subject, body, enabled, date_created, date_updated
+	FROM
+		email_templates
+	WHERE
+		template_id = :template_id`
+
+	var dbTmpl template
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbTmpl); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return templatebus.Template{}, fmt.Errorf("db: %w", templatebus.ErrNotFound)
+		}
+		return templatebus.Template{}, fmt.Errorf("db: %w", err)
+	}
+
+	return toBusTemplate(dbTmpl), nil
+}
+
+// QueryByNameLocale gets the template with the specified name and locale
+// from the database.
+func (s *Store) QueryByNameLocale(ctx context.Context, name string, locale string) (templatebus.Template, error) {
+	data := struct {
+		Name   string `db:"name"`
+		Locale string `db:"locale"`
+	}{
+		Name:   name,
+		Locale: locale,
+	}
+
+	const q = `
+	SELECT
+		template_id, name, locale, subject, body, enabled, date_created, date_updated
+	FROM
+		email_templates
+	WHERE
+		name = :name AND locale = :locale`
+
+	var dbTmpl template
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbTmpl); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return templatebus.Template{}, fmt.Errorf("db: %w", templatebus.ErrNotFound)
+		}
+		return templatebus.Template{}, fmt.Errorf("db: %w", err)
+	}
+
+	return toBusTemplate(dbTmpl), nil
+}
@@ -0,0 +1,220 @@
+// Package templatebus provides business access to the transactional email
+// template domain.
+package templatebus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ardanlabs/service/business/sdk/buserr"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/foundation/clock"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/otel"
+	"github.com/google/uuid"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrNotFound        = buserr.New(buserr.NotFound, "template not found")
+	ErrUniqueTemplate  = buserr.New(buserr.Conflict, "template already exists for the locale")
+	ErrInvalidTemplate = buserr.New(buserr.InvalidArgument, "invalid template")
+)
+
+// Storer interface declares the behavior this package needs to persist and
+// retrieve data.
+type Storer interface {
+	NewWithTx(tx sqldb.CommitRollbacker) (Storer, error)
+	Create(ctx context.Context, tmpl Template) error
+	Update(ctx context.Context, tmpl Template) error
+	Delete(ctx context.Context, tmpl Template) error
+	Query(ctx context.Context, filter QueryFilter, orderBy order.By, page page.Page) ([]Template, error)
+	Count(ctx context.Context, filter QueryFilter) (int, error)
+	QueryByID(ctx context.Context, templateID uuid.UUID) (Template, error)
+	QueryByNameLocale(ctx context.Context, name string, locale string) (Template, error)
+}
+
+// Business manages the set of APIs for template access.
+type Business struct {
+	log    *logger.Logger
+	storer Storer
+}
+
+// NewBusiness constructs a template business API for use.
+func NewBusiness(log *logger.Logger, storer Storer) *Business {
+	return &Business{
+		log:    log,
+		storer: storer,
+	}
+}
+
+// NewWithTx constructs a new business value that will use the
+// specified transaction in any store related calls.
+func (b *Business) NewWithTx(tx sqldb.CommitRollbacker) (*Business, error) {
+	storer, err := b.storer.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	bus := Business{
+		log:    b.log,
+		storer: storer,
+	}
+
+	return &bus, nil
+}
+
+// Create adds a new template override to the system. The template must
+// compile before it's stored.
+func (b *Business) Create(ctx context.Context, nt NewTemplate) (Template, error) {
+	ctx, span := otel.AddSpan(ctx, "business.templatebus.create")
+	defer span.End()
+
+	if _, err := compile(nt.Subject, nt.Body); err != nil {
+		return Template{}, err
+	}
+
+	now := clock.Now()
+
+	tmpl := Template{
+		ID:          uuid.New(),
+		Name:        nt.Name,
+		Locale:      nt.Locale,
+		Subject:     nt.Subject,
+		Body:        nt.Body,
+		Enabled:     false,
+		DateCreated: now,
+		DateUpdated: now,
+	}
+
+	if err := b.storer.Create(ctx, tmpl); err != nil {
+		return Template{}, fmt.Errorf("create: %w", err)
+	}
+
+	return tmpl, nil
+}
+
+// Update modifies information about a template override.
+func (b *Business) Update(ctx context.Context, tmpl Template, ut UpdateTemplate) (Template, error) {
+	ctx, span := otel.AddSpan(ctx, "business.templatebus.update")
+	defer span.End()
+
+	if ut.Subject != nil {
+		tmpl.Subject = *ut.Subject
+	}
+
+	if ut.Body != nil {
+		tmpl.Body = *ut.Body
+	}
+
+	if ut.Enabled != nil {
+		tmpl.Enabled = *ut.Enabled
+	}
+
+	if _, err := compile(tmpl.Subject, tmpl.Body); err != nil {
+		return Template{}, err
+	}
+
+	tmpl.DateUpdated = clock.Now()
+
+	if err := b.storer.Update(ctx, tmpl); err != nil {
+		return Template{}, fmt.Errorf("update: %w", err)
+	}
+
+	return tmpl, nil
+}
+
+// Delete removes the specified template override. The shipped template, if
+// there is one, is used again.
+func (b *Business) Delete(ctx context.Context, tmpl Template) error {
+	ctx, span := otel.AddSpan(ctx, "business.templatebus.delete")
+	defer span.End()
+
+	if err := b.storer.Delete(ctx, tmpl); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	return nil
+}
+
+// Query retrieves a list of existing template overrides.
+func (b *Business) Query(ctx context.Context, filter QueryFilter, orderBy order.By, page page.Page) ([]Template, error) {
+	ctx, span := otel.AddSpan(ctx, "business.templatebus.query")
+	defer span.End()
+
+	tmpls, err := b.storer.Query(ctx, filter, orderBy, page)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return tmpls, nil
+}
+
+// Count returns the total number of template overrides.
+func (b *Business) Count(ctx context.Context, filter QueryFilter) (int, error) {
+	ctx, span := otel.AddSpan(ctx, "business.templatebus.count")
+	defer span.End()
+
+	return b.storer.Count(ctx, filter)
+}
+
+// QueryByID finds the template override by the specified ID.
+func (b *Business) QueryByID(ctx context.Context, templateID uuid.UUID) (Template, error) {
+	ctx, span := otel.AddSpan(ctx, "business.templatebus.querybyid")
+	defer span.End()
+
+	tmpl, err := b.storer.QueryByID(ctx, templateID)
+	if err != nil {
+		return Template{}, fmt.Errorf("query: templateID[%s]: %w", templateID, err)
+	}
+
+	return tmpl, nil
+}
+
+// =============================================================================
+
+// Render resolves the named template for the locale and renders it with the
+// data. Each locale in the fallback chain is tried in order and, for each
+// one, an enabled override wins over the shipped template.
+func (b *Business) Render(ctx context.Context, name string, locale string, data map[string]string) (Message, error) {
+	ctx, span := otel.AddSpan(ctx, "business.templatebus.render")
+	defer span.End()
+
+	for _, loc := range Fallbacks(locale) {
+		tmpl, err := b.storer.QueryByNameLocale(ctx, name, loc)
+		switch {
+		case err == nil && tmpl.Enabled:
+			c, err := compile(tmpl.Subject, tmpl.Body)
+			if err != nil {
+				return Message{}, fmt.Errorf("compile: templateID[%s]: %w", tmpl.ID, err)
+			}
+			return c.render(name, loc, data)
+
+		case err != nil && !errors.Is(err, ErrNotFound):
+			return Message{}, fmt.Errorf("querybynamelocale: name[%s] locale[%s]: %w", name, loc, err)
+		}
+
+		if c, exists := shipped.lookup(name, loc); exists {
+			return c.render(name, loc, data)
+		}
+	}
+
+	return Message{}, fmt.Errorf("name[%s] locale[%s]: %w", name, locale, ErrNotFound)
+}
+
+// Preview renders the template with sample data whether it's enabled or
+// not, so it can be checked before it's enabled.
+func (b *Business) Preview(ctx context.Context, tmpl Template, data map[string]string) (Message, error) {
+	_, span := otel.AddSpan(ctx, "business.templatebus.preview")
+	defer span.End()
+
+	c, err := compile(tmpl.Subject, tmpl.Body)
+	if err != nil {
+		return Message{}, err
+	}
+
+	return c.render(tmpl.Name, tmpl.Locale, data)
+}
@@ -0,0 +1,221 @@
+package templatebus_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ardanlabs/service/business/domain/templatebus"
+	"github.com/ardanlabs/service/business/sdk/dbtest"
+	"github.com/ardanlabs/service/business/sdk/unitest"
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_Template(t *testing.T) {
+	t.Parallel()
+
+	db := dbtest.New(t, "Test_Template")
+
+	// -------------------------------------------------------------------------
+
+	unitest.Run(t, create(db.BusDomain), "create")
+	unitest.Run(t, render(db.BusDomain), "render")
+	unitest.Run(t, preview(db.BusDomain), "preview")
+}
+
+// =============================================================================
+
+func create(busDomain dbtest.BusDomain) []unitest.Table {
+	table := []unitest.Table{
+		{
+			Name: "basic",
+			ExpResp: templatebus.Template{
+				Name:    "welcome",
+				Locale:  "es-MX",
+				Subject: "Hola {{.name}}",
+				Body:    "<p>Bienvenido</p>",
+				Enabled: false,
+			},
+			ExcFunc: func(ctx context.Context) any {
+				nt := templatebus.NewTemplate{
+					Name:    "welcome",
+					Locale:  "es-MX",
+					Subject: "Hola {{.name}}",
+					Body:    "<p>Bienvenido</p>",
+				}
+
+				resp, err := busDomain.Template.Create(ctx, nt)
+				if err != nil {
+					return err
+				}
+
+				return resp
+			},
+			CmpFunc: func(got any, exp any) string {
+				gotResp, exists := got.(templatebus.Template)
+				if !exists {
+					return "error occurred"
+				}
+
+				expResp := exp.(templatebus.Template)
+
+				expResp.ID = gotResp.ID
+				expResp.DateCreated = gotResp.DateCreated
+				expResp.DateUpdated = gotResp.DateUpdated
+
+				return cmp.Diff(gotResp, expResp)
+			},
+		},
+		{
+			Name:    "invalid",
+			ExpResp: templatebus.ErrInvalidTemplate,
+			ExcFunc: func(ctx context.Context) any {
+				nt := templatebus.NewTemplate{
+					Name:    "welcome",
+					Locale:  "fr",
+					Subject: "Bonjour",
+					Body:    `{{define "loop"}}{{template "loop"}}{{end}}`,
+				}
+
+				_, err := busDomain.Template.Create(ctx, nt)
+				return err
+			},
+			CmpFunc: func(got any, exp any) string {
+				err, _ := got.(error)
+				if !errors.Is(err, exp.(error)) {
+					return "expected an invalid template error"
+				}
+
+				return ""
+			},
+		},
+	}
+
+	return table
+}
+
+func render(busDomain dbtest.BusDomain) []unitest.Table {
+	data := map[string]string{
+		"name":  "Ana",
+		"email": "ana@example.com",
+	}
+
+	table := []unitest.Table{
+		{
+			Name: "fallback",
+			ExpResp: templatebus.Message{
+				Name:    "welcome",
+				Locale:  "pt",
+				Subject: "Bem-vindo, Ana",
+			},
+			ExcFunc: func(ctx context.Context) any {
+				resp, err := busDomain.Template.Render(ctx, "welcome", "pt-BR", data)
+				if err != nil {
+					return err
+				}
+
+				return resp
+			},
+			CmpFunc: func(got any, exp any) string {
+				gotResp, exists := got.(templatebus.Message)
+				if !exists {
+					return "error occurred"
+				}
+
+				expResp := exp.(templatebus.Message)
+				expResp.Body = gotResp.Body
+
+				return cmp.Diff(gotResp, expResp)
+			},
+		},
+		{
+			Name: "override",
+			ExpResp: templatebus.Message{
+				Name:    "welcome",
+				Locale:  "pt-BR",
+				Subject: "Olá, Ana",
+				Body:    "<p>Seja bem-vinda</p>",
+			},
+			ExcFunc: func(ctx context.Context) any {
+				nt := templatebus.NewTemplate{
+					Name:    "welcome",
+					Locale:  "pt-BR",
+					Subject: "Olá, {{.name}}",
+					Body:    "<p>Seja bem-vinda</p>",
+				}
+
+				tmpl, err := busDomain.Template.Create(ctx, nt)
+				if err != nil {
+					return err
+				}
+
+				enabled := true
+				if _, err := busDomain.Template.Update(ctx, tmpl, templatebus.UpdateTemplate{Enabled: &enabled}); err != nil {
+					return err
+				}
+
+				resp, err := busDomain.Template.Render(ctx, "welcome", "pt-BR", data)
+				if err != nil {
+					return err
+				}
+
+				return resp
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+		{
+			Name:    "notfound",
+			ExpResp: templatebus.ErrNotFound,
+			ExcFunc: func(ctx context.Context) any {
+				_, err := busDomain.Template.Render(ctx, "missing", "en", data)
+				return err
+			},
+			CmpFunc: func(got any, exp any) string {
+				err, _ := got.(error)
+				if !errors.Is(err, exp.(error)) {
+					return "expected a not found error"
+				}
+
+				return ""
+			},
+		},
+	}
+
+	return table
+}
+
+func preview(busDomain dbtest.BusDomain) []unitest.Table {
+	table := []unitest.Table{
+		{
+			Name: "escaped",
+			ExpResp: templatebus.Message{
+				Name:    "welcome",
+				Locale:  "en",
+				Subject: "Hi <b>Ana</b>",
+				Body:    "<p>&lt;b&gt;Ana&lt;/b&gt;</p>",
+			},
+			ExcFunc: func(ctx context.Context) any {
+				tmpl := templatebus.Template{
+					Name:    "welcome",
+					Locale:  "en",
+					Subject: "Hi {{.name}}",
+					Body:    "<p>{{.name}}</p>",
+				}
+
+				resp, err := busDomain.Template.Preview(ctx, tmpl, map[string]string{"name": "<b>Ana</b>"})
+				if err != nil {
+					return err
+				}
+
+				return resp
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+	}
+
+	return table
+}
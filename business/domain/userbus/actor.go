@@ -0,0 +1,90 @@
+package userbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/ardanlabs/service/business/sdk/buserr"
+)
+
+// ErrInvalidActor is returned when a change is attributed to an actor that
+// isn't an enabled user or a registered system identity.
+var ErrInvalidActor = buserr.New(buserr.PermissionDenied, "actor is not an enabled user or system identity")
+
+// Set of system identities known to the user domain. Work that isn't done
+// on behalf of a user is attributed to one of these so the audit trail
+// says what made the change.
+var (
+	// ActorSystem is the service itself, used by background jobs.
+	ActorSystem = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+	// ActorTooling is the admin tooling, used to seed and bootstrap users.
+	ActorTooling = uuid.MustParse("00000000-0000-0000-0000-000000000002")
+)
+
+var systemActors = struct {
+	mu     sync.RWMutex
+	actors map[uuid.UUID]string
+}{
+	actors: map[uuid.UUID]string{
+		ActorSystem:  "system",
+		ActorTooling: "tooling",
+	},
+}
+
+// RegisterSystemActor declares an identity a background component can act
+// as. Registering an identity again replaces its name. The zero UUID can't
+// be registered.
+func RegisterSystemActor(actorID uuid.UUID, name string) error {
+	if actorID == uuid.Nil {
+		return fmt.Errorf("actorID[%s]: %w", actorID, ErrInvalidActor)
+	}
+
+	systemActors.mu.Lock()
+	defer systemActors.mu.Unlock()
+
+	systemActors.actors[actorID] = name
+
+	return nil
+}
+
+// SystemActor returns the name of the system identity, false is returned
+// when the actor isn't one.
+func SystemActor(actorID uuid.UUID) (string, bool) {
+	systemActors.mu.RLock()
+	defer systemActors.mu.RUnlock()
+
+	name, exists := systemActors.actors[actorID]
+	return name, exists
+}
+
+// checkActor validates that a change can be attributed to the actor. The
+// actor has to be a registered system identity or a user that exists and
+// is enabled.
+func (b *business) checkActor(ctx context.Context, actorID uuid.UUID) error {
+	if actorID == uuid.Nil {
+		return fmt.Errorf("actorID[%s]: %w", actorID, ErrInvalidActor)
+	}
+
+	if _, exists := SystemActor(actorID); exists {
+		return nil
+	}
+
+	actor, err := b.storer.QueryByID(ctx, actorID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return fmt.Errorf("actorID[%s]: %w", actorID, ErrInvalidActor)
+		}
+		return fmt.Errorf("querybyid: actorID[%s]: %w", actorID, err)
+	}
+
+	if !actor.Enabled {
+		return fmt.Errorf("actorID[%s]: actor disabled: %w", actorID, ErrInvalidActor)
+	}
+
+	return nil
+}
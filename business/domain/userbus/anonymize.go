@@ -0,0 +1,98 @@
+package userbus
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"github.com/ardanlabs/service/business/types/name"
+	"github.com/ardanlabs/service/foundation/clock"
+	"github.com/ardanlabs/service/foundation/otel"
+	"github.com/google/uuid"
+)
+
+const (
+	// AnonymizedName is the name given to an anonymized user.
+	AnonymizedName = "Anonymized User"
+
+	// AnonymizedEmailDomain is the domain of the email given to an
+	// anonymized user. The .invalid top level domain is reserved so mail
+	// can never be delivered to it.
+	AnonymizedEmailDomain = "anonymized.invalid"
+)
+
+// Anonymize scrubs the personal data of the user as an alternative to
+// deleting them. The user is kept, disabled and with tombstone values for
+// the name and email, so the data of other domains that refers to the user
+// stays intact. Without a password hash the user can't authenticate, the
+// same as a federated user. The redact action is then invoked so other
+// domains redact the copies they hold. When one of them can't, the error is
+// returned and calling Anonymize again retries the redaction. Once every
+// domain has redacted its copies, the anonymized action is called.
+func (b *business) Anonymize(ctx context.Context, actorID uuid.UUID, userID uuid.UUID) (User, error) {
+	ctx, span := otel.AddSpan(ctx, "business.userbus.anonymize")
+	defer span.End()
+
+	if err := b.checkActor(ctx, actorID); err != nil {
+		return User{}, err
+	}
+
+	usr, err := b.QueryByID(ctx, userID)
+	if err != nil {
+		return User{}, err
+	}
+
+	nme, err := name.Parse(AnonymizedName)
+	if err != nil {
+		return User{}, fmt.Errorf("parse name: %w", err)
+	}
+
+	oldAvatarKey := usr.AvatarKey
+
+	usr.Name = nme
+	usr.Email = AnonymizedEmail(usr.ID)
+	usr.PasswordHash = nil
+	usr.Enabled = false
+	usr.TOTPSecret = ""
+	usr.TOTPEnabled = false
+	usr.AvatarKey = ""
+	usr.UpdatedBy = actorID
+	usr.DateUpdated = clock.Now()
+	usr.Version++
+
+	if err := b.storer.Update(ctx, usr); err != nil {
+		return User{}, fmt.Errorf("update: %w", err)
+	}
+
+	if err := b.storer.DeletePersonalData(ctx, usr.ID); err != nil {
+		return User{}, fmt.Errorf("deletepersonaldata: %w", err)
+	}
+
+	b.deleteAvatar(ctx, oldAvatarKey)
+
+	// Other domains may hold copies of the user's personal data. The user
+	// isn't anonymized until every one of them has redacted its copies.
+	if err := b.delegate.Invoke(ctx, ActionRedactData(usr.ID)); err != nil {
+		return User{}, fmt.Errorf("failed to execute `%s` action: %w", ActionRedact, err)
+	}
+
+	if err := b.delegate.Call(ctx, ActionAnonymizedData(usr.ID, usr.TenantID)); err != nil {
+		return User{}, fmt.Errorf("failed to execute `%s` action: %w", ActionAnonymized, err)
+	}
+
+	return usr, nil
+}
+
+// AnonymizedEmail returns the tombstone email given to the user when they
+// are anonymized. It's unique to the user so the email stays unique.
+func AnonymizedEmail(userID uuid.UUID) mail.Address {
+	return mail.Address{
+		Address: fmt.Sprintf("%s@%s", userID, AnonymizedEmailDomain),
+	}
+}
+
+// IsAnonymized reports whether the user has been anonymized.
+func IsAnonymized(usr User) bool {
+	return strings.HasSuffix(usr.Email.Address, "@"+AnonymizedEmailDomain)
+}
@@ -0,0 +1,43 @@
+package userbus
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ardanlabs/service/foundation/ctxval"
+)
+
+// AttemptsError is returned when an authentication attempt is rejected
+// because too many were made. It matches ErrTooManyAttempts.
+type AttemptsError struct {
+	Key        string
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface.
+func (e *AttemptsError) Error() string {
+	return fmt.Sprintf("%s: %s, retry in %s", ErrTooManyAttempts, e.Key, e.RetryAfter)
+}
+
+// Unwrap returns ErrTooManyAttempts so errors.Is matches the error against
+// it.
+func (e *AttemptsError) Unwrap() error {
+	return ErrTooManyAttempts
+}
+
+// =============================================================================
+
+var sourceIPKey = ctxval.NewKey[string]("source ip")
+
+// WithSourceIP returns a copy of the context holding the address the call
+// came from, so authentication attempts can be limited by it.
+func WithSourceIP(ctx context.Context, ip string) context.Context {
+	return sourceIPKey.Set(ctx, ip)
+}
+
+// SourceIP returns the address the call came from, or an empty string when
+// it isn't known.
+func SourceIP(ctx context.Context) string {
+	return sourceIPKey.Value(ctx)
+}
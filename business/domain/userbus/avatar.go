@@ -0,0 +1,152 @@
+package userbus
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/ardanlabs/service/business/sdk/buserr"
+	"github.com/ardanlabs/service/foundation/clock"
+	"github.com/ardanlabs/service/foundation/otel"
+	"github.com/google/uuid"
+)
+
+// Set of error variables for avatars.
+var (
+	ErrAvatarsDisabled = buserr.New(buserr.Unimplemented, "avatars are not supported")
+	ErrAvatarTooLarge  = buserr.New(buserr.InvalidArgument, "avatar is too large")
+	ErrAvatarType      = buserr.New(buserr.InvalidArgument, "avatar must be a png, jpeg, gif or webp image")
+	ErrNoAvatar        = buserr.New(buserr.NotFound, "user has no avatar")
+)
+
+// AvatarMaxSize is the largest avatar that can be uploaded in bytes.
+const AvatarMaxSize = 2 << 20
+
+// AvatarURLTTL is how long a signed avatar url can be used for.
+const AvatarURLTTL = 15 * time.Minute
+
+// AvatarStorer defines the behavior required to keep avatars in blob
+// storage.
+type AvatarStorer interface {
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	Delete(ctx context.Context, key string) error
+	URL(ctx context.Context, key string, ttl time.Duration) (string, error)
+}
+
+// avatarTypes are the image types an avatar can be, with the extension the
+// blob is stored under.
+var avatarTypes = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// UpdateAvatar replaces the user's avatar with the image. The content type
+// has to name one of the supported image types and match what the image
+// turns out to be.
+func (b *business) UpdateAvatar(ctx context.Context, actorID uuid.UUID, userID uuid.UUID, r io.Reader, contentType string) (User, error) {
+	ctx, span := otel.AddSpan(ctx, "business.userbus.updateavatar")
+	defer span.End()
+
+	if err := b.checkActor(ctx, actorID); err != nil {
+		return User{}, err
+	}
+
+	if b.avatars == nil {
+		return User{}, ErrAvatarsDisabled
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return User{}, fmt.Errorf("content type[%s]: %w", contentType, ErrAvatarType)
+	}
+
+	ext, ok := avatarTypes[mediaType]
+	if !ok {
+		return User{}, fmt.Errorf("content type[%s]: %w", mediaType, ErrAvatarType)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, AvatarMaxSize+1))
+	if err != nil {
+		return User{}, fmt.Errorf("read: %w", err)
+	}
+
+	if len(data) > AvatarMaxSize {
+		return User{}, fmt.Errorf("max %d bytes: %w", AvatarMaxSize, ErrAvatarTooLarge)
+	}
+
+	// The content type is only what the client claims, the image is
+	// sniffed so a script can't be served back as a picture.
+	if sniffed := http.DetectContentType(data); sniffed != mediaType {
+		return User{}, fmt.Errorf("content type[%s] sniffed[%s]: %w", mediaType, sniffed, ErrAvatarType)
+	}
+
+	usr, err := b.QueryByID(ctx, userID)
+	if err != nil {
+		return User{}, err
+	}
+
+	// Every upload gets a new key so a cached copy of the old avatar is
+	// never served for the new one.
+	key := fmt.Sprintf("avatars/%s/%s%s", usr.ID, uuid.New(), ext)
+
+	if err := b.avatars.Put(ctx, key, bytes.NewReader(data), mediaType); err != nil {
+		return User{}, fmt.Errorf("put: %w", err)
+	}
+
+	oldKey := usr.AvatarKey
+
+	usr.AvatarKey = key
+	usr.UpdatedBy = actorID
+	usr.DateUpdated = clock.Now()
+	usr.Version++
+
+	if err := b.storer.Update(ctx, usr); err != nil {
+		b.deleteAvatar(ctx, key)
+		return User{}, fmt.Errorf("update: %w", err)
+	}
+
+	b.deleteAvatar(ctx, oldKey)
+
+	return usr, nil
+}
+
+// AvatarURL returns the address the user's avatar can be read from. The
+// address may be signed, in which case it expires after AvatarURLTTL.
+func (b *business) AvatarURL(ctx context.Context, usr User) (string, error) {
+	ctx, span := otel.AddSpan(ctx, "business.userbus.avatarurl")
+	defer span.End()
+
+	if b.avatars == nil {
+		return "", ErrAvatarsDisabled
+	}
+
+	if usr.AvatarKey == "" {
+		return "", fmt.Errorf("userID[%s]: %w", usr.ID, ErrNoAvatar)
+	}
+
+	u, err := b.avatars.URL(ctx, usr.AvatarKey, AvatarURLTTL)
+	if err != nil {
+		return "", fmt.Errorf("url: %w", err)
+	}
+
+	return u, nil
+}
+
+// deleteAvatar removes an avatar that's no longer used. A failure is only
+// logged, the user's data is already correct and an orphaned blob is
+// harmless.
+func (b *business) deleteAvatar(ctx context.Context, key string) {
+	if key == "" || b.avatars == nil {
+		return
+	}
+
+	if err := b.avatars.Delete(ctx, key); err != nil {
+		b.log.Error(ctx, "userbus: delete avatar", "key", key, "ERROR", err)
+	}
+}
@@ -0,0 +1,276 @@
+package userbus
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"runtime"
+	"slices"
+	"sync"
+
+	"github.com/ardanlabs/service/business/sdk/tenant"
+	"github.com/ardanlabs/service/foundation/clock"
+	"github.com/ardanlabs/service/foundation/otel"
+	"github.com/google/uuid"
+)
+
+// BatchMode determines how CreateBatch handles rows that fail.
+type BatchMode int
+
+// Set of batch modes.
+const (
+	// BatchAtomic doesn't insert any user if a single row fails validation
+	// and reports the first row the store rejects. The caller is expected
+	// to run the batch inside a transaction so it can be rolled back.
+	BatchAtomic BatchMode = iota
+
+	// BatchBestEffort inserts every row that passes validation and reports
+	// the rows that failed.
+	BatchBestEffort
+)
+
+// BatchError represents the failure of a single row in a batch. An Index of
+// -1 represents a failure that applies to the whole batch.
+type BatchError struct {
+	Index int
+	Err   error
+}
+
+// Error implements the error interface.
+func (be BatchError) Error() string {
+	return fmt.Sprintf("index[%d]: %s", be.Index, be.Err)
+}
+
+// Unwrap provides support for errors.Is and errors.As.
+func (be BatchError) Unwrap() error {
+	return be.Err
+}
+
+// CreateBatch adds a set of new users to the system. All rows are validated
+// up front, including email uniqueness against the batch and the store, and
+// the rows that pass are inserted in bulk. The users that were created are
+// returned along with the failure of each row that wasn't.
+func (b *business) CreateBatch(ctx context.Context, actorID uuid.UUID, nus []NewUser, mode BatchMode) ([]User, []BatchError) {
+	ctx, span := otel.AddSpan(ctx, "business.userbus.createbatch")
+	defer span.End()
+
+	if err := b.checkActor(ctx, actorID); err != nil {
+		return nil, []BatchError{{Index: -1, Err: err}}
+	}
+
+	failed := make(map[int]error)
+
+	if err := b.checkBatchEmails(ctx, nus, failed); err != nil {
+		return nil, []BatchError{{Index: -1, Err: err}}
+	}
+
+	for i, nu := range nus {
+		if _, exists := failed[i]; exists {
+			continue
+		}
+
+		if err := nu.Validate(); err != nil {
+			failed[i] = err
+			continue
+		}
+
+		if err := b.checkNewPassword(ctx, nu.Password); err != nil {
+			failed[i] = err
+			continue
+		}
+
+		if err := b.checkManager(ctx, uuid.Nil, nu.ManagerID); err != nil {
+			failed[i] = err
+		}
+	}
+
+	if mode == BatchAtomic && len(failed) > 0 {
+		return nil, toBatchErrors(failed)
+	}
+
+	hashes := b.hashBatch(nus, failed)
+
+	if mode == BatchAtomic && len(failed) > 0 {
+		return nil, toBatchErrors(failed)
+	}
+
+	now := clock.Now()
+	valid := make([]User, 0, len(nus))
+	index := make(map[uuid.UUID]int, len(nus))
+
+	for i, nu := range nus {
+		if _, exists := failed[i]; exists {
+			continue
+		}
+
+		usr := User{
+			ID:           uuid.New(),
+			TenantID:     tenant.OrDefault(ctx),
+			Name:         nu.Name,
+			Email:        nu.Email,
+			PasswordHash: hashes[i],
+			Roles:        nu.Roles,
+			Department:   nu.Department,
+			ManagerID:    nu.ManagerID,
+			Enabled:      true,
+			CreatedBy:    actorID,
+			UpdatedBy:    actorID,
+			DateCreated:  now,
+			DateUpdated:  now,
+			Version:      1,
+		}
+
+		if err := b.checkCreateRules(ctx, actorID, usr); err != nil {
+			if mode == BatchAtomic {
+				return nil, []BatchError{{Index: i, Err: err}}
+			}
+
+			failed[i] = err
+			continue
+		}
+
+		valid = append(valid, usr)
+		index[usr.ID] = i
+	}
+
+	usrs, err := b.createMany(ctx, valid, index, failed)
+	if err != nil {
+		return nil, []BatchError{{Index: -1, Err: err}}
+	}
+
+	if mode == BatchAtomic && len(failed) > 0 {
+		return nil, toBatchErrors(failed)[:1]
+	}
+
+	for _, usr := range usrs {
+		if err := b.delegate.Call(ctx, ActionCreatedData(usr.ID, usr.TenantID)); err != nil {
+			b.log.Error(ctx, "userbus: createbatch", "userID", usr.ID, "ERROR", fmt.Errorf("failed to execute `%s` action: %w", ActionCreated, err))
+		}
+	}
+
+	return usrs, toBatchErrors(failed)
+}
+
+// createMany inserts the users in bulk along with their password history.
+// A user the store skipped because their email was taken in the meantime
+// is marked as failed. The users that were inserted are returned.
+func (b *business) createMany(ctx context.Context, usrs []User, index map[uuid.UUID]int, failed map[int]error) ([]User, error) {
+	if len(usrs) == 0 {
+		return nil, nil
+	}
+
+	ids, err := b.storer.CreateMany(ctx, usrs)
+	if err != nil {
+		return nil, fmt.Errorf("createmany: %w", err)
+	}
+
+	created := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		created[id] = true
+	}
+
+	inserted := make([]User, 0, len(ids))
+	for _, usr := range usrs {
+		if !created[usr.ID] {
+			failed[index[usr.ID]] = fmt.Errorf("createmany: %w", ErrUniqueEmail)
+			continue
+		}
+
+		inserted = append(inserted, usr)
+	}
+
+	if b.policy.History > 0 && len(inserted) > 0 {
+		if err := b.storer.AddPasswordHistories(ctx, inserted); err != nil {
+			return nil, fmt.Errorf("addpasswordhistories: %w", err)
+		}
+	}
+
+	return inserted, nil
+}
+
+// checkBatchEmails marks the rows whose email is repeated in the batch or
+// already belongs to an existing user.
+func (b *business) checkBatchEmails(ctx context.Context, nus []NewUser, failed map[int]error) error {
+	seen := make(map[string]int)
+	emails := make([]mail.Address, 0, len(nus))
+
+	for i, nu := range nus {
+		key := nu.Email.Address
+
+		if first, exists := seen[key]; exists {
+			failed[i] = fmt.Errorf("duplicate of index[%d]: %w", first, ErrUniqueEmail)
+			continue
+		}
+
+		seen[key] = i
+		emails = append(emails, nu.Email)
+	}
+
+	usrs, err := b.storer.QueryByEmails(ctx, emails)
+	if err != nil {
+		return fmt.Errorf("querybyemails: %w", err)
+	}
+
+	for _, usr := range usrs {
+		if i, exists := seen[usr.Email.Address]; exists {
+			failed[i] = ErrUniqueEmail
+		}
+	}
+
+	return nil
+}
+
+// hashBatch hashes the passwords for the rows that haven't failed. Hashing is
+// intentionally slow so the work is spread across the available CPUs.
+func (b *business) hashBatch(nus []NewUser, failed map[int]error) [][]byte {
+	hashes := make([][]byte, len(nus))
+	errs := make([]error, len(nus))
+
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+
+	var wg sync.WaitGroup
+	for i, nu := range nus {
+		if _, exists := failed[i]; exists {
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			hashes[i], errs[i] = b.hasher.Hash(nu.Password)
+		}()
+	}
+
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			failed[i] = fmt.Errorf("hash: %w", err)
+		}
+	}
+
+	return hashes
+}
+
+func toBatchErrors(failed map[int]error) []BatchError {
+	if len(failed) == 0 {
+		return nil
+	}
+
+	bes := make([]BatchError, 0, len(failed))
+	for i, err := range failed {
+		bes = append(bes, BatchError{Index: i, Err: err})
+	}
+
+	slices.SortFunc(bes, func(a, b BatchError) int {
+		return a.Index - b.Index
+	})
+
+	return bes
+}
@@ -1,10 +1,13 @@
 package userbus
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"slices"
 
 	"github.com/ardanlabs/service/business/sdk/delegate"
+	"github.com/ardanlabs/service/business/types/role"
 	"github.com/google/uuid"
 )
 
@@ -13,17 +16,158 @@ const DomainName = "user"
 
 // Set of delegate actions.
 const (
-	ActionDeleted = "deleted"
+	ActionCreated         = "created"
+	ActionUpdated         = "updated"
+	ActionDeleted         = "deleted"
+	ActionCollect         = "collect"
+	ActionRedact          = "redact"
+	ActionAnonymized      = "anonymized"
+	ActionPasswordChanged = "passwordchanged"
 )
 
+// Set of fields reported as changed by the updated action.
+const (
+	FieldName       = "name"
+	FieldEmail      = "email"
+	FieldRoles      = "roles"
+	FieldPassword   = "password"
+	FieldDepartment = "department"
+	FieldEnabled    = "enabled"
+	FieldManager    = "manager"
+)
+
+// =============================================================================
+
+// ActionCreatedParms represents the parameters for the created action. The
+// events about a user carry the tenant the user belongs to, so they are only
+// sent to the subscribers of that tenant.
+type ActionCreatedParms struct {
+	UserID   uuid.UUID
+	TenantID uuid.UUID
+}
+
+// String returns a string representation of the action parameters.
+func (act *ActionCreatedParms) String() string {
+	return fmt.Sprintf("&EventParamsCreated{UserID:%v, TenantID:%v}", act.UserID, act.TenantID)
+}
+
+// Marshal returns the event parameters encoded as JSON.
+func (act *ActionCreatedParms) Marshal() ([]byte, error) {
+	return json.Marshal(act)
+}
+
+// ActionCreatedData constructs the data for the created action.
+func ActionCreatedData(userID uuid.UUID, tenantID uuid.UUID) delegate.Data {
+	params := ActionCreatedParms{
+		UserID:   userID,
+		TenantID: tenantID,
+	}
+
+	rawParams, err := params.Marshal()
+	if err != nil {
+		panic(err)
+	}
+
+	return delegate.Data{
+		Domain:    DomainName,
+		Action:    ActionCreated,
+		RawParams: rawParams,
+	}
+}
+
+// =============================================================================
+
+// ActionUpdatedParms represents the parameters for the updated action. The
+// fields hold the names of the fields whose value changed. The changes to
+// the fields are carried by the event itself.
+type ActionUpdatedParms struct {
+	UserID   uuid.UUID
+	TenantID uuid.UUID
+	Fields   []string
+}
+
+// String returns a string representation of the action parameters.
+func (act *ActionUpdatedParms) String() string {
+	return fmt.Sprintf("&EventParamsUpdated{UserID:%v, TenantID:%v, Fields:%v}", act.UserID, act.TenantID, act.Fields)
+}
+
+// Marshal returns the event parameters encoded as JSON.
+func (act *ActionUpdatedParms) Marshal() ([]byte, error) {
+	return json.Marshal(act)
+}
+
+// ActionUpdatedData constructs the data for the updated action.
+func ActionUpdatedData(userID uuid.UUID, tenantID uuid.UUID, changes []delegate.Change) delegate.Data {
+	data := delegate.Data{
+		Domain:  DomainName,
+		Action:  ActionUpdated,
+		Changes: changes,
+	}
+
+	params := ActionUpdatedParms{
+		UserID:   userID,
+		TenantID: tenantID,
+		Fields:   data.Fields(),
+	}
+
+	rawParams, err := params.Marshal()
+	if err != nil {
+		panic(err)
+	}
+
+	data.RawParams = rawParams
+
+	return data
+}
+
+// changes returns the changes to the fields whose value differs between the
+// two versions of the user. The values of fields classified above internal
+// are left out so they aren't copied into events.
+func changes(before User, after User) []delegate.Change {
+	var chgs []delegate.Change
+
+	if !before.Name.Equal(after.Name) {
+		chgs = append(chgs, delegate.Change{Field: FieldName})
+	}
+
+	if before.Email.Address != after.Email.Address || before.Email.Name != after.Email.Name {
+		chgs = append(chgs, delegate.Change{Field: FieldEmail})
+	}
+
+	if !slices.EqualFunc(before.Roles, after.Roles, role.Role.Equal) {
+		chgs = append(chgs, delegate.NewChange(FieldRoles, before.Roles, after.Roles))
+	}
+
+	if !bytes.Equal(before.PasswordHash, after.PasswordHash) {
+		chgs = append(chgs, delegate.Change{Field: FieldPassword})
+	}
+
+	if !before.Department.Equal(after.Department) {
+		chgs = append(chgs, delegate.NewChange(FieldDepartment, before.Department, after.Department))
+	}
+
+	if before.Enabled != after.Enabled {
+		chgs = append(chgs, delegate.NewChange(FieldEnabled, before.Enabled, after.Enabled))
+	}
+
+	if before.ManagerID != after.ManagerID {
+		chgs = append(chgs, delegate.NewChange(FieldManager, before.ManagerID, after.ManagerID))
+	}
+
+	return chgs
+}
+
+// =============================================================================
+
 // ActionDeletedParms represents the parameters for the deleted action.
 type ActionDeletedParms struct {
-	UserID uuid.UUID
+	UserID   uuid.UUID
+	TenantID uuid.UUID
 }
 
 // String returns a string representation of the action parameters.
 func (act *ActionDeletedParms) String() string {
-	return fmt.Sprintf("&EventParamsUpdated{UserID:%v}", act.UserID)
+	return fmt.Sprintf("&EventParamsDeleted{UserID:%v, TenantID:%v}", act.UserID, act.TenantID)
 }
 
 // Marshal returns the event parameters encoded as JSON.
@@ -32,9 +176,10 @@ func (act *ActionDeletedParms) Marshal() ([]byte, error) {
 }
 
 // ActionDeletedData constructs the data for the deleted action.
-func ActionDeletedData(userID uuid.UUID) delegate.Data {
+func ActionDeletedData(userID uuid.UUID, tenantID uuid.UUID) delegate.Data {
 	params := ActionDeletedParms{
-		UserID: userID,
+		UserID:   userID,
+		TenantID: tenantID,
 	}
 
 	rawParams, err := params.Marshal()
@@ -48,3 +193,155 @@ func ActionDeletedData(userID uuid.UUID) delegate.Data {
 		RawParams: rawParams,
 	}
 }
+
+// =============================================================================
+
+// ActionCollectParms represents the parameters for the collect action. It's
+// called when the user's data is exported, handlers add the data their
+// domain holds about the user with AddExportData.
+type ActionCollectParms struct {
+	UserID uuid.UUID
+}
+
+// String returns a string representation of the action parameters.
+func (act *ActionCollectParms) String() string {
+	return fmt.Sprintf("&EventParamsCollect{UserID:%v}", act.UserID)
+}
+
+// Marshal returns the event parameters encoded as JSON.
+func (act *ActionCollectParms) Marshal() ([]byte, error) {
+	return json.Marshal(act)
+}
+
+// ActionCollectData constructs the data for the collect action.
+func ActionCollectData(userID uuid.UUID) delegate.Data {
+	params := ActionCollectParms{
+		UserID: userID,
+	}
+
+	rawParams, err := params.Marshal()
+	if err != nil {
+		panic(err)
+	}
+
+	return delegate.Data{
+		Domain:    DomainName,
+		Action:    ActionCollect,
+		RawParams: rawParams,
+	}
+}
+
+// =============================================================================
+
+// ActionRedactParms represents the parameters for the redact action. It's
+// invoked when a user is anonymized, domains holding copies of the user's
+// personal data are expected to redact them and return an error when they
+// can't, which fails the anonymization.
+type ActionRedactParms struct {
+	UserID uuid.UUID
+}
+
+// String returns a string representation of the action parameters.
+func (act *ActionRedactParms) String() string {
+	return fmt.Sprintf("&EventParamsRedact{UserID:%v}", act.UserID)
+}
+
+// Marshal returns the event parameters encoded as JSON.
+func (act *ActionRedactParms) Marshal() ([]byte, error) {
+	return json.Marshal(act)
+}
+
+// ActionRedactData constructs the data for the redact action.
+func ActionRedactData(userID uuid.UUID) delegate.Data {
+	params := ActionRedactParms{
+		UserID: userID,
+	}
+
+	rawParams, err := params.Marshal()
+	if err != nil {
+		panic(err)
+	}
+
+	return delegate.Data{
+		Domain:    DomainName,
+		Action:    ActionRedact,
+		RawParams: rawParams,
+	}
+}
+
+// =============================================================================
+
+// ActionAnonymizedParms represents the parameters for the anonymized action.
+// It's called once every domain has redacted the user's personal data.
+type ActionAnonymizedParms struct {
+	UserID   uuid.UUID
+	TenantID uuid.UUID
+}
+
+// String returns a string representation of the action parameters.
+func (act *ActionAnonymizedParms) String() string {
+	return fmt.Sprintf("&EventParamsAnonymized{UserID:%v, TenantID:%v}", act.UserID, act.TenantID)
+}
+
+// Marshal returns the event parameters encoded as JSON.
+func (act *ActionAnonymizedParms) Marshal() ([]byte, error) {
+	return json.Marshal(act)
+}
+
+// ActionAnonymizedData constructs the data for the anonymized action.
+func ActionAnonymizedData(userID uuid.UUID, tenantID uuid.UUID) delegate.Data {
+	params := ActionAnonymizedParms{
+		UserID:   userID,
+		TenantID: tenantID,
+	}
+
+	rawParams, err := params.Marshal()
+	if err != nil {
+		panic(err)
+	}
+
+	return delegate.Data{
+		Domain:    DomainName,
+		Action:    ActionAnonymized,
+		RawParams: rawParams,
+	}
+}
+
+// =============================================================================
+
+// ActionPasswordChangedParms represents the parameters for the password
+// changed action. It's called when users change their own password, domains
+// holding credentials derived from the old password are expected to revoke
+// them.
+type ActionPasswordChangedParms struct {
+	UserID uuid.UUID
+}
+
+// String returns a string representation of the action parameters.
+func (act *ActionPasswordChangedParms) String() string {
+	return fmt.Sprintf("&EventParamsPasswordChanged{UserID:%v}", act.UserID)
+}
+
+// Marshal returns the event parameters encoded as JSON.
+func (act *ActionPasswordChangedParms) Marshal() ([]byte, error) {
+	return json.Marshal(act)
+}
+
+// ActionPasswordChangedData constructs the data for the password changed
+// action.
+func ActionPasswordChangedData(userID uuid.UUID) delegate.Data {
+	params := ActionPasswordChangedParms{
+		UserID: userID,
+	}
+
+	rawParams, err := params.Marshal()
+	if err != nil {
+		panic(err)
+	}
+
+	return delegate.Data{
+		Domain:    DomainName,
+		Action:    ActionPasswordChanged,
+		RawParams: rawParams,
+	}
+}
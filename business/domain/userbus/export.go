@@ -0,0 +1,155 @@
+package userbus
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/ardanlabs/service/business/sdk/buserr"
+	"github.com/ardanlabs/service/business/sdk/classify"
+	"github.com/ardanlabs/service/foundation/clock"
+	"github.com/ardanlabs/service/foundation/ctxval"
+	"github.com/ardanlabs/service/foundation/otel"
+	"github.com/google/uuid"
+)
+
+// ErrNoExport is returned when data is added outside of an export.
+var ErrNoExport = buserr.New(buserr.FailedPrecondition, "no export is being collected")
+
+// ExportData gathers what the system holds about the user into a ZIP
+// archive for a data portability request. The archive has a JSON file for
+// the user and one for each domain that handled the collect action. Fields
+// classified as restricted, like the password hash, are left out.
+func (b *business) ExportData(ctx context.Context, actorID uuid.UUID, userID uuid.UUID) (io.Reader, error) {
+	ctx, span := otel.AddSpan(ctx, "business.userbus.exportdata")
+	defer span.End()
+
+	if err := b.checkActor(ctx, actorID); err != nil {
+		return nil, err
+	}
+
+	usr, err := b.QueryByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	prefs, err := b.GetPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, _ := classify.Filter(usr, "json", classify.Confidential)
+
+	prefValues := make(map[string]json.RawMessage, len(prefs))
+	for _, pref := range prefs {
+		prefValues[pref.Key] = pref.Value
+	}
+	fields["Preferences"] = prefValues
+
+	exp := export{
+		files: make(map[string][]byte),
+	}
+
+	if err := exp.add(DomainName, fields); err != nil {
+		return nil, err
+	}
+
+	// Other domains hold data about the user as well. The handlers of the
+	// collect action add it to the export carried by the context. The
+	// archive would be incomplete if one of them failed, so the export
+	// fails with it.
+	ctx = exportKey.Set(ctx, &exp)
+
+	if err := b.delegate.Invoke(ctx, ActionCollectData(userID)); err != nil {
+		return nil, fmt.Errorf("failed to execute `%s` action: %w", ActionCollect, err)
+	}
+
+	return exp.archive()
+}
+
+// AddExportData adds the data the domain holds about the user to the export
+// being collected. It's meant to be called by the handlers of the collect
+// action, which the export invokes synchronously so the data is added
+// before the archive is written.
+func AddExportData(ctx context.Context, domain string, v any) error {
+	exp, ok := exportKey.Get(ctx)
+	if !ok {
+		return ErrNoExport
+	}
+
+	return exp.add(domain, v)
+}
+
+// =============================================================================
+
+var exportKey = ctxval.NewKey[*export]("export")
+
+// export holds the JSON files of an export keyed by the domain they came
+// from.
+type export struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+func (exp *export) add(domain string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal: domain[%s]: %w", domain, err)
+	}
+
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+
+	if _, exists := exp.files[domain]; exists {
+		return fmt.Errorf("domain[%s] already added to the export", domain)
+	}
+
+	exp.files[domain] = data
+
+	return nil
+}
+
+func (exp *export) archive() (io.Reader, error) {
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+
+	domains := make([]string, 0, len(exp.files))
+	for domain := range exp.files {
+		domains = append(domains, domain)
+	}
+	slices.SortFunc(domains, strings.Compare)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	now := clock.Now()
+
+	for _, domain := range domains {
+		hdr := zip.FileHeader{
+			Name:     domain + ".json",
+			Method:   zip.Deflate,
+			Modified: now,
+		}
+
+		w, err := zw.CreateHeader(&hdr)
+		if err != nil {
+			return nil, fmt.Errorf("create: domain[%s]: %w", domain, err)
+		}
+
+		if _, err := w.Write(exp.files[domain]); err != nil {
+			return nil, fmt.Errorf("write: domain[%s]: %w", domain, err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("close: %w", err)
+	}
+
+	return bytes.NewReader(buf.Bytes()), nil
+}
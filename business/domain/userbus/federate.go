@@ -0,0 +1,141 @@
+package userbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"github.com/ardanlabs/service/business/sdk/tenant"
+	"github.com/ardanlabs/service/business/types/name"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/ardanlabs/service/foundation/clock"
+	"github.com/ardanlabs/service/foundation/otel"
+	"github.com/google/uuid"
+)
+
+// FederateLogin finds the user linked to an identity from an external
+// provider like Google, GitHub or any OIDC issuer. An identity that isn't
+// linked yet is linked to the user with the same email, but only when the
+// provider verified the email so nobody can take over an account by
+// claiming its address. When no user has the email a new one is created
+// with the user role and no password, and true is returned so the caller
+// knows the user was provisioned.
+func (b *business) FederateLogin(ctx context.Context, provider string, externalID string, email mail.Address, profile Profile) (User, bool, error) {
+	ctx, span := otel.AddSpan(ctx, "business.userbus.federatelogin")
+	defer span.End()
+
+	idn, err := b.storer.QueryIdentity(ctx, provider, externalID)
+	switch {
+	case err == nil:
+		usr, err := b.QueryByID(ctx, idn.UserID)
+		if err != nil {
+			return User{}, false, err
+		}
+		return b.recordLogin(ctx, usr), false, nil
+
+	case !errors.Is(err, ErrNotFound):
+		return User{}, false, fmt.Errorf("queryidentity: provider[%s]: %w", provider, err)
+	}
+
+	var provisioned bool
+
+	usr, err := b.storer.QueryByEmail(ctx, email)
+	switch {
+	case err == nil:
+		if !profile.EmailVerified {
+			return User{}, false, fmt.Errorf("provider[%s] email[%s]: %w", provider, email.Address, ErrEmailNotVerified)
+		}
+
+	case errors.Is(err, ErrNotFound):
+		if usr, err = b.provision(ctx, email, profile); err != nil {
+			return User{}, false, err
+		}
+		provisioned = true
+
+	default:
+		return User{}, false, fmt.Errorf("query: email[%s]: %w", email.Address, err)
+	}
+
+	idn = Identity{
+		Provider:    provider,
+		ExternalID:  externalID,
+		UserID:      usr.ID,
+		Email:       email,
+		DateCreated: clock.Now(),
+	}
+
+	if err := b.storer.AddIdentity(ctx, idn); err != nil {
+		return User{}, false, fmt.Errorf("addidentity: provider[%s]: %w", provider, err)
+	}
+
+	return b.recordLogin(ctx, usr), provisioned, nil
+}
+
+// provision creates a user for a federated identity. The user doesn't have
+// a password so they can only log in through a provider until they set one.
+// Nobody asked for the user to be created so it's attributed to the system.
+func (b *business) provision(ctx context.Context, email mail.Address, profile Profile) (User, error) {
+	nme := profile.Name
+	if nme.String() == "" {
+		nme = nameFromEmail(email)
+	}
+
+	now := clock.Now()
+
+	usr := User{
+		ID:          uuid.New(),
+		TenantID:    tenant.OrDefault(ctx),
+		Name:        nme,
+		Email:       email,
+		Roles:       []role.Role{role.User},
+		Enabled:     true,
+		CreatedBy:   ActorSystem,
+		UpdatedBy:   ActorSystem,
+		DateCreated: now,
+		DateUpdated: now,
+		Version:     1,
+	}
+
+	if err := b.checkCreateRules(ctx, ActorSystem, usr); err != nil {
+		return User{}, err
+	}
+
+	if err := b.storer.Create(ctx, usr); err != nil {
+		return User{}, fmt.Errorf("create: %w", err)
+	}
+
+	if err := b.delegate.Call(ctx, ActionCreatedData(usr.ID, usr.TenantID)); err != nil {
+		return User{}, fmt.Errorf("failed to execute `%s` action: %w", ActionCreated, err)
+	}
+
+	return usr, nil
+}
+
+// nameFromEmail derives a name from the local part of the email for
+// providers that don't share the user's name.
+func nameFromEmail(email mail.Address) name.Name {
+	local, _, _ := strings.Cut(email.Address, "@")
+
+	local = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case r == '.' || r == '_' || r == '-':
+			return ' '
+		}
+		return -1
+	}, local)
+
+	if len(local) > 20 {
+		local = local[:20]
+	}
+
+	nme, err := name.Parse(strings.TrimSpace(local))
+	if err != nil {
+		return name.MustParse("New User")
+	}
+
+	return nme
+}
@@ -4,16 +4,29 @@ import (
 	"net/mail"
 	"time"
 
+	"github.com/ardanlabs/service/business/types/department"
 	"github.com/ardanlabs/service/business/types/name"
+	"github.com/ardanlabs/service/business/types/role"
 	"github.com/google/uuid"
 )
 
 // QueryFilter holds the available fields a query can be filtered on.
 // We are using pointer semantics because the With API mutates the value.
+// Search matches users whose name or email contain the words, and the
+// users can be ranked by how well they match. Roles matches users that have
+// any of the roles. NotLoggedInSince matches users whose last login, or
+// creation for users that never logged in, is before the date. TenantID is
+// set by the stores from the tenant the call is made for.
 type QueryFilter struct {
 	ID               *uuid.UUID
+	TenantID         *uuid.UUID
 	Name             *name.Name
 	Email            *mail.Address
+	Search           *string
+	Roles            []role.Role
+	Enabled          *bool
+	Department       *department.Department
 	StartCreatedDate *time.Time
 	EndCreatedDate   *time.Time
+	NotLoggedInSince *time.Time
 }
@@ -0,0 +1,227 @@
+package userbus
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Hasher defines the behavior required to hash and verify passwords.
+type Hasher interface {
+	Hash(password string) ([]byte, error)
+	Compare(hash []byte, password string) error
+	NeedsRehash(hash []byte) bool
+}
+
+// errMismatchedPassword is returned by a hasher when the password doesn't
+// match the hash.
+var errMismatchedPassword = errors.New("hash and password mismatch")
+
+// Set of hashing algorithms supported by NewHasher.
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+// NewHasher constructs the hasher for the specified algorithm. The cost is
+// only used by bcrypt and the params are only used by argon2id.
+func NewHasher(algorithm string, cost int, params Argon2idParams) (Hasher, error) {
+	switch algorithm {
+	case AlgorithmBcrypt:
+		return NewBcryptHasher(cost), nil
+
+	case AlgorithmArgon2id:
+		if params.SaltLength == 0 {
+			params.SaltLength = 16
+		}
+		if params.KeyLength == 0 {
+			params.KeyLength = 32
+		}
+		return NewArgon2idHasher(params), nil
+	}
+
+	return nil, fmt.Errorf("unknown hashing algorithm %q", algorithm)
+}
+
+// =============================================================================
+
+// BcryptHasher hashes passwords with bcrypt at a configured cost.
+type BcryptHasher struct {
+	cost int
+}
+
+// NewBcryptHasher constructs a bcrypt hasher. A cost outside of the range
+// supported by bcrypt results in the bcrypt default cost.
+func NewBcryptHasher(cost int) *BcryptHasher {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		cost = bcrypt.DefaultCost
+	}
+
+	return &BcryptHasher{
+		cost: cost,
+	}
+}
+
+// Hash implements the Hasher interface.
+func (h *BcryptHasher) Hash(password string) ([]byte, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return nil, fmt.Errorf("generatefrompassword: %w", err)
+	}
+
+	return hash, nil
+}
+
+// Compare implements the Hasher interface.
+func (h *BcryptHasher) Compare(hash []byte, password string) error {
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil {
+		return fmt.Errorf("comparehashandpassword: %w", errMismatchedPassword)
+	}
+
+	return nil
+}
+
+// NeedsRehash implements the Hasher interface. A hash that wasn't generated
+// by bcrypt or was generated below the configured cost needs a rehash.
+func (h *BcryptHasher) NeedsRehash(hash []byte) bool {
+	cost, err := bcrypt.Cost(hash)
+	if err != nil {
+		return true
+	}
+
+	return cost < h.cost
+}
+
+// =============================================================================
+
+// Argon2idParams represents the parameters used to hash with argon2id.
+type Argon2idParams struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// Argon2idHasher hashes passwords with argon2id. Hashes are encoded in the
+// PHC string format so the parameters travel with the hash. Existing bcrypt
+// hashes can still be compared so a system can switch over transparently.
+type Argon2idHasher struct {
+	params Argon2idParams
+}
+
+// NewArgon2idHasher constructs an argon2id hasher.
+func NewArgon2idHasher(params Argon2idParams) *Argon2idHasher {
+	return &Argon2idHasher{
+		params: params,
+	}
+}
+
+// Hash implements the Hasher interface.
+func (h *Argon2idHasher) Hash(password string) ([]byte, error) {
+	salt := make([]byte, h.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("salt: %w", err)
+	}
+
+	p := h.params
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+
+	enc := fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key))
+
+	return []byte(enc), nil
+}
+
+// Compare implements the Hasher interface.
+func (h *Argon2idHasher) Compare(hash []byte, password string) error {
+	if isBcrypt(hash) {
+		if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil {
+			return fmt.Errorf("comparehashandpassword: %w", errMismatchedPassword)
+		}
+		return nil
+	}
+
+	p, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return err
+	}
+
+	other := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return errMismatchedPassword
+	}
+
+	return nil
+}
+
+// NeedsRehash implements the Hasher interface. A hash that wasn't generated
+// by argon2id or was generated with different parameters needs a rehash.
+func (h *Argon2idHasher) NeedsRehash(hash []byte) bool {
+	p, salt, _, err := decodeArgon2id(hash)
+	if err != nil {
+		return true
+	}
+
+	return p.Memory != h.params.Memory ||
+		p.Iterations != h.params.Iterations ||
+		p.Parallelism != h.params.Parallelism ||
+		p.KeyLength != h.params.KeyLength ||
+		uint32(len(salt)) != h.params.SaltLength
+}
+
+func decodeArgon2id(hash []byte) (Argon2idParams, []byte, []byte, error) {
+	parts := strings.Split(string(hash), "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return Argon2idParams{}, nil, nil, errors.New("invalid argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return Argon2idParams{}, nil, nil, errors.New("unsupported argon2id version")
+	}
+
+	var p Argon2idParams
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil {
+		return Argon2idParams{}, nil, nil, fmt.Errorf("parse params: %w", err)
+	}
+
+	// argon2 panics on zero iterations or parallelism.
+	if p.Memory == 0 || p.Iterations == 0 || p.Parallelism == 0 {
+		return Argon2idParams{}, nil, nil, errors.New("invalid argon2id params")
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Argon2idParams{}, nil, nil, fmt.Errorf("decode salt: %w", err)
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return Argon2idParams{}, nil, nil, fmt.Errorf("decode key: %w", err)
+	}
+
+	// An empty key would match every password.
+	if len(salt) == 0 || len(key) == 0 {
+		return Argon2idParams{}, nil, nil, errors.New("invalid argon2id salt or key")
+	}
+
+	p.SaltLength = uint32(len(salt))
+	p.KeyLength = uint32(len(key))
+
+	return p, salt, key, nil
+}
+
+func isBcrypt(hash []byte) bool {
+	return bytes.HasPrefix(hash, []byte("$2"))
+}
@@ -0,0 +1,216 @@
+package userbus_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// argon2idParams keeps the tests fast, they aren't meant for production.
+var argon2idParams = userbus.Argon2idParams{
+	Memory:      64,
+	Iterations:  1,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+func Test_HasherRoundTrip(t *testing.T) {
+	const password = "gophers"
+
+	table := []struct {
+		name   string
+		hasher userbus.Hasher
+		prefix string
+	}{
+		{name: "bcrypt", hasher: userbus.NewBcryptHasher(bcrypt.MinCost), prefix: "$2a$"},
+		{name: "argon2id", hasher: userbus.NewArgon2idHasher(argon2idParams), prefix: "$argon2id$v=19$m=64,t=1,p=1$"},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, err := tt.hasher.Hash(password)
+			if err != nil {
+				t.Fatalf("Should be able to hash the password : %s", err)
+			}
+
+			if !bytes.HasPrefix(hash, []byte(tt.prefix)) {
+				t.Fatalf("Should encode the hash with prefix %q : got %s", tt.prefix, hash)
+			}
+
+			if err := tt.hasher.Compare(hash, password); err != nil {
+				t.Fatalf("Should match the password : %s", err)
+			}
+
+			if err := tt.hasher.Compare(hash, password+"x"); err == nil {
+				t.Fatalf("Should not match a different password")
+			}
+
+			if tt.hasher.NeedsRehash(hash) {
+				t.Fatalf("Should not need a rehash for its own hash")
+			}
+
+			other, err := tt.hasher.Hash(password)
+			if err != nil {
+				t.Fatalf("Should be able to hash the password : %s", err)
+			}
+
+			if bytes.Equal(hash, other) {
+				t.Fatalf("Should salt every hash")
+			}
+		})
+	}
+}
+
+func Test_HasherDecode(t *testing.T) {
+	const password = "gophers"
+
+	hash, err := userbus.NewArgon2idHasher(argon2idParams).Hash(password)
+	if err != nil {
+		t.Fatalf("Should be able to hash the password : %s", err)
+	}
+
+	// The params are read back from the PHC string, so a hasher with other
+	// params still matches the hash but asks for a rehash.
+	table := []struct {
+		name   string
+		params userbus.Argon2idParams
+		rehash bool
+	}{
+		{name: "same", params: argon2idParams, rehash: false},
+		{name: "memory", params: with(func(p *userbus.Argon2idParams) { p.Memory = 128 }), rehash: true},
+		{name: "iterations", params: with(func(p *userbus.Argon2idParams) { p.Iterations = 2 }), rehash: true},
+		{name: "parallelism", params: with(func(p *userbus.Argon2idParams) { p.Parallelism = 2 }), rehash: true},
+		{name: "saltlength", params: with(func(p *userbus.Argon2idParams) { p.SaltLength = 32 }), rehash: true},
+		{name: "keylength", params: with(func(p *userbus.Argon2idParams) { p.KeyLength = 64 }), rehash: true},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			h := userbus.NewArgon2idHasher(tt.params)
+
+			if err := h.Compare(hash, password); err != nil {
+				t.Fatalf("Should match the password with the params of the hash : %s", err)
+			}
+
+			if got := h.NeedsRehash(hash); got != tt.rehash {
+				t.Fatalf("Should report a rehash of %t : got %t", tt.rehash, got)
+			}
+		})
+	}
+}
+
+func Test_HasherMalformed(t *testing.T) {
+	const (
+		salt = "c2FsdHNhbHRzYWx0c2FsdA"
+		key  = "a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U"
+	)
+
+	table := []struct {
+		name string
+		hash string
+	}{
+		{name: "empty", hash: ""},
+		{name: "garbage", hash: "not a hash"},
+		{name: "segments", hash: "$argon2id$v=19$m=64,t=1,p=1$" + salt},
+		{name: "algorithm", hash: "$argon2i$v=19$m=64,t=1,p=1$" + salt + "$" + key},
+		{name: "version", hash: "$argon2id$v=16$m=64,t=1,p=1$" + salt + "$" + key},
+		{name: "params", hash: "$argon2id$v=19$m=x,t=1,p=1$" + salt + "$" + key},
+		{name: "zeroiterations", hash: "$argon2id$v=19$m=64,t=0,p=1$" + salt + "$" + key},
+		{name: "zeroparallelism", hash: "$argon2id$v=19$m=64,t=1,p=0$" + salt + "$" + key},
+		{name: "zeromemory", hash: "$argon2id$v=19$m=0,t=1,p=1$" + salt + "$" + key},
+		{name: "salt", hash: "$argon2id$v=19$m=64,t=1,p=1$***$" + key},
+		{name: "key", hash: "$argon2id$v=19$m=64,t=1,p=1$" + salt + "$***"},
+		{name: "emptysalt", hash: "$argon2id$v=19$m=64,t=1,p=1$$" + key},
+		{name: "emptykey", hash: "$argon2id$v=19$m=64,t=1,p=1$" + salt + "$"},
+		{name: "bcrypt", hash: "$2a$04$" + strings.Repeat("x", 10)},
+	}
+
+	hashers := map[string]userbus.Hasher{
+		"bcrypt":   userbus.NewBcryptHasher(bcrypt.MinCost),
+		"argon2id": userbus.NewArgon2idHasher(argon2idParams),
+	}
+
+	for _, tt := range table {
+		for hname, h := range hashers {
+			t.Run(fmt.Sprintf("%s-%s", tt.name, hname), func(t *testing.T) {
+				if err := h.Compare([]byte(tt.hash), ""); err == nil {
+					t.Fatalf("Should not match a malformed hash")
+				}
+
+				if !h.NeedsRehash([]byte(tt.hash)) {
+					t.Fatalf("Should need a rehash for a malformed hash")
+				}
+			})
+		}
+	}
+}
+
+func Test_HasherLegacyBcrypt(t *testing.T) {
+	const password = "gophers"
+
+	legacy, err := userbus.NewBcryptHasher(bcrypt.MinCost).Hash(password)
+	if err != nil {
+		t.Fatalf("Should be able to hash the password : %s", err)
+	}
+
+	// A system switched to argon2id still lets users in with the bcrypt
+	// hash they have and replaces it on their next login.
+	h := userbus.NewArgon2idHasher(argon2idParams)
+
+	if err := h.Compare(legacy, password); err != nil {
+		t.Fatalf("Should match the password of a bcrypt hash : %s", err)
+	}
+
+	if err := h.Compare(legacy, password+"x"); err == nil {
+		t.Fatalf("Should not match a different password of a bcrypt hash")
+	}
+
+	if !h.NeedsRehash(legacy) {
+		t.Fatalf("Should need a rehash for a bcrypt hash")
+	}
+
+	// A bcrypt hasher asks for a rehash when its cost was raised.
+	if !userbus.NewBcryptHasher(bcrypt.MinCost + 1).NeedsRehash(legacy) {
+		t.Fatalf("Should need a rehash for a hash below the cost")
+	}
+
+	if userbus.NewBcryptHasher(bcrypt.MinCost).NeedsRehash(legacy) {
+		t.Fatalf("Should not need a rehash for a hash at the cost")
+	}
+}
+
+func Test_NewHasher(t *testing.T) {
+	h, err := userbus.NewHasher(userbus.AlgorithmArgon2id, 0, userbus.Argon2idParams{Memory: 64, Iterations: 1, Parallelism: 1})
+	if err != nil {
+		t.Fatalf("Should be able to construct an argon2id hasher : %s", err)
+	}
+
+	hash, err := h.Hash("gophers")
+	if err != nil {
+		t.Fatalf("Should be able to hash the password : %s", err)
+	}
+
+	if h.NeedsRehash(hash) {
+		t.Fatalf("Should not need a rehash for its own hash")
+	}
+
+	// The salt and key lengths default to 16 and 32 bytes.
+	if userbus.NewArgon2idHasher(argon2idParams).NeedsRehash(hash) {
+		t.Fatalf("Should default to a 16 byte salt and 32 byte key")
+	}
+
+	if _, err := userbus.NewHasher("md5", 0, userbus.Argon2idParams{}); err == nil {
+		t.Fatalf("Should not construct a hasher for an unknown algorithm")
+	}
+}
+
+func with(fn func(p *userbus.Argon2idParams)) userbus.Argon2idParams {
+	p := argon2idParams
+	fn(&p)
+	return p
+}
@@ -0,0 +1,93 @@
+package userbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ardanlabs/service/business/sdk/jobs"
+	"github.com/ardanlabs/service/foundation/clock"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/otel"
+	"github.com/google/uuid"
+)
+
+// IdempotencyTTL is how long an idempotency key is remembered. A create
+// retried after this returns ErrUniqueEmail like any other create.
+const IdempotencyTTL = 24 * time.Hour
+
+// IdempotencyKey records the user created by a call to Create that carried a
+// key so the call can be retried safely. Keys are scoped to the actor.
+type IdempotencyKey struct {
+	ActorID     uuid.UUID
+	Key         string
+	UserID      uuid.UUID
+	DateCreated time.Time
+}
+
+// replay returns the user created by an earlier call with the same key. It
+// returns ErrNotFound when the key hasn't been used, has expired, or the
+// user it created has since been deleted.
+func (b *business) replay(ctx context.Context, actorID uuid.UUID, nu NewUser) (User, error) {
+	ik, err := b.storer.QueryIdempotencyKey(ctx, actorID, nu.IdempotencyKey)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return User{}, err
+		}
+		return User{}, fmt.Errorf("queryidempotencykey: %w", err)
+	}
+
+	if clock.Now().Sub(ik.DateCreated) > IdempotencyTTL {
+		return User{}, fmt.Errorf("key expired: %w", ErrNotFound)
+	}
+
+	usr, err := b.storer.QueryByID(ctx, ik.UserID)
+	if err != nil {
+		return User{}, fmt.Errorf("querybyid: userID[%s]: %w", ik.UserID, err)
+	}
+
+	// A key sent with a different user is a client bug, replaying the
+	// original user would hide it.
+	if !strings.EqualFold(usr.Email.Address, nu.Email.Address) {
+		return User{}, fmt.Errorf("email[%s]: %w", nu.Email.Address, ErrIdempotencyMismatch)
+	}
+
+	return usr, nil
+}
+
+// PurgeIdempotencyKeys removes the idempotency keys older than the TTL and
+// returns how many were removed.
+func (b *business) PurgeIdempotencyKeys(ctx context.Context) (int, error) {
+	ctx, span := otel.AddSpan(ctx, "business.userbus.purgeidempotencykeys")
+	defer span.End()
+
+	n, err := b.storer.DeleteIdempotencyKeys(ctx, clock.Now().Add(-IdempotencyTTL))
+	if err != nil {
+		return 0, fmt.Errorf("deleteidempotencykeys: %w", err)
+	}
+
+	return n, nil
+}
+
+// PurgeJob constructs the job that removes expired idempotency keys every
+// interval.
+func PurgeJob(log *logger.Logger, bus Business, interval time.Duration) jobs.Job {
+	return jobs.Job{
+		Name:     "userbus.purgeidempotencykeys",
+		Schedule: jobs.Every(interval),
+		Run: func(ctx context.Context) error {
+			n, err := bus.PurgeIdempotencyKeys(ctx)
+			if err != nil {
+				return err
+			}
+
+			if n > 0 {
+				log.Info(ctx, "userbus: purge idempotency keys", "removed", n)
+			}
+
+			return nil
+		},
+	}
+}
@@ -0,0 +1,111 @@
+package userbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ardanlabs/service/business/sdk/jobs"
+	"github.com/ardanlabs/service/foundation/clock"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/otel"
+	"github.com/google/uuid"
+)
+
+// recordLogin sets the date the user last logged in. A failure is logged
+// and doesn't fail the login since the user did authenticate.
+func (b *business) recordLogin(ctx context.Context, usr User) User {
+	now := clock.Now()
+
+	if err := b.storer.UpdateLastLogin(ctx, usr.ID, now); err != nil {
+		b.log.Error(ctx, "userbus: record login", "userID", usr.ID, "ERROR", err)
+		return usr
+	}
+
+	usr.DateLastLogin = now
+
+	return usr
+}
+
+// DisableDormant disables the enabled users that haven't logged in for the
+// specified duration and returns the users it disabled. Users that never
+// logged in are measured from when they were created. A user that fails to
+// be disabled is logged and skipped so one bad row doesn't hold up the rest.
+func (b *business) DisableDormant(ctx context.Context, inactiveFor time.Duration) ([]User, error) {
+	ctx, span := otel.AddSpan(ctx, "business.userbus.disabledormant")
+	defer span.End()
+
+	enabled := true
+	since := clock.Now().Add(-inactiveFor)
+
+	filter := QueryFilter{
+		Enabled:          &enabled,
+		NotLoggedInSince: &since,
+	}
+
+	// The ids are gathered first so the rows aren't rewritten while the
+	// store is still reading them.
+	var userIDs []uuid.UUID
+
+	err := b.storer.QueryAll(ctx, filter, DefaultOrderBy, func(usr User) error {
+		userIDs = append(userIDs, usr.ID)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("queryall: %w", err)
+	}
+
+	disabled := false
+	uu := UpdateUser{
+		Enabled: &disabled,
+	}
+
+	var usrs []User
+
+	for _, userID := range userIDs {
+		if err := ctx.Err(); err != nil {
+			return usrs, err
+		}
+
+		usr, err := b.storer.QueryByID(ctx, userID)
+		if err != nil {
+			b.log.Error(ctx, "userbus: disable dormant", "userID", userID, "ERROR", err)
+			continue
+		}
+
+		updUsr, err := b.Update(ctx, ActorSystem, usr, uu)
+		if err != nil {
+			// A user updated in the meantime is checked again next time.
+			if !errors.Is(err, ErrVersionConflict) {
+				b.log.Error(ctx, "userbus: disable dormant", "userID", userID, "ERROR", err)
+			}
+			continue
+		}
+
+		usrs = append(usrs, updUsr)
+	}
+
+	return usrs, nil
+}
+
+// DisableDormantJob constructs the job that disables the users that haven't
+// logged in for the inactive duration every interval.
+func DisableDormantJob(log *logger.Logger, bus Business, interval time.Duration, inactiveFor time.Duration) jobs.Job {
+	return jobs.Job{
+		Name:     "userbus.disabledormant",
+		Schedule: jobs.Every(interval),
+		Run: func(ctx context.Context) error {
+			usrs, err := bus.DisableDormant(ctx, inactiveFor)
+			if err != nil {
+				return err
+			}
+
+			if len(usrs) > 0 {
+				log.Info(ctx, "userbus: disable dormant", "disabled", len(usrs))
+			}
+
+			return nil
+		},
+	}
+}
@@ -0,0 +1,457 @@
+package mocks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/mail"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/google/uuid"
+)
+
+// Business is a userbus.Business whose behavior is programmed by the
+// function fields.
+type Business struct {
+	recorder
+
+	NewWithTxFunc            func(tx sqldb.CommitRollbacker) (userbus.Business, error)
+	CreateFunc               func(ctx context.Context, actorID uuid.UUID, nu userbus.NewUser) (userbus.User, bool, error)
+	CreateOrGetFunc          func(ctx context.Context, actorID uuid.UUID, nu userbus.NewUser) (userbus.User, bool, error)
+	CreateBatchFunc          func(ctx context.Context, actorID uuid.UUID, nus []userbus.NewUser, mode userbus.BatchMode) ([]userbus.User, []userbus.BatchError)
+	UpdateFunc               func(ctx context.Context, actorID uuid.UUID, usr userbus.User, uu userbus.UpdateUser) (userbus.User, error)
+	DeleteFunc               func(ctx context.Context, actorID uuid.UUID, usr userbus.User) error
+	AnonymizeFunc            func(ctx context.Context, actorID uuid.UUID, userID uuid.UUID) (userbus.User, error)
+	QueryFunc                func(ctx context.Context, filter userbus.QueryFilter, orderBy order.By, page page.Page) ([]userbus.User, error)
+	QueryWithCountFunc       func(ctx context.Context, filter userbus.QueryFilter, orderBy order.By, page page.Page) ([]userbus.User, int, error)
+	QueryAllFunc             func(ctx context.Context, filter userbus.QueryFilter, orderBy order.By, fn func(userbus.User) error) error
+	CountFunc                func(ctx context.Context, filter userbus.QueryFilter) (int, error)
+	CountEstimateFunc        func(ctx context.Context, filter userbus.QueryFilter) (int, bool, error)
+	QueryByIDFunc            func(ctx context.Context, userID uuid.UUID) (userbus.User, error)
+	QueryByIDsFunc           func(ctx context.Context, userIDs []uuid.UUID) ([]userbus.User, error)
+	QueryByEmailFunc         func(ctx context.Context, email mail.Address) (userbus.User, error)
+	AuthenticateFunc         func(ctx context.Context, email mail.Address, password string) (userbus.User, error)
+	AuthenticateWithTOTPFunc func(ctx context.Context, email mail.Address, password string, code string) (userbus.User, error)
+	EnrollTOTPFunc           func(ctx context.Context, userID uuid.UUID) (string, string, error)
+	ConfirmTOTPFunc          func(ctx context.Context, userID uuid.UUID, code string) ([]string, error)
+	DisableTOTPFunc          func(ctx context.Context, userID uuid.UUID) error
+	ChangePasswordFunc       func(ctx context.Context, userID uuid.UUID, currentPassword string, newPassword string) error
+	DirectReportsFunc        func(ctx context.Context, userID uuid.UUID) ([]userbus.User, error)
+	ManagementChainFunc      func(ctx context.Context, userID uuid.UUID) ([]userbus.User, error)
+	FederateLoginFunc        func(ctx context.Context, provider string, externalID string, email mail.Address, profile userbus.Profile) (userbus.User, bool, error)
+	AssignRolesByFilterFunc  func(ctx context.Context, actorID uuid.UUID, filter userbus.QueryFilter, addRoles []role.Role, removeRoles []role.Role) (userbus.RoleAssignment, error)
+	ApplyRoleAssignmentFunc  func(ctx context.Context, actorID uuid.UUID, assignmentID uuid.UUID) (userbus.RoleAssignment, string, error)
+	RevertRoleAssignmentFunc func(ctx context.Context, actorID uuid.UUID, token string) (userbus.RoleAssignment, error)
+	PurgeIdempotencyKeysFunc func(ctx context.Context) (int, error)
+	NormalizeNamesFunc       func(ctx context.Context) (int, error)
+	SetPreferenceFunc        func(ctx context.Context, userID uuid.UUID, key string, value json.RawMessage) (userbus.Preference, error)
+	GetPreferencesFunc       func(ctx context.Context, userID uuid.UUID) ([]userbus.Preference, error)
+	DeletePreferenceFunc     func(ctx context.Context, userID uuid.UUID, key string) error
+	UpdateAvatarFunc         func(ctx context.Context, actorID uuid.UUID, userID uuid.UUID, r io.Reader, contentType string) (userbus.User, error)
+	AvatarURLFunc            func(ctx context.Context, usr userbus.User) (string, error)
+	ExportDataFunc           func(ctx context.Context, actorID uuid.UUID, userID uuid.UUID) (io.Reader, error)
+	DisableDormantFunc       func(ctx context.Context, inactiveFor time.Duration) ([]userbus.User, error)
+}
+
+var _ userbus.Business = (*Business)(nil)
+
+// NewWithTx implements the userbus.Business interface.
+func (m *Business) NewWithTx(tx sqldb.CommitRollbacker) (userbus.Business, error) {
+	m.record("NewWithTx", tx)
+
+	if m.NewWithTxFunc == nil {
+		return m, nil
+	}
+
+	return m.NewWithTxFunc(tx)
+}
+
+// Create implements the userbus.Business interface.
+func (m *Business) Create(ctx context.Context, actorID uuid.UUID, nu userbus.NewUser) (userbus.User, bool, error) {
+	m.record("Create", actorID, nu)
+
+	if m.CreateFunc == nil {
+		return userbus.User{}, false, notExpected("Create")
+	}
+
+	return m.CreateFunc(ctx, actorID, nu)
+}
+
+// CreateOrGet implements the userbus.Business interface.
+func (m *Business) CreateOrGet(ctx context.Context, actorID uuid.UUID, nu userbus.NewUser) (userbus.User, bool, error) {
+	m.record("CreateOrGet", actorID, nu)
+
+	if m.CreateOrGetFunc == nil {
+		return userbus.User{}, false, notExpected("CreateOrGet")
+	}
+
+	return m.CreateOrGetFunc(ctx, actorID, nu)
+}
+
+// CreateBatch implements the userbus.Business interface.
+func (m *Business) CreateBatch(ctx context.Context, actorID uuid.UUID, nus []userbus.NewUser, mode userbus.BatchMode) ([]userbus.User, []userbus.BatchError) {
+	m.record("CreateBatch", actorID, nus, mode)
+
+	if m.CreateBatchFunc == nil {
+		return nil, []userbus.BatchError{{Index: -1, Err: notExpected("CreateBatch")}}
+	}
+
+	return m.CreateBatchFunc(ctx, actorID, nus, mode)
+}
+
+// Update implements the userbus.Business interface.
+func (m *Business) Update(ctx context.Context, actorID uuid.UUID, usr userbus.User, uu userbus.UpdateUser) (userbus.User, error) {
+	m.record("Update", actorID, usr, uu)
+
+	if m.UpdateFunc == nil {
+		return userbus.User{}, notExpected("Update")
+	}
+
+	return m.UpdateFunc(ctx, actorID, usr, uu)
+}
+
+// Delete implements the userbus.Business interface.
+func (m *Business) Delete(ctx context.Context, actorID uuid.UUID, usr userbus.User) error {
+	m.record("Delete", actorID, usr)
+
+	if m.DeleteFunc == nil {
+		return notExpected("Delete")
+	}
+
+	return m.DeleteFunc(ctx, actorID, usr)
+}
+
+// Anonymize implements the userbus.Business interface.
+func (m *Business) Anonymize(ctx context.Context, actorID uuid.UUID, userID uuid.UUID) (userbus.User, error) {
+	m.record("Anonymize", actorID, userID)
+
+	if m.AnonymizeFunc == nil {
+		return userbus.User{}, notExpected("Anonymize")
+	}
+
+	return m.AnonymizeFunc(ctx, actorID, userID)
+}
+
+// Query implements the userbus.Business interface.
+func (m *Business) Query(ctx context.Context, filter userbus.QueryFilter, orderBy order.By, page page.Page) ([]userbus.User, error) {
+	m.record("Query", filter, orderBy, page)
+
+	if m.QueryFunc == nil {
+		return nil, notExpected("Query")
+	}
+
+	return m.QueryFunc(ctx, filter, orderBy, page)
+}
+
+// QueryWithCount implements the userbus.Business interface.
+func (m *Business) QueryWithCount(ctx context.Context, filter userbus.QueryFilter, orderBy order.By, page page.Page) ([]userbus.User, int, error) {
+	m.record("QueryWithCount", filter, orderBy, page)
+
+	if m.QueryWithCountFunc == nil {
+		return nil, 0, notExpected("QueryWithCount")
+	}
+
+	return m.QueryWithCountFunc(ctx, filter, orderBy, page)
+}
+
+// QueryAll implements the userbus.Business interface.
+func (m *Business) QueryAll(ctx context.Context, filter userbus.QueryFilter, orderBy order.By, fn func(userbus.User) error) error {
+	m.record("QueryAll", filter, orderBy, fn)
+
+	if m.QueryAllFunc == nil {
+		return notExpected("QueryAll")
+	}
+
+	return m.QueryAllFunc(ctx, filter, orderBy, fn)
+}
+
+// Count implements the userbus.Business interface.
+func (m *Business) Count(ctx context.Context, filter userbus.QueryFilter) (int, error) {
+	m.record("Count", filter)
+
+	if m.CountFunc == nil {
+		return 0, notExpected("Count")
+	}
+
+	return m.CountFunc(ctx, filter)
+}
+
+// CountEstimate implements the userbus.Business interface.
+func (m *Business) CountEstimate(ctx context.Context, filter userbus.QueryFilter) (int, bool, error) {
+	m.record("CountEstimate", filter)
+
+	if m.CountEstimateFunc == nil {
+		return 0, false, notExpected("CountEstimate")
+	}
+
+	return m.CountEstimateFunc(ctx, filter)
+}
+
+// QueryByID implements the userbus.Business interface.
+func (m *Business) QueryByID(ctx context.Context, userID uuid.UUID) (userbus.User, error) {
+	m.record("QueryByID", userID)
+
+	if m.QueryByIDFunc == nil {
+		return userbus.User{}, notExpected("QueryByID")
+	}
+
+	return m.QueryByIDFunc(ctx, userID)
+}
+
+// QueryByIDs implements the userbus.Business interface.
+func (m *Business) QueryByIDs(ctx context.Context, userIDs []uuid.UUID) ([]userbus.User, error) {
+	m.record("QueryByIDs", userIDs)
+
+	if m.QueryByIDsFunc == nil {
+		return nil, notExpected("QueryByIDs")
+	}
+
+	return m.QueryByIDsFunc(ctx, userIDs)
+}
+
+// QueryByEmail implements the userbus.Business interface.
+func (m *Business) QueryByEmail(ctx context.Context, email mail.Address) (userbus.User, error) {
+	m.record("QueryByEmail", email)
+
+	if m.QueryByEmailFunc == nil {
+		return userbus.User{}, notExpected("QueryByEmail")
+	}
+
+	return m.QueryByEmailFunc(ctx, email)
+}
+
+// Authenticate implements the userbus.Business interface.
+func (m *Business) Authenticate(ctx context.Context, email mail.Address, password string) (userbus.User, error) {
+	m.record("Authenticate", email, password)
+
+	if m.AuthenticateFunc == nil {
+		return userbus.User{}, notExpected("Authenticate")
+	}
+
+	return m.AuthenticateFunc(ctx, email, password)
+}
+
+// AuthenticateWithTOTP implements the userbus.Business interface.
+func (m *Business) AuthenticateWithTOTP(ctx context.Context, email mail.Address, password string, code string) (userbus.User, error) {
+	m.record("AuthenticateWithTOTP", email, password, code)
+
+	if m.AuthenticateWithTOTPFunc == nil {
+		return userbus.User{}, notExpected("AuthenticateWithTOTP")
+	}
+
+	return m.AuthenticateWithTOTPFunc(ctx, email, password, code)
+}
+
+// EnrollTOTP implements the userbus.Business interface.
+func (m *Business) EnrollTOTP(ctx context.Context, userID uuid.UUID) (string, string, error) {
+	m.record("EnrollTOTP", userID)
+
+	if m.EnrollTOTPFunc == nil {
+		return "", "", notExpected("EnrollTOTP")
+	}
+
+	return m.EnrollTOTPFunc(ctx, userID)
+}
+
+// ConfirmTOTP implements the userbus.Business interface.
+func (m *Business) ConfirmTOTP(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	m.record("ConfirmTOTP", userID, code)
+
+	if m.ConfirmTOTPFunc == nil {
+		return nil, notExpected("ConfirmTOTP")
+	}
+
+	return m.ConfirmTOTPFunc(ctx, userID, code)
+}
+
+// DisableTOTP implements the userbus.Business interface.
+func (m *Business) DisableTOTP(ctx context.Context, userID uuid.UUID) error {
+	m.record("DisableTOTP", userID)
+
+	if m.DisableTOTPFunc == nil {
+		return notExpected("DisableTOTP")
+	}
+
+	return m.DisableTOTPFunc(ctx, userID)
+}
+
+// ChangePassword implements the userbus.Business interface.
+func (m *Business) ChangePassword(ctx context.Context, userID uuid.UUID, currentPassword string, newPassword string) error {
+	m.record("ChangePassword", userID, currentPassword, newPassword)
+
+	if m.ChangePasswordFunc == nil {
+		return notExpected("ChangePassword")
+	}
+
+	return m.ChangePasswordFunc(ctx, userID, currentPassword, newPassword)
+}
+
+// DirectReports implements the userbus.Business interface.
+func (m *Business) DirectReports(ctx context.Context, userID uuid.UUID) ([]userbus.User, error) {
+	m.record("DirectReports", userID)
+
+	if m.DirectReportsFunc == nil {
+		return nil, notExpected("DirectReports")
+	}
+
+	return m.DirectReportsFunc(ctx, userID)
+}
+
+// ManagementChain implements the userbus.Business interface.
+func (m *Business) ManagementChain(ctx context.Context, userID uuid.UUID) ([]userbus.User, error) {
+	m.record("ManagementChain", userID)
+
+	if m.ManagementChainFunc == nil {
+		return nil, notExpected("ManagementChain")
+	}
+
+	return m.ManagementChainFunc(ctx, userID)
+}
+
+// FederateLogin implements the userbus.Business interface.
+func (m *Business) FederateLogin(ctx context.Context, provider string, externalID string, email mail.Address, profile userbus.Profile) (userbus.User, bool, error) {
+	m.record("FederateLogin", provider, externalID, email, profile)
+
+	if m.FederateLoginFunc == nil {
+		return userbus.User{}, false, notExpected("FederateLogin")
+	}
+
+	return m.FederateLoginFunc(ctx, provider, externalID, email, profile)
+}
+
+// AssignRolesByFilter implements the userbus.Business interface.
+func (m *Business) AssignRolesByFilter(ctx context.Context, actorID uuid.UUID, filter userbus.QueryFilter, addRoles []role.Role, removeRoles []role.Role) (userbus.RoleAssignment, error) {
+	m.record("AssignRolesByFilter", actorID, filter, addRoles, removeRoles)
+
+	if m.AssignRolesByFilterFunc == nil {
+		return userbus.RoleAssignment{}, notExpected("AssignRolesByFilter")
+	}
+
+	return m.AssignRolesByFilterFunc(ctx, actorID, filter, addRoles, removeRoles)
+}
+
+// ApplyRoleAssignment implements the userbus.Business interface.
+func (m *Business) ApplyRoleAssignment(ctx context.Context, actorID uuid.UUID, assignmentID uuid.UUID) (userbus.RoleAssignment, string, error) {
+	m.record("ApplyRoleAssignment", actorID, assignmentID)
+
+	if m.ApplyRoleAssignmentFunc == nil {
+		return userbus.RoleAssignment{}, "", notExpected("ApplyRoleAssignment")
+	}
+
+	return m.ApplyRoleAssignmentFunc(ctx, actorID, assignmentID)
+}
+
+// RevertRoleAssignment implements the userbus.Business interface.
+func (m *Business) RevertRoleAssignment(ctx context.Context, actorID uuid.UUID, token string) (userbus.RoleAssignment, error) {
+	m.record("RevertRoleAssignment", actorID, token)
+
+	if m.RevertRoleAssignmentFunc == nil {
+		return userbus.RoleAssignment{}, notExpected("RevertRoleAssignment")
+	}
+
+	return m.RevertRoleAssignmentFunc(ctx, actorID, token)
+}
+
+// PurgeIdempotencyKeys implements the userbus.Business interface.
+func (m *Business) PurgeIdempotencyKeys(ctx context.Context) (int, error) {
+	m.record("PurgeIdempotencyKeys")
+
+	if m.PurgeIdempotencyKeysFunc == nil {
+		return 0, notExpected("PurgeIdempotencyKeys")
+	}
+
+	return m.PurgeIdempotencyKeysFunc(ctx)
+}
+
+// NormalizeNames implements the userbus.Business interface.
+func (m *Business) NormalizeNames(ctx context.Context) (int, error) {
+	m.record("NormalizeNames")
+
+	if m.NormalizeNamesFunc == nil {
+		return 0, notExpected("NormalizeNames")
+	}
+
+	return m.NormalizeNamesFunc(ctx)
+}
+
+// SetPreference implements the userbus.Business interface.
+func (m *Business) SetPreference(ctx context.Context, userID uuid.UUID, key string, value json.RawMessage) (userbus.Preference, error) {
+	m.record("SetPreference", userID, key, value)
+
+	if m.SetPreferenceFunc == nil {
+		return userbus.Preference{}, notExpected("SetPreference")
+	}
+
+	return m.SetPreferenceFunc(ctx, userID, key, value)
+}
+
+// GetPreferences implements the userbus.Business interface.
+func (m *Business) GetPreferences(ctx context.Context, userID uuid.UUID) ([]userbus.Preference, error) {
+	m.record("GetPreferences", userID)
+
+	if m.GetPreferencesFunc == nil {
+		return nil, notExpected("GetPreferences")
+	}
+
+	return m.GetPreferencesFunc(ctx, userID)
+}
+
+// DeletePreference implements the userbus.Business interface.
+func (m *Business) DeletePreference(ctx context.Context, userID uuid.UUID, key string) error {
+	m.record("DeletePreference", userID, key)
+
+	if m.DeletePreferenceFunc == nil {
+		return notExpected("DeletePreference")
+	}
+
+	return m.DeletePreferenceFunc(ctx, userID, key)
+}
+
+// UpdateAvatar implements the userbus.Business interface.
+func (m *Business) UpdateAvatar(ctx context.Context, actorID uuid.UUID, userID uuid.UUID, r io.Reader, contentType string) (userbus.User, error) {
+	m.record("UpdateAvatar", actorID, userID, r, contentType)
+
+	if m.UpdateAvatarFunc == nil {
+		return userbus.User{}, notExpected("UpdateAvatar")
+	}
+
+	return m.UpdateAvatarFunc(ctx, actorID, userID, r, contentType)
+}
+
+// AvatarURL implements the userbus.Business interface.
+func (m *Business) AvatarURL(ctx context.Context, usr userbus.User) (string, error) {
+	m.record("AvatarURL", usr)
+
+	if m.AvatarURLFunc == nil {
+		return "", notExpected("AvatarURL")
+	}
+
+	return m.AvatarURLFunc(ctx, usr)
+}
+
+// ExportData implements the userbus.Business interface.
+func (m *Business) ExportData(ctx context.Context, actorID uuid.UUID, userID uuid.UUID) (io.Reader, error) {
+	m.record("ExportData", actorID, userID)
+
+	if m.ExportDataFunc == nil {
+		return nil, notExpected("ExportData")
+	}
+
+	return m.ExportDataFunc(ctx, actorID, userID)
+}
+
+// DisableDormant implements the userbus.Business interface.
+func (m *Business) DisableDormant(ctx context.Context, inactiveFor time.Duration) ([]userbus.User, error) {
+	m.record("DisableDormant", inactiveFor)
+
+	if m.DisableDormantFunc == nil {
+		return nil, notExpected("DisableDormant")
+	}
+
+	return m.DisableDormantFunc(ctx, inactiveFor)
+}
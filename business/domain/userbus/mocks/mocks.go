@@ -0,0 +1,79 @@
+// Package mocks provides implementations of the userbus Business and Storer
+// interfaces for tests that don't need a database. The behavior of a method
+// is programmed by setting its function field. Calling a method whose field
+// isn't set fails with ErrNotExpected, except for NewWithTx which returns
+// the mock itself. Every call is recorded so tests can check what was called.
+//
+//	usrBus := &mocks.Business{
+//		QueryByIDFunc: func(ctx context.Context, userID uuid.UUID) (userbus.User, error) {
+//			return usr, nil
+//		},
+//	}
+package mocks
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrNotExpected is returned by a method that was called without its
+// behavior being programmed.
+var ErrNotExpected = errors.New("call not expected")
+
+// Call represents a call made to a mock. The context isn't recorded.
+type Call struct {
+	Method string
+	Args   []any
+}
+
+// recorder records the calls made to a mock. It's safe for concurrent use.
+type recorder struct {
+	mu    sync.Mutex
+	calls []Call
+}
+
+func (r *recorder) record(method string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = append(r.calls, Call{Method: method, Args: args})
+}
+
+// Calls returns the calls made to the mock in the order they were made.
+func (r *recorder) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	calls := make([]Call, len(r.calls))
+	copy(calls, r.calls)
+
+	return calls
+}
+
+// CallCount returns the number of times the method was called.
+func (r *recorder) CallCount(method string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var n int
+	for _, c := range r.calls {
+		if c.Method == method {
+			n++
+		}
+	}
+
+	return n
+}
+
+// Reset forgets the calls made to the mock.
+func (r *recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = nil
+}
+
+func notExpected(method string) error {
+	return fmt.Errorf("%s: %w", method, ErrNotExpected)
+}
@@ -0,0 +1,86 @@
+package mocks_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/domain/userbus/mocks"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/google/uuid"
+)
+
+func Test_Storer(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.New(&buf, logger.LevelInfo, "TEST", func(context.Context) string { return "" })
+
+	exp := userbus.User{
+		ID:      uuid.New(),
+		Enabled: true,
+	}
+
+	storer := mocks.Storer{
+		QueryByIDFunc: func(ctx context.Context, userID uuid.UUID) (userbus.User, error) {
+			if userID != exp.ID {
+				return userbus.User{}, userbus.ErrNotFound
+			}
+			return exp, nil
+		},
+	}
+
+	userBus := userbus.NewBusiness(log, nil, &storer, userbus.PasswordPolicy{}, userbus.NewBcryptHasher(0), nil)
+
+	ctx := context.Background()
+
+	usr, err := userBus.QueryByID(ctx, exp.ID)
+	if err != nil {
+		t.Fatalf("Should be able to query the programmed user: %s", err)
+	}
+
+	if usr.ID != exp.ID {
+		t.Fatalf("Should get the programmed user: got %s, exp %s", usr.ID, exp.ID)
+	}
+
+	if _, err := userBus.QueryByID(ctx, uuid.New()); !errors.Is(err, userbus.ErrNotFound) {
+		t.Fatalf("Should get the programmed error: %v", err)
+	}
+
+	if _, err := userBus.DirectReports(ctx, exp.ID); !errors.Is(err, mocks.ErrNotExpected) {
+		t.Fatalf("Should fail a call that isn't programmed: %v", err)
+	}
+
+	if n := storer.CallCount("QueryByID"); n != 2 {
+		t.Fatalf("Should record the calls: got %d, exp 2", n)
+	}
+
+	calls := storer.Calls()
+	if len(calls) != 3 || calls[2].Method != "DirectReports" || calls[2].Args[0] != exp.ID {
+		t.Fatalf("Should record the calls in order with their arguments: %+v", calls)
+	}
+
+	storer.Reset()
+
+	if n := len(storer.Calls()); n != 0 {
+		t.Fatalf("Should forget the calls: %d", n)
+	}
+}
+
+func Test_Business(t *testing.T) {
+	var userBus userbus.Business = &mocks.Business{}
+
+	txBus, err := userBus.NewWithTx(nil)
+	if err != nil {
+		t.Fatalf("Should be able to start a transaction: %s", err)
+	}
+
+	if txBus != userBus {
+		t.Fatal("Should use the mock inside the transaction")
+	}
+
+	_, bes := userBus.CreateBatch(context.Background(), uuid.New(), nil, userbus.BatchAtomic)
+	if len(bes) != 1 || bes[0].Index != -1 || !errors.Is(bes[0].Err, mocks.ErrNotExpected) {
+		t.Fatalf("Should fail a batch that isn't programmed: %+v", bes)
+	}
+}
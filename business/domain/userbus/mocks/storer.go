@@ -0,0 +1,478 @@
+package mocks
+
+import (
+	"context"
+	"net/mail"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/google/uuid"
+)
+
+// Storer is a userbus.Storer whose behavior is programmed by the function
+// fields.
+type Storer struct {
+	recorder
+
+	NewWithTxFunc                       func(tx sqldb.CommitRollbacker) (userbus.Storer, error)
+	CreateFunc                          func(ctx context.Context, usr userbus.User) error
+	CreateManyFunc                      func(ctx context.Context, usrs []userbus.User) ([]uuid.UUID, error)
+	UpdateFunc                          func(ctx context.Context, usr userbus.User) error
+	DeleteFunc                          func(ctx context.Context, usr userbus.User) error
+	QueryFunc                           func(ctx context.Context, filter userbus.QueryFilter, orderBy order.By, page page.Page) ([]userbus.User, error)
+	QueryWithCountFunc                  func(ctx context.Context, filter userbus.QueryFilter, orderBy order.By, page page.Page) ([]userbus.User, int, error)
+	QueryAllFunc                        func(ctx context.Context, filter userbus.QueryFilter, orderBy order.By, fn func(userbus.User) error) error
+	CountFunc                           func(ctx context.Context, filter userbus.QueryFilter) (int, error)
+	CountEstimateFunc                   func(ctx context.Context, filter userbus.QueryFilter) (int, bool, error)
+	QueryByIDFunc                       func(ctx context.Context, userID uuid.UUID) (userbus.User, error)
+	QueryByIDsFunc                      func(ctx context.Context, userIDs []uuid.UUID) ([]userbus.User, error)
+	QueryByEmailFunc                    func(ctx context.Context, email mail.Address) (userbus.User, error)
+	QueryByEmailsFunc                   func(ctx context.Context, emails []mail.Address) ([]userbus.User, error)
+	QueryPasswordHistoryFunc            func(ctx context.Context, userID uuid.UUID, limit int) ([][]byte, error)
+	AddPasswordHistoryFunc              func(ctx context.Context, userID uuid.UUID, passwordHash []byte, dateCreated time.Time) error
+	AddPasswordHistoriesFunc            func(ctx context.Context, usrs []userbus.User) error
+	AddRecoveryCodesFunc                func(ctx context.Context, userID uuid.UUID, codeHashes []string, dateCreated time.Time) error
+	DeleteRecoveryCodesFunc             func(ctx context.Context, userID uuid.UUID) error
+	UseRecoveryCodeFunc                 func(ctx context.Context, userID uuid.UUID, codeHash string) error
+	DirectReportsFunc                   func(ctx context.Context, userID uuid.UUID) ([]userbus.User, error)
+	ManagementChainFunc                 func(ctx context.Context, userID uuid.UUID) ([]userbus.User, error)
+	AddIdentityFunc                     func(ctx context.Context, idn userbus.Identity) error
+	QueryIdentityFunc                   func(ctx context.Context, provider string, externalID string) (userbus.Identity, error)
+	CreateRoleAssignmentFunc            func(ctx context.Context, ra userbus.RoleAssignment, chgs []userbus.RoleChange) error
+	UpdateRoleAssignmentFunc            func(ctx context.Context, ra userbus.RoleAssignment) error
+	QueryRoleAssignmentFunc             func(ctx context.Context, assignmentID uuid.UUID) (userbus.RoleAssignment, error)
+	QueryRoleAssignmentByRevertHashFunc func(ctx context.Context, revertHash string) (userbus.RoleAssignment, error)
+	QueryRoleChangesFunc                func(ctx context.Context, assignmentID uuid.UUID) ([]userbus.RoleChange, error)
+	AddIdempotencyKeyFunc               func(ctx context.Context, ik userbus.IdempotencyKey) error
+	QueryIdempotencyKeyFunc             func(ctx context.Context, actorID uuid.UUID, key string) (userbus.IdempotencyKey, error)
+	DeleteIdempotencyKeysFunc           func(ctx context.Context, before time.Time) (int, error)
+	QueryNamesFunc                      func(ctx context.Context, fn func(userbus.StoredName) error) error
+	SetPreferenceFunc                   func(ctx context.Context, pref userbus.Preference) error
+	QueryPreferencesFunc                func(ctx context.Context, userID uuid.UUID) ([]userbus.Preference, error)
+	DeletePreferenceFunc                func(ctx context.Context, userID uuid.UUID, key string) error
+	DeletePersonalDataFunc              func(ctx context.Context, userID uuid.UUID) error
+	UpdateLastLoginFunc                 func(ctx context.Context, userID uuid.UUID, dateLastLogin time.Time) error
+}
+
+var _ userbus.Storer = (*Storer)(nil)
+
+// NewWithTx implements the userbus.Storer interface.
+func (m *Storer) NewWithTx(tx sqldb.CommitRollbacker) (userbus.Storer, error) {
+	m.record("NewWithTx", tx)
+
+	if m.NewWithTxFunc == nil {
+		return m, nil
+	}
+
+	return m.NewWithTxFunc(tx)
+}
+
+// Create implements the userbus.Storer interface.
+func (m *Storer) Create(ctx context.Context, usr userbus.User) error {
+	m.record("Create", usr)
+
+	if m.CreateFunc == nil {
+		return notExpected("Create")
+	}
+
+	return m.CreateFunc(ctx, usr)
+}
+
+// CreateMany implements the userbus.Storer interface.
+func (m *Storer) CreateMany(ctx context.Context, usrs []userbus.User) ([]uuid.UUID, error) {
+	m.record("CreateMany", usrs)
+
+	if m.CreateManyFunc == nil {
+		return nil, notExpected("CreateMany")
+	}
+
+	return m.CreateManyFunc(ctx, usrs)
+}
+
+// Update implements the userbus.Storer interface.
+func (m *Storer) Update(ctx context.Context, usr userbus.User) error {
+	m.record("Update", usr)
+
+	if m.UpdateFunc == nil {
+		return notExpected("Update")
+	}
+
+	return m.UpdateFunc(ctx, usr)
+}
+
+// Delete implements the userbus.Storer interface.
+func (m *Storer) Delete(ctx context.Context, usr userbus.User) error {
+	m.record("Delete", usr)
+
+	if m.DeleteFunc == nil {
+		return notExpected("Delete")
+	}
+
+	return m.DeleteFunc(ctx, usr)
+}
+
+// Query implements the userbus.Storer interface.
+func (m *Storer) Query(ctx context.Context, filter userbus.QueryFilter, orderBy order.By, page page.Page) ([]userbus.User, error) {
+	m.record("Query", filter, orderBy, page)
+
+	if m.QueryFunc == nil {
+		return nil, notExpected("Query")
+	}
+
+	return m.QueryFunc(ctx, filter, orderBy, page)
+}
+
+// QueryWithCount implements the userbus.Storer interface.
+func (m *Storer) QueryWithCount(ctx context.Context, filter userbus.QueryFilter, orderBy order.By, page page.Page) ([]userbus.User, int, error) {
+	m.record("QueryWithCount", filter, orderBy, page)
+
+	if m.QueryWithCountFunc == nil {
+		return nil, 0, notExpected("QueryWithCount")
+	}
+
+	return m.QueryWithCountFunc(ctx, filter, orderBy, page)
+}
+
+// QueryAll implements the userbus.Storer interface.
+func (m *Storer) QueryAll(ctx context.Context, filter userbus.QueryFilter, orderBy order.By, fn func(userbus.User) error) error {
+	m.record("QueryAll", filter, orderBy, fn)
+
+	if m.QueryAllFunc == nil {
+		return notExpected("QueryAll")
+	}
+
+	return m.QueryAllFunc(ctx, filter, orderBy, fn)
+}
+
+// Count implements the userbus.Storer interface.
+func (m *Storer) Count(ctx context.Context, filter userbus.QueryFilter) (int, error) {
+	m.record("Count", filter)
+
+	if m.CountFunc == nil {
+		return 0, notExpected("Count")
+	}
+
+	return m.CountFunc(ctx, filter)
+}
+
+// CountEstimate implements the userbus.Storer interface.
+func (m *Storer) CountEstimate(ctx context.Context, filter userbus.QueryFilter) (int, bool, error) {
+	m.record("CountEstimate", filter)
+
+	if m.CountEstimateFunc == nil {
+		return 0, false, notExpected("CountEstimate")
+	}
+
+	return m.CountEstimateFunc(ctx, filter)
+}
+
+// QueryByID implements the userbus.Storer interface.
+func (m *Storer) QueryByID(ctx context.Context, userID uuid.UUID) (userbus.User, error) {
+	m.record("QueryByID", userID)
+
+	if m.QueryByIDFunc == nil {
+		return userbus.User{}, notExpected("QueryByID")
+	}
+
+	return m.QueryByIDFunc(ctx, userID)
+}
+
+// QueryByIDs implements the userbus.Storer interface.
+func (m *Storer) QueryByIDs(ctx context.Context, userIDs []uuid.UUID) ([]userbus.User, error) {
+	m.record("QueryByIDs", userIDs)
+
+	if m.QueryByIDsFunc == nil {
+		return nil, notExpected("QueryByIDs")
+	}
+
+	return m.QueryByIDsFunc(ctx, userIDs)
+}
+
+// QueryByEmail implements the userbus.Storer interface.
+func (m *Storer) QueryByEmail(ctx context.Context, email mail.Address) (userbus.User, error) {
+	m.record("QueryByEmail", email)
+
+	if m.QueryByEmailFunc == nil {
+		return userbus.User{}, notExpected("QueryByEmail")
+	}
+
+	return m.QueryByEmailFunc(ctx, email)
+}
+
+// QueryByEmails implements the userbus.Storer interface.
+func (m *Storer) QueryByEmails(ctx context.Context, emails []mail.Address) ([]userbus.User, error) {
+	m.record("QueryByEmails", emails)
+
+	if m.QueryByEmailsFunc == nil {
+		return nil, notExpected("QueryByEmails")
+	}
+
+	return m.QueryByEmailsFunc(ctx, emails)
+}
+
+// QueryPasswordHistory implements the userbus.Storer interface.
+func (m *Storer) QueryPasswordHistory(ctx context.Context, userID uuid.UUID, limit int) ([][]byte, error) {
+	m.record("QueryPasswordHistory", userID, limit)
+
+	if m.QueryPasswordHistoryFunc == nil {
+		return nil, notExpected("QueryPasswordHistory")
+	}
+
+	return m.QueryPasswordHistoryFunc(ctx, userID, limit)
+}
+
+// AddPasswordHistory implements the userbus.Storer interface.
+func (m *Storer) AddPasswordHistory(ctx context.Context, userID uuid.UUID, passwordHash []byte, dateCreated time.Time) error {
+	m.record("AddPasswordHistory", userID, passwordHash, dateCreated)
+
+	if m.AddPasswordHistoryFunc == nil {
+		return notExpected("AddPasswordHistory")
+	}
+
+	return m.AddPasswordHistoryFunc(ctx, userID, passwordHash, dateCreated)
+}
+
+// AddPasswordHistories implements the userbus.Storer interface.
+func (m *Storer) AddPasswordHistories(ctx context.Context, usrs []userbus.User) error {
+	m.record("AddPasswordHistories", usrs)
+
+	if m.AddPasswordHistoriesFunc == nil {
+		return notExpected("AddPasswordHistories")
+	}
+
+	return m.AddPasswordHistoriesFunc(ctx, usrs)
+}
+
+// AddRecoveryCodes implements the userbus.Storer interface.
+func (m *Storer) AddRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes []string, dateCreated time.Time) error {
+	m.record("AddRecoveryCodes", userID, codeHashes, dateCreated)
+
+	if m.AddRecoveryCodesFunc == nil {
+		return notExpected("AddRecoveryCodes")
+	}
+
+	return m.AddRecoveryCodesFunc(ctx, userID, codeHashes, dateCreated)
+}
+
+// DeleteRecoveryCodes implements the userbus.Storer interface.
+func (m *Storer) DeleteRecoveryCodes(ctx context.Context, userID uuid.UUID) error {
+	m.record("DeleteRecoveryCodes", userID)
+
+	if m.DeleteRecoveryCodesFunc == nil {
+		return notExpected("DeleteRecoveryCodes")
+	}
+
+	return m.DeleteRecoveryCodesFunc(ctx, userID)
+}
+
+// UseRecoveryCode implements the userbus.Storer interface.
+func (m *Storer) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) error {
+	m.record("UseRecoveryCode", userID, codeHash)
+
+	if m.UseRecoveryCodeFunc == nil {
+		return notExpected("UseRecoveryCode")
+	}
+
+	return m.UseRecoveryCodeFunc(ctx, userID, codeHash)
+}
+
+// DirectReports implements the userbus.Storer interface.
+func (m *Storer) DirectReports(ctx context.Context, userID uuid.UUID) ([]userbus.User, error) {
+	m.record("DirectReports", userID)
+
+	if m.DirectReportsFunc == nil {
+		return nil, notExpected("DirectReports")
+	}
+
+	return m.DirectReportsFunc(ctx, userID)
+}
+
+// ManagementChain implements the userbus.Storer interface.
+func (m *Storer) ManagementChain(ctx context.Context, userID uuid.UUID) ([]userbus.User, error) {
+	m.record("ManagementChain", userID)
+
+	if m.ManagementChainFunc == nil {
+		return nil, notExpected("ManagementChain")
+	}
+
+	return m.ManagementChainFunc(ctx, userID)
+}
+
+// AddIdentity implements the userbus.Storer interface.
+func (m *Storer) AddIdentity(ctx context.Context, idn userbus.Identity) error {
+	m.record("AddIdentity", idn)
+
+	if m.AddIdentityFunc == nil {
+		return notExpected("AddIdentity")
+	}
+
+	return m.AddIdentityFunc(ctx, idn)
+}
+
+// QueryIdentity implements the userbus.Storer interface.
+func (m *Storer) QueryIdentity(ctx context.Context, provider string, externalID string) (userbus.Identity, error) {
+	m.record("QueryIdentity", provider, externalID)
+
+	if m.QueryIdentityFunc == nil {
+		return userbus.Identity{}, notExpected("QueryIdentity")
+	}
+
+	return m.QueryIdentityFunc(ctx, provider, externalID)
+}
+
+// CreateRoleAssignment implements the userbus.Storer interface.
+func (m *Storer) CreateRoleAssignment(ctx context.Context, ra userbus.RoleAssignment, chgs []userbus.RoleChange) error {
+	m.record("CreateRoleAssignment", ra, chgs)
+
+	if m.CreateRoleAssignmentFunc == nil {
+		return notExpected("CreateRoleAssignment")
+	}
+
+	return m.CreateRoleAssignmentFunc(ctx, ra, chgs)
+}
+
+// UpdateRoleAssignment implements the userbus.Storer interface.
+func (m *Storer) UpdateRoleAssignment(ctx context.Context, ra userbus.RoleAssignment) error {
+	m.record("UpdateRoleAssignment", ra)
+
+	if m.UpdateRoleAssignmentFunc == nil {
+		return notExpected("UpdateRoleAssignment")
+	}
+
+	return m.UpdateRoleAssignmentFunc(ctx, ra)
+}
+
+// QueryRoleAssignment implements the userbus.Storer interface.
+func (m *Storer) QueryRoleAssignment(ctx context.Context, assignmentID uuid.UUID) (userbus.RoleAssignment, error) {
+	m.record("QueryRoleAssignment", assignmentID)
+
+	if m.QueryRoleAssignmentFunc == nil {
+		return userbus.RoleAssignment{}, notExpected("QueryRoleAssignment")
+	}
+
+	return m.QueryRoleAssignmentFunc(ctx, assignmentID)
+}
+
+// QueryRoleAssignmentByRevertHash implements the userbus.Storer interface.
+func (m *Storer) QueryRoleAssignmentByRevertHash(ctx context.Context, revertHash string) (userbus.RoleAssignment, error) {
+	m.record("QueryRoleAssignmentByRevertHash", revertHash)
+
+	if m.QueryRoleAssignmentByRevertHashFunc == nil {
+		return userbus.RoleAssignment{}, notExpected("QueryRoleAssignmentByRevertHash")
+	}
+
+	return m.QueryRoleAssignmentByRevertHashFunc(ctx, revertHash)
+}
+
+// QueryRoleChanges implements the userbus.Storer interface.
+func (m *Storer) QueryRoleChanges(ctx context.Context, assignmentID uuid.UUID) ([]userbus.RoleChange, error) {
+	m.record("QueryRoleChanges", assignmentID)
+
+	if m.QueryRoleChangesFunc == nil {
+		return nil, notExpected("QueryRoleChanges")
+	}
+
+	return m.QueryRoleChangesFunc(ctx, assignmentID)
+}
+
+// AddIdempotencyKey implements the userbus.Storer interface.
+func (m *Storer) AddIdempotencyKey(ctx context.Context, ik userbus.IdempotencyKey) error {
+	m.record("AddIdempotencyKey", ik)
+
+	if m.AddIdempotencyKeyFunc == nil {
+		return notExpected("AddIdempotencyKey")
+	}
+
+	return m.AddIdempotencyKeyFunc(ctx, ik)
+}
+
+// QueryIdempotencyKey implements the userbus.Storer interface.
+func (m *Storer) QueryIdempotencyKey(ctx context.Context, actorID uuid.UUID, key string) (userbus.IdempotencyKey, error) {
+	m.record("QueryIdempotencyKey", actorID, key)
+
+	if m.QueryIdempotencyKeyFunc == nil {
+		return userbus.IdempotencyKey{}, notExpected("QueryIdempotencyKey")
+	}
+
+	return m.QueryIdempotencyKeyFunc(ctx, actorID, key)
+}
+
+// DeleteIdempotencyKeys implements the userbus.Storer interface.
+func (m *Storer) DeleteIdempotencyKeys(ctx context.Context, before time.Time) (int, error) {
+	m.record("DeleteIdempotencyKeys", before)
+
+	if m.DeleteIdempotencyKeysFunc == nil {
+		return 0, notExpected("DeleteIdempotencyKeys")
+	}
+
+	return m.DeleteIdempotencyKeysFunc(ctx, before)
+}
+
+// QueryNames implements the userbus.Storer interface.
+func (m *Storer) QueryNames(ctx context.Context, fn func(userbus.StoredName) error) error {
+	m.record("QueryNames", fn)
+
+	if m.QueryNamesFunc == nil {
+		return notExpected("QueryNames")
+	}
+
+	return m.QueryNamesFunc(ctx, fn)
+}
+
+// SetPreference implements the userbus.Storer interface.
+func (m *Storer) SetPreference(ctx context.Context, pref userbus.Preference) error {
+	m.record("SetPreference", pref)
+
+	if m.SetPreferenceFunc == nil {
+		return notExpected("SetPreference")
+	}
+
+	return m.SetPreferenceFunc(ctx, pref)
+}
+
+// QueryPreferences implements the userbus.Storer interface.
+func (m *Storer) QueryPreferences(ctx context.Context, userID uuid.UUID) ([]userbus.Preference, error) {
+	m.record("QueryPreferences", userID)
+
+	if m.QueryPreferencesFunc == nil {
+		return nil, notExpected("QueryPreferences")
+	}
+
+	return m.QueryPreferencesFunc(ctx, userID)
+}
+
+// DeletePreference implements the userbus.Storer interface.
+func (m *Storer) DeletePreference(ctx context.Context, userID uuid.UUID, key string) error {
+	m.record("DeletePreference", userID, key)
+
+	if m.DeletePreferenceFunc == nil {
+		return notExpected("DeletePreference")
+	}
+
+	return m.DeletePreferenceFunc(ctx, userID, key)
+}
+
+// DeletePersonalData implements the userbus.Storer interface.
+func (m *Storer) DeletePersonalData(ctx context.Context, userID uuid.UUID) error {
+	m.record("DeletePersonalData", userID)
+
+	if m.DeletePersonalDataFunc == nil {
+		return notExpected("DeletePersonalData")
+	}
+
+	return m.DeletePersonalDataFunc(ctx, userID)
+}
+
+// UpdateLastLogin implements the userbus.Storer interface.
+func (m *Storer) UpdateLastLogin(ctx context.Context, userID uuid.UUID, dateLastLogin time.Time) error {
+	m.record("UpdateLastLogin", userID, dateLastLogin)
+
+	if m.UpdateLastLoginFunc == nil {
+		return notExpected("UpdateLastLogin")
+	}
+
+	return m.UpdateLastLoginFunc(ctx, userID, dateLastLogin)
+}
@@ -4,22 +4,52 @@ import (
 	"net/mail"
 	"time"
 
+	"github.com/ardanlabs/service/business/types/department"
 	"github.com/ardanlabs/service/business/types/name"
 	"github.com/ardanlabs/service/business/types/role"
 	"github.com/google/uuid"
 )
 
-// User represents information about an individual user.
+// User represents information about an individual user. The class tags
+// classify the fields so they can be kept inside the service boundary. A
+// user belongs to the tenant it was created for and is only seen by calls
+// made for that tenant.
 type User struct {
-	ID           uuid.UUID
-	Name         name.Name
-	Email        mail.Address
-	Roles        []role.Role
-	PasswordHash []byte
-	Department   name.Null
-	Enabled      bool
-	DateCreated  time.Time
-	DateUpdated  time.Time
+	ID            uuid.UUID
+	TenantID      uuid.UUID       `class:"internal"`
+	Name          name.Name       `class:"confidential"`
+	Email         mail.Address    `class:"confidential"`
+	Roles         []role.Role     `class:"internal"`
+	PasswordHash  []byte          `class:"restricted"`
+	Department    department.Null `class:"internal"`
+	ManagerID     uuid.NullUUID   `class:"internal"`
+	Enabled       bool
+	TOTPSecret    string `class:"restricted"`
+	TOTPEnabled   bool
+	AvatarKey     string
+	CreatedBy     uuid.UUID `class:"internal"`
+	UpdatedBy     uuid.UUID `class:"internal"`
+	DateCreated   time.Time
+	DateUpdated   time.Time
+	DateLastLogin time.Time
+	Version       int
+}
+
+// Identity links a user to an account with an external identity provider.
+type Identity struct {
+	Provider    string
+	ExternalID  string
+	UserID      uuid.UUID
+	Email       mail.Address `class:"confidential"`
+	DateCreated time.Time
+}
+
+// Profile contains what an identity provider shares about the user. The
+// name is used when a user is created for the identity, and a verified
+// email allows the identity to be linked to an existing user.
+type Profile struct {
+	Name          name.Name
+	EmailVerified bool
 }
 
 // NewUser contains information needed to create a new user.
@@ -27,8 +57,13 @@ type NewUser struct {
 	Name       name.Name
 	Email      mail.Address
 	Roles      []role.Role
-	Department name.Null
+	Department department.Null
+	ManagerID  uuid.NullUUID
 	Password   string
+
+	// IdempotencyKey is optional. Create calls from the same actor with the
+	// same key create the user once, see IdempotencyTTL.
+	IdempotencyKey string
 }
 
 // UpdateUser contains information needed to update a user.
@@ -36,7 +71,47 @@ type UpdateUser struct {
 	Name       *name.Name
 	Email      *mail.Address
 	Roles      []role.Role
-	Department *name.Null
+	Department *department.Null
+	ManagerID  *uuid.NullUUID
 	Password   *string
 	Enabled    *bool
+	Version    *int
+}
+
+// RoleAssignment represents a change of roles for the set of users that
+// matched a filter. An assignment is previewed before it can be applied, and
+// once applied it can be reverted for a while with its revert token.
+// Changed holds the changes made by the apply or revert that returned the
+// assignment, from the roles the users had to the roles they got, and isn't
+// stored.
+type RoleAssignment struct {
+	ID           uuid.UUID
+	ActorID      uuid.UUID
+	AddRoles     []role.Role
+	RemoveRoles  []role.Role
+	Affected     int
+	Sample       []RoleChange
+	Changed      []RoleChange
+	RevertHash   string `class:"restricted"`
+	DateCreated  time.Time
+	DateApplied  time.Time
+	DateReverted time.Time
+}
+
+// Applied reports whether the assignment has been applied.
+func (ra RoleAssignment) Applied() bool {
+	return !ra.DateApplied.IsZero()
+}
+
+// Reverted reports whether the assignment has been reverted.
+func (ra RoleAssignment) Reverted() bool {
+	return !ra.DateReverted.IsZero()
+}
+
+// RoleChange represents the change of roles for a single user in a role
+// assignment.
+type RoleChange struct {
+	UserID   uuid.UUID
+	OldRoles []role.Role `class:"internal"`
+	NewRoles []role.Role `class:"internal"`
 }
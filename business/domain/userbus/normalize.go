@@ -0,0 +1,73 @@
+package userbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ardanlabs/service/business/types/department"
+	"github.com/ardanlabs/service/business/types/name"
+	"github.com/ardanlabs/service/foundation/otel"
+	"github.com/google/uuid"
+)
+
+// StoredName is a user's name and department the way they are stored, which
+// for users saved before names were normalized may not be normalized.
+type StoredName struct {
+	UserID     uuid.UUID
+	Name       string
+	Department string
+}
+
+// NormalizeNames rewrites the users whose stored name or department isn't
+// normalized and returns how many were rewritten. It's meant to run in the
+// background once after an upgrade. A user that fails to be rewritten is
+// logged and skipped so one bad row doesn't hold up the rest.
+func (b *business) NormalizeNames(ctx context.Context) (int, error) {
+	ctx, span := otel.AddSpan(ctx, "business.userbus.normalizenames")
+	defer span.End()
+
+	// The ids are gathered first so the rows aren't rewritten while the
+	// store is still reading them.
+	var userIDs []uuid.UUID
+
+	err := b.storer.QueryNames(ctx, func(sn StoredName) error {
+		if name.Normalize(sn.Name) != sn.Name || department.Normalize(sn.Department) != sn.Department {
+			userIDs = append(userIDs, sn.UserID)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("querynames: %w", err)
+	}
+
+	var n int
+
+	for _, userID := range userIDs {
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+
+		// Reading the user normalizes the name, writing it back stores the
+		// normalized form.
+		usr, err := b.storer.QueryByID(ctx, userID)
+		if err != nil {
+			b.log.Error(ctx, "userbus: normalize names", "userID", userID, "ERROR", err)
+			continue
+		}
+
+		usr.Version++
+
+		if err := b.storer.Update(ctx, usr); err != nil {
+			// A user updated in the meantime was saved normalized.
+			if !errors.Is(err, ErrVersionConflict) {
+				b.log.Error(ctx, "userbus: normalize names", "userID", userID, "ERROR", err)
+			}
+			continue
+		}
+
+		n++
+	}
+
+	return n, nil
+}
@@ -1,6 +1,13 @@
 package userbus
 
-import "github.com/ardanlabs/service/business/sdk/order"
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/types/role"
+)
 
 // DefaultOrderBy represents the default way we sort.
 var DefaultOrderBy = order.NewBy(OrderByID, order.ASC)
@@ -12,4 +19,85 @@ const (
 	OrderByEmail   = "c"
 	OrderByRoles   = "d"
 	OrderByEnabled = "e"
+	OrderByDept    = "g"
+	OrderByCreated = "h"
+
+	// OrderByRelevance ranks the users by how well they match the search,
+	// best matches first when ascending. It requires a search and can't be
+	// used with keyset pages.
+	OrderByRelevance = "f"
+)
+
+// RelevanceOrderBy represents the way search results are ranked.
+var RelevanceOrderBy = order.NewBy(OrderByRelevance, order.ASC)
+
+// OrderFields represents the fields the results can be ordered by, the names
+// clients use for them and the columns the stores order by.
+var OrderFields = order.Register(DomainName,
+	order.Field{Name: "user_id", Key: OrderByID, Column: "user_id"},
+	order.Field{Name: "name", Key: OrderByName, Column: "name"},
+	order.Field{Name: "email", Key: OrderByEmail, Column: "email"},
+	order.Field{Name: "roles", Key: OrderByRoles, Column: "roles"},
+	order.Field{Name: "enabled", Key: OrderByEnabled, Column: "enabled"},
+	order.Field{Name: "relevance", Key: OrderByRelevance, Column: "search_rank"},
+	order.Field{Name: "department", Key: OrderByDept, Column: "department"},
+	order.Field{Name: "date_created", Key: OrderByCreated, Column: "date_created"},
 )
+
+// rankOrder drops the ordering by relevance when there is no search to rank
+// the users against, falling back to the fields that break ties or the
+// default order.
+func rankOrder(filter QueryFilter, orderBy order.By) order.By {
+	if orderBy.Field != OrderByRelevance || filter.Search != nil {
+		return orderBy
+	}
+
+	if len(orderBy.Then) == 0 {
+		return DefaultOrderBy
+	}
+
+	by := orderBy.Then[0]
+	by.Then = append(by.Then, orderBy.Then[1:]...)
+
+	return by
+}
+
+// keysetFields represents the fields a keyset page can be ordered by.
+var keysetFields = map[string]bool{
+	OrderByID:      true,
+	OrderByName:    true,
+	OrderByEmail:   true,
+	OrderByRoles:   true,
+	OrderByEnabled: true,
+}
+
+// KeysetOrder reports whether results in the specified order can be paged
+// by cursor. Only a single field that is never null can be used.
+func KeysetOrder(orderBy order.By) bool {
+	return keysetFields[orderBy.Field] && len(orderBy.Then) == 0
+}
+
+// NextCursor returns the cursor for the keyset page that follows the
+// specified users. The cursor holds the sort key of the last user followed
+// by its ID to break ties. An empty cursor means there are no more users.
+func NextCursor(orderBy order.By, pg page.Page, usrs []User) string {
+	if len(usrs) == 0 || len(usrs) < pg.RowsPerPage() {
+		return ""
+	}
+
+	last := usrs[len(usrs)-1]
+
+	switch orderBy.Field {
+	case OrderByName:
+		return page.NextCursor(last.Name.String(), last.ID.String())
+	case OrderByEmail:
+		return page.NextCursor(last.Email.Address, last.ID.String())
+	case OrderByRoles:
+		roles := "{" + strings.Join(role.ParseToString(last.Roles), ",") + "}"
+		return page.NextCursor(roles, last.ID.String())
+	case OrderByEnabled:
+		return page.NextCursor(strconv.FormatBool(last.Enabled), last.ID.String())
+	default:
+		return page.NextCursor(last.ID.String())
+	}
+}
@@ -0,0 +1,103 @@
+package userbus
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ardanlabs/service/foundation/clock"
+	"github.com/ardanlabs/service/foundation/otel"
+	"github.com/google/uuid"
+)
+
+// DirectReports returns the users that report directly to the user.
+func (b *business) DirectReports(ctx context.Context, userID uuid.UUID) ([]User, error) {
+	ctx, span := otel.AddSpan(ctx, "business.userbus.directreports")
+	defer span.End()
+
+	usrs, err := b.storer.DirectReports(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("directreports: userID[%s]: %w", userID, err)
+	}
+
+	return usrs, nil
+}
+
+// ManagementChain returns the managers above the user, starting with the
+// user's own manager and ending with the top of the org chart.
+func (b *business) ManagementChain(ctx context.Context, userID uuid.UUID) ([]User, error) {
+	ctx, span := otel.AddSpan(ctx, "business.userbus.managementchain")
+	defer span.End()
+
+	usrs, err := b.storer.ManagementChain(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("managementchain: userID[%s]: %w", userID, err)
+	}
+
+	return usrs, nil
+}
+
+// =============================================================================
+
+// checkManager verifies the manager exists and that the user isn't the
+// manager or anywhere in the manager's chain, which would make the user
+// manage themselves. A new user has no reports so the nil id is used.
+func (b *business) checkManager(ctx context.Context, userID uuid.UUID, managerID uuid.NullUUID) error {
+	if !managerID.Valid {
+		return nil
+	}
+
+	if managerID.UUID == userID {
+		return fmt.Errorf("userID[%s]: %w", userID, ErrManagerCycle)
+	}
+
+	if _, err := b.storer.QueryByID(ctx, managerID.UUID); err != nil {
+		return fmt.Errorf("manager: %w", err)
+	}
+
+	if userID == uuid.Nil {
+		return nil
+	}
+
+	chain, err := b.storer.ManagementChain(ctx, managerID.UUID)
+	if err != nil {
+		return fmt.Errorf("managementchain: managerID[%s]: %w", managerID.UUID, err)
+	}
+
+	for _, mgr := range chain {
+		if mgr.ID == userID {
+			return fmt.Errorf("userID[%s] managerID[%s]: %w", userID, managerID.UUID, ErrManagerCycle)
+		}
+	}
+
+	return nil
+}
+
+// reassignReports moves the direct reports of a user that is being deleted
+// up to the user's own manager, so the org chart keeps the reports attached.
+// Each move is reported as an update so other domains see the new manager
+// and is attributed to the actor deleting the user.
+func (b *business) reassignReports(ctx context.Context, actorID uuid.UUID, usr User) error {
+	reports, err := b.storer.DirectReports(ctx, usr.ID)
+	if err != nil {
+		return fmt.Errorf("directreports: userID[%s]: %w", usr.ID, err)
+	}
+
+	for _, rpt := range reports {
+		orgRpt := rpt
+
+		rpt.ManagerID = usr.ManagerID
+		rpt.UpdatedBy = actorID
+		rpt.DateUpdated = clock.Now()
+		rpt.Version++
+
+		if err := b.storer.Update(ctx, rpt); err != nil {
+			return fmt.Errorf("update: userID[%s]: %w", rpt.ID, err)
+		}
+
+		if err := b.delegate.Call(ctx, ActionUpdatedData(rpt.ID, rpt.TenantID, changes(orgRpt, rpt))); err != nil {
+			return fmt.Errorf("failed to execute `%s` action: %w", ActionUpdated, err)
+		}
+	}
+
+	return nil
+}
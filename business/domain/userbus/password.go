@@ -0,0 +1,62 @@
+package userbus
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ardanlabs/service/foundation/clock"
+	"github.com/ardanlabs/service/foundation/otel"
+	"github.com/google/uuid"
+)
+
+// ChangePassword replaces the user's password after verifying the current
+// one. Unlike an update made by an administrator, it's meant to be called by
+// the users themselves, so the user is recorded as the one making the change.
+// Other domains are told through the delegate so credentials issued with the
+// old password, like sessions, can be revoked.
+func (b *business) ChangePassword(ctx context.Context, userID uuid.UUID, currentPassword string, newPassword string) error {
+	ctx, span := otel.AddSpan(ctx, "business.userbus.changepassword")
+	defer span.End()
+
+	usr, err := b.QueryByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	// Federated users may not have a password.
+	if len(usr.PasswordHash) == 0 {
+		return fmt.Errorf("userID[%s]: no password: %w", usr.ID, ErrAuthenticationFailure)
+	}
+
+	if err := b.hasher.Compare(usr.PasswordHash, currentPassword); err != nil {
+		return fmt.Errorf("compare: %w", ErrAuthenticationFailure)
+	}
+
+	if err := b.checkPassword(ctx, usr, newPassword); err != nil {
+		return err
+	}
+
+	pw, err := b.hasher.Hash(newPassword)
+	if err != nil {
+		return fmt.Errorf("hash: %w", err)
+	}
+
+	usr.PasswordHash = pw
+	usr.UpdatedBy = userID
+	usr.DateUpdated = clock.Now()
+	usr.Version++
+
+	if err := b.storer.Update(ctx, usr); err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+
+	if err := b.addPasswordHistory(ctx, usr); err != nil {
+		return err
+	}
+
+	if err := b.delegate.Call(ctx, ActionPasswordChangedData(usr.ID)); err != nil {
+		return fmt.Errorf("failed to execute `%s` action: %w", ActionPasswordChanged, err)
+	}
+
+	return nil
+}
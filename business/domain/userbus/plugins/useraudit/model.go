@@ -0,0 +1,124 @@
+package useraudit
+
+import (
+	"bytes"
+	"slices"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/types/role"
+)
+
+// redacted is the value recorded in place of sensitive information.
+const redacted = "[REDACTED]"
+
+// Snapshot represents the state of a user at the time of an audit. The
+// password hash is intentionally left out.
+type Snapshot struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Email       string   `json:"email"`
+	Roles       []string `json:"roles"`
+	Department  string   `json:"department"`
+	Enabled     bool     `json:"enabled"`
+	DateCreated string   `json:"dateCreated"`
+	DateUpdated string   `json:"dateUpdated"`
+}
+
+func toSnapshot(usr userbus.User) *Snapshot {
+	snp := Snapshot{
+		ID:          usr.ID.String(),
+		Name:        usr.Name.String(),
+		Email:       usr.Email.Address,
+		Roles:       role.ParseToString(usr.Roles),
+		Enabled:     usr.Enabled,
+		DateCreated: usr.DateCreated.Format(time.RFC3339),
+		DateUpdated: usr.DateUpdated.Format(time.RFC3339),
+	}
+
+	// A null department reads as "NULL", record it as empty instead.
+	if usr.Department.Valid() {
+		snp.Department = usr.Department.String()
+	}
+
+	return &snp
+}
+
+// Change represents the before and after value of a single field.
+type Change struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// Diff represents the data stored with an audit record. Before is nil for
+// a created user and After is nil for a deleted user.
+type Diff struct {
+	Before  *Snapshot         `json:"before"`
+	After   *Snapshot         `json:"after"`
+	Changes map[string]Change `json:"changes,omitempty"`
+}
+
+// newDiff constructs the audit data for the before and after versions of
+// a user. A nil value represents a user that doesn't exist on that side
+// of the change.
+func newDiff(before *userbus.User, after *userbus.User) Diff {
+	var diff Diff
+
+	if before != nil {
+		diff.Before = toSnapshot(*before)
+	}
+
+	if after != nil {
+		diff.After = toSnapshot(*after)
+	}
+
+	if before == nil || after == nil {
+		return diff
+	}
+
+	changes := make(map[string]Change)
+
+	add := func(field string, b any, a any) {
+		changes[field] = Change{Before: b, After: a}
+	}
+
+	if diff.Before.Name != diff.After.Name {
+		add("name", diff.Before.Name, diff.After.Name)
+	}
+
+	if diff.Before.Email != diff.After.Email {
+		add("email", diff.Before.Email, diff.After.Email)
+	}
+
+	if !equalRoles(diff.Before.Roles, diff.After.Roles) {
+		add("roles", diff.Before.Roles, diff.After.Roles)
+	}
+
+	if diff.Before.Department != diff.After.Department {
+		add("department", diff.Before.Department, diff.After.Department)
+	}
+
+	if diff.Before.Enabled != diff.After.Enabled {
+		add("enabled", diff.Before.Enabled, diff.After.Enabled)
+	}
+
+	if before.AvatarKey != after.AvatarKey {
+		add("avatar", before.AvatarKey, after.AvatarKey)
+	}
+
+	if !bytes.Equal(before.PasswordHash, after.PasswordHash) {
+		add("password", redacted, redacted)
+	}
+
+	if len(changes) > 0 {
+		diff.Changes = changes
+	}
+
+	return diff
+}
+
+// equalRoles reports whether both sets hold the same roles. The order the
+// roles are stored in isn't a change worth auditing.
+func equalRoles(r1 []string, r2 []string) bool {
+	return slices.Equal(slices.Sorted(slices.Values(r1)), slices.Sorted(slices.Values(r2)))
+}
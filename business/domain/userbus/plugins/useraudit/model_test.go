@@ -0,0 +1,189 @@
+package useraudit
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/mail"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/types/department"
+	"github.com/ardanlabs/service/business/types/name"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+)
+
+func user() userbus.User {
+	now := time.Date(2026, time.January, 2, 3, 4, 5, 0, time.UTC)
+
+	return userbus.User{
+		ID:           uuid.MustParse("5cf37266-3473-4006-984f-9325122678b7"),
+		Name:         name.MustParse("Bill Kennedy"),
+		Email:        mail.Address{Address: "bill@ardanlabs.com"},
+		Roles:        []role.Role{role.Admin, role.User},
+		PasswordHash: []byte("$2a$10$hashhashhashhashhashha"),
+		Department:   department.MustParseNull("Engineering"),
+		Enabled:      true,
+		TOTPSecret:   "JBSWY3DPEHPK3PXP",
+		AvatarKey:    "avatars/bill.png",
+		DateCreated:  now,
+		DateUpdated:  now,
+	}
+}
+
+func Test_Snapshot(t *testing.T) {
+	usr := user()
+
+	exp := &Snapshot{
+		ID:          "5cf37266-3473-4006-984f-9325122678b7",
+		Name:        "Bill Kennedy",
+		Email:       "bill@ardanlabs.com",
+		Roles:       []string{"ADMIN", "USER"},
+		Department:  "Engineering",
+		Enabled:     true,
+		DateCreated: "2026-01-02T03:04:05Z",
+		DateUpdated: "2026-01-02T03:04:05Z",
+	}
+
+	got := toSnapshot(usr)
+
+	if diff := cmp.Diff(got, exp); diff != "" {
+		t.Fatalf("Should snapshot the user : %s", diff)
+	}
+
+	data, err := json.Marshal(got)
+	if err != nil {
+		t.Fatalf("Should be able to marshal the snapshot : %s", err)
+	}
+
+	for _, secret := range [][]byte{usr.PasswordHash, []byte(usr.TOTPSecret)} {
+		if bytes.Contains(data, secret) {
+			t.Fatalf("Should not record secrets in the snapshot : %s", data)
+		}
+	}
+}
+
+func Test_Diff(t *testing.T) {
+	table := []struct {
+		name    string
+		update  func(usr *userbus.User)
+		changes map[string]Change
+	}{
+		{
+			name:    "unchanged",
+			update:  func(usr *userbus.User) {},
+			changes: nil,
+		},
+		{
+			name:    "updated",
+			update:  func(usr *userbus.User) { usr.DateUpdated = usr.DateUpdated.Add(time.Hour) },
+			changes: nil,
+		},
+		{
+			name:    "name",
+			update:  func(usr *userbus.User) { usr.Name = name.MustParse("William Kennedy") },
+			changes: map[string]Change{"name": {Before: "Bill Kennedy", After: "William Kennedy"}},
+		},
+		{
+			name:    "email",
+			update:  func(usr *userbus.User) { usr.Email = mail.Address{Address: "william@ardanlabs.com"} },
+			changes: map[string]Change{"email": {Before: "bill@ardanlabs.com", After: "william@ardanlabs.com"}},
+		},
+		{
+			name:    "rolesremoved",
+			update:  func(usr *userbus.User) { usr.Roles = []role.Role{role.User} },
+			changes: map[string]Change{"roles": {Before: []string{"ADMIN", "USER"}, After: []string{"USER"}}},
+		},
+		{
+			name:    "rolesreplaced",
+			update:  func(usr *userbus.User) { usr.Roles = []role.Role{role.Admin, role.Admin} },
+			changes: map[string]Change{"roles": {Before: []string{"ADMIN", "USER"}, After: []string{"ADMIN", "ADMIN"}}},
+		},
+		{
+			name:    "rolesreordered",
+			update:  func(usr *userbus.User) { usr.Roles = []role.Role{role.User, role.Admin} },
+			changes: nil,
+		},
+		{
+			name:    "department",
+			update:  func(usr *userbus.User) { usr.Department = department.Null{} },
+			changes: map[string]Change{"department": {Before: "Engineering", After: ""}},
+		},
+		{
+			name:    "enabled",
+			update:  func(usr *userbus.User) { usr.Enabled = false },
+			changes: map[string]Change{"enabled": {Before: true, After: false}},
+		},
+		{
+			name:    "avatar",
+			update:  func(usr *userbus.User) { usr.AvatarKey = "" },
+			changes: map[string]Change{"avatar": {Before: "avatars/bill.png", After: ""}},
+		},
+		{
+			name:    "password",
+			update:  func(usr *userbus.User) { usr.PasswordHash = []byte("$2a$10$otherotherotherotherot") },
+			changes: map[string]Change{"password": {Before: redacted, After: redacted}},
+		},
+		{
+			name: "several",
+			update: func(usr *userbus.User) {
+				usr.Name = name.MustParse("William Kennedy")
+				usr.Enabled = false
+			},
+			changes: map[string]Change{
+				"name":    {Before: "Bill Kennedy", After: "William Kennedy"},
+				"enabled": {Before: true, After: false},
+			},
+		},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			before := user()
+
+			after := user()
+			tt.update(&after)
+
+			diff := newDiff(&before, &after)
+
+			if d := cmp.Diff(diff.Changes, tt.changes); d != "" {
+				t.Fatalf("Should record the changed fields only : %s", d)
+			}
+
+			if d := cmp.Diff(diff.Before, toSnapshot(before)); d != "" {
+				t.Fatalf("Should snapshot the user before the change : %s", d)
+			}
+
+			if d := cmp.Diff(diff.After, toSnapshot(after)); d != "" {
+				t.Fatalf("Should snapshot the user after the change : %s", d)
+			}
+
+			data, err := json.Marshal(diff)
+			if err != nil {
+				t.Fatalf("Should be able to marshal the diff : %s", err)
+			}
+
+			for _, secret := range [][]byte{before.PasswordHash, after.PasswordHash} {
+				if bytes.Contains(data, secret) {
+					t.Fatalf("Should not record the password hash : %s", data)
+				}
+			}
+		})
+	}
+}
+
+func Test_DiffCreateDelete(t *testing.T) {
+	usr := user()
+
+	created := newDiff(nil, &usr)
+	if created.Before != nil || created.After == nil || created.Changes != nil {
+		t.Fatalf("Should only record the user after a create : %+v", created)
+	}
+
+	deleted := newDiff(&usr, nil)
+	if deleted.Before == nil || deleted.After != nil || deleted.Changes != nil {
+		t.Fatalf("Should only record the user before a delete : %+v", deleted)
+	}
+}
@@ -4,7 +4,13 @@ package useraudit
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/mail"
+	"slices"
+	"time"
 
 	"github.com/ardanlabs/service/business/domain/auditbus"
 	"github.com/ardanlabs/service/business/domain/userbus"
@@ -12,18 +18,36 @@ import (
 	"github.com/ardanlabs/service/business/sdk/page"
 	"github.com/ardanlabs/service/business/sdk/sqldb"
 	"github.com/ardanlabs/service/business/types/domain"
-	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/business/types/role"
 	"github.com/google/uuid"
 )
 
+// Set of audit actions recorded by this plugin.
+const (
+	ActionCreated         = "created"
+	ActionUpdated         = "updated"
+	ActionDeleted         = "deleted"
+	ActionAnonymized      = "anonymized"
+	ActionExported        = "exported"
+	ActionPasswordChanged = "passwordchanged"
+	ActionRolesAssigned   = "rolesassigned"
+	ActionRolesReverted   = "rolesreverted"
+)
+
+// roleChangeBatchSize is the number of users queried at once to record the
+// audits of a role assignment.
+const roleChangeBatchSize = 500
+
 // Plugin provides a wrapper for audit functionality around the userbus.
 type Plugin struct {
 	bus      userbus.Business
 	auditBus *auditbus.Business
 }
 
-// NewPlugin constructs a new plugin that wraps the userbus with audit.
-func NewPlugin(log *logger.Logger, auditBus *auditbus.Business) userbus.Plugin {
+// NewPlugin constructs a new plugin that wraps the userbus with audit. Each
+// change records the actor who made it along with a diff of the user before
+// and after the change.
+func NewPlugin(auditBus *auditbus.Business) userbus.Plugin {
 	return func(bus userbus.Business) userbus.Business {
 		return &Plugin{
 			bus:      bus,
@@ -33,16 +57,28 @@ func NewPlugin(log *logger.Logger, auditBus *auditbus.Business) userbus.Plugin {
 }
 
 // NewWithTx constructs a new business value that will use the
-// specified transaction in any store related calls.
+// specified transaction in any store related calls. The new value is
+// wrapped with auditing as well.
 func (p *Plugin) NewWithTx(tx sqldb.CommitRollbacker) (userbus.Business, error) {
-	return p.bus.NewWithTx(tx)
+	bus, err := p.bus.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	plugin := Plugin{
+		bus:      bus,
+		auditBus: p.auditBus,
+	}
+
+	return &plugin, nil
 }
 
-// Create adds a new user to the system.
-func (p *Plugin) Create(ctx context.Context, actorID uuid.UUID, nu userbus.NewUser) (userbus.User, error) {
-	usr, err := p.bus.Create(ctx, actorID, nu)
-	if err != nil {
-		return userbus.User{}, err
+// Create adds a new user to the system. A call replayed with an idempotency
+// key returns the user created by the first call and isn't audited again.
+func (p *Plugin) Create(ctx context.Context, actorID uuid.UUID, nu userbus.NewUser) (userbus.User, bool, error) {
+	usr, replayed, err := p.bus.Create(ctx, actorID, nu)
+	if err != nil || replayed {
+		return usr, replayed, err
 	}
 
 	na := auditbus.NewAudit{
@@ -50,32 +86,81 @@ func (p *Plugin) Create(ctx context.Context, actorID uuid.UUID, nu userbus.NewUs
 		ObjDomain: domain.User,
 		ObjName:   usr.Name,
 		ActorID:   actorID,
-		Action:    "created",
-		Data:      nu,
+		Action:    ActionCreated,
+		Data:      newDiff(nil, &usr),
 		Message:   "user created",
 	}
 
 	if _, err := p.auditBus.Create(ctx, na); err != nil {
-		return userbus.User{}, err
+		return userbus.User{}, false, err
 	}
 
-	return usr, nil
+	return usr, false, nil
+}
+
+// CreateOrGet adds a new user to the system or returns the user that
+// already has the email. An audit is only recorded when a user is created.
+func (p *Plugin) CreateOrGet(ctx context.Context, actorID uuid.UUID, nu userbus.NewUser) (userbus.User, bool, error) {
+	usr, created, err := p.bus.CreateOrGet(ctx, actorID, nu)
+	if err != nil || !created {
+		return usr, created, err
+	}
+
+	na := auditbus.NewAudit{
+		ObjID:     usr.ID,
+		ObjDomain: domain.User,
+		ObjName:   usr.Name,
+		ActorID:   actorID,
+		Action:    ActionCreated,
+		Data:      newDiff(nil, &usr),
+		Message:   "user created",
+	}
+
+	if _, err := p.auditBus.Create(ctx, na); err != nil {
+		return userbus.User{}, false, err
+	}
+
+	return usr, true, nil
+}
+
+// CreateBatch adds a set of new users to the system. An audit is recorded
+// for each user that was created.
+func (p *Plugin) CreateBatch(ctx context.Context, actorID uuid.UUID, nus []userbus.NewUser, mode userbus.BatchMode) ([]userbus.User, []userbus.BatchError) {
+	usrs, bes := p.bus.CreateBatch(ctx, actorID, nus, mode)
+
+	for _, usr := range usrs {
+		na := auditbus.NewAudit{
+			ObjID:     usr.ID,
+			ObjDomain: domain.User,
+			ObjName:   usr.Name,
+			ActorID:   actorID,
+			Action:    ActionCreated,
+			Data:      newDiff(nil, &usr),
+			Message:   "user created in batch",
+		}
+
+		if _, err := p.auditBus.Create(ctx, na); err != nil {
+			bes = append(bes, userbus.BatchError{Index: -1, Err: fmt.Errorf("audit: userID[%s]: %w", usr.ID, err)})
+		}
+	}
+
+	return usrs, bes
 }
 
 // Update modifies information about a user.
 func (p *Plugin) Update(ctx context.Context, actorID uuid.UUID, usr userbus.User, uu userbus.UpdateUser) (userbus.User, error) {
-	usr, err := p.bus.Update(ctx, actorID, usr, uu)
+	updUsr, err := p.bus.Update(ctx, actorID, usr, uu)
 	if err != nil {
 		return userbus.User{}, err
 	}
 
 	na := auditbus.NewAudit{
-		ObjID:     usr.ID,
+		ObjID:     updUsr.ID,
 		ObjDomain: domain.User,
-		ObjName:   usr.Name,
+		ObjName:   updUsr.Name,
 		ActorID:   actorID,
-		Action:    "updated",
-		Data:      uu,
+		Action:    ActionUpdated,
+		Data:      newDiff(&usr, &updUsr),
 		Message:   "user updated",
 	}
 
@@ -83,7 +168,7 @@ func (p *Plugin) Update(ctx context.Context, actorID uuid.UUID, usr userbus.User
 		return userbus.User{}, err
 	}
 
-	return usr, nil
+	return updUsr, nil
 }
 
 // Delete removes the specified user.
@@ -97,8 +182,8 @@ func (p *Plugin) Delete(ctx context.Context, actorID uuid.UUID, usr userbus.User
 		ObjDomain: domain.User,
 		ObjName:   usr.Name,
 		ActorID:   actorID,
-		Action:    "deleted",
-		Data:      nil,
+		Action:    ActionDeleted,
+		Data:      newDiff(&usr, nil),
 		Message:   "user deleted",
 	}
 
@@ -109,21 +194,67 @@ func (p *Plugin) Delete(ctx context.Context, actorID uuid.UUID, usr userbus.User
 	return nil
 }
 
+// Anonymize scrubs the personal data of the user. Only the anonymized
+// user is recorded so the audit doesn't keep a copy of what was scrubbed.
+func (p *Plugin) Anonymize(ctx context.Context, actorID uuid.UUID, userID uuid.UUID) (userbus.User, error) {
+	usr, err := p.bus.Anonymize(ctx, actorID, userID)
+	if err != nil {
+		return userbus.User{}, err
+	}
+
+	na := auditbus.NewAudit{
+		ObjID:     usr.ID,
+		ObjDomain: domain.User,
+		ObjName:   usr.Name,
+		ActorID:   actorID,
+		Action:    ActionAnonymized,
+		Data:      newDiff(nil, &usr),
+		Message:   "user anonymized",
+	}
+
+	if _, err := p.auditBus.Create(ctx, na); err != nil {
+		return userbus.User{}, err
+	}
+
+	return usr, nil
+}
+
 // Query retrieves a list of existing users.
 func (p *Plugin) Query(ctx context.Context, filter userbus.QueryFilter, orderBy order.By, page page.Page) ([]userbus.User, error) {
 	return p.bus.Query(ctx, filter, orderBy, page)
 }
 
+// QueryWithCount retrieves a list of existing users along with the number
+// of users that match the filter.
+func (p *Plugin) QueryWithCount(ctx context.Context, filter userbus.QueryFilter, orderBy order.By, page page.Page) ([]userbus.User, int, error) {
+	return p.bus.QueryWithCount(ctx, filter, orderBy, page)
+}
+
+// QueryAll streams every user that matches the filter.
+func (p *Plugin) QueryAll(ctx context.Context, filter userbus.QueryFilter, orderBy order.By, fn func(userbus.User) error) error {
+	return p.bus.QueryAll(ctx, filter, orderBy, fn)
+}
+
 // Count returns the total number of users.
 func (p *Plugin) Count(ctx context.Context, filter userbus.QueryFilter) (int, error) {
 	return p.bus.Count(ctx, filter)
 }
 
+// CountEstimate returns the number of users, which may not be exact.
+func (p *Plugin) CountEstimate(ctx context.Context, filter userbus.QueryFilter) (int, bool, error) {
+	return p.bus.CountEstimate(ctx, filter)
+}
+
 // QueryByID finds the user by the specified ID.
 func (p *Plugin) QueryByID(ctx context.Context, userID uuid.UUID) (userbus.User, error) {
 	return p.bus.QueryByID(ctx, userID)
 }
 
+// QueryByIDs finds the users by the specified IDs.
+func (p *Plugin) QueryByIDs(ctx context.Context, userIDs []uuid.UUID) ([]userbus.User, error) {
+	return p.bus.QueryByIDs(ctx, userIDs)
+}
+
 // QueryByEmail finds the user by a specified user email.
 func (p *Plugin) QueryByEmail(ctx context.Context, email mail.Address) (userbus.User, error) {
 	return p.bus.QueryByEmail(ctx, email)
@@ -135,3 +266,296 @@ func (p *Plugin) QueryByEmail(ctx context.Context, email mail.Address) (userbus.
 func (p *Plugin) Authenticate(ctx context.Context, email mail.Address, password string) (userbus.User, error) {
 	return p.bus.Authenticate(ctx, email, password)
 }
+
+// AuthenticateWithTOTP finds a user by their email and verifies their
+// password along with a one-time code or recovery code.
+func (p *Plugin) AuthenticateWithTOTP(ctx context.Context, email mail.Address, password string, code string) (userbus.User, error) {
+	return p.bus.AuthenticateWithTOTP(ctx, email, password, code)
+}
+
+// EnrollTOTP generates a new one-time code secret for the user.
+func (p *Plugin) EnrollTOTP(ctx context.Context, userID uuid.UUID) (string, string, error) {
+	return p.bus.EnrollTOTP(ctx, userID)
+}
+
+// ConfirmTOTP enables one-time codes for the user and returns their
+// recovery codes.
+func (p *Plugin) ConfirmTOTP(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	return p.bus.ConfirmTOTP(ctx, userID, code)
+}
+
+// DisableTOTP turns off one-time codes for the user.
+func (p *Plugin) DisableTOTP(ctx context.Context, userID uuid.UUID) error {
+	return p.bus.DisableTOTP(ctx, userID)
+}
+
+// ChangePassword replaces the user's password after verifying the current
+// one. The user is recorded as the actor and no data is kept.
+func (p *Plugin) ChangePassword(ctx context.Context, userID uuid.UUID, currentPassword string, newPassword string) error {
+	if err := p.bus.ChangePassword(ctx, userID, currentPassword, newPassword); err != nil {
+		return err
+	}
+
+	usr, err := p.bus.QueryByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	na := auditbus.NewAudit{
+		ObjID:     usr.ID,
+		ObjDomain: domain.User,
+		ObjName:   usr.Name,
+		ActorID:   userID,
+		Action:    ActionPasswordChanged,
+		Message:   "user password changed",
+	}
+
+	if _, err := p.auditBus.Create(ctx, na); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DirectReports returns the users that report directly to the user.
+func (p *Plugin) DirectReports(ctx context.Context, userID uuid.UUID) ([]userbus.User, error) {
+	return p.bus.DirectReports(ctx, userID)
+}
+
+// ManagementChain returns the managers above the user.
+func (p *Plugin) ManagementChain(ctx context.Context, userID uuid.UUID) ([]userbus.User, error) {
+	return p.bus.ManagementChain(ctx, userID)
+}
+
+// FederateLogin finds, links or creates the user for an external identity.
+// A user provisioned for the identity is audited the same as one created,
+// attributed to the service since nobody asked for it.
+func (p *Plugin) FederateLogin(ctx context.Context, provider string, externalID string, email mail.Address, profile userbus.Profile) (userbus.User, bool, error) {
+	usr, provisioned, err := p.bus.FederateLogin(ctx, provider, externalID, email, profile)
+	if err != nil || !provisioned {
+		return usr, provisioned, err
+	}
+
+	na := auditbus.NewAudit{
+		ObjID:     usr.ID,
+		ObjDomain: domain.User,
+		ObjName:   usr.Name,
+		ActorID:   usr.CreatedBy,
+		Action:    ActionCreated,
+		Data:      newDiff(nil, &usr),
+		Message:   fmt.Sprintf("user provisioned by %s", provider),
+	}
+
+	if _, err := p.auditBus.Create(ctx, na); err != nil {
+		return userbus.User{}, false, err
+	}
+
+	return usr, true, nil
+}
+
+// AssignRolesByFilter previews a change of roles for the users that match
+// the filter.
+func (p *Plugin) AssignRolesByFilter(ctx context.Context, actorID uuid.UUID, filter userbus.QueryFilter, addRoles []role.Role, removeRoles []role.Role) (userbus.RoleAssignment, error) {
+	return p.bus.AssignRolesByFilter(ctx, actorID, filter, addRoles, removeRoles)
+}
+
+// ApplyRoleAssignment changes the roles of the users in a previewed
+// assignment. An audit is recorded for each user whose roles changed.
+func (p *Plugin) ApplyRoleAssignment(ctx context.Context, actorID uuid.UUID, assignmentID uuid.UUID) (userbus.RoleAssignment, string, error) {
+	ra, token, err := p.bus.ApplyRoleAssignment(ctx, actorID, assignmentID)
+	if err != nil {
+		return userbus.RoleAssignment{}, "", err
+	}
+
+	msg := fmt.Sprintf("roles assigned by assignment %s", ra.ID)
+	if err := p.auditRoleChanges(ctx, actorID, ActionRolesAssigned, msg, ra.Changed); err != nil {
+		return userbus.RoleAssignment{}, "", err
+	}
+
+	return ra, token, nil
+}
+
+// RevertRoleAssignment puts back the roles changed by an assignment. An
+// audit is recorded for each user whose roles were put back.
+func (p *Plugin) RevertRoleAssignment(ctx context.Context, actorID uuid.UUID, token string) (userbus.RoleAssignment, error) {
+	ra, err := p.bus.RevertRoleAssignment(ctx, actorID, token)
+	if err != nil {
+		return userbus.RoleAssignment{}, err
+	}
+
+	msg := fmt.Sprintf("roles reverted by assignment %s", ra.ID)
+	if err := p.auditRoleChanges(ctx, actorID, ActionRolesReverted, msg, ra.Changed); err != nil {
+		return userbus.RoleAssignment{}, err
+	}
+
+	return ra, nil
+}
+
+// PurgeIdempotencyKeys removes the idempotency keys older than the TTL.
+func (p *Plugin) PurgeIdempotencyKeys(ctx context.Context) (int, error) {
+	return p.bus.PurgeIdempotencyKeys(ctx)
+}
+
+// NormalizeNames rewrites the users whose stored name isn't normalized. The
+// names don't change the way a reader sees them so no audit is recorded.
+func (p *Plugin) NormalizeNames(ctx context.Context) (int, error) {
+	return p.bus.NormalizeNames(ctx)
+}
+
+// SetPreference saves a preference for the user. Preferences aren't
+// audited.
+func (p *Plugin) SetPreference(ctx context.Context, userID uuid.UUID, key string, value json.RawMessage) (userbus.Preference, error) {
+	return p.bus.SetPreference(ctx, userID, key, value)
+}
+
+// GetPreferences returns every preference for the user.
+func (p *Plugin) GetPreferences(ctx context.Context, userID uuid.UUID) ([]userbus.Preference, error) {
+	return p.bus.GetPreferences(ctx, userID)
+}
+
+// DeletePreference puts the user's preference back to its default.
+func (p *Plugin) DeletePreference(ctx context.Context, userID uuid.UUID, key string) error {
+	return p.bus.DeletePreference(ctx, userID, key)
+}
+
+// UpdateAvatar replaces the user's avatar.
+func (p *Plugin) UpdateAvatar(ctx context.Context, actorID uuid.UUID, userID uuid.UUID, r io.Reader, contentType string) (userbus.User, error) {
+	usr, err := p.bus.QueryByID(ctx, userID)
+	if err != nil {
+		return userbus.User{}, err
+	}
+
+	updUsr, err := p.bus.UpdateAvatar(ctx, actorID, userID, r, contentType)
+	if err != nil {
+		return userbus.User{}, err
+	}
+
+	na := auditbus.NewAudit{
+		ObjID:     updUsr.ID,
+		ObjDomain: domain.User,
+		ObjName:   updUsr.Name,
+		ActorID:   actorID,
+		Action:    ActionUpdated,
+		Data:      newDiff(&usr, &updUsr),
+		Message:   "avatar updated",
+	}
+
+	if _, err := p.auditBus.Create(ctx, na); err != nil {
+		return userbus.User{}, err
+	}
+
+	return updUsr, nil
+}
+
+// AvatarURL returns the address the user's avatar can be read from.
+func (p *Plugin) AvatarURL(ctx context.Context, usr userbus.User) (string, error) {
+	return p.bus.AvatarURL(ctx, usr)
+}
+
+// ExportData gathers what the system holds about the user. The export is
+// audited since it hands the user's personal data out of the system.
+func (p *Plugin) ExportData(ctx context.Context, actorID uuid.UUID, userID uuid.UUID) (io.Reader, error) {
+	r, err := p.bus.ExportData(ctx, actorID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	usr, err := p.bus.QueryByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	na := auditbus.NewAudit{
+		ObjID:     usr.ID,
+		ObjDomain: domain.User,
+		ObjName:   usr.Name,
+		ActorID:   actorID,
+		Action:    ActionExported,
+		Message:   "user data exported",
+	}
+
+	if _, err := p.auditBus.Create(ctx, na); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// DisableDormant disables the users that haven't logged in for the
+// specified duration. An audit is recorded for each user disabled, with the
+// service as the actor.
+func (p *Plugin) DisableDormant(ctx context.Context, inactiveFor time.Duration) ([]userbus.User, error) {
+	usrs, err := p.bus.DisableDormant(ctx, inactiveFor)
+
+	for _, usr := range usrs {
+		before := usr
+		before.Enabled = true
+
+		na := auditbus.NewAudit{
+			ObjID:     usr.ID,
+			ObjDomain: domain.User,
+			ObjName:   usr.Name,
+			ActorID:   userbus.ActorSystem,
+			Action:    ActionUpdated,
+			Data:      newDiff(&before, &usr),
+			Message:   "dormant user disabled",
+		}
+
+		if _, aerr := p.auditBus.Create(ctx, na); aerr != nil {
+			return usrs, errors.Join(err, fmt.Errorf("audit: userID[%s]: %w", usr.ID, aerr))
+		}
+	}
+
+	return usrs, err
+}
+
+// =============================================================================
+
+// auditRoleChanges records an audit for each user changed by a role
+// assignment. The users are queried after the change, so the user before it
+// is the changed user with the roles they had.
+func (p *Plugin) auditRoleChanges(ctx context.Context, actorID uuid.UUID, action string, msg string, chgs []userbus.RoleChange) error {
+	for batch := range slices.Chunk(chgs, roleChangeBatchSize) {
+		ids := make([]uuid.UUID, len(batch))
+		for i, chg := range batch {
+			ids[i] = chg.UserID
+		}
+
+		usrs, err := p.bus.QueryByIDs(ctx, ids)
+		if err != nil {
+			return fmt.Errorf("querybyids: %w", err)
+		}
+
+		byID := make(map[uuid.UUID]userbus.User, len(usrs))
+		for _, usr := range usrs {
+			byID[usr.ID] = usr
+		}
+
+		for _, chg := range batch {
+			usr, exists := byID[chg.UserID]
+			if !exists {
+				usr = userbus.User{ID: chg.UserID}
+			}
+
+			before, after := usr, usr
+			before.Roles = chg.OldRoles
+			after.Roles = chg.NewRoles
+
+			na := auditbus.NewAudit{
+				ObjID:     usr.ID,
+				ObjDomain: domain.User,
+				ObjName:   usr.Name,
+				ActorID:   actorID,
+				Action:    action,
+				Data:      newDiff(&before, &after),
+				Message:   msg,
+			}
+
+			if _, err := p.auditBus.Create(ctx, na); err != nil {
+				return fmt.Errorf("audit: userID[%s]: %w", usr.ID, err)
+			}
+		}
+	}
+
+	return nil
+}
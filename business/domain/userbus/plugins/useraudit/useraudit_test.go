@@ -0,0 +1,352 @@
+package useraudit_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/mail"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/auditbus"
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/domain/userbus/mocks"
+	"github.com/ardanlabs/service/business/domain/userbus/plugins/useraudit"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/types/name"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/google/uuid"
+)
+
+// auditStore keeps the audits created in memory.
+type auditStore struct {
+	mu     sync.Mutex
+	audits []auditbus.Audit
+}
+
+func (s *auditStore) Create(ctx context.Context, audit auditbus.Audit) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.audits = append(s.audits, audit)
+	return nil
+}
+
+func (s *auditStore) Query(ctx context.Context, filter auditbus.QueryFilter, orderBy order.By, page page.Page) ([]auditbus.Audit, error) {
+	return nil, nil
+}
+
+func (s *auditStore) Count(ctx context.Context, filter auditbus.QueryFilter) (int, error) {
+	return 0, nil
+}
+
+func (s *auditStore) take() []auditbus.Audit {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	audits := s.audits
+	s.audits = nil
+
+	return audits
+}
+
+func newPlugin(bus userbus.Business) (userbus.Business, *auditStore) {
+	var buf bytes.Buffer
+	log := logger.New(&buf, logger.LevelInfo, "TEST", func(context.Context) string { return "" })
+
+	var store auditStore
+	plugin := useraudit.NewPlugin(auditbus.NewBusiness(log, &store))
+
+	return plugin(bus), &store
+}
+
+func Test_Create(t *testing.T) {
+	actorID := uuid.New()
+
+	usr := userbus.User{
+		ID:   uuid.New(),
+		Name: name.MustParse("Ann Smith"),
+	}
+
+	var replayed bool
+
+	bus := mocks.Business{
+		CreateFunc: func(ctx context.Context, actorID uuid.UUID, nu userbus.NewUser) (userbus.User, bool, error) {
+			return usr, replayed, nil
+		},
+	}
+
+	plugin, store := newPlugin(&bus)
+
+	table := []struct {
+		name     string
+		replayed bool
+		audits   int
+	}{
+		{name: "created", replayed: false, audits: 1},
+		{name: "replayed", replayed: true, audits: 0},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			replayed = tt.replayed
+
+			got, gotReplayed, err := plugin.Create(context.Background(), actorID, userbus.NewUser{IdempotencyKey: "create-1"})
+			if err != nil {
+				t.Fatalf("Should be able to create the user : %s", err)
+			}
+
+			if got.ID != usr.ID || gotReplayed != tt.replayed {
+				t.Fatalf("Should return the user and whether it was replayed : got %s %t", got.ID, gotReplayed)
+			}
+
+			audits := store.take()
+			if len(audits) != tt.audits {
+				t.Fatalf("Should only audit a user that was created : got %d, exp %d", len(audits), tt.audits)
+			}
+
+			for _, audit := range audits {
+				if audit.ObjID != usr.ID || audit.ActorID != actorID || audit.Action != useraudit.ActionCreated {
+					t.Errorf("Should audit the user created by the actor : got %s %s %s", audit.ObjID, audit.ActorID, audit.Action)
+				}
+			}
+		})
+	}
+}
+
+func Test_RoleAssignment(t *testing.T) {
+	actorID := uuid.New()
+
+	usrs := []userbus.User{
+		{ID: uuid.New(), Name: name.MustParse("Ann Smith"), Roles: []role.Role{role.Admin}},
+		{ID: uuid.New(), Name: name.MustParse("Bob Jones"), Roles: []role.Role{role.Admin}},
+	}
+
+	changed := []userbus.RoleChange{
+		{UserID: usrs[0].ID, OldRoles: []role.Role{role.User}, NewRoles: []role.Role{role.Admin}},
+		{UserID: usrs[1].ID, OldRoles: []role.Role{role.User}, NewRoles: []role.Role{role.Admin}},
+	}
+
+	ra := userbus.RoleAssignment{
+		ID:       uuid.New(),
+		AddRoles: []role.Role{role.Admin},
+		Affected: len(changed),
+		Changed:  changed,
+	}
+
+	bus := mocks.Business{
+		ApplyRoleAssignmentFunc: func(ctx context.Context, actorID uuid.UUID, assignmentID uuid.UUID) (userbus.RoleAssignment, string, error) {
+			return ra, "token", nil
+		},
+		RevertRoleAssignmentFunc: func(ctx context.Context, actorID uuid.UUID, token string) (userbus.RoleAssignment, error) {
+			return ra, nil
+		},
+		QueryByIDsFunc: func(ctx context.Context, userIDs []uuid.UUID) ([]userbus.User, error) {
+			var found []userbus.User
+			for _, usr := range usrs {
+				if slices.Contains(userIDs, usr.ID) {
+					found = append(found, usr)
+				}
+			}
+			return found, nil
+		},
+	}
+
+	plugin, store := newPlugin(&bus)
+
+	table := []struct {
+		name   string
+		call   func() error
+		action string
+	}{
+		{
+			name: "apply",
+			call: func() error {
+				_, token, err := plugin.ApplyRoleAssignment(context.Background(), actorID, ra.ID)
+				if token != "token" {
+					t.Errorf("Should return the revert token : got %q", token)
+				}
+				return err
+			},
+			action: useraudit.ActionRolesAssigned,
+		},
+		{
+			name: "revert",
+			call: func() error {
+				_, err := plugin.RevertRoleAssignment(context.Background(), actorID, "token")
+				return err
+			},
+			action: useraudit.ActionRolesReverted,
+		},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); err != nil {
+				t.Fatalf("Should be able to make the call : %s", err)
+			}
+
+			audits := store.take()
+			if len(audits) != len(changed) {
+				t.Fatalf("Should record an audit for each changed user : got %d, exp %d", len(audits), len(changed))
+			}
+
+			for i, audit := range audits {
+				if audit.ObjID != usrs[i].ID || audit.ObjName != usrs[i].Name {
+					t.Errorf("Should audit the changed user : got %s %s, exp %s %s", audit.ObjID, audit.ObjName, usrs[i].ID, usrs[i].Name)
+				}
+
+				if audit.ActorID != actorID || audit.Action != tt.action {
+					t.Errorf("Should record the actor and action : got %s %s", audit.ActorID, audit.Action)
+				}
+
+				var diff useraudit.Diff
+				if err := json.Unmarshal(audit.Data, &diff); err != nil {
+					t.Fatalf("Should be able to unmarshal the diff : %s", err)
+				}
+
+				chg, exists := diff.Changes["roles"]
+				if !exists || len(diff.Changes) != 1 {
+					t.Fatalf("Should only record the change of roles : got %v", diff.Changes)
+				}
+
+				if !slices.Equal(toStrings(chg.Before), []string{"USER"}) || !slices.Equal(toStrings(chg.After), []string{"ADMIN"}) {
+					t.Errorf("Should record the roles before and after : got %v", chg)
+				}
+			}
+		})
+	}
+}
+
+func Test_DisableDormant(t *testing.T) {
+	usrs := []userbus.User{
+		{ID: uuid.New(), Name: name.MustParse("Ann Smith"), Enabled: false},
+		{ID: uuid.New(), Name: name.MustParse("Bob Jones"), Enabled: false},
+	}
+
+	bus := mocks.Business{
+		DisableDormantFunc: func(ctx context.Context, inactiveFor time.Duration) ([]userbus.User, error) {
+			return usrs, nil
+		},
+	}
+
+	plugin, store := newPlugin(&bus)
+
+	disabled, err := plugin.DisableDormant(context.Background(), 90*24*time.Hour)
+	if err != nil {
+		t.Fatalf("Should be able to disable the dormant users : %s", err)
+	}
+
+	if len(disabled) != len(usrs) {
+		t.Fatalf("Should return the disabled users : got %d, exp %d", len(disabled), len(usrs))
+	}
+
+	audits := store.take()
+	if len(audits) != len(usrs) {
+		t.Fatalf("Should record an audit for each disabled user : got %d, exp %d", len(audits), len(usrs))
+	}
+
+	for i, audit := range audits {
+		if audit.ObjID != usrs[i].ID || audit.ObjName != usrs[i].Name {
+			t.Errorf("Should audit the disabled user : got %s %s, exp %s %s", audit.ObjID, audit.ObjName, usrs[i].ID, usrs[i].Name)
+		}
+
+		if audit.ActorID != userbus.ActorSystem || audit.Action != useraudit.ActionUpdated {
+			t.Errorf("Should record the service as the actor : got %s %s", audit.ActorID, audit.Action)
+		}
+
+		var diff useraudit.Diff
+		if err := json.Unmarshal(audit.Data, &diff); err != nil {
+			t.Fatalf("Should be able to unmarshal the diff : %s", err)
+		}
+
+		chg, exists := diff.Changes["enabled"]
+		if !exists || len(diff.Changes) != 1 {
+			t.Fatalf("Should only record the change of enabled : got %v", diff.Changes)
+		}
+
+		if chg.Before != true || chg.After != false {
+			t.Errorf("Should record the user was enabled before : got %v", chg)
+		}
+	}
+}
+
+func Test_FederateLogin(t *testing.T) {
+	usr := userbus.User{
+		ID:        uuid.New(),
+		Name:      name.MustParse("Ann Smith"),
+		Email:     mail.Address{Address: "ann@example.com"},
+		Roles:     []role.Role{role.User},
+		Enabled:   true,
+		CreatedBy: userbus.ActorSystem,
+	}
+
+	var provisioned bool
+
+	bus := mocks.Business{
+		FederateLoginFunc: func(ctx context.Context, provider string, externalID string, email mail.Address, profile userbus.Profile) (userbus.User, bool, error) {
+			return usr, provisioned, nil
+		},
+	}
+
+	plugin, store := newPlugin(&bus)
+
+	table := []struct {
+		name        string
+		provisioned bool
+		audits      int
+	}{
+		{name: "provisioned", provisioned: true, audits: 1},
+		{name: "linked", provisioned: false, audits: 0},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			provisioned = tt.provisioned
+
+			got, gotProvisioned, err := plugin.FederateLogin(context.Background(), "google", "g-1", usr.Email, userbus.Profile{})
+			if err != nil {
+				t.Fatalf("Should be able to log in : %s", err)
+			}
+
+			if got.ID != usr.ID || gotProvisioned != tt.provisioned {
+				t.Fatalf("Should return the user and whether it was provisioned : got %s %t", got.ID, gotProvisioned)
+			}
+
+			audits := store.take()
+			if len(audits) != tt.audits {
+				t.Fatalf("Should only audit a provisioned user : got %d, exp %d", len(audits), tt.audits)
+			}
+
+			for _, audit := range audits {
+				if audit.ObjID != usr.ID || audit.ActorID != userbus.ActorSystem || audit.Action != useraudit.ActionCreated {
+					t.Errorf("Should audit the user as created by the service : got %s %s %s", audit.ObjID, audit.ActorID, audit.Action)
+				}
+
+				var diff useraudit.Diff
+				if err := json.Unmarshal(audit.Data, &diff); err != nil {
+					t.Fatalf("Should be able to unmarshal the diff : %s", err)
+				}
+
+				if diff.Before != nil || diff.After == nil || diff.After.Email != usr.Email.Address {
+					t.Errorf("Should record the user created : got %+v", diff)
+				}
+			}
+		})
+	}
+}
+
+func toStrings(v any) []string {
+	values, _ := v.([]any)
+
+	strs := make([]string, len(values))
+	for i, value := range values {
+		strs[i], _ = value.(string)
+	}
+
+	return strs
+}
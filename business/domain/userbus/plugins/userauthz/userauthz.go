@@ -0,0 +1,402 @@
+// Package userauthz provides a plugin for userbus that enforces role based
+// access on the methods that change user data.
+package userauthz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/mail"
+	"slices"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/google/uuid"
+)
+
+// Plugin provides a wrapper for authorization functionality around the
+// userbus. The rules are applied to every caller, not just the http layer.
+//
+//   - Only an admin can create users.
+//   - Only an admin can change the roles or enabled state of a user.
+//   - A user can only update, delete, anonymize or export themselves, or
+//     change their own avatar.
+//   - A registered system identity is treated as an admin.
+//
+// The methods that don't take an actor pass through unchecked, each one
+// documents why: reads, the calls that authenticate the user, the calls a
+// user makes about themselves with the user taken from their credentials,
+// and the jobs the service runs on its own.
+type Plugin struct {
+	log *logger.Logger
+	bus userbus.Business
+}
+
+// NewPlugin constructs a new plugin that wraps the userbus with authorization.
+func NewPlugin(log *logger.Logger) userbus.Plugin {
+	return func(bus userbus.Business) userbus.Business {
+		return &Plugin{
+			log: log,
+			bus: bus,
+		}
+	}
+}
+
+// NewWithTx constructs a new business value that will use the
+// specified transaction in any store related calls. The new value is
+// wrapped with authorization as well.
+func (p *Plugin) NewWithTx(tx sqldb.CommitRollbacker) (userbus.Business, error) {
+	bus, err := p.bus.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	plugin := Plugin{
+		log: p.log,
+		bus: bus,
+	}
+
+	return &plugin, nil
+}
+
+// Create adds a new user to the system.
+func (p *Plugin) Create(ctx context.Context, actorID uuid.UUID, nu userbus.NewUser) (userbus.User, bool, error) {
+	actor, err := p.actor(ctx, actorID)
+	if err != nil {
+		return userbus.User{}, false, err
+	}
+
+	if !isAdmin(actor) {
+		return userbus.User{}, false, fmt.Errorf("create: actorID[%s]: %w", actorID, userbus.ErrForbidden)
+	}
+
+	return p.bus.Create(ctx, actorID, nu)
+}
+
+// CreateOrGet adds a new user to the system or returns the user that
+// already has the email.
+func (p *Plugin) CreateOrGet(ctx context.Context, actorID uuid.UUID, nu userbus.NewUser) (userbus.User, bool, error) {
+	actor, err := p.actor(ctx, actorID)
+	if err != nil {
+		return userbus.User{}, false, err
+	}
+
+	if !isAdmin(actor) {
+		return userbus.User{}, false, fmt.Errorf("createorget: actorID[%s]: %w", actorID, userbus.ErrForbidden)
+	}
+
+	return p.bus.CreateOrGet(ctx, actorID, nu)
+}
+
+// CreateBatch adds a set of new users to the system.
+func (p *Plugin) CreateBatch(ctx context.Context, actorID uuid.UUID, nus []userbus.NewUser, mode userbus.BatchMode) ([]userbus.User, []userbus.BatchError) {
+	actor, err := p.actor(ctx, actorID)
+	if err != nil {
+		return nil, []userbus.BatchError{{Index: -1, Err: err}}
+	}
+
+	if !isAdmin(actor) {
+		return nil, []userbus.BatchError{{Index: -1, Err: fmt.Errorf("createbatch: actorID[%s]: %w", actorID, userbus.ErrForbidden)}}
+	}
+
+	return p.bus.CreateBatch(ctx, actorID, nus, mode)
+}
+
+// Update modifies information about a user.
+func (p *Plugin) Update(ctx context.Context, actorID uuid.UUID, usr userbus.User, uu userbus.UpdateUser) (userbus.User, error) {
+	actor, err := p.actor(ctx, actorID)
+	if err != nil {
+		return userbus.User{}, err
+	}
+
+	if !isAdmin(actor) {
+		if actor.ID != usr.ID {
+			return userbus.User{}, fmt.Errorf("update: actorID[%s] userID[%s]: %w", actorID, usr.ID, userbus.ErrForbidden)
+		}
+
+		if uu.Roles != nil || uu.Enabled != nil || uu.ManagerID != nil {
+			return userbus.User{}, fmt.Errorf("update: actorID[%s]: only an admin can change roles, enabled or manager: %w", actorID, userbus.ErrForbidden)
+		}
+	}
+
+	return p.bus.Update(ctx, actorID, usr, uu)
+}
+
+// Delete removes the specified user.
+func (p *Plugin) Delete(ctx context.Context, actorID uuid.UUID, usr userbus.User) error {
+	actor, err := p.actor(ctx, actorID)
+	if err != nil {
+		return err
+	}
+
+	if !isAdmin(actor) && actor.ID != usr.ID {
+		return fmt.Errorf("delete: actorID[%s] userID[%s]: %w", actorID, usr.ID, userbus.ErrForbidden)
+	}
+
+	return p.bus.Delete(ctx, actorID, usr)
+}
+
+// Anonymize scrubs the personal data of the user.
+func (p *Plugin) Anonymize(ctx context.Context, actorID uuid.UUID, userID uuid.UUID) (userbus.User, error) {
+	actor, err := p.actor(ctx, actorID)
+	if err != nil {
+		return userbus.User{}, err
+	}
+
+	if !isAdmin(actor) && actor.ID != userID {
+		return userbus.User{}, fmt.Errorf("anonymize: actorID[%s] userID[%s]: %w", actorID, userID, userbus.ErrForbidden)
+	}
+
+	return p.bus.Anonymize(ctx, actorID, userID)
+}
+
+// Query retrieves a list of existing users.
+func (p *Plugin) Query(ctx context.Context, filter userbus.QueryFilter, orderBy order.By, page page.Page) ([]userbus.User, error) {
+	return p.bus.Query(ctx, filter, orderBy, page)
+}
+
+// QueryWithCount retrieves a list of existing users along with the number
+// of users that match the filter.
+func (p *Plugin) QueryWithCount(ctx context.Context, filter userbus.QueryFilter, orderBy order.By, page page.Page) ([]userbus.User, int, error) {
+	return p.bus.QueryWithCount(ctx, filter, orderBy, page)
+}
+
+// QueryAll streams every user that matches the filter.
+func (p *Plugin) QueryAll(ctx context.Context, filter userbus.QueryFilter, orderBy order.By, fn func(userbus.User) error) error {
+	return p.bus.QueryAll(ctx, filter, orderBy, fn)
+}
+
+// Count returns the total number of users.
+func (p *Plugin) Count(ctx context.Context, filter userbus.QueryFilter) (int, error) {
+	return p.bus.Count(ctx, filter)
+}
+
+// CountEstimate returns the number of users, which may not be exact.
+func (p *Plugin) CountEstimate(ctx context.Context, filter userbus.QueryFilter) (int, bool, error) {
+	return p.bus.CountEstimate(ctx, filter)
+}
+
+// QueryByID finds the user by the specified ID.
+func (p *Plugin) QueryByID(ctx context.Context, userID uuid.UUID) (userbus.User, error) {
+	return p.bus.QueryByID(ctx, userID)
+}
+
+// QueryByIDs finds the users by the specified IDs.
+func (p *Plugin) QueryByIDs(ctx context.Context, userIDs []uuid.UUID) ([]userbus.User, error) {
+	return p.bus.QueryByIDs(ctx, userIDs)
+}
+
+// QueryByEmail finds the user by a specified user email.
+func (p *Plugin) QueryByEmail(ctx context.Context, email mail.Address) (userbus.User, error) {
+	return p.bus.QueryByEmail(ctx, email)
+}
+
+// Authenticate finds a user by their email and verifies their password. On
+// success it returns a Claims User representing this user. The claims can be
+// used to generate a token for future authentication.
+func (p *Plugin) Authenticate(ctx context.Context, email mail.Address, password string) (userbus.User, error) {
+	return p.bus.Authenticate(ctx, email, password)
+}
+
+// AuthenticateWithTOTP finds a user by their email and verifies their
+// password along with a one-time code or recovery code.
+func (p *Plugin) AuthenticateWithTOTP(ctx context.Context, email mail.Address, password string, code string) (userbus.User, error) {
+	return p.bus.AuthenticateWithTOTP(ctx, email, password, code)
+}
+
+// EnrollTOTP generates a new one-time code secret for the user. It isn't
+// checked since it's self service, the caller must pass the authenticated
+// user taken from their credentials, never a user from the request. An
+// admin can't enroll someone else.
+func (p *Plugin) EnrollTOTP(ctx context.Context, userID uuid.UUID) (string, string, error) {
+	return p.bus.EnrollTOTP(ctx, userID)
+}
+
+// ConfirmTOTP enables one-time codes for the user and returns their
+// recovery codes. It isn't checked since the code proves the caller holds
+// the secret handed out by EnrollTOTP to that same user.
+func (p *Plugin) ConfirmTOTP(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	return p.bus.ConfirmTOTP(ctx, userID, code)
+}
+
+// DisableTOTP turns off one-time codes for the user. It isn't checked
+// since it's self service like EnrollTOTP, the caller must pass the
+// authenticated user taken from their credentials.
+func (p *Plugin) DisableTOTP(ctx context.Context, userID uuid.UUID) error {
+	return p.bus.DisableTOTP(ctx, userID)
+}
+
+// ChangePassword replaces the user's password after verifying the current
+// one. Knowing the current password is the authorization.
+func (p *Plugin) ChangePassword(ctx context.Context, userID uuid.UUID, currentPassword string, newPassword string) error {
+	return p.bus.ChangePassword(ctx, userID, currentPassword, newPassword)
+}
+
+// DirectReports returns the users that report directly to the user.
+func (p *Plugin) DirectReports(ctx context.Context, userID uuid.UUID) ([]userbus.User, error) {
+	return p.bus.DirectReports(ctx, userID)
+}
+
+// ManagementChain returns the managers above the user.
+func (p *Plugin) ManagementChain(ctx context.Context, userID uuid.UUID) ([]userbus.User, error) {
+	return p.bus.ManagementChain(ctx, userID)
+}
+
+// FederateLogin finds, links or creates the user for an external identity.
+func (p *Plugin) FederateLogin(ctx context.Context, provider string, externalID string, email mail.Address, profile userbus.Profile) (userbus.User, bool, error) {
+	return p.bus.FederateLogin(ctx, provider, externalID, email, profile)
+}
+
+// AssignRolesByFilter previews a change of roles for the users that match
+// the filter. Only an admin can assign roles.
+func (p *Plugin) AssignRolesByFilter(ctx context.Context, actorID uuid.UUID, filter userbus.QueryFilter, addRoles []role.Role, removeRoles []role.Role) (userbus.RoleAssignment, error) {
+	actor, err := p.actor(ctx, actorID)
+	if err != nil {
+		return userbus.RoleAssignment{}, err
+	}
+
+	if !isAdmin(actor) {
+		return userbus.RoleAssignment{}, fmt.Errorf("assignrolesbyfilter: actorID[%s]: %w", actorID, userbus.ErrForbidden)
+	}
+
+	return p.bus.AssignRolesByFilter(ctx, actorID, filter, addRoles, removeRoles)
+}
+
+// ApplyRoleAssignment changes the roles of the users in a previewed
+// assignment. Only an admin can apply an assignment.
+func (p *Plugin) ApplyRoleAssignment(ctx context.Context, actorID uuid.UUID, assignmentID uuid.UUID) (userbus.RoleAssignment, string, error) {
+	actor, err := p.actor(ctx, actorID)
+	if err != nil {
+		return userbus.RoleAssignment{}, "", err
+	}
+
+	if !isAdmin(actor) {
+		return userbus.RoleAssignment{}, "", fmt.Errorf("applyroleassignment: actorID[%s]: %w", actorID, userbus.ErrForbidden)
+	}
+
+	return p.bus.ApplyRoleAssignment(ctx, actorID, assignmentID)
+}
+
+// RevertRoleAssignment puts back the roles changed by an assignment. Only
+// an admin can revert an assignment.
+func (p *Plugin) RevertRoleAssignment(ctx context.Context, actorID uuid.UUID, token string) (userbus.RoleAssignment, error) {
+	actor, err := p.actor(ctx, actorID)
+	if err != nil {
+		return userbus.RoleAssignment{}, err
+	}
+
+	if !isAdmin(actor) {
+		return userbus.RoleAssignment{}, fmt.Errorf("revertroleassignment: actorID[%s]: %w", actorID, userbus.ErrForbidden)
+	}
+
+	return p.bus.RevertRoleAssignment(ctx, actorID, token)
+}
+
+// PurgeIdempotencyKeys removes the idempotency keys older than the TTL. It
+// is run by the service itself, not on behalf of an actor.
+func (p *Plugin) PurgeIdempotencyKeys(ctx context.Context) (int, error) {
+	return p.bus.PurgeIdempotencyKeys(ctx)
+}
+
+// NormalizeNames rewrites the users whose stored name isn't normalized. It
+// is run by the service itself, not on behalf of an actor.
+func (p *Plugin) NormalizeNames(ctx context.Context) (int, error) {
+	return p.bus.NormalizeNames(ctx)
+}
+
+// SetPreference saves a preference for the user. It isn't checked since
+// preferences only affect how the user's own clients behave, and the app
+// layer already limits the route to the user or an admin.
+func (p *Plugin) SetPreference(ctx context.Context, userID uuid.UUID, key string, value json.RawMessage) (userbus.Preference, error) {
+	return p.bus.SetPreference(ctx, userID, key, value)
+}
+
+// GetPreferences returns every preference for the user.
+func (p *Plugin) GetPreferences(ctx context.Context, userID uuid.UUID) ([]userbus.Preference, error) {
+	return p.bus.GetPreferences(ctx, userID)
+}
+
+// DeletePreference puts the user's preference back to its default. It
+// isn't checked for the same reason as SetPreference.
+func (p *Plugin) DeletePreference(ctx context.Context, userID uuid.UUID, key string) error {
+	return p.bus.DeletePreference(ctx, userID, key)
+}
+
+// UpdateAvatar replaces the user's avatar.
+func (p *Plugin) UpdateAvatar(ctx context.Context, actorID uuid.UUID, userID uuid.UUID, r io.Reader, contentType string) (userbus.User, error) {
+	actor, err := p.actor(ctx, actorID)
+	if err != nil {
+		return userbus.User{}, err
+	}
+
+	if !isAdmin(actor) && actor.ID != userID {
+		return userbus.User{}, fmt.Errorf("updateavatar: actorID[%s] userID[%s]: %w", actorID, userID, userbus.ErrForbidden)
+	}
+
+	return p.bus.UpdateAvatar(ctx, actorID, userID, r, contentType)
+}
+
+// AvatarURL returns the address the user's avatar can be read from.
+func (p *Plugin) AvatarURL(ctx context.Context, usr userbus.User) (string, error) {
+	return p.bus.AvatarURL(ctx, usr)
+}
+
+// ExportData gathers what the system holds about the user.
+func (p *Plugin) ExportData(ctx context.Context, actorID uuid.UUID, userID uuid.UUID) (io.Reader, error) {
+	actor, err := p.actor(ctx, actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isAdmin(actor) && actor.ID != userID {
+		return nil, fmt.Errorf("exportdata: actorID[%s] userID[%s]: %w", actorID, userID, userbus.ErrForbidden)
+	}
+
+	return p.bus.ExportData(ctx, actorID, userID)
+}
+
+// DisableDormant disables the users that haven't logged in for the
+// specified duration. It isn't checked since it's only run by the dormant
+// user job, on behalf of the service rather than an actor.
+func (p *Plugin) DisableDormant(ctx context.Context, inactiveFor time.Duration) ([]userbus.User, error) {
+	return p.bus.DisableDormant(ctx, inactiveFor)
+}
+
+// =============================================================================
+
+// actor looks up the user performing the action. An unknown or disabled
+// actor is not allowed to perform any action. A system identity isn't a
+// user, background jobs act as one and are trusted like an admin.
+func (p *Plugin) actor(ctx context.Context, actorID uuid.UUID) (userbus.User, error) {
+	if _, exists := userbus.SystemActor(actorID); exists {
+		system := userbus.User{
+			ID:      actorID,
+			Roles:   []role.Role{role.Admin},
+			Enabled: true,
+		}
+
+		return system, nil
+	}
+
+	actor, err := p.bus.QueryByID(ctx, actorID)
+	if err != nil {
+		p.log.Info(ctx, "userauthz", "actorID", actorID, "ERROR", err)
+		return userbus.User{}, fmt.Errorf("actorID[%s]: %w", actorID, userbus.ErrForbidden)
+	}
+
+	if !actor.Enabled {
+		return userbus.User{}, fmt.Errorf("actorID[%s]: actor disabled: %w", actorID, userbus.ErrForbidden)
+	}
+
+	return actor, nil
+}
+
+func isAdmin(usr userbus.User) bool {
+	return slices.ContainsFunc(usr.Roles, role.Admin.Equal)
+}
@@ -0,0 +1,254 @@
+package userauthz_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/domain/userbus/plugins/userauthz"
+	"github.com/ardanlabs/service/business/types/name"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/google/uuid"
+)
+
+// business stands in for the userbus behind the plugin. It knows the users
+// by id and lets every call the plugin passes on succeed.
+type business struct {
+	userbus.Business
+	users map[uuid.UUID]userbus.User
+}
+
+func (b *business) QueryByID(ctx context.Context, userID uuid.UUID) (userbus.User, error) {
+	usr, exists := b.users[userID]
+	if !exists {
+		return userbus.User{}, userbus.ErrNotFound
+	}
+
+	return usr, nil
+}
+
+func (b *business) Create(ctx context.Context, actorID uuid.UUID, nu userbus.NewUser) (userbus.User, bool, error) {
+	return userbus.User{}, false, nil
+}
+
+func (b *business) CreateOrGet(ctx context.Context, actorID uuid.UUID, nu userbus.NewUser) (userbus.User, bool, error) {
+	return userbus.User{}, false, nil
+}
+
+func (b *business) CreateBatch(ctx context.Context, actorID uuid.UUID, nus []userbus.NewUser, mode userbus.BatchMode) ([]userbus.User, []userbus.BatchError) {
+	return nil, nil
+}
+
+func (b *business) Update(ctx context.Context, actorID uuid.UUID, usr userbus.User, uu userbus.UpdateUser) (userbus.User, error) {
+	return usr, nil
+}
+
+func (b *business) Delete(ctx context.Context, actorID uuid.UUID, usr userbus.User) error {
+	return nil
+}
+
+func (b *business) Anonymize(ctx context.Context, actorID uuid.UUID, userID uuid.UUID) (userbus.User, error) {
+	return userbus.User{}, nil
+}
+
+func (b *business) AssignRolesByFilter(ctx context.Context, actorID uuid.UUID, filter userbus.QueryFilter, addRoles []role.Role, removeRoles []role.Role) (userbus.RoleAssignment, error) {
+	return userbus.RoleAssignment{}, nil
+}
+
+func (b *business) ApplyRoleAssignment(ctx context.Context, actorID uuid.UUID, assignmentID uuid.UUID) (userbus.RoleAssignment, string, error) {
+	return userbus.RoleAssignment{}, "", nil
+}
+
+func (b *business) RevertRoleAssignment(ctx context.Context, actorID uuid.UUID, token string) (userbus.RoleAssignment, error) {
+	return userbus.RoleAssignment{}, nil
+}
+
+func (b *business) UpdateAvatar(ctx context.Context, actorID uuid.UUID, userID uuid.UUID, r io.Reader, contentType string) (userbus.User, error) {
+	return userbus.User{}, nil
+}
+
+func (b *business) ExportData(ctx context.Context, actorID uuid.UUID, userID uuid.UUID) (io.Reader, error) {
+	return strings.NewReader(""), nil
+}
+
+// =============================================================================
+
+// Set of actors the matrix is run for. The user actor is also the user the
+// calls act on, so it's acting on itself and the other user isn't.
+const (
+	actorAdmin    = "admin"
+	actorUser     = "user"
+	actorOther    = "other"
+	actorDisabled = "disabled"
+	actorSystem   = "system"
+	actorUnknown  = "unknown"
+)
+
+var actors = []string{actorAdmin, actorUser, actorOther, actorDisabled, actorSystem, actorUnknown}
+
+func Test_Authorization(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.New(&buf, logger.LevelInfo, "TEST", func(context.Context) string { return "" })
+
+	ids := make(map[string]uuid.UUID)
+	for _, actor := range actors {
+		ids[actor] = uuid.New()
+	}
+
+	if err := userbus.RegisterSystemActor(ids[actorSystem], "userauthz-test"); err != nil {
+		t.Fatalf("Should be able to register the system actor : %s", err)
+	}
+
+	users := map[uuid.UUID]userbus.User{
+		ids[actorAdmin]:    {ID: ids[actorAdmin], Roles: []role.Role{role.Admin}, Enabled: true},
+		ids[actorUser]:     {ID: ids[actorUser], Roles: []role.Role{role.User}, Enabled: true},
+		ids[actorOther]:    {ID: ids[actorOther], Roles: []role.Role{role.User}, Enabled: true},
+		ids[actorDisabled]: {ID: ids[actorDisabled], Roles: []role.Role{role.Admin}, Enabled: false},
+	}
+
+	bus := userauthz.NewPlugin(log)(&business{users: users})
+
+	target := users[ids[actorUser]]
+	newName := name.MustParse("Changed Name")
+	enabled := false
+
+	type op func(ctx context.Context, actorID uuid.UUID) error
+
+	table := []struct {
+		name    string
+		op      op
+		allowed []string
+	}{
+		{
+			name: "create",
+			op: func(ctx context.Context, actorID uuid.UUID) error {
+				_, _, err := bus.Create(ctx, actorID, userbus.NewUser{})
+				return err
+			},
+			allowed: []string{actorAdmin, actorSystem},
+		},
+		{
+			name: "createorget",
+			op: func(ctx context.Context, actorID uuid.UUID) error {
+				_, _, err := bus.CreateOrGet(ctx, actorID, userbus.NewUser{})
+				return err
+			},
+			allowed: []string{actorAdmin, actorSystem},
+		},
+		{
+			name: "createbatch",
+			op: func(ctx context.Context, actorID uuid.UUID) error {
+				_, errs := bus.CreateBatch(ctx, actorID, []userbus.NewUser{{}}, userbus.BatchAtomic)
+				if len(errs) > 0 {
+					return errs[0].Err
+				}
+				return nil
+			},
+			allowed: []string{actorAdmin, actorSystem},
+		},
+		{
+			name: "updatename",
+			op: func(ctx context.Context, actorID uuid.UUID) error {
+				_, err := bus.Update(ctx, actorID, target, userbus.UpdateUser{Name: &newName})
+				return err
+			},
+			allowed: []string{actorAdmin, actorUser, actorSystem},
+		},
+		{
+			name: "updateroles",
+			op: func(ctx context.Context, actorID uuid.UUID) error {
+				_, err := bus.Update(ctx, actorID, target, userbus.UpdateUser{Roles: []role.Role{role.Admin}})
+				return err
+			},
+			allowed: []string{actorAdmin, actorSystem},
+		},
+		{
+			name: "updateenabled",
+			op: func(ctx context.Context, actorID uuid.UUID) error {
+				_, err := bus.Update(ctx, actorID, target, userbus.UpdateUser{Enabled: &enabled})
+				return err
+			},
+			allowed: []string{actorAdmin, actorSystem},
+		},
+		{
+			name: "delete",
+			op: func(ctx context.Context, actorID uuid.UUID) error {
+				return bus.Delete(ctx, actorID, target)
+			},
+			allowed: []string{actorAdmin, actorUser, actorSystem},
+		},
+		{
+			name: "anonymize",
+			op: func(ctx context.Context, actorID uuid.UUID) error {
+				_, err := bus.Anonymize(ctx, actorID, target.ID)
+				return err
+			},
+			allowed: []string{actorAdmin, actorUser, actorSystem},
+		},
+		{
+			name: "assignroles",
+			op: func(ctx context.Context, actorID uuid.UUID) error {
+				_, err := bus.AssignRolesByFilter(ctx, actorID, userbus.QueryFilter{}, []role.Role{role.Admin}, nil)
+				return err
+			},
+			allowed: []string{actorAdmin, actorSystem},
+		},
+		{
+			name: "applyroles",
+			op: func(ctx context.Context, actorID uuid.UUID) error {
+				_, _, err := bus.ApplyRoleAssignment(ctx, actorID, uuid.New())
+				return err
+			},
+			allowed: []string{actorAdmin, actorSystem},
+		},
+		{
+			name: "revertroles",
+			op: func(ctx context.Context, actorID uuid.UUID) error {
+				_, err := bus.RevertRoleAssignment(ctx, actorID, "token")
+				return err
+			},
+			allowed: []string{actorAdmin, actorSystem},
+		},
+		{
+			name: "avatar",
+			op: func(ctx context.Context, actorID uuid.UUID) error {
+				_, err := bus.UpdateAvatar(ctx, actorID, target.ID, strings.NewReader(""), "image/png")
+				return err
+			},
+			allowed: []string{actorAdmin, actorUser, actorSystem},
+		},
+		{
+			name: "export",
+			op: func(ctx context.Context, actorID uuid.UUID) error {
+				_, err := bus.ExportData(ctx, actorID, target.ID)
+				return err
+			},
+			allowed: []string{actorAdmin, actorUser, actorSystem},
+		},
+	}
+
+	for _, tt := range table {
+		for _, actor := range actors {
+			t.Run(fmt.Sprintf("%s-%s", tt.name, actor), func(t *testing.T) {
+				allowed := slices.Contains(tt.allowed, actor)
+
+				err := tt.op(context.Background(), ids[actor])
+
+				switch {
+				case allowed && err != nil:
+					t.Fatalf("Should allow the call : %s", err)
+
+				case !allowed && !errors.Is(err, userbus.ErrForbidden):
+					t.Fatalf("Should deny the call with ErrForbidden : got %v", err)
+				}
+			})
+		}
+	}
+}
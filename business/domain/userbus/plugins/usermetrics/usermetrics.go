@@ -0,0 +1,501 @@
+// Package usermetrics provides a plugin for userbus that records the rate,
+// errors and duration of every call, so operators get RED metrics for the
+// user domain without writing a wrapper for each method.
+package usermetrics
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io"
+	"net/mail"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Config represents where the metrics are recorded.
+type Config struct {
+	// Meter creates the instruments. When nil the meter of the global
+	// provider is used, which records nothing until a provider is set.
+	Meter metric.Meter
+
+	// Expvar is the name of an expvar map the metrics are published in as
+	// well. An empty name doesn't publish them.
+	Expvar string
+}
+
+// Plugin provides a wrapper for metrics functionality around the userbus.
+// Every call is counted by method, along with the calls that failed and
+// how long they took.
+type Plugin struct {
+	bus userbus.Business
+	rec *recorder
+}
+
+// NewPlugin constructs a new plugin that wraps the userbus with metrics.
+// The instruments are created once and shared by every business value the
+// plugin wraps.
+func NewPlugin(cfg Config) (userbus.Plugin, error) {
+	rec, err := newRecorder(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	plugin := func(bus userbus.Business) userbus.Business {
+		return &Plugin{
+			bus: bus,
+			rec: rec,
+		}
+	}
+
+	return plugin, nil
+}
+
+// NewWithTx constructs a new business value that will use the
+// specified transaction in any store related calls. The new value is
+// wrapped with metrics as well.
+func (p *Plugin) NewWithTx(tx sqldb.CommitRollbacker) (userbus.Business, error) {
+	bus, err := p.bus.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	plugin := Plugin{
+		bus: bus,
+		rec: p.rec,
+	}
+
+	return &plugin, nil
+}
+
+// Create adds a new user to the system.
+func (p *Plugin) Create(ctx context.Context, actorID uuid.UUID, nu userbus.NewUser) (userbus.User, bool, error) {
+	start := time.Now()
+	usr, replayed, err := p.bus.Create(ctx, actorID, nu)
+	p.rec.record(ctx, "Create", start, err != nil)
+
+	return usr, replayed, err
+}
+
+// CreateOrGet adds a new user to the system or returns the user that
+// already has the email.
+func (p *Plugin) CreateOrGet(ctx context.Context, actorID uuid.UUID, nu userbus.NewUser) (userbus.User, bool, error) {
+	start := time.Now()
+	usr, created, err := p.bus.CreateOrGet(ctx, actorID, nu)
+	p.rec.record(ctx, "CreateOrGet", start, err != nil)
+
+	return usr, created, err
+}
+
+// CreateBatch adds a set of new users to the system. The call is counted
+// as failed when any of the users failed.
+func (p *Plugin) CreateBatch(ctx context.Context, actorID uuid.UUID, nus []userbus.NewUser, mode userbus.BatchMode) ([]userbus.User, []userbus.BatchError) {
+	start := time.Now()
+	usrs, errs := p.bus.CreateBatch(ctx, actorID, nus, mode)
+	p.rec.record(ctx, "CreateBatch", start, len(errs) > 0)
+
+	return usrs, errs
+}
+
+// Update modifies information about a user.
+func (p *Plugin) Update(ctx context.Context, actorID uuid.UUID, usr userbus.User, uu userbus.UpdateUser) (userbus.User, error) {
+	start := time.Now()
+	usr, err := p.bus.Update(ctx, actorID, usr, uu)
+	p.rec.record(ctx, "Update", start, err != nil)
+
+	return usr, err
+}
+
+// Delete removes the specified user.
+func (p *Plugin) Delete(ctx context.Context, actorID uuid.UUID, usr userbus.User) error {
+	start := time.Now()
+	err := p.bus.Delete(ctx, actorID, usr)
+	p.rec.record(ctx, "Delete", start, err != nil)
+
+	return err
+}
+
+// Anonymize scrubs the personal data of the user.
+func (p *Plugin) Anonymize(ctx context.Context, actorID uuid.UUID, userID uuid.UUID) (userbus.User, error) {
+	start := time.Now()
+	usr, err := p.bus.Anonymize(ctx, actorID, userID)
+	p.rec.record(ctx, "Anonymize", start, err != nil)
+
+	return usr, err
+}
+
+// Query retrieves a list of existing users.
+func (p *Plugin) Query(ctx context.Context, filter userbus.QueryFilter, orderBy order.By, page page.Page) ([]userbus.User, error) {
+	start := time.Now()
+	usrs, err := p.bus.Query(ctx, filter, orderBy, page)
+	p.rec.record(ctx, "Query", start, err != nil)
+
+	return usrs, err
+}
+
+// QueryWithCount retrieves a list of existing users along with the number
+// of users that match the filter.
+func (p *Plugin) QueryWithCount(ctx context.Context, filter userbus.QueryFilter, orderBy order.By, page page.Page) ([]userbus.User, int, error) {
+	start := time.Now()
+	usrs, total, err := p.bus.QueryWithCount(ctx, filter, orderBy, page)
+	p.rec.record(ctx, "QueryWithCount", start, err != nil)
+
+	return usrs, total, err
+}
+
+// QueryAll streams every user that matches the filter.
+func (p *Plugin) QueryAll(ctx context.Context, filter userbus.QueryFilter, orderBy order.By, fn func(userbus.User) error) error {
+	start := time.Now()
+	err := p.bus.QueryAll(ctx, filter, orderBy, fn)
+	p.rec.record(ctx, "QueryAll", start, err != nil)
+
+	return err
+}
+
+// Count returns the total number of users.
+func (p *Plugin) Count(ctx context.Context, filter userbus.QueryFilter) (int, error) {
+	start := time.Now()
+	n, err := p.bus.Count(ctx, filter)
+	p.rec.record(ctx, "Count", start, err != nil)
+
+	return n, err
+}
+
+// CountEstimate returns the number of users, which may not be exact.
+func (p *Plugin) CountEstimate(ctx context.Context, filter userbus.QueryFilter) (int, bool, error) {
+	start := time.Now()
+	n, exact, err := p.bus.CountEstimate(ctx, filter)
+	p.rec.record(ctx, "CountEstimate", start, err != nil)
+
+	return n, exact, err
+}
+
+// QueryByID finds the user by the specified ID.
+func (p *Plugin) QueryByID(ctx context.Context, userID uuid.UUID) (userbus.User, error) {
+	start := time.Now()
+	usr, err := p.bus.QueryByID(ctx, userID)
+	p.rec.record(ctx, "QueryByID", start, err != nil)
+
+	return usr, err
+}
+
+// QueryByIDs finds the users by the specified IDs.
+func (p *Plugin) QueryByIDs(ctx context.Context, userIDs []uuid.UUID) ([]userbus.User, error) {
+	start := time.Now()
+	usrs, err := p.bus.QueryByIDs(ctx, userIDs)
+	p.rec.record(ctx, "QueryByIDs", start, err != nil)
+
+	return usrs, err
+}
+
+// QueryByEmail finds the user by a specified user email.
+func (p *Plugin) QueryByEmail(ctx context.Context, email mail.Address) (userbus.User, error) {
+	start := time.Now()
+	usr, err := p.bus.QueryByEmail(ctx, email)
+	p.rec.record(ctx, "QueryByEmail", start, err != nil)
+
+	return usr, err
+}
+
+// Authenticate finds a user by their email and verifies their password.
+func (p *Plugin) Authenticate(ctx context.Context, email mail.Address, password string) (userbus.User, error) {
+	start := time.Now()
+	usr, err := p.bus.Authenticate(ctx, email, password)
+	p.rec.record(ctx, "Authenticate", start, err != nil)
+
+	return usr, err
+}
+
+// AuthenticateWithTOTP finds a user by their email and verifies their
+// password and one-time code.
+func (p *Plugin) AuthenticateWithTOTP(ctx context.Context, email mail.Address, password string, code string) (userbus.User, error) {
+	start := time.Now()
+	usr, err := p.bus.AuthenticateWithTOTP(ctx, email, password, code)
+	p.rec.record(ctx, "AuthenticateWithTOTP", start, err != nil)
+
+	return usr, err
+}
+
+// EnrollTOTP generates a new one-time code secret for the user.
+func (p *Plugin) EnrollTOTP(ctx context.Context, userID uuid.UUID) (string, string, error) {
+	start := time.Now()
+	secret, uri, err := p.bus.EnrollTOTP(ctx, userID)
+	p.rec.record(ctx, "EnrollTOTP", start, err != nil)
+
+	return secret, uri, err
+}
+
+// ConfirmTOTP enables one-time codes for the user and returns their
+// recovery codes.
+func (p *Plugin) ConfirmTOTP(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	start := time.Now()
+	codes, err := p.bus.ConfirmTOTP(ctx, userID, code)
+	p.rec.record(ctx, "ConfirmTOTP", start, err != nil)
+
+	return codes, err
+}
+
+// DisableTOTP turns off one-time codes for the user.
+func (p *Plugin) DisableTOTP(ctx context.Context, userID uuid.UUID) error {
+	start := time.Now()
+	err := p.bus.DisableTOTP(ctx, userID)
+	p.rec.record(ctx, "DisableTOTP", start, err != nil)
+
+	return err
+}
+
+// ChangePassword replaces the user's password after verifying the current
+// one.
+func (p *Plugin) ChangePassword(ctx context.Context, userID uuid.UUID, currentPassword string, newPassword string) error {
+	start := time.Now()
+	err := p.bus.ChangePassword(ctx, userID, currentPassword, newPassword)
+	p.rec.record(ctx, "ChangePassword", start, err != nil)
+
+	return err
+}
+
+// DirectReports returns the users that report directly to the user.
+func (p *Plugin) DirectReports(ctx context.Context, userID uuid.UUID) ([]userbus.User, error) {
+	start := time.Now()
+	usrs, err := p.bus.DirectReports(ctx, userID)
+	p.rec.record(ctx, "DirectReports", start, err != nil)
+
+	return usrs, err
+}
+
+// ManagementChain returns the managers above the user.
+func (p *Plugin) ManagementChain(ctx context.Context, userID uuid.UUID) ([]userbus.User, error) {
+	start := time.Now()
+	usrs, err := p.bus.ManagementChain(ctx, userID)
+	p.rec.record(ctx, "ManagementChain", start, err != nil)
+
+	return usrs, err
+}
+
+// FederateLogin finds, links or creates the user for an external identity.
+func (p *Plugin) FederateLogin(ctx context.Context, provider string, externalID string, email mail.Address, profile userbus.Profile) (userbus.User, bool, error) {
+	start := time.Now()
+	usr, provisioned, err := p.bus.FederateLogin(ctx, provider, externalID, email, profile)
+	p.rec.record(ctx, "FederateLogin", start, err != nil)
+
+	return usr, provisioned, err
+}
+
+// AssignRolesByFilter previews a change of roles for the users that match
+// the filter.
+func (p *Plugin) AssignRolesByFilter(ctx context.Context, actorID uuid.UUID, filter userbus.QueryFilter, addRoles []role.Role, removeRoles []role.Role) (userbus.RoleAssignment, error) {
+	start := time.Now()
+	ra, err := p.bus.AssignRolesByFilter(ctx, actorID, filter, addRoles, removeRoles)
+	p.rec.record(ctx, "AssignRolesByFilter", start, err != nil)
+
+	return ra, err
+}
+
+// ApplyRoleAssignment changes the roles of the users in a previewed
+// assignment.
+func (p *Plugin) ApplyRoleAssignment(ctx context.Context, actorID uuid.UUID, assignmentID uuid.UUID) (userbus.RoleAssignment, string, error) {
+	start := time.Now()
+	ra, token, err := p.bus.ApplyRoleAssignment(ctx, actorID, assignmentID)
+	p.rec.record(ctx, "ApplyRoleAssignment", start, err != nil)
+
+	return ra, token, err
+}
+
+// RevertRoleAssignment puts back the roles changed by an assignment.
+func (p *Plugin) RevertRoleAssignment(ctx context.Context, actorID uuid.UUID, token string) (userbus.RoleAssignment, error) {
+	start := time.Now()
+	ra, err := p.bus.RevertRoleAssignment(ctx, actorID, token)
+	p.rec.record(ctx, "RevertRoleAssignment", start, err != nil)
+
+	return ra, err
+}
+
+// PurgeIdempotencyKeys removes the idempotency keys older than the TTL.
+func (p *Plugin) PurgeIdempotencyKeys(ctx context.Context) (int, error) {
+	start := time.Now()
+	n, err := p.bus.PurgeIdempotencyKeys(ctx)
+	p.rec.record(ctx, "PurgeIdempotencyKeys", start, err != nil)
+
+	return n, err
+}
+
+// NormalizeNames rewrites the users whose stored name isn't normalized.
+func (p *Plugin) NormalizeNames(ctx context.Context) (int, error) {
+	start := time.Now()
+	n, err := p.bus.NormalizeNames(ctx)
+	p.rec.record(ctx, "NormalizeNames", start, err != nil)
+
+	return n, err
+}
+
+// SetPreference saves a preference for the user.
+func (p *Plugin) SetPreference(ctx context.Context, userID uuid.UUID, key string, value json.RawMessage) (userbus.Preference, error) {
+	start := time.Now()
+	pref, err := p.bus.SetPreference(ctx, userID, key, value)
+	p.rec.record(ctx, "SetPreference", start, err != nil)
+
+	return pref, err
+}
+
+// GetPreferences returns every preference for the user.
+func (p *Plugin) GetPreferences(ctx context.Context, userID uuid.UUID) ([]userbus.Preference, error) {
+	start := time.Now()
+	prefs, err := p.bus.GetPreferences(ctx, userID)
+	p.rec.record(ctx, "GetPreferences", start, err != nil)
+
+	return prefs, err
+}
+
+// DeletePreference puts the user's preference back to its default.
+func (p *Plugin) DeletePreference(ctx context.Context, userID uuid.UUID, key string) error {
+	start := time.Now()
+	err := p.bus.DeletePreference(ctx, userID, key)
+	p.rec.record(ctx, "DeletePreference", start, err != nil)
+
+	return err
+}
+
+// UpdateAvatar replaces the user's avatar.
+func (p *Plugin) UpdateAvatar(ctx context.Context, actorID uuid.UUID, userID uuid.UUID, r io.Reader, contentType string) (userbus.User, error) {
+	start := time.Now()
+	usr, err := p.bus.UpdateAvatar(ctx, actorID, userID, r, contentType)
+	p.rec.record(ctx, "UpdateAvatar", start, err != nil)
+
+	return usr, err
+}
+
+// AvatarURL returns the address the user's avatar can be read from.
+func (p *Plugin) AvatarURL(ctx context.Context, usr userbus.User) (string, error) {
+	start := time.Now()
+	url, err := p.bus.AvatarURL(ctx, usr)
+	p.rec.record(ctx, "AvatarURL", start, err != nil)
+
+	return url, err
+}
+
+// ExportData gathers what the system holds about the user.
+func (p *Plugin) ExportData(ctx context.Context, actorID uuid.UUID, userID uuid.UUID) (io.Reader, error) {
+	start := time.Now()
+	r, err := p.bus.ExportData(ctx, actorID, userID)
+	p.rec.record(ctx, "ExportData", start, err != nil)
+
+	return r, err
+}
+
+// DisableDormant disables the users that haven't logged in for the
+// specified duration.
+func (p *Plugin) DisableDormant(ctx context.Context, inactiveFor time.Duration) ([]userbus.User, error) {
+	start := time.Now()
+	usrs, err := p.bus.DisableDormant(ctx, inactiveFor)
+	p.rec.record(ctx, "DisableDormant", start, err != nil)
+
+	return usrs, err
+}
+
+// =============================================================================
+
+// recorder holds the instruments the calls are recorded with.
+type recorder struct {
+	calls    metric.Int64Counter
+	errors   metric.Int64Counter
+	duration metric.Float64Histogram
+	vars     *expvar.Map
+}
+
+func newRecorder(cfg Config) (*recorder, error) {
+	meter := cfg.Meter
+	if meter == nil {
+		meter = otel.Meter("github.com/ardanlabs/service/business/domain/userbus")
+	}
+
+	calls, err := meter.Int64Counter("userbus.calls",
+		metric.WithDescription("The number of calls to the user business API."),
+		metric.WithUnit("{call}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("calls counter: %w", err)
+	}
+
+	errors, err := meter.Int64Counter("userbus.errors",
+		metric.WithDescription("The number of calls to the user business API that failed."),
+		metric.WithUnit("{call}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("errors counter: %w", err)
+	}
+
+	duration, err := meter.Float64Histogram("userbus.duration",
+		metric.WithDescription("The duration of calls to the user business API."),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("duration histogram: %w", err)
+	}
+
+	rec := recorder{
+		calls:    calls,
+		errors:   errors,
+		duration: duration,
+	}
+
+	if cfg.Expvar != "" {
+		vars, err := publish(cfg.Expvar)
+		if err != nil {
+			return nil, err
+		}
+		rec.vars = vars
+	}
+
+	return &rec, nil
+}
+
+// publish returns the expvar map with the name, creating it the first time.
+// Expvar panics when a name is published twice, so a map that already
+// exists is reused.
+func publish(name string) (*expvar.Map, error) {
+	v := expvar.Get(name)
+	if v == nil {
+		return expvar.NewMap(name), nil
+	}
+
+	vars, ok := v.(*expvar.Map)
+	if !ok {
+		return nil, fmt.Errorf("expvar %q is already published and isn't a map", name)
+	}
+
+	return vars, nil
+}
+
+// record records a call to the method that started at the time. In expvar
+// the total milliseconds are kept rather than a histogram, so the average
+// duration can be worked out from the number of calls.
+func (r *recorder) record(ctx context.Context, method string, start time.Time, failed bool) {
+	elapsed := time.Since(start)
+	attrs := metric.WithAttributes(attribute.String("method", method))
+
+	r.calls.Add(ctx, 1, attrs)
+	r.duration.Record(ctx, elapsed.Seconds(), attrs)
+	if failed {
+		r.errors.Add(ctx, 1, attrs)
+	}
+
+	if r.vars == nil {
+		return
+	}
+
+	r.vars.Add(method+".calls", 1)
+	r.vars.AddFloat(method+".duration_ms", float64(elapsed)/float64(time.Millisecond))
+	if failed {
+		r.vars.Add(method+".errors", 1)
+	}
+}
@@ -0,0 +1,257 @@
+package usermetrics_test
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/domain/userbus/plugins/usermetrics"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+// meter keeps what the instruments it creates recorded, by instrument name
+// and method.
+type meter struct {
+	noop.Meter
+
+	mu        sync.Mutex
+	counts    map[string]map[string]int64
+	durations map[string][]float64
+	fail      string
+}
+
+func newMeter() *meter {
+	return &meter{
+		counts:    make(map[string]map[string]int64),
+		durations: make(map[string][]float64),
+	}
+}
+
+func (m *meter) Int64Counter(name string, opts ...metric.Int64CounterOption) (metric.Int64Counter, error) {
+	if name == m.fail {
+		return nil, errors.New("instrument failed")
+	}
+
+	return &counter{name: name, m: m}, nil
+}
+
+func (m *meter) Float64Histogram(name string, opts ...metric.Float64HistogramOption) (metric.Float64Histogram, error) {
+	if name == m.fail {
+		return nil, errors.New("instrument failed")
+	}
+
+	return &histogram{m: m}, nil
+}
+
+func (m *meter) count(name string, method string) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.counts[name][method]
+}
+
+func (m *meter) recorded(method string) []float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.durations[method]
+}
+
+type counter struct {
+	noop.Int64Counter
+	name string
+	m    *meter
+}
+
+func (c *counter) Add(ctx context.Context, incr int64, opts ...metric.AddOption) {
+	method := methodOf(metric.NewAddConfig(opts).Attributes())
+
+	c.m.mu.Lock()
+	defer c.m.mu.Unlock()
+
+	if c.m.counts[c.name] == nil {
+		c.m.counts[c.name] = make(map[string]int64)
+	}
+	c.m.counts[c.name][method] += incr
+}
+
+type histogram struct {
+	noop.Float64Histogram
+	m *meter
+}
+
+func (h *histogram) Record(ctx context.Context, value float64, opts ...metric.RecordOption) {
+	method := methodOf(metric.NewRecordConfig(opts).Attributes())
+
+	h.m.mu.Lock()
+	defer h.m.mu.Unlock()
+
+	h.m.durations[method] = append(h.m.durations[method], value)
+}
+
+func methodOf(set attribute.Set) string {
+	v, _ := set.Value("method")
+	return v.AsString()
+}
+
+// business stands in for the userbus behind the plugin. QueryByID fails for
+// the nil id and takes the delay for every other id.
+type business struct {
+	userbus.Business
+	delay time.Duration
+}
+
+func (b *business) NewWithTx(tx sqldb.CommitRollbacker) (userbus.Business, error) {
+	return b, nil
+}
+
+func (b *business) QueryByID(ctx context.Context, userID uuid.UUID) (userbus.User, error) {
+	if userID == uuid.Nil {
+		return userbus.User{}, userbus.ErrNotFound
+	}
+
+	time.Sleep(b.delay)
+
+	return userbus.User{ID: userID}, nil
+}
+
+func (b *business) CreateBatch(ctx context.Context, actorID uuid.UUID, nus []userbus.NewUser, mode userbus.BatchMode) ([]userbus.User, []userbus.BatchError) {
+	if len(nus) == 0 {
+		return nil, []userbus.BatchError{{Index: 0, Err: userbus.ErrNotFound}}
+	}
+
+	return make([]userbus.User, len(nus)), nil
+}
+
+// =============================================================================
+
+func Test_Metrics(t *testing.T) {
+	const delay = 10 * time.Millisecond
+
+	m := newMeter()
+
+	plugin, err := usermetrics.NewPlugin(usermetrics.Config{Meter: m, Expvar: "usermetrics_test"})
+	if err != nil {
+		t.Fatalf("Should be able to construct the plugin : %s", err)
+	}
+
+	bus := plugin(&business{delay: delay})
+
+	ctx := context.Background()
+
+	if _, err := bus.QueryByID(ctx, uuid.New()); err != nil {
+		t.Fatalf("Should pass on a successful call : %s", err)
+	}
+
+	if _, err := bus.QueryByID(ctx, uuid.Nil); !errors.Is(err, userbus.ErrNotFound) {
+		t.Fatalf("Should pass on the error of a failed call : got %v", err)
+	}
+
+	// A business value with a transaction records into the same instruments.
+	txBus, err := bus.NewWithTx(nil)
+	if err != nil {
+		t.Fatalf("Should be able to construct a business value with a transaction : %s", err)
+	}
+
+	if _, err := txBus.QueryByID(ctx, uuid.New()); err != nil {
+		t.Fatalf("Should pass on a successful call : %s", err)
+	}
+
+	bus.CreateBatch(ctx, uuid.New(), []userbus.NewUser{{}}, userbus.BatchAtomic)
+	bus.CreateBatch(ctx, uuid.New(), nil, userbus.BatchAtomic)
+
+	table := []struct {
+		method string
+		calls  int64
+		errors int64
+	}{
+		{method: "QueryByID", calls: 3, errors: 1},
+		{method: "CreateBatch", calls: 2, errors: 1},
+		{method: "Create", calls: 0, errors: 0},
+	}
+
+	vars := expvar.Get("usermetrics_test").(*expvar.Map)
+
+	for _, tt := range table {
+		t.Run(tt.method, func(t *testing.T) {
+			if got := m.count("userbus.calls", tt.method); got != tt.calls {
+				t.Fatalf("Should count %d calls : got %d", tt.calls, got)
+			}
+
+			if got := m.count("userbus.errors", tt.method); got != tt.errors {
+				t.Fatalf("Should count %d errors : got %d", tt.errors, got)
+			}
+
+			if got := len(m.recorded(tt.method)); int64(got) != tt.calls {
+				t.Fatalf("Should record %d durations : got %d", tt.calls, got)
+			}
+
+			if tt.calls == 0 {
+				if vars.Get(tt.method+".calls") != nil {
+					t.Fatalf("Should not publish a method that wasn't called")
+				}
+				return
+			}
+
+			if got := vars.Get(tt.method + ".calls").(*expvar.Int).Value(); got != tt.calls {
+				t.Fatalf("Should publish %d calls : got %d", tt.calls, got)
+			}
+
+			var errs int64
+			if v := vars.Get(tt.method + ".errors"); v != nil {
+				errs = v.(*expvar.Int).Value()
+			}
+
+			if errs != tt.errors {
+				t.Fatalf("Should publish %d errors : got %d", tt.errors, errs)
+			}
+		})
+	}
+
+	// The successful lookups took the delay, the failed one returned at once.
+	var slow int
+	for _, d := range m.recorded("QueryByID") {
+		if d >= delay.Seconds() {
+			slow++
+		}
+	}
+
+	if slow != 2 {
+		t.Fatalf("Should record the duration of the calls in seconds : got %v", m.recorded("QueryByID"))
+	}
+}
+
+func Test_MetricsInstruments(t *testing.T) {
+	for _, name := range []string{"userbus.calls", "userbus.errors", "userbus.duration"} {
+		t.Run(name, func(t *testing.T) {
+			m := newMeter()
+			m.fail = name
+
+			if _, err := usermetrics.NewPlugin(usermetrics.Config{Meter: m}); err == nil {
+				t.Fatalf("Should fail when the instrument can't be created")
+			}
+		})
+	}
+
+	// The expvar name is taken by a value that isn't a map.
+	expvar.NewInt("usermetrics_test_int")
+
+	if _, err := usermetrics.NewPlugin(usermetrics.Config{Meter: newMeter(), Expvar: "usermetrics_test_int"}); err == nil {
+		t.Fatalf("Should fail when the expvar name isn't a map")
+	}
+
+	// Publishing the same map twice reuses it instead of panicking.
+	for range 2 {
+		if _, err := usermetrics.NewPlugin(usermetrics.Config{Meter: newMeter(), Expvar: "usermetrics_test_twice"}); err != nil {
+			t.Fatalf("Should reuse the published map : %s", err)
+		}
+	}
+}
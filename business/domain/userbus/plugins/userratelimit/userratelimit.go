@@ -0,0 +1,322 @@
+// Package userratelimit provides a plugin for userbus that limits the rate
+// of authentication attempts to protect against brute force guessing.
+package userratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/google/uuid"
+)
+
+// Bucket defines the behavior required to limit the rate of attempts by
+// key with token bucket semantics. A zero duration means the attempt is
+// allowed, otherwise it's how long the key must wait.
+type Bucket interface {
+	Take(ctx context.Context, key string) (time.Duration, error)
+}
+
+// Config represents the buckets attempts are counted in. A nil bucket
+// disables limiting by that key. Password changes are counted in the email
+// bucket keyed by user since the email isn't provided.
+type Config struct {
+	Email Bucket
+	IP    Bucket
+}
+
+// Plugin provides a wrapper for rate limiting functionality around the
+// userbus. Every authentication attempt takes a token for the email and,
+// when the context carries one, for the source IP. An attempt is rejected
+// with ErrTooManyAttempts once either bucket is empty, before the password
+// is checked.
+type Plugin struct {
+	log *logger.Logger
+	bus userbus.Business
+	cfg Config
+}
+
+// NewPlugin constructs a new plugin that wraps the userbus with rate
+// limiting.
+func NewPlugin(log *logger.Logger, cfg Config) userbus.Plugin {
+	return func(bus userbus.Business) userbus.Business {
+		return &Plugin{
+			log: log,
+			bus: bus,
+			cfg: cfg,
+		}
+	}
+}
+
+// NewWithTx constructs a new business value that will use the
+// specified transaction in any store related calls. The new value is
+// wrapped with rate limiting as well.
+func (p *Plugin) NewWithTx(tx sqldb.CommitRollbacker) (userbus.Business, error) {
+	bus, err := p.bus.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	plugin := Plugin{
+		log: p.log,
+		bus: bus,
+		cfg: p.cfg,
+	}
+
+	return &plugin, nil
+}
+
+// Authenticate finds a user by their email and verifies their password.
+func (p *Plugin) Authenticate(ctx context.Context, email mail.Address, password string) (userbus.User, error) {
+	if err := p.limit(ctx, email); err != nil {
+		return userbus.User{}, err
+	}
+
+	return p.bus.Authenticate(ctx, email, password)
+}
+
+// AuthenticateWithTOTP finds a user by their email and verifies their
+// password and one-time code.
+func (p *Plugin) AuthenticateWithTOTP(ctx context.Context, email mail.Address, password string, code string) (userbus.User, error) {
+	if err := p.limit(ctx, email); err != nil {
+		return userbus.User{}, err
+	}
+
+	return p.bus.AuthenticateWithTOTP(ctx, email, password, code)
+}
+
+// Create adds a new user to the system.
+func (p *Plugin) Create(ctx context.Context, actorID uuid.UUID, nu userbus.NewUser) (userbus.User, bool, error) {
+	return p.bus.Create(ctx, actorID, nu)
+}
+
+// CreateOrGet adds a new user to the system or returns the user that
+// already has the email.
+func (p *Plugin) CreateOrGet(ctx context.Context, actorID uuid.UUID, nu userbus.NewUser) (userbus.User, bool, error) {
+	return p.bus.CreateOrGet(ctx, actorID, nu)
+}
+
+// CreateBatch adds a set of new users to the system.
+func (p *Plugin) CreateBatch(ctx context.Context, actorID uuid.UUID, nus []userbus.NewUser, mode userbus.BatchMode) ([]userbus.User, []userbus.BatchError) {
+	return p.bus.CreateBatch(ctx, actorID, nus, mode)
+}
+
+// Update modifies information about a user.
+func (p *Plugin) Update(ctx context.Context, actorID uuid.UUID, usr userbus.User, uu userbus.UpdateUser) (userbus.User, error) {
+	return p.bus.Update(ctx, actorID, usr, uu)
+}
+
+// Delete removes the specified user.
+func (p *Plugin) Delete(ctx context.Context, actorID uuid.UUID, usr userbus.User) error {
+	return p.bus.Delete(ctx, actorID, usr)
+}
+
+// Anonymize scrubs the personal data of the user.
+func (p *Plugin) Anonymize(ctx context.Context, actorID uuid.UUID, userID uuid.UUID) (userbus.User, error) {
+	return p.bus.Anonymize(ctx, actorID, userID)
+}
+
+// Query retrieves a list of existing users.
+func (p *Plugin) Query(ctx context.Context, filter userbus.QueryFilter, orderBy order.By, page page.Page) ([]userbus.User, error) {
+	return p.bus.Query(ctx, filter, orderBy, page)
+}
+
+// QueryWithCount retrieves a list of existing users along with the number
+// of users that match the filter.
+func (p *Plugin) QueryWithCount(ctx context.Context, filter userbus.QueryFilter, orderBy order.By, page page.Page) ([]userbus.User, int, error) {
+	return p.bus.QueryWithCount(ctx, filter, orderBy, page)
+}
+
+// QueryAll streams every user that matches the filter.
+func (p *Plugin) QueryAll(ctx context.Context, filter userbus.QueryFilter, orderBy order.By, fn func(userbus.User) error) error {
+	return p.bus.QueryAll(ctx, filter, orderBy, fn)
+}
+
+// Count returns the total number of users.
+func (p *Plugin) Count(ctx context.Context, filter userbus.QueryFilter) (int, error) {
+	return p.bus.Count(ctx, filter)
+}
+
+// CountEstimate returns the number of users, which may not be exact.
+func (p *Plugin) CountEstimate(ctx context.Context, filter userbus.QueryFilter) (int, bool, error) {
+	return p.bus.CountEstimate(ctx, filter)
+}
+
+// QueryByID finds the user by the specified ID.
+func (p *Plugin) QueryByID(ctx context.Context, userID uuid.UUID) (userbus.User, error) {
+	return p.bus.QueryByID(ctx, userID)
+}
+
+// QueryByIDs finds the users by the specified IDs.
+func (p *Plugin) QueryByIDs(ctx context.Context, userIDs []uuid.UUID) ([]userbus.User, error) {
+	return p.bus.QueryByIDs(ctx, userIDs)
+}
+
+// QueryByEmail finds the user by a specified user email.
+func (p *Plugin) QueryByEmail(ctx context.Context, email mail.Address) (userbus.User, error) {
+	return p.bus.QueryByEmail(ctx, email)
+}
+
+// EnrollTOTP generates a new one-time code secret for the user.
+func (p *Plugin) EnrollTOTP(ctx context.Context, userID uuid.UUID) (string, string, error) {
+	return p.bus.EnrollTOTP(ctx, userID)
+}
+
+// ConfirmTOTP enables one-time codes for the user and returns their
+// recovery codes.
+func (p *Plugin) ConfirmTOTP(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	return p.bus.ConfirmTOTP(ctx, userID, code)
+}
+
+// DisableTOTP turns off one-time codes for the user.
+func (p *Plugin) DisableTOTP(ctx context.Context, userID uuid.UUID) error {
+	return p.bus.DisableTOTP(ctx, userID)
+}
+
+// ChangePassword replaces the user's password after verifying the current
+// one. Attempts are counted per user so a stolen access token can't be used
+// to guess the current password.
+func (p *Plugin) ChangePassword(ctx context.Context, userID uuid.UUID, currentPassword string, newPassword string) error {
+	if err := p.limitUser(ctx, userID); err != nil {
+		return err
+	}
+
+	return p.bus.ChangePassword(ctx, userID, currentPassword, newPassword)
+}
+
+// DirectReports returns the users that report directly to the user.
+func (p *Plugin) DirectReports(ctx context.Context, userID uuid.UUID) ([]userbus.User, error) {
+	return p.bus.DirectReports(ctx, userID)
+}
+
+// ManagementChain returns the managers above the user.
+func (p *Plugin) ManagementChain(ctx context.Context, userID uuid.UUID) ([]userbus.User, error) {
+	return p.bus.ManagementChain(ctx, userID)
+}
+
+// FederateLogin finds, links or creates the user for an external identity.
+func (p *Plugin) FederateLogin(ctx context.Context, provider string, externalID string, email mail.Address, profile userbus.Profile) (userbus.User, bool, error) {
+	return p.bus.FederateLogin(ctx, provider, externalID, email, profile)
+}
+
+// AssignRolesByFilter previews a change of roles for the users that match
+// the filter. Only an admin can assign roles.
+func (p *Plugin) AssignRolesByFilter(ctx context.Context, actorID uuid.UUID, filter userbus.QueryFilter, addRoles []role.Role, removeRoles []role.Role) (userbus.RoleAssignment, error) {
+	return p.bus.AssignRolesByFilter(ctx, actorID, filter, addRoles, removeRoles)
+}
+
+// ApplyRoleAssignment changes the roles of the users in a previewed
+// assignment. Only an admin can apply an assignment.
+func (p *Plugin) ApplyRoleAssignment(ctx context.Context, actorID uuid.UUID, assignmentID uuid.UUID) (userbus.RoleAssignment, string, error) {
+	return p.bus.ApplyRoleAssignment(ctx, actorID, assignmentID)
+}
+
+// RevertRoleAssignment puts back the roles changed by an assignment. Only
+// an admin can revert an assignment.
+func (p *Plugin) RevertRoleAssignment(ctx context.Context, actorID uuid.UUID, token string) (userbus.RoleAssignment, error) {
+	return p.bus.RevertRoleAssignment(ctx, actorID, token)
+}
+
+// PurgeIdempotencyKeys removes the idempotency keys older than the TTL. It
+// is run by the service itself, not on behalf of an actor.
+func (p *Plugin) PurgeIdempotencyKeys(ctx context.Context) (int, error) {
+	return p.bus.PurgeIdempotencyKeys(ctx)
+}
+
+// NormalizeNames rewrites the users whose stored name isn't normalized. It
+// is run by the service itself, not on behalf of an actor.
+func (p *Plugin) NormalizeNames(ctx context.Context) (int, error) {
+	return p.bus.NormalizeNames(ctx)
+}
+
+// SetPreference saves a preference for the user. The app layer checks
+// the actor is the user or an admin.
+func (p *Plugin) SetPreference(ctx context.Context, userID uuid.UUID, key string, value json.RawMessage) (userbus.Preference, error) {
+	return p.bus.SetPreference(ctx, userID, key, value)
+}
+
+// GetPreferences returns every preference for the user.
+func (p *Plugin) GetPreferences(ctx context.Context, userID uuid.UUID) ([]userbus.Preference, error) {
+	return p.bus.GetPreferences(ctx, userID)
+}
+
+// DeletePreference puts the user's preference back to its default.
+func (p *Plugin) DeletePreference(ctx context.Context, userID uuid.UUID, key string) error {
+	return p.bus.DeletePreference(ctx, userID, key)
+}
+
+// UpdateAvatar replaces the user's avatar.
+func (p *Plugin) UpdateAvatar(ctx context.Context, actorID uuid.UUID, userID uuid.UUID, r io.Reader, contentType string) (userbus.User, error) {
+	return p.bus.UpdateAvatar(ctx, actorID, userID, r, contentType)
+}
+
+// AvatarURL returns the address the user's avatar can be read from.
+func (p *Plugin) AvatarURL(ctx context.Context, usr userbus.User) (string, error) {
+	return p.bus.AvatarURL(ctx, usr)
+}
+
+// ExportData gathers what the system holds about the user.
+func (p *Plugin) ExportData(ctx context.Context, actorID uuid.UUID, userID uuid.UUID) (io.Reader, error) {
+	return p.bus.ExportData(ctx, actorID, userID)
+}
+
+// DisableDormant disables the users that haven't logged in for the
+// specified duration.
+func (p *Plugin) DisableDormant(ctx context.Context, inactiveFor time.Duration) ([]userbus.User, error) {
+	return p.bus.DisableDormant(ctx, inactiveFor)
+}
+
+// =============================================================================
+
+// limit takes a token for the source IP and the email of the attempt. The
+// IP is checked first so an address guessing many accounts doesn't use up
+// the tokens of the accounts it tries. A bucket that can't be reached is
+// logged and the attempt allowed, so an outage of the backing store
+// doesn't stop everyone from logging in.
+func (p *Plugin) limit(ctx context.Context, email mail.Address) error {
+	if ip := userbus.SourceIP(ctx); ip != "" {
+		if err := p.take(ctx, p.cfg.IP, "ip:"+ip); err != nil {
+			return err
+		}
+	}
+
+	return p.take(ctx, p.cfg.Email, "email:"+strings.ToLower(email.Address))
+}
+
+func (p *Plugin) limitUser(ctx context.Context, userID uuid.UUID) error {
+	if ip := userbus.SourceIP(ctx); ip != "" {
+		if err := p.take(ctx, p.cfg.IP, "ip:"+ip); err != nil {
+			return err
+		}
+	}
+
+	return p.take(ctx, p.cfg.Email, "user:"+userID.String())
+}
+
+func (p *Plugin) take(ctx context.Context, bucket Bucket, key string) error {
+	if bucket == nil {
+		return nil
+	}
+
+	wait, err := bucket.Take(ctx, key)
+	if err != nil {
+		p.log.Error(ctx, "userratelimit: take", "key", key, "ERROR", err)
+		return nil
+	}
+
+	if wait > 0 {
+		return fmt.Errorf("authenticate: %w", &userbus.AttemptsError{Key: key, RetryAfter: wait})
+	}
+
+	return nil
+}
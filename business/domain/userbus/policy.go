@@ -0,0 +1,150 @@
+package userbus
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ardanlabs/service/business/sdk/buserr"
+)
+
+// ErrPasswordPolicy is returned when a password doesn't satisfy the
+// configured password policy. Use errors.As with a *PasswordPolicyError to
+// find out which rules failed.
+var ErrPasswordPolicy = buserr.New(buserr.InvalidArgument, "password does not satisfy the password policy")
+
+// Set of rules a password policy can enforce.
+const (
+	RuleMinLength = "min_length"
+	RuleUpper     = "upper"
+	RuleLower     = "lower"
+	RuleDigit     = "digit"
+	RuleSymbol    = "symbol"
+	RuleBanned    = "banned"
+	RuleReused    = "reused"
+	RuleBreached  = "breached"
+)
+
+// PasswordPolicyError provides the set of rules a password failed.
+type PasswordPolicyError struct {
+	Rules []string
+}
+
+// Error implements the error interface.
+func (ppe *PasswordPolicyError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrPasswordPolicy, strings.Join(ppe.Rules, ","))
+}
+
+// Unwrap returns ErrPasswordPolicy with a violation of the password field
+// for each rule that failed, so errors.Is matches the error against
+// ErrPasswordPolicy.
+func (ppe *PasswordPolicyError) Unwrap() error {
+	fields := make([]buserr.FieldViolation, len(ppe.Rules))
+	for i, rule := range ppe.Rules {
+		fields[i] = buserr.FieldViolation{
+			Field:   "password",
+			Message: rule,
+		}
+	}
+
+	return ErrPasswordPolicy.WithFields(fields...)
+}
+
+// =============================================================================
+
+// BreachChecker represents a service that knows if a password has appeared
+// in a known data breach.
+type BreachChecker interface {
+	Breached(ctx context.Context, password string) (bool, error)
+}
+
+// PasswordPolicy represents the rules a password must satisfy. The zero
+// value enforces no rules.
+type PasswordPolicy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	Banned        []string
+	History       int
+	Breach        BreachChecker
+}
+
+// Check validates the password against the policy rules that don't require
+// knowledge of the user's previous passwords or a call to the breach checker.
+func (pp PasswordPolicy) Check(password string) error {
+	if rules := pp.check(password); len(rules) > 0 {
+		return &PasswordPolicyError{Rules: rules}
+	}
+
+	return nil
+}
+
+// checkContext validates the password against the same rules as Check along
+// with the breach checker when one is configured.
+func (pp PasswordPolicy) checkContext(ctx context.Context, password string) ([]string, error) {
+	rules := pp.check(password)
+
+	if pp.Breach != nil {
+		breached, err := pp.Breach.Breached(ctx, password)
+		if err != nil {
+			return nil, fmt.Errorf("breached: %w", err)
+		}
+
+		if breached {
+			rules = append(rules, RuleBreached)
+		}
+	}
+
+	return rules, nil
+}
+
+func (pp PasswordPolicy) check(password string) []string {
+	var rules []string
+
+	if utf8.RuneCountInString(password) < pp.MinLength {
+		rules = append(rules, RuleMinLength)
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			symbol = true
+		}
+	}
+
+	if pp.RequireUpper && !upper {
+		rules = append(rules, RuleUpper)
+	}
+
+	if pp.RequireLower && !lower {
+		rules = append(rules, RuleLower)
+	}
+
+	if pp.RequireDigit && !digit {
+		rules = append(rules, RuleDigit)
+	}
+
+	if pp.RequireSymbol && !symbol {
+		rules = append(rules, RuleSymbol)
+	}
+
+	for _, banned := range pp.Banned {
+		if strings.EqualFold(password, banned) {
+			rules = append(rules, RuleBanned)
+			break
+		}
+	}
+
+	return rules
+}
@@ -0,0 +1,201 @@
+package userbus
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type breachChecker struct {
+	breached map[string]bool
+	err      error
+}
+
+func (bc breachChecker) Breached(ctx context.Context, password string) (bool, error) {
+	return bc.breached[password], bc.err
+}
+
+// historyStorer returns the same password history for every user.
+type historyStorer struct {
+	Storer
+	hashes [][]byte
+	limit  int
+}
+
+func (s *historyStorer) QueryPasswordHistory(ctx context.Context, userID uuid.UUID, limit int) ([][]byte, error) {
+	s.limit = limit
+	return s.hashes, nil
+}
+
+// =============================================================================
+
+func Test_PolicyCheck(t *testing.T) {
+	table := []struct {
+		name     string
+		policy   PasswordPolicy
+		password string
+		rules    []string
+	}{
+		{name: "zero", policy: PasswordPolicy{}, password: "", rules: nil},
+		{name: "minlength", policy: PasswordPolicy{MinLength: 8}, password: "short", rules: []string{RuleMinLength}},
+		{name: "minlengthexact", policy: PasswordPolicy{MinLength: 8}, password: "eightchr", rules: nil},
+		{name: "minlengthrunes", policy: PasswordPolicy{MinLength: 8}, password: "ééééééé", rules: []string{RuleMinLength}},
+		{name: "minlengthmultibyte", policy: PasswordPolicy{MinLength: 8}, password: "éééééééé", rules: nil},
+		{name: "upper", policy: PasswordPolicy{RequireUpper: true}, password: "lower1!", rules: []string{RuleUpper}},
+		{name: "upperok", policy: PasswordPolicy{RequireUpper: true}, password: "Upper", rules: nil},
+		{name: "upperunicode", policy: PasswordPolicy{RequireUpper: true}, password: "Élan", rules: nil},
+		{name: "lower", policy: PasswordPolicy{RequireLower: true}, password: "UPPER1!", rules: []string{RuleLower}},
+		{name: "lowerok", policy: PasswordPolicy{RequireLower: true}, password: "lower", rules: nil},
+		{name: "digit", policy: PasswordPolicy{RequireDigit: true}, password: "nodigits", rules: []string{RuleDigit}},
+		{name: "digitok", policy: PasswordPolicy{RequireDigit: true}, password: "d1git", rules: nil},
+		{name: "symbol", policy: PasswordPolicy{RequireSymbol: true}, password: "nosymbol1", rules: []string{RuleSymbol}},
+		{name: "symbolpunct", policy: PasswordPolicy{RequireSymbol: true}, password: "punct!", rules: nil},
+		{name: "symbolsymbol", policy: PasswordPolicy{RequireSymbol: true}, password: "plus+", rules: nil},
+		{name: "banned", policy: PasswordPolicy{Banned: []string{"password", "letmein"}}, password: "letmein", rules: []string{RuleBanned}},
+		{name: "bannedcase", policy: PasswordPolicy{Banned: []string{"password"}}, password: "PassWord", rules: []string{RuleBanned}},
+		{name: "bannedsubstring", policy: PasswordPolicy{Banned: []string{"password"}}, password: "password1", rules: nil},
+		{
+			name:     "all",
+			policy:   PasswordPolicy{MinLength: 8, RequireUpper: true, RequireLower: true, RequireDigit: true, RequireSymbol: true, Banned: []string{"abc"}},
+			password: "abc",
+			rules:    []string{RuleMinLength, RuleUpper, RuleDigit, RuleSymbol, RuleBanned},
+		},
+		{
+			name:     "strong",
+			policy:   PasswordPolicy{MinLength: 8, RequireUpper: true, RequireLower: true, RequireDigit: true, RequireSymbol: true, Banned: []string{"password"}},
+			password: "G0pher!sFun",
+			rules:    nil,
+		},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Check(tt.password)
+
+			if tt.rules == nil {
+				if err != nil {
+					t.Fatalf("Should satisfy the policy : %s", err)
+				}
+				return
+			}
+
+			var ppe *PasswordPolicyError
+			if !errors.As(err, &ppe) {
+				t.Fatalf("Should return a PasswordPolicyError : got %v", err)
+			}
+
+			if !errors.Is(err, ErrPasswordPolicy) {
+				t.Fatalf("Should match ErrPasswordPolicy : got %v", err)
+			}
+
+			if diff := cmp.Diff(ppe.Rules, tt.rules); diff != "" {
+				t.Fatalf("Should fail the expected rules : %s", diff)
+			}
+		})
+	}
+}
+
+func Test_PolicyBreached(t *testing.T) {
+	errService := errors.New("service unavailable")
+
+	table := []struct {
+		name     string
+		breach   BreachChecker
+		password string
+		rules    []string
+		err      error
+	}{
+		{name: "nochecker", breach: nil, password: "hunter2", rules: nil},
+		{name: "breached", breach: breachChecker{breached: map[string]bool{"hunter2": true}}, password: "hunter2", rules: []string{RuleBreached}},
+		{name: "clean", breach: breachChecker{breached: map[string]bool{"hunter2": true}}, password: "G0pher!sFun", rules: nil},
+		{name: "error", breach: breachChecker{err: errService}, password: "hunter2", err: errService},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			pp := PasswordPolicy{MinLength: 4, Breach: tt.breach}
+
+			rules, err := pp.checkContext(context.Background(), tt.password)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Should return error %v : got %v", tt.err, err)
+			}
+
+			if diff := cmp.Diff(rules, tt.rules); diff != "" {
+				t.Fatalf("Should fail the expected rules : %s", diff)
+			}
+
+			// Check never calls the breach checker.
+			if err := pp.Check(tt.password); err != nil {
+				t.Fatalf("Should not check for breaches : %s", err)
+			}
+		})
+	}
+}
+
+func Test_PolicyHistory(t *testing.T) {
+	hasher := NewBcryptHasher(bcrypt.MinCost)
+
+	hash := func(password string) []byte {
+		h, err := hasher.Hash(password)
+		if err != nil {
+			t.Fatalf("Should be able to hash the password : %s", err)
+		}
+		return h
+	}
+
+	current := hash("current")
+	history := [][]byte{hash("previous1"), hash("previous2")}
+
+	table := []struct {
+		name     string
+		history  int
+		password string
+		rules    []string
+		limit    int
+	}{
+		{name: "new", history: 5, password: "brandnew", rules: nil, limit: 5},
+		{name: "current", history: 5, password: "current", rules: []string{RuleReused}, limit: 5},
+		{name: "previous", history: 5, password: "previous2", rules: []string{RuleReused}, limit: 5},
+		{name: "disabled", history: 0, password: "current", rules: nil, limit: 0},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			storer := historyStorer{hashes: history}
+
+			b := business{
+				policy: PasswordPolicy{History: tt.history},
+				hasher: hasher,
+				storer: &storer,
+			}
+
+			usr := User{ID: uuid.New(), PasswordHash: current}
+
+			err := b.checkPassword(context.Background(), usr, tt.password)
+
+			if storer.limit != tt.limit {
+				t.Fatalf("Should query %d passwords of history : got %d", tt.limit, storer.limit)
+			}
+
+			if tt.rules == nil {
+				if err != nil {
+					t.Fatalf("Should accept the password : %s", err)
+				}
+				return
+			}
+
+			var ppe *PasswordPolicyError
+			if !errors.As(err, &ppe) {
+				t.Fatalf("Should return a PasswordPolicyError : got %v", err)
+			}
+
+			if diff := cmp.Diff(ppe.Rules, tt.rules); diff != "" {
+				t.Fatalf("Should fail the expected rules : %s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package userbus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ardanlabs/service/business/sdk/buserr"
+	"github.com/ardanlabs/service/foundation/clock"
+	"github.com/ardanlabs/service/foundation/otel"
+	"github.com/google/uuid"
+	"golang.org/x/text/language"
+)
+
+// Set of error variables for preferences.
+var (
+	ErrUnknownPreference = buserr.New(buserr.NotFound, "unknown preference")
+	ErrInvalidPreference = buserr.New(buserr.InvalidArgument, "invalid preference value")
+)
+
+// Preference represents a setting the user has chosen. The value is JSON of
+// the type the preference's key calls for.
+type Preference struct {
+	UserID      uuid.UUID
+	Key         string
+	Value       json.RawMessage
+	DateUpdated time.Time
+}
+
+// =============================================================================
+
+// preferenceSpec describes a preference the system knows about. The check
+// returns the value in the form it's stored in.
+type preferenceSpec struct {
+	def   json.RawMessage
+	check func(json.RawMessage) (any, error)
+}
+
+// preferences are the preferences a user can set. A preference that isn't
+// set has its default value.
+var preferences = map[string]preferenceSpec{
+	"theme":              {json.RawMessage(`"system"`), oneOf("system", "light", "dark")},
+	"locale":             {json.RawMessage(`"en"`), locale},
+	"timezone":           {json.RawMessage(`"UTC"`), timezone},
+	"emailNotifications": {json.RawMessage(`true`), boolean},
+	"pageSize":           {json.RawMessage(`10`), intRange(1, 100)},
+}
+
+func oneOf(values ...string) func(json.RawMessage) (any, error) {
+	return func(data json.RawMessage) (any, error) {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil || !slices.Contains(values, s) {
+			return nil, fmt.Errorf("must be one of %s", strings.Join(values, ", "))
+		}
+		return s, nil
+	}
+}
+
+func intRange(min int, max int) func(json.RawMessage) (any, error) {
+	return func(data json.RawMessage) (any, error) {
+		var n int
+		if err := json.Unmarshal(data, &n); err != nil || n < min || n > max {
+			return nil, fmt.Errorf("must be a whole number from %d to %d", min, max)
+		}
+		return n, nil
+	}
+}
+
+func boolean(data json.RawMessage) (any, error) {
+	var b bool
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, errors.New("must be true or false")
+	}
+	return b, nil
+}
+
+func locale(data json.RawMessage) (any, error) {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, errors.New("must be a language tag like en-US")
+	}
+
+	tag, err := language.Parse(s)
+	if err != nil {
+		return nil, errors.New("must be a language tag like en-US")
+	}
+
+	return tag.String(), nil
+}
+
+func timezone(data json.RawMessage) (any, error) {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil || s == "" || strings.EqualFold(s, "local") {
+		return nil, errors.New("must be an IANA time zone like America/New_York")
+	}
+
+	if _, err := time.LoadLocation(s); err != nil {
+		return nil, errors.New("must be an IANA time zone like America/New_York")
+	}
+
+	return s, nil
+}
+
+// =============================================================================
+
+// SetPreference validates the value against the type the preference calls
+// for and saves it for the user.
+func (b *business) SetPreference(ctx context.Context, userID uuid.UUID, key string, value json.RawMessage) (Preference, error) {
+	ctx, span := otel.AddSpan(ctx, "business.userbus.setpreference")
+	defer span.End()
+
+	spec, ok := preferences[key]
+	if !ok {
+		return Preference{}, fmt.Errorf("key[%s]: %w", key, ErrUnknownPreference)
+	}
+
+	v, err := spec.check(value)
+	if err != nil {
+		return Preference{}, fmt.Errorf("key[%s]: %s: %w", key, err, ErrInvalidPreference)
+	}
+
+	// The value is stored the way it was checked, so "en-us" is kept as
+	// "en-US".
+	data, err := json.Marshal(v)
+	if err != nil {
+		return Preference{}, fmt.Errorf("marshal: key[%s]: %w", key, err)
+	}
+
+	if _, err := b.QueryByID(ctx, userID); err != nil {
+		return Preference{}, err
+	}
+
+	pref := Preference{
+		UserID:      userID,
+		Key:         key,
+		Value:       data,
+		DateUpdated: clock.Now(),
+	}
+
+	if err := b.storer.SetPreference(ctx, pref); err != nil {
+		return Preference{}, fmt.Errorf("setpreference: key[%s]: %w", key, err)
+	}
+
+	return pref, nil
+}
+
+// GetPreferences returns every preference for the user sorted by key. The
+// preferences the user hasn't set have their default value and a zero
+// DateUpdated.
+func (b *business) GetPreferences(ctx context.Context, userID uuid.UUID) ([]Preference, error) {
+	ctx, span := otel.AddSpan(ctx, "business.userbus.getpreferences")
+	defer span.End()
+
+	stored, err := b.storer.QueryPreferences(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("querypreferences: userID[%s]: %w", userID, err)
+	}
+
+	prefs := make([]Preference, 0, len(preferences))
+
+	for key, spec := range preferences {
+		pref := Preference{
+			UserID: userID,
+			Key:    key,
+			Value:  spec.def,
+		}
+
+		// A stored value the current rules reject, like a time zone that
+		// was removed, falls back to the default.
+		if i := slices.IndexFunc(stored, func(p Preference) bool { return p.Key == key }); i >= 0 {
+			if _, err := spec.check(stored[i].Value); err == nil {
+				pref = stored[i]
+			}
+		}
+
+		prefs = append(prefs, pref)
+	}
+
+	slices.SortFunc(prefs, func(a, b Preference) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+
+	return prefs, nil
+}
+
+// DeletePreference removes the value the user set for the preference so it
+// goes back to its default. Deleting a preference that isn't set does
+// nothing.
+func (b *business) DeletePreference(ctx context.Context, userID uuid.UUID, key string) error {
+	ctx, span := otel.AddSpan(ctx, "business.userbus.deletepreference")
+	defer span.End()
+
+	if _, ok := preferences[key]; !ok {
+		return fmt.Errorf("key[%s]: %w", key, ErrUnknownPreference)
+	}
+
+	if err := b.storer.DeletePreference(ctx, userID, key); err != nil {
+		return fmt.Errorf("deletepreference: key[%s]: %w", key, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,305 @@
+package userbus
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/ardanlabs/service/business/sdk/buserr"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/ardanlabs/service/foundation/clock"
+	"github.com/ardanlabs/service/foundation/otel"
+	"github.com/google/uuid"
+)
+
+// Set of error variables for role assignments.
+var (
+	ErrAssignmentApplied  = buserr.New(buserr.Conflict, "role assignment already applied")
+	ErrAssignmentReverted = buserr.New(buserr.Conflict, "role assignment already reverted")
+	ErrAssignmentExpired  = buserr.New(buserr.FailedPrecondition, "role assignment expired")
+	ErrAssignmentEmpty    = buserr.New(buserr.InvalidArgument, "role assignment changes no roles")
+)
+
+const (
+	// assignmentPreviewTTL is how long a preview can be applied for before
+	// it has to be previewed again.
+	assignmentPreviewTTL = 15 * time.Minute
+
+	// assignmentRevertWindow is how long an applied assignment can be
+	// reverted for.
+	assignmentRevertWindow = 24 * time.Hour
+
+	// assignmentSampleSize is the number of changes returned by a preview.
+	assignmentSampleSize = 10
+
+	// assignmentBatchSize is the number of users changed per batch.
+	assignmentBatchSize = 100
+)
+
+// AssignRolesByFilter previews adding and removing the roles for every user
+// that matches the filter. Nothing is changed until the assignment is
+// applied, and only the users in the preview are changed then. The number of
+// users affected is returned along with a sample of the changes.
+func (b *business) AssignRolesByFilter(ctx context.Context, actorID uuid.UUID, filter QueryFilter, addRoles []role.Role, removeRoles []role.Role) (RoleAssignment, error) {
+	ctx, span := otel.AddSpan(ctx, "business.userbus.assignrolesbyfilter")
+	defer span.End()
+
+	if err := b.checkActor(ctx, actorID); err != nil {
+		return RoleAssignment{}, err
+	}
+
+	if len(addRoles) == 0 && len(removeRoles) == 0 {
+		return RoleAssignment{}, ErrAssignmentEmpty
+	}
+
+	var chgs []RoleChange
+
+	f := func(usr User) error {
+		newRoles := assignRoles(usr.Roles, addRoles, removeRoles)
+		if slices.EqualFunc(usr.Roles, newRoles, role.Role.Equal) {
+			return nil
+		}
+
+		chgs = append(chgs, RoleChange{
+			UserID:   usr.ID,
+			OldRoles: usr.Roles,
+			NewRoles: newRoles,
+		})
+
+		return nil
+	}
+
+	if err := b.storer.QueryAll(ctx, filter, DefaultOrderBy, f); err != nil {
+		return RoleAssignment{}, fmt.Errorf("queryall: %w", err)
+	}
+
+	ra := RoleAssignment{
+		ID:          uuid.New(),
+		ActorID:     actorID,
+		AddRoles:    addRoles,
+		RemoveRoles: removeRoles,
+		Affected:    len(chgs),
+		DateCreated: clock.Now(),
+	}
+
+	if err := b.storer.CreateRoleAssignment(ctx, ra, chgs); err != nil {
+		return RoleAssignment{}, fmt.Errorf("createroleassignment: %w", err)
+	}
+
+	ra.Sample = chgs[:min(len(chgs), assignmentSampleSize)]
+
+	return ra, nil
+}
+
+// ApplyRoleAssignment changes the roles of the users in a previewed
+// assignment in batches. A user whose roles changed since the preview is
+// left alone. The number of users changed is recorded and a token that can
+// revert the assignment is returned, which is the only time it's available.
+func (b *business) ApplyRoleAssignment(ctx context.Context, actorID uuid.UUID, assignmentID uuid.UUID) (RoleAssignment, string, error) {
+	ctx, span := otel.AddSpan(ctx, "business.userbus.applyroleassignment")
+	defer span.End()
+
+	if err := b.checkActor(ctx, actorID); err != nil {
+		return RoleAssignment{}, "", err
+	}
+
+	ra, err := b.storer.QueryRoleAssignment(ctx, assignmentID)
+	if err != nil {
+		return RoleAssignment{}, "", fmt.Errorf("queryroleassignment: assignmentID[%s]: %w", assignmentID, err)
+	}
+
+	now := clock.Now()
+
+	switch {
+	case ra.Applied():
+		return RoleAssignment{}, "", fmt.Errorf("assignmentID[%s]: %w", ra.ID, ErrAssignmentApplied)
+	case now.Sub(ra.DateCreated) > assignmentPreviewTTL:
+		return RoleAssignment{}, "", fmt.Errorf("assignmentID[%s]: %w", ra.ID, ErrAssignmentExpired)
+	}
+
+	chgs, err := b.storer.QueryRoleChanges(ctx, ra.ID)
+	if err != nil {
+		return RoleAssignment{}, "", fmt.Errorf("queryrolechanges: assignmentID[%s]: %w", ra.ID, err)
+	}
+
+	changed, err := b.changeRoles(ctx, actorID, chgs, false)
+	if err != nil {
+		return RoleAssignment{}, "", err
+	}
+
+	token, err := generateRevertToken()
+	if err != nil {
+		return RoleAssignment{}, "", err
+	}
+
+	ra.Affected = len(changed)
+	ra.RevertHash = hashRevertToken(token)
+	ra.DateApplied = clock.Now()
+
+	if err := b.storer.UpdateRoleAssignment(ctx, ra); err != nil {
+		return RoleAssignment{}, "", fmt.Errorf("updateroleassignment: %w", err)
+	}
+
+	ra.Changed = changed
+
+	return ra, token, nil
+}
+
+// RevertRoleAssignment puts back the roles the users had before the
+// assignment identified by the revert token was applied. A user whose roles
+// changed again since is left alone.
+func (b *business) RevertRoleAssignment(ctx context.Context, actorID uuid.UUID, token string) (RoleAssignment, error) {
+	ctx, span := otel.AddSpan(ctx, "business.userbus.revertroleassignment")
+	defer span.End()
+
+	if err := b.checkActor(ctx, actorID); err != nil {
+		return RoleAssignment{}, err
+	}
+
+	ra, err := b.storer.QueryRoleAssignmentByRevertHash(ctx, hashRevertToken(token))
+	if err != nil {
+		return RoleAssignment{}, fmt.Errorf("queryroleassignmentbyreverthash: %w", err)
+	}
+
+	now := clock.Now()
+
+	switch {
+	case ra.Reverted():
+		return RoleAssignment{}, fmt.Errorf("assignmentID[%s]: %w", ra.ID, ErrAssignmentReverted)
+	case now.Sub(ra.DateApplied) > assignmentRevertWindow:
+		return RoleAssignment{}, fmt.Errorf("assignmentID[%s]: %w", ra.ID, ErrAssignmentExpired)
+	}
+
+	chgs, err := b.storer.QueryRoleChanges(ctx, ra.ID)
+	if err != nil {
+		return RoleAssignment{}, fmt.Errorf("queryrolechanges: assignmentID[%s]: %w", ra.ID, err)
+	}
+
+	changed, err := b.changeRoles(ctx, actorID, chgs, true)
+	if err != nil {
+		return RoleAssignment{}, err
+	}
+
+	ra.DateReverted = clock.Now()
+
+	if err := b.storer.UpdateRoleAssignment(ctx, ra); err != nil {
+		return RoleAssignment{}, fmt.Errorf("updateroleassignment: %w", err)
+	}
+
+	ra.Changed = changed
+
+	return ra, nil
+}
+
+// =============================================================================
+
+// changeRoles moves the users from their old roles to their new ones, or
+// back when reverting, one batch at a time. Only the users that still have
+// the roles being moved from, and that the registered update rules allow to
+// change, are changed. The changes made are returned from the roles the users
+// had to the roles they got.
+func (b *business) changeRoles(ctx context.Context, actorID uuid.UUID, chgs []RoleChange, revert bool) ([]RoleChange, error) {
+	var changed []RoleChange
+
+	for batch := range slices.Chunk(chgs, assignmentBatchSize) {
+		ids := make([]uuid.UUID, len(batch))
+		for i, chg := range batch {
+			ids[i] = chg.UserID
+		}
+
+		usrs, err := b.storer.QueryByIDs(ctx, ids)
+		if err != nil {
+			return changed, fmt.Errorf("querybyids: %w", err)
+		}
+
+		byID := make(map[uuid.UUID]User, len(usrs))
+		for _, usr := range usrs {
+			byID[usr.ID] = usr
+		}
+
+		for _, chg := range batch {
+			from, to := chg.OldRoles, chg.NewRoles
+			if revert {
+				from, to = to, from
+			}
+
+			usr, exists := byID[chg.UserID]
+			if !exists || !slices.EqualFunc(usr.Roles, from, role.Role.Equal) {
+				continue
+			}
+
+			orgUsr := usr
+
+			usr.Roles = to
+			usr.UpdatedBy = actorID
+			usr.DateUpdated = clock.Now()
+			usr.Version++
+
+			// A user the change would break a rule for is left alone like
+			// one whose roles changed, the rest of the assignment still
+			// applies.
+			if err := b.checkUpdateRules(ctx, actorID, orgUsr, usr); err != nil {
+				if !errors.Is(err, ErrRuleViolation) {
+					return changed, fmt.Errorf("userID[%s]: %w", usr.ID, err)
+				}
+				b.log.Info(ctx, "userbus: changeroles", "userID", usr.ID, "status", "skipped", "reason", err)
+				continue
+			}
+
+			if err := b.storer.Update(ctx, usr); err != nil {
+				return changed, fmt.Errorf("update: userID[%s]: %w", usr.ID, err)
+			}
+
+			if err := b.delegate.Call(ctx, ActionUpdatedData(usr.ID, usr.TenantID, changes(orgUsr, usr))); err != nil {
+				return changed, fmt.Errorf("failed to execute `%s` action: %w", ActionUpdated, err)
+			}
+
+			changed = append(changed, RoleChange{UserID: usr.ID, OldRoles: from, NewRoles: to})
+		}
+	}
+
+	return changed, nil
+}
+
+// assignRoles returns the roles with the roles to add appended and the roles
+// to remove taken out. The order of the existing roles is kept.
+func assignRoles(roles []role.Role, addRoles []role.Role, removeRoles []role.Role) []role.Role {
+	newRoles := make([]role.Role, 0, len(roles)+len(addRoles))
+
+	for _, r := range roles {
+		if !slices.ContainsFunc(removeRoles, r.Equal) {
+			newRoles = append(newRoles, r)
+		}
+	}
+
+	for _, r := range addRoles {
+		if !slices.ContainsFunc(newRoles, r.Equal) && !slices.ContainsFunc(removeRoles, r.Equal) {
+			newRoles = append(newRoles, r)
+		}
+	}
+
+	return newRoles
+}
+
+// generateRevertToken returns a new random revert token.
+func generateRevertToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("read random: %w", err)
+	}
+
+	return "ra_" + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashRevertToken returns the stored form of a revert token. The tokens are
+// random enough that a plain SHA-256 hash is sufficient.
+func hashRevertToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,204 @@
+package userbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/ardanlabs/service/business/sdk/buserr"
+)
+
+// ErrRuleViolation is returned when a change breaks a rule registered by the
+// deployment. Use errors.As with a *RuleError to find out which rules
+// failed, or with a *buserr.Error to get them as field violations.
+var ErrRuleViolation = buserr.New(buserr.InvalidArgument, "user violates a business rule")
+
+// RuleViolation describes why a user broke a rule. A rule returns one as
+// its error, any other error fails the change as an internal error.
+type RuleViolation struct {
+	Rule    string
+	Field   string
+	Message string
+}
+
+// Error implements the error interface.
+func (rv *RuleViolation) Error() string {
+	return fmt.Sprintf("%s: %s", rv.Rule, rv.Message)
+}
+
+// RuleError provides the set of rules a change broke.
+type RuleError struct {
+	Violations []RuleViolation
+}
+
+// Error implements the error interface.
+func (re *RuleError) Error() string {
+	msgs := make([]string, len(re.Violations))
+	for i, rv := range re.Violations {
+		msgs[i] = rv.Error()
+	}
+
+	return fmt.Sprintf("%s: %s", ErrRuleViolation, strings.Join(msgs, ", "))
+}
+
+// Unwrap returns ErrRuleViolation with a field violation for each rule
+// that failed, so errors.Is matches the error against ErrRuleViolation.
+func (re *RuleError) Unwrap() error {
+	fields := make([]buserr.FieldViolation, len(re.Violations))
+	for i, rv := range re.Violations {
+		fields[i] = buserr.FieldViolation{
+			Field:   rv.Field,
+			Message: rv.Error(),
+		}
+	}
+
+	return ErrRuleViolation.WithFields(fields...)
+}
+
+// RuleReader provides the read access rules have to the users. Reads are
+// made in the same transaction as the change being checked, so a rule sees
+// the other changes made in it.
+type RuleReader interface {
+	QueryByID(ctx context.Context, userID uuid.UUID) (User, error)
+	QueryByEmail(ctx context.Context, email mail.Address) (User, error)
+	Count(ctx context.Context, filter QueryFilter) (int, error)
+}
+
+// CreateRule checks a user about to be created.
+type CreateRule func(ctx context.Context, r RuleReader, actorID uuid.UUID, usr User) error
+
+// UpdateRule checks a change to a user about to be stored. The before value
+// is the user as it was read.
+type UpdateRule func(ctx context.Context, r RuleReader, actorID uuid.UUID, before User, after User) error
+
+type namedRule[T any] struct {
+	name string
+	rule T
+}
+
+var rules = struct {
+	mu     sync.RWMutex
+	create []namedRule[CreateRule]
+	update []namedRule[UpdateRule]
+}{}
+
+// RegisterCreateRule adds a rule checked every time a user is created,
+// including users created in a batch and provisioned for a federated login.
+// Registering a name again replaces the rule. Rules are checked in the
+// order they were first registered.
+func RegisterCreateRule(name string, rule CreateRule) {
+	rules.mu.Lock()
+	defer rules.mu.Unlock()
+
+	rules.create = register(rules.create, name, rule)
+}
+
+// RegisterUpdateRule adds a rule checked every time a user is updated or
+// has its roles changed by an assignment. Registering a name again replaces
+// the rule. Rules are checked in the order they were first registered.
+func RegisterUpdateRule(name string, rule UpdateRule) {
+	rules.mu.Lock()
+	defer rules.mu.Unlock()
+
+	rules.update = register(rules.update, name, rule)
+}
+
+func register[T any](list []namedRule[T], name string, rule T) []namedRule[T] {
+	idx := slices.IndexFunc(list, func(nr namedRule[T]) bool { return nr.name == name })
+	if idx >= 0 {
+		list[idx].rule = rule
+		return list
+	}
+
+	return append(list, namedRule[T]{name: name, rule: rule})
+}
+
+// =============================================================================
+
+// ruleReader gives rules read access through the store the business was
+// constructed with, which is bound to the transaction when there is one.
+type ruleReader struct {
+	storer Storer
+}
+
+func (rr ruleReader) QueryByID(ctx context.Context, userID uuid.UUID) (User, error) {
+	return rr.storer.QueryByID(ctx, userID)
+}
+
+func (rr ruleReader) QueryByEmail(ctx context.Context, email mail.Address) (User, error) {
+	return rr.storer.QueryByEmail(ctx, email)
+}
+
+func (rr ruleReader) Count(ctx context.Context, filter QueryFilter) (int, error) {
+	return rr.storer.Count(ctx, filter)
+}
+
+// checkCreateRules runs every create rule against the user. Every rule is
+// run so all the violations are reported together.
+func (b *business) checkCreateRules(ctx context.Context, actorID uuid.UUID, usr User) error {
+	rules.mu.RLock()
+	list := slices.Clone(rules.create)
+	rules.mu.RUnlock()
+
+	var re RuleError
+	for _, nr := range list {
+		if err := collect(&re, nr.name, nr.rule(ctx, ruleReader{storer: b.storer}, actorID, usr)); err != nil {
+			return err
+		}
+	}
+
+	if len(re.Violations) > 0 {
+		return &re
+	}
+
+	return nil
+}
+
+// checkUpdateRules runs every update rule against the change. Every rule is
+// run so all the violations are reported together.
+func (b *business) checkUpdateRules(ctx context.Context, actorID uuid.UUID, before User, after User) error {
+	rules.mu.RLock()
+	list := slices.Clone(rules.update)
+	rules.mu.RUnlock()
+
+	var re RuleError
+	for _, nr := range list {
+		if err := collect(&re, nr.name, nr.rule(ctx, ruleReader{storer: b.storer}, actorID, before, after)); err != nil {
+			return err
+		}
+	}
+
+	if len(re.Violations) > 0 {
+		return &re
+	}
+
+	return nil
+}
+
+// collect adds the violation a rule returned to the set. Any other error is
+// returned so the change fails.
+func collect(re *RuleError, name string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var rv *RuleViolation
+	if !errors.As(err, &rv) {
+		return fmt.Errorf("rule[%s]: %w", name, err)
+	}
+
+	v := *rv
+	if v.Rule == "" {
+		v.Rule = name
+	}
+
+	re.Violations = append(re.Violations, v)
+
+	return nil
+}
@@ -0,0 +1,348 @@
+package storertest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"slices"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/google/uuid"
+)
+
+// Driver names a store to measure. Stores are registered by the test that
+// owns their database, so a report only covers the stores it can reach.
+type Driver struct {
+	Name   string
+	Storer userbus.Storer
+}
+
+// Load describes the traffic each driver is put under.
+type Load struct {
+	// Workers is how many goroutines call the store at the same time.
+	Workers int
+
+	// Duration is how long each driver is measured for.
+	Duration time.Duration
+}
+
+// OpResult is the measurement of one operation against one driver. A call
+// that fails or returns something the suite wouldn't accept counts as an
+// error.
+type OpResult struct {
+	Op     string        `json:"op"`
+	Calls  int           `json:"calls"`
+	Errors int           `json:"errors"`
+	P50    time.Duration `json:"p50"`
+	P95    time.Duration `json:"p95"`
+	P99    time.Duration `json:"p99"`
+	Max    time.Duration `json:"max"`
+}
+
+// ErrorRate returns the share of calls that failed.
+func (r OpResult) ErrorRate() float64 {
+	if r.Calls == 0 {
+		return 0
+	}
+
+	return float64(r.Errors) / float64(r.Calls)
+}
+
+// DriverResult is the measurement of one driver. Allocations are counted
+// for the whole process while the driver runs, so they include the driver
+// and the database client as well as the store.
+type DriverResult struct {
+	Driver      string        `json:"driver"`
+	Duration    time.Duration `json:"duration"`
+	Calls       int           `json:"calls"`
+	Errors      int           `json:"errors"`
+	CallsPerSec float64       `json:"callsPerSec"`
+	AllocsPerOp float64       `json:"allocsPerOp"`
+	BytesPerOp  float64       `json:"bytesPerOp"`
+	Ops         []OpResult    `json:"ops"`
+}
+
+// Report is the comparison of the drivers under the same load.
+type Report struct {
+	Load    Load           `json:"load"`
+	Drivers []DriverResult `json:"drivers"`
+}
+
+// Measure runs the load against each driver in turn and reports how they
+// compare. The drivers are measured one after the other so they don't
+// compete for the machine.
+func Measure(ctx context.Context, drivers []Driver, load Load) (Report, error) {
+	if load.Workers <= 0 || load.Duration <= 0 {
+		return Report{}, errors.New("load needs workers and a duration")
+	}
+
+	rpt := Report{
+		Load: load,
+	}
+
+	for _, d := range drivers {
+		res, err := measure(ctx, d, load)
+		if err != nil {
+			return Report{}, fmt.Errorf("driver[%s]: %w", d.Name, err)
+		}
+
+		rpt.Drivers = append(rpt.Drivers, res)
+	}
+
+	return rpt, nil
+}
+
+// WriteText writes the report as a table, one row per driver and
+// operation.
+func (rpt Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+
+	fmt.Fprintf(tw, "DRIVER\tOP\tCALLS\tERR%%\tP50\tP95\tP99\tMAX\t\n")
+
+	for _, d := range rpt.Drivers {
+		for _, op := range d.Ops {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%.2f\t%s\t%s\t%s\t%s\t\n", d.Driver, op.Op, op.Calls, op.ErrorRate()*100, op.P50, op.P95, op.P99, op.Max)
+		}
+	}
+
+	fmt.Fprintf(tw, "\t\t\t\t\t\t\t\t\n")
+	fmt.Fprintf(tw, "DRIVER\tCALLS/SEC\tERRORS\tALLOCS/OP\tBYTES/OP\t\t\t\t\n")
+
+	for _, d := range rpt.Drivers {
+		fmt.Fprintf(tw, "%s\t%.0f\t%d\t%.0f\t%.0f\t\t\t\t\n", d.Driver, d.CallsPerSec, d.Errors, d.AllocsPerOp, d.BytesPerOp)
+	}
+
+	return tw.Flush()
+}
+
+// WriteJSON writes the report as JSON so runs can be compared over time.
+func (rpt Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(rpt)
+}
+
+// =============================================================================
+
+// Set of operations a worker runs, in the order it runs them.
+const (
+	opCreate   = "create"
+	opByID     = "byid"
+	opByEmail  = "byemail"
+	opNotFound = "notfound"
+	opUpdate   = "update"
+	opQuery    = "query"
+	opCount    = "count"
+	opDelete   = "delete"
+)
+
+var ops = []string{opCreate, opByID, opByEmail, opNotFound, opUpdate, opQuery, opCount, opDelete}
+
+type sample struct {
+	durations []time.Duration
+	errors    int
+}
+
+// worker holds what one goroutine measured. Workers don't share anything so
+// recording a call doesn't add contention to the measurement.
+type worker map[string]*sample
+
+func (w worker) record(op string, start time.Time, err error) {
+	s := w[op]
+	s.durations = append(s.durations, time.Since(start))
+	if err != nil {
+		s.errors++
+	}
+}
+
+func measure(ctx context.Context, d Driver, load Load) (DriverResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, load.Duration)
+	defer cancel()
+
+	workers := make([]worker, load.Workers)
+	for i := range workers {
+		workers[i] = make(worker, len(ops))
+		for _, op := range ops {
+			workers[i][op] = &sample{}
+		}
+	}
+
+	runtime.GC()
+
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	wg.Add(load.Workers)
+
+	for i := range load.Workers {
+		go func() {
+			defer wg.Done()
+
+			for n := 0; ctx.Err() == nil; n++ {
+				iterate(ctx, d.Storer, workers[i], i*1_000_000+n)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	elapsed := time.Since(start)
+
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+
+	res := DriverResult{
+		Driver:   d.Name,
+		Duration: elapsed,
+	}
+
+	for _, op := range ops {
+		var all sample
+		for _, w := range workers {
+			all.durations = append(all.durations, w[op].durations...)
+			all.errors += w[op].errors
+		}
+
+		opRes := summarize(op, all)
+
+		res.Ops = append(res.Ops, opRes)
+		res.Calls += opRes.Calls
+		res.Errors += opRes.Errors
+	}
+
+	if res.Calls == 0 {
+		return DriverResult{}, errors.New("no calls completed within the duration")
+	}
+
+	res.CallsPerSec = float64(res.Calls) / elapsed.Seconds()
+	res.AllocsPerOp = float64(after.Mallocs-before.Mallocs) / float64(res.Calls)
+	res.BytesPerOp = float64(after.TotalAlloc-before.TotalAlloc) / float64(res.Calls)
+
+	return res, nil
+}
+
+// iterate takes a new user through its life, checking each result the way
+// the conformance suite does. A call cut short by the end of the run isn't
+// recorded.
+func iterate(ctx context.Context, storer userbus.Storer, w worker, idx int) {
+	usr := newUser(idx)
+	filter := userbus.QueryFilter{Email: &usr.Email}
+
+	call := func(op string, fn func() error) bool {
+		start := time.Now()
+		err := fn()
+
+		if ctx.Err() == nil {
+			w.record(op, start, err)
+		}
+
+		return err == nil
+	}
+
+	if !call(opCreate, func() error { return storer.Create(ctx, usr) }) {
+		return
+	}
+
+	// A user left behind when the run ends mid iteration is still removed.
+	var deleted bool
+	defer func() {
+		if !deleted {
+			storer.Delete(context.WithoutCancel(ctx), usr)
+		}
+	}()
+
+	call(opByID, func() error {
+		got, err := storer.QueryByID(ctx, usr.ID)
+		return expect(err, got.ID == usr.ID)
+	})
+
+	call(opByEmail, func() error {
+		got, err := storer.QueryByEmail(ctx, usr.Email)
+		return expect(err, got.ID == usr.ID)
+	})
+
+	call(opNotFound, func() error {
+		_, err := storer.QueryByID(ctx, uuid.New())
+		if errors.Is(err, userbus.ErrNotFound) {
+			return nil
+		}
+		return expect(err, false)
+	})
+
+	call(opUpdate, func() error {
+		upd := usr
+		upd.Enabled = false
+		upd.Version++
+		upd.DateUpdated = time.Now().Truncate(time.Millisecond)
+
+		if err := storer.Update(ctx, upd); err != nil {
+			return err
+		}
+
+		usr = upd
+		return nil
+	})
+
+	call(opQuery, func() error {
+		got, err := storer.Query(ctx, filter, userbus.DefaultOrderBy, page.MustParse("1", "10"))
+		return expect(err, slices.ContainsFunc(got, func(u userbus.User) bool { return u.ID == usr.ID }))
+	})
+
+	call(opCount, func() error {
+		n, err := storer.Count(ctx, filter)
+		return expect(err, n == 1)
+	})
+
+	deleted = call(opDelete, func() error { return storer.Delete(ctx, usr) })
+}
+
+// errUnexpected is recorded for a call that succeeded with a result the
+// conformance suite would reject.
+var errUnexpected = errors.New("unexpected result")
+
+func expect(err error, ok bool) error {
+	switch {
+	case err != nil:
+		return err
+	case !ok:
+		return errUnexpected
+	}
+
+	return nil
+}
+
+func summarize(op string, s sample) OpResult {
+	res := OpResult{
+		Op:     op,
+		Calls:  len(s.durations),
+		Errors: s.errors,
+	}
+
+	if len(s.durations) == 0 {
+		return res
+	}
+
+	slices.Sort(s.durations)
+
+	pct := func(p float64) time.Duration {
+		return s.durations[int(p*float64(len(s.durations)-1))]
+	}
+
+	res.P50 = pct(0.50)
+	res.P95 = pct(0.95)
+	res.P99 = pct(0.99)
+	res.Max = s.durations[len(s.durations)-1]
+
+	return res
+}
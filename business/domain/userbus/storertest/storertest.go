@@ -0,0 +1,778 @@
+// Package storertest provides a conformance suite for userbus.Storer
+// implementations. A store passes when it behaves the way the business layer
+// relies on: unique emails, ErrNotFound for missing data, filtering, ordering
+// and paging, and rolling back with its transaction when it supports one.
+//
+// Every new store should run the suite against an empty database, and new
+// behavior added to the Storer interface should be covered here so the
+// stores that already exist are held to it.
+package storertest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/mail"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/business/sdk/unitest"
+	"github.com/ardanlabs/service/business/types/department"
+	"github.com/ardanlabs/service/business/types/name"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+)
+
+// Config describes the store under test.
+type Config struct {
+	// Storer is the store under test.
+	Storer userbus.Storer
+
+	// Beginner starts the transactions used by the rollback tests. Stores
+	// that can't take part in a transaction leave it nil and are expected
+	// to refuse one with sqldb.ErrTxNotSupported instead.
+	Beginner sqldb.Beginner
+}
+
+// Run executes the conformance suite against the store.
+func Run(t *testing.T, cfg Config) {
+	usrs, err := seed(context.Background(), cfg.Storer)
+	if err != nil {
+		t.Fatalf("Seeding error: %s", err)
+	}
+
+	unitest.Run(t, crud(cfg.Storer), "storer-crud")
+	unitest.Run(t, notFound(cfg.Storer), "storer-notfound")
+	unitest.Run(t, query(cfg.Storer, usrs), "storer-query")
+
+	if cfg.Beginner == nil {
+		unitest.Run(t, noTx(cfg.Storer), "storer-notx")
+		return
+	}
+
+	unitest.Run(t, rollback(cfg.Storer, cfg.Beginner), "storer-rollback")
+}
+
+// =============================================================================
+
+// prefix makes the names of the users created by a run unique so the
+// suite can share a database with other data.
+var prefix = func() string {
+	const letters = "abcdefghijklmnopqrstuvwxyz"
+
+	b := []byte("St")
+	for range 6 {
+		b = append(b, letters[rand.Intn(len(letters))])
+	}
+
+	return string(b)
+}()
+
+func newUser(idx int) userbus.User {
+	now := time.Now().Truncate(time.Millisecond)
+
+	return userbus.User{
+		ID:           uuid.New(),
+		Name:         name.MustParse(fmt.Sprintf("%s %d", prefix, idx)),
+		Email:        mail.Address{Address: fmt.Sprintf("%s%d-%s@example.com", prefix, idx, uuid.NewString()[:8])},
+		Roles:        []role.Role{role.User},
+		PasswordHash: []byte("hash"),
+		Department:   department.MustParseNull(fmt.Sprintf("Dept %s", prefix)),
+		Enabled:      true,
+		CreatedBy:    userbus.ActorTooling,
+		UpdatedBy:    userbus.ActorTooling,
+		DateCreated:  now,
+		DateUpdated:  now,
+		Version:      1,
+	}
+}
+
+// seed creates the users the query tests run against, in name order.
+func seed(ctx context.Context, storer userbus.Storer) ([]userbus.User, error) {
+	usrs := make([]userbus.User, 5)
+
+	for i := range usrs {
+		usrs[i] = newUser(i + 1)
+
+		if err := storer.Create(ctx, usrs[i]); err != nil {
+			return nil, fmt.Errorf("seeding user: idx: %d : %w", i, err)
+		}
+	}
+
+	return usrs, nil
+}
+
+func ids(usrs []userbus.User) []uuid.UUID {
+	ids := make([]uuid.UUID, len(usrs))
+	for i, usr := range usrs {
+		ids[i] = usr.ID
+	}
+
+	return ids
+}
+
+func cmpErr(target error) func(got any, exp any) string {
+	return func(got any, exp any) string {
+		err, ok := got.(error)
+		if !ok || !errors.Is(err, target) {
+			return fmt.Sprintf("expected %v, got %v", target, got)
+		}
+		return ""
+	}
+}
+
+// =============================================================================
+
+func crud(storer userbus.Storer) []unitest.Table {
+	usr := newUser(100)
+
+	updated := usr
+	updated.Name = name.MustParse(prefix + " upd")
+	updated.Email = mail.Address{Address: "upd-" + usr.Email.Address}
+	updated.Enabled = false
+	updated.AvatarKey = "avatars/" + usr.ID.String() + ".png"
+	updated.UpdatedBy = userbus.ActorSystem
+
+	table := []unitest.Table{
+		{
+			Name:    "create",
+			ExpResp: usr,
+			ExcFunc: func(ctx context.Context) any {
+				if err := storer.Create(ctx, usr); err != nil {
+					return err
+				}
+
+				got, err := storer.QueryByID(ctx, usr.ID)
+				if err != nil {
+					return err
+				}
+
+				return got
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+		{
+			Name:    "byemail",
+			ExpResp: usr.ID,
+			ExcFunc: func(ctx context.Context) any {
+				got, err := storer.QueryByEmail(ctx, usr.Email)
+				if err != nil {
+					return err
+				}
+
+				return got.ID
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+		{
+			Name:    "unique-email",
+			ExpResp: userbus.ErrUniqueEmail,
+			ExcFunc: func(ctx context.Context) any {
+				dup := newUser(101)
+				dup.Email = usr.Email
+
+				return storer.Create(ctx, dup)
+			},
+			CmpFunc: cmpErr(userbus.ErrUniqueEmail),
+		},
+		{
+			Name:    "unique-email-race",
+			ExpResp: 1,
+			ExcFunc: func(ctx context.Context) any {
+				email := mail.Address{Address: fmt.Sprintf("%s-race-%s@example.com", prefix, uuid.NewString()[:8])}
+
+				const racers = 4
+
+				var wg sync.WaitGroup
+				usrs := make([]userbus.User, racers)
+				errs := make([]error, racers)
+
+				for i := range racers {
+					usrs[i] = newUser(110 + i)
+					usrs[i].Email = email
+
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						errs[i] = storer.Create(ctx, usrs[i])
+					}()
+				}
+
+				wg.Wait()
+
+				// The user that won is removed so it doesn't show up in the
+				// query tests.
+				var created int
+				for i, err := range errs {
+					switch {
+					case err == nil:
+						created++
+						if err := storer.Delete(ctx, usrs[i]); err != nil {
+							return err
+						}
+					case !errors.Is(err, userbus.ErrUniqueEmail):
+						return err
+					}
+				}
+
+				return created
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+		{
+			Name:    "create-many",
+			ExpResp: [][]byte{[]byte("hash")},
+			ExcFunc: func(ctx context.Context) any {
+				usrs := []userbus.User{newUser(120), newUser(121), newUser(122)}
+				usrs[1].Email = usr.Email
+
+				got, err := storer.CreateMany(ctx, usrs)
+				if err != nil {
+					return err
+				}
+
+				if exp := []uuid.UUID{usrs[0].ID, usrs[2].ID}; !slices.Equal(got, exp) {
+					return fmt.Errorf("expected ids %v, got %v", exp, got)
+				}
+
+				created := []userbus.User{usrs[0], usrs[2]}
+				if err := storer.AddPasswordHistories(ctx, created); err != nil {
+					return err
+				}
+
+				hashes, err := storer.QueryPasswordHistory(ctx, usrs[2].ID, 5)
+				if err != nil {
+					return err
+				}
+
+				// The users are removed so they don't show up in the query
+				// tests.
+				for _, usr := range created {
+					if err := storer.Delete(ctx, usr); err != nil {
+						return err
+					}
+				}
+
+				return hashes
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+		{
+			Name:    "update",
+			ExpResp: updated,
+			ExcFunc: func(ctx context.Context) any {
+				if err := storer.Update(ctx, updated); err != nil {
+					return err
+				}
+
+				got, err := storer.QueryByEmail(ctx, updated.Email)
+				if err != nil {
+					return err
+				}
+
+				return got
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+		{
+			Name:    "update-frees-email",
+			ExpResp: nil,
+			ExcFunc: func(ctx context.Context) any {
+				reuse := newUser(102)
+				reuse.Email = usr.Email
+
+				if err := storer.Create(ctx, reuse); err != nil {
+					return err
+				}
+
+				return storer.Delete(ctx, reuse)
+			},
+			CmpFunc: func(got any, exp any) string {
+				if got != nil {
+					return fmt.Sprintf("expected no error, got %v", got)
+				}
+				return ""
+			},
+		},
+		{
+			Name:    "idempotency-key",
+			ExpResp: userbus.ErrNotFound,
+			ExcFunc: func(ctx context.Context) any {
+				ik := userbus.IdempotencyKey{
+					ActorID:     uuid.New(),
+					Key:         "storertest",
+					UserID:      updated.ID,
+					DateCreated: updated.DateCreated,
+				}
+
+				if err := storer.AddIdempotencyKey(ctx, ik); err != nil {
+					return err
+				}
+
+				got, err := storer.QueryIdempotencyKey(ctx, ik.ActorID, ik.Key)
+				if err != nil {
+					return err
+				}
+
+				if got.UserID != ik.UserID {
+					return fmt.Errorf("expected user %s, got %s", ik.UserID, got.UserID)
+				}
+
+				n, err := storer.DeleteIdempotencyKeys(ctx, ik.DateCreated.Add(time.Second))
+				if err != nil {
+					return err
+				}
+
+				if n == 0 {
+					return errors.New("expected the key to be deleted")
+				}
+
+				_, err = storer.QueryIdempotencyKey(ctx, ik.ActorID, ik.Key)
+				return err
+			},
+			CmpFunc: cmpErr(userbus.ErrNotFound),
+		},
+		{
+			Name:    "preferences",
+			ExpResp: 0,
+			ExcFunc: func(ctx context.Context) any {
+				pref := userbus.Preference{
+					UserID:      updated.ID,
+					Key:         "theme",
+					Value:       json.RawMessage(`"dark"`),
+					DateUpdated: updated.DateUpdated,
+				}
+
+				if err := storer.SetPreference(ctx, pref); err != nil {
+					return err
+				}
+
+				pref.Value = json.RawMessage(`"light"`)
+				if err := storer.SetPreference(ctx, pref); err != nil {
+					return err
+				}
+
+				prefs, err := storer.QueryPreferences(ctx, updated.ID)
+				if err != nil {
+					return err
+				}
+
+				if len(prefs) != 1 || prefs[0].Key != "theme" || string(prefs[0].Value) != `"light"` {
+					return fmt.Errorf("expected theme to be replaced with light, got %+v", prefs)
+				}
+
+				if err := storer.DeletePreference(ctx, updated.ID, "theme"); err != nil {
+					return err
+				}
+
+				prefs, err = storer.QueryPreferences(ctx, updated.ID)
+				if err != nil {
+					return err
+				}
+
+				return len(prefs)
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+		{
+			Name:    "personaldata",
+			ExpResp: userbus.ErrNotFound,
+			ExcFunc: func(ctx context.Context) any {
+				idn := userbus.Identity{
+					Provider:    "storertest",
+					ExternalID:  updated.ID.String(),
+					UserID:      updated.ID,
+					Email:       updated.Email,
+					DateCreated: updated.DateUpdated,
+				}
+
+				if err := storer.AddIdentity(ctx, idn); err != nil {
+					return err
+				}
+
+				pref := userbus.Preference{
+					UserID:      updated.ID,
+					Key:         "theme",
+					Value:       json.RawMessage(`"dark"`),
+					DateUpdated: updated.DateUpdated,
+				}
+
+				if err := storer.SetPreference(ctx, pref); err != nil {
+					return err
+				}
+
+				if err := storer.DeletePersonalData(ctx, updated.ID); err != nil {
+					return err
+				}
+
+				prefs, err := storer.QueryPreferences(ctx, updated.ID)
+				if err != nil {
+					return err
+				}
+
+				if len(prefs) != 0 {
+					return fmt.Errorf("expected the preferences to be deleted, got %+v", prefs)
+				}
+
+				if _, err := storer.QueryByID(ctx, updated.ID); err != nil {
+					return fmt.Errorf("expected the user to be kept: %w", err)
+				}
+
+				_, err = storer.QueryIdentity(ctx, idn.Provider, idn.ExternalID)
+				return err
+			},
+			CmpFunc: cmpErr(userbus.ErrNotFound),
+		},
+		{
+			Name:    "delete",
+			ExpResp: userbus.ErrNotFound,
+			ExcFunc: func(ctx context.Context) any {
+				if err := storer.Delete(ctx, updated); err != nil {
+					return err
+				}
+
+				_, err := storer.QueryByID(ctx, updated.ID)
+				return err
+			},
+			CmpFunc: cmpErr(userbus.ErrNotFound),
+		},
+	}
+
+	return table
+}
+
+func notFound(storer userbus.Storer) []unitest.Table {
+	table := []unitest.Table{
+		{
+			Name:    "byid",
+			ExpResp: userbus.ErrNotFound,
+			ExcFunc: func(ctx context.Context) any {
+				_, err := storer.QueryByID(ctx, uuid.New())
+				return err
+			},
+			CmpFunc: cmpErr(userbus.ErrNotFound),
+		},
+		{
+			Name:    "byemail",
+			ExpResp: userbus.ErrNotFound,
+			ExcFunc: func(ctx context.Context) any {
+				_, err := storer.QueryByEmail(ctx, mail.Address{Address: uuid.NewString() + "@example.com"})
+				return err
+			},
+			CmpFunc: cmpErr(userbus.ErrNotFound),
+		},
+		{
+			Name:    "identity",
+			ExpResp: userbus.ErrNotFound,
+			ExcFunc: func(ctx context.Context) any {
+				_, err := storer.QueryIdentity(ctx, "storertest", uuid.NewString())
+				return err
+			},
+			CmpFunc: cmpErr(userbus.ErrNotFound),
+		},
+		{
+			Name:    "idempotency-key",
+			ExpResp: userbus.ErrNotFound,
+			ExcFunc: func(ctx context.Context) any {
+				_, err := storer.QueryIdempotencyKey(ctx, uuid.New(), "missing")
+				return err
+			},
+			CmpFunc: cmpErr(userbus.ErrNotFound),
+		},
+		{
+			Name:    "recovery-code",
+			ExpResp: userbus.ErrNotFound,
+			ExcFunc: func(ctx context.Context) any {
+				return storer.UseRecoveryCode(ctx, uuid.New(), "missing")
+			},
+			CmpFunc: cmpErr(userbus.ErrNotFound),
+		},
+		{
+			Name:    "role-assignment",
+			ExpResp: userbus.ErrNotFound,
+			ExcFunc: func(ctx context.Context) any {
+				_, err := storer.QueryRoleAssignment(ctx, uuid.New())
+				return err
+			},
+			CmpFunc: cmpErr(userbus.ErrNotFound),
+		},
+		{
+			Name:    "last-login",
+			ExpResp: userbus.ErrNotFound,
+			ExcFunc: func(ctx context.Context) any {
+				return storer.UpdateLastLogin(ctx, uuid.New(), time.Now())
+			},
+			CmpFunc: cmpErr(userbus.ErrNotFound),
+		},
+	}
+
+	return table
+}
+
+func query(storer userbus.Storer, usrs []userbus.User) []unitest.Table {
+	nme := name.MustParse(prefix)
+	filter := userbus.QueryFilter{
+		Name: &nme,
+	}
+
+	byNameDesc := order.NewBy(userbus.OrderByName, order.DESC)
+
+	reversed := make([]userbus.User, len(usrs))
+	for i, usr := range usrs {
+		reversed[len(usrs)-1-i] = usr
+	}
+
+	table := []unitest.Table{
+		{
+			Name:    "count",
+			ExpResp: len(usrs),
+			ExcFunc: func(ctx context.Context) any {
+				n, err := storer.Count(ctx, filter)
+				if err != nil {
+					return err
+				}
+
+				return n
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+		{
+			Name:    "offset-page",
+			ExpResp: ids(reversed[2:4]),
+			ExcFunc: func(ctx context.Context) any {
+				got, err := storer.Query(ctx, filter, byNameDesc, page.MustParse("2", "2"))
+				if err != nil {
+					return err
+				}
+
+				return ids(got)
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+		{
+			Name:    "keyset-pages",
+			ExpResp: ids(usrs),
+			ExcFunc: func(ctx context.Context) any {
+				byName := order.NewBy(userbus.OrderByName, order.ASC)
+
+				var got []userbus.User
+
+				pg := page.MustParseCursor("", "2")
+				for {
+					usrs, err := storer.Query(ctx, filter, byName, pg)
+					if err != nil {
+						return err
+					}
+
+					got = append(got, usrs...)
+
+					cursor := userbus.NextCursor(byName, pg, usrs)
+					if cursor == "" {
+						break
+					}
+
+					pg = page.MustParseCursor(cursor, "2")
+				}
+
+				return ids(got)
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+		{
+			Name:    "filter-email",
+			ExpResp: ids(usrs[2:3]),
+			ExcFunc: func(ctx context.Context) any {
+				f := filter
+				f.Email = &usrs[2].Email
+
+				got, err := storer.Query(ctx, f, userbus.DefaultOrderBy, page.MustParse("1", "10"))
+				if err != nil {
+					return err
+				}
+
+				return ids(got)
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+		{
+			Name:    "filter-disabled",
+			ExpResp: 0,
+			ExcFunc: func(ctx context.Context) any {
+				f := filter
+				f.Enabled = new(bool)
+
+				n, err := storer.Count(ctx, f)
+				if err != nil {
+					return err
+				}
+
+				return n
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+		{
+			Name:    "byids",
+			ExpResp: len(usrs[:3]),
+			ExcFunc: func(ctx context.Context) any {
+				got, err := storer.QueryByIDs(ctx, append(ids(usrs[:3]), uuid.New()))
+				if err != nil {
+					return err
+				}
+
+				return len(got)
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+		{
+			Name:    "names",
+			ExpResp: usrs[0].Name.String(),
+			ExcFunc: func(ctx context.Context) any {
+				var got string
+				err := storer.QueryNames(ctx, func(sn userbus.StoredName) error {
+					if sn.UserID == usrs[0].ID {
+						got = sn.Name
+					}
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+
+				return got
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+		{
+			// The first user logs in after the cutoff, the rest were
+			// created before it and never logged in.
+			Name: "filter-not-logged-in-since",
+			ExpResp: struct {
+				LastLogin bool
+				IDs       []uuid.UUID
+			}{
+				LastLogin: true,
+				IDs:       ids(usrs[1:]),
+			},
+			ExcFunc: func(ctx context.Context) any {
+				since := time.Now().Add(time.Minute)
+				lastLogin := since.Add(time.Minute).Truncate(time.Millisecond)
+
+				if err := storer.UpdateLastLogin(ctx, usrs[0].ID, lastLogin); err != nil {
+					return err
+				}
+
+				usr, err := storer.QueryByID(ctx, usrs[0].ID)
+				if err != nil {
+					return err
+				}
+
+				f := filter
+				f.NotLoggedInSince = &since
+
+				got, err := storer.Query(ctx, f, order.NewBy(userbus.OrderByName, order.ASC), page.MustParse("1", "10"))
+				if err != nil {
+					return err
+				}
+
+				return struct {
+					LastLogin bool
+					IDs       []uuid.UUID
+				}{
+					LastLogin: usr.DateLastLogin.Equal(lastLogin),
+					IDs:       ids(got),
+				}
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+	}
+
+	return table
+}
+
+func rollback(storer userbus.Storer, beginner sqldb.Beginner) []unitest.Table {
+	table := []unitest.Table{
+		{
+			Name:    "create",
+			ExpResp: userbus.ErrNotFound,
+			ExcFunc: func(ctx context.Context) any {
+				tx, err := beginner.Begin()
+				if err != nil {
+					return err
+				}
+
+				txStorer, err := storer.NewWithTx(tx)
+				if err != nil {
+					return err
+				}
+
+				usr := newUser(200)
+				if err := txStorer.Create(ctx, usr); err != nil {
+					return err
+				}
+
+				if err := tx.Rollback(); err != nil {
+					return err
+				}
+
+				_, err = storer.QueryByID(ctx, usr.ID)
+				return err
+			},
+			CmpFunc: cmpErr(userbus.ErrNotFound),
+		},
+	}
+
+	return table
+}
+
+func noTx(storer userbus.Storer) []unitest.Table {
+	table := []unitest.Table{
+		{
+			Name:    "refused",
+			ExpResp: sqldb.ErrTxNotSupported,
+			ExcFunc: func(ctx context.Context) any {
+				_, err := storer.NewWithTx(nil)
+				return err
+			},
+			CmpFunc: cmpErr(sqldb.ErrTxNotSupported),
+		},
+	}
+
+	return table
+}
@@ -0,0 +1,107 @@
+package usercache
+
+import (
+	"fmt"
+	"net/mail"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/types/department"
+	"github.com/ardanlabs/service/business/types/name"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/google/uuid"
+)
+
+// user represents a user as it's held in the cache.
+type user struct {
+	ID            uuid.UUID     `json:"id"`
+	TenantID      uuid.UUID     `json:"tenantID"`
+	Name          string        `json:"name" class:"confidential"`
+	Email         string        `json:"email" class:"confidential"`
+	Roles         []string      `json:"roles" class:"internal"`
+	PasswordHash  []byte        `json:"passwordHash" class:"restricted"`
+	Department    string        `json:"department,omitempty" class:"internal"`
+	ManagerID     uuid.NullUUID `json:"managerID"`
+	Enabled       bool          `json:"enabled"`
+	TOTPSecret    string        `json:"totpSecret,omitempty" class:"restricted"`
+	TOTPEnabled   bool          `json:"totpEnabled"`
+	AvatarKey     string        `json:"avatarKey,omitempty"`
+	CreatedBy     uuid.UUID     `json:"createdBy"`
+	UpdatedBy     uuid.UUID     `json:"updatedBy"`
+	DateCreated   time.Time     `json:"dateCreated"`
+	DateUpdated   time.Time     `json:"dateUpdated"`
+	DateLastLogin time.Time     `json:"dateLastLogin"`
+	Version       int           `json:"version"`
+}
+
+func toCacheUser(bus userbus.User) user {
+	usr := user{
+		ID:            bus.ID,
+		TenantID:      bus.TenantID,
+		Name:          bus.Name.String(),
+		Email:         bus.Email.Address,
+		Roles:         role.ParseToString(bus.Roles),
+		PasswordHash:  bus.PasswordHash,
+		ManagerID:     bus.ManagerID,
+		Enabled:       bus.Enabled,
+		TOTPSecret:    bus.TOTPSecret,
+		TOTPEnabled:   bus.TOTPEnabled,
+		AvatarKey:     bus.AvatarKey,
+		CreatedBy:     bus.CreatedBy,
+		UpdatedBy:     bus.UpdatedBy,
+		DateCreated:   bus.DateCreated,
+		DateUpdated:   bus.DateUpdated,
+		DateLastLogin: bus.DateLastLogin,
+		Version:       bus.Version,
+	}
+
+	if bus.Department.Valid() {
+		usr.Department = bus.Department.String()
+	}
+
+	return usr
+}
+
+func toBusUser(usr user) (userbus.User, error) {
+	roles, err := role.ParseMany(usr.Roles)
+	if err != nil {
+		return userbus.User{}, fmt.Errorf("parse: %w", err)
+	}
+
+	nme, err := name.Parse(usr.Name)
+	if err != nil {
+		return userbus.User{}, fmt.Errorf("parse name: %w", err)
+	}
+
+	dept, err := department.ParseNull(usr.Department)
+	if err != nil {
+		return userbus.User{}, fmt.Errorf("parse department: %w", err)
+	}
+
+	bus := userbus.User{
+		ID:            usr.ID,
+		TenantID:      usr.TenantID,
+		Name:          nme,
+		Email:         mail.Address{Address: usr.Email},
+		Roles:         roles,
+		PasswordHash:  usr.PasswordHash,
+		Department:    dept,
+		ManagerID:     usr.ManagerID,
+		Enabled:       usr.Enabled,
+		TOTPSecret:    usr.TOTPSecret,
+		TOTPEnabled:   usr.TOTPEnabled,
+		AvatarKey:     usr.AvatarKey,
+		CreatedBy:     usr.CreatedBy,
+		UpdatedBy:     usr.UpdatedBy,
+		DateCreated:   usr.DateCreated.In(time.Local),
+		DateUpdated:   usr.DateUpdated.In(time.Local),
+		DateLastLogin: usr.DateLastLogin,
+		Version:       usr.Version,
+	}
+
+	if !bus.DateLastLogin.IsZero() {
+		bus.DateLastLogin = bus.DateLastLogin.In(time.Local)
+	}
+
+	return bus, nil
+}
@@ -3,35 +3,46 @@ package usercache
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"net/mail"
+	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/cache"
 	"github.com/ardanlabs/service/business/sdk/order"
 	"github.com/ardanlabs/service/business/sdk/page"
 	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/business/sdk/tenant"
+	"github.com/ardanlabs/service/foundation/diag"
 	"github.com/ardanlabs/service/foundation/logger"
 	"github.com/google/uuid"
-	"github.com/viccon/sturdyc"
 )
 
-// Store manages the set of APIs for user data and caching.
+// Store manages the set of APIs for user data and caching. Users are held
+// by ID and by email. Counts are cached by filter for CountEstimate and
+// dropped whenever a user is written through the store.
 type Store struct {
-	log    *logger.Logger
-	storer userbus.Storer
-	cache  *sturdyc.Client[userbus.User]
+	log        *logger.Logger
+	storer     userbus.Storer
+	users      *cache.Aside[user]
+	counts     *cache.Aside[int]
+	generation atomic.Uint64
 }
 
-// NewStore constructs the api for data and caching access.
-func NewStore(log *logger.Logger, storer userbus.Storer, ttl time.Duration) *Store {
-	const capacity = 10000
-	const numShards = 10
-	const evictionPercentage = 10
-
+// NewStore constructs the api for data and caching access. The cache can be
+// shared with other instances, in which case their writes drop the users
+// they change from it as well.
+func NewStore(log *logger.Logger, storer userbus.Storer, c cache.Cache, ttl time.Duration) *Store {
 	return &Store{
 		log:    log,
 		storer: storer,
-		cache:  sturdyc.New[userbus.User](capacity, numShards, ttl, evictionPercentage),
+		users:  cache.NewAside[user](log, c, "user:", ttl),
+		counts: cache.NewAside[int](log, c, "user:count:", ttl),
 	}
 }
 
@@ -47,18 +58,48 @@ func (s *Store) Create(ctx context.Context, usr userbus.User) error {
 		return err
 	}
 
-	s.writeCache(usr)
+	s.writeCache(ctx, usr)
+	s.generation.Add(1)
 
 	return nil
 }
 
-// Update replaces a user document in the database.
+// CreateMany inserts a set of users into the database. The users that were
+// inserted are cached.
+func (s *Store) CreateMany(ctx context.Context, usrs []userbus.User) ([]uuid.UUID, error) {
+	ids, err := s.storer.CreateMany(ctx, usrs)
+	if err != nil {
+		return nil, err
+	}
+
+	created := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		created[id] = true
+	}
+
+	for _, usr := range usrs {
+		if created[usr.ID] {
+			s.writeCache(ctx, usr)
+		}
+	}
+
+	s.generation.Add(1)
+
+	return ids, nil
+}
+
+// Update replaces a user document in the database. A version conflict
+// means the cached user may be stale, so it's dropped from the cache.
 func (s *Store) Update(ctx context.Context, usr userbus.User) error {
 	if err := s.storer.Update(ctx, usr); err != nil {
+		if errors.Is(err, userbus.ErrVersionConflict) {
+			s.deleteCache(ctx, usr)
+		}
 		return err
 	}
 
-	s.writeCache(usr)
+	s.writeCache(ctx, usr)
+	s.generation.Add(1)
 
 	return nil
 }
@@ -69,7 +110,8 @@ func (s *Store) Delete(ctx context.Context, usr userbus.User) error {
 		return err
 	}
 
-	s.deleteCache(usr)
+	s.deleteCache(ctx, usr)
+	s.generation.Add(1)
 
 	return nil
 }
@@ -79,63 +121,335 @@ func (s *Store) Query(ctx context.Context, filter userbus.QueryFilter, orderBy o
 	return s.storer.Query(ctx, filter, orderBy, page)
 }
 
+// QueryAll implements the userbus.Storer interface. Streamed users aren't
+// cached since the result set can be very large.
+func (s *Store) QueryAll(ctx context.Context, filter userbus.QueryFilter, orderBy order.By, fn func(userbus.User) error) error {
+	return s.storer.QueryAll(ctx, filter, orderBy, fn)
+}
+
+// QueryWithCount implements the userbus.Storer interface. The count comes
+// with the page, so it isn't cached.
+func (s *Store) QueryWithCount(ctx context.Context, filter userbus.QueryFilter, orderBy order.By, page page.Page) ([]userbus.User, int, error) {
+	return s.storer.QueryWithCount(ctx, filter, orderBy, page)
+}
+
 // Count returns the total number of cards in the DB.
 func (s *Store) Count(ctx context.Context, filter userbus.QueryFilter) (int, error) {
 	return s.storer.Count(ctx, filter)
 }
 
+// CountEstimate implements the userbus.Storer interface. A cached count
+// isn't exact since other instances may have changed the users since it
+// was taken.
+func (s *Store) CountEstimate(ctx context.Context, filter userbus.QueryFilter) (int, bool, error) {
+	if filter.TenantID == nil {
+		filter.TenantID = tenant.Scope(ctx)
+	}
+
+	key, err := s.countKey(filter)
+	if err != nil {
+		return s.storer.CountEstimate(ctx, filter)
+	}
+
+	n, exists := s.counts.Get(ctx, key)
+	diag.AddCache(ctx, "user:count:"+key, exists)
+
+	if exists {
+		return n, false, nil
+	}
+
+	n, exact, err := s.storer.CountEstimate(ctx, filter)
+	if err != nil {
+		return 0, false, err
+	}
+
+	s.counts.Set(ctx, key, n)
+
+	return n, exact, nil
+}
+
 // QueryByID gets the specified user from the database.
 func (s *Store) QueryByID(ctx context.Context, userID uuid.UUID) (userbus.User, error) {
-	cachedUsr, ok := s.readCache(userID.String())
-	if ok {
-		return cachedUsr, nil
+	return s.fetch(ctx, userID.String(), func(ctx context.Context) (userbus.User, error) {
+		return s.storer.QueryByID(ctx, userID)
+	})
+}
+
+// QueryByIDs gets the specified users, only going to the database for the
+// users that aren't cached.
+func (s *Store) QueryByIDs(ctx context.Context, userIDs []uuid.UUID) ([]userbus.User, error) {
+	usrs := make([]userbus.User, 0, len(userIDs))
+	var missing []uuid.UUID
+
+	for _, id := range userIDs {
+		cachedUsr, ok := s.readCache(ctx, id.String())
+		if !ok {
+			missing = append(missing, id)
+			continue
+		}
+
+		usrs = append(usrs, cachedUsr)
+	}
+
+	if len(missing) == 0 {
+		return usrs, nil
 	}
 
-	usr, err := s.storer.QueryByID(ctx, userID)
+	dbUsrs, err := s.storer.QueryByIDs(ctx, missing)
 	if err != nil {
-		return userbus.User{}, err
+		return nil, err
 	}
 
-	s.writeCache(usr)
+	for _, usr := range dbUsrs {
+		s.writeCache(ctx, usr)
+	}
 
-	return usr, nil
+	return append(usrs, dbUsrs...), nil
 }
 
 // QueryByEmail gets the specified user from the database by email.
 func (s *Store) QueryByEmail(ctx context.Context, email mail.Address) (userbus.User, error) {
-	cachedUsr, ok := s.readCache(email.Address)
-	if ok {
-		return cachedUsr, nil
+	return s.fetch(ctx, email.Address, func(ctx context.Context) (userbus.User, error) {
+		return s.storer.QueryByEmail(ctx, email)
+	})
+}
+
+// QueryByEmails implements the userbus.Storer interface. Batch lookups
+// always go to the store so uniqueness checks never see stale data.
+func (s *Store) QueryByEmails(ctx context.Context, emails []mail.Address) ([]userbus.User, error) {
+	return s.storer.QueryByEmails(ctx, emails)
+}
+
+// QueryPasswordHistory implements the userbus.Storer interface. Password
+// history isn't cached.
+func (s *Store) QueryPasswordHistory(ctx context.Context, userID uuid.UUID, limit int) ([][]byte, error) {
+	return s.storer.QueryPasswordHistory(ctx, userID, limit)
+}
+
+// AddPasswordHistory implements the userbus.Storer interface.
+func (s *Store) AddPasswordHistory(ctx context.Context, userID uuid.UUID, passwordHash []byte, dateCreated time.Time) error {
+	return s.storer.AddPasswordHistory(ctx, userID, passwordHash, dateCreated)
+}
+
+// AddPasswordHistories implements the userbus.Storer interface.
+func (s *Store) AddPasswordHistories(ctx context.Context, usrs []userbus.User) error {
+	return s.storer.AddPasswordHistories(ctx, usrs)
+}
+
+// AddRecoveryCodes implements the userbus.Storer interface. Recovery codes
+// aren't cached.
+func (s *Store) AddRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes []string, dateCreated time.Time) error {
+	return s.storer.AddRecoveryCodes(ctx, userID, codeHashes, dateCreated)
+}
+
+// DeleteRecoveryCodes implements the userbus.Storer interface.
+func (s *Store) DeleteRecoveryCodes(ctx context.Context, userID uuid.UUID) error {
+	return s.storer.DeleteRecoveryCodes(ctx, userID)
+}
+
+// UseRecoveryCode implements the userbus.Storer interface.
+func (s *Store) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) error {
+	return s.storer.UseRecoveryCode(ctx, userID, codeHash)
+}
+
+// DirectReports implements the userbus.Storer interface. The org chart
+// isn't cached since any update can change it.
+func (s *Store) DirectReports(ctx context.Context, userID uuid.UUID) ([]userbus.User, error) {
+	return s.storer.DirectReports(ctx, userID)
+}
+
+// ManagementChain implements the userbus.Storer interface.
+func (s *Store) ManagementChain(ctx context.Context, userID uuid.UUID) ([]userbus.User, error) {
+	return s.storer.ManagementChain(ctx, userID)
+}
+
+// AddIdentity implements the userbus.Storer interface. Identities aren't
+// cached.
+func (s *Store) AddIdentity(ctx context.Context, idn userbus.Identity) error {
+	return s.storer.AddIdentity(ctx, idn)
+}
+
+// QueryIdentity implements the userbus.Storer interface.
+func (s *Store) QueryIdentity(ctx context.Context, provider string, externalID string) (userbus.Identity, error) {
+	return s.storer.QueryIdentity(ctx, provider, externalID)
+}
+
+// CreateRoleAssignment implements the userbus.Storer interface. Role
+// assignments aren't cached.
+func (s *Store) CreateRoleAssignment(ctx context.Context, ra userbus.RoleAssignment, chgs []userbus.RoleChange) error {
+	return s.storer.CreateRoleAssignment(ctx, ra, chgs)
+}
+
+// UpdateRoleAssignment implements the userbus.Storer interface.
+func (s *Store) UpdateRoleAssignment(ctx context.Context, ra userbus.RoleAssignment) error {
+	return s.storer.UpdateRoleAssignment(ctx, ra)
+}
+
+// QueryRoleAssignment implements the userbus.Storer interface.
+func (s *Store) QueryRoleAssignment(ctx context.Context, assignmentID uuid.UUID) (userbus.RoleAssignment, error) {
+	return s.storer.QueryRoleAssignment(ctx, assignmentID)
+}
+
+// QueryRoleAssignmentByRevertHash implements the userbus.Storer interface.
+func (s *Store) QueryRoleAssignmentByRevertHash(ctx context.Context, revertHash string) (userbus.RoleAssignment, error) {
+	return s.storer.QueryRoleAssignmentByRevertHash(ctx, revertHash)
+}
+
+// QueryRoleChanges implements the userbus.Storer interface.
+func (s *Store) QueryRoleChanges(ctx context.Context, assignmentID uuid.UUID) ([]userbus.RoleChange, error) {
+	return s.storer.QueryRoleChanges(ctx, assignmentID)
+}
+
+// AddIdempotencyKey implements the userbus.Storer interface. Idempotency keys
+// aren't cached.
+func (s *Store) AddIdempotencyKey(ctx context.Context, ik userbus.IdempotencyKey) error {
+	return s.storer.AddIdempotencyKey(ctx, ik)
+}
+
+// QueryIdempotencyKey implements the userbus.Storer interface.
+func (s *Store) QueryIdempotencyKey(ctx context.Context, actorID uuid.UUID, key string) (userbus.IdempotencyKey, error) {
+	return s.storer.QueryIdempotencyKey(ctx, actorID, key)
+}
+
+// DeleteIdempotencyKeys implements the userbus.Storer interface.
+func (s *Store) DeleteIdempotencyKeys(ctx context.Context, before time.Time) (int, error) {
+	return s.storer.DeleteIdempotencyKeys(ctx, before)
+}
+
+// QueryNames implements the userbus.Storer interface. The names are read
+// from the store since the cached users hold them normalized.
+func (s *Store) QueryNames(ctx context.Context, fn func(userbus.StoredName) error) error {
+	return s.storer.QueryNames(ctx, fn)
+}
+
+// SetPreference implements the userbus.Storer interface. Preferences aren't
+// cached.
+func (s *Store) SetPreference(ctx context.Context, pref userbus.Preference) error {
+	return s.storer.SetPreference(ctx, pref)
+}
+
+// QueryPreferences implements the userbus.Storer interface.
+func (s *Store) QueryPreferences(ctx context.Context, userID uuid.UUID) ([]userbus.Preference, error) {
+	return s.storer.QueryPreferences(ctx, userID)
+}
+
+// DeletePreference implements the userbus.Storer interface.
+func (s *Store) DeletePreference(ctx context.Context, userID uuid.UUID, key string) error {
+	return s.storer.DeletePreference(ctx, userID, key)
+}
+
+// DeletePersonalData removes the identities, password history, recovery
+// codes and preferences held for the user.
+func (s *Store) DeletePersonalData(ctx context.Context, userID uuid.UUID) error {
+	return s.storer.DeletePersonalData(ctx, userID)
+}
+
+// UpdateLastLogin sets the date the user last logged in. The cached user
+// is dropped so the next read picks up the date.
+func (s *Store) UpdateLastLogin(ctx context.Context, userID uuid.UUID, dateLastLogin time.Time) error {
+	if err := s.storer.UpdateLastLogin(ctx, userID, dateLastLogin); err != nil {
+		return err
 	}
 
-	usr, err := s.storer.QueryByEmail(ctx, email)
+	if usr, exists := s.users.Get(ctx, userID.String()); exists {
+		s.users.Delete(ctx, usr.ID.String(), usr.Email)
+	}
+	s.generation.Add(1)
+
+	return nil
+}
+
+// fetch gets the user held for the key, calling fn for it when there isn't
+// one. Concurrent misses for the same key share one call to fn.
+func (s *Store) fetch(ctx context.Context, key string, fn func(ctx context.Context) (userbus.User, error)) (userbus.User, error) {
+	cachedUsr, cached, err := s.users.Fetch(ctx, key, func(ctx context.Context) (user, error) {
+		usr, err := fn(ctx)
+		if err != nil {
+			return user{}, err
+		}
+
+		cu := toCacheUser(usr)
+		for _, k := range []string{usr.ID.String(), usr.Email.Address} {
+			if k != key {
+				s.users.Set(ctx, k, cu)
+			}
+		}
+
+		return cu, nil
+	})
+	diag.AddCache(ctx, "user:"+key, cached)
+
 	if err != nil {
 		return userbus.User{}, err
 	}
 
-	s.writeCache(usr)
+	usr, err := toBusUser(cachedUsr)
+	if err != nil {
+		s.log.Error(ctx, "usercache: fetch", "key", key, "ERROR", err)
+		s.users.Delete(ctx, key)
+		return fn(ctx)
+	}
+
+	if !inScope(ctx, usr) {
+		return userbus.User{}, userbus.ErrNotFound
+	}
 
 	return usr, nil
 }
 
 // readCache performs a safe search in the cache for the specified key.
-func (s *Store) readCache(key string) (userbus.User, bool) {
-	usr, exists := s.cache.Get(key)
+func (s *Store) readCache(ctx context.Context, key string) (userbus.User, bool) {
+	cachedUsr, exists := s.users.Get(ctx, key)
+	diag.AddCache(ctx, "user:"+key, exists)
+
 	if !exists {
 		return userbus.User{}, false
 	}
 
+	usr, err := toBusUser(cachedUsr)
+	if err != nil {
+		s.log.Error(ctx, "usercache: read", "key", key, "ERROR", err)
+		return userbus.User{}, false
+	}
+
+	if !inScope(ctx, usr) {
+		return userbus.User{}, false
+	}
+
 	return usr, true
 }
 
 // writeCache performs a safe write to the cache for the specified userbus.
-func (s *Store) writeCache(bus userbus.User) {
-	s.cache.Set(bus.ID.String(), bus)
-	s.cache.Set(bus.Email.Address, bus)
+func (s *Store) writeCache(ctx context.Context, bus userbus.User) {
+	usr := toCacheUser(bus)
+
+	s.users.Set(ctx, bus.ID.String(), usr)
+	s.users.Set(ctx, bus.Email.Address, usr)
+}
+
+// countKey returns the cache key for the count of the users that match the
+// filter. The key changes with every write through the store so the counts
+// cached before it are never read.
+func (s *Store) countKey(filter userbus.QueryFilter) (string, error) {
+	data, err := json.Marshal(filter)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+
+	return strconv.FormatUint(s.generation.Load(), 10) + ":" + hex.EncodeToString(sum[:]), nil
 }
 
 // deleteCache performs a safe removal from the cache for the specified userbus.
-func (s *Store) deleteCache(bus userbus.User) {
-	s.cache.Delete(bus.ID.String())
-	s.cache.Delete(bus.Email.Address)
+func (s *Store) deleteCache(ctx context.Context, bus userbus.User) {
+	s.users.Delete(ctx, bus.ID.String(), bus.Email.Address)
+}
+
+// inScope reports whether a cached user can be returned for the tenant in
+// the context. The cache is shared by every tenant, so a user of another
+// tenant is treated as if it wasn't there.
+func inScope(ctx context.Context, usr userbus.User) bool {
+	tenantID, ok := tenant.Get(ctx)
+	return !ok || usr.TenantID == tenantID
 }
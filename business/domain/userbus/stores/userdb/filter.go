@@ -6,9 +6,15 @@ import (
 	"strings"
 
 	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/sqldb/dbarray"
+	"github.com/ardanlabs/service/business/types/role"
 )
 
 func applyFilter(filter userbus.QueryFilter, data map[string]any, buf *bytes.Buffer) {
+	writeWhere(filterClauses(filter, data), buf)
+}
+
+func filterClauses(filter userbus.QueryFilter, data map[string]any) []string {
 	var wc []string
 
 	if filter.ID != nil {
@@ -16,6 +22,11 @@ func applyFilter(filter userbus.QueryFilter, data map[string]any, buf *bytes.Buf
 		wc = append(wc, "user_id = :user_id")
 	}
 
+	if filter.TenantID != nil {
+		data["tenant_id"] = filter.TenantID
+		wc = append(wc, "tenant_id = :tenant_id")
+	}
+
 	if filter.Name != nil {
 		data["name"] = fmt.Sprintf("%%%s%%", filter.Name)
 		wc = append(wc, "name LIKE :name")
@@ -26,6 +37,27 @@ func applyFilter(filter userbus.QueryFilter, data map[string]any, buf *bytes.Buf
 		wc = append(wc, "email = :email")
 	}
 
+	if filter.Search != nil {
+		data["search"] = *filter.Search
+		data["search_like"] = "%" + escapeLike(*filter.Search) + "%"
+		wc = append(wc, "(search_vector @@ websearch_to_tsquery('simple', :search) OR name ILIKE :search_like OR email ILIKE :search_like)")
+	}
+
+	if len(filter.Roles) > 0 {
+		data["roles"] = dbarray.String(role.ParseToString(filter.Roles))
+		wc = append(wc, "roles && CAST(:roles AS TEXT[])")
+	}
+
+	if filter.Enabled != nil {
+		data["enabled"] = *filter.Enabled
+		wc = append(wc, "enabled = :enabled")
+	}
+
+	if filter.Department != nil {
+		data["department"] = filter.Department.String()
+		wc = append(wc, "department = :department")
+	}
+
 	if filter.StartCreatedDate != nil {
 		data["start_date_created"] = filter.StartCreatedDate.UTC()
 		wc = append(wc, "date_created >= :start_date_created")
@@ -36,6 +68,22 @@ func applyFilter(filter userbus.QueryFilter, data map[string]any, buf *bytes.Buf
 		wc = append(wc, "date_created <= :end_date_created")
 	}
 
+	// Users that never logged in are measured from when they were created.
+	if filter.NotLoggedInSince != nil {
+		data["not_logged_in_since"] = filter.NotLoggedInSince.UTC()
+		wc = append(wc, "COALESCE(date_last_login, date_created) < :not_logged_in_since")
+	}
+
+	return wc
+}
+
+// escapeLike escapes the characters that are wildcards in a LIKE pattern so
+// the search is matched literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func writeWhere(wc []string, buf *bytes.Buffer) {
 	if len(wc) > 0 {
 		buf.WriteString(" WHERE ")
 		buf.WriteString(strings.Join(wc, " AND "))
@@ -0,0 +1,56 @@
+package userdb
+
+import (
+	"fmt"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+)
+
+// keysetTypes maps the fields a keyset page can be ordered by to the type
+// the cursor values are cast to.
+var keysetTypes = map[string]string{
+	userbus.OrderByName:    "TEXT",
+	userbus.OrderByEmail:   "TEXT",
+	userbus.OrderByRoles:   "TEXT[]",
+	userbus.OrderByEnabled: "BOOLEAN",
+}
+
+// keysetClause returns the condition that selects the rows following the
+// cursor in the specified order. Ties on the order field are broken by the
+// user id.
+func keysetClause(orderBy order.By, cursor []string, data map[string]any) (string, error) {
+	if len(orderBy.Then) > 0 {
+		return "", fmt.Errorf("order[%s]: multiple fields: %w", orderBy.Field, page.ErrInvalidCursor)
+	}
+
+	op := ">"
+	if orderBy.Direction == order.DESC {
+		op = "<"
+	}
+
+	if orderBy.Field == userbus.OrderByID {
+		if len(cursor) != 1 {
+			return "", fmt.Errorf("order[%s]: %w", orderBy.Field, page.ErrInvalidCursor)
+		}
+
+		data["cursor_id"] = cursor[0]
+
+		return "user_id " + op + " CAST(:cursor_id AS UUID)", nil
+	}
+
+	typ, exists := keysetTypes[orderBy.Field]
+	if !exists || len(cursor) != 2 {
+		return "", fmt.Errorf("order[%s]: %w", orderBy.Field, page.ErrInvalidCursor)
+	}
+
+	data["cursor_key"] = cursor[0]
+	data["cursor_id"] = cursor[1]
+
+	by := userbus.OrderFields.Columns()[orderBy.Field]
+
+	clause := fmt.Sprintf("(%s, user_id) %s (CAST(:cursor_key AS %s), CAST(:cursor_id AS UUID))", by, op, typ)
+
+	return clause, nil
+}
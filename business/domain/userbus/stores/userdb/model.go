@@ -2,32 +2,52 @@ package userdb
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"net/mail"
 	"time"
 
 	"github.com/ardanlabs/service/business/domain/userbus"
 	"github.com/ardanlabs/service/business/sdk/sqldb/dbarray"
+	"github.com/ardanlabs/service/business/types/department"
 	"github.com/ardanlabs/service/business/types/name"
 	"github.com/ardanlabs/service/business/types/role"
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx/types"
 )
 
 type user struct {
-	ID           uuid.UUID      `db:"user_id"`
-	Name         string         `db:"name"`
-	Email        string         `db:"email"`
-	Roles        dbarray.String `db:"roles"`
-	PasswordHash []byte         `db:"password_hash"`
-	Department   sql.NullString `db:"department"`
-	Enabled      bool           `db:"enabled"`
-	DateCreated  time.Time      `db:"date_created"`
-	DateUpdated  time.Time      `db:"date_updated"`
+	ID            uuid.UUID      `db:"user_id"`
+	TenantID      uuid.UUID      `db:"tenant_id"`
+	Name          string         `db:"name" class:"confidential"`
+	Email         string         `db:"email" class:"confidential"`
+	Roles         dbarray.String `db:"roles" class:"internal"`
+	PasswordHash  []byte         `db:"password_hash" class:"restricted"`
+	Department    sql.NullString `db:"department" class:"internal"`
+	ManagerID     uuid.NullUUID  `db:"manager_id"`
+	Enabled       bool           `db:"enabled"`
+	TOTPSecret    sql.NullString `db:"totp_secret" class:"restricted"`
+	TOTPEnabled   bool           `db:"totp_enabled"`
+	AvatarKey     sql.NullString `db:"avatar_key"`
+	CreatedBy     uuid.NullUUID  `db:"created_by"`
+	UpdatedBy     uuid.NullUUID  `db:"updated_by"`
+	DateCreated   time.Time      `db:"date_created"`
+	DateUpdated   time.Time      `db:"date_updated"`
+	DateLastLogin sql.NullTime   `db:"date_last_login"`
+	Version       int            `db:"version"`
+}
+
+// userWithCount is a user along with the number of users that match the
+// query that selected it.
+type userWithCount struct {
+	user
+	Total int `db:"total"`
 }
 
 func toDBUser(bus userbus.User) user {
 	return user{
 		ID:           bus.ID,
+		TenantID:     bus.TenantID,
 		Name:         bus.Name.String(),
 		Email:        bus.Email.Address,
 		Roles:        role.ParseToString(bus.Roles),
@@ -36,9 +56,26 @@ func toDBUser(bus userbus.User) user {
 			String: bus.Department.String(),
 			Valid:  bus.Department.Valid(),
 		},
-		Enabled:     bus.Enabled,
+		ManagerID: bus.ManagerID,
+		Enabled:   bus.Enabled,
+		TOTPSecret: sql.NullString{
+			String: bus.TOTPSecret,
+			Valid:  bus.TOTPSecret != "",
+		},
+		TOTPEnabled: bus.TOTPEnabled,
+		AvatarKey: sql.NullString{
+			String: bus.AvatarKey,
+			Valid:  bus.AvatarKey != "",
+		},
+		CreatedBy:   toDBActor(bus.CreatedBy),
+		UpdatedBy:   toDBActor(bus.UpdatedBy),
 		DateCreated: bus.DateCreated.UTC(),
 		DateUpdated: bus.DateUpdated.UTC(),
+		DateLastLogin: sql.NullTime{
+			Time:  bus.DateLastLogin.UTC(),
+			Valid: !bus.DateLastLogin.IsZero(),
+		},
+		Version: bus.Version,
 	}
 }
 
@@ -57,26 +94,49 @@ func toBusUser(db user) (userbus.User, error) {
 		return userbus.User{}, fmt.Errorf("parse name: %w", err)
 	}
 
-	department, err := name.ParseNull(db.Department.String)
+	dept, err := department.ParseNull(db.Department.String)
 	if err != nil {
 		return userbus.User{}, fmt.Errorf("parse department: %w", err)
 	}
 
+	var dateLastLogin time.Time
+	if db.DateLastLogin.Valid {
+		dateLastLogin = db.DateLastLogin.Time.In(time.Local)
+	}
+
 	bus := userbus.User{
-		ID:           db.ID,
-		Name:         nme,
-		Email:        addr,
-		Roles:        roles,
-		PasswordHash: db.PasswordHash,
-		Enabled:      db.Enabled,
-		Department:   department,
-		DateCreated:  db.DateCreated.In(time.Local),
-		DateUpdated:  db.DateUpdated.In(time.Local),
+		ID:            db.ID,
+		TenantID:      db.TenantID,
+		Name:          nme,
+		Email:         addr,
+		Roles:         roles,
+		PasswordHash:  db.PasswordHash,
+		Enabled:       db.Enabled,
+		Department:    dept,
+		ManagerID:     db.ManagerID,
+		TOTPSecret:    db.TOTPSecret.String,
+		TOTPEnabled:   db.TOTPEnabled,
+		AvatarKey:     db.AvatarKey.String,
+		CreatedBy:     db.CreatedBy.UUID,
+		UpdatedBy:     db.UpdatedBy.UUID,
+		DateCreated:   db.DateCreated.In(time.Local),
+		DateUpdated:   db.DateUpdated.In(time.Local),
+		DateLastLogin: dateLastLogin,
+		Version:       db.Version,
 	}
 
 	return bus, nil
 }
 
+// toDBActor stores an unknown actor as NULL, users created before changes
+// were attributed don't have one.
+func toDBActor(actorID uuid.UUID) uuid.NullUUID {
+	return uuid.NullUUID{
+		UUID:  actorID,
+		Valid: actorID != uuid.Nil,
+	}
+}
+
 func toBusUsers(dbs []user) ([]userbus.User, error) {
 	bus := make([]userbus.User, len(dbs))
 
@@ -90,3 +150,222 @@ func toBusUsers(dbs []user) ([]userbus.User, error) {
 
 	return bus, nil
 }
+
+// =============================================================================
+
+type passwordHistory struct {
+	UserID       uuid.UUID `db:"user_id"`
+	PasswordHash []byte    `db:"password_hash" class:"restricted"`
+	DateCreated  time.Time `db:"date_created"`
+}
+
+type identity struct {
+	Provider    string    `db:"provider"`
+	ExternalID  string    `db:"external_id"`
+	UserID      uuid.UUID `db:"user_id"`
+	Email       string    `db:"email" class:"confidential"`
+	DateCreated time.Time `db:"date_created"`
+}
+
+func toDBIdentity(bus userbus.Identity) identity {
+	return identity{
+		Provider:    bus.Provider,
+		ExternalID:  bus.ExternalID,
+		UserID:      bus.UserID,
+		Email:       bus.Email.Address,
+		DateCreated: bus.DateCreated.UTC(),
+	}
+}
+
+func toBusIdentity(db identity) userbus.Identity {
+	return userbus.Identity{
+		Provider:    db.Provider,
+		ExternalID:  db.ExternalID,
+		UserID:      db.UserID,
+		Email:       mail.Address{Address: db.Email},
+		DateCreated: db.DateCreated.In(time.Local),
+	}
+}
+
+type storedName struct {
+	ID         uuid.UUID      `db:"user_id"`
+	Name       string         `db:"name" class:"confidential"`
+	Department sql.NullString `db:"department" class:"internal"`
+}
+
+func toBusStoredName(db storedName) userbus.StoredName {
+	return userbus.StoredName{
+		UserID:     db.ID,
+		Name:       db.Name,
+		Department: db.Department.String,
+	}
+}
+
+type preference struct {
+	UserID      uuid.UUID      `db:"user_id"`
+	Key         string         `db:"key"`
+	Value       types.JSONText `db:"value"`
+	DateUpdated time.Time      `db:"date_updated"`
+}
+
+func toDBPreference(bus userbus.Preference) preference {
+	return preference{
+		UserID:      bus.UserID,
+		Key:         bus.Key,
+		Value:       types.JSONText(bus.Value),
+		DateUpdated: bus.DateUpdated.UTC(),
+	}
+}
+
+func toBusPreferences(dbs []preference) []userbus.Preference {
+	bus := make([]userbus.Preference, len(dbs))
+
+	for i, db := range dbs {
+		bus[i] = userbus.Preference{
+			UserID:      db.UserID,
+			Key:         db.Key,
+			Value:       json.RawMessage(db.Value),
+			DateUpdated: db.DateUpdated.In(time.Local),
+		}
+	}
+
+	return bus
+}
+
+type idempotencyKey struct {
+	ActorID     uuid.UUID `db:"actor_id"`
+	Key         string    `db:"key"`
+	UserID      uuid.UUID `db:"user_id"`
+	DateCreated time.Time `db:"date_created"`
+}
+
+func toDBIdempotencyKey(bus userbus.IdempotencyKey) idempotencyKey {
+	return idempotencyKey{
+		ActorID:     bus.ActorID,
+		Key:         bus.Key,
+		UserID:      bus.UserID,
+		DateCreated: bus.DateCreated.UTC(),
+	}
+}
+
+func toBusIdempotencyKey(db idempotencyKey) userbus.IdempotencyKey {
+	return userbus.IdempotencyKey{
+		ActorID:     db.ActorID,
+		Key:         db.Key,
+		UserID:      db.UserID,
+		DateCreated: db.DateCreated.In(time.Local),
+	}
+}
+
+type recoveryCode struct {
+	UserID      uuid.UUID `db:"user_id"`
+	CodeHash    string    `db:"code_hash" class:"restricted"`
+	DateCreated time.Time `db:"date_created"`
+}
+
+type roleAssignment struct {
+	ID           uuid.UUID      `db:"assignment_id"`
+	ActorID      uuid.UUID      `db:"actor_id"`
+	AddRoles     dbarray.String `db:"add_roles"`
+	RemoveRoles  dbarray.String `db:"remove_roles"`
+	Affected     int            `db:"affected"`
+	RevertHash   sql.NullString `db:"revert_hash" class:"restricted"`
+	DateCreated  time.Time      `db:"date_created"`
+	DateApplied  sql.NullTime   `db:"date_applied"`
+	DateReverted sql.NullTime   `db:"date_reverted"`
+}
+
+func toDBRoleAssignment(bus userbus.RoleAssignment) roleAssignment {
+	return roleAssignment{
+		ID:          bus.ID,
+		ActorID:     bus.ActorID,
+		AddRoles:    role.ParseToString(bus.AddRoles),
+		RemoveRoles: role.ParseToString(bus.RemoveRoles),
+		Affected:    bus.Affected,
+		RevertHash: sql.NullString{
+			String: bus.RevertHash,
+			Valid:  bus.RevertHash != "",
+		},
+		DateCreated: bus.DateCreated.UTC(),
+		DateApplied: sql.NullTime{
+			Time:  bus.DateApplied.UTC(),
+			Valid: !bus.DateApplied.IsZero(),
+		},
+		DateReverted: sql.NullTime{
+			Time:  bus.DateReverted.UTC(),
+			Valid: !bus.DateReverted.IsZero(),
+		},
+	}
+}
+
+func toBusRoleAssignment(db roleAssignment) (userbus.RoleAssignment, error) {
+	addRoles, err := role.ParseMany(db.AddRoles)
+	if err != nil {
+		return userbus.RoleAssignment{}, fmt.Errorf("parse add roles: %w", err)
+	}
+
+	removeRoles, err := role.ParseMany(db.RemoveRoles)
+	if err != nil {
+		return userbus.RoleAssignment{}, fmt.Errorf("parse remove roles: %w", err)
+	}
+
+	bus := userbus.RoleAssignment{
+		ID:          db.ID,
+		ActorID:     db.ActorID,
+		AddRoles:    addRoles,
+		RemoveRoles: removeRoles,
+		Affected:    db.Affected,
+		RevertHash:  db.RevertHash.String,
+		DateCreated: db.DateCreated.In(time.Local),
+	}
+
+	if db.DateApplied.Valid {
+		bus.DateApplied = db.DateApplied.Time.In(time.Local)
+	}
+
+	if db.DateReverted.Valid {
+		bus.DateReverted = db.DateReverted.Time.In(time.Local)
+	}
+
+	return bus, nil
+}
+
+type roleChange struct {
+	AssignmentID uuid.UUID      `db:"assignment_id"`
+	UserID       uuid.UUID      `db:"user_id"`
+	OldRoles     dbarray.String `db:"old_roles" class:"internal"`
+	NewRoles     dbarray.String `db:"new_roles" class:"internal"`
+}
+
+func toDBRoleChange(assignmentID uuid.UUID, bus userbus.RoleChange) roleChange {
+	return roleChange{
+		AssignmentID: assignmentID,
+		UserID:       bus.UserID,
+		OldRoles:     role.ParseToString(bus.OldRoles),
+		NewRoles:     role.ParseToString(bus.NewRoles),
+	}
+}
+
+func toBusRoleChanges(dbs []roleChange) ([]userbus.RoleChange, error) {
+	bus := make([]userbus.RoleChange, len(dbs))
+
+	for i, db := range dbs {
+		oldRoles, err := role.ParseMany(db.OldRoles)
+		if err != nil {
+			return nil, fmt.Errorf("parse old roles: %w", err)
+		}
+
+		newRoles, err := role.ParseMany(db.NewRoles)
+		if err != nil {
+			return nil, fmt.Errorf("parse new roles: %w", err)
+		}
+
+		bus[i] = userbus.RoleChange{
+			UserID:   db.UserID,
+			OldRoles: oldRoles,
+			NewRoles: newRoles,
+		}
+	}
+
+	return bus, nil
+}
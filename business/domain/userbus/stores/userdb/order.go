@@ -2,24 +2,39 @@ package userdb
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/ardanlabs/service/business/domain/userbus"
 	"github.com/ardanlabs/service/business/sdk/order"
 )
 
-var orderByFields = map[string]string{
-	userbus.OrderByID:      "user_id",
-	userbus.OrderByName:    "name",
-	userbus.OrderByEmail:   "email",
-	userbus.OrderByRoles:   "roles",
-	userbus.OrderByEnabled: "enabled",
-}
+// rankClause orders by the rank of the search, which is bound by the search
+// filter. The best matches have the highest rank so the direction is
+// reversed.
+const rankClause = " ORDER BY ts_rank(search_vector, websearch_to_tsquery('simple', :search))"
 
 func orderByClause(orderBy order.By) (string, error) {
-	by, exists := orderByFields[orderBy.Field]
-	if !exists {
-		return "", fmt.Errorf("field %q does not exist", orderBy.Field)
+	if orderBy.Field != userbus.OrderByRelevance {
+		return userbus.OrderFields.Clause(orderBy)
+	}
+
+	var clause string
+	switch orderBy.Direction {
+	case order.ASC:
+		clause = rankClause + " DESC"
+	case order.DESC:
+		clause = rankClause + " ASC"
+	default:
+		return "", fmt.Errorf("field %q has an unknown direction %q", orderBy.Field, orderBy.Direction)
+	}
+
+	for _, then := range orderBy.Then {
+		terms, err := userbus.OrderFields.Terms(then)
+		if err != nil {
+			return "", err
+		}
+		clause += ", " + strings.Join(terms, ", ")
 	}
 
-	return " ORDER BY " + by + " " + orderBy.Direction, nil
+	return clause, nil
 }
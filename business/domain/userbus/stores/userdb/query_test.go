@@ -0,0 +1,209 @@
+package userdb
+
+import (
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/types/department"
+	"github.com/ardanlabs/service/business/types/name"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// FuzzQueryStatement checks the statements built for the users query never
+// carry the values of the filter or the page. A statement built from any
+// values must be the same statement built from fixed values for the same
+// filters, must bind every parameter it names and must stay well formed.
+// The order is the only input written into the statement and it must be one
+// of the fields and directions that can be ordered by.
+func FuzzQueryStatement(f *testing.F) {
+	f.Add("Bill", "bill@example.com", "bill kennedy", "Engineering", "ADMIN", uint8(0), "a", "ASC", "", "", "", "10")
+	f.Add("Name", "", "'; DROP TABLE users; --", "", "USER", uint8(1), "f", "DESC", "b", "ASC", "", "10")
+	f.Add("", "x@y.z", "%_\\", "R&D", "", uint8(2), "b", "DESC", "", "", page.NextCursor("bill", uuid.NewString()), "5")
+	f.Add("", "", "", "", "", uint8(3), "b", "ASC; DELETE FROM users", "", "", "", "10")
+	f.Add("", "", "search", "", "", uint8(4), "f", "ASC) UNION SELECT 1 --", "", "", "", "10")
+	f.Add("", "", "", "", "", uint8(5), "a) OR (1=1", "ASC", "", "", page.NextCursor(`') OR 1=1 --`), "10")
+	f.Add("", "", "search", "", "", uint8(16), "f", "ASC, (SELECT 1)", "", "", "", "10")
+	f.Add("Bill", "", "", "Engineering", "", uint8(33), "a", "DESC", "", "", "", "10")
+
+	f.Fuzz(func(t *testing.T, nme string, email string, search string, dept string, rle string, flags uint8, field string, dir string, thenField string, thenDir string, cursor string, rows string) {
+		filter, fixed := fuzzFilters(nme, email, search, dept, rle, flags)
+
+		orderBy := order.By{Field: field, Direction: dir}
+		if thenField != "" {
+			orderBy.Then = []order.By{{Field: thenField, Direction: thenDir}}
+		}
+
+		pg, fixedPg, ok := fuzzPages(flags, cursor, rows)
+		if !ok {
+			return
+		}
+
+		withCount := flags&32 != 0
+
+		q, data, err := queryStatement(filter, orderBy, pg, withCount)
+		if err != nil {
+			return
+		}
+
+		// The order is written into the statement so it has to be one the
+		// users can be ordered by.
+		columns := userbus.OrderFields.Columns()
+		for _, by := range orderBy.Columns() {
+			if _, exists := columns[by.Field]; !exists {
+				t.Fatalf("Should reject an unknown field %q:\n%s", by.Field, q)
+			}
+			if by.Direction != order.ASC && by.Direction != order.DESC {
+				t.Fatalf("Should reject an unknown direction %q:\n%s", by.Direction, q)
+			}
+		}
+
+		// The values are bound, so fixed values give the same statement.
+		fixedQ, _, err := queryStatement(fixed, orderBy, fixedPg, withCount)
+		if err != nil {
+			t.Fatalf("Should build the statement for fixed values: %s", err)
+		}
+
+		if q != fixedQ {
+			t.Fatalf("Should not write values into the statement:\n%s\n%s", q, fixedQ)
+		}
+
+		// Every parameter the statement names must be bound.
+		if _, _, err := sqlx.Named(q, data); err != nil {
+			t.Fatalf("Should bind every parameter: %s:\n%s", err, q)
+		}
+
+		checkWellFormed(t, q)
+	})
+}
+
+// fuzzFilters returns a filter made from the values and the same filter made
+// from fixed values. The flags decide which of the filters are set.
+func fuzzFilters(nme string, email string, search string, dept string, rle string, flags uint8) (userbus.QueryFilter, userbus.QueryFilter) {
+	var filter, fixed userbus.QueryFilter
+
+	if n, err := name.Parse(nme); err == nil {
+		filter.Name = &n
+		fixed.Name = ptr(name.MustParse("Name"))
+	}
+
+	if email != "" {
+		filter.Email = &mail.Address{Address: email}
+		fixed.Email = &mail.Address{Address: "fixed@example.com"}
+	}
+
+	if search != "" {
+		filter.Search = &search
+		fixed.Search = ptr("fixed")
+	}
+
+	if d, err := department.Parse(dept); err == nil {
+		filter.Department = &d
+		fixed.Department = ptr(department.MustParse("Department"))
+	}
+
+	if r, err := role.Parse(rle); err == nil {
+		filter.Roles = []role.Role{r}
+		fixed.Roles = []role.Role{role.User}
+	}
+
+	if flags&1 != 0 {
+		enabled := flags&2 != 0
+		filter.Enabled = &enabled
+		fixed.Enabled = ptr(true)
+	}
+
+	if flags&4 != 0 {
+		id := uuid.New()
+		filter.ID = &id
+		fixed.ID = ptr(uuid.Nil)
+		filter.TenantID = &id
+		fixed.TenantID = ptr(uuid.Nil)
+	}
+
+	if flags&8 != 0 {
+		now := time.Now()
+		filter.StartCreatedDate = &now
+		filter.EndCreatedDate = &now
+		filter.NotLoggedInSince = &now
+		fixed.StartCreatedDate = ptr(time.Time{})
+		fixed.EndCreatedDate = ptr(time.Time{})
+		fixed.NotLoggedInSince = ptr(time.Time{})
+	}
+
+	return filter, fixed
+}
+
+// fuzzPages returns the page made from the values and the same page with
+// fixed values. Keyset pages are used when the flags ask for them.
+func fuzzPages(flags uint8, cursor string, rows string) (page.Page, page.Page, bool) {
+	if flags&16 == 0 {
+		pg, err := page.Parse("1", rows)
+		if err != nil {
+			return page.Page{}, page.Page{}, false
+		}
+
+		return pg, page.MustParse("2", rows), true
+	}
+
+	pg, err := page.ParseCursor(cursor, rows)
+	if err != nil {
+		return page.Page{}, page.Page{}, false
+	}
+
+	var fixedCursor string
+	if n := len(pg.Cursor()); n > 0 {
+		values := make([]string, n)
+		for i := range values {
+			values[i] = "fixed"
+		}
+		fixedCursor = page.NextCursor(values...)
+	}
+
+	return pg, page.MustParseCursor(fixedCursor, rows), true
+}
+
+// checkWellFormed fails the test if the statement has unbalanced
+// parentheses or quotes, or holds anything that ends or comments out a
+// statement.
+func checkWellFormed(t *testing.T, q string) {
+	t.Helper()
+
+	for _, bad := range []string{";", "--", "/*", "*/"} {
+		if strings.Contains(q, bad) {
+			t.Fatalf("Should not contain %q:\n%s", bad, q)
+		}
+	}
+
+	if strings.Count(q, "'")%2 != 0 {
+		t.Fatalf("Should have balanced quotes:\n%s", q)
+	}
+
+	var depth int
+	for _, c := range q {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
+
+		if depth < 0 {
+			t.Fatalf("Should have balanced parentheses:\n%s", q)
+		}
+	}
+
+	if depth != 0 {
+		t.Fatalf("Should have balanced parentheses:\n%s", q)
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -7,27 +7,52 @@ import (
 	"errors"
 	"fmt"
 	"net/mail"
+	"slices"
+	"time"
 
 	"github.com/ardanlabs/service/business/domain/userbus"
 	"github.com/ardanlabs/service/business/sdk/order"
 	"github.com/ardanlabs/service/business/sdk/page"
 	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/business/sdk/sqldb/dbarray"
+	"github.com/ardanlabs/service/business/sdk/tenant"
 	"github.com/ardanlabs/service/foundation/logger"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 )
 
+// Options represent optional parameters.
+type Options struct {
+	costBudget float64
+}
+
+// WithCostBudget rejects the list queries the planner estimates will cost
+// more than the budget, in the planner's own units, with
+// ErrQueryTooExpensive. A budget of zero leaves the queries unchecked.
+func WithCostBudget(budget float64) func(opts *Options) {
+	return func(opts *Options) {
+		opts.costBudget = budget
+	}
+}
+
 // Store manages the set of APIs for user database access.
 type Store struct {
-	log *logger.Logger
-	db  sqlx.ExtContext
+	log        *logger.Logger
+	db         sqlx.ExtContext
+	costBudget float64
 }
 
 // NewStore constructs the api for data access.
-func NewStore(log *logger.Logger, db *sqlx.DB) *Store {
+func NewStore(log *logger.Logger, db sqlx.ExtContext, options ...func(opts *Options)) *Store {
+	var opts Options
+	for _, option := range options {
+		option(&opts)
+	}
+
 	return &Store{
-		log: log,
-		db:  db,
+		log:        log,
+		db:         db,
+		costBudget: opts.costBudget,
 	}
 }
 
@@ -40,8 +65,9 @@ func (s *Store) NewWithTx(tx sqldb.CommitRollbacker) (userbus.Storer, error) {
 	}
 
 	store := Store{
-		log: s.log,
-		db:  ec,
+		log:        s.log,
+		db:         ec,
+		costBudget: s.costBudget,
 	}
 
 	return &store, nil
@@ -51,9 +77,9 @@ func (s *Store) NewWithTx(tx sqldb.CommitRollbacker) (userbus.Storer, error) {
 func (s *Store) Create(ctx context.Context, usr userbus.User) error {
 	const q = `
 	INSERT INTO users
-		(user_id, name, email, password_hash, roles, department, enabled, date_created, date_updated)
+		(user_id, tenant_id, name, email, password_hash, roles, department, manager_id, enabled, totp_secret, totp_enabled, avatar_key, created_by, updated_by, date_created, date_updated, date_last_login, version)
 	VALUES
-		(:user_id, :name, :email, :password_hash, :roles, :department, :enabled, :date_created, :date_updated)`
+		(:user_id, :tenant_id, :name, :email, :password_hash, :roles, :department, :manager_id, :enabled, :totp_secret, :totp_enabled, :avatar_key, :created_by, :updated_by, :date_created, :date_updated, :date_last_login, :version)`
 
 	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBUser(usr)); err != nil {
 		if errors.Is(err, sqldb.ErrDBDuplicatedEntry) {
@@ -65,8 +91,54 @@ func (s *Store) Create(ctx context.Context, usr userbus.User) error {
 	return nil
 }
 
-// Update replaces a user document in the database.
+// createChunk is the number of users inserted by a single statement in
+// CreateMany. It keeps the parameters of a statement well under the 65535
+// postgres allows.
+const createChunk = 1000
+
+// CreateMany inserts a set of users with one multi-row insert per chunk
+// instead of a round trip per user. Users whose email is already taken are
+// skipped rather than failing the set. The IDs of the users that were
+// inserted are returned.
+func (s *Store) CreateMany(ctx context.Context, usrs []userbus.User) ([]uuid.UUID, error) {
+	const q = `
+	INSERT INTO users
+		(user_id, tenant_id, name, email, password_hash, roles, department, manager_id, enabled, totp_secret, totp_enabled, avatar_key, created_by, updated_by, date_created, date_updated, date_last_login, version)
+	VALUES
+		(:user_id, :tenant_id, :name, :email, :password_hash, :roles, :department, :manager_id, :enabled, :totp_secret, :totp_enabled, :avatar_key, :created_by, :updated_by, :date_created, :date_updated, :date_last_login, :version)
+	ON CONFLICT (email) DO NOTHING
+	RETURNING
+		user_id`
+
+	ids := make([]uuid.UUID, 0, len(usrs))
+
+	for chunk := range slices.Chunk(usrs, createChunk) {
+		dbUsrs := make([]user, len(chunk))
+		for i, usr := range chunk {
+			dbUsrs[i] = toDBUser(usr)
+		}
+
+		var created []struct {
+			ID uuid.UUID `db:"user_id"`
+		}
+		if err := sqldb.NamedQuerySlice(ctx, s.log, s.db, q, dbUsrs, &created); err != nil {
+			return nil, fmt.Errorf("namedqueryslice: %w", err)
+		}
+
+		for _, c := range created {
+			ids = append(ids, c.ID)
+		}
+	}
+
+	return ids, nil
+}
+
+// Update replaces a user document in the database. The update only applies
+// when the stored version is the one before the user's version, otherwise
+// ErrVersionConflict is returned.
 func (s *Store) Update(ctx context.Context, usr userbus.User) error {
+	data := scopeUser(ctx, usr)
+
 	const q = `
 	UPDATE
 		users
@@ -76,16 +148,33 @@ func (s *Store) Update(ctx context.Context, usr userbus.User) error {
 		"roles" = :roles,
 		"password_hash" = :password_hash,
 		"department" = :department,
+		"manager_id" = :manager_id,
 		"enabled" = :enabled,
-		"date_updated" = :date_updated
+		"totp_secret" = :totp_secret,
+		"totp_enabled" = :totp_enabled,
+		"avatar_key" = :avatar_key,
+		"updated_by" = :updated_by,
+		"date_updated" = :date_updated,
+		"version" = :version
 	WHERE
-		user_id = :user_id`
+		user_id = :user_id AND
+		tenant_id = :tenant_id AND
+		version = :version - 1
+	RETURNING
+		user_id`
 
-	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBUser(usr)); err != nil {
+	var dest struct {
+		ID uuid.UUID `db:"user_id"`
+	}
+
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &dest); err != nil {
 		if errors.Is(err, sqldb.ErrDBDuplicatedEntry) {
 			return userbus.ErrUniqueEmail
 		}
-		return fmt.Errorf("namedexeccontext: %w", err)
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return fmt.Errorf("namedquerystruct: userID[%s] version[%d]: %w", usr.ID, usr.Version, userbus.ErrVersionConflict)
+		}
+		return fmt.Errorf("namedquerystruct: %w", err)
 	}
 
 	return nil
@@ -93,13 +182,15 @@ func (s *Store) Update(ctx context.Context, usr userbus.User) error {
 
 // Delete removes a user from the database.
 func (s *Store) Delete(ctx context.Context, usr userbus.User) error {
+	data := scopeUser(ctx, usr)
+
 	const q = `
 	DELETE FROM
 		users
 	WHERE
-		user_id = :user_id`
+		user_id = :user_id AND tenant_id = :tenant_id`
 
-	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBUser(usr)); err != nil {
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
 		return fmt.Errorf("namedexeccontext: %w", err)
 	}
 
@@ -108,6 +199,91 @@ func (s *Store) Delete(ctx context.Context, usr userbus.User) error {
 
 // Query retrieves a list of existing users from the database.
 func (s *Store) Query(ctx context.Context, filter userbus.QueryFilter, orderBy order.By, page page.Page) ([]userbus.User, error) {
+	filter = scopeFilter(ctx, filter)
+
+	q, data, err := queryStatement(filter, orderBy, page, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkCost(ctx, q, data); err != nil {
+		return nil, err
+	}
+
+	var dbUsrs []user
+	if err := sqldb.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbUsrs); err != nil {
+		return nil, queryErr("namedqueryslice", err)
+	}
+
+	return toBusUsers(dbUsrs)
+}
+
+// QueryWithCount retrieves a page of users along with the number of users
+// that match the filter in a single statement, which counts the matches with
+// a window function over the filtered rows. The window can't see the rows
+// before a keyset cursor, and a page past the end has no rows to carry the
+// count, so those are counted with a second statement.
+func (s *Store) QueryWithCount(ctx context.Context, filter userbus.QueryFilter, orderBy order.By, page page.Page) ([]userbus.User, int, error) {
+	filter = scopeFilter(ctx, filter)
+
+	if page.IsKeyset() && len(page.Cursor()) > 0 {
+		usrs, err := s.Query(ctx, filter, orderBy, page)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		total, err := s.Count(ctx, filter)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		return usrs, total, nil
+	}
+
+	q, data, err := queryStatement(filter, orderBy, page, true)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if err := s.checkCost(ctx, q, data); err != nil {
+		return nil, 0, err
+	}
+
+	var dbUsrs []userWithCount
+	if err := sqldb.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbUsrs); err != nil {
+		return nil, 0, queryErr("namedqueryslice", err)
+	}
+
+	if len(dbUsrs) == 0 && page.Number() > 1 {
+		total, err := s.Count(ctx, filter)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		return nil, total, nil
+	}
+
+	var total int
+	users := make([]user, len(dbUsrs))
+	for i, dbUsr := range dbUsrs {
+		users[i] = dbUsr.user
+		total = dbUsr.Total
+	}
+
+	usrs, err := toBusUsers(users)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return usrs, total, nil
+}
+
+// queryStatement returns the statement that selects the page of users that
+// match the filter in the specified order, with the data bound to it. The
+// values of the filter and page are only ever bound, the order is checked
+// against the fields that can be ordered by. When asked, each row also
+// carries the number of users that match as total.
+func queryStatement(filter userbus.QueryFilter, orderBy order.By, page page.Page, withCount bool) (string, map[string]any, error) {
 	data := map[string]any{
 		"offset":        (page.Number() - 1) * page.RowsPerPage(),
 		"rows_per_page": page.RowsPerPage(),
@@ -115,7 +291,58 @@ func (s *Store) Query(ctx context.Context, filter userbus.QueryFilter, orderBy o
 
 	const q = `
 	SELECT
-		user_id, name, email, password_hash, roles, department, enabled, date_created, date_updated
+		user_id, tenant_id, name, email, password_hash, roles, department, manager_id, enabled, totp_secret, totp_enabled, avatar_key, created_by, updated_by, date_created, date_updated, date_last_login, version`
+
+	buf := bytes.NewBufferString(q)
+
+	if withCount {
+		buf.WriteString(", count(1) OVER () AS total")
+	}
+
+	buf.WriteString(" FROM users")
+
+	orderByClause, err := orderByClause(orderBy)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if page.IsKeyset() {
+		wc := filterClauses(filter, data)
+
+		if cursor := page.Cursor(); len(cursor) > 0 {
+			kc, err := keysetClause(orderBy, cursor, data)
+			if err != nil {
+				return "", nil, err
+			}
+			wc = append(wc, kc)
+		}
+
+		writeWhere(wc, buf)
+		buf.WriteString(orderByClause)
+
+		if orderBy.Field != userbus.OrderByID {
+			buf.WriteString(", user_id " + orderBy.Direction)
+		}
+
+		buf.WriteString(" FETCH NEXT :rows_per_page ROWS ONLY")
+	} else {
+		applyFilter(filter, data, buf)
+		buf.WriteString(orderByClause)
+		buf.WriteString(" OFFSET :offset ROWS FETCH NEXT :rows_per_page ROWS ONLY")
+	}
+
+	return buf.String(), data, nil
+}
+
+// QueryAll streams every user that matches the filter from the database.
+func (s *Store) QueryAll(ctx context.Context, filter userbus.QueryFilter, orderBy order.By, fn func(userbus.User) error) error {
+	filter = scopeFilter(ctx, filter)
+
+	data := map[string]any{}
+
+	const q = `
+	SELECT
+		user_id, tenant_id, name, email, password_hash, roles, department, manager_id, enabled, totp_secret, totp_enabled, avatar_key, created_by, updated_by, date_created, date_updated, date_last_login, version
 	FROM
 		users`
 
@@ -124,22 +351,57 @@ func (s *Store) Query(ctx context.Context, filter userbus.QueryFilter, orderBy o
 
 	orderByClause, err := orderByClause(orderBy)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	buf.WriteString(orderByClause)
-	buf.WriteString(" OFFSET :offset ROWS FETCH NEXT :rows_per_page ROWS ONLY")
 
-	var dbUsrs []user
-	if err := sqldb.NamedQuerySlice(ctx, s.log, s.db, buf.String(), data, &dbUsrs); err != nil {
-		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	f := func(dbUsr user) error {
+		usr, err := toBusUser(dbUsr)
+		if err != nil {
+			return err
+		}
+
+		return fn(usr)
 	}
 
-	return toBusUsers(dbUsrs)
+	if err := sqldb.NamedQueryIter(ctx, s.log, s.db, buf.String(), data, f); err != nil {
+		return queryErr("namedqueryiter", err)
+	}
+
+	return nil
+}
+
+// QueryNames calls the function with the name and department of every user
+// as they are stored.
+func (s *Store) QueryNames(ctx context.Context, fn func(userbus.StoredName) error) error {
+	data := map[string]any{
+		"tenant_id": tenant.Scope(ctx),
+	}
+
+	const q = `
+	SELECT
+		user_id, name, department
+	FROM
+		users
+	WHERE
+		CAST(:tenant_id AS UUID) IS NULL OR tenant_id = :tenant_id`
+
+	f := func(dbName storedName) error {
+		return fn(toBusStoredName(dbName))
+	}
+
+	if err := sqldb.NamedQueryIter(ctx, s.log, s.db, q, data, f); err != nil {
+		return fmt.Errorf("namedqueryiter: %w", err)
+	}
+
+	return nil
 }
 
 // Count returns the total number of users in the DB.
 func (s *Store) Count(ctx context.Context, filter userbus.QueryFilter) (int, error) {
+	filter = scopeFilter(ctx, filter)
+
 	data := map[string]any{}
 
 	const q = `
@@ -151,31 +413,46 @@ func (s *Store) Count(ctx context.Context, filter userbus.QueryFilter) (int, err
 	buf := bytes.NewBufferString(q)
 	applyFilter(filter, data, buf)
 
+	if err := s.checkCost(ctx, buf.String(), data); err != nil {
+		return 0, err
+	}
+
 	var count struct {
 		Count int `db:"count"`
 	}
 	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, buf.String(), data, &count); err != nil {
-		return 0, fmt.Errorf("db: %w", err)
+		return 0, queryErr("db", err)
 	}
 
 	return count.Count, nil
 }
 
+// CountEstimate implements the userbus.Storer interface. The users are
+// always counted, so the number is exact.
+func (s *Store) CountEstimate(ctx context.Context, filter userbus.QueryFilter) (int, bool, error) {
+	n, err := s.Count(ctx, filter)
+	if err != nil {
+		return 0, false, err
+	}
+
+	return n, true, nil
+}
+
 // QueryByID gets the specified user from the database.
 func (s *Store) QueryByID(ctx context.Context, userID uuid.UUID) (userbus.User, error) {
-	data := struct {
-		ID string `db:"user_id"`
-	}{
-		ID: userID.String(),
+	data := map[string]any{
+		"user_id":   userID.String(),
+		"tenant_id": tenant.Scope(ctx),
 	}
 
 	const q = `
 	SELECT
-        user_id, name, email, password_hash, roles, department, enabled, date_created, date_updated
+        user_id, tenant_id, name, email, password_hash, roles, department, manager_id, enabled, totp_secret, totp_enabled, avatar_key, created_by, updated_by, date_created, date_updated, date_last_login, version
 	FROM
 		users
 	WHERE 
-		user_id = :user_id`
+		user_id = :user_id AND
+		(CAST(:tenant_id AS UUID) IS NULL OR tenant_id = :tenant_id)`
 
 	var dbUsr user
 	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbUsr); err != nil {
@@ -188,21 +465,55 @@ func (s *Store) QueryByID(ctx context.Context, userID uuid.UUID) (userbus.User,
 	return toBusUser(dbUsr)
 }
 
+// QueryByIDs gets the users from the database that match any of the
+// specified ids.
+func (s *Store) QueryByIDs(ctx context.Context, userIDs []uuid.UUID) ([]userbus.User, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+
+	ids := make(dbarray.String, len(userIDs))
+	for i, id := range userIDs {
+		ids[i] = id.String()
+	}
+
+	data := map[string]any{
+		"user_ids":  ids,
+		"tenant_id": tenant.Scope(ctx),
+	}
+
+	const q = `
+	SELECT
+        user_id, tenant_id, name, email, password_hash, roles, department, manager_id, enabled, totp_secret, totp_enabled, avatar_key, created_by, updated_by, date_created, date_updated, date_last_login, version
+	FROM
+		users
+	WHERE
+		user_id = ANY(CAST(:user_ids AS UUID[])) AND
+		(CAST(:tenant_id AS UUID) IS NULL OR tenant_id = :tenant_id)`
+
+	var dbUsrs []user
+	if err := sqldb.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbUsrs); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toBusUsers(dbUsrs)
+}
+
 // QueryByEmail gets the specified user from the database by email.
 func (s *Store) QueryByEmail(ctx context.Context, email mail.Address) (userbus.User, error) {
-	data := struct {
-		Email string `db:"email"`
-	}{
-		Email: email.Address,
+	data := map[string]any{
+		"email":     email.Address,
+		"tenant_id": tenant.Scope(ctx),
 	}
 
 	const q = `
 	SELECT
-        user_id, name, email, password_hash, roles, department, enabled, date_created, date_updated
+        user_id, tenant_id, name, email, password_hash, roles, department, manager_id, enabled, totp_secret, totp_enabled, avatar_key, created_by, updated_by, date_created, date_updated, date_last_login, version
 	FROM
 		users
 	WHERE
-		email = :email`
+		email = :email AND
+		(CAST(:tenant_id AS UUID) IS NULL OR tenant_id = :tenant_id)`
 
 	var dbUsr user
 	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbUsr); err != nil {
@@ -214,3 +525,692 @@ func (s *Store) QueryByEmail(ctx context.Context, email mail.Address) (userbus.U
 
 	return toBusUser(dbUsr)
 }
+
+// QueryByEmails gets the users from the database that match any of the
+// specified emails.
+func (s *Store) QueryByEmails(ctx context.Context, emails []mail.Address) ([]userbus.User, error) {
+	if len(emails) == 0 {
+		return nil, nil
+	}
+
+	addrs := make(dbarray.String, len(emails))
+	for i, email := range emails {
+		addrs[i] = email.Address
+	}
+
+	data := map[string]any{
+		"emails":    addrs,
+		"tenant_id": tenant.Scope(ctx),
+	}
+
+	const q = `
+	SELECT
+        user_id, tenant_id, name, email, password_hash, roles, department, manager_id, enabled, totp_secret, totp_enabled, avatar_key, created_by, updated_by, date_created, date_updated, date_last_login, version
+	FROM
+		users
+	WHERE
+		email = ANY(:emails) AND
+		(CAST(:tenant_id AS UUID) IS NULL OR tenant_id = :tenant_id)`
+
+	var dbUsrs []user
+	if err := sqldb.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbUsrs); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toBusUsers(dbUsrs)
+}
+
+// QueryPasswordHistory retrieves the most recent password hashes recorded
+// for the specified user.
+func (s *Store) QueryPasswordHistory(ctx context.Context, userID uuid.UUID, limit int) ([][]byte, error) {
+	data := map[string]any{
+		"user_id": userID,
+		"limit":   limit,
+	}
+
+	const q = `
+	SELECT
+		user_id, password_hash, date_created
+	FROM
+		user_password_history
+	WHERE
+		user_id = :user_id
+	ORDER BY
+		date_created DESC
+	LIMIT :limit`
+
+	var dbHist []passwordHistory
+	if err := sqldb.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbHist); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	hashes := make([][]byte, len(dbHist))
+	for i, h := range dbHist {
+		hashes[i] = h.PasswordHash
+	}
+
+	return hashes, nil
+}
+
+// AddPasswordHistory records a password hash for the specified user.
+func (s *Store) AddPasswordHistory(ctx context.Context, userID uuid.UUID, passwordHash []byte, dateCreated time.Time) error {
+	const q = `
+	INSERT INTO user_password_history
+		(user_id, password_hash, date_created)
+	VALUES
+		(:user_id, :password_hash, :date_created)`
+
+	ph := passwordHistory{
+		UserID:       userID,
+		PasswordHash: passwordHash,
+		DateCreated:  dateCreated.UTC(),
+	}
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, ph); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// AddPasswordHistories records the current password hash of each of the
+// users with one multi-row insert per chunk.
+func (s *Store) AddPasswordHistories(ctx context.Context, usrs []userbus.User) error {
+	const q = `
+	INSERT INTO user_password_history
+		(user_id, password_hash, date_created)
+	VALUES
+		(:user_id, :password_hash, :date_created)`
+
+	for chunk := range slices.Chunk(usrs, createChunk) {
+		phs := make([]passwordHistory, len(chunk))
+		for i, usr := range chunk {
+			phs[i] = passwordHistory{
+				UserID:       usr.ID,
+				PasswordHash: usr.PasswordHash,
+				DateCreated:  usr.DateUpdated.UTC(),
+			}
+		}
+
+		if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, phs); err != nil {
+			return fmt.Errorf("namedexeccontext: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// AddRecoveryCodes records the hashes of a set of recovery codes for the
+// specified user.
+func (s *Store) AddRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes []string, dateCreated time.Time) error {
+	const q = `
+	INSERT INTO user_recovery_codes
+		(user_id, code_hash, date_created)
+	VALUES
+		(:user_id, :code_hash, :date_created)`
+
+	for _, hash := range codeHashes {
+		rc := recoveryCode{
+			UserID:      userID,
+			CodeHash:    hash,
+			DateCreated: dateCreated.UTC(),
+		}
+
+		if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, rc); err != nil {
+			return fmt.Errorf("namedexeccontext: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// DeleteRecoveryCodes removes all the recovery codes for the specified user.
+func (s *Store) DeleteRecoveryCodes(ctx context.Context, userID uuid.UUID) error {
+	data := struct {
+		UserID uuid.UUID `db:"user_id"`
+	}{
+		UserID: userID,
+	}
+
+	const q = `
+	DELETE FROM
+		user_recovery_codes
+	WHERE
+		user_id = :user_id`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// UseRecoveryCode removes the recovery code with the specified hash so it
+// can't be used again. ErrNotFound is returned if the user has no such code.
+func (s *Store) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) error {
+	data := struct {
+		UserID   uuid.UUID `db:"user_id"`
+		CodeHash string    `db:"code_hash"`
+	}{
+		UserID:   userID,
+		CodeHash: codeHash,
+	}
+
+	const q = `
+	DELETE FROM
+		user_recovery_codes
+	WHERE
+		user_id = :user_id AND code_hash = :code_hash
+	RETURNING
+		user_id, code_hash, date_created`
+
+	var rc recoveryCode
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &rc); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return fmt.Errorf("namedquerystruct: %w", userbus.ErrNotFound)
+		}
+		return fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return nil
+}
+
+// DirectReports gets the users from the database that report directly to
+// the specified user.
+func (s *Store) DirectReports(ctx context.Context, userID uuid.UUID) ([]userbus.User, error) {
+	data := map[string]any{
+		"user_id":   userID.String(),
+		"tenant_id": tenant.Scope(ctx),
+	}
+
+	const q = `
+	SELECT
+        user_id, tenant_id, name, email, password_hash, roles, department, manager_id, enabled, totp_secret, totp_enabled, avatar_key, created_by, updated_by, date_created, date_updated, date_last_login, version
+	FROM
+		users
+	WHERE
+		manager_id = :user_id AND
+		(CAST(:tenant_id AS UUID) IS NULL OR tenant_id = :tenant_id)
+	ORDER BY
+		name, user_id`
+
+	var dbUsrs []user
+	if err := sqldb.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbUsrs); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toBusUsers(dbUsrs)
+}
+
+// ManagementChain gets the managers above the specified user from the
+// database, starting with the user's own manager. The path of each row is
+// tracked so bad data with a cycle can't make the query run forever.
+func (s *Store) ManagementChain(ctx context.Context, userID uuid.UUID) ([]userbus.User, error) {
+	data := map[string]any{
+		"user_id":   userID.String(),
+		"tenant_id": tenant.Scope(ctx),
+	}
+
+	const q = `
+	WITH RECURSIVE chain AS (
+		SELECT
+			m.user_id, m.tenant_id, m.name, m.email, m.password_hash, m.roles, m.department, m.manager_id, m.enabled, m.totp_secret, m.totp_enabled, m.avatar_key, m.created_by, m.updated_by, m.date_created, m.date_updated, m.date_last_login, m.version,
+			1 AS depth, ARRAY[u.user_id, m.user_id] AS path
+		FROM
+			users u
+		JOIN
+			users m ON m.user_id = u.manager_id
+		WHERE
+			u.user_id = :user_id AND
+			m.tenant_id = u.tenant_id AND
+			(CAST(:tenant_id AS UUID) IS NULL OR u.tenant_id = :tenant_id)
+		UNION ALL
+		SELECT
+			m.user_id, m.tenant_id, m.name, m.email, m.password_hash, m.roles, m.department, m.manager_id, m.enabled, m.totp_secret, m.totp_enabled, m.avatar_key, m.created_by, m.updated_by, m.date_created, m.date_updated, m.date_last_login, m.version,
+			c.depth + 1, c.path || m.user_id
+		FROM
+			chain c
+		JOIN
+			users m ON m.user_id = c.manager_id
+		WHERE
+			NOT m.user_id = ANY(c.path) AND
+			m.tenant_id = c.tenant_id
+	)
+	SELECT
+        user_id, tenant_id, name, email, password_hash, roles, department, manager_id, enabled, totp_secret, totp_enabled, avatar_key, created_by, updated_by, date_created, date_updated, date_last_login, version
+	FROM
+		chain
+	ORDER BY
+		depth`
+
+	var dbUsrs []user
+	if err := sqldb.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbUsrs); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toBusUsers(dbUsrs)
+}
+
+// AddIdentity links an external identity to a user in the database.
+func (s *Store) AddIdentity(ctx context.Context, idn userbus.Identity) error {
+	const q = `
+	INSERT INTO user_identities
+		(provider, external_id, user_id, email, date_created)
+	VALUES
+		(:provider, :external_id, :user_id, :email, :date_created)`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBIdentity(idn)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// QueryIdentity gets the external identity from the database.
+func (s *Store) QueryIdentity(ctx context.Context, provider string, externalID string) (userbus.Identity, error) {
+	data := struct {
+		Provider   string `db:"provider"`
+		ExternalID string `db:"external_id"`
+	}{
+		Provider:   provider,
+		ExternalID: externalID,
+	}
+
+	const q = `
+	SELECT
+		provider, external_id, user_id, email, date_created
+	FROM
+		user_identities
+	WHERE
+		provider = :provider AND external_id = :external_id`
+
+	var dbIdn identity
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbIdn); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return userbus.Identity{}, fmt.Errorf("db: %w", userbus.ErrNotFound)
+		}
+		return userbus.Identity{}, fmt.Errorf("db: %w", err)
+	}
+
+	return toBusIdentity(dbIdn), nil
+}
+
+// SetPreference saves the user's preference, replacing any value already
+// set.
+func (s *Store) SetPreference(ctx context.Context, pref userbus.Preference) error {
+	const q = `
+	INSERT INTO user_preferences
+		(user_id, key, value, date_updated)
+	VALUES
+		(:user_id, :key, :value, :date_updated)
+	ON CONFLICT (user_id, key) DO UPDATE SET
+		value = EXCLUDED.value,
+		date_updated = EXCLUDED.date_updated`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBPreference(pref)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// QueryPreferences gets the preferences the user has set from the database.
+func (s *Store) QueryPreferences(ctx context.Context, userID uuid.UUID) ([]userbus.Preference, error) {
+	data := struct {
+		UserID uuid.UUID `db:"user_id"`
+	}{
+		UserID: userID,
+	}
+
+	const q = `
+	SELECT
+		user_id, key, value, date_updated
+	FROM
+		user_preferences
+	WHERE
+		user_id = :user_id`
+
+	var dbPrefs []preference
+	if err := sqldb.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbPrefs); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toBusPreferences(dbPrefs), nil
+}
+
+// DeletePreference removes the user's preference from the database.
+func (s *Store) DeletePreference(ctx context.Context, userID uuid.UUID, key string) error {
+	data := struct {
+		UserID uuid.UUID `db:"user_id"`
+		Key    string    `db:"key"`
+	}{
+		UserID: userID,
+		Key:    key,
+	}
+
+	const q = `
+	DELETE FROM
+		user_preferences
+	WHERE
+		user_id = :user_id AND key = :key`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// DeletePersonalData removes the identities, password history, recovery
+// codes and preferences held for the user.
+func (s *Store) DeletePersonalData(ctx context.Context, userID uuid.UUID) error {
+	data := struct {
+		UserID uuid.UUID `db:"user_id"`
+	}{
+		UserID: userID,
+	}
+
+	tables := []string{"user_identities", "user_password_history", "user_recovery_codes", "user_preferences"}
+
+	for _, table := range tables {
+		q := fmt.Sprintf(`
+	DELETE FROM
+		%s
+	WHERE
+		user_id = :user_id`, table)
+
+		if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+			return fmt.Errorf("namedexeccontext: %s: %w", table, err)
+		}
+	}
+
+	return nil
+}
+
+// UpdateLastLogin sets the date the user last logged in. The version isn't
+// changed so a login doesn't conflict with an update of the user.
+func (s *Store) UpdateLastLogin(ctx context.Context, userID uuid.UUID, dateLastLogin time.Time) error {
+	data := map[string]any{
+		"user_id":         userID,
+		"date_last_login": dateLastLogin.UTC(),
+		"tenant_id":       tenant.Scope(ctx),
+	}
+
+	const q = `
+	UPDATE
+		users
+	SET
+		date_last_login = :date_last_login
+	WHERE
+		user_id = :user_id AND
+		(CAST(:tenant_id AS UUID) IS NULL OR tenant_id = :tenant_id)
+	RETURNING
+		user_id`
+
+	var dest struct {
+		ID uuid.UUID `db:"user_id"`
+	}
+
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &dest); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return fmt.Errorf("namedquerystruct: userID[%s]: %w", userID, userbus.ErrNotFound)
+		}
+		return fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return nil
+}
+
+// AddIdempotencyKey records the user created for the actor's idempotency key.
+// A key that has expired but not yet been purged is replaced.
+func (s *Store) AddIdempotencyKey(ctx context.Context, ik userbus.IdempotencyKey) error {
+	const q = `
+	INSERT INTO user_idempotency_keys
+		(actor_id, key, user_id, date_created)
+	VALUES
+		(:actor_id, :key, :user_id, :date_created)
+	ON CONFLICT (actor_id, key) DO UPDATE SET
+		user_id = EXCLUDED.user_id,
+		date_created = EXCLUDED.date_created`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBIdempotencyKey(ik)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// QueryIdempotencyKey gets the actor's idempotency key from the database.
+func (s *Store) QueryIdempotencyKey(ctx context.Context, actorID uuid.UUID, key string) (userbus.IdempotencyKey, error) {
+	data := struct {
+		ActorID uuid.UUID `db:"actor_id"`
+		Key     string    `db:"key"`
+	}{
+		ActorID: actorID,
+		Key:     key,
+	}
+
+	const q = `
+	SELECT
+		actor_id, key, user_id, date_created
+	FROM
+		user_idempotency_keys
+	WHERE
+		actor_id = :actor_id AND key = :key`
+
+	var dbIK idempotencyKey
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbIK); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return userbus.IdempotencyKey{}, fmt.Errorf("db: %w", userbus.ErrNotFound)
+		}
+		return userbus.IdempotencyKey{}, fmt.Errorf("db: %w", err)
+	}
+
+	return toBusIdempotencyKey(dbIK), nil
+}
+
+// DeleteIdempotencyKeys removes the idempotency keys created before the time
+// and returns the number removed.
+func (s *Store) DeleteIdempotencyKeys(ctx context.Context, before time.Time) (int, error) {
+	data := struct {
+		Before time.Time `db:"before"`
+	}{
+		Before: before.UTC(),
+	}
+
+	const q = `
+	DELETE FROM
+		user_idempotency_keys
+	WHERE
+		date_created < :before
+	RETURNING
+		key`
+
+	var keys []struct {
+		Key string `db:"key"`
+	}
+	if err := sqldb.NamedQuerySlice(ctx, s.log, s.db, q, data, &keys); err != nil {
+		return 0, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return len(keys), nil
+}
+
+// CreateRoleAssignment inserts a previewed role assignment along with the
+// change for each user into the database.
+func (s *Store) CreateRoleAssignment(ctx context.Context, ra userbus.RoleAssignment, chgs []userbus.RoleChange) error {
+	const q = `
+	INSERT INTO role_assignments
+		(assignment_id, actor_id, add_roles, remove_roles, affected, revert_hash, date_created, date_applied, date_reverted)
+	VALUES
+		(:assignment_id, :actor_id, :add_roles, :remove_roles, :affected, :revert_hash, :date_created, :date_applied, :date_reverted)`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBRoleAssignment(ra)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	const qc = `
+	INSERT INTO role_assignment_changes
+		(assignment_id, user_id, old_roles, new_roles)
+	VALUES
+		(:assignment_id, :user_id, :old_roles, :new_roles)`
+
+	for _, chg := range chgs {
+		if err := sqldb.NamedExecContext(ctx, s.log, s.db, qc, toDBRoleChange(ra.ID, chg)); err != nil {
+			return fmt.Errorf("namedexeccontext: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// UpdateRoleAssignment replaces a role assignment document in the database.
+func (s *Store) UpdateRoleAssignment(ctx context.Context, ra userbus.RoleAssignment) error {
+	const q = `
+	UPDATE
+		role_assignments
+	SET
+		"affected" = :affected,
+		"revert_hash" = :revert_hash,
+		"date_applied" = :date_applied,
+		"date_reverted" = :date_reverted
+	WHERE
+		assignment_id = :assignment_id`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBRoleAssignment(ra)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// QueryRoleAssignment gets the specified role assignment from the database.
+func (s *Store) QueryRoleAssignment(ctx context.Context, assignmentID uuid.UUID) (userbus.RoleAssignment, error) {
+	data := struct {
+		ID string `db:"assignment_id"`
+	}{
+		ID: assignmentID.String(),
+	}
+
+	const q = `
+	SELECT
+		assignment_id, actor_id, add_roles, remove_roles, affected, revert_hash, date_created, date_applied, date_reverted
+	FROM
+		role_assignments
+	WHERE
+		assignment_id = :assignment_id`
+
+	var dbRA roleAssignment
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbRA); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return userbus.RoleAssignment{}, fmt.Errorf("db: %w", userbus.ErrNotFound)
+		}
+		return userbus.RoleAssignment{}, fmt.Errorf("db: %w", err)
+	}
+
+	return toBusRoleAssignment(dbRA)
+}
+
+// QueryRoleAssignmentByRevertHash gets the role assignment with the
+// specified revert hash from the database.
+func (s *Store) QueryRoleAssignmentByRevertHash(ctx context.Context, revertHash string) (userbus.RoleAssignment, error) {
+	data := struct {
+		RevertHash string `db:"revert_hash"`
+	}{
+		RevertHash: revertHash,
+	}
+
+	const q = `
+	SELECT
+		assignment_id, actor_id, add_roles, remove_roles, affected, revert_hash, date_created, date_applied, date_reverted
+	FROM
+		role_assignments
+	WHERE
+		revert_hash = :revert_hash`
+
+	var dbRA roleAssignment
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbRA); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return userbus.RoleAssignment{}, fmt.Errorf("db: %w", userbus.ErrNotFound)
+		}
+		return userbus.RoleAssignment{}, fmt.Errorf("db: %w", err)
+	}
+
+	return toBusRoleAssignment(dbRA)
+}
+
+// QueryRoleChanges gets the change for each user in the specified role
+// assignment from the database.
+func (s *Store) QueryRoleChanges(ctx context.Context, assignmentID uuid.UUID) ([]userbus.RoleChange, error) {
+	data := struct {
+		ID string `db:"assignment_id"`
+	}{
+		ID: assignmentID.String(),
+	}
+
+	const q = `
+	SELECT
+		assignment_id, user_id, old_roles, new_roles
+	FROM
+		role_assignment_changes
+	WHERE
+		assignment_id = :assignment_id
+	ORDER BY
+		user_id`
+
+	var dbChgs []roleChange
+	if err := sqldb.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbChgs); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toBusRoleChanges(dbChgs)
+}
+
+// =============================================================================
+
+// checkCost rejects the query when the planner estimates it will cost more
+// than the store's budget.
+func (s *Store) checkCost(ctx context.Context, query string, data any) error {
+	if err := sqldb.CheckCost(ctx, s.log, s.db, query, data, s.costBudget); err != nil {
+		if errors.Is(err, sqldb.ErrQueryTooExpensive) {
+			return fmt.Errorf("checkcost: %s: %w", err, userbus.ErrQueryTooExpensive)
+		}
+		return fmt.Errorf("checkcost: %w", err)
+	}
+
+	return nil
+}
+
+// queryErr wraps the error of a list query. A query cancelled for running
+// too long is reported as ErrQueryTimeout.
+func queryErr(op string, err error) error {
+	if errors.Is(err, sqldb.ErrQueryTimeout) {
+		return fmt.Errorf("%s: %s: %w", op, err, userbus.ErrQueryTimeout)
+	}
+
+	return fmt.Errorf("%s: %w", op, err)
+}
+
+// =============================================================================
+
+// scopeFilter limits the filter to the tenant the call is made for. A
+// tenant asked for in the filter can't widen what the call sees.
+func scopeFilter(ctx context.Context, filter userbus.QueryFilter) userbus.QueryFilter {
+	if tenantID := tenant.Scope(ctx); tenantID != nil {
+		filter.TenantID = tenantID
+	}
+
+	return filter
+}
+
+// scopeUser returns the user for a statement that changes it. The tenant is
+// the one the call is made for, so a user of another tenant isn't matched.
+// Calls that aren't made for a tenant match the user's own tenant.
+func scopeUser(ctx context.Context, usr userbus.User) user {
+	dbUsr := toDBUser(usr)
+	if tenantID := tenant.Scope(ctx); tenantID != nil {
+		dbUsr.TenantID = *tenantID
+	}
+
+	return dbUsr
+}
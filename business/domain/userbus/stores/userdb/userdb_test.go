@@ -0,0 +1,75 @@
+package userdb_test
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/userbus/storertest"
+	"github.com/ardanlabs/service/business/domain/userbus/stores/usercache"
+	"github.com/ardanlabs/service/business/domain/userbus/stores/userdb"
+	"github.com/ardanlabs/service/business/sdk/cache"
+	"github.com/ardanlabs/service/business/sdk/dbtest"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+)
+
+var (
+	loadWorkers  = flag.Int("load.workers", 8, "goroutines calling each store at the same time")
+	loadDuration = flag.Duration("load.duration", 5*time.Second, "how long each store is measured for")
+	loadReport   = flag.String("load.report", "", "file the JSON report is written to")
+)
+
+func Test_Storer(t *testing.T) {
+	t.Parallel()
+
+	db := dbtest.New(t, "Test_Storer")
+
+	storertest.Run(t, storertest.Config{
+		Storer:   userdb.NewStore(db.Log, db.DB),
+		Beginner: sqldb.NewBeginner(db.DB),
+	})
+}
+
+// Benchmark_Storer compares the stores this package can reach under the
+// same load. Run it with -bench=Storer -benchtime=1x.
+func Benchmark_Storer(b *testing.B) {
+	db := dbtest.New(b, "Benchmark_Storer")
+
+	drivers := []storertest.Driver{
+		{Name: "sqlx", Storer: userdb.NewStore(db.Log, db.DB)},
+		{Name: "sqlx+cache", Storer: usercache.NewStore(db.Log, userdb.NewStore(db.Log, db.DB), cache.NewMemory(0), time.Minute)},
+	}
+
+	load := storertest.Load{
+		Workers:  *loadWorkers,
+		Duration: *loadDuration,
+	}
+
+	for range b.N {
+		rpt, err := storertest.Measure(context.Background(), drivers, load)
+		if err != nil {
+			b.Fatalf("Should be able to measure the stores : %s", err)
+		}
+
+		var buf bytes.Buffer
+		if err := rpt.WriteText(&buf); err != nil {
+			b.Fatalf("Should be able to write the report : %s", err)
+		}
+		b.Log("\n" + buf.String())
+
+		if *loadReport != "" {
+			f, err := os.Create(*loadReport)
+			if err != nil {
+				b.Fatalf("Should be able to create the report file : %s", err)
+			}
+
+			if err := rpt.WriteJSON(f); err != nil {
+				b.Fatalf("Should be able to write the report file : %s", err)
+			}
+			f.Close()
+		}
+	}
+}
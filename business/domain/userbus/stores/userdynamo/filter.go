@@ -0,0 +1,266 @@
+package userdynamo
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/types/role"
+)
+
+// matchFilter reports whether the user matches the filter. DynamoDB can't
+// express the filters the business layer needs against a single table, so
+// they are applied to the users as they are read.
+func matchFilter(filter userbus.QueryFilter, usr userbus.User) bool {
+	if filter.ID != nil && usr.ID != *filter.ID {
+		return false
+	}
+
+	if filter.TenantID != nil && usr.TenantID != *filter.TenantID {
+		return false
+	}
+
+	if filter.Name != nil && !strings.Contains(usr.Name.String(), filter.Name.String()) {
+		return false
+	}
+
+	if filter.Email != nil && usr.Email.Address != filter.Email.Address {
+		return false
+	}
+
+	if filter.Search != nil {
+		search := strings.ToLower(*filter.Search)
+		if !strings.Contains(strings.ToLower(usr.Name.String()), search) &&
+			!strings.Contains(strings.ToLower(usr.Email.Address), search) {
+			return false
+		}
+	}
+
+	if len(filter.Roles) > 0 && !slices.ContainsFunc(usr.Roles, func(r role.Role) bool {
+		return slices.ContainsFunc(filter.Roles, r.Equal)
+	}) {
+		return false
+	}
+
+	if filter.Enabled != nil && usr.Enabled != *filter.Enabled {
+		return false
+	}
+
+	if filter.Department != nil && (!usr.Department.Valid() || usr.Department.String() != filter.Department.String()) {
+		return false
+	}
+
+	if filter.StartCreatedDate != nil && usr.DateCreated.Before(*filter.StartCreatedDate) {
+		return false
+	}
+
+	if filter.EndCreatedDate != nil && usr.DateCreated.After(*filter.EndCreatedDate) {
+		return false
+	}
+
+	if filter.NotLoggedInSince != nil {
+		lastLogin := usr.DateLastLogin
+		if lastLogin.IsZero() {
+			lastLogin = usr.DateCreated
+		}
+
+		if !lastLogin.Before(*filter.NotLoggedInSince) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// =============================================================================
+
+// compareFields compares two users on each of the fields the results can be
+// ordered by. There is no ranking of search results, so ordering by
+// relevance leaves the order to the fields that break ties. Users without a
+// department sort last, as they do in Postgres.
+var compareFields = map[string]func(a, b userbus.User) int{
+	userbus.OrderByID: func(a, b userbus.User) int {
+		return strings.Compare(a.ID.String(), b.ID.String())
+	},
+	userbus.OrderByName: func(a, b userbus.User) int {
+		return strings.Compare(a.Name.String(), b.Name.String())
+	},
+	userbus.OrderByEmail: func(a, b userbus.User) int {
+		return strings.Compare(a.Email.Address, b.Email.Address)
+	},
+	userbus.OrderByRoles: func(a, b userbus.User) int {
+		return slices.Compare(role.ParseToString(a.Roles), role.ParseToString(b.Roles))
+	},
+	userbus.OrderByEnabled: func(a, b userbus.User) int {
+		return compareBool(a.Enabled, b.Enabled)
+	},
+	userbus.OrderByDept: func(a, b userbus.User) int {
+		switch {
+		case a.Department.Valid() && b.Department.Valid():
+			return strings.Compare(a.Department.String(), b.Department.String())
+		default:
+			return compareBool(!a.Department.Valid(), !b.Department.Valid())
+		}
+	},
+	userbus.OrderByCreated: func(a, b userbus.User) int {
+		return a.DateCreated.Compare(b.DateCreated)
+	},
+	userbus.OrderByRelevance: func(a, b userbus.User) int {
+		return 0
+	},
+}
+
+func compareBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case !a:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// sortUsers sorts the users in the specified order. Ties are always broken
+// by the user id so the order is stable.
+func sortUsers(usrs []userbus.User, orderBy order.By) error {
+	columns := orderBy.Columns()
+
+	cmps := make([]func(a, b userbus.User) int, 0, len(columns)+1)
+	for _, by := range columns {
+		fn, exists := compareFields[by.Field]
+		if !exists {
+			return fmt.Errorf("field %q does not exist", by.Field)
+		}
+
+		cmps = append(cmps, direction(fn, by.Direction))
+	}
+
+	cmps = append(cmps, direction(compareFields[userbus.OrderByID], orderBy.Direction))
+
+	slices.SortStableFunc(usrs, func(a, b userbus.User) int {
+		for _, fn := range cmps {
+			if c := fn(a, b); c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+
+	return nil
+}
+
+func direction(fn func(a, b userbus.User) int, dir string) func(a, b userbus.User) int {
+	if dir == order.DESC {
+		return func(a, b userbus.User) int {
+			return fn(b, a)
+		}
+	}
+
+	return fn
+}
+
+// pageUsers returns the users on the specified page of the sorted users.
+// Offset pages are sliced from the users and keyset pages start with the
+// first user that follows the cursor.
+func pageUsers(usrs []userbus.User, orderBy order.By, pg page.Page) ([]userbus.User, error) {
+	start := 0
+
+	switch {
+	case pg.IsKeyset():
+		if cursor := pg.Cursor(); len(cursor) > 0 {
+			after, err := keysetAfter(orderBy, cursor)
+			if err != nil {
+				return nil, err
+			}
+
+			start = len(usrs)
+			for i, usr := range usrs {
+				if after(usr) {
+					start = i
+					break
+				}
+			}
+		}
+
+	default:
+		start = min((pg.Number()-1)*pg.RowsPerPage(), len(usrs))
+	}
+
+	end := min(start+pg.RowsPerPage(), len(usrs))
+
+	return usrs[start:end], nil
+}
+
+// keysetAfter returns the function that reports whether a user follows the
+// cursor in the specified order. Ties on the order field are broken by the
+// user id.
+func keysetAfter(orderBy order.By, cursor []string) (func(usr userbus.User) bool, error) {
+	if len(orderBy.Then) > 0 {
+		return nil, fmt.Errorf("order[%s]: multiple fields: %w", orderBy.Field, page.ErrInvalidCursor)
+	}
+
+	dir := 1
+	if orderBy.Direction == order.DESC {
+		dir = -1
+	}
+
+	if orderBy.Field == userbus.OrderByID {
+		if len(cursor) != 1 {
+			return nil, fmt.Errorf("order[%s]: %w", orderBy.Field, page.ErrInvalidCursor)
+		}
+
+		f := func(usr userbus.User) bool {
+			return dir*strings.Compare(usr.ID.String(), cursor[0]) > 0
+		}
+
+		return f, nil
+	}
+
+	if len(cursor) != 2 {
+		return nil, fmt.Errorf("order[%s]: %w", orderBy.Field, page.ErrInvalidCursor)
+	}
+
+	var compare func(usr userbus.User) int
+	switch orderBy.Field {
+	case userbus.OrderByName:
+		compare = func(usr userbus.User) int {
+			return strings.Compare(usr.Name.String(), cursor[0])
+		}
+
+	case userbus.OrderByEmail:
+		compare = func(usr userbus.User) int {
+			return strings.Compare(usr.Email.Address, cursor[0])
+		}
+
+	case userbus.OrderByRoles:
+		roles := strings.Split(strings.Trim(cursor[0], "{}"), ",")
+		compare = func(usr userbus.User) int {
+			return slices.Compare(role.ParseToString(usr.Roles), roles)
+		}
+
+	case userbus.OrderByEnabled:
+		enabled, err := strconv.ParseBool(cursor[0])
+		if err != nil {
+			return nil, fmt.Errorf("order[%s]: %w", orderBy.Field, page.ErrInvalidCursor)
+		}
+		compare = func(usr userbus.User) int {
+			return compareBool(usr.Enabled, enabled)
+		}
+
+	default:
+		return nil, fmt.Errorf("order[%s]: %w", orderBy.Field, page.ErrInvalidCursor)
+	}
+
+	f := func(usr userbus.User) bool {
+		c := cmp.Or(compare(usr), strings.Compare(usr.ID.String(), cursor[1]))
+		return dir*c > 0
+	}
+
+	return f, nil
+}
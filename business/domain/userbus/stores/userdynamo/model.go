@@ -0,0 +1,561 @@
+package userdynamo
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/types/department"
+	"github.com/ardanlabs/service/business/types/name"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/google/uuid"
+)
+
+// Set of item types kept in the table.
+const (
+	typeUser       = "USER"
+	typeEmail      = "EMAIL"
+	typePassword   = "PASSWORD"
+	typeRecovery   = "RECOVERY"
+	typeIdentity   = "IDENTITY"
+	typeAssignment = "ROLEASSIGN"
+	typeChange     = "ROLECHANGE"
+	typeRevert     = "REVERT"
+	typeIdemKey    = "IDEMPOTENCY"
+	typePreference = "PREFERENCE"
+)
+
+// key identifies an item in the table.
+type key struct {
+	PK string `dynamodbav:"pk"`
+	SK string `dynamodbav:"sk"`
+}
+
+func userKey(userID string) key {
+	return key{PK: "USER#" + userID, SK: "PROFILE"}
+}
+
+func emailKey(email string) key {
+	return key{PK: "EMAIL#" + email, SK: "EMAIL"}
+}
+
+func passwordKey(userID string, dateCreated time.Time) key {
+	return key{PK: "USER#" + userID, SK: "PASSWORD#" + dateCreated.UTC().Format(sortableTime)}
+}
+
+func recoveryKey(userID string, codeHash string) key {
+	return key{PK: "USER#" + userID, SK: "RECOVERY#" + codeHash}
+}
+
+func preferenceKey(userID string, k string) key {
+	return key{PK: "USER#" + userID, SK: "PREF#" + k}
+}
+
+func identityKey(provider string, externalID string) key {
+	return key{PK: "IDENTITY#" + provider + "#" + externalID, SK: "IDENTITY"}
+}
+
+func assignmentKey(assignmentID string) key {
+	return key{PK: "ROLEASSIGN#" + assignmentID, SK: "ASSIGNMENT"}
+}
+
+func changeKey(assignmentID string, userID string) key {
+	return key{PK: "ROLEASSIGN#" + assignmentID, SK: "CHANGE#" + userID}
+}
+
+func revertKey(revertHash string) key {
+	return key{PK: "REVERT#" + revertHash, SK: "REVERT"}
+}
+
+func idempotencyKeyKey(actorID string, k string) key {
+	return key{PK: "IDEMPOTENCY#" + actorID + "#" + k, SK: "IDEMPOTENCY"}
+}
+
+// sortableTime formats times with a fixed width so they sort as strings.
+const sortableTime = "2006-01-02T15:04:05.000000000Z"
+
+// =============================================================================
+
+type user struct {
+	key
+	Type          string     `dynamodbav:"type"`
+	EmailIndex    string     `dynamodbav:"gsi1pk"`
+	ID            string     `dynamodbav:"user_id"`
+	TenantID      string     `dynamodbav:"tenant_id,omitempty"`
+	Name          string     `dynamodbav:"name" class:"confidential"`
+	Email         string     `dynamodbav:"email" class:"confidential"`
+	Roles         []string   `dynamodbav:"roles" class:"internal"`
+	PasswordHash  []byte     `dynamodbav:"password_hash,omitempty" class:"restricted"`
+	Department    string     `dynamodbav:"department,omitempty" class:"internal"`
+	ManagerID     string     `dynamodbav:"manager_id,omitempty"`
+	Enabled       bool       `dynamodbav:"enabled"`
+	TOTPSecret    string     `dynamodbav:"totp_secret,omitempty" class:"restricted"`
+	TOTPEnabled   bool       `dynamodbav:"totp_enabled"`
+	AvatarKey     string     `dynamodbav:"avatar_key,omitempty"`
+	CreatedBy     string     `dynamodbav:"created_by,omitempty"`
+	UpdatedBy     string     `dynamodbav:"updated_by,omitempty"`
+	DateCreated   time.Time  `dynamodbav:"date_created"`
+	DateUpdated   time.Time  `dynamodbav:"date_updated"`
+	DateLastLogin *time.Time `dynamodbav:"date_last_login,omitempty"`
+	Version       int        `dynamodbav:"version"`
+}
+
+func toItemUser(bus userbus.User) user {
+	item := user{
+		key:          userKey(bus.ID.String()),
+		Type:         typeUser,
+		EmailIndex:   emailKey(bus.Email.Address).PK,
+		ID:           bus.ID.String(),
+		Name:         bus.Name.String(),
+		Email:        bus.Email.Address,
+		Roles:        role.ParseToString(bus.Roles),
+		PasswordHash: bus.PasswordHash,
+		Enabled:      bus.Enabled,
+		TOTPSecret:   bus.TOTPSecret,
+		TOTPEnabled:  bus.TOTPEnabled,
+		AvatarKey:    bus.AvatarKey,
+		DateCreated:  bus.DateCreated.UTC(),
+		DateUpdated:  bus.DateUpdated.UTC(),
+		Version:      bus.Version,
+	}
+
+	if bus.Department.Valid() {
+		item.Department = bus.Department.String()
+	}
+
+	if bus.ManagerID.Valid {
+		item.ManagerID = bus.ManagerID.UUID.String()
+	}
+
+	// Users of the default tenant aren't stored with one.
+	if bus.TenantID != uuid.Nil {
+		item.TenantID = bus.TenantID.String()
+	}
+
+	// Users created before changes were attributed don't have actors.
+	if bus.CreatedBy != uuid.Nil {
+		item.CreatedBy = bus.CreatedBy.String()
+	}
+
+	if bus.UpdatedBy != uuid.Nil {
+		item.UpdatedBy = bus.UpdatedBy.String()
+	}
+
+	if !bus.DateLastLogin.IsZero() {
+		dateLastLogin := bus.DateLastLogin.UTC()
+		item.DateLastLogin = &dateLastLogin
+	}
+
+	return item
+}
+
+func toBusUser(item user) (userbus.User, error) {
+	id, err := uuid.Parse(item.ID)
+	if err != nil {
+		return userbus.User{}, fmt.Errorf("parse id: %w", err)
+	}
+
+	roles, err := role.ParseMany(item.Roles)
+	if err != nil {
+		return userbus.User{}, fmt.Errorf("parse: %w", err)
+	}
+
+	nme, err := name.Parse(item.Name)
+	if err != nil {
+		return userbus.User{}, fmt.Errorf("parse name: %w", err)
+	}
+
+	dept, err := department.ParseNull(item.Department)
+	if err != nil {
+		return userbus.User{}, fmt.Errorf("parse department: %w", err)
+	}
+
+	var managerID uuid.NullUUID
+	if item.ManagerID != "" {
+		if managerID.UUID, err = uuid.Parse(item.ManagerID); err != nil {
+			return userbus.User{}, fmt.Errorf("parse manager id: %w", err)
+		}
+		managerID.Valid = true
+	}
+
+	tenantID, err := parseActor(item.TenantID)
+	if err != nil {
+		return userbus.User{}, fmt.Errorf("parse tenant id: %w", err)
+	}
+
+	createdBy, err := parseActor(item.CreatedBy)
+	if err != nil {
+		return userbus.User{}, fmt.Errorf("parse created by: %w", err)
+	}
+
+	updatedBy, err := parseActor(item.UpdatedBy)
+	if err != nil {
+		return userbus.User{}, fmt.Errorf("parse updated by: %w", err)
+	}
+
+	var dateLastLogin time.Time
+	if item.DateLastLogin != nil {
+		dateLastLogin = item.DateLastLogin.In(time.Local)
+	}
+
+	bus := userbus.User{
+		ID:            id,
+		TenantID:      tenantID,
+		Name:          nme,
+		Email:         mail.Address{Address: item.Email},
+		Roles:         roles,
+		PasswordHash:  item.PasswordHash,
+		Enabled:       item.Enabled,
+		Department:    dept,
+		ManagerID:     managerID,
+		TOTPSecret:    item.TOTPSecret,
+		TOTPEnabled:   item.TOTPEnabled,
+		AvatarKey:     item.AvatarKey,
+		CreatedBy:     createdBy,
+		UpdatedBy:     updatedBy,
+		DateCreated:   item.DateCreated.In(time.Local),
+		DateUpdated:   item.DateUpdated.In(time.Local),
+		DateLastLogin: dateLastLogin,
+		Version:       item.Version,
+	}
+
+	return bus, nil
+}
+
+// parseActor reads an actor that may not have been stored.
+func parseActor(s string) (uuid.UUID, error) {
+	if s == "" {
+		return uuid.Nil, nil
+	}
+
+	return uuid.Parse(s)
+}
+
+func toBusStoredName(item user) (userbus.StoredName, error) {
+	userID, err := uuid.Parse(item.ID)
+	if err != nil {
+		return userbus.StoredName{}, fmt.Errorf("parse user id: %w", err)
+	}
+
+	sn := userbus.StoredName{
+		UserID:     userID,
+		Name:       item.Name,
+		Department: item.Department,
+	}
+
+	return sn, nil
+}
+
+func toBusUsers(items []user) ([]userbus.User, error) {
+	bus := make([]userbus.User, len(items))
+
+	for i, item := range items {
+		var err error
+		bus[i], err = toBusUser(item)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return bus, nil
+}
+
+// emailGuard reserves an email for a user, which is how the table enforces
+// that emails are unique.
+type emailGuard struct {
+	key
+	Type   string `dynamodbav:"type"`
+	UserID string `dynamodbav:"user_id"`
+}
+
+func toItemEmailGuard(usr user) emailGuard {
+	return emailGuard{
+		key:    emailKey(usr.Email),
+		Type:   typeEmail,
+		UserID: usr.ID,
+	}
+}
+
+// =============================================================================
+
+type passwordHistory struct {
+	key
+	Type         string    `dynamodbav:"type"`
+	UserID       string    `dynamodbav:"user_id"`
+	PasswordHash []byte    `dynamodbav:"password_hash" class:"restricted"`
+	DateCreated  time.Time `dynamodbav:"date_created"`
+}
+
+type recoveryCode struct {
+	key
+	Type        string    `dynamodbav:"type"`
+	UserID      string    `dynamodbav:"user_id"`
+	CodeHash    string    `dynamodbav:"code_hash" class:"restricted"`
+	DateCreated time.Time `dynamodbav:"date_created"`
+}
+
+type identity struct {
+	key
+	Type        string    `dynamodbav:"type"`
+	Provider    string    `dynamodbav:"provider"`
+	ExternalID  string    `dynamodbav:"external_id"`
+	UserID      string    `dynamodbav:"user_id"`
+	Email       string    `dynamodbav:"email" class:"confidential"`
+	DateCreated time.Time `dynamodbav:"date_created"`
+}
+
+func toItemIdentity(bus userbus.Identity) identity {
+	return identity{
+		key:         identityKey(bus.Provider, bus.ExternalID),
+		Type:        typeIdentity,
+		Provider:    bus.Provider,
+		ExternalID:  bus.ExternalID,
+		UserID:      bus.UserID.String(),
+		Email:       bus.Email.Address,
+		DateCreated: bus.DateCreated.UTC(),
+	}
+}
+
+func toBusIdentity(item identity) (userbus.Identity, error) {
+	userID, err := uuid.Parse(item.UserID)
+	if err != nil {
+		return userbus.Identity{}, fmt.Errorf("parse user id: %w", err)
+	}
+
+	bus := userbus.Identity{
+		Provider:    item.Provider,
+		ExternalID:  item.ExternalID,
+		UserID:      userID,
+		Email:       mail.Address{Address: item.Email},
+		DateCreated: item.DateCreated.In(time.Local),
+	}
+
+	return bus, nil
+}
+
+// =============================================================================
+
+type preference struct {
+	key
+	Type        string    `dynamodbav:"type"`
+	UserID      string    `dynamodbav:"user_id"`
+	Key         string    `dynamodbav:"preference_key"`
+	Value       string    `dynamodbav:"value"`
+	DateUpdated time.Time `dynamodbav:"date_updated"`
+}
+
+func toItemPreference(bus userbus.Preference) preference {
+	return preference{
+		key:         preferenceKey(bus.UserID.String(), bus.Key),
+		Type:        typePreference,
+		UserID:      bus.UserID.String(),
+		Key:         bus.Key,
+		Value:       string(bus.Value),
+		DateUpdated: bus.DateUpdated.UTC(),
+	}
+}
+
+func toBusPreferences(items []preference) ([]userbus.Preference, error) {
+	bus := make([]userbus.Preference, len(items))
+
+	for i, item := range items {
+		userID, err := uuid.Parse(item.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("parse user id: %w", err)
+		}
+
+		bus[i] = userbus.Preference{
+			UserID:      userID,
+			Key:         item.Key,
+			Value:       json.RawMessage(item.Value),
+			DateUpdated: item.DateUpdated.In(time.Local),
+		}
+	}
+
+	return bus, nil
+}
+
+// =============================================================================
+
+type idempotencyKey struct {
+	key
+	Type        string    `dynamodbav:"type"`
+	ActorID     string    `dynamodbav:"actor_id"`
+	Key         string    `dynamodbav:"idempotency_key"`
+	UserID      string    `dynamodbav:"user_id"`
+	DateCreated time.Time `dynamodbav:"date_created"`
+}
+
+func toItemIdempotencyKey(bus userbus.IdempotencyKey) idempotencyKey {
+	return idempotencyKey{
+		key:         idempotencyKeyKey(bus.ActorID.String(), bus.Key),
+		Type:        typeIdemKey,
+		ActorID:     bus.ActorID.String(),
+		Key:         bus.Key,
+		UserID:      bus.UserID.String(),
+		DateCreated: bus.DateCreated.UTC(),
+	}
+}
+
+func toBusIdempotencyKey(item idempotencyKey) (userbus.IdempotencyKey, error) {
+	actorID, err := uuid.Parse(item.ActorID)
+	if err != nil {
+		return userbus.IdempotencyKey{}, fmt.Errorf("parse actor id: %w", err)
+	}
+
+	userID, err := uuid.Parse(item.UserID)
+	if err != nil {
+		return userbus.IdempotencyKey{}, fmt.Errorf("parse user id: %w", err)
+	}
+
+	bus := userbus.IdempotencyKey{
+		ActorID:     actorID,
+		Key:         item.Key,
+		UserID:      userID,
+		DateCreated: item.DateCreated.In(time.Local),
+	}
+
+	return bus, nil
+}
+
+// =============================================================================
+
+type roleAssignment struct {
+	key
+	Type         string     `dynamodbav:"type"`
+	ID           string     `dynamodbav:"assignment_id"`
+	ActorID      string     `dynamodbav:"actor_id"`
+	AddRoles     []string   `dynamodbav:"add_roles"`
+	RemoveRoles  []string   `dynamodbav:"remove_roles"`
+	Affected     int        `dynamodbav:"affected"`
+	RevertHash   string     `dynamodbav:"revert_hash,omitempty" class:"restricted"`
+	DateCreated  time.Time  `dynamodbav:"date_created"`
+	DateApplied  *time.Time `dynamodbav:"date_applied,omitempty"`
+	DateReverted *time.Time `dynamodbav:"date_reverted,omitempty"`
+}
+
+func toItemRoleAssignment(bus userbus.RoleAssignment) roleAssignment {
+	item := roleAssignment{
+		key:         assignmentKey(bus.ID.String()),
+		Type:        typeAssignment,
+		ID:          bus.ID.String(),
+		ActorID:     bus.ActorID.String(),
+		AddRoles:    role.ParseToString(bus.AddRoles),
+		RemoveRoles: role.ParseToString(bus.RemoveRoles),
+		Affected:    bus.Affected,
+		RevertHash:  bus.RevertHash,
+		DateCreated: bus.DateCreated.UTC(),
+	}
+
+	if bus.Applied() {
+		applied := bus.DateApplied.UTC()
+		item.DateApplied = &applied
+	}
+
+	if bus.Reverted() {
+		reverted := bus.DateReverted.UTC()
+		item.DateReverted = &reverted
+	}
+
+	return item
+}
+
+func toBusRoleAssignment(item roleAssignment) (userbus.RoleAssignment, error) {
+	id, err := uuid.Parse(item.ID)
+	if err != nil {
+		return userbus.RoleAssignment{}, fmt.Errorf("parse id: %w", err)
+	}
+
+	actorID, err := uuid.Parse(item.ActorID)
+	if err != nil {
+		return userbus.RoleAssignment{}, fmt.Errorf("parse actor id: %w", err)
+	}
+
+	addRoles, err := role.ParseMany(item.AddRoles)
+	if err != nil {
+		return userbus.RoleAssignment{}, fmt.Errorf("parse add roles: %w", err)
+	}
+
+	removeRoles, err := role.ParseMany(item.RemoveRoles)
+	if err != nil {
+		return userbus.RoleAssignment{}, fmt.Errorf("parse remove roles: %w", err)
+	}
+
+	bus := userbus.RoleAssignment{
+		ID:          id,
+		ActorID:     actorID,
+		AddRoles:    addRoles,
+		RemoveRoles: removeRoles,
+		Affected:    item.Affected,
+		RevertHash:  item.RevertHash,
+		DateCreated: item.DateCreated.In(time.Local),
+	}
+
+	if item.DateApplied != nil {
+		bus.DateApplied = item.DateApplied.In(time.Local)
+	}
+
+	if item.DateReverted != nil {
+		bus.DateReverted = item.DateReverted.In(time.Local)
+	}
+
+	return bus, nil
+}
+
+// revertGuard points from the hash of a revert token to its assignment.
+type revertGuard struct {
+	key
+	Type         string `dynamodbav:"type"`
+	AssignmentID string `dynamodbav:"assignment_id"`
+}
+
+type roleChange struct {
+	key
+	Type         string   `dynamodbav:"type"`
+	AssignmentID string   `dynamodbav:"assignment_id"`
+	UserID       string   `dynamodbav:"user_id"`
+	OldRoles     []string `dynamodbav:"old_roles" class:"internal"`
+	NewRoles     []string `dynamodbav:"new_roles" class:"internal"`
+}
+
+func toItemRoleChange(assignmentID uuid.UUID, bus userbus.RoleChange) roleChange {
+	return roleChange{
+		key:          changeKey(assignmentID.String(), bus.UserID.String()),
+		Type:         typeChange,
+		AssignmentID: assignmentID.String(),
+		UserID:       bus.UserID.String(),
+		OldRoles:     role.ParseToString(bus.OldRoles),
+		NewRoles:     role.ParseToString(bus.NewRoles),
+	}
+}
+
+func toBusRoleChanges(items []roleChange) ([]userbus.RoleChange, error) {
+	bus := make([]userbus.RoleChange, len(items))
+
+	for i, item := range items {
+		userID, err := uuid.Parse(item.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("parse user id: %w", err)
+		}
+
+		oldRoles, err := role.ParseMany(item.OldRoles)
+		if err != nil {
+			return nil, fmt.Errorf("parse old roles: %w", err)
+		}
+
+		newRoles, err := role.ParseMany(item.NewRoles)
+		if err != nil {
+			return nil, fmt.Errorf("parse new roles: %w", err)
+		}
+
+		bus[i] = userbus.RoleChange{
+			UserID:   userID,
+			OldRoles: oldRoles,
+			NewRoles: newRoles,
+		}
+	}
+
+	return bus, nil
+}
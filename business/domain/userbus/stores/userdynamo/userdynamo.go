@@ -0,0 +1,1142 @@
+// Package userdynamo contains user related CRUD functionality for DynamoDB.
+//
+// The store keeps the users and the data that belongs to them in a single
+// table keyed by pk and sk. A user's profile, password history and recovery
+// codes share the user's partition, and a global secondary index on gsi1pk
+// finds users by email. Emails are kept unique by writing a guard item for
+// each one in the same transaction as the user. CreateTable creates the
+// table for environments where it isn't provisioned separately.
+//
+// DynamoDB can't filter, sort or count users the way the business layer
+// asks for, so Query and Count scan the users and do that work in memory.
+// That's fine for the size of user tables the template is meant for.
+package userdynamo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+)
+
+// indexEmail is the global secondary index that finds users by email.
+const indexEmail = "gsi1"
+
+// Client represents the DynamoDB calls the store makes. It's satisfied by
+// *dynamodb.Client.
+type Client interface {
+	CreateTable(ctx context.Context, params *dynamodb.CreateTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error)
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+	Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+	TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error)
+}
+
+// Store manages the set of APIs for user database access.
+type Store struct {
+	log    *logger.Logger
+	client Client
+	table  string
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *logger.Logger, client Client, table string) *Store {
+	return &Store{
+		log:    log,
+		client: client,
+		table:  table,
+	}
+}
+
+// CreateTable creates the table the store relies on, billed per request. A
+// table that already exists is left alone.
+func (s *Store) CreateTable(ctx context.Context) error {
+	input := dynamodb.CreateTableInput{
+		TableName:   aws.String(s.table),
+		BillingMode: types.BillingModePayPerRequest,
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String("pk"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("sk"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("gsi1pk"), AttributeType: types.ScalarAttributeTypeS},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String("pk"), KeyType: types.KeyTypeHash},
+			{AttributeName: aws.String("sk"), KeyType: types.KeyTypeRange},
+		},
+		GlobalSecondaryIndexes: []types.GlobalSecondaryIndex{
+			{
+				IndexName: aws.String(indexEmail),
+				KeySchema: []types.KeySchemaElement{
+					{AttributeName: aws.String("gsi1pk"), KeyType: types.KeyTypeHash},
+				},
+				Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+			},
+		},
+	}
+
+	if _, err := s.client.CreateTable(ctx, &input); err != nil {
+		var inUse *types.ResourceInUseException
+		if errors.As(err, &inUse) {
+			return nil
+		}
+		return fmt.Errorf("createtable: %w", err)
+	}
+
+	return nil
+}
+
+// NewWithTx fails with sqldb.ErrTxNotSupported. A DynamoDB store can't take
+// part in a SQL transaction, and handing back the store as is would let the
+// calls made through it survive a rollback.
+func (s *Store) NewWithTx(tx sqldb.CommitRollbacker) (userbus.Storer, error) {
+	return nil, fmt.Errorf("dynamodb: %w", sqldb.ErrTxNotSupported)
+}
+
+// Create inserts a new user into the database along with the guard item
+// that reserves their email.
+func (s *Store) Create(ctx context.Context, usr userbus.User) error {
+	item := toItemUser(usr)
+
+	puts, err := s.marshalPuts(item, toItemEmailGuard(item))
+	if err != nil {
+		return err
+	}
+
+	if err := s.transact(ctx, puts); err != nil {
+		return fmt.Errorf("transactwriteitems: %w", err)
+	}
+
+	return nil
+}
+
+// CreateMany inserts a set of users into the database. DynamoDB can't
+// insert a user and its email guard for more than one user in a conditional
+// batch, so each user is inserted on its own and a taken email only skips
+// that user. The IDs of the users that were inserted are returned.
+func (s *Store) CreateMany(ctx context.Context, usrs []userbus.User) ([]uuid.UUID, error) {
+	ids := make([]uuid.UUID, 0, len(usrs))
+
+	for _, usr := range usrs {
+		if err := s.Create(ctx, usr); err != nil {
+			if errors.Is(err, userbus.ErrUniqueEmail) {
+				continue
+			}
+			return nil, err
+		}
+
+		ids = append(ids, usr.ID)
+	}
+
+	return ids, nil
+}
+
+// Update replaces a user item in the database. When the email changes the
+// guard item for the old email is swapped for one for the new email. The
+// item is only replaced when its version is the one before the user's
+// version, otherwise ErrVersionConflict is returned.
+func (s *Store) Update(ctx context.Context, usr userbus.User) error {
+	item := toItemUser(usr)
+
+	var old user
+	if err := s.get(ctx, userKey(item.ID), &old); err != nil {
+		return err
+	}
+
+	conflict := fmt.Errorf("userID[%s] version[%d]: %w", usr.ID, usr.Version, userbus.ErrVersionConflict)
+
+	if old.Version != item.Version-1 {
+		return conflict
+	}
+
+	av, err := attributevalue.MarshalMap(item)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	// The version is checked again as the item is written so an update that
+	// got in since the item was read is not overwritten.
+	condition := aws.String("version = :version")
+	values := map[string]types.AttributeValue{
+		":version": &types.AttributeValueMemberN{Value: strconv.Itoa(old.Version)},
+	}
+
+	if old.Email == item.Email {
+		input := dynamodb.PutItemInput{
+			TableName:                 aws.String(s.table),
+			Item:                      av,
+			ConditionExpression:       condition,
+			ExpressionAttributeValues: values,
+		}
+
+		if _, err := s.client.PutItem(ctx, &input); err != nil {
+			var ccf *types.ConditionalCheckFailedException
+			if errors.As(err, &ccf) {
+				return conflict
+			}
+			return fmt.Errorf("putitem: %w", err)
+		}
+
+		return nil
+	}
+
+	// The guard for the new email goes last so a failed condition is
+	// reported against it and maps to ErrUniqueEmail.
+	guard, err := attributevalue.MarshalMap(toItemEmailGuard(item))
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	oldGuard, err := attributevalue.MarshalMap(emailKey(old.Email))
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	items := []types.TransactWriteItem{
+		{Put: &types.Put{TableName: aws.String(s.table), Item: av, ConditionExpression: condition, ExpressionAttributeValues: values}},
+		{Delete: &types.Delete{TableName: aws.String(s.table), Key: oldGuard}},
+		{Put: &types.Put{TableName: aws.String(s.table), Item: guard, ConditionExpression: aws.String("attribute_not_exists(pk)")}},
+	}
+
+	if err := s.transact(ctx, items); err != nil {
+		var tce *types.TransactionCanceledException
+		if errors.As(err, &tce) && len(tce.CancellationReasons) > 0 {
+			if code := tce.CancellationReasons[0].Code; code != nil && *code == "ConditionalCheckFailed" {
+				return conflict
+			}
+		}
+		return fmt.Errorf("transactwriteitems: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes a user from the database along with the data that belongs
+// to them. The users that reported to them are left without a manager.
+func (s *Store) Delete(ctx context.Context, usr userbus.User) error {
+	userID := usr.ID.String()
+
+	// The user's partition holds the profile, password history and
+	// recovery codes.
+	keys, err := s.queryKeys(ctx, "USER#"+userID, "")
+	if err != nil {
+		return err
+	}
+
+	keys = append(keys, emailKey(usr.Email.Address))
+
+	var reports []user
+	var owned []key
+	var assignments []string
+
+	err = s.scan(ctx, func(av map[string]types.AttributeValue) error {
+		var it struct {
+			key
+			Type         string `dynamodbav:"type"`
+			UserID       string `dynamodbav:"user_id"`
+			ManagerID    string `dynamodbav:"manager_id"`
+			ActorID      string `dynamodbav:"actor_id"`
+			AssignmentID string `dynamodbav:"assignment_id"`
+		}
+		if err := attributevalue.UnmarshalMap(av, &it); err != nil {
+			return fmt.Errorf("unmarshal: %w", err)
+		}
+
+		switch {
+		case it.Type == typeUser && it.ManagerID == userID:
+			var rpt user
+			if err := attributevalue.UnmarshalMap(av, &rpt); err != nil {
+				return fmt.Errorf("unmarshal: %w", err)
+			}
+			reports = append(reports, rpt)
+
+		case (it.Type == typeIdentity || it.Type == typeChange || it.Type == typeIdemKey) && it.UserID == userID:
+			owned = append(owned, it.key)
+
+		case it.Type == typeAssignment && it.ActorID == userID:
+			assignments = append(assignments, it.AssignmentID)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, rpt := range reports {
+		rpt.ManagerID = ""
+
+		av, err := attributevalue.MarshalMap(rpt)
+		if err != nil {
+			return fmt.Errorf("marshal: %w", err)
+		}
+
+		if _, err := s.client.PutItem(ctx, &dynamodb.PutItemInput{TableName: aws.String(s.table), Item: av}); err != nil {
+			return fmt.Errorf("putitem: reports: %w", err)
+		}
+	}
+
+	keys = append(keys, owned...)
+
+	for _, assignmentID := range assignments {
+		ra, err := s.QueryRoleAssignment(ctx, uuid.MustParse(assignmentID))
+		if err != nil {
+			return err
+		}
+
+		if ra.RevertHash != "" {
+			keys = append(keys, revertKey(ra.RevertHash))
+		}
+
+		raKeys, err := s.queryKeys(ctx, "ROLEASSIGN#"+assignmentID, "")
+		if err != nil {
+			return err
+		}
+
+		keys = append(keys, raKeys...)
+	}
+
+	for _, k := range keys {
+		if err := s.delete(ctx, k, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Query retrieves a list of existing users from the database.
+func (s *Store) Query(ctx context.Context, filter userbus.QueryFilter, orderBy order.By, page page.Page) ([]userbus.User, error) {
+	usrs, err := s.scanUsers(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := sortUsers(usrs, orderBy); err != nil {
+		return nil, err
+	}
+
+	return pageUsers(usrs, orderBy, page)
+}
+
+// QueryAll calls the function with every user that matches the filter.
+func (s *Store) QueryAll(ctx context.Context, filter userbus.QueryFilter, orderBy order.By, fn func(userbus.User) error) error {
+	usrs, err := s.scanUsers(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	if err := sortUsers(usrs, orderBy); err != nil {
+		return err
+	}
+
+	for _, usr := range usrs {
+		if err := fn(usr); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// QueryWithCount retrieves a page of users along with the number of users
+// that match the filter from a single scan of the users.
+func (s *Store) QueryWithCount(ctx context.Context, filter userbus.QueryFilter, orderBy order.By, page page.Page) ([]userbus.User, int, error) {
+	usrs, err := s.scanUsers(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if err := sortUsers(usrs, orderBy); err != nil {
+		return nil, 0, err
+	}
+
+	pg, err := pageUsers(usrs, orderBy, page)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return pg, len(usrs), nil
+}
+
+// Count returns the total number of users in the DB.
+func (s *Store) Count(ctx context.Context, filter userbus.QueryFilter) (int, error) {
+	usrs, err := s.scanUsers(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(usrs), nil
+}
+
+// CountEstimate implements the userbus.Storer interface. The table's item
+// count covers every type of item, so the users are counted and the number
+// is exact.
+func (s *Store) CountEstimate(ctx context.Context, filter userbus.QueryFilter) (int, bool, error) {
+	n, err := s.Count(ctx, filter)
+	if err != nil {
+		return 0, false, err
+	}
+
+	return n, true, nil
+}
+
+// QueryByID gets the specified user from the database.
+func (s *Store) QueryByID(ctx context.Context, userID uuid.UUID) (userbus.User, error) {
+	var item user
+	if err := s.get(ctx, userKey(userID.String()), &item); err != nil {
+		return userbus.User{}, err
+	}
+
+	return toBusUser(item)
+}
+
+// QueryByIDs gets the specified users from the database.
+func (s *Store) QueryByIDs(ctx context.Context, userIDs []uuid.UUID) ([]userbus.User, error) {
+	var items []user
+
+	for _, id := range userIDs {
+		var item user
+		if err := s.get(ctx, userKey(id.String()), &item); err != nil {
+			if errors.Is(err, userbus.ErrNotFound) {
+				continue
+			}
+			return nil, err
+		}
+
+		items = append(items, item)
+	}
+
+	return toBusUsers(items)
+}
+
+// QueryByEmail gets the specified user from the database by email.
+func (s *Store) QueryByEmail(ctx context.Context, email mail.Address) (userbus.User, error) {
+	input := dynamodb.QueryInput{
+		TableName:                 aws.String(s.table),
+		IndexName:                 aws.String(indexEmail),
+		KeyConditionExpression:    aws.String("gsi1pk = :email"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{":email": &types.AttributeValueMemberS{Value: emailKey(email.Address).PK}},
+	}
+
+	out, err := s.client.Query(ctx, &input)
+	if err != nil {
+		return userbus.User{}, fmt.Errorf("query: %w", err)
+	}
+
+	if len(out.Items) == 0 {
+		return userbus.User{}, fmt.Errorf("db: %w", userbus.ErrNotFound)
+	}
+
+	var item user
+	if err := attributevalue.UnmarshalMap(out.Items[0], &item); err != nil {
+		return userbus.User{}, fmt.Errorf("unmarshal: %w", err)
+	}
+
+	return toBusUser(item)
+}
+
+// QueryByEmails gets the users from the database that match any of the
+// specified emails.
+func (s *Store) QueryByEmails(ctx context.Context, emails []mail.Address) ([]userbus.User, error) {
+	var usrs []userbus.User
+
+	for _, email := range emails {
+		usr, err := s.QueryByEmail(ctx, email)
+		if err != nil {
+			if errors.Is(err, userbus.ErrNotFound) {
+				continue
+			}
+			return nil, err
+		}
+
+		usrs = append(usrs, usr)
+	}
+
+	return usrs, nil
+}
+
+// QueryPasswordHistory retrieves the most recent password hashes recorded
+// for the specified user.
+func (s *Store) QueryPasswordHistory(ctx context.Context, userID uuid.UUID, limit int) ([][]byte, error) {
+	input := dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
+		KeyConditionExpression: aws.String("pk = :pk AND begins_with(sk, :sk)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: "USER#" + userID.String()},
+			":sk": &types.AttributeValueMemberS{Value: "PASSWORD#"},
+		},
+		ScanIndexForward: aws.Bool(false),
+		Limit:            aws.Int32(int32(limit)),
+	}
+
+	out, err := s.client.Query(ctx, &input)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	var items []passwordHistory
+	if err := attributevalue.UnmarshalListOfMaps(out.Items, &items); err != nil {
+		return nil, fmt.Errorf("unmarshal: %w", err)
+	}
+
+	hashes := make([][]byte, len(items))
+	for i, h := range items {
+		hashes[i] = h.PasswordHash
+	}
+
+	return hashes, nil
+}
+
+// AddPasswordHistory records a password hash for the specified user.
+func (s *Store) AddPasswordHistory(ctx context.Context, userID uuid.UUID, passwordHash []byte, dateCreated time.Time) error {
+	ph := passwordHistory{
+		key:          passwordKey(userID.String(), dateCreated),
+		Type:         typePassword,
+		UserID:       userID.String(),
+		PasswordHash: passwordHash,
+		DateCreated:  dateCreated.UTC(),
+	}
+
+	return s.put(ctx, ph)
+}
+
+// AddPasswordHistories records the current password hash of each of the
+// users.
+func (s *Store) AddPasswordHistories(ctx context.Context, usrs []userbus.User) error {
+	for _, usr := range usrs {
+		if err := s.AddPasswordHistory(ctx, usr.ID, usr.PasswordHash, usr.DateUpdated); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AddRecoveryCodes records the hashes of a set of recovery codes for the
+// specified user.
+func (s *Store) AddRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes []string, dateCreated time.Time) error {
+	for _, hash := range codeHashes {
+		rc := recoveryCode{
+			key:         recoveryKey(userID.String(), hash),
+			Type:        typeRecovery,
+			UserID:      userID.String(),
+			CodeHash:    hash,
+			DateCreated: dateCreated.UTC(),
+		}
+
+		if err := s.put(ctx, rc); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteRecoveryCodes removes all the recovery codes for the specified user.
+func (s *Store) DeleteRecoveryCodes(ctx context.Context, userID uuid.UUID) error {
+	keys, err := s.queryKeys(ctx, "USER#"+userID.String(), "RECOVERY#")
+	if err != nil {
+		return err
+	}
+
+	for _, k := range keys {
+		if err := s.delete(ctx, k, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UseRecoveryCode removes the recovery code with the specified hash so it
+// can't be used again. ErrNotFound is returned if the user has no such code.
+func (s *Store) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) error {
+	return s.delete(ctx, recoveryKey(userID.String(), codeHash), true)
+}
+
+// DirectReports gets the users from the database that report directly to
+// the specified user.
+func (s *Store) DirectReports(ctx context.Context, userID uuid.UUID) ([]userbus.User, error) {
+	var items []user
+
+	err := s.scan(ctx, func(av map[string]types.AttributeValue) error {
+		var item user
+		if err := attributevalue.UnmarshalMap(av, &item); err != nil {
+			return fmt.Errorf("unmarshal: %w", err)
+		}
+
+		if item.Type == typeUser && item.ManagerID == userID.String() {
+			items = append(items, item)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(items, func(a, b user) int {
+		if c := strings.Compare(a.Name, b.Name); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	return toBusUsers(items)
+}
+
+// ManagementChain gets the managers above the specified user from the
+// database, starting with the user's own manager. The users already visited
+// are tracked so bad data with a cycle can't make the walk run forever.
+func (s *Store) ManagementChain(ctx context.Context, userID uuid.UUID) ([]userbus.User, error) {
+	var item user
+	if err := s.get(ctx, userKey(userID.String()), &item); err != nil {
+		if errors.Is(err, userbus.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	seen := map[string]bool{item.ID: true}
+
+	var chain []user
+	for item.ManagerID != "" && !seen[item.ManagerID] {
+		managerID := item.ManagerID
+
+		if err := s.get(ctx, userKey(managerID), &item); err != nil {
+			if errors.Is(err, userbus.ErrNotFound) {
+				break
+			}
+			return nil, err
+		}
+
+		seen[managerID] = true
+		chain = append(chain, item)
+	}
+
+	return toBusUsers(chain)
+}
+
+// AddIdentity links an external identity to a user in the database.
+func (s *Store) AddIdentity(ctx context.Context, idn userbus.Identity) error {
+	return s.put(ctx, toItemIdentity(idn))
+}
+
+// QueryIdentity gets the identity for the account with the provider from
+// the database.
+func (s *Store) QueryIdentity(ctx context.Context, provider string, externalID string) (userbus.Identity, error) {
+	var item identity
+	if err := s.get(ctx, identityKey(provider, externalID), &item); err != nil {
+		return userbus.Identity{}, err
+	}
+
+	return toBusIdentity(item)
+}
+
+// CreateRoleAssignment inserts a previewed role assignment along with the
+// change for each user into the database. The changes share the
+// assignment's partition.
+func (s *Store) CreateRoleAssignment(ctx context.Context, ra userbus.RoleAssignment, chgs []userbus.RoleChange) error {
+	if err := s.UpdateRoleAssignment(ctx, ra); err != nil {
+		return err
+	}
+
+	for _, chg := range chgs {
+		if err := s.put(ctx, toItemRoleChange(ra.ID, chg)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UpdateRoleAssignment replaces a role assignment item in the database.
+// Once the assignment has a revert hash, a guard item points from the hash
+// to the assignment so it can be found by the revert token.
+func (s *Store) UpdateRoleAssignment(ctx context.Context, ra userbus.RoleAssignment) error {
+	if err := s.put(ctx, toItemRoleAssignment(ra)); err != nil {
+		return err
+	}
+
+	if ra.RevertHash == "" {
+		return nil
+	}
+
+	rg := revertGuard{
+		key:          revertKey(ra.RevertHash),
+		Type:         typeRevert,
+		AssignmentID: ra.ID.String(),
+	}
+
+	return s.put(ctx, rg)
+}
+
+// QueryRoleAssignment gets the specified role assignment from the database.
+func (s *Store) QueryRoleAssignment(ctx context.Context, assignmentID uuid.UUID) (userbus.RoleAssignment, error) {
+	var item roleAssignment
+	if err := s.get(ctx, assignmentKey(assignmentID.String()), &item); err != nil {
+		return userbus.RoleAssignment{}, err
+	}
+
+	return toBusRoleAssignment(item)
+}
+
+// QueryRoleAssignmentByRevertHash gets the role assignment with the
+// specified revert hash from the database.
+func (s *Store) QueryRoleAssignmentByRevertHash(ctx context.Context, revertHash string) (userbus.RoleAssignment, error) {
+	var rg revertGuard
+	if err := s.get(ctx, revertKey(revertHash), &rg); err != nil {
+		return userbus.RoleAssignment{}, err
+	}
+
+	var item roleAssignment
+	if err := s.get(ctx, assignmentKey(rg.AssignmentID), &item); err != nil {
+		return userbus.RoleAssignment{}, err
+	}
+
+	return toBusRoleAssignment(item)
+}
+
+// QueryRoleChanges gets the change for each user in the specified role
+// assignment from the database.
+func (s *Store) QueryRoleChanges(ctx context.Context, assignmentID uuid.UUID) ([]userbus.RoleChange, error) {
+	input := dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
+		KeyConditionExpression: aws.String("pk = :pk AND begins_with(sk, :sk)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: "ROLEASSIGN#" + assignmentID.String()},
+			":sk": &types.AttributeValueMemberS{Value: "CHANGE#"},
+		},
+	}
+
+	var items []roleChange
+
+	for {
+		out, err := s.client.Query(ctx, &input)
+		if err != nil {
+			return nil, fmt.Errorf("query: %w", err)
+		}
+
+		var batch []roleChange
+		if err := attributevalue.UnmarshalListOfMaps(out.Items, &batch); err != nil {
+			return nil, fmt.Errorf("unmarshal: %w", err)
+		}
+		items = append(items, batch...)
+
+		if len(out.LastEvaluatedKey) == 0 {
+			break
+		}
+		input.ExclusiveStartKey = out.LastEvaluatedKey
+	}
+
+	return toBusRoleChanges(items)
+}
+
+// QueryNames calls the function with the name and department of every user
+// as they are stored.
+func (s *Store) QueryNames(ctx context.Context, fn func(userbus.StoredName) error) error {
+	return s.scan(ctx, func(av map[string]types.AttributeValue) error {
+		if t, ok := av["type"].(*types.AttributeValueMemberS); !ok || t.Value != typeUser {
+			return nil
+		}
+
+		var item user
+		if err := attributevalue.UnmarshalMap(av, &item); err != nil {
+			return fmt.Errorf("unmarshal: %w", err)
+		}
+
+		sn, err := toBusStoredName(item)
+		if err != nil {
+			return err
+		}
+
+		return fn(sn)
+	})
+}
+
+// SetPreference saves the user's preference, replacing any value already
+// set. Preferences live in the user's partition so they are removed with
+// the user.
+func (s *Store) SetPreference(ctx context.Context, pref userbus.Preference) error {
+	return s.put(ctx, toItemPreference(pref))
+}
+
+// QueryPreferences gets the preferences the user has set from the database.
+func (s *Store) QueryPreferences(ctx context.Context, userID uuid.UUID) ([]userbus.Preference, error) {
+	input := dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
+		KeyConditionExpression: aws.String("pk = :pk AND begins_with(sk, :sk)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: "USER#" + userID.String()},
+			":sk": &types.AttributeValueMemberS{Value: "PREF#"},
+		},
+	}
+
+	var items []preference
+
+	for {
+		out, err := s.client.Query(ctx, &input)
+		if err != nil {
+			return nil, fmt.Errorf("query: %w", err)
+		}
+
+		var batch []preference
+		if err := attributevalue.UnmarshalListOfMaps(out.Items, &batch); err != nil {
+			return nil, fmt.Errorf("unmarshal: %w", err)
+		}
+		items = append(items, batch...)
+
+		if len(out.LastEvaluatedKey) == 0 {
+			break
+		}
+		input.ExclusiveStartKey = out.LastEvaluatedKey
+	}
+
+	return toBusPreferences(items)
+}
+
+// DeletePreference removes the user's preference from the database.
+func (s *Store) DeletePreference(ctx context.Context, userID uuid.UUID, key string) error {
+	return s.delete(ctx, preferenceKey(userID.String(), key), false)
+}
+
+// DeletePersonalData removes the identities, password history, recovery
+// codes and preferences held for the user.
+func (s *Store) DeletePersonalData(ctx context.Context, userID uuid.UUID) error {
+	id := userID.String()
+
+	// Everything in the user's partition other than the profile belongs to
+	// the user, identities are keyed by the provider and have to be found.
+	keys, err := s.queryKeys(ctx, "USER#"+id, "")
+	if err != nil {
+		return err
+	}
+
+	keys = slices.DeleteFunc(keys, func(k key) bool { return k == userKey(id) })
+
+	err = s.scan(ctx, func(av map[string]types.AttributeValue) error {
+		var it struct {
+			key
+			Type   string `dynamodbav:"type"`
+			UserID string `dynamodbav:"user_id"`
+		}
+		if err := attributevalue.UnmarshalMap(av, &it); err != nil {
+			return fmt.Errorf("unmarshal: %w", err)
+		}
+
+		if it.Type == typeIdentity && it.UserID == id {
+			keys = append(keys, it.key)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, k := range keys {
+		if err := s.delete(ctx, k, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UpdateLastLogin sets the date the user last logged in. The version isn't
+// changed so a login doesn't conflict with an update of the user.
+func (s *Store) UpdateLastLogin(ctx context.Context, userID uuid.UUID, dateLastLogin time.Time) error {
+	k, err := attributevalue.MarshalMap(userKey(userID.String()))
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	date, err := attributevalue.Marshal(dateLastLogin.UTC())
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	input := dynamodb.UpdateItemInput{
+		TableName:                 aws.String(s.table),
+		Key:                       k,
+		UpdateExpression:          aws.String("SET date_last_login = :date"),
+		ConditionExpression:       aws.String("attribute_exists(pk)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{":date": date},
+	}
+
+	if _, err := s.client.UpdateItem(ctx, &input); err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return fmt.Errorf("updateitem: userID[%s]: %w", userID, userbus.ErrNotFound)
+		}
+		return fmt.Errorf("updateitem: %w", err)
+	}
+
+	return nil
+}
+
+// AddIdempotencyKey records the user created for the actor's idempotency key.
+// A key that has expired but not yet been purged is replaced.
+func (s *Store) AddIdempotencyKey(ctx context.Context, ik userbus.IdempotencyKey) error {
+	return s.put(ctx, toItemIdempotencyKey(ik))
+}
+
+// QueryIdempotencyKey gets the actor's idempotency key from the database.
+func (s *Store) QueryIdempotencyKey(ctx context.Context, actorID uuid.UUID, key string) (userbus.IdempotencyKey, error) {
+	var item idempotencyKey
+	if err := s.get(ctx, idempotencyKeyKey(actorID.String(), key), &item); err != nil {
+		return userbus.IdempotencyKey{}, err
+	}
+
+	return toBusIdempotencyKey(item)
+}
+
+// DeleteIdempotencyKeys removes the idempotency keys created before the time
+// and returns the number removed. The keys are spread across partitions so
+// the table is scanned for them.
+func (s *Store) DeleteIdempotencyKeys(ctx context.Context, before time.Time) (int, error) {
+	var expired []key
+
+	err := s.scan(ctx, func(av map[string]types.AttributeValue) error {
+		if t, ok := av["type"].(*types.AttributeValueMemberS); !ok || t.Value != typeIdemKey {
+			return nil
+		}
+
+		var item idempotencyKey
+		if err := attributevalue.UnmarshalMap(av, &item); err != nil {
+			return fmt.Errorf("unmarshal: %w", err)
+		}
+
+		if item.DateCreated.Before(before) {
+			expired = append(expired, item.key)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, k := range expired {
+		if err := s.delete(ctx, k, false); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(expired), nil
+}
+
+// =============================================================================
+
+// get decodes the item with the key. ErrNotFound is returned when there is
+// no such item.
+func (s *Store) get(ctx context.Context, k key, dest any) error {
+	av, err := attributevalue.MarshalMap(k)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	out, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{TableName: aws.String(s.table), Key: av})
+	if err != nil {
+		return fmt.Errorf("getitem: %w", err)
+	}
+
+	if out.Item == nil {
+		return fmt.Errorf("db: %w", userbus.ErrNotFound)
+	}
+
+	if err := attributevalue.UnmarshalMap(out.Item, dest); err != nil {
+		return fmt.Errorf("unmarshal: %w", err)
+	}
+
+	return nil
+}
+
+// put writes the item, replacing any item with the same key.
+func (s *Store) put(ctx context.Context, item any) error {
+	av, err := attributevalue.MarshalMap(item)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	if _, err := s.client.PutItem(ctx, &dynamodb.PutItemInput{TableName: aws.String(s.table), Item: av}); err != nil {
+		return fmt.Errorf("putitem: %w", err)
+	}
+
+	return nil
+}
+
+// delete removes the item with the key. When mustExist is set ErrNotFound
+// is returned if there is no such item.
+func (s *Store) delete(ctx context.Context, k key, mustExist bool) error {
+	av, err := attributevalue.MarshalMap(k)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	input := dynamodb.DeleteItemInput{TableName: aws.String(s.table), Key: av}
+	if mustExist {
+		input.ConditionExpression = aws.String("attribute_exists(pk)")
+	}
+
+	if _, err := s.client.DeleteItem(ctx, &input); err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return fmt.Errorf("deleteitem: %w", userbus.ErrNotFound)
+		}
+		return fmt.Errorf("deleteitem: %w", err)
+	}
+
+	return nil
+}
+
+// marshalPuts returns the conditional puts that create a user and the guard
+// for their email. Neither item may already exist.
+func (s *Store) marshalPuts(usr user, guard emailGuard) ([]types.TransactWriteItem, error) {
+	items := make([]types.TransactWriteItem, 0, 2)
+
+	for _, v := range []any{usr, guard} {
+		av, err := attributevalue.MarshalMap(v)
+		if err != nil {
+			return nil, fmt.Errorf("marshal: %w", err)
+		}
+
+		items = append(items, types.TransactWriteItem{
+			Put: &types.Put{
+				TableName:           aws.String(s.table),
+				Item:                av,
+				ConditionExpression: aws.String("attribute_not_exists(pk)"),
+			},
+		})
+	}
+
+	return items, nil
+}
+
+// transact writes the items in a single transaction. A failed condition on
+// the last item, which is always an email guard, means the email is taken.
+func (s *Store) transact(ctx context.Context, items []types.TransactWriteItem) error {
+	if _, err := s.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items}); err != nil {
+		var tce *types.TransactionCanceledException
+		if errors.As(err, &tce) && len(tce.CancellationReasons) == len(items) {
+			if code := tce.CancellationReasons[len(items)-1].Code; code != nil && *code == "ConditionalCheckFailed" {
+				return userbus.ErrUniqueEmail
+			}
+		}
+		return err
+	}
+
+	return nil
+}
+
+// queryKeys returns the keys of the items in the partition, limited to the
+// sort keys with the prefix when one is given.
+func (s *Store) queryKeys(ctx context.Context, pk string, skPrefix string) ([]key, error) {
+	input := dynamodb.QueryInput{
+		TableName:                 aws.String(s.table),
+		KeyConditionExpression:    aws.String("pk = :pk"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{":pk": &types.AttributeValueMemberS{Value: pk}},
+		ProjectionExpression:      aws.String("pk, sk"),
+	}
+
+	if skPrefix != "" {
+		input.KeyConditionExpression = aws.String("pk = :pk AND begins_with(sk, :sk)")
+		input.ExpressionAttributeValues[":sk"] = &types.AttributeValueMemberS{Value: skPrefix}
+	}
+
+	var keys []key
+
+	for {
+		out, err := s.client.Query(ctx, &input)
+		if err != nil {
+			return nil, fmt.Errorf("query: %w", err)
+		}
+
+		var batch []key
+		if err := attributevalue.UnmarshalListOfMaps(out.Items, &batch); err != nil {
+			return nil, fmt.Errorf("unmarshal: %w", err)
+		}
+		keys = append(keys, batch...)
+
+		if len(out.LastEvaluatedKey) == 0 {
+			break
+		}
+		input.ExclusiveStartKey = out.LastEvaluatedKey
+	}
+
+	return keys, nil
+}
+
+// scan calls the function with every item in the table, following the
+// paging tokens DynamoDB returns until the table is exhausted.
+func (s *Store) scan(ctx context.Context, fn func(map[string]types.AttributeValue) error) error {
+	input := dynamodb.ScanInput{TableName: aws.String(s.table)}
+
+	for {
+		out, err := s.client.Scan(ctx, &input)
+		if err != nil {
+			return fmt.Errorf("scan: %w", err)
+		}
+
+		for _, av := range out.Items {
+			if err := fn(av); err != nil {
+				return err
+			}
+		}
+
+		if len(out.LastEvaluatedKey) == 0 {
+			return nil
+		}
+		input.ExclusiveStartKey = out.LastEvaluatedKey
+	}
+}
+
+// scanUsers returns the users in the table that match the filter.
+func (s *Store) scanUsers(ctx context.Context, filter userbus.QueryFilter) ([]userbus.User, error) {
+	var usrs []userbus.User
+
+	err := s.scan(ctx, func(av map[string]types.AttributeValue) error {
+		if t, ok := av["type"].(*types.AttributeValueMemberS); !ok || t.Value != typeUser {
+			return nil
+		}
+
+		var item user
+		if err := attributevalue.UnmarshalMap(av, &item); err != nil {
+			return fmt.Errorf("unmarshal: %w", err)
+		}
+
+		usr, err := toBusUser(item)
+		if err != nil {
+			return err
+		}
+
+		if matchFilter(filter, usr) {
+			usrs = append(usrs, usr)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return usrs, nil
+}
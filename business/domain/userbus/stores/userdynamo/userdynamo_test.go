@@ -0,0 +1,61 @@
+package userdynamo_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/userbus/storertest"
+	"github.com/ardanlabs/service/business/domain/userbus/stores/userdynamo"
+	"github.com/ardanlabs/service/foundation/docker"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+func Test_Storer(t *testing.T) {
+	t.Parallel()
+
+	c, err := docker.StartContainer("amazon/dynamodb-local:2.6.0", "servicetest-dynamo", "8000", nil, nil)
+	if err != nil {
+		t.Fatalf("Starting dynamodb: %v", err)
+	}
+
+	t.Logf("Name    : %s\n", c.Name)
+	t.Logf("HostPort: %s\n", c.HostPort)
+
+	client := dynamodb.New(dynamodb.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String("http://" + c.HostPort),
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "test", SecretAccessKey: "test", Source: "userdynamo_test"}, nil
+		}),
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	table := fmt.Sprintf("test_storer_%d", rand.Int31())
+
+	var buf bytes.Buffer
+	log := logger.New(&buf, logger.LevelInfo, "TEST", func(context.Context) string { return "" })
+
+	store := userdynamo.NewStore(log, client, table)
+	if err := store.CreateTable(ctx); err != nil {
+		t.Logf("Logs for %s\n%s:", c.Name, docker.DumpContainerLogs(c.Name))
+		t.Fatalf("Creating table: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if _, err := client.DeleteTable(context.Background(), &dynamodb.DeleteTableInput{TableName: aws.String(table)}); err != nil {
+			t.Errorf("Deleting table %s: %v", table, err)
+		}
+	})
+
+	storertest.Run(t, storertest.Config{
+		Storer: store,
+	})
+}
@@ -0,0 +1,187 @@
+package usermongo
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func applyFilter(filter userbus.QueryFilter) bson.D {
+	var f bson.D
+
+	if filter.ID != nil {
+		f = append(f, bson.E{Key: "_id", Value: filter.ID.String()})
+	}
+
+	if filter.TenantID != nil {
+		switch *filter.TenantID {
+		case uuid.Nil:
+			f = append(f, bson.E{Key: "tenant_id", Value: bson.D{{Key: "$exists", Value: false}}})
+		default:
+			f = append(f, bson.E{Key: "tenant_id", Value: filter.TenantID.String()})
+		}
+	}
+
+	if filter.Name != nil {
+		f = append(f, bson.E{Key: "name", Value: bson.Regex{Pattern: regexp.QuoteMeta(filter.Name.String())}})
+	}
+
+	if filter.Email != nil {
+		f = append(f, bson.E{Key: "email", Value: filter.Email.Address})
+	}
+
+	if filter.Search != nil {
+		search := bson.Regex{Pattern: regexp.QuoteMeta(*filter.Search), Options: "i"}
+		f = append(f, bson.E{Key: "$or", Value: bson.A{
+			bson.D{{Key: "name", Value: search}},
+			bson.D{{Key: "email", Value: search}},
+		}})
+	}
+
+	if len(filter.Roles) > 0 {
+		f = append(f, bson.E{Key: "roles", Value: bson.D{{Key: "$in", Value: role.ParseToString(filter.Roles)}}})
+	}
+
+	if filter.Enabled != nil {
+		f = append(f, bson.E{Key: "enabled", Value: *filter.Enabled})
+	}
+
+	if filter.Department != nil {
+		f = append(f, bson.E{Key: "department", Value: filter.Department.String()})
+	}
+
+	var created bson.D
+
+	if filter.StartCreatedDate != nil {
+		created = append(created, bson.E{Key: "$gte", Value: filter.StartCreatedDate.UTC()})
+	}
+
+	if filter.EndCreatedDate != nil {
+		created = append(created, bson.E{Key: "$lte", Value: filter.EndCreatedDate.UTC()})
+	}
+
+	if created != nil {
+		f = append(f, bson.E{Key: "date_created", Value: created})
+	}
+
+	// Users that never logged in are measured from when they were created.
+	if filter.NotLoggedInSince != nil {
+		lastLogin := bson.D{{Key: "$ifNull", Value: bson.A{"$date_last_login", "$date_created"}}}
+		f = append(f, bson.E{Key: "$expr", Value: bson.D{{Key: "$lt", Value: bson.A{lastLogin, filter.NotLoggedInSince.UTC()}}}})
+	}
+
+	if f == nil {
+		return bson.D{}
+	}
+
+	return f
+}
+
+// =============================================================================
+
+// sortFields maps the fields the results can be ordered by to the document
+// fields. There is no ranking of search results, so ordering by relevance
+// leaves the order to the fields that break ties.
+var sortFields = map[string]string{
+	userbus.OrderByID:        "_id",
+	userbus.OrderByName:      "name",
+	userbus.OrderByEmail:     "email",
+	userbus.OrderByRoles:     "roles",
+	userbus.OrderByEnabled:   "enabled",
+	userbus.OrderByDept:      "department",
+	userbus.OrderByCreated:   "date_created",
+	userbus.OrderByRelevance: "",
+}
+
+// orderBySort returns the sort document for the specified ordering. Ties
+// are always broken by the user id so the order is stable.
+func orderBySort(orderBy order.By) (bson.D, error) {
+	var sort bson.D
+	var byID bool
+
+	for _, by := range orderBy.Columns() {
+		field, exists := sortFields[by.Field]
+		if !exists {
+			return nil, fmt.Errorf("field %q does not exist", by.Field)
+		}
+
+		if field == "" {
+			continue
+		}
+
+		sort = append(sort, bson.E{Key: field, Value: direction(by.Direction)})
+		byID = byID || field == "_id"
+	}
+
+	if !byID {
+		sort = append(sort, bson.E{Key: "_id", Value: direction(orderBy.Direction)})
+	}
+
+	return sort, nil
+}
+
+func direction(dir string) int {
+	if dir == order.DESC {
+		return -1
+	}
+
+	return 1
+}
+
+// keysetFilter returns the condition that selects the documents following
+// the cursor in the specified order. Ties on the order field are broken by
+// the user id.
+func keysetFilter(orderBy order.By, cursor []string) (bson.D, error) {
+	if len(orderBy.Then) > 0 {
+		return nil, fmt.Errorf("order[%s]: multiple fields: %w", orderBy.Field, page.ErrInvalidCursor)
+	}
+
+	op := "$gt"
+	if orderBy.Direction == order.DESC {
+		op = "$lt"
+	}
+
+	if orderBy.Field == userbus.OrderByID {
+		if len(cursor) != 1 {
+			return nil, fmt.Errorf("order[%s]: %w", orderBy.Field, page.ErrInvalidCursor)
+		}
+
+		return bson.D{{Key: "_id", Value: bson.D{{Key: op, Value: cursor[0]}}}}, nil
+	}
+
+	if len(cursor) != 2 {
+		return nil, fmt.Errorf("order[%s]: %w", orderBy.Field, page.ErrInvalidCursor)
+	}
+
+	var key any
+	switch orderBy.Field {
+	case userbus.OrderByName, userbus.OrderByEmail:
+		key = cursor[0]
+
+	case userbus.OrderByEnabled:
+		enabled, err := strconv.ParseBool(cursor[0])
+		if err != nil {
+			return nil, fmt.Errorf("order[%s]: %w", orderBy.Field, page.ErrInvalidCursor)
+		}
+		key = enabled
+
+	default:
+		return nil, fmt.Errorf("order[%s]: %w", orderBy.Field, page.ErrInvalidCursor)
+	}
+
+	field := sortFields[orderBy.Field]
+
+	f := bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: field, Value: bson.D{{Key: op, Value: key}}}},
+		bson.D{{Key: field, Value: key}, {Key: "_id", Value: bson.D{{Key: op, Value: cursor[1]}}}},
+	}}}
+
+	return f, nil
+}
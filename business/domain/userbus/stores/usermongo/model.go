@@ -0,0 +1,456 @@
+package usermongo
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/types/department"
+	"github.com/ardanlabs/service/business/types/name"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/google/uuid"
+)
+
+type user struct {
+	ID            string     `bson:"_id"`
+	TenantID      string     `bson:"tenant_id,omitempty"`
+	Name          string     `bson:"name" class:"confidential"`
+	Email         string     `bson:"email" class:"confidential"`
+	Roles         []string   `bson:"roles" class:"internal"`
+	PasswordHash  []byte     `bson:"password_hash" class:"restricted"`
+	Department    *string    `bson:"department" class:"internal"`
+	ManagerID     *string    `bson:"manager_id"`
+	Enabled       bool       `bson:"enabled"`
+	TOTPSecret    string     `bson:"totp_secret" class:"restricted"`
+	TOTPEnabled   bool       `bson:"totp_enabled"`
+	AvatarKey     string     `bson:"avatar_key,omitempty"`
+	CreatedBy     string     `bson:"created_by,omitempty"`
+	UpdatedBy     string     `bson:"updated_by,omitempty"`
+	DateCreated   time.Time  `bson:"date_created"`
+	DateUpdated   time.Time  `bson:"date_updated"`
+	DateLastLogin *time.Time `bson:"date_last_login,omitempty"`
+	Version       int        `bson:"version"`
+}
+
+func toDocUser(bus userbus.User) user {
+	doc := user{
+		ID:           bus.ID.String(),
+		Name:         bus.Name.String(),
+		Email:        bus.Email.Address,
+		Roles:        role.ParseToString(bus.Roles),
+		PasswordHash: bus.PasswordHash,
+		Enabled:      bus.Enabled,
+		TOTPSecret:   bus.TOTPSecret,
+		TOTPEnabled:  bus.TOTPEnabled,
+		AvatarKey:    bus.AvatarKey,
+		DateCreated:  bus.DateCreated.UTC(),
+		DateUpdated:  bus.DateUpdated.UTC(),
+		Version:      bus.Version,
+	}
+
+	if bus.Department.Valid() {
+		department := bus.Department.String()
+		doc.Department = &department
+	}
+
+	if bus.ManagerID.Valid {
+		managerID := bus.ManagerID.UUID.String()
+		doc.ManagerID = &managerID
+	}
+
+	// Users of the default tenant aren't stored with one.
+	if bus.TenantID != uuid.Nil {
+		doc.TenantID = bus.TenantID.String()
+	}
+
+	// Users created before changes were attributed don't have actors.
+	if bus.CreatedBy != uuid.Nil {
+		doc.CreatedBy = bus.CreatedBy.String()
+	}
+
+	if bus.UpdatedBy != uuid.Nil {
+		doc.UpdatedBy = bus.UpdatedBy.String()
+	}
+
+	if !bus.DateLastLogin.IsZero() {
+		dateLastLogin := bus.DateLastLogin.UTC()
+		doc.DateLastLogin = &dateLastLogin
+	}
+
+	return doc
+}
+
+func toBusUser(doc user) (userbus.User, error) {
+	id, err := uuid.Parse(doc.ID)
+	if err != nil {
+		return userbus.User{}, fmt.Errorf("parse id: %w", err)
+	}
+
+	roles, err := role.ParseMany(doc.Roles)
+	if err != nil {
+		return userbus.User{}, fmt.Errorf("parse: %w", err)
+	}
+
+	nme, err := name.Parse(doc.Name)
+	if err != nil {
+		return userbus.User{}, fmt.Errorf("parse name: %w", err)
+	}
+
+	var dept department.Null
+	if doc.Department != nil {
+		if dept, err = department.ParseNull(*doc.Department); err != nil {
+			return userbus.User{}, fmt.Errorf("parse department: %w", err)
+		}
+	}
+
+	var managerID uuid.NullUUID
+	if doc.ManagerID != nil {
+		if managerID.UUID, err = uuid.Parse(*doc.ManagerID); err != nil {
+			return userbus.User{}, fmt.Errorf("parse manager id: %w", err)
+		}
+		managerID.Valid = true
+	}
+
+	tenantID, err := parseActor(doc.TenantID)
+	if err != nil {
+		return userbus.User{}, fmt.Errorf("parse tenant id: %w", err)
+	}
+
+	createdBy, err := parseActor(doc.CreatedBy)
+	if err != nil {
+		return userbus.User{}, fmt.Errorf("parse created by: %w", err)
+	}
+
+	updatedBy, err := parseActor(doc.UpdatedBy)
+	if err != nil {
+		return userbus.User{}, fmt.Errorf("parse updated by: %w", err)
+	}
+
+	var dateLastLogin time.Time
+	if doc.DateLastLogin != nil {
+		dateLastLogin = doc.DateLastLogin.In(time.Local)
+	}
+
+	bus := userbus.User{
+		ID:            id,
+		TenantID:      tenantID,
+		Name:          nme,
+		Email:         mail.Address{Address: doc.Email},
+		Roles:         roles,
+		PasswordHash:  doc.PasswordHash,
+		Enabled:       doc.Enabled,
+		Department:    dept,
+		ManagerID:     managerID,
+		TOTPSecret:    doc.TOTPSecret,
+		TOTPEnabled:   doc.TOTPEnabled,
+		AvatarKey:     doc.AvatarKey,
+		CreatedBy:     createdBy,
+		UpdatedBy:     updatedBy,
+		DateCreated:   doc.DateCreated.In(time.Local),
+		DateUpdated:   doc.DateUpdated.In(time.Local),
+		DateLastLogin: dateLastLogin,
+		Version:       doc.Version,
+	}
+
+	return bus, nil
+}
+
+// parseActor reads an actor that may not have been stored.
+func parseActor(s string) (uuid.UUID, error) {
+	if s == "" {
+		return uuid.Nil, nil
+	}
+
+	return uuid.Parse(s)
+}
+
+func toBusStoredName(doc user) (userbus.StoredName, error) {
+	userID, err := uuid.Parse(doc.ID)
+	if err != nil {
+		return userbus.StoredName{}, fmt.Errorf("parse user id: %w", err)
+	}
+
+	sn := userbus.StoredName{
+		UserID: userID,
+		Name:   doc.Name,
+	}
+
+	if doc.Department != nil {
+		sn.Department = *doc.Department
+	}
+
+	return sn, nil
+}
+
+func toBusUsers(docs []user) ([]userbus.User, error) {
+	bus := make([]userbus.User, len(docs))
+
+	for i, doc := range docs {
+		var err error
+		bus[i], err = toBusUser(doc)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return bus, nil
+}
+
+// =============================================================================
+
+type passwordHistory struct {
+	UserID       string    `bson:"user_id"`
+	PasswordHash []byte    `bson:"password_hash" class:"restricted"`
+	DateCreated  time.Time `bson:"date_created"`
+}
+
+type recoveryCode struct {
+	UserID      string    `bson:"user_id"`
+	CodeHash    string    `bson:"code_hash" class:"restricted"`
+	DateCreated time.Time `bson:"date_created"`
+}
+
+type identity struct {
+	Provider    string    `bson:"provider"`
+	ExternalID  string    `bson:"external_id"`
+	UserID      string    `bson:"user_id"`
+	Email       string    `bson:"email" class:"confidential"`
+	DateCreated time.Time `bson:"date_created"`
+}
+
+func toDocIdentity(bus userbus.Identity) identity {
+	return identity{
+		Provider:    bus.Provider,
+		ExternalID:  bus.ExternalID,
+		UserID:      bus.UserID.String(),
+		Email:       bus.Email.Address,
+		DateCreated: bus.DateCreated.UTC(),
+	}
+}
+
+func toBusIdentity(doc identity) (userbus.Identity, error) {
+	userID, err := uuid.Parse(doc.UserID)
+	if err != nil {
+		return userbus.Identity{}, fmt.Errorf("parse user id: %w", err)
+	}
+
+	bus := userbus.Identity{
+		Provider:    doc.Provider,
+		ExternalID:  doc.ExternalID,
+		UserID:      userID,
+		Email:       mail.Address{Address: doc.Email},
+		DateCreated: doc.DateCreated.In(time.Local),
+	}
+
+	return bus, nil
+}
+
+// =============================================================================
+
+type preference struct {
+	UserID      string    `bson:"user_id"`
+	Key         string    `bson:"key"`
+	Value       string    `bson:"value"`
+	DateUpdated time.Time `bson:"date_updated"`
+}
+
+func toDocPreference(bus userbus.Preference) preference {
+	return preference{
+		UserID:      bus.UserID.String(),
+		Key:         bus.Key,
+		Value:       string(bus.Value),
+		DateUpdated: bus.DateUpdated.UTC(),
+	}
+}
+
+func toBusPreferences(docs []preference) ([]userbus.Preference, error) {
+	bus := make([]userbus.Preference, len(docs))
+
+	for i, doc := range docs {
+		userID, err := uuid.Parse(doc.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("parse user id: %w", err)
+		}
+
+		bus[i] = userbus.Preference{
+			UserID:      userID,
+			Key:         doc.Key,
+			Value:       json.RawMessage(doc.Value),
+			DateUpdated: doc.DateUpdated.In(time.Local),
+		}
+	}
+
+	return bus, nil
+}
+
+// =============================================================================
+
+type idempotencyKey struct {
+	ActorID     string    `bson:"actor_id"`
+	Key         string    `bson:"key"`
+	UserID      string    `bson:"user_id"`
+	DateCreated time.Time `bson:"date_created"`
+}
+
+func toDocIdempotencyKey(bus userbus.IdempotencyKey) idempotencyKey {
+	return idempotencyKey{
+		ActorID:     bus.ActorID.String(),
+		Key:         bus.Key,
+		UserID:      bus.UserID.String(),
+		DateCreated: bus.DateCreated.UTC(),
+	}
+}
+
+func toBusIdempotencyKey(doc idempotencyKey) (userbus.IdempotencyKey, error) {
+	actorID, err := uuid.Parse(doc.ActorID)
+	if err != nil {
+		return userbus.IdempotencyKey{}, fmt.Errorf("parse actor id: %w", err)
+	}
+
+	userID, err := uuid.Parse(doc.UserID)
+	if err != nil {
+		return userbus.IdempotencyKey{}, fmt.Errorf("parse user id: %w", err)
+	}
+
+	bus := userbus.IdempotencyKey{
+		ActorID:     actorID,
+		Key:         doc.Key,
+		UserID:      userID,
+		DateCreated: doc.DateCreated.In(time.Local),
+	}
+
+	return bus, nil
+}
+
+// =============================================================================
+
+type roleAssignment struct {
+	ID           string     `bson:"_id"`
+	ActorID      string     `bson:"actor_id"`
+	AddRoles     []string   `bson:"add_roles"`
+	RemoveRoles  []string   `bson:"remove_roles"`
+	Affected     int        `bson:"affected"`
+	RevertHash   *string    `bson:"revert_hash" class:"restricted"`
+	DateCreated  time.Time  `bson:"date_created"`
+	DateApplied  *time.Time `bson:"date_applied"`
+	DateReverted *time.Time `bson:"date_reverted"`
+}
+
+func toDocRoleAssignment(bus userbus.RoleAssignment) roleAssignment {
+	doc := roleAssignment{
+		ID:          bus.ID.String(),
+		ActorID:     bus.ActorID.String(),
+		AddRoles:    role.ParseToString(bus.AddRoles),
+		RemoveRoles: role.ParseToString(bus.RemoveRoles),
+		Affected:    bus.Affected,
+		DateCreated: bus.DateCreated.UTC(),
+	}
+
+	if bus.RevertHash != "" {
+		doc.RevertHash = &bus.RevertHash
+	}
+
+	if bus.Applied() {
+		applied := bus.DateApplied.UTC()
+		doc.DateApplied = &applied
+	}
+
+	if bus.Reverted() {
+		reverted := bus.DateReverted.UTC()
+		doc.DateReverted = &reverted
+	}
+
+	return doc
+}
+
+func toBusRoleAssignment(doc roleAssignment) (userbus.RoleAssignment, error) {
+	id, err := uuid.Parse(doc.ID)
+	if err != nil {
+		return userbus.RoleAssignment{}, fmt.Errorf("parse id: %w", err)
+	}
+
+	actorID, err := uuid.Parse(doc.ActorID)
+	if err != nil {
+		return userbus.RoleAssignment{}, fmt.Errorf("parse actor id: %w", err)
+	}
+
+	addRoles, err := role.ParseMany(doc.AddRoles)
+	if err != nil {
+		return userbus.RoleAssignment{}, fmt.Errorf("parse add roles: %w", err)
+	}
+
+	removeRoles, err := role.ParseMany(doc.RemoveRoles)
+	if err != nil {
+		return userbus.RoleAssignment{}, fmt.Errorf("parse remove roles: %w", err)
+	}
+
+	bus := userbus.RoleAssignment{
+		ID:          id,
+		ActorID:     actorID,
+		AddRoles:    addRoles,
+		RemoveRoles: removeRoles,
+		Affected:    doc.Affected,
+		DateCreated: doc.DateCreated.In(time.Local),
+	}
+
+	if doc.RevertHash != nil {
+		bus.RevertHash = *doc.RevertHash
+	}
+
+	if doc.DateApplied != nil {
+		bus.DateApplied = doc.DateApplied.In(time.Local)
+	}
+
+	if doc.DateReverted != nil {
+		bus.DateReverted = doc.DateReverted.In(time.Local)
+	}
+
+	return bus, nil
+}
+
+type roleChange struct {
+	AssignmentID string   `bson:"assignment_id"`
+	UserID       string   `bson:"user_id"`
+	OldRoles     []string `bson:"old_roles" class:"internal"`
+	NewRoles     []string `bson:"new_roles" class:"internal"`
+}
+
+func toDocRoleChange(assignmentID uuid.UUID, bus userbus.RoleChange) roleChange {
+	return roleChange{
+		AssignmentID: assignmentID.String(),
+		UserID:       bus.UserID.String(),
+		OldRoles:     role.ParseToString(bus.OldRoles),
+		NewRoles:     role.ParseToString(bus.NewRoles),
+	}
+}
+
+func toBusRoleChanges(docs []roleChange) ([]userbus.RoleChange, error) {
+	bus := make([]userbus.RoleChange, len(docs))
+
+	for i, doc := range docs {
+		userID, err := uuid.Parse(doc.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("parse user id: %w", err)
+		}
+
+		oldRoles, err := role.ParseMany(doc.OldRoles)
+		if err != nil {
+			return nil, fmt.Errorf("parse old roles: %w", err)
+		}
+
+		newRoles, err := role.ParseMany(doc.NewRoles)
+		if err != nil {
+			return nil, fmt.Errorf("parse new roles: %w", err)
+		}
+
+		bus[i] = userbus.RoleChange{
+			UserID:   userID,
+			OldRoles: oldRoles,
+			NewRoles: newRoles,
+		}
+	}
+
+	return bus, nil
+}
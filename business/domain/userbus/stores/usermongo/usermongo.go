@@ -0,0 +1,805 @@
+// Package usermongo contains user related CRUD functionality for MongoDB.
+//
+// The store keeps the users and the data that belongs to them in their own
+// collections of the database it's given. EnsureIndexes must be called once
+// at startup so the unique email constraint is enforced.
+package usermongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Set of collections used by the store.
+const (
+	colUsers           = "users"
+	colPasswordHistory = "user_password_history"
+	colRecoveryCodes   = "user_recovery_codes"
+	colIdentities      = "user_identities"
+	colRoleAssignments = "role_assignments"
+	colRoleChanges     = "role_assignment_changes"
+	colIdempotencyKeys = "user_idempotency_keys"
+	colPreferences     = "user_preferences"
+)
+
+// Store manages the set of APIs for user database access.
+type Store struct {
+	log *logger.Logger
+	db  *mongo.Database
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *logger.Logger, db *mongo.Database) *Store {
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// EnsureIndexes creates the indexes the store relies on, which includes the
+// unique indexes that enforce the constraints the business layer expects.
+// Indexes that already exist are left alone.
+func (s *Store) EnsureIndexes(ctx context.Context) error {
+	indexes := map[string][]mongo.IndexModel{
+		colUsers: {
+			{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "manager_id", Value: 1}}},
+		},
+		colPasswordHistory: {
+			{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "date_created", Value: -1}}},
+		},
+		colRecoveryCodes: {
+			{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "code_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		},
+		colIdentities: {
+			{Keys: bson.D{{Key: "provider", Value: 1}, {Key: "external_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		},
+		colRoleAssignments: {
+			{
+				Keys: bson.D{{Key: "revert_hash", Value: 1}},
+				Options: options.Index().
+					SetUnique(true).
+					SetPartialFilterExpression(bson.D{{Key: "revert_hash", Value: bson.D{{Key: "$type", Value: "string"}}}}),
+			},
+		},
+		colRoleChanges: {
+			{Keys: bson.D{{Key: "assignment_id", Value: 1}, {Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		},
+		colPreferences: {
+			{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "key", Value: 1}}, Options: options.Index().SetUnique(true)},
+		},
+		colIdempotencyKeys: {
+			{Keys: bson.D{{Key: "actor_id", Value: 1}, {Key: "key", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "user_id", Value: 1}}},
+			{Keys: bson.D{{Key: "date_created", Value: 1}}},
+		},
+	}
+
+	for col, models := range indexes {
+		if _, err := s.db.Collection(col).Indexes().CreateMany(ctx, models); err != nil {
+			return fmt.Errorf("createindexes: %s: %w", col, err)
+		}
+	}
+
+	return nil
+}
+
+// NewWithTx fails with sqldb.ErrTxNotSupported. A MongoDB store can't take
+// part in a SQL transaction, and handing back the store as is would let the
+// calls made through it survive a rollback.
+func (s *Store) NewWithTx(tx sqldb.CommitRollbacker) (userbus.Storer, error) {
+	return nil, fmt.Errorf("mongodb: %w", sqldb.ErrTxNotSupported)
+}
+
+// Create inserts a new user into the database.
+func (s *Store) Create(ctx context.Context, usr userbus.User) error {
+	if _, err := s.db.Collection(colUsers).InsertOne(ctx, toDocUser(usr)); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("insertone: %w", userbus.ErrUniqueEmail)
+		}
+		return fmt.Errorf("insertone: %w", err)
+	}
+
+	return nil
+}
+
+// CreateMany inserts a set of users into the database. Each user is inserted
+// on its own so a taken email only skips that user. The IDs of the users
+// that were inserted are returned.
+func (s *Store) CreateMany(ctx context.Context, usrs []userbus.User) ([]uuid.UUID, error) {
+	ids := make([]uuid.UUID, 0, len(usrs))
+
+	for _, usr := range usrs {
+		if err := s.Create(ctx, usr); err != nil {
+			if errors.Is(err, userbus.ErrUniqueEmail) {
+				continue
+			}
+			return nil, err
+		}
+
+		ids = append(ids, usr.ID)
+	}
+
+	return ids, nil
+}
+
+// Update replaces a user document in the database. The document is only
+// replaced when its version is the one before the user's version, otherwise
+// ErrVersionConflict is returned.
+func (s *Store) Update(ctx context.Context, usr userbus.User) error {
+	doc := toDocUser(usr)
+
+	filter := bson.D{
+		{Key: "_id", Value: doc.ID},
+		{Key: "version", Value: doc.Version - 1},
+	}
+
+	res, err := s.db.Collection(colUsers).ReplaceOne(ctx, filter, doc)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return userbus.ErrUniqueEmail
+		}
+		return fmt.Errorf("replaceone: %w", err)
+	}
+
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("replaceone: userID[%s] version[%d]: %w", usr.ID, usr.Version, userbus.ErrVersionConflict)
+	}
+
+	return nil
+}
+
+// Delete removes a user from the database along with the data that belongs
+// to them. The users that reported to them are left without a manager.
+func (s *Store) Delete(ctx context.Context, usr userbus.User) error {
+	userID := usr.ID.String()
+	byUser := bson.D{{Key: "user_id", Value: userID}}
+
+	if _, err := s.db.Collection(colUsers).DeleteOne(ctx, bson.D{{Key: "_id", Value: userID}}); err != nil {
+		return fmt.Errorf("deleteone: %w", err)
+	}
+
+	reports := bson.D{{Key: "manager_id", Value: userID}}
+	unset := bson.D{{Key: "$set", Value: bson.D{{Key: "manager_id", Value: nil}}}}
+
+	if _, err := s.db.Collection(colUsers).UpdateMany(ctx, reports, unset); err != nil {
+		return fmt.Errorf("updatemany: reports: %w", err)
+	}
+
+	for _, col := range []string{colPasswordHistory, colRecoveryCodes, colIdentities, colRoleChanges, colIdempotencyKeys, colPreferences} {
+		if _, err := s.db.Collection(col).DeleteMany(ctx, byUser); err != nil {
+			return fmt.Errorf("deletemany: %s: %w", col, err)
+		}
+	}
+
+	var ids []string
+	if err := findAll(ctx, s, colRoleAssignments, bson.D{{Key: "actor_id", Value: userID}}, nil, func(ra roleAssignment) error {
+		ids = append(ids, ra.ID)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if len(ids) > 0 {
+		if _, err := s.db.Collection(colRoleChanges).DeleteMany(ctx, bson.D{{Key: "assignment_id", Value: bson.D{{Key: "$in", Value: ids}}}}); err != nil {
+			return fmt.Errorf("deletemany: %s: %w", colRoleChanges, err)
+		}
+
+		if _, err := s.db.Collection(colRoleAssignments).DeleteMany(ctx, bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: ids}}}}); err != nil {
+			return fmt.Errorf("deletemany: %s: %w", colRoleAssignments, err)
+		}
+	}
+
+	return nil
+}
+
+// Query retrieves a list of existing users from the database.
+func (s *Store) Query(ctx context.Context, filter userbus.QueryFilter, orderBy order.By, page page.Page) ([]userbus.User, error) {
+	f := applyFilter(filter)
+
+	sort, err := orderBySort(orderBy)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(sort).SetLimit(int64(page.RowsPerPage()))
+
+	switch {
+	case page.IsKeyset():
+		if cursor := page.Cursor(); len(cursor) > 0 {
+			kf, err := keysetFilter(orderBy, cursor)
+			if err != nil {
+				return nil, err
+			}
+			f = bson.D{{Key: "$and", Value: bson.A{f, kf}}}
+		}
+
+	default:
+		opts.SetSkip(int64((page.Number() - 1) * page.RowsPerPage()))
+	}
+
+	var docs []user
+	if err := s.find(ctx, colUsers, f, opts, &docs); err != nil {
+		return nil, err
+	}
+
+	return toBusUsers(docs)
+}
+
+// QueryAll streams every user that matches the filter from the database.
+func (s *Store) QueryAll(ctx context.Context, filter userbus.QueryFilter, orderBy order.By, fn func(userbus.User) error) error {
+	sort, err := orderBySort(orderBy)
+	if err != nil {
+		return err
+	}
+
+	return findAll(ctx, s, colUsers, applyFilter(filter), options.Find().SetSort(sort), func(doc user) error {
+		usr, err := toBusUser(doc)
+		if err != nil {
+			return err
+		}
+
+		return fn(usr)
+	})
+}
+
+// QueryWithCount retrieves a page of users along with the number of users
+// that match the filter. MongoDB counts with a separate call.
+func (s *Store) QueryWithCount(ctx context.Context, filter userbus.QueryFilter, orderBy order.By, page page.Page) ([]userbus.User, int, error) {
+	usrs, err := s.Query(ctx, filter, orderBy, page)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := s.Count(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return usrs, total, nil
+}
+
+// Count returns the total number of users in the DB.
+func (s *Store) Count(ctx context.Context, filter userbus.QueryFilter) (int, error) {
+	n, err := s.db.Collection(colUsers).CountDocuments(ctx, applyFilter(filter))
+	if err != nil {
+		return 0, fmt.Errorf("countdocuments: %w", err)
+	}
+
+	return int(n), nil
+}
+
+// CountEstimate implements the userbus.Storer interface. Without a filter
+// the number comes from the collection metadata instead of counting the
+// documents, so it may not be exact.
+func (s *Store) CountEstimate(ctx context.Context, filter userbus.QueryFilter) (int, bool, error) {
+	f := applyFilter(filter)
+	if len(f) > 0 {
+		n, err := s.Count(ctx, filter)
+		if err != nil {
+			return 0, false, err
+		}
+		return n, true, nil
+	}
+
+	n, err := s.db.Collection(colUsers).EstimatedDocumentCount(ctx)
+	if err != nil {
+		return 0, false, fmt.Errorf("estimateddocumentcount: %w", err)
+	}
+
+	return int(n), false, nil
+}
+
+// QueryByID gets the specified user from the database.
+func (s *Store) QueryByID(ctx context.Context, userID uuid.UUID) (userbus.User, error) {
+	var doc user
+	if err := s.findOne(ctx, colUsers, bson.D{{Key: "_id", Value: userID.String()}}, &doc); err != nil {
+		return userbus.User{}, err
+	}
+
+	return toBusUser(doc)
+}
+
+// QueryByIDs gets the specified users from the database.
+func (s *Store) QueryByIDs(ctx context.Context, userIDs []uuid.UUID) ([]userbus.User, error) {
+	ids := make([]string, len(userIDs))
+	for i, id := range userIDs {
+		ids[i] = id.String()
+	}
+
+	var docs []user
+	if err := s.find(ctx, colUsers, bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: ids}}}}, nil, &docs); err != nil {
+		return nil, err
+	}
+
+	return toBusUsers(docs)
+}
+
+// QueryByEmail gets the specified user from the database by email.
+func (s *Store) QueryByEmail(ctx context.Context, email mail.Address) (userbus.User, error) {
+	var doc user
+	if err := s.findOne(ctx, colUsers, bson.D{{Key: "email", Value: email.Address}}, &doc); err != nil {
+		return userbus.User{}, err
+	}
+
+	return toBusUser(doc)
+}
+
+// QueryByEmails gets the users from the database that match any of the
+// specified emails.
+func (s *Store) QueryByEmails(ctx context.Context, emails []mail.Address) ([]userbus.User, error) {
+	if len(emails) == 0 {
+		return nil, nil
+	}
+
+	addrs := make([]string, len(emails))
+	for i, email := range emails {
+		addrs[i] = email.Address
+	}
+
+	var docs []user
+	if err := s.find(ctx, colUsers, bson.D{{Key: "email", Value: bson.D{{Key: "$in", Value: addrs}}}}, nil, &docs); err != nil {
+		return nil, err
+	}
+
+	return toBusUsers(docs)
+}
+
+// QueryPasswordHistory retrieves the most recent password hashes recorded
+// for the specified user.
+func (s *Store) QueryPasswordHistory(ctx context.Context, userID uuid.UUID, limit int) ([][]byte, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "date_created", Value: -1}}).
+		SetLimit(int64(limit))
+
+	var docs []passwordHistory
+	if err := s.find(ctx, colPasswordHistory, bson.D{{Key: "user_id", Value: userID.String()}}, opts, &docs); err != nil {
+		return nil, err
+	}
+
+	hashes := make([][]byte, len(docs))
+	for i, h := range docs {
+		hashes[i] = h.PasswordHash
+	}
+
+	return hashes, nil
+}
+
+// AddPasswordHistory records a password hash for the specified user.
+func (s *Store) AddPasswordHistory(ctx context.Context, userID uuid.UUID, passwordHash []byte, dateCreated time.Time) error {
+	ph := passwordHistory{
+		UserID:       userID.String(),
+		PasswordHash: passwordHash,
+		DateCreated:  dateCreated.UTC(),
+	}
+
+	if _, err := s.db.Collection(colPasswordHistory).InsertOne(ctx, ph); err != nil {
+		return fmt.Errorf("insertone: %w", err)
+	}
+
+	return nil
+}
+
+// AddPasswordHistories records the current password hash of each of the
+// users.
+func (s *Store) AddPasswordHistories(ctx context.Context, usrs []userbus.User) error {
+	if len(usrs) == 0 {
+		return nil
+	}
+
+	phs := make([]passwordHistory, len(usrs))
+	for i, usr := range usrs {
+		phs[i] = passwordHistory{
+			UserID:       usr.ID.String(),
+			PasswordHash: usr.PasswordHash,
+			DateCreated:  usr.DateUpdated.UTC(),
+		}
+	}
+
+	if _, err := s.db.Collection(colPasswordHistory).InsertMany(ctx, phs); err != nil {
+		return fmt.Errorf("insertmany: %w", err)
+	}
+
+	return nil
+}
+
+// AddRecoveryCodes records the hashes of a set of recovery codes for the
+// specified user.
+func (s *Store) AddRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes []string, dateCreated time.Time) error {
+	if len(codeHashes) == 0 {
+		return nil
+	}
+
+	docs := make([]any, len(codeHashes))
+	for i, hash := range codeHashes {
+		docs[i] = recoveryCode{
+			UserID:      userID.String(),
+			CodeHash:    hash,
+			DateCreated: dateCreated.UTC(),
+		}
+	}
+
+	if _, err := s.db.Collection(colRecoveryCodes).InsertMany(ctx, docs); err != nil {
+		return fmt.Errorf("insertmany: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteRecoveryCodes removes all the recovery codes for the specified user.
+func (s *Store) DeleteRecoveryCodes(ctx context.Context, userID uuid.UUID) error {
+	if _, err := s.db.Collection(colRecoveryCodes).DeleteMany(ctx, bson.D{{Key: "user_id", Value: userID.String()}}); err != nil {
+		return fmt.Errorf("deletemany: %w", err)
+	}
+
+	return nil
+}
+
+// UseRecoveryCode removes the recovery code with the specified hash so it
+// can't be used again. ErrNotFound is returned if the user has no such code.
+func (s *Store) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) error {
+	f := bson.D{{Key: "user_id", Value: userID.String()}, {Key: "code_hash", Value: codeHash}}
+
+	res, err := s.db.Collection(colRecoveryCodes).DeleteOne(ctx, f)
+	if err != nil {
+		return fmt.Errorf("deleteone: %w", err)
+	}
+
+	if res.DeletedCount == 0 {
+		return fmt.Errorf("deleteone: %w", userbus.ErrNotFound)
+	}
+
+	return nil
+}
+
+// DirectReports gets the users from the database that report directly to
+// the specified user.
+func (s *Store) DirectReports(ctx context.Context, userID uuid.UUID) ([]userbus.User, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}})
+
+	var docs []user
+	if err := s.find(ctx, colUsers, bson.D{{Key: "manager_id", Value: userID.String()}}, opts, &docs); err != nil {
+		return nil, err
+	}
+
+	return toBusUsers(docs)
+}
+
+// ManagementChain gets the managers above the specified user from the
+// database, starting with the user's own manager. The users already visited
+// are tracked so bad data with a cycle can't make the walk run forever.
+func (s *Store) ManagementChain(ctx context.Context, userID uuid.UUID) ([]userbus.User, error) {
+	var doc user
+	if err := s.findOne(ctx, colUsers, bson.D{{Key: "_id", Value: userID.String()}}, &doc); err != nil {
+		if errors.Is(err, userbus.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	seen := map[string]bool{doc.ID: true}
+
+	var chain []user
+	for doc.ManagerID != nil && !seen[*doc.ManagerID] {
+		managerID := *doc.ManagerID
+
+		if err := s.findOne(ctx, colUsers, bson.D{{Key: "_id", Value: managerID}}, &doc); err != nil {
+			if errors.Is(err, userbus.ErrNotFound) {
+				break
+			}
+			return nil, err
+		}
+
+		seen[managerID] = true
+		chain = append(chain, doc)
+	}
+
+	return toBusUsers(chain)
+}
+
+// AddIdentity links an external identity to a user in the database.
+func (s *Store) AddIdentity(ctx context.Context, idn userbus.Identity) error {
+	if _, err := s.db.Collection(colIdentities).InsertOne(ctx, toDocIdentity(idn)); err != nil {
+		return fmt.Errorf("insertone: %w", err)
+	}
+
+	return nil
+}
+
+// QueryIdentity gets the identity for the account with the provider from
+// the database.
+func (s *Store) QueryIdentity(ctx context.Context, provider string, externalID string) (userbus.Identity, error) {
+	f := bson.D{{Key: "provider", Value: provider}, {Key: "external_id", Value: externalID}}
+
+	var doc identity
+	if err := s.findOne(ctx, colIdentities, f, &doc); err != nil {
+		return userbus.Identity{}, err
+	}
+
+	return toBusIdentity(doc)
+}
+
+// CreateRoleAssignment inserts a previewed role assignment along with the
+// change for each user into the database.
+func (s *Store) CreateRoleAssignment(ctx context.Context, ra userbus.RoleAssignment, chgs []userbus.RoleChange) error {
+	if _, err := s.db.Collection(colRoleAssignments).InsertOne(ctx, toDocRoleAssignment(ra)); err != nil {
+		return fmt.Errorf("insertone: %w", err)
+	}
+
+	if len(chgs) == 0 {
+		return nil
+	}
+
+	docs := make([]any, len(chgs))
+	for i, chg := range chgs {
+		docs[i] = toDocRoleChange(ra.ID, chg)
+	}
+
+	if _, err := s.db.Collection(colRoleChanges).InsertMany(ctx, docs); err != nil {
+		return fmt.Errorf("insertmany: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateRoleAssignment replaces a role assignment document in the database.
+func (s *Store) UpdateRoleAssignment(ctx context.Context, ra userbus.RoleAssignment) error {
+	doc := toDocRoleAssignment(ra)
+
+	if _, err := s.db.Collection(colRoleAssignments).ReplaceOne(ctx, bson.D{{Key: "_id", Value: doc.ID}}, doc); err != nil {
+		return fmt.Errorf("replaceone: %w", err)
+	}
+
+	return nil
+}
+
+// QueryRoleAssignment gets the specified role assignment from the database.
+func (s *Store) QueryRoleAssignment(ctx context.Context, assignmentID uuid.UUID) (userbus.RoleAssignment, error) {
+	var doc roleAssignment
+	if err := s.findOne(ctx, colRoleAssignments, bson.D{{Key: "_id", Value: assignmentID.String()}}, &doc); err != nil {
+		return userbus.RoleAssignment{}, err
+	}
+
+	return toBusRoleAssignment(doc)
+}
+
+// QueryRoleAssignmentByRevertHash gets the role assignment with the
+// specified revert hash from the database.
+func (s *Store) QueryRoleAssignmentByRevertHash(ctx context.Context, revertHash string) (userbus.RoleAssignment, error) {
+	var doc roleAssignment
+	if err := s.findOne(ctx, colRoleAssignments, bson.D{{Key: "revert_hash", Value: revertHash}}, &doc); err != nil {
+		return userbus.RoleAssignment{}, err
+	}
+
+	return toBusRoleAssignment(doc)
+}
+
+// QueryRoleChanges gets the change for each user in the specified role
+// assignment from the database.
+func (s *Store) QueryRoleChanges(ctx context.Context, assignmentID uuid.UUID) ([]userbus.RoleChange, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "user_id", Value: 1}})
+
+	var docs []roleChange
+	if err := s.find(ctx, colRoleChanges, bson.D{{Key: "assignment_id", Value: assignmentID.String()}}, opts, &docs); err != nil {
+		return nil, err
+	}
+
+	return toBusRoleChanges(docs)
+}
+
+// QueryNames calls the function with the name and department of every user
+// as they are stored.
+func (s *Store) QueryNames(ctx context.Context, fn func(userbus.StoredName) error) error {
+	opts := options.Find().SetProjection(bson.D{{Key: "name", Value: 1}, {Key: "department", Value: 1}})
+
+	return findAll(ctx, s, colUsers, bson.D{}, opts, func(doc user) error {
+		sn, err := toBusStoredName(doc)
+		if err != nil {
+			return err
+		}
+
+		return fn(sn)
+	})
+}
+
+// SetPreference saves the user's preference, replacing any value already
+// set. The value is kept as JSON text so it reads back exactly as written.
+func (s *Store) SetPreference(ctx context.Context, pref userbus.Preference) error {
+	doc := toDocPreference(pref)
+
+	filter := bson.D{
+		{Key: "user_id", Value: doc.UserID},
+		{Key: "key", Value: doc.Key},
+	}
+
+	if _, err := s.db.Collection(colPreferences).ReplaceOne(ctx, filter, doc, options.Replace().SetUpsert(true)); err != nil {
+		return fmt.Errorf("replaceone: %w", err)
+	}
+
+	return nil
+}
+
+// QueryPreferences gets the preferences the user has set from the database.
+func (s *Store) QueryPreferences(ctx context.Context, userID uuid.UUID) ([]userbus.Preference, error) {
+	var docs []preference
+	if err := s.find(ctx, colPreferences, bson.D{{Key: "user_id", Value: userID.String()}}, nil, &docs); err != nil {
+		return nil, err
+	}
+
+	return toBusPreferences(docs)
+}
+
+// DeletePreference removes the user's preference from the database.
+func (s *Store) DeletePreference(ctx context.Context, userID uuid.UUID, key string) error {
+	filter := bson.D{
+		{Key: "user_id", Value: userID.String()},
+		{Key: "key", Value: key},
+	}
+
+	if _, err := s.db.Collection(colPreferences).DeleteOne(ctx, filter); err != nil {
+		return fmt.Errorf("deleteone: %w", err)
+	}
+
+	return nil
+}
+
+// DeletePersonalData removes the identities, password history, recovery
+// codes and preferences held for the user.
+func (s *Store) DeletePersonalData(ctx context.Context, userID uuid.UUID) error {
+	byUser := bson.D{{Key: "user_id", Value: userID.String()}}
+
+	for _, col := range []string{colIdentities, colPasswordHistory, colRecoveryCodes, colPreferences} {
+		if _, err := s.db.Collection(col).DeleteMany(ctx, byUser); err != nil {
+			return fmt.Errorf("deletemany: %s: %w", col, err)
+		}
+	}
+
+	return nil
+}
+
+// UpdateLastLogin sets the date the user last logged in. The version isn't
+// changed so a login doesn't conflict with an update of the user.
+func (s *Store) UpdateLastLogin(ctx context.Context, userID uuid.UUID, dateLastLogin time.Time) error {
+	filter := bson.D{{Key: "_id", Value: userID.String()}}
+	set := bson.D{{Key: "$set", Value: bson.D{{Key: "date_last_login", Value: dateLastLogin.UTC()}}}}
+
+	res, err := s.db.Collection(colUsers).UpdateOne(ctx, filter, set)
+	if err != nil {
+		return fmt.Errorf("updateone: %w", err)
+	}
+
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("updateone: userID[%s]: %w", userID, userbus.ErrNotFound)
+	}
+
+	return nil
+}
+
+// AddIdempotencyKey records the user created for the actor's idempotency key.
+// A key that has expired but not yet been purged is replaced.
+func (s *Store) AddIdempotencyKey(ctx context.Context, ik userbus.IdempotencyKey) error {
+	doc := toDocIdempotencyKey(ik)
+
+	filter := bson.D{
+		{Key: "actor_id", Value: doc.ActorID},
+		{Key: "key", Value: doc.Key},
+	}
+
+	if _, err := s.db.Collection(colIdempotencyKeys).ReplaceOne(ctx, filter, doc, options.Replace().SetUpsert(true)); err != nil {
+		return fmt.Errorf("replaceone: %w", err)
+	}
+
+	return nil
+}
+
+// QueryIdempotencyKey gets the actor's idempotency key from the database.
+func (s *Store) QueryIdempotencyKey(ctx context.Context, actorID uuid.UUID, key string) (userbus.IdempotencyKey, error) {
+	filter := bson.D{
+		{Key: "actor_id", Value: actorID.String()},
+		{Key: "key", Value: key},
+	}
+
+	var doc idempotencyKey
+	if err := s.findOne(ctx, colIdempotencyKeys, filter, &doc); err != nil {
+		return userbus.IdempotencyKey{}, err
+	}
+
+	return toBusIdempotencyKey(doc)
+}
+
+// DeleteIdempotencyKeys removes the idempotency keys created before the time
+// and returns the number removed.
+func (s *Store) DeleteIdempotencyKeys(ctx context.Context, before time.Time) (int, error) {
+	filter := bson.D{{Key: "date_created", Value: bson.D{{Key: "$lt", Value: before.UTC()}}}}
+
+	res, err := s.db.Collection(colIdempotencyKeys).DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("deletemany: %w", err)
+	}
+
+	return int(res.DeletedCount), nil
+}
+
+// =============================================================================
+
+// findOne decodes the document that matches the filter. ErrNotFound is
+// returned when there is no such document.
+func (s *Store) findOne(ctx context.Context, col string, filter bson.D, dest any) error {
+	if err := s.db.Collection(col).FindOne(ctx, filter).Decode(dest); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return fmt.Errorf("db: %w", userbus.ErrNotFound)
+		}
+		return fmt.Errorf("db: %w", err)
+	}
+
+	return nil
+}
+
+// find decodes every document that matches the filter into the slice.
+func (s *Store) find(ctx context.Context, col string, filter bson.D, opts *options.FindOptionsBuilder, dest any) error {
+	if opts == nil {
+		opts = options.Find()
+	}
+
+	cur, err := s.db.Collection(col).Find(ctx, filter, opts)
+	if err != nil {
+		return fmt.Errorf("find: %s: %w", col, err)
+	}
+
+	if err := cur.All(ctx, dest); err != nil {
+		return fmt.Errorf("all: %s: %w", col, err)
+	}
+
+	return nil
+}
+
+// findAll calls the function with each document that matches the filter
+// without holding them all in memory.
+func findAll[T any](ctx context.Context, s *Store, col string, filter bson.D, opts *options.FindOptionsBuilder, fn func(T) error) error {
+	if opts == nil {
+		opts = options.Find()
+	}
+
+	cur, err := s.db.Collection(col).Find(ctx, filter, opts)
+	if err != nil {
+		return fmt.Errorf("find: %s: %w", col, err)
+	}
+
+	defer func() {
+		if err := cur.Close(ctx); err != nil {
+			s.log.Error(ctx, "usermongo: close cursor", "collection", col, "ERROR", err)
+		}
+	}()
+
+	for cur.Next(ctx) {
+		var doc T
+		if err := cur.Decode(&doc); err != nil {
+			return fmt.Errorf("decode: %s: %w", col, err)
+		}
+
+		if err := fn(doc); err != nil {
+			return err
+		}
+	}
+
+	if err := cur.Err(); err != nil {
+		return fmt.Errorf("next: %s: %w", col, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package usermongo_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/userbus/storertest"
+	"github.com/ardanlabs/service/business/domain/userbus/stores/usermongo"
+	"github.com/ardanlabs/service/foundation/docker"
+	"github.com/ardanlabs/service/foundation/logger"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func Test_Storer(t *testing.T) {
+	t.Parallel()
+
+	c, err := docker.StartContainer("mongo:8.0", "servicetest-mongo", "27017", nil, nil)
+	if err != nil {
+		t.Fatalf("Starting mongodb: %v", err)
+	}
+
+	t.Logf("Name    : %s\n", c.Name)
+	t.Logf("HostPort: %s\n", c.HostPort)
+
+	client, err := mongo.Connect(options.Client().ApplyURI("mongodb://" + c.HostPort))
+	if err != nil {
+		t.Fatalf("Connecting to mongodb: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx, nil); err != nil {
+		t.Logf("Logs for %s\n%s:", c.Name, docker.DumpContainerLogs(c.Name))
+		t.Fatalf("Pinging mongodb: %v", err)
+	}
+
+	db := client.Database(fmt.Sprintf("test_storer_%d", rand.Int31()))
+
+	t.Cleanup(func() {
+		if err := db.Drop(context.Background()); err != nil {
+			t.Errorf("Dropping database %s: %v", db.Name(), err)
+		}
+		client.Disconnect(context.Background())
+	})
+
+	var buf bytes.Buffer
+	log := logger.New(&buf, logger.LevelInfo, "TEST", func(context.Context) string { return "" })
+
+	store := usermongo.NewStore(log, db)
+	if err := store.EnsureIndexes(ctx); err != nil {
+		t.Fatalf("Creating indexes: %v", err)
+	}
+
+	storertest.Run(t, storertest.Config{
+		Storer: store,
+	})
+}
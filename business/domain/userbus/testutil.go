@@ -5,17 +5,28 @@ import (
 	"fmt"
 	"math/rand"
 	"net/mail"
+	"sync/atomic"
 
+	"github.com/ardanlabs/service/business/types/department"
 	"github.com/ardanlabs/service/business/types/name"
 	"github.com/ardanlabs/service/business/types/role"
-	"github.com/google/uuid"
 )
 
-// TestNewUsers is a helper method for testing.
+// testIdx numbers the users generated for testing. It's shared by every
+// call so users generated by separate calls in the same test don't share
+// an email, and starts at a random number so tests don't depend on it.
+var testIdx = func() *atomic.Int64 {
+	var idx atomic.Int64
+	idx.Store(rand.Int63n(10000))
+	return &idx
+}()
+
+// TestNewUsers is a helper method for testing. The users are unique across
+// calls and their names all contain "Name" so tests can filter for them.
 func TestNewUsers(n int, rle role.Role) []NewUser {
 	newUsrs := make([]NewUser, n)
 
-	idx := rand.Intn(10000)
+	idx := testIdx.Add(int64(n)) - int64(n)
 	for i := range n {
 		idx++
 
@@ -23,7 +34,7 @@ func TestNewUsers(n int, rle role.Role) []NewUser {
 			Name:       name.MustParse(fmt.Sprintf("Name%d", idx)),
 			Email:      mail.Address{Address: fmt.Sprintf("Email%d@gmail.com", idx)},
 			Roles:      []role.Role{rle},
-			Department: name.MustParseNull(fmt.Sprintf("Department%d", idx)),
+			Department: department.MustParseNull(fmt.Sprintf("Department%d", idx)),
 			Password:   fmt.Sprintf("Password%d", idx),
 		}
 
@@ -33,13 +44,14 @@ func TestNewUsers(n int, rle role.Role) []NewUser {
 	return newUsrs
 }
 
-// TestSeedUsers is a helper method for testing.
+// TestSeedUsers is a helper method for testing. The users are created by
+// the tooling actor.
 func TestSeedUsers(ctx context.Context, n int, role role.Role, api Business) ([]User, error) {
 	newUsrs := TestNewUsers(n, role)
 
 	usrs := make([]User, len(newUsrs))
 	for i, nu := range newUsrs {
-		usr, err := api.Create(ctx, uuid.UUID{}, nu)
+		usr, _, err := api.Create(ctx, ActorTooling, nu)
 		if err != nil {
 			return nil, fmt.Errorf("seeding user: idx: %d : %w", i, err)
 		}
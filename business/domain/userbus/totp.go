@@ -0,0 +1,212 @@
+package userbus
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/ardanlabs/service/foundation/clock"
+	"github.com/ardanlabs/service/foundation/otel"
+	"github.com/ardanlabs/service/foundation/totp"
+	"github.com/google/uuid"
+)
+
+const (
+	totpIssuer        = "service"
+	recoveryCodeCount = 10
+	recoveryCodeBytes = 10
+)
+
+// EnrollTOTP generates a new one-time code secret for the user. The secret
+// isn't used for authentication until it's confirmed with a code generated
+// from it, so enrolling again before confirming replaces the secret. The
+// secret and an otpauth URL for authenticator apps are returned.
+func (b *business) EnrollTOTP(ctx context.Context, userID uuid.UUID) (string, string, error) {
+	ctx, span := otel.AddSpan(ctx, "business.userbus.enrolltotp")
+	defer span.End()
+
+	usr, err := b.QueryByID(ctx, userID)
+	if err != nil {
+		return "", "", err
+	}
+
+	if usr.TOTPEnabled {
+		return "", "", fmt.Errorf("userID[%s]: %w", userID, ErrTOTPEnabled)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return "", "", fmt.Errorf("generate secret: %w", err)
+	}
+
+	usr.TOTPSecret = secret
+	usr.UpdatedBy = userID
+	usr.DateUpdated = clock.Now()
+	usr.Version++
+
+	if err := b.storer.Update(ctx, usr); err != nil {
+		return "", "", fmt.Errorf("update: %w", err)
+	}
+
+	return secret, totp.URL(totpIssuer, usr.Email.Address, secret), nil
+}
+
+// ConfirmTOTP enables one-time codes for the user once the code proves the
+// secret was enrolled. A new set of recovery codes is returned, which is the
+// only time they are available since just their hashes are stored.
+func (b *business) ConfirmTOTP(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	ctx, span := otel.AddSpan(ctx, "business.userbus.confirmtotp")
+	defer span.End()
+
+	usr, err := b.QueryByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case usr.TOTPEnabled:
+		return nil, fmt.Errorf("userID[%s]: %w", userID, ErrTOTPEnabled)
+	case usr.TOTPSecret == "":
+		return nil, fmt.Errorf("userID[%s]: %w", userID, ErrTOTPNotEnrolled)
+	}
+
+	now := clock.Now()
+
+	if !totp.Validate(usr.TOTPSecret, code, now) {
+		return nil, fmt.Errorf("validate: %w", ErrAuthenticationFailure)
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	usr.TOTPEnabled = true
+	usr.UpdatedBy = userID
+	usr.DateUpdated = now
+	usr.Version++
+
+	if err := b.storer.Update(ctx, usr); err != nil {
+		return nil, fmt.Errorf("update: %w", err)
+	}
+
+	if err := b.storer.DeleteRecoveryCodes(ctx, userID); err != nil {
+		return nil, fmt.Errorf("deleterecoverycodes: %w", err)
+	}
+
+	if err := b.storer.AddRecoveryCodes(ctx, userID, hashes, now); err != nil {
+		return nil, fmt.Errorf("addrecoverycodes: %w", err)
+	}
+
+	return codes, nil
+}
+
+// DisableTOTP turns off one-time codes for the user, removing the secret
+// and any unused recovery codes.
+func (b *business) DisableTOTP(ctx context.Context, userID uuid.UUID) error {
+	ctx, span := otel.AddSpan(ctx, "business.userbus.disabletotp")
+	defer span.End()
+
+	usr, err := b.QueryByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if usr.TOTPSecret == "" {
+		return fmt.Errorf("userID[%s]: %w", userID, ErrTOTPNotEnrolled)
+	}
+
+	usr.TOTPSecret = ""
+	usr.TOTPEnabled = false
+	usr.UpdatedBy = userID
+	usr.DateUpdated = clock.Now()
+	usr.Version++
+
+	if err := b.storer.Update(ctx, usr); err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+
+	if err := b.storer.DeleteRecoveryCodes(ctx, userID); err != nil {
+		return fmt.Errorf("deleterecoverycodes: %w", err)
+	}
+
+	return nil
+}
+
+// AuthenticateWithTOTP finds a user by their email and verifies their
+// password along with a one-time code, or one of their recovery codes. A
+// recovery code can only be used once. The code is ignored for users that
+// don't have one-time codes enabled.
+func (b *business) AuthenticateWithTOTP(ctx context.Context, email mail.Address, password string, code string) (User, error) {
+	ctx, span := otel.AddSpan(ctx, "business.userbus.authenticatewithtotp")
+	defer span.End()
+
+	usr, err := b.authenticate(ctx, email, password)
+	if err != nil {
+		return User{}, err
+	}
+
+	if !usr.TOTPEnabled {
+		return b.recordLogin(ctx, usr), nil
+	}
+
+	if code == "" {
+		return User{}, fmt.Errorf("userID[%s]: %w", usr.ID, ErrTOTPRequired)
+	}
+
+	if totp.Validate(usr.TOTPSecret, code, time.Now()) {
+		return b.recordLogin(ctx, usr), nil
+	}
+
+	if err := b.storer.UseRecoveryCode(ctx, usr.ID, hashRecoveryCode(code)); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return User{}, fmt.Errorf("validate: %w", ErrAuthenticationFailure)
+		}
+		return User{}, fmt.Errorf("userecoverycode: %w", err)
+	}
+
+	return b.recordLogin(ctx, usr), nil
+}
+
+// =============================================================================
+
+// recoveryEncoding produces recovery codes that are easy to read back and
+// type in.
+var recoveryEncoding = base32.NewEncoding("abcdefghijkmnpqrstuvwxyz23456789").WithPadding(base32.NoPadding)
+
+// newRecoveryCodes generates a set of recovery codes along with the hashes
+// that are stored. The codes are random enough that a plain SHA-256 hash is
+// sufficient, which lets a code be found by its hash.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+
+	for i := range codes {
+		b := make([]byte, recoveryCodeBytes)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, fmt.Errorf("read random: %w", err)
+		}
+
+		code := recoveryEncoding.EncodeToString(b)
+		codes[i] = code[:8] + "-" + code[8:]
+		hashes[i] = hashRecoveryCode(code)
+	}
+
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode returns the stored form of a recovery code. The dash and
+// case are ignored so the code can be typed the way it reads.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
@@ -3,26 +3,42 @@ package userbus
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/mail"
 	"time"
 
+	"github.com/ardanlabs/service/business/sdk/buserr"
 	"github.com/ardanlabs/service/business/sdk/delegate"
 	"github.com/ardanlabs/service/business/sdk/order"
 	"github.com/ardanlabs/service/business/sdk/page"
 	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/business/sdk/tenant"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/ardanlabs/service/foundation/clock"
 	"github.com/ardanlabs/service/foundation/logger"
 	"github.com/ardanlabs/service/foundation/otel"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // Set of error variables for CRUD operations.
 var (
-	ErrNotFound              = errors.New("user not found")
-	ErrUniqueEmail           = errors.New("email is not unique")
-	ErrAuthenticationFailure = errors.New("authentication failed")
+	ErrNotFound              = buserr.New(buserr.NotFound, "user not found")
+	ErrUniqueEmail           = buserr.New(buserr.Conflict, "email is not unique")
+	ErrAuthenticationFailure = buserr.New(buserr.Unauthenticated, "authentication failed")
+	ErrForbidden             = buserr.New(buserr.PermissionDenied, "attempted action is not allowed")
+	ErrTOTPRequired          = buserr.New(buserr.Unauthenticated, "one-time code required")
+	ErrTOTPNotEnrolled       = buserr.New(buserr.FailedPrecondition, "one-time codes not enrolled")
+	ErrTOTPEnabled           = buserr.New(buserr.FailedPrecondition, "one-time codes already enabled")
+	ErrManagerCycle          = buserr.New(buserr.InvalidArgument, "manager would create a reporting cycle")
+	ErrEmailNotVerified      = buserr.New(buserr.PermissionDenied, "email not verified by identity provider")
+	ErrQueryTooExpensive     = buserr.New(buserr.FailedPrecondition, "query is too expensive, narrow the filters")
+	ErrQueryTimeout          = buserr.New(buserr.DeadlineExceeded, "query took too long, narrow the filters")
+	ErrVersionConflict       = buserr.New(buserr.Conflict, "user was changed by another update")
+	ErrIdempotencyMismatch   = buserr.New(buserr.FailedPrecondition, "idempotency key was used for a different user")
+	ErrTooManyAttempts       = buserr.New(buserr.ResourceExhausted, "too many authentication attempts")
 )
 
 // Storer interface declares the behavior this package needs to persist and
@@ -30,12 +46,42 @@ var (
 type Storer interface {
 	NewWithTx(tx sqldb.CommitRollbacker) (Storer, error)
 	Create(ctx context.Context, usr User) error
+	CreateMany(ctx context.Context, usrs []User) ([]uuid.UUID, error)
 	Update(ctx context.Context, usr User) error
 	Delete(ctx context.Context, usr User) error
 	Query(ctx context.Context, filter QueryFilter, orderBy order.By, page page.Page) ([]User, error)
+	QueryWithCount(ctx context.Context, filter QueryFilter, orderBy order.By, page page.Page) ([]User, int, error)
+	QueryAll(ctx context.Context, filter QueryFilter, orderBy order.By, fn func(User) error) error
 	Count(ctx context.Context, filter QueryFilter) (int, error)
+	CountEstimate(ctx context.Context, filter QueryFilter) (int, bool, error)
 	QueryByID(ctx context.Context, userID uuid.UUID) (User, error)
+	QueryByIDs(ctx context.Context, userIDs []uuid.UUID) ([]User, error)
 	QueryByEmail(ctx context.Context, email mail.Address) (User, error)
+	QueryByEmails(ctx context.Context, emails []mail.Address) ([]User, error)
+	QueryPasswordHistory(ctx context.Context, userID uuid.UUID, limit int) ([][]byte, error)
+	AddPasswordHistory(ctx context.Context, userID uuid.UUID, passwordHash []byte, dateCreated time.Time) error
+	AddPasswordHistories(ctx context.Context, usrs []User) error
+	AddRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes []string, dateCreated time.Time) error
+	DeleteRecoveryCodes(ctx context.Context, userID uuid.UUID) error
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) error
+	DirectReports(ctx context.Context, userID uuid.UUID) ([]User, error)
+	ManagementChain(ctx context.Context, userID uuid.UUID) ([]User, error)
+	AddIdentity(ctx context.Context, idn Identity) error
+	QueryIdentity(ctx context.Context, provider string, externalID string) (Identity, error)
+	CreateRoleAssignment(ctx context.Context, ra RoleAssignment, chgs []RoleChange) error
+	UpdateRoleAssignment(ctx context.Context, ra RoleAssignment) error
+	QueryRoleAssignment(ctx context.Context, assignmentID uuid.UUID) (RoleAssignment, error)
+	QueryRoleAssignmentByRevertHash(ctx context.Context, revertHash string) (RoleAssignment, error)
+	QueryRoleChanges(ctx context.Context, assignmentID uuid.UUID) ([]RoleChange, error)
+	AddIdempotencyKey(ctx context.Context, ik IdempotencyKey) error
+	QueryIdempotencyKey(ctx context.Context, actorID uuid.UUID, key string) (IdempotencyKey, error)
+	DeleteIdempotencyKeys(ctx context.Context, before time.Time) (int, error)
+	QueryNames(ctx context.Context, fn func(StoredName) error) error
+	SetPreference(ctx context.Context, pref Preference) error
+	QueryPreferences(ctx context.Context, userID uuid.UUID) ([]Preference, error)
+	DeletePreference(ctx context.Context, userID uuid.UUID, key string) error
+	DeletePersonalData(ctx context.Context, userID uuid.UUID) error
+	UpdateLastLogin(ctx context.Context, userID uuid.UUID, dateLastLogin time.Time) error
 }
 
 // Plugin is a function that wraps different layers of business logic around
@@ -46,14 +92,41 @@ type Plugin func(Business) Business
 // around the core busines logic.
 type Business interface {
 	NewWithTx(tx sqldb.CommitRollbacker) (Business, error)
-	Create(ctx context.Context, actorID uuid.UUID, nu NewUser) (User, error)
+	Create(ctx context.Context, actorID uuid.UUID, nu NewUser) (User, bool, error)
+	CreateOrGet(ctx context.Context, actorID uuid.UUID, nu NewUser) (User, bool, error)
+	CreateBatch(ctx context.Context, actorID uuid.UUID, nus []NewUser, mode BatchMode) ([]User, []BatchError)
 	Update(ctx context.Context, actorID uuid.UUID, usr User, uu UpdateUser) (User, error)
 	Delete(ctx context.Context, actorID uuid.UUID, usr User) error
+	Anonymize(ctx context.Context, actorID uuid.UUID, userID uuid.UUID) (User, error)
 	Query(ctx context.Context, filter QueryFilter, orderBy order.By, page page.Page) ([]User, error)
+	QueryWithCount(ctx context.Context, filter QueryFilter, orderBy order.By, page page.Page) ([]User, int, error)
+	QueryAll(ctx context.Context, filter QueryFilter, orderBy order.By, fn func(User) error) error
 	Count(ctx context.Context, filter QueryFilter) (int, error)
+	CountEstimate(ctx context.Context, filter QueryFilter) (int, bool, error)
 	QueryByID(ctx context.Context, userID uuid.UUID) (User, error)
+	QueryByIDs(ctx context.Context, userIDs []uuid.UUID) ([]User, error)
 	QueryByEmail(ctx context.Context, email mail.Address) (User, error)
 	Authenticate(ctx context.Context, email mail.Address, password string) (User, error)
+	AuthenticateWithTOTP(ctx context.Context, email mail.Address, password string, code string) (User, error)
+	EnrollTOTP(ctx context.Context, userID uuid.UUID) (string, string, error)
+	ConfirmTOTP(ctx context.Context, userID uuid.UUID, code string) ([]string, error)
+	DisableTOTP(ctx context.Context, userID uuid.UUID) error
+	ChangePassword(ctx context.Context, userID uuid.UUID, currentPassword string, newPassword string) error
+	DirectReports(ctx context.Context, userID uuid.UUID) ([]User, error)
+	ManagementChain(ctx context.Context, userID uuid.UUID) ([]User, error)
+	FederateLogin(ctx context.Context, provider string, externalID string, email mail.Address, profile Profile) (User, bool, error)
+	AssignRolesByFilter(ctx context.Context, actorID uuid.UUID, filter QueryFilter, addRoles []role.Role, removeRoles []role.Role) (RoleAssignment, error)
+	ApplyRoleAssignment(ctx context.Context, actorID uuid.UUID, assignmentID uuid.UUID) (RoleAssignment, string, error)
+	RevertRoleAssignment(ctx context.Context, actorID uuid.UUID, token string) (RoleAssignment, error)
+	PurgeIdempotencyKeys(ctx context.Context) (int, error)
+	NormalizeNames(ctx context.Context) (int, error)
+	SetPreference(ctx context.Context, userID uuid.UUID, key string, value json.RawMessage) (Preference, error)
+	GetPreferences(ctx context.Context, userID uuid.UUID) ([]Preference, error)
+	DeletePreference(ctx context.Context, userID uuid.UUID, key string) error
+	UpdateAvatar(ctx context.Context, actorID uuid.UUID, userID uuid.UUID, r io.Reader, contentType string) (User, error)
+	AvatarURL(ctx context.Context, usr User) (string, error)
+	ExportData(ctx context.Context, actorID uuid.UUID, userID uuid.UUID) (io.Reader, error)
+	DisableDormant(ctx context.Context, inactiveFor time.Duration) ([]User, error)
 }
 
 // Business manages the set of APIs for user access.
@@ -61,14 +134,23 @@ type business struct {
 	log      *logger.Logger
 	storer   Storer
 	delegate *delegate.Delegate
+	policy   PasswordPolicy
+	hasher   Hasher
+	avatars  AvatarStorer
 }
 
-// NewBusiness constructs a user business API for use.
-func NewBusiness(log *logger.Logger, delegate *delegate.Delegate, storer Storer, plugins ...Plugin) Business {
+// NewBusiness constructs a user business API for use. The password policy
+// is enforced any time a password is set and the hasher is used to hash and
+// verify passwords. Avatars are kept in the avatar storer, which can be nil
+// when they aren't supported.
+func NewBusiness(log *logger.Logger, delegate *delegate.Delegate, storer Storer, policy PasswordPolicy, hasher Hasher, avatars AvatarStorer, plugins ...Plugin) Business {
 	b := Business(&business{
 		log:      log,
 		delegate: delegate,
 		storer:   storer,
+		policy:   policy,
+		hasher:   hasher,
+		avatars:  avatars,
 	})
 
 	for i := len(plugins) - 1; i >= 0; i-- {
@@ -93,40 +175,152 @@ func (b *business) NewWithTx(tx sqldb.CommitRollbacker) (Business, error) {
 		log:      b.log,
 		delegate: b.delegate,
 		storer:   storer,
+		policy:   b.policy,
+		hasher:   b.hasher,
+		avatars:  b.avatars,
 	}
 
 	return &bus, nil
 }
 
-// Create adds a new user to the system.
-func (b *business) Create(ctx context.Context, actorID uuid.UUID, nu NewUser) (User, error) {
+// Create adds a new user to the system. When the new user carries an
+// idempotency key, a retried call from the same actor with the same key
+// returns the user created by the first call instead of failing, and the
+// bool reports the user was replayed rather than created.
+func (b *business) Create(ctx context.Context, actorID uuid.UUID, nu NewUser) (User, bool, error) {
 	ctx, span := otel.AddSpan(ctx, "business.userbus.create")
 	defer span.End()
 
-	hash, err := bcrypt.GenerateFromPassword([]byte(nu.Password), bcrypt.DefaultCost)
+	if err := b.checkActor(ctx, actorID); err != nil {
+		return User{}, false, err
+	}
+
+	if err := nu.Validate(); err != nil {
+		return User{}, false, err
+	}
+
+	if nu.IdempotencyKey != "" {
+		usr, err := b.replay(ctx, actorID, nu)
+		switch {
+		case err == nil:
+			return usr, true, nil
+		case !errors.Is(err, ErrNotFound):
+			return User{}, false, err
+		}
+	}
+
+	if err := b.checkNewPassword(ctx, nu.Password); err != nil {
+		return User{}, false, err
+	}
+
+	if err := b.checkManager(ctx, uuid.Nil, nu.ManagerID); err != nil {
+		return User{}, false, err
+	}
+
+	hash, err := b.hasher.Hash(nu.Password)
 	if err != nil {
-		return User{}, fmt.Errorf("generatefrompassword: %w", err)
+		return User{}, false, fmt.Errorf("hash: %w", err)
 	}
 
-	now := time.Now()
+	now := clock.Now()
 
 	usr := User{
 		ID:           uuid.New(),
+		TenantID:     tenant.OrDefault(ctx),
 		Name:         nu.Name,
 		Email:        nu.Email,
 		PasswordHash: hash,
 		Roles:        nu.Roles,
 		Department:   nu.Department,
+		ManagerID:    nu.ManagerID,
 		Enabled:      true,
+		CreatedBy:    actorID,
+		UpdatedBy:    actorID,
 		DateCreated:  now,
 		DateUpdated:  now,
+		Version:      1,
+	}
+
+	if err := b.checkCreateRules(ctx, actorID, usr); err != nil {
+		return User{}, false, err
 	}
 
 	if err := b.storer.Create(ctx, usr); err != nil {
-		return User{}, fmt.Errorf("create: %w", err)
+		// A retry that ran alongside the original call loses the race on
+		// the email, by then the original may have recorded the key.
+		if nu.IdempotencyKey != "" && errors.Is(err, ErrUniqueEmail) {
+			if prev, perr := b.replay(ctx, actorID, nu); perr == nil {
+				return prev, true, nil
+			}
+		}
+		return User{}, false, fmt.Errorf("create: %w", err)
 	}
 
-	return usr, nil
+	if err := b.addPasswordHistory(ctx, usr); err != nil {
+		return User{}, false, err
+	}
+
+	if nu.IdempotencyKey != "" {
+		ik := IdempotencyKey{
+			ActorID:     actorID,
+			Key:         nu.IdempotencyKey,
+			UserID:      usr.ID,
+			DateCreated: now,
+		}
+
+		// The user exists either way, failing the call now would make the
+		// retry it's meant to protect fail on the email.
+		if err := b.storer.AddIdempotencyKey(ctx, ik); err != nil {
+			b.log.Error(ctx, "userbus: add idempotency key", "userID", usr.ID, "ERROR", err)
+		}
+	}
+
+	// Other domains may need to know when a user is created so business
+	// logic can be applied. This represents a delegate call to other domains.
+	if err := b.delegate.Call(ctx, ActionCreatedData(usr.ID, usr.TenantID)); err != nil {
+		return User{}, false, fmt.Errorf("failed to execute `%s` action: %w", ActionCreated, err)
+	}
+
+	return usr, false, nil
+}
+
+// CreateOrGet adds a new user to the system or, when a user with the email
+// already exists, returns that user so a repeated signup succeeds. The bool
+// reports whether the user was created. A failed insert aborts a Postgres
+// transaction, so this shouldn't be called inside one.
+func (b *business) CreateOrGet(ctx context.Context, actorID uuid.UUID, nu NewUser) (User, bool, error) {
+	ctx, span := otel.AddSpan(ctx, "business.userbus.createorget")
+	defer span.End()
+
+	if err := b.checkActor(ctx, actorID); err != nil {
+		return User{}, false, err
+	}
+
+	usr, err := b.storer.QueryByEmail(ctx, nu.Email)
+	switch {
+	case err == nil:
+		return usr, false, nil
+	case !errors.Is(err, ErrNotFound):
+		return User{}, false, fmt.Errorf("querybyemail: %w", err)
+	}
+
+	usr, replayed, err := b.Create(ctx, actorID, nu)
+	if err == nil {
+		return usr, !replayed, nil
+	}
+
+	// The email was taken between the lookup and the insert, so the user
+	// created by the other call is the one to return.
+	if !errors.Is(err, ErrUniqueEmail) {
+		return User{}, false, err
+	}
+
+	usr, err = b.storer.QueryByEmail(ctx, nu.Email)
+	if err != nil {
+		return User{}, false, fmt.Errorf("querybyemail: %w", err)
+	}
+
+	return usr, false, nil
 }
 
 // Update modifies information about a user.
@@ -134,6 +328,16 @@ func (b *business) Update(ctx context.Context, actorID uuid.UUID, usr User, uu U
 	ctx, span := otel.AddSpan(ctx, "business.userbus.update")
 	defer span.End()
 
+	if err := b.checkActor(ctx, actorID); err != nil {
+		return User{}, err
+	}
+
+	if err := uu.Validate(); err != nil {
+		return User{}, err
+	}
+
+	orgUsr := usr
+
 	if uu.Name != nil {
 		usr.Name = *uu.Name
 	}
@@ -147,9 +351,13 @@ func (b *business) Update(ctx context.Context, actorID uuid.UUID, usr User, uu U
 	}
 
 	if uu.Password != nil {
-		pw, err := bcrypt.GenerateFromPassword([]byte(*uu.Password), bcrypt.DefaultCost)
+		if err := b.checkPassword(ctx, usr, *uu.Password); err != nil {
+			return User{}, err
+		}
+
+		pw, err := b.hasher.Hash(*uu.Password)
 		if err != nil {
-			return User{}, fmt.Errorf("generatefrompassword: %w", err)
+			return User{}, fmt.Errorf("hash: %w", err)
 		}
 		usr.PasswordHash = pw
 	}
@@ -162,12 +370,45 @@ func (b *business) Update(ctx context.Context, actorID uuid.UUID, usr User, uu U
 		usr.Enabled = *uu.Enabled
 	}
 
-	usr.DateUpdated = time.Now()
+	if uu.ManagerID != nil && *uu.ManagerID != usr.ManagerID {
+		if err := b.checkManager(ctx, usr.ID, *uu.ManagerID); err != nil {
+			return User{}, err
+		}
+		usr.ManagerID = *uu.ManagerID
+	}
+
+	// The version the caller last read wins over the one passed in, so an
+	// update based on stale data is rejected by the store.
+	if uu.Version != nil {
+		usr.Version = *uu.Version
+	}
+
+	usr.UpdatedBy = actorID
+	usr.DateUpdated = clock.Now()
+	usr.Version++
+
+	if err := b.checkUpdateRules(ctx, actorID, orgUsr, usr); err != nil {
+		return User{}, err
+	}
 
 	if err := b.storer.Update(ctx, usr); err != nil {
 		return User{}, fmt.Errorf("update: %w", err)
 	}
 
+	if uu.Password != nil {
+		if err := b.addPasswordHistory(ctx, usr); err != nil {
+			return User{}, err
+		}
+	}
+
+	// Other domains may need to know what changed about a user so business
+	// logic can be applied. This represents a delegate call to other domains.
+	if chgs := changes(orgUsr, usr); len(chgs) > 0 {
+		if err := b.delegate.Call(ctx, ActionUpdatedData(usr.ID, usr.TenantID, chgs)); err != nil {
+			return User{}, fmt.Errorf("failed to execute `%s` action: %w", ActionUpdated, err)
+		}
+	}
+
 	return usr, nil
 }
 
@@ -176,13 +417,23 @@ func (b *business) Delete(ctx context.Context, actorID uuid.UUID, usr User) erro
 	ctx, span := otel.AddSpan(ctx, "business.userbus.delete")
 	defer span.End()
 
+	if err := b.checkActor(ctx, actorID); err != nil {
+		return err
+	}
+
+	if err := b.reassignReports(ctx, actorID, usr); err != nil {
+		return err
+	}
+
 	if err := b.storer.Delete(ctx, usr); err != nil {
 		return fmt.Errorf("delete: %w", err)
 	}
 
+	b.deleteAvatar(ctx, usr.AvatarKey)
+
 	// Other domains may need to know when a user is deleted so business
 	// logic can be applied. This represents a delegate call to other domains.
-	if err := b.delegate.Call(ctx, ActionDeletedData(usr.ID)); err != nil {
+	if err := b.delegate.Call(ctx, ActionDeletedData(usr.ID, usr.TenantID)); err != nil {
 		return fmt.Errorf("failed to execute `%s` action: %w", ActionDeleted, err)
 	}
 
@@ -194,7 +445,7 @@ func (b *business) Query(ctx context.Context, filter QueryFilter, orderBy order.
 	ctx, span := otel.AddSpan(ctx, "business.userbus.query")
 	defer span.End()
 
-	users, err := b.storer.Query(ctx, filter, orderBy, page)
+	users, err := b.storer.Query(ctx, filter, rankOrder(filter, orderBy), page)
 	if err != nil {
 		return nil, fmt.Errorf("query: %w", err)
 	}
@@ -202,6 +453,34 @@ func (b *business) Query(ctx context.Context, filter QueryFilter, orderBy order.
 	return users, nil
 }
 
+// QueryWithCount retrieves a page of users along with the total number of
+// users that match the filter, saving the round trip of a separate Count.
+func (b *business) QueryWithCount(ctx context.Context, filter QueryFilter, orderBy order.By, page page.Page) ([]User, int, error) {
+	ctx, span := otel.AddSpan(ctx, "business.userbus.querywithcount")
+	defer span.End()
+
+	users, total, err := b.storer.QueryWithCount(ctx, filter, rankOrder(filter, orderBy), page)
+	if err != nil {
+		return nil, 0, fmt.Errorf("querywithcount: %w", err)
+	}
+
+	return users, total, nil
+}
+
+// QueryAll streams every user that matches the filter to the function in
+// the specified order without holding the result set in memory. The stream
+// stops at the first error the function returns.
+func (b *business) QueryAll(ctx context.Context, filter QueryFilter, orderBy order.By, fn func(User) error) error {
+	ctx, span := otel.AddSpan(ctx, "business.userbus.queryall")
+	defer span.End()
+
+	if err := b.storer.QueryAll(ctx, filter, rankOrder(filter, orderBy), fn); err != nil {
+		return fmt.Errorf("queryall: %w", err)
+	}
+
+	return nil
+}
+
 // Count returns the total number of users.
 func (b *business) Count(ctx context.Context, filter QueryFilter) (int, error) {
 	ctx, span := otel.AddSpan(ctx, "business.userbus.count")
@@ -210,6 +489,16 @@ func (b *business) Count(ctx context.Context, filter QueryFilter) (int, error) {
 	return b.storer.Count(ctx, filter)
 }
 
+// CountEstimate returns the number of users that match the filter, which
+// the store may answer from a cache or an estimate when counting is
+// expensive. The flag reports whether the number is exact.
+func (b *business) CountEstimate(ctx context.Context, filter QueryFilter) (int, bool, error) {
+	ctx, span := otel.AddSpan(ctx, "business.userbus.countestimate")
+	defer span.End()
+
+	return b.storer.CountEstimate(ctx, filter)
+}
+
 // QueryByID finds the user by the specified ID.
 func (b *business) QueryByID(ctx context.Context, userID uuid.UUID) (User, error) {
 	ctx, span := otel.AddSpan(ctx, "business.userbus.querybyid")
@@ -223,6 +512,47 @@ func (b *business) QueryByID(ctx context.Context, userID uuid.UUID) (User, error
 	return user, nil
 }
 
+// QueryByIDs finds the users by the specified IDs in a single call. The
+// users are returned in the order of the IDs, each user once, and IDs that
+// don't match a user are skipped.
+func (b *business) QueryByIDs(ctx context.Context, userIDs []uuid.UUID) ([]User, error) {
+	ctx, span := otel.AddSpan(ctx, "business.userbus.querybyids")
+	defer span.End()
+
+	ids := make([]uuid.UUID, 0, len(userIDs))
+	seen := make(map[uuid.UUID]struct{}, len(userIDs))
+	for _, id := range userIDs {
+		if _, exists := seen[id]; exists {
+			continue
+		}
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+	}
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	usrs, err := b.storer.QueryByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	byID := make(map[uuid.UUID]User, len(usrs))
+	for _, usr := range usrs {
+		byID[usr.ID] = usr
+	}
+
+	ordered := make([]User, 0, len(usrs))
+	for _, id := range ids {
+		if usr, exists := byID[id]; exists {
+			ordered = append(ordered, usr)
+		}
+	}
+
+	return ordered, nil
+}
+
 // QueryByEmail finds the user by a specified user email.
 func (b *business) QueryByEmail(ctx context.Context, email mail.Address) (User, error) {
 	ctx, span := otel.AddSpan(ctx, "business.userbus.querybyemail")
@@ -238,19 +568,128 @@ func (b *business) QueryByEmail(ctx context.Context, email mail.Address) (User,
 
 // Authenticate finds a user by their email and verifies their password. On
 // success it returns a Claims User representing this user. The claims can be
-// used to generate a token for future authentication.
+// used to generate a token for future authentication. A user with one-time
+// codes enabled must use AuthenticateWithTOTP instead.
 func (b *business) Authenticate(ctx context.Context, email mail.Address, password string) (User, error) {
 	ctx, span := otel.AddSpan(ctx, "business.userbus.authenticate")
 	defer span.End()
 
+	usr, err := b.authenticate(ctx, email, password)
+	if err != nil {
+		return User{}, err
+	}
+
+	if usr.TOTPEnabled {
+		return User{}, fmt.Errorf("userID[%s]: %w", usr.ID, ErrTOTPRequired)
+	}
+
+	return b.recordLogin(ctx, usr), nil
+}
+
+// =============================================================================
+
+// authenticate verifies the user's password without looking at the second
+// factor.
+func (b *business) authenticate(ctx context.Context, email mail.Address, password string) (User, error) {
 	usr, err := b.QueryByEmail(ctx, email)
 	if err != nil {
 		return User{}, fmt.Errorf("query: email[%s]: %w", email, err)
 	}
 
-	if err := bcrypt.CompareHashAndPassword(usr.PasswordHash, []byte(password)); err != nil {
-		return User{}, fmt.Errorf("comparehashandpassword: %w", ErrAuthenticationFailure)
+	// Federated users may not have a password.
+	if len(usr.PasswordHash) == 0 {
+		return User{}, fmt.Errorf("userID[%s]: no password: %w", usr.ID, ErrAuthenticationFailure)
+	}
+
+	if err := b.hasher.Compare(usr.PasswordHash, password); err != nil {
+		return User{}, fmt.Errorf("compare: %w", ErrAuthenticationFailure)
+	}
+
+	if b.hasher.NeedsRehash(usr.PasswordHash) {
+		usr = b.rehash(ctx, usr, password)
 	}
 
 	return usr, nil
 }
+
+// checkNewPassword validates the password of a new user against the password
+// policy.
+func (b *business) checkNewPassword(ctx context.Context, password string) error {
+	rules, err := b.policy.checkContext(ctx, password)
+	if err != nil {
+		return fmt.Errorf("check password: %w", err)
+	}
+
+	if len(rules) > 0 {
+		return fmt.Errorf("check password: %w", &PasswordPolicyError{Rules: rules})
+	}
+
+	return nil
+}
+
+// checkPassword validates a new password for an existing user against the
+// password policy, including the reuse of recent passwords.
+func (b *business) checkPassword(ctx context.Context, usr User, password string) error {
+	rules, err := b.policy.checkContext(ctx, password)
+	if err != nil {
+		return fmt.Errorf("check password: %w", err)
+	}
+
+	if b.policy.History > 0 {
+		hashes, err := b.storer.QueryPasswordHistory(ctx, usr.ID, b.policy.History)
+		if err != nil {
+			return fmt.Errorf("querypasswordhistory: %w", err)
+		}
+
+		hashes = append(hashes, usr.PasswordHash)
+
+		for _, hash := range hashes {
+			if b.hasher.Compare(hash, password) == nil {
+				rules = append(rules, RuleReused)
+				break
+			}
+		}
+	}
+
+	if len(rules) > 0 {
+		return fmt.Errorf("check password: %w", &PasswordPolicyError{Rules: rules})
+	}
+
+	return nil
+}
+
+// addPasswordHistory records the user's current password hash so it can't
+// be reused while it remains in the history.
+func (b *business) addPasswordHistory(ctx context.Context, usr User) error {
+	if b.policy.History <= 0 {
+		return nil
+	}
+
+	if err := b.storer.AddPasswordHistory(ctx, usr.ID, usr.PasswordHash, usr.DateUpdated); err != nil {
+		return fmt.Errorf("addpasswordhistory: %w", err)
+	}
+
+	return nil
+}
+
+// rehash replaces the user's password hash with one generated by the
+// configured hasher. A failure is logged and doesn't fail the login since the
+// existing hash is still valid.
+func (b *business) rehash(ctx context.Context, usr User, password string) User {
+	hash, err := b.hasher.Hash(password)
+	if err != nil {
+		b.log.Error(ctx, "userbus: rehash", "userID", usr.ID, "ERROR", err)
+		return usr
+	}
+
+	updUsr := usr
+	updUsr.PasswordHash = hash
+	updUsr.Version++
+
+	if err := b.storer.Update(ctx, updUsr); err != nil {
+		b.log.Error(ctx, "userbus: rehash", "userID", usr.ID, "ERROR", err)
+		return usr
+	}
+
+	return updUsr
+}
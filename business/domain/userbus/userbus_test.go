@@ -1,19 +1,32 @@
 package userbus_test
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/mail"
+	"slices"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/ardanlabs/service/business/domain/sessionbus"
 	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/domain/userbus/stores/userdb"
+	"github.com/ardanlabs/service/business/sdk/buserr"
 	"github.com/ardanlabs/service/business/sdk/dbtest"
+	"github.com/ardanlabs/service/business/sdk/order"
 	"github.com/ardanlabs/service/business/sdk/page"
 	"github.com/ardanlabs/service/business/sdk/unitest"
+	"github.com/ardanlabs/service/business/types/department"
 	"github.com/ardanlabs/service/business/types/name"
 	"github.com/ardanlabs/service/business/types/role"
+	"github.com/ardanlabs/service/foundation/totp"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
@@ -33,7 +46,22 @@ func Test_User(t *testing.T) {
 
 	unitest.Run(t, query(db.BusDomain, sd), "query")
 	unitest.Run(t, create(db.BusDomain), "create")
+	unitest.Run(t, createBatch(db.BusDomain, sd), "createbatch")
 	unitest.Run(t, update(db.BusDomain, sd), "update")
+	unitest.Run(t, actor(db.BusDomain), "actor")
+	unitest.Run(t, rules(db.BusDomain), "rules")
+	unitest.Run(t, validate(db.BusDomain, sd), "validate")
+	unitest.Run(t, totpFlow(db.BusDomain), "totp")
+	unitest.Run(t, changePassword(db.BusDomain), "changepassword")
+	unitest.Run(t, rehash(db), "rehash")
+	unitest.Run(t, lastLogin(db.BusDomain), "lastlogin")
+	unitest.Run(t, orgChart(db.BusDomain), "orgchart")
+	unitest.Run(t, federate(db.BusDomain, sd), "federate")
+	unitest.Run(t, roleAssign(db.BusDomain, sd), "roleassign")
+	unitest.Run(t, preferences(db.BusDomain, sd), "preferences")
+	unitest.Run(t, avatar(db.BusDomain, sd), "avatar")
+	unitest.Run(t, exportData(db.BusDomain, sd), "exportdata")
+	unitest.Run(t, anonymize(db.BusDomain, sd), "anonymize")
 	unitest.Run(t, delete(db.BusDomain, sd), "delete")
 }
 
@@ -134,6 +162,263 @@ func query(busDomain dbtest.BusDomain, sd unitest.SeedData) []unitest.Table {
 				return cmp.Diff(gotResp, expResp)
 			},
 		},
+		{
+			Name:    "keyset",
+			ExpResp: usrs,
+			ExcFunc: func(ctx context.Context) any {
+				filter := userbus.QueryFilter{
+					Name: dbtest.NamePointer("Name"),
+				}
+
+				var resp []userbus.User
+
+				pg := page.MustParseCursor("", "3")
+				for {
+					usrs, err := busDomain.User.Query(ctx, filter, userbus.DefaultOrderBy, pg)
+					if err != nil {
+						return err
+					}
+
+					resp = append(resp, usrs...)
+
+					cursor := userbus.NextCursor(userbus.DefaultOrderBy, pg, usrs)
+					if cursor == "" {
+						break
+					}
+
+					pg = page.MustParseCursor(cursor, "3")
+				}
+
+				return resp
+			},
+			CmpFunc: func(got any, exp any) string {
+				gotResp, exists := got.([]userbus.User)
+				if !exists {
+					return "error occurred"
+				}
+
+				expResp := exp.([]userbus.User)
+
+				for i := range gotResp {
+					if i >= len(expResp) {
+						break
+					}
+
+					if gotResp[i].DateCreated.Format(time.RFC3339) == expResp[i].DateCreated.Format(time.RFC3339) {
+						expResp[i].DateCreated = gotResp[i].DateCreated
+					}
+
+					if gotResp[i].DateUpdated.Format(time.RFC3339) == expResp[i].DateUpdated.Format(time.RFC3339) {
+						expResp[i].DateUpdated = gotResp[i].DateUpdated
+					}
+				}
+
+				return cmp.Diff(gotResp, expResp)
+			},
+		},
+		{
+			Name:    "with-count",
+			ExpResp: []int{2, len(usrs), 0, len(usrs), 2, len(usrs)},
+			ExcFunc: func(ctx context.Context) any {
+				filter := userbus.QueryFilter{
+					Name: dbtest.NamePointer("Name"),
+				}
+
+				pages := []page.Page{
+					page.MustParse("1", "2"),
+					page.MustParse("100", "2"),
+					page.MustParseCursor(page.NextCursor(usrs[0].ID.String()), "2"),
+				}
+
+				var resp []int
+				for _, pg := range pages {
+					got, total, err := busDomain.User.QueryWithCount(ctx, filter, userbus.DefaultOrderBy, pg)
+					if err != nil {
+						return err
+					}
+					resp = append(resp, len(got), total)
+				}
+
+				return resp
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+		{
+			Name:    "count-estimate",
+			ExpResp: []any{len(usrs), true, len(usrs), false},
+			ExcFunc: func(ctx context.Context) any {
+				filter := userbus.QueryFilter{
+					Name: dbtest.NamePointer("Name"),
+				}
+
+				var resp []any
+				for range 2 {
+					n, exact, err := busDomain.User.CountEstimate(ctx, filter)
+					if err != nil {
+						return err
+					}
+					resp = append(resp, n, exact)
+				}
+
+				return resp
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+		{
+			Name:    "all-stream",
+			ExpResp: usrs,
+			ExcFunc: func(ctx context.Context) any {
+				filter := userbus.QueryFilter{
+					Name: dbtest.NamePointer("Name"),
+				}
+
+				var resp []userbus.User
+				f := func(usr userbus.User) error {
+					resp = append(resp, usr)
+					return nil
+				}
+
+				if err := busDomain.User.QueryAll(ctx, filter, userbus.DefaultOrderBy, f); err != nil {
+					return err
+				}
+
+				return resp
+			},
+			CmpFunc: func(got any, exp any) string {
+				gotResp, exists := got.([]userbus.User)
+				if !exists {
+					return "error occurred"
+				}
+
+				expResp := exp.([]userbus.User)
+
+				for i := range gotResp {
+					if i >= len(expResp) {
+						break
+					}
+
+					if gotResp[i].DateCreated.Format(time.RFC3339) == expResp[i].DateCreated.Format(time.RFC3339) {
+						expResp[i].DateCreated = gotResp[i].DateCreated
+					}
+
+					if gotResp[i].DateUpdated.Format(time.RFC3339) == expResp[i].DateUpdated.Format(time.RFC3339) {
+						expResp[i].DateUpdated = gotResp[i].DateUpdated
+					}
+				}
+
+				return cmp.Diff(gotResp, expResp)
+			},
+		},
+		{
+			Name:    "search",
+			ExpResp: []uuid.UUID{sd.Users[0].ID},
+			ExcFunc: func(ctx context.Context) any {
+				search := sd.Users[0].Email.Address
+
+				filter := userbus.QueryFilter{
+					Search: &search,
+				}
+
+				resp, err := busDomain.User.Query(ctx, filter, userbus.RelevanceOrderBy, page.MustParse("1", "10"))
+				if err != nil {
+					return err
+				}
+
+				return toIDs(resp)
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+		{
+			Name:    "roles",
+			ExpResp: sortedIDs(sd.Admins),
+			ExcFunc: func(ctx context.Context) any {
+				filter := userbus.QueryFilter{
+					Roles:   []role.Role{role.Admin},
+					Enabled: dbtest.BoolPointer(true),
+				}
+
+				resp, err := busDomain.User.Query(ctx, filter, userbus.DefaultOrderBy, page.MustParse("1", "10"))
+				if err != nil {
+					return err
+				}
+
+				return toIDs(resp)
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+		{
+			Name:    "department",
+			ExpResp: []uuid.UUID{sd.Users[1].ID},
+			ExcFunc: func(ctx context.Context) any {
+				filter := userbus.QueryFilter{
+					Department: dbtest.DepartmentPointer(sd.Users[1].Department.String()),
+				}
+
+				resp, err := busDomain.User.Query(ctx, filter, userbus.DefaultOrderBy, page.MustParse("1", "10"))
+				if err != nil {
+					return err
+				}
+
+				return toIDs(resp)
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+		{
+			Name: "multi-order",
+			ExpResp: func() []uuid.UUID {
+				ids := append(sortedIDs(sd.Users), sortedIDs(sd.Admins)...)
+				slices.Reverse(ids[:len(sd.Users)])
+				slices.Reverse(ids[len(sd.Users):])
+				return ids
+			}(),
+			ExcFunc: func(ctx context.Context) any {
+				filter := userbus.QueryFilter{
+					Name: dbtest.NamePointer("Name"),
+				}
+
+				orderBy := order.NewBy(userbus.OrderByRoles, order.DESC, order.NewBy(userbus.OrderByID, order.DESC))
+
+				resp, err := busDomain.User.Query(ctx, filter, orderBy, page.MustParse("1", "10"))
+				if err != nil {
+					return err
+				}
+
+				return toIDs(resp)
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+		{
+			Name:    "disabled",
+			ExpResp: []uuid.UUID{},
+			ExcFunc: func(ctx context.Context) any {
+				filter := userbus.QueryFilter{
+					Name:    dbtest.NamePointer("Name"),
+					Enabled: dbtest.BoolPointer(false),
+				}
+
+				resp, err := busDomain.User.Query(ctx, filter, userbus.DefaultOrderBy, page.MustParse("1", "10"))
+				if err != nil {
+					return err
+				}
+
+				return toIDs(resp)
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
 		{
 			Name:    "byid",
 			ExpResp: sd.Users[0].User,
@@ -161,6 +446,44 @@ func query(busDomain dbtest.BusDomain, sd unitest.SeedData) []unitest.Table {
 					expResp.DateUpdated = gotResp.DateUpdated
 				}
 
+				return cmp.Diff(gotResp, expResp)
+			},
+		},
+		{
+			Name:    "byids",
+			ExpResp: []userbus.User{sd.Users[1].User, sd.Admins[0].User},
+			ExcFunc: func(ctx context.Context) any {
+				ids := []uuid.UUID{sd.Users[1].ID, uuid.New(), sd.Admins[0].ID, sd.Users[1].ID}
+
+				resp, err := busDomain.User.QueryByIDs(ctx, ids)
+				if err != nil {
+					return err
+				}
+
+				return resp
+			},
+			CmpFunc: func(got any, exp any) string {
+				gotResp, exists := got.([]userbus.User)
+				if !exists {
+					return "error occurred"
+				}
+
+				expResp := exp.([]userbus.User)
+
+				for i := range gotResp {
+					if i >= len(expResp) {
+						break
+					}
+
+					if gotResp[i].DateCreated.Format(time.RFC3339) == expResp[i].DateCreated.Format(time.RFC3339) {
+						expResp[i].DateCreated = gotResp[i].DateCreated
+					}
+
+					if gotResp[i].DateUpdated.Format(time.RFC3339) == expResp[i].DateUpdated.Format(time.RFC3339) {
+						expResp[i].DateUpdated = gotResp[i].DateUpdated
+					}
+				}
+
 				return cmp.Diff(gotResp, expResp)
 			},
 		},
@@ -179,19 +502,22 @@ func create(busDomain dbtest.BusDomain) []unitest.Table {
 				Name:       name.MustParse("Bill Kennedy"),
 				Email:      *email,
 				Roles:      []role.Role{role.Admin},
-				Department: name.MustParseNull("ITO"),
+				Department: department.MustParseNull("ITO"),
 				Enabled:    true,
+				CreatedBy:  userbus.ActorSystem,
+				UpdatedBy:  userbus.ActorSystem,
+				Version:    1,
 			},
 			ExcFunc: func(ctx context.Context) any {
 				nu := userbus.NewUser{
 					Name:       name.MustParse("Bill Kennedy"),
 					Email:      *email,
 					Roles:      []role.Role{role.Admin},
-					Department: name.MustParseNull("ITO"),
+					Department: department.MustParseNull("ITO"),
 					Password:   "123",
 				}
 
-				resp, err := busDomain.User.Create(ctx, uuid.UUID{}, nu)
+				resp, _, err := busDomain.User.Create(ctx, userbus.ActorSystem, nu)
 				if err != nil {
 					return err
 				}
@@ -218,49 +544,165 @@ func create(busDomain dbtest.BusDomain) []unitest.Table {
 				return cmp.Diff(gotResp, expResp)
 			},
 		},
-	}
-
-	return table
-}
-
-func update(busDomain dbtest.BusDomain, sd unitest.SeedData) []unitest.Table {
-	email, _ := mail.ParseAddress("jack@ardanlabs.com")
-
-	table := []unitest.Table{
 		{
-			Name: "basic",
-			ExpResp: userbus.User{
-				ID:          sd.Users[0].ID,
-				Name:        name.MustParse("Jack Kennedy"),
-				Email:       *email,
-				Roles:       []role.Role{role.Admin},
-				Department:  name.MustParseNull("ITO"),
-				Enabled:     true,
-				DateCreated: sd.Users[0].DateCreated,
-			},
+			Name:    "create-or-get",
+			ExpResp: []any{true, false, true},
 			ExcFunc: func(ctx context.Context) any {
-				uu := userbus.UpdateUser{
-					Name:       dbtest.NamePointer("Jack Kennedy"),
-					Email:      email,
-					Roles:      []role.Role{role.Admin},
-					Department: dbtest.NameNullPointer("ITO"),
-					Password:   dbtest.StringPointer("1234"),
+				nu := userbus.NewUser{
+					Name:     name.MustParse("Signup User"),
+					Email:    mail.Address{Address: "signup@ardanlabs.com"},
+					Roles:    []role.Role{role.User},
+					Password: "123",
 				}
 
-				resp, err := busDomain.User.Update(ctx, uuid.UUID{}, sd.Users[0].User, uu)
+				first, created1, err := busDomain.User.CreateOrGet(ctx, userbus.ActorSystem, nu)
 				if err != nil {
 					return err
 				}
 
-				return resp
+				second, created2, err := busDomain.User.CreateOrGet(ctx, userbus.ActorSystem, nu)
+				if err != nil {
+					return err
+				}
+
+				return []any{created1, created2, first.ID == second.ID}
 			},
 			CmpFunc: func(got any, exp any) string {
-				gotResp, exists := got.(userbus.User)
-				if !exists {
-					return "error occurred"
+				return cmp.Diff(got, exp)
+			},
+		},
+		{
+			Name:    "idempotency-key",
+			ExpResp: []any{false, true, true, true},
+			ExcFunc: func(ctx context.Context) any {
+				nu := userbus.NewUser{
+					Name:           name.MustParse("Retried User"),
+					Email:          mail.Address{Address: "retried@ardanlabs.com"},
+					Roles:          []role.Role{role.User},
+					Password:       "123",
+					IdempotencyKey: "create-retried",
 				}
 
-				if err := bcrypt.CompareHashAndPassword(gotResp.PasswordHash, []byte("1234")); err != nil {
+				first, firstReplayed, err := busDomain.User.Create(ctx, userbus.ActorSystem, nu)
+				if err != nil {
+					return err
+				}
+
+				second, secondReplayed, err := busDomain.User.Create(ctx, userbus.ActorSystem, nu)
+				if err != nil {
+					return err
+				}
+
+				nu.Email = mail.Address{Address: "other@ardanlabs.com"}
+				_, _, err = busDomain.User.Create(ctx, userbus.ActorSystem, nu)
+
+				return []any{firstReplayed, secondReplayed, first.ID == second.ID, errors.Is(err, userbus.ErrIdempotencyMismatch)}
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+	}
+
+	return table
+}
+
+func createBatch(busDomain dbtest.BusDomain, sd unitest.SeedData) []unitest.Table {
+	type result struct {
+		Created int
+		Failed  []int
+	}
+
+	newUser := func(email mail.Address) userbus.NewUser {
+		return userbus.NewUser{
+			Name:     name.MustParse("Batch User"),
+			Email:    email,
+			Roles:    []role.Role{role.User},
+			Password: "123",
+		}
+	}
+
+	email, _ := mail.ParseAddress("batch@ardanlabs.com")
+
+	table := []unitest.Table{
+		{
+			Name: "besteffort",
+			ExpResp: result{
+				Created: 1,
+				Failed:  []int{1, 2},
+			},
+			ExcFunc: func(ctx context.Context) any {
+				nus := []userbus.NewUser{
+					newUser(*email),
+					newUser(sd.Admins[0].Email),
+					newUser(*email),
+				}
+
+				usrs, bes := busDomain.User.CreateBatch(ctx, userbus.ActorSystem, nus, userbus.BatchBestEffort)
+
+				resp := result{
+					Created: len(usrs),
+				}
+
+				for _, be := range bes {
+					if !errors.Is(be, userbus.ErrUniqueEmail) {
+						return be
+					}
+					resp.Failed = append(resp.Failed, be.Index)
+				}
+
+				return resp
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+	}
+
+	return table
+}
+
+func update(busDomain dbtest.BusDomain, sd unitest.SeedData) []unitest.Table {
+	email, _ := mail.ParseAddress("jack@ardanlabs.com")
+
+	table := []unitest.Table{
+		{
+			Name: "basic",
+			ExpResp: userbus.User{
+				ID:          sd.Users[0].ID,
+				Name:        name.MustParse("Jack Kennedy"),
+				Email:       *email,
+				Roles:       []role.Role{role.Admin},
+				Department:  department.MustParseNull("ITO"),
+				Enabled:     true,
+				CreatedBy:   userbus.ActorTooling,
+				UpdatedBy:   sd.Admins[0].ID,
+				DateCreated: sd.Users[0].DateCreated,
+				Version:     sd.Users[0].Version + 1,
+			},
+			ExcFunc: func(ctx context.Context) any {
+				uu := userbus.UpdateUser{
+					Name:       dbtest.NamePointer("Jack Kennedy"),
+					Email:      email,
+					Roles:      []role.Role{role.Admin},
+					Department: dbtest.DepartmentNullPointer("ITO"),
+					Password:   dbtest.StringPointer("1234"),
+				}
+
+				resp, err := busDomain.User.Update(ctx, sd.Admins[0].ID, sd.Users[0].User, uu)
+				if err != nil {
+					return err
+				}
+
+				return resp
+			},
+			CmpFunc: func(got any, exp any) string {
+				gotResp, exists := got.(userbus.User)
+				if !exists {
+					return "error occurred"
+				}
+
+				if err := bcrypt.CompareHashAndPassword(gotResp.PasswordHash, []byte("1234")); err != nil {
 					return err.Error()
 				}
 
@@ -272,18 +714,1141 @@ func update(busDomain dbtest.BusDomain, sd unitest.SeedData) []unitest.Table {
 				return cmp.Diff(gotResp, expResp)
 			},
 		},
+		{
+			Name:    "version-conflict",
+			ExpResp: userbus.ErrVersionConflict,
+			ExcFunc: func(ctx context.Context) any {
+				stale := sd.Users[1].Version - 1
+
+				uu := userbus.UpdateUser{
+					Name:    dbtest.NamePointer("Stale Edit"),
+					Version: &stale,
+				}
+
+				_, err := busDomain.User.Update(ctx, userbus.ActorSystem, sd.Users[1].User, uu)
+				return unwrap(err, userbus.ErrVersionConflict)
+			},
+			CmpFunc: func(got any, exp any) string {
+				if got != exp {
+					return fmt.Sprintf("expected %v, got %v", exp, got)
+				}
+				return ""
+			},
+		},
+	}
+
+	return table
+}
+
+func actor(busDomain dbtest.BusDomain) []unitest.Table {
+	create := func(ctx context.Context, actorID uuid.UUID) error {
+		nu := userbus.TestNewUsers(1, role.User)[0]
+
+		_, _, err := busDomain.User.Create(ctx, actorID, nu)
+		return err
+	}
+
+	table := []unitest.Table{
+		{
+			Name:    "zero",
+			ExpResp: userbus.ErrInvalidActor,
+			ExcFunc: func(ctx context.Context) any {
+				return create(ctx, uuid.UUID{})
+			},
+			CmpFunc: cmpActorError,
+		},
+		{
+			Name:    "unknown",
+			ExpResp: userbus.ErrInvalidActor,
+			ExcFunc: func(ctx context.Context) any {
+				return create(ctx, uuid.New())
+			},
+			CmpFunc: cmpActorError,
+		},
+		{
+			Name:    "disabled",
+			ExpResp: userbus.ErrInvalidActor,
+			ExcFunc: func(ctx context.Context) any {
+				usrs, err := userbus.TestSeedUsers(ctx, 1, role.Admin, busDomain.User)
+				if err != nil {
+					return err
+				}
+
+				enabled := false
+				if _, err := busDomain.User.Update(ctx, userbus.ActorSystem, usrs[0], userbus.UpdateUser{Enabled: &enabled}); err != nil {
+					return err
+				}
+
+				return create(ctx, usrs[0].ID)
+			},
+			CmpFunc: cmpActorError,
+		},
+		{
+			Name:    "registered",
+			ExpResp: nil,
+			ExcFunc: func(ctx context.Context) any {
+				jobID := uuid.New()
+				if err := userbus.RegisterSystemActor(jobID, "test-job"); err != nil {
+					return err
+				}
+
+				return create(ctx, jobID)
+			},
+			CmpFunc: cmpActorError,
+		},
+	}
+
+	return table
+}
+
+func cmpActorError(got any, exp any) string {
+	gotErr, _ := got.(error)
+	expErr, _ := exp.(error)
+
+	if expErr == nil {
+		if gotErr != nil {
+			return fmt.Sprintf("expected no error, got %v", gotErr)
+		}
+		return ""
+	}
+
+	if !errors.Is(gotErr, expErr) {
+		return fmt.Sprintf("expected %v, got %v", expErr, gotErr)
+	}
+
+	return ""
+}
+
+func validate(busDomain dbtest.BusDomain, sd unitest.SeedData) []unitest.Table {
+	fields := func(err error) any {
+		if !errors.Is(err, userbus.ErrInvalidUser) {
+			return fmt.Errorf("expected an invalid user error, got %v", err)
+		}
+
+		be, ok := buserr.As(err)
+		if !ok {
+			return fmt.Errorf("expected a business error, got %v", err)
+		}
+
+		return be.Fields
+	}
+
+	table := []unitest.Table{
+		{
+			Name: "create",
+			ExpResp: []buserr.FieldViolation{
+				{Field: "email", Message: `invalid email "not-an-email"`},
+				{Field: "roles", Message: "must have at least one role"},
+			},
+			ExcFunc: func(ctx context.Context) any {
+				nu := userbus.TestNewUsers(1, role.User)[0]
+				nu.Email = mail.Address{Address: "not-an-email"}
+				nu.Roles = nil
+
+				_, _, err := busDomain.User.Create(ctx, userbus.ActorSystem, nu)
+				return fields(err)
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+		{
+			Name: "update",
+			ExpResp: []buserr.FieldViolation{
+				{Field: "name", Message: "is required"},
+				{Field: "roles", Message: `duplicate role "USER"`},
+			},
+			ExcFunc: func(ctx context.Context) any {
+				uu := userbus.UpdateUser{
+					Name:  &name.Name{},
+					Roles: []role.Role{role.User, role.User},
+				}
+
+				_, err := busDomain.User.Update(ctx, userbus.ActorSystem, sd.Users[0].User, uu)
+				return fields(err)
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+	}
+
+	return table
+}
+
+func rules(busDomain dbtest.BusDomain) []unitest.Table {
+	// The rules are registered for every business in the process, so they
+	// only apply to users in a domain no other test uses.
+	const domain = "@rules.ardanlabs.com"
+
+	userbus.RegisterCreateRule("corporate-email", func(ctx context.Context, r userbus.RuleReader, actorID uuid.UUID, usr userbus.User) error {
+		if strings.HasSuffix(usr.Email.Address, domain) && strings.HasPrefix(usr.Email.Address, "personal") {
+			return &userbus.RuleViolation{Field: "email", Message: "must be a corporate email"}
+		}
+		return nil
+	})
+
+	userbus.RegisterUpdateRule("max-admins", func(ctx context.Context, r userbus.RuleReader, actorID uuid.UUID, before userbus.User, after userbus.User) error {
+		if !strings.HasSuffix(after.Email.Address, domain) || !slices.Contains(after.Roles, role.Admin) || slices.Contains(before.Roles, role.Admin) {
+			return nil
+		}
+
+		// The domain allows one admin, who is read in the same transaction
+		// as the change.
+		adm, err := r.QueryByEmail(ctx, mail.Address{Address: "max" + domain})
+		switch {
+		case errors.Is(err, userbus.ErrNotFound):
+			return nil
+		case err != nil:
+			return err
+		}
+
+		if slices.Contains(adm.Roles, role.Admin) {
+			return &userbus.RuleViolation{Field: "roles", Message: "too many admins"}
+		}
+		return nil
+	})
+
+	newUser := func(local string) userbus.NewUser {
+		nu := userbus.TestNewUsers(1, role.User)[0]
+		nu.Email = mail.Address{Address: local + domain}
+		return nu
+	}
+
+	table := []unitest.Table{
+		{
+			Name:    "create-violation",
+			ExpResp: []userbus.RuleViolation{{Rule: "corporate-email", Field: "email", Message: "must be a corporate email"}},
+			ExcFunc: func(ctx context.Context) any {
+				_, _, err := busDomain.User.Create(ctx, userbus.ActorSystem, newUser("personal"))
+
+				var re *userbus.RuleError
+				if !errors.As(err, &re) || !errors.Is(err, userbus.ErrRuleViolation) {
+					return fmt.Errorf("expected a rule error, got %v", err)
+				}
+
+				return re.Violations
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+		{
+			Name:    "update-violation",
+			ExpResp: []userbus.RuleViolation{{Rule: "max-admins", Field: "roles", Message: "too many admins"}},
+			ExcFunc: func(ctx context.Context) any {
+				nu := newUser("max")
+				nu.Roles = []role.Role{role.Admin}
+				if _, _, err := busDomain.User.Create(ctx, userbus.ActorSystem, nu); err != nil {
+					return err
+				}
+
+				usr, _, err := busDomain.User.Create(ctx, userbus.ActorSystem, newUser("work"))
+				if err != nil {
+					return err
+				}
+
+				_, err = busDomain.User.Update(ctx, userbus.ActorSystem, usr, userbus.UpdateUser{Roles: []role.Role{role.Admin}})
+
+				var re *userbus.RuleError
+				if !errors.As(err, &re) {
+					return fmt.Errorf("expected a rule error, got %v", err)
+				}
+
+				return re.Violations
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+	}
+
+	return table
+}
+
+func totpFlow(busDomain dbtest.BusDomain) []unitest.Table {
+	email, _ := mail.ParseAddress("totp@ardanlabs.com")
+
+	type result struct {
+		PasswordOnly error
+		MissingCode  error
+		WithCode     bool
+		WithRecovery bool
+		ReusedCode   error
+		AfterDisable bool
+	}
+
+	table := []unitest.Table{
+		{
+			Name: "flow",
+			ExpResp: result{
+				PasswordOnly: userbus.ErrTOTPRequired,
+				MissingCode:  userbus.ErrTOTPRequired,
+				WithCode:     true,
+				WithRecovery: true,
+				ReusedCode:   userbus.ErrAuthenticationFailure,
+				AfterDisable: true,
+			},
+			ExcFunc: func(ctx context.Context) any {
+				nu := userbus.NewUser{
+					Name:     name.MustParse("Otto Pass"),
+					Email:    *email,
+					Roles:    []role.Role{role.User},
+					Password: "123",
+				}
+
+				usr, _, err := busDomain.User.Create(ctx, userbus.ActorSystem, nu)
+				if err != nil {
+					return err
+				}
+
+				secret, _, err := busDomain.User.EnrollTOTP(ctx, usr.ID)
+				if err != nil {
+					return err
+				}
+
+				code, err := totp.Code(secret, time.Now())
+				if err != nil {
+					return err
+				}
+
+				recovery, err := busDomain.User.ConfirmTOTP(ctx, usr.ID, code)
+				if err != nil {
+					return err
+				}
+
+				var resp result
+
+				_, err = busDomain.User.Authenticate(ctx, *email, "123")
+				resp.PasswordOnly = unwrap(err, userbus.ErrTOTPRequired)
+
+				_, err = busDomain.User.AuthenticateWithTOTP(ctx, *email, "123", "")
+				resp.MissingCode = unwrap(err, userbus.ErrTOTPRequired)
+
+				_, err = busDomain.User.AuthenticateWithTOTP(ctx, *email, "123", code)
+				resp.WithCode = err == nil
+
+				_, err = busDomain.User.AuthenticateWithTOTP(ctx, *email, "123", recovery[0])
+				resp.WithRecovery = err == nil
+
+				_, err = busDomain.User.AuthenticateWithTOTP(ctx, *email, "123", recovery[0])
+				resp.ReusedCode = unwrap(err, userbus.ErrAuthenticationFailure)
+
+				if err := busDomain.User.DisableTOTP(ctx, usr.ID); err != nil {
+					return err
+				}
+
+				_, err = busDomain.User.Authenticate(ctx, *email, "123")
+				resp.AfterDisable = err == nil
+
+				return resp
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp, cmp.Comparer(func(a, b error) bool { return a == b }))
+			},
+		},
 	}
 
 	return table
 }
 
+func rehash(db *dbtest.Database) []unitest.Table {
+	// The business switched over to argon2id after the users were created
+	// with bcrypt.
+	argonBus := userbus.NewBusiness(db.Log, nil, userdb.NewStore(db.Log, db.DB), userbus.PasswordPolicy{}, userbus.NewArgon2idHasher(argon2idParams), nil)
+
+	type result struct {
+		Returned      bool
+		Stored        bool
+		Authenticated bool
+	}
+
+	isArgon2id := func(hash []byte) bool {
+		return bytes.HasPrefix(hash, []byte("$argon2id$"))
+	}
+
+	table := []unitest.Table{
+		{
+			Name: "authenticate",
+			ExpResp: result{
+				Returned:      true,
+				Stored:        true,
+				Authenticated: true,
+			},
+			ExcFunc: func(ctx context.Context) any {
+				nu := userbus.TestNewUsers(1, role.User)[0]
+
+				usr, _, err := db.BusDomain.User.Create(ctx, userbus.ActorSystem, nu)
+				if err != nil {
+					return err
+				}
+
+				if isArgon2id(usr.PasswordHash) {
+					return errors.New("expected the user to be created with bcrypt")
+				}
+
+				authUsr, err := argonBus.Authenticate(ctx, nu.Email, nu.Password)
+				if err != nil {
+					return err
+				}
+
+				stored, err := db.BusDomain.User.QueryByID(ctx, usr.ID)
+				if err != nil {
+					return err
+				}
+
+				_, err = argonBus.Authenticate(ctx, nu.Email, nu.Password)
+
+				return result{
+					Returned:      isArgon2id(authUsr.PasswordHash),
+					Stored:        isArgon2id(stored.PasswordHash),
+					Authenticated: err == nil,
+				}
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+	}
+
+	return table
+}
+
+func changePassword(busDomain dbtest.BusDomain) []unitest.Table {
+	type result struct {
+		WrongPassword error
+		Authenticated bool
+		OldPassword   error
+		Revoked       bool
+	}
+
+	table := []unitest.Table{
+		{
+			Name: "flow",
+			ExpResp: result{
+				WrongPassword: userbus.ErrAuthenticationFailure,
+				Authenticated: true,
+				OldPassword:   userbus.ErrAuthenticationFailure,
+				Revoked:       true,
+			},
+			ExcFunc: func(ctx context.Context) any {
+				nu := userbus.TestNewUsers(1, role.User)[0]
+				oldPassword := nu.Password
+
+				usr, _, err := busDomain.User.Create(ctx, userbus.ActorSystem, nu)
+				if err != nil {
+					return err
+				}
+
+				sess, _, err := busDomain.Session.Create(ctx, sessionbus.NewSession{UserID: usr.ID, Device: "test"})
+				if err != nil {
+					return err
+				}
+
+				var resp result
+
+				err = busDomain.User.ChangePassword(ctx, usr.ID, "wrong", "NewPassword123")
+				resp.WrongPassword = unwrap(err, userbus.ErrAuthenticationFailure)
+
+				if err := busDomain.User.ChangePassword(ctx, usr.ID, oldPassword, "NewPassword123"); err != nil {
+					return err
+				}
+
+				_, err = busDomain.User.Authenticate(ctx, usr.Email, "NewPassword123")
+				resp.Authenticated = err == nil
+
+				_, err = busDomain.User.Authenticate(ctx, usr.Email, oldPassword)
+				resp.OldPassword = unwrap(err, userbus.ErrAuthenticationFailure)
+
+				sess, err = busDomain.Session.QueryByID(ctx, sess.ID)
+				if err != nil {
+					return err
+				}
+				resp.Revoked = sess.Revoked()
+
+				return resp
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp, cmp.Comparer(func(a, b error) bool { return a == b }))
+			},
+		},
+	}
+
+	return table
+}
+
+func lastLogin(busDomain dbtest.BusDomain) []unitest.Table {
+	type result struct {
+		Recorded bool
+		Dormant  int
+		Active   int
+	}
+
+	table := []unitest.Table{
+		{
+			Name: "flow",
+			ExpResp: result{
+				Recorded: true,
+				Dormant:  1,
+				Active:   0,
+			},
+			ExcFunc: func(ctx context.Context) any {
+				nu := userbus.TestNewUsers(1, role.User)[0]
+
+				usr, _, err := busDomain.User.Create(ctx, userbus.ActorSystem, nu)
+				if err != nil {
+					return err
+				}
+
+				if _, err := busDomain.User.Authenticate(ctx, usr.Email, nu.Password); err != nil {
+					return err
+				}
+
+				usr, err = busDomain.User.QueryByID(ctx, usr.ID)
+				if err != nil {
+					return err
+				}
+
+				var resp result
+				resp.Recorded = !usr.DateLastLogin.IsZero()
+
+				after := usr.DateLastLogin.Add(time.Minute)
+				resp.Dormant, err = busDomain.User.Count(ctx, userbus.QueryFilter{ID: &usr.ID, NotLoggedInSince: &after})
+				if err != nil {
+					return err
+				}
+
+				before := usr.DateLastLogin.Add(-time.Minute)
+				resp.Active, err = busDomain.User.Count(ctx, userbus.QueryFilter{ID: &usr.ID, NotLoggedInSince: &before})
+				if err != nil {
+					return err
+				}
+
+				return resp
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+	}
+
+	return table
+}
+
+func orgChart(busDomain dbtest.BusDomain) []unitest.Table {
+	type result struct {
+		Chain        []uuid.UUID
+		Reports      []uuid.UUID
+		Cycle        error
+		AfterDelete  uuid.UUID
+		ReportsAfter []uuid.UUID
+	}
+
+	ids := func(usrs []userbus.User) []uuid.UUID {
+		ids := make([]uuid.UUID, len(usrs))
+		for i, usr := range usrs {
+			ids[i] = usr.ID
+		}
+		return ids
+	}
+
+	manager := func(usr userbus.User) *uuid.NullUUID {
+		return &uuid.NullUUID{UUID: usr.ID, Valid: true}
+	}
+
+	var top, mid, bottom userbus.User
+
+	table := []unitest.Table{
+		{
+			Name: "chain",
+			ExpResp: result{
+				Cycle: userbus.ErrManagerCycle,
+			},
+			ExcFunc: func(ctx context.Context) any {
+				usrs, err := userbus.TestSeedUsers(ctx, 3, role.User, busDomain.User)
+				if err != nil {
+					return err
+				}
+				top, mid, bottom = usrs[0], usrs[1], usrs[2]
+
+				if mid, err = busDomain.User.Update(ctx, userbus.ActorSystem, mid, userbus.UpdateUser{ManagerID: manager(top)}); err != nil {
+					return err
+				}
+
+				if bottom, err = busDomain.User.Update(ctx, userbus.ActorSystem, bottom, userbus.UpdateUser{ManagerID: manager(mid)}); err != nil {
+					return err
+				}
+
+				var resp result
+
+				chain, err := busDomain.User.ManagementChain(ctx, bottom.ID)
+				if err != nil {
+					return err
+				}
+				resp.Chain = ids(chain)
+
+				reports, err := busDomain.User.DirectReports(ctx, top.ID)
+				if err != nil {
+					return err
+				}
+				resp.Reports = ids(reports)
+
+				_, err = busDomain.User.Update(ctx, userbus.ActorSystem, top, userbus.UpdateUser{ManagerID: manager(bottom)})
+				resp.Cycle = unwrap(err, userbus.ErrManagerCycle)
+
+				if err := busDomain.User.Delete(ctx, userbus.ActorSystem, mid); err != nil {
+					return err
+				}
+
+				usr, err := busDomain.User.QueryByID(ctx, bottom.ID)
+				if err != nil {
+					return err
+				}
+				resp.AfterDelete = usr.ManagerID.UUID
+
+				reports, err = busDomain.User.DirectReports(ctx, top.ID)
+				if err != nil {
+					return err
+				}
+				resp.ReportsAfter = ids(reports)
+
+				return resp
+			},
+			CmpFunc: func(got any, exp any) string {
+				gotResp, exists := got.(result)
+				if !exists {
+					return fmt.Sprintf("error occurred: %v", got)
+				}
+
+				expResp := exp.(result)
+
+				// The users are created by the test so the ids are only
+				// known once it has run.
+				expResp.Chain = []uuid.UUID{mid.ID, top.ID}
+				expResp.Reports = []uuid.UUID{mid.ID}
+				expResp.AfterDelete = top.ID
+				expResp.ReportsAfter = []uuid.UUID{bottom.ID}
+
+				return cmp.Diff(gotResp, expResp, cmp.Comparer(func(a, b error) bool { return a == b }))
+			},
+		},
+	}
+
+	return table
+}
+
+func federate(busDomain dbtest.BusDomain, sd unitest.SeedData) []unitest.Table {
+	email, _ := mail.ParseAddress("fed.user@example.com")
+
+	type result struct {
+		Provisioned  bool
+		SameUser     bool
+		NoPassword   error
+		Unverified   error
+		LinkedUserID uuid.UUID
+	}
+
+	table := []unitest.Table{
+		{
+			Name: "flow",
+			ExpResp: result{
+				Provisioned:  true,
+				SameUser:     true,
+				NoPassword:   userbus.ErrAuthenticationFailure,
+				Unverified:   userbus.ErrEmailNotVerified,
+				LinkedUserID: sd.Admins[0].ID,
+			},
+			ExcFunc: func(ctx context.Context) any {
+				var resp result
+
+				usr, provisioned, err := busDomain.User.FederateLogin(ctx, "google", "g-1", *email, userbus.Profile{})
+				if err != nil {
+					return err
+				}
+				resp.Provisioned = provisioned && usr.Email.Address == email.Address && len(usr.PasswordHash) == 0
+
+				again, provisioned, err := busDomain.User.FederateLogin(ctx, "google", "g-1", *email, userbus.Profile{})
+				if err != nil {
+					return err
+				}
+				resp.SameUser = again.ID == usr.ID && !provisioned
+
+				_, err = busDomain.User.Authenticate(ctx, *email, "")
+				resp.NoPassword = unwrap(err, userbus.ErrAuthenticationFailure)
+
+				existing := sd.Admins[0].Email
+
+				_, _, err = busDomain.User.FederateLogin(ctx, "github", "gh-1", existing, userbus.Profile{})
+				resp.Unverified = unwrap(err, userbus.ErrEmailNotVerified)
+
+				linked, _, err := busDomain.User.FederateLogin(ctx, "github", "gh-1", existing, userbus.Profile{EmailVerified: true})
+				if err != nil {
+					return err
+				}
+				resp.LinkedUserID = linked.ID
+
+				return resp
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp, cmp.Comparer(func(a, b error) bool { return a == b }))
+			},
+		},
+	}
+
+	return table
+}
+
+func roleAssign(busDomain dbtest.BusDomain, sd unitest.SeedData) []unitest.Table {
+	type result struct {
+		Affected      int
+		Sample        int
+		Unchanged     bool
+		Applied       [][]string
+		ApplyAgain    error
+		Reverted      [][]string
+		RevertAgain   error
+		EmptyPreview  error
+		UnknownRevert error
+	}
+
+	table := []unitest.Table{
+		{
+			Name: "flow",
+			ExpResp: result{
+				Affected:      2,
+				Sample:        2,
+				Unchanged:     true,
+				Applied:       [][]string{{"USER", "ADMIN"}, {"USER", "ADMIN"}},
+				ApplyAgain:    userbus.ErrAssignmentApplied,
+				Reverted:      [][]string{{"USER"}, {"USER"}},
+				RevertAgain:   userbus.ErrAssignmentReverted,
+				EmptyPreview:  userbus.ErrAssignmentEmpty,
+				UnknownRevert: userbus.ErrNotFound,
+			},
+			ExcFunc: func(ctx context.Context) any {
+				usrs, err := userbus.TestSeedUsers(ctx, 2, role.User, busDomain.User)
+				if err != nil {
+					return err
+				}
+
+				dept := department.MustParseNull("Role Assignment")
+				for i, usr := range usrs {
+					if usrs[i], err = busDomain.User.Update(ctx, userbus.ActorSystem, usr, userbus.UpdateUser{Department: &dept}); err != nil {
+						return err
+					}
+				}
+
+				roles := func() ([][]string, error) {
+					var all [][]string
+					for _, usr := range usrs {
+						got, err := busDomain.User.QueryByID(ctx, usr.ID)
+						if err != nil {
+							return nil, err
+						}
+						all = append(all, role.ParseToString(got.Roles))
+					}
+					return all, nil
+				}
+
+				actorID := sd.Admins[0].ID
+				deptName := department.MustParse(dept.String())
+				filter := userbus.QueryFilter{Department: &deptName}
+
+				var resp result
+
+				ra, err := busDomain.User.AssignRolesByFilter(ctx, actorID, filter, []role.Role{role.Admin}, nil)
+				if err != nil {
+					return err
+				}
+				resp.Affected = ra.Affected
+				resp.Sample = len(ra.Sample)
+
+				before, err := roles()
+				if err != nil {
+					return err
+				}
+				resp.Unchanged = cmp.Equal(before, [][]string{{"USER"}, {"USER"}})
+
+				_, token, err := busDomain.User.ApplyRoleAssignment(ctx, actorID, ra.ID)
+				if err != nil {
+					return err
+				}
+
+				if resp.Applied, err = roles(); err != nil {
+					return err
+				}
+
+				_, _, err = busDomain.User.ApplyRoleAssignment(ctx, actorID, ra.ID)
+				resp.ApplyAgain = unwrap(err, userbus.ErrAssignmentApplied)
+
+				if _, err := busDomain.User.RevertRoleAssignment(ctx, actorID, token); err != nil {
+					return err
+				}
+
+				if resp.Reverted, err = roles(); err != nil {
+					return err
+				}
+
+				_, err = busDomain.User.RevertRoleAssignment(ctx, actorID, token)
+				resp.RevertAgain = unwrap(err, userbus.ErrAssignmentReverted)
+
+				_, err = busDomain.User.AssignRolesByFilter(ctx, actorID, filter, nil, nil)
+				resp.EmptyPreview = unwrap(err, userbus.ErrAssignmentEmpty)
+
+				_, err = busDomain.User.RevertRoleAssignment(ctx, actorID, "ra_unknown")
+				resp.UnknownRevert = unwrap(err, userbus.ErrNotFound)
+
+				return resp
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp, cmp.Comparer(func(a, b error) bool { return a == b }))
+			},
+		},
+	}
+
+	return table
+}
+
+func preferences(busDomain dbtest.BusDomain, sd unitest.SeedData) []unitest.Table {
+	type result struct {
+		Set      map[string]string
+		Reset    string
+		Invalid  error
+		Unknown  error
+		NotFound error
+	}
+
+	table := []unitest.Table{
+		{
+			Name: "flow",
+			ExpResp: result{
+				Set: map[string]string{
+					"emailNotifications": "true",
+					"locale":             `"en-US"`,
+					"pageSize":           "10",
+					"theme":              `"dark"`,
+					"timezone":           `"UTC"`,
+				},
+				Reset:    `"system"`,
+				Invalid:  userbus.ErrInvalidPreference,
+				Unknown:  userbus.ErrUnknownPreference,
+				NotFound: userbus.ErrNotFound,
+			},
+			ExcFunc: func(ctx context.Context) any {
+				userID := sd.Users[0].ID
+
+				if _, err := busDomain.User.SetPreference(ctx, userID, "theme", json.RawMessage(`"dark"`)); err != nil {
+					return err
+				}
+
+				if _, err := busDomain.User.SetPreference(ctx, userID, "locale", json.RawMessage(`"en-us"`)); err != nil {
+					return err
+				}
+
+				prefs, err := busDomain.User.GetPreferences(ctx, userID)
+				if err != nil {
+					return err
+				}
+
+				resp := result{
+					Set: make(map[string]string),
+				}
+				for _, pref := range prefs {
+					resp.Set[pref.Key] = string(pref.Value)
+				}
+
+				if err := busDomain.User.DeletePreference(ctx, userID, "theme"); err != nil {
+					return err
+				}
+
+				if prefs, err = busDomain.User.GetPreferences(ctx, userID); err != nil {
+					return err
+				}
+
+				for _, pref := range prefs {
+					if pref.Key == "theme" {
+						resp.Reset = string(pref.Value)
+					}
+				}
+
+				_, err = busDomain.User.SetPreference(ctx, userID, "pageSize", json.RawMessage(`500`))
+				resp.Invalid = unwrap(err, userbus.ErrInvalidPreference)
+
+				_, err = busDomain.User.SetPreference(ctx, userID, "colour", json.RawMessage(`"red"`))
+				resp.Unknown = unwrap(err, userbus.ErrUnknownPreference)
+
+				_, err = busDomain.User.SetPreference(ctx, uuid.New(), "theme", json.RawMessage(`"dark"`))
+				resp.NotFound = unwrap(err, userbus.ErrNotFound)
+
+				return resp
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp, cmp.Comparer(func(a, b error) bool { return a == b }))
+			},
+		},
+	}
+
+	return table
+}
+
+func avatar(busDomain dbtest.BusDomain, sd unitest.SeedData) []unitest.Table {
+	type result struct {
+		Replaced  bool
+		URL       bool
+		WrongType error
+		Spoofed   error
+		TooLarge  error
+		NoAvatar  error
+	}
+
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+	table := []unitest.Table{
+		{
+			Name: "flow",
+			ExpResp: result{
+				Replaced:  true,
+				URL:       true,
+				WrongType: userbus.ErrAvatarType,
+				Spoofed:   userbus.ErrAvatarType,
+				TooLarge:  userbus.ErrAvatarTooLarge,
+				NoAvatar:  userbus.ErrNoAvatar,
+			},
+			ExcFunc: func(ctx context.Context) any {
+				usrs, err := userbus.TestSeedUsers(ctx, 1, role.User, busDomain.User)
+				if err != nil {
+					return err
+				}
+
+				usr := usrs[0]
+				actorID := sd.Admins[0].ID
+
+				first, err := busDomain.User.UpdateAvatar(ctx, actorID, usr.ID, bytes.NewReader(png), "image/png")
+				if err != nil {
+					return err
+				}
+
+				second, err := busDomain.User.UpdateAvatar(ctx, actorID, usr.ID, bytes.NewReader(png), "image/png; charset=binary")
+				if err != nil {
+					return err
+				}
+
+				var resp result
+				resp.Replaced = first.AvatarKey != "" && second.AvatarKey != first.AvatarKey && second.Version == first.Version+1
+
+				url, err := busDomain.User.AvatarURL(ctx, second)
+				if err != nil {
+					return err
+				}
+				resp.URL = strings.HasSuffix(url, second.AvatarKey)
+
+				_, err = busDomain.User.UpdateAvatar(ctx, actorID, usr.ID, bytes.NewReader(png), "image/svg+xml")
+				resp.WrongType = unwrap(err, userbus.ErrAvatarType)
+
+				_, err = busDomain.User.UpdateAvatar(ctx, actorID, usr.ID, strings.NewReader("<script>alert(1)</script>"), "image/png")
+				resp.Spoofed = unwrap(err, userbus.ErrAvatarType)
+
+				large := append(slices.Clone(png), make([]byte, userbus.AvatarMaxSize)...)
+				_, err = busDomain.User.UpdateAvatar(ctx, actorID, usr.ID, bytes.NewReader(large), "image/png")
+				resp.TooLarge = unwrap(err, userbus.ErrAvatarTooLarge)
+
+				_, err = busDomain.User.AvatarURL(ctx, sd.Users[0].User)
+				resp.NoAvatar = unwrap(err, userbus.ErrNoAvatar)
+
+				return resp
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp, cmp.Comparer(func(a, b error) bool { return a == b }))
+			},
+		},
+	}
+
+	return table
+}
+
+func exportData(busDomain dbtest.BusDomain, sd unitest.SeedData) []unitest.Table {
+	type result struct {
+		Files      []string
+		Email      bool
+		Restricted bool
+		NoExport   error
+		NotFound   error
+	}
+
+	table := []unitest.Table{
+		{
+			Name: "flow",
+			ExpResp: result{
+				Files:      []string{"group.json", "home.json", "notification.json", "product.json", "user.json"},
+				Email:      true,
+				Restricted: false,
+				NoExport:   userbus.ErrNoExport,
+				NotFound:   userbus.ErrNotFound,
+			},
+			ExcFunc: func(ctx context.Context) any {
+				usr := sd.Users[0]
+
+				rdr, err := busDomain.User.ExportData(ctx, usr.ID, usr.ID)
+				if err != nil {
+					return err
+				}
+
+				data, err := io.ReadAll(rdr)
+				if err != nil {
+					return err
+				}
+
+				zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+				if err != nil {
+					return err
+				}
+
+				var resp result
+				for _, f := range zr.File {
+					resp.Files = append(resp.Files, f.Name)
+
+					if f.Name != "user.json" {
+						continue
+					}
+
+					rc, err := f.Open()
+					if err != nil {
+						return err
+					}
+					content, err := io.ReadAll(rc)
+					rc.Close()
+					if err != nil {
+						return err
+					}
+
+					resp.Email = bytes.Contains(content, []byte(usr.Email.Address))
+					resp.Restricted = bytes.Contains(content, []byte("PasswordHash")) || bytes.Contains(content, []byte("TOTPSecret"))
+				}
+
+				err = userbus.AddExportData(ctx, "product", nil)
+				resp.NoExport = unwrap(err, userbus.ErrNoExport)
+
+				_, err = busDomain.User.ExportData(ctx, usr.ID, uuid.New())
+				resp.NotFound = unwrap(err, userbus.ErrNotFound)
+
+				return resp
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp, cmp.Comparer(func(a, b error) bool { return a == b }))
+			},
+		},
+	}
+
+	return table
+}
+
+func anonymize(busDomain dbtest.BusDomain, sd unitest.SeedData) []unitest.Table {
+	type result struct {
+		Name       string
+		Email      bool
+		Enabled    bool
+		Anonymized bool
+		Login      error
+		OldEmail   error
+	}
+
+	table := []unitest.Table{
+		{
+			Name: "flow",
+			ExpResp: result{
+				Name:       userbus.AnonymizedName,
+				Email:      true,
+				Enabled:    false,
+				Anonymized: true,
+				Login:      userbus.ErrAuthenticationFailure,
+				OldEmail:   userbus.ErrNotFound,
+			},
+			ExcFunc: func(ctx context.Context) any {
+				usrs, err := userbus.TestSeedUsers(ctx, 1, role.User, busDomain.User)
+				if err != nil {
+					return err
+				}
+
+				usr := usrs[0]
+
+				if _, err := busDomain.User.SetPreference(ctx, usr.ID, "theme", json.RawMessage(`"dark"`)); err != nil {
+					return err
+				}
+
+				anon, err := busDomain.User.Anonymize(ctx, sd.Admins[0].ID, usr.ID)
+				if err != nil {
+					return err
+				}
+
+				got, err := busDomain.User.QueryByID(ctx, usr.ID)
+				if err != nil {
+					return err
+				}
+
+				resp := result{
+					Name:       got.Name.String(),
+					Email:      got.Email.Address == userbus.AnonymizedEmail(usr.ID).Address && anon.Email.Address == got.Email.Address,
+					Enabled:    got.Enabled,
+					Anonymized: userbus.IsAnonymized(got),
+				}
+
+				_, err = busDomain.User.Authenticate(ctx, got.Email, "Password1")
+				resp.Login = unwrap(err, userbus.ErrAuthenticationFailure)
+
+				_, err = busDomain.User.QueryByEmail(ctx, usr.Email)
+				resp.OldEmail = unwrap(err, userbus.ErrNotFound)
+
+				return resp
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp, cmp.Comparer(func(a, b error) bool { return a == b }))
+			},
+		},
+	}
+
+	return table
+}
+
+// toIDs returns the ids of the users in the order they were returned.
+func toIDs(usrs []userbus.User) []uuid.UUID {
+	ids := make([]uuid.UUID, len(usrs))
+	for i, usr := range usrs {
+		ids[i] = usr.ID
+	}
+
+	return ids
+}
+
+// sortedIDs returns the ids of the test users in the default order.
+func sortedIDs(usrs []unitest.User) []uuid.UUID {
+	ids := make([]uuid.UUID, len(usrs))
+	for i, usr := range usrs {
+		ids[i] = usr.ID
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i].String() <= ids[j].String()
+	})
+
+	return ids
+}
+
+// unwrap returns the target if the error wraps it, otherwise the error.
+func unwrap(err error, target error) error {
+	if errors.Is(err, target) {
+		return target
+	}
+
+	return err
+}
+
 func delete(busDomain dbtest.BusDomain, sd unitest.SeedData) []unitest.Table {
 	table := []unitest.Table{
 		{
 			Name:    "user",
 			ExpResp: nil,
 			ExcFunc: func(ctx context.Context) any {
-				if err := busDomain.User.Delete(ctx, uuid.UUID{}, sd.Users[1].User); err != nil {
+				if err := busDomain.User.Delete(ctx, userbus.ActorSystem, sd.Users[1].User); err != nil {
 					return err
 				}
 
@@ -297,7 +1862,7 @@ func delete(busDomain dbtest.BusDomain, sd unitest.SeedData) []unitest.Table {
 			Name:    "admin",
 			ExpResp: nil,
 			ExcFunc: func(ctx context.Context) any {
-				if err := busDomain.User.Delete(ctx, uuid.UUID{}, sd.Admins[1].User); err != nil {
+				if err := busDomain.User.Delete(ctx, userbus.ActorSystem, sd.Admins[1].User); err != nil {
 					return err
 				}
 
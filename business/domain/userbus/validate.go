@@ -0,0 +1,164 @@
+package userbus
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+
+	"github.com/ardanlabs/service/business/sdk/buserr"
+	"github.com/ardanlabs/service/business/types/department"
+	"github.com/ardanlabs/service/business/types/name"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/google/uuid"
+)
+
+// ErrInvalidUser is returned when a new user or a change to a user has
+// values that aren't valid. The field violations of the error say which
+// fields failed and why.
+var ErrInvalidUser = buserr.New(buserr.InvalidArgument, "user is not valid")
+
+// maxEmailLength is the longest email address that can be delivered to.
+const maxEmailLength = 254
+
+// Validate checks the values of the new user. The types of the fields
+// check their values when they are parsed, this catches the zero values
+// and anything built without parsing before it reaches the store.
+func (nu NewUser) Validate() error {
+	var v violations
+
+	v.add("name", validateName(nu.Name))
+	v.add("email", validateEmail(nu.Email))
+	v.add("roles", validateRoles(nu.Roles))
+	v.add("department", validateDepartment(nu.Department))
+	v.add("managerID", validateManagerID(nu.ManagerID))
+
+	return v.err()
+}
+
+// Validate checks the values of the fields the update changes.
+func (uu UpdateUser) Validate() error {
+	var v violations
+
+	if uu.Name != nil {
+		v.add("name", validateName(*uu.Name))
+	}
+
+	if uu.Email != nil {
+		v.add("email", validateEmail(*uu.Email))
+	}
+
+	if uu.Roles != nil {
+		v.add("roles", validateRoles(uu.Roles))
+	}
+
+	if uu.Department != nil {
+		v.add("department", validateDepartment(*uu.Department))
+	}
+
+	if uu.ManagerID != nil {
+		v.add("managerID", validateManagerID(*uu.ManagerID))
+	}
+
+	if uu.Version != nil && *uu.Version < 1 {
+		v.add("version", errors.New("must be at least 1"))
+	}
+
+	return v.err()
+}
+
+// =============================================================================
+
+// violations collects the fields that failed validation.
+type violations []buserr.FieldViolation
+
+func (v *violations) add(field string, err error) {
+	if err == nil {
+		return
+	}
+
+	*v = append(*v, buserr.FieldViolation{
+		Field:   field,
+		Message: err.Error(),
+	})
+}
+
+func (v violations) err() error {
+	if len(v) == 0 {
+		return nil
+	}
+
+	return ErrInvalidUser.WithFields(v...)
+}
+
+// -----------------------------------------------------------------------------
+
+func validateName(n name.Name) error {
+	if n.String() == "" {
+		return errors.New("is required")
+	}
+
+	// The name is parsed again so a name built with other limits, or one
+	// that was never parsed, is held to the current rules.
+	if _, err := name.Parse(n.String()); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func validateEmail(email mail.Address) error {
+	if email.Address == "" {
+		return errors.New("is required")
+	}
+
+	if len(email.Address) > maxEmailLength {
+		return fmt.Errorf("must be at most %d characters", maxEmailLength)
+	}
+
+	addr, err := mail.ParseAddress(email.Address)
+	if err != nil || addr.Address != email.Address {
+		return fmt.Errorf("invalid email %q", email.Address)
+	}
+
+	return nil
+}
+
+func validateRoles(roles []role.Role) error {
+	if len(roles) == 0 {
+		return errors.New("must have at least one role")
+	}
+
+	seen := make(map[role.Role]bool, len(roles))
+	for _, r := range roles {
+		if _, err := role.Parse(r.String()); err != nil {
+			return err
+		}
+
+		if seen[r] {
+			return fmt.Errorf("duplicate role %q", r)
+		}
+		seen[r] = true
+	}
+
+	return nil
+}
+
+func validateDepartment(dept department.Null) error {
+	if !dept.Valid() {
+		return nil
+	}
+
+	if _, err := department.ParseNull(dept.String()); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func validateManagerID(managerID uuid.NullUUID) error {
+	if managerID.Valid && managerID.UUID == uuid.Nil {
+		return errors.New("must not be the nil uuid")
+	}
+
+	return nil
+}
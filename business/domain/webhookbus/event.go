@@ -0,0 +1,135 @@
+package webhookbus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/delegate"
+	"github.com/ardanlabs/service/foundation/clock"
+	"github.com/ardanlabs/service/foundation/otel"
+	"github.com/google/uuid"
+)
+
+// DomainName represents the name of this domain.
+const DomainName = "webhook"
+
+// EventAll is the event a webhook is registered for to be sent every event.
+const EventAll = "*"
+
+// events holds the events that can be sent to webhooks.
+var events = []string{
+	userbus.DomainName + "." + userbus.ActionCreated,
+	userbus.DomainName + "." + userbus.ActionUpdated,
+	userbus.DomainName + "." + userbus.ActionDeleted,
+	userbus.DomainName + "." + userbus.ActionAnonymized,
+}
+
+// Events returns the events that can be sent to webhooks.
+func Events() []string {
+	return slices.Clone(events)
+}
+
+// ValidEvent reports whether a webhook can be registered for the event,
+// which is one of the events, a domain raising them or EventAll.
+func ValidEvent(event string) bool {
+	if event == EventAll {
+		return true
+	}
+
+	for _, e := range events {
+		if e == event || strings.HasPrefix(e, event+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// registerDelegateFunctions will register action functions with the delegate
+// system. If the business was constructed for query only, there won't be a
+// delegate provided. The deliveries are recorded synchronously so an event
+// isn't lost once the request that raised it has returned, sending them is
+// left to the job.
+func (b *Business) registerDelegateFunctions() {
+	if b.delegate != nil {
+		for _, event := range events {
+			domain, action, _ := strings.Cut(event, ".")
+			b.delegate.Register(domain, action, b.actionEvent)
+		}
+	}
+}
+
+// payload is the document posted to a webhook.
+type payload struct {
+	ID    uuid.UUID       `json:"id"`
+	Event string          `json:"event"`
+	Time  time.Time       `json:"time"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// actionEvent is executed by the domains indirectly when an event is raised.
+// A pending delivery is recorded for every enabled webhook of the tenant the
+// event belongs to that is registered for the event. Events that don't
+// carry a tenant belong to the default tenant.
+func (b *Business) actionEvent(ctx context.Context, data delegate.Data) error {
+	event := data.Domain + "." + data.Action
+
+	var params struct {
+		TenantID uuid.UUID
+	}
+
+	if err := json.Unmarshal(data.RawParams, &params); err != nil {
+		return fmt.Errorf("expected params with a tenant id: event[%s]: %w", event, err)
+	}
+
+	whs, err := b.storer.QueryByEvent(ctx, params.TenantID, []string{EventAll, data.Domain, event})
+	if err != nil {
+		return fmt.Errorf("querybyevent: event[%s]: %w", event, err)
+	}
+
+	if len(whs) == 0 {
+		return nil
+	}
+
+	raw, err := delegate.EncodeJSON(data)
+	if err != nil {
+		return fmt.Errorf("encode: event[%s]: %w", event, err)
+	}
+
+	now := clock.Now()
+	trace := otel.InjectCarrier(ctx)
+
+	for _, wh := range whs {
+		dlv := Delivery{
+			ID:          uuid.New(),
+			WebhookID:   wh.ID,
+			Event:       event,
+			Status:      StatusPending,
+			NextAttempt: now,
+			Trace:       trace,
+			DateCreated: now,
+			DateUpdated: now,
+		}
+
+		dlv.Payload, err = json.Marshal(payload{
+			ID:    dlv.ID,
+			Event: event,
+			Time:  now.UTC(),
+			Data:  raw,
+		})
+		if err != nil {
+			return fmt.Errorf("marshal: event[%s]: %w", event, err)
+		}
+
+		if err := b.storer.CreateDelivery(ctx, dlv); err != nil {
+			return fmt.Errorf("createdelivery: webhookID[%s]: %w", wh.ID, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,22 @@
+package webhookbus
+
+import (
+	"github.com/google/uuid"
+)
+
+// QueryFilter holds the available fields a query can be filtered on.
+// We are using pointer semantics because the With API mutates the value.
+type QueryFilter struct {
+	ID       *uuid.UUID
+	UserID   *uuid.UUID
+	TenantID *uuid.UUID
+	Event    *string
+	Enabled  *bool
+}
+
+// DeliveryFilter holds the available fields a query for deliveries can be
+// filtered on.
+type DeliveryFilter struct {
+	Status *string
+	Event  *string
+}
@@ -0,0 +1,68 @@
+package webhookbus
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Webhook represents a callback URL registered by an admin to be told about
+// domain events. Events holds the events it's sent, each one either
+// "domain.action", "domain" for every action of the domain, or "*" for
+// every event. The secret signs the deliveries so the receiver can check
+// they came from the service. A webhook belongs to the tenant of the user
+// who registered it and is only sent the events of that tenant.
+type Webhook struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	TenantID    uuid.UUID
+	URL         string
+	Events      []string
+	Secret      string
+	Enabled     bool
+	DateCreated time.Time
+	DateUpdated time.Time
+}
+
+// NewWebhook is what we require from clients when adding a Webhook.
+type NewWebhook struct {
+	UserID uuid.UUID
+	URL    string
+	Events []string
+}
+
+// UpdateWebhook defines what information may be provided to modify an
+// existing Webhook. All fields are optional so clients can send just the
+// fields they want changed.
+type UpdateWebhook struct {
+	URL     *string
+	Events  []string
+	Enabled *bool
+}
+
+// =============================================================================
+
+// Set of delivery statuses.
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+)
+
+// Delivery represents an event to be sent to a webhook. A delivery is
+// pending until it's sent or has used up its attempts. NextAttempt is when
+// a pending delivery is tried next. Trace holds the trace of the call that
+// raised the event so sending it can be linked back to that call.
+type Delivery struct {
+	ID          uuid.UUID
+	WebhookID   uuid.UUID
+	Event       string
+	Payload     []byte
+	Status      string
+	Attempts    int
+	LastError   string
+	NextAttempt time.Time
+	Trace       map[string]string
+	DateCreated time.Time
+	DateUpdated time.Time
+}
@@ -0,0 +1,23 @@
+package webhookbus
+
+import "github.com/ardanlabs/service/business/sdk/order"
+
+// DefaultOrderBy represents the default way we sort.
+var DefaultOrderBy = order.NewBy(OrderByID, order.ASC)
+
+// Set of fields that the results can be ordered by.
+const (
+	OrderByID          = "a"
+	OrderByUserID      = "b"
+	OrderByURL         = "c"
+	OrderByDateCreated = "d"
+)
+
+// OrderFields represents the fields the results can be ordered by, the names
+// clients use for them and the columns the stores order by.
+var OrderFields = order.Register("webhook",
+	order.Field{Name: "webhook_id", Key: OrderByID, Column: "webhook_id"},
+	order.Field{Name: "user_id", Key: OrderByUserID, Column: "user_id"},
+	order.Field{Name: "url", Key: OrderByURL, Column: "url"},
+	order.Field{Name: "date_created", Key: OrderByDateCreated, Column: "date_created"},
+)
@@ -0,0 +1,82 @@
+package webhookbus
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ardanlabs/service/foundation/otel"
+)
+
+// Set of headers sent with every delivery. The delivery id stays the same
+// across retries so receivers can ignore deliveries they already handled.
+const (
+	HeaderID        = "X-Webhook-ID"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Sender defines the behavior required to send a delivery to a webhook.
+type Sender interface {
+	Send(ctx context.Context, wh Webhook, dlv Delivery, now time.Time) error
+}
+
+// HTTPSender sends deliveries by posting them to the webhook URL.
+type HTTPSender struct {
+	client *http.Client
+}
+
+// NewHTTPSender constructs a sender that posts deliveries with the client.
+func NewHTTPSender(client *http.Client) *HTTPSender {
+	return &HTTPSender{
+		client: client,
+	}
+}
+
+// Send implements the Sender interface.
+func (s *HTTPSender) Send(ctx context.Context, wh Webhook, dlv Delivery, now time.Time) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wh.URL, bytes.NewReader(dlv.Payload))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderID, dlv.ID.String())
+	req.Header.Set(HeaderEvent, dlv.Event)
+	req.Header.Set(HeaderSignature, Signature(wh.Secret, now, dlv.Payload))
+	otel.AddTraceToRequest(ctx, req)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// Signature returns the value of the signature header for a payload sent at
+// the specified time, in the form "t=<unix time>,v1=<hex hmac>". The HMAC
+// is a SHA-256 over the unix time, a dot and the payload, keyed with the
+// webhook secret. Receivers compute the same and reject old timestamps to
+// stop replays.
+func Signature(secret string, now time.Time, payload []byte) string {
+	ts := strconv.FormatInt(now.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,66 @@
+package webhookdb
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	"github.com/ardanlabs/service/business/domain/webhookbus"
+	"github.com/ardanlabs/service/business/sdk/tenant"
+)
+
+// scopeFilter limits the filter to the tenant the call is made for. A
+// tenant asked for in the filter can't widen what the call sees.
+func scopeFilter(ctx context.Context, filter webhookbus.QueryFilter) webhookbus.QueryFilter {
+	if tenantID := tenant.Scope(ctx); tenantID != nil {
+		filter.TenantID = tenantID
+	}
+
+	return filter
+}
+
+func applyFilter(filter webhookbus.QueryFilter, data map[string]any, buf *bytes.Buffer) {
+	var wc []string
+
+	if filter.ID != nil {
+		data["webhook_id"] = filter.ID
+		wc = append(wc, "webhook_id = :webhook_id")
+	}
+
+	if filter.UserID != nil {
+		data["user_id"] = filter.UserID
+		wc = append(wc, "user_id = :user_id")
+	}
+
+	if filter.TenantID != nil {
+		data["tenant_id"] = filter.TenantID
+		wc = append(wc, "tenant_id = :tenant_id")
+	}
+
+	if filter.Event != nil {
+		data["event"] = *filter.Event
+		wc = append(wc, ":event = ANY(events)")
+	}
+
+	if filter.Enabled != nil {
+		data["enabled"] = *filter.Enabled
+		wc = append(wc, "enabled = :enabled")
+	}
+
+	if len(wc) > 0 {
+		buf.WriteString(" WHERE ")
+		buf.WriteString(strings.Join(wc, " AND "))
+	}
+}
+
+func applyDeliveryFilter(filter webhookbus.DeliveryFilter, data map[string]any, buf *bytes.Buffer) {
+	if filter.Status != nil {
+		data["status"] = *filter.Status
+		buf.WriteString(" AND status = :status")
+	}
+
+	if filter.Event != nil {
+		data["event"] = *filter.Event
+		buf.WriteString(" AND event = :event")
+	}
+}
@@ -0,0 +1,137 @@
+package webhookdb
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/webhookbus"
+	"github.com/ardanlabs/service/business/sdk/sqldb/dbarray"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx/types"
+)
+
+type webhook struct {
+	ID          uuid.UUID      `db:"webhook_id"`
+	UserID      uuid.UUID      `db:"user_id"`
+	TenantID    uuid.UUID      `db:"tenant_id"`
+	URL         string         `db:"url"`
+	Events      dbarray.String `db:"events"`
+	Secret      string         `db:"secret" class:"restricted"`
+	Enabled     bool           `db:"enabled"`
+	DateCreated time.Time      `db:"date_created"`
+	DateUpdated time.Time      `db:"date_updated"`
+}
+
+func toDBWebhook(bus webhookbus.Webhook) webhook {
+	return webhook{
+		ID:          bus.ID,
+		UserID:      bus.UserID,
+		TenantID:    bus.TenantID,
+		URL:         bus.URL,
+		Events:      bus.Events,
+		Secret:      bus.Secret,
+		Enabled:     bus.Enabled,
+		DateCreated: bus.DateCreated.UTC(),
+		DateUpdated: bus.DateUpdated.UTC(),
+	}
+}
+
+func toBusWebhook(db webhook) webhookbus.Webhook {
+	return webhookbus.Webhook{
+		ID:          db.ID,
+		UserID:      db.UserID,
+		TenantID:    db.TenantID,
+		URL:         db.URL,
+		Events:      db.Events,
+		Secret:      db.Secret,
+		Enabled:     db.Enabled,
+		DateCreated: db.DateCreated.In(time.Local),
+		DateUpdated: db.DateUpdated.In(time.Local),
+	}
+}
+
+func toBusWebhooks(dbs []webhook) []webhookbus.Webhook {
+	bus := make([]webhookbus.Webhook, len(dbs))
+
+	for i, db := range dbs {
+		bus[i] = toBusWebhook(db)
+	}
+
+	return bus
+}
+
+// =============================================================================
+
+type delivery struct {
+	ID          uuid.UUID          `db:"delivery_id"`
+	WebhookID   uuid.UUID          `db:"webhook_id"`
+	Event       string             `db:"event"`
+	Payload     []byte             `db:"payload"`
+	Status      string             `db:"status"`
+	Attempts    int                `db:"attempts"`
+	LastError   sql.NullString     `db:"last_error"`
+	NextAttempt time.Time          `db:"next_attempt"`
+	Trace       types.NullJSONText `db:"trace"`
+	DateCreated time.Time          `db:"date_created"`
+	DateUpdated time.Time          `db:"date_updated"`
+}
+
+func toDBDelivery(bus webhookbus.Delivery) delivery {
+	var trace types.NullJSONText
+	if len(bus.Trace) > 0 {
+		// A map of strings always marshals.
+		data, _ := json.Marshal(bus.Trace)
+		trace = types.NullJSONText{JSONText: data, Valid: true}
+	}
+
+	return delivery{
+		ID:        bus.ID,
+		WebhookID: bus.WebhookID,
+		Event:     bus.Event,
+		Payload:   bus.Payload,
+		Status:    bus.Status,
+		Attempts:  bus.Attempts,
+		LastError: sql.NullString{
+			String: bus.LastError,
+			Valid:  bus.LastError != "",
+		},
+		NextAttempt: bus.NextAttempt.UTC(),
+		Trace:       trace,
+		DateCreated: bus.DateCreated.UTC(),
+		DateUpdated: bus.DateUpdated.UTC(),
+	}
+}
+
+func toBusDelivery(db delivery) webhookbus.Delivery {
+	var trace map[string]string
+	if db.Trace.Valid {
+		// A trace that can't be read only costs the link back to the
+		// call that raised the event.
+		json.Unmarshal(db.Trace.JSONText, &trace)
+	}
+
+	return webhookbus.Delivery{
+		ID:          db.ID,
+		WebhookID:   db.WebhookID,
+		Event:       db.Event,
+		Payload:     db.Payload,
+		Status:      db.Status,
+		Attempts:    db.Attempts,
+		LastError:   db.LastError.String,
+		NextAttempt: db.NextAttempt.In(time.Local),
+		Trace:       trace,
+		DateCreated: db.DateCreated.In(time.Local),
+		DateUpdated: db.DateUpdated.In(time.Local),
+	}
+}
+
+func toBusDeliveries(dbs []delivery) []webhookbus.Delivery {
+	bus := make([]webhookbus.Delivery, len(dbs))
+
+	for i, db := range dbs {
+		bus[i] = toBusDelivery(db)
+	}
+
+	return bus
+}
@@ -0,0 +1,10 @@
+package webhookdb
+
+import (
+	"github.com/ardanlabs/service/business/domain/webhookbus"
+	"github.com/ardanlabs/service/business/sdk/order"
+)
+
+func orderByClause(orderBy order.By) (string, error) {
+	return webhookbus.OrderFields.Clause(orderBy)
+}
@@ -0,0 +1,366 @@
+// Package webhookdb contains webhook related CRUD functionality.
+package webhookdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/webhookbus"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/business/sdk/sqldb/dbarray"
+	"github.com/ardanlabs/service/business/sdk/tenant"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Store manages the set of APIs for webhook database access.
+type Store struct {
+	log *logger.Logger
+	db  sqlx.ExtContext
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *logger.Logger, db sqlx.ExtContext) *Store {
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// NewWithTx constructs a new Store value replacing the sqlx DB
+// value with a sqlx DB value that is currently inside a transaction.
+func (s *Store) NewWithTx(tx sqldb.CommitRollbacker) (webhookbus.Storer, error) {
+	ec, err := sqldb.GetExtContext(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	store := Store{
+		log: s.log,
+		db:  ec,
+	}
+
+	return &store, nil
+}
+
+// Create inserts a new webhook into the database.
+func (s *Store) Create(ctx context.Context, wh webhookbus.Webhook) error {
+	const q = `
+	INSERT INTO webhooks
+		(webhook_id, user_id, tenant_id, url, events, secret, enabled, date_created, date_updated)
+	VALUES
+		(:webhook_id, :user_id, :tenant_id, :url, :events, :secret, :enabled, :date_created, :date_updated)`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBWebhook(wh)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Update replaces a webhook document in the database.
+func (s *Store) Update(ctx context.Context, wh webhookbus.Webhook) error {
+	const q = `
+	UPDATE
+		webhooks
+	SET
+		"url" = :url,
+		"events" = :events,
+		"enabled" = :enabled,
+		"date_updated" = :date_updated
+	WHERE
+		webhook_id = :webhook_id`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBWebhook(wh)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes a webhook from the database.
+func (s *Store) Delete(ctx context.Context, wh webhookbus.Webhook) error {
+	data := struct {
+		ID string `db:"webhook_id"`
+	}{
+		ID: wh.ID.String(),
+	}
+
+	const q = `
+	DELETE FROM
+		webhooks
+	WHERE
+		webhook_id = :webhook_id`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Query retrieves a list of existing webhooks from the database.
+func (s *Store) Query(ctx context.Context, filter webhookbus.QueryFilter, orderBy order.By, page page.Page) ([]webhookbus.Webhook, error) {
+	data := map[string]any{
+		"offset":        (page.Number() - 1) * page.RowsPerPage(),
+		"rows_per_page": page.RowsPerPage(),
+	}
+
+	const q = `
+	SELECT
+		webhook_id, user_id, tenant_id, url, events, secret, enabled, date_created, date_updated
+	FROM
+		webhooks`
+
+	buf := bytes.NewBufferString(q)
+	applyFilter(scopeFilter(ctx, filter), data, buf)
+
+	orderByClause, err := orderByClause(orderBy)
+	if err != nil {
+		return nil, err
+	}
+
+	buf.WriteString(orderByClause)
+	buf.WriteString(" OFFSET :offset ROWS FETCH NEXT :rows_per_page ROWS ONLY")
+
+	var dbWhs []webhook
+	if err := sqldb.NamedQuerySlice(ctx, s.log, s.db, buf.String(), data, &dbWhs); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toBusWebhooks(dbWhs), nil
+}
+
+// Count returns the total number of webhooks in the DB.
+func (s *Store) Count(ctx context.Context, filter webhookbus.QueryFilter) (int, error) {
+	data := map[string]any{}
+
+	const q = `
+	SELECT
+		count(1)
+	FROM
+		webhooks`
+
+	buf := bytes.NewBufferString(q)
+	applyFilter(scopeFilter(ctx, filter), data, buf)
+
+	var count struct {
+		Count int `db:"count"`
+	}
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, buf.String(), data, &count); err != nil {
+		return 0, fmt.Errorf("db: %w", err)
+	}
+
+	return count.Count, nil
+}
+
+// QueryByID gets the specified webhook from the database. A webhook of
+// another tenant than the one the call is made for isn't found.
+func (s *Store) QueryByID(ctx context.Context, webhookID uuid.UUID) (webhookbus.Webhook, error) {
+	data := map[string]any{
+		"webhook_id": webhookID,
+		"tenant_id":  tenant.Scope(ctx),
+	}
+
+	const q = `
+	SELECT
+		webhook_id, user_id, tenant_id, url, events, secret, enabled, date_created, date_updated
+	FROM
+		webhooks
+	WHERE
+		webhook_id = :webhook_id AND (CAST(:tenant_id AS UUID) IS NULL OR tenant_id = :tenant_id)`
+
+	var dbWh webhook
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbWh); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return webhookbus.Webhook{}, fmt.Errorf("db: %w", webhookbus.ErrNotFound)
+		}
+		return webhookbus.Webhook{}, fmt.Errorf("db: %w", err)
+	}
+
+	return toBusWebhook(dbWh), nil
+}
+
+// QueryByEvent retrieves the enabled webhooks of the tenant registered for
+// any of the specified events.
+func (s *Store) QueryByEvent(ctx context.Context, tenantID uuid.UUID, events []string) ([]webhookbus.Webhook, error) {
+	data := map[string]any{
+		"tenant_id": tenantID,
+		"events":    dbarray.String(events),
+	}
+
+	const q = `
+	SELECT
+		webhook_id, user_id, tenant_id, url, events, secret, enabled, date_created, date_updated
+	FROM
+		webhooks
+	WHERE
+		tenant_id = :tenant_id AND enabled = TRUE AND events && CAST(:events AS TEXT[])`
+
+	var dbWhs []webhook
+	if err := sqldb.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbWhs); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toBusWebhooks(dbWhs), nil
+}
+
+// =============================================================================
+
+// CreateDelivery inserts a new delivery into the database.
+func (s *Store) CreateDelivery(ctx context.Context, dlv webhookbus.Delivery) error {
+	const q = `
+	INSERT INTO webhook_deliveries
+		(delivery_id, webhook_id, event, payload, status, attempts, last_error, next_attempt, trace, date_created, date_updated)
+	VALUES
+		(:delivery_id, :webhook_id, :event, :payload, :status, :attempts, :last_error, :next_attempt, :trace, :date_created, :date_updated)`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBDelivery(dlv)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateDelivery replaces a delivery document in the database.
+func (s *Store) UpdateDelivery(ctx context.Context, dlv webhookbus.Delivery) error {
+	const q = `
+	UPDATE
+		webhook_deliveries
+	SET
+		"status" = :status,
+		"attempts" = :attempts,
+		"last_error" = :last_error,
+		"next_attempt" = :next_attempt,
+		"date_updated" = :date_updated
+	WHERE
+		delivery_id = :delivery_id`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBDelivery(dlv)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// QueryDeliveries retrieves the deliveries for a webhook from the database,
+// most recent first.
+func (s *Store) QueryDeliveries(ctx context.Context, webhookID uuid.UUID, filter webhookbus.DeliveryFilter, page page.Page) ([]webhookbus.Delivery, error) {
+	data := map[string]any{
+		"webhook_id":    webhookID,
+		"offset":        (page.Number() - 1) * page.RowsPerPage(),
+		"rows_per_page": page.RowsPerPage(),
+	}
+
+	const q = `
+	SELECT
+		delivery_id, webhook_id, event, payload, status, attempts, last_error, next_attempt, trace, date_created, date_updated
+	FROM
+		webhook_deliveries
+	WHERE
+		webhook_id = :webhook_id`
+
+	buf := bytes.NewBufferString(q)
+	applyDeliveryFilter(filter, data, buf)
+
+	buf.WriteString(" ORDER BY date_created DESC")
+	buf.WriteString(" OFFSET :offset ROWS FETCH NEXT :rows_per_page ROWS ONLY")
+
+	var dbDlvs []delivery
+	if err := sqldb.NamedQuerySlice(ctx, s.log, s.db, buf.String(), data, &dbDlvs); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toBusDeliveries(dbDlvs), nil
+}
+
+// CountDeliveries returns the total number of deliveries for a webhook.
+func (s *Store) CountDeliveries(ctx context.Context, webhookID uuid.UUID, filter webhookbus.DeliveryFilter) (int, error) {
+	data := map[string]any{
+		"webhook_id": webhookID,
+	}
+
+	const q = `
+	SELECT
+		count(1)
+	FROM
+		webhook_deliveries
+	WHERE
+		webhook_id = :webhook_id`
+
+	buf := bytes.NewBufferString(q)
+	applyDeliveryFilter(filter, data, buf)
+
+	var count struct {
+		Count int `db:"count"`
+	}
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, buf.String(), data, &count); err != nil {
+		return 0, fmt.Errorf("db: %w", err)
+	}
+
+	return count.Count, nil
+}
+
+// QueryDeliveryByID gets the specified delivery from the database.
+func (s *Store) QueryDeliveryByID(ctx context.Context, deliveryID uuid.UUID) (webhookbus.Delivery, error) {
+	data := struct {
+		ID string `db:"delivery_id"`
+	}{
+		ID: deliveryID.String(),
+	}
+
+	const q = `
+	SELECT
+		delivery_id, webhook_id, event, payload, status, attempts, last_error, next_attempt, trace, date_created, date_updated
+	FROM
+		webhook_deliveries
+	WHERE
+		delivery_id = :delivery_id`
+
+	var dbDlv delivery
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbDlv); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return webhookbus.Delivery{}, fmt.Errorf("db: %w", webhookbus.ErrDeliveryNotFound)
+		}
+		return webhookbus.Delivery{}, fmt.Errorf("db: %w", err)
+	}
+
+	return toBusDelivery(dbDlv), nil
+}
+
+// QueryDueDeliveries retrieves up to limit pending deliveries that are due
+// to be sent, the longest waiting first. Deliveries for disabled webhooks
+// are held back until the webhook is enabled again.
+func (s *Store) QueryDueDeliveries(ctx context.Context, now time.Time, limit int) ([]webhookbus.Delivery, error) {
+	data := map[string]any{
+		"status": webhookbus.StatusPending,
+		"now":    now.UTC(),
+		"limit":  limit,
+	}
+
+	const q = `
+	SELECT
+		d.delivery_id, d.webhook_id, d.event, d.payload, d.status, d.attempts, d.last_error, d.next_attempt, d.trace, d.date_created, d.date_updated
+	FROM
+		webhook_deliveries AS d
+	JOIN
+		webhooks AS w ON w.webhook_id = d.webhook_id
+	WHERE
+		d.status = :status AND d.next_attempt <= :now AND w.enabled = TRUE
+	ORDER BY
+		d.next_attempt
+	FETCH NEXT :limit ROWS ONLY`
+
+	var dbDlvs []delivery
+	if err := sqldb.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbDlvs); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toBusDeliveries(dbDlvs), nil
+}
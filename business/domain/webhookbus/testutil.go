@@ -0,0 +1,46 @@
+package webhookbus
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+
+	"github.com/google/uuid"
+)
+
+// TestGenerateNewWebhooks is a helper method for testing.
+func TestGenerateNewWebhooks(n int, userID uuid.UUID) []NewWebhook {
+	newWhs := make([]NewWebhook, n)
+
+	idx := rand.Intn(10000)
+	for i := range n {
+		idx++
+
+		nw := NewWebhook{
+			UserID: userID,
+			URL:    fmt.Sprintf("https://example.com/hooks/%d", idx),
+			Events: []string{EventAll},
+		}
+
+		newWhs[i] = nw
+	}
+
+	return newWhs
+}
+
+// TestGenerateSeedWebhooks is a helper method for testing.
+func TestGenerateSeedWebhooks(ctx context.Context, n int, api *Business, userID uuid.UUID) ([]Webhook, error) {
+	newWhs := TestGenerateNewWebhooks(n, userID)
+
+	whs := make([]Webhook, len(newWhs))
+	for i, nw := range newWhs {
+		wh, err := api.Create(ctx, nw)
+		if err != nil {
+			return nil, fmt.Errorf("seeding webhook: idx: %d : %w", i, err)
+		}
+
+		whs[i] = wh
+	}
+
+	return whs, nil
+}
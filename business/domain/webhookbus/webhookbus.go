@@ -0,0 +1,419 @@
+// Package webhookbus provides business access to webhook domain. Webhooks
+// are told about domain events by posting them to a callback URL. The
+// deliveries are recorded when the event is raised and sent by a job, which
+// retries the ones that fail with an exponential backoff.
+package webhookbus
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/buserr"
+	"github.com/ardanlabs/service/business/sdk/delegate"
+	"github.com/ardanlabs/service/business/sdk/jobs"
+	"github.com/ardanlabs/service/business/sdk/order"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/foundation/clock"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/otel"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrNotFound         = buserr.New(buserr.NotFound, "webhook not found")
+	ErrDeliveryNotFound = buserr.New(buserr.NotFound, "delivery not found")
+	ErrUserDisabled     = buserr.New(buserr.FailedPrecondition, "user disabled")
+	ErrNoEvents         = buserr.New(buserr.InvalidArgument, "webhook must be sent at least one event")
+	ErrDelivered        = buserr.New(buserr.FailedPrecondition, "delivery already delivered")
+)
+
+// Retry defines how failed deliveries are retried. The wait before an
+// attempt doubles from Backoff with each failure, up to MaxBackoff, and a
+// delivery is marked failed after MaxAttempts. Zero values use the
+// defaults.
+type Retry struct {
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+}
+
+// Set of defaults for retrying deliveries.
+const (
+	DefaultMaxAttempts = 8
+	DefaultBackoff     = 30 * time.Second
+	DefaultMaxBackoff  = 6 * time.Hour
+)
+
+// dueLimit is the number of deliveries sent each time the job runs, so a
+// backlog is worked through over several runs.
+const dueLimit = 100
+
+// Storer interface declares the behavior this package needs to persist and
+// retrieve data.
+type Storer interface {
+	NewWithTx(tx sqldb.CommitRollbacker) (Storer, error)
+	Create(ctx context.Context, wh Webhook) error
+	Update(ctx context.Context, wh Webhook) error
+	Delete(ctx context.Context, wh Webhook) error
+	Query(ctx context.Context, filter QueryFilter, orderBy order.By, page page.Page) ([]Webhook, error)
+	Count(ctx context.Context, filter QueryFilter) (int, error)
+	QueryByID(ctx context.Context, webhookID uuid.UUID) (Webhook, error)
+	QueryByEvent(ctx context.Context, tenantID uuid.UUID, events []string) ([]Webhook, error)
+	CreateDelivery(ctx context.Context, dlv Delivery) error
+	UpdateDelivery(ctx context.Context, dlv Delivery) error
+	QueryDeliveries(ctx context.Context, webhookID uuid.UUID, filter DeliveryFilter, page page.Page) ([]Delivery, error)
+	CountDeliveries(ctx context.Context, webhookID uuid.UUID, filter DeliveryFilter) (int, error)
+	QueryDeliveryByID(ctx context.Context, deliveryID uuid.UUID) (Delivery, error)
+	QueryDueDeliveries(ctx context.Context, now time.Time, limit int) ([]Delivery, error)
+}
+
+// Business manages the set of APIs for webhook access.
+type Business struct {
+	log      *logger.Logger
+	userBus  userbus.Business
+	delegate *delegate.Delegate
+	storer   Storer
+	sender   Sender
+	retry    Retry
+}
+
+// NewBusiness constructs a webhook business API for use. Deliveries are
+// recorded for the events raised through the delegate and sent with the
+// sender, without a sender they stay pending.
+func NewBusiness(log *logger.Logger, userBus userbus.Business, delegate *delegate.Delegate, storer Storer, sender Sender, retry Retry) *Business {
+	if retry.MaxAttempts <= 0 {
+		retry.MaxAttempts = DefaultMaxAttempts
+	}
+
+	if retry.Backoff <= 0 {
+		retry.Backoff = DefaultBackoff
+	}
+
+	if retry.MaxBackoff <= 0 {
+		retry.MaxBackoff = DefaultMaxBackoff
+	}
+
+	b := Business{
+		log:      log,
+		userBus:  userBus,
+		delegate: delegate,
+		storer:   storer,
+		sender:   sender,
+		retry:    retry,
+	}
+
+	b.registerDelegateFunctions()
+
+	return &b
+}
+
+// NewWithTx constructs a new business value that will use the
+// specified transaction in any store related calls.
+func (b *Business) NewWithTx(tx sqldb.CommitRollbacker) (*Business, error) {
+	storer, err := b.storer.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	userBus, err := b.userBus.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	bus := Business{
+		log:      b.log,
+		userBus:  userBus,
+		delegate: b.delegate,
+		storer:   storer,
+		sender:   b.sender,
+		retry:    b.retry,
+	}
+
+	return &bus, nil
+}
+
+// Create adds a new webhook to the system. The webhook is given a secret to
+// sign its deliveries with.
+func (b *Business) Create(ctx context.Context, nw NewWebhook) (Webhook, error) {
+	ctx, span := otel.AddSpan(ctx, "business.webhookbus.create")
+	defer span.End()
+
+	if len(nw.Events) == 0 {
+		return Webhook{}, ErrNoEvents
+	}
+
+	usr, err := b.userBus.QueryByID(ctx, nw.UserID)
+	if err != nil {
+		return Webhook{}, fmt.Errorf("user.querybyid: %s: %w", nw.UserID, err)
+	}
+
+	if !usr.Enabled {
+		return Webhook{}, ErrUserDisabled
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return Webhook{}, fmt.Errorf("newsecret: %w", err)
+	}
+
+	now := clock.Now()
+
+	wh := Webhook{
+		ID:          uuid.New(),
+		UserID:      nw.UserID,
+		TenantID:    usr.TenantID,
+		URL:         nw.URL,
+		Events:      nw.Events,
+		Secret:      secret,
+		Enabled:     true,
+		DateCreated: now,
+		DateUpdated: now,
+	}
+
+	if err := b.storer.Create(ctx, wh); err != nil {
+		return Webhook{}, fmt.Errorf("create: %w", err)
+	}
+
+	return wh, nil
+}
+
+// Update modifies information about a webhook.
+func (b *Business) Update(ctx context.Context, wh Webhook, uw UpdateWebhook) (Webhook, error) {
+	ctx, span := otel.AddSpan(ctx, "business.webhookbus.update")
+	defer span.End()
+
+	if uw.URL != nil {
+		wh.URL = *uw.URL
+	}
+
+	if uw.Events != nil {
+		if len(uw.Events) == 0 {
+			return Webhook{}, ErrNoEvents
+		}
+		wh.Events = uw.Events
+	}
+
+	if uw.Enabled != nil {
+		wh.Enabled = *uw.Enabled
+	}
+
+	wh.DateUpdated = clock.Now()
+
+	if err := b.storer.Update(ctx, wh); err != nil {
+		return Webhook{}, fmt.Errorf("update: %w", err)
+	}
+
+	return wh, nil
+}
+
+// Delete removes the specified webhook and its deliveries.
+func (b *Business) Delete(ctx context.Context, wh Webhook) error {
+	ctx, span := otel.AddSpan(ctx, "business.webhookbus.delete")
+	defer span.End()
+
+	if err := b.storer.Delete(ctx, wh); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	return nil
+}
+
+// Query retrieves a list of existing webhooks.
+func (b *Business) Query(ctx context.Context, filter QueryFilter, orderBy order.By, page page.Page) ([]Webhook, error) {
+	ctx, span := otel.AddSpan(ctx, "business.webhookbus.query")
+	defer span.End()
+
+	whs, err := b.storer.Query(ctx, filter, orderBy, page)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return whs, nil
+}
+
+// Count returns the total number of webhooks.
+func (b *Business) Count(ctx context.Context, filter QueryFilter) (int, error) {
+	ctx, span := otel.AddSpan(ctx, "business.webhookbus.count")
+	defer span.End()
+
+	return b.storer.Count(ctx, filter)
+}
+
+// QueryByID finds the webhook by the specified ID.
+func (b *Business) QueryByID(ctx context.Context, webhookID uuid.UUID) (Webhook, error) {
+	ctx, span := otel.AddSpan(ctx, "business.webhookbus.querybyid")
+	defer span.End()
+
+	wh, err := b.storer.QueryByID(ctx, webhookID)
+	if err != nil {
+		return Webhook{}, fmt.Errorf("query: webhookID[%s]: %w", webhookID, err)
+	}
+
+	return wh, nil
+}
+
+// QueryDeliveries retrieves the deliveries for a webhook, most recent
+// first.
+func (b *Business) QueryDeliveries(ctx context.Context, webhookID uuid.UUID, filter DeliveryFilter, page page.Page) ([]Delivery, error) {
+	ctx, span := otel.AddSpan(ctx, "business.webhookbus.querydeliveries")
+	defer span.End()
+
+	dlvs, err := b.storer.QueryDeliveries(ctx, webhookID, filter, page)
+	if err != nil {
+		return nil, fmt.Errorf("query: webhookID[%s]: %w", webhookID, err)
+	}
+
+	return dlvs, nil
+}
+
+// CountDeliveries returns the total number of deliveries for a webhook.
+func (b *Business) CountDeliveries(ctx context.Context, webhookID uuid.UUID, filter DeliveryFilter) (int, error) {
+	ctx, span := otel.AddSpan(ctx, "business.webhookbus.countdeliveries")
+	defer span.End()
+
+	return b.storer.CountDeliveries(ctx, webhookID, filter)
+}
+
+// QueryDeliveryByID finds the delivery by the specified ID.
+func (b *Business) QueryDeliveryByID(ctx context.Context, deliveryID uuid.UUID) (Delivery, error) {
+	ctx, span := otel.AddSpan(ctx, "business.webhookbus.querydeliverybyid")
+	defer span.End()
+
+	dlv, err := b.storer.QueryDeliveryByID(ctx, deliveryID)
+	if err != nil {
+		return Delivery{}, fmt.Errorf("query: deliveryID[%s]: %w", deliveryID, err)
+	}
+
+	return dlv, nil
+}
+
+// Redeliver queues a failed delivery to be sent again with a fresh set of
+// attempts.
+func (b *Business) Redeliver(ctx context.Context, dlv Delivery) (Delivery, error) {
+	ctx, span := otel.AddSpan(ctx, "business.webhookbus.redeliver")
+	defer span.End()
+
+	if dlv.Status == StatusDelivered {
+		return Delivery{}, ErrDelivered
+	}
+
+	now := clock.Now()
+
+	dlv.Status = StatusPending
+	dlv.Attempts = 0
+	dlv.NextAttempt = now
+	dlv.DateUpdated = now
+
+	if err := b.storer.UpdateDelivery(ctx, dlv); err != nil {
+		return Delivery{}, fmt.Errorf("updatedelivery: %w", err)
+	}
+
+	return dlv, nil
+}
+
+// =============================================================================
+
+// DeliverDue sends the pending deliveries that are due at the specified
+// time. A failure to send one delivery doesn't stop the others and is
+// recorded on the delivery for it to be retried.
+func (b *Business) DeliverDue(ctx context.Context, now time.Time) error {
+	ctx, span := otel.AddSpan(ctx, "business.webhookbus.deliverdue")
+	defer span.End()
+
+	if b.sender == nil {
+		return nil
+	}
+
+	dlvs, err := b.storer.QueryDueDeliveries(ctx, now, dueLimit)
+	if err != nil {
+		return fmt.Errorf("queryduedeliveries: %w", err)
+	}
+
+	for _, dlv := range dlvs {
+		if err := b.deliver(ctx, dlv, now); err != nil {
+			b.log.Error(ctx, "webhookbus: deliver", "deliveryID", dlv.ID, "ERROR", err)
+		}
+	}
+
+	return nil
+}
+
+// Job constructs the job that sends due deliveries every interval.
+func (b *Business) Job(interval time.Duration) jobs.Job {
+	return jobs.Job{
+		Name:     "webhookbus.deliverdue",
+		Schedule: jobs.Every(interval),
+		Run: func(ctx context.Context) error {
+			return b.DeliverDue(ctx, clock.Now())
+		},
+	}
+}
+
+// deliver sends the delivery inside a consumer span linked to the trace of
+// the call that raised the event.
+func (b *Business) deliver(ctx context.Context, dlv Delivery, now time.Time) error {
+	ctx, span := otel.AddConsumerSpan(ctx, otel.ExtractCarrier(dlv.Trace), "business.webhookbus.deliver",
+		attribute.String("deliveryID", dlv.ID.String()),
+		attribute.String("event", dlv.Event),
+	)
+	defer span.End()
+
+	wh, err := b.storer.QueryByID(ctx, dlv.WebhookID)
+	if err != nil {
+		return fmt.Errorf("querybyid: webhookID[%s]: %w", dlv.WebhookID, err)
+	}
+
+	dlv.Attempts++
+	dlv.DateUpdated = now
+
+	switch err := b.sender.Send(ctx, wh, dlv, now); {
+	case err == nil:
+		dlv.Status = StatusDelivered
+		dlv.LastError = ""
+
+	case dlv.Attempts >= b.retry.MaxAttempts:
+		dlv.Status = StatusFailed
+		dlv.LastError = err.Error()
+
+	default:
+		dlv.LastError = err.Error()
+		dlv.NextAttempt = now.Add(b.backoff(dlv.Attempts))
+	}
+
+	if err := b.storer.UpdateDelivery(ctx, dlv); err != nil {
+		return fmt.Errorf("updatedelivery: %w", err)
+	}
+
+	return nil
+}
+
+// backoff returns how long to wait before the next attempt after the
+// specified number of attempts failed.
+func (b *Business) backoff(attempts int) time.Duration {
+	d := b.retry.Backoff
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= b.retry.MaxBackoff {
+			return b.retry.MaxBackoff
+		}
+	}
+
+	return min(d, b.retry.MaxBackoff)
+}
+
+// newSecret returns a random secret for signing deliveries.
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return "whsec_" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,483 @@
+package webhookbus_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/domain/webhookbus"
+	"github.com/ardanlabs/service/business/domain/webhookbus/stores/webhookdb"
+	"github.com/ardanlabs/service/business/sdk/dbtest"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/sdk/tenant"
+	"github.com/ardanlabs/service/business/sdk/unitest"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/ardanlabs/service/foundation/otel"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	gotel "go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func Test_Webhook(t *testing.T) {
+	t.Parallel()
+
+	db := dbtest.New(t, "Test_Webhook")
+
+	sd, err := insertSeedData(db.BusDomain)
+	if err != nil {
+		t.Fatalf("Seeding error: %s", err)
+	}
+
+	// The business used for sending is built with a sender that can be told
+	// to fail so the retries can be checked.
+	sender := &stubSender{}
+	retry := webhookbus.Retry{
+		MaxAttempts: 2,
+		Backoff:     time.Minute,
+	}
+	bus := webhookbus.NewBusiness(db.Log, db.BusDomain.User, nil, webhookdb.NewStore(db.Log, db.DB), sender, retry)
+
+	// -------------------------------------------------------------------------
+
+	unitest.Run(t, query(db.BusDomain, sd), "query")
+	unitest.Run(t, create(db.BusDomain, sd), "create")
+	unitest.Run(t, update(db.BusDomain, sd), "update")
+	unitest.Run(t, event(db.BusDomain, sd), "event")
+	unitest.Run(t, deliver(bus, sender, sd), "deliver")
+}
+
+func Test_HTTPSender(t *testing.T) {
+	t.Parallel()
+
+	const secret = "whsec_test"
+
+	wh := webhookbus.Webhook{
+		Secret: secret,
+	}
+
+	dlv := webhookbus.Delivery{
+		ID:      uuid.New(),
+		Event:   "user.created",
+		Payload: []byte(`{"event":"user.created"}`),
+	}
+
+	now := time.Unix(1700000000, 0)
+
+	// The delivery is sent in a trace so the receiver can be checked to
+	// get it.
+	gotel.SetTextMapPropagator(propagation.TraceContext{})
+
+	tp := sdktrace.NewTracerProvider()
+	defer tp.Shutdown(context.Background())
+
+	ctx := otel.InjectTracing(context.Background(), tp.Tracer("test"))
+
+	ctx, span := otel.AddSpan(ctx, "deliver")
+	defer span.End()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte("1700000000." + string(body)))
+		exp := "t=1700000000,v1=" + hex.EncodeToString(mac.Sum(nil))
+
+		switch {
+		case r.Header.Get(webhookbus.HeaderSignature) != exp:
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.Header.Get(webhookbus.HeaderID) != dlv.ID.String():
+			w.WriteHeader(http.StatusBadRequest)
+		case r.Header.Get(webhookbus.HeaderEvent) != dlv.Event:
+			w.WriteHeader(http.StatusBadRequest)
+		case otel.ExtractCarrier(map[string]string{"traceparent": r.Header.Get("traceparent")}).TraceID() != span.SpanContext().TraceID():
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	wh.URL = srv.URL
+
+	if err := webhookbus.NewHTTPSender(srv.Client()).Send(ctx, wh, dlv, now); err != nil {
+		t.Fatalf("Should be able to send the delivery: %s", err)
+	}
+
+	wh.Secret = "whsec_other"
+
+	if err := webhookbus.NewHTTPSender(srv.Client()).Send(ctx, wh, dlv, now); err == nil {
+		t.Fatalf("Should fail when the receiver rejects the signature")
+	}
+}
+
+// =============================================================================
+
+func insertSeedData(busDomain dbtest.BusDomain) (unitest.SeedData, error) {
+	ctx := context.Background()
+
+	usrs, err := userbus.TestSeedUsers(ctx, 1, role.Admin, busDomain.User)
+	if err != nil {
+		return unitest.SeedData{}, fmt.Errorf("seeding users : %w", err)
+	}
+
+	whs, err := webhookbus.TestGenerateSeedWebhooks(ctx, 2, busDomain.Webhook, usrs[0].ID)
+	if err != nil {
+		return unitest.SeedData{}, fmt.Errorf("seeding webhooks : %w", err)
+	}
+
+	tu1 := unitest.User{
+		User:     usrs[0],
+		Webhooks: whs,
+	}
+
+	// -------------------------------------------------------------------------
+
+	sd := unitest.SeedData{
+		Admins: []unitest.User{tu1},
+	}
+
+	return sd, nil
+}
+
+// =============================================================================
+
+type stubSender struct {
+	err error
+}
+
+func (s *stubSender) Send(ctx context.Context, wh webhookbus.Webhook, dlv webhookbus.Delivery, now time.Time) error {
+	return s.err
+}
+
+// =============================================================================
+
+func query(busDomain dbtest.BusDomain, sd unitest.SeedData) []unitest.Table {
+	table := []unitest.Table{
+		{
+			Name:    "byid",
+			ExpResp: sd.Admins[0].Webhooks[0],
+			ExcFunc: func(ctx context.Context) any {
+				resp, err := busDomain.Webhook.QueryByID(ctx, sd.Admins[0].Webhooks[0].ID)
+				if err != nil {
+					return err
+				}
+
+				return resp
+			},
+			CmpFunc: func(got any, exp any) string {
+				gotResp, exists := got.(webhookbus.Webhook)
+				if !exists {
+					return "error occurred"
+				}
+
+				expResp := exp.(webhookbus.Webhook)
+
+				if gotResp.DateCreated.Format(time.RFC3339) == expResp.DateCreated.Format(time.RFC3339) {
+					expResp.DateCreated = gotResp.DateCreated
+				}
+
+				if gotResp.DateUpdated.Format(time.RFC3339) == expResp.DateUpdated.Format(time.RFC3339) {
+					expResp.DateUpdated = gotResp.DateUpdated
+				}
+
+				return cmp.Diff(gotResp, expResp)
+			},
+		},
+	}
+
+	return table
+}
+
+func create(busDomain dbtest.BusDomain, sd unitest.SeedData) []unitest.Table {
+	table := []unitest.Table{
+		{
+			Name: "basic",
+			ExpResp: webhookbus.Webhook{
+				UserID:  sd.Admins[0].ID,
+				URL:     "https://example.com/hooks/users",
+				Events:  []string{"user"},
+				Enabled: true,
+			},
+			ExcFunc: func(ctx context.Context) any {
+				nw := webhookbus.NewWebhook{
+					UserID: sd.Admins[0].ID,
+					URL:    "https://example.com/hooks/users",
+					Events: []string{"user"},
+				}
+
+				resp, err := busDomain.Webhook.Create(ctx, nw)
+				if err != nil {
+					return err
+				}
+
+				return resp
+			},
+			CmpFunc: func(got any, exp any) string {
+				gotResp, exists := got.(webhookbus.Webhook)
+				if !exists {
+					return "error occurred"
+				}
+
+				if !strings.HasPrefix(gotResp.Secret, "whsec_") {
+					return fmt.Sprintf("unexpected secret %q", gotResp.Secret)
+				}
+
+				expResp := exp.(webhookbus.Webhook)
+
+				expResp.ID = gotResp.ID
+				expResp.Secret = gotResp.Secret
+				expResp.DateCreated = gotResp.DateCreated
+				expResp.DateUpdated = gotResp.DateUpdated
+
+				return cmp.Diff(gotResp, expResp)
+			},
+		},
+		{
+			Name:    "noevents",
+			ExpResp: webhookbus.ErrNoEvents,
+			ExcFunc: func(ctx context.Context) any {
+				nw := webhookbus.NewWebhook{
+					UserID: sd.Admins[0].ID,
+					URL:    "https://example.com/hooks/none",
+				}
+
+				_, err := busDomain.Webhook.Create(ctx, nw)
+
+				return err
+			},
+			CmpFunc: func(got any, exp any) string {
+				if !errors.Is(got.(error), exp.(error)) {
+					return fmt.Sprintf("got %v, expected %v", got, exp)
+				}
+
+				return ""
+			},
+		},
+	}
+
+	return table
+}
+
+func update(busDomain dbtest.BusDomain, sd unitest.SeedData) []unitest.Table {
+	enabled := false
+
+	table := []unitest.Table{
+		{
+			Name: "disable",
+			ExpResp: webhookbus.Webhook{
+				ID:          sd.Admins[0].Webhooks[1].ID,
+				UserID:      sd.Admins[0].ID,
+				URL:         sd.Admins[0].Webhooks[1].URL,
+				Events:      sd.Admins[0].Webhooks[1].Events,
+				Secret:      sd.Admins[0].Webhooks[1].Secret,
+				Enabled:     false,
+				DateCreated: sd.Admins[0].Webhooks[1].DateCreated,
+			},
+			ExcFunc: func(ctx context.Context) any {
+				uw := webhookbus.UpdateWebhook{
+					Enabled: &enabled,
+				}
+
+				resp, err := busDomain.Webhook.Update(ctx, sd.Admins[0].Webhooks[1], uw)
+				if err != nil {
+					return err
+				}
+
+				return resp
+			},
+			CmpFunc: func(got any, exp any) string {
+				gotResp, exists := got.(webhookbus.Webhook)
+				if !exists {
+					return "error occurred"
+				}
+
+				expResp := exp.(webhookbus.Webhook)
+
+				expResp.DateUpdated = gotResp.DateUpdated
+
+				return cmp.Diff(gotResp, expResp)
+			},
+		},
+	}
+
+	return table
+}
+
+func event(busDomain dbtest.BusDomain, sd unitest.SeedData) []unitest.Table {
+	table := []unitest.Table{
+		{
+			Name: "usercreated",
+			ExpResp: []int{
+				1,
+				0,
+			},
+			ExcFunc: func(ctx context.Context) any {
+				if _, err := userbus.TestSeedUsers(ctx, 1, role.User, busDomain.User); err != nil {
+					return err
+				}
+
+				var counts []int
+				for _, wh := range sd.Admins[0].Webhooks {
+					n, err := busDomain.Webhook.CountDeliveries(ctx, wh.ID, webhookbus.DeliveryFilter{})
+					if err != nil {
+						return err
+					}
+
+					counts = append(counts, n)
+				}
+
+				return counts
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+		{
+			Name: "othertenant",
+			ExpResp: []int{
+				1,
+				0,
+			},
+			ExcFunc: func(ctx context.Context) any {
+				tenantCtx := tenant.With(ctx, uuid.New())
+
+				if _, err := userbus.TestSeedUsers(tenantCtx, 1, role.User, busDomain.User); err != nil {
+					return err
+				}
+
+				var counts []int
+				for _, wh := range sd.Admins[0].Webhooks {
+					n, err := busDomain.Webhook.CountDeliveries(ctx, wh.ID, webhookbus.DeliveryFilter{})
+					if err != nil {
+						return err
+					}
+
+					counts = append(counts, n)
+				}
+
+				if _, err := busDomain.Webhook.QueryByID(tenantCtx, sd.Admins[0].Webhooks[0].ID); !errors.Is(err, webhookbus.ErrNotFound) {
+					return fmt.Errorf("should not find the webhook of another tenant: %v", err)
+				}
+
+				return counts
+			},
+			CmpFunc: func(got any, exp any) string {
+				return cmp.Diff(got, exp)
+			},
+		},
+	}
+
+	return table
+}
+
+func deliver(bus *webhookbus.Business, sender *stubSender, sd unitest.SeedData) []unitest.Table {
+	wh := sd.Admins[0].Webhooks[0]
+
+	// latest returns the most recent delivery for the webhook.
+	latest := func(ctx context.Context) (webhookbus.Delivery, error) {
+		dlvs, err := bus.QueryDeliveries(ctx, wh.ID, webhookbus.DeliveryFilter{}, page.MustParse("1", "1"))
+		if err != nil {
+			return webhookbus.Delivery{}, err
+		}
+
+		if len(dlvs) != 1 {
+			return webhookbus.Delivery{}, fmt.Errorf("expected 1 delivery, got %d", len(dlvs))
+		}
+
+		return dlvs[0], nil
+	}
+
+	type state struct {
+		Status   string
+		Attempts int
+	}
+
+	cmpFunc := func(got any, exp any) string {
+		return cmp.Diff(got, exp)
+	}
+
+	now := time.Now().Add(time.Second)
+
+	table := []unitest.Table{
+		{
+			Name:    "retry",
+			ExpResp: state{Status: webhookbus.StatusPending, Attempts: 1},
+			ExcFunc: func(ctx context.Context) any {
+				sender.err = errors.New("connection refused")
+
+				if err := bus.DeliverDue(ctx, now); err != nil {
+					return err
+				}
+
+				dlv, err := latest(ctx)
+				if err != nil {
+					return err
+				}
+
+				if !dlv.NextAttempt.Equal(now.Add(time.Minute).Truncate(time.Microsecond)) {
+					return fmt.Errorf("unexpected next attempt %s", dlv.NextAttempt)
+				}
+
+				return state{Status: dlv.Status, Attempts: dlv.Attempts}
+			},
+			CmpFunc: cmpFunc,
+		},
+		{
+			Name:    "failed",
+			ExpResp: state{Status: webhookbus.StatusFailed, Attempts: 2},
+			ExcFunc: func(ctx context.Context) any {
+				if err := bus.DeliverDue(ctx, now.Add(2*time.Minute)); err != nil {
+					return err
+				}
+
+				dlv, err := latest(ctx)
+				if err != nil {
+					return err
+				}
+
+				return state{Status: dlv.Status, Attempts: dlv.Attempts}
+			},
+			CmpFunc: cmpFunc,
+		},
+		{
+			Name:    "redeliver",
+			ExpResp: state{Status: webhookbus.StatusDelivered, Attempts: 1},
+			ExcFunc: func(ctx context.Context) any {
+				sender.err = nil
+
+				dlv, err := latest(ctx)
+				if err != nil {
+					return err
+				}
+
+				if _, err := bus.Redeliver(ctx, dlv); err != nil {
+					return err
+				}
+
+				if err := bus.DeliverDue(ctx, time.Now().Add(time.Second)); err != nil {
+					return err
+				}
+
+				dlv, err = latest(ctx)
+				if err != nil {
+					return err
+				}
+
+				return state{Status: dlv.Status, Attempts: dlv.Attempts}
+			},
+			CmpFunc: cmpFunc,
+		},
+	}
+
+	return table
+}
@@ -0,0 +1,152 @@
+// Package breach provides support for checking if a password has appeared in
+// a known data breach. Only the first five characters of the SHA-1 hash of a
+// password are sent to the provider, which returns every breached hash that
+// starts with them, so the password itself never leaves the service.
+package breach
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"expvar"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Set of metrics for the checks performed.
+var (
+	rejected = expvar.NewInt("breach_rejected")
+	failures = expvar.NewInt("breach_failures")
+)
+
+// Provider represents a service that supports the k-anonymity range API. The
+// suffixes of the breached hashes that start with the prefix are returned in
+// upper case hex along with the number of times each was seen.
+type Provider interface {
+	Range(ctx context.Context, prefix string) (map[string]int, error)
+}
+
+// Options represent optional parameters.
+type Options struct {
+	cacheTTL time.Duration
+	failOpen bool
+}
+
+// WithCache keeps the results for a prefix for the specified duration so
+// repeated checks don't go back to the provider.
+func WithCache(ttl time.Duration) func(opts *Options) {
+	return func(opts *Options) {
+		opts.cacheTTL = ttl
+	}
+}
+
+// WithFailOpen treats a password as not breached when the provider can't be
+// reached, instead of failing the check.
+func WithFailOpen() func(opts *Options) {
+	return func(opts *Options) {
+		opts.failOpen = true
+	}
+}
+
+// =============================================================================
+
+type entry struct {
+	suffixes map[string]int
+	expires  time.Time
+}
+
+// maxEntries bounds the number of prefixes kept in the cache.
+const maxEntries = 10_000
+
+// Checker checks passwords against a provider.
+type Checker struct {
+	provider Provider
+	cacheTTL time.Duration
+	failOpen bool
+	mu       sync.Mutex
+	cache    map[string]entry
+}
+
+// NewChecker constructs a checker that uses the specified provider.
+func NewChecker(provider Provider, options ...func(opts *Options)) *Checker {
+	var opts Options
+	for _, option := range options {
+		option(&opts)
+	}
+
+	return &Checker{
+		provider: provider,
+		cacheTTL: opts.cacheTTL,
+		failOpen: opts.failOpen,
+		cache:    make(map[string]entry),
+	}
+}
+
+// Breached reports if the password has appeared in a known breach.
+func (c *Checker) Breached(ctx context.Context, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	suffixes, err := c.lookup(ctx, prefix)
+	if err != nil {
+		failures.Add(1)
+
+		if c.failOpen {
+			return false, nil
+		}
+		return false, fmt.Errorf("range: %w", err)
+	}
+
+	if suffixes[suffix] > 0 {
+		rejected.Add(1)
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (c *Checker) lookup(ctx context.Context, prefix string) (map[string]int, error) {
+	now := time.Now()
+
+	if c.cacheTTL > 0 {
+		c.mu.Lock()
+		e, exists := c.cache[prefix]
+		c.mu.Unlock()
+
+		if exists && now.Before(e.expires) {
+			return e.suffixes, nil
+		}
+	}
+
+	suffixes, err := c.provider.Range(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.cacheTTL > 0 {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		if len(c.cache) >= maxEntries {
+			for k, e := range c.cache {
+				if now.After(e.expires) {
+					delete(c.cache, k)
+				}
+			}
+
+			if len(c.cache) >= maxEntries {
+				clear(c.cache)
+			}
+		}
+
+		c.cache[prefix] = entry{
+			suffixes: suffixes,
+			expires:  now.Add(c.cacheTTL),
+		}
+	}
+
+	return suffixes, nil
+}
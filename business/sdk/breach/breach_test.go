@@ -0,0 +1,77 @@
+package breach_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/service/business/sdk/breach"
+)
+
+// The SHA-1 hash of "password" is 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8.
+const (
+	prefix = "5BAA6"
+	suffix = "1E4C9B93F3F0682250B6CF8331B7EE68FD8"
+)
+
+func Test_HIBP(t *testing.T) {
+	var calls atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+
+		if r.URL.Path != "/range/"+prefix {
+			http.NotFound(w, r)
+			return
+		}
+
+		if r.Header.Get("Add-Padding") != "true" {
+			t.Errorf("Should request padding")
+		}
+
+		fmt.Fprintf(w, "%s:3861493\r\n0018A45C4D1DEF81644B54AB7F969B88D65:0\r\n", suffix)
+	}))
+	defer srv.Close()
+
+	checker := breach.NewChecker(breach.NewHIBP(srv.Client(), srv.URL), breach.WithCache(time.Minute))
+
+	breached, err := checker.Breached(context.Background(), "password")
+	if err != nil {
+		t.Fatalf("Should be able to check the password : %s", err)
+	}
+
+	if !breached {
+		t.Fatalf("Should report the password as breached")
+	}
+
+	breached, err = checker.Breached(context.Background(), "password")
+	if err != nil || !breached {
+		t.Fatalf("Should report the password as breached from the cache : %t, %v", breached, err)
+	}
+
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("Should only call the provider once : %d", n)
+	}
+}
+
+type failing struct{}
+
+func (failing) Range(ctx context.Context, prefix string) (map[string]int, error) {
+	return nil, errors.New("unavailable")
+}
+
+func Test_FailOpen(t *testing.T) {
+	if _, err := breach.NewChecker(failing{}).Breached(context.Background(), "password"); err == nil {
+		t.Fatalf("Should fail the check when the provider fails")
+	}
+
+	breached, err := breach.NewChecker(failing{}, breach.WithFailOpen()).Breached(context.Background(), "password")
+	if err != nil || breached {
+		t.Fatalf("Should accept the password when failing open : %t, %v", breached, err)
+	}
+}
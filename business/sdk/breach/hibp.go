@@ -0,0 +1,68 @@
+package breach
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// HIBP provides access to the Have I Been Pwned range API.
+type HIBP struct {
+	client *http.Client
+	url    string
+}
+
+// NewHIBP constructs a provider for the range API at the specified address.
+func NewHIBP(client *http.Client, url string) *HIBP {
+	return &HIBP{
+		client: client,
+		url:    strings.TrimRight(url, "/"),
+	}
+}
+
+// Range implements the Provider interface. Padding is requested so the size
+// of the response doesn't reveal anything about the prefix, and the padded
+// entries, which have a count of zero, are dropped.
+func (h *HIBP) Range(ctx context.Context, prefix string) (map[string]int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.url+"/range/"+prefix, nil)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Add-Padding", "true")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	suffixes := make(map[string]int)
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		suffix, count, found := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !found {
+			continue
+		}
+
+		n, err := strconv.Atoi(count)
+		if err != nil || n == 0 {
+			continue
+		}
+
+		suffixes[strings.ToUpper(suffix)] = n
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	return suffixes, nil
+}
@@ -0,0 +1,137 @@
+// Package buserr provides the error type the business domains use for the
+// errors they return, so a caller can tell what kind of failure it was from
+// its code instead of matching on the message.
+package buserr
+
+import (
+	"errors"
+	"strings"
+)
+
+// Code represents the kind of failure a business error is. The codes are
+// independent of any transport, the app layer maps them to the status of
+// the protocol it serves.
+type Code string
+
+// Set of codes a business error can have.
+const (
+	// NotFound means the entity the call refers to doesn't exist.
+	NotFound Code = "NOT_FOUND"
+
+	// Conflict means the call clashes with the current state of an entity,
+	// like a value that must be unique or a change made by someone else.
+	Conflict Code = "CONFLICT"
+
+	// InvalidArgument means a value given to the call isn't valid whatever
+	// the state of the system.
+	InvalidArgument Code = "INVALID_ARGUMENT"
+
+	// FailedPrecondition means the system isn't in the state the call
+	// requires, like an entity that has expired or is disabled.
+	FailedPrecondition Code = "FAILED_PRECONDITION"
+
+	// Unauthenticated means the credentials given to the call aren't valid.
+	Unauthenticated Code = "UNAUTHENTICATED"
+
+	// PermissionDenied means the caller isn't allowed to make the call.
+	PermissionDenied Code = "PERMISSION_DENIED"
+
+	// ResourceExhausted means the caller has used up a limit and must wait.
+	ResourceExhausted Code = "RESOURCE_EXHAUSTED"
+
+	// DeadlineExceeded means the call ran out of time before it finished,
+	// like a query cancelled for running too long.
+	DeadlineExceeded Code = "DEADLINE_EXCEEDED"
+
+	// Unimplemented means the call isn't supported by this deployment.
+	Unimplemented Code = "UNIMPLEMENTED"
+
+	// Internal means the call failed for a reason the caller can't fix. It
+	// is the code of any error that isn't a business error.
+	Internal Code = "INTERNAL"
+)
+
+// FieldViolation describes why the value of a field isn't valid.
+type FieldViolation struct {
+	Field   string
+	Message string
+}
+
+// Error represents an error returned by a business domain. Domains declare
+// them as sentinel values with New and return them as is or wrapped, so
+// errors.Is works as it does for any sentinel.
+type Error struct {
+	Code    Code
+	Message string
+	Fields  []FieldViolation
+	base    *Error
+}
+
+// New constructs a business error with the code and message.
+func New(code Code, message string) *Error {
+	return &Error{
+		Code:    code,
+		Message: message,
+	}
+}
+
+// Error implements the error interface. The field violations follow the
+// message when there are any.
+func (e *Error) Error() string {
+	if len(e.Fields) == 0 {
+		return e.Message
+	}
+
+	msgs := make([]string, len(e.Fields))
+	for i, fv := range e.Fields {
+		msgs[i] = fv.Field + ": " + fv.Message
+	}
+
+	return e.Message + ": " + strings.Join(msgs, ", ")
+}
+
+// WithFields returns a copy of the error that carries the field violations.
+// The copy still matches the original with errors.Is.
+func (e *Error) WithFields(fields ...FieldViolation) *Error {
+	base := e
+	if e.base != nil {
+		base = e.base
+	}
+
+	return &Error{
+		Code:    e.Code,
+		Message: e.Message,
+		Fields:  append(append([]FieldViolation{}, e.Fields...), fields...),
+		base:    base,
+	}
+}
+
+// Is allows errors.Is to match a copy made by WithFields against the error
+// it was made from.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && e.base != nil && e.base == t
+}
+
+// =============================================================================
+
+// As finds the first business error in the error's chain.
+func As(err error) (*Error, bool) {
+	var be *Error
+	if !errors.As(err, &be) {
+		return nil, false
+	}
+
+	return be, true
+}
+
+// CodeOf returns the code of the first business error in the error's
+// chain, or Internal when there isn't one.
+func CodeOf(err error) Code {
+	be, ok := As(err)
+	if !ok {
+		return Internal
+	}
+
+	return be.Code
+}
@@ -0,0 +1,69 @@
+package buserr_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ardanlabs/service/business/sdk/buserr"
+	"github.com/google/go-cmp/cmp"
+)
+
+var errNotFound = buserr.New(buserr.NotFound, "thing not found")
+
+func Test_Error(t *testing.T) {
+	err := fmt.Errorf("query: %w", errNotFound)
+
+	if !errors.Is(err, errNotFound) {
+		t.Fatalf("Should match a wrapped sentinel")
+	}
+
+	if code := buserr.CodeOf(err); code != buserr.NotFound {
+		t.Fatalf("Should find the code of a wrapped error : %s", code)
+	}
+
+	if code := buserr.CodeOf(errors.New("boom")); code != buserr.Internal {
+		t.Fatalf("Should treat other errors as internal : %s", code)
+	}
+
+	if err.Error() != "query: thing not found" {
+		t.Fatalf("Should use the message as the error : %s", err)
+	}
+}
+
+func Test_WithFields(t *testing.T) {
+	errInvalid := buserr.New(buserr.InvalidArgument, "thing is not valid")
+
+	fe := errInvalid.WithFields(buserr.FieldViolation{Field: "name", Message: "is required"})
+	fe = fe.WithFields(buserr.FieldViolation{Field: "cost", Message: "must be positive"})
+
+	if !errors.Is(fmt.Errorf("create: %w", fe), errInvalid) {
+		t.Fatalf("Should match the error the copy was made from")
+	}
+
+	if errors.Is(fe, errNotFound) {
+		t.Fatalf("Should not match a different error")
+	}
+
+	if len(errInvalid.Fields) != 0 {
+		t.Fatalf("Should not change the sentinel : %v", errInvalid.Fields)
+	}
+
+	be, ok := buserr.As(fmt.Errorf("create: %w", fe))
+	if !ok {
+		t.Fatalf("Should find the business error")
+	}
+
+	exp := []buserr.FieldViolation{
+		{Field: "name", Message: "is required"},
+		{Field: "cost", Message: "must be positive"},
+	}
+
+	if diff := cmp.Diff(be.Fields, exp); diff != "" {
+		t.Fatalf("Should carry the field violations : %s", diff)
+	}
+
+	if msg := fe.Error(); msg != "thing is not valid: name: is required, cost: must be positive" {
+		t.Fatalf("Should include the field violations in the error : %s", msg)
+	}
+}
@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/ardanlabs/service/foundation/logger"
+	"golang.org/x/sync/singleflight"
+)
+
+// Aside holds values of one type in a cache for the cache-aside pattern.
+// Values are stored as JSON under the prefix. A cache that fails is logged
+// and treated as a miss so the source of truth is still used.
+type Aside[T any] struct {
+	log    *logger.Logger
+	cache  Cache
+	prefix string
+	ttl    time.Duration
+	group  singleflight.Group
+}
+
+// NewAside constructs an Aside for values held for the ttl.
+func NewAside[T any](log *logger.Logger, cache Cache, prefix string, ttl time.Duration) *Aside[T] {
+	return &Aside[T]{
+		log:    log,
+		cache:  cache,
+		prefix: prefix,
+		ttl:    ttl,
+	}
+}
+
+// Get returns the value held for the key and reports whether there was one.
+func (a *Aside[T]) Get(ctx context.Context, key string) (T, bool) {
+	var v T
+
+	data, err := a.cache.Get(ctx, a.prefix+key)
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			a.log.Error(ctx, "cache: get", "key", a.prefix+key, "ERROR", err)
+		}
+		return v, false
+	}
+
+	if err := json.Unmarshal(data, &v); err != nil {
+		a.log.Error(ctx, "cache: decode", "key", a.prefix+key, "ERROR", err)
+		return v, false
+	}
+
+	return v, true
+}
+
+// Set holds the value for the key.
+func (a *Aside[T]) Set(ctx context.Context, key string, v T) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		a.log.Error(ctx, "cache: encode", "key", a.prefix+key, "ERROR", err)
+		return
+	}
+
+	if err := a.cache.Set(ctx, a.prefix+key, data, a.ttl); err != nil {
+		a.log.Error(ctx, "cache: set", "key", a.prefix+key, "ERROR", err)
+	}
+}
+
+// Delete removes the values held for the keys.
+func (a *Aside[T]) Delete(ctx context.Context, keys ...string) {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = a.prefix + key
+	}
+
+	if err := a.cache.Delete(ctx, prefixed...); err != nil {
+		a.log.Error(ctx, "cache: delete", "keys", prefixed, "ERROR", err)
+	}
+}
+
+// Fetch returns the value held for the key, calling fn for it and holding
+// the result when there isn't one. Concurrent fetches that miss on the same
+// key share a single call to fn. It reports whether the value came from
+// the cache.
+func (a *Aside[T]) Fetch(ctx context.Context, key string, fn func(ctx context.Context) (T, error)) (T, bool, error) {
+	if v, exists := a.Get(ctx, key); exists {
+		return v, true, nil
+	}
+
+	res, err, _ := a.group.Do(key, func() (any, error) {
+		v, err := fn(ctx)
+		if err != nil {
+			return v, err
+		}
+
+		a.Set(ctx, key, v)
+
+		return v, nil
+	})
+
+	v, _ := res.(T)
+
+	return v, false, err
+}
@@ -0,0 +1,26 @@
+// Package cache provides a shared caching backend for the domain caching
+// plugins.
+//
+// A cache holds raw bytes by key and is either kept in memory, for a single
+// instance, or in Redis so every instance sees the same entries. Aside
+// layers typed values on top of a cache for the cache-aside pattern, where
+// reads that miss go to the source of truth and concurrent misses for the
+// same key share one trip to it.
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned when a key isn't in the cache.
+var ErrNotFound = errors.New("not found")
+
+// Cache represents the behavior of a caching backend. A ttl of zero or less
+// keeps the entry until it's deleted or evicted.
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
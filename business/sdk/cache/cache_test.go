@@ -0,0 +1,310 @@
+package cache_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/service/business/sdk/cache"
+	"github.com/ardanlabs/service/foundation/logger"
+)
+
+func Test_Cache(t *testing.T) {
+	rds, err := cache.NewRedis(cache.RedisConfig{Addr: fakeRedis(t), Password: "secret"})
+	if err != nil {
+		t.Fatalf("Should be able to create a redis cache : %s", err)
+	}
+	t.Cleanup(func() { rds.Close() })
+
+	backends := []struct {
+		name  string
+		cache cache.Cache
+	}{
+		{"memory", cache.NewMemory(0)},
+		{"redis", rds},
+	}
+
+	for _, be := range backends {
+		t.Run(be.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			if _, err := be.cache.Get(ctx, "missing"); !errors.Is(err, cache.ErrNotFound) {
+				t.Fatalf("expected %v for a missing key, got %v", cache.ErrNotFound, err)
+			}
+
+			if err := be.cache.Set(ctx, "key", []byte("value\r\nwith a break"), time.Minute); err != nil {
+				t.Fatalf("Should be able to set : %s", err)
+			}
+
+			got, err := be.cache.Get(ctx, "key")
+			if err != nil {
+				t.Fatalf("Should be able to get : %s", err)
+			}
+
+			if string(got) != "value\r\nwith a break" {
+				t.Fatalf("expected %q, got %q", "value\r\nwith a break", got)
+			}
+
+			if err := be.cache.Set(ctx, "expired", []byte("value"), time.Millisecond); err != nil {
+				t.Fatalf("Should be able to set : %s", err)
+			}
+
+			time.Sleep(5 * time.Millisecond)
+
+			if _, err := be.cache.Get(ctx, "expired"); !errors.Is(err, cache.ErrNotFound) {
+				t.Fatalf("expected %v for an expired key, got %v", cache.ErrNotFound, err)
+			}
+
+			if err := be.cache.Delete(ctx, "key", "missing"); err != nil {
+				t.Fatalf("Should be able to delete : %s", err)
+			}
+
+			if _, err := be.cache.Get(ctx, "key"); !errors.Is(err, cache.ErrNotFound) {
+				t.Fatalf("expected %v for a deleted key, got %v", cache.ErrNotFound, err)
+			}
+		})
+	}
+}
+
+func Test_MemoryCapacity(t *testing.T) {
+	ctx := context.Background()
+	mem := cache.NewMemory(2)
+
+	for _, key := range []string{"a", "b", "c"} {
+		if err := mem.Set(ctx, key, []byte(key), 0); err != nil {
+			t.Fatalf("Should be able to set : %s", err)
+		}
+	}
+
+	var held int
+	for _, key := range []string{"a", "b", "c"} {
+		if _, err := mem.Get(ctx, key); err == nil {
+			held++
+		}
+	}
+
+	if held != 2 {
+		t.Fatalf("expected the cache to hold 2 entries, got %d", held)
+	}
+
+	if _, err := mem.Get(ctx, "c"); err != nil {
+		t.Fatalf("expected the last key set to be held: %s", err)
+	}
+}
+
+func Test_Aside(t *testing.T) {
+	type value struct {
+		Name string
+		N    int
+	}
+
+	var buf bytes.Buffer
+	log := logger.New(&buf, logger.LevelInfo, "TEST", func(context.Context) string { return "" })
+
+	aside := cache.NewAside[value](log, cache.NewMemory(0), "value:", time.Minute)
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+
+	fn := func(ctx context.Context) (value, error) {
+		calls.Add(1)
+		<-release
+		return value{Name: "bill", N: 42}, nil
+	}
+
+	const n = 10
+
+	var wg sync.WaitGroup
+	wg.Add(n)
+
+	results := make([]value, n)
+	for i := range n {
+		go func() {
+			defer wg.Done()
+
+			v, _, err := aside.Fetch(context.Background(), "key", fn)
+			if err != nil {
+				t.Errorf("Should be able to fetch : %s", err)
+			}
+			results[i] = v
+		}()
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("expected concurrent misses to share one call, got %d", got)
+	}
+
+	for _, v := range results {
+		if v != (value{Name: "bill", N: 42}) {
+			t.Fatalf("expected every fetch to get the value, got %+v", v)
+		}
+	}
+
+	v, cached, err := aside.Fetch(context.Background(), "key", fn)
+	if err != nil {
+		t.Fatalf("Should be able to fetch : %s", err)
+	}
+
+	if !cached || v.N != 42 {
+		t.Fatalf("expected the value to come from the cache, got %+v cached %v", v, cached)
+	}
+
+	aside.Delete(context.Background(), "key")
+
+	if _, exists := aside.Get(context.Background(), "key"); exists {
+		t.Fatal("expected the value to be deleted")
+	}
+
+	if _, _, err := aside.Fetch(context.Background(), "error", func(ctx context.Context) (value, error) {
+		return value{}, errors.New("source failed")
+	}); err == nil {
+		t.Fatal("expected the source error to be returned")
+	}
+
+	if _, exists := aside.Get(context.Background(), "error"); exists {
+		t.Fatal("expected a failed fetch not to be cached")
+	}
+}
+
+// =============================================================================
+
+// fakeRedis starts a server that speaks enough of the Redis protocol for
+// the cache and returns its address.
+func fakeRedis(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Should be able to listen : %s", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	type entry struct {
+		value   string
+		expires time.Time
+	}
+
+	var mu sync.Mutex
+	data := make(map[string]entry)
+
+	handle := func(conn net.Conn) {
+		defer conn.Close()
+
+		rd := bufio.NewReader(conn)
+		authed := false
+
+		for {
+			args, err := readCommand(rd)
+			if err != nil {
+				return
+			}
+
+			mu.Lock()
+
+			switch cmd := strings.ToUpper(args[0]); {
+			case cmd == "AUTH":
+				authed = args[len(args)-1] == "secret"
+				if authed {
+					io.WriteString(conn, "+OK\r\n")
+				} else {
+					io.WriteString(conn, "-WRONGPASS invalid password\r\n")
+				}
+
+			case !authed:
+				io.WriteString(conn, "-NOAUTH Authentication required\r\n")
+
+			case cmd == "PING":
+				io.WriteString(conn, "+PONG\r\n")
+
+			case cmd == "GET":
+				e, exists := data[args[1]]
+				if !exists || (!e.expires.IsZero() && time.Now().After(e.expires)) {
+					io.WriteString(conn, "$-1\r\n")
+					break
+				}
+				fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(e.value), e.value)
+
+			case cmd == "SET":
+				e := entry{value: args[2]}
+				if len(args) == 5 && strings.ToUpper(args[3]) == "PX" {
+					ms, _ := strconv.Atoi(args[4])
+					e.expires = time.Now().Add(time.Duration(ms) * time.Millisecond)
+				}
+				data[args[1]] = e
+				io.WriteString(conn, "+OK\r\n")
+
+			case cmd == "DEL":
+				var n int
+				for _, key := range args[1:] {
+					if _, exists := data[key]; exists {
+						delete(data, key)
+						n++
+					}
+				}
+				fmt.Fprintf(conn, ":%d\r\n", n)
+
+			default:
+				fmt.Fprintf(conn, "-ERR unknown command '%s'\r\n", cmd)
+			}
+
+			mu.Unlock()
+		}
+	}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go handle(conn)
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+func readCommand(rd *bufio.Reader) ([]string, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("malformed command %q", line)
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		line, err := rd.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, fmt.Errorf("malformed argument %q", line)
+		}
+
+		arg := make([]byte, size+2)
+		if _, err := io.ReadFull(rd, arg); err != nil {
+			return nil, err
+		}
+		args[i] = string(arg[:size])
+	}
+
+	return args, nil
+}
@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ardanlabs/service/foundation/clock"
+)
+
+type entry struct {
+	value   []byte
+	expires time.Time
+}
+
+func (e entry) expired(now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+// Memory is a cache kept in the memory of the instance. It's the fallback
+// when there's no Redis to share entries between instances.
+type Memory struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[string]entry
+}
+
+// NewMemory constructs a memory cache that holds up to capacity entries.
+// When it's full the expired entries are evicted first, then arbitrary ones.
+// A capacity of zero or less doesn't limit the number of entries.
+func NewMemory(capacity int) *Memory {
+	return &Memory{
+		capacity: capacity,
+		entries:  make(map[string]entry),
+	}
+}
+
+// Get returns the value held for the key.
+func (m *Memory) Get(ctx context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, exists := m.entries[key]
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	if e.expired(clock.Now()) {
+		delete(m.entries, key)
+		return nil, ErrNotFound
+	}
+
+	return slices.Clone(e.value), nil
+}
+
+// Set holds the value for the key for the ttl.
+func (m *Memory) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := clock.Now()
+
+	if _, exists := m.entries[key]; !exists {
+		m.evict(now)
+	}
+
+	e := entry{
+		value: slices.Clone(value),
+	}
+
+	if ttl > 0 {
+		e.expires = now.Add(ttl)
+	}
+
+	m.entries[key] = e
+
+	return nil
+}
+
+// Delete removes the keys from the cache.
+func (m *Memory) Delete(ctx context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range keys {
+		delete(m.entries, key)
+	}
+
+	return nil
+}
+
+// evict makes room for one more entry when the cache is full.
+func (m *Memory) evict(now time.Time) {
+	if m.capacity <= 0 || len(m.entries) < m.capacity {
+		return
+	}
+
+	for key, e := range m.entries {
+		if e.expired(now) {
+			delete(m.entries, key)
+		}
+	}
+
+	for key := range m.entries {
+		if len(m.entries) < m.capacity {
+			return
+		}
+
+		delete(m.entries, key)
+	}
+}
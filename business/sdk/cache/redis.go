@@ -0,0 +1,289 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// RedisConfig represents the Redis server the cache is kept in.
+type RedisConfig struct {
+	Addr     string
+	Password string
+	DB       int
+
+	// PoolSize is the most idle connections kept for reuse.
+	PoolSize int
+
+	// Timeout limits a call to Redis when the context has no deadline.
+	Timeout time.Duration
+}
+
+// Redis is a cache kept in Redis, so every instance of a service shares the
+// same entries. Connections are dialed as they're needed and kept in a pool
+// for reuse.
+type Redis struct {
+	cfg  RedisConfig
+	pool chan *redisConn
+}
+
+// NewRedis constructs a cache for the Redis server. The server isn't
+// contacted until the first call.
+func NewRedis(cfg RedisConfig) (*Redis, error) {
+	if cfg.Addr == "" {
+		return nil, errors.New("addr is required")
+	}
+
+	if cfg.PoolSize <= 0 {
+		cfg.PoolSize = 10
+	}
+
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = time.Second
+	}
+
+	r := Redis{
+		cfg:  cfg,
+		pool: make(chan *redisConn, cfg.PoolSize),
+	}
+
+	return &r, nil
+}
+
+// Get returns the value held for the key.
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, error) {
+	reply, err := r.do(ctx, "GET", key)
+	if err != nil {
+		return nil, fmt.Errorf("get: %w", err)
+	}
+
+	if reply == nil {
+		return nil, ErrNotFound
+	}
+
+	value, ok := reply.(string)
+	if !ok {
+		return nil, fmt.Errorf("get: unexpected reply %v", reply)
+	}
+
+	return []byte(value), nil
+}
+
+// Set holds the value for the key for the ttl.
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", key, string(value)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+
+	if _, err := r.do(ctx, args...); err != nil {
+		return fmt.Errorf("set: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes the keys from the cache.
+func (r *Redis) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	if _, err := r.do(ctx, append([]string{"DEL"}, keys...)...); err != nil {
+		return fmt.Errorf("del: %w", err)
+	}
+
+	return nil
+}
+
+// Check returns nil if the server can be reached.
+func (r *Redis) Check(ctx context.Context) error {
+	if _, err := r.do(ctx, "PING"); err != nil {
+		return fmt.Errorf("ping: %w", err)
+	}
+
+	return nil
+}
+
+// Close closes the idle connections.
+func (r *Redis) Close() error {
+	for {
+		select {
+		case rc := <-r.pool:
+			rc.conn.Close()
+
+		default:
+			return nil
+		}
+	}
+}
+
+// =============================================================================
+
+type redisConn struct {
+	conn net.Conn
+	rw   *bufio.ReadWriter
+}
+
+// do sends the command on a pooled connection and reads the reply. The
+// connection is dropped after any failure other than an error reply so the
+// next call starts clean.
+func (r *Redis) do(ctx context.Context, args ...string) (any, error) {
+	rc, err := r.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(r.cfg.Timeout)
+	}
+
+	if err := rc.conn.SetDeadline(deadline); err != nil {
+		rc.conn.Close()
+		return nil, fmt.Errorf("set deadline: %w", err)
+	}
+
+	reply, err := rc.roundTrip(args...)
+	if err != nil {
+		var rerr redisError
+		if !errors.As(err, &rerr) {
+			rc.conn.Close()
+			return nil, err
+		}
+	}
+
+	r.put(rc)
+
+	return reply, err
+}
+
+// get takes an idle connection from the pool or dials a new one.
+func (r *Redis) get(ctx context.Context) (*redisConn, error) {
+	select {
+	case rc := <-r.pool:
+		return rc, nil
+
+	default:
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.Timeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", r.cfg.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("dial: %w", err)
+	}
+
+	rc := redisConn{
+		conn: conn,
+		rw:   bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn)),
+	}
+
+	if err := conn.SetDeadline(time.Now().Add(r.cfg.Timeout)); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("set deadline: %w", err)
+	}
+
+	if r.cfg.Password != "" {
+		if _, err := rc.roundTrip("AUTH", r.cfg.Password); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("auth: %w", err)
+		}
+	}
+
+	if r.cfg.DB != 0 {
+		if _, err := rc.roundTrip("SELECT", strconv.Itoa(r.cfg.DB)); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("select: %w", err)
+		}
+	}
+
+	return &rc, nil
+}
+
+// put returns the connection to the pool, closing it when the pool is full.
+func (r *Redis) put(rc *redisConn) {
+	select {
+	case r.pool <- rc:
+
+	default:
+		rc.conn.Close()
+	}
+}
+
+// roundTrip writes the command in the Redis protocol and reads the reply.
+func (rc *redisConn) roundTrip(args ...string) (any, error) {
+	fmt.Fprintf(rc.rw, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(rc.rw, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+
+	if err := rc.rw.Flush(); err != nil {
+		return nil, fmt.Errorf("write: %w", err)
+	}
+
+	return readReply(rc.rw.Reader)
+}
+
+// redisError is an error reply from the server. The connection is still
+// usable after one.
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// readReply reads a single reply. Only the reply types the cache gets back
+// are supported, a missing key comes back as nil.
+func readReply(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("read: malformed reply %q", line)
+	}
+
+	kind, value := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return value, nil
+
+	case '-':
+		return nil, redisError(value)
+
+	case ':':
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("read: integer: %w", err)
+		}
+		return n, nil
+
+	case '$':
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("read: bulk length: %w", err)
+		}
+
+		if n < 0 {
+			return nil, nil
+		}
+
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, fmt.Errorf("read: bulk: %w", err)
+		}
+		return string(buf[:n]), nil
+	}
+
+	return nil, fmt.Errorf("read: unsupported reply type %q", kind)
+}
@@ -0,0 +1,278 @@
+package cache_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/service/business/sdk/cache"
+	"github.com/ardanlabs/service/foundation/docker"
+)
+
+// Test_Redis runs the client against a real Redis server, where
+// Test_Cache only covers what the fake server understands.
+func Test_Redis(t *testing.T) {
+	t.Parallel()
+
+	const password = "secret"
+
+	c, err := docker.StartContainer("redis:7.4-alpine", "servicetest-redis", "6379", nil, []string{"redis-server", "--requirepass", password})
+	if err != nil {
+		t.Fatalf("Starting redis: %v", err)
+	}
+
+	t.Logf("Name    : %s\n", c.Name)
+	t.Logf("HostPort: %s\n", c.HostPort)
+
+	rds := newRedis(t, cache.RedisConfig{Addr: c.HostPort, Password: password, PoolSize: 2})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for {
+		err := rds.Check(ctx)
+		if err == nil {
+			break
+		}
+
+		if ctx.Err() != nil {
+			t.Logf("Logs for %s\n%s:", c.Name, docker.DumpContainerLogs(c.Name))
+			t.Fatalf("Pinging redis: %v", err)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	prefix := fmt.Sprintf("test_redis_%d:", time.Now().UnixNano())
+
+	t.Run("values", func(t *testing.T) {
+		values := []struct {
+			name  string
+			value []byte
+		}{
+			{name: "empty", value: []byte{}},
+			{name: "crlf", value: []byte("value\r\nwith a break")},
+			{name: "binary", value: []byte{0, 1, 2, '\r', '\n', 0xff, '$', '-', '*'}},
+			{name: "large", value: bytes.Repeat([]byte("0123456789"), 100_000)},
+			{name: "unicode", value: []byte("gophers 🐹")},
+		}
+
+		for _, tt := range values {
+			t.Run(tt.name, func(t *testing.T) {
+				key := prefix + "value:" + tt.name
+
+				if err := rds.Set(ctx, key, tt.value, time.Minute); err != nil {
+					t.Fatalf("Should be able to set : %s", err)
+				}
+
+				got, err := rds.Get(ctx, key)
+				if err != nil {
+					t.Fatalf("Should be able to get : %s", err)
+				}
+
+				if !bytes.Equal(got, tt.value) {
+					t.Fatalf("Should get back the value set : got %d bytes, exp %d bytes", len(got), len(tt.value))
+				}
+			})
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		if _, err := rds.Get(ctx, prefix+"missing"); !errors.Is(err, cache.ErrNotFound) {
+			t.Fatalf("Should return ErrNotFound for a missing key : got %v", err)
+		}
+	})
+
+	t.Run("ttl", func(t *testing.T) {
+		if err := rds.Set(ctx, prefix+"ttl", []byte("value"), 50*time.Millisecond); err != nil {
+			t.Fatalf("Should be able to set : %s", err)
+		}
+
+		if err := rds.Set(ctx, prefix+"nottl", []byte("value"), 0); err != nil {
+			t.Fatalf("Should be able to set without a ttl : %s", err)
+		}
+
+		time.Sleep(200 * time.Millisecond)
+
+		if _, err := rds.Get(ctx, prefix+"ttl"); !errors.Is(err, cache.ErrNotFound) {
+			t.Fatalf("Should expire the key after the ttl : got %v", err)
+		}
+
+		if _, err := rds.Get(ctx, prefix+"nottl"); err != nil {
+			t.Fatalf("Should keep a key without a ttl : %s", err)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		keys := []string{prefix + "delete:1", prefix + "delete:2", prefix + "delete:missing"}
+
+		for _, key := range keys[:2] {
+			if err := rds.Set(ctx, key, []byte("value"), time.Minute); err != nil {
+				t.Fatalf("Should be able to set : %s", err)
+			}
+		}
+
+		if err := rds.Delete(ctx, keys...); err != nil {
+			t.Fatalf("Should be able to delete : %s", err)
+		}
+
+		for _, key := range keys {
+			if _, err := rds.Get(ctx, key); !errors.Is(err, cache.ErrNotFound) {
+				t.Fatalf("Should delete %s : got %v", key, err)
+			}
+		}
+
+		if err := rds.Delete(ctx); err != nil {
+			t.Fatalf("Should accept an empty delete : %s", err)
+		}
+	})
+
+	t.Run("errorreply", func(t *testing.T) {
+		key := prefix + "list"
+
+		// The cache can only set strings, so the list is pushed on a
+		// connection of its own.
+		if reply := command(t, c.HostPort, []string{"AUTH", password}, []string{"RPUSH", key, "a"}); reply != ":1\r\n" {
+			t.Fatalf("Should be able to push a list : got %q", reply)
+		}
+
+		_, err := rds.Get(ctx, key)
+		if err == nil || !strings.Contains(err.Error(), "WRONGTYPE") {
+			t.Fatalf("Should return the error reply : got %v", err)
+		}
+
+		// The connection that got the error reply is still in sync.
+		for range 3 {
+			if err := rds.Check(ctx); err != nil {
+				t.Fatalf("Should keep using the pool after an error reply : %s", err)
+			}
+		}
+	})
+
+	t.Run("db", func(t *testing.T) {
+		db1 := newRedis(t, cache.RedisConfig{Addr: c.HostPort, Password: password, DB: 1})
+
+		if err := db1.Set(ctx, prefix+"db", []byte("value"), time.Minute); err != nil {
+			t.Fatalf("Should be able to set in db 1 : %s", err)
+		}
+
+		if _, err := db1.Get(ctx, prefix+"db"); err != nil {
+			t.Fatalf("Should be able to get from db 1 : %s", err)
+		}
+
+		if _, err := rds.Get(ctx, prefix+"db"); !errors.Is(err, cache.ErrNotFound) {
+			t.Fatalf("Should not see the keys of db 1 from db 0 : got %v", err)
+		}
+	})
+
+	t.Run("auth", func(t *testing.T) {
+		wrong := newRedis(t, cache.RedisConfig{Addr: c.HostPort, Password: "wrong"})
+		if err := wrong.Check(ctx); err == nil {
+			t.Fatalf("Should fail with the wrong password")
+		}
+
+		none := newRedis(t, cache.RedisConfig{Addr: c.HostPort})
+		if err := none.Check(ctx); err == nil {
+			t.Fatalf("Should fail without a password")
+		}
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		const n = 50
+
+		var wg sync.WaitGroup
+		errs := make(chan error, n)
+
+		for i := range n {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				key := fmt.Sprintf("%sconcurrent:%d", prefix, i)
+				value := []byte(strings.Repeat(fmt.Sprint(i), 100))
+
+				if err := rds.Set(ctx, key, value, time.Minute); err != nil {
+					errs <- err
+					return
+				}
+
+				got, err := rds.Get(ctx, key)
+				if err != nil {
+					errs <- err
+					return
+				}
+
+				if !bytes.Equal(got, value) {
+					errs <- fmt.Errorf("key %s: got %q", key, got)
+				}
+			}()
+		}
+
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			t.Fatalf("Should read back every value through the pool : %s", err)
+		}
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		ctx, cancel := context.WithDeadline(ctx, time.Now().Add(-time.Second))
+		defer cancel()
+
+		if _, err := rds.Get(ctx, prefix+"missing"); err == nil || errors.Is(err, cache.ErrNotFound) {
+			t.Fatalf("Should fail a call past the deadline : got %v", err)
+		}
+
+		// The connection that timed out was dropped, not put back.
+		if err := rds.Check(context.Background()); err != nil {
+			t.Fatalf("Should recover after a timed out call : %s", err)
+		}
+	})
+}
+
+func newRedis(t *testing.T, cfg cache.RedisConfig) *cache.Redis {
+	rds, err := cache.NewRedis(cfg)
+	if err != nil {
+		t.Fatalf("Should be able to create a redis cache : %s", err)
+	}
+	t.Cleanup(func() { rds.Close() })
+
+	return rds
+}
+
+// command sends the commands on a new connection and returns the first line
+// of the last reply.
+func command(t *testing.T, addr string, cmds ...[]string) string {
+	conn, err := net.DialTimeout("tcp", addr, time.Second)
+	if err != nil {
+		t.Fatalf("Should be able to dial redis : %s", err)
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(time.Second))
+
+	rd := bufio.NewReader(conn)
+
+	var reply string
+	for _, cmd := range cmds {
+		fmt.Fprintf(conn, "*%d\r\n", len(cmd))
+		for _, arg := range cmd {
+			fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(arg), arg)
+		}
+
+		reply, err = rd.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Should be able to read the reply : %s", err)
+		}
+	}
+
+	return reply
+}
@@ -0,0 +1,199 @@
+// Package classify provides support for tagging model fields with a data
+// classification and for keeping classified values inside the service
+// boundary.
+package classify
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Tag is the struct tag used to classify a field. Fields without the tag
+// are considered public.
+//
+//	PasswordHash []byte `db:"password_hash" class:"restricted"`
+const Tag = "class"
+
+// Redacted is the value that replaces a field that can't be shown.
+const Redacted = "[REDACTED]"
+
+// The set of classification levels from the least to the most sensitive.
+var (
+	Public       = newLevel("public", 0)
+	Internal     = newLevel("internal", 1)
+	Confidential = newLevel("confidential", 2)
+	Restricted   = newLevel("restricted", 3)
+)
+
+// =============================================================================
+
+// Set of known levels.
+var levels = make(map[string]Level)
+
+// Level represents the sensitivity of a field.
+type Level struct {
+	value string
+	rank  int
+}
+
+func newLevel(level string, rank int) Level {
+	l := Level{level, rank}
+	levels[level] = l
+	return l
+}
+
+// String returns the name of the level.
+func (l Level) String() string {
+	return l.value
+}
+
+// Equal provides support for the go-cmp package and testing.
+func (l Level) Equal(l2 Level) bool {
+	return l.value == l2.value
+}
+
+// MarshalText provides support for logging and any marshal needs.
+func (l Level) MarshalText() ([]byte, error) {
+	return []byte(l.value), nil
+}
+
+// Exceeds reports whether the level is more sensitive than the ceiling.
+func (l Level) Exceeds(ceiling Level) bool {
+	return l.rank > ceiling.rank
+}
+
+// =============================================================================
+
+// Parse parses the string value and returns a level if one exists.
+func Parse(value string) (Level, error) {
+	l, exists := levels[strings.ToLower(value)]
+	if !exists {
+		return Level{}, fmt.Errorf("invalid level %q", value)
+	}
+
+	return l, nil
+}
+
+// MustParse parses the string value and returns a level if one exists. If
+// an error occurs the function panics.
+func MustParse(value string) Level {
+	l, err := Parse(value)
+	if err != nil {
+		panic(err)
+	}
+
+	return l
+}
+
+// =============================================================================
+
+// Redact returns the fields of the struct keyed by the name found in the
+// specified key tag, like db or json. The value of any field more sensitive
+// than the ceiling is replaced with Redacted. The boolean is false when the
+// value isn't a struct.
+func Redact(v any, keyTag string, ceiling Level) (map[string]any, bool) {
+	return fields(v, keyTag, ceiling, true)
+}
+
+// Filter returns the fields of the struct keyed by the name found in the
+// specified key tag, like db or json. Any field more sensitive than the
+// ceiling is left out. The boolean is false when the value isn't a struct.
+func Filter(v any, keyTag string, ceiling Level) (map[string]any, bool) {
+	return fields(v, keyTag, ceiling, false)
+}
+
+func fields(v any, keyTag string, ceiling Level, redact bool) (map[string]any, bool) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, false
+		}
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return nil, false
+	}
+
+	m := make(map[string]any)
+	walk(rv, keyTag, ceiling, redact, m)
+
+	return m, true
+}
+
+func walk(rv reflect.Value, keyTag string, ceiling Level, redact bool, m map[string]any) {
+	rt := rv.Type()
+
+	for i := range rt.NumField() {
+		sf := rt.Field(i)
+
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			walk(rv.Field(i), keyTag, ceiling, redact, m)
+			continue
+		}
+
+		if !sf.IsExported() {
+			continue
+		}
+
+		key := strings.Split(sf.Tag.Get(keyTag), ",")[0]
+		switch key {
+		case "-":
+			continue
+		case "":
+			key = sf.Name
+		}
+
+		// A tag that can't be parsed is treated as the most sensitive level
+		// so a typo never leaks data.
+		level := Public
+		if tag := sf.Tag.Get(Tag); tag != "" {
+			var err error
+			if level, err = Parse(tag); err != nil {
+				level = Restricted
+			}
+		}
+
+		if level.Exceeds(ceiling) {
+			if redact {
+				m[key] = Redacted
+			}
+			continue
+		}
+
+		m[key] = rv.Field(i).Interface()
+	}
+}
+
+// =============================================================================
+
+// Policy decides how sensitive the data sent to each destination can be.
+// Restricted data never leaves the service unless the destination has been
+// explicitly allowed to receive it.
+type Policy struct {
+	destinations map[string]Level
+}
+
+// NewPolicy constructs a policy with the ceiling allowed for each
+// destination.
+func NewPolicy(destinations map[string]Level) Policy {
+	return Policy{
+		destinations: destinations,
+	}
+}
+
+// Ceiling returns the most sensitive level the destination can receive.
+func (p Policy) Ceiling(destination string) Level {
+	if l, exists := p.destinations[destination]; exists {
+		return l
+	}
+
+	return Confidential
+}
+
+// Filter returns the fields of the struct the destination is allowed to
+// receive keyed by their json names.
+func (p Policy) Filter(destination string, v any) (map[string]any, bool) {
+	return Filter(v, "json", p.Ceiling(destination))
+}
@@ -0,0 +1,65 @@
+package classify_test
+
+import (
+	"testing"
+
+	"github.com/ardanlabs/service/business/sdk/classify"
+	"github.com/google/go-cmp/cmp"
+)
+
+type account struct {
+	ID       string `json:"id"`
+	Name     string `json:"name" class:"confidential"`
+	Team     string `json:"team" class:"internal"`
+	Secret   string `json:"secret" class:"restricted"`
+	Typo     string `json:"typo" class:"restircted"`
+	Ignored  string `json:"-"`
+	internal string
+}
+
+func Test_Redact(t *testing.T) {
+	acc := account{ID: "1", Name: "Bill", Team: "Ops", Secret: "s3cr3t", Typo: "t", Ignored: "i", internal: "x"}
+
+	got, ok := classify.Redact(&acc, "json", classify.Internal)
+	if !ok {
+		t.Fatalf("Should be able to redact a struct")
+	}
+
+	exp := map[string]any{
+		"id":     "1",
+		"name":   classify.Redacted,
+		"team":   "Ops",
+		"secret": classify.Redacted,
+		"typo":   classify.Redacted,
+	}
+
+	if diff := cmp.Diff(got, exp); diff != "" {
+		t.Fatalf("Should redact the fields above the ceiling : %s", diff)
+	}
+
+	if _, ok := classify.Redact(map[string]any{}, "json", classify.Internal); ok {
+		t.Fatalf("Should not redact a value that isn't a struct")
+	}
+}
+
+func Test_Policy(t *testing.T) {
+	acc := account{ID: "1", Name: "Bill", Team: "Ops", Secret: "s3cr3t"}
+
+	policy := classify.NewPolicy(map[string]classify.Level{
+		"vault": classify.Restricted,
+	})
+
+	got, _ := policy.Filter("partner", acc)
+	if _, exists := got["secret"]; exists {
+		t.Fatalf("Should not send restricted fields to a destination that isn't allowed")
+	}
+
+	if got["name"] != "Bill" {
+		t.Fatalf("Should send confidential fields by default : %v", got)
+	}
+
+	got, _ = policy.Filter("vault", acc)
+	if got["secret"] != "s3cr3t" {
+		t.Fatalf("Should send restricted fields to an allowed destination : %v", got)
+	}
+}
@@ -3,18 +3,39 @@ package dbtest
 import (
 	"time"
 
+	"github.com/ardanlabs/service/business/domain/apikeybus"
+	"github.com/ardanlabs/service/business/domain/apikeybus/stores/apikeydb"
 	"github.com/ardanlabs/service/business/domain/auditbus"
 	"github.com/ardanlabs/service/business/domain/auditbus/stores/auditdb"
+	"github.com/ardanlabs/service/business/domain/groupbus"
+	"github.com/ardanlabs/service/business/domain/groupbus/stores/groupdb"
 	"github.com/ardanlabs/service/business/domain/homebus"
 	"github.com/ardanlabs/service/business/domain/homebus/stores/homedb"
+	"github.com/ardanlabs/service/business/domain/invitebus"
+	"github.com/ardanlabs/service/business/domain/invitebus/stores/invitedb"
+	"github.com/ardanlabs/service/business/domain/notificationbus"
+	"github.com/ardanlabs/service/business/domain/notificationbus/stores/notificationdb"
+	"github.com/ardanlabs/service/business/domain/permissionbus"
+	"github.com/ardanlabs/service/business/domain/permissionbus/stores/permissiondb"
 	"github.com/ardanlabs/service/business/domain/productbus"
 	"github.com/ardanlabs/service/business/domain/productbus/stores/productdb"
+	"github.com/ardanlabs/service/business/domain/reportbus"
+	"github.com/ardanlabs/service/business/domain/reportbus/stores/reportdb"
+	"github.com/ardanlabs/service/business/domain/searchbus"
+	"github.com/ardanlabs/service/business/domain/searchbus/stores/searchdb"
+	"github.com/ardanlabs/service/business/domain/sessionbus"
+	"github.com/ardanlabs/service/business/domain/sessionbus/stores/sessiondb"
+	"github.com/ardanlabs/service/business/domain/templatebus"
+	"github.com/ardanlabs/service/business/domain/templatebus/stores/templatedb"
 	"github.com/ardanlabs/service/business/domain/userbus"
 	"github.com/ardanlabs/service/business/domain/userbus/plugins/useraudit"
 	"github.com/ardanlabs/service/business/domain/userbus/stores/usercache"
 	"github.com/ardanlabs/service/business/domain/userbus/stores/userdb"
 	"github.com/ardanlabs/service/business/domain/vproductbus"
 	"github.com/ardanlabs/service/business/domain/vproductbus/stores/vproductdb"
+	"github.com/ardanlabs/service/business/domain/webhookbus"
+	"github.com/ardanlabs/service/business/domain/webhookbus/stores/webhookdb"
+	"github.com/ardanlabs/service/business/sdk/cache"
 	"github.com/ardanlabs/service/business/sdk/delegate"
 	"github.com/ardanlabs/service/foundation/logger"
 	"github.com/jmoiron/sqlx"
@@ -22,31 +43,61 @@ import (
 
 // BusDomain represents all the business domain apis needed for testing.
 type BusDomain struct {
-	Delegate *delegate.Delegate
-	Audit    *auditbus.Business
-	Home     *homebus.Business
-	Product  *productbus.Business
-	User     userbus.Business
-	VProduct *vproductbus.Business
+	Delegate     *delegate.Delegate
+	APIKey       *apikeybus.Business
+	Audit        *auditbus.Business
+	Group        *groupbus.Business
+	Home         *homebus.Business
+	Invite       *invitebus.Business
+	Notification *notificationbus.Business
+	Permission   *permissionbus.Business
+	Product      *productbus.Business
+	Report       *reportbus.Business
+	Search       *searchbus.Business
+	Session      *sessionbus.Business
+	Template     *templatebus.Business
+	User         userbus.Business
+	VProduct     *vproductbus.Business
+	Webhook      *webhookbus.Business
 }
 
-func newBusDomains(log *logger.Logger, db *sqlx.DB) BusDomain {
-	userAuditPlugin := useraudit.NewPlugin(log, auditbus.NewBusiness(log, auditdb.NewStore(log, db)))
-	userStorage := usercache.NewStore(log, userdb.NewStore(log, db), time.Hour)
+func newBusDomains(log *logger.Logger, db *sqlx.DB, avatars userbus.AvatarStorer) BusDomain {
+	userAuditPlugin := useraudit.NewPlugin(auditbus.NewBusiness(log, auditdb.NewStore(log, db)))
+	userStorage := usercache.NewStore(log, userdb.NewStore(log, db), cache.NewMemory(0), time.Hour)
 
 	delegate := delegate.New(log)
 	auditBus := auditbus.NewBusiness(log, auditdb.NewStore(log, db))
-	userBus := userbus.NewBusiness(log, delegate, userStorage, userAuditPlugin)
+	userBus := userbus.NewBusiness(log, delegate, userStorage, userbus.PasswordPolicy{}, userbus.NewBcryptHasher(0), avatars, userAuditPlugin)
 	productBus := productbus.NewBusiness(log, userBus, delegate, productdb.NewStore(log, db))
 	homeBus := homebus.NewBusiness(log, userBus, delegate, homedb.NewStore(log, db))
+	groupBus := groupbus.NewBusiness(log, userBus, delegate, groupdb.NewStore(log, db))
+	inviteBus := invitebus.NewBusiness(log, userBus, delegate, invitedb.NewStore(log, db), nil)
+	permissionBus := permissionbus.NewBusiness(log, userBus, permissiondb.NewStore(log, db))
 	vproductBus := vproductbus.NewBusiness(vproductdb.NewStore(log, db))
+	reportBus := reportbus.NewBusiness(log, userBus, reportdb.NewStore(log, db), nil)
+	templateBus := templatebus.NewBusiness(log, templatedb.NewStore(log, db))
+	apiKeyBus := apikeybus.NewBusiness(log, userBus, apikeydb.NewStore(log, db))
+	notificationBus := notificationbus.NewBusiness(log, userBus, templateBus, delegate, notificationdb.NewStore(log, db), nil)
+	searchBus := searchbus.NewBusiness(log, searchdb.NewStore(log, db))
+	sessionBus := sessionbus.NewBusiness(log, userBus, delegate, sessiondb.NewStore(log, db), time.Hour)
+	webhookBus := webhookbus.NewBusiness(log, userBus, delegate, webhookdb.NewStore(log, db), nil, webhookbus.Retry{})
 
 	return BusDomain{
-		Delegate: delegate,
-		Audit:    auditBus,
-		Home:     homeBus,
-		Product:  productBus,
-		User:     userBus,
-		VProduct: vproductBus,
+		Delegate:     delegate,
+		APIKey:       apiKeyBus,
+		Audit:        auditBus,
+		Group:        groupBus,
+		Home:         homeBus,
+		Invite:       inviteBus,
+		Notification: notificationBus,
+		Permission:   permissionBus,
+		Product:      productBus,
+		Report:       reportBus,
+		Search:       searchBus,
+		Session:      sessionBus,
+		Template:     templateBus,
+		User:         userBus,
+		VProduct:     vproductBus,
+		Webhook:      webhookBus,
 	}
 }
@@ -5,11 +5,13 @@ import (
 	"bytes"
 	"context"
 	"math/rand"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/ardanlabs/service/business/sdk/migrate"
 	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/foundation/blob/diskblob"
 	"github.com/ardanlabs/service/foundation/docker"
 	"github.com/ardanlabs/service/foundation/logger"
 	"github.com/ardanlabs/service/foundation/otel"
@@ -26,7 +28,7 @@ type Database struct {
 // New creates a new test database inside the database that was started
 // to handle testing. The database is migrated to the current version and
 // a connection pool is provided with business domain packages.
-func New(t *testing.T, testName string) *Database {
+func New(t testing.TB, testName string) *Database {
 	image := "postgres:17.4"
 	name := "servicetest"
 	port := "5432"
@@ -61,12 +63,7 @@ func New(t *testing.T, testName string) *Database {
 
 	// -------------------------------------------------------------------------
 
-	const letterBytes = "abcdefghijklmnopqrstuvwxyz"
-	b := make([]byte, 4)
-	for i := range b {
-		b[i] = letterBytes[rand.Intn(len(letterBytes))]
-	}
-	dbName := string(b)
+	dbName := databaseName(testName)
 
 	t.Logf("Create Database: %s\n", dbName)
 	if _, err := dbM.ExecContext(context.Background(), "CREATE DATABASE "+dbName); err != nil {
@@ -99,15 +96,25 @@ func New(t *testing.T, testName string) *Database {
 
 	// -------------------------------------------------------------------------
 
+	avatars, err := diskblob.New(t.TempDir(), "http://localhost/avatars")
+	if err != nil {
+		t.Fatalf("creating avatar store: %v", err)
+	}
+
+	// -------------------------------------------------------------------------
+
 	t.Cleanup(func() {
 		t.Helper()
 
+		// The connections to the test database are closed first since a
+		// database can't be dropped while it's in use.
+		db.Close()
+
 		t.Logf("Drop Database: %s\n", dbName)
 		if _, err := dbM.ExecContext(context.Background(), "DROP DATABASE "+dbName); err != nil {
 			t.Fatalf("dropping database %s: %v", dbName, err)
 		}
 
-		db.Close()
 		dbM.Close()
 
 		t.Logf("******************** LOGS (%s) ********************\n\n", testName)
@@ -118,6 +125,33 @@ func New(t *testing.T, testName string) *Database {
 	return &Database{
 		DB:        db,
 		Log:       log,
-		BusDomain: newBusDomains(log, db),
+		BusDomain: newBusDomains(log, db, avatars),
 	}
 }
+
+// databaseName returns a name for the database of the test that no other
+// test uses. It's made from the test name so the databases left behind by
+// a failed run can be told apart, with a random suffix so tests with the
+// same name, in different packages, don't collide.
+func databaseName(testName string) string {
+	const letterBytes = "abcdefghijklmnopqrstuvwxyz"
+
+	name := []byte(strings.ToLower(testName))
+	for i, c := range name {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			name[i] = '_'
+		}
+	}
+
+	// Postgres truncates identifiers to 63 bytes.
+	if len(name) > 48 {
+		name = name[:48]
+	}
+
+	suffix := make([]byte, 8)
+	for i := range suffix {
+		suffix[i] = letterBytes[rand.Intn(len(letterBytes))]
+	}
+
+	return "test_" + string(name) + "_" + string(suffix)
+}
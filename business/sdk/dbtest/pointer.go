@@ -1,6 +1,7 @@
 package dbtest
 
 import (
+	"github.com/ardanlabs/service/business/types/department"
 	"github.com/ardanlabs/service/business/types/money"
 	"github.com/ardanlabs/service/business/types/name"
 	"github.com/ardanlabs/service/business/types/quantity"
@@ -50,6 +51,22 @@ func NameNullPointer(value string) *name.Null {
 	return &name
 }
 
+// DepartmentPointer is a helper to get a *Department from a string. It's in
+// the tests package because we normally don't want to deal with pointers to
+// basic types but it's useful in some tests.
+func DepartmentPointer(value string) *department.Department {
+	dept := department.MustParse(value)
+	return &dept
+}
+
+// DepartmentNullPointer is a helper to get a *department.Null from a string.
+// It's in the tests package because we normally don't want to deal with
+// pointers to basic types but it's useful in some tests.
+func DepartmentNullPointer(value string) *department.Null {
+	dept := department.MustParseNull(value)
+	return &dept
+}
+
 // MoneyPointer is a helper to get a *Money from a float. It's in the tests
 // package because we normally don't want to deal with pointers to basic types
 // but it's useful in some tests.
@@ -0,0 +1,218 @@
+package delegate
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"reflect"
+	"runtime"
+	"time"
+
+	"github.com/ardanlabs/service/business/sdk/buserr"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Set of error variables for dead letters.
+var (
+	ErrDeadLetterNotFound = buserr.New(buserr.NotFound, "dead letter not found")
+	ErrHandlerNotFound    = buserr.New(buserr.FailedPrecondition, "handler for dead letter is no longer registered")
+	ErrNoDeadLetters      = buserr.New(buserr.Unimplemented, "dead letters are not configured")
+)
+
+// deadLetters counts the failed calls recorded as dead letters.
+var deadLetters = expvar.NewInt("delegate_dead_letters")
+
+// DeadLetterStorer interface declares the behavior the delegate needs to
+// keep the calls that failed.
+type DeadLetterStorer interface {
+	Create(ctx context.Context, dl DeadLetter) error
+	Update(ctx context.Context, dl DeadLetter) error
+	Delete(ctx context.Context, dl DeadLetter) error
+	Query(ctx context.Context, page page.Page) ([]DeadLetter, error)
+	Count(ctx context.Context) (int, error)
+	QueryByID(ctx context.Context, id uuid.UUID) (DeadLetter, error)
+}
+
+// DeadLetter represents a call to a function that failed. The handler is the
+// name of the function, see WithName, and is used to find it again when the
+// call is replayed.
+type DeadLetter struct {
+	ID          uuid.UUID
+	Handler     string
+	Data        Data
+	Error       string
+	Attempts    int
+	DateCreated time.Time
+	DateUpdated time.Time
+}
+
+// WithDeadLetters records the calls to functions that fail in the store so
+// they can be inspected and replayed once the cause is fixed. The failure of
+// a function never fails the call to the delegate, with or without a store.
+func WithDeadLetters(storer DeadLetterStorer) func(opts *Options) {
+	return func(opts *Options) {
+		opts.deadLetters = storer
+	}
+}
+
+// WithName names the function for the dead letters of the calls to it that
+// fail. By default the name of the Go function is used, which changes when
+// the function is renamed or moved, leaving its dead letters unable to be
+// replayed.
+func WithName(name string) func(opts *HandlerOptions) {
+	return func(opts *HandlerOptions) {
+		opts.name = name
+	}
+}
+
+// QueryDeadLetters retrieves a page of the dead letters, the most recent
+// first.
+func (d *Delegate) QueryDeadLetters(ctx context.Context, page page.Page) ([]DeadLetter, error) {
+	if d.opts.deadLetters == nil {
+		return nil, ErrNoDeadLetters
+	}
+
+	dls, err := d.opts.deadLetters.Query(ctx, page)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return dls, nil
+}
+
+// CountDeadLetters returns the total number of dead letters.
+func (d *Delegate) CountDeadLetters(ctx context.Context) (int, error) {
+	if d.opts.deadLetters == nil {
+		return 0, ErrNoDeadLetters
+	}
+
+	n, err := d.opts.deadLetters.Count(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("count: %w", err)
+	}
+
+	return n, nil
+}
+
+// QueryDeadLetterByID finds the dead letter by the specified ID.
+func (d *Delegate) QueryDeadLetterByID(ctx context.Context, id uuid.UUID) (DeadLetter, error) {
+	if d.opts.deadLetters == nil {
+		return DeadLetter{}, ErrNoDeadLetters
+	}
+
+	dl, err := d.opts.deadLetters.QueryByID(ctx, id)
+	if err != nil {
+		return DeadLetter{}, fmt.Errorf("query: deadLetterID[%s]: %w", id, err)
+	}
+
+	return dl, nil
+}
+
+// Replay calls the function of the dead letter again with the same data,
+// synchronously. The dead letter is removed when the call succeeds. When it
+// fails again the dead letter is kept with the new error and returned along
+// with it.
+func (d *Delegate) Replay(ctx context.Context, dl DeadLetter) (DeadLetter, error) {
+	if d.opts.deadLetters == nil {
+		return DeadLetter{}, ErrNoDeadLetters
+	}
+
+	h, ok := d.lookup(dl.Data, dl.Handler)
+	if !ok {
+		return DeadLetter{}, fmt.Errorf("lookup: handler[%s]: %w", dl.Handler, ErrHandlerNotFound)
+	}
+
+	d.log.Info(ctx, "delegate replay", "deadLetterID", dl.ID, "handler", dl.Handler, "domain", dl.Data.Domain, "action", dl.Data.Action)
+
+	callErr := d.run(ctx, trace.SpanContext{}, h, dl.Data)
+	if callErr == nil {
+		if err := d.opts.deadLetters.Delete(ctx, dl); err != nil {
+			return DeadLetter{}, fmt.Errorf("delete: deadLetterID[%s]: %w", dl.ID, err)
+		}
+
+		return DeadLetter{}, nil
+	}
+
+	dl.Error = callErr.Error()
+	dl.Attempts++
+	dl.DateUpdated = time.Now()
+
+	if err := d.opts.deadLetters.Update(ctx, dl); err != nil {
+		return DeadLetter{}, fmt.Errorf("update: deadLetterID[%s]: %w", dl.ID, err)
+	}
+
+	return dl, fmt.Errorf("replay: handler[%s]: %w", dl.Handler, callErr)
+}
+
+// Discard removes the dead letter without calling its function again.
+func (d *Delegate) Discard(ctx context.Context, dl DeadLetter) error {
+	if d.opts.deadLetters == nil {
+		return ErrNoDeadLetters
+	}
+
+	if err := d.opts.deadLetters.Delete(ctx, dl); err != nil {
+		return fmt.Errorf("delete: deadLetterID[%s]: %w", dl.ID, err)
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// deadLetter records the failed call when a store is configured. A failure
+// to record it is only logged since the call can't be failed anymore.
+func (d *Delegate) deadLetter(ctx context.Context, h handler, data Data, callErr error) {
+	if d.opts.deadLetters == nil {
+		return
+	}
+
+	now := time.Now()
+
+	dl := DeadLetter{
+		ID:          uuid.New(),
+		Handler:     h.name,
+		Data:        data,
+		Error:       callErr.Error(),
+		Attempts:    1,
+		DateCreated: now,
+		DateUpdated: now,
+	}
+
+	// The call may have failed because its context was canceled, which
+	// mustn't stop it from being recorded.
+	if err := d.opts.deadLetters.Create(context.WithoutCancel(ctx), dl); err != nil {
+		d.log.Error(ctx, "delegate call", "status", "recording dead letter", "handler", h.name, "err", err)
+		return
+	}
+
+	deadLetters.Add(1)
+}
+
+// lookup finds the function with the name among the ones called for the
+// event.
+func (d *Delegate) lookup(data Data, name string) (handler, bool) {
+	for _, h := range d.handlers(data) {
+		if h.name == name {
+			return h, true
+		}
+	}
+
+	for i, p := range d.opts.publishers {
+		if h := p.handler(i); h.name == name {
+			return h, true
+		}
+	}
+
+	return handler{}, false
+}
+
+// funcName returns the name of the Go function.
+func funcName(fn Func) string {
+	if f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()); f != nil {
+		return f.Name()
+	}
+
+	return ""
+}
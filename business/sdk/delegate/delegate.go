@@ -3,9 +3,19 @@
 package delegate
 
 import (
+	"cmp"
 	"context"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // These types are just for documentation so we know what keys go
@@ -15,51 +25,480 @@ type (
 	action string
 )
 
+// handler represents a registered function and how it's executed. The
+// pattern is set for functions that subscribed to a pattern of events, and
+// seq is the order the function was registered in. The name identifies the
+// function in dead letters.
+type handler struct {
+	fn      Func
+	opts    HandlerOptions
+	name    string
+	pattern string
+	seq     int
+}
+
+// job represents an asynchronous call waiting for a worker.
+type job struct {
+	ctx      context.Context
+	producer trace.SpanContext
+	h        handler
+	data     Data
+}
+
 // Delegate manages the set of functions to be called by domain
 // packages when an import is not possible.
 type Delegate struct {
-	log   *logger.Logger
-	funcs map[domain]map[action][]Func
+	log      *logger.Logger
+	funcs    map[domain]map[action][]handler
+	patterns []handler
+	seq      int
+	opts     Options
+	jobs     chan job
+	start    sync.Once
+	mu       sync.Mutex
+	closed   bool
+	pending  int
+	waiters  []chan struct{}
 }
 
 // New constructs a delegate for indirect api access.
-func New(log *logger.Logger) *Delegate {
+func New(log *logger.Logger, options ...func(opts *Options)) *Delegate {
+	opts := Options{
+		workers:   4,
+		queueSize: 1000,
+	}
+
+	for _, option := range options {
+		option(&opts)
+	}
+
 	return &Delegate{
 		log:   log,
-		funcs: make(map[domain]map[action][]Func),
+		funcs: make(map[domain]map[action][]handler),
+		opts:  opts,
+		jobs:  make(chan job, opts.queueSize),
 	}
 }
 
 // Register adds a function to be called for a specified domain and action.
-func (d *Delegate) Register(domainType string, actionType string, fn Func) {
+// By default the function is executed synchronously with no timeout.
+func (d *Delegate) Register(domainType string, actionType string, fn Func, options ...func(opts *HandlerOptions)) {
+	h := d.newHandler(fn, options)
+
 	aMap, ok := d.funcs[domain(domainType)]
 	if !ok {
-		aMap = make(map[action][]Func)
+		aMap = make(map[action][]handler)
 		d.funcs[domain(domainType)] = aMap
 	}
 
 	funcs := aMap[action(actionType)]
-	funcs = append(funcs, fn)
+	funcs = append(funcs, h)
 	aMap[action(actionType)] = funcs
 }
 
+// Subscribe adds a function to be called for every event whose domain and
+// action, written as domain.action, match the pattern. Patterns have the
+// syntax of path.Match, so "user.*" matches every action of the user domain
+// and "*.deleted" matches the deleted action of every domain. The function
+// is executed the same as a registered one.
+func (d *Delegate) Subscribe(pattern string, fn Func, options ...func(opts *HandlerOptions)) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("pattern[%s]: %w", pattern, err)
+	}
+
+	h := d.newHandler(fn, options)
+	h.pattern = pattern
+
+	d.patterns = append(d.patterns, h)
+
+	return nil
+}
+
 // Call executes all functions registered for the specified domain and
-// action. These functions are executed synchronously on the G making the call.
+// action, and subscribed to a pattern they match, in priority order.
+// Synchronous functions are executed on the G making the call.
+// Asynchronous functions are handed to the worker pool and the call doesn't
+// wait for them. If the pool is full or shut down they are executed
+// synchronously so no call is lost. The event is then sent to every
+// publisher the same way asynchronous functions are executed. Calling a nil
+// delegate does nothing so tools constructing a business without one keep
+// working. A function that fails doesn't fail the call, it's logged and
+// recorded as a dead letter when a store is configured, see
+// WithDeadLetters.
 func (d *Delegate) Call(ctx context.Context, data Data) error {
+	if d == nil {
+		return nil
+	}
+
+	ctx, span := otel.AddProducerSpan(ctx, "business.sdk.delegate.call",
+		attribute.String("domain", data.Domain),
+		attribute.String("action", data.Action),
+	)
+	defer span.End()
+
 	d.log.Info(ctx, "delegate call", "status", "started", "domain", data.Domain, "action", data.Action, "params", data.RawParams)
 	defer d.log.Info(ctx, "delegate call", "status", "completed")
 
+	for _, h := range d.handlers(data) {
+		data, ok := data.only(h.opts.fields)
+		if !ok {
+			continue
+		}
+
+		if h.opts.async && d.dispatch(ctx, span.SpanContext(), h, data) {
+			d.log.Info(ctx, "delegate call", "status", "dispatched")
+			continue
+		}
+
+		d.log.Info(ctx, "delegate call", "status", "sending")
+
+		d.execute(ctx, span.SpanContext(), h, data)
+	}
+
+	for i, p := range d.opts.publishers {
+		h := p.handler(i)
+
+		data, ok := data.only(h.opts.fields)
+		if !ok {
+			continue
+		}
+
+		if !d.dispatch(ctx, span.SpanContext(), h, data) {
+			d.execute(ctx, span.SpanContext(), h, data)
+		}
+	}
+
+	return nil
+}
+
+// Invoke executes the functions registered for the specified domain and
+// action on the G making the call, in priority order, and returns the errors
+// of the ones that fail. It's meant for actions the caller can't complete
+// unless every function does, like collecting or erasing the data the
+// domains hold about a user. Unlike Call, functions registered as
+// asynchronous are still executed synchronously, functions subscribed to a
+// pattern aren't called, the event isn't sent to the publishers and failures
+// aren't recorded as dead letters, since the caller acts on them. Invoking a
+// nil delegate does nothing.
+func (d *Delegate) Invoke(ctx context.Context, data Data) error {
+	if d == nil {
+		return nil
+	}
+
+	ctx, span := otel.AddProducerSpan(ctx, "business.sdk.delegate.invoke",
+		attribute.String("domain", data.Domain),
+		attribute.String("action", data.Action),
+	)
+	defer span.End()
+
+	d.log.Info(ctx, "delegate invoke", "status", "started", "domain", data.Domain, "action", data.Action, "params", data.RawParams)
+	defer d.log.Info(ctx, "delegate invoke", "status", "completed")
+
+	var errs []error
+
+	for _, h := range d.handlers(data) {
+		if h.pattern != "" {
+			continue
+		}
+
+		data, ok := data.only(h.opts.fields)
+		if !ok {
+			continue
+		}
+
+		if err := d.run(ctx, span.SpanContext(), h, data); err != nil {
+			d.log.Error(ctx, "delegate invoke", "handler", h.name, "err", err)
+			errs = append(errs, fmt.Errorf("handler[%s]: %w", h.name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Drain waits for all the asynchronous calls that have been dispatched to
+// complete.
+func (d *Delegate) Drain(ctx context.Context) error {
+	d.mu.Lock()
+
+	if d.pending == 0 {
+		d.mu.Unlock()
+		return nil
+	}
+
+	ch := make(chan struct{})
+	d.waiters = append(d.waiters, ch)
+
+	d.mu.Unlock()
+
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown stops accepting asynchronous calls and waits for the ones that
+// have been dispatched to complete. Calls made after shutdown are executed
+// synchronously.
+func (d *Delegate) Shutdown(ctx context.Context) error {
+	d.mu.Lock()
+
+	if !d.closed {
+		d.closed = true
+		close(d.jobs)
+	}
+
+	d.mu.Unlock()
+
+	return d.Drain(ctx)
+}
+
+// =============================================================================
+
+// newHandler constructs the handler for a function being registered.
+func (d *Delegate) newHandler(fn Func, options []func(opts *HandlerOptions)) handler {
+	var opts HandlerOptions
+	for _, option := range options {
+		option(&opts)
+	}
+
+	d.seq++
+
+	name := opts.name
+	if name == "" {
+		name = funcName(fn)
+	}
+
+	return handler{
+		fn:   fn,
+		opts: opts,
+		name: name,
+		seq:  d.seq,
+	}
+}
+
+// handlers returns the functions to call for the event, the ones registered
+// for its domain and action along with the ones subscribed to a pattern it
+// matches. They are ordered by priority and then by the order they were
+// registered in.
+func (d *Delegate) handlers(data Data) []handler {
+	var hs []handler
+
 	if dMap, ok := d.funcs[domain(data.Domain)]; ok {
-		if funcs, ok := dMap[action(data.Action)]; ok {
-			for _, fn := range funcs {
-				d.log.Info(ctx, "delegate call", "status", "sending")
+		hs = append(hs, dMap[action(data.Action)]...)
+	}
+
+	event := data.Domain + "." + data.Action
+	for _, h := range d.patterns {
+		if matched, _ := path.Match(h.pattern, event); matched {
+			hs = append(hs, h)
+		}
+	}
+
+	hs = slices.DeleteFunc(hs, func(h handler) bool {
+		return len(h.opts.domains) > 0 && !slices.Contains(h.opts.domains, data.Domain)
+	})
+
+	slices.SortFunc(hs, func(a handler, b handler) int {
+		if a.opts.priority != b.opts.priority {
+			return cmp.Compare(b.opts.priority, a.opts.priority)
+		}
+		return cmp.Compare(a.seq, b.seq)
+	})
+
+	return hs
+}
+
+// dispatch queues the call for the worker pool. It returns false if the call
+// couldn't be queued.
+func (d *Delegate) dispatch(ctx context.Context, producer trace.SpanContext, h handler, data Data) bool {
+	d.start.Do(d.startWorkers)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return false
+	}
+
+	// The values from the caller's context are kept, but not its
+	// cancellation since the caller isn't going to wait.
+	j := job{
+		ctx:      context.WithoutCancel(ctx),
+		producer: producer,
+		h:        h,
+		data:     data,
+	}
 
-				if err := fn(ctx, data); err != nil {
-					d.log.Error(ctx, "delegate call", "err", err)
-				}
+	select {
+	case d.jobs <- j:
+		d.pending++
+		return true
+
+	default:
+		d.log.Warn(ctx, "delegate call", "status", "worker pool is full, executing synchronously", "domain", data.Domain, "action", data.Action)
+		return false
+	}
+}
+
+func (d *Delegate) startWorkers() {
+	for range d.opts.workers {
+		go func() {
+			for j := range d.jobs {
+				d.execute(j.ctx, j.producer, j.h, j.data)
+				d.done()
 			}
+		}()
+	}
+}
+
+// done records the completion of an asynchronous call and releases the
+// callers waiting on a drain when nothing is left.
+func (d *Delegate) done() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.pending--
+
+	if d.pending == 0 {
+		for _, ch := range d.waiters {
+			close(ch)
 		}
+		d.waiters = nil
+	}
+}
+
+// execute runs the function and records a dead letter when it fails.
+func (d *Delegate) execute(ctx context.Context, producer trace.SpanContext, h handler, data Data) {
+	if err := d.run(ctx, producer, h, data); err != nil {
+		d.log.Error(ctx, "delegate call", "handler", h.name, "err", err)
+		d.deadLetter(ctx, h, data, err)
+	}
+}
+
+// run runs the function inside a consumer span linked to the span that
+// produced the call.
+func (d *Delegate) run(ctx context.Context, producer trace.SpanContext, h handler, data Data) error {
+	ctx, span := otel.AddConsumerSpan(ctx, producer, "business.sdk.delegate.handle",
+		attribute.String("domain", data.Domain),
+		attribute.String("action", data.Action),
+	)
+	defer span.End()
+
+	if h.opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.opts.timeout)
+		defer cancel()
+	}
+
+	if err := h.fn(ctx, data); err != nil {
+		span.RecordError(err)
+		return err
 	}
 
 	return nil
 }
+
+// =============================================================================
+
+// Options represent optional parameters for constructing a delegate.
+type Options struct {
+	workers     int
+	queueSize   int
+	publishers  []publisher
+	deadLetters DeadLetterStorer
+}
+
+// WithWorkers sets the number of goroutines executing asynchronous calls.
+func WithWorkers(workers int) func(opts *Options) {
+	return func(opts *Options) {
+		if workers > 0 {
+			opts.workers = workers
+		}
+	}
+}
+
+// WithQueueSize sets the number of asynchronous calls that can wait for a
+// worker before calls start executing synchronously.
+func WithQueueSize(size int) func(opts *Options) {
+	return func(opts *Options) {
+		if size >= 0 {
+			opts.queueSize = size
+		}
+	}
+}
+
+// WithPublisher sends every event to the publisher on the topic described
+// by the topics using the encoder. Events are published asynchronously on
+// the worker pool. When fields are specified, events that report changes are
+// only published if they changed one of the fields, and with just those
+// changes.
+func WithPublisher(pub Publisher, topics Topics, encode Encoder, fields ...string) func(opts *Options) {
+	return func(opts *Options) {
+		if encode == nil {
+			encode = EncodeJSON
+		}
+
+		opts.publishers = append(opts.publishers, publisher{
+			pub:    pub,
+			topics: topics,
+			encode: encode,
+			fields: fields,
+		})
+	}
+}
+
+// HandlerOptions represent optional parameters for registering a function.
+type HandlerOptions struct {
+	async    bool
+	timeout  time.Duration
+	fields   []string
+	priority int
+	domains  []string
+	name     string
+}
+
+// WithAsync executes the function on the worker pool so the caller doesn't
+// wait for it to complete.
+func WithAsync() func(opts *HandlerOptions) {
+	return func(opts *HandlerOptions) {
+		opts.async = true
+	}
+}
+
+// WithFields limits the function to events that changed one of the fields,
+// and it only receives the changes to those fields. Events that don't report
+// changes are not affected.
+func WithFields(fields ...string) func(opts *HandlerOptions) {
+	return func(opts *HandlerOptions) {
+		opts.fields = fields
+	}
+}
+
+// WithTimeout limits how long the function can take before its context is
+// canceled.
+func WithTimeout(timeout time.Duration) func(opts *HandlerOptions) {
+	return func(opts *HandlerOptions) {
+		opts.timeout = timeout
+	}
+}
+
+// WithPriority orders the function among the others called for an event.
+// Functions with a higher priority are called first, and functions with the
+// same priority are called in the order they were registered. The default
+// priority is zero. Asynchronous functions are dispatched in that order but
+// may complete in any order.
+func WithPriority(priority int) func(opts *HandlerOptions) {
+	return func(opts *HandlerOptions) {
+		opts.priority = priority
+	}
+}
+
+// WithDomains limits the function to events raised by the domains. It's
+// meant for functions subscribed to a pattern that spans domains.
+func WithDomains(domains ...string) func(opts *HandlerOptions) {
+	return func(opts *HandlerOptions) {
+		opts.domains = domains
+	}
+}
@@ -0,0 +1,483 @@
+package delegate_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/service/business/sdk/delegate"
+	"github.com/ardanlabs/service/business/sdk/delegate/publishers/mempub"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/otel"
+	"github.com/google/uuid"
+	gotel "go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func Test_Async(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.New(&buf, logger.LevelInfo, "TEST", func(context.Context) string { return "" })
+
+	d := delegate.New(log, delegate.WithWorkers(2))
+
+	release := make(chan struct{})
+	var calls atomic.Int32
+
+	d.Register("user", "deleted", func(ctx context.Context, data delegate.Data) error {
+		<-release
+		calls.Add(1)
+		return nil
+	}, delegate.WithAsync())
+
+	var timedOut atomic.Bool
+
+	d.Register("user", "deleted", func(ctx context.Context, data delegate.Data) error {
+		<-ctx.Done()
+		timedOut.Store(errors.Is(ctx.Err(), context.DeadlineExceeded))
+		return nil
+	}, delegate.WithTimeout(10*time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	if err := d.Call(ctx, delegate.Data{Domain: "user", Action: "deleted"}); err != nil {
+		t.Fatalf("Should be able to call the delegate : %s", err)
+	}
+
+	// The caller's context is canceled once the request is done, which
+	// must not affect the asynchronous call.
+	cancel()
+
+	if !timedOut.Load() {
+		t.Fatalf("Should have timed out the synchronous handler")
+	}
+
+	if calls.Load() != 0 {
+		t.Fatalf("Should not wait for the asynchronous handler")
+	}
+
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer drainCancel()
+
+	if err := d.Drain(drainCtx); err == nil {
+		t.Fatalf("Should not drain while the asynchronous handler is blocked")
+	}
+
+	close(release)
+
+	if err := d.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Should be able to shutdown : %s", err)
+	}
+
+	if calls.Load() != 1 {
+		t.Fatalf("Should have completed the asynchronous handler, got %d calls", calls.Load())
+	}
+
+	if err := d.Call(context.Background(), delegate.Data{Domain: "user", Action: "deleted"}); err != nil {
+		t.Fatalf("Should be able to call the delegate after shutdown : %s", err)
+	}
+
+	if calls.Load() != 2 {
+		t.Fatalf("Should execute synchronously after shutdown, got %d calls", calls.Load())
+	}
+}
+
+func Test_Publish(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.New(&buf, logger.LevelInfo, "TEST", func(context.Context) string { return "" })
+
+	topics, err := delegate.ParseTopics("sales.", "user.deleted:users-deleted")
+	if err != nil {
+		t.Fatalf("Should be able to parse the topics : %s", err)
+	}
+
+	pub := mempub.New()
+	d := delegate.New(log, delegate.WithPublisher(pub, topics, delegate.EncodeJSON))
+
+	calls := []delegate.Data{
+		{Domain: "user", Action: "deleted", RawParams: []byte(`{"userID":"1"}`)},
+		{Domain: "product", Action: "updated"},
+	}
+
+	for _, data := range calls {
+		if err := d.Call(context.Background(), data); err != nil {
+			t.Fatalf("Should be able to call the delegate : %s", err)
+		}
+	}
+
+	if err := d.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Should be able to shutdown the delegate : %s", err)
+	}
+
+	msgs := pub.Messages()
+	if len(msgs) != 2 {
+		t.Fatalf("Should have published both events : got %d", len(msgs))
+	}
+
+	topicsByEvent := make(map[string]string)
+	for _, msg := range msgs {
+		var event struct {
+			Domain string          `json:"domain"`
+			Action string          `json:"action"`
+			Params json.RawMessage `json:"params"`
+		}
+
+		if err := json.Unmarshal(msg.Body, &event); err != nil {
+			t.Fatalf("Should be able to decode the event : %s", err)
+		}
+
+		topicsByEvent[event.Domain+"."+event.Action] = msg.Topic
+	}
+
+	if topic := topicsByEvent["user.deleted"]; topic != "users-deleted" {
+		t.Fatalf("Should use the mapped topic : got %q", topic)
+	}
+
+	if topic := topicsByEvent["product.updated"]; topic != "sales.product.updated" {
+		t.Fatalf("Should use the prefixed topic : got %q", topic)
+	}
+}
+
+func Test_PublishTrace(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.New(&buf, logger.LevelInfo, "TEST", func(context.Context) string { return "" })
+
+	gotel.SetTextMapPropagator(propagation.TraceContext{})
+
+	tp := sdktrace.NewTracerProvider()
+	defer tp.Shutdown(context.Background())
+
+	ctx := otel.InjectTracing(context.Background(), tp.Tracer("test"))
+
+	ctx, span := otel.AddSpan(ctx, "request")
+	defer span.End()
+
+	pub := mempub.New()
+	d := delegate.New(log, delegate.WithPublisher(pub, delegate.Topics{}, delegate.EncodeJSON))
+
+	if err := d.Call(ctx, delegate.Data{Domain: "user", Action: "deleted"}); err != nil {
+		t.Fatalf("Should be able to call the delegate : %s", err)
+	}
+
+	if err := d.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Should be able to shutdown the delegate : %s", err)
+	}
+
+	msgs := pub.Messages()
+	if len(msgs) != 1 {
+		t.Fatalf("Should have published the event : got %d", len(msgs))
+	}
+
+	sc := otel.ExtractCarrier(msgs[0].Headers)
+	if sc.TraceID() != span.SpanContext().TraceID() {
+		t.Fatalf("Should carry the trace of the call : got %s, exp %s", sc.TraceID(), span.SpanContext().TraceID())
+	}
+}
+
+func Test_Fields(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.New(&buf, logger.LevelInfo, "TEST", func(context.Context) string { return "" })
+
+	pub := mempub.New()
+	d := delegate.New(log, delegate.WithPublisher(pub, delegate.Topics{}, delegate.EncodeJSON, "roles"))
+
+	var got []delegate.Data
+	d.Register("user", "updated", func(ctx context.Context, data delegate.Data) error {
+		got = append(got, data)
+		return nil
+	}, delegate.WithFields("enabled", "roles"))
+
+	calls := []delegate.Data{
+		{Domain: "user", Action: "updated", Changes: []delegate.Change{{Field: "name"}}},
+		{Domain: "user", Action: "updated", Changes: []delegate.Change{
+			{Field: "name"},
+			delegate.NewChange("enabled", true, false),
+		}},
+		{Domain: "user", Action: "updated"},
+	}
+
+	for _, data := range calls {
+		if err := d.Call(context.Background(), data); err != nil {
+			t.Fatalf("Should be able to call the delegate : %s", err)
+		}
+	}
+
+	if err := d.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Should be able to shutdown the delegate : %s", err)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("Should skip events that didn't change the fields : got %d calls", len(got))
+	}
+
+	if fields := got[0].Fields(); len(fields) != 1 || fields[0] != "enabled" {
+		t.Fatalf("Should only receive the changes to the fields : got %v", fields)
+	}
+
+	if string(got[0].Changes[0].Old) != "true" || string(got[0].Changes[0].New) != "false" {
+		t.Fatalf("Should receive the old and new values : got %s -> %s", got[0].Changes[0].Old, got[0].Changes[0].New)
+	}
+
+	if got[1].Changes != nil {
+		t.Fatalf("Should pass events without changes as is : got %v", got[1].Changes)
+	}
+
+	if msgs := pub.Messages(); len(msgs) != 1 {
+		t.Fatalf("Should only publish events without changes : got %d", len(msgs))
+	}
+}
+
+func Test_Subscribe(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.New(&buf, logger.LevelInfo, "TEST", func(context.Context) string { return "" })
+
+	d := delegate.New(log)
+
+	var got []string
+	record := func(name string) delegate.Func {
+		return func(ctx context.Context, data delegate.Data) error {
+			got = append(got, name+":"+data.Domain+"."+data.Action)
+			return nil
+		}
+	}
+
+	d.Register("user", "deleted", record("exact"))
+	d.Register("user", "deleted", record("first"), delegate.WithPriority(10))
+
+	if err := d.Subscribe("user.*", record("user")); err != nil {
+		t.Fatalf("Should be able to subscribe : %s", err)
+	}
+
+	if err := d.Subscribe("*.deleted", record("deleted"), delegate.WithPriority(5), delegate.WithDomains("product")); err != nil {
+		t.Fatalf("Should be able to subscribe : %s", err)
+	}
+
+	if err := d.Subscribe("user.[", record("bad")); err == nil {
+		t.Fatalf("Should reject a malformed pattern")
+	}
+
+	calls := []delegate.Data{
+		{Domain: "user", Action: "deleted"},
+		{Domain: "user", Action: "created"},
+		{Domain: "product", Action: "deleted"},
+		{Domain: "product", Action: "created"},
+	}
+
+	for _, data := range calls {
+		if err := d.Call(context.Background(), data); err != nil {
+			t.Fatalf("Should be able to call the delegate : %s", err)
+		}
+	}
+
+	exp := []string{
+		"first:user.deleted",
+		"exact:user.deleted",
+		"user:user.deleted",
+		"user:user.created",
+		"deleted:product.deleted",
+	}
+
+	if !slices.Equal(got, exp) {
+		t.Fatalf("Should call the matching functions in priority order :\ngot %v\nexp %v", got, exp)
+	}
+}
+
+func Test_Invoke(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.New(&buf, logger.LevelInfo, "TEST", func(context.Context) string { return "" })
+
+	dls := newDeadLetterStore()
+	pub := mempub.New()
+
+	d := delegate.New(log, delegate.WithDeadLetters(dls), delegate.WithPublisher(pub, delegate.Topics{}, nil))
+
+	var got []string
+	d.Register("user", "collect", func(ctx context.Context, data delegate.Data) error {
+		got = append(got, "product")
+		return nil
+	}, delegate.WithAsync())
+	d.Register("user", "collect", func(ctx context.Context, data delegate.Data) error {
+		got = append(got, "home")
+		return errors.New("home unavailable")
+	}, delegate.WithName("home"))
+	d.Register("user", "collect", func(ctx context.Context, data delegate.Data) error {
+		got = append(got, "group")
+		return nil
+	})
+
+	if err := d.Subscribe("user.*", func(ctx context.Context, data delegate.Data) error {
+		got = append(got, "audit")
+		return nil
+	}); err != nil {
+		t.Fatalf("Should be able to subscribe : %s", err)
+	}
+
+	err := d.Invoke(context.Background(), delegate.Data{Domain: "user", Action: "collect"})
+	if err == nil || !strings.Contains(err.Error(), "handler[home]: home unavailable") {
+		t.Fatalf("Should return the error of the failed function : %v", err)
+	}
+
+	exp := []string{"product", "home", "group"}
+	if !slices.Equal(got, exp) {
+		t.Fatalf("Should call every registered function synchronously :\ngot %v\nexp %v", got, exp)
+	}
+
+	if n, _ := dls.Count(context.Background()); n != 0 {
+		t.Fatalf("Should not record dead letters : %d", n)
+	}
+
+	if err := d.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Should be able to shutdown the delegate : %s", err)
+	}
+
+	if msgs := pub.Messages(); len(msgs) != 0 {
+		t.Fatalf("Should not publish the event : %d", len(msgs))
+	}
+
+	var nilDelegate *delegate.Delegate
+	if err := nilDelegate.Invoke(context.Background(), delegate.Data{}); err != nil {
+		t.Fatalf("Should do nothing with a nil delegate : %s", err)
+	}
+}
+
+func Test_DeadLetters(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.New(&buf, logger.LevelInfo, "TEST", func(context.Context) string { return "" })
+
+	store := newDeadLetterStore()
+	d := delegate.New(log, delegate.WithDeadLetters(store))
+
+	var fail atomic.Bool
+	fail.Store(true)
+
+	d.Register("user", "deleted", func(ctx context.Context, data delegate.Data) error {
+		if fail.Load() {
+			return errors.New("mail server down")
+		}
+		return nil
+	}, delegate.WithName("send-goodbye"))
+
+	ctx := context.Background()
+
+	if err := d.Call(ctx, delegate.Data{Domain: "user", Action: "deleted", RawParams: []byte(`{"userID":"1"}`)}); err != nil {
+		t.Fatalf("Should not fail the call when a function fails : %s", err)
+	}
+
+	pg := page.MustParse("1", "10")
+
+	dls, err := d.QueryDeadLetters(ctx, pg)
+	if err != nil {
+		t.Fatalf("Should be able to query the dead letters : %s", err)
+	}
+
+	if len(dls) != 1 {
+		t.Fatalf("Should record the failed call : got %d dead letters", len(dls))
+	}
+
+	dl := dls[0]
+	if dl.Handler != "send-goodbye" || dl.Data.Action != "deleted" || dl.Error != "mail server down" || dl.Attempts != 1 {
+		t.Fatalf("Should describe the failed call : %+v", dl)
+	}
+
+	// A replay that fails again keeps the dead letter.
+
+	updDL, err := d.Replay(ctx, dl)
+	if err == nil {
+		t.Fatalf("Should fail the replay while the function fails")
+	}
+
+	if updDL.Attempts != 2 {
+		t.Fatalf("Should count the attempts : got %d, exp 2", updDL.Attempts)
+	}
+
+	// A replay that succeeds removes the dead letter.
+
+	fail.Store(false)
+
+	if _, err := d.Replay(ctx, updDL); err != nil {
+		t.Fatalf("Should be able to replay the call : %s", err)
+	}
+
+	if _, err := d.QueryDeadLetterByID(ctx, dl.ID); !errors.Is(err, delegate.ErrDeadLetterNotFound) {
+		t.Fatalf("Should remove the replayed dead letter : %v", err)
+	}
+
+	// A dead letter whose function is gone can't be replayed.
+
+	dl.Handler = "unknown"
+	if _, err := d.Replay(ctx, dl); !errors.Is(err, delegate.ErrHandlerNotFound) {
+		t.Fatalf("Should not replay a dead letter without a function : %v", err)
+	}
+
+	if _, err := delegate.New(log).QueryDeadLetters(ctx, pg); !errors.Is(err, delegate.ErrNoDeadLetters) {
+		t.Fatalf("Should report dead letters are not configured : %v", err)
+	}
+}
+
+// =============================================================================
+
+type deadLetterStore struct {
+	mu  sync.Mutex
+	dls map[uuid.UUID]delegate.DeadLetter
+}
+
+func newDeadLetterStore() *deadLetterStore {
+	return &deadLetterStore{
+		dls: make(map[uuid.UUID]delegate.DeadLetter),
+	}
+}
+
+func (s *deadLetterStore) Create(ctx context.Context, dl delegate.DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.dls[dl.ID] = dl
+	return nil
+}
+
+func (s *deadLetterStore) Update(ctx context.Context, dl delegate.DeadLetter) error {
+	return s.Create(ctx, dl)
+}
+
+func (s *deadLetterStore) Delete(ctx context.Context, dl delegate.DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.dls, dl.ID)
+	return nil
+}
+
+func (s *deadLetterStore) Query(ctx context.Context, pg page.Page) ([]delegate.DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Collect(maps.Values(s.dls)), nil
+}
+
+func (s *deadLetterStore) Count(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.dls), nil
+}
+
+func (s *deadLetterStore) QueryByID(ctx context.Context, id uuid.UUID) (delegate.DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dl, exists := s.dls[id]
+	if !exists {
+		return delegate.DeadLetter{}, delegate.ErrDeadLetterNotFound
+	}
+
+	return dl, nil
+}
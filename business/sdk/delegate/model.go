@@ -2,23 +2,96 @@ package delegate
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"slices"
 )
 
 // Func represents a function that is registered and called by the system.
 type Func func(context.Context, Data) error
 
-// Data represents an event between domains.
+// Data represents an event between domains. Events that report a change to
+// an entity describe each field that changed.
 type Data struct {
 	Domain    string
 	Action    string
 	RawParams []byte
+	Changes   []Change
 }
 
 // String implements the Stringer interface.
 func (d Data) String() string {
 	return fmt.Sprintf(
-		"Event{Domain:%#v, Action:%#v, RawParams:%#v}",
-		d.Domain, d.Action, string(d.RawParams),
+		"Event{Domain:%#v, Action:%#v, RawParams:%#v, Fields:%v}",
+		d.Domain, d.Action, string(d.RawParams), d.Fields(),
 	)
 }
+
+// Fields returns the names of the fields that changed.
+func (d Data) Fields() []string {
+	if len(d.Changes) == 0 {
+		return nil
+	}
+
+	fields := make([]string, len(d.Changes))
+	for i, chg := range d.Changes {
+		fields[i] = chg.Field
+	}
+
+	return fields
+}
+
+// only returns the data with just the changes to the specified fields. False
+// is returned when the event reports changes but none to those fields. Events
+// that don't report changes are returned as is.
+func (d Data) only(fields []string) (Data, bool) {
+	if len(fields) == 0 || len(d.Changes) == 0 {
+		return d, true
+	}
+
+	var changes []Change
+	for _, chg := range d.Changes {
+		if slices.Contains(fields, chg.Field) {
+			changes = append(changes, chg)
+		}
+	}
+
+	if len(changes) == 0 {
+		return Data{}, false
+	}
+
+	d.Changes = changes
+
+	return d, true
+}
+
+// =============================================================================
+
+// Change describes the change to a single field. The old and new values are
+// encoded as JSON and are left empty for fields whose values must not be
+// copied into events.
+type Change struct {
+	Field string          `json:"field"`
+	Old   json.RawMessage `json:"old,omitempty"`
+	New   json.RawMessage `json:"new,omitempty"`
+}
+
+// NewChange constructs a change to the field that includes the old and new
+// values.
+func NewChange(field string, old any, new any) Change {
+	oldRaw, err := json.Marshal(old)
+	if err != nil {
+		panic(err)
+	}
+
+	newRaw, err := json.Marshal(new)
+	if err != nil {
+		panic(err)
+	}
+
+	return Change{
+		Field: field,
+		Old:   oldRaw,
+		New:   newRaw,
+	}
+}
@@ -0,0 +1,192 @@
+package delegate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Publisher represents a broker the events are sent to so services outside
+// of this process can consume them.
+type Publisher interface {
+	Publish(ctx context.Context, topic string, msg []byte) error
+}
+
+// Encoder converts an event into the bytes sent to a publisher.
+type Encoder func(data Data) ([]byte, error)
+
+// Topics describes the topic each event is published on. The map is keyed
+// by "domain.action" or "domain" and the most specific key wins. Events
+// that aren't mapped are published on the prefix followed by
+// "domain.action".
+type Topics struct {
+	Prefix string
+	Map    map[string]string
+}
+
+// Topic returns the topic the event is published on.
+func (t Topics) Topic(data Data) string {
+	if topic, exists := t.Map[data.Domain+"."+data.Action]; exists {
+		return topic
+	}
+
+	if topic, exists := t.Map[data.Domain]; exists {
+		return topic
+	}
+
+	return t.Prefix + data.Domain + "." + data.Action
+}
+
+// ParseTopics parses a comma separated list of key:topic pairs in the form
+// of "user.deleted:users-deleted,product:products" into a topic map.
+func ParseTopics(prefix string, mappings string) (Topics, error) {
+	topics := Topics{
+		Prefix: prefix,
+		Map:    make(map[string]string),
+	}
+
+	for pair := range strings.SplitSeq(mappings, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		key, topic, found := strings.Cut(pair, ":")
+		if !found || key == "" || topic == "" {
+			return Topics{}, fmt.Errorf("invalid topic mapping %q", pair)
+		}
+
+		topics.Map[strings.TrimSpace(key)] = strings.TrimSpace(topic)
+	}
+
+	return topics, nil
+}
+
+// =============================================================================
+
+// event is the json form of an event sent to a publisher.
+type event struct {
+	Domain  string          `json:"domain"`
+	Action  string          `json:"action"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Changes []Change        `json:"changes,omitempty"`
+}
+
+// EncodeJSON encodes the event as a json document.
+func EncodeJSON(data Data) ([]byte, error) {
+	e := event{
+		Domain:  data.Domain,
+		Action:  data.Action,
+		Changes: data.Changes,
+	}
+
+	if len(data.RawParams) > 0 {
+		e.Params = data.RawParams
+	}
+
+	return json.Marshal(e)
+}
+
+// EncodeProtobuf encodes the event in the protobuf wire format using the
+// following message definition.
+//
+//	message Event {
+//	  string domain = 1;
+//	  string action = 2;
+//	  bytes params = 3;
+//	  repeated Change changes = 4;
+//	}
+//
+//	message Change {
+//	  string field = 1;
+//	  bytes old = 2;
+//	  bytes new = 3;
+//	}
+func EncodeProtobuf(data Data) ([]byte, error) {
+	var b []byte
+
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendString(b, data.Domain)
+
+	b = protowire.AppendTag(b, 2, protowire.BytesType)
+	b = protowire.AppendString(b, data.Action)
+
+	if len(data.RawParams) > 0 {
+		b = protowire.AppendTag(b, 3, protowire.BytesType)
+		b = protowire.AppendBytes(b, data.RawParams)
+	}
+
+	for _, chg := range data.Changes {
+		var c []byte
+
+		c = protowire.AppendTag(c, 1, protowire.BytesType)
+		c = protowire.AppendString(c, chg.Field)
+
+		if len(chg.Old) > 0 {
+			c = protowire.AppendTag(c, 2, protowire.BytesType)
+			c = protowire.AppendBytes(c, chg.Old)
+		}
+
+		if len(chg.New) > 0 {
+			c = protowire.AppendTag(c, 3, protowire.BytesType)
+			c = protowire.AppendBytes(c, chg.New)
+		}
+
+		b = protowire.AppendTag(b, 4, protowire.BytesType)
+		b = protowire.AppendBytes(b, c)
+	}
+
+	return b, nil
+}
+
+// ParseEncoder returns the encoder with the specified name, either json or
+// protobuf.
+func ParseEncoder(name string) (Encoder, error) {
+	switch strings.ToLower(name) {
+	case "json":
+		return EncodeJSON, nil
+	case "protobuf", "proto":
+		return EncodeProtobuf, nil
+	default:
+		return nil, fmt.Errorf("invalid encoding %q", name)
+	}
+}
+
+// =============================================================================
+
+// publisher represents a publisher along with how events are sent to it.
+type publisher struct {
+	pub    Publisher
+	topics Topics
+	encode Encoder
+	fields []string
+}
+
+// handler returns a function that sends the event to the publisher so it
+// can be executed like any other asynchronous function. The index of the
+// publisher names the function.
+func (p publisher) handler(i int) handler {
+	fn := func(ctx context.Context, data Data) error {
+		msg, err := p.encode(data)
+		if err != nil {
+			return fmt.Errorf("encode: %w", err)
+		}
+
+		topic := p.topics.Topic(data)
+
+		if err := p.pub.Publish(ctx, topic, msg); err != nil {
+			return fmt.Errorf("publish: topic[%s]: %w", topic, err)
+		}
+
+		return nil
+	}
+
+	return handler{
+		fn:   fn,
+		opts: HandlerOptions{async: true, fields: p.fields},
+		name: fmt.Sprintf("publisher-%d", i),
+	}
+}
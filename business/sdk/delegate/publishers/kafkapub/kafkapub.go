@@ -0,0 +1,58 @@
+// Package kafkapub provides a delegate publisher for Kafka.
+package kafkapub
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ardanlabs/service/foundation/otel"
+	"github.com/segmentio/kafka-go"
+)
+
+// Publisher manages the set of APIs for publishing events to Kafka.
+type Publisher struct {
+	writer *kafka.Writer
+}
+
+// New constructs a publisher that writes to the specified brokers. Topics
+// are created on first use if the brokers allow it.
+func New(brokers []string) *Publisher {
+	writer := kafka.Writer{
+		Addr:                   kafka.TCP(brokers...),
+		Balancer:               &kafka.LeastBytes{},
+		AllowAutoTopicCreation: true,
+	}
+
+	return &Publisher{
+		writer: &writer,
+	}
+}
+
+// Publish writes the event to the specified topic. The trace of the call
+// that raised the event travels in the message headers so consumers can
+// link back to it.
+func (p *Publisher) Publish(ctx context.Context, topic string, msg []byte) error {
+	m := kafka.Message{
+		Topic: topic,
+		Value: msg,
+	}
+
+	for k, v := range otel.InjectCarrier(ctx) {
+		m.Headers = append(m.Headers, kafka.Header{Key: k, Value: []byte(v)})
+	}
+
+	if err := p.writer.WriteMessages(ctx, m); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	return nil
+}
+
+// Close flushes the pending events and closes the writer.
+func (p *Publisher) Close() error {
+	if err := p.writer.Close(); err != nil {
+		return fmt.Errorf("close: %w", err)
+	}
+
+	return nil
+}
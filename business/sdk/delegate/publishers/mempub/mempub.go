@@ -0,0 +1,52 @@
+// Package mempub provides a delegate publisher that keeps the events in
+// memory. It's useful for tests and for running without a broker.
+package mempub
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/ardanlabs/service/foundation/otel"
+)
+
+// Message represents an event that was published. Headers holds the trace
+// of the call that raised the event, the way a broker would carry it.
+type Message struct {
+	Topic   string
+	Body    []byte
+	Headers map[string]string
+}
+
+// Publisher manages the set of APIs for publishing events in memory.
+type Publisher struct {
+	mu   sync.Mutex
+	msgs []Message
+}
+
+// New constructs a publisher that keeps the events in memory.
+func New() *Publisher {
+	return &Publisher{}
+}
+
+// Publish records the event on the specified topic.
+func (p *Publisher) Publish(ctx context.Context, topic string, msg []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.msgs = append(p.msgs, Message{
+		Topic:   topic,
+		Body:    slices.Clone(msg),
+		Headers: otel.InjectCarrier(ctx),
+	})
+
+	return nil
+}
+
+// Messages returns the events published so far.
+func (p *Publisher) Messages() []Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return slices.Clone(p.msgs)
+}
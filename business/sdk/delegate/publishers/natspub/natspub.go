@@ -0,0 +1,55 @@
+// Package natspub provides a delegate publisher for NATS.
+package natspub
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ardanlabs/service/foundation/otel"
+	"github.com/nats-io/nats.go"
+)
+
+// Publisher manages the set of APIs for publishing events to NATS.
+type Publisher struct {
+	conn *nats.Conn
+}
+
+// New constructs a publisher connected to the NATS servers at the
+// specified url.
+func New(url string, name string) (*Publisher, error) {
+	conn, err := nats.Connect(url, nats.Name(name))
+	if err != nil {
+		return nil, fmt.Errorf("connect: %w", err)
+	}
+
+	return &Publisher{
+		conn: conn,
+	}, nil
+}
+
+// Publish sends the event on the specified subject. The trace of the call
+// that raised the event travels in the message headers so consumers can
+// link back to it.
+func (p *Publisher) Publish(ctx context.Context, topic string, msg []byte) error {
+	m := nats.NewMsg(topic)
+	m.Data = msg
+
+	for k, v := range otel.InjectCarrier(ctx) {
+		m.Header.Set(k, v)
+	}
+
+	if err := p.conn.PublishMsg(m); err != nil {
+		return fmt.Errorf("publish: %w", err)
+	}
+
+	return nil
+}
+
+// Close flushes the pending events and closes the connection.
+func (p *Publisher) Close() error {
+	if err := p.conn.Drain(); err != nil {
+		return fmt.Errorf("drain: %w", err)
+	}
+
+	return nil
+}
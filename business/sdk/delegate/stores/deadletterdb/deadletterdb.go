@@ -0,0 +1,165 @@
+// Package deadletterdb contains the database storage for the dead letters
+// of the delegate.
+package deadletterdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ardanlabs/service/business/sdk/delegate"
+	"github.com/ardanlabs/service/business/sdk/page"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Store manages the set of APIs for dead letter database access.
+type Store struct {
+	log *logger.Logger
+	db  sqlx.ExtContext
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *logger.Logger, db sqlx.ExtContext) *Store {
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// Create inserts a new dead letter into the database.
+func (s *Store) Create(ctx context.Context, dl delegate.DeadLetter) error {
+	dbDL, err := toDBDeadLetter(dl)
+	if err != nil {
+		return err
+	}
+
+	const q = `
+	INSERT INTO delegate_dead_letters
+		(dead_letter_id, handler, domain, action, params, changes, error, attempts, date_created, date_updated)
+	VALUES
+		(:dead_letter_id, :handler, :domain, :action, :params, :changes, :error, :attempts, :date_created, :date_updated)`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, dbDL); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Update replaces a dead letter document in the database.
+func (s *Store) Update(ctx context.Context, dl delegate.DeadLetter) error {
+	dbDL, err := toDBDeadLetter(dl)
+	if err != nil {
+		return err
+	}
+
+	const q = `
+	UPDATE
+		delegate_dead_letters
+	SET
+		"error" = :error,
+		"attempts" = :attempts,
+		"date_updated" = :date_updated
+	WHERE
+		dead_letter_id = :dead_letter_id`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, dbDL); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes a dead letter from the database.
+func (s *Store) Delete(ctx context.Context, dl delegate.DeadLetter) error {
+	data := struct {
+		ID string `db:"dead_letter_id"`
+	}{
+		ID: dl.ID.String(),
+	}
+
+	const q = `
+	DELETE FROM
+		delegate_dead_letters
+	WHERE
+		dead_letter_id = :dead_letter_id`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Query retrieves a page of the dead letters from the database, the most
+// recent first.
+func (s *Store) Query(ctx context.Context, page page.Page) ([]delegate.DeadLetter, error) {
+	data := map[string]any{
+		"offset":        (page.Number() - 1) * page.RowsPerPage(),
+		"rows_per_page": page.RowsPerPage(),
+	}
+
+	const q = `
+	SELECT
+		dead_letter_id, handler, domain, action, params, changes, error, attempts, date_created, date_updated
+	FROM
+		delegate_dead_letters
+	ORDER BY
+		date_created DESC
+	OFFSET :offset ROWS FETCH NEXT :rows_per_page ROWS ONLY`
+
+	var dbDLs []deadLetter
+	if err := sqldb.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbDLs); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toBusDeadLetters(dbDLs)
+}
+
+// Count returns the total number of dead letters in the DB.
+func (s *Store) Count(ctx context.Context) (int, error) {
+	const q = `
+	SELECT
+		count(1)
+	FROM
+		delegate_dead_letters`
+
+	var count struct {
+		Count int `db:"count"`
+	}
+	if err := sqldb.QueryStruct(ctx, s.log, s.db, q, &count); err != nil {
+		return 0, fmt.Errorf("db: %w", err)
+	}
+
+	return count.Count, nil
+}
+
+// QueryByID gets the specified dead letter from the database.
+func (s *Store) QueryByID(ctx context.Context, id uuid.UUID) (delegate.DeadLetter, error) {
+	data := struct {
+		ID string `db:"dead_letter_id"`
+	}{
+		ID: id.String(),
+	}
+
+	const q = `
+	SELECT
+		dead_letter_id, handler, domain, action, params, changes, error, attempts, date_created, date_updated
+	FROM
+		delegate_dead_letters
+	WHERE
+		dead_letter_id = :dead_letter_id`
+
+	var dbDL deadLetter
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbDL); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return delegate.DeadLetter{}, fmt.Errorf("db: %w", delegate.ErrDeadLetterNotFound)
+		}
+		return delegate.DeadLetter{}, fmt.Errorf("db: %w", err)
+	}
+
+	return toBusDeadLetter(dbDL)
+}
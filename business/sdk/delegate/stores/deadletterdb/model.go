@@ -0,0 +1,89 @@
+package deadletterdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ardanlabs/service/business/sdk/delegate"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx/types"
+)
+
+type deadLetter struct {
+	ID          uuid.UUID          `db:"dead_letter_id"`
+	Handler     string             `db:"handler"`
+	Domain      string             `db:"domain"`
+	Action      string             `db:"action"`
+	Params      []byte             `db:"params"`
+	Changes     types.NullJSONText `db:"changes"`
+	Error       string             `db:"error"`
+	Attempts    int                `db:"attempts"`
+	DateCreated time.Time          `db:"date_created"`
+	DateUpdated time.Time          `db:"date_updated"`
+}
+
+func toDBDeadLetter(bus delegate.DeadLetter) (deadLetter, error) {
+	db := deadLetter{
+		ID:          bus.ID,
+		Handler:     bus.Handler,
+		Domain:      bus.Data.Domain,
+		Action:      bus.Data.Action,
+		Params:      bus.Data.RawParams,
+		Error:       bus.Error,
+		Attempts:    bus.Attempts,
+		DateCreated: bus.DateCreated.UTC(),
+		DateUpdated: bus.DateUpdated.UTC(),
+	}
+
+	if len(bus.Data.Changes) > 0 {
+		changes, err := json.Marshal(bus.Data.Changes)
+		if err != nil {
+			return deadLetter{}, fmt.Errorf("marshal changes: %w", err)
+		}
+
+		db.Changes = types.NullJSONText{JSONText: changes, Valid: true}
+	}
+
+	return db, nil
+}
+
+func toBusDeadLetter(db deadLetter) (delegate.DeadLetter, error) {
+	var changes []delegate.Change
+	if db.Changes.Valid {
+		if err := json.Unmarshal(db.Changes.JSONText, &changes); err != nil {
+			return delegate.DeadLetter{}, fmt.Errorf("unmarshal changes: %w", err)
+		}
+	}
+
+	bus := delegate.DeadLetter{
+		ID:      db.ID,
+		Handler: db.Handler,
+		Data: delegate.Data{
+			Domain:    db.Domain,
+			Action:    db.Action,
+			RawParams: db.Params,
+			Changes:   changes,
+		},
+		Error:       db.Error,
+		Attempts:    db.Attempts,
+		DateCreated: db.DateCreated.In(time.Local),
+		DateUpdated: db.DateUpdated.In(time.Local),
+	}
+
+	return bus, nil
+}
+
+func toBusDeadLetters(dbs []deadLetter) ([]delegate.DeadLetter, error) {
+	bus := make([]delegate.DeadLetter, len(dbs))
+
+	for i, db := range dbs {
+		var err error
+		bus[i], err = toBusDeadLetter(db)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return bus, nil
+}
@@ -0,0 +1,260 @@
+// Package jobs provides support for running periodic background work.
+//
+// Domains describe their work as jobs and the service registers them with a
+// runner. When the service runs more than one instance, a leader is elected
+// so each job only runs on one of them at a time. The instance that leads
+// runs every job, the others wait to take over if it goes away.
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ardanlabs/service/foundation/clock"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Schedule reports when a job runs next. The zero time means it never runs
+// again. A cron schedule from the schedule type satisfies it.
+type Schedule interface {
+	Next(t time.Time) time.Time
+}
+
+// Job represents a unit of periodic work. A job that is still running when
+// it's due again is skipped until the next time, so runs never overlap.
+type Job struct {
+	Name     string
+	Schedule Schedule
+	Timeout  time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Leader decides which instance runs the jobs. A nil leader means every
+// instance runs them, which is fine for a single instance.
+type Leader interface {
+	Acquire(ctx context.Context) (bool, error)
+	Check(ctx context.Context) error
+	Release(ctx context.Context) error
+}
+
+// Runner manages the set of jobs and runs each one on its schedule while
+// the instance is the leader.
+type Runner struct {
+	log     *logger.Logger
+	leader  Leader
+	opts    Options
+	mu      sync.Mutex
+	jobs    []Job
+	running map[string]bool
+	wg      sync.WaitGroup
+}
+
+// New constructs a runner for the jobs that will be registered.
+func New(log *logger.Logger, leader Leader, options ...func(opts *Options)) *Runner {
+	opts := Options{
+		campaign: 15 * time.Second,
+	}
+
+	for _, option := range options {
+		option(&opts)
+	}
+
+	return &Runner{
+		log:     log,
+		leader:  leader,
+		opts:    opts,
+		running: make(map[string]bool),
+	}
+}
+
+// Register adds a job to be run. Jobs registered after Run is called are
+// picked up the next time the instance becomes the leader.
+func (r *Runner) Register(job Job) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.jobs = append(r.jobs, job)
+}
+
+// Run campaigns to be the leader and runs the jobs while it is, until the
+// context is canceled. It waits for the jobs that are running to return
+// before it does.
+func (r *Runner) Run(ctx context.Context) {
+	for {
+		leading, err := r.acquire(ctx)
+		switch {
+		case err != nil:
+			r.log.Error(ctx, "jobs: acquire leadership", "ERROR", err)
+
+		case leading:
+			r.log.Info(ctx, "jobs: leadership", "status", "acquired")
+			r.lead(ctx)
+			r.log.Info(ctx, "jobs: leadership", "status", "released")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-time.After(r.opts.campaign):
+		}
+	}
+}
+
+// =============================================================================
+
+func (r *Runner) acquire(ctx context.Context) (bool, error) {
+	if r.leader == nil {
+		return true, nil
+	}
+
+	return r.leader.Acquire(ctx)
+}
+
+// lead runs the jobs on their schedules until the context is canceled or
+// the leadership is lost. The jobs still running are canceled and waited
+// on before the leadership is released.
+func (r *Runner) lead(ctx context.Context) {
+	if r.leader != nil {
+		defer func() {
+			if err := r.leader.Release(context.WithoutCancel(ctx)); err != nil {
+				r.log.Error(ctx, "jobs: release leadership", "ERROR", err)
+			}
+		}()
+	}
+	defer r.wg.Wait()
+
+	leadCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	r.mu.Lock()
+	jobs := make([]Job, len(r.jobs))
+	copy(jobs, r.jobs)
+	r.mu.Unlock()
+
+	now := clock.Now()
+
+	next := make([]time.Time, len(jobs))
+	for i, job := range jobs {
+		next[i] = job.Schedule.Next(now)
+	}
+
+	check := time.NewTicker(r.opts.campaign)
+	defer check.Stop()
+
+	for {
+		var timer *time.Timer
+		var due <-chan time.Time
+
+		if earliest := earliest(next); !earliest.IsZero() {
+			timer = time.NewTimer(earliest.Sub(clock.Now()))
+			due = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-check.C:
+			if r.leader != nil {
+				if err := r.leader.Check(ctx); err != nil {
+					r.log.Error(ctx, "jobs: leadership lost", "ERROR", err)
+					return
+				}
+			}
+
+		case <-due:
+			now := clock.Now()
+
+			for i, job := range jobs {
+				if next[i].IsZero() || next[i].After(now) {
+					continue
+				}
+
+				r.start(leadCtx, job)
+				next[i] = job.Schedule.Next(now)
+			}
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// start runs the job on its own G unless the previous run hasn't returned.
+func (r *Runner) start(ctx context.Context, job Job) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.running[job.Name] {
+		r.log.Info(ctx, "jobs: run", "job", job.Name, "status", "skipped, still running")
+		return
+	}
+
+	r.running[job.Name] = true
+	r.wg.Add(1)
+
+	go func() {
+		defer func() {
+			r.mu.Lock()
+			delete(r.running, job.Name)
+			r.mu.Unlock()
+
+			r.wg.Done()
+		}()
+
+		if err := r.run(ctx, job); err != nil {
+			r.log.Error(ctx, "jobs: run", "job", job.Name, "ERROR", err)
+		}
+	}()
+}
+
+func (r *Runner) run(ctx context.Context, job Job) (err error) {
+	ctx, span := otel.AddSpan(ctx, "business.sdk.jobs.run", attribute.String("job", job.Name))
+	defer span.End()
+
+	if job.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, job.Timeout)
+		defer cancel()
+	}
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("panic: %v", rec)
+		}
+	}()
+
+	start := time.Now()
+	r.log.Info(ctx, "jobs: run", "job", job.Name, "status", "started")
+
+	if err := job.Run(ctx); err != nil {
+		return err
+	}
+
+	r.log.Info(ctx, "jobs: run", "job", job.Name, "status", "completed", "took", time.Since(start).String())
+
+	return nil
+}
+
+// earliest returns the soonest of the times that aren't zero.
+func earliest(times []time.Time) time.Time {
+	var t time.Time
+
+	for _, tm := range times {
+		if tm.IsZero() {
+			continue
+		}
+
+		if t.IsZero() || tm.Before(t) {
+			t = tm
+		}
+	}
+
+	return t
+}
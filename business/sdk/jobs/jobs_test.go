@@ -0,0 +1,182 @@
+package jobs_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/service/business/sdk/jobs"
+	"github.com/ardanlabs/service/business/types/schedule"
+	"github.com/ardanlabs/service/foundation/logger"
+)
+
+type leader struct {
+	mu       sync.Mutex
+	acquire  bool
+	checkErr error
+	released int
+}
+
+func (l *leader) Acquire(ctx context.Context) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.acquire, nil
+}
+
+func (l *leader) Check(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.checkErr
+}
+
+func (l *leader) Release(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.released++
+	return nil
+}
+
+func newLog() *logger.Logger {
+	var buf bytes.Buffer
+	return logger.New(&buf, logger.LevelInfo, "TEST", func(context.Context) string { return "" })
+}
+
+// runFor runs the runner until the duration passes and waits for it to
+// return.
+func runFor(r *jobs.Runner, d time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+
+	r.Run(ctx)
+}
+
+func Test_Run(t *testing.T) {
+	r := jobs.New(newLog(), nil, jobs.WithCampaignInterval(10*time.Millisecond))
+
+	var calls atomic.Int32
+	r.Register(jobs.Job{
+		Name:     "count",
+		Schedule: jobs.Every(10 * time.Millisecond),
+		Run: func(ctx context.Context) error {
+			calls.Add(1)
+			return errors.New("errors are logged and the job keeps running")
+		},
+	})
+
+	runFor(r, 200*time.Millisecond)
+
+	if n := calls.Load(); n < 2 {
+		t.Fatalf("expected the job to run at least twice, got %d", n)
+	}
+}
+
+func Test_Follower(t *testing.T) {
+	ldr := leader{acquire: false}
+	r := jobs.New(newLog(), &ldr, jobs.WithCampaignInterval(10*time.Millisecond))
+
+	var calls atomic.Int32
+	r.Register(jobs.Job{
+		Name:     "count",
+		Schedule: jobs.Every(10 * time.Millisecond),
+		Run: func(ctx context.Context) error {
+			calls.Add(1)
+			return nil
+		},
+	})
+
+	runFor(r, 100*time.Millisecond)
+
+	if n := calls.Load(); n != 0 {
+		t.Fatalf("expected a follower not to run jobs, got %d runs", n)
+	}
+}
+
+func Test_LeadershipLost(t *testing.T) {
+	ldr := leader{acquire: true, checkErr: errors.New("connection lost")}
+	r := jobs.New(newLog(), &ldr, jobs.WithCampaignInterval(20*time.Millisecond))
+
+	canceled := make(chan struct{}, 1)
+	r.Register(jobs.Job{
+		Name:     "block",
+		Schedule: jobs.Every(time.Millisecond),
+		Run: func(ctx context.Context) error {
+			<-ctx.Done()
+			select {
+			case canceled <- struct{}{}:
+			default:
+			}
+			return ctx.Err()
+		},
+	})
+
+	runFor(r, 30*time.Millisecond)
+
+	select {
+	case <-canceled:
+	default:
+		t.Fatal("expected the running job to be canceled when leadership was lost")
+	}
+
+	ldr.mu.Lock()
+	defer ldr.mu.Unlock()
+
+	if ldr.released == 0 {
+		t.Fatal("expected the leadership to be released")
+	}
+}
+
+func Test_NoOverlap(t *testing.T) {
+	r := jobs.New(newLog(), nil, jobs.WithCampaignInterval(10*time.Millisecond))
+
+	var running, most atomic.Int32
+	r.Register(jobs.Job{
+		Name:     "slow",
+		Schedule: jobs.Every(time.Millisecond),
+		Run: func(ctx context.Context) error {
+			n := running.Add(1)
+			defer running.Add(-1)
+
+			if n > most.Load() {
+				most.Store(n)
+			}
+
+			time.Sleep(20 * time.Millisecond)
+			return nil
+		},
+	})
+
+	runFor(r, 100*time.Millisecond)
+
+	if n := most.Load(); n != 1 {
+		t.Fatalf("expected runs of a job not to overlap, got %d at once", n)
+	}
+}
+
+func Test_Schedule(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		sched jobs.Schedule
+		exp   time.Time
+	}{
+		{"every", jobs.Every(time.Hour), now.Add(time.Hour)},
+		{"never", jobs.Every(0), time.Time{}},
+		{"cron", schedule.MustParse("@daily"), time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.sched.Next(now); !got.Equal(tt.exp) {
+				t.Fatalf("expected %v, got %v", tt.exp, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// AdvisoryLock elects a leader with a Postgres session level advisory lock.
+// The lock is held on a dedicated connection for as long as the instance
+// leads, so it's released by the database if the instance goes away. It
+// isn't safe for concurrent use, a runner is expected to own it.
+type AdvisoryLock struct {
+	db   *sqlx.DB
+	key  int64
+	conn *sql.Conn
+}
+
+// NewAdvisoryLock constructs a leader for the named group of instances.
+// Instances using the same name compete for the same lock.
+func NewAdvisoryLock(db *sqlx.DB, name string) *AdvisoryLock {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+
+	return &AdvisoryLock{
+		db:  db,
+		key: int64(h.Sum64()),
+	}
+}
+
+// Acquire tries to take the lock. False is returned when another instance
+// holds it.
+func (l *AdvisoryLock) Acquire(ctx context.Context) (bool, error) {
+	if l.conn != nil {
+		return true, nil
+	}
+
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return false, fmt.Errorf("conn: %w", err)
+	}
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", l.key).Scan(&locked); err != nil {
+		conn.Close()
+		return false, fmt.Errorf("trylock: %w", err)
+	}
+
+	if !locked {
+		conn.Close()
+		return false, nil
+	}
+
+	l.conn = conn
+
+	return true, nil
+}
+
+// Check verifies the connection holding the lock is still alive. The lock
+// is lost with the connection.
+func (l *AdvisoryLock) Check(ctx context.Context) error {
+	if l.conn == nil {
+		return errors.New("lock not held")
+	}
+
+	if err := l.conn.PingContext(ctx); err != nil {
+		l.conn.Close()
+		l.conn = nil
+		return fmt.Errorf("ping: %w", err)
+	}
+
+	return nil
+}
+
+// Release gives up the lock so another instance can take it.
+func (l *AdvisoryLock) Release(ctx context.Context) error {
+	if l.conn == nil {
+		return nil
+	}
+
+	defer func() {
+		l.conn.Close()
+		l.conn = nil
+	}()
+
+	if _, err := l.conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", l.key); err != nil {
+		return fmt.Errorf("unlock: %w", err)
+	}
+
+	return nil
+}
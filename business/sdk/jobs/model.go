@@ -0,0 +1,36 @@
+package jobs
+
+import "time"
+
+// Options represents optional parameters for the runner.
+type Options struct {
+	campaign time.Duration
+}
+
+// WithCampaignInterval sets how often an instance that isn't the leader
+// tries to become it, and how often the leader checks it still is.
+func WithCampaignInterval(d time.Duration) func(opts *Options) {
+	return func(opts *Options) {
+		opts.campaign = d
+	}
+}
+
+// =============================================================================
+
+// every is a schedule that repeats on a fixed interval.
+type every time.Duration
+
+// Every returns a schedule that runs a job on a fixed interval, counted from
+// when the instance became the leader.
+func Every(interval time.Duration) Schedule {
+	return every(interval)
+}
+
+// Next returns the time one interval after t.
+func (e every) Next(t time.Time) time.Time {
+	if e <= 0 {
+		return time.Time{}
+	}
+
+	return t.Add(time.Duration(e))
+}
@@ -2,11 +2,13 @@
 package migrate
 
 import (
+	"cmp"
 	"context"
 	"database/sql"
 	_ "embed"
 	"errors"
 	"fmt"
+	"slices"
 
 	"github.com/ardanlabs/darwin/v3"
 	"github.com/ardanlabs/darwin/v3/dialects/postgres"
@@ -23,20 +25,128 @@ var (
 	seedDoc string
 )
 
+// Migration represents a migration defined in this package.
+type Migration struct {
+	Version     float64
+	Description string
+	Script      string
+}
+
+// Migrations returns the migrations defined in this package in the order
+// they are applied.
+func Migrations() []Migration {
+	migs := darwin.ParseMigrations(migrateDoc)
+
+	out := make([]Migration, len(migs))
+	for i, mig := range migs {
+		out[i] = Migration{
+			Version:     mig.Version,
+			Description: mig.Description,
+			Script:      mig.Script,
+		}
+	}
+
+	slices.SortFunc(out, func(a, b Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+
+	return out
+}
+
+// Latest returns the version of the last migration defined in this package.
+func Latest() float64 {
+	migs := Migrations()
+	if len(migs) == 0 {
+		return 0
+	}
+
+	return migs[len(migs)-1].Version
+}
+
 // Migrate attempts to bring the database up to date with the migrations
 // defined in this package.
 func Migrate(ctx context.Context, db *sqlx.DB) error {
+	d, _, err := newDarwin(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	return d.Migrate()
+}
+
+// Version returns the version of the last migration applied to the
+// database, zero if none have been.
+func Version(ctx context.Context, db *sqlx.DB) (float64, error) {
+	_, driver, err := newDarwin(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+
+	return appliedVersion(driver)
+}
+
+// appliedVersion returns the version of the last migration recorded as
+// applied, creating the table they are recorded in if needed.
+func appliedVersion(driver *generic.Driver) (float64, error) {
+	if err := driver.Create(); err != nil {
+		return 0, fmt.Errorf("create migrations table: %w", err)
+	}
+
+	records, err := driver.All()
+	if err != nil {
+		return 0, fmt.Errorf("query applied migrations: %w", err)
+	}
+
+	var version float64
+	for _, rec := range records {
+		version = max(version, rec.Version)
+	}
+
+	return version, nil
+}
+
+// Plan returns the migrations Migrate would apply to the database, in the
+// order it would apply them, without applying any. The migrations already
+// applied are validated against the ones defined so a plan that Migrate
+// would refuse to run fails the same way.
+func Plan(ctx context.Context, db *sqlx.DB) ([]Migration, error) {
+	d, driver, err := newDarwin(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := appliedVersion(driver)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.Validate(); err != nil {
+		return nil, fmt.Errorf("validate: %w", err)
+	}
+
+	var planned []Migration
+	for _, mig := range Migrations() {
+		if mig.Version > version {
+			planned = append(planned, mig)
+		}
+	}
+
+	return planned, nil
+}
+
+// newDarwin checks the database can be reached and constructs the darwin
+// value for the migrations defined in this package.
+func newDarwin(ctx context.Context, db *sqlx.DB) (darwin.Darwin, *generic.Driver, error) {
 	if err := sqldb.StatusCheck(ctx, db); err != nil {
-		return fmt.Errorf("status check database: %w", err)
+		return darwin.Darwin{}, nil, fmt.Errorf("status check database: %w", err)
 	}
 
 	driver, err := generic.New(db.DB, postgres.Dialect{})
 	if err != nil {
-		return fmt.Errorf("construct darwin driver: %w", err)
+		return darwin.Darwin{}, nil, fmt.Errorf("construct darwin driver: %w", err)
 	}
 
-	d := darwin.New(driver, darwin.ParseMigrations(migrateDoc))
-	return d.Migrate()
+	return darwin.New(driver, darwin.ParseMigrations(migrateDoc)), driver, nil
 }
 
 // Seed runs the seed document defined in this package against db. The queries
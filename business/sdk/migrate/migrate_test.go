@@ -0,0 +1,56 @@
+package migrate_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ardanlabs/service/business/sdk/dbtest"
+	"github.com/ardanlabs/service/business/sdk/migrate"
+)
+
+func Test_Migrate(t *testing.T) {
+	t.Parallel()
+
+	db := dbtest.New(t, "Test_Migrate")
+
+	ctx := context.Background()
+
+	version, err := migrate.Version(ctx, db.DB)
+	if err != nil {
+		t.Fatalf("Should be able to query the version: %s", err)
+	}
+
+	if version != migrate.Latest() {
+		t.Fatalf("Should be at the latest version: got %v, exp %v", version, migrate.Latest())
+	}
+
+	migs, err := migrate.Plan(ctx, db.DB)
+	if err != nil {
+		t.Fatalf("Should be able to plan the migrations: %s", err)
+	}
+
+	if len(migs) != 0 {
+		t.Fatalf("Should have nothing left to apply: %d", len(migs))
+	}
+}
+
+func Test_Migrations(t *testing.T) {
+	migs := migrate.Migrations()
+	if len(migs) == 0 {
+		t.Fatal("Should have migrations defined")
+	}
+
+	for i := 1; i < len(migs); i++ {
+		if migs[i].Version <= migs[i-1].Version {
+			t.Fatalf("Should have increasing versions: %v after %v", migs[i].Version, migs[i-1].Version)
+		}
+
+		if migs[i].Description == "" || migs[i].Script == "" {
+			t.Fatalf("Should have a description and script: %v", migs[i].Version)
+		}
+	}
+
+	if migrate.Latest() != migs[len(migs)-1].Version {
+		t.Fatalf("Should report the last version as latest: %v", migrate.Latest())
+	}
+}
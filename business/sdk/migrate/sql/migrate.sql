@@ -79,4 +79,450 @@ CREATE TABLE audit (
     timestamp   TIMESTAMP NOT NULL,
 
     PRIMARY KEY (id)
-);
\ No newline at end of file
+);
+
+-- Version: 1.06
+-- Description: Create table user_password_history
+CREATE TABLE user_password_history (
+    user_id       UUID       NOT NULL,
+    password_hash TEXT       NOT NULL,
+    date_created  TIMESTAMP  NOT NULL,
+
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+-- Version: 1.07
+-- Description: Create tables for report subscriptions
+CREATE TABLE report_subscriptions (
+    subscription_id UUID       NOT NULL,
+    user_id         UUID       NOT NULL,
+    report          TEXT       NOT NULL,
+    schedule        TEXT       NOT NULL,
+    channel         TEXT       NOT NULL,
+    target          TEXT       NOT NULL,
+    enabled         BOOLEAN    NOT NULL,
+    last_run        TIMESTAMP  NULL,
+    next_run        TIMESTAMP  NOT NULL,
+    date_created    TIMESTAMP  NOT NULL,
+    date_updated    TIMESTAMP  NOT NULL,
+
+    PRIMARY KEY (subscription_id),
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+CREATE TABLE report_deliveries (
+    delivery_id     UUID       NOT NULL,
+    subscription_id UUID       NOT NULL,
+    status          TEXT       NOT NULL,
+    error           TEXT       NULL,
+    date_created    TIMESTAMP  NOT NULL,
+
+    PRIMARY KEY (delivery_id),
+    FOREIGN KEY (subscription_id) REFERENCES report_subscriptions(subscription_id) ON DELETE CASCADE
+);
+
+-- Version: 1.08
+-- Description: Create table email_templates
+CREATE TABLE email_templates (
+    template_id  UUID       NOT NULL,
+    name         TEXT       NOT NULL,
+    locale       TEXT       NOT NULL,
+    subject      TEXT       NOT NULL,
+    body         TEXT       NOT NULL,
+    enabled      BOOLEAN    NOT NULL,
+    date_created TIMESTAMP  NOT NULL,
+    date_updated TIMESTAMP  NOT NULL,
+
+    PRIMARY KEY (template_id),
+    UNIQUE (name, locale)
+);
+
+-- Version: 1.09
+-- Description: Add one-time code support to users
+ALTER TABLE users
+    ADD COLUMN totp_secret  TEXT     NULL,
+    ADD COLUMN totp_enabled BOOLEAN  NOT NULL DEFAULT FALSE;
+
+CREATE TABLE user_recovery_codes (
+    user_id      UUID       NOT NULL,
+    code_hash    TEXT       NOT NULL,
+    date_created TIMESTAMP  NOT NULL,
+
+    PRIMARY KEY (user_id, code_hash),
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+-- Version: 1.10
+-- Description: Create table api_keys
+CREATE TABLE api_keys (
+    key_id         UUID       NOT NULL,
+    user_id        UUID       NOT NULL,
+    name           TEXT       NOT NULL,
+    prefix         TEXT       NOT NULL,
+    key_hash       TEXT       NOT NULL,
+    date_created   TIMESTAMP  NOT NULL,
+    date_last_used TIMESTAMP  NULL,
+    date_revoked   TIMESTAMP  NULL,
+
+    PRIMARY KEY (key_id),
+    UNIQUE (prefix),
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+-- Version: 1.11
+-- Description: Create table sessions
+CREATE TABLE sessions (
+    session_id     UUID       NOT NULL,
+    user_id        UUID       NOT NULL,
+    device         TEXT       NOT NULL,
+    token_hash     TEXT       NOT NULL,
+    previous_hash  TEXT       NULL,
+    date_created   TIMESTAMP  NOT NULL,
+    date_expires   TIMESTAMP  NOT NULL,
+    date_last_used TIMESTAMP  NOT NULL,
+    date_revoked   TIMESTAMP  NULL,
+
+    PRIMARY KEY (session_id),
+    UNIQUE (token_hash),
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+CREATE INDEX sessions_previous_hash_idx ON sessions (previous_hash);
+
+-- Version: 1.12
+-- Description: Add manager to users
+ALTER TABLE users
+    ADD COLUMN manager_id UUID NULL REFERENCES users(user_id) ON DELETE SET NULL;
+
+CREATE INDEX users_manager_id_idx ON users (manager_id);
+
+-- Version: 1.13
+-- Description: Add federated identities to users
+ALTER TABLE users
+    ALTER COLUMN password_hash DROP NOT NULL;
+
+CREATE TABLE user_identities (
+    provider     TEXT       NOT NULL,
+    external_id  TEXT       NOT NULL,
+    user_id      UUID       NOT NULL,
+    email        TEXT       NOT NULL,
+    date_created TIMESTAMP  NOT NULL,
+
+    PRIMARY KEY (provider, external_id),
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+CREATE INDEX user_identities_user_id_idx ON user_identities (user_id);
+
+-- Version: 1.14
+-- Description: Create table saved_searches
+CREATE TABLE saved_searches (
+    search_id       UUID       NOT NULL,
+    user_id         UUID       NOT NULL,
+    name            TEXT       NOT NULL,
+    domain          TEXT       NOT NULL,
+    filter          JSONB      NOT NULL,
+    order_field     TEXT       NOT NULL,
+    order_direction TEXT       NOT NULL,
+    shared          BOOLEAN    NOT NULL,
+    date_created    TIMESTAMP  NOT NULL,
+    date_updated    TIMESTAMP  NOT NULL,
+
+    PRIMARY KEY (search_id),
+    UNIQUE (user_id, domain, name),
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+-- Version: 1.15
+-- Description: Add full text search to users
+ALTER TABLE users
+    ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (to_tsvector('simple', name || ' ' || email)) STORED;
+
+CREATE INDEX users_search_vector_idx ON users USING GIN (search_vector);
+
+-- Version: 1.16
+-- Description: Create tables for bulk role assignments
+CREATE TABLE role_assignments (
+    assignment_id UUID       NOT NULL,
+    actor_id      UUID       NOT NULL,
+    add_roles     TEXT[]     NOT NULL,
+    remove_roles  TEXT[]     NOT NULL,
+    affected      INT        NOT NULL,
+    revert_hash   TEXT       NULL,
+    date_created  TIMESTAMP  NOT NULL,
+    date_applied  TIMESTAMP  NULL,
+    date_reverted TIMESTAMP  NULL,
+
+    PRIMARY KEY (assignment_id),
+    UNIQUE (revert_hash),
+    FOREIGN KEY (actor_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+CREATE TABLE role_assignment_changes (
+    assignment_id UUID    NOT NULL,
+    user_id       UUID    NOT NULL,
+    old_roles     TEXT[]  NOT NULL,
+    new_roles     TEXT[]  NOT NULL,
+
+    PRIMARY KEY (assignment_id, user_id),
+    FOREIGN KEY (assignment_id) REFERENCES role_assignments(assignment_id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+-- Version: 1.17
+-- Description: Add the fields that break ties to saved searches
+ALTER TABLE saved_searches
+    ADD COLUMN order_then JSONB NOT NULL DEFAULT '[]';
+
+-- Version: 1.18
+-- Description: Add the version used to detect concurrent user updates
+ALTER TABLE users
+    ADD COLUMN version INT NOT NULL DEFAULT 1;
+
+-- Version: 1.19
+-- Description: Create table user_idempotency_keys
+CREATE TABLE user_idempotency_keys (
+    actor_id     UUID       NOT NULL,
+    key          TEXT       NOT NULL,
+    user_id      UUID       NOT NULL,
+    date_created TIMESTAMP  NOT NULL,
+
+    PRIMARY KEY (actor_id, key),
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+CREATE INDEX user_idempotency_keys_date_created_idx ON user_idempotency_keys (date_created);
+
+-- Version: 1.20
+-- Description: Create table user_preferences
+CREATE TABLE user_preferences (
+    user_id      UUID       NOT NULL,
+    key          TEXT       NOT NULL,
+    value        JSONB      NOT NULL,
+    date_updated TIMESTAMP  NOT NULL,
+
+    PRIMARY KEY (user_id, key),
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+-- Version: 1.21
+-- Description: Add the key of the user's avatar in blob storage
+ALTER TABLE users
+    ADD COLUMN avatar_key TEXT NULL;
+
+-- Version: 1.22
+-- Description: Create tables groups and group_members
+CREATE TABLE groups (
+    group_id     UUID       NOT NULL,
+    name         TEXT       NOT NULL,
+    description  TEXT       NOT NULL,
+    date_created TIMESTAMP  NOT NULL,
+    date_updated TIMESTAMP  NOT NULL,
+
+    PRIMARY KEY (group_id),
+    UNIQUE (name)
+);
+
+-- There is no foreign key to users, the group domain removes the
+-- memberships of a deleted user when the user domain tells it to.
+CREATE TABLE group_members (
+    group_id   UUID       NOT NULL,
+    user_id    UUID       NOT NULL,
+    role       TEXT       NOT NULL,
+    date_added TIMESTAMP  NOT NULL,
+
+    PRIMARY KEY (group_id, user_id),
+    FOREIGN KEY (group_id) REFERENCES groups(group_id) ON DELETE CASCADE
+);
+
+CREATE INDEX group_members_user_id_idx ON group_members (user_id);
+
+-- Version: 1.23
+-- Description: Create tables permissions and role_permissions
+CREATE TABLE permissions (
+    name         TEXT       NOT NULL,
+    description  TEXT       NOT NULL,
+    date_created TIMESTAMP  NOT NULL,
+
+    PRIMARY KEY (name)
+);
+
+CREATE TABLE role_permissions (
+    role         TEXT       NOT NULL,
+    permission   TEXT       NOT NULL,
+    date_created TIMESTAMP  NOT NULL,
+
+    PRIMARY KEY (role, permission),
+    FOREIGN KEY (permission) REFERENCES permissions(name) ON DELETE CASCADE
+);
+
+-- Admins can manage permissions from the start, otherwise nobody could.
+INSERT INTO permissions (name, description, date_created) VALUES
+    ('permission:manage', 'Manage permissions and the roles they are granted to', NOW());
+
+INSERT INTO role_permissions (role, permission, date_created) VALUES
+    ('ADMIN', 'permission:manage', NOW());
+
+-- Version: 1.24
+-- Description: Add the actors that created and last updated users
+ALTER TABLE users
+    ADD COLUMN created_by UUID NULL,
+    ADD COLUMN updated_by UUID NULL;
+
+-- Version: 1.25
+-- Description: Create table invites
+CREATE TABLE invites (
+    invite_id     UUID       NOT NULL,
+    email         TEXT       NOT NULL,
+    roles         TEXT[]     NOT NULL,
+    invited_by    UUID       NOT NULL,
+    token_hash    TEXT       NOT NULL UNIQUE,
+    user_id       UUID       NULL,
+    date_created  TIMESTAMP  NOT NULL,
+    date_expires  TIMESTAMP  NOT NULL,
+    date_accepted TIMESTAMP  NULL,
+    date_revoked  TIMESTAMP  NULL,
+
+    PRIMARY KEY (invite_id)
+);
+
+CREATE INDEX invites_email_idx ON invites (email);
+
+-- Version: 1.26
+-- Description: Create table notification_preferences
+CREATE TABLE notification_preferences (
+    user_id      UUID      NOT NULL,
+    kind         TEXT      NOT NULL,
+    channel      TEXT      NOT NULL,
+    enabled      BOOLEAN   NOT NULL,
+    date_updated TIMESTAMP NOT NULL,
+
+    PRIMARY KEY (user_id, kind, channel),
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+-- Version: 1.27
+-- Description: Add the date users last logged in
+ALTER TABLE users
+    ADD COLUMN date_last_login TIMESTAMP NULL;
+
+-- Version: 1.28
+-- Description: Create tables webhooks and webhook_deliveries
+CREATE TABLE webhooks (
+    webhook_id   UUID      NOT NULL,
+    user_id      UUID      NOT NULL,
+    url          TEXT      NOT NULL,
+    events       TEXT[]    NOT NULL,
+    secret       TEXT      NOT NULL,
+    enabled      BOOLEAN   NOT NULL,
+    date_created TIMESTAMP NOT NULL,
+    date_updated TIMESTAMP NOT NULL,
+
+    PRIMARY KEY (webhook_id),
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+CREATE TABLE webhook_deliveries (
+    delivery_id  UUID      NOT NULL,
+    webhook_id   UUID      NOT NULL,
+    event        TEXT      NOT NULL,
+    payload      BYTEA     NOT NULL,
+    status       TEXT      NOT NULL,
+    attempts     INT       NOT NULL,
+    last_error   TEXT      NULL,
+    next_attempt TIMESTAMP NOT NULL,
+    date_created TIMESTAMP NOT NULL,
+    date_updated TIMESTAMP NOT NULL,
+
+    PRIMARY KEY (delivery_id),
+    FOREIGN KEY (webhook_id) REFERENCES webhooks(webhook_id) ON DELETE CASCADE
+);
+
+CREATE INDEX webhook_deliveries_due_idx ON webhook_deliveries (status, next_attempt);
+CREATE INDEX webhook_deliveries_webhook_idx ON webhook_deliveries (webhook_id, date_created);
+
+-- Version: 1.29
+-- Description: Add the tenant users belong to
+ALTER TABLE users ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000';
+
+CREATE INDEX users_tenant_idx ON users (tenant_id);
+
+-- Version: 1.30
+-- Description: Add helpers for row-level security by tenant
+CREATE FUNCTION app_current_tenant() RETURNS UUID AS $$
+    SELECT NULLIF(current_setting('app.current_tenant', true), '')::UUID;
+$$ LANGUAGE SQL STABLE;
+
+CREATE FUNCTION app_current_user() RETURNS UUID AS $$
+    SELECT NULLIF(current_setting('app.current_user', true), '')::UUID;
+$$ LANGUAGE SQL STABLE;
+
+CREATE FUNCTION app_enable_tenant_rls(tbl REGCLASS, col TEXT DEFAULT 'tenant_id') RETURNS VOID AS $$
+BEGIN
+    EXECUTE format('ALTER TABLE %s ENABLE ROW LEVEL SECURITY', tbl);
+    EXECUTE format(
+        'CREATE POLICY tenant_isolation ON %s USING (app_current_tenant() IS NULL OR %I = app_current_tenant()) WITH CHECK (app_current_tenant() IS NULL OR %I = app_current_tenant())',
+        tbl, col, col
+    );
+END;
+$$ LANGUAGE plpgsql;
+
+SELECT app_enable_tenant_rls('users');
+
+-- Version: 1.31
+-- Description: Create table delegate_dead_letters
+CREATE TABLE delegate_dead_letters (
+    dead_letter_id UUID      NOT NULL,
+    handler        TEXT      NOT NULL,
+    domain         TEXT      NOT NULL,
+    action         TEXT      NOT NULL,
+    params         BYTEA     NULL,
+    changes        JSONB     NULL,
+    error          TEXT      NOT NULL,
+    attempts       INT       NOT NULL,
+    date_created   TIMESTAMP NOT NULL,
+    date_updated   TIMESTAMP NOT NULL,
+
+    PRIMARY KEY (dead_letter_id)
+);
+
+CREATE INDEX delegate_dead_letters_created_idx ON delegate_dead_letters (date_created);
+
+-- Version: 1.32
+-- Description: Create table sagas
+CREATE TABLE sagas (
+    saga_id      UUID      NOT NULL,
+    name         TEXT      NOT NULL,
+    status       TEXT      NOT NULL,
+    step         INT       NOT NULL,
+    data         JSONB     NOT NULL,
+    error        TEXT      NULL,
+    version      INT       NOT NULL,
+    date_created TIMESTAMP NOT NULL,
+    date_updated TIMESTAMP NOT NULL,
+
+    PRIMARY KEY (saga_id)
+);
+
+CREATE INDEX sagas_in_flight_idx ON sagas (date_updated) WHERE status IN ('running', 'compensating');
+
+-- Version: 1.33
+-- Description: Add the tenant webhooks belong to
+ALTER TABLE webhooks ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000';
+
+UPDATE webhooks SET tenant_id = users.tenant_id FROM users WHERE webhooks.user_id = users.user_id;
+
+CREATE INDEX webhooks_tenant_idx ON webhooks (tenant_id);
+
+SELECT app_enable_tenant_rls('webhooks');
+
+-- Version: 1.34
+-- Description: Add the trace of the event a webhook delivery was raised by
+ALTER TABLE webhook_deliveries ADD COLUMN trace JSONB NULL;
+
+-- Version: 1.35
+-- Description: Add the successor and deadline of a rotated api key
+ALTER TABLE api_keys ADD COLUMN successor_id UUID NULL;
+ALTER TABLE api_keys ADD COLUMN date_expires TIMESTAMP NULL;
+
+CREATE INDEX api_keys_expires_idx ON api_keys (date_expires) WHERE date_revoked IS NULL;
@@ -17,29 +17,45 @@ var directions = map[string]string{
 	DESC: "DESC",
 }
 
-// By represents a field used to order by and direction.
+// By represents a field used to order by and direction. Then holds the
+// fields used to order the rows that have the same value for the field, in
+// the order they are applied.
 type By struct {
 	Field     string
 	Direction string
+	Then      []By
 }
 
-// NewBy constructs a new By value with no checks.
-func NewBy(field string, direction string) By {
+// NewBy constructs a new By value with no checks. The optional then values
+// order the rows that tie on the field.
+func NewBy(field string, direction string, then ...By) By {
 	if _, exists := directions[direction]; !exists {
-		return By{
-			Field:     field,
-			Direction: ASC,
-		}
+		direction = ASC
 	}
 
 	return By{
 		Field:     field,
 		Direction: direction,
+		Then:      then,
+	}
+}
+
+// Columns returns the field followed by the fields used to break ties, each
+// without any fields of its own.
+func (b By) Columns() []By {
+	bys := []By{{Field: b.Field, Direction: b.Direction}}
+	for _, then := range b.Then {
+		bys = append(bys, then.Columns()...)
 	}
+
+	return bys
 }
 
 // Parse constructs a By value by parsing a string in the form of
-// "field,direction" ie "user_id,ASC".
+// "field,direction" ie "user_id,ASC". Several fields can be listed to break
+// ties ie "department,ASC,date_created,DESC", where a field without a
+// direction is ordered ascending. Only the fields in the mappings can be
+// used and each only once.
 func Parse(fieldMappings map[string]string, orderBy string, defaultOrder By) (By, error) {
 	if orderBy == "" {
 		return defaultOrder, nil
@@ -47,25 +63,43 @@ func Parse(fieldMappings map[string]string, orderBy string, defaultOrder By) (By
 
 	orderParts := strings.Split(orderBy, ",")
 
-	orgFieldName := strings.TrimSpace(orderParts[0])
-	fieldName, exists := fieldMappings[orgFieldName]
-	if !exists {
-		return By{}, fmt.Errorf("unknown order: %s", orgFieldName)
-	}
+	var bys []By
+	seen := make(map[string]bool)
 
-	switch len(orderParts) {
-	case 1:
-		return NewBy(fieldName, ASC), nil
+	for i := 0; i < len(orderParts); i++ {
+		orgFieldName := strings.TrimSpace(orderParts[i])
+		fieldName, exists := fieldMappings[orgFieldName]
+		if !exists {
+			if _, isDirection := directions[orgFieldName]; isDirection {
+				return By{}, fmt.Errorf("unknown order: %s", orderBy)
+			}
+			return By{}, fmt.Errorf("unknown order: %s", orgFieldName)
+		}
 
-	case 2:
-		direction := strings.TrimSpace(orderParts[1])
-		if _, exists := directions[direction]; !exists {
-			return By{}, fmt.Errorf("unknown direction: %s", direction)
+		if seen[fieldName] {
+			return By{}, fmt.Errorf("duplicate order: %s", orgFieldName)
 		}
+		seen[fieldName] = true
 
-		return NewBy(fieldName, direction), nil
+		direction := ASC
+		if i+1 < len(orderParts) {
+			next := strings.TrimSpace(orderParts[i+1])
+			if _, isField := fieldMappings[next]; !isField {
+				if _, exists := directions[next]; !exists {
+					return By{}, fmt.Errorf("unknown direction: %s", next)
+				}
+				direction = next
+				i++
+			}
+		}
 
-	default:
-		return By{}, fmt.Errorf("unknown order: %s", orderBy)
+		bys = append(bys, NewBy(fieldName, direction))
 	}
+
+	by := bys[0]
+	if len(bys) > 1 {
+		by.Then = bys[1:]
+	}
+
+	return by, nil
 }
@@ -0,0 +1,204 @@
+package order
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Field describes a field a domain can be ordered by.
+type Field struct {
+	Name   string // Name clients use in the orderBy parameter.
+	Key    string // Key the business layer uses to identify the field.
+	Column string // Column the store orders the results by.
+}
+
+// Fields represents the set of fields a domain can be ordered by.
+type Fields struct {
+	domain string
+	fields []Field
+}
+
+// Domain returns the name of the domain the fields belong to.
+func (f Fields) Domain() string {
+	return f.domain
+}
+
+// List returns a copy of the fields in the order they were declared.
+func (f Fields) List() []Field {
+	return slices.Clone(f.fields)
+}
+
+// Names returns the names clients can use in the orderBy parameter.
+func (f Fields) Names() []string {
+	names := make([]string, len(f.fields))
+	for i, fld := range f.fields {
+		names[i] = fld.Name
+	}
+
+	return names
+}
+
+// Mappings returns the field names mapped to the business keys in the form
+// expected by Parse.
+func (f Fields) Mappings() map[string]string {
+	m := make(map[string]string, len(f.fields))
+	for _, fld := range f.fields {
+		m[fld.Name] = fld.Key
+	}
+
+	return m
+}
+
+// Columns returns the business keys mapped to the store columns.
+func (f Fields) Columns() map[string]string {
+	m := make(map[string]string, len(f.fields))
+	for _, fld := range f.fields {
+		m[fld.Key] = fld.Column
+	}
+
+	return m
+}
+
+// Clause returns the ORDER BY clause for the specified ordering, including
+// the fields used to break ties.
+func (f Fields) Clause(orderBy By) (string, error) {
+	terms, err := f.Terms(orderBy)
+	if err != nil {
+		return "", err
+	}
+
+	return " ORDER BY " + strings.Join(terms, ", "), nil
+}
+
+// Terms returns the column and direction for each field of the specified
+// ordering, so a store can combine them with terms of its own.
+func (f Fields) Terms(orderBy By) ([]string, error) {
+	columns := f.Columns()
+
+	bys := orderBy.Columns()
+	terms := make([]string, len(bys))
+
+	for i, by := range bys {
+		column, exists := columns[by.Field]
+		if !exists {
+			return nil, fmt.Errorf("field %q does not exist", by.Field)
+		}
+
+		if _, exists := directions[by.Direction]; !exists {
+			return nil, fmt.Errorf("field %q has an unknown direction %q", by.Field, by.Direction)
+		}
+
+		terms[i] = column + " " + by.Direction
+	}
+
+	return terms, nil
+}
+
+// =============================================================================
+
+var registry = struct {
+	mu      sync.RWMutex
+	domains map[string]Fields
+}{
+	domains: make(map[string]Fields),
+}
+
+// Register declares the fields the domain can be ordered by and returns
+// them. Registering a domain again replaces its fields. The fields are not
+// checked until Validate is called, which should happen at startup.
+func Register(domain string, fields ...Field) Fields {
+	f := Fields{
+		domain: domain,
+		fields: slices.Clone(fields),
+	}
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	registry.domains[domain] = f
+
+	return f
+}
+
+// Lookup returns the fields registered for the domain.
+func Lookup(domain string) (Fields, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	f, exists := registry.domains[domain]
+	return f, exists
+}
+
+// Registered returns the fields of every registered domain sorted by the
+// domain name.
+func Registered() []Fields {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	all := make([]Fields, 0, len(registry.domains))
+	for _, f := range registry.domains {
+		all = append(all, f)
+	}
+
+	slices.SortFunc(all, func(a, b Fields) int {
+		return strings.Compare(a.domain, b.domain)
+	})
+
+	return all
+}
+
+// columnRegEx restricts columns to plain, optionally qualified, identifiers
+// since they are written into the SQL as is.
+var columnRegEx = regexp.MustCompile(`^[a-z_][a-z0-9_]*(\.[a-z_][a-z0-9_]*)?$`)
+
+// Validate checks the fields of every registered domain and reports all the
+// problems found.
+func Validate() error {
+	var errs []error
+	for _, f := range Registered() {
+		if err := f.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("domain[%s]: %w", f.domain, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (f Fields) validate() error {
+	if len(f.fields) == 0 {
+		return errors.New("no fields declared")
+	}
+
+	names := make(map[string]bool)
+	keys := make(map[string]bool)
+
+	var errs []error
+	for _, fld := range f.fields {
+		switch {
+		case fld.Name == "":
+			errs = append(errs, fmt.Errorf("field with key %q has no name", fld.Key))
+		case names[fld.Name]:
+			errs = append(errs, fmt.Errorf("field %q declared more than once", fld.Name))
+		}
+
+		switch {
+		case fld.Key == "":
+			errs = append(errs, fmt.Errorf("field %q has no key", fld.Name))
+		case keys[fld.Key]:
+			errs = append(errs, fmt.Errorf("field %q reuses key %q", fld.Name, fld.Key))
+		}
+
+		if !columnRegEx.MatchString(fld.Column) {
+			errs = append(errs, fmt.Errorf("field %q has an invalid column %q", fld.Name, fld.Column))
+		}
+
+		names[fld.Name] = true
+		keys[fld.Key] = true
+	}
+
+	return errors.Join(errs...)
+}
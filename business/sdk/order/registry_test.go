@@ -0,0 +1,65 @@
+package order_test
+
+import (
+	"testing"
+
+	"github.com/ardanlabs/service/business/sdk/order"
+)
+
+func Test_Registry(t *testing.T) {
+	fields := order.Register("test",
+		order.Field{Name: "test_id", Key: "a", Column: "test_id"},
+		order.Field{Name: "name", Key: "b", Column: "t.name"},
+	)
+
+	if err := order.Validate(); err != nil {
+		t.Fatalf("Should be able to validate the fields : %s", err)
+	}
+
+	by, err := order.Parse(fields.Mappings(), "name,DESC", order.NewBy("a", order.ASC))
+	if err != nil {
+		t.Fatalf("Should be able to parse a registered field : %s", err)
+	}
+
+	clause, err := fields.Clause(by)
+	if err != nil {
+		t.Fatalf("Should be able to build the clause : %s", err)
+	}
+
+	if exp := " ORDER BY t.name DESC"; clause != exp {
+		t.Fatalf("Should get back the right clause : got %q, exp %q", clause, exp)
+	}
+
+	by, err = order.Parse(fields.Mappings(), "name,DESC,test_id", order.NewBy("a", order.ASC))
+	if err != nil {
+		t.Fatalf("Should be able to parse several fields : %s", err)
+	}
+
+	clause, err = fields.Clause(by)
+	if err != nil {
+		t.Fatalf("Should be able to build the clause for several fields : %s", err)
+	}
+
+	if exp := " ORDER BY t.name DESC, test_id ASC"; clause != exp {
+		t.Fatalf("Should get back the right clause for several fields : got %q, exp %q", clause, exp)
+	}
+
+	for _, orderBy := range []string{"name,name", "name,DESC,ASC", "name,UP", "name,DESC,unknown"} {
+		if _, err := order.Parse(fields.Mappings(), orderBy, order.NewBy("a", order.ASC)); err == nil {
+			t.Fatalf("Should not be able to parse %q", orderBy)
+		}
+	}
+
+	if _, exists := order.Lookup("test"); !exists {
+		t.Fatalf("Should be able to lookup the domain")
+	}
+
+	order.Register("test",
+		order.Field{Name: "test_id", Key: "a", Column: "test_id"},
+		order.Field{Name: "test_id", Key: "a", Column: "name; DROP TABLE users"},
+	)
+
+	if err := order.Validate(); err == nil {
+		t.Fatalf("Should not validate duplicate fields or unsafe columns")
+	}
+}
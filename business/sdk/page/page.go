@@ -2,14 +2,25 @@
 package page
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 )
 
-// Page represents the requested page and rows per page.
+// ErrInvalidCursor is returned when a cursor can't be decoded or doesn't
+// match the order of the query it's used with.
+var ErrInvalidCursor = errors.New("cursor is invalid")
+
+// Page represents the requested page and rows per page. A page can either
+// be an offset page identified by number or a keyset page identified by a
+// cursor that marks the last row of the previous page.
 type Page struct {
 	number int
 	rows   int
+	keyset bool
+	cursor []string
 }
 
 // Parse parses the strings and validates the values are in reason.
@@ -23,30 +34,44 @@ func Parse(page string, rowsPerPage string) (Page, error) {
 		}
 	}
 
-	rows := 10
-	if rowsPerPage != "" {
-		var err error
-		rows, err = strconv.Atoi(rowsPerPage)
-		if err != nil {
-			return Page{}, fmt.Errorf("rows conversion: %w", err)
-		}
+	rows, err := parseRows(rowsPerPage)
+	if err != nil {
+		return Page{}, err
 	}
 
 	if number <= 0 {
 		return Page{}, fmt.Errorf("page value too small, must be larger than 0")
 	}
 
-	if rows <= 0 {
-		return Page{}, fmt.Errorf("rows value too small, must be larger than 0")
+	p := Page{
+		number: number,
+		rows:   rows,
 	}
 
-	if rows > 100 {
-		return Page{}, fmt.Errorf("rows value too large, must be less than 100")
+	return p, nil
+}
+
+// ParseCursor parses the strings for a keyset page. An empty cursor
+// represents the first page.
+func ParseCursor(cursor string, rowsPerPage string) (Page, error) {
+	rows, err := parseRows(rowsPerPage)
+	if err != nil {
+		return Page{}, err
+	}
+
+	var values []string
+	if cursor != "" {
+		values, err = decodeCursor(cursor)
+		if err != nil {
+			return Page{}, err
+		}
 	}
 
 	p := Page{
-		number: number,
+		number: 1,
 		rows:   rows,
+		keyset: true,
+		cursor: values,
 	}
 
 	return p, nil
@@ -62,8 +87,22 @@ func MustParse(page string, rowsPerPage string) Page {
 	return pg
 }
 
+// MustParseCursor creates a keyset paging value for testing.
+func MustParseCursor(cursor string, rowsPerPage string) Page {
+	pg, err := ParseCursor(cursor, rowsPerPage)
+	if err != nil {
+		panic(err)
+	}
+
+	return pg
+}
+
 // String implements the stringer interface.
 func (p Page) String() string {
+	if p.keyset {
+		return fmt.Sprintf("cursor: %v rows: %d", p.cursor, p.rows)
+	}
+
 	return fmt.Sprintf("page: %d rows: %d", p.number, p.rows)
 }
 
@@ -76,3 +115,62 @@ func (p Page) Number() int {
 func (p Page) RowsPerPage() int {
 	return p.rows
 }
+
+// IsKeyset reports whether the page is a keyset page.
+func (p Page) IsKeyset() bool {
+	return p.keyset
+}
+
+// Cursor returns the sort key values of the last row of the previous page.
+// There are no values for the first keyset page.
+func (p Page) Cursor() []string {
+	return p.cursor
+}
+
+// NextCursor encodes the sort key values of the last row of a page into an
+// opaque cursor that can be used to request the next page.
+func NextCursor(values ...string) string {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return ""
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// =============================================================================
+
+func parseRows(rowsPerPage string) (int, error) {
+	rows := 10
+	if rowsPerPage != "" {
+		var err error
+		rows, err = strconv.Atoi(rowsPerPage)
+		if err != nil {
+			return 0, fmt.Errorf("rows conversion: %w", err)
+		}
+	}
+
+	if rows <= 0 {
+		return 0, fmt.Errorf("rows value too small, must be larger than 0")
+	}
+
+	if rows > 100 {
+		return 0, fmt.Errorf("rows value too large, must be less than 100")
+	}
+
+	return rows, nil
+}
+
+func decodeCursor(cursor string) ([]string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var values []string
+	if err := json.Unmarshal(data, &values); err != nil || len(values) == 0 {
+		return nil, ErrInvalidCursor
+	}
+
+	return values, nil
+}
@@ -0,0 +1,87 @@
+package saga
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Status represents where a saga is in its life.
+type Status string
+
+// Set of statuses a saga can have. Running and compensating sagas are in
+// flight, the others are finished.
+const (
+	StatusRunning      Status = "running"
+	StatusCompensating Status = "compensating"
+	StatusCompleted    Status = "completed"
+	StatusCompensated  Status = "compensated"
+	StatusFailed       Status = "failed"
+)
+
+// Finished reports whether the saga with the status has stopped running.
+func (s Status) Finished() bool {
+	return s != StatusRunning && s != StatusCompensating
+}
+
+// Func represents an action or compensation of a step. It receives the
+// data of the saga and can add to it for the steps that follow.
+type Func func(ctx context.Context, data Data) error
+
+// Step represents a unit of work in a saga along with how to undo it. A step
+// without a compensation has nothing to undo. The functions of a step must
+// be safe to call again, a saga resumed after a restart repeats the step
+// that was in flight.
+type Step struct {
+	Name       string
+	Action     Func
+	Compensate Func
+}
+
+// Data holds the values a saga carries from one step to the next, encoded
+// as JSON so they are kept with the saga.
+type Data map[string]json.RawMessage
+
+// Get decodes the value of the key into v.
+func (d Data) Get(key string, v any) error {
+	raw, exists := d[key]
+	if !exists {
+		return fmt.Errorf("key[%s]: %w", key, ErrNoValue)
+	}
+
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("unmarshal: key[%s]: %w", key, err)
+	}
+
+	return nil
+}
+
+// Set encodes v as the value of the key.
+func (d Data) Set(key string, v any) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("marshal: key[%s]: %w", key, err)
+	}
+
+	d[key] = raw
+
+	return nil
+}
+
+// Saga represents a run of a registered workflow. Step is the number of
+// steps whose action completed and haven't been compensated. The version
+// goes up with every change so two instances can't drive the same saga.
+type Saga struct {
+	ID          uuid.UUID
+	Name        string
+	Status      Status
+	Step        int
+	Data        Data
+	Error       string
+	Version     int
+	DateCreated time.Time
+	DateUpdated time.Time
+}
@@ -0,0 +1,263 @@
+// Package saga provides support for workflows that span domains.
+//
+// A workflow is registered as a named list of steps, each with an action
+// and a compensation that undoes it. When a step fails, the steps that
+// completed before it are compensated in reverse order. The progress of a
+// saga is stored after every step, so a saga that was in flight when the
+// service stopped is picked up by Resume where it left off.
+package saga
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ardanlabs/service/business/sdk/buserr"
+	"github.com/ardanlabs/service/business/sdk/jobs"
+	"github.com/ardanlabs/service/foundation/clock"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/ardanlabs/service/foundation/otel"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Set of error variables for sagas.
+var (
+	ErrNotFound        = buserr.New(buserr.NotFound, "saga not found")
+	ErrNotRegistered   = buserr.New(buserr.InvalidArgument, "saga not registered")
+	ErrVersionConflict = buserr.New(buserr.Conflict, "saga changed by another instance")
+	ErrNoValue         = errors.New("no value for key")
+)
+
+// Storer interface declares the behavior the orchestrator needs to keep
+// the sagas. Update must fail with ErrVersionConflict unless the stored
+// saga has the version before the one given.
+type Storer interface {
+	Create(ctx context.Context, s Saga) error
+	Update(ctx context.Context, s Saga) error
+	QueryByID(ctx context.Context, id uuid.UUID) (Saga, error)
+	QueryInFlight(ctx context.Context, updatedBefore time.Time) ([]Saga, error)
+}
+
+// Orchestrator manages the set of registered workflows and runs the sagas
+// started for them.
+type Orchestrator struct {
+	log    *logger.Logger
+	storer Storer
+	mu     sync.RWMutex
+	steps  map[string][]Step
+}
+
+// New constructs an orchestrator that keeps the sagas in the store.
+func New(log *logger.Logger, storer Storer) *Orchestrator {
+	return &Orchestrator{
+		log:    log,
+		storer: storer,
+		steps:  make(map[string][]Step),
+	}
+}
+
+// Register adds a workflow to be started by name. Registering a name again
+// replaces its steps. The steps of a workflow with sagas in flight must
+// only be changed by adding steps to the end.
+func (o *Orchestrator) Register(name string, steps ...Step) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.steps[name] = steps
+}
+
+// Start runs a saga of the named workflow with the data and returns it once
+// it's finished. When a step fails the saga is compensated and the error of
+// the step is returned along with the saga.
+func (o *Orchestrator) Start(ctx context.Context, name string, data Data) (Saga, error) {
+	steps, ok := o.lookup(name)
+	if !ok {
+		return Saga{}, fmt.Errorf("start: name[%s]: %w", name, ErrNotRegistered)
+	}
+
+	if data == nil {
+		data = make(Data)
+	}
+
+	now := clock.Now()
+
+	s := Saga{
+		ID:          uuid.New(),
+		Name:        name,
+		Status:      StatusRunning,
+		Data:        data,
+		Version:     1,
+		DateCreated: now,
+		DateUpdated: now,
+	}
+
+	if err := o.storer.Create(ctx, s); err != nil {
+		return Saga{}, fmt.Errorf("create: %w", err)
+	}
+
+	return o.run(ctx, s, steps)
+}
+
+// QueryByID finds the saga by the specified ID.
+func (o *Orchestrator) QueryByID(ctx context.Context, id uuid.UUID) (Saga, error) {
+	s, err := o.storer.QueryByID(ctx, id)
+	if err != nil {
+		return Saga{}, fmt.Errorf("query: sagaID[%s]: %w", id, err)
+	}
+
+	return s, nil
+}
+
+// Resume picks up the sagas in flight that haven't made progress for the
+// stale duration, which must be longer than any step takes so sagas still
+// being run aren't picked up. Each saga is run from where it stopped.
+// Sagas another instance resumes first are skipped.
+func (o *Orchestrator) Resume(ctx context.Context, stale time.Duration) error {
+	sagas, err := o.storer.QueryInFlight(ctx, clock.Now().Add(-stale))
+	if err != nil {
+		return fmt.Errorf("queryinflight: %w", err)
+	}
+
+	for _, s := range sagas {
+		steps, ok := o.lookup(s.Name)
+		if !ok {
+			o.log.Error(ctx, "saga: resume", "sagaID", s.ID, "name", s.Name, "ERROR", ErrNotRegistered)
+			continue
+		}
+
+		// Saving the saga claims it, the version stops any other instance
+		// resuming it at the same time.
+		if err := o.save(ctx, &s); err != nil {
+			if !errors.Is(err, ErrVersionConflict) {
+				o.log.Error(ctx, "saga: resume", "sagaID", s.ID, "name", s.Name, "ERROR", err)
+			}
+			continue
+		}
+
+		o.log.Info(ctx, "saga: resume", "sagaID", s.ID, "name", s.Name, "status", s.Status, "step", s.Step)
+
+		if _, err := o.run(ctx, s, steps); err != nil {
+			o.log.Error(ctx, "saga: resume", "sagaID", s.ID, "name", s.Name, "ERROR", err)
+		}
+	}
+
+	return nil
+}
+
+// Job returns a job that resumes the sagas in flight on the interval, see
+// Resume. Running it with the jobs runner keeps the instances from
+// resuming sagas at the same time.
+func (o *Orchestrator) Job(interval time.Duration, stale time.Duration) jobs.Job {
+	return jobs.Job{
+		Name:     "saga.resume",
+		Schedule: jobs.Every(interval),
+		Run: func(ctx context.Context) error {
+			return o.Resume(ctx, stale)
+		},
+	}
+}
+
+// =============================================================================
+
+func (o *Orchestrator) lookup(name string) ([]Step, bool) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	steps, ok := o.steps[name]
+	return steps, ok
+}
+
+// run drives the saga from where it is until it's finished, storing its
+// progress after every step.
+func (o *Orchestrator) run(ctx context.Context, s Saga, steps []Step) (Saga, error) {
+	ctx, span := otel.AddSpan(ctx, "business.sdk.saga.run",
+		attribute.String("name", s.Name),
+		attribute.String("saga_id", s.ID.String()),
+	)
+	defer span.End()
+
+	var stepErr error
+
+	for s.Status == StatusRunning && s.Step < len(steps) {
+		step := steps[s.Step]
+
+		if err := step.Action(ctx, s.Data); err != nil {
+			stepErr = fmt.Errorf("step[%s]: %w", step.Name, err)
+
+			o.log.Info(ctx, "saga: step", "sagaID", s.ID, "name", s.Name, "step", step.Name, "status", "failed, compensating", "ERROR", err)
+
+			s.Status = StatusCompensating
+			s.Error = stepErr.Error()
+		} else {
+			s.Step++
+		}
+
+		if err := o.save(ctx, &s); err != nil {
+			return s, err
+		}
+	}
+
+	if s.Status == StatusRunning {
+		s.Status = StatusCompleted
+		if err := o.save(ctx, &s); err != nil {
+			return s, err
+		}
+
+		return s, nil
+	}
+
+	// The steps that completed are undone even if the caller gives up.
+	ctx = context.WithoutCancel(ctx)
+
+	for s.Status == StatusCompensating && s.Step > 0 {
+		step := steps[s.Step-1]
+
+		if step.Compensate != nil {
+			if err := step.Compensate(ctx, s.Data); err != nil {
+				o.log.Error(ctx, "saga: compensate", "sagaID", s.ID, "name", s.Name, "step", step.Name, "ERROR", err)
+
+				s.Status = StatusFailed
+				s.Error = fmt.Sprintf("compensate: step[%s]: %s", step.Name, err)
+
+				if err := o.save(ctx, &s); err != nil {
+					return s, err
+				}
+
+				return s, fmt.Errorf("compensate: step[%s]: %w", step.Name, err)
+			}
+		}
+
+		s.Step--
+		if err := o.save(ctx, &s); err != nil {
+			return s, err
+		}
+	}
+
+	if s.Status == StatusCompensating {
+		s.Status = StatusCompensated
+		if err := o.save(ctx, &s); err != nil {
+			return s, err
+		}
+	}
+
+	if stepErr == nil {
+		stepErr = errors.New(s.Error)
+	}
+
+	return s, stepErr
+}
+
+// save stores the progress of the saga with the next version.
+func (o *Orchestrator) save(ctx context.Context, s *Saga) error {
+	s.Version++
+	s.DateUpdated = clock.Now()
+
+	if err := o.storer.Update(ctx, *s); err != nil {
+		return fmt.Errorf("update: sagaID[%s]: %w", s.ID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,223 @@
+package saga_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/service/business/sdk/saga"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/google/uuid"
+)
+
+type store struct {
+	mu    sync.Mutex
+	sagas map[uuid.UUID]saga.Saga
+}
+
+func newStore() *store {
+	return &store{
+		sagas: make(map[uuid.UUID]saga.Saga),
+	}
+}
+
+func (s *store) Create(ctx context.Context, sg saga.Saga) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sagas[sg.ID] = sg
+	return nil
+}
+
+func (s *store) Update(ctx context.Context, sg saga.Saga) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.sagas[sg.ID].Version != sg.Version-1 {
+		return saga.ErrVersionConflict
+	}
+
+	s.sagas[sg.ID] = sg
+	return nil
+}
+
+func (s *store) QueryByID(ctx context.Context, id uuid.UUID) (saga.Saga, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sg, exists := s.sagas[id]
+	if !exists {
+		return saga.Saga{}, saga.ErrNotFound
+	}
+
+	return sg, nil
+}
+
+func (s *store) QueryInFlight(ctx context.Context, updatedBefore time.Time) ([]saga.Saga, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var sgs []saga.Saga
+	for _, sg := range s.sagas {
+		if !sg.Status.Finished() && sg.DateUpdated.Before(updatedBefore) {
+			sgs = append(sgs, sg)
+		}
+	}
+
+	return sgs, nil
+}
+
+func newLog() *logger.Logger {
+	var buf bytes.Buffer
+	return logger.New(&buf, logger.LevelInfo, "TEST", func(context.Context) string { return "" })
+}
+
+// recorder keeps the order the functions of the steps are called in.
+type recorder struct {
+	calls []string
+}
+
+func (r *recorder) step(name string, fail bool) saga.Step {
+	return saga.Step{
+		Name: name,
+		Action: func(ctx context.Context, data saga.Data) error {
+			r.calls = append(r.calls, "do "+name)
+			if fail {
+				return errors.New(name + " failed")
+			}
+			return data.Set(name, true)
+		},
+		Compensate: func(ctx context.Context, data saga.Data) error {
+			r.calls = append(r.calls, "undo "+name)
+			return nil
+		},
+	}
+}
+
+func Test_Start(t *testing.T) {
+	ctx := context.Background()
+
+	var rec recorder
+
+	o := saga.New(newLog(), newStore())
+	o.Register("signup", rec.step("user", false), rec.step("group", false), rec.step("invite", false))
+
+	sg, err := o.Start(ctx, "signup", nil)
+	if err != nil {
+		t.Fatalf("Should be able to run the saga: %s", err)
+	}
+
+	if sg.Status != saga.StatusCompleted || sg.Step != 3 {
+		t.Fatalf("Should complete the saga: %+v", sg)
+	}
+
+	var invited bool
+	if err := sg.Data.Get("invite", &invited); err != nil || !invited {
+		t.Fatalf("Should keep the data of the steps: %v", err)
+	}
+
+	exp := []string{"do user", "do group", "do invite"}
+	if !slices.Equal(rec.calls, exp) {
+		t.Fatalf("Should run the steps in order:\ngot %v\nexp %v", rec.calls, exp)
+	}
+
+	stored, err := o.QueryByID(ctx, sg.ID)
+	if err != nil || stored.Status != saga.StatusCompleted {
+		t.Fatalf("Should store the saga: %+v %v", stored, err)
+	}
+
+	if _, err := o.Start(ctx, "unknown", nil); !errors.Is(err, saga.ErrNotRegistered) {
+		t.Fatalf("Should not start a saga that isn't registered: %v", err)
+	}
+}
+
+func Test_Compensate(t *testing.T) {
+	ctx := context.Background()
+
+	var rec recorder
+
+	o := saga.New(newLog(), newStore())
+	o.Register("signup", rec.step("user", false), rec.step("group", false), rec.step("invite", true))
+
+	sg, err := o.Start(ctx, "signup", nil)
+	if err == nil {
+		t.Fatalf("Should return the error of the step")
+	}
+
+	if sg.Status != saga.StatusCompensated || sg.Step != 0 || sg.Error == "" {
+		t.Fatalf("Should compensate the saga: %+v", sg)
+	}
+
+	exp := []string{"do user", "do group", "do invite", "undo group", "undo user"}
+	if !slices.Equal(rec.calls, exp) {
+		t.Fatalf("Should undo the completed steps in reverse order:\ngot %v\nexp %v", rec.calls, exp)
+	}
+
+	// A compensation that fails stops the saga for someone to look at.
+
+	broken := rec.step("group", false)
+	broken.Compensate = func(ctx context.Context, data saga.Data) error {
+		return errors.New("group locked")
+	}
+
+	o.Register("broken", rec.step("user", false), broken, rec.step("invite", true))
+
+	sg, err = o.Start(ctx, "broken", nil)
+	if err == nil {
+		t.Fatalf("Should return the error of the compensation")
+	}
+
+	if sg.Status != saga.StatusFailed || sg.Step != 2 {
+		t.Fatalf("Should fail the saga where the compensation failed: %+v", sg)
+	}
+}
+
+func Test_Resume(t *testing.T) {
+	ctx := context.Background()
+
+	var rec recorder
+
+	st := newStore()
+	o := saga.New(newLog(), st)
+	o.Register("signup", rec.step("user", false), rec.step("group", false), rec.step("invite", false))
+
+	// A saga that stopped after its first step along with one that was
+	// compensating its first step.
+
+	old := time.Now().Add(-time.Hour)
+
+	running := saga.Saga{ID: uuid.New(), Name: "signup", Status: saga.StatusRunning, Step: 1, Data: saga.Data{}, Version: 2, DateUpdated: old}
+	compensating := saga.Saga{ID: uuid.New(), Name: "signup", Status: saga.StatusCompensating, Step: 1, Data: saga.Data{}, Version: 3, DateUpdated: old}
+	recent := saga.Saga{ID: uuid.New(), Name: "signup", Status: saga.StatusRunning, Step: 1, Data: saga.Data{}, Version: 2, DateUpdated: time.Now()}
+
+	for _, sg := range []saga.Saga{running, compensating, recent} {
+		st.Create(ctx, sg)
+	}
+
+	if err := o.Resume(ctx, time.Minute); err != nil {
+		t.Fatalf("Should be able to resume the sagas: %s", err)
+	}
+
+	sg, _ := o.QueryByID(ctx, running.ID)
+	if sg.Status != saga.StatusCompleted || sg.Step != 3 {
+		t.Fatalf("Should run the rest of the steps: %+v", sg)
+	}
+
+	sg, _ = o.QueryByID(ctx, compensating.ID)
+	if sg.Status != saga.StatusCompensated || sg.Step != 0 {
+		t.Fatalf("Should finish the compensation: %+v", sg)
+	}
+
+	sg, _ = o.QueryByID(ctx, recent.ID)
+	if sg.Status != saga.StatusRunning || sg.Version != recent.Version {
+		t.Fatalf("Should leave the saga that is still being run: %+v", sg)
+	}
+
+	if n := len(rec.calls); n != 3 || !slices.Contains(rec.calls, "undo user") {
+		t.Fatalf("Should call the remaining functions once: %v", rec.calls)
+	}
+}
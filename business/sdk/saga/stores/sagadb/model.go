@@ -0,0 +1,83 @@
+package sagadb
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ardanlabs/service/business/sdk/saga"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx/types"
+)
+
+type dbSaga struct {
+	ID          uuid.UUID      `db:"saga_id"`
+	Name        string         `db:"name"`
+	Status      string         `db:"status"`
+	Step        int            `db:"step"`
+	Data        types.JSONText `db:"data"`
+	Error       sql.NullString `db:"error"`
+	Version     int            `db:"version"`
+	DateCreated time.Time      `db:"date_created"`
+	DateUpdated time.Time      `db:"date_updated"`
+}
+
+func toDBSaga(bus saga.Saga) (dbSaga, error) {
+	data, err := json.Marshal(bus.Data)
+	if err != nil {
+		return dbSaga{}, fmt.Errorf("marshal data: %w", err)
+	}
+
+	db := dbSaga{
+		ID:     bus.ID,
+		Name:   bus.Name,
+		Status: string(bus.Status),
+		Step:   bus.Step,
+		Data:   types.JSONText(data),
+		Error: sql.NullString{
+			String: bus.Error,
+			Valid:  bus.Error != "",
+		},
+		Version:     bus.Version,
+		DateCreated: bus.DateCreated.UTC(),
+		DateUpdated: bus.DateUpdated.UTC(),
+	}
+
+	return db, nil
+}
+
+func toBusSaga(db dbSaga) (saga.Saga, error) {
+	data := make(saga.Data)
+	if err := json.Unmarshal(db.Data, &data); err != nil {
+		return saga.Saga{}, fmt.Errorf("unmarshal data: %w", err)
+	}
+
+	bus := saga.Saga{
+		ID:          db.ID,
+		Name:        db.Name,
+		Status:      saga.Status(db.Status),
+		Step:        db.Step,
+		Data:        data,
+		Error:       db.Error.String,
+		Version:     db.Version,
+		DateCreated: db.DateCreated.In(time.Local),
+		DateUpdated: db.DateUpdated.In(time.Local),
+	}
+
+	return bus, nil
+}
+
+func toBusSagas(dbs []dbSaga) ([]saga.Saga, error) {
+	bus := make([]saga.Saga, len(dbs))
+
+	for i, db := range dbs {
+		var err error
+		bus[i], err = toBusSaga(db)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return bus, nil
+}
@@ -0,0 +1,141 @@
+// Package sagadb contains the database storage for sagas.
+package sagadb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ardanlabs/service/business/sdk/saga"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Store manages the set of APIs for saga database access.
+type Store struct {
+	log *logger.Logger
+	db  sqlx.ExtContext
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *logger.Logger, db sqlx.ExtContext) *Store {
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// Create inserts a new saga into the database.
+func (s *Store) Create(ctx context.Context, sg saga.Saga) error {
+	dbSg, err := toDBSaga(sg)
+	if err != nil {
+		return err
+	}
+
+	const q = `
+	INSERT INTO sagas
+		(saga_id, name, status, step, data, error, version, date_created, date_updated)
+	VALUES
+		(:saga_id, :name, :status, :step, :data, :error, :version, :date_created, :date_updated)`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, dbSg); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Update replaces a saga document in the database. The saga is only
+// replaced when its version is the one before the saga's version.
+func (s *Store) Update(ctx context.Context, sg saga.Saga) error {
+	dbSg, err := toDBSaga(sg)
+	if err != nil {
+		return err
+	}
+
+	const q = `
+	UPDATE
+		sagas
+	SET
+		"status" = :status,
+		"step" = :step,
+		"data" = :data,
+		"error" = :error,
+		"version" = :version,
+		"date_updated" = :date_updated
+	WHERE
+		saga_id = :saga_id AND
+		version = :version - 1
+	RETURNING
+		saga_id`
+
+	var dest struct {
+		ID uuid.UUID `db:"saga_id"`
+	}
+
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, dbSg, &dest); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return fmt.Errorf("namedquerystruct: sagaID[%s] version[%d]: %w", sg.ID, sg.Version, saga.ErrVersionConflict)
+		}
+		return fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return nil
+}
+
+// QueryByID gets the specified saga from the database.
+func (s *Store) QueryByID(ctx context.Context, id uuid.UUID) (saga.Saga, error) {
+	data := struct {
+		ID string `db:"saga_id"`
+	}{
+		ID: id.String(),
+	}
+
+	const q = `
+	SELECT
+		saga_id, name, status, step, data, error, version, date_created, date_updated
+	FROM
+		sagas
+	WHERE
+		saga_id = :saga_id`
+
+	var dbSg dbSaga
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbSg); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return saga.Saga{}, fmt.Errorf("db: %w", saga.ErrNotFound)
+		}
+		return saga.Saga{}, fmt.Errorf("db: %w", err)
+	}
+
+	return toBusSaga(dbSg)
+}
+
+// QueryInFlight retrieves the sagas still running or compensating that were
+// last updated before the specified time, the oldest first.
+func (s *Store) QueryInFlight(ctx context.Context, updatedBefore time.Time) ([]saga.Saga, error) {
+	data := map[string]any{
+		"running":        saga.StatusRunning,
+		"compensating":   saga.StatusCompensating,
+		"updated_before": updatedBefore.UTC(),
+	}
+
+	const q = `
+	SELECT
+		saga_id, name, status, step, data, error, version, date_created, date_updated
+	FROM
+		sagas
+	WHERE
+		status IN (:running, :compensating) AND date_updated < :updated_before
+	ORDER BY
+		date_updated`
+
+	var dbSgs []dbSaga
+	if err := sqldb.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbSgs); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toBusSagas(dbSgs)
+}
@@ -0,0 +1,207 @@
+// Package scenario provides support for describing a business workflow as a
+// series of steps and running it against the business layer. Steps refer to
+// users by an alias so a scenario reads like the workflow it tests, and the
+// expectations check the stored state, the delegate events and the audit
+// records the workflow left behind.
+package scenario
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/ardanlabs/service/business/domain/auditbus"
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/dbtest"
+	"github.com/ardanlabs/service/business/sdk/delegate"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/google/uuid"
+)
+
+// Step represents a single action or expectation in a scenario.
+type Step struct {
+	Name string
+	Run  func(ctx context.Context, st *State) error
+
+	// fails is the error the step is expected to fail with.
+	fails error
+
+	// events are the domain and action pairs the step needs recorded.
+	events [][2]string
+}
+
+// Scenario represents a workflow to run against the business layer.
+type Scenario struct {
+	name  string
+	steps []Step
+}
+
+// New constructs an empty scenario.
+func New(name string) *Scenario {
+	return &Scenario{
+		name: name,
+	}
+}
+
+// Step adds a step with custom logic. It's the escape hatch for anything
+// the built in steps don't cover.
+func (sc *Scenario) Step(name string, fn func(ctx context.Context, st *State) error) *Scenario {
+	sc.steps = append(sc.steps, Step{Name: name, Run: fn})
+	return sc
+}
+
+// Fails marks the previous step as expected to fail with the error. The
+// scenario fails if the step succeeds or fails for another reason.
+func (sc *Scenario) Fails(target error) *Scenario {
+	if len(sc.steps) > 0 {
+		sc.steps[len(sc.steps)-1].fails = target
+	}
+	return sc
+}
+
+// Run executes the steps in order as subtests and stops at the first step
+// that doesn't do what the scenario says.
+func (sc *Scenario) Run(t *testing.T, bus dbtest.BusDomain) {
+	st := State{
+		Bus:       bus,
+		users:     make(map[string]userbus.User),
+		passwords: make(map[string]string),
+		events:    newRecorder(),
+	}
+
+	for _, step := range sc.steps {
+		for _, ev := range step.events {
+			st.events.watch(bus.Delegate, ev[0], ev[1])
+		}
+	}
+
+	t.Run(sc.name, func(t *testing.T) {
+		for i, step := range sc.steps {
+			ok := t.Run(fmt.Sprintf("%02d-%s", i+1, step.Name), func(t *testing.T) {
+				err := step.Run(context.Background(), &st)
+
+				switch {
+				case step.fails == nil && err != nil:
+					t.Fatalf("Should be able to %s : %s", step.Name, err)
+
+				case step.fails != nil && !errors.Is(err, step.fails):
+					t.Fatalf("Should fail to %s with %q : got %v", step.Name, step.fails, err)
+				}
+			})
+
+			if !ok {
+				t.FailNow()
+			}
+		}
+	})
+}
+
+// =============================================================================
+
+// State is what the steps of a running scenario share.
+type State struct {
+	Bus       dbtest.BusDomain
+	users     map[string]userbus.User
+	passwords map[string]string
+	events    *recorder
+}
+
+// User returns the user known by the alias as it was last seen by the
+// scenario.
+func (st *State) User(alias string) (userbus.User, error) {
+	usr, ok := st.users[alias]
+	if !ok {
+		return userbus.User{}, fmt.Errorf("unknown alias %q", alias)
+	}
+
+	return usr, nil
+}
+
+// SetUser records the user under the alias.
+func (st *State) SetUser(alias string, usr userbus.User) {
+	st.users[alias] = usr
+}
+
+// actorID returns the id of the user the alias refers to. The empty alias
+// is the system, which acts as the system identity.
+func (st *State) actorID(alias string) (uuid.UUID, error) {
+	if alias == "" {
+		return userbus.ActorSystem, nil
+	}
+
+	usr, err := st.User(alias)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+
+	return usr.ID, nil
+}
+
+// =============================================================================
+
+// recorder keeps the delegate events raised while a scenario runs.
+type recorder struct {
+	mu      sync.Mutex
+	watched map[[2]string]bool
+	events  []delegate.Data
+}
+
+func newRecorder() *recorder {
+	return &recorder{
+		watched: make(map[[2]string]bool),
+	}
+}
+
+func (r *recorder) watch(d *delegate.Delegate, domain string, action string) {
+	key := [2]string{domain, action}
+	if r.watched[key] {
+		return
+	}
+	r.watched[key] = true
+
+	d.Register(domain, action, func(ctx context.Context, data delegate.Data) error {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		r.events = append(r.events, data)
+		return nil
+	})
+}
+
+func (r *recorder) find(match func(delegate.Data) bool) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return slices.ContainsFunc(r.events, match)
+}
+
+// =============================================================================
+
+// auditCount returns how many audit records there are for the user and
+// action.
+func auditCount(ctx context.Context, bus *auditbus.Business, userID uuid.UUID, action string) (int, error) {
+	filter := auditbus.QueryFilter{
+		ObjID:  &userID,
+		Action: &action,
+	}
+
+	return bus.Count(ctx, filter)
+}
+
+// hasRoles reports whether the user has exactly the roles, in any order.
+func hasRoles(usr userbus.User, roles []role.Role) bool {
+	if len(usr.Roles) != len(roles) {
+		return false
+	}
+
+	for _, r := range roles {
+		if !slices.ContainsFunc(usr.Roles, r.Equal) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,47 @@
+package scenario_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/domain/userbus/plugins/useraudit"
+	"github.com/ardanlabs/service/business/sdk/dbtest"
+	"github.com/ardanlabs/service/business/sdk/scenario"
+	"github.com/ardanlabs/service/business/types/role"
+)
+
+func Test_Scenarios(t *testing.T) {
+	t.Parallel()
+
+	db := dbtest.New(t, "Test_Scenarios")
+
+	scenario.New("user-lifecycle").
+		CreateUser("admin", role.Admin).
+		CreateUser("bob").
+		ExpectAudit("bob", useraudit.ActionCreated).
+		Authenticate("bob").
+		AuthenticateWith("bob", "not-the-password").Fails(userbus.ErrAuthenticationFailure).
+		SetRoles("admin", "bob", role.User, role.Admin).
+		ExpectRoles("bob", role.User, role.Admin).
+		ExpectEvent("bob", userbus.ActionUpdated, userbus.FieldRoles).
+		ExpectAudit("bob", useraudit.ActionUpdated).
+		DeleteUser("admin", "bob").
+		ExpectNoUser("bob").
+		ExpectEvent("bob", userbus.ActionDeleted).
+		ExpectAudit("bob", useraudit.ActionDeleted).
+		Run(t, db.BusDomain)
+
+	scenario.New("disabled-user").
+		CreateUser("carol").
+		SetEnabled("", "carol", false).
+		ExpectEvent("carol", userbus.ActionUpdated, userbus.FieldEnabled).
+		ExpectUser("carol", func(usr userbus.User) error {
+			if usr.Enabled {
+				return errors.New("expected carol to be disabled")
+			}
+			return nil
+		}).
+		ExpectAudit("carol", useraudit.ActionUpdated).
+		Run(t, db.BusDomain)
+}
@@ -0,0 +1,248 @@
+package scenario
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/delegate"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/google/uuid"
+)
+
+// CreateUser adds a user with the roles and a generated password, and
+// remembers it under the alias. The user is created by the system.
+func (sc *Scenario) CreateUser(alias string, roles ...role.Role) *Scenario {
+	return sc.Step("create user "+alias, func(ctx context.Context, st *State) error {
+		nu := userbus.TestNewUsers(1, role.User)[0]
+		if len(roles) > 0 {
+			nu.Roles = roles
+		}
+
+		usr, _, err := st.Bus.User.Create(ctx, userbus.ActorSystem, nu)
+		if err != nil {
+			return err
+		}
+
+		st.SetUser(alias, usr)
+		st.passwords[alias] = nu.Password
+
+		return nil
+	})
+}
+
+// Authenticate signs the user in with the password it was created with.
+func (sc *Scenario) Authenticate(alias string) *Scenario {
+	return sc.Step("authenticate "+alias, func(ctx context.Context, st *State) error {
+		usr, err := st.User(alias)
+		if err != nil {
+			return err
+		}
+
+		_, err = st.Bus.User.Authenticate(ctx, usr.Email, st.passwords[alias])
+		return err
+	})
+}
+
+// AuthenticateWith signs the user in with the password given instead of
+// the one it was created with.
+func (sc *Scenario) AuthenticateWith(alias string, password string) *Scenario {
+	return sc.Step("authenticate "+alias+" with a password", func(ctx context.Context, st *State) error {
+		usr, err := st.User(alias)
+		if err != nil {
+			return err
+		}
+
+		_, err = st.Bus.User.Authenticate(ctx, usr.Email, password)
+		return err
+	})
+}
+
+// SetRoles has the actor replace the user's roles. An empty actor is the
+// system.
+func (sc *Scenario) SetRoles(actor string, alias string, roles ...role.Role) *Scenario {
+	return sc.update(fmt.Sprintf("set roles of %s by %s", alias, actorName(actor)), actor, alias, userbus.UpdateUser{Roles: roles})
+}
+
+// SetEnabled has the actor enable or disable the user. An empty actor is
+// the system.
+func (sc *Scenario) SetEnabled(actor string, alias string, enabled bool) *Scenario {
+	return sc.update(fmt.Sprintf("set enabled of %s by %s", alias, actorName(actor)), actor, alias, userbus.UpdateUser{Enabled: &enabled})
+}
+
+// Update has the actor apply the changes to the user. An empty actor is the
+// system.
+func (sc *Scenario) Update(actor string, alias string, uu userbus.UpdateUser) *Scenario {
+	return sc.update(fmt.Sprintf("update %s by %s", alias, actorName(actor)), actor, alias, uu)
+}
+
+// DeleteUser has the actor remove the user. An empty actor is the system.
+func (sc *Scenario) DeleteUser(actor string, alias string) *Scenario {
+	return sc.Step(fmt.Sprintf("delete %s by %s", alias, actorName(actor)), func(ctx context.Context, st *State) error {
+		actorID, err := st.actorID(actor)
+		if err != nil {
+			return err
+		}
+
+		usr, err := st.User(alias)
+		if err != nil {
+			return err
+		}
+
+		return st.Bus.User.Delete(ctx, actorID, usr)
+	})
+}
+
+func (sc *Scenario) update(stepName string, actor string, alias string, uu userbus.UpdateUser) *Scenario {
+	return sc.Step(stepName, func(ctx context.Context, st *State) error {
+		actorID, err := st.actorID(actor)
+		if err != nil {
+			return err
+		}
+
+		usr, err := st.User(alias)
+		if err != nil {
+			return err
+		}
+
+		// The stored user is used so an earlier step that changed it
+		// doesn't cause a version conflict.
+		usr, err = st.Bus.User.QueryByID(ctx, usr.ID)
+		if err != nil {
+			return err
+		}
+
+		updUsr, err := st.Bus.User.Update(ctx, actorID, usr, uu)
+		if err != nil {
+			return err
+		}
+
+		st.SetUser(alias, updUsr)
+
+		return nil
+	})
+}
+
+// =============================================================================
+
+// ExpectUser checks the user as it's stored now.
+func (sc *Scenario) ExpectUser(alias string, check func(usr userbus.User) error) *Scenario {
+	return sc.Step("expect "+alias, func(ctx context.Context, st *State) error {
+		usr, err := st.User(alias)
+		if err != nil {
+			return err
+		}
+
+		got, err := st.Bus.User.QueryByID(ctx, usr.ID)
+		if err != nil {
+			return err
+		}
+
+		st.SetUser(alias, got)
+
+		return check(got)
+	})
+}
+
+// ExpectRoles checks the user has exactly the roles.
+func (sc *Scenario) ExpectRoles(alias string, roles ...role.Role) *Scenario {
+	return sc.ExpectUser(alias, func(usr userbus.User) error {
+		if !hasRoles(usr, roles) {
+			return fmt.Errorf("roles are %v, expected %v", role.ParseToString(usr.Roles), role.ParseToString(roles))
+		}
+		return nil
+	})
+}
+
+// ExpectNoUser checks the user no longer exists.
+func (sc *Scenario) ExpectNoUser(alias string) *Scenario {
+	return sc.Step("expect no "+alias, func(ctx context.Context, st *State) error {
+		usr, err := st.User(alias)
+		if err != nil {
+			return err
+		}
+
+		_, err = st.Bus.User.QueryByID(ctx, usr.ID)
+		if !errors.Is(err, userbus.ErrNotFound) {
+			return fmt.Errorf("expected the user to be gone, got %v", err)
+		}
+
+		return nil
+	})
+}
+
+// ExpectEvent checks an event for the user was raised with the action of
+// the user domain. When fields are given the event has to report a change
+// to each of them.
+func (sc *Scenario) ExpectEvent(alias string, action string, fields ...string) *Scenario {
+	sc.Step(fmt.Sprintf("expect %s event for %s", action, alias), func(ctx context.Context, st *State) error {
+		usr, err := st.User(alias)
+		if err != nil {
+			return err
+		}
+
+		found := st.events.find(func(data delegate.Data) bool {
+			if data.Domain != userbus.DomainName || data.Action != action {
+				return false
+			}
+
+			var params struct {
+				UserID uuid.UUID
+			}
+			if err := json.Unmarshal(data.RawParams, &params); err != nil || params.UserID != usr.ID {
+				return false
+			}
+
+			for _, f := range fields {
+				if !slices.Contains(data.Fields(), f) {
+					return false
+				}
+			}
+
+			return true
+		})
+
+		if !found {
+			return fmt.Errorf("no %s event with fields %v", action, fields)
+		}
+
+		return nil
+	})
+
+	sc.steps[len(sc.steps)-1].events = [][2]string{{userbus.DomainName, action}}
+
+	return sc
+}
+
+// ExpectAudit checks the user has at least one audit record for the
+// action.
+func (sc *Scenario) ExpectAudit(alias string, action string) *Scenario {
+	return sc.Step(fmt.Sprintf("expect %s audit for %s", action, alias), func(ctx context.Context, st *State) error {
+		usr, err := st.User(alias)
+		if err != nil {
+			return err
+		}
+
+		n, err := auditCount(ctx, st.Bus.Audit, usr.ID, action)
+		if err != nil {
+			return err
+		}
+
+		if n == 0 {
+			return fmt.Errorf("no %s audit record", action)
+		}
+
+		return nil
+	})
+}
+
+func actorName(alias string) string {
+	if alias == "" {
+		return "system"
+	}
+
+	return alias
+}
@@ -0,0 +1,233 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/ardanlabs/service/foundation/ctxval"
+	"github.com/jmoiron/sqlx"
+)
+
+// Cluster routes the calls made by the stores between a primary database
+// and its read replicas. It can be used anywhere a *sqlx.DB is used as an
+// sqlx.ExtContext or a Beginner.
+//
+// Statements that only read, SELECT and EXPLAIN, are spread across the
+// replicas. Everything else goes to the primary, including statements that
+// are run as queries because they return rows, like DELETE ... RETURNING.
+// Transactions always run on the primary, so reads inside a transaction see
+// its writes. For reads outside a transaction to see earlier writes, the
+// context must carry a session, see WithSession.
+type Cluster struct {
+	primary  *sqlx.DB
+	replicas []*sqlx.DB
+	stmts    []*StmtCache
+	next     atomic.Uint64
+}
+
+// NewCluster constructs a cluster for the primary and its replicas. With no
+// replicas every call goes to the primary.
+func NewCluster(primary *sqlx.DB, replicas ...*sqlx.DB) *Cluster {
+	return &Cluster{
+		primary:  primary,
+		replicas: replicas,
+	}
+}
+
+// OpenCluster opens a connection to the primary described by the
+// configuration and to a read-only connection for each replica host.
+func OpenCluster(cfg Config, replicaHosts []string) (*Cluster, error) {
+	primary, err := Open(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	replicas := make([]*sqlx.DB, 0, len(replicaHosts))
+
+	for _, host := range replicaHosts {
+		rcfg := cfg
+		rcfg.Host = host
+		rcfg.ReadOnly = true
+
+		replica, err := Open(rcfg)
+		if err != nil {
+			cerr := NewCluster(primary, replicas...).Close()
+			return nil, errors.Join(err, cerr)
+		}
+
+		replicas = append(replicas, replica)
+	}
+
+	return NewCluster(primary, replicas...), nil
+}
+
+// CacheStatements caches up to size prepared statements for the primary and
+// for each replica, see StmtCache. It must be called before the cluster is
+// used.
+func (c *Cluster) CacheStatements(size int) {
+	c.stmts = make([]*StmtCache, 0, 1+len(c.replicas))
+
+	c.stmts = append(c.stmts, NewStmtCache(c.primary, size))
+	for _, replica := range c.replicas {
+		c.stmts = append(c.stmts, NewStmtCache(replica, size))
+	}
+}
+
+// Primary returns the connection to the primary database.
+func (c *Cluster) Primary() *sqlx.DB {
+	return c.primary
+}
+
+// Pools returns the databases of the cluster keyed by the name their pool
+// is reported under, primary for the primary and replica-N for the replicas.
+func (c *Cluster) Pools() map[string]*sqlx.DB {
+	pools := map[string]*sqlx.DB{
+		"primary": c.primary,
+	}
+
+	for i, replica := range c.replicas {
+		pools[fmt.Sprintf("replica-%d", i+1)] = replica
+	}
+
+	return pools
+}
+
+// Close closes the connections to the primary and the replicas.
+func (c *Cluster) Close() error {
+	var errs []error
+	for _, stmts := range c.stmts {
+		errs = append(errs, stmts.Close())
+	}
+
+	errs = append(errs, c.primary.Close())
+	for _, replica := range c.replicas {
+		errs = append(errs, replica.Close())
+	}
+
+	return errors.Join(errs...)
+}
+
+// Begin implements the Beginner interface. Transactions run on the primary.
+func (c *Cluster) Begin() (CommitRollbacker, error) {
+	return c.primary.Beginx()
+}
+
+// DriverName implements the sqlx.ExtContext interface.
+func (c *Cluster) DriverName() string {
+	return c.primary.DriverName()
+}
+
+// Rebind implements the sqlx.ExtContext interface.
+func (c *Cluster) Rebind(query string) string {
+	return c.primary.Rebind(query)
+}
+
+// BindNamed implements the sqlx.ExtContext interface.
+func (c *Cluster) BindNamed(query string, arg any) (string, []any, error) {
+	return c.primary.BindNamed(query, arg)
+}
+
+// QueryContext implements the sqlx.ExtContext interface.
+func (c *Cluster) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return c.route(ctx, query).QueryContext(ctx, query, args...)
+}
+
+// QueryxContext implements the sqlx.ExtContext interface.
+func (c *Cluster) QueryxContext(ctx context.Context, query string, args ...any) (*sqlx.Rows, error) {
+	return c.route(ctx, query).QueryxContext(ctx, query, args...)
+}
+
+// QueryRowxContext implements the sqlx.ExtContext interface.
+func (c *Cluster) QueryRowxContext(ctx context.Context, query string, args ...any) *sqlx.Row {
+	return c.route(ctx, query).QueryRowxContext(ctx, query, args...)
+}
+
+// ExecContext implements the sqlx.ExtContext interface.
+func (c *Cluster) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	markWrite(ctx)
+	return c.conn(0).ExecContext(ctx, query, args...)
+}
+
+// route returns the database the query runs on. Reads go to the next
+// replica unless the session in the context has already written.
+func (c *Cluster) route(ctx context.Context, query string) sqlx.ExtContext {
+	if !readOnlyStatement(query) {
+		markWrite(ctx)
+		return c.conn(0)
+	}
+
+	if len(c.replicas) == 0 || hasWritten(ctx) {
+		return c.conn(0)
+	}
+
+	n := c.next.Add(1)
+
+	return c.conn(1 + int(n%uint64(len(c.replicas))))
+}
+
+// conn returns the database calls are made on, the primary at zero and
+// the replicas after it, going through the statement cache when there is
+// one.
+func (c *Cluster) conn(i int) sqlx.ExtContext {
+	switch {
+	case c.stmts != nil:
+		return c.stmts[i]
+	case i == 0:
+		return c.primary
+	default:
+		return c.replicas[i-1]
+	}
+}
+
+// readOnlyStatement reports whether the statement can only read.
+func readOnlyStatement(query string) bool {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return false
+	}
+
+	switch strings.ToUpper(fields[0]) {
+	case "SELECT", "EXPLAIN":
+		return true
+	}
+
+	return false
+}
+
+// =============================================================================
+
+var sessionKey = ctxval.NewKey[*session]("database session")
+
+type session struct {
+	written atomic.Bool
+}
+
+// WithSession returns a context that carries a session. Once a write is made
+// with the context, every read made with it goes to the primary so it sees
+// the write, no matter how far the replicas lag behind.
+func WithSession(ctx context.Context) context.Context {
+	return sessionKey.Set(ctx, &session{})
+}
+
+// WithPrimary returns a context whose reads all go to the primary.
+func WithPrimary(ctx context.Context) context.Context {
+	ctx = WithSession(ctx)
+	markWrite(ctx)
+
+	return ctx
+}
+
+func markWrite(ctx context.Context) {
+	if s, ok := sessionKey.Get(ctx); ok {
+		s.written.Store(true)
+	}
+}
+
+func hasWritten(ctx context.Context) bool {
+	s, ok := sessionKey.Get(ctx)
+	return ok && s.written.Load()
+}
@@ -0,0 +1,68 @@
+package sqldb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrQueryTooExpensive is returned when the planner estimates a query will
+// cost more than the budget it's checked against.
+var ErrQueryTooExpensive = errors.New("query too expensive")
+
+// costRejected counts the queries rejected for going over their budget.
+var costRejected = expvar.NewInt("query_cost_rejected")
+
+// EstimateCost returns the planner's estimate of the total cost of the
+// query, in the planner's own units, without running it.
+func EstimateCost(ctx context.Context, log *logger.Logger, db sqlx.ExtContext, query string, data any) (float64, error) {
+	var dest struct {
+		Plan string `db:"QUERY PLAN"`
+	}
+
+	if err := NamedQueryStruct(ctx, log, db, "EXPLAIN (FORMAT JSON) "+query, data, &dest); err != nil {
+		return 0, fmt.Errorf("explain: %w", err)
+	}
+
+	var plans []struct {
+		Plan struct {
+			TotalCost float64 `json:"Total Cost"`
+		} `json:"Plan"`
+	}
+
+	if err := json.Unmarshal([]byte(dest.Plan), &plans); err != nil {
+		return 0, fmt.Errorf("unmarshal plan: %w", err)
+	}
+
+	if len(plans) == 0 {
+		return 0, errors.New("explain: no plan returned")
+	}
+
+	return plans[0].Plan.TotalCost, nil
+}
+
+// CheckCost estimates the cost of the query and returns ErrQueryTooExpensive
+// if it's over the budget. A budget of zero or less disables the check.
+func CheckCost(ctx context.Context, log *logger.Logger, db sqlx.ExtContext, query string, data any, budget float64) error {
+	if budget <= 0 {
+		return nil
+	}
+
+	cost, err := EstimateCost(ctx, log, db, query, data)
+	if err != nil {
+		return err
+	}
+
+	if cost > budget {
+		costRejected.Add(1)
+		log.Info(ctx, "database.CheckCost: query rejected", "cost", cost, "budget", budget, "query", queryString(query, data))
+		return fmt.Errorf("estimated cost %.0f exceeds budget %.0f: %w", cost, budget, ErrQueryTooExpensive)
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package sqldb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// PoolStats is a snapshot of the connection pool of a database.
+type PoolStats struct {
+	MaxOpen           int
+	Open              int
+	InUse             int
+	Idle              int
+	WaitCount         int64
+	WaitDuration      time.Duration
+	MaxIdleClosed     int64
+	MaxIdleTimeClosed int64
+	MaxLifetimeClosed int64
+}
+
+// Stats returns a snapshot of the connection pool of the database.
+func Stats(db *sqlx.DB) PoolStats {
+	s := db.Stats()
+
+	return PoolStats{
+		MaxOpen:           s.MaxOpenConnections,
+		Open:              s.OpenConnections,
+		InUse:             s.InUse,
+		Idle:              s.Idle,
+		WaitCount:         s.WaitCount,
+		WaitDuration:      s.WaitDuration,
+		MaxIdleClosed:     s.MaxIdleClosed,
+		MaxIdleTimeClosed: s.MaxIdleTimeClosed,
+		MaxLifetimeClosed: s.MaxLifetimeClosed,
+	}
+}
+
+// RegisterPoolMetrics registers instruments with the meter that report the
+// pool stats of each of the databases when the metrics are collected. The
+// pools are told apart by the db.client.connection.pool.name attribute.
+// When the meter is nil the meter of the global provider is used.
+func RegisterPoolMetrics(meter metric.Meter, pools map[string]*sqlx.DB) (metric.Registration, error) {
+	if meter == nil {
+		meter = otel.Meter("github.com/ardanlabs/service/business/sdk/sqldb")
+	}
+
+	count, err := meter.Int64ObservableUpDownCounter("db.client.connection.count",
+		metric.WithDescription("The number of connections in the pool by state."),
+		metric.WithUnit("{connection}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("count: %w", err)
+	}
+
+	maxOpen, err := meter.Int64ObservableUpDownCounter("db.client.connection.max",
+		metric.WithDescription("The maximum number of open connections allowed, zero is unlimited."),
+		metric.WithUnit("{connection}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("max: %w", err)
+	}
+
+	waits, err := meter.Int64ObservableCounter("db.client.connection.wait_count",
+		metric.WithDescription("The number of times a call waited for a connection."),
+		metric.WithUnit("{wait}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("wait count: %w", err)
+	}
+
+	waitTime, err := meter.Float64ObservableCounter("db.client.connection.wait_time",
+		metric.WithDescription("The total time calls waited for a connection."),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("wait time: %w", err)
+	}
+
+	callback := func(ctx context.Context, o metric.Observer) error {
+		for name, db := range pools {
+			s := Stats(db)
+			pool := attribute.String("db.client.connection.pool.name", name)
+
+			o.ObserveInt64(count, int64(s.Idle), metric.WithAttributes(pool, attribute.String("db.client.connection.state", "idle")))
+			o.ObserveInt64(count, int64(s.InUse), metric.WithAttributes(pool, attribute.String("db.client.connection.state", "used")))
+			o.ObserveInt64(maxOpen, int64(s.MaxOpen), metric.WithAttributes(pool))
+			o.ObserveInt64(waits, s.WaitCount, metric.WithAttributes(pool))
+			o.ObserveFloat64(waitTime, s.WaitDuration.Seconds(), metric.WithAttributes(pool))
+		}
+
+		return nil
+	}
+
+	reg, err := meter.RegisterCallback(callback, count, maxOpen, waits, waitTime)
+	if err != nil {
+		return nil, fmt.Errorf("register callback: %w", err)
+	}
+
+	return reg, nil
+}
@@ -0,0 +1,48 @@
+package sqldb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+func Test_PoolStats(t *testing.T) {
+	cfg := sqldb.Config{
+		User:            "postgres",
+		Password:        "postgres",
+		Host:            "localhost",
+		Name:            "postgres",
+		MaxOpenConns:    7,
+		DisableTLS:      true,
+		ConnMaxLifetime: time.Minute,
+		ConnMaxIdleTime: time.Second,
+	}
+
+	// Opening the cluster doesn't connect, so no database is needed.
+	cluster, err := sqldb.OpenCluster(cfg, []string{"replica-host"})
+	if err != nil {
+		t.Fatalf("Should be able to open the cluster: %s", err)
+	}
+	defer cluster.Close()
+
+	pools := cluster.Pools()
+	if len(pools) != 2 || pools["primary"] != cluster.Primary() || pools["replica-1"] == nil {
+		t.Fatalf("Should name the pools of the cluster: %v", pools)
+	}
+
+	stats := sqldb.Stats(cluster.Primary())
+	if stats.MaxOpen != 7 || stats.Open != 0 {
+		t.Fatalf("Should report the pool: %+v", stats)
+	}
+
+	reg, err := sqldb.RegisterPoolMetrics(noop.NewMeterProvider().Meter("test"), pools)
+	if err != nil {
+		t.Fatalf("Should be able to register the metrics: %s", err)
+	}
+
+	if err := reg.Unregister(); err != nil {
+		t.Fatalf("Should be able to unregister the metrics: %s", err)
+	}
+}
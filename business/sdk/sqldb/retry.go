@@ -0,0 +1,373 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"expvar"
+	"io"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrCircuitOpen is returned without calling the database while the circuit
+// breaker is open.
+var ErrCircuitOpen = errors.New("database circuit breaker is open")
+
+// Set of metrics for the retries and the circuit breaker.
+var (
+	retries        = expvar.NewInt("db_retries")
+	breakerOpen    = expvar.NewInt("db_breaker_open")
+	breakerTrips   = expvar.NewInt("db_breaker_trips")
+	breakerRejects = expvar.NewInt("db_breaker_rejected")
+)
+
+// ExtBeginner is a database that can run statements and start transactions,
+// like a *Cluster.
+type ExtBeginner interface {
+	sqlx.ExtContext
+	Beginner
+}
+
+// RetryOptions represents the retry and circuit breaker settings.
+type RetryOptions struct {
+	attempts  int
+	base      time.Duration
+	max       time.Duration
+	threshold int
+	cooldown  time.Duration
+}
+
+// WithRetries sets how many times a call is attempted and the range of the
+// delay between attempts. The delay doubles after every attempt starting at
+// base and never goes over max. One attempt disables retries.
+func WithRetries(attempts int, base time.Duration, max time.Duration) func(opts *RetryOptions) {
+	return func(opts *RetryOptions) {
+		opts.attempts = attempts
+		opts.base = base
+		opts.max = max
+	}
+}
+
+// WithBreaker sets how many connection failures in a row open the circuit
+// breaker and how long it stays open before a call is let through to test
+// the database. A threshold of zero disables the breaker.
+func WithBreaker(threshold int, cooldown time.Duration) func(opts *RetryOptions) {
+	return func(opts *RetryOptions) {
+		opts.threshold = threshold
+		opts.cooldown = cooldown
+	}
+}
+
+// Resilient wraps a database so calls that fail for a transient reason are
+// retried with a backoff, and calls fail fast while the database is down.
+// It can be used anywhere the database it wraps is used.
+//
+// Serialization failures and deadlocks are retried for every statement since
+// the database rolled the statement back. Connection failures are retried for
+// statements that only read, or when the driver knows the statement was never
+// sent, so a write is never applied twice. Statements inside a transaction
+// don't go through the wrapper, a failed transaction must be retried as a
+// whole by its caller.
+type Resilient struct {
+	log     *logger.Logger
+	db      ExtBeginner
+	opts    RetryOptions
+	breaker *breaker
+}
+
+// NewResilient constructs a wrapper for the database. By default calls are
+// attempted 3 times and the breaker opens after 5 connection failures in a
+// row for 10 seconds.
+func NewResilient(log *logger.Logger, db ExtBeginner, options ...func(opts *RetryOptions)) *Resilient {
+	opts := RetryOptions{
+		attempts:  3,
+		base:      50 * time.Millisecond,
+		max:       time.Second,
+		threshold: 5,
+		cooldown:  10 * time.Second,
+	}
+
+	for _, option := range options {
+		option(&opts)
+	}
+
+	return &Resilient{
+		log:     log,
+		db:      db,
+		opts:    opts,
+		breaker: newBreaker(opts.threshold, opts.cooldown),
+	}
+}
+
+// Begin implements the Beginner interface. Starting a transaction is retried
+// when the connection fails since nothing has been done yet.
+func (r *Resilient) Begin() (CommitRollbacker, error) {
+	var tx CommitRollbacker
+
+	err := r.do(context.Background(), "BEGIN", func() error {
+		var err error
+		tx, err = r.db.Begin()
+		return err
+	})
+
+	return tx, err
+}
+
+// DriverName implements the sqlx.ExtContext interface.
+func (r *Resilient) DriverName() string {
+	return r.db.DriverName()
+}
+
+// Rebind implements the sqlx.ExtContext interface.
+func (r *Resilient) Rebind(query string) string {
+	return r.db.Rebind(query)
+}
+
+// BindNamed implements the sqlx.ExtContext interface.
+func (r *Resilient) BindNamed(query string, arg any) (string, []any, error) {
+	return r.db.BindNamed(query, arg)
+}
+
+// QueryContext implements the sqlx.ExtContext interface.
+func (r *Resilient) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	var rows *sql.Rows
+
+	err := r.do(ctx, query, func() error {
+		var err error
+		rows, err = r.db.QueryContext(ctx, query, args...)
+		return err
+	})
+
+	return rows, err
+}
+
+// QueryxContext implements the sqlx.ExtContext interface.
+func (r *Resilient) QueryxContext(ctx context.Context, query string, args ...any) (*sqlx.Rows, error) {
+	var rows *sqlx.Rows
+
+	err := r.do(ctx, query, func() error {
+		var err error
+		rows, err = r.db.QueryxContext(ctx, query, args...)
+		return err
+	})
+
+	return rows, err
+}
+
+// QueryRowxContext implements the sqlx.ExtContext interface. A row can't be
+// made to hold ErrCircuitOpen, so while the breaker is open the query is
+// passed to the database once without retries.
+func (r *Resilient) QueryRowxContext(ctx context.Context, query string, args ...any) *sqlx.Row {
+	var row *sqlx.Row
+
+	r.do(ctx, query, func() error {
+		row = r.db.QueryRowxContext(ctx, query, args...)
+		return row.Err()
+	})
+
+	if row == nil {
+		return r.db.QueryRowxContext(ctx, query, args...)
+	}
+
+	return row
+}
+
+// ExecContext implements the sqlx.ExtContext interface.
+func (r *Resilient) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	var result sql.Result
+
+	err := r.do(ctx, query, func() error {
+		var err error
+		result, err = r.db.ExecContext(ctx, query, args...)
+		return err
+	})
+
+	return result, err
+}
+
+// do makes the call through the breaker and retries it while it fails for a
+// reason that is safe to retry.
+func (r *Resilient) do(ctx context.Context, query string, call func() error) error {
+	delay := r.opts.base
+
+	for attempt := 1; ; attempt++ {
+		if err := r.breaker.allow(); err != nil {
+			return err
+		}
+
+		err := call()
+		r.breaker.record(err)
+
+		if err == nil || attempt >= r.opts.attempts || !retryable(query, err) {
+			return err
+		}
+
+		// Full jitter spreads out the retries of the calls that failed
+		// together.
+		var wait time.Duration
+		if delay > 0 {
+			wait = rand.N(delay) + 1
+		}
+
+		retries.Add(1)
+		r.log.Info(ctx, "database: retrying", "attempt", attempt, "wait", wait, "ERROR", err)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+
+		delay = min(delay*2, r.opts.max)
+	}
+}
+
+// =============================================================================
+
+// Postgres error codes for failures that are worth retrying.
+const (
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
+	adminShutdown        = "57P01"
+	cannotConnectNow     = "57P03"
+	connectionException  = "08"
+)
+
+// rolledBack reports whether the database rolled the statement back because
+// it conflicted with another one, so running it again can succeed.
+func rolledBack(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+
+	switch pgErr.Code {
+	case serializationFailure, deadlockDetected:
+		return true
+	}
+
+	return false
+}
+
+// connectionFailed reports whether the call failed because the connection
+// to the database did.
+func connectionFailed(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch {
+		case pgErr.Code == adminShutdown, pgErr.Code == cannotConnectNow:
+			return true
+		case strings.HasPrefix(pgErr.Code, connectionException):
+			return true
+		}
+
+		return false
+	}
+
+	return errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		pgconn.SafeToRetry(err)
+}
+
+// retryable reports whether the statement can be run again after failing
+// with the error.
+func retryable(query string, err error) bool {
+	switch {
+	case rolledBack(err):
+		return true
+	case connectionFailed(err):
+		return pgconn.SafeToRetry(err) || query == "BEGIN" || readOnlyStatement(query)
+	}
+
+	return false
+}
+
+// =============================================================================
+
+// breaker stops calls to the database after a run of connection failures.
+// A statement the database rolled back because of a conflict shows the
+// database is up and busy, so it doesn't count.
+// Once the cooldown passes a single call is let through, and the breaker
+// closes if it succeeds or opens again if it fails.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	return &breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+// allow returns ErrCircuitOpen if the call can't be made.
+func (b *breaker) allow() error {
+	if b.threshold <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return nil
+	}
+
+	if time.Now().Before(b.openUntil) || b.probing {
+		breakerRejects.Add(1)
+		return ErrCircuitOpen
+	}
+
+	b.probing = true
+
+	return nil
+}
+
+// record updates the breaker with the result of a call. Errors that say
+// nothing about the database being down, like a serialization failure, a
+// canceled context or a missing row, count as the database working.
+func (b *breaker) record(err error) {
+	if b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	wasProbing := b.probing
+	b.probing = false
+
+	switch {
+	case err != nil && connectionFailed(err):
+		b.failures++
+		if b.failures >= b.threshold {
+			if b.failures == b.threshold || wasProbing {
+				breakerTrips.Add(1)
+			}
+			b.openUntil = time.Now().Add(b.cooldown)
+			breakerOpen.Set(1)
+		}
+
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+
+	default:
+		b.failures = 0
+		breakerOpen.Set(0)
+	}
+}
@@ -0,0 +1,238 @@
+package sqldb_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/foundation/logger"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jmoiron/sqlx"
+)
+
+// fakeDB fails every call with the next error in its list and succeeds once
+// the list runs out.
+type fakeDB struct {
+	sqlx.ExtContext
+
+	mu    sync.Mutex
+	errs  []error
+	calls int
+}
+
+func (db *fakeDB) next() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.calls++
+
+	if len(db.errs) == 0 {
+		return nil
+	}
+
+	err := db.errs[0]
+	db.errs = db.errs[1:]
+
+	return err
+}
+
+func (db *fakeDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if err := db.next(); err != nil {
+		return nil, err
+	}
+
+	return driver.RowsAffected(1), nil
+}
+
+func (db *fakeDB) Begin() (sqldb.CommitRollbacker, error) {
+	if err := db.next(); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (db *fakeDB) failWith(errs ...error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.errs = errs
+	db.calls = 0
+}
+
+func (db *fakeDB) callCount() int {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return db.calls
+}
+
+// unsent is a connection failure the driver knows happened before the
+// statement was sent.
+type unsent struct{}
+
+func (unsent) Error() string     { return "dial failed" }
+func (unsent) SafeToRetry() bool { return true }
+
+func newLog() *logger.Logger {
+	var buf bytes.Buffer
+	return logger.New(&buf, logger.LevelInfo, "TEST", func(context.Context) string { return "" })
+}
+
+// =============================================================================
+
+var (
+	errSerialization = &pgconn.PgError{Code: "40001"}
+	errDeadlock      = &pgconn.PgError{Code: "40P01"}
+	errShutdown      = &pgconn.PgError{Code: "57P01"}
+	errConnection    = &pgconn.PgError{Code: "08006"}
+	errUnique        = &pgconn.PgError{Code: "23505"}
+)
+
+func Test_Retry(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		errs  []error
+		calls int
+		err   error
+	}{
+		{name: "success", query: "UPDATE users SET name = 'a'", calls: 1},
+		{name: "serialization", query: "UPDATE users SET name = 'a'", errs: []error{errSerialization}, calls: 2},
+		{name: "deadlock", query: "UPDATE users SET name = 'a'", errs: []error{errDeadlock, errDeadlock}, calls: 3},
+		{name: "exhausted", query: "UPDATE users SET name = 'a'", errs: []error{errDeadlock, errDeadlock, errDeadlock}, calls: 3, err: errDeadlock},
+		{name: "readconnection", query: "SELECT 1", errs: []error{errShutdown}, calls: 2},
+		{name: "readbadconn", query: "select 1", errs: []error{driver.ErrBadConn}, calls: 2},
+		{name: "writeconnection", query: "UPDATE users SET name = 'a'", errs: []error{errConnection}, calls: 1, err: errConnection},
+		{name: "writeunsent", query: "UPDATE users SET name = 'a'", errs: []error{unsent{}}, calls: 2},
+		{name: "begin", query: "BEGIN", errs: []error{driver.ErrBadConn}, calls: 2},
+		{name: "notransient", query: "SELECT 1", errs: []error{errUnique}, calls: 1, err: errUnique},
+		{name: "norows", query: "SELECT 1", errs: []error{sql.ErrNoRows}, calls: 1, err: sql.ErrNoRows},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := fakeDB{errs: tt.errs}
+			r := sqldb.NewResilient(newLog(), &db, sqldb.WithRetries(3, 0, 0), sqldb.WithBreaker(0, 0))
+
+			var err error
+			if tt.query == "BEGIN" {
+				_, err = r.Begin()
+			} else {
+				_, err = r.ExecContext(context.Background(), tt.query)
+			}
+
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+
+			if n := db.callCount(); n != tt.calls {
+				t.Fatalf("expected %d calls, got %d", tt.calls, n)
+			}
+		})
+	}
+}
+
+func Test_Breaker(t *testing.T) {
+	const cooldown = 50 * time.Millisecond
+
+	tests := []struct {
+		name string
+		errs []error
+		open bool
+	}{
+		{name: "connection", errs: []error{errShutdown, errShutdown}, open: true},
+		{name: "badconn", errs: []error{driver.ErrBadConn, errConnection}, open: true},
+		{name: "belowthreshold", errs: []error{errShutdown}, open: false},
+		{name: "reset", errs: []error{errShutdown, nil, errShutdown}, open: false},
+		{name: "serialization", errs: []error{errSerialization, errSerialization, errDeadlock}, open: false},
+		{name: "rolledbackresets", errs: []error{errShutdown, errSerialization, errShutdown}, open: false},
+		{name: "canceled", errs: []error{errShutdown, context.Canceled, errShutdown}, open: true},
+		{name: "notransient", errs: []error{errUnique, errUnique, errUnique}, open: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var db fakeDB
+			r := sqldb.NewResilient(newLog(), &db, sqldb.WithRetries(1, 0, 0), sqldb.WithBreaker(2, cooldown))
+
+			for _, err := range tt.errs {
+				db.failWith(err)
+				r.ExecContext(context.Background(), "UPDATE users SET name = 'a'")
+			}
+
+			db.failWith()
+			_, err := r.ExecContext(context.Background(), "UPDATE users SET name = 'a'")
+
+			if !tt.open {
+				if err != nil {
+					t.Fatalf("expected the breaker to be closed, got %v", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, sqldb.ErrCircuitOpen) {
+				t.Fatalf("expected the breaker to be open, got %v", err)
+			}
+
+			if n := db.callCount(); n != 0 {
+				t.Fatalf("expected no call to reach the database, got %d", n)
+			}
+		})
+	}
+}
+
+func Test_BreakerProbe(t *testing.T) {
+	const cooldown = 50 * time.Millisecond
+
+	tests := []struct {
+		name  string
+		probe error
+		open  bool
+	}{
+		{name: "recovered", probe: nil, open: false},
+		{name: "stilldown", probe: errShutdown, open: true},
+		{name: "busy", probe: errSerialization, open: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var db fakeDB
+			r := sqldb.NewResilient(newLog(), &db, sqldb.WithRetries(1, 0, 0), sqldb.WithBreaker(2, cooldown))
+
+			db.failWith(errShutdown, errShutdown)
+			r.ExecContext(context.Background(), "UPDATE users SET name = 'a'")
+			r.ExecContext(context.Background(), "UPDATE users SET name = 'a'")
+
+			time.Sleep(2 * cooldown)
+
+			// The first call after the cooldown goes through as the probe.
+			db.failWith(tt.probe)
+			r.ExecContext(context.Background(), "UPDATE users SET name = 'a'")
+
+			if n := db.callCount(); n != 1 {
+				t.Fatalf("expected the probe to reach the database, got %d calls", n)
+			}
+
+			db.failWith()
+			_, err := r.ExecContext(context.Background(), "UPDATE users SET name = 'a'")
+
+			if !tt.open {
+				if err != nil {
+					t.Fatalf("expected the breaker to close, got %v", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, sqldb.ErrCircuitOpen) {
+				t.Fatalf("expected the breaker to open again, got %v", err)
+			}
+		})
+	}
+}
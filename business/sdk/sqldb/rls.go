@@ -0,0 +1,46 @@
+package sqldb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ardanlabs/service/business/sdk/tenant"
+	"github.com/google/uuid"
+)
+
+// Set of settings made for a transaction so the row-level security policies
+// in the database know who the statements are run for. The policies read
+// them with current_setting, see app_enable_tenant_rls in the migrations.
+const (
+	SettingTenant = "app.current_tenant"
+	SettingUser   = "app.current_user"
+)
+
+// SetCurrent sets the tenant in the context and the specified user as the
+// current tenant and user of the transaction. The settings are local to the
+// transaction and go away when it ends. A tenant or user that isn't known
+// is set to an empty string, which the policies treat as not scoped.
+func SetCurrent(ctx context.Context, tx CommitRollbacker, userID uuid.UUID) error {
+	ec, err := GetExtContext(tx)
+	if err != nil {
+		return err
+	}
+
+	var tenantID string
+	if id, ok := tenant.Get(ctx); ok {
+		tenantID = id.String()
+	}
+
+	var user string
+	if userID != uuid.Nil {
+		user = userID.String()
+	}
+
+	const q = `SELECT set_config($1, $2, true), set_config($3, $4, true)`
+
+	if _, err := ec.ExecContext(ctx, q, SettingTenant, tenantID, SettingUser, user); err != nil {
+		return fmt.Errorf("set current: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// savepointSeq names the savepoints so nested ones don't clash.
+var savepointSeq atomic.Int64
+
+// Savepoint represents an inner atomic section of a transaction. It can be
+// used anywhere the transaction is used, including NewWithTx, so the calls
+// made with it can be undone without rolling back the whole transaction.
+// Commit releases the savepoint, keeping its changes as part of the
+// transaction, and Rollback undoes the changes made since it was created.
+// Savepoints can be nested by creating one from another.
+type Savepoint struct {
+	sqlx.ExtContext
+	ctx  context.Context
+	name string
+	mu   sync.Mutex
+	done bool
+}
+
+// NewSavepoint creates a savepoint in the transaction. The context is used
+// to release or roll back the savepoint, without its cancellation so the
+// section can always be ended.
+func NewSavepoint(ctx context.Context, tx CommitRollbacker) (*Savepoint, error) {
+	ec, err := GetExtContext(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	name := fmt.Sprintf("sp_%d", savepointSeq.Add(1))
+
+	if _, err := ec.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return nil, fmt.Errorf("savepoint: %w", err)
+	}
+
+	sp := Savepoint{
+		ExtContext: ec,
+		ctx:        context.WithoutCancel(ctx),
+		name:       name,
+	}
+
+	return &sp, nil
+}
+
+// Commit implements the CommitRollbacker interface and releases the
+// savepoint. The changes are only saved when the transaction commits.
+func (sp *Savepoint) Commit() error {
+	return sp.end("RELEASE SAVEPOINT ")
+}
+
+// Rollback implements the CommitRollbacker interface and undoes the changes
+// made since the savepoint was created. The transaction can carry on, even
+// after a statement in the section failed. Like a transaction, rolling back
+// a savepoint that has ended returns sql.ErrTxDone.
+func (sp *Savepoint) Rollback() error {
+	return sp.end("ROLLBACK TO SAVEPOINT ")
+}
+
+func (sp *Savepoint) end(stmt string) error {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	if sp.done {
+		return sql.ErrTxDone
+	}
+
+	if _, err := sp.ExecContext(sp.ctx, stmt+sp.name); err != nil {
+		return fmt.Errorf("%s: %w", stmt+sp.name, err)
+	}
+
+	sp.done = true
+
+	return nil
+}
+
+// =============================================================================
+
+// SavepointBeginner implements the Beginner interface for a transaction that
+// is already running. Every begin creates a savepoint in it, so code that
+// starts its own transaction from a Beginner runs as an inner section of
+// the transaction instead.
+type SavepointBeginner struct {
+	tx CommitRollbacker
+}
+
+// NewSavepointBeginner constructs a beginner for the transaction.
+func NewSavepointBeginner(tx CommitRollbacker) *SavepointBeginner {
+	return &SavepointBeginner{
+		tx: tx,
+	}
+}
+
+// Begin implements the Beginner interface and returns a savepoint in the
+// transaction.
+func (b *SavepointBeginner) Begin() (CommitRollbacker, error) {
+	return NewSavepoint(context.Background(), b.tx)
+}
@@ -0,0 +1,110 @@
+package sqldb_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/ardanlabs/service/business/sdk/dbtest"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+)
+
+func Test_Savepoint(t *testing.T) {
+	t.Parallel()
+
+	db := dbtest.New(t, "Test_Savepoint")
+
+	ctx := context.Background()
+
+	if err := sqldb.ExecContext(ctx, db.Log, db.DB, `CREATE TABLE savepoints (n INT PRIMARY KEY)`); err != nil {
+		t.Fatalf("Should be able to create the table: %s", err)
+	}
+
+	tx, err := sqldb.NewBeginner(db.DB).Begin()
+	if err != nil {
+		t.Fatalf("Should be able to begin a transaction: %s", err)
+	}
+	defer tx.Rollback()
+
+	insert := func(ec sqldb.CommitRollbacker, n int) error {
+		c, err := sqldb.GetExtContext(ec)
+		if err != nil {
+			return err
+		}
+
+		return sqldb.NamedExecContext(ctx, db.Log, c, `INSERT INTO savepoints (n) VALUES (:n)`, map[string]any{"n": n})
+	}
+
+	if err := insert(tx, 1); err != nil {
+		t.Fatalf("Should be able to insert in the transaction: %s", err)
+	}
+
+	// -------------------------------------------------------------------------
+	// A section that fails is rolled back and the transaction carries on.
+
+	sp, err := sqldb.NewSavepoint(ctx, tx)
+	if err != nil {
+		t.Fatalf("Should be able to create a savepoint: %s", err)
+	}
+
+	if err := insert(sp, 2); err != nil {
+		t.Fatalf("Should be able to insert in the savepoint: %s", err)
+	}
+
+	if err := insert(sp, 1); !errors.Is(err, sqldb.ErrDBDuplicatedEntry) {
+		t.Fatalf("Should fail the duplicate insert: %v", err)
+	}
+
+	if err := sp.Rollback(); err != nil {
+		t.Fatalf("Should be able to roll back the savepoint: %s", err)
+	}
+
+	if err := sp.Rollback(); !errors.Is(err, sql.ErrTxDone) {
+		t.Fatalf("Should report the savepoint has ended: %v", err)
+	}
+
+	// -------------------------------------------------------------------------
+	// Nested sections that succeed are kept, the inner one rolled back.
+
+	outer, err := sqldb.NewSavepointBeginner(tx).Begin()
+	if err != nil {
+		t.Fatalf("Should be able to begin a savepoint: %s", err)
+	}
+
+	if err := insert(outer, 3); err != nil {
+		t.Fatalf("Should be able to insert in the savepoint: %s", err)
+	}
+
+	inner, err := sqldb.NewSavepoint(ctx, outer)
+	if err != nil {
+		t.Fatalf("Should be able to nest a savepoint: %s", err)
+	}
+
+	if err := insert(inner, 4); err != nil {
+		t.Fatalf("Should be able to insert in the nested savepoint: %s", err)
+	}
+
+	if err := inner.Rollback(); err != nil {
+		t.Fatalf("Should be able to roll back the nested savepoint: %s", err)
+	}
+
+	if err := outer.Commit(); err != nil {
+		t.Fatalf("Should be able to release the savepoint: %s", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Should be able to commit the transaction: %s", err)
+	}
+
+	var rows []struct {
+		N int `db:"n"`
+	}
+	if err := sqldb.QuerySlice(ctx, db.Log, db.DB, `SELECT n FROM savepoints ORDER BY n`, &rows); err != nil {
+		t.Fatalf("Should be able to query the rows: %s", err)
+	}
+
+	if len(rows) != 2 || rows[0].N != 1 || rows[1].N != 3 {
+		t.Fatalf("Should keep the rows outside the rolled back sections: %+v", rows)
+	}
+}
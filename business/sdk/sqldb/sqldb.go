@@ -7,13 +7,19 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"reflect"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/ardanlabs/service/business/sdk/classify"
+	"github.com/ardanlabs/service/foundation/diag"
 	"github.com/ardanlabs/service/foundation/logger"
 	"github.com/ardanlabs/service/foundation/otel"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
-	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jackc/pgx/v5/pgconn/ctxwatch"
+	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
 	"go.opentelemetry.io/otel/attribute"
 )
@@ -21,8 +27,9 @@ import (
 // lib/pq errorCodeNames
 // https://github.com/lib/pq/blob/master/error.go#L178
 const (
-	uniqueViolation = "23505"
-	undefinedTable  = "42P01"
+	uniqueViolation     = "23505"
+	undefinedTable      = "42P01"
+	readOnlyTransaction = "25006"
 )
 
 // Set of error variables for CRUD operations.
@@ -30,6 +37,7 @@ var (
 	ErrDBNotFound        = sql.ErrNoRows
 	ErrDBDuplicatedEntry = errors.New("duplicated entry")
 	ErrUndefinedTable    = errors.New("undefined table")
+	ErrDBReadOnly        = errors.New("database is read-only")
 )
 
 // Config is the required properties to use the database.
@@ -42,6 +50,17 @@ type Config struct {
 	MaxIdleConns int
 	MaxOpenConns int
 	DisableTLS   bool
+	ReadOnly     bool
+
+	// StatementTimeout is the longest the database lets a statement run
+	// before cancelling it. Zero leaves statements unbounded.
+	StatementTimeout time.Duration
+
+	// ConnMaxLifetime is how long a connection is used before it's closed
+	// and ConnMaxIdleTime is how long it can sit idle in the pool. Zero
+	// keeps connections for as long as the pool wants them.
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
 }
 
 // Open knows how to open a database connection based on the configuration.
@@ -58,6 +77,16 @@ func Open(cfg Config) (*sqlx.DB, error) {
 		q.Set("search_path", cfg.Schema)
 	}
 
+	// Every transaction on a read-only connection is started read-only so
+	// the database rejects any write that gets this far.
+	if cfg.ReadOnly {
+		q.Set("default_transaction_read_only", "on")
+	}
+
+	if cfg.StatementTimeout > 0 {
+		q.Set("statement_timeout", strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10))
+	}
+
 	u := url.URL{
 		Scheme:   "postgres",
 		User:     url.UserPassword(cfg.User, cfg.Password),
@@ -66,12 +95,26 @@ func Open(cfg Config) (*sqlx.DB, error) {
 		RawQuery: q.Encode(),
 	}
 
-	db, err := sqlx.Open("pgx", u.String())
+	connCfg, err := pgx.ParseConfig(u.String())
 	if err != nil {
 		return nil, err
 	}
+
+	// By default a cancelled context only drops the connection and leaves
+	// the statement running on the database. A cancel request is sent
+	// instead so the statement is killed there too.
+	connCfg.BuildContextWatcherHandler = func(pgConn *pgconn.PgConn) ctxwatch.Handler {
+		return &pgconn.CancelRequestContextWatcherHandler{
+			Conn:          pgConn,
+			DeadlineDelay: time.Second,
+		}
+	}
+
+	db := sqlx.NewDb(stdlib.OpenDB(*connCfg), "pgx")
 	db.SetMaxIdleConns(cfg.MaxIdleConns)
 	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
 
 	return db, nil
 }
@@ -120,8 +163,11 @@ func ExecContext(ctx context.Context, log *logger.Logger, db sqlx.ExtContext, qu
 // logging and tracing where field replacement is necessary.
 func NamedExecContext(ctx context.Context, log *logger.Logger, db sqlx.ExtContext, query string, data any) (err error) {
 	q := queryString(query, data)
+	start := time.Now()
 
 	defer func() {
+		diag.AddQuery(ctx, query, time.Since(start), err)
+
 		if err != nil {
 			switch data.(type) {
 			case struct{}:
@@ -135,17 +181,11 @@ func NamedExecContext(ctx context.Context, log *logger.Logger, db sqlx.ExtContex
 	ctx, span := otel.AddSpan(ctx, "business.sdk.sqldb.exec", attribute.String("query", q))
 	defer span.End()
 
+	ctx, cancel := statementContext(ctx)
+	defer cancel()
+
 	if _, err := sqlx.NamedExecContext(ctx, db, query, data); err != nil {
-		var pqerr *pgconn.PgError
-		if errors.As(err, &pqerr) {
-			switch pqerr.Code {
-			case undefinedTable:
-				return ErrUndefinedTable
-			case uniqueViolation:
-				return ErrDBDuplicatedEntry
-			}
-		}
-		return err
+		return translate(ctx, err)
 	}
 
 	return nil
@@ -173,8 +213,11 @@ func NamedQuerySliceUsingIn[T any](ctx context.Context, log *logger.Logger, db s
 
 func namedQuerySlice[T any](ctx context.Context, log *logger.Logger, db sqlx.ExtContext, query string, data any, dest *[]T, withIn bool) (err error) {
 	q := queryString(query, data)
+	start := time.Now()
 
 	defer func() {
+		diag.AddQuery(ctx, query, time.Since(start), err)
+
 		if err != nil {
 			log.Infoc(ctx, 6, "database.NamedQuerySlice", "query", q, "ERROR", err)
 		}
@@ -183,6 +226,9 @@ func namedQuerySlice[T any](ctx context.Context, log *logger.Logger, db sqlx.Ext
 	ctx, span := otel.AddSpan(ctx, "business.sdk.sqldb.queryslice", attribute.String("query", q))
 	defer span.End()
 
+	ctx, cancel := statementContext(ctx)
+	defer cancel()
+
 	var rows *sqlx.Rows
 
 	switch withIn {
@@ -207,11 +253,7 @@ func namedQuerySlice[T any](ctx context.Context, log *logger.Logger, db sqlx.Ext
 	}
 
 	if err != nil {
-		var pqerr *pgconn.PgError
-		if errors.As(err, &pqerr) && pqerr.Code == undefinedTable {
-			return ErrUndefinedTable
-		}
-		return err
+		return translate(ctx, err)
 	}
 	defer rows.Close()
 
@@ -223,11 +265,59 @@ func namedQuerySlice[T any](ctx context.Context, log *logger.Logger, db sqlx.Ext
 		}
 		slice = append(slice, *v)
 	}
+
+	if err := rows.Err(); err != nil {
+		return translate(ctx, err)
+	}
+
 	*dest = slice
 
 	return nil
 }
 
+// NamedQueryIter is a helper function for executing queries that return a
+// large collection of data where field replacement is necessary. Each row is
+// unmarshalled and handed to the function as it's read so the result set is
+// never held in memory. Iteration stops at the first error the function
+// returns.
+func NamedQueryIter[T any](ctx context.Context, log *logger.Logger, db sqlx.ExtContext, query string, data any, fn func(T) error) (err error) {
+	q := queryString(query, data)
+	start := time.Now()
+
+	defer func() {
+		diag.AddQuery(ctx, query, time.Since(start), err)
+
+		if err != nil {
+			log.Infoc(ctx, 5, "database.NamedQueryIter", "query", q, "ERROR", err)
+		}
+	}()
+
+	ctx, span := otel.AddSpan(ctx, "business.sdk.sqldb.queryiter", attribute.String("query", q))
+	defer span.End()
+
+	ctx, cancel := statementContext(ctx)
+	defer cancel()
+
+	rows, err := sqlx.NamedQueryContext(ctx, db, query, data)
+	if err != nil {
+		return translate(ctx, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var v T
+		if err := rows.StructScan(&v); err != nil {
+			return err
+		}
+
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+
+	return translate(ctx, rows.Err())
+}
+
 // QueryStruct is a helper function for executing queries that return a
 // single value to be unmarshalled into a struct type where field replacement is necessary.
 func QueryStruct(ctx context.Context, log *logger.Logger, db sqlx.ExtContext, query string, dest any) error {
@@ -249,8 +339,11 @@ func NamedQueryStructUsingIn(ctx context.Context, log *logger.Logger, db sqlx.Ex
 
 func namedQueryStruct(ctx context.Context, log *logger.Logger, db sqlx.ExtContext, query string, data any, dest any, withIn bool) (err error) {
 	q := queryString(query, data)
+	start := time.Now()
 
 	defer func() {
+		diag.AddQuery(ctx, query, time.Since(start), err)
+
 		if err != nil {
 			log.Infoc(ctx, 6, "database.NamedQuerySlice", "query", q, "ERROR", err)
 		}
@@ -259,6 +352,9 @@ func namedQueryStruct(ctx context.Context, log *logger.Logger, db sqlx.ExtContex
 	ctx, span := otel.AddSpan(ctx, "business.sdk.sqldb.query", attribute.String("query", q))
 	defer span.End()
 
+	ctx, cancel := statementContext(ctx)
+	defer cancel()
+
 	var rows *sqlx.Rows
 
 	switch withIn {
@@ -283,15 +379,14 @@ func namedQueryStruct(ctx context.Context, log *logger.Logger, db sqlx.ExtContex
 	}
 
 	if err != nil {
-		var pqerr *pgconn.PgError
-		if errors.As(err, &pqerr) && pqerr.Code == undefinedTable {
-			return ErrUndefinedTable
-		}
-		return err
+		return translate(ctx, err)
 	}
 	defer rows.Close()
 
 	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return translate(ctx, err)
+		}
 		return ErrDBNotFound
 	}
 
@@ -302,8 +397,38 @@ func namedQueryStruct(ctx context.Context, log *logger.Logger, db sqlx.ExtContex
 	return nil
 }
 
+// translate maps the database errors the stores handle to the errors of this
+// package. Statements that return rows can violate a constraint too, like
+// INSERT ... RETURNING, so queries are translated the same as executions.
+// A statement that timed out keeps its cause so a cancelled context can
+// still be told apart.
+func translate(ctx context.Context, err error) error {
+	if timedOut(ctx, err) {
+		queryTimeouts.Add(1)
+		return fmt.Errorf("%w: %w", ErrQueryTimeout, err)
+	}
+
+	var pqerr *pgconn.PgError
+	if errors.As(err, &pqerr) {
+		switch pqerr.Code {
+		case undefinedTable:
+			return ErrUndefinedTable
+		case uniqueViolation:
+			return ErrDBDuplicatedEntry
+		case readOnlyTransaction:
+			return ErrDBReadOnly
+		}
+	}
+
+	return err
+}
+
 // queryString provides a pretty print version of the query and parameters.
+// Classified fields above internal are redacted since the result is logged
+// and added to traces.
 func queryString(query string, args any) string {
+	args = redact(args)
+
 	query, params, err := sqlx.Named(query, args)
 	if err != nil {
 		return err.Error()
@@ -327,3 +452,27 @@ func queryString(query string, args any) string {
 
 	return strings.Trim(query, " ")
 }
+
+// redact returns the arguments with the classified fields above internal
+// redacted. The elements of a slice, used for multi-row inserts, are each
+// redacted.
+func redact(args any) any {
+	rv := reflect.ValueOf(args)
+	if rv.Kind() != reflect.Slice {
+		if redacted, ok := classify.Redact(args, "db", classify.Internal); ok {
+			return redacted
+		}
+		return args
+	}
+
+	elems := make([]map[string]any, rv.Len())
+	for i := range elems {
+		redacted, ok := classify.Redact(rv.Index(i).Interface(), "db", classify.Internal)
+		if !ok {
+			return args
+		}
+		elems[i] = redacted
+	}
+
+	return elems
+}
@@ -0,0 +1,228 @@
+package sqldb
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"expvar"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Set of metrics for the prepared statement cache. The hit rate is the hits
+// over the hits and misses.
+var (
+	stmtHits      = expvar.NewInt("db_stmt_cache_hits")
+	stmtMisses    = expvar.NewInt("db_stmt_cache_misses")
+	stmtEvictions = expvar.NewInt("db_stmt_cache_evictions")
+)
+
+// StmtCache wraps a database so the statements run on it are prepared once
+// and reused, keyed by their SQL text, which saves the database from
+// parsing and planning the hot queries on every call. It can be used
+// anywhere the database it wraps is used. Transactions aren't cached.
+//
+// The cache holds up to the configured number of statements and drops the
+// least recently used one when it's full, so queries built with varying
+// text don't grow it without bound. A statement that can't be prepared is
+// run as is.
+type StmtCache struct {
+	db    *sqlx.DB
+	size  int
+	mu    sync.Mutex
+	stmts map[string]*list.Element
+	lru   *list.List
+}
+
+// stmtEntry is a statement held in the cache. A statement dropped from the
+// cache while it's in use is closed by the last call using it.
+type stmtEntry struct {
+	query   string
+	stmt    *sqlx.Stmt
+	refs    int
+	evicted bool
+}
+
+// NewStmtCache constructs a cache of up to size prepared statements for the
+// database. A size of zero or less disables the cache.
+func NewStmtCache(db *sqlx.DB, size int) *StmtCache {
+	return &StmtCache{
+		db:    db,
+		size:  size,
+		stmts: make(map[string]*list.Element),
+		lru:   list.New(),
+	}
+}
+
+// Close closes the statements held in the cache. The database isn't closed.
+func (c *StmtCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var err error
+	for e := c.lru.Front(); e != nil; e = e.Next() {
+		entry := e.Value.(*stmtEntry)
+		entry.evicted = true
+
+		if entry.refs == 0 {
+			if cerr := entry.stmt.Close(); cerr != nil && err == nil {
+				err = cerr
+			}
+		}
+	}
+
+	c.stmts = make(map[string]*list.Element)
+	c.lru.Init()
+
+	return err
+}
+
+// Len returns the number of statements held in the cache.
+func (c *StmtCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lru.Len()
+}
+
+// Begin implements the Beginner interface.
+func (c *StmtCache) Begin() (CommitRollbacker, error) {
+	return c.db.Beginx()
+}
+
+// DriverName implements the sqlx.ExtContext interface.
+func (c *StmtCache) DriverName() string {
+	return c.db.DriverName()
+}
+
+// Rebind implements the sqlx.ExtContext interface.
+func (c *StmtCache) Rebind(query string) string {
+	return c.db.Rebind(query)
+}
+
+// BindNamed implements the sqlx.ExtContext interface.
+func (c *StmtCache) BindNamed(query string, arg any) (string, []any, error) {
+	return c.db.BindNamed(query, arg)
+}
+
+// QueryContext implements the sqlx.ExtContext interface.
+func (c *StmtCache) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	entry := c.acquire(ctx, query)
+	if entry == nil {
+		return c.db.QueryContext(ctx, query, args...)
+	}
+	defer c.release(entry)
+
+	return entry.stmt.QueryContext(ctx, args...)
+}
+
+// QueryxContext implements the sqlx.ExtContext interface.
+func (c *StmtCache) QueryxContext(ctx context.Context, query string, args ...any) (*sqlx.Rows, error) {
+	entry := c.acquire(ctx, query)
+	if entry == nil {
+		return c.db.QueryxContext(ctx, query, args...)
+	}
+	defer c.release(entry)
+
+	return entry.stmt.QueryxContext(ctx, args...)
+}
+
+// QueryRowxContext implements the sqlx.ExtContext interface.
+func (c *StmtCache) QueryRowxContext(ctx context.Context, query string, args ...any) *sqlx.Row {
+	entry := c.acquire(ctx, query)
+	if entry == nil {
+		return c.db.QueryRowxContext(ctx, query, args...)
+	}
+	defer c.release(entry)
+
+	return entry.stmt.QueryRowxContext(ctx, args...)
+}
+
+// ExecContext implements the sqlx.ExtContext interface.
+func (c *StmtCache) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	entry := c.acquire(ctx, query)
+	if entry == nil {
+		return c.db.ExecContext(ctx, query, args...)
+	}
+	defer c.release(entry)
+
+	return entry.stmt.ExecContext(ctx, args...)
+}
+
+// acquire returns the prepared statement for the query, preparing it on a
+// miss. It returns nil when the cache is disabled or the statement can't
+// be prepared. The statement must be released once the call is made.
+func (c *StmtCache) acquire(ctx context.Context, query string) *stmtEntry {
+	if c.size <= 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	if e, exists := c.stmts[query]; exists {
+		c.lru.MoveToFront(e)
+
+		entry := e.Value.(*stmtEntry)
+		entry.refs++
+		c.mu.Unlock()
+
+		stmtHits.Add(1)
+		return entry
+	}
+	c.mu.Unlock()
+
+	stmtMisses.Add(1)
+
+	// The statement is prepared without holding the lock, so calls
+	// preparing the same query at the same time keep the first one.
+	stmt, err := c.db.PreparexContext(ctx, query)
+	if err != nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, exists := c.stmts[query]; exists {
+		stmt.Close()
+
+		entry := e.Value.(*stmtEntry)
+		entry.refs++
+		return entry
+	}
+
+	entry := &stmtEntry{query: query, stmt: stmt, refs: 1}
+	c.stmts[query] = c.lru.PushFront(entry)
+
+	for c.lru.Len() > c.size {
+		c.evict(c.lru.Back())
+	}
+
+	return entry
+}
+
+// release hands back a statement returned by acquire, closing it if it was
+// dropped from the cache while in use. Rows returned by the statement keep
+// it open until they are closed.
+func (c *StmtCache) release(entry *stmtEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry.refs--
+	if entry.evicted && entry.refs == 0 {
+		entry.stmt.Close()
+	}
+}
+
+// evict drops the statement from the cache. The caller holds the lock.
+func (c *StmtCache) evict(e *list.Element) {
+	entry := c.lru.Remove(e).(*stmtEntry)
+	delete(c.stmts, entry.query)
+
+	entry.evicted = true
+	if entry.refs == 0 {
+		entry.stmt.Close()
+	}
+
+	stmtEvictions.Add(1)
+}
@@ -0,0 +1,91 @@
+package sqldb_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/ardanlabs/service/business/sdk/dbtest"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+)
+
+func Test_StmtCache(t *testing.T) {
+	t.Parallel()
+
+	db := dbtest.New(t, "Test_StmtCache")
+
+	ctx := context.Background()
+
+	stmts := sqldb.NewStmtCache(db.DB, 2)
+	defer stmts.Close()
+
+	type row struct {
+		N int `db:"n"`
+	}
+
+	query := func(stmts *sqldb.StmtCache, q string, n int) error {
+		var r row
+		if err := sqldb.NamedQueryStruct(ctx, db.Log, stmts, q, map[string]any{"n": n}, &r); err != nil {
+			return err
+		}
+
+		if r.N != n {
+			return fmt.Errorf("got %d, exp %d", r.N, n)
+		}
+
+		return nil
+	}
+
+	const q1 = `SELECT CAST(:n AS INT) AS n`
+	const q2 = `SELECT CAST(:n AS INT) + 0 AS n`
+	const q3 = `SELECT CAST(:n AS INT) * 1 AS n`
+
+	for i := range 2 {
+		if err := query(stmts, q1, i); err != nil {
+			t.Fatalf("Should be able to query: %s", err)
+		}
+	}
+
+	if n := stmts.Len(); n != 1 {
+		t.Fatalf("Should prepare a statement once: got %d", n)
+	}
+
+	for i, q := range []string{q2, q3} {
+		if err := query(stmts, q, i); err != nil {
+			t.Fatalf("Should be able to query: %s", err)
+		}
+	}
+
+	if n := stmts.Len(); n != 2 {
+		t.Fatalf("Should drop statements over the size: got %d", n)
+	}
+
+	// Calls using the statements while they are being dropped must still
+	// succeed.
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := query(stmts, []string{q1, q2, q3}[i%3], i); err != nil {
+				t.Errorf("Should be able to query while statements are dropped: %s", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := sqldb.ExecContext(ctx, db.Log, stmts, `CREATE TABLE stmt_cache (id INT)`); err != nil {
+		t.Fatalf("Should be able to exec: %s", err)
+	}
+
+	disabled := sqldb.NewStmtCache(db.DB, 0)
+
+	if err := query(disabled, q1, 5); err != nil {
+		t.Fatalf("Should be able to query without a cache: %s", err)
+	}
+
+	if n := disabled.Len(); n != 0 {
+		t.Fatalf("Should not cache with a size of zero: got %d", n)
+	}
+}
@@ -0,0 +1,82 @@
+package sqldb
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ardanlabs/service/foundation/ctxval"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrQueryTimeout is returned when a statement is cancelled for running
+// longer than its timeout, whether the database or the context ran out.
+var ErrQueryTimeout = errors.New("query timeout")
+
+// queryTimeouts counts the statements cancelled for running too long.
+var queryTimeouts = expvar.NewInt("db_query_timeouts")
+
+// queryCanceled is the postgres error code for a statement the database
+// cancelled, for going over statement_timeout or on a cancel request.
+const queryCanceled = "57014"
+
+var timeoutKey = ctxval.NewKey[time.Duration]("statement timeout")
+
+// WithStatementTimeout returns a context whose statements, run through the
+// helpers of this package, are cancelled once they run longer than the
+// timeout. A cancelled statement is killed on the database as well, see
+// Open. Transactions begun by the app layer with the context use it as
+// their statement_timeout.
+func WithStatementTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return timeoutKey.Set(ctx, timeout)
+}
+
+// StatementTimeout returns the statement timeout carried by the context.
+func StatementTimeout(ctx context.Context) (time.Duration, bool) {
+	timeout, ok := timeoutKey.Get(ctx)
+	return timeout, ok && timeout > 0
+}
+
+// SetStatementTimeout sets the statement_timeout of the transaction so the
+// database cancels any statement in it that runs longer than the timeout.
+// The setting is local to the transaction and goes away when it ends.
+func SetStatementTimeout(ctx context.Context, tx CommitRollbacker, timeout time.Duration) error {
+	ec, err := GetExtContext(tx)
+	if err != nil {
+		return err
+	}
+
+	const q = `SELECT set_config('statement_timeout', $1, true)`
+
+	if _, err := ec.ExecContext(ctx, q, strconv.FormatInt(timeout.Milliseconds(), 10)); err != nil {
+		return fmt.Errorf("set statement timeout: %w", err)
+	}
+
+	return nil
+}
+
+// statementContext returns the context to run a statement with, bounded by
+// the statement timeout the context carries.
+func statementContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout, ok := StatementTimeout(ctx)
+	if !ok {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
+
+// timedOut reports whether the statement run with the context failed for
+// running too long. The database reports a statement the caller cancelled
+// the same as one that timed out, so the context tells them apart.
+func timedOut(ctx context.Context, err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == queryCanceled && !errors.Is(ctx.Err(), context.Canceled)
+	}
+
+	return errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,95 @@
+package sqldb_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/service/business/sdk/dbtest"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+)
+
+func Test_StatementTimeout(t *testing.T) {
+	t.Parallel()
+
+	db := dbtest.New(t, "Test_StatementTimeout")
+
+	const sleep = `SELECT pg_sleep(5) AS slept`
+
+	var dest struct {
+		Slept string `db:"slept"`
+	}
+
+	// -------------------------------------------------------------------------
+	// A call that runs past the timeout in its context.
+
+	ctx := sqldb.WithStatementTimeout(context.Background(), 100*time.Millisecond)
+
+	start := time.Now()
+	err := sqldb.QueryStruct(ctx, db.Log, db.DB, sleep, &dest)
+	if !errors.Is(err, sqldb.ErrQueryTimeout) {
+		t.Fatalf("Should time out the call: %v", err)
+	}
+
+	if d := time.Since(start); d > 3*time.Second {
+		t.Fatalf("Should stop waiting at the timeout: %s", d)
+	}
+
+	// The cancel request kills the statement on the database as well.
+	var running struct {
+		N int `db:"n"`
+	}
+
+	const q = `SELECT count(1) AS n FROM pg_stat_activity WHERE state = 'active' AND query = '` + sleep + `'`
+
+	for range 20 {
+		if err := sqldb.QueryStruct(context.Background(), db.Log, db.DB, q, &running); err != nil {
+			t.Fatalf("Should be able to query the activity: %s", err)
+		}
+
+		if running.N == 0 {
+			break
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if running.N != 0 {
+		t.Fatalf("Should kill the statement on the database: %d running", running.N)
+	}
+
+	// -------------------------------------------------------------------------
+	// A transaction with a statement timeout set.
+
+	tx, err := sqldb.NewBeginner(db.DB).Begin()
+	if err != nil {
+		t.Fatalf("Should be able to begin a transaction: %s", err)
+	}
+	defer tx.Rollback()
+
+	if err := sqldb.SetStatementTimeout(context.Background(), tx, 100*time.Millisecond); err != nil {
+		t.Fatalf("Should be able to set the timeout: %s", err)
+	}
+
+	ec, err := sqldb.GetExtContext(tx)
+	if err != nil {
+		t.Fatalf("Should be able to get the transaction: %s", err)
+	}
+
+	err = sqldb.QueryStruct(context.Background(), db.Log, ec, sleep, &dest)
+	if !errors.Is(err, sqldb.ErrQueryTimeout) {
+		t.Fatalf("Should time out the statement in the transaction: %v", err)
+	}
+
+	// -------------------------------------------------------------------------
+	// A call whose context is cancelled isn't a timeout.
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	err = sqldb.QueryStruct(ctx, db.Log, db.DB, sleep, &dest)
+	if err == nil || errors.Is(err, sqldb.ErrQueryTimeout) {
+		t.Fatalf("Should fail the cancelled call without a timeout: %v", err)
+	}
+}
@@ -1,11 +1,16 @@
 package sqldb
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/jmoiron/sqlx"
 )
 
+// ErrTxNotSupported is returned by stores that can't take part in a SQL
+// transaction when they are asked to join one.
+var ErrTxNotSupported = errors.New("store does not support sql transactions")
+
 // Beginner represents a value that can begin a transaction.
 type Beginner interface {
 	Begin() (CommitRollbacker, error)
@@ -0,0 +1,52 @@
+// Package tenant carries the tenant a call is made for through the context.
+// The auth layer sets the tenant from the claims of the caller and the
+// stores scope their queries to it, so a caller can only see and change the
+// data of its own tenant. Calls without a tenant, like background jobs and
+// the admin tooling, aren't scoped.
+package tenant
+
+import (
+	"context"
+
+	"github.com/ardanlabs/service/foundation/ctxval"
+	"github.com/google/uuid"
+)
+
+// Default is the tenant of a service that isn't shared between tenants. The
+// data created before tenants were added belongs to it.
+var Default = uuid.Nil
+
+var tenantKey = ctxval.NewKey[uuid.UUID]("tenant")
+
+// With returns a copy of the context for calls made for the tenant.
+func With(ctx context.Context, tenantID uuid.UUID) context.Context {
+	return tenantKey.Set(ctx, tenantID)
+}
+
+// Get returns the tenant the call is made for, false is returned when the
+// call isn't made for a tenant.
+func Get(ctx context.Context) (uuid.UUID, bool) {
+	return tenantKey.Get(ctx)
+}
+
+// Scope returns the tenant queries are scoped to as a value for a query
+// parameter, nil when the call isn't made for a tenant.
+func Scope(ctx context.Context) *uuid.UUID {
+	tenantID, ok := Get(ctx)
+	if !ok {
+		return nil
+	}
+
+	return &tenantID
+}
+
+// OrDefault returns the tenant the call is made for, or the default tenant
+// when it isn't made for one.
+func OrDefault(ctx context.Context) uuid.UUID {
+	tenantID, ok := Get(ctx)
+	if !ok {
+		return Default
+	}
+
+	return tenantID
+}
@@ -0,0 +1,40 @@
+package tenant_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ardanlabs/service/business/sdk/tenant"
+	"github.com/google/uuid"
+)
+
+func Test_Tenant(t *testing.T) {
+	ctx := context.Background()
+
+	if _, ok := tenant.Get(ctx); ok {
+		t.Fatalf("Should not have a tenant without one being set")
+	}
+
+	if scope := tenant.Scope(ctx); scope != nil {
+		t.Fatalf("Should not scope a call without a tenant, got %s", scope)
+	}
+
+	if got := tenant.OrDefault(ctx); got != tenant.Default {
+		t.Fatalf("Should get the default tenant, got %s", got)
+	}
+
+	tenantID := uuid.New()
+	ctx = tenant.With(ctx, tenantID)
+
+	if got, ok := tenant.Get(ctx); !ok || got != tenantID {
+		t.Fatalf("Should get the tenant that was set, got %s %t", got, ok)
+	}
+
+	if scope := tenant.Scope(ctx); scope == nil || *scope != tenantID {
+		t.Fatalf("Should scope the call to the tenant, got %v", scope)
+	}
+
+	if got := tenant.OrDefault(ctx); got != tenantID {
+		t.Fatalf("Should get the tenant that was set, got %s", got)
+	}
+}
@@ -3,18 +3,28 @@ package unitest
 import (
 	"context"
 
+	"github.com/ardanlabs/service/business/domain/apikeybus"
 	"github.com/ardanlabs/service/business/domain/auditbus"
+	"github.com/ardanlabs/service/business/domain/groupbus"
 	"github.com/ardanlabs/service/business/domain/homebus"
+	"github.com/ardanlabs/service/business/domain/invitebus"
 	"github.com/ardanlabs/service/business/domain/productbus"
+	"github.com/ardanlabs/service/business/domain/reportbus"
 	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/domain/webhookbus"
 )
 
 // User represents an app user specified for the test.
 type User struct {
 	userbus.User
-	Products []productbus.Product
-	Homes    []homebus.Home
-	Audits   []auditbus.Audit
+	Products      []productbus.Product
+	Homes         []homebus.Home
+	Audits        []auditbus.Audit
+	Subscriptions []reportbus.Subscription
+	APIKeys       []apikeybus.Key
+	Groups        []groupbus.Group
+	Invites       []invitebus.Invite
+	Webhooks      []webhookbus.Webhook
 }
 
 // SeedData represents data that was seeded for the test.
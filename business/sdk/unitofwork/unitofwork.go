@@ -0,0 +1,111 @@
+// Package unitofwork provides support for running calls to several
+// business values in one transaction.
+//
+// Instead of beginning a transaction, calling NewWithTx on each business
+// and remembering to commit or roll back on every path, the businesses are
+// bound to a unit of work and the calls are made in a function:
+//
+//	userBus, productBus := a.userBus, a.productBus
+//
+//	err := unitofwork.Run(ctx, a.bgn, subjectID, func(ctx context.Context) error {
+//		usr, _, err := userBus.Create(ctx, subjectID, nu)
+//		...
+//		_, err = productBus.Create(ctx, np)
+//		return err
+//	}, unitofwork.Bind(&userBus), unitofwork.Bind(&productBus))
+package unitofwork
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/google/uuid"
+)
+
+// TxBinder represents a value that can construct a copy of itself that uses
+// a transaction, which every business does.
+type TxBinder[T any] interface {
+	NewWithTx(tx sqldb.CommitRollbacker) (T, error)
+}
+
+// Binding represents a business bound to the transaction of a unit of work.
+type Binding interface {
+	bind(tx sqldb.CommitRollbacker) (restore func(), err error)
+}
+
+type binding[T TxBinder[T]] struct {
+	bus *T
+}
+
+func (b binding[T]) bind(tx sqldb.CommitRollbacker) (func(), error) {
+	orig := *b.bus
+
+	txBus, err := orig.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	*b.bus = txBus
+
+	return func() { *b.bus = orig }, nil
+}
+
+// Bind binds the business the pointer refers to. While the function of the
+// unit of work runs, the pointer refers to a business that uses the
+// transaction, and afterwards to the business it referred to before.
+func Bind[T TxBinder[T]](bus *T) Binding {
+	return binding[T]{bus: bus}
+}
+
+// Run begins a transaction, binds the businesses to it and runs the
+// function. The transaction is committed when the function returns nil and
+// rolled back otherwise, with the function's error returned as is. The
+// tenant in the context and the user are set on the transaction for the
+// row-level security policies, along with the statement timeout the
+// context carries. The user can be uuid.Nil when it isn't known.
+//
+// To run the unit of work as an inner section of a transaction that is
+// already running, pass sqldb.NewSavepointBeginner with the transaction.
+// The section is rolled back on its own when the function fails, and the
+// settings of the transaction are left as they are.
+func Run(ctx context.Context, bgn sqldb.Beginner, userID uuid.UUID, fn func(ctx context.Context) error, bindings ...Binding) error {
+	tx, err := bgn.Begin()
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+
+	// Rolling back after a commit does nothing, so the deferred call covers
+	// every path that doesn't commit, including a panic.
+	defer tx.Rollback()
+
+	if _, nested := tx.(*sqldb.Savepoint); !nested {
+		if err := sqldb.SetCurrent(ctx, tx, userID); err != nil {
+			return fmt.Errorf("setcurrent: %w", err)
+		}
+
+		if timeout, ok := sqldb.StatementTimeout(ctx); ok {
+			if err := sqldb.SetStatementTimeout(ctx, tx, timeout); err != nil {
+				return fmt.Errorf("setstatementtimeout: %w", err)
+			}
+		}
+	}
+
+	for _, b := range bindings {
+		restore, err := b.bind(tx)
+		if err != nil {
+			return fmt.Errorf("newwithtx: %w", err)
+		}
+		defer restore()
+	}
+
+	if err := fn(ctx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package unitofwork_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ardanlabs/service/business/domain/productbus"
+	"github.com/ardanlabs/service/business/domain/userbus"
+	"github.com/ardanlabs/service/business/sdk/dbtest"
+	"github.com/ardanlabs/service/business/sdk/sqldb"
+	"github.com/ardanlabs/service/business/sdk/unitofwork"
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/google/uuid"
+)
+
+func Test_Run(t *testing.T) {
+	t.Parallel()
+
+	db := dbtest.New(t, "Test_Run")
+
+	ctx := context.Background()
+	bgn := sqldb.NewBeginner(db.DB)
+
+	userBus := db.BusDomain.User
+	productBus := db.BusDomain.Product
+
+	// -------------------------------------------------------------------------
+	// A unit of work that succeeds is committed.
+
+	var usr userbus.User
+
+	err := unitofwork.Run(ctx, bgn, uuid.Nil, func(ctx context.Context) error {
+		var err error
+		if usr, _, err = userBus.Create(ctx, userbus.ActorSystem, userbus.TestNewUsers(1, role.User)[0]); err != nil {
+			return err
+		}
+
+		np := productbus.TestGenerateNewProducts(1, usr.ID)[0]
+		if _, err := productBus.Create(ctx, np); err != nil {
+			return err
+		}
+
+		return nil
+	}, unitofwork.Bind(&userBus), unitofwork.Bind(&productBus))
+
+	if err != nil {
+		t.Fatalf("Should be able to run the unit of work: %s", err)
+	}
+
+	if userBus != db.BusDomain.User || productBus != db.BusDomain.Product {
+		t.Fatalf("Should restore the businesses once the unit of work is done")
+	}
+
+	if _, err := userBus.QueryByID(ctx, usr.ID); err != nil {
+		t.Fatalf("Should commit the user: %s", err)
+	}
+
+	// -------------------------------------------------------------------------
+	// A unit of work that fails is rolled back.
+
+	errStop := errors.New("stop")
+
+	err = unitofwork.Run(ctx, bgn, uuid.Nil, func(ctx context.Context) error {
+		var err error
+		if usr, _, err = userBus.Create(ctx, userbus.ActorSystem, userbus.TestNewUsers(1, role.User)[0]); err != nil {
+			return err
+		}
+
+		return errStop
+	}, unitofwork.Bind(&userBus))
+
+	if !errors.Is(err, errStop) {
+		t.Fatalf("Should return the error of the function: %v", err)
+	}
+
+	if _, err := userBus.QueryByID(ctx, usr.ID); !errors.Is(err, userbus.ErrNotFound) {
+		t.Fatalf("Should roll back the user: %v", err)
+	}
+}
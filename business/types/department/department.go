@@ -0,0 +1,173 @@
+// Package department represents the department of a user in the system.
+//
+// Departments are normalized the same way as names. A department may hold
+// letters and digits from any script, emoji, spaces and the punctuation
+// department names commonly use, like the ampersand in "R&D", and its
+// length is counted in grapheme clusters. The rules allow every value the
+// name rules allow, so departments stored as names are still valid.
+package department
+
+import (
+	"fmt"
+	"unicode"
+
+	"github.com/ardanlabs/service/business/types/name"
+	"github.com/rivo/uniseg"
+)
+
+// The length limits in grapheme clusters.
+const (
+	minLength = 2
+	maxLength = 64
+)
+
+// Department represents a department in the system.
+type Department struct {
+	value string
+}
+
+// String returns the value of the department.
+func (d Department) String() string {
+	return d.value
+}
+
+// Equal provides support for the go-cmp package and testing.
+func (d Department) Equal(d2 Department) bool {
+	return d.value == d2.value
+}
+
+// MarshalText provides support for logging and any marshal needs.
+func (d Department) MarshalText() ([]byte, error) {
+	return []byte(d.value), nil
+}
+
+// =============================================================================
+
+// Parse parses the string value and returns a department if the normalized
+// value complies with the rules for a department.
+func Parse(value string) (Department, error) {
+	value = Normalize(value)
+	if err := check(value); err != nil {
+		return Department{}, err
+	}
+
+	return Department{value}, nil
+}
+
+// MustParse parses the string value and returns a department if the value
+// complies with the rules for a department. If an error occurs the function
+// panics.
+func MustParse(value string) Department {
+	dept, err := Parse(value)
+	if err != nil {
+		panic(err)
+	}
+
+	return dept
+}
+
+// =============================================================================
+
+// Null represents a department in the system that can be empty.
+type Null struct {
+	value string
+	valid bool
+}
+
+// String returns the value of the department.
+func (n Null) String() string {
+	if !n.valid {
+		return "NULL"
+	}
+
+	return n.value
+}
+
+// Valid tests if the value is null.
+func (n Null) Valid() bool {
+	return n.valid
+}
+
+// Equal provides support for the go-cmp package and testing.
+func (n Null) Equal(n2 Null) bool {
+	return n.value == n2.value && n.valid == n2.valid
+}
+
+// MarshalText provides support for logging and any marshal needs.
+func (n Null) MarshalText() ([]byte, error) {
+	return []byte(n.value), nil
+}
+
+// =============================================================================
+
+// ParseNull parses the string value and returns a department if the value
+// complies with the rules for a department. An empty value is null.
+func ParseNull(value string) (Null, error) {
+	value = Normalize(value)
+	if value == "" {
+		return Null{}, nil
+	}
+
+	if err := check(value); err != nil {
+		return Null{}, err
+	}
+
+	return Null{value, true}, nil
+}
+
+// MustParseNull parses the string value and returns a department if the
+// value complies with the rules for a department. If an error occurs the
+// function panics.
+func MustParseNull(value string) Null {
+	dept, err := ParseNull(value)
+	if err != nil {
+		panic(err)
+	}
+
+	return dept
+}
+
+// =============================================================================
+
+// Normalize returns the value in the form it's stored in. It doesn't check
+// that the value is a valid department.
+func Normalize(value string) string {
+	return name.Normalize(value)
+}
+
+// check reports whether the normalized value is a valid department.
+func check(value string) error {
+	for _, r := range value {
+		if !allowed(r) {
+			return fmt.Errorf("invalid department %q: character %U is not allowed", value, r)
+		}
+	}
+
+	switch n := uniseg.GraphemeClusterCount(value); {
+	case n < minLength:
+		return fmt.Errorf("invalid department %q: must be at least %d characters", value, minLength)
+	case n > maxLength:
+		return fmt.Errorf("invalid department %q: must be at most %d characters", value, maxLength)
+	}
+
+	return nil
+}
+
+// allowed reports whether the character can be part of a department.
+func allowed(r rune) bool {
+	switch r {
+	case ' ', '\'', '-', '&', '/', '.', ',', '(', ')', '\u200D':
+		return true
+	}
+
+	// Skin tone modifiers are the only part of an emoji that isn't a
+	// symbol or a mark.
+	if r >= '\U0001F3FB' && r <= '\U0001F3FF' {
+		return true
+	}
+
+	return unicode.IsLetter(r) ||
+		unicode.IsMark(r) ||
+		unicode.Is(unicode.Nd, r) ||
+		unicode.Is(unicode.So, r)
+}
@@ -0,0 +1,72 @@
+package department_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ardanlabs/service/business/types/department"
+)
+
+func Test_Parse(t *testing.T) {
+	tests := []struct {
+		value string
+		exp   string
+	}{
+		{"IT", "IT"},
+		{"R&D", "R&D"},
+		{"  Sales  / Marketing ", "Sales / Marketing"},
+		{"Ops (EMEA)", "Ops (EMEA)"},
+		{"Dept. of Finance, Tax", "Dept. of Finance, Tax"},
+		{"Müller–Team", "Müller-Team"},
+		{"Team \U0001F680", "Team \U0001F680"},
+	}
+
+	for _, tt := range tests {
+		got, err := department.Parse(tt.value)
+		if err != nil {
+			t.Errorf("Should be able to parse %q : %s", tt.value, err)
+			continue
+		}
+
+		if got.String() != tt.exp {
+			t.Errorf("%q: Exp: %q", tt.value, tt.exp)
+			t.Errorf("%q: Got: %q", tt.value, got.String())
+		}
+	}
+}
+
+func Test_ParseInvalid(t *testing.T) {
+	values := []string{
+		"",
+		"I",
+		"Ops <script>",
+		"Ops^Dev",
+		strings.Repeat("a", 65),
+	}
+
+	for _, value := range values {
+		if _, err := department.Parse(value); err == nil {
+			t.Errorf("Should not be able to parse %q", value)
+		}
+	}
+}
+
+func Test_ParseNull(t *testing.T) {
+	dept, err := department.ParseNull(" \u200B ")
+	if err != nil {
+		t.Fatalf("Should be able to parse an empty value : %s", err)
+	}
+
+	if dept.Valid() {
+		t.Fatalf("Should treat an empty value as null")
+	}
+
+	dept, err = department.ParseNull("HR")
+	if err != nil || !dept.Valid() || dept.String() != "HR" {
+		t.Fatalf("Should be able to parse a department : %v %s", dept, err)
+	}
+
+	if _, err := department.ParseNull("I"); err == nil {
+		t.Fatalf("Should check a value that isn't empty")
+	}
+}
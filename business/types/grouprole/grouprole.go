@@ -0,0 +1,64 @@
+// Package grouprole represents the role a user has within a group.
+package grouprole
+
+import "fmt"
+
+// The set of roles that can be used.
+var (
+	Owner  = newRole("OWNER")
+	Member = newRole("MEMBER")
+)
+
+// =============================================================================
+
+// Set of known group roles.
+var roles = make(map[string]Role)
+
+// Role represents a role within a group.
+type Role struct {
+	value string
+}
+
+func newRole(role string) Role {
+	r := Role{role}
+	roles[role] = r
+	return r
+}
+
+// String returns the name of the role.
+func (r Role) String() string {
+	return r.value
+}
+
+// Equal provides support for the go-cmp package and testing.
+func (r Role) Equal(r2 Role) bool {
+	return r.value == r2.value
+}
+
+// MarshalText provides support for logging and any marshal needs.
+func (r Role) MarshalText() ([]byte, error) {
+	return []byte(r.value), nil
+}
+
+// =============================================================================
+
+// Parse parses the string value and returns a group role if one exists.
+func Parse(value string) (Role, error) {
+	r, exists := roles[value]
+	if !exists {
+		return Role{}, fmt.Errorf("invalid group role %q", value)
+	}
+
+	return r, nil
+}
+
+// MustParse parses the string value and returns a group role if one exists.
+// If an error occurs the function panics.
+func MustParse(value string) Role {
+	r, err := Parse(value)
+	if err != nil {
+		panic(err)
+	}
+
+	return r
+}
@@ -1,9 +1,21 @@
 // Package name represents a name in the system.
+//
+// Names are normalized before they are checked: invisible characters are
+// removed, characters that look like a space, an apostrophe or a hyphen are
+// replaced by the ASCII one, runs of spaces are collapsed and the result is
+// put in Unicode NFC form. A name may hold letters and digits from any
+// script, emoji, spaces, apostrophes and hyphens, and its length is counted
+// in grapheme clusters, the characters a reader sees.
 package name
 
 import (
 	"fmt"
-	"regexp"
+	"strings"
+	"sync/atomic"
+	"unicode"
+
+	"github.com/rivo/uniseg"
+	"golang.org/x/text/unicode/norm"
 )
 
 // Name represents a name in the system.
@@ -28,13 +40,12 @@ func (n Name) MarshalText() ([]byte, error) {
 
 // =============================================================================
 
-var nameRegEx = regexp.MustCompile("^[a-zA-Z0-9' -]{3,20}$")
-
-// Parse parses the string value and returns a name if the value complies
-// with the rules for a name.
+// Parse parses the string value and returns a name if the normalized value
+// complies with the rules for a name.
 func Parse(value string) (Name, error) {
-	if !nameRegEx.MatchString(value) {
-		return Name{}, fmt.Errorf("invalid name %q", value)
+	value = Normalize(value)
+	if err := check(value); err != nil {
+		return Name{}, err
 	}
 
 	return Name{value}, nil
@@ -88,12 +99,13 @@ func (n Null) MarshalText() ([]byte, error) {
 // ParseNull parses the string value and returns a name if the value complies
 // with the rules for a name.
 func ParseNull(value string) (Null, error) {
+	value = Normalize(value)
 	if value == "" {
 		return Null{}, nil
 	}
 
-	if !nameRegEx.MatchString(value) {
-		return Null{}, fmt.Errorf("invalid name %q", value)
+	if err := check(value); err != nil {
+		return Null{}, err
 	}
 
 	return Null{value, true}, nil
@@ -109,3 +121,162 @@ func MustParseNull(value string) Null {
 
 	return name
 }
+
+// =============================================================================
+
+// The length limits in grapheme clusters.
+var (
+	minLength atomic.Int64
+	maxLength atomic.Int64
+)
+
+func init() {
+	minLength.Store(3)
+	maxLength.Store(20)
+}
+
+// SetLimits sets how many grapheme clusters a name can have. The default
+// is 3 to 20. Names already stored are parsed with the same limits when they
+// are read, so the limits shouldn't be narrowed past existing names.
+func SetLimits(min int, max int) error {
+	if min < 1 || max < min {
+		return fmt.Errorf("invalid limits min[%d] max[%d]", min, max)
+	}
+
+	minLength.Store(int64(min))
+	maxLength.Store(int64(max))
+
+	return nil
+}
+
+// Limits returns how many grapheme clusters a name can have.
+func Limits() (int, int) {
+	return int(minLength.Load()), int(maxLength.Load())
+}
+
+// Normalize returns the value in the form it's stored in. It doesn't check
+// that the value is a valid name.
+func Normalize(value string) string {
+	runes := []rune(value)
+
+	var b strings.Builder
+	b.Grow(len(value))
+
+	space := true
+	var prev rune
+
+	for i, r := range runes {
+		switch {
+		case r == zeroWidthJoiner:
+			// The joiner builds emoji like families and flags out of other
+			// emoji, anywhere else it's invisible.
+			if i+1 < len(runes) && emoji(prev) && emoji(runes[i+1]) {
+				b.WriteRune(r)
+				prev = r
+			}
+			continue
+
+		case invisible(r):
+			continue
+
+		case unicode.IsSpace(r):
+			if !space {
+				b.WriteRune(' ')
+				space = true
+				prev = ' '
+			}
+			continue
+		}
+
+		if c, ok := confusables[r]; ok {
+			r = c
+		}
+
+		b.WriteRune(r)
+		space = false
+		prev = r
+	}
+
+	return norm.NFC.String(strings.TrimRight(b.String(), " "))
+}
+
+// check reports whether the normalized value is a valid name.
+func check(value string) error {
+	for _, r := range value {
+		if !allowed(r) {
+			return fmt.Errorf("invalid name %q: character %U is not allowed", value, r)
+		}
+	}
+
+	min, max := Limits()
+
+	switch n := uniseg.GraphemeClusterCount(value); {
+	case n < min:
+		return fmt.Errorf("invalid name %q: must be at least %d characters", value, min)
+	case n > max:
+		return fmt.Errorf("invalid name %q: must be at most %d characters", value, max)
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+const (
+	zeroWidthJoiner   = '\u200D'
+	variationSelector = '\uFE0F'
+)
+
+// confusables maps the characters that are easily mistaken for the
+// punctuation a name allows to that punctuation.
+var confusables = map[rune]rune{
+	'\u2018': '\'', // left single quotation mark
+	'\u2019': '\'', // right single quotation mark
+	'\u02BC': '\'', // modifier letter apostrophe
+	'\u2032': '\'', // prime
+	'\uFF07': '\'', // fullwidth apostrophe
+	'\u2010': '-',  // hyphen
+	'\u2011': '-',  // non-breaking hyphen
+	'\u2012': '-',  // figure dash
+	'\u2013': '-',  // en dash
+	'\u2212': '-',  // minus sign
+	'\uFE63': '-',  // small hyphen-minus
+	'\uFF0D': '-',  // fullwidth hyphen-minus
+}
+
+// invisible reports whether the character can't be seen, like zero-width
+// spaces, direction overrides and the fillers some fonts draw as blank.
+func invisible(r rune) bool {
+	switch r {
+	case '\u034F', '\u115F', '\u1160', '\u3164', '\uFFA0':
+		return true
+	}
+
+	return unicode.Is(unicode.Cf, r) || unicode.Is(unicode.Cc, r)
+}
+
+// emoji reports whether the character is part of an emoji, skin tone
+// modifiers included.
+func emoji(r rune) bool {
+	switch {
+	case r == variationSelector:
+		return true
+	case r >= '\U0001F3FB' && r <= '\U0001F3FF':
+		return true
+	}
+
+	return unicode.Is(unicode.So, r)
+}
+
+// allowed reports whether the character can be part of a name.
+func allowed(r rune) bool {
+	switch r {
+	case ' ', '\'', '-', zeroWidthJoiner:
+		return true
+	}
+
+	return unicode.IsLetter(r) ||
+		unicode.IsMark(r) ||
+		unicode.Is(unicode.Nd, r) ||
+		emoji(r)
+}
@@ -0,0 +1,78 @@
+package name_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ardanlabs/service/business/types/name"
+)
+
+func Test_Parse(t *testing.T) {
+	family := "\U0001F469\u200D\U0001F469\u200D\U0001F467"
+
+	tests := []struct {
+		value string
+		exp   string
+	}{
+		{"Bill Kennedy", "Bill Kennedy"},
+		{"  Bill\u00A0\t Kennedy ", "Bill Kennedy"},
+		{"Bi\u200Bll\u202E", "Bill"},
+		{"O\u2019Brien\u2013Smith", "O'Brien-Smith"},
+		{"Cafe\u0301", "Caf\u00E9"},
+		{"张伟强", "张伟强"},
+		{"Ann " + family, "Ann " + family},
+		{"Ann\u200D Lee", "Ann Lee"},
+		{strings.Repeat(family, 20), strings.Repeat(family, 20)},
+	}
+
+	for _, tt := range tests {
+		got, err := name.Parse(tt.value)
+		if err != nil {
+			t.Errorf("Should be able to parse %q : %s", tt.value, err)
+			continue
+		}
+
+		if got.String() != tt.exp {
+			t.Errorf("%q: Exp: %q", tt.value, tt.exp)
+			t.Errorf("%q: Got: %q", tt.value, got.String())
+		}
+	}
+}
+
+func Test_ParseInvalid(t *testing.T) {
+	values := []string{
+		"",
+		"Al",
+		"A\u200B\u200Bl",
+		"Bill <script>",
+		"Bill^Kennedy",
+		strings.Repeat("a", 21),
+		strings.Repeat("\U0001F469\u200D\U0001F469\u200D\U0001F467", 21),
+	}
+
+	for _, value := range values {
+		if _, err := name.Parse(value); err == nil {
+			t.Errorf("Should not be able to parse %q", value)
+		}
+	}
+}
+
+func Test_SetLimits(t *testing.T) {
+	defer name.SetLimits(name.Limits())
+
+	if err := name.SetLimits(5, 2); err == nil {
+		t.Fatal("Should not be able to set a max below the min")
+	}
+
+	if err := name.SetLimits(1, 4); err != nil {
+		t.Fatalf("Should be able to set the limits : %s", err)
+	}
+
+	if _, err := name.Parse("B"); err != nil {
+		t.Errorf("Should be able to parse a name within the limits : %s", err)
+	}
+
+	if _, err := name.Parse("Bill K"); err == nil {
+		t.Error("Should not be able to parse a name over the limit")
+	}
+}
@@ -1,7 +1,11 @@
 // Package role represents the role type in the system.
 package role
 
-import "fmt"
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
 
 // The set of roles that can be used.
 var (
@@ -40,8 +44,35 @@ func (r Role) MarshalText() ([]byte, error) {
 	return []byte(r.value), nil
 }
 
+// UnmarshalText provides support for any unmarshal needs. Only a known
+// role can be unmarshaled.
+func (r *Role) UnmarshalText(data []byte) error {
+	role, err := Parse(string(data))
+	if err != nil {
+		return err
+	}
+
+	*r = role
+
+	return nil
+}
+
 // =============================================================================
 
+// All returns the set of known roles ordered by name.
+func All() []Role {
+	all := make([]Role, 0, len(roles))
+	for _, role := range roles {
+		all = append(all, role)
+	}
+
+	slices.SortFunc(all, func(a Role, b Role) int {
+		return strings.Compare(a.value, b.value)
+	})
+
+	return all
+}
+
 // Parse parses the string value and returns a role if one exists.
 func Parse(value string) (Role, error) {
 	role, exists := roles[value]
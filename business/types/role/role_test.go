@@ -0,0 +1,56 @@
+package role_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ardanlabs/service/business/types/role"
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_Parse(t *testing.T) {
+	r, err := role.Parse("ADMIN")
+	if err != nil {
+		t.Fatalf("Should be able to parse a known role : %s", err)
+	}
+
+	if r != role.Admin {
+		t.Fatalf("Should get the canonical role : %s", r)
+	}
+
+	for _, value := range []string{"", "admin", "SUPER"} {
+		if _, err := role.Parse(value); err == nil {
+			t.Errorf("Should not be able to parse %q", value)
+		}
+	}
+}
+
+func Test_JSON(t *testing.T) {
+	data, err := json.Marshal([]role.Role{role.Admin, role.User})
+	if err != nil {
+		t.Fatalf("Should be able to marshal roles : %s", err)
+	}
+
+	if string(data) != `["ADMIN","USER"]` {
+		t.Fatalf("Should marshal roles as their names : %s", data)
+	}
+
+	var got []role.Role
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Should be able to unmarshal roles : %s", err)
+	}
+
+	if diff := cmp.Diff(got, []role.Role{role.Admin, role.User}); diff != "" {
+		t.Fatalf("Should get the roles back : %s", diff)
+	}
+
+	if err := json.Unmarshal([]byte(`["SUPER"]`), &got); err == nil {
+		t.Fatalf("Should not be able to unmarshal an unknown role")
+	}
+}
+
+func Test_All(t *testing.T) {
+	if diff := cmp.Diff(role.All(), []role.Role{role.Admin, role.User}); diff != "" {
+		t.Fatalf("Should list the known roles in order : %s", diff)
+	}
+}
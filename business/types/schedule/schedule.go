@@ -0,0 +1,242 @@
+// Package schedule represents a cron schedule in the system.
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Set of macros that can be used in place of a cron expression.
+var macros = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// bounds represents the range of values a field can hold.
+type bounds struct {
+	min uint
+	max uint
+}
+
+var fieldBounds = [5]bounds{
+	{0, 59}, // minute
+	{0, 23}, // hour
+	{1, 31}, // day of month
+	{1, 12}, // month
+	{0, 6},  // day of week
+}
+
+// Schedule represents a standard five field cron schedule. The fields are
+// minute, hour, day of month, month and day of week.
+type Schedule struct {
+	value  string
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+	domAll bool
+	dowAll bool
+}
+
+// String returns the schedule expression.
+func (s Schedule) String() string {
+	return s.value
+}
+
+// Equal provides support for the go-cmp package and testing.
+func (s Schedule) Equal(s2 Schedule) bool {
+	return s.value == s2.value
+}
+
+// MarshalText provides support for logging and any marshal needs.
+func (s Schedule) MarshalText() ([]byte, error) {
+	return []byte(s.value), nil
+}
+
+// IsZero reports if the schedule wasn't initialized.
+func (s Schedule) IsZero() bool {
+	return s.value == ""
+}
+
+// Next returns the first time after t that matches the schedule. The zero
+// time is returned if the schedule never matches.
+func (s Schedule) Next(t time.Time) time.Time {
+	if s.IsZero() {
+		return time.Time{}
+	}
+
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	// A schedule that matches must do so within the next leap year cycle.
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if !has(s.month, uint(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if !has(s.hour, uint(t.Hour())) {
+			t = t.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+
+		if !has(s.minute, uint(t.Minute())) {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+// dayMatches applies the cron rule that when both the day of month and day
+// of week are restricted, a day matching either one is a match.
+func (s Schedule) dayMatches(t time.Time) bool {
+	dom := has(s.dom, uint(t.Day()))
+	dow := has(s.dow, uint(t.Weekday()))
+
+	switch {
+	case s.domAll && s.dowAll:
+		return true
+	case s.domAll:
+		return dow
+	case s.dowAll:
+		return dom
+	}
+
+	return dom || dow
+}
+
+// =============================================================================
+
+// Parse parses the string value and returns a schedule if the expression
+// is valid.
+func Parse(value string) (Schedule, error) {
+	expr := strings.TrimSpace(value)
+	if m, exists := macros[expr]; exists {
+		expr = m
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return Schedule{}, fmt.Errorf("invalid schedule %q: expected 5 fields", value)
+	}
+
+	var bits [5]uint64
+	for i, field := range fields {
+		var err error
+		bits[i], err = parseField(field, fieldBounds[i])
+		if err != nil {
+			return Schedule{}, fmt.Errorf("invalid schedule %q: %w", value, err)
+		}
+	}
+
+	s := Schedule{
+		value:  strings.TrimSpace(value),
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		domAll: fields[2] == "*",
+		dowAll: fields[4] == "*",
+	}
+
+	return s, nil
+}
+
+// MustParse parses the string value and returns a schedule if the expression
+// is valid. If an error occurs the function panics.
+func MustParse(value string) Schedule {
+	s, err := Parse(value)
+	if err != nil {
+		panic(err)
+	}
+
+	return s
+}
+
+// parseField parses a comma separated list of values, ranges and steps
+// into a bit set.
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+
+	for part := range strings.SplitSeq(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+
+		step := uint(1)
+		if hasStep {
+			v, err := strconv.ParseUint(stepStr, 10, 8)
+			if err != nil || v == 0 {
+				return 0, fmt.Errorf("invalid step %q", part)
+			}
+			step = uint(v)
+		}
+
+		start, end := b.min, b.max
+
+		switch {
+		case rng == "*":
+
+		case strings.Contains(rng, "-"):
+			lo, hi, _ := strings.Cut(rng, "-")
+
+			var err error
+			if start, err = parseValue(lo, b); err != nil {
+				return 0, err
+			}
+			if end, err = parseValue(hi, b); err != nil {
+				return 0, err
+			}
+			if start > end {
+				return 0, fmt.Errorf("invalid range %q", rng)
+			}
+
+		default:
+			v, err := parseValue(rng, b)
+			if err != nil {
+				return 0, err
+			}
+			start = v
+			if !hasStep {
+				end = v
+			}
+		}
+
+		for v := start; v <= end; v += step {
+			bits |= 1 << v
+		}
+	}
+
+	return bits, nil
+}
+
+func parseValue(s string, b bounds) (uint, error) {
+	v, err := strconv.ParseUint(s, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+
+	if uint(v) < b.min || uint(v) > b.max {
+		return 0, fmt.Errorf("value %d out of range [%d-%d]", v, b.min, b.max)
+	}
+
+	return uint(v), nil
+}
+
+func has(bits uint64, v uint) bool {
+	return bits&(1<<v) != 0
+}
@@ -0,0 +1,56 @@
+package schedule_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/service/business/types/schedule"
+)
+
+func Test_Next(t *testing.T) {
+	start := time.Date(2024, time.January, 31, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		exp  time.Time
+	}{
+		{"* * * * *", time.Date(2024, time.January, 31, 10, 31, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, time.January, 31, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"*/15 9-17 * * 1-5", time.Date(2024, time.January, 31, 10, 45, 0, 0, time.UTC)},
+		{"0 8 * * 1", time.Date(2024, time.February, 5, 8, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1,15 * *", time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		s, err := schedule.Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Should be able to parse %q : %s", tt.expr, err)
+		}
+
+		if got := s.Next(start); !got.Equal(tt.exp) {
+			t.Errorf("%s: Exp: %s", tt.expr, tt.exp)
+			t.Errorf("%s: Got: %s", tt.expr, got)
+		}
+	}
+}
+
+func Test_ParseInvalid(t *testing.T) {
+	exprs := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+	}
+
+	for _, expr := range exprs {
+		if _, err := schedule.Parse(expr); err == nil {
+			t.Errorf("Should not be able to parse %q", expr)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Package diskblob provides support for storing blobs as files in a local
+// directory. It's meant for development and single node installs, the
+// directory has to be served by something else for the URLs to resolve.
+package diskblob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrInvalidKey is returned for a key that would leave the directory.
+var ErrInvalidKey = errors.New("invalid key")
+
+// Store manages blobs kept in a local directory.
+type Store struct {
+	root    string
+	baseURL string
+}
+
+// New constructs a store that keeps blobs under the root directory, which
+// is created if needed. The URL of a blob is its key joined to the base url.
+func New(root string, baseURL string) (*Store, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("mkdir: %w", err)
+	}
+
+	s := Store{
+		root:    root,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+
+	return &s, nil
+}
+
+// Put writes the blob under the key, replacing any blob already there. The
+// blob is written to a temporary file first so a reader never sees part of
+// it. The content type isn't kept, the file is served by its extension.
+func (s *Store) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("mkdir: %w", err)
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), ".blob-*")
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return fmt.Errorf("write: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close: %w", err)
+	}
+
+	if err := os.Chmod(f.Name(), 0o644); err != nil {
+		return fmt.Errorf("chmod: %w", err)
+	}
+
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("rename: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes the blob under the key. Deleting a blob that doesn't exist
+// isn't an error.
+func (s *Store) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("remove: %w", err)
+	}
+
+	return nil
+}
+
+// URL returns the address the blob is served from. The files aren't signed
+// so the ttl is ignored.
+func (s *Store) URL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	if _, err := s.path(key); err != nil {
+		return "", err
+	}
+
+	u, err := url.JoinPath(s.baseURL, strings.Split(key, "/")...)
+	if err != nil {
+		return "", fmt.Errorf("join: %w", err)
+	}
+
+	return u, nil
+}
+
+func (s *Store) path(key string) (string, error) {
+	if strings.Contains(key, `\`) || !filepath.IsLocal(filepath.FromSlash(key)) {
+		return "", fmt.Errorf("key[%s]: %w", key, ErrInvalidKey)
+	}
+
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
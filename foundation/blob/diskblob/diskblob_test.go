@@ -0,0 +1,61 @@
+package diskblob_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/service/foundation/blob/diskblob"
+)
+
+func Test_Store(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+
+	s, err := diskblob.New(root, "https://cdn.example.com/blobs/")
+	if err != nil {
+		t.Fatalf("Should be able to create a store : %s", err)
+	}
+
+	const key = "avatars/123/a b.png"
+
+	if err := s.Put(ctx, key, strings.NewReader("first"), "image/png"); err != nil {
+		t.Fatalf("Should be able to put a blob : %s", err)
+	}
+
+	if err := s.Put(ctx, key, strings.NewReader("second"), "image/png"); err != nil {
+		t.Fatalf("Should be able to replace a blob : %s", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(root, "avatars", "123", "a b.png"))
+	if err != nil || string(data) != "second" {
+		t.Fatalf("Should find the replaced blob on disk : %q, %v", data, err)
+	}
+
+	u, err := s.URL(ctx, key, time.Minute)
+	if err != nil {
+		t.Fatalf("Should be able to get the url : %s", err)
+	}
+
+	if exp := "https://cdn.example.com/blobs/avatars/123/a%20b.png"; u != exp {
+		t.Fatalf("Should join the key to the base url : got %s, exp %s", u, exp)
+	}
+
+	if err := s.Delete(ctx, key); err != nil {
+		t.Fatalf("Should be able to delete a blob : %s", err)
+	}
+
+	if err := s.Delete(ctx, key); err != nil {
+		t.Fatalf("Should be able to delete a blob that's gone : %s", err)
+	}
+
+	for _, key := range []string{"../escape.png", "/abs.png", `avatars\..\..\x.png`, ""} {
+		if err := s.Put(ctx, key, strings.NewReader("x"), "image/png"); !errors.Is(err, diskblob.ErrInvalidKey) {
+			t.Fatalf("Should reject key %q : %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+// Package s3blob provides support for storing blobs in an Amazon S3 bucket
+// or any service that speaks the S3 API.
+package s3blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Config represents the bucket blobs are stored in.
+type Config struct {
+	Bucket          string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+
+	// Endpoint is the address of an S3 compatible service. It's left empty
+	// for Amazon S3.
+	Endpoint string
+
+	// PathStyle puts the bucket in the path of a request instead of the
+	// host name, which most S3 compatible services need.
+	PathStyle bool
+
+	// PublicURL is the address a public bucket or a CDN in front of the
+	// bucket is served from. When it's empty, URLs are signed.
+	PublicURL string
+}
+
+// Store manages blobs kept in an S3 bucket.
+type Store struct {
+	client    *s3.Client
+	presign   *s3.PresignClient
+	bucket    string
+	publicURL string
+}
+
+// New constructs a store for the bucket. The options are applied to the
+// client after the config.
+func New(cfg Config, optFns ...func(*s3.Options)) (*Store, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("bucket is required")
+	}
+
+	opts := s3.Options{
+		Region:       cfg.Region,
+		UsePathStyle: cfg.PathStyle,
+		Credentials: aws.NewCredentialsCache(aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{
+				AccessKeyID:     cfg.AccessKeyID,
+				SecretAccessKey: cfg.SecretAccessKey,
+				Source:          "s3blob",
+			}, nil
+		})),
+	}
+
+	if cfg.Endpoint != "" {
+		opts.BaseEndpoint = aws.String(cfg.Endpoint)
+	}
+
+	client := s3.New(opts, optFns...)
+
+	s := Store{
+		client:    client,
+		presign:   s3.NewPresignClient(client),
+		bucket:    cfg.Bucket,
+		publicURL: strings.TrimSuffix(cfg.PublicURL, "/"),
+	}
+
+	return &s, nil
+}
+
+// Put writes the blob under the key, replacing any blob already there. The
+// reader should be seekable, the SDK can't sign a stream it can't rewind.
+func (s *Store) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	input := s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        r,
+		ContentType: aws.String(contentType),
+	}
+
+	if _, err := s.client.PutObject(ctx, &input); err != nil {
+		return fmt.Errorf("putobject: key[%s]: %w", key, err)
+	}
+
+	return nil
+}
+
+// Delete removes the blob under the key. Deleting a blob that doesn't exist
+// isn't an error.
+func (s *Store) Delete(ctx context.Context, key string) error {
+	input := s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}
+
+	if _, err := s.client.DeleteObject(ctx, &input); err != nil {
+		return fmt.Errorf("deleteobject: key[%s]: %w", key, err)
+	}
+
+	return nil
+}
+
+// URL returns the address the blob can be read from. Without a public url
+// it's a signed url that expires after the ttl.
+func (s *Store) URL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	if s.publicURL != "" {
+		u, err := url.JoinPath(s.publicURL, strings.Split(key, "/")...)
+		if err != nil {
+			return "", fmt.Errorf("join: %w", err)
+		}
+		return u, nil
+	}
+
+	input := s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}
+
+	req, err := s.presign.PresignGetObject(ctx, &input, s3.WithPresignExpires(ttl))
+	if err != nil {
+		return "", fmt.Errorf("presign: key[%s]: %w", key, err)
+	}
+
+	return req.URL, nil
+}
@@ -0,0 +1,180 @@
+// Package boot provides support for timing the phases of starting a service
+// so a slow cold start can be traced to the phase that caused it.
+package boot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ardanlabs/service/foundation/logger"
+)
+
+// ErrOverBudget is returned when starting the service took longer than the
+// budget it was given.
+var ErrOverBudget = errors.New("startup over budget")
+
+// Phase represents a single phase of starting the service.
+type Phase struct {
+	Name     string `json:"name"`
+	Duration string `json:"duration"`
+}
+
+// Report represents the phases recorded while starting the service.
+type Report struct {
+	Started    time.Time `json:"started"`
+	Duration   string    `json:"duration"`
+	Budget     string    `json:"budget,omitempty"`
+	Phases     []Phase   `json:"phases"`
+	Complete   bool      `json:"complete"`
+	OverBudget bool      `json:"overBudget"`
+}
+
+// Recorder records how long each phase of starting the service takes. It is
+// safe for concurrent use.
+type Recorder struct {
+	log        *logger.Logger
+	mu         sync.Mutex
+	start      time.Time
+	end        time.Time
+	budget     time.Duration
+	phase      string
+	phaseStart time.Time
+	phases     []Phase
+}
+
+// last is the recorder of the most recent startup.
+var last atomic.Pointer[Recorder]
+
+// New constructs a recorder that starts timing now. The recorder becomes the
+// one returned by Last.
+func New(log *logger.Logger) *Recorder {
+	now := time.Now()
+
+	r := Recorder{
+		log:        log,
+		start:      now,
+		phaseStart: now,
+	}
+
+	last.Store(&r)
+
+	return &r
+}
+
+// Last returns the report of the most recent startup and false if no
+// startup has been recorded.
+func Last() (Report, bool) {
+	r := last.Load()
+	if r == nil {
+		return Report{}, false
+	}
+
+	return r.Report(), true
+}
+
+// SetBudget sets how long starting the service may take. A budget of zero
+// or less disables the check.
+func (r *Recorder) SetBudget(budget time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.budget = budget
+}
+
+// Phase ends the current phase, if any, and starts timing the named one.
+func (r *Recorder) Phase(ctx context.Context, name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	r.endPhase(ctx, now)
+
+	r.phase = name
+	r.phaseStart = now
+}
+
+// Finish ends the current phase and logs the report. It returns
+// ErrOverBudget if starting the service took longer than the budget.
+func (r *Recorder) Finish(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	r.endPhase(ctx, now)
+	r.end = now
+
+	total := r.end.Sub(r.start)
+
+	phases := make([]string, len(r.phases))
+	for i, p := range r.phases {
+		phases[i] = p.Name + "=" + p.Duration
+	}
+
+	r.log.Info(ctx, "startup", "status", "startup complete", "duration", total, "budget", r.budget, "phases", strings.Join(phases, " "))
+
+	if r.overBudget() {
+		return fmt.Errorf("took %s, budget %s: %w", total, r.budget, ErrOverBudget)
+	}
+
+	return nil
+}
+
+// Report returns a copy of the phases recorded so far.
+func (r *Recorder) Report() Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	end := r.end
+	if end.IsZero() {
+		end = time.Now()
+	}
+
+	rpt := Report{
+		Started:    r.start,
+		Duration:   end.Sub(r.start).String(),
+		Phases:     append([]Phase{}, r.phases...),
+		Complete:   !r.end.IsZero(),
+		OverBudget: r.overBudget(),
+	}
+
+	if r.budget > 0 {
+		rpt.Budget = r.budget.String()
+	}
+
+	return rpt
+}
+
+func (r *Recorder) endPhase(ctx context.Context, now time.Time) {
+	if r.phase == "" {
+		return
+	}
+
+	d := now.Sub(r.phaseStart)
+
+	r.phases = append(r.phases, Phase{
+		Name:     r.phase,
+		Duration: d.String(),
+	})
+
+	r.log.Info(ctx, "startup", "phase", r.phase, "duration", d)
+
+	r.phase = ""
+}
+
+func (r *Recorder) overBudget() bool {
+	if r.budget <= 0 {
+		return false
+	}
+
+	end := r.end
+	if end.IsZero() {
+		end = time.Now()
+	}
+
+	return end.Sub(r.start) > r.budget
+}
@@ -0,0 +1,53 @@
+package boot_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/service/foundation/boot"
+	"github.com/ardanlabs/service/foundation/logger"
+)
+
+func Test_Recorder(t *testing.T) {
+	ctx := context.Background()
+	log := logger.New(io.Discard, logger.LevelInfo, "TEST", func(context.Context) string { return "" })
+
+	r := boot.New(log)
+	r.SetBudget(time.Hour)
+
+	r.Phase(ctx, "config")
+	r.Phase(ctx, "db")
+
+	if rpt, ok := boot.Last(); !ok || rpt.Complete || len(rpt.Phases) != 1 {
+		t.Fatalf("Should report the finished phases before startup completes : %+v", rpt)
+	}
+
+	if err := r.Finish(ctx); err != nil {
+		t.Fatalf("Should finish within the budget : %s", err)
+	}
+
+	rpt := r.Report()
+	if !rpt.Complete || rpt.OverBudget {
+		t.Fatalf("Should report a complete startup within the budget : %+v", rpt)
+	}
+
+	if len(rpt.Phases) != 2 || rpt.Phases[0].Name != "config" || rpt.Phases[1].Name != "db" {
+		t.Fatalf("Should record the phases in order : %+v", rpt.Phases)
+	}
+
+	r = boot.New(log)
+	r.SetBudget(time.Nanosecond)
+	r.Phase(ctx, "servers")
+	time.Sleep(time.Millisecond)
+
+	if err := r.Finish(ctx); !errors.Is(err, boot.ErrOverBudget) {
+		t.Fatalf("Should fail a startup over the budget : %v", err)
+	}
+
+	if rpt, _ := boot.Last(); !rpt.OverBudget {
+		t.Fatalf("Should report the last startup as over budget : %+v", rpt)
+	}
+}
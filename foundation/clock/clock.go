@@ -0,0 +1,21 @@
+// Package clock provides the current time for the service. Business and
+// app code that compares against the current time, for expirations and
+// deadlines, reads it from here instead of calling time.Now directly.
+//
+// In regular builds the clock is the system clock. Builds with the staging
+// tag can skew it forward or back with Skew, so expirations that normally
+// take days can be exercised by QA without waiting for them.
+package clock
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNotSkewable is returned by Skew in builds without the staging tag.
+var ErrNotSkewable = errors.New("clock can't be skewed in this build")
+
+// Now returns the current time, skewed by the current offset.
+func Now() time.Time {
+	return time.Now().Add(Offset())
+}
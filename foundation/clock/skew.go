@@ -0,0 +1,25 @@
+//go:build staging
+
+package clock
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Skewable reports whether the clock can be skewed in this build.
+const Skewable = true
+
+var offset atomic.Int64
+
+// Offset returns how far the clock is skewed from the system clock.
+func Offset() time.Duration {
+	return time.Duration(offset.Load())
+}
+
+// Skew sets how far the clock is skewed from the system clock. An offset
+// of zero restores the system clock.
+func Skew(d time.Duration) error {
+	offset.Store(int64(d))
+	return nil
+}
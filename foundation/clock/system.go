@@ -0,0 +1,20 @@
+//go:build !staging
+
+package clock
+
+import "time"
+
+// Skewable reports whether the clock can be skewed in this build.
+const Skewable = false
+
+// Offset returns how far the clock is skewed from the system clock, which
+// is always zero in this build.
+func Offset() time.Duration {
+	return 0
+}
+
+// Skew returns ErrNotSkewable since the clock can only be skewed in builds
+// with the staging tag.
+func Skew(d time.Duration) error {
+	return ErrNotSkewable
+}
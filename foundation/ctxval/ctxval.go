@@ -0,0 +1,85 @@
+// Package ctxval provides typed keys for storing values in a context. Every
+// value placed in a context by the service goes through this package so
+// the type stored under a key can't drift from the type read back.
+package ctxval
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+)
+
+// devMode controls if a missing required value panics.
+var devMode atomic.Bool
+
+// SetDevMode turns dev mode on or off. In dev mode a missing required value
+// panics so the mistake is found during development instead of showing up
+// as a zero value in production.
+func SetDevMode(on bool) {
+	devMode.Store(on)
+}
+
+// DevMode reports if dev mode is on.
+func DevMode() bool {
+	return devMode.Load()
+}
+
+// =============================================================================
+
+// name is the value used as the context key. Each key gets its own pointer
+// so two keys can never collide, even with the same name.
+type name struct {
+	value string
+}
+
+// Key represents a context key that stores values of type T.
+type Key[T any] struct {
+	name *name
+}
+
+// NewKey constructs a key for storing values of type T. The name is only
+// used in error messages.
+func NewKey[T any](keyName string) Key[T] {
+	return Key[T]{
+		name: &name{value: keyName},
+	}
+}
+
+// String returns the name of the key.
+func (k Key[T]) String() string {
+	return k.name.value
+}
+
+// Set returns a copy of the context holding the value.
+func (k Key[T]) Set(ctx context.Context, v T) context.Context {
+	return context.WithValue(ctx, k.name, v)
+}
+
+// Get returns the value from the context and reports if it was found.
+func (k Key[T]) Get(ctx context.Context) (T, bool) {
+	v, ok := ctx.Value(k.name).(T)
+	return v, ok
+}
+
+// Value returns the value from the context or the zero value of T if it
+// was not found.
+func (k Key[T]) Value(ctx context.Context) T {
+	v, _ := k.Get(ctx)
+	return v
+}
+
+// Require returns the value from the context or an error if it was not
+// found. In dev mode a missing value panics.
+func (k Key[T]) Require(ctx context.Context) (T, error) {
+	v, ok := k.Get(ctx)
+	if !ok {
+		err := fmt.Errorf("%s not found in context", k.name.value)
+		if devMode.Load() {
+			panic(err)
+		}
+
+		return v, err
+	}
+
+	return v, nil
+}
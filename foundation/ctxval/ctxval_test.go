@@ -0,0 +1,128 @@
+package ctxval_test
+
+import (
+	"context"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ardanlabs/service/foundation/ctxval"
+)
+
+func Test_Key(t *testing.T) {
+	userKey := ctxval.NewKey[string]("user")
+	otherKey := ctxval.NewKey[string]("user")
+
+	ctx := userKey.Set(context.Background(), "bill")
+
+	if v, ok := userKey.Get(ctx); !ok || v != "bill" {
+		t.Fatalf("Should get back the value : got %q, %t", v, ok)
+	}
+
+	if _, ok := otherKey.Get(ctx); ok {
+		t.Fatalf("Should not collide with a key of the same name")
+	}
+
+	if _, err := otherKey.Require(ctx); err == nil {
+		t.Fatalf("Should get an error for a missing required value")
+	}
+
+	ctxval.SetDevMode(true)
+	defer ctxval.SetDevMode(false)
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("Should panic for a missing required value in dev mode")
+		}
+	}()
+
+	otherKey.Require(ctx)
+}
+
+// Test_DirectUse flags any use of context.WithValue, or of Value on a
+// context named ctx, outside of this package. All context values must go
+// through a typed key.
+func Test_DirectUse(t *testing.T) {
+	root := moduleRoot(t)
+	fset := token.NewFileSet()
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			switch d.Name() {
+			case "vendor", ".git", "zarf", "ctxval":
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return err
+		}
+
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+
+			x, ok := sel.X.(*ast.Ident)
+			if !ok {
+				return true
+			}
+
+			switch {
+			case x.Name == "context" && sel.Sel.Name == "WithValue",
+				x.Name == "ctx" && sel.Sel.Name == "Value" && len(call.Args) == 1:
+				t.Errorf("%s: use a ctxval key instead of %s.%s", fset.Position(call.Pos()), x.Name, sel.Sel.Name)
+			}
+
+			return true
+		})
+
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("Should be able to walk the module : %s", err)
+	}
+}
+
+func moduleRoot(t *testing.T) string {
+	t.Helper()
+
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Should be able to get the working directory : %s", err)
+	}
+
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			t.Fatalf("Should be able to find the module root")
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,187 @@
+// Package dataloader batches the loads of values requested by concurrent
+// callers into a single call, so resolving a list of items that each need a
+// related value doesn't cost a call per item.
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned when the batch function doesn't return a value
+// for a key.
+var ErrNotFound = errors.New("not found")
+
+// BatchFunc loads the values for the keys in a single call. Keys left out
+// of the map returned are reported as not found.
+type BatchFunc[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+// Options represents optional settings for a loader.
+type Options struct {
+	wait     time.Duration
+	maxBatch int
+}
+
+// WithWait sets how long a loader waits for more keys after the first one
+// before it calls the batch function. The default is 2ms.
+func WithWait(wait time.Duration) func(opts *Options) {
+	return func(opts *Options) {
+		if wait > 0 {
+			opts.wait = wait
+		}
+	}
+}
+
+// WithMaxBatch sets the most keys passed to a single call of the batch
+// function. The default is 100.
+func WithMaxBatch(n int) func(opts *Options) {
+	return func(opts *Options) {
+		if n > 0 {
+			opts.maxBatch = n
+		}
+	}
+}
+
+// Loader loads values by key in batches. Values are kept for the life of
+// the loader, so a loader is meant to be constructed for each request.
+type Loader[K comparable, V any] struct {
+	fetch   BatchFunc[K, V]
+	opts    Options
+	mu      sync.Mutex
+	results map[K]*result[V]
+	pending *batch[K, V]
+}
+
+// New constructs a loader that calls fetch with the keys requested.
+func New[K comparable, V any](fetch BatchFunc[K, V], options ...func(opts *Options)) *Loader[K, V] {
+	opts := Options{
+		wait:     2 * time.Millisecond,
+		maxBatch: 100,
+	}
+
+	for _, option := range options {
+		option(&opts)
+	}
+
+	l := Loader[K, V]{
+		fetch:   fetch,
+		opts:    opts,
+		results: make(map[K]*result[V]),
+	}
+
+	return &l
+}
+
+// Load returns the value for the key. The key is added to the pending batch
+// and the call blocks until the batch has been loaded.
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.mu.Lock()
+
+	r, exists := l.results[key]
+	if !exists {
+		r = &result[V]{
+			done: make(chan struct{}),
+		}
+		l.results[key] = r
+
+		l.add(ctx, key, r)
+	}
+
+	l.mu.Unlock()
+
+	select {
+	case <-r.done:
+		return r.value, r.err
+
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// Prime holds the value for the key when it's already known, so a later
+// load doesn't need to fetch it.
+func (l *Loader[K, V]) Prime(key K, value V) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, exists := l.results[key]; exists {
+		return
+	}
+
+	r := result[V]{
+		done:  make(chan struct{}),
+		value: value,
+	}
+	close(r.done)
+
+	l.results[key] = &r
+}
+
+// =============================================================================
+
+type result[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+type batch[K comparable, V any] struct {
+	keys    []K
+	results []*result[V]
+}
+
+// add puts the key in the pending batch, starting a new one when there's
+// none. The batch is dispatched once it's full or the wait is over. The
+// loader's mutex must be held.
+func (l *Loader[K, V]) add(ctx context.Context, key K, r *result[V]) {
+	b := l.pending
+	if b == nil {
+		b = &batch[K, V]{}
+		l.pending = b
+
+		time.AfterFunc(l.opts.wait, func() {
+			l.mu.Lock()
+			if l.pending != b {
+				l.mu.Unlock()
+				return
+			}
+			l.pending = nil
+			l.mu.Unlock()
+
+			l.dispatch(ctx, b)
+		})
+	}
+
+	b.keys = append(b.keys, key)
+	b.results = append(b.results, r)
+
+	if len(b.keys) >= l.opts.maxBatch {
+		l.pending = nil
+		go l.dispatch(ctx, b)
+	}
+}
+
+// dispatch calls the batch function and hands each caller its result.
+func (l *Loader[K, V]) dispatch(ctx context.Context, b *batch[K, V]) {
+	values, err := l.fetch(context.WithoutCancel(ctx), b.keys)
+
+	for i, key := range b.keys {
+		r := b.results[i]
+
+		switch v, exists := values[key]; {
+		case err != nil:
+			r.err = err
+
+		case !exists:
+			r.err = ErrNotFound
+
+		default:
+			r.value = v
+		}
+
+		close(r.done)
+	}
+}
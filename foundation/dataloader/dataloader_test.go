@@ -0,0 +1,114 @@
+package dataloader_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/service/foundation/dataloader"
+)
+
+func Test_Batch(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][]int
+
+	fetch := func(ctx context.Context, keys []int) (map[int]string, error) {
+		mu.Lock()
+		batches = append(batches, slices.Clone(keys))
+		mu.Unlock()
+
+		values := make(map[int]string)
+		for _, k := range keys {
+			if k%10 != 0 {
+				values[k] = string(rune('a' + k))
+			}
+		}
+
+		return values, nil
+	}
+
+	l := dataloader.New(fetch, dataloader.WithWait(10*time.Millisecond), dataloader.WithMaxBatch(3))
+
+	keys := []int{1, 2, 2, 3, 4, 10}
+	values := make([]string, len(keys))
+	errs := make([]error, len(keys))
+
+	var wg sync.WaitGroup
+	wg.Add(len(keys))
+
+	for i, key := range keys {
+		go func() {
+			defer wg.Done()
+			values[i], errs[i] = l.Load(context.Background(), key)
+		}()
+	}
+
+	wg.Wait()
+
+	for i, key := range keys {
+		if key == 10 {
+			if !errors.Is(errs[i], dataloader.ErrNotFound) {
+				t.Fatalf("expected %v for a missing key, got %v", dataloader.ErrNotFound, errs[i])
+			}
+			continue
+		}
+
+		if errs[i] != nil {
+			t.Fatalf("Should be able to load key %d : %s", key, errs[i])
+		}
+
+		if values[i] != string(rune('a'+key)) {
+			t.Fatalf("expected %q for key %d, got %q", string(rune('a'+key)), key, values[i])
+		}
+	}
+
+	var loaded []int
+	for _, b := range batches {
+		if len(b) > 3 {
+			t.Fatalf("expected batches of at most 3 keys, got %v", b)
+		}
+		loaded = append(loaded, b...)
+	}
+
+	slices.Sort(loaded)
+	if !slices.Equal(loaded, []int{1, 2, 3, 4, 10}) {
+		t.Fatalf("expected each key to be loaded once, got %v", loaded)
+	}
+
+	if len(batches) != 2 {
+		t.Fatalf("expected 2 batches, got %d", len(batches))
+	}
+
+	if _, err := l.Load(context.Background(), 1); err != nil {
+		t.Fatalf("Should be able to load a key again : %s", err)
+	}
+
+	if len(batches) != 2 {
+		t.Fatalf("expected a loaded key to be kept, got %d batches", len(batches))
+	}
+}
+
+func Test_Prime(t *testing.T) {
+	fetch := func(ctx context.Context, keys []string) (map[string]int, error) {
+		return nil, errors.New("fetch should not be called")
+	}
+
+	l := dataloader.New(fetch)
+	l.Prime("bill", 42)
+
+	v, err := l.Load(context.Background(), "bill")
+	if err != nil {
+		t.Fatalf("Should be able to load a primed key : %s", err)
+	}
+
+	if v != 42 {
+		t.Fatalf("expected 42, got %d", v)
+	}
+
+	if _, err := l.Load(context.Background(), "jill"); err == nil {
+		t.Fatal("expected the fetch error to be returned")
+	}
+}
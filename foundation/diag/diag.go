@@ -0,0 +1,175 @@
+// Package diag provides support for collecting execution diagnostics for a
+// single request so a misbehaving call can be inspected in isolation.
+package diag
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ardanlabs/service/foundation/ctxval"
+)
+
+// Span represents a span that was started while handling the request.
+type Span struct {
+	Name     string `json:"name"`
+	Offset   string `json:"offset"`
+	Duration string `json:"duration"`
+}
+
+// Query represents a SQL statement that was executed while handling the
+// request. The statement is recorded in its parameterized form.
+type Query struct {
+	Statement string `json:"statement"`
+	Duration  string `json:"duration"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Cache represents a cache lookup that was performed while handling the
+// request.
+type Cache struct {
+	Key string `json:"key"`
+	Hit bool   `json:"hit"`
+}
+
+// Report represents the diagnostics collected for a request.
+type Report struct {
+	Duration string  `json:"duration"`
+	Spans    []Span  `json:"spans"`
+	Queries  []Query `json:"queries"`
+	Cache    []Cache `json:"cache"`
+	Dropped  int     `json:"dropped,omitempty"`
+}
+
+// Limit returns a copy of the report that keeps at most n spans, queries
+// and cache lookups each. The number of entries left out is added to
+// Dropped.
+func (r Report) Limit(n int) Report {
+	n = max(n, 0)
+
+	limit := func(count int) int {
+		if count <= n {
+			return count
+		}
+		r.Dropped += count - n
+		return n
+	}
+
+	r.Spans = r.Spans[:limit(len(r.Spans))]
+	r.Queries = r.Queries[:limit(len(r.Queries))]
+	r.Cache = r.Cache[:limit(len(r.Cache))]
+
+	return r
+}
+
+// Diagnostics collects the execution details of a single request. It is
+// safe for concurrent use.
+type Diagnostics struct {
+	mu      sync.Mutex
+	start   time.Time
+	spans   []Span
+	queries []Query
+	cache   []Cache
+}
+
+// Report returns a copy of the diagnostics collected so far.
+func (d *Diagnostics) Report() Report {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return Report{
+		Duration: time.Since(d.start).String(),
+		Spans:    append([]Span{}, d.spans...),
+		Queries:  append([]Query{}, d.queries...),
+		Cache:    append([]Cache{}, d.cache...),
+	}
+}
+
+// =============================================================================
+
+var diagKey = ctxval.NewKey[*Diagnostics]("diagnostics")
+
+// Start enables the collection of diagnostics for the specified context.
+func Start(ctx context.Context) context.Context {
+	d := Diagnostics{
+		start: time.Now(),
+	}
+
+	return diagKey.Set(ctx, &d)
+}
+
+// Get returns the diagnostics being collected in the context if
+// collection was started.
+func Get(ctx context.Context) (*Diagnostics, bool) {
+	return diagKey.Get(ctx)
+}
+
+// Enabled reports if diagnostics are being collected for the context.
+func Enabled(ctx context.Context) bool {
+	_, ok := Get(ctx)
+	return ok
+}
+
+// AddSpan records the start of a span and returns a function that must be
+// called when the span ends.
+func AddSpan(ctx context.Context, name string) func() {
+	d, ok := Get(ctx)
+	if !ok {
+		return func() {}
+	}
+
+	start := time.Now()
+
+	return func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		d.spans = append(d.spans, Span{
+			Name:     name,
+			Offset:   start.Sub(d.start).String(),
+			Duration: time.Since(start).String(),
+		})
+	}
+}
+
+// AddQuery records a SQL statement that was executed.
+func AddQuery(ctx context.Context, statement string, duration time.Duration, err error) {
+	d, ok := Get(ctx)
+	if !ok {
+		return
+	}
+
+	statement = strings.ReplaceAll(statement, "\t", "")
+	statement = strings.ReplaceAll(statement, "\n", " ")
+
+	q := Query{
+		Statement: strings.TrimSpace(statement),
+		Duration:  duration.String(),
+	}
+
+	if err != nil {
+		q.Error = err.Error()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.queries = append(d.queries, q)
+}
+
+// AddCache records a cache lookup.
+func AddCache(ctx context.Context, key string, hit bool) {
+	d, ok := Get(ctx)
+	if !ok {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.cache = append(d.cache, Cache{
+		Key: key,
+		Hit: hit,
+	})
+}
@@ -14,24 +14,30 @@ import (
 	"io/fs"
 	"path"
 	"strings"
+	"sync"
+	"time"
 )
 
 // key represents key information.
 type key struct {
 	privatePEM string
 	publicPEM  string
+	rotation   *Rotation
 }
 
 // KeyStore represents an in memory store implementation of the
 // KeyLookup interface for use with the auth package.
 type KeyStore struct {
+	mu    sync.RWMutex
 	store map[string]key
+	now   func() time.Time
 }
 
 // New constructs an empty KeyStore ready for use.
 func New() *KeyStore {
 	return &KeyStore{
 		store: make(map[string]key),
+		now:   time.Now,
 	}
 }
 
@@ -60,6 +66,9 @@ func (ks *KeyStore) LoadByJSON(document string) (int, error) {
 		publicPEM:  publicPEM,
 	}
 
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
 	ks.store[d.Key] = key
 
 	return len(ks.store), nil
@@ -109,6 +118,9 @@ func (ks *KeyStore) LoadByFileSystem(fsys fs.FS) (int, error) {
 			publicPEM:  publicPEM,
 		}
 
+		ks.mu.Lock()
+		defer ks.mu.Unlock()
+
 		ks.store[strings.TrimSuffix(dirEntry.Name(), ".pem")] = key
 
 		return nil
@@ -118,11 +130,17 @@ func (ks *KeyStore) LoadByFileSystem(fsys fs.FS) (int, error) {
 		return 0, fmt.Errorf("walking directory: %w", err)
 	}
 
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
 	return len(ks.store), nil
 }
 
 // PrivateKey searches the key store for a given kid and returns the private key.
 func (ks *KeyStore) PrivateKey(kid string) (string, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
 	key, found := ks.store[kid]
 	if !found {
 		return "", errors.New("kid lookup failed")
@@ -132,12 +150,26 @@ func (ks *KeyStore) PrivateKey(kid string) (string, error) {
 }
 
 // PublicKey searches the key store for a given kid and returns the public key.
+// Looking up a key that is being retired is recorded against its rotation.
 func (ks *KeyStore) PublicKey(kid string) (string, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
 	key, found := ks.store[kid]
 	if !found {
 		return "", errors.New("kid lookup failed")
 	}
 
+	if key.rotation != nil {
+		now := ks.now()
+		if !now.Before(key.rotation.Deadline) {
+			return "", errors.New("kid has been retired")
+		}
+
+		key.rotation.Uses++
+		key.rotation.LastUsed = now
+	}
+
 	return key.publicPEM, nil
 }
 
@@ -0,0 +1,98 @@
+package keystore
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Rotation represents a key being replaced by a successor. The retiring key
+// can still be used to verify tokens until the deadline so callers holding
+// tokens signed with it keep working while they move to the successor.
+type Rotation struct {
+	KID          string
+	SuccessorKID string
+	Deadline     time.Time
+	Uses         int
+	LastUsed     time.Time
+}
+
+// Rotate starts the retirement of the key identified by kid in favor of the
+// successor. Both keys must already be loaded into the store.
+func (ks *KeyStore) Rotate(kid string, successorKID string, deadline time.Time) error {
+	if kid == successorKID {
+		return errors.New("key can't be its own successor")
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	k, found := ks.store[kid]
+	if !found {
+		return errors.New("kid lookup failed")
+	}
+
+	successor, found := ks.store[successorKID]
+	if !found {
+		return errors.New("successor kid lookup failed")
+	}
+
+	if successor.rotation != nil {
+		return errors.New("successor kid is being retired")
+	}
+
+	k.rotation = &Rotation{
+		KID:          kid,
+		SuccessorKID: successorKID,
+		Deadline:     deadline,
+	}
+
+	ks.store[kid] = k
+
+	return nil
+}
+
+// Rotations returns the rotations that are in progress.
+func (ks *KeyStore) Rotations() []Rotation {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	var rots []Rotation
+	for _, k := range ks.store {
+		if k.rotation != nil {
+			rots = append(rots, *k.rotation)
+		}
+	}
+
+	slices.SortFunc(rots, func(a, b Rotation) int {
+		return strings.Compare(a.KID, b.KID)
+	})
+
+	return rots
+}
+
+// RevokeExpired removes the retiring keys whose deadline has passed and
+// returns the rotations that were completed.
+func (ks *KeyStore) RevokeExpired() []Rotation {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	now := ks.now()
+
+	var rots []Rotation
+	for kid, k := range ks.store {
+		if k.rotation == nil || now.Before(k.rotation.Deadline) {
+			continue
+		}
+
+		rots = append(rots, *k.rotation)
+		delete(ks.store, kid)
+	}
+
+	slices.SortFunc(rots, func(a, b Rotation) int {
+		return strings.Compare(a.KID, b.KID)
+	})
+
+	return rots
+}
@@ -0,0 +1,93 @@
+package keystore_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/service/foundation/keystore"
+)
+
+func Test_Rotation(t *testing.T) {
+	ks := keystore.New()
+	loadKey(t, ks, "old")
+	loadKey(t, ks, "new")
+
+	if err := ks.Rotate("old", "old", time.Now().Add(time.Hour)); err == nil {
+		t.Fatalf("Should not allow a key to succeed itself")
+	}
+
+	if err := ks.Rotate("old", "missing", time.Now().Add(time.Hour)); err == nil {
+		t.Fatalf("Should not allow an unknown successor")
+	}
+
+	if err := ks.Rotate("old", "new", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Should be able to rotate the key : %s", err)
+	}
+
+	if _, err := ks.PublicKey("old"); err != nil {
+		t.Fatalf("Should accept the retiring key before the deadline : %s", err)
+	}
+
+	rots := ks.Rotations()
+	if len(rots) != 1 || rots[0].Uses != 1 || rots[0].SuccessorKID != "new" {
+		t.Fatalf("Should track the use of the retiring key : %+v", rots)
+	}
+
+	if rots := ks.RevokeExpired(); len(rots) != 0 {
+		t.Fatalf("Should not revoke the key before the deadline : %+v", rots)
+	}
+
+	if err := ks.Rotate("old", "new", time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("Should be able to move the deadline : %s", err)
+	}
+
+	if _, err := ks.PublicKey("old"); err == nil {
+		t.Fatalf("Should not accept the retiring key after the deadline")
+	}
+
+	if rots := ks.RevokeExpired(); len(rots) != 1 || rots[0].KID != "old" {
+		t.Fatalf("Should revoke the key after the deadline : %+v", rots)
+	}
+
+	if _, err := ks.PrivateKey("old"); err == nil {
+		t.Fatalf("Should have removed the revoked key")
+	}
+
+	if _, err := ks.PublicKey("new"); err != nil {
+		t.Fatalf("Should still accept the successor : %s", err)
+	}
+}
+
+func loadKey(t *testing.T, ks *keystore.KeyStore, kid string) {
+	t.Helper()
+
+	pk, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Should be able to generate a key : %s", err)
+	}
+
+	block := pem.Block{
+		Type:  "PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(pk),
+	}
+
+	doc, err := json.Marshal(struct {
+		Key string `json:"key"`
+		PEM string `json:"pem"`
+	}{
+		Key: kid,
+		PEM: string(pem.EncodeToMemory(&block)),
+	})
+	if err != nil {
+		t.Fatalf("Should be able to marshal the key document : %s", err)
+	}
+
+	if _, err := ks.LoadByJSON(string(doc)); err != nil {
+		t.Fatalf("Should be able to load the key : %s", err)
+	}
+}
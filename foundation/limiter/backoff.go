@@ -0,0 +1,119 @@
+package limiter
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+type failures struct {
+	count int
+	until time.Time
+	last  time.Time
+}
+
+// Backoff delays the next attempt for a key after each failure. The delay
+// doubles with every consecutive failure up to a maximum, and the failures
+// are forgotten once the key has been quiet for the forget period.
+type Backoff struct {
+	base      time.Duration
+	max       time.Duration
+	forget    time.Duration
+	now       func() time.Time
+	mu        sync.Mutex
+	keys      map[string]failures
+	nextSweep time.Time
+}
+
+// NewBackoff constructs a backoff whose first delay is base, and that never
+// delays for longer than max.
+func NewBackoff(base time.Duration, max time.Duration, forget time.Duration) (*Backoff, error) {
+	if base <= 0 {
+		return nil, errors.New("base must be greater than zero")
+	}
+
+	if max < base {
+		return nil, errors.New("max must not be less than base")
+	}
+
+	if forget < max {
+		return nil, errors.New("forget must not be less than max")
+	}
+
+	b := Backoff{
+		base:   base,
+		max:    max,
+		forget: forget,
+		now:    time.Now,
+		keys:   make(map[string]failures),
+	}
+
+	return &b, nil
+}
+
+// Wait returns how long the key must wait before its next attempt. A zero
+// duration means the attempt can be made now.
+func (b *Backoff) Wait(key string) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	f, exists := b.keys[key]
+	if !exists {
+		return 0
+	}
+
+	return max(f.until.Sub(b.now()), 0)
+}
+
+// Fail records a failed attempt for the key and returns the delay before
+// the next attempt is allowed.
+func (b *Backoff) Fail(key string) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.sweep(now)
+
+	f := b.keys[key]
+	if now.Sub(f.last) >= b.forget {
+		f = failures{}
+	}
+
+	f.count++
+	f.last = now
+
+	delay := b.base
+	for i := 1; i < f.count && delay < b.max; i++ {
+		delay *= 2
+	}
+	delay = min(delay, b.max)
+
+	f.until = now.Add(delay)
+	b.keys[key] = f
+
+	return delay
+}
+
+// Reset forgets the failures recorded for the key.
+func (b *Backoff) Reset(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.keys, key)
+}
+
+// sweep removes the keys that have been quiet for the forget period so the
+// set of keys doesn't grow without bound.
+func (b *Backoff) sweep(now time.Time) {
+	if now.Before(b.nextSweep) {
+		return
+	}
+
+	for key, f := range b.keys {
+		if now.Sub(f.last) >= b.forget {
+			delete(b.keys, key)
+		}
+	}
+
+	b.nextSweep = now.Add(b.forget)
+}
@@ -0,0 +1,48 @@
+package limiter_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/service/foundation/limiter"
+)
+
+func Test_Backoff(t *testing.T) {
+	b, err := limiter.NewBackoff(10*time.Millisecond, 40*time.Millisecond, time.Second)
+	if err != nil {
+		t.Fatalf("Should be able to create a backoff : %s", err)
+	}
+
+	if d := b.Wait("caller"); d != 0 {
+		t.Fatalf("Should not delay a key without failures : %s", d)
+	}
+
+	exp := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond, 40 * time.Millisecond}
+	for i, e := range exp {
+		if d := b.Fail("caller"); d != e {
+			t.Fatalf("Should double the delay on failure %d : got %s, exp %s", i, d, e)
+		}
+	}
+
+	if d := b.Wait("caller"); d <= 0 || d > 40*time.Millisecond {
+		t.Fatalf("Should delay the next attempt : %s", d)
+	}
+
+	if d := b.Wait("other"); d != 0 {
+		t.Fatalf("Should track each key separately : %s", d)
+	}
+
+	b.Reset("caller")
+
+	if d := b.Wait("caller"); d != 0 {
+		t.Fatalf("Should not delay after a reset : %s", d)
+	}
+
+	if d := b.Fail("caller"); d != 10*time.Millisecond {
+		t.Fatalf("Should start over after a reset : %s", d)
+	}
+
+	if _, err := limiter.NewBackoff(time.Second, time.Millisecond, time.Hour); err == nil {
+		t.Fatalf("Should not allow a max less than the base")
+	}
+}
@@ -0,0 +1,100 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+type tokens struct {
+	count float64
+	last  time.Time
+}
+
+// TokenBucket limits the rate of attempts per key. Each key has a bucket
+// holding up to burst tokens that refills at rate tokens per second, and
+// every attempt takes a token from it.
+type TokenBucket struct {
+	rate      float64
+	burst     float64
+	now       func() time.Time
+	mu        sync.Mutex
+	keys      map[string]tokens
+	nextSweep time.Time
+}
+
+// NewTokenBucket constructs a token bucket that allows burst attempts at
+// once and rate attempts per second after that.
+func NewTokenBucket(rate float64, burst int) (*TokenBucket, error) {
+	if rate <= 0 {
+		return nil, errors.New("rate must be greater than zero")
+	}
+
+	if burst <= 0 {
+		return nil, errors.New("burst must be greater than zero")
+	}
+
+	tb := TokenBucket{
+		rate:  rate,
+		burst: float64(burst),
+		now:   time.Now,
+		keys:  make(map[string]tokens),
+	}
+
+	return &tb, nil
+}
+
+// Take takes a token for the key. A zero duration means the attempt is
+// allowed, otherwise it's how long the key must wait for the next token.
+// The error is always nil, it's there so the bucket can be used where one
+// backed by a remote store is expected.
+func (tb *TokenBucket) Take(ctx context.Context, key string) (time.Duration, error) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	now := tb.now()
+	tb.sweep(now)
+
+	t, exists := tb.keys[key]
+	if !exists {
+		t = tokens{count: tb.burst, last: now}
+	}
+
+	t.count = min(tb.burst, t.count+now.Sub(t.last).Seconds()*tb.rate)
+	t.last = now
+
+	if t.count < 1 {
+		tb.keys[key] = t
+		return time.Duration(math.Ceil((1 - t.count) / tb.rate * float64(time.Second))), nil
+	}
+
+	t.count--
+	tb.keys[key] = t
+
+	return 0, nil
+}
+
+// refill returns how long an empty bucket takes to fill up.
+func (tb *TokenBucket) refill() time.Duration {
+	return time.Duration(tb.burst / tb.rate * float64(time.Second))
+}
+
+// sweep removes the keys whose bucket has filled up again so the set of
+// keys doesn't grow without bound.
+func (tb *TokenBucket) sweep(now time.Time) {
+	if now.Before(tb.nextSweep) {
+		return
+	}
+
+	refill := tb.refill()
+
+	for key, t := range tb.keys {
+		if now.Sub(t.last) >= refill {
+			delete(tb.keys, key)
+		}
+	}
+
+	tb.nextSweep = now.Add(refill)
+}
@@ -0,0 +1,51 @@
+package limiter_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/service/foundation/limiter"
+)
+
+func Test_TokenBucket(t *testing.T) {
+	ctx := context.Background()
+
+	tb, err := limiter.NewTokenBucket(10, 2)
+	if err != nil {
+		t.Fatalf("Should be able to create a token bucket : %s", err)
+	}
+
+	for i := range 2 {
+		if d, _ := tb.Take(ctx, "caller"); d != 0 {
+			t.Fatalf("Should allow attempt %d inside the burst : %s", i, d)
+		}
+	}
+
+	d, _ := tb.Take(ctx, "caller")
+	if d <= 0 || d > 100*time.Millisecond {
+		t.Fatalf("Should make an attempt over the burst wait for a token : %s", d)
+	}
+
+	if d, _ := tb.Take(ctx, "other"); d != 0 {
+		t.Fatalf("Should track each key separately : %s", d)
+	}
+
+	time.Sleep(110 * time.Millisecond)
+
+	if d, _ := tb.Take(ctx, "caller"); d != 0 {
+		t.Fatalf("Should allow an attempt once a token is refilled : %s", d)
+	}
+
+	if d, _ := tb.Take(ctx, "caller"); d == 0 {
+		t.Fatalf("Should only refill the tokens for the time that passed")
+	}
+
+	if _, err := limiter.NewTokenBucket(0, 1); err == nil {
+		t.Fatalf("Should not allow a zero rate")
+	}
+
+	if _, err := limiter.NewTokenBucket(1, 0); err == nil {
+		t.Fatalf("Should not allow a zero burst")
+	}
+}
@@ -0,0 +1,120 @@
+// Package limiter provides a fixed window rate limiter that tracks the
+// consumption of individual callers by key.
+package limiter
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Status represents the state of a caller's window at the time it was
+// inspected.
+type Status struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+	Allowed   bool
+}
+
+type window struct {
+	count int
+	reset time.Time
+}
+
+// Limiter allows up to a limit of requests per key inside each window.
+type Limiter struct {
+	limit     int
+	window    time.Duration
+	now       func() time.Time
+	mu        sync.Mutex
+	windows   map[string]window
+	nextSweep time.Time
+}
+
+// New constructs a limiter that allows limit requests per key in each window.
+func New(limit int, win time.Duration) (*Limiter, error) {
+	if limit <= 0 {
+		return nil, errors.New("limit must be greater than zero")
+	}
+
+	if win <= 0 {
+		return nil, errors.New("window must be greater than zero")
+	}
+
+	l := Limiter{
+		limit:   limit,
+		window:  win,
+		now:     time.Now,
+		windows: make(map[string]window),
+	}
+
+	return &l, nil
+}
+
+// Allow consumes a request for the specified key and reports whether the
+// request fits inside the current window.
+func (l *Limiter) Allow(key string) Status {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	w := l.current(key, now)
+	if w.count >= l.limit {
+		return l.status(w, false)
+	}
+
+	w.count++
+	l.windows[key] = w
+
+	return l.status(w, true)
+}
+
+// Peek returns the state of the current window for the specified key
+// without consuming a request.
+func (l *Limiter) Peek(key string) Status {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w := l.current(key, l.now())
+
+	return l.status(w, w.count < l.limit)
+}
+
+// current returns the window for the key, starting a new one when the
+// previous window has expired.
+func (l *Limiter) current(key string, now time.Time) window {
+	w, exists := l.windows[key]
+	if !exists || !now.Before(w.reset) {
+		return window{reset: now.Add(l.window)}
+	}
+
+	return w
+}
+
+func (l *Limiter) status(w window, allowed bool) Status {
+	return Status{
+		Limit:     l.limit,
+		Remaining: max(l.limit-w.count, 0),
+		Reset:     w.reset,
+		Allowed:   allowed,
+	}
+}
+
+// sweep removes the expired windows once per window so the set of keys
+// doesn't grow without bound.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Before(l.nextSweep) {
+		return
+	}
+
+	for key, w := range l.windows {
+		if !now.Before(w.reset) {
+			delete(l.windows, key)
+		}
+	}
+
+	l.nextSweep = now.Add(l.window)
+}
@@ -0,0 +1,55 @@
+package limiter_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/service/foundation/limiter"
+)
+
+func Test_Limiter(t *testing.T) {
+	l, err := limiter.New(2, 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Should be able to create a limiter : %s", err)
+	}
+
+	for i := range 2 {
+		s := l.Allow("caller")
+		if !s.Allowed {
+			t.Fatalf("Should allow request %d inside the limit", i)
+		}
+
+		if s.Remaining != 1-i {
+			t.Fatalf("Should have %d remaining, got %d", 1-i, s.Remaining)
+		}
+	}
+
+	if s := l.Allow("caller"); s.Allowed || s.Remaining != 0 {
+		t.Fatalf("Should reject a request over the limit : %+v", s)
+	}
+
+	if s := l.Allow("other"); !s.Allowed {
+		t.Fatalf("Should track each key separately : %+v", s)
+	}
+
+	s := l.Peek("caller")
+	if s.Allowed || s.Limit != 2 {
+		t.Fatalf("Should peek the exhausted window : %+v", s)
+	}
+
+	time.Sleep(time.Until(s.Reset))
+
+	if s := l.Peek("caller"); !s.Allowed || s.Remaining != 2 {
+		t.Fatalf("Should start a new window after the reset : %+v", s)
+	}
+}
+
+func Test_LimiterConfig(t *testing.T) {
+	if _, err := limiter.New(0, time.Second); err == nil {
+		t.Fatalf("Should not allow a zero limit")
+	}
+
+	if _, err := limiter.New(1, 0); err == nil {
+		t.Fatalf("Should not allow a zero window")
+	}
+}
@@ -0,0 +1,272 @@
+// Package redisbucket provides a token bucket kept in Redis, so every
+// instance of a service shares the same limits.
+package redisbucket
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// script takes a token from the bucket stored under the key and returns how
+// many milliseconds to wait for one when the bucket is empty. The time is
+// read from Redis so the instances don't need to agree on the clock.
+const script = `
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local b = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(b[1]) or burst
+local ts = tonumber(b[2]) or now
+
+tokens = math.min(burst, tokens + (now - ts) / 1000 * rate)
+
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+else
+	wait = math.ceil((1 - tokens) / rate * 1000)
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000))
+
+return wait
+`
+
+// Config represents the Redis server and the limits of the bucket.
+type Config struct {
+	Addr     string
+	Password string
+	DB       int
+
+	// Prefix is added to every key so several buckets can share a server.
+	Prefix string
+
+	// Rate is the number of tokens added to a bucket per second, and Burst
+	// the most tokens a bucket can hold.
+	Rate  float64
+	Burst int
+
+	// Timeout limits a call to Redis when the context has no deadline.
+	Timeout time.Duration
+}
+
+// Bucket manages token buckets kept in Redis. A single connection is used
+// and it's dialed again after an error.
+type Bucket struct {
+	cfg  Config
+	mu   sync.Mutex
+	conn net.Conn
+	rw   *bufio.ReadWriter
+}
+
+// New constructs a bucket for the Redis server. The server isn't contacted
+// until the first token is taken.
+func New(cfg Config) (*Bucket, error) {
+	if cfg.Addr == "" {
+		return nil, errors.New("addr is required")
+	}
+
+	if cfg.Rate <= 0 {
+		return nil, errors.New("rate must be greater than zero")
+	}
+
+	if cfg.Burst <= 0 {
+		return nil, errors.New("burst must be greater than zero")
+	}
+
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = time.Second
+	}
+
+	return &Bucket{cfg: cfg}, nil
+}
+
+// Take takes a token for the key. A zero duration means the attempt is
+// allowed, otherwise it's how long the key must wait for the next token.
+func (b *Bucket) Take(ctx context.Context, key string) (time.Duration, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	rate := strconv.FormatFloat(b.cfg.Rate, 'f', -1, 64)
+	burst := strconv.Itoa(b.cfg.Burst)
+
+	reply, err := b.do(ctx, "EVAL", script, "1", b.cfg.Prefix+key, rate, burst)
+	if err != nil {
+		return 0, fmt.Errorf("eval: %w", err)
+	}
+
+	ms, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("eval: unexpected reply %v", reply)
+	}
+
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+// Close closes the connection to the server.
+func (b *Bucket) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.close()
+}
+
+// =============================================================================
+
+// do sends the command and reads the reply, connecting first if needed. The
+// connection is dropped after any failure so the next call starts clean.
+func (b *Bucket) do(ctx context.Context, args ...string) (any, error) {
+	if b.conn == nil {
+		if err := b.connect(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(b.cfg.Timeout)
+	}
+
+	if err := b.conn.SetDeadline(deadline); err != nil {
+		b.close()
+		return nil, fmt.Errorf("set deadline: %w", err)
+	}
+
+	reply, err := b.roundTrip(args...)
+	if err != nil {
+		var rerr redisError
+		if !errors.As(err, &rerr) {
+			b.close()
+		}
+		return nil, err
+	}
+
+	return reply, nil
+}
+
+func (b *Bucket) connect(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, b.cfg.Timeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", b.cfg.Addr)
+	if err != nil {
+		return fmt.Errorf("dial: %w", err)
+	}
+
+	b.conn = conn
+	b.rw = bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+	if err := conn.SetDeadline(time.Now().Add(b.cfg.Timeout)); err != nil {
+		b.close()
+		return fmt.Errorf("set deadline: %w", err)
+	}
+
+	if b.cfg.Password != "" {
+		if _, err := b.roundTrip("AUTH", b.cfg.Password); err != nil {
+			b.close()
+			return fmt.Errorf("auth: %w", err)
+		}
+	}
+
+	if b.cfg.DB != 0 {
+		if _, err := b.roundTrip("SELECT", strconv.Itoa(b.cfg.DB)); err != nil {
+			b.close()
+			return fmt.Errorf("select: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (b *Bucket) close() error {
+	if b.conn == nil {
+		return nil
+	}
+
+	err := b.conn.Close()
+	b.conn = nil
+	b.rw = nil
+
+	return err
+}
+
+// roundTrip writes the command in the Redis protocol and reads the reply.
+func (b *Bucket) roundTrip(args ...string) (any, error) {
+	fmt.Fprintf(b.rw, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(b.rw, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+
+	if err := b.rw.Flush(); err != nil {
+		return nil, fmt.Errorf("write: %w", err)
+	}
+
+	return readReply(b.rw.Reader)
+}
+
+// redisError is an error reply from the server. The connection is still
+// usable after one.
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// readReply reads a single reply. Only the reply types the bucket gets back
+// are supported.
+func readReply(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("read: malformed reply %q", line)
+	}
+
+	kind, value := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return value, nil
+
+	case '-':
+		return nil, redisError(value)
+
+	case ':':
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("read: integer: %w", err)
+		}
+		return n, nil
+
+	case '$':
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("read: bulk length: %w", err)
+		}
+
+		if n < 0 {
+			return nil, nil
+		}
+
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, fmt.Errorf("read: bulk: %w", err)
+		}
+		return string(buf[:n]), nil
+	}
+
+	return nil, fmt.Errorf("read: unsupported reply type %q", kind)
+}
@@ -0,0 +1,133 @@
+package redisbucket_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/service/foundation/limiter/redisbucket"
+)
+
+func Test_Take(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Should be able to listen : %s", err)
+	}
+	defer ln.Close()
+
+	cmds := make(chan []string, 10)
+	replies := []string{"+OK\r\n", ":0\r\n", ":250\r\n", "-ERR wrong\r\n"}
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		for _, reply := range replies {
+			cmd, err := readCommand(r)
+			if err != nil {
+				return
+			}
+			cmds <- cmd
+
+			if _, err := io.WriteString(conn, reply); err != nil {
+				return
+			}
+		}
+	}()
+
+	b, err := redisbucket.New(redisbucket.Config{
+		Addr:     ln.Addr().String(),
+		Password: "secret",
+		Prefix:   "login:",
+		Rate:     0.5,
+		Burst:    5,
+		Timeout:  time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Should be able to create a bucket : %s", err)
+	}
+	defer b.Close()
+
+	ctx := context.Background()
+
+	d, err := b.Take(ctx, "email:bill@example.com")
+	if err != nil || d != 0 {
+		t.Fatalf("Should allow the attempt : %s %s", d, err)
+	}
+
+	if cmd := <-cmds; strings.Join(cmd, " ") != "AUTH secret" {
+		t.Fatalf("Should authenticate first : %q", cmd)
+	}
+
+	cmd := <-cmds
+	if len(cmd) != 6 || cmd[0] != "EVAL" || cmd[2] != "1" || cmd[3] != "login:email:bill@example.com" || cmd[4] != "0.5" || cmd[5] != "5" {
+		t.Fatalf("Should send the script with the key and limits : %q", cmd)
+	}
+
+	if d, err := b.Take(ctx, "email:bill@example.com"); err != nil || d != 250*time.Millisecond {
+		t.Fatalf("Should report the wait from the reply : %s %s", d, err)
+	}
+	<-cmds
+
+	if _, err := b.Take(ctx, "email:bill@example.com"); err == nil || !strings.Contains(err.Error(), "ERR wrong") {
+		t.Fatalf("Should return an error reply : %v", err)
+	}
+}
+
+func Test_Config(t *testing.T) {
+	if _, err := redisbucket.New(redisbucket.Config{Rate: 1, Burst: 1}); err == nil {
+		t.Fatalf("Should require an address")
+	}
+
+	if _, err := redisbucket.New(redisbucket.Config{Addr: "localhost:6379", Burst: 1}); err == nil {
+		t.Fatalf("Should not allow a zero rate")
+	}
+
+	if _, err := redisbucket.New(redisbucket.Config{Addr: "localhost:6379", Rate: 1}); err == nil {
+		t.Fatalf("Should not allow a zero burst")
+	}
+}
+
+// readCommand reads a command sent in the Redis protocol.
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, fmt.Errorf("array length: %w", err)
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, fmt.Errorf("bulk length: %w", err)
+		}
+
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+
+	return args, nil
+}
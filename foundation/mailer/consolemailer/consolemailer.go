@@ -0,0 +1,40 @@
+// Package consolemailer provides support for writing emails to the console
+// instead of delivering them. It's meant for development, the emails can
+// contain tokens and shouldn't end up in production logs.
+package consolemailer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/mail"
+	"sync"
+)
+
+// Mailer writes emails to a writer.
+type Mailer struct {
+	mu   sync.Mutex
+	w    io.Writer
+	from mail.Address
+}
+
+// New constructs a mailer that writes emails to the writer.
+func New(w io.Writer, from mail.Address) *Mailer {
+	return &Mailer{
+		w:    w,
+		from: from,
+	}
+}
+
+// Send writes the email to the writer.
+func (m *Mailer) Send(ctx context.Context, to mail.Address, subject string, body string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := fmt.Fprintf(m.w, "----- email -----\nFrom: %s\nTo: %s\nSubject: %s\n\n%s\n-----------------\n", m.from.String(), to.String(), subject, body)
+	if err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	return nil
+}
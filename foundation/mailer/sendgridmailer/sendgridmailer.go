@@ -0,0 +1,121 @@
+// Package sendgridmailer provides support for delivering emails through the
+// SendGrid v3 mail API.
+package sendgridmailer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+)
+
+// DefaultEndpoint is the address of the SendGrid mail send API.
+const DefaultEndpoint = "https://api.sendgrid.com/v3/mail/send"
+
+// Config represents the account emails are delivered through.
+type Config struct {
+	APIKey string
+	From   mail.Address
+
+	// Endpoint is the address of the mail send API. It's left empty for
+	// SendGrid.
+	Endpoint string
+}
+
+// Mailer delivers emails through SendGrid.
+type Mailer struct {
+	client   *http.Client
+	apiKey   string
+	from     mail.Address
+	endpoint string
+}
+
+// New constructs a mailer for the account.
+func New(client *http.Client, cfg Config) (*Mailer, error) {
+	if cfg.APIKey == "" {
+		return nil, errors.New("api key is required")
+	}
+
+	if cfg.From.Address == "" {
+		return nil, errors.New("from address is required")
+	}
+
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+
+	m := Mailer{
+		client:   client,
+		apiKey:   cfg.APIKey,
+		from:     cfg.From,
+		endpoint: endpoint,
+	}
+
+	return &m, nil
+}
+
+type address struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+type personalization struct {
+	To []address `json:"to"`
+}
+
+type content struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type message struct {
+	Personalizations []personalization `json:"personalizations"`
+	From             address           `json:"from"`
+	Subject          string            `json:"subject"`
+	Content          []content         `json:"content"`
+}
+
+// Send delivers the email with an HTML body.
+func (m *Mailer) Send(ctx context.Context, to mail.Address, subject string, body string) error {
+	msg := message{
+		Personalizations: []personalization{
+			{To: []address{{Email: to.Address, Name: to.Name}}},
+		},
+		From:    address{Email: m.from.Address, Name: m.from.Name},
+		Subject: subject,
+		Content: []content{
+			{Type: "text/html", Value: body},
+		},
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.endpoint, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+m.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("sendgrid responded with status %d: %s", resp.StatusCode, detail)
+	}
+
+	return nil
+}
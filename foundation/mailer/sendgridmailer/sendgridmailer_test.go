@@ -0,0 +1,72 @@
+package sendgridmailer_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"testing"
+
+	"github.com/ardanlabs/service/foundation/mailer/sendgridmailer"
+)
+
+func Test_Send(t *testing.T) {
+	var got struct {
+		Personalizations []struct {
+			To []struct {
+				Email string `json:"email"`
+			} `json:"to"`
+		} `json:"personalizations"`
+		Subject string `json:"subject"`
+		Content []struct {
+			Type  string `json:"type"`
+			Value string `json:"value"`
+		} `json:"content"`
+	}
+	var auth string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	m, err := sendgridmailer.New(srv.Client(), sendgridmailer.Config{
+		APIKey:   "SG.key",
+		From:     mail.Address{Name: "Sales", Address: "no-reply@example.com"},
+		Endpoint: srv.URL,
+	})
+	if err != nil {
+		t.Fatalf("Should be able to create a mailer : %s", err)
+	}
+
+	to := mail.Address{Address: "bill@example.com"}
+	if err := m.Send(context.Background(), to, "Hello", "<p>Hi</p>"); err != nil {
+		t.Fatalf("Should be able to send : %s", err)
+	}
+
+	if auth != "Bearer SG.key" {
+		t.Fatalf("Should send the api key : got %q", auth)
+	}
+
+	if len(got.Personalizations) != 1 || got.Personalizations[0].To[0].Email != to.Address {
+		t.Fatalf("Should send to the recipient : got %+v", got.Personalizations)
+	}
+
+	if got.Subject != "Hello" || len(got.Content) != 1 || got.Content[0].Type != "text/html" || got.Content[0].Value != "<p>Hi</p>" {
+		t.Fatalf("Should send the subject and html body : got %+v", got)
+	}
+
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+
+	if err := m.Send(context.Background(), to, "Hello", "<p>Hi</p>"); err == nil {
+		t.Fatal("Should fail when sendgrid rejects the request")
+	}
+}
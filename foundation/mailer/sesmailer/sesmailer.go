@@ -0,0 +1,134 @@
+// Package sesmailer provides support for delivering emails through the
+// Amazon SES v2 API. Requests are signed with the SDK's signer so only the
+// core SDK module is needed.
+package sesmailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+// Config represents the account and region emails are delivered through.
+type Config struct {
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	From            mail.Address
+
+	// Endpoint is the address of an SES compatible service. It's left empty
+	// for Amazon SES.
+	Endpoint string
+}
+
+// Mailer delivers emails through SES.
+type Mailer struct {
+	client   *http.Client
+	signer   *v4.Signer
+	creds    aws.Credentials
+	region   string
+	from     mail.Address
+	endpoint string
+}
+
+// New constructs a mailer for the account.
+func New(client *http.Client, cfg Config) (*Mailer, error) {
+	if cfg.Region == "" {
+		return nil, errors.New("region is required")
+	}
+
+	if cfg.From.Address == "" {
+		return nil, errors.New("from address is required")
+	}
+
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://email.%s.amazonaws.com", cfg.Region)
+	}
+
+	m := Mailer{
+		client: client,
+		signer: v4.NewSigner(),
+		creds: aws.Credentials{
+			AccessKeyID:     cfg.AccessKeyID,
+			SecretAccessKey: cfg.SecretAccessKey,
+			Source:          "sesmailer",
+		},
+		region:   cfg.Region,
+		from:     cfg.From,
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+	}
+
+	return &m, nil
+}
+
+type text struct {
+	Data    string `json:"Data"`
+	Charset string `json:"Charset"`
+}
+
+type message struct {
+	FromEmailAddress string `json:"FromEmailAddress"`
+	Destination      struct {
+		ToAddresses []string `json:"ToAddresses"`
+	} `json:"Destination"`
+	Content struct {
+		Simple struct {
+			Subject text `json:"Subject"`
+			Body    struct {
+				HTML text `json:"Html"`
+			} `json:"Body"`
+		} `json:"Simple"`
+	} `json:"Content"`
+}
+
+// Send delivers the email with an HTML body.
+func (m *Mailer) Send(ctx context.Context, to mail.Address, subject string, body string) error {
+	var msg message
+	msg.FromEmailAddress = m.from.String()
+	msg.Destination.ToAddresses = []string{to.String()}
+	msg.Content.Simple.Subject = text{Data: subject, Charset: "UTF-8"}
+	msg.Content.Simple.Body.HTML = text{Data: body, Charset: "UTF-8"}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.endpoint+"/v2/email/outbound-emails", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	sum := sha256.Sum256(data)
+	if err := m.signer.SignHTTP(ctx, m.creds, req, hex.EncodeToString(sum[:]), "ses", m.region, time.Now()); err != nil {
+		return fmt.Errorf("sign: %w", err)
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("ses responded with status %d: %s", resp.StatusCode, detail)
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package sesmailer_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/ardanlabs/service/foundation/mailer/sesmailer"
+)
+
+func Test_Send(t *testing.T) {
+	var got struct {
+		FromEmailAddress string
+		Destination      struct {
+			ToAddresses []string
+		}
+	}
+	var path, auth string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		auth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	m, err := sesmailer.New(srv.Client(), sesmailer.Config{
+		Region:          "us-east-1",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+		From:            mail.Address{Address: "no-reply@example.com"},
+		Endpoint:        srv.URL,
+	})
+	if err != nil {
+		t.Fatalf("Should be able to create a mailer : %s", err)
+	}
+
+	if err := m.Send(context.Background(), mail.Address{Address: "bill@example.com"}, "Hello", "<p>Hi</p>"); err != nil {
+		t.Fatalf("Should be able to send : %s", err)
+	}
+
+	if path != "/v2/email/outbound-emails" {
+		t.Fatalf("Should post to the send email api : got %s", path)
+	}
+
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(auth, "/us-east-1/ses/aws4_request") {
+		t.Fatalf("Should sign the request for ses : got %q", auth)
+	}
+
+	if got.FromEmailAddress != "<no-reply@example.com>" || len(got.Destination.ToAddresses) != 1 {
+		t.Fatalf("Should send from and to the addresses : got %+v", got)
+	}
+}
@@ -0,0 +1,114 @@
+// Package smtpmailer provides support for delivering emails through an SMTP
+// server.
+package smtpmailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// Config represents the server emails are delivered through.
+type Config struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     mail.Address
+}
+
+// Mailer delivers emails through an SMTP server.
+type Mailer struct {
+	addr string
+	host string
+	auth smtp.Auth
+	from mail.Address
+}
+
+// New constructs a mailer for the server. The server is asked to upgrade
+// the connection with STARTTLS when it supports it, credentials are only
+// sent over TLS or to localhost.
+func New(cfg Config) (*Mailer, error) {
+	if cfg.Host == "" {
+		return nil, errors.New("host is required")
+	}
+
+	if cfg.From.Address == "" {
+		return nil, errors.New("from address is required")
+	}
+
+	port := cfg.Port
+	if port == 0 {
+		port = 587
+	}
+
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+
+	m := Mailer{
+		addr: net.JoinHostPort(cfg.Host, strconv.Itoa(port)),
+		host: cfg.Host,
+		auth: auth,
+		from: cfg.From,
+	}
+
+	return &m, nil
+}
+
+// Send delivers the email with an HTML body. The context isn't used by the
+// standard library client, the server's timeouts apply.
+func (m *Mailer) Send(ctx context.Context, to mail.Address, subject string, body string) error {
+	msg, err := m.message(to, subject, body)
+	if err != nil {
+		return err
+	}
+
+	if err := smtp.SendMail(m.addr, m.auth, m.from.Address, []string{to.Address}, msg); err != nil {
+		return fmt.Errorf("sendmail: %w", err)
+	}
+
+	return nil
+}
+
+// message builds the MIME message. The body is base64 encoded so long lines
+// and non-ASCII text survive every server.
+func (m *Mailer) message(to mail.Address, subject string, body string) ([]byte, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("read random: %w", err)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", m.from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", to.String())
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@%s>\r\n", hex.EncodeToString(id), m.host)
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/html; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: base64\r\n")
+	buf.WriteString("\r\n")
+
+	encoded := base64.StdEncoding.EncodeToString([]byte(body))
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76])
+		buf.WriteString("\r\n")
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded)
+	buf.WriteString("\r\n")
+
+	return buf.Bytes(), nil
+}
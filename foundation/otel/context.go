@@ -3,30 +3,47 @@ package otel
 import (
 	"context"
 
+	"github.com/ardanlabs/service/foundation/ctxval"
 	"go.opentelemetry.io/otel/trace"
 )
 
-type ctxKey int
-
-const (
-	tracerKey ctxKey = iota + 1
-	traceIDKey
+var (
+	tracerKey  = ctxval.NewKey[trace.Tracer]("tracer")
+	traceIDKey = ctxval.NewKey[string]("trace id")
 )
 
 func setTracer(ctx context.Context, tracer trace.Tracer) context.Context {
-	return context.WithValue(ctx, tracerKey, tracer)
+	return tracerKey.Set(ctx, tracer)
 }
 
 func setTraceID(ctx context.Context, traceID string) context.Context {
-	return context.WithValue(ctx, traceIDKey, traceID)
+	return traceIDKey.Set(ctx, traceID)
 }
 
 // GetTraceID returns the trace id from the context.
 func GetTraceID(ctx context.Context) string {
-	v, ok := ctx.Value(traceIDKey).(string)
+	v, ok := traceIDKey.Get(ctx)
 	if !ok {
 		return "00000000000000000000000000000000"
 	}
 
 	return v
 }
+
+// Detach returns a new context that carries only the trace information of
+// the specified context: the tracer, the trace id and the current span. Work
+// that outlives the call that started it runs with it so it joins the trace
+// without holding on to the other values of the caller's context.
+func Detach(ctx context.Context) context.Context {
+	detached := trace.ContextWithSpanContext(context.Background(), trace.SpanContextFromContext(ctx))
+
+	if v, ok := tracerKey.Get(ctx); ok {
+		detached = setTracer(detached, v)
+	}
+
+	if v, ok := traceIDKey.Get(ctx); ok {
+		detached = setTraceID(detached, v)
+	}
+
+	return detached
+}
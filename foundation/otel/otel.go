@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/ardanlabs/service/foundation/diag"
 	"github.com/ardanlabs/service/foundation/logger"
 	"github.com/google/uuid"
 	"go.opentelemetry.io/otel"
@@ -108,17 +109,86 @@ func InjectTracing(ctx context.Context, tracer trace.Tracer) context.Context {
 	return ctx
 }
 
-// AddSpan adds an otel span to the existing trace.
+// AddSpan adds an otel span to the existing trace. If diagnostics are being
+// collected for the request, the span is also recorded there.
 func AddSpan(ctx context.Context, spanName string, keyValues ...attribute.KeyValue) (context.Context, trace.Span) {
-	v, ok := ctx.Value(tracerKey).(trace.Tracer)
+	return startSpan(ctx, spanName, keyValues)
+}
+
+// AddProducerSpan adds an otel span to the existing trace that represents
+// work being handed off to be processed asynchronously. The span context of
+// the returned span should travel with the work so the consumer can link
+// back to it.
+func AddProducerSpan(ctx context.Context, spanName string, keyValues ...attribute.KeyValue) (context.Context, trace.Span) {
+	return startSpan(ctx, spanName, keyValues, trace.WithSpanKind(trace.SpanKindProducer))
+}
+
+// AddConsumerSpan adds an otel span that represents the processing of work
+// handed off by a producer. The span is linked to the producer's span context
+// so the asynchronous fan-out can be followed from the original trace.
+func AddConsumerSpan(ctx context.Context, producer trace.SpanContext, spanName string, keyValues ...attribute.KeyValue) (context.Context, trace.Span) {
+	opts := []trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindConsumer),
+	}
+
+	if producer.IsValid() {
+		opts = append(opts, trace.WithLinks(trace.Link{SpanContext: producer}))
+	}
+
+	return startSpan(ctx, spanName, keyValues, opts...)
+}
+
+// InjectCarrier returns the trace information for the context in a form that
+// can be stored or transmitted with work that crosses a process boundary.
+func InjectCarrier(ctx context.Context) map[string]string {
+	carrier := make(propagation.MapCarrier)
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+
+	return carrier
+}
+
+// ExtractCarrier returns the span context stored in the carrier produced by
+// InjectCarrier. The span context can be used to link a consumer span.
+func ExtractCarrier(carrier map[string]string) trace.SpanContext {
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.MapCarrier(carrier))
+
+	return trace.SpanContextFromContext(ctx)
+}
+
+func startSpan(ctx context.Context, spanName string, keyValues []attribute.KeyValue, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	v, ok := tracerKey.Get(ctx)
 	if !ok || v == nil {
-		return ctx, trace.SpanFromContext(ctx)
+		return ctx, newDiagSpan(ctx, spanName, trace.SpanFromContext(ctx))
 	}
 
-	ctx, span := v.Start(ctx, spanName)
+	ctx, span := v.Start(ctx, spanName, opts...)
 	span.SetAttributes(keyValues...)
 
-	return ctx, span
+	return ctx, newDiagSpan(ctx, spanName, span)
+}
+
+// diagSpan wraps a span so the end of the span can be recorded in the
+// request diagnostics.
+type diagSpan struct {
+	trace.Span
+	end func()
+}
+
+func newDiagSpan(ctx context.Context, spanName string, span trace.Span) trace.Span {
+	if !diag.Enabled(ctx) {
+		return span
+	}
+
+	return &diagSpan{
+		Span: span,
+		end:  diag.AddSpan(ctx, spanName),
+	}
+}
+
+// End implements the trace.Span interface.
+func (s *diagSpan) End(options ...trace.SpanEndOption) {
+	s.end()
+	s.Span.End(options...)
 }
 
 // AddTraceToRequest adds the current trace id to the request so it
@@ -0,0 +1,124 @@
+package otel_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/ardanlabs/service/foundation/otel"
+	gotel "go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recorder keeps the spans that ended.
+type recorder struct {
+	mu    sync.Mutex
+	spans []sdktrace.ReadOnlySpan
+}
+
+func (r *recorder) OnStart(context.Context, sdktrace.ReadWriteSpan) {}
+func (r *recorder) Shutdown(context.Context) error                  { return nil }
+func (r *recorder) ForceFlush(context.Context) error                { return nil }
+
+func (r *recorder) OnEnd(s sdktrace.ReadOnlySpan) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.spans = append(r.spans, s)
+}
+
+func (r *recorder) span(name string) (sdktrace.ReadOnlySpan, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, s := range r.spans {
+		if s.Name() == name {
+			return s, true
+		}
+	}
+
+	return nil, false
+}
+
+func traced(t *testing.T) (context.Context, *recorder) {
+	gotel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var rec recorder
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(&rec))
+	t.Cleanup(func() { tp.Shutdown(context.Background()) })
+
+	ctx := otel.InjectTracing(context.Background(), tp.Tracer("test"))
+
+	return ctx, &rec
+}
+
+// =============================================================================
+
+func Test_Carrier(t *testing.T) {
+	ctx, _ := traced(t)
+
+	ctx, span := otel.AddProducerSpan(ctx, "producer")
+	defer span.End()
+
+	carrier := otel.InjectCarrier(ctx)
+	if carrier["traceparent"] == "" {
+		t.Fatalf("Should carry the trace parent: %v", carrier)
+	}
+
+	sc := otel.ExtractCarrier(carrier)
+	if !sc.Equal(span.SpanContext().WithRemote(true)) {
+		t.Fatalf("Should extract the producer span context: got %v, exp %v", sc, span.SpanContext())
+	}
+
+	if sc := otel.ExtractCarrier(nil); sc.IsValid() {
+		t.Fatalf("Should not extract a span context from an empty carrier: %v", sc)
+	}
+}
+
+func Test_Detach(t *testing.T) {
+	type key struct{}
+
+	ctx, rec := traced(t)
+
+	ctx, span := otel.AddSpan(ctx, "request")
+	defer span.End()
+
+	ctx = context.WithValue(ctx, key{}, "value")
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+
+	detached := otel.Detach(ctx)
+
+	if detached.Value(key{}) != nil {
+		t.Fatalf("Should not keep the values of the context")
+	}
+
+	if detached.Err() != nil {
+		t.Fatalf("Should not keep the cancellation of the context: %v", detached.Err())
+	}
+
+	if got, exp := otel.GetTraceID(detached), otel.GetTraceID(ctx); got != exp {
+		t.Fatalf("Should keep the trace id: got %s, exp %s", got, exp)
+	}
+
+	if sc := trace.SpanContextFromContext(detached); !sc.Equal(span.SpanContext()) {
+		t.Fatalf("Should keep the span context: got %v, exp %v", sc, span.SpanContext())
+	}
+
+	// The tracer is kept so spans started from the new context are
+	// recorded in the same trace.
+	_, child := otel.AddSpan(detached, "child")
+	child.End()
+
+	s, ok := rec.span("child")
+	if !ok {
+		t.Fatalf("Should record the span started from the detached context")
+	}
+
+	if s.Parent().SpanID() != span.SpanContext().SpanID() {
+		t.Fatalf("Should parent the span to the caller's span: got %s, exp %s", s.Parent().SpanID(), span.SpanContext().SpanID())
+	}
+}
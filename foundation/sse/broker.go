@@ -0,0 +1,155 @@
+package sse
+
+import (
+	"sync"
+)
+
+// Item represents a value published to the broker along with the id it was
+// given. Ids increase by one with each value published.
+type Item[T any] struct {
+	ID    uint64
+	Value T
+}
+
+// Broker fans the values published to it out to its subscribers and keeps
+// the most recent ones so subscribers can catch up after reconnecting.
+// Publishing never waits on a subscriber. One that falls a full buffer
+// behind is closed so its client reconnects and catches up from the
+// history instead of holding the others back.
+type Broker[T any] struct {
+	mu      sync.Mutex
+	lastID  uint64
+	history []Item[T]
+	size    int
+	buffer  int
+	subs    map[*Subscription[T]]struct{}
+	closed  bool
+}
+
+// NewBroker constructs a broker that keeps the specified number of values
+// for catching up and buffers up to buffer values for each subscriber.
+func NewBroker[T any](history int, buffer int) *Broker[T] {
+	return &Broker[T]{
+		size:   history,
+		buffer: buffer,
+		subs:   make(map[*Subscription[T]]struct{}),
+	}
+}
+
+// Publish sends the value to every subscriber.
+func (b *Broker[T]) Publish(v T) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	item := Item[T]{ID: b.lastID, Value: v}
+
+	if b.size > 0 {
+		if len(b.history) == b.size {
+			b.history = append(b.history[:0], b.history[1:]...)
+		}
+		b.history = append(b.history, item)
+	}
+
+	for sub := range b.subs {
+		select {
+		case sub.ch <- item:
+		default:
+			b.remove(sub)
+		}
+	}
+}
+
+// Subscribe adds a subscriber. When lastID is set, the values published
+// after it that are still in the history are sent first. Missed reports
+// whether values were published after lastID that are no longer held, or
+// lastID wasn't given by this broker, so the subscriber knows it has a gap.
+func (b *Broker[T]) Subscribe(lastID uint64) (sub *Subscription[T], missed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var replay []Item[T]
+	switch {
+	case lastID > b.lastID:
+		missed = true
+
+	case lastID > 0 && lastID < b.lastID:
+		for _, item := range b.history {
+			if item.ID > lastID {
+				replay = append(replay, item)
+			}
+		}
+
+		missed = len(replay) == 0 || replay[0].ID != lastID+1
+	}
+
+	sub = &Subscription[T]{
+		broker: b,
+		ch:     make(chan Item[T], max(b.buffer, len(replay))),
+	}
+
+	for _, item := range replay {
+		sub.ch <- item
+	}
+
+	if b.closed {
+		close(sub.ch)
+		return sub, missed
+	}
+
+	b.subs[sub] = struct{}{}
+
+	return sub, missed
+}
+
+// LastID returns the id of the last value published.
+func (b *Broker[T]) LastID() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.lastID
+}
+
+// Close closes every subscription. Subscriptions added afterwards are
+// closed once the history has been replayed.
+func (b *Broker[T]) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+
+	for sub := range b.subs {
+		b.remove(sub)
+	}
+}
+
+func (b *Broker[T]) remove(sub *Subscription[T]) {
+	if _, exists := b.subs[sub]; !exists {
+		return
+	}
+
+	delete(b.subs, sub)
+	close(sub.ch)
+}
+
+// =============================================================================
+
+// Subscription represents a subscriber to a broker.
+type Subscription[T any] struct {
+	broker *Broker[T]
+	ch     chan Item[T]
+}
+
+// C returns the channel the values are received on. It's closed when the
+// subscription is closed or falls too far behind.
+func (s *Subscription[T]) C() <-chan Item[T] {
+	return s.ch
+}
+
+// Close removes the subscriber from the broker.
+func (s *Subscription[T]) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+
+	s.broker.remove(s)
+}
@@ -0,0 +1,121 @@
+// Package sse provides support for streaming server-sent events to
+// browsers. Clients that lose the stream reconnect with the id of the last
+// event they saw, and the broker replays what they missed from its history.
+package sse
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event represents a single event sent to the client.
+type Event struct {
+	ID   string
+	Name string
+	Data []byte
+}
+
+// Write writes the event in the text/event-stream format. Data holding
+// several lines is sent as several data fields, which the client joins
+// back together.
+func Write(w io.Writer, e Event) error {
+	var b strings.Builder
+
+	if e.ID != "" {
+		b.WriteString("id: " + e.ID + "\n")
+	}
+
+	if e.Name != "" {
+		b.WriteString("event: " + e.Name + "\n")
+	}
+
+	for line := range strings.SplitSeq(string(e.Data), "\n") {
+		b.WriteString("data: " + strings.TrimSuffix(line, "\r") + "\n")
+	}
+
+	b.WriteString("\n")
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// Stream writes events to a client that's connected for a stream.
+type Stream struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+// Start sends the headers for a stream. The controller is used to lift the
+// write deadline of the server, since a stream stays open far longer than a
+// response is expected to take. The retry tells the client how long to wait
+// before reconnecting.
+func Start(w http.ResponseWriter, rc *http.ResponseController, retry time.Duration) (*Stream, error) {
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return nil, fmt.Errorf("write deadline: %w", err)
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no")
+
+	w.WriteHeader(http.StatusOK)
+
+	s := Stream{
+		w:  w,
+		rc: rc,
+	}
+
+	if _, err := io.WriteString(w, "retry: "+strconv.FormatInt(retry.Milliseconds(), 10)+"\n\n"); err != nil {
+		return nil, fmt.Errorf("write: %w", err)
+	}
+
+	if err := s.flush(); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// Send writes the event and flushes it to the client.
+func (s *Stream) Send(e Event) error {
+	if err := Write(s.w, e); err != nil {
+		return err
+	}
+
+	return s.flush()
+}
+
+// Ping writes a comment, which clients ignore. It keeps proxies from
+// closing a stream that's idle.
+func (s *Stream) Ping() error {
+	if _, err := io.WriteString(s.w, ": ping\n\n"); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	return s.flush()
+}
+
+func (s *Stream) flush() error {
+	if f, ok := s.w.(http.Flusher); ok {
+		f.Flush()
+		return nil
+	}
+
+	if err := s.rc.Flush(); err != nil {
+		return fmt.Errorf("flush: %w", err)
+	}
+
+	return nil
+}
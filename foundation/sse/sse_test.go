@@ -0,0 +1,76 @@
+package sse_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ardanlabs/service/foundation/sse"
+)
+
+func Test_Write(t *testing.T) {
+	var b bytes.Buffer
+
+	e := sse.Event{
+		ID:   "7",
+		Name: "user.updated",
+		Data: []byte("line one\nline two"),
+	}
+
+	if err := sse.Write(&b, e); err != nil {
+		t.Fatalf("Should be able to write the event : %s", err)
+	}
+
+	exp := "id: 7\nevent: user.updated\ndata: line one\ndata: line two\n\n"
+	if b.String() != exp {
+		t.Errorf("expected %q, got %q", exp, b.String())
+	}
+}
+
+func Test_Broker(t *testing.T) {
+	b := sse.NewBroker[string](3, 2)
+
+	sub, missed := b.Subscribe(0)
+	if missed {
+		t.Fatalf("expected a new subscriber to have nothing missed")
+	}
+
+	b.Publish("a")
+	b.Publish("b")
+
+	for _, exp := range []string{"a", "b"} {
+		if item := <-sub.C(); item.Value != exp {
+			t.Fatalf("expected %s, got %s", exp, item.Value)
+		}
+	}
+
+	// The buffer holds two values so the third one published without the
+	// subscriber reading drops it.
+	b.Publish("c")
+	b.Publish("d")
+	b.Publish("e")
+
+	for range sub.C() {
+	}
+
+	// The history holds c, d and e. Catching up after b replays all of
+	// them, catching up after a has lost b.
+	sub, missed = b.Subscribe(2)
+	if missed {
+		t.Fatalf("expected nothing missed after id 2")
+	}
+
+	for _, exp := range []string{"c", "d", "e"} {
+		if item := <-sub.C(); item.Value != exp {
+			t.Fatalf("expected %s, got %s", exp, item.Value)
+		}
+	}
+	sub.Close()
+
+	if _, missed := b.Subscribe(1); !missed {
+		t.Errorf("expected values to be missed after id 1")
+	}
+
+	if _, missed := b.Subscribe(99); !missed {
+		t.Errorf("expected values to be missed for an unknown id")
+	}
+}
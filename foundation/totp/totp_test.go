@@ -0,0 +1,79 @@
+package totp_test
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/service/foundation/totp"
+)
+
+// secret is the SHA1 key from the RFC 6238 test vectors.
+var secret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func Test_Code(t *testing.T) {
+	// The RFC lists 8 digit codes, these are the last 6 digits of each.
+	tt := []struct {
+		unix int64
+		exp  string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tst := range tt {
+		got, err := totp.Code(secret, time.Unix(tst.unix, 0))
+		if err != nil {
+			t.Fatalf("Should be able to generate a code : %s", err)
+		}
+
+		if got != tst.exp {
+			t.Errorf("Should get the RFC code for %d : got %s, exp %s", tst.unix, got, tst.exp)
+		}
+	}
+}
+
+func Test_Validate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+
+	code, err := totp.Code(secret, now)
+	if err != nil {
+		t.Fatalf("Should be able to generate a code : %s", err)
+	}
+
+	if !totp.Validate(secret, code, now) {
+		t.Fatalf("Should accept the current code")
+	}
+
+	if !totp.Validate(secret, code, now.Add(30*time.Second)) {
+		t.Fatalf("Should accept the code from the previous period")
+	}
+
+	if totp.Validate(secret, code, now.Add(90*time.Second)) {
+		t.Fatalf("Should reject a code outside the allowed drift")
+	}
+
+	if totp.Validate(secret, "12345", now) {
+		t.Fatalf("Should reject a code of the wrong length")
+	}
+}
+
+func Test_Secret(t *testing.T) {
+	s, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatalf("Should be able to generate a secret : %s", err)
+	}
+
+	if _, err := totp.Code(s, time.Now()); err != nil {
+		t.Fatalf("Should be able to use the generated secret : %s", err)
+	}
+
+	u := totp.URL("service", "bill@example.com", s)
+	if !strings.HasPrefix(u, "otpauth://totp/") || !strings.Contains(u, "secret="+s) {
+		t.Fatalf("Should get an otpauth url : %s", u)
+	}
+}
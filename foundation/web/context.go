@@ -4,23 +4,23 @@ import (
 	"context"
 	"net/http"
 
+	"github.com/ardanlabs/service/foundation/ctxval"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 )
 
-type ctxKey int
-
-const (
-	tracerKey ctxKey = iota + 1
-	writerKey
+var (
+	tracerKey = ctxval.NewKey[trace.Tracer]("tracer")
+	writerKey = ctxval.NewKey[http.ResponseWriter]("writer")
+	connKey   = ctxval.NewKey[*http.ResponseController]("conn")
 )
 
 func setTracer(ctx context.Context, tracer trace.Tracer) context.Context {
-	return context.WithValue(ctx, tracerKey, tracer)
+	return tracerKey.Set(ctx, tracer)
 }
 
 func addSpan(ctx context.Context, spanName string, keyValues ...attribute.KeyValue) (context.Context, trace.Span) {
-	v, ok := ctx.Value(tracerKey).(trace.Tracer)
+	v, ok := tracerKey.Get(ctx)
 	if !ok || v == nil {
 		return ctx, trace.SpanFromContext(ctx)
 	}
@@ -32,15 +32,26 @@ func addSpan(ctx context.Context, spanName string, keyValues ...attribute.KeyVal
 }
 
 func setWriter(ctx context.Context, w http.ResponseWriter) context.Context {
-	return context.WithValue(ctx, writerKey, w)
+	return writerKey.Set(ctx, w)
 }
 
 // GetWriter returns the underlying writer for the request.
 func GetWriter(ctx context.Context) http.ResponseWriter {
-	v, ok := ctx.Value(writerKey).(http.ResponseWriter)
-	if !ok {
-		return nil
+	return writerKey.Value(ctx)
+}
+
+func setConn(ctx context.Context, rc *http.ResponseController) context.Context {
+	return connKey.Set(ctx, rc)
+}
+
+// GetConn returns a controller for the connection the request came in on.
+// The writer returned by GetWriter is wrapped by the tracing support in a
+// way that hides the connection, so handlers that stream responses use this
+// to lift the server's write deadline.
+func GetConn(ctx context.Context) *http.ResponseController {
+	if rc, ok := connKey.Get(ctx); ok {
+		return rc
 	}
 
-	return v
+	return http.NewResponseController(GetWriter(ctx))
 }
@@ -8,6 +8,7 @@ import (
 	"io/fs"
 	"net/http"
 	"regexp"
+	"slices"
 
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.opentelemetry.io/otel"
@@ -39,6 +40,13 @@ type App struct {
 	otmux   http.Handler
 	mw      []MidFunc
 	origins []string
+	routes  []Route
+}
+
+// Route represents a method and path pair handled by the app.
+type Route struct {
+	Method string
+	Path   string
 }
 
 // NewApp creates an App value that handle a set of routes for the application.
@@ -66,6 +74,7 @@ func NewApp(log Logger, tracer trace.Tracer, mw ...MidFunc) *App {
 // tracing. The opentelemetry mux then calls the application mux to handle
 // application traffic. This was set up in the NewApp function.
 func (a *App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r = r.WithContext(setConn(r.Context(), http.NewResponseController(w)))
 	a.otmux.ServeHTTP(w, r)
 }
 
@@ -133,6 +142,7 @@ func (a *App) HandlerFuncNoMid(method string, group string, path string, handler
 	if group != "" {
 		finalPath = "/" + group + path
 	}
+	a.routes = append(a.routes, Route{Method: method, Path: finalPath})
 	finalPath = fmt.Sprintf("%s %s", method, finalPath)
 
 	a.mux.HandleFunc(finalPath, h)
@@ -166,6 +176,7 @@ func (a *App) HandlerFunc(method string, group string, path string, handlerFunc
 	if group != "" {
 		finalPath = "/" + group + path
 	}
+	a.routes = append(a.routes, Route{Method: method, Path: finalPath})
 	finalPath = fmt.Sprintf("%s %s", method, finalPath)
 
 	a.mux.HandleFunc(finalPath, h)
@@ -200,11 +211,18 @@ func (a *App) RawHandlerFunc(method string, group string, path string, rawHandle
 	if group != "" {
 		finalPath = "/" + group + path
 	}
+	a.routes = append(a.routes, Route{Method: method, Path: finalPath})
 	finalPath = fmt.Sprintf("%s %s", method, finalPath)
 
 	a.mux.HandleFunc(finalPath, h)
 }
 
+// Routes returns the method and path pairs that have been added to the app
+// in the order they were added. File servers aren't included.
+func (a *App) Routes() []Route {
+	return slices.Clone(a.routes)
+}
+
 // FileServerReact starts a file server based on the specified file system and
 // directory inside that file system for a statically built react webapp.
 func (a *App) FileServerReact(static embed.FS, dir string, path string) error {
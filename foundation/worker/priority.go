@@ -0,0 +1,260 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Priority represents the lane a job is scheduled in. Jobs in a higher
+// priority lane are given a free slot before jobs in a lower one.
+type Priority int
+
+// Set of priorities supported by the worker.
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+)
+
+const numPriorities = int(PriorityHigh) + 1
+
+// String implements the fmt.Stringer interface.
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityNormal:
+		return "normal"
+	case PriorityHigh:
+		return "high"
+	}
+
+	return "unknown"
+}
+
+func (p Priority) valid() bool {
+	return p >= PriorityLow && p <= PriorityHigh
+}
+
+// =============================================================================
+
+// Options represents optional parameters for constructing a worker.
+type Options struct {
+	reserved [numPriorities]int
+	maxWait  time.Duration
+}
+
+// WithReserved adds slots that can only be used by jobs of the specified
+// priority. These slots are in addition to the shared slots, so a lane
+// always has capacity no matter how busy the other lanes are.
+func WithReserved(priority Priority, slots int) func(opts *Options) {
+	return func(opts *Options) {
+		if priority.valid() && slots > 0 {
+			opts.reserved[priority] = slots
+		}
+	}
+}
+
+// WithMaxWait sets how long a job can wait for a shared slot before it's
+// given the next slot regardless of its priority. This prevents low
+// priority jobs from starving when the higher lanes are always busy. The
+// default of zero disables starvation protection.
+func WithMaxWait(d time.Duration) func(opts *Options) {
+	return func(opts *Options) {
+		opts.maxWait = d
+	}
+}
+
+// =============================================================================
+
+// waiter represents a job waiting for a slot.
+type waiter struct {
+	priority Priority
+	enqueued time.Time
+	ready    chan struct{}
+	slot     slot
+}
+
+// slot represents a unit of capacity that was given to a job. A reserved
+// slot belongs to a single lane, a shared slot can be used by any lane.
+type slot struct {
+	lane     Priority
+	reserved bool
+}
+
+// scheduler hands out slots to jobs based on their priority.
+type scheduler struct {
+	mu       sync.Mutex
+	shared   int
+	reserved [numPriorities]int
+	maxWait  time.Duration
+	waiting  [numPriorities][]*waiter
+}
+
+func newScheduler(shared int, opts Options) *scheduler {
+	return &scheduler{
+		shared:   shared,
+		reserved: opts.reserved,
+		maxWait:  opts.maxWait,
+	}
+}
+
+// acquire blocks until a slot is available for the specified priority, the
+// context is done, or the worker is shutting down.
+func (s *scheduler) acquire(ctx context.Context, priority Priority, isShutdown <-chan struct{}) (slot, error) {
+	s.mu.Lock()
+
+	if s.reserved[priority] > 0 {
+		s.reserved[priority]--
+		s.mu.Unlock()
+		return slot{lane: priority, reserved: true}, nil
+	}
+
+	if s.shared > 0 && !s.hasWaiters() {
+		s.shared--
+		s.mu.Unlock()
+		return slot{lane: priority}, nil
+	}
+
+	w := waiter{
+		priority: priority,
+		enqueued: time.Now(),
+		ready:    make(chan struct{}),
+	}
+	s.waiting[priority] = append(s.waiting[priority], &w)
+
+	s.mu.Unlock()
+
+	// The shutdown is first to handle that event as priority.
+	var err error
+	select {
+	case <-isShutdown:
+		err = errors.New("shutting down")
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-w.ready:
+		return w.slot, nil
+	}
+
+	// The slot might have been handed to us while we were giving up, in
+	// that case it needs to be given back.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		s.releaseLocked(w.slot)
+	default:
+		s.removeLocked(&w)
+	}
+
+	return slot{}, err
+}
+
+// release gives the slot to the next waiting job or returns it to the pool.
+func (s *scheduler) release(sl slot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.releaseLocked(sl)
+}
+
+func (s *scheduler) releaseLocked(sl slot) {
+	if sl.reserved {
+		if w := s.popLane(sl.lane); w != nil {
+			s.grant(w, sl)
+			return
+		}
+
+		s.reserved[sl.lane]++
+		return
+	}
+
+	if w := s.next(); w != nil {
+		s.grant(w, slot{lane: w.priority})
+		return
+	}
+
+	s.shared++
+}
+
+func (s *scheduler) grant(w *waiter, sl slot) {
+	w.slot = sl
+	close(w.ready)
+}
+
+// next selects the waiter that should receive a shared slot. A waiter that
+// has waited longer than maxWait is served first, oldest first. Otherwise
+// the highest priority lane is served in FIFO order.
+func (s *scheduler) next() *waiter {
+	if s.maxWait > 0 {
+		var oldest *waiter
+		for p := range s.waiting {
+			if len(s.waiting[p]) == 0 {
+				continue
+			}
+
+			w := s.waiting[p][0]
+			if time.Since(w.enqueued) < s.maxWait {
+				continue
+			}
+
+			if oldest == nil || w.enqueued.Before(oldest.enqueued) {
+				oldest = w
+			}
+		}
+
+		if oldest != nil {
+			return s.popLane(oldest.priority)
+		}
+	}
+
+	for p := numPriorities - 1; p >= 0; p-- {
+		if w := s.popLane(Priority(p)); w != nil {
+			return w
+		}
+	}
+
+	return nil
+}
+
+func (s *scheduler) popLane(priority Priority) *waiter {
+	if len(s.waiting[priority]) == 0 {
+		return nil
+	}
+
+	w := s.waiting[priority][0]
+	s.waiting[priority] = s.waiting[priority][1:]
+
+	return w
+}
+
+func (s *scheduler) removeLocked(w *waiter) {
+	lane := s.waiting[w.priority]
+	for i := range lane {
+		if lane[i] == w {
+			s.waiting[w.priority] = append(lane[:i], lane[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *scheduler) hasWaiters() bool {
+	for p := range s.waiting {
+		if len(s.waiting[p]) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// waitingCount returns the number of jobs waiting in the specified lane.
+func (s *scheduler) waitingCount(priority Priority) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.waiting[priority])
+}
@@ -8,7 +8,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/ardanlabs/service/foundation/otel"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // JobFn defines a function that can execute work for a specific job.
@@ -18,25 +20,27 @@ type JobFn func(ctx context.Context)
 type Worker struct {
 	wg         sync.WaitGroup
 	mu         sync.RWMutex
-	sem        chan bool
+	sched      *scheduler
 	isShutdown chan struct{}
 	running    map[string]context.CancelFunc
 }
 
 // New constructs a Worker for managing and executing jobs. The capacity value
-// represents the maximum number of G's that can be executing at any given time.
-func New(maxRunningJobs int) (*Worker, error) {
+// represents the maximum number of G's that can be executing at any given time
+// across all priorities. Reserved slots per priority can be added with the
+// WithReserved option.
+func New(maxRunningJobs int, options ...func(opts *Options)) (*Worker, error) {
 	if maxRunningJobs <= 0 {
 		return nil, errors.New("max running jobs must be greater than 0")
 	}
 
-	sem := make(chan bool, maxRunningJobs)
-	for i := 0; i < maxRunningJobs; i++ {
-		sem <- true
+	var opts Options
+	for _, option := range options {
+		option(&opts)
 	}
 
 	w := Worker{
-		sem:        sem,
+		sched:      newScheduler(maxRunningJobs, opts),
 		isShutdown: make(chan struct{}),
 		running:    make(map[string]context.CancelFunc),
 	}
@@ -86,31 +90,52 @@ func (w *Worker) Shutdown(ctx context.Context) error {
 	}
 }
 
-// Start lookups a job by key and launches a goroutine to perform the work. A
+// Waiting returns the number of jobs waiting for a slot in the specified
+// priority lane.
+func (w *Worker) Waiting(priority Priority) int {
+	return w.sched.waitingCount(priority)
+}
+
+// Start launches a goroutine to perform the work at normal priority. A
 // work key is returned so the caller can cancel work early.
 func (w *Worker) Start(ctx context.Context, jobFn JobFn) (string, error) {
+	return w.StartPriority(ctx, PriorityNormal, jobFn)
+}
 
-	// We need to block here waiting to capture a semaphore, timeout or shutdown.
-	// The shutdown is first to handle that event as priority.
-	select {
-	case <-w.isShutdown:
-		return "", errors.New("shutting down")
-	case <-ctx.Done():
-		return "", ctx.Err()
-	case <-w.sem:
+// StartPriority launches a goroutine to perform the work in the specified
+// priority lane. A work key is returned so the caller can cancel work early.
+func (w *Worker) StartPriority(ctx context.Context, priority Priority, jobFn JobFn) (string, error) {
+	if !priority.valid() {
+		return "", fmt.Errorf("invalid priority %d", priority)
+	}
+
+	// We need to block here waiting to capture a slot, timeout or shutdown.
+	sl, err := w.sched.acquire(ctx, priority, w.isShutdown)
+	if err != nil {
+		return "", err
 	}
 
 	// Need a unique key for this work.
 	workKey := uuid.NewString()
 
+	// Record the hand off of the work so the job can be linked back to
+	// the trace that started it.
+	_, span := otel.AddProducerSpan(ctx, "foundation.worker.start", attribute.String("workKey", workKey), attribute.String("priority", priority.String()))
+	defer span.End()
+
+	producer := span.SpanContext()
+
 	// Let's continue with the current context's deadline.
 	deadline, ok := ctx.Deadline()
 	if !ok {
 		deadline = time.Now().Add(time.Second)
 	}
 
-	// Create a cancel function and keep it for stop/shutdown purposes.
-	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	// Create a cancel function and keep it for stop/shutdown purposes. The
+	// job starts from a new context that only carries the caller's trace,
+	// so it doesn't keep the caller's values, like a database transaction,
+	// alive or share its cancellation.
+	ctx, cancel := context.WithDeadline(otel.Detach(ctx), deadline)
 
 	// Register this new G as running.
 	w.trackWork(workKey, cancel)
@@ -120,9 +145,8 @@ func (w *Worker) Start(ctx context.Context, jobFn JobFn) (string, error) {
 	go func() {
 
 		// Do this in a separate defer in case the other defer panics.
-		// This adds a value back to the semaphore allowing a new message
-		// to be processed.
-		defer func() { w.sem <- true }()
+		// This gives the slot back allowing a new job to be processed.
+		defer func() { w.sched.release(sl) }()
 
 		// We must call cancel regardless, remove the work key and report
 		// to the outer G we are done.
@@ -133,6 +157,9 @@ func (w *Worker) Start(ctx context.Context, jobFn JobFn) (string, error) {
 		}()
 
 		// Execute the actual workload.
+		ctx, span := otel.AddConsumerSpan(ctx, producer, "foundation.worker.job", attribute.String("workKey", workKey))
+		defer span.End()
+
 		jobFn(ctx)
 	}()
 
@@ -6,7 +6,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ardanlabs/service/foundation/otel"
 	"github.com/ardanlabs/service/foundation/worker"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func Test_Worker(t *testing.T) {
@@ -150,3 +153,276 @@ func Test_StopWorker(t *testing.T) {
 		t.Fatalf("Should be able to shutdown work cleanly : %s", err)
 	}
 }
+
+func Test_PriorityWorker(t *testing.T) {
+	// Create a worker with a single shared slot.
+	w, err := worker.New(1)
+	if err != nil {
+		t.Fatalf("Should be able to create a worker with max 1 : %s", err)
+	}
+
+	// Occupy the only slot with a job that waits to be canceled.
+	block := func(ctx context.Context) {
+		<-ctx.Done()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	blockKey, err := w.Start(ctx, block)
+	if err != nil {
+		t.Fatalf("Should be able to execute work : %s", err)
+	}
+
+	// Queue a low and a high priority job behind the blocking job and
+	// record the order they are executed in.
+	var mu sync.Mutex
+	var order []worker.Priority
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	record := func(p worker.Priority) worker.JobFn {
+		return func(ctx context.Context) {
+			mu.Lock()
+			order = append(order, p)
+			mu.Unlock()
+			wg.Done()
+		}
+	}
+
+	for _, p := range []worker.Priority{worker.PriorityLow, worker.PriorityHigh} {
+		go w.StartPriority(ctx, p, record(p))
+
+		for w.Waiting(p) == 0 {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// Release the slot and wait for the queued jobs to complete.
+	if err := w.Stop(blockKey); err != nil {
+		t.Fatalf("Should be able to stop work : %s", err)
+	}
+
+	wg.Wait()
+
+	if order[0] != worker.PriorityHigh {
+		t.Errorf("Exp: %s", worker.PriorityHigh)
+		t.Errorf("Got: %s", order[0])
+		t.Error("Should execute the high priority job first")
+	}
+
+	if err := w.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Should be able to shutdown work cleanly : %s", err)
+	}
+}
+
+func Test_ReservedWorker(t *testing.T) {
+	// Create a worker with one shared slot and one slot reserved for high
+	// priority jobs.
+	w, err := worker.New(1, worker.WithReserved(worker.PriorityHigh, 1))
+	if err != nil {
+		t.Fatalf("Should be able to create a worker : %s", err)
+	}
+
+	block := func(ctx context.Context) {
+		<-ctx.Done()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// Occupy the shared slot with a low priority job.
+	if _, err := w.StartPriority(ctx, worker.PriorityLow, block); err != nil {
+		t.Fatalf("Should be able to execute low priority work : %s", err)
+	}
+
+	// A high priority job should still be able to start.
+	hctx, hcancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer hcancel()
+
+	if _, err := w.StartPriority(hctx, worker.PriorityHigh, block); err != nil {
+		t.Fatalf("Should be able to execute high priority work : %s", err)
+	}
+
+	if err := w.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Should be able to shutdown work cleanly : %s", err)
+	}
+}
+
+func Test_StarvationWorker(t *testing.T) {
+	// Create a worker with a single shared slot and starvation protection.
+	const maxWait = 50 * time.Millisecond
+
+	w, err := worker.New(1, worker.WithMaxWait(maxWait))
+	if err != nil {
+		t.Fatalf("Should be able to create a worker : %s", err)
+	}
+
+	// Occupy the only slot with a job that waits to be canceled.
+	block := func(ctx context.Context) {
+		<-ctx.Done()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	blockKey, err := w.Start(ctx, block)
+	if err != nil {
+		t.Fatalf("Should be able to execute work : %s", err)
+	}
+
+	var mu sync.Mutex
+	var order []worker.Priority
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	record := func(p worker.Priority) worker.JobFn {
+		return func(ctx context.Context) {
+			mu.Lock()
+			order = append(order, p)
+			mu.Unlock()
+			wg.Done()
+		}
+	}
+
+	// Queue a low priority job and let it wait past the max wait.
+	go w.StartPriority(ctx, worker.PriorityLow, record(worker.PriorityLow))
+
+	for w.Waiting(worker.PriorityLow) == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	time.Sleep(2 * maxWait)
+
+	// Queue a high priority job that hasn't waited long.
+	go w.StartPriority(ctx, worker.PriorityHigh, record(worker.PriorityHigh))
+
+	for w.Waiting(worker.PriorityHigh) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Release the slot and wait for the queued jobs to complete.
+	if err := w.Stop(blockKey); err != nil {
+		t.Fatalf("Should be able to stop work : %s", err)
+	}
+
+	wg.Wait()
+
+	if order[0] != worker.PriorityLow {
+		t.Errorf("Exp: %s", worker.PriorityLow)
+		t.Errorf("Got: %s", order[0])
+		t.Error("Should execute the starved low priority job first")
+	}
+
+	if err := w.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Should be able to shutdown work cleanly : %s", err)
+	}
+}
+
+// recorder keeps the spans that ended.
+type recorder struct {
+	mu    sync.Mutex
+	spans []sdktrace.ReadOnlySpan
+}
+
+func (r *recorder) OnStart(context.Context, sdktrace.ReadWriteSpan) {}
+func (r *recorder) Shutdown(context.Context) error                  { return nil }
+func (r *recorder) ForceFlush(context.Context) error                { return nil }
+
+func (r *recorder) OnEnd(s sdktrace.ReadOnlySpan) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.spans = append(r.spans, s)
+}
+
+func (r *recorder) span(name string) (sdktrace.ReadOnlySpan, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, s := range r.spans {
+		if s.Name() == name {
+			return s, true
+		}
+	}
+
+	return nil, false
+}
+
+func Test_TraceWorker(t *testing.T) {
+	type key struct{}
+
+	var rec recorder
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(&rec))
+	defer tp.Shutdown(context.Background())
+
+	ctx := otel.InjectTracing(context.Background(), tp.Tracer("test"))
+
+	ctx, span := otel.AddSpan(ctx, "request")
+	defer span.End()
+
+	ctx = context.WithValue(ctx, key{}, "value")
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	w, err := worker.New(1)
+	if err != nil {
+		t.Fatalf("Should be able to create a worker with max 1 : %s", err)
+	}
+
+	type result struct {
+		value   any
+		traceID trace.TraceID
+	}
+
+	results := make(chan result, 1)
+
+	work := func(ctx context.Context) {
+		results <- result{
+			value:   ctx.Value(key{}),
+			traceID: trace.SpanContextFromContext(ctx).TraceID(),
+		}
+	}
+
+	if _, err := w.Start(ctx, work); err != nil {
+		t.Fatalf("Should be able to execute work : %s", err)
+	}
+
+	// The caller is done with its context once the job is handed off.
+	cancel()
+
+	got := <-results
+
+	if got.value != nil {
+		t.Errorf("Got: %v", got.value)
+		t.Error("Should not pass the caller's values to the job")
+	}
+
+	if got.traceID != span.SpanContext().TraceID() {
+		t.Errorf("Exp: %s", span.SpanContext().TraceID())
+		t.Errorf("Got: %s", got.traceID)
+		t.Error("Should run the job in the caller's trace")
+	}
+
+	if err := w.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Should be able to shutdown work cleanly : %s", err)
+	}
+
+	producer, ok := rec.span("foundation.worker.start")
+	if !ok {
+		t.Fatal("Should record the hand off of the job")
+	}
+
+	job, ok := rec.span("foundation.worker.job")
+	if !ok {
+		t.Fatal("Should record the job")
+	}
+
+	if links := job.Links(); len(links) != 1 || links[0].SpanContext.SpanID() != producer.SpanContext().SpanID() {
+		t.Errorf("Got: %v", links)
+		t.Error("Should link the job to the hand off")
+	}
+}